@@ -0,0 +1,390 @@
+package builder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// defaultLogLimit is how many request log entries or events GET /logs and
+// GET /events return by default
+const defaultLogLimit = 100
+
+// resetPath is where the app's own listener takes admin resets
+const resetPath = "/_flawfactory/reset"
+
+// vulnToggles records the vulnerabilities switched off through the admin API.
+// It outlives reloads, so a toggle holds until it is switched back.
+type vulnToggles struct {
+	mu       sync.Mutex
+	disabled map[string]bool // By vulnerability ID
+}
+
+// newVulnToggles creates toggles with every vulnerability on
+func newVulnToggles() *vulnToggles {
+	return &vulnToggles{disabled: make(map[string]bool)}
+}
+
+// enabled reports whether a vulnerability is on
+func (t *vulnToggles) enabled(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.disabled[id]
+}
+
+// set switches a vulnerability on or off
+func (t *vulnToggles) set(id string, enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if enabled {
+		delete(t.disabled, id)
+	} else {
+		t.disabled[id] = true
+	}
+}
+
+// vulnID identifies the vulnerability at index of an endpoint, e.g. "GET /search#0"
+func vulnID(endpoint config.EndpointConfig, index int) string {
+	return fmt.Sprintf("%s#%d", endpointKey(endpoint), index)
+}
+
+// enabledVulnerabilities returns the endpoint's vulnerabilities that aren't
+// switched off, in their configured order
+func (b *Builder) enabledVulnerabilities(endpoint config.EndpointConfig) []config.VulnerabilityConfig {
+	enabled := make([]config.VulnerabilityConfig, 0, len(endpoint.Vulnerabilities))
+	for i, vuln := range endpoint.Vulnerabilities {
+		if b.toggles.enabled(vulnID(endpoint, i)) {
+			enabled = append(enabled, vuln)
+		}
+	}
+	return enabled
+}
+
+// vulnReport describes a vulnerability in the admin API
+type vulnReport struct {
+	ID        string `json:"id"`
+	Endpoint  string `json:"endpoint"`
+	Type      string `json:"type"`
+	Placement string `json:"placement"`
+	Param     string `json:"param,omitempty"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// vulnReports lists every configured vulnerability and whether it is on
+func (b *Builder) vulnReports() []vulnReport {
+	reports := []vulnReport{}
+	for _, endpoint := range b.config.Endpoints {
+		for i, vuln := range endpoint.Vulnerabilities {
+			id := vulnID(endpoint, i)
+			reports = append(reports, vulnReport{
+				ID:        id,
+				Endpoint:  endpointKey(endpoint),
+				Type:      vuln.Type,
+				Placement: vuln.Placement,
+				Param:     vuln.Param,
+				Enabled:   b.toggles.enabled(id),
+			})
+		}
+	}
+	return reports
+}
+
+// ResetState puts the lab back as it was seeded: the database, files, sessions,
+// module state, captured mail, OOB interactions, chain progress and isolated
+// copies. It returns what was reset.
+func (b *Builder) ResetState() ([]string, error) {
+	var reset []string
+	if b.sinks.sqlite != nil && b.sinks.seed != nil {
+		if err := b.sinks.sqlite.Restore(b.sinks.seed); err != nil {
+			return reset, fmt.Errorf("failed to reset the database: %w", err)
+		}
+		reset = append(reset, "database")
+	}
+	if b.sinks.filesystem != nil {
+		if err := b.ResetFilesystem(); err != nil {
+			return reset, fmt.Errorf("failed to reset the filesystem: %w", err)
+		}
+		reset = append(reset, "filesystem")
+	}
+	if b.sessions != nil {
+		b.sessions.Clear()
+		reset = append(reset, "sessions")
+	}
+	b.state.Clear()
+	reset = append(reset, "module_state")
+	if b.sinks.mail != nil {
+		b.sinks.mail.Clear()
+		reset = append(reset, "mail")
+	}
+	if b.sinks.oob != nil {
+		b.sinks.oob.Clear()
+		reset = append(reset, "oob")
+	}
+	if b.sinks.copies != nil {
+		b.sinks.copies.reset()
+		reset = append(reset, "isolated_copies")
+	}
+	b.chains.reset()
+	return append(reset, "chains"), nil
+}
+
+// serveAdmin registers the admin API on a fresh router and swaps it into srv,
+// when app.admin is set
+func (b *Builder) serveAdmin(srv *server.Server) {
+	admin := b.config.App.Admin
+	if admin == nil {
+		return
+	}
+	router := server.NewRouter(nil)
+	b.registerAdminEndpoints(router, srv)
+	srv.ServeAdmin(admin.Address(b.config.App.Port), admin.Token, router)
+}
+
+// registerAdminEndpoints serves the admin API:
+//
+//	GET /routes            the app's registered routes
+//	GET /stats             sink health and activity, sessions and isolated clients
+//	POST /reset            reset the lab to its seeded state
+//	GET /vulnerabilities   every vulnerability and whether it is on
+//	PUT /vulnerabilities   switch one on or off: {"id": "GET /search#0", "enabled": false}
+//	GET /logs              the last request log entries of a json or ecs log (?limit=, default 100)
+//	GET /events            the last exploitation events (?limit=, default 100)
+//	GET /coverage          which vulnerabilities were attacked and exploited, how often, with which attack types and by whom
+//	DELETE /coverage       start counting coverage afresh
+//	GET /clients           a profile of each client: when it was seen, the endpoints it probed, the
+//	                       modules it triggered, and the tools, user agents and sessions it used
+//	GET /clients/{client}  the profile of one client, by IP
+//	DELETE /clients        forget the profiles
+//	GET /stream            requests and exploitation events as they happen, as
+//	                       server-sent events or over a WebSocket (?types=request,exploitation)
+//	GET /capture.har       the recorded request/response pairs as a HAR file, with app.capture
+//	GET /capture.pcap      the listener's recorded TCP streams as a pcap file, with app.capture.pcap
+//	DELETE /capture        drop the recorded traffic
+func (b *Builder) registerAdminEndpoints(router *server.Router, srv *server.Server) {
+	router.HandleFunc("GET", "/routes", func(w http.ResponseWriter, r *http.Request) {
+		routes := srv.Router().Routes()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(routes), "routes": routes})
+	})
+
+	router.HandleFunc("GET", "/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats := map[string]interface{}{
+			"app":   b.config.App.Name,
+			"sinks": b.sinkReports(),
+		}
+		if b.sessions != nil {
+			stats["sessions"] = b.sessions.Count()
+		}
+		if b.sinks.copies != nil {
+			stats["isolated_clients"] = b.sinks.copies.count()
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})
+
+	router.HandleFunc("POST", "/reset", b.serveReset)
+
+	router.HandleFunc("GET", "/vulnerabilities", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"vulnerabilities": b.vulnReports()})
+	})
+
+	router.HandleFunc("PUT", "/vulnerabilities", func(w http.ResponseWriter, r *http.Request) {
+		var toggle struct {
+			ID      string `json:"id"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&toggle); err != nil || toggle.ID == "" || toggle.Enabled == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": `expected {"id": "...", "enabled": true|false}`})
+			return
+		}
+		for _, report := range b.vulnReports() {
+			if report.ID == toggle.ID {
+				b.toggles.set(toggle.ID, *toggle.Enabled)
+				report.Enabled = *toggle.Enabled
+				writeJSON(w, http.StatusOK, report)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "unknown vulnerability " + toggle.ID})
+	})
+
+	router.HandleFunc("GET", "/logs", func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := queryLimit(w, r)
+		if !ok {
+			return
+		}
+		if b.logFilePath == "" {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "request logging is off"})
+			return
+		}
+		if format := b.config.App.Logging.FormatName(); format == "cef" {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "request logs are written in " + format + ", and only JSON logs (json, ecs) can be listed"})
+			return
+		}
+		entries, err := tailLog(b.logFilePath, limit)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(entries), "entries": entries})
+	})
+
+	router.HandleFunc("GET", "/stream", srv.Feed().ServeHTTP)
+
+	router.HandleFunc("GET", "/events", func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := queryLimit(w, r)
+		if !ok {
+			return
+		}
+		events := b.events.last(limit)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(events), "events": events})
+	})
+
+	router.HandleFunc("GET", "/coverage", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.coverageReport())
+	})
+
+	router.HandleFunc("DELETE", "/coverage", func(w http.ResponseWriter, r *http.Request) {
+		b.coverage.reset()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cleared": true})
+	})
+
+	router.HandleFunc("GET", "/clients", func(w http.ResponseWriter, r *http.Request) {
+		profiles := srv.Profiles().List()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(profiles), "clients": profiles})
+	})
+
+	router.HandleFunc("GET", "/clients/{client}", func(w http.ResponseWriter, r *http.Request) {
+		profile, ok := srv.Profiles().Get(r.PathValue("client"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "no requests from " + r.PathValue("client")})
+			return
+		}
+		writeJSON(w, http.StatusOK, profile)
+	})
+
+	router.HandleFunc("DELETE", "/clients", func(w http.ResponseWriter, r *http.Request) {
+		srv.Profiles().Clear()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cleared": true})
+	})
+
+	router.HandleFunc("GET", "/capture.har", func(w http.ResponseWriter, r *http.Request) {
+		if srv.Capture() == nil {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "traffic capture is off, set app.capture to record it"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+b.captureName()+`.har"`)
+		srv.Capture().WriteHAR(w)
+	})
+
+	router.HandleFunc("GET", "/capture.pcap", func(w http.ResponseWriter, r *http.Request) {
+		if !srv.Capture().RecordsPCAP() {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "packet capture is off, set app.capture.pcap to record it"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+b.captureName()+`.pcap"`)
+		srv.Capture().WritePCAP(w)
+	})
+
+	router.HandleFunc("DELETE", "/capture", func(w http.ResponseWriter, r *http.Request) {
+		if srv.Capture() == nil {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "traffic capture is off, set app.capture to record it"})
+			return
+		}
+		srv.Capture().Clear()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"cleared": true})
+	})
+}
+
+// captureName names exported captures after the app, e.g. "internal-api"
+func (b *Builder) captureName() string {
+	name := strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, b.config.App.Name), "-")
+	if name == "" {
+		return "capture"
+	}
+	return name
+}
+
+// queryLimit reads the ?limit= of a listing, defaultLogLimit if unset. An
+// invalid limit is answered with 400 and ok false.
+func queryLimit(w http.ResponseWriter, r *http.Request) (limit int, ok bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLogLimit, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid limit"})
+		return 0, false
+	}
+	return n, true
+}
+
+// registerResetEndpoint lets grading pipelines reset the lab between attempts
+// through the app's own listener, with the admin token:
+//
+//	POST /_flawfactory/reset   reset the lab to its seeded state
+func (b *Builder) registerResetEndpoint(router *server.Router) {
+	admin := b.config.App.Admin
+	if admin == nil {
+		return
+	}
+	router.HandleFunc("POST", resetPath, server.AdminAuth(admin.Token, http.HandlerFunc(b.serveReset)).ServeHTTP)
+}
+
+// serveReset resets the lab and reports what was reset
+func (b *Builder) serveReset(w http.ResponseWriter, r *http.Request) {
+	reset, err := b.ResetState()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error(), "reset": reset})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reset": reset})
+}
+
+// tailLog returns the last limit entries of a JSON lines request log, oldest first
+func tailLog(path string, limit int) ([]json.RawMessage, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []json.RawMessage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []json.RawMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !json.Valid(line) {
+			continue
+		}
+		entries = append(entries, json.RawMessage(append([]byte(nil), line...)))
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request log: %w", err)
+	}
+	if entries == nil {
+		entries = []json.RawMessage{}
+	}
+	return entries, nil
+}
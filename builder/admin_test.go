@@ -0,0 +1,233 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// TestAdminEndpoints tests listing routes, toggling a vulnerability off and
+// resetting the lab through the admin API
+func TestAdminEndpoints(t *testing.T) {
+	adminConfig := func() *config.Config {
+		cfg := reloadConfig("/file", "v1")
+		cfg.App.Admin = &config.AdminConfig{Token: "secret"}
+		return cfg
+	}
+	b := New(adminConfig(), "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer func() { b.Close() }()
+
+	admin := func(method, target, body string) map[string]interface{} {
+		rec := httptest.NewRecorder()
+		srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		response["status"] = rec.Code
+		return response
+	}
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+
+	routes, _ := admin("GET", "/routes", "")["routes"].([]interface{})
+	if !slices.Contains(routes, interface{}("GET /file")) {
+		t.Errorf("Expected GET /file among the routes, got %v", routes)
+	}
+
+	if response := admin("PUT", "/vulnerabilities", `{"id": "GET /file#0", "enabled": false}`); response["enabled"] != false {
+		t.Fatalf("Expected the vulnerability switched off, got %v", response)
+	}
+	if body := get("/file?name=notes.txt"); !strings.Contains(body, "Hello from FlawFactory") {
+		t.Errorf("Expected the endpoint to answer as if unconfigured, got %s", body)
+	}
+	if response := admin("PUT", "/vulnerabilities", `{"id": "GET /nope#0", "enabled": false}`); response["status"] != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown vulnerability, got %v", response)
+	}
+
+	if err := b.sinks.filesystem.WriteFile("upload.txt", "uploaded"); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if response := admin("POST", "/reset", ""); response["status"] != http.StatusOK {
+		t.Fatalf("Expected the reset to succeed, got %v", response)
+	}
+	if b.sinks.filesystem.Exists("upload.txt") {
+		t.Error("Expected the reset to remove the upload")
+	}
+
+	// Toggles hold across a reload, served by the reloaded builder
+	if b, _, err = b.Reload(srv, adminConfig()); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	vulns, _ := admin("GET", "/vulnerabilities", "")["vulnerabilities"].([]interface{})
+	if len(vulns) != 1 || vulns[0].(map[string]interface{})["enabled"] != false {
+		t.Errorf("Expected the vulnerability to stay off, got %v", vulns)
+	}
+
+	if response := admin("GET", "/logs", ""); response["status"] != http.StatusNotFound {
+		t.Errorf("Expected 404 without a request log, got %v", response)
+	}
+	if response := admin("GET", "/capture.har", ""); response["status"] != http.StatusNotFound {
+		t.Errorf("Expected 404 without app.capture, got %v", response)
+	}
+}
+
+// TestCaptureEndpoints tests the recorded traffic is exported as HAR and
+// cleared through the admin API, and PCAP is only served when recorded
+func TestCaptureEndpoints(t *testing.T) {
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.Name = "Capture Lab"
+	cfg.App.Admin = &config.AdminConfig{Token: "secret"}
+	cfg.App.Capture = &config.CaptureConfig{}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+
+	srv.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil))
+	admin := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := admin("GET", "/capture.har")
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					Content struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &har)
+	if len(har.Log.Entries) != 1 || !strings.Contains(har.Log.Entries[0].Response.Content.Text, "v1") {
+		t.Fatalf("Expected the request and the file it served, got %s", rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="capture-lab.har"` {
+		t.Errorf("Expected the file named after the app, got %s", disposition)
+	}
+
+	if rec := admin("GET", "/capture.pcap"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without app.capture.pcap, got %d", rec.Code)
+	}
+	if rec := admin("DELETE", "/capture"); rec.Code != http.StatusOK || srv.Capture().Len() != 0 {
+		t.Errorf("Expected the capture cleared, got %d with %d entries", rec.Code, srv.Capture().Len())
+	}
+}
+
+// TestClientsEndpoints tests the admin API reports each client's profile,
+// with the sessions of app.sessions
+func TestClientsEndpoints(t *testing.T) {
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.Admin = &config.AdminConfig{Token: "secret"}
+	cfg.App.Sessions = &config.SessionsConfig{Cookie: "lab_sid"}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil)
+	req.RemoteAddr = "10.0.0.9:40000"
+	req.Header.Set("User-Agent", "Nuclei - Open-source project (github.com/projectdiscovery/nuclei)")
+	req.AddCookie(&http.Cookie{Name: "lab_sid", Value: "s1"})
+	srv.Router().ServeHTTP(httptest.NewRecorder(), req)
+	admin := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	var list struct {
+		Count   int                    `json:"count"`
+		Clients []server.ClientProfile `json:"clients"`
+	}
+	json.Unmarshal(admin("GET", "/clients").Body.Bytes(), &list)
+	if list.Count != 1 || list.Clients[0].Client != "10.0.0.9" {
+		t.Fatalf("Expected the client listed, got %+v", list)
+	}
+	var profile server.ClientProfile
+	rec := admin("GET", "/clients/10.0.0.9")
+	json.Unmarshal(rec.Body.Bytes(), &profile)
+	if profile.Endpoints["GET /file"] != 1 || !slices.Equal(profile.Tools, []string{"Nuclei"}) || !slices.Equal(profile.Sessions, []string{"s1"}) {
+		t.Errorf("Expected the endpoint, tool and session, got %s", rec.Body.String())
+	}
+	if rec := admin("GET", "/clients/10.0.0.10"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unseen client, got %d", rec.Code)
+	}
+	if rec := admin("DELETE", "/clients"); rec.Code != http.StatusOK || len(srv.Profiles().List()) != 0 {
+		t.Errorf("Expected the profiles cleared, got %d", rec.Code)
+	}
+}
+
+// TestResetEndpoint tests the app's own listener resets the lab, files and
+// sessions included, for requests with the admin token
+func TestResetEndpoint(t *testing.T) {
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.Admin = &config.AdminConfig{Token: "secret"}
+	cfg.App.Sessions = &config.SessionsConfig{}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+
+	b.sinks.filesystem.WriteFile("upload.txt", "uploaded")
+	if _, err := b.sessions.Start(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+
+	reset := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, resetPath, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := reset("wrong"); code != http.StatusUnauthorized || !b.sinks.filesystem.Exists("upload.txt") {
+		t.Fatalf("Expected a wrong token to be refused, got %d", code)
+	}
+	if code := reset("secret"); code != http.StatusOK {
+		t.Fatalf("Expected the reset to succeed, got %d", code)
+	}
+	if b.sinks.filesystem.Exists("upload.txt") || b.sessions.Count() != 0 {
+		t.Errorf("Expected the upload and sessions gone, %d sessions left", b.sessions.Count())
+	}
+}
+
+// TestTailLog tests the last entries of a request log are returned, oldest first
+func TestTailLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.json")
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\nnot json\n{\"n\":3}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	entries, err := tailLog(path, 2)
+	if err != nil || len(entries) != 2 || string(entries[0]) != `{"n":2}` || string(entries[1]) != `{"n":3}` {
+		t.Errorf("Expected the last two entries, got %s (%v)", entries, err)
+	}
+	if entries, err := tailLog(filepath.Join(t.TempDir(), "missing.json"), 2); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries for a missing log, got %s (%v)", entries, err)
+	}
+}
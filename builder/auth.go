@@ -0,0 +1,269 @@
+package builder
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// authColumns are the columns of the auth table seeded from auth.users
+var authColumns = []string{"username", "password", "role", "token"}
+
+// userFinder returns the row of the auth table whose column equals value, or nil
+type userFinder func(column, value string) (map[string]interface{}, error)
+
+// authenticator is the login wall in front of protected endpoints
+type authenticator struct {
+	config *config.AuthConfig
+	realm  string
+	find   userFinder
+	random io.Reader // Issued tokens; nil for crypto/rand
+
+	// Session auth logs users in to the app's sessions, where modules see them
+	sessions *server.SessionStore
+
+	mu     sync.Mutex
+	tokens map[string]string // Issued bearer tokens, to usernames
+}
+
+// newAuthenticator creates the login wall for an auth section
+func newAuthenticator(cfg *config.AuthConfig, appName string, find userFinder, random io.Reader, sessions *server.SessionStore) *authenticator {
+	realm := cfg.Realm
+	if realm == "" {
+		realm = appName
+	}
+	return &authenticator{
+		config:   cfg,
+		realm:    realm,
+		find:     find,
+		random:   random,
+		sessions: sessions,
+		tokens:   make(map[string]string),
+	}
+}
+
+// findUser looks a user up in the auth table with a bound parameter, so the
+// login wall itself can't be injected
+func (b *Builder) findUser(column, value string) (map[string]interface{}, error) {
+	if b.sinks.sqlite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ? LIMIT 1", b.config.Auth.TableName(), column)
+	rows, err := b.sinks.sqlite.QueryArgs(query, value)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// seedAuthUsers stores the configured users in the auth table, replacing any
+// left in a persistent database
+func (b *Builder) seedAuthUsers() error {
+	auth := b.config.Auth
+	if auth == nil || len(auth.Users) == 0 || b.sinks.sqlite == nil {
+		return nil
+	}
+
+	rows := make([][]interface{}, len(auth.Users))
+	for i, user := range auth.Users {
+		rows[i] = []interface{}{user.Username, user.Password, user.Role, user.Token}
+	}
+
+	table := auth.TableName()
+	if err := b.sinks.sqlite.DropTable(table); err != nil {
+		return err
+	}
+	if err := b.sinks.sqlite.SeedTable(table, authColumns, rows); err != nil {
+		return fmt.Errorf("failed to seed table %s: %w", table, err)
+	}
+	log.Printf("Seeded table '%s' with %d auth users", table, len(rows))
+	return nil
+}
+
+// withAuth puts a protected endpoint's handler behind the login wall
+func (b *Builder) withAuth(endpoint config.EndpointConfig, responseType string, next http.HandlerFunc) http.HandlerFunc {
+	if !b.config.Auth.Protects(endpoint) {
+		return next
+	}
+	a := b.auth
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := a.authenticate(r); err != nil {
+			a.challenge(w)
+			server.NewResponseBuilder().SendError(w, responseType, http.StatusUnauthorized, "authentication required", server.DebugInfo{
+				Message: err.Error(),
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authenticate returns the username the request's credentials belong to
+func (a *authenticator) authenticate(r *http.Request) (string, error) {
+	switch a.config.Type {
+	case "basic":
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return "", errors.New("no basic credentials in the Authorization header")
+		}
+		return a.checkPassword(username, password)
+
+	case "session":
+		if _, err := r.Cookie(a.sessions.CookieName()); err != nil {
+			return "", fmt.Errorf("no %s cookie; log in at POST %s", a.sessions.CookieName(), a.config.LoginRoute())
+		}
+		if session := a.sessions.Lookup(r); session != nil && session.Get(server.SessionUserKey) != "" {
+			return session.Get(server.SessionUserKey), nil
+		}
+		return "", errors.New("invalid or expired session")
+
+	case "bearer":
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return "", fmt.Errorf("no bearer token in the Authorization header; get one at POST %s", a.config.LoginRoute())
+		}
+		if username, ok := a.issuedToken(token); ok {
+			return username, nil
+		}
+		return a.checkToken(token)
+
+	case "api_key":
+		key := r.Header.Get(a.config.HeaderName())
+		if key == "" {
+			return "", fmt.Errorf("no API key in the %s header", a.config.HeaderName())
+		}
+		return a.checkToken(key)
+	}
+	return "", fmt.Errorf("unsupported auth type '%s'", a.config.Type)
+}
+
+// checkPassword verifies a username and password against the auth table
+func (a *authenticator) checkPassword(username, password string) (string, error) {
+	// Token-only users have no password to log in with
+	if password == "" {
+		return "", errors.New("invalid username or password")
+	}
+	user, err := a.find("username", username)
+	if err != nil {
+		return "", err
+	}
+	if user == nil || subtle.ConstantTimeCompare([]byte(fmt.Sprint(user["password"])), []byte(password)) != 1 {
+		return "", errors.New("invalid username or password")
+	}
+	return username, nil
+}
+
+// checkToken finds the user a token or API key belongs to
+func (a *authenticator) checkToken(token string) (string, error) {
+	user, err := a.find("token", token)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", errors.New("invalid token")
+	}
+	return fmt.Sprint(user["username"]), nil
+}
+
+// issuedToken returns the user an issued bearer token belongs to
+func (a *authenticator) issuedToken(token string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	username, ok := a.tokens[token]
+	return username, ok
+}
+
+// issueToken issues a new bearer token for a user
+func (a *authenticator) issueToken(username string) (string, error) {
+	source := a.random
+	if source == nil {
+		source = rand.Reader
+	}
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(source, buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[token] = username
+	return token, nil
+}
+
+// challenge tells the client how to authenticate
+func (a *authenticator) challenge(w http.ResponseWriter) {
+	switch a.config.Type {
+	case "basic":
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", a.realm))
+	case "bearer":
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", a.realm))
+	}
+}
+
+// registerLoginEndpoint serves the login for session and bearer auth. It takes
+// a username and password as form fields or JSON and sets a session cookie or
+// returns a bearer token.
+func (b *Builder) registerLoginEndpoint(router *server.Router) {
+	a := b.auth
+	if a == nil || (a.config.Type != "session" && a.config.Type != "bearer") {
+		return
+	}
+
+	router.HandleFunc("POST", a.config.LoginRoute(), func(w http.ResponseWriter, r *http.Request) {
+		username, password := loginCredentials(r)
+		if _, err := a.checkPassword(username, password); err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "invalid username or password"})
+			return
+		}
+
+		if a.config.Type == "bearer" {
+			token, err := a.issueToken(username)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"token":      token,
+				"token_type": "Bearer",
+				"username":   username,
+			})
+			return
+		}
+
+		// The session keeps what modules stored in it before the login
+		if _, err := a.sessions.Login(w, r, username); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message":  "logged in",
+			"username": username,
+		})
+	})
+}
+
+// loginCredentials reads the username and password from a JSON body or form fields
+func loginCredentials(r *http.Request) (string, string) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body)
+		return body.Username, body.Password
+	}
+	return r.FormValue("username"), r.FormValue("password")
+}
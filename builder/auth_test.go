@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// authBuilder returns a builder whose login wall checks users held in memory
+// rather than in SQLite
+func authBuilder(auth *config.AuthConfig) *Builder {
+	users := []map[string]interface{}{
+		{"username": "alice", "password": "wonderland", "role": "admin", "token": "key_alice"},
+		{"username": "bob", "password": "", "role": "user", "token": "key_bob"},
+	}
+	find := func(column, value string) (map[string]interface{}, error) {
+		for _, user := range users {
+			if user[column] == value {
+				return user, nil
+			}
+		}
+		return nil, nil
+	}
+
+	b := &Builder{config: &config.Config{App: config.AppConfig{Name: "lab"}, Auth: auth}}
+	b.sessions = newSessionStore(b.config, newRandom(1))
+	b.auth = newAuthenticator(auth, b.config.App.Name, find, newRandom(1), b.sessions)
+	return b
+}
+
+// TestWithAuth tests each auth type lets valid credentials through and challenges the rest
+func TestWithAuth(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		authType string
+		set      func(r *http.Request)
+		expected int
+	}{
+		{"basic", func(r *http.Request) { r.SetBasicAuth("alice", "wonderland") }, http.StatusOK},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("alice", "guess") }, http.StatusUnauthorized},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("bob", "") }, http.StatusUnauthorized},
+		{"basic", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer key_bob") }, http.StatusOK},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"api_key", func(r *http.Request) { r.Header.Set("X-API-Key", "key_alice") }, http.StatusOK},
+		{"api_key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer key_alice") }, http.StatusUnauthorized},
+		{"session", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "session", Value: "forged"}) }, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		b := authBuilder(&config.AuthConfig{Type: tt.authType})
+		handler := b.withAuth(config.EndpointConfig{Path: "/admin", Method: "GET"}, "json", ok)
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		tt.set(req)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != tt.expected {
+			t.Errorf("%s %v: expected %d, got %d", tt.authType, req.Header, tt.expected, rec.Code)
+		}
+		if tt.authType == "basic" && rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Basic realm="lab"` {
+			t.Errorf("Expected a basic challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// TestWithAuth_Protect tests which endpoints protect: listed and auth: none leave public
+func TestWithAuth_Protect(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	tests := []struct {
+		protect  string
+		auth     string
+		expected int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"", "none", http.StatusOK},
+		{"listed", "", http.StatusOK},
+		{"listed", "required", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		b := authBuilder(&config.AuthConfig{Type: "basic", Protect: tt.protect})
+		handler := b.withAuth(config.EndpointConfig{Path: "/", Method: "GET", Auth: tt.auth}, "json", ok)
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != tt.expected {
+			t.Errorf("protect %q, auth %q: expected %d, got %d", tt.protect, tt.auth, tt.expected, rec.Code)
+		}
+	}
+}
+
+// TestLoginEndpoint tests logging in issues a session cookie or bearer token the wall accepts
+func TestLoginEndpoint(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	endpoint := config.EndpointConfig{Path: "/admin", Method: "GET"}
+
+	// Session: form login sets the cookie
+	b := authBuilder(&config.AuthConfig{Type: "session", Cookie: "sid"})
+	router := server.NewRouter(nil)
+	b.registerLoginEndpoint(router)
+
+	form := url.Values{"username": {"alice"}, "password": {"wonderland"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != "sid" || !cookies[0].HttpOnly {
+		t.Fatalf("Expected a session cookie, got %d %v", rec.Code, cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	b.withAuth(endpoint, "json", ok)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the session to be accepted, got %d", rec.Code)
+	}
+
+	// Bearer: JSON login returns a token; wrong passwords are rejected
+	b = authBuilder(&config.AuthConfig{Type: "bearer", LoginPath: "/api/token"})
+	router = server.NewRouter(nil)
+	b.registerLoginEndpoint(router)
+
+	login := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/token", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := login(`{"username":"alice","password":"guess"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong password to fail, got %d", rec.Code)
+	}
+
+	rec = login(`{"username":"alice","password":"wonderland"}`)
+	var issued struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &issued); err != nil || issued.Token == "" {
+		t.Fatalf("Expected a token, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	rec = httptest.NewRecorder()
+	b.withAuth(endpoint, "json", ok)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the issued token to be accepted, got %d", rec.Code)
+	}
+}
@@ -1,23 +1,46 @@
 package builder
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strings"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/RIZZZIOM/FlawFactory/config"
 	"github.com/RIZZZIOM/FlawFactory/modules"
 	"github.com/RIZZZIOM/FlawFactory/server"
 	"github.com/RIZZZIOM/FlawFactory/sinks"
+	"github.com/RIZZZIOM/FlawFactory/tracing"
 )
 
 // Builder constructs the server from configuration
 type Builder struct {
 	config      *config.Config
+	tables      map[string]config.TableConfig // Configured tables with generated rows added
+	chains      *chainProgress                // Steps of each chain completed per client
+	auth        *authenticator                // Login wall, or nil without an auth section
+	sessions    *server.SessionStore          // Sessions, or nil without app.sessions or session auth
+	state       *modules.State                // What modules keep between requests, for this app
+	toggles     *vulnToggles                  // Vulnerabilities switched off through the admin API
+	events      *exploitEvents                // Latest exploitation events, for the admin API
+	coverage    *coverage                     // Vulnerabilities requests triggered, for the admin API
+	notifiers   []*notifier                   // Targets of exploitation events, from app.notifications
+	notifying   sync.WaitGroup                // Events being sent to the notifiers
+	grpc        *server.Router                // Routes of the grpc endpoints, or nil without app.grpc
+	random      io.Reader                     // Seeded from app.seed, or nil for crypto/rand
+	flags       map[string]string             // Flag values by name, for ${flag:NAME} references
+	flagEnv     []string                      // NAME=value for flags set in the environment of commands
 	sinks       *SinkManager
 	logFilePath string
+	stop        chan struct{}   // Closed by Close to stop background tasks
+	running     []runningModule // Modules started by Init, in start order
+	inMemory    bool            // Set by InMemory and DryRun, keeping a persistent database in memory
 }
 
 // SinkManager holds all initialized sinks
@@ -26,141 +49,449 @@ type SinkManager struct {
 	filesystem *sinks.Filesystem
 	command    *sinks.Command
 	httpSink   *sinks.HTTP
+	redis      *sinks.Redis
+	documents  *sinks.DocumentStore
+	ldap       *sinks.LDAP
+	mail       *sinks.SMTP
+	oob        *sinks.OOB
+	template   *sinks.Template
+	objects    *sinks.ObjectStore
+	metadata   *sinks.MetadataService
+	stats      sinkStats
+	copies     *sinkCopies   // Each client's copy of the SQLite database and filesystem, with app.isolation
+	seed       *sinks.SQLite // The database as seeded, kept for isolated copies and admin resets
+
+	initialized []namedSink // In initialization order, including plugin sinks
 }
 
 // New creates a new builder for the given configuration
-// logFilePath specifies where to save JSON request logs (empty string disables logging)
+// logFilePath specifies where to save request logs (empty string disables logging)
 func New(cfg *config.Config, logFilePath string) *Builder {
-	return &Builder{
+	b := &Builder{
 		config:      cfg,
 		sinks:       &SinkManager{},
+		chains:      newChainProgress(),
+		toggles:     newVulnToggles(),
+		events:      newExploitEvents(),
+		coverage:    newCoverage(),
+		notifiers:   newNotifiers(cfg),
+		random:      newRandom(cfg.App.Seed),
+		state:       modules.NewState(),
 		logFilePath: logFilePath,
 	}
+	b.sessions = newSessionStore(cfg, b.random)
+	if cfg.Auth != nil {
+		b.auth = newAuthenticator(cfg.Auth, cfg.App.Name, b.findUser, b.random, b.sessions)
+	}
+	return b
 }
 
 // Build initializes all sinks and returns a configured server
 func (b *Builder) Build() (*server.Server, error) {
+	if err := b.prepare(); err != nil {
+		return nil, err
+	}
+
+	// Determine host (default to 127.0.0.1 if not specified)
+	host := b.config.App.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	// Create the server with JSON logging and TLS config
+	srv, err := server.New(host, b.config.App.Port, b.logFilePath, b.config.App.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+	srv.ConfigureHTTP(b.config.App.HTTP)
+	if err := srv.ConfigureLogging(b.config.App.Logging, b.config.App.Name); err != nil {
+		return nil, fmt.Errorf("failed to configure logging: %w", err)
+	}
+	b.redactSecrets(srv)
+	srv.ConfigureTracing(b.config.App.Tracing, b.config.App.Name)
+	srv.ConfigureCapture(b.config.App.Capture)
+	b.events.feed = srv.Feed()
+
+	if err := b.registerRoutes(srv.Router()); err != nil {
+		return nil, err
+	}
+	b.serveAdmin(srv)
+	b.serveGRPC(srv)
+
+	return srv, nil
+}
+
+// prepare initializes the sinks and seeds them from the config
+func (b *Builder) prepare() error {
+	// Generate the flags placed in the data, files and responses
+	if err := b.generateFlags(); err != nil {
+		return err
+	}
+
 	// Initialize sinks based on what modules need
 	if err := b.initializeSinks(); err != nil {
-		return nil, fmt.Errorf("failed to initialize sinks: %w", err)
+		return fmt.Errorf("failed to initialize sinks: %w", err)
 	}
 
 	// Seed database with data from config
 	if err := b.seedDatabase(); err != nil {
-		return nil, fmt.Errorf("failed to seed database: %w", err)
+		return fmt.Errorf("failed to seed database: %w", err)
+	}
+
+	// Seed the login wall's users
+	if err := b.seedAuthUsers(); err != nil {
+		return fmt.Errorf("failed to seed auth users: %w", err)
+	}
+
+	// Seed Redis keys from config
+	if err := b.seedRedis(); err != nil {
+		return fmt.Errorf("failed to seed redis: %w", err)
+	}
+
+	// Seed document collections from config
+	if err := b.seedDocuments(); err != nil {
+		return fmt.Errorf("failed to seed documents: %w", err)
+	}
+
+	// Seed the LDAP directory from config
+	if err := b.seedLDAP(); err != nil {
+		return fmt.Errorf("failed to seed LDAP directory: %w", err)
+	}
+
+	// Seed object store buckets from config
+	if err := b.seedBuckets(); err != nil {
+		return fmt.Errorf("failed to seed buckets: %w", err)
 	}
 
 	// Create files from config
 	if err := b.createFiles(); err != nil {
-		return nil, fmt.Errorf("failed to create files: %w", err)
+		return fmt.Errorf("failed to create files: %w", err)
 	}
 
-	// Determine host (default to 127.0.0.1 if not specified)
-	host := b.config.App.Host
-	if host == "" {
-		host = "127.0.0.1"
+	// Create the directories served as static assets
+	if err := b.createStaticDirs(); err != nil {
+		return fmt.Errorf("failed to create static directories: %w", err)
 	}
 
-	// Create the server with JSON logging and TLS config
-	srv, err := server.New(host, b.config.App.Port, b.logFilePath, b.config.App.TLS)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create server: %w", err)
+	// Create per-vulnerability filesystem roots
+	if err := b.createFilesystemRoots(); err != nil {
+		return fmt.Errorf("failed to create filesystem roots: %w", err)
 	}
 
-	// Register health endpoint
-	srv.Router().HandleFunc("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"healthy","app":"%s"}`, b.config.App.Name)
-	})
+	// Record the initial files so the filesystem can be reset, then apply quotas
+	if err := b.checkpointFilesystem(); err != nil {
+		return fmt.Errorf("failed to checkpoint filesystem: %w", err)
+	}
 
-	// Register endpoints from config
-	for _, endpoint := range b.config.Endpoints {
-		if err := b.registerEndpoint(srv, endpoint); err != nil {
-			return nil, fmt.Errorf("failed to register endpoint %s: %w", endpoint.Path, err)
-		}
+	// Keep the seeded database that copies and resets are made from
+	if err := b.saveSeed(); err != nil {
+		return fmt.Errorf("failed to save the seeded database: %w", err)
 	}
 
-	return srv, nil
+	// Keep the seeded state that each client's copy is made from
+	if err := b.createSinkCopies(); err != nil {
+		return fmt.Errorf("failed to set up isolation: %w", err)
+	}
+
+	// Let modules with background state set it up
+	return b.startModules()
 }
 
-// initializeSinks creates the sinks needed by configured modules
-func (b *Builder) initializeSinks() error {
-	needsSQLite := false
-	needsFilesystem := false
-	needsCommand := false
-	needsHTTP := false
+// registerRoutes registers the configured endpoints and the lab's own APIs on router
+func (b *Builder) registerRoutes(router *server.Router) error {
+	// Refuse request bodies over app.http.max_body_size
+	if settings := b.config.App.HTTP; settings != nil && settings.Protected() {
+		router.LimitBodies(settings.MaxBodySize)
+	}
+	// Client profiles list the sessions each client used
+	if b.sessions != nil {
+		router.ProfileSessions(b.sessions.CookieName())
+	}
+
+	// Register health and sink statistics endpoints
+	b.registerHealthEndpoints(router)
 
-	// Check what sinks are needed based on vulnerability types
+	// Register endpoints from config, grpc ones on the gRPC listener's router
+	b.grpc = b.newGRPCRouter(router)
 	for _, endpoint := range b.config.Endpoints {
-		for _, vuln := range endpoint.Vulnerabilities {
-			switch vuln.Type {
-			case "sql_injection":
-				needsSQLite = true
-			case "path_traversal":
-				needsFilesystem = true
-			case "command_injection":
-				needsCommand = true
-			case "ssrf":
-				needsHTTP = true
+		target := router
+		if endpoint.Type == "grpc" {
+			if b.grpc == nil {
+				// app.grpc was added by a reload, which can't open listeners
+				log.Printf("Warning: %s is served once a restart opens the gRPC listener", endpoint.Path)
+				continue
 			}
+			target = b.grpc
+		}
+		if err := b.registerEndpoint(target, endpoint); err != nil {
+			return fmt.Errorf("failed to register endpoint %s: %w", endpoint.Path, err)
 		}
 	}
 
-	// Also check if data section exists (implies SQLite needed)
-	if b.config.Data != nil && len(b.config.Data.Tables) > 0 {
-		needsSQLite = true
+	// Let clients log in to protected endpoints
+	b.registerLoginEndpoint(router)
+
+	// Serve static directories from the filesystem sink
+	b.registerStaticEndpoints(router)
+
+	// Serve the browsable pages of the ui section
+	if err := b.registerUIPages(router); err != nil {
+		return err
 	}
 
-	// Also check if files section exists
-	if len(b.config.Files) > 0 {
-		needsFilesystem = true
+	// Serve JSONP for CSP policies that allowlist the app's own origin
+	b.registerJSONPEndpoints(router)
+
+	// Expose captured email for inspection
+	b.registerMailboxEndpoints(router)
+
+	// Expose OOB tokens and recorded interactions
+	b.registerOOBEndpoints(router)
+
+	// Let internal hostnames routed to the app reach its endpoints
+	b.routeAppHosts(router)
+
+	// Expose filesystem usage and reset
+	b.registerFilesystemEndpoints(router)
+
+	// Let grading pipelines reset the lab with the admin token
+	b.registerResetEndpoint(router)
+
+	// Answer unmatched requests with the configured error pages
+	if err := b.registerErrorPages(router); err != nil {
+		return err
 	}
 
-	// Initialize required sinks
-	var err error
+	return nil
+}
+
+// defaultMaxResponseSize caps HTTP sink response bodies when egress isn't configured
+const defaultMaxResponseSize = 5 << 20
 
-	if needsSQLite {
-		b.sinks.sqlite, err = sinks.NewSQLite()
-		if err != nil {
-			return fmt.Errorf("failed to create SQLite sink: %w", err)
-		}
-		log.Println("Initialized SQLite sink (in-memory)")
+// configureEgress applies the egress policy and routes internal hosts to emulated
+// services. Without configuration only loopback and emulated services are reachable.
+func (b *Builder) configureEgress() {
+	egress := b.config.App.Egress
+	if egress == nil {
+		egress = &config.EgressConfig{}
 	}
 
-	if needsFilesystem {
-		b.sinks.filesystem, err = sinks.NewFilesystem()
-		if err != nil {
-			return fmt.Errorf("failed to create filesystem sink: %w", err)
+	policy := sinks.EgressPolicy{
+		DenyExternal:    !egress.AllowExternal,
+		AllowedHosts:    egress.AllowedHosts,
+		MaxResponseSize: egress.MaxResponseSize,
+	}
+	if policy.MaxResponseSize == 0 {
+		policy.MaxResponseSize = defaultMaxResponseSize
+	}
+	if egress.Timeout != "" {
+		policy.Timeout, _ = time.ParseDuration(egress.Timeout)
+	}
+	b.sinks.httpSink.SetEgressPolicy(policy)
+
+	for host, service := range egress.Routes {
+		switch service {
+		case "metadata":
+			if b.sinks.metadata != nil {
+				b.sinks.httpSink.Route(host, b.sinks.metadata)
+			}
+		case "object_storage":
+			b.sinks.httpSink.Route(host, b.sinks.objects)
 		}
-		log.Printf("Initialized filesystem sink at %s", b.sinks.filesystem.BasePath())
 	}
 
-	if needsCommand {
-		b.sinks.command = sinks.NewCommand()
-		log.Println("Initialized command sink")
+	if policy.DenyExternal {
+		log.Printf("HTTP sink egress limited to loopback and %d allowed hosts", len(policy.AllowedHosts))
 	}
+}
 
-	if needsHTTP {
-		b.sinks.httpSink = sinks.NewHTTP()
-		log.Println("Initialized HTTP sink")
+// routeAppHosts points egress routes for "app" at the lab's own router, so
+// internal hostnames reach endpoints without leaving the process
+func (b *Builder) routeAppHosts(router *server.Router) {
+	if b.sinks.httpSink == nil || b.config.App.Egress == nil {
+		return
 	}
+	for host, service := range b.config.App.Egress.Routes {
+		if service == "app" {
+			b.sinks.httpSink.Route(host, router)
+		}
+	}
+}
 
-	return nil
+// defaultDatabasePath is where a persistent database is stored when no path is configured
+const defaultDatabasePath = "flawfactory.db"
+
+// InMemory keeps a persistent database in memory, so building and serving
+// the lab leave its file untouched. Call it before Build.
+func (b *Builder) InMemory() {
+	b.inMemory = true
 }
 
-// seedDatabase populates the database with data from config
+// persistence returns the persistence settings when file-backed storage is enabled
+func (b *Builder) persistence() *config.PersistenceConfig {
+	if b.inMemory || b.config.Data == nil || b.config.Data.Persistence == nil || !b.config.Data.Persistence.Enabled {
+		return nil
+	}
+	return b.config.Data.Persistence
+}
+
+// seedDatabase populates the database with data from config. A persistent
+// database that already holds the configured seed data is reused as-is.
 func (b *Builder) seedDatabase() error {
 	if b.config.Data == nil || b.sinks.sqlite == nil {
 		return nil
 	}
 
-	for tableName, table := range b.config.Data.Tables {
+	tables, err := b.seedTables()
+	if err != nil {
+		return err
+	}
+
+	var hash string
+	if p := b.persistence(); p != nil {
+		encoded, err := json.Marshal(b.config.Data.Tables)
+		if err != nil {
+			return fmt.Errorf("failed to hash seed data: %w", err)
+		}
+		// Generated flags differ between instances, so they are part of the seed data
+		if len(b.flags) > 0 {
+			flags, _ := json.Marshal(b.flags)
+			encoded = append(encoded, flags...)
+		}
+		sum := sha256.Sum256(encoded)
+		hash = hex.EncodeToString(sum[:])
+
+		previous, err := b.sinks.sqlite.SeedHash()
+		if err != nil {
+			return err
+		}
+		if previous != "" && ((previous == hash && p.Reseed != "always") || p.Reseed == "never") {
+			log.Printf("Reusing persistent database %s", b.sinks.sqlite.Path())
+			return nil
+		}
+
+		// Drop the configured tables so reseeding doesn't duplicate rows
+		for tableName := range tables {
+			if err := b.sinks.sqlite.DropTable(tableName); err != nil {
+				return err
+			}
+		}
+	}
+
+	for tableName, table := range tables {
 		if err := b.sinks.sqlite.SeedTable(tableName, table.Columns, table.Rows); err != nil {
 			return fmt.Errorf("failed to seed table %s: %w", tableName, err)
 		}
 		log.Printf("Seeded table '%s' with %d rows", tableName, len(table.Rows))
 	}
 
+	if hash != "" {
+		return b.sinks.sqlite.SetSeedHash(hash)
+	}
+	return nil
+}
+
+// seedRedis stores the configured keys in the Redis sink
+func (b *Builder) seedRedis() error {
+	if b.config.Data == nil || b.sinks.redis == nil {
+		return nil
+	}
+
+	for key, value := range b.config.Data.Redis {
+		if err := b.sinks.redis.Seed(key, value); err != nil {
+			return fmt.Errorf("failed to seed key %s: %w", key, err)
+		}
+	}
+	if len(b.config.Data.Redis) > 0 {
+		log.Printf("Seeded Redis with %d keys", len(b.config.Data.Redis))
+	}
+
+	return nil
+}
+
+// seedDocuments loads the configured collections into the document store.
+// Tables without a collection of the same name are loaded too, one document per row.
+func (b *Builder) seedDocuments() error {
+	if b.config.Data == nil || b.sinks.documents == nil {
+		return nil
+	}
+
+	tables, err := b.seedTables()
+	if err != nil {
+		return err
+	}
+
+	for name, docs := range b.config.Data.Collections {
+		b.sinks.documents.Seed(name, docs)
+		log.Printf("Seeded collection '%s' with %d documents", name, len(docs))
+	}
+
+	for name, table := range tables {
+		if _, exists := b.config.Data.Collections[name]; exists {
+			continue
+		}
+		docs := make([]map[string]interface{}, 0, len(table.Rows))
+		for _, row := range table.Rows {
+			doc := make(map[string]interface{}, len(table.Columns))
+			for i, column := range table.Columns {
+				if i < len(row) {
+					doc[column] = row[i]
+				}
+			}
+			docs = append(docs, doc)
+		}
+		b.sinks.documents.Seed(name, docs)
+		log.Printf("Seeded collection '%s' from table with %d documents", name, len(docs))
+	}
+
+	return nil
+}
+
+// seedBuckets creates the configured buckets and objects
+func (b *Builder) seedBuckets() error {
+	if b.config.Data == nil || b.sinks.objects == nil {
+		return nil
+	}
+
+	objects := 0
+	for _, bucket := range b.config.Data.Buckets {
+		if err := b.sinks.objects.CreateBucket(bucket.Name, bucket.ACL); err != nil {
+			return err
+		}
+		for _, object := range bucket.Objects {
+			if _, err := b.sinks.objects.PutObject(bucket.Name, object.Key, object.ContentType, []byte(b.resolveFlags(object.Content))); err != nil {
+				return err
+			}
+			objects++
+		}
+	}
+	log.Printf("Seeded object store with %d buckets and %d objects", len(b.config.Data.Buckets), objects)
+	return nil
+}
+
+// seedLDAP adds the configured users and groups to the directory
+func (b *Builder) seedLDAP() error {
+	if b.config.Data == nil || b.config.Data.LDAP == nil || b.sinks.ldap == nil {
+		return nil
+	}
+
+	directory := b.config.Data.LDAP
+	b.sinks.ldap.Lenient = !directory.StrictFilters
+
+	for _, user := range directory.Users {
+		if _, err := b.sinks.ldap.AddUser(user); err != nil {
+			return err
+		}
+	}
+	for _, group := range directory.Groups {
+		attrs := map[string]interface{}{}
+		if group.Description != "" {
+			attrs["description"] = group.Description
+		}
+		b.sinks.ldap.AddGroup(group.CN, group.Members, attrs)
+	}
+	log.Printf("Seeded LDAP directory with %d users and %d groups", len(directory.Users), len(directory.Groups))
+
 	return nil
 }
 
@@ -171,7 +502,7 @@ func (b *Builder) createFiles() error {
 	}
 
 	for _, file := range b.config.Files {
-		if err := b.sinks.filesystem.WriteFile(file.Path, file.Content); err != nil {
+		if err := b.sinks.filesystem.WriteFile(file.Path, b.resolveFlags(file.Content)); err != nil {
 			return fmt.Errorf("failed to create file %s: %w", file.Path, err)
 		}
 		log.Printf("Created file: %s", file.Path)
@@ -181,31 +512,134 @@ func (b *Builder) createFiles() error {
 }
 
 // registerEndpoint registers a single endpoint with the router
-func (b *Builder) registerEndpoint(srv *server.Server, endpoint config.EndpointConfig) error {
+func (b *Builder) registerEndpoint(router *server.Router, endpoint config.EndpointConfig) error {
+	// ServeMux only matches {name} segments, so their types are checked by the handler
+	pattern, params, err := endpoint.Route()
+	if err != nil {
+		return err
+	}
+
+	// An alias serves its target's handler under its own path
+	handled := endpoint
+	if endpoint.Type == "alias" {
+		target, ok := endpoint.AliasTarget(b.config.Endpoints)
+		if !ok {
+			return fmt.Errorf("no endpoint matches alias target '%s'", endpoint.Target)
+		}
+		_, targetParams, err := target.Route()
+		if err != nil {
+			return err
+		}
+		handled, params = target, append(params, targetParams...)
+	}
+
+	handler, err := b.endpointHandler(handled)
+	if err != nil {
+		return err
+	}
+	handler = withRouteTypes(params, handler)
+
+	// WebSocket endpoints upgrade a GET request and process each message
+	method := endpoint.Method
+	if handled.WebSocket {
+		method = "GET"
+	}
+
+	// Register the route, scoped to a virtual host if one is configured
+	handle := func(method string, handler http.HandlerFunc) {
+		if endpoint.Host != "" {
+			router.HandleHostFunc(method, endpoint.Host, pattern, handler)
+		} else {
+			router.HandleFunc(method, pattern, handler)
+		}
+	}
+	handle(method, handler)
+
+	// A soap endpoint serves its WSDL to GET requests, outside the login wall
+	if endpoint.Type == "soap" {
+		handle("GET", wsdlHandler(endpoint))
+	}
+
+	// Cap its request bodies in place of app.http.max_body_size
+	if endpoint.MaxBodySize > 0 && b.config.App.HTTP.Protected() {
+		router.LimitBody(method, endpoint.Host, pattern, endpoint.MaxBodySize)
+	}
+
+	return nil
+}
+
+// endpointHandler creates the handler for an endpoint, behind its faults and login wall
+func (b *Builder) endpointHandler(endpoint config.EndpointConfig) (http.HandlerFunc, error) {
+	endpoint = b.resolveEndpointFlags(endpoint)
+
 	// Determine response type
 	responseType := endpoint.ResponseType
 	if responseType == "" {
 		responseType = "json"
 	}
 
-	// Create handler
-	handler := b.createHandler(endpoint, responseType)
+	// A response template replaces the default response wrapper
+	rt, err := b.newResponseTemplate(endpoint)
+	if err != nil {
+		return nil, err
+	}
 
-	// Register the route
-	srv.Router().HandleFunc(endpoint.Method, endpoint.Path, handler)
+	var handler http.HandlerFunc
+	switch {
+	case endpoint.Type == "redirect":
+		handler = redirectHandler(endpoint)
+	case endpoint.Type == "proxy":
+		handler = b.proxyHandler(endpoint, responseType)
+	case endpoint.WebSocket:
+		handler = b.createWebSocketHandler(endpoint, responseType, rt)
+	default:
+		handler = b.createHandler(endpoint, responseType, rt)
+	}
 
-	return nil
+	handler = withFaults(endpoint, responseType, b.withAuth(endpoint, responseType, handler))
+
+	// A soap endpoint's JSON responses, errors included, go out in envelopes
+	if endpoint.Type == "soap" {
+		handler = soapHandler(endpoint, handler)
+	}
+	// A grpc endpoint's go out as response messages, errors as statuses
+	if endpoint.Type == "grpc" {
+		handler = grpcHandler(endpoint, handler)
+	}
+	return handler, nil
 }
 
 // createHandler creates an HTTP handler for an endpoint
-func (b *Builder) createHandler(endpoint config.EndpointConfig, responseType string) http.HandlerFunc {
-	extractor := server.NewExtractor()
+func (b *Builder) createHandler(endpoint config.EndpointConfig, responseType string, rt *responseTemplate) http.HandlerFunc {
+	extractor := b.newExtractor()
 	respBuilder := server.NewResponseBuilder()
+	steps := b.chainSteps(endpoint)
+	reveals := b.flagReveals(endpoint)
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		// If no vulnerabilities, just return a simple response
-		if len(endpoint.Vulnerabilities) == 0 {
-			respBuilder.Send(w, responseType, map[string]interface{}{
+		defer removeUploads(r)
+		r = b.withSession(w, r)
+		r, err := b.withSinkCopy(r)
+		if err != nil {
+			respBuilder.SendError(w, responseType, http.StatusInternalServerError, "failed to copy the lab for this client", server.DebugInfo{
+				Message: err.Error(),
+			})
+			return
+		}
+		applyCSP(w, endpoint.CSP)
+		applyHeaders(w, endpoint.Headers)
+		okStatus := successStatus(endpoint.Status)
+
+		// Chain steps stay locked until the client has exploited the step before
+		if step, locked := b.lockedStep(steps, r); locked {
+			sendLocked(w, responseType, step)
+			return
+		}
+
+		// If no vulnerabilities are active for this request, just return a simple response
+		vulns := activeVulnerabilities(b.enabledVulnerabilities(endpoint), r)
+		if len(vulns) == 0 && rt == nil {
+			respBuilder.SendWithStatus(w, responseType, okStatus, map[string]interface{}{
 				"message":  "Hello from FlawFactory",
 				"endpoint": endpoint.Path,
 			})
@@ -215,51 +649,192 @@ func (b *Builder) createHandler(endpoint config.EndpointConfig, responseType str
 		// Process each vulnerability
 		var results []server.ModuleResult
 
-		for _, vuln := range endpoint.Vulnerabilities {
+		for _, vuln := range vulns {
 			result := b.processVulnerability(r, w, extractor, vuln)
 			results = append(results, result)
 		}
+		b.recordProof(steps, r, results)
+		revealFlags(w, r, reveals, results)
+		b.emitExploits(endpoint, r, results)
+		classifyAttacks(r, results)
+		b.coverage.record(b.vulnIDs(endpoint, vulns), r, results)
+
+		// A response template renders every result, including errors, itself
+		if rt != nil {
+			body, statusCode, err := rt.render(r, results)
+			if err != nil {
+				respBuilder.SendError(w, responseType, http.StatusInternalServerError, "response template failed", server.DebugInfo{
+					Message: err.Error(),
+				})
+				return
+			}
+			if statusCode == 0 {
+				statusCode = okStatus
+			}
+			respBuilder.SendBody(w, responseType, statusCode, body)
+			return
+		}
 
 		// If single vulnerability, return its result directly
 		if len(results) == 1 {
 			result := results[0]
 			statusCode := result.StatusCode
-			if statusCode == 0 {
-				statusCode = http.StatusOK
-			}
 			if result.Error != "" {
-				if statusCode == http.StatusOK {
+				if statusCode == 0 || statusCode == http.StatusOK {
 					statusCode = http.StatusInternalServerError
 				}
 				respBuilder.SendError(w, responseType, statusCode, result.Error, server.DebugInfo{
 					Message:   result.Error,
 					Module:    result.Module,
-					Placement: endpoint.Vulnerabilities[0].Placement,
+					Placement: result.Placement,
 					Param:     result.Param,
 				})
 				return
 			}
+			if statusCode == 0 {
+				statusCode = okStatus
+			}
 			respBuilder.SendWithStatus(w, responseType, statusCode, result.Data)
 			return
 		}
 
 		// Multiple vulnerabilities - return combined results
-		respBuilder.SendCombined(w, responseType, results)
+		respBuilder.SendWithStatus(w, responseType, okStatus, server.CombinedResult{Results: results})
 	}
 }
 
+// createWebSocketHandler creates a handler that runs every message through the endpoint's modules
+func (b *Builder) createWebSocketHandler(endpoint config.EndpointConfig, responseType string, rt *responseTemplate) http.HandlerFunc {
+	extractor := b.newExtractor()
+	steps := b.chainSteps(endpoint)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyHeaders(w, endpoint.Headers)
+		if step, locked := b.lockedStep(steps, r); locked {
+			sendLocked(w, responseType, step)
+			return
+		}
+
+		conn, err := server.UpgradeWebSocket(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		r = b.withSession(nil, r) // Messages can use the session, not start one
+		if r, err = b.withSinkCopy(r); err != nil {
+			conn.WriteMessage(fmt.Sprintf(`{"error":%q}`, "failed to copy the lab for this client: "+err.Error()))
+			return
+		}
+
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var results []server.ModuleResult
+			vulns := activeVulnerabilities(b.enabledVulnerabilities(endpoint), r)
+			for _, vuln := range vulns {
+				// ws_message reads from the message, other placements from the upgrade request
+				var input string
+				if vuln.Placement == "ws_message" {
+					input, vuln.Param = extractMessageInput(extractor, message, vuln)
+				} else if input, vuln.Placement, vuln.Param, err = extractInput(r, extractor, vuln); err != nil {
+					results = append(results, server.ModuleResult{Module: vuln.Type, Param: vuln.Param, Placement: vuln.Placement, Error: err.Error(), RequestID: server.RequestID(r)})
+					continue
+				}
+				// The connection is hijacked, so modules get no ResponseWriter
+				results = append(results, b.runModule(r, nil, vuln, input))
+			}
+			b.recordProof(steps, r, results)
+			b.emitExploits(endpoint, r, results)
+			classifyAttacks(r, results)
+			b.coverage.record(b.vulnIDs(endpoint, vulns), r, results)
+
+			var reply string
+			if rt != nil {
+				if reply, _, err = rt.render(r, results); err != nil {
+					reply = fmt.Sprintf(`{"error":%q}`, "response template failed: "+err.Error())
+				}
+			} else {
+				reply = formatWebSocketResponse(responseType, results)
+			}
+
+			if err := conn.WriteMessage(reply); err != nil {
+				log.Printf("WebSocket write failed on %s: %v", endpoint.Path, err)
+				return
+			}
+		}
+	}
+}
+
+// formatWebSocketResponse renders module results as a single WebSocket message
+func formatWebSocketResponse(responseType string, results []server.ModuleResult) string {
+	var payload interface{}
+
+	switch {
+	case len(results) == 0:
+		// No vulnerability was active for the message
+		payload = server.ResponseData{Data: map[string]interface{}{"message": "Hello from FlawFactory"}}
+	case len(results) == 1 && results[0].Error != "":
+		payload = server.ErrorResponse{
+			Error:     results[0].Error,
+			RequestID: results[0].RequestID,
+			Debug:     server.DebugInfo{Message: results[0].Error, Module: results[0].Module, Placement: results[0].Placement, Param: results[0].Param},
+		}
+	case len(results) == 1:
+		// HTML output (e.g. XSS) is sent raw so it can be rendered by the client
+		if html, ok := results[0].Data.(string); ok && responseType != "json" {
+			return html
+		}
+		payload = server.ResponseData{Data: results[0].Data}
+	default:
+		payload = server.ResponseData{Data: server.CombinedResult{Results: results}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(body)
+}
+
 // processVulnerability processes a single vulnerability and returns the result
 func (b *Builder) processVulnerability(r *http.Request, w http.ResponseWriter, extractor *server.Extractor, vuln config.VulnerabilityConfig) server.ModuleResult {
+	// Extract input, resolving which param and placement it came from
+	_, span := tracing.Start(r.Context(), "extract "+vuln.Placement, tracing.KindInternal)
+	input, placement, param, err := extractInput(r, extractor, vuln)
+	traceExtraction(span, placement, param, input, err)
+	if err != nil {
+		return server.ModuleResult{
+			Module:    vuln.Type,
+			Param:     param,
+			Placement: placement,
+			Error:     err.Error(),
+			RequestID: server.RequestID(r),
+		}
+	}
+
+	vuln.Placement, vuln.Param = placement, param
+	return b.runModule(r, w, vuln, input)
+}
+
+// runModule runs the vulnerability module on the extracted input
+func (b *Builder) runModule(r *http.Request, w http.ResponseWriter, vuln config.VulnerabilityConfig, input string) server.ModuleResult {
 	result := server.ModuleResult{
-		Module: vuln.Type,
-		Param:  vuln.Param,
+		Module:    vuln.Type,
+		Param:     vuln.Param,
+		Placement: vuln.Placement,
+		Input:     input,
+		RequestID: server.RequestID(r),
 	}
 
-	// Extract input
-	input, err := extractor.Extract(r, vuln.Placement, vuln.Param)
-	if err != nil {
-		result.Error = err.Error()
-		return result
+	// Trace the module run, with its sink operations beneath it
+	spanCtx, span := tracing.Start(r.Context(), "module "+vuln.Type, tracing.KindInternal)
+	if span != nil {
+		r = r.WithContext(spanCtx)
+		defer func() { traceModule(span, vuln, &result) }()
 	}
 
 	// Get the module
@@ -274,10 +849,36 @@ func (b *Builder) processVulnerability(r *http.Request, w http.ResponseWriter, e
 		Request:        r,
 		ResponseWriter: w,
 		Input:          input,
+		Body:           server.Body(r),
 		Placement:      vuln.Placement,
 		Param:          vuln.Param,
 		Config:         vuln.Config,
 		Sinks:          b.createSinkContext(),
+		Random:         b.random,
+		State:          b.state,
+	}
+	if session := requestSession(r); session != nil {
+		ctx.Session = session
+	}
+
+	// Give modules the uploaded file's name and content type, not just its content
+	if vuln.Placement == "file" {
+		file, err := b.newExtractor().ExtractFile(r, vuln.Param)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if file != nil {
+			ctx.File = &modules.UploadedFile{Filename: file.Filename, ContentType: file.ContentType, Content: file.Content}
+		}
+	}
+
+	// Use the client's own copy of the sinks, then scope them to the
+	// vulnerability: filesystem roots and command sandboxes
+	b.useSinkCopy(ctx.Sinks, r)
+	b.applySinkOptions(ctx.Sinks, vuln)
+	if span != nil {
+		traceSinks(spanCtx, ctx.Sinks)
 	}
 
 	// Handle the request
@@ -305,99 +906,196 @@ func (b *Builder) processVulnerability(r *http.Request, w http.ResponseWriter, e
 	return result
 }
 
+// newExtractor creates an extractor with the configured upload limits
+func (b *Builder) newExtractor() *server.Extractor {
+	if uploads := b.config.App.Uploads; uploads != nil {
+		return server.NewExtractorWithLimits(uploads.MaxBodySize, uploads.MaxFileSize)
+	}
+	return server.NewExtractor()
+}
+
+// removeUploads deletes the temporary files large uploads were buffered in
+func removeUploads(r *http.Request) {
+	if r.MultipartForm != nil {
+		r.MultipartForm.RemoveAll()
+	}
+}
+
 // createSinkContext creates the sink context for modules
 func (b *Builder) createSinkContext() *modules.SinkContext {
 	ctx := &modules.SinkContext{}
 
 	if b.sinks.sqlite != nil {
-		ctx.SQLite = &sqliteSinkAdapter{b.sinks.sqlite}
+		ctx.SQLite = &sqliteSinkAdapter{b.sinks.sqlite, &b.sinks.stats}
 	}
 
 	if b.sinks.filesystem != nil {
-		ctx.Filesystem = &filesystemSinkAdapter{b.sinks.filesystem}
+		ctx.Filesystem = &filesystemSinkAdapter{sink: b.sinks.filesystem, stats: &b.sinks.stats}
 	}
 
 	if b.sinks.command != nil {
-		ctx.Command = &commandSinkAdapter{b.sinks.command}
+		ctx.Command = &commandSinkAdapter{sink: b.sinks.command, policy: sinks.CommandPolicy{Env: b.flagEnv}, stats: &b.sinks.stats}
 	}
 
 	if b.sinks.httpSink != nil {
-		ctx.HTTP = &httpSinkAdapter{b.sinks.httpSink}
+		ctx.HTTP = &httpSinkAdapter{b.sinks.httpSink, &b.sinks.stats}
 	}
 
-	return ctx
-}
+	if b.sinks.redis != nil {
+		ctx.Redis = &redisSinkAdapter{b.sinks.redis, &b.sinks.stats}
+	}
 
-// Close releases all sink resources
-func (b *Builder) Close() error {
-	var errs []string
+	if b.sinks.documents != nil {
+		ctx.Documents = &documentSinkAdapter{b.sinks.documents, &b.sinks.stats}
+	}
 
-	if b.sinks.sqlite != nil {
-		if err := b.sinks.sqlite.Close(); err != nil {
-			errs = append(errs, fmt.Sprintf("sqlite: %v", err))
-		}
+	if b.sinks.ldap != nil {
+		ctx.LDAP = &ldapSinkAdapter{b.sinks.ldap, &b.sinks.stats}
 	}
 
-	if b.sinks.filesystem != nil {
-		if err := b.sinks.filesystem.Close(); err != nil {
-			errs = append(errs, fmt.Sprintf("filesystem: %v", err))
+	if b.sinks.mail != nil {
+		ctx.Mail = &mailSinkAdapter{b.sinks.mail, &b.sinks.stats}
+	}
+
+	if b.sinks.oob != nil {
+		ctx.OOB = &oobSinkAdapter{b.sinks.oob, &b.sinks.stats}
+	}
+
+	if b.sinks.template != nil {
+		ctx.Template = &templateSinkAdapter{b.sinks.template, &b.sinks.stats}
+	}
+
+	for _, s := range b.sinks.initialized {
+		if ms, ok := s.sink.(ModuleSink); ok {
+			if ctx.Extra == nil {
+				ctx.Extra = make(map[string]interface{})
+			}
+			ctx.Extra[s.name] = ms.ModuleSink()
 		}
 	}
 
-	if len(errs) > 0 {
-		return fmt.Errorf("errors closing sinks: %s", strings.Join(errs, "; "))
+	return ctx
+}
+
+// Close shuts down the running modules and releases all sink resources
+func (b *Builder) Close() error {
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
 	}
 
-	return nil
+	// Events already emitted still reach their targets
+	b.notifying.Wait()
+
+	// Modules may still use the sinks while shutting down
+	if err := b.stopModules(nil); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return b.closeSinks()
 }
 
 // Sink adapters to implement the module interfaces
 
 type sqliteSinkAdapter struct {
-	sink *sinks.SQLite
+	sink  *sinks.SQLite
+	stats *sinkStats
 }
 
 func (a *sqliteSinkAdapter) Query(query string) ([]map[string]interface{}, error) {
-	return a.sink.Query(query)
+	rows, err := a.sink.Query(query)
+	a.stats.record("sqlite", 0, err)
+	return rows, err
 }
 
 func (a *sqliteSinkAdapter) Exec(statement string) error {
-	return a.sink.Exec(statement)
+	err := a.sink.Exec(statement)
+	a.stats.record("sqlite", 0, err)
+	return err
+}
+
+func (a *sqliteSinkAdapter) ExecArgs(statement string, args ...interface{}) (int64, error) {
+	id, err := a.sink.ExecArgs(statement, args...)
+	a.stats.record("sqlite", 0, err)
+	return id, err
 }
 
 type filesystemSinkAdapter struct {
-	sink *sinks.Filesystem
+	sink  *sinks.Filesystem
+	root  string // Directory under the base path that paths are relative to
+	stats *sinkStats
+}
+
+func (a *filesystemSinkAdapter) path(path string) string {
+	if a.root == "" {
+		return path
+	}
+	return filepath.Join(a.root, path)
 }
 
 func (a *filesystemSinkAdapter) Read(path string) (string, error) {
-	return a.sink.Read(path)
+	content, err := a.sink.Read(a.path(path))
+	a.stats.record("filesystem", len(content), err)
+	return content, err
 }
 
 func (a *filesystemSinkAdapter) Exists(path string) bool {
-	return a.sink.Exists(path)
+	return a.sink.Exists(a.path(path))
 }
 
 func (a *filesystemSinkAdapter) BasePath() string {
-	return a.sink.BasePath()
+	return filepath.Join(a.sink.BasePath(), a.root)
 }
 
 type commandSinkAdapter struct {
-	sink *sinks.Command
+	sink   *sinks.Command
+	policy sinks.CommandPolicy
+	stats  *sinkStats
 }
 
 func (a *commandSinkAdapter) Execute(command string) (string, error) {
-	return a.sink.Execute(command)
+	output, err := a.sink.ExecuteWithPolicy(command, a.policy)
+	a.stats.record("command", len(output), err)
+	return output, err
+}
+
+// commandPolicy converts a vulnerability's sandbox config into a command policy.
+// The timeout is a duration string ("5s") or a number of seconds.
+func commandPolicy(sandbox map[string]interface{}) sinks.CommandPolicy {
+	var policy sinks.CommandPolicy
+
+	if allowed, ok := sandbox["allowed_binaries"].([]interface{}); ok {
+		for _, name := range allowed {
+			policy.AllowedBinaries = append(policy.AllowedBinaries, fmt.Sprintf("%v", name))
+		}
+	}
+
+	switch timeout := sandbox["timeout"].(type) {
+	case int:
+		policy.Timeout = time.Duration(timeout) * time.Second
+	case float64:
+		policy.Timeout = time.Duration(timeout * float64(time.Second))
+	case string:
+		policy.Timeout, _ = time.ParseDuration(timeout)
+	}
+
+	policy.WorkDir, _ = sandbox["workdir"].(string)
+	policy.ScrubEnv, _ = sandbox["scrub_env"].(bool)
+	policy.EmulateOnly, _ = sandbox["emulate_only"].(bool)
+	return policy
 }
 
 type httpSinkAdapter struct {
-	sink *sinks.HTTP
+	sink  *sinks.HTTP
+	stats *sinkStats
 }
 
 func (a *httpSinkAdapter) Fetch(url string) (*modules.HTTPResponse, error) {
 	resp, err := a.sink.Fetch(url)
 	if err != nil {
+		a.stats.record("http", 0, err)
 		return nil, err
 	}
+	a.stats.record("http", len(resp.Body), nil)
 	return &modules.HTTPResponse{
 		StatusCode: resp.StatusCode,
 		Body:       resp.Body,
@@ -415,8 +1113,10 @@ func (a *httpSinkAdapter) FetchWithOptions(url string, opts modules.HTTPOptions)
 	}
 	resp, err := a.sink.FetchWithOptions(url, sinkOpts)
 	if err != nil {
+		a.stats.record("http", 0, err)
 		return nil, err
 	}
+	a.stats.record("http", len(resp.Body), nil)
 	return &modules.HTTPResponse{
 		StatusCode: resp.StatusCode,
 		Body:       resp.Body,
@@ -424,6 +1124,116 @@ func (a *httpSinkAdapter) FetchWithOptions(url string, opts modules.HTTPOptions)
 	}, nil
 }
 
+type redisSinkAdapter struct {
+	sink  *sinks.Redis
+	stats *sinkStats
+}
+
+func (a *redisSinkAdapter) Execute(command string) (interface{}, error) {
+	reply, err := a.sink.Execute(command)
+	a.stats.record("redis", 0, err)
+	return reply, err
+}
+
+type documentSinkAdapter struct {
+	sink  *sinks.DocumentStore
+	stats *sinkStats
+}
+
+func (a *documentSinkAdapter) Find(collection string, filter map[string]interface{}) ([]map[string]interface{}, error) {
+	docs, err := a.sink.Find(collection, filter)
+	a.stats.record("documents", 0, err)
+	return docs, err
+}
+
+func (a *documentSinkAdapter) Insert(collection string, doc map[string]interface{}) (interface{}, error) {
+	id, err := a.sink.Insert(collection, doc)
+	a.stats.record("documents", 0, err)
+	return id, err
+}
+
+func (a *documentSinkAdapter) Update(collection string, filter, update map[string]interface{}, multi bool) (int, error) {
+	n, err := a.sink.Update(collection, filter, update, multi)
+	a.stats.record("documents", 0, err)
+	return n, err
+}
+
+func (a *documentSinkAdapter) Delete(collection string, filter map[string]interface{}, multi bool) (int, error) {
+	n, err := a.sink.Delete(collection, filter, multi)
+	a.stats.record("documents", 0, err)
+	return n, err
+}
+
+type ldapSinkAdapter struct {
+	sink  *sinks.LDAP
+	stats *sinkStats
+}
+
+func (a *ldapSinkAdapter) Search(baseDN, scope, filter string) ([]modules.LDAPEntry, error) {
+	entries, err := a.sink.Search(baseDN, scope, filter)
+	a.stats.record("ldap", 0, err)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]modules.LDAPEntry, len(entries))
+	for i, entry := range entries {
+		results[i] = modules.LDAPEntry{DN: entry.DN, Attributes: entry.Attributes}
+	}
+	return results, nil
+}
+
+func (a *ldapSinkAdapter) BaseDN() string {
+	return a.sink.BaseDN()
+}
+
+type mailSinkAdapter struct {
+	sink  *sinks.SMTP
+	stats *sinkStats
+}
+
+func (a *mailSinkAdapter) Send(from string, to []string, message string) (int, error) {
+	email, err := a.sink.Send(from, to, message)
+	a.stats.record("mail", len(message), err)
+	if err != nil {
+		return 0, err
+	}
+	return email.ID, nil
+}
+
+type oobSinkAdapter struct {
+	sink  *sinks.OOB
+	stats *sinkStats
+}
+
+func (a *oobSinkAdapter) Hosts() []string {
+	return a.sink.Hosts()
+}
+
+func (a *oobSinkAdapter) Lookup(name string) bool {
+	a.stats.record("oob", 0, nil)
+	return a.sink.Lookup(name)
+}
+
+type templateSinkAdapter struct {
+	sink  *sinks.Template
+	stats *sinkStats
+}
+
+func (a *templateSinkAdapter) Render(engine, source string, data map[string]interface{}, unsafe func(modules.UnsafeExpression) string) (string, error) {
+	output, err := a.sink.Render(engine, source, data, func(u sinks.UnsafeExpression) string {
+		return unsafe(modules.UnsafeExpression{
+			Engine:     u.Engine,
+			Expression: u.Expression,
+			Kind:       u.Kind,
+			Command:    u.Command,
+			Path:       u.Path,
+		})
+	})
+	a.stats.record("template", len(output), err)
+	return output, err
+}
+
 // GetFilesystemWithFilter returns the filesystem sink with filter support
 func (b *Builder) GetFilesystemWithFilter() *sinks.Filesystem {
 	return b.sinks.filesystem
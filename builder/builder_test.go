@@ -1,9 +1,15 @@
 package builder
 
 import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
 )
 
 // TestNew tests builder creation
@@ -260,6 +266,27 @@ func TestBuilder_Build_WithCommandInjection(t *testing.T) {
 	}
 }
 
+// TestCommandPolicy tests sandbox config conversion
+func TestCommandPolicy(t *testing.T) {
+	policy := commandPolicy(map[string]interface{}{
+		"allowed_binaries": []interface{}{"ping", "nslookup"},
+		"timeout":          "1500ms",
+		"workdir":          "/tmp/jail",
+		"scrub_env":        true,
+		"emulate_only":     true,
+	})
+
+	if len(policy.AllowedBinaries) != 2 || policy.AllowedBinaries[1] != "nslookup" {
+		t.Errorf("Unexpected allowlist %v", policy.AllowedBinaries)
+	}
+	if policy.Timeout != 1500*time.Millisecond || policy.WorkDir != "/tmp/jail" || !policy.ScrubEnv || !policy.EmulateOnly {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+	if p := commandPolicy(map[string]interface{}{"timeout": 3}); p.Timeout != 3*time.Second {
+		t.Errorf("Expected numeric timeout in seconds, got %v", p.Timeout)
+	}
+}
+
 // TestBuilder_Build_WithSSRF tests building with SSRF endpoint
 func TestBuilder_Build_WithSSRF(t *testing.T) {
 	cfg := &config.Config{
@@ -298,6 +325,314 @@ func TestBuilder_Build_WithSSRF(t *testing.T) {
 	}
 }
 
+// TestBuilder_Build_WithRedis tests the Redis sink is created and seeded for real Redis mode
+func TestBuilder_Build_WithRedis(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "test-app",
+			Port: 8080,
+		},
+		Data: &config.DataConfig{
+			Redis: map[string]interface{}{
+				"user:1":  map[string]interface{}{"name": "admin"},
+				"api_key": "sk_live_123",
+			},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/cache",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{
+						Type:      "nosql_injection",
+						Param:     "key",
+						Placement: "query_param",
+						Config: map[string]interface{}{
+							"database":      "redis",
+							"use_real_sink": true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if builder.sinks.redis == nil {
+		t.Fatal("Expected Redis sink to be initialized for use_real_sink")
+	}
+	if builder.sinks.sqlite != nil {
+		t.Error("Expected no SQLite sink for a Redis-only data section")
+	}
+
+	value, err := builder.sinks.redis.Execute("GET api_key")
+	if err != nil || value != "sk_live_123" {
+		t.Errorf("Expected seeded key, got %v (%v)", value, err)
+	}
+}
+
+// TestBuilder_Build_WithDocumentStore tests the document store is created and seeded for real MongoDB mode
+func TestBuilder_Build_WithDocumentStore(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "test-app",
+			Port: 8080,
+		},
+		Data: &config.DataConfig{
+			Collections: map[string][]map[string]interface{}{
+				"products": {{"name": "Plumbus", "price": 5}},
+			},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/login",
+				Method: "POST",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{
+						Type:      "nosql_injection",
+						Param:     "filter",
+						Placement: "json_field",
+						Config: map[string]interface{}{
+							"database":      "mongodb",
+							"use_real_sink": true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if builder.sinks.documents == nil {
+		t.Fatal("Expected document store to be initialized for use_real_sink")
+	}
+	products, _ := builder.sinks.documents.Find("products", nil)
+	if len(products) != 1 {
+		t.Errorf("Expected seeded collection, got %v", products)
+	}
+}
+
+// TestBuilder_SeedDocuments_FromTables tests tables are loaded as collections
+func TestBuilder_SeedDocuments_FromTables(t *testing.T) {
+	cfg := &config.Config{
+		Data: &config.DataConfig{
+			Tables: map[string]config.TableConfig{
+				"users": {
+					Columns: []string{"id", "username"},
+					Rows:    [][]interface{}{{1, "admin"}, {2, "rick"}},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	builder.sinks.documents = sinks.NewDocumentStore()
+	builder.seedDocuments()
+
+	users, err := builder.sinks.documents.Find("users", map[string]interface{}{"username": "rick"})
+	if err != nil || len(users) != 1 {
+		t.Fatalf("Expected table rows as documents, got %v (%v)", users, err)
+	}
+	if users[0]["id"] != 2 {
+		t.Errorf("Expected row values to be kept, got %v", users[0])
+	}
+}
+
+// TestBuilder_Build_WithLDAP tests the directory is created and seeded for LDAP injection
+func TestBuilder_Build_WithLDAP(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "test-app",
+			Port: 8080,
+		},
+		Data: &config.DataConfig{
+			LDAP: &config.LDAPDataConfig{
+				BaseDN: "dc=example,dc=org",
+				Users: []map[string]interface{}{
+					{"uid": "admin", "userPassword": "S3cret!"},
+					{"uid": "rick"},
+				},
+				Groups: []config.LDAPGroupConfig{
+					{CN: "admins", Members: []string{"admin"}},
+				},
+			},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/directory",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{
+						Type:      "ldap_injection",
+						Param:     "user",
+						Placement: "query_param",
+					},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if builder.sinks.ldap == nil {
+		t.Fatal("Expected LDAP sink to be initialized for LDAP injection")
+	}
+	if builder.sinks.ldap.BaseDN() != "dc=example,dc=org" {
+		t.Errorf("Expected configured base DN, got %s", builder.sinks.ldap.BaseDN())
+	}
+
+	entries, err := builder.sinks.ldap.Search("", "sub", "(member=uid=admin,ou=people,dc=example,dc=org)")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected seeded group, got %v (%v)", entries, err)
+	}
+}
+
+// TestBuilder_Build_WithSSTI tests the template sink renders injected input end to end
+func TestBuilder_Build_WithSSTI(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "test-app",
+			Port: 8080,
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:         "/greet",
+				Method:       "GET",
+				ResponseType: "html",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{
+						Type:      "ssti",
+						Param:     "name",
+						Placement: "query_param",
+						Config:    map[string]interface{}{"engine": "jinja2", "template": "Hello {input}"},
+					},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if builder.sinks.template == nil {
+		t.Fatal("Expected template sink to be initialized for SSTI")
+	}
+	if builder.sinks.command != nil {
+		t.Error("Expected no command sink without execute_commands")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/greet?name="+url.QueryEscape("{{7*7}}"), nil))
+	if body := rec.Body.String(); !strings.Contains(body, "Hello 49") {
+		t.Errorf("Expected rendered expression, got %q", body)
+	}
+}
+
+// TestBuilder_Build_WithObjectStorage tests seeded buckets are reachable through SSRF
+func TestBuilder_Build_WithObjectStorage(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:          "test-app",
+			Port:          8080,
+			CloudMetadata: &config.CloudMetadataConfig{Provider: "aws"},
+		},
+		Data: &config.DataConfig{
+			Buckets: []config.BucketConfig{
+				{Name: "public-assets", ACL: "public-read", Objects: []config.ObjectConfig{{Key: "flag.txt", Content: "FLAG{s3}"}}},
+			},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/fetch",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "ssrf", Param: "url", Placement: "query_param"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	if builder.sinks.objects == nil {
+		t.Fatal("Expected object store to be initialized for buckets")
+	}
+	metadataKey, _ := sinks.NewMetadataService(sinks.MetadataConfig{}).Credentials()
+	if builder.sinks.objects.AccessKeyID() != metadataKey {
+		t.Errorf("Expected object store to accept the metadata role's keys, got %s", builder.sinks.objects.AccessKeyID())
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/fetch?url="+url.QueryEscape("http://s3.flawfactory.local/public-assets/flag.txt"), nil))
+	if body := rec.Body.String(); !strings.Contains(body, "FLAG{s3}") {
+		t.Errorf("Expected object contents through SSRF, got %q", body)
+	}
+}
+
+// TestBuilder_Build_WithEgress tests the default egress policy and routes to the app
+func TestBuilder_Build_WithEgress(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "test-app",
+			Port: 8080,
+			Egress: &config.EgressConfig{
+				Routes: map[string]string{"admin.internal": "app"},
+			},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/fetch",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "ssrf", Param: "url", Placement: "query_param"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if !builder.sinks.httpSink.EgressPolicy().DenyExternal {
+		t.Error("Expected external egress to be denied by default")
+	}
+
+	fetch := func(target string) string {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest("GET", "/fetch?url="+url.QueryEscape(target), nil))
+		return rec.Body.String()
+	}
+
+	if body := fetch("http://admin.internal/health"); !strings.Contains(body, "healthy") {
+		t.Errorf("Expected routed host to reach the app, got %q", body)
+	}
+	if body := fetch("http://10.255.255.1/"); !strings.Contains(body, "blocked by policy") {
+		t.Errorf("Expected private address to be blocked, got %q", body)
+	}
+}
+
 // TestBuilder_Build_MultipleSinks tests multiple sinks initialized together
 func TestBuilder_Build_MultipleSinks(t *testing.T) {
 	cfg := &config.Config{
@@ -362,3 +697,27 @@ func TestBuilder_Build_MultipleSinks(t *testing.T) {
 		builder.sinks.filesystem.Close()
 	}
 }
+
+// TestFormatWebSocketResponse tests how module results are rendered as messages
+func TestFormatWebSocketResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		responseType string
+		results      []server.ModuleResult
+		contains     string
+	}{
+		{"json data", "json", []server.ModuleResult{{Module: "websocket_injection", Data: map[string]interface{}{"count": 1}}}, `{"data":{"count":1}}`},
+		{"raw html", "html", []server.ModuleResult{{Module: "websocket_injection", Data: "<b>hi</b>"}}, "<b>hi</b>"},
+		{"error", "json", []server.ModuleResult{{Module: "websocket_injection", Error: "boom"}}, `"error":"boom"`},
+		{"combined", "json", []server.ModuleResult{{Module: "a"}, {Module: "b"}}, `"results":[`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatWebSocketResponse(tt.responseType, tt.results)
+			if !strings.Contains(got, tt.contains) {
+				t.Errorf("Expected '%s' in '%s'", tt.contains, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// chainProgress tracks how many steps of each chain every client has completed
+type chainProgress struct {
+	mu        sync.Mutex
+	completed map[string]map[string]int // Chain name -> client address -> steps completed
+}
+
+// newChainProgress creates an empty progress tracker
+func newChainProgress() *chainProgress {
+	return &chainProgress{completed: make(map[string]map[string]int)}
+}
+
+// get returns how many steps of a chain the client has completed
+func (p *chainProgress) get(chain, client string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.completed[chain][client]
+}
+
+// reset forgets every client's progress
+func (p *chainProgress) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.completed)
+}
+
+// advance records that the client completed the step at index, if it was the
+// next one for them. It reports whether progress was made.
+func (p *chainProgress) advance(chain, client string, index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.completed[chain][client] != index {
+		return false
+	}
+	if p.completed[chain] == nil {
+		p.completed[chain] = make(map[string]int)
+	}
+	p.completed[chain][client] = index + 1
+	return true
+}
+
+// chainStep is a step of a chain that an endpoint serves
+type chainStep struct {
+	chain *config.ChainConfig
+	index int
+}
+
+// chainSteps returns the chain steps that refer to the endpoint
+func (b *Builder) chainSteps(endpoint config.EndpointConfig) []chainStep {
+	var steps []chainStep
+	for i := range b.config.Chains {
+		chain := &b.config.Chains[i]
+		for j, step := range chain.Steps {
+			if step.Targets(endpoint) {
+				steps = append(steps, chainStep{chain: chain, index: j})
+			}
+		}
+	}
+	return steps
+}
+
+// chainClient identifies the client a request's chain progress belongs to
+func chainClient(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// lockedStep returns the first of the endpoint's steps the client hasn't unlocked
+// yet, by completing the step before it
+func (b *Builder) lockedStep(steps []chainStep, r *http.Request) (chainStep, bool) {
+	client := chainClient(r)
+	for _, step := range steps {
+		if b.chains.get(step.chain.Name, client) < step.index {
+			return step, true
+		}
+	}
+	return chainStep{}, false
+}
+
+// sendLocked answers a request for a step that is still locked
+func sendLocked(w http.ResponseWriter, responseType string, step chainStep) {
+	previous := step.chain.Steps[step.index-1]
+	server.NewResponseBuilder().SendError(w, responseType, http.StatusForbidden, "forbidden", server.DebugInfo{
+		Message: fmt.Sprintf("step %d of chain '%s' is locked until %s has been exploited", step.index+1, step.chain.Name, previous.Endpoint),
+	})
+}
+
+// recordProof completes the client's current step of each chain whose proof
+// appears in the module results. Only the modules' data and errors are
+// searched, so sending the proof as input doesn't count.
+func (b *Builder) recordProof(steps []chainStep, r *http.Request, results []server.ModuleResult) {
+	if len(steps) == 0 || len(results) == 0 {
+		return
+	}
+	var output strings.Builder
+	for _, result := range results {
+		fmt.Fprintf(&output, "%v\n%s\n", result.Data, result.Error)
+	}
+
+	client := chainClient(r)
+	for _, step := range steps {
+		proof := step.chain.Steps[step.index].Proof
+		if proof == "" || !strings.Contains(output.String(), proof) {
+			continue
+		}
+		if b.chains.advance(step.chain.Name, client, step.index) {
+			log.Printf("Chain '%s': %s completed step %d of %d", step.chain.Name, client, step.index+1, len(step.chain.Steps))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_Chains tests a chain step stays locked per client until the step before is exploited
+func TestBuilder_Chains(t *testing.T) {
+	cfg := reloadConfig("/file", "token=tok_123")
+	admin := cfg.Endpoints[0]
+	admin.Path = "/admin"
+	cfg.Endpoints = append(cfg.Endpoints, admin)
+	cfg.Chains = []config.ChainConfig{{
+		Name: "leak_to_admin",
+		Steps: []config.ChainStepConfig{
+			{Endpoint: "GET /file", Proof: "tok_123"},
+			{Endpoint: "get /admin"},
+		},
+	}}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	serve := func(target, client string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = client + ":40000"
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("/admin?name=notes.txt", "10.0.0.1"); code != http.StatusForbidden {
+		t.Errorf("Expected the second step to be locked, got %d", code)
+	}
+	if code := serve("/file?name=notes.txt", "10.0.0.1"); code != http.StatusOK {
+		t.Fatalf("Expected the first step to be open, got %d", code)
+	}
+	if code := serve("/admin?name=notes.txt", "10.0.0.1"); code != http.StatusOK {
+		t.Errorf("Expected the second step to unlock after the proof leaked, got %d", code)
+	}
+	if code := serve("/admin?name=notes.txt", "10.0.0.2"); code != http.StatusForbidden {
+		t.Errorf("Expected progress to be tracked per client, got %d", code)
+	}
+}
@@ -0,0 +1,158 @@
+package builder
+
+import (
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/RIZZZIOM/FlawFactory/logger"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// maxAttackPayload bounds the payload snippet a classified attack carries
+const maxAttackPayload = 256
+
+// snippetContext is how much input either side of a match a snippet keeps
+const snippetContext = 24
+
+// defaultAttackScore scores attacks of modules not in attackScores
+const defaultAttackScore = 5.0
+
+// attackSignature recognises the payloads of one kind of attack
+type attackSignature struct {
+	attackType string
+	pattern    *regexp.Regexp
+}
+
+// attackSignatures are tried in order, after the one of the module that ran,
+// so the most specific payloads are named first: a DOCTYPE carrying a
+// traversal is XXE, a template expression carrying a command is SSTI
+var attackSignatures = []attackSignature{
+	{"xxe", regexp.MustCompile(`(?i)<!(DOCTYPE|ENTITY)\b`)},
+	{"insecure_deserialization", regexp.MustCompile(`rO0AB|(?i)aced0005|^[OC]:\d+:"|!!python/|__reduce__`)},
+	{"nosql_injection", regexp.MustCompile(`["\[]?\$(ne|eq|gt|gte|lt|lte|in|nin|regex|where|exists|or|and|expr)\b`)},
+	{"ssti", regexp.MustCompile(`\{\{.*\}\}|\{%.*%\}|<%.*%>|\$\{.*\}|#\{.*\}`)},
+	{"sql_injection", regexp.MustCompile(`(?i)['"]\s*(or|and)\s+['"\w]+\s*(=|like)|\bunion\b.+\bselect\b|['"]\s*(--|#|/\*)|;\s*(drop|insert|update|delete|select)\b|\b(sleep|benchmark|pg_sleep)\s*\(|\bwaitfor\s+delay\b`)},
+	{"command_injection", regexp.MustCompile("(?i)[;&|\n]\\s*(cat|id|whoami|ls|uname|curl|wget|nc|ncat|bash|sh|ping|sleep|echo|type|dir)\\b|\\$\\([^)]*\\)|`[^`]+`")},
+	{"xss_reflected", regexp.MustCompile(`(?i)<\s*/?\s*script\b|<[^>]*\bon[a-z]+\s*=|javascript\s*:|<\s*(iframe|svg|img|object|embed)\b`)},
+	{"path_traversal", regexp.MustCompile(`(?i)\.\.[/\\]|%2e%2e(%2f|%5c|/|\\)|\.\.%(2f|5c)|^/etc/|^[a-z]:\\|/etc/(passwd|shadow|hosts)|win\.ini`)},
+	{"ldap_injection", regexp.MustCompile(`\)\s*\(\s*[|&!]|\*\)\s*\(|\)\s*\(\s*\w+\s*=`)},
+	{"ssrf", regexp.MustCompile(`(?i)^(file|gopher|dict|ftp|ldap)://|://(localhost|127\.\d+\.\d+\.\d+|0\.0\.0\.0|\[::1?\]|169\.254\.169\.254|metadata\.google\.internal)\b`)},
+}
+
+// attackScores are the base scores of attacks, by the module names and the
+// attack types modules report, after the CVSS scores typical of each
+var attackScores = map[string]float64{
+	"sql_injection":            9.8,
+	"command_injection":        9.8,
+	"insecure_deserialization": 9.8,
+	"ssti":                     9.0,
+	"nosql_injection":          8.8,
+	"ssrf":                     8.6,
+	"xxe":                      8.2,
+	"bfla":                     8.1,
+	"two_factor_bypass":        8.1,
+	"path_traversal":           7.5,
+	"websocket_injection":      7.5,
+	"vhost_takeover":           7.4,
+	"ldap_injection":           7.3,
+	"idor":                     6.5,
+	"business_logic":           6.5,
+	"method_override":          6.5,
+	"xss_reflected":            6.1,
+	"excessive_data_exposure":  5.3,
+	"account_enumeration":      5.3,
+	"insecure_cookies":         4.3,
+}
+
+// classifyAttacks labels each result's input for the request's log entry:
+// with the attack type the module reported, or the signature it matched, a
+// severity score, and the snippet of the payload that matched
+func classifyAttacks(r *http.Request, results []server.ModuleResult) {
+	var attacks []logger.Attack
+	for _, result := range results {
+		if attack, ok := classify(result); ok {
+			attacks = append(attacks, attack)
+		}
+	}
+	logger.Classify(r, attacks...)
+}
+
+// classify labels one result's input, reporting false when it isn't an attack:
+// the module reported neither an attack nor success, and no signature matched
+func classify(result server.ModuleResult) (logger.Attack, bool) {
+	data := resultData(result)
+	exploitable, _ := data["exploitable"].(bool)
+	reported := reportedAttackType(data)
+	matched, snippet := matchSignature(result.Module, result.Input)
+	if !exploitable && reported == "" && matched == "" {
+		return logger.Attack{}, false
+	}
+
+	attack := logger.Attack{
+		Module:      result.Module,
+		Type:        reported,
+		Param:       result.Param,
+		Placement:   result.Placement,
+		Exploitable: exploitable,
+		Payload:     snippet,
+	}
+	if attack.Type == "" {
+		attack.Type = matched
+	}
+	if attack.Type == "" {
+		attack.Type = result.Module
+	}
+	if attack.Payload == "" {
+		attack.Payload = truncate(result.Input, maxAttackPayload)
+	}
+	attack.Score = attackScore(attack.Type, result.Module)
+	attack.Severity = logger.Severity(attack.Score)
+	return attack, true
+}
+
+// attackScore returns the base score of an attack type, else of the module
+// that ran, else defaultAttackScore
+func attackScore(attackType, module string) float64 {
+	if score, ok := attackScores[attackType]; ok {
+		return score
+	}
+	if score, ok := attackScores[module]; ok {
+		return score
+	}
+	return defaultAttackScore
+}
+
+// matchSignature returns the attack type whose signature input matches and
+// the snippet around the match, trying the module's own signature first
+func matchSignature(module, input string) (attackType, snippet string) {
+	if input == "" {
+		return "", ""
+	}
+	for _, signature := range attackSignatures {
+		if signature.attackType == module {
+			if loc := signature.pattern.FindStringIndex(input); loc != nil {
+				return module, snippetOf(input, loc)
+			}
+		}
+	}
+	for _, signature := range attackSignatures {
+		if loc := signature.pattern.FindStringIndex(input); loc != nil {
+			return signature.attackType, snippetOf(input, loc)
+		}
+	}
+	return "", ""
+}
+
+// snippetOf cuts the match at loc out of input with snippetContext bytes
+// either side, on rune boundaries, bounded by maxAttackPayload
+func snippetOf(input string, loc []int) string {
+	start, end := max(loc[0]-snippetContext, 0), min(loc[1]+snippetContext, len(input))
+	for start > 0 && !utf8.RuneStart(input[start]) {
+		start--
+	}
+	for end < len(input) && !utf8.RuneStart(input[end]) {
+		end++
+	}
+	return truncate(input[start:end], maxAttackPayload)
+}
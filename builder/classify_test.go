@@ -0,0 +1,89 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/logger"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// TestClassify tests inputs are labelled with the type the module reported,
+// else the signature they match, and harmless ones aren't labelled
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  server.ModuleResult
+		attack  bool
+		kind    string
+		score   float64
+		payload string
+	}{
+		{"harmless", server.ModuleResult{Module: "sql_injection", Input: "42"}, false, "", 0, ""},
+		{"own signature", server.ModuleResult{Module: "sql_injection", Input: "1' OR '1'='1"}, true, "sql_injection", 9.8, "1' OR '1'='1"},
+		{"other signature", server.ModuleResult{Module: "idor", Input: "../../etc/passwd"}, true, "path_traversal", 7.5, "../../etc/passwd"},
+		{"module first", server.ModuleResult{Module: "command_injection", Input: "x; cat /etc/passwd"}, true, "command_injection", 9.8, "x; cat /etc/passwd"},
+		{"reported", server.ModuleResult{Module: "xxe", Input: "<x/>", Data: map[string]interface{}{"exploitable": true, "attack_type": "file_disclosure"}}, true, "file_disclosure", 8.2, "<x/>"},
+		{"failed", server.ModuleResult{Module: "xxe", Input: "<x/>", Error: "boom"}, false, "", 0, ""},
+	}
+	for _, tt := range tests {
+		attack, ok := classify(tt.result)
+		if ok != tt.attack {
+			t.Errorf("%s: expected attack %v, got %+v", tt.name, tt.attack, attack)
+			continue
+		}
+		if ok && (attack.Type != tt.kind || attack.Score != tt.score || attack.Payload != tt.payload) {
+			t.Errorf("%s: expected %s %.1f %q, got %s %.1f %q", tt.name, tt.kind, tt.score, tt.payload, attack.Type, attack.Score, attack.Payload)
+		}
+	}
+
+	padding := strings.Repeat("a", 100)
+	attack, _ := classify(server.ModuleResult{Module: "xss_reflected", Input: padding + "<script>alert(1)</script>" + padding})
+	if attack.Severity != "medium" || attack.Payload != padding[:snippetContext]+"<script>alert(1)</script>"+padding[:snippetContext-len(">alert(1)</script>")] {
+		t.Errorf("Expected a medium attack with the match and its context, got %s %q", attack.Severity, attack.Payload)
+	}
+}
+
+// TestClassifyAttacks tests a request's log entry carries the attacks its
+// modules saw
+func TestClassifyAttacks(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{Name: "labels", Port: 8080},
+		Endpoints: []config.EndpointConfig{{
+			Path:   "/search",
+			Method: "GET",
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xss_reflected", Placement: "query_param", Param: "q"},
+			},
+		}},
+	}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+
+	entries, cancel := srv.Feed().Subscribe()
+	defer cancel()
+	get := func(q string) *logger.RequestLog {
+		srv.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(q), nil))
+		return (<-entries).Data.(*logger.RequestLog)
+	}
+
+	if entry := get("shoes"); len(entry.Attacks) != 0 {
+		t.Errorf("Expected no attacks, got %+v", entry.Attacks)
+	}
+	entry := get("<img src=x onerror=alert(1)>")
+	if len(entry.Attacks) != 1 {
+		t.Fatalf("Expected an attack, got %+v", entry.Attacks)
+	}
+	attack := entry.Attacks[0]
+	if attack.Module != "xss_reflected" || attack.Type != "xss_reflected" || attack.Param != "q" || attack.Placement != "query_param" || attack.Severity != "medium" {
+		t.Errorf("Expected a labelled reflected XSS, got %+v", attack)
+	}
+}
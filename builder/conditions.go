@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// activeVulnerabilities returns the vulnerabilities whose enabled_if conditions
+// hold for r, in their configured order
+func activeVulnerabilities(vulns []config.VulnerabilityConfig, r *http.Request) []config.VulnerabilityConfig {
+	now := time.Now()
+	active := make([]config.VulnerabilityConfig, 0, len(vulns))
+	for _, vuln := range vulns {
+		if conditionsHold(vuln.EnabledIf, r, now, rand.IntN(100)) {
+			active = append(active, vuln)
+		}
+	}
+	return active
+}
+
+// conditionsHold reports whether r meets every condition that is set. roll is
+// a number from 0 to 99 drawn for the request, compared against percent.
+func conditionsHold(cond *config.ConditionConfig, r *http.Request, now time.Time, roll int) bool {
+	if cond == nil {
+		return true
+	}
+
+	if cond.Header != "" {
+		name, value, hasValue := config.SplitCondition(cond.Header)
+		if len(r.Header.Values(name)) == 0 || (hasValue && r.Header.Get(name) != value) {
+			return false
+		}
+	}
+
+	if cond.Cookie != "" {
+		name, value, hasValue := config.SplitCondition(cond.Cookie)
+		cookie, err := r.Cookie(name)
+		if err != nil || (hasValue && cookie.Value != value) {
+			return false
+		}
+	}
+
+	if cond.Query != "" {
+		name, value, hasValue := config.SplitCondition(cond.Query)
+		query := r.URL.Query()
+		if !query.Has(name) || (hasValue && query.Get(name) != value) {
+			return false
+		}
+	}
+
+	if cond.Time != "" {
+		start, end, err := cond.Window()
+		if err != nil {
+			return false
+		}
+		minute := now.Hour()*60 + now.Minute()
+		if start < end && (minute < start || minute >= end) {
+			return false
+		}
+		// The window wraps past midnight, e.g. 22:00-06:00
+		if start > end && minute < start && minute >= end {
+			return false
+		}
+	}
+
+	if cond.Percent > 0 && roll >= cond.Percent {
+		return false
+	}
+
+	return true
+}
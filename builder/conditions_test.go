@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestConditionsHold tests each enabled_if condition against requests
+func TestConditionsHold(t *testing.T) {
+	at := func(clock string) time.Time {
+		now, _ := time.Parse("15:04", clock)
+		return now
+	}
+	request := func(mutate func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/?debug=1", nil)
+		if mutate != nil {
+			mutate(r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		cond *config.ConditionConfig
+		r    *http.Request
+		now  time.Time
+		roll int
+		want bool
+	}{
+		{"no conditions", nil, request(nil), at("12:00"), 99, true},
+		{"header value", &config.ConditionConfig{Header: "X-Debug=1"}, request(func(r *http.Request) { r.Header.Set("X-Debug", "1") }), at("12:00"), 0, true},
+		{"header wrong value", &config.ConditionConfig{Header: "X-Debug=1"}, request(func(r *http.Request) { r.Header.Set("X-Debug", "0") }), at("12:00"), 0, false},
+		{"header presence", &config.ConditionConfig{Header: "X-Debug"}, request(func(r *http.Request) { r.Header.Set("x-debug", "") }), at("12:00"), 0, true},
+		{"header missing", &config.ConditionConfig{Header: "X-Debug"}, request(nil), at("12:00"), 0, false},
+		{"cookie value", &config.ConditionConfig{Cookie: "beta=true"}, request(func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "beta", Value: "true"}) }), at("12:00"), 0, true},
+		{"cookie missing", &config.ConditionConfig{Cookie: "beta"}, request(nil), at("12:00"), 0, false},
+		{"query value", &config.ConditionConfig{Query: "debug=1"}, request(nil), at("12:00"), 0, true},
+		{"query wrong value", &config.ConditionConfig{Query: "debug=true"}, request(nil), at("12:00"), 0, false},
+		{"inside window", &config.ConditionConfig{Time: "09:00-17:00"}, request(nil), at("09:00"), 0, true},
+		{"window end is exclusive", &config.ConditionConfig{Time: "09:00-17:00"}, request(nil), at("17:00"), 0, false},
+		{"inside wrapping window", &config.ConditionConfig{Time: "22:00-06:00"}, request(nil), at("02:30"), 0, true},
+		{"outside wrapping window", &config.ConditionConfig{Time: "22:00-06:00"}, request(nil), at("12:00"), 0, false},
+		{"percent hit", &config.ConditionConfig{Percent: 25}, request(nil), at("12:00"), 24, true},
+		{"percent miss", &config.ConditionConfig{Percent: 25}, request(nil), at("12:00"), 25, false},
+		{"every condition must hold", &config.ConditionConfig{Query: "debug=1", Percent: 50}, request(nil), at("12:00"), 80, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conditionsHold(tt.cond, tt.r, tt.now, tt.roll); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestBuilder_EnabledIf tests a conditional vulnerability is skipped when its conditions don't hold
+func TestBuilder_EnabledIf(t *testing.T) {
+	cfg := reloadConfig("/file", "hidden")
+	cfg.Endpoints[0].Vulnerabilities[0].EnabledIf = &config.ConditionConfig{Header: "X-Debug=1"}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil))
+	if body := rec.Body.String(); strings.Contains(body, "hidden") || !strings.Contains(body, "Hello from FlawFactory") {
+		t.Errorf("Expected the plain endpoint response, got %s", body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil)
+	req.Header.Set("X-Debug", "1")
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, req)
+	if body := rec.Body.String(); !strings.Contains(body, "hidden") {
+		t.Errorf("Expected the vulnerability to run with X-Debug, got %s", body)
+	}
+}
@@ -0,0 +1,207 @@
+package builder
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// CoverageReport tells which of an app's vulnerabilities requests triggered,
+// by sending them an attack payload, which of them were exploited, and by whom
+type CoverageReport struct {
+	App             string           `json:"app"`
+	Total           int              `json:"total"`
+	Triggered       int              `json:"triggered"`
+	Exploited       int              `json:"exploited"`
+	Percent         float64          `json:"coverage_percent"` // Share of the vulnerabilities triggered
+	Vulnerabilities []VulnCoverage   `json:"vulnerabilities"`  // In configured order
+	Clients         []ClientCoverage `json:"clients"`          // Most vulnerabilities triggered first
+}
+
+// VulnCoverage counts the requests that reached one vulnerability
+type VulnCoverage struct {
+	ID             string           `json:"id"`
+	Endpoint       string           `json:"endpoint"`
+	Type           string           `json:"type"`
+	Enabled        bool             `json:"enabled"`
+	Requests       int64            `json:"requests"`               // Requests its module ran on
+	Attacks        int64            `json:"attacks"`                // Of those, the ones carrying an attack payload
+	Exploits       int64            `json:"exploits"`               // Of those, the ones the module reported exploitable
+	AttackTypes    map[string]int64 `json:"attack_types,omitempty"` // Attacks by attack type
+	Clients        map[string]int64 `json:"clients,omitempty"`      // Attacks by client
+	FirstTriggered *time.Time       `json:"first_triggered,omitempty"`
+	LastTriggered  *time.Time       `json:"last_triggered,omitempty"`
+}
+
+// Triggered reports whether a request attacked the vulnerability
+func (v VulnCoverage) Triggered() bool {
+	return v.Attacks > 0
+}
+
+// ClientCoverage lists the vulnerabilities one client triggered
+type ClientCoverage struct {
+	Client    string    `json:"client"`
+	Triggered []string  `json:"triggered"`           // Vulnerability IDs
+	Exploited []string  `json:"exploited,omitempty"` // Vulnerability IDs
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// coverage tracks the requests each vulnerability's module ran on. It
+// outlives reloads, like the vulnerability toggles.
+type coverage struct {
+	mu      sync.Mutex
+	vulns   map[string]*VulnCoverage   // By vulnerability ID
+	clients map[string]*ClientCoverage // By client
+}
+
+// newCoverage creates a coverage with nothing triggered
+func newCoverage() *coverage {
+	c := &coverage{}
+	c.reset()
+	return c
+}
+
+// reset forgets every request recorded
+func (c *coverage) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.vulns = make(map[string]*VulnCoverage)
+	c.clients = make(map[string]*ClientCoverage)
+}
+
+// record counts the results of the vulnerabilities with the given IDs, whose
+// modules ran on r
+func (c *coverage) record(ids []string, r *http.Request, results []server.ModuleResult) {
+	client := chainClient(r)
+	now := time.Now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, result := range results {
+		if i >= len(ids) {
+			break
+		}
+		if result.Error != "" && result.Input == "" {
+			continue // The request didn't carry the input, so the module didn't run
+		}
+		vuln, ok := c.vulns[ids[i]]
+		if !ok {
+			vuln = &VulnCoverage{AttackTypes: make(map[string]int64), Clients: make(map[string]int64)}
+			c.vulns[ids[i]] = vuln
+		}
+		vuln.Requests++
+
+		// Exploits are attacks too, whether or not they match a signature
+		attack, attacked := classify(result)
+		if !attacked {
+			continue
+		}
+		exploited := attack.Exploitable
+		vuln.Attacks++
+		vuln.AttackTypes[attack.Type]++
+		if exploited {
+			vuln.Exploits++
+		}
+		vuln.Clients[client]++
+		if vuln.FirstTriggered == nil {
+			vuln.FirstTriggered = &now
+		}
+		vuln.LastTriggered = &now
+
+		seen, ok := c.clients[client]
+		if !ok {
+			seen = &ClientCoverage{Client: client}
+			c.clients[client] = seen
+		}
+		seen.LastSeen = now
+		if !slices.Contains(seen.Triggered, ids[i]) {
+			seen.Triggered = append(seen.Triggered, ids[i])
+		}
+		if exploited && !slices.Contains(seen.Exploited, ids[i]) {
+			seen.Exploited = append(seen.Exploited, ids[i])
+		}
+	}
+}
+
+// vulnIDs returns the IDs of vulns, the endpoint's vulnerabilities left after
+// toggles and conditions, which keep their configured order
+func (b *Builder) vulnIDs(endpoint config.EndpointConfig, vulns []config.VulnerabilityConfig) []string {
+	ids := make([]string, 0, len(vulns))
+	next := 0
+	for _, vuln := range vulns {
+		for next < len(endpoint.Vulnerabilities) && (!b.toggles.enabled(vulnID(endpoint, next)) || !reflect.DeepEqual(endpoint.Vulnerabilities[next], vuln)) {
+			next++
+		}
+		ids = append(ids, vulnID(endpoint, next))
+		next++
+	}
+	return ids
+}
+
+// coverageReport reports the coverage of the configured vulnerabilities
+func (b *Builder) coverageReport() CoverageReport {
+	report := CoverageReport{
+		App:             b.config.App.Name,
+		Vulnerabilities: []VulnCoverage{},
+		Clients:         []ClientCoverage{},
+	}
+	configured := make(map[string]bool)
+
+	b.coverage.mu.Lock()
+	defer b.coverage.mu.Unlock()
+	for _, vuln := range b.vulnReports() {
+		configured[vuln.ID] = true
+		entry := VulnCoverage{ID: vuln.ID, Endpoint: vuln.Endpoint, Type: vuln.Type, Enabled: vuln.Enabled}
+		if recorded, ok := b.coverage.vulns[vuln.ID]; ok {
+			entry.Requests, entry.Attacks, entry.Exploits = recorded.Requests, recorded.Attacks, recorded.Exploits
+			entry.FirstTriggered, entry.LastTriggered = recorded.FirstTriggered, recorded.LastTriggered
+			if entry.Triggered() {
+				entry.AttackTypes, entry.Clients = maps.Clone(recorded.AttackTypes), maps.Clone(recorded.Clients)
+			}
+		}
+		report.Total++
+		if entry.Triggered() {
+			report.Triggered++
+		}
+		if entry.Exploits > 0 {
+			report.Exploited++
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, entry)
+	}
+	if report.Total > 0 {
+		report.Percent = float64(report.Triggered*1000/report.Total) / 10
+	}
+
+	// Clients are reported on the vulnerabilities still configured
+	for _, seen := range b.coverage.clients {
+		client := ClientCoverage{Client: seen.Client, LastSeen: seen.LastSeen}
+		for _, id := range seen.Triggered {
+			if configured[id] {
+				client.Triggered = append(client.Triggered, id)
+			}
+		}
+		for _, id := range seen.Exploited {
+			if configured[id] {
+				client.Exploited = append(client.Exploited, id)
+			}
+		}
+		if len(client.Triggered) > 0 {
+			report.Clients = append(report.Clients, client)
+		}
+	}
+	slices.SortFunc(report.Clients, func(a, b ClientCoverage) int {
+		return cmp.Or(
+			cmp.Compare(len(b.Triggered), len(a.Triggered)),
+			cmp.Compare(len(b.Exploited), len(a.Exploited)),
+			cmp.Compare(a.Client, b.Client),
+		)
+	})
+	return report
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestCoverage tests attacks are counted on the vulnerability they reached,
+// by attack type and client, and harmless requests don't trigger it
+func TestCoverage(t *testing.T) {
+	xss := config.VulnerabilityConfig{Type: "xss_reflected", Placement: "query_param", Param: "q"}
+	cfg := &config.Config{
+		App: config.AppConfig{Name: "coverage", Port: 8080, Admin: &config.AdminConfig{Token: "secret"}},
+		Endpoints: []config.EndpointConfig{
+			{Path: "/search", Method: "GET", Vulnerabilities: []config.VulnerabilityConfig{xss, xss}},
+			{Path: "/profile", Method: "GET", Vulnerabilities: []config.VulnerabilityConfig{{Type: "xss_reflected", Placement: "query_param", Param: "name"}}},
+		},
+	}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+	b.toggles.set("GET /search#0", false) // The second, identical vulnerability is reached
+
+	get := func(client, target string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = client + ":40000"
+		srv.Router().ServeHTTP(httptest.NewRecorder(), req)
+	}
+	get("10.0.0.1", "/search?q=shoes")
+	get("10.0.0.1", "/search?q="+url.QueryEscape("<script>alert(1)</script>"))
+	get("10.0.0.2", "/search?q="+url.QueryEscape("<img src=x onerror=alert(1)>"))
+	get("10.0.0.2", "/profile") // Reaches the module, without an attack
+
+	rec := httptest.NewRecorder()
+	srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/coverage", nil))
+	var report CoverageReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a report, got %s", rec.Body.String())
+	}
+	if report.Total != 3 || report.Triggered != 1 || report.Percent != 33.3 {
+		t.Errorf("Expected 1 of 3 triggered, got %d of %d (%.1f%%)", report.Triggered, report.Total, report.Percent)
+	}
+
+	search := report.Vulnerabilities[1]
+	if search.ID != "GET /search#1" || search.Requests != 3 || search.Attacks != 2 || search.AttackTypes["xss_reflected"] != 2 {
+		t.Errorf("Expected 2 attacks among 3 requests on GET /search#1, got %+v", search)
+	}
+	if search.Clients["10.0.0.1"] != 1 || search.Clients["10.0.0.2"] != 1 || search.FirstTriggered == nil {
+		t.Errorf("Expected an attack by each client, got %+v", search.Clients)
+	}
+	if first, profile := report.Vulnerabilities[0], report.Vulnerabilities[2]; first.Enabled || first.Requests != 0 || profile.Requests != 1 || profile.Triggered() {
+		t.Errorf("Expected the disabled vulnerability untouched and the other reached, not triggered, got %+v and %+v", first, profile)
+	}
+	if len(report.Clients) != 2 || report.Clients[0].Client != "10.0.0.1" || report.Clients[0].Triggered[0] != "GET /search#1" {
+		t.Errorf("Expected both clients, got %+v", report.Clients)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest("DELETE", "/coverage", nil))
+	if report := b.coverageReport(); rec.Code != http.StatusOK || report.Triggered != 0 || len(report.Clients) != 0 {
+		t.Errorf("Expected the coverage cleared, got %d and %+v", rec.Code, report)
+	}
+}
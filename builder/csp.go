@@ -0,0 +1,108 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// defaultJSONPPath is where the JSONP endpoint is served for the jsonp preset
+const defaultJSONPPath = "/api/jsonp"
+
+// cspPresets are intentionally bypassable policies, plus a strict one for comparison.
+// Inline styles are allowed throughout so the response wrapper still renders.
+var cspPresets = map[string]string{
+	// Injected inline <script> and event handlers run
+	"unsafe_inline": "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; object-src 'none'",
+	// Scripts load from any host, including the attacker's
+	"wildcard": "default-src 'self'; script-src 'self' *; style-src 'self' 'unsafe-inline'; object-src 'none'",
+	// 'self' looks safe, but the app serves a JSONP endpoint with an unvalidated callback
+	"jsonp": "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; object-src 'none'",
+	// No script sources at all
+	"strict": "default-src 'none'; script-src 'none'; style-src 'self' 'unsafe-inline'; img-src 'self'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'",
+}
+
+// cspPolicy returns the policy to send for an endpoint's CSP settings
+func cspPolicy(csp *config.CSPConfig) string {
+	if csp.Policy != "" {
+		return csp.Policy
+	}
+	return cspPresets[csp.Preset]
+}
+
+// applyCSP sets the endpoint's Content-Security-Policy header
+func applyCSP(w http.ResponseWriter, csp *config.CSPConfig) {
+	if csp == nil {
+		return
+	}
+
+	policy := cspPolicy(csp)
+	if policy == "" {
+		return
+	}
+
+	header := "Content-Security-Policy"
+	if csp.ReportOnly {
+		header = "Content-Security-Policy-Report-Only"
+	}
+	w.Header().Set(header, policy)
+}
+
+// jsonpPath returns the JSONP endpoint path for an endpoint's CSP settings
+func jsonpPath(csp *config.CSPConfig) string {
+	if csp.JSONPPath != "" {
+		return csp.JSONPPath
+	}
+	return defaultJSONPPath
+}
+
+// registerJSONPEndpoints serves JSONP for endpoints using the jsonp preset.
+// Paths already claimed by a configured endpoint are left alone.
+func (b *Builder) registerJSONPEndpoints(router *server.Router) {
+	claimed := make(map[string]bool)
+	for _, endpoint := range b.config.Endpoints {
+		if endpoint.Host == "" {
+			claimed[strings.ToUpper(endpoint.Method)+" "+endpoint.Path] = true
+		}
+	}
+
+	for _, endpoint := range b.config.Endpoints {
+		if endpoint.CSP == nil || endpoint.CSP.Preset != "jsonp" {
+			continue
+		}
+
+		path := jsonpPath(endpoint.CSP)
+		if claimed["GET "+path] {
+			continue
+		}
+		claimed["GET "+path] = true
+
+		router.HandleFunc("GET", path, serveJSONP)
+	}
+}
+
+// serveJSONP wraps a JSON payload in the caller-supplied callback.
+// The callback is not validated, so arbitrary script can be returned from a same-origin URL.
+func serveJSONP(w http.ResponseWriter, r *http.Request) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"status": "ok",
+		"user":   "guest",
+	})
+
+	callback := r.URL.Query().Get("callback")
+	if callback == "" {
+		callback = r.URL.Query().Get("cb")
+	}
+	if callback == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(payload)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/javascript")
+	fmt.Fprintf(w, "%s(%s);", callback, payload)
+}
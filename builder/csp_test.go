@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestCSPPolicy tests preset and raw policy selection
+func TestCSPPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		csp      config.CSPConfig
+		contains string
+	}{
+		{"unsafe_inline preset", config.CSPConfig{Preset: "unsafe_inline"}, "script-src 'self' 'unsafe-inline'"},
+		{"wildcard preset", config.CSPConfig{Preset: "wildcard"}, "script-src 'self' *"},
+		{"strict preset", config.CSPConfig{Preset: "strict"}, "script-src 'none'"},
+		{"raw policy overrides preset", config.CSPConfig{Preset: "strict", Policy: "script-src https://cdn.example.com"}, "https://cdn.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if policy := cspPolicy(&tt.csp); !strings.Contains(policy, tt.contains) {
+				t.Errorf("Expected policy to contain %q, got %q", tt.contains, policy)
+			}
+		})
+	}
+}
+
+// TestBuilder_Build_WithCSP tests CSP headers and the app-served JSONP endpoint
+func TestBuilder_Build_WithCSP(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "csp-test",
+			Port: 8080,
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:         "/search",
+				Method:       "GET",
+				ResponseType: "html",
+				CSP:          &config.CSPConfig{Preset: "jsonp"},
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "xss_reflected", Placement: "query_param", Param: "q"},
+				},
+			},
+			{
+				Path:   "/report",
+				Method: "GET",
+				CSP:    &config.CSPConfig{Preset: "unsafe_inline", ReportOnly: true},
+			},
+		},
+	}
+
+	srv, err := New(cfg, "").Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=test", nil))
+	if got := rec.Header().Get("Content-Security-Policy"); got != cspPresets["jsonp"] {
+		t.Errorf("Expected jsonp policy, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+	if rec.Header().Get("Content-Security-Policy-Report-Only") == "" {
+		t.Error("Expected report-only CSP header")
+	}
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Error("Expected no enforcing CSP header in report-only mode")
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jsonp?callback=alert(document.domain)//", nil))
+	if body := rec.Body.String(); !strings.HasPrefix(body, "alert(document.domain)//(") {
+		t.Errorf("Expected unvalidated callback in JSONP response, got %q", body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/javascript" {
+		t.Errorf("Expected application/javascript, got %q", ct)
+	}
+}
@@ -0,0 +1,62 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// targetURL expands an endpoint's target for a request. The request's query
+// string is kept unless the target has its own.
+func targetURL(endpoint config.EndpointConfig, r *http.Request) string {
+	target := endpoint.ExpandTarget(r.PathValue)
+	if r.URL.RawQuery != "" && !strings.Contains(target, "?") {
+		target += "?" + r.URL.RawQuery
+	}
+	return target
+}
+
+// redirectHandler answers every request with a redirect to the endpoint's target
+func redirectHandler(endpoint config.EndpointConfig) http.HandlerFunc {
+	status := endpoint.Status
+	if status == 0 {
+		status = http.StatusFound
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		applyHeaders(w, endpoint.Headers)
+		http.Redirect(w, r, targetURL(endpoint, r), status)
+	}
+}
+
+// proxyHandler forwards requests to the endpoint's target through the HTTP
+// sink, so the egress policy applies and routed hosts, like another app or an
+// emulated service, are reached in-process
+func (b *Builder) proxyHandler(endpoint config.EndpointConfig, responseType string) http.HandlerFunc {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			target, err := url.Parse(targetURL(endpoint, pr.In))
+			if err != nil {
+				return // Left to fail in the transport
+			}
+			pr.Out.URL = target
+			pr.Out.Host = ""
+			pr.SetXForwarded()
+		},
+		Transport: b.sinks.httpSink.Transport(),
+		ModifyResponse: func(resp *http.Response) error {
+			setHeaders(resp.Header, endpoint.Headers)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			server.NewResponseBuilder().SendError(w, responseType, http.StatusBadGateway, "bad gateway", server.DebugInfo{
+				Message: err.Error(),
+			})
+		},
+	}
+	return proxy.ServeHTTP
+}
@@ -0,0 +1,104 @@
+package builder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// endpointTypesRouter registers endpoints on a router, with an HTTP sink for proxies
+func endpointTypesRouter(t *testing.T, endpoints []config.EndpointConfig) *server.Router {
+	t.Helper()
+	b := New(&config.Config{App: config.AppConfig{Name: "gateway"}, Endpoints: endpoints}, "")
+	b.sinks.httpSink = sinks.NewHTTP()
+
+	router := server.NewRouter(nil)
+	for _, endpoint := range endpoints {
+		if err := b.registerEndpoint(router, endpoint); err != nil {
+			t.Fatalf("Failed to register %s %s: %v", endpoint.Method, endpoint.Path, err)
+		}
+	}
+	return router
+}
+
+// TestRedirectEndpoint tests redirects expand path parameters and keep the query
+func TestRedirectEndpoint(t *testing.T) {
+	router := endpointTypesRouter(t, []config.EndpointConfig{
+		{Path: "/old/{id}", Method: "GET", Type: "redirect", Target: "/users/{id}"},
+		{Path: "/legacy", Method: "GET", Type: "redirect", Target: "https://example.com/new?from=legacy", Status: 301},
+	})
+
+	tests := []struct {
+		target   string
+		status   int
+		location string
+	}{
+		{"/old/42?tab=profile", http.StatusFound, "/users/42?tab=profile"},
+		{"/old/a%20b", http.StatusFound, "/users/a%20b"},
+		{"/legacy?x=1", http.StatusMovedPermanently, "https://example.com/new?from=legacy"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.status || rec.Header().Get("Location") != tt.location {
+			t.Errorf("%s: expected %d to %s, got %d to %s", tt.target, tt.status, tt.location, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
+
+// TestProxyEndpoint tests requests are forwarded with their method, body and
+// wildcard path, and the endpoint's headers are set on the response
+func TestProxyEndpoint(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Server", "internal")
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+	}))
+	defer upstream.Close()
+
+	router := endpointTypesRouter(t, []config.EndpointConfig{
+		{Path: "/api/{rest...}", Method: "POST", Type: "proxy", Target: upstream.URL + "/v2/{rest}", Headers: map[string]string{"Server": "gateway"}},
+		{Path: "/down", Method: "GET", Type: "proxy", Target: "http://127.0.0.1:1/"},
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/users/7?debug=1", strings.NewReader("name=x")))
+	if rec.Code != http.StatusOK || rec.Body.String() != "POST /v2/users/7?debug=1 name=x" {
+		t.Errorf("Unexpected proxied response %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Server") != "gateway" {
+		t.Errorf("Expected the endpoint's headers to win, got Server %q", rec.Header().Get("Server"))
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/down", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected 502 when the target is down, got %d", rec.Code)
+	}
+}
+
+// TestAliasEndpoint tests an alias serves its target's handler, path types included
+func TestAliasEndpoint(t *testing.T) {
+	router := endpointTypesRouter(t, []config.EndpointConfig{
+		{Path: "/users/{id:int}", Method: "GET", Status: http.StatusAccepted},
+		{Path: "/u/{id}", Method: "GET", Type: "alias", Target: "GET /users/{id:int}"},
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/u/5", nil))
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), "/users/{id:int}") {
+		t.Errorf("Expected the target's response, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/u/abc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the target's {id:int} to apply, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// errorPage is a configured response to unmatched requests
+type errorPage struct {
+	config *config.ErrorPageConfig
+	body   *template.Template
+}
+
+// errorView is the data an error page's body is executed with
+type errorView struct {
+	Request   *http.Request
+	Status    int
+	RequestID string
+}
+
+// newErrorPage parses a page's body template, or returns nil if the page isn't configured
+func newErrorPage(page *config.ErrorPageConfig, name string) (*errorPage, error) {
+	if page == nil {
+		return nil, nil
+	}
+	body, err := page.ParseBody(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", name, err)
+	}
+	return &errorPage{config: page, body: body}, nil
+}
+
+// registerErrorPages answers requests no route matches with the pages of
+// app.errors; unconfigured ones keep the plain ServeMux responses
+func (b *Builder) registerErrorPages(router *server.Router) error {
+	cfg := b.config.App.Errors
+	if cfg == nil {
+		return nil
+	}
+	notFound, err := newErrorPage(cfg.NotFound, "not_found")
+	if err != nil {
+		return err
+	}
+	methodNotAllowed, err := newErrorPage(cfg.MethodNotAllowed, "method_not_allowed")
+	if err != nil {
+		return err
+	}
+
+	router.HandleUnmatched(func(w http.ResponseWriter, r *http.Request, status int) {
+		page := notFound
+		if status == http.StatusMethodNotAllowed {
+			page = methodNotAllowed
+		}
+		if page == nil {
+			if status == http.StatusNotFound {
+				http.NotFound(w, r)
+			} else {
+				http.Error(w, http.StatusText(status), status)
+			}
+			return
+		}
+		page.serve(w, r, status, router)
+	})
+	return nil
+}
+
+// serve writes the page for a request that got status
+func (p *errorPage) serve(w http.ResponseWriter, r *http.Request, status int, router *server.Router) {
+	if p.config.Status != 0 {
+		status = p.config.Status
+	}
+
+	if p.config.Verbose {
+		contentType := p.config.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(map[string]interface{}{
+			"error":      http.StatusText(status),
+			"status":     status,
+			"request_id": server.RequestID(r),
+			"request": map[string]interface{}{
+				"method":  r.Method,
+				"path":    r.URL.Path,
+				"query":   r.URL.RawQuery,
+				"host":    r.Host,
+				"headers": r.Header,
+			},
+			"routes": router.Routes(),
+			"stack":  strings.Split(strings.TrimSpace(string(debug.Stack())), "\n"),
+		})
+		return
+	}
+
+	body := http.StatusText(status)
+	if p.body != nil {
+		var buf bytes.Buffer
+		if err := p.body.Execute(&buf, errorView{Request: r, Status: status, RequestID: server.RequestID(r)}); err != nil {
+			http.Error(w, "error page failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = buf.String()
+	}
+	contentType := p.config.ContentType
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
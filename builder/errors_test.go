@@ -0,0 +1,62 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestErrorPages tests a soft 404 rendered from its template, a verbose 405
+// leaking the routes, and plain responses for pages left unconfigured
+func TestErrorPages(t *testing.T) {
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.Errors = &config.ErrorsConfig{
+		NotFound:         &config.ErrorPageConfig{Status: http.StatusOK, Body: "<h1>No page at {{.Request.URL.Path}}</h1>"},
+		MethodNotAllowed: &config.ErrorPageConfig{Verbose: true},
+	}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer func() { b.Close() }()
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/backup.zip")
+	if rec.Code != http.StatusOK || rec.Body.String() != "<h1>No page at /backup.zip</h1>" {
+		t.Errorf("Expected a soft 404, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(http.MethodDelete, "/file")
+	var verbose struct {
+		Status int      `json:"status"`
+		Routes []string `json:"routes"`
+		Stack  []string `json:"stack"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &verbose)
+	if rec.Code != http.StatusMethodNotAllowed || !strings.Contains(rec.Header().Get("Allow"), "GET") {
+		t.Errorf("Expected 405 allowing GET, got %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	if verbose.Status != http.StatusMethodNotAllowed || len(verbose.Stack) == 0 || !strings.Contains(strings.Join(verbose.Routes, ","), "GET /file") {
+		t.Errorf("Expected the routes and a stack trace, got %s", rec.Body.String())
+	}
+
+	cfg.App.Errors.MethodNotAllowed = nil
+	b.Close()
+	b = New(cfg, "")
+	if srv, err = b.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	if rec := serve(http.MethodDelete, "/file"); rec.Code != http.StatusMethodNotAllowed || !strings.Contains(rec.Body.String(), "Method Not Allowed") {
+		t.Errorf("Expected the plain 405, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,239 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// maxRecentEvents is how many exploitation events GET /events can list
+const maxRecentEvents = 500
+
+// maxEventPayload bounds the payload carried by an event, as request logs bound bodies
+const maxEventPayload = 10000
+
+// ExploitEvent records a request a module reported exploited its vulnerability
+type ExploitEvent struct {
+	Event       string    `json:"event"` // Always "exploitation"
+	Time        time.Time `json:"time"`
+	App         string    `json:"app"`
+	Endpoint    string    `json:"endpoint"` // METHOD host/path
+	Module      string    `json:"module"`
+	Param       string    `json:"param,omitempty"`
+	Placement   string    `json:"placement,omitempty"`
+	Payload     string    `json:"payload"`
+	AttackType  string    `json:"attack_type"`
+	Exploitable bool      `json:"exploitable"`
+	Client      string    `json:"client"`
+	RequestID   string    `json:"request_id,omitempty"` // The exploiting request's X-Request-ID
+}
+
+// exploitEvents keeps the latest events for the admin API. It outlives
+// reloads, like the vulnerability toggles.
+type exploitEvents struct {
+	mu     sync.Mutex
+	recent []ExploitEvent // Oldest first
+	feed   *server.Feed   // The server's live feed, once built
+}
+
+// newExploitEvents creates an empty event history
+func newExploitEvents() *exploitEvents {
+	return &exploitEvents{}
+}
+
+// add records an event, dropping the oldest past maxRecentEvents, and
+// streams it live
+func (e *exploitEvents) add(event ExploitEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recent = append(e.recent, event)
+	if len(e.recent) > maxRecentEvents {
+		e.recent = e.recent[len(e.recent)-maxRecentEvents:]
+	}
+	e.feed.Publish("exploitation", event)
+}
+
+// last returns up to limit of the latest events, oldest first
+func (e *exploitEvents) last(limit int) []ExploitEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	start := max(len(e.recent)-limit, 0)
+	return append([]ExploitEvent{}, e.recent[start:]...)
+}
+
+// notifier POSTs events to one of app.notifications
+type notifier struct {
+	config config.NotificationConfig
+	body   *template.Template // http targets only
+	client *http.Client
+}
+
+// newNotifiers creates a notifier per configured target. Body templates were
+// checked by validation, so a target that fails to parse here is skipped.
+func newNotifiers(cfg *config.Config) []*notifier {
+	var notifiers []*notifier
+	for _, target := range cfg.App.Notifications {
+		body, err := target.ParseBody()
+		if err != nil {
+			log.Printf("Warning: skipping notifications to %s: %v", target.URL, err)
+			continue
+		}
+		notifiers = append(notifiers, &notifier{
+			config: target,
+			body:   body,
+			client: &http.Client{Timeout: target.TimeoutDuration()},
+		})
+	}
+	return notifiers
+}
+
+// request builds the POST carrying an event in the target's format
+func (n *notifier) request(event ExploitEvent) (*http.Request, error) {
+	var body []byte
+	contentType := "application/json"
+	switch n.config.TypeName() {
+	case "slack":
+		text := fmt.Sprintf("*%s*: %s exploited on `%s` by %s (%s)\n```%s```",
+			event.App, event.Module, event.Endpoint, event.Client, event.AttackType, truncate(event.Payload, 2000))
+		body, _ = json.Marshal(map[string]string{"text": text})
+	case "http":
+		var buf bytes.Buffer
+		if err := n.body.Execute(&buf, event); err != nil {
+			return nil, fmt.Errorf("body template failed: %w", err)
+		}
+		body = buf.Bytes()
+		contentType = n.config.ContentType
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+	default:
+		body, _ = json.Marshal(event)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "FlawFactory")
+	for name, value := range n.config.Headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// send POSTs an event, logging failures: an unreachable target must not
+// affect the lab
+func (n *notifier) send(event ExploitEvent) {
+	req, err := n.request(event)
+	if err != nil {
+		log.Printf("Warning: failed to notify %s: %v", n.config.URL, err)
+		return
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		log.Printf("Warning: failed to notify %s: %v", n.config.URL, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Warning: %s rejected an exploitation event: %s", n.config.URL, resp.Status)
+	}
+}
+
+// emitExploits records an exploitation event for each result that reports
+// exploitable: true, and sends it to the notification targets in the background
+func (b *Builder) emitExploits(endpoint config.EndpointConfig, r *http.Request, results []server.ModuleResult) {
+	for _, result := range results {
+		data := exploitableData(result)
+		if data == nil {
+			continue
+		}
+		event := ExploitEvent{
+			Event:       "exploitation",
+			Time:        time.Now().UTC(),
+			App:         b.config.App.Name,
+			Endpoint:    endpointKey(endpoint),
+			Module:      result.Module,
+			Param:       result.Param,
+			Placement:   result.Placement,
+			Payload:     truncate(result.Input, maxEventPayload),
+			AttackType:  attackType(result.Module, data),
+			Exploitable: true,
+			Client:      chainClient(r),
+			RequestID:   result.RequestID,
+		}
+		b.events.add(event)
+		log.Printf("Exploitation: %s on %s by %s (%s)", event.Module, event.Endpoint, event.Client, event.AttackType)
+
+		for _, n := range b.notifiers {
+			b.notifying.Add(1)
+			go func() {
+				defer b.notifying.Done()
+				n.send(event)
+			}()
+		}
+	}
+}
+
+// attackType names the attack from the fields modules report it in, falling
+// back to the module's name
+func attackType(module string, data map[string]interface{}) string {
+	if reported := reportedAttackType(data); reported != "" {
+		return reported
+	}
+	return module
+}
+
+// reportedAttackType returns the attack a module's data names, or ""
+func reportedAttackType(data map[string]interface{}) string {
+	for _, key := range []string{"attack_type", "injection_type", "payload_type"} {
+		if s, ok := data[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// truncate cuts s to n bytes, marking that it was cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "... (truncated)"
+}
+
+// exploitableData returns a result's data decoded as a JSON object when it
+// reports exploitable: true, or nil
+func exploitableData(result server.ModuleResult) map[string]interface{} {
+	data := resultData(result)
+	if exploitable, _ := data["exploitable"].(bool); !exploitable {
+		return nil
+	}
+	return data
+}
+
+// resultData returns a result's data decoded as a JSON object, or nil when it
+// failed or its data isn't one
+func resultData(result server.ModuleResult) map[string]interface{} {
+	if result.Error != "" || result.Data == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(result.Data)
+	if err != nil {
+		return nil
+	}
+	var data map[string]interface{}
+	if json.Unmarshal(encoded, &data) != nil {
+		return nil
+	}
+	return data
+}
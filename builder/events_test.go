@@ -0,0 +1,91 @@
+package builder
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestExploitEvents tests an exploited vulnerability is recorded and sent to
+// each kind of notification target, and a harmless request isn't
+func TestExploitEvents(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]string)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = r.Header.Get("Content-Type") + " " + r.Header.Get("Authorization") + " " + string(body)
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:  "events",
+			Port:  8080,
+			Admin: &config.AdminConfig{Token: "secret"},
+			Notifications: []config.NotificationConfig{
+				{URL: target.URL + "/webhook", Headers: map[string]string{"Authorization": "Bearer hook"}},
+				{Type: "slack", URL: target.URL + "/slack"},
+				{Type: "http", URL: target.URL + "/http", Body: "{{.Module}} by {{.Client}}"},
+			},
+		},
+		Endpoints: []config.EndpointConfig{{
+			Path:   "/import",
+			Method: "POST",
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xxe", Placement: "raw_body", Param: "body"},
+			},
+		}},
+	}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	post := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(body))
+		req.RemoteAddr = "10.0.0.7:51234"
+		srv.Router().ServeHTTP(httptest.NewRecorder(), req)
+	}
+	post(`<note>hello</note>`)
+	post(`<!DOCTYPE x [<!ENTITY e SYSTEM "file:///etc/passwd">]><x>&e;</x>`)
+	b.Close() // Waits for the notifications
+
+	events := b.events.last(defaultLogLimit)
+	if len(events) != 1 {
+		t.Fatalf("Expected one exploitation event, got %v", events)
+	}
+	event := events[0]
+	if event.Event != "exploitation" || event.Module != "xxe" || event.Endpoint != "POST /import" || event.Client != "10.0.0.7" || !event.Exploitable || !strings.Contains(event.Payload, "ENTITY") || event.AttackType == "" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	var webhook ExploitEvent
+	if body, ok := strings.CutPrefix(received["/webhook"], "application/json Bearer hook "); !ok || json.Unmarshal([]byte(body), &webhook) != nil || webhook.Module != "xxe" {
+		t.Errorf("Expected the event as JSON with the configured header, got %q", received["/webhook"])
+	}
+	var slack map[string]string
+	if body, _ := strings.CutPrefix(received["/slack"], "application/json  "); json.Unmarshal([]byte(body), &slack) != nil || !strings.Contains(slack["text"], "xxe exploited on `POST /import` by 10.0.0.7") {
+		t.Errorf("Expected a Slack message, got %q", received["/slack"])
+	}
+	if received["/http"] != "text/plain  xxe by 10.0.0.7" {
+		t.Errorf("Expected the rendered body template, got %q", received["/http"])
+	}
+
+	rec := httptest.NewRecorder()
+	srv.AdminRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?limit=5", nil))
+	var listing struct {
+		Count int `json:"count"`
+	}
+	if json.Unmarshal(rec.Body.Bytes(), &listing); listing.Count != 1 {
+		t.Errorf("Expected GET /events to list the event, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,46 @@
+package builder
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// withFaults wraps an endpoint's handler with its artificial latency and
+// error rate, or returns it unchanged if neither is configured
+func withFaults(endpoint config.EndpointConfig, responseType string, next http.HandlerFunc) http.HandlerFunc {
+	var minDelay, maxDelay time.Duration
+	if endpoint.Latency != nil {
+		minDelay, maxDelay, _ = endpoint.Latency.Range() // Validated when the config was loaded
+	}
+	if maxDelay == 0 && endpoint.ErrorRate == 0 {
+		return next
+	}
+	respBuilder := server.NewResponseBuilder()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maxDelay > 0 {
+			delay := minDelay
+			if maxDelay > minDelay {
+				delay += rand.N(maxDelay - minDelay + 1)
+			}
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return // The client gave up waiting
+			}
+		}
+
+		if endpoint.ErrorRate > 0 && rand.Float64() < endpoint.ErrorRate {
+			respBuilder.SendError(w, responseType, http.StatusServiceUnavailable, "service temporarily unavailable", server.DebugInfo{
+				Message: "error injected by the endpoint's error_rate",
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
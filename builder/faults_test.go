@@ -0,0 +1,55 @@
+package builder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestWithFaults tests requests are delayed within the latency range and failed at the error rate
+func TestWithFaults(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	serve := func(endpoint config.EndpointConfig) (*httptest.ResponseRecorder, time.Duration) {
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		withFaults(endpoint, "json", ok)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec, time.Since(start)
+	}
+
+	rec, elapsed := serve(config.EndpointConfig{Latency: &config.LatencyConfig{Min: "40ms", Max: "60ms"}})
+	if rec.Code != http.StatusOK || elapsed < 40*time.Millisecond {
+		t.Errorf("Expected a delayed 200, got %d after %s", rec.Code, elapsed)
+	}
+
+	rec, _ = serve(config.EndpointConfig{ErrorRate: 1})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected an injected 503, got %d", rec.Code)
+	}
+
+	rec, elapsed = serve(config.EndpointConfig{})
+	if rec.Code != http.StatusOK || elapsed > 20*time.Millisecond {
+		t.Errorf("Expected an immediate 200, got %d after %s", rec.Code, elapsed)
+	}
+}
+
+// TestWithFaults_ClientGivesUp tests the delay ends early when the request is canceled
+func TestWithFaults_ClientGivesUp(t *testing.T) {
+	called := false
+	handler := withFaults(config.EndpointConfig{Latency: &config.LatencyConfig{Min: "10s"}}, "json", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	handler(httptest.NewRecorder(), req.WithContext(ctx))
+	if called || time.Since(start) > time.Second {
+		t.Errorf("Expected the handler to be skipped once the client gave up")
+	}
+}
@@ -0,0 +1,135 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// defaultFilesystemAPIPath is where filesystem usage and reset are served
+const defaultFilesystemAPIPath = "/filesystem"
+
+// filesystemAPIPath returns the configured API path
+func (b *Builder) filesystemAPIPath() string {
+	if cfg := b.config.App.Filesystem; cfg != nil && cfg.APIPath != "" {
+		return strings.TrimRight(cfg.APIPath, "/")
+	}
+	return defaultFilesystemAPIPath
+}
+
+// checkpointFilesystem records the seeded files as the reset state, applies the
+// configured quota and starts the periodic reset
+func (b *Builder) checkpointFilesystem() error {
+	fs := b.sinks.filesystem
+	if fs == nil {
+		return nil
+	}
+
+	if err := fs.Checkpoint(); err != nil {
+		return err
+	}
+
+	cfg := b.config.App.Filesystem
+	if cfg == nil {
+		return nil
+	}
+
+	fs.SetQuota(sinks.FilesystemQuota{MaxBytes: cfg.MaxBytes, MaxFiles: cfg.MaxFiles})
+
+	if cfg.ResetInterval != "" {
+		interval, err := time.ParseDuration(cfg.ResetInterval)
+		if err != nil {
+			return fmt.Errorf("invalid reset interval: %w", err)
+		}
+		b.stop = make(chan struct{})
+		go b.resetFilesystemEvery(interval, b.stop)
+		log.Printf("Filesystem resets to its initial files every %s", interval)
+	}
+
+	return nil
+}
+
+// resetFilesystemEvery restores the initial files on each tick until stop is closed
+func (b *Builder) resetFilesystemEvery(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := b.ResetFilesystem(); err != nil {
+				log.Printf("Filesystem reset failed: %v", err)
+			}
+		}
+	}
+}
+
+// ResetFilesystem restores the filesystem sink, and every client's copy of it,
+// to the files present at startup, discarding uploads and other changes made since
+func (b *Builder) ResetFilesystem() error {
+	if b.sinks.filesystem == nil {
+		return fmt.Errorf("filesystem sink not initialized")
+	}
+	if err := b.sinks.filesystem.Reset(); err != nil {
+		return err
+	}
+	if b.sinks.copies != nil {
+		return b.sinks.copies.resetFilesystems()
+	}
+	return nil
+}
+
+// registerFilesystemEndpoints serves usage and reset when the filesystem sink
+// is active. With isolation they apply to the client's own copy.
+//
+//	GET  /filesystem         bytes and files used, and the quota
+//	POST /filesystem/reset   restore the files present at startup
+func (b *Builder) registerFilesystemEndpoints(router *server.Router) {
+	if b.sinks.filesystem == nil {
+		return
+	}
+
+	path := b.filesystemAPIPath()
+
+	router.HandleFunc("GET", path, func(w http.ResponseWriter, r *http.Request) {
+		fs, err := b.filesystemFor(r)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		size, files, err := fs.Usage()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+			return
+		}
+
+		usage := map[string]interface{}{
+			"bytes": size,
+			"files": files,
+		}
+		if cfg := b.config.App.Filesystem; cfg != nil {
+			usage["max_bytes"] = cfg.MaxBytes
+			usage["max_files"] = cfg.MaxFiles
+		}
+		writeJSON(w, http.StatusOK, usage)
+	})
+
+	router.HandleFunc("POST", path+"/reset", func(w http.ResponseWriter, r *http.Request) {
+		reset := b.ResetFilesystem
+		if own, err := b.sinkCopyFor(r); err == nil && own != nil && own.filesystem != nil {
+			reset = own.filesystem.Reset
+		}
+		if err := reset(); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "reset"})
+	})
+}
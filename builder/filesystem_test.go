@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_Build_WithFilesystemReset tests quotas, the usage endpoint and resetting to the initial files
+func TestBuilder_Build_WithFilesystemReset(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:       "fs-test",
+			Port:       8080,
+			Filesystem: &config.FilesystemConfig{MaxFiles: 7, APIPath: "/_fs/"},
+		},
+		Files: []config.FileConfig{
+			{Path: "uploads/readme.txt", Content: "uploads go here"},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/file",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	fs := builder.sinks.filesystem
+	if err := fs.WriteFile("uploads/shell.php", "<?php ?>"); err != nil {
+		t.Fatalf("Expected write within quota, got %v", err)
+	}
+	if err := fs.WriteFile("uploads/second.php", "<?php ?>"); err == nil {
+		t.Error("Expected file quota to be enforced")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_fs", nil))
+	var usage map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &usage)
+	if usage["files"] != float64(7) || usage["max_files"] != float64(7) {
+		t.Errorf("Unexpected usage %v", usage)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_fs/reset", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from reset, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if fs.Exists("uploads/shell.php") || !fs.Exists("uploads/readme.txt") {
+		t.Error("Expected reset to remove uploads and keep configured files")
+	}
+}
+
+// TestBuilder_FilesystemResetInterval tests the periodic reset
+func TestBuilder_FilesystemResetInterval(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:       "fs-test",
+			Port:       8080,
+			Filesystem: &config.FilesystemConfig{ResetInterval: "50ms"},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/file",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	builder.sinks.filesystem.WriteFile("dropped.txt", "x")
+	time.Sleep(200 * time.Millisecond)
+	if builder.sinks.filesystem.Exists("dropped.txt") {
+		t.Error("Expected periodic reset to remove the dropped file")
+	}
+}
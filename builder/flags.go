@@ -0,0 +1,146 @@
+package builder
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// generateFlags sets the value of each configured flag. Flags without a value
+// are generated: from app.seed, so every instance of a seeded lab has the same
+// ones, or else from crypto/rand.
+func (b *Builder) generateFlags() error {
+	flags := make(map[string]string, len(b.config.Flags))
+	var env []string
+	for _, flag := range b.config.Flags {
+		value := flag.Value
+		if value == "" {
+			var source io.Reader = rand.Reader
+			if b.config.App.Seed != 0 {
+				source = newRandom(deriveSeed(b.config.App.Seed, "flag:"+flag.Name))
+			}
+			buf := make([]byte, 16)
+			if _, err := io.ReadFull(source, buf); err != nil {
+				return fmt.Errorf("failed to generate flag %s: %w", flag.Name, err)
+			}
+
+			format := flag.Format
+			if format == "" {
+				format = config.DefaultFlagFormat
+			}
+			value = fmt.Sprintf(format, hex.EncodeToString(buf))
+		}
+
+		flags[flag.Name] = value
+		if flag.Env != "" {
+			env = append(env, flag.Env+"="+value)
+		}
+	}
+
+	b.flags, b.flagEnv = flags, env
+	return nil
+}
+
+// resolveFlags replaces ${flag:NAME} references in s with the flags' values
+func (b *Builder) resolveFlags(s string) string {
+	return config.ResolveFlags(s, b.flags)
+}
+
+// resolveTableFlags returns the table with flag references in its rows
+// resolved. The rows are copied, so the config is left untouched.
+func (b *Builder) resolveTableFlags(table config.TableConfig) config.TableConfig {
+	if len(b.flags) == 0 {
+		return table
+	}
+	rows := make([][]interface{}, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i] = make([]interface{}, len(row))
+		for j, value := range row {
+			if s, ok := value.(string); ok {
+				value = b.resolveFlags(s)
+			}
+			rows[i][j] = value
+		}
+	}
+	table.Rows = rows
+	return table
+}
+
+// resolveEndpointFlags resolves flag references in the endpoint's headers and
+// response template. Requests are never searched for references, so sending
+// ${flag:NAME} as input doesn't give the flag away.
+func (b *Builder) resolveEndpointFlags(endpoint config.EndpointConfig) config.EndpointConfig {
+	if len(b.flags) == 0 {
+		return endpoint
+	}
+	if len(endpoint.Headers) > 0 {
+		headers := make(map[string]string, len(endpoint.Headers))
+		for name, value := range endpoint.Headers {
+			headers[name] = b.resolveFlags(value)
+		}
+		endpoint.Headers = headers
+	}
+	endpoint.ResponseTemplate = b.resolveFlags(endpoint.ResponseTemplate)
+	return endpoint
+}
+
+// flagReveal is a flag an endpoint hands out once exploited
+type flagReveal struct {
+	name   string
+	value  string
+	reveal *config.FlagRevealConfig
+}
+
+// flagReveals returns the flags the endpoint reveals
+func (b *Builder) flagReveals(endpoint config.EndpointConfig) []flagReveal {
+	var reveals []flagReveal
+	for _, flag := range b.config.Flags {
+		if flag.Reveal != nil && flag.Reveal.Targets(endpoint) {
+			reveals = append(reveals, flagReveal{name: flag.Name, value: b.flags[flag.Name], reveal: flag.Reveal})
+		}
+	}
+	return reveals
+}
+
+// revealFlags sets the header of each flag whose endpoint the results show was
+// exploited: they contain its proof, or one of them reports exploitable: true.
+// Only the modules' data and errors are searched, as for chain proofs.
+func revealFlags(w http.ResponseWriter, r *http.Request, reveals []flagReveal, results []server.ModuleResult) {
+	if len(reveals) == 0 || len(results) == 0 {
+		return
+	}
+	var output strings.Builder
+	for _, result := range results {
+		fmt.Fprintf(&output, "%v\n%s\n", result.Data, result.Error)
+	}
+
+	for _, flag := range reveals {
+		var exploited bool
+		if flag.reveal.Proof != "" {
+			exploited = strings.Contains(output.String(), flag.reveal.Proof)
+		} else {
+			exploited = reportsExploitable(results)
+		}
+		if exploited {
+			w.Header().Set(flag.reveal.HeaderName(), flag.value)
+			log.Printf("Flag '%s' revealed to %s", flag.name, chainClient(r))
+		}
+	}
+}
+
+// reportsExploitable reports whether a module result has exploitable: true in its data
+func reportsExploitable(results []server.ModuleResult) bool {
+	for _, result := range results {
+		if exploitableData(result) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// TestGenerateFlags tests fixed, generated and seeded flags
+func TestGenerateFlags(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{Seed: 42},
+		Flags: []config.FlagConfig{
+			{Name: "fixed", Value: "FLAG{fixed}", Env: "FIXED_FLAG"},
+			{Name: "generated", Format: "CTF{%s}"},
+			{Name: "other"},
+		},
+	}
+
+	b := New(cfg, "")
+	if err := b.generateFlags(); err != nil {
+		t.Fatalf("Failed to generate flags: %v", err)
+	}
+	if b.flags["fixed"] != "FLAG{fixed}" {
+		t.Errorf("Expected the fixed value, got %q", b.flags["fixed"])
+	}
+	if !regexp.MustCompile(`^CTF\{[0-9a-f]{32}\}$`).MatchString(b.flags["generated"]) {
+		t.Errorf("Expected a generated flag in the format, got %q", b.flags["generated"])
+	}
+	if !strings.HasPrefix(b.flags["other"], "FLAG{") || b.flags["other"] == b.flags["generated"] {
+		t.Errorf("Expected each flag to get its own value, got %q", b.flags["other"])
+	}
+	if len(b.flagEnv) != 1 || b.flagEnv[0] != "FIXED_FLAG=FLAG{fixed}" {
+		t.Errorf("Expected the env flag, got %v", b.flagEnv)
+	}
+
+	// The same seed gives the same flags; without one every instance differs
+	again := New(cfg, "")
+	again.generateFlags()
+	if again.flags["generated"] != b.flags["generated"] {
+		t.Error("Expected seeded flags to be reproducible")
+	}
+	cfg.App.Seed = 0
+	first, second := New(cfg, ""), New(cfg, "")
+	first.generateFlags()
+	second.generateFlags()
+	if first.flags["generated"] == second.flags["generated"] {
+		t.Error("Expected unseeded flags to differ between instances")
+	}
+}
+
+// TestResolveFlags tests references are resolved in table rows and endpoint headers
+func TestResolveFlags(t *testing.T) {
+	cfg := &config.Config{
+		Data: &config.DataConfig{Tables: map[string]config.TableConfig{
+			"secrets": {Columns: []string{"id", "value"}, Rows: [][]interface{}{{1, "${flag:db}"}}},
+		}},
+		Flags: []config.FlagConfig{{Name: "db", Value: "FLAG{db}"}},
+	}
+	b := New(cfg, "")
+	b.generateFlags()
+
+	tables, err := b.generateTables()
+	if err != nil {
+		t.Fatalf("Failed to generate tables: %v", err)
+	}
+	if row := tables["secrets"].Rows[0]; row[0] != 1 || row[1] != "FLAG{db}" {
+		t.Errorf("Expected the flag in the row, got %v", row)
+	}
+	if cfg.Data.Tables["secrets"].Rows[0][1] != "${flag:db}" {
+		t.Error("Expected the config's rows to be left alone")
+	}
+
+	endpoint := b.resolveEndpointFlags(config.EndpointConfig{
+		Headers:          map[string]string{"X-Debug": "token=${flag:db}", "X-Other": "${flag:unknown}"},
+		ResponseTemplate: "{{.Input}} ${flag:db}",
+	})
+	if endpoint.Headers["X-Debug"] != "token=FLAG{db}" || endpoint.Headers["X-Other"] != "${flag:unknown}" {
+		t.Errorf("Unexpected headers %v", endpoint.Headers)
+	}
+	if endpoint.ResponseTemplate != "{{.Input}} FLAG{db}" {
+		t.Errorf("Unexpected template %q", endpoint.ResponseTemplate)
+	}
+}
+
+// TestRevealFlags tests a flag is only sent once the results show an exploit
+func TestRevealFlags(t *testing.T) {
+	exploitable := []flagReveal{{name: "nosql", value: "FLAG{nosql}", reveal: &config.FlagRevealConfig{}}}
+	proof := []flagReveal{{name: "sqli", value: "FLAG{sqli}", reveal: &config.FlagRevealConfig{Proof: "admin_hash", Header: "X-Loot"}}}
+
+	tests := []struct {
+		reveals  []flagReveal
+		data     interface{}
+		header   string
+		expected string
+	}{
+		{exploitable, map[string]interface{}{"exploitable": true}, "X-Flag", "FLAG{nosql}"},
+		{exploitable, struct {
+			Exploitable bool `json:"exploitable"`
+		}{false}, "X-Flag", ""},
+		{exploitable, "<b>reflected</b>", "X-Flag", ""},
+		{proof, []map[string]interface{}{{"user": "admin", "password": "admin_hash"}}, "X-Loot", "FLAG{sqli}"},
+		{proof, []map[string]interface{}{{"user": "guest"}}, "X-Loot", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		revealFlags(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.reveals, []server.ModuleResult{{Data: tt.data, Input: "admin_hash"}})
+		if got := rec.Header().Get(tt.header); got != tt.expected {
+			t.Errorf("%v: expected %s %q, got %q", tt.data, tt.header, tt.expected, got)
+		}
+	}
+}
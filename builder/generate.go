@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/faker"
+)
+
+// defaultGenerateSeed is used when a table's generate section has no seed, so
+// labs get the same rows on every start
+const defaultGenerateSeed = 1
+
+// seedTables returns the tables to seed, generating their rows on first use
+func (b *Builder) seedTables() (map[string]config.TableConfig, error) {
+	if b.tables == nil {
+		tables, err := b.generateTables()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate data: %w", err)
+		}
+		b.tables = tables
+	}
+	return b.tables, nil
+}
+
+// generateTables returns the configured tables, with flag references resolved
+// and generated rows appended after the listed ones. Columns without a generator are NULL, except an "id"
+// column, which continues numbering from the listed rows.
+func (b *Builder) generateTables() (map[string]config.TableConfig, error) {
+	if b.config.Data == nil {
+		return nil, nil
+	}
+
+	tables := make(map[string]config.TableConfig, len(b.config.Data.Tables))
+	for name, table := range b.config.Data.Tables {
+		table = b.resolveTableFlags(table)
+		gen := table.Generate
+		if gen == nil || gen.Rows <= 0 {
+			tables[name] = table
+			continue
+		}
+
+		generators := make([]faker.Generator, len(table.Columns))
+		for i, column := range table.Columns {
+			spec, ok := gen.Columns[column]
+			if !ok && column == "id" {
+				spec = "sequence"
+			}
+			if spec == "" {
+				continue
+			}
+			generator, err := faker.Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("table %s column %s: %w", name, column, err)
+			}
+			generators[i] = generator
+		}
+
+		seed := gen.Seed
+		if seed == 0 && b.config.App.Seed != 0 {
+			seed = deriveSeed(b.config.App.Seed, name)
+		}
+		if seed == 0 {
+			seed = defaultGenerateSeed
+		}
+
+		rows := make([][]interface{}, 0, len(table.Rows)+gen.Rows)
+		rows = append(rows, table.Rows...)
+		rows = append(rows, faker.New(seed).Rows(gen.Rows, len(table.Rows)+1, generators)...)
+		table.Rows = rows
+		tables[name] = table
+
+		log.Printf("Generated %d rows for table '%s'", gen.Rows, name)
+	}
+
+	return tables, nil
+}
@@ -0,0 +1,58 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_GenerateTables tests generated rows follow the listed ones
+func TestBuilder_GenerateTables(t *testing.T) {
+	cfg := &config.Config{
+		Data: &config.DataConfig{
+			Tables: map[string]config.TableConfig{
+				"users": {
+					Columns: []string{"id", "email", "ssn", "notes"},
+					Rows: [][]interface{}{
+						{1, "admin@corp.local", "000-00-0000", "flag{admin}"},
+					},
+					Generate: &config.GenerateConfig{
+						Rows:    100,
+						Columns: map[string]string{"email": "faker.email", "ssn": "faker.ssn"},
+					},
+				},
+				"products": {
+					Columns: []string{"id", "name"},
+					Rows:    [][]interface{}{{1, "widget"}},
+				},
+			},
+		},
+	}
+
+	tables, err := New(cfg, "").generateTables()
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	users := tables["users"].Rows
+	if len(users) != 101 || users[0][3] != "flag{admin}" {
+		t.Fatalf("Expected listed row then 100 generated rows, got %d", len(users))
+	}
+	if users[1][0] != 2 || users[100][0] != 101 {
+		t.Errorf("Expected ids to continue from the listed rows, got %v and %v", users[1][0], users[100][0])
+	}
+	if users[1][1] == nil || users[1][2] == nil || users[1][3] != nil {
+		t.Errorf("Expected generated email and ssn with NULL notes, got %v", users[1])
+	}
+	if len(tables["products"].Rows) != 1 {
+		t.Error("Expected tables without generate to be unchanged")
+	}
+	if len(cfg.Data.Tables["users"].Rows) != 1 {
+		t.Error("Expected the config's rows to be left alone")
+	}
+
+	again, _ := New(cfg, "").generateTables()
+	if again["users"].Rows[50][1] != users[50][1] {
+		t.Error("Expected the default seed to give the same rows")
+	}
+}
@@ -0,0 +1,111 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// grpcHandler serves a grpc endpoint: it decodes the request message for the
+// grpc_field placement, then sends what next answers in JSON as the result
+// field of the method's response message, or a gRPC status for an error. A
+// response template's body is sent as the result as written.
+func grpcHandler(endpoint config.EndpointConfig, next http.HandlerFunc) http.HandlerFunc {
+	respBuilder := server.NewResponseBuilder()
+	var fields []config.GRPCField
+	if endpoint.GRPC != nil {
+		fields = endpoint.GRPC.Fields
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// gRPC answers other content types with HTTP errors, not statuses
+		if !server.IsGRPC(r) {
+			http.Error(w, "content type must be application/grpc", http.StatusUnsupportedMediaType)
+			return
+		}
+		message, err := server.ReadGRPC(r, fields)
+		if err != nil {
+			var grpcErr *server.GRPCError
+			errors.As(err, &grpcErr)
+			respBuilder.SendGRPCStatus(w, grpcErr.Code, grpcErr.Message)
+			return
+		}
+
+		rec := &responseRecorder{header: w.Header()}
+		next(rec, server.WithGRPCMessage(r, message))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		var data interface{}
+		decoder := json.NewDecoder(bytes.NewReader(rec.body.Bytes()))
+		decoder.UseNumber()
+		if endpoint.ResponseTemplate != "" || decoder.Decode(&data) != nil {
+			if rec.status >= 400 {
+				respBuilder.SendGRPCStatus(w, server.GRPCCode(rec.status), rec.body.String())
+				return
+			}
+			respBuilder.SendGRPC(w, rec.body.String())
+			return
+		}
+
+		fields, _ := data.(map[string]interface{})
+		if rec.status >= 400 {
+			message, ok := fields["error"].(string)
+			if !ok {
+				message = http.StatusText(rec.status)
+			}
+			respBuilder.SendGRPCStatus(w, server.GRPCCode(rec.status), message)
+			return
+		}
+		if wrapped, ok := fields["data"]; ok && len(fields) == 1 {
+			data = wrapped // The response wrapper's {"data": ...}
+		}
+
+		var result bytes.Buffer
+		encoder := json.NewEncoder(&result)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(data)
+		respBuilder.SendGRPC(w, string(bytes.TrimSuffix(result.Bytes(), []byte("\n"))))
+	}
+}
+
+// newGRPCRouter creates the router of the grpc endpoints, when app.grpc is set.
+// Calls to methods it doesn't serve are answered UNIMPLEMENTED, as a gRPC
+// server would.
+func (b *Builder) newGRPCRouter(router *server.Router) *server.Router {
+	if b.config.App.GRPC == nil {
+		return nil
+	}
+	grpc := router.Sibling()
+	if settings := b.config.App.HTTP; settings != nil && settings.Protected() {
+		grpc.LimitBodies(settings.MaxBodySize)
+	}
+	respBuilder := server.NewResponseBuilder()
+	grpc.HandleUnmatched(func(w http.ResponseWriter, r *http.Request, status int) {
+		respBuilder.SendGRPCStatus(w, server.GRPCUnimplemented, "unknown method "+r.URL.Path)
+	})
+	return grpc
+}
+
+// serveGRPC swaps the grpc endpoints' router into srv, with the reflection
+// service unless it is switched off, when app.grpc is set
+func (b *Builder) serveGRPC(srv *server.Server) {
+	grpc := b.config.App.GRPC
+	if grpc == nil || b.grpc == nil {
+		return
+	}
+	host := b.config.App.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	var reflection http.Handler
+	if grpc.ReflectionEnabled() {
+		reflection = server.NewGRPCReflection(b.config.Endpoints)
+	}
+	srv.ServeGRPC(grpc.Address(host), b.grpc, reflection)
+}
@@ -0,0 +1,84 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// grpcCall frames a request message holding value in string field 1
+func grpcCall(path, value string) *http.Request {
+	message := append([]byte{1<<3 | 2}, binary.AppendUvarint(nil, uint64(len(value)))...)
+	message = append(message, value...)
+	body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message)))
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(append(body, message...)))
+	req.Header.Set("Content-Type", "application/grpc")
+	return req
+}
+
+// TestGRPCEndpoint tests a grpc endpoint runs its modules on message fields,
+// answers their JSON as the result field of its response, and errors as statuses
+func TestGRPCEndpoint(t *testing.T) {
+	b := New(&config.Config{App: config.AppConfig{Name: "grpc", GRPC: &config.GRPCConfig{Port: 50051}}}, "")
+	router := b.newGRPCRouter(endpointTypesRouter(t, nil))
+	for _, endpoint := range []config.EndpointConfig{
+		{
+			Path:   "/ops.Sessions/Restore",
+			Method: "POST",
+			Type:   "grpc",
+			GRPC:   &config.GRPCMethodConfig{Fields: []config.GRPCField{{Name: "state", Type: "bytes"}}},
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "insecure_deserialization", Placement: "grpc_field", Param: "state", Config: map[string]interface{}{"format": "php"}},
+			},
+		},
+		{Path: "/ops.Sessions/Down", Method: "POST", Type: "grpc", ErrorRate: 1},
+	} {
+		if err := b.registerEndpoint(router, endpoint); err != nil {
+			t.Fatalf("Failed to register %s: %v", endpoint.Path, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		status   string
+		expected string
+	}{
+		{"call", grpcCall("/ops.Sessions/Restore", `O:4:"User":1:{s:5:"admin";b:1;}`), "0", `"class_name":"User"`},
+		{"error", grpcCall("/ops.Sessions/Down", ""), "14", ""},
+		{"unknown method", grpcCall("/ops.Sessions/Export", ""), "12", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, tt.req)
+		res := rec.Result()
+		body, _ := io.ReadAll(res.Body)
+		if status := res.Trailer.Get("Grpc-Status"); status != tt.status {
+			t.Errorf("%s: expected status %s, got %s (%s)", tt.name, tt.status, status, res.Trailer.Get("Grpc-Message"))
+		}
+		if tt.expected == "" {
+			if len(body) != 0 {
+				t.Errorf("%s: expected no message, got %q", tt.name, body)
+			}
+			continue
+		}
+		// The frame's 5 byte prefix, then field 1's tag and length
+		if len(body) < 7 || body[5] != 1<<3|2 || !strings.Contains(string(body), tt.expected) {
+			t.Errorf("%s: expected a result containing %s, got %q", tt.name, tt.expected, body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ops.Sessions/Restore", strings.NewReader(`{"state":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a request that isn't gRPC, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package builder
+
+import (
+	"net/http"
+	"strings"
+)
+
+// applyHeaders sets an endpoint's static response headers. Each line of a value
+// is sent as its own header, so several cookies can be set at once.
+func applyHeaders(w http.ResponseWriter, headers map[string]string) {
+	setHeaders(w.Header(), headers)
+}
+
+// setHeaders sets static headers on a header map, as applyHeaders does
+func setHeaders(h http.Header, headers map[string]string) {
+	for name, value := range headers {
+		h.Del(name)
+		for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+			h.Add(name, line)
+		}
+	}
+}
+
+// successStatus returns the status an endpoint sends in place of 200 OK
+func successStatus(status int) int {
+	if status == 0 {
+		return http.StatusOK
+	}
+	return status
+}
@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestBuilder_Headers tests static headers and the endpoint status are sent, without hiding module errors
+func TestBuilder_Headers(t *testing.T) {
+	cfg := reloadConfig("/file", "notes")
+	cfg.Endpoints[0].Status = http.StatusAccepted
+	cfg.Endpoints[0].Headers = map[string]string{
+		"Server":     "Apache/2.4.29 (Ubuntu)",
+		"Set-Cookie": "PHPSESSID=abc123; Path=/\nremember_me=1; Path=/\n",
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Server"); got != "Apache/2.4.29 (Ubuntu)" {
+		t.Errorf("Expected the Server banner, got '%s'", got)
+	}
+	if cookies := rec.Header().Values("Set-Cookie"); len(cookies) != 2 || cookies[1] != "remember_me=1; Path=/" {
+		t.Errorf("Expected one Set-Cookie header per line, got %q", cookies)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=missing.txt", nil))
+	if rec.Code == http.StatusAccepted || rec.Code < 400 {
+		t.Errorf("Expected the module's error status, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Server"); got == "" {
+		t.Error("Expected headers on error responses too")
+	}
+}
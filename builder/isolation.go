@@ -0,0 +1,228 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// sinkCopyKey is the context key of the request's copy of the sinks
+type sinkCopyKey struct{}
+
+// sinkCopy is one client's copy of the mutable sinks
+type sinkCopy struct {
+	sqlite     *sinks.SQLite
+	filesystem *sinks.Filesystem
+	lastUsed   time.Time
+}
+
+// sinkCopies hands each client its own copy of the SQLite database and
+// filesystem, made from their seeded state, so one client's destructive
+// payloads don't spoil the lab for the others
+type sinkCopies struct {
+	seed       *sinks.SQLite     // The database as seeded, which copies are made from
+	filesystem *sinks.Filesystem // Forked from its checkpoint
+	limit      int
+	idle       time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	copies map[string]*sinkCopy
+}
+
+// createSinkCopies takes the seeded state that clients' copies are made from,
+// when app.isolation is set. It runs after the filesystem is checkpointed.
+func (b *Builder) createSinkCopies() error {
+	isolation := b.config.App.Isolation
+	if isolation == nil {
+		return nil
+	}
+
+	c := &sinkCopies{
+		filesystem: b.sinks.filesystem,
+		limit:      isolation.CopyLimit(),
+		idle:       isolation.IdleTimeout(),
+		seed:       b.sinks.seed,
+		now:        time.Now,
+		copies:     make(map[string]*sinkCopy),
+	}
+	b.sinks.copies = c
+	log.Printf("Each %s gets its own copy of the database and filesystem", isolation.By)
+	return nil
+}
+
+// get returns the client's copy, making it on first use
+func (c *sinkCopies) get(client string) (*sinkCopy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.idle > 0 {
+		for key, own := range c.copies {
+			if now.Sub(own.lastUsed) > c.idle {
+				c.drop(key)
+			}
+		}
+	}
+	if own, ok := c.copies[client]; ok {
+		own.lastUsed = now
+		return own, nil
+	}
+
+	if c.limit > 0 && len(c.copies) >= c.limit {
+		oldest := ""
+		for key, own := range c.copies {
+			if oldest == "" || own.lastUsed.Before(c.copies[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		c.drop(oldest)
+	}
+
+	own := &sinkCopy{lastUsed: now}
+	if c.seed != nil {
+		db, err := c.seed.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy the database: %w", err)
+		}
+		own.sqlite = db
+	}
+	if c.filesystem != nil {
+		fs, err := c.filesystem.Fork()
+		if err != nil {
+			if own.sqlite != nil {
+				own.sqlite.Close()
+			}
+			return nil, fmt.Errorf("failed to copy the filesystem: %w", err)
+		}
+		own.filesystem = fs
+	}
+	c.copies[client] = own
+	return own, nil
+}
+
+// drop closes and forgets a client's copy; the caller holds c.mu
+func (c *sinkCopies) drop(client string) {
+	own := c.copies[client]
+	delete(c.copies, client)
+	if own.sqlite != nil {
+		own.sqlite.Close()
+	}
+	if own.filesystem != nil {
+		own.filesystem.Close()
+	}
+}
+
+// count returns the number of copies held
+func (c *sinkCopies) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.copies)
+}
+
+// resetFilesystems restores every copy's files to the seeded ones
+func (c *sinkCopies) resetFilesystems() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, own := range c.copies {
+		if own.filesystem != nil {
+			if err := own.filesystem.Reset(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reset drops every copy, so clients get fresh ones on their next request
+func (c *sinkCopies) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for client := range c.copies {
+		c.drop(client)
+	}
+}
+
+// Close closes every copy
+func (c *sinkCopies) Close() error {
+	c.reset()
+	return nil
+}
+
+// client returns who the request's copy belongs to: the logged-in user, or
+// the session. It's empty for clients isolation can't tell apart.
+func (b *Builder) client(r *http.Request) string {
+	isolation := b.config.App.Isolation
+	switch {
+	case isolation == nil:
+		return ""
+	case isolation.By == "user" && b.auth != nil:
+		if username, err := b.auth.authenticate(r); err == nil {
+			return "user:" + username
+		}
+	case isolation.By == "session":
+		if session := requestSession(r); session != nil && session.ID() != "" {
+			return "session:" + session.ID()
+		}
+		if b.sessions != nil {
+			if session := b.sessions.Lookup(r); session != nil {
+				return "session:" + session.ID()
+			}
+		}
+	}
+	return ""
+}
+
+// sinkCopyFor returns the client's copy of the sinks, or nil if the request
+// uses the original ones
+func (b *Builder) sinkCopyFor(r *http.Request) (*sinkCopy, error) {
+	if b.sinks.copies == nil {
+		return nil, nil
+	}
+	client := b.client(r)
+	if client == "" {
+		return nil, nil
+	}
+	return b.sinks.copies.get(client)
+}
+
+// withSinkCopy finds the client's copy once for all the request's vulnerabilities
+func (b *Builder) withSinkCopy(r *http.Request) (*http.Request, error) {
+	own, err := b.sinkCopyFor(r)
+	if err != nil || own == nil {
+		return r, err
+	}
+	return r.WithContext(context.WithValue(r.Context(), sinkCopyKey{}, own)), nil
+}
+
+// useSinkCopy points modules at the request's copy of the sinks
+func (b *Builder) useSinkCopy(ctx *modules.SinkContext, r *http.Request) {
+	own, _ := r.Context().Value(sinkCopyKey{}).(*sinkCopy)
+	if own == nil {
+		return
+	}
+	if own.sqlite != nil {
+		ctx.SQLite = &sqliteSinkAdapter{own.sqlite, &b.sinks.stats}
+	}
+	if own.filesystem != nil {
+		ctx.Filesystem = &filesystemSinkAdapter{sink: own.filesystem, stats: &b.sinks.stats}
+	}
+}
+
+// filesystemFor returns the filesystem the request sees: the client's copy, or the original
+func (b *Builder) filesystemFor(r *http.Request) (*sinks.Filesystem, error) {
+	own, err := b.sinkCopyFor(r)
+	if err != nil {
+		return nil, err
+	}
+	if own != nil && own.filesystem != nil {
+		return own.filesystem, nil
+	}
+	return b.sinks.filesystem, nil
+}
@@ -0,0 +1,109 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// TestSinkCopies tests clients get their own filesystem, and copies are
+// dropped when idle or to make room
+func TestSinkCopies(t *testing.T) {
+	fs, err := sinks.NewFilesystemWithPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create filesystem: %v", err)
+	}
+	if err := fs.WriteFile("notes.txt", "seeded"); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+
+	now := time.Now()
+	c := &sinkCopies{filesystem: fs, limit: 2, idle: time.Hour, now: func() time.Time { return now }, copies: make(map[string]*sinkCopy)}
+	defer c.Close()
+
+	alice, err := c.get("alice")
+	if err != nil {
+		t.Fatalf("Failed to copy: %v", err)
+	}
+	if err := alice.filesystem.WriteFile("notes.txt", "defaced"); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if again, _ := c.get("alice"); again != alice {
+		t.Error("Expected the same copy for the same client")
+	}
+	bob, _ := c.get("bob")
+	for name, f := range map[string]*sinks.Filesystem{"original": fs, "bob": bob.filesystem} {
+		if content, _ := f.Read("notes.txt"); content != "seeded" {
+			t.Errorf("Expected %s's file untouched, got %q", name, content)
+		}
+	}
+
+	if err := c.resetFilesystems(); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if content, _ := alice.filesystem.Read("notes.txt"); content != "seeded" {
+		t.Errorf("Expected alice's copy reset, got %q", content)
+	}
+
+	now = now.Add(time.Minute)
+	c.get("bob")
+	c.get("carol")
+	if _, err := os.Stat(alice.filesystem.BasePath()); !os.IsNotExist(err) || c.count() != 2 {
+		t.Errorf("Expected the least recently used copy removed, %d left", c.count())
+	}
+
+	now = now.Add(2 * time.Hour)
+	c.get("dave")
+	if c.count() != 1 {
+		t.Errorf("Expected idle copies dropped, %d left", c.count())
+	}
+}
+
+// TestIsolationBySession tests each session sees its own filesystem, and
+// clients without one the original
+func TestIsolationBySession(t *testing.T) {
+	fs, err := sinks.NewFilesystemWithPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create filesystem: %v", err)
+	}
+	b := New(&config.Config{App: config.AppConfig{
+		Name:      "lab",
+		Sessions:  &config.SessionsConfig{Cookie: "sid"},
+		Isolation: &config.IsolationConfig{By: "session"},
+	}}, "")
+	b.sinks.filesystem = fs
+	if err := b.createSinkCopies(); err != nil {
+		t.Fatalf("Failed to set up isolation: %v", err)
+	}
+	defer b.sinks.copies.Close()
+
+	withSession := func() *http.Request {
+		rec := httptest.NewRecorder()
+		if _, err := b.sessions.Start(rec, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(rec.Result().Cookies()[0])
+		return req
+	}
+	first, second := withSession(), withSession()
+
+	own, _ := b.filesystemFor(first)
+	if again, _ := b.filesystemFor(first); own == fs || again != own {
+		t.Fatal("Expected the session's own filesystem")
+	}
+	if other, _ := b.filesystemFor(second); other == own || other == fs {
+		t.Error("Expected another session to get another filesystem")
+	}
+	if anonymous, _ := b.filesystemFor(httptest.NewRequest(http.MethodGet, "/", nil)); anonymous != fs {
+		t.Error("Expected a client without a session to use the original filesystem")
+	}
+}
@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// runningModule is a module started for the config's vulnerabilities
+type runningModule struct {
+	name   string
+	module modules.Module
+	cancel context.CancelFunc // Cancels the context Init was given
+}
+
+// usedModules returns the modules the config's vulnerabilities use, in order of first use
+func usedModules(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, endpoint := range cfg.Endpoints {
+		for _, vuln := range endpoint.Vulnerabilities {
+			if !seen[vuln.Type] {
+				seen[vuln.Type] = true
+				names = append(names, vuln.Type)
+			}
+		}
+	}
+	return names
+}
+
+// startModules calls Init on the modules the config uses that aren't running
+// yet, so they can set up their background state before the first request
+func (b *Builder) startModules() error {
+	running := make(map[string]bool, len(b.running))
+	for _, r := range b.running {
+		running[r.name] = true
+	}
+
+	for _, name := range usedModules(b.config) {
+		if running[name] {
+			continue
+		}
+		module, err := modules.Get(name)
+		if err != nil {
+			continue // Validation reports unknown modules
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := modules.Init(ctx, module); err != nil {
+			cancel()
+			return fmt.Errorf("failed to start module %s: %w", name, err)
+		}
+		b.running = append(b.running, runningModule{name: name, module: module, cancel: cancel})
+	}
+	return nil
+}
+
+// stopModules calls Shutdown on the running modules that keep returns false
+// for, newest first, and cancels their Init contexts
+func (b *Builder) stopModules(keep func(name string) bool) error {
+	var errs []string
+	var kept []runningModule
+	for i := len(b.running) - 1; i >= 0; i-- {
+		r := b.running[i]
+		if keep != nil && keep(r.name) {
+			kept = append([]runningModule{r}, kept...)
+			continue
+		}
+		if err := modules.Shutdown(r.module); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.name, err))
+		}
+		r.cancel()
+	}
+	b.running = kept
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors shutting down modules: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// takeModules hands prev's running modules over to b on a reload that keeps
+// the sinks: modules b uses newly are started, and those it no longer uses
+// shut down. If a module fails to start, prev keeps its modules.
+func (b *Builder) takeModules(prev *Builder) error {
+	b.running = prev.running
+	if err := b.startModules(); err != nil {
+		b.running = b.running[len(prev.running):]
+		b.stopModules(nil)
+		return err
+	}
+	prev.running = nil
+
+	used := make(map[string]bool)
+	for _, name := range usedModules(b.config) {
+		used[name] = true
+	}
+	return b.stopModules(func(name string) bool { return used[name] })
+}
@@ -0,0 +1,91 @@
+package builder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// lifecycleProbe records the lifecycle hooks the builder calls
+type lifecycleProbe struct {
+	inits, shutdowns int
+	ctx              context.Context
+}
+
+var probe = &lifecycleProbe{}
+
+func (p *lifecycleProbe) Info() modules.ModuleInfo {
+	return modules.ModuleInfo{Name: "test_lifecycle_probe", SupportedPlacements: []string{"query_param"}}
+}
+
+func (p *lifecycleProbe) Handle(ctx *modules.HandlerContext) (*modules.Result, error) {
+	return modules.NewResult("ok"), nil
+}
+
+func (p *lifecycleProbe) Init(ctx context.Context) error {
+	p.inits++
+	p.ctx = ctx
+	return nil
+}
+
+func (p *lifecycleProbe) Shutdown() error {
+	p.shutdowns++
+	return nil
+}
+
+func init() {
+	modules.Register(probe)
+}
+
+// TestModuleLifecycle tests modules are started once, kept across reloads
+// that still use them, and shut down when dropped or the lab closes
+func TestModuleLifecycle(t *testing.T) {
+	*probe = lifecycleProbe{}
+	withProbe := func(content string) *config.Config {
+		cfg := reloadConfig("/file", content)
+		cfg.Endpoints[0].Vulnerabilities = append(cfg.Endpoints[0].Vulnerabilities,
+			config.VulnerabilityConfig{Type: "test_lifecycle_probe", Placement: "query_param", Param: "q"})
+		return cfg
+	}
+
+	builder := New(withProbe("v1"), "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer func() { builder.Close() }()
+	if probe.inits != 1 || probe.shutdowns != 0 {
+		t.Fatalf("Expected one Init, got %+v", probe)
+	}
+
+	// Reloads keeping the sinks keep the module running
+	if builder, _, err = builder.Reload(srv, withProbe("v1")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if probe.inits != 1 || probe.shutdowns != 0 {
+		t.Errorf("Expected the module to keep running, got %+v", probe)
+	}
+
+	// Dropping its last vulnerability shuts it down
+	ctx := probe.ctx
+	if builder, _, err = builder.Reload(srv, reloadConfig("/file", "v1")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if probe.shutdowns != 1 || ctx.Err() == nil {
+		t.Errorf("Expected Shutdown and a cancelled context, got %+v", probe)
+	}
+
+	// Reloads recreating the sinks restart it, and Close shuts it down
+	if builder, _, err = builder.Reload(srv, withProbe("v2")); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if probe.inits != 2 {
+		t.Errorf("Expected a second Init, got %+v", probe)
+	}
+	builder.Close()
+	if probe.shutdowns != 2 || probe.ctx.Err() == nil {
+		t.Errorf("Expected Close to shut the module down, got %+v", probe)
+	}
+}
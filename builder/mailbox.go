@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// defaultMailboxPath is where captured email is listed
+const defaultMailboxPath = "/mailbox"
+
+// mailboxPath returns the configured inspection path
+func (b *Builder) mailboxPath() string {
+	if mail := b.config.App.Mail; mail != nil && mail.MailboxPath != "" {
+		return strings.TrimRight(mail.MailboxPath, "/")
+	}
+	return defaultMailboxPath
+}
+
+// registerMailboxEndpoints serves the captured messages when the SMTP sink is active:
+//
+//	GET    /mailbox           list messages (?to= filters by recipient)
+//	GET    /mailbox/{id}      a single message, headers and raw source included
+//	DELETE /mailbox           empty the mailbox
+func (b *Builder) registerMailboxEndpoints(router *server.Router) {
+	mailbox := b.sinks.mail
+	if mailbox == nil {
+		return
+	}
+
+	path := b.mailboxPath()
+
+	router.HandleFunc("GET", path, func(w http.ResponseWriter, r *http.Request) {
+		var messages []sinks.Email
+		if to := r.URL.Query().Get("to"); to != "" {
+			messages = mailbox.MessagesFor(to)
+		} else {
+			messages = mailbox.Messages()
+		}
+		if messages == nil {
+			messages = []sinks.Email{}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"count":    len(messages),
+			"messages": messages,
+		})
+	})
+
+	router.HandleFunc("GET", path+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid message id"})
+			return
+		}
+
+		email, ok := mailbox.Message(id)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "message not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, email)
+	})
+
+	router.HandleFunc("DELETE", path, func(w http.ResponseWriter, r *http.Request) {
+		mailbox.Clear()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "cleared"})
+	})
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
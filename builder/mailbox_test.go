@@ -0,0 +1,108 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_Build_WithMailbox tests the SMTP sink and its inspection endpoints
+func TestBuilder_Build_WithMailbox(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "mail-test",
+			Port: 8080,
+			Mail: &config.MailConfig{MailboxPath: "/inbox/"},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/search",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "xss", Placement: "query_param", Param: "q"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	if builder.sinks.mail == nil {
+		t.Fatal("Expected SMTP sink to be initialized for mail config")
+	}
+	if ctx := builder.createSinkContext(); ctx.Mail == nil {
+		t.Fatal("Expected mail sink in module context")
+	}
+
+	builder.sinks.mail.Send("app@example.com", nil, "To: rick@example.com\r\nSubject: one\r\n\r\nbody")
+	builder.sinks.mail.Send("app@example.com", nil, "To: morty@example.com\r\nSubject: two\r\n\r\nbody")
+
+	list := func(target string) map[string]interface{} {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 from %s, got %d", target, rec.Code)
+		}
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return body
+	}
+
+	if count := list("/inbox")["count"]; count != float64(2) {
+		t.Errorf("Expected 2 messages, got %v", count)
+	}
+	if count := list("/inbox?to=morty@example.com")["count"]; count != float64(1) {
+		t.Errorf("Expected 1 message for morty, got %v", count)
+	}
+	if subject := list("/inbox/1")["subject"]; subject != "one" {
+		t.Errorf("Expected message 1 subject 'one', got %v", subject)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/inbox/9", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing message, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/inbox", nil))
+	if count := list("/inbox")["count"]; count != float64(0) {
+		t.Errorf("Expected empty mailbox after DELETE, got %v", count)
+	}
+}
+
+// TestBuilder_Build_WithoutMailbox tests that /mailbox is only served when mail is configured
+func TestBuilder_Build_WithoutMailbox(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{Name: "no-mail", Port: 8080},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/search",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "xss", Placement: "query_param", Param: "q"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/mailbox", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without mail config, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// defaultOOBAPIPath is where OOB tokens and interactions are served
+const defaultOOBAPIPath = "/oob"
+
+// maxOOBResponseSize caps a payload uploaded for a token
+const maxOOBResponseSize = 1 << 20
+
+// oobAPIPath returns the configured API path
+func (b *Builder) oobAPIPath() string {
+	if cfg := b.config.App.OOB; cfg != nil && cfg.APIPath != "" {
+		return strings.TrimRight(cfg.APIPath, "/")
+	}
+	return defaultOOBAPIPath
+}
+
+// registerOOBEndpoints serves the OOB listener's query API when it is active:
+//
+//	POST   /oob/tokens                    new token (?label= to name it)
+//	GET    /oob/tokens                    registered tokens
+//	PUT    /oob/tokens/{token}/response   body served to HTTP callbacks (e.g. an external DTD)
+//	GET    /oob/interactions              recorded callbacks (?token=, ?protocol=http|dns)
+//	DELETE /oob/interactions              clear recorded callbacks
+func (b *Builder) registerOOBEndpoints(router *server.Router) {
+	oob := b.sinks.oob
+	if oob == nil {
+		return
+	}
+
+	path := b.oobAPIPath()
+
+	router.HandleFunc("POST", path+"/tokens", func(w http.ResponseWriter, r *http.Request) {
+		token := oob.NewToken(r.URL.Query().Get("label"))
+
+		data := map[string]interface{}{
+			"token":    token.Token,
+			"label":    token.Label,
+			"url":      oob.URL(token.Token),
+			"dns_name": oob.DNSName(token.Token),
+		}
+		if addr := oob.HTTPAddr(); addr != "" {
+			data["listener_url"] = "http://" + addr + "/" + token.Token + "/"
+		}
+		writeJSON(w, http.StatusCreated, data)
+	})
+
+	router.HandleFunc("GET", path+"/tokens", func(w http.ResponseWriter, r *http.Request) {
+		tokens := oob.Tokens()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"count":  len(tokens),
+			"tokens": tokens,
+		})
+	})
+
+	router.HandleFunc("PUT", path+"/tokens/{token}/response", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxOOBResponseSize))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if err := oob.SetResponse(r.PathValue("token"), r.Header.Get("Content-Type"), string(body)); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "updated"})
+	})
+
+	router.HandleFunc("GET", path+"/interactions", func(w http.ResponseWriter, r *http.Request) {
+		interactions := oob.Interactions(r.URL.Query().Get("token"))
+		if protocol := r.URL.Query().Get("protocol"); protocol != "" {
+			filtered := make([]sinks.OOBInteraction, 0, len(interactions))
+			for _, interaction := range interactions {
+				if strings.EqualFold(interaction.Protocol, protocol) {
+					filtered = append(filtered, interaction)
+				}
+			}
+			interactions = filtered
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"count":        len(interactions),
+			"interactions": interactions,
+		})
+	})
+
+	router.HandleFunc("DELETE", path+"/interactions", func(w http.ResponseWriter, r *http.Request) {
+		oob.Clear()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "cleared"})
+	})
+}
@@ -0,0 +1,87 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_Build_WithOOB tests that blind SSRF callbacks reach the OOB listener
+// and can be queried through its API
+func TestBuilder_Build_WithOOB(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "oob-test",
+			Port: 8080,
+			OOB:  &config.OOBConfig{Domain: "oob.test"},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/webhook",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{
+						Type:      "ssrf",
+						Placement: "query_param",
+						Param:     "url",
+						Config:    map[string]interface{}{"return_body": false},
+					},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	if builder.sinks.oob == nil {
+		t.Fatal("Expected OOB listener to be initialized")
+	}
+	if ctx := builder.createSinkContext(); ctx.OOB == nil {
+		t.Fatal("Expected OOB sink in module context")
+	}
+
+	call := func(method, target string) map[string]interface{} {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return body
+	}
+
+	created := call(http.MethodPost, "/oob/tokens?label=webhook")
+	token, _ := created["token"].(string)
+	callback, _ := created["url"].(string)
+	if token == "" || !strings.HasSuffix(callback, ".oob.test/") {
+		t.Fatalf("Unexpected token response: %v", created)
+	}
+
+	call(http.MethodGet, "/webhook?url="+url.QueryEscape(callback+"ping"))
+
+	interactions := call(http.MethodGet, "/oob/interactions?token="+token)
+	if interactions["count"] != float64(1) {
+		t.Fatalf("Expected 1 interaction, got %v", interactions)
+	}
+	first := interactions["interactions"].([]interface{})[0].(map[string]interface{})
+	if first["path"] != "/ping" || first["label"] != "webhook" {
+		t.Errorf("Unexpected interaction: %v", first)
+	}
+
+	if dns := call(http.MethodGet, "/oob/interactions?protocol=dns"); dns["count"] != float64(0) {
+		t.Errorf("Expected no DNS interactions, got %v", dns["count"])
+	}
+
+	call(http.MethodDelete, "/oob/interactions")
+	if after := call(http.MethodGet, "/oob/interactions"); after["count"] != float64(0) {
+		t.Errorf("Expected no interactions after DELETE, got %v", after["count"])
+	}
+}
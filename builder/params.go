@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// anyPlacements are tried in this order for placement any
+var anyPlacements = []string{"query_param", "path_param", "form_field", "json_field", "graphql_variable", "graphql_query", "soap_param", "grpc_field", "multipart_field", "file", "cookie", "header"}
+
+// placementsFor returns the placements to read a vulnerability's params from
+func placementsFor(vuln config.VulnerabilityConfig) []string {
+	if vuln.Placement != config.AnyPlacement {
+		return []string{vuln.Placement}
+	}
+
+	module, err := modules.Get(vuln.Type)
+	if err != nil {
+		return anyPlacements
+	}
+	supported := module.Info().SupportedPlacements
+	if len(supported) == 0 {
+		return anyPlacements
+	}
+
+	var placements []string
+	for _, placement := range anyPlacements {
+		if slices.Contains(supported, placement) {
+			placements = append(placements, placement)
+		}
+	}
+	return placements
+}
+
+// extractInput reads the first of the vulnerability's params that is present in
+// the request, trying each param in every placement before moving to the next,
+// and returns its value with the placement and param it came from. If none is
+// present, the input is empty and the first param is reported. With placement
+// any, placements that can't be parsed (e.g. a body that isn't JSON) are skipped.
+func extractInput(r *http.Request, extractor *server.Extractor, vuln config.VulnerabilityConfig) (input, placement, param string, err error) {
+	params := vuln.ParamNames()
+	placements := placementsFor(vuln)
+	if len(params) == 1 && len(placements) == 1 {
+		input, err = extractor.Extract(r, placements[0], params[0])
+		return input, placements[0], params[0], err
+	}
+
+	for _, name := range params {
+		for _, where := range placements {
+			value, extractErr := extractor.Extract(r, where, name)
+			if extractErr != nil {
+				if vuln.Placement == config.AnyPlacement {
+					continue
+				}
+				return "", where, name, extractErr
+			}
+			if value != "" {
+				return value, where, name, nil
+			}
+		}
+	}
+
+	return "", placements[0], params[0], nil
+}
+
+// extractMessageInput reads the first of the vulnerability's params that is
+// present in a WebSocket message, like extractInput does for requests
+func extractMessageInput(extractor *server.Extractor, message string, vuln config.VulnerabilityConfig) (input, param string) {
+	params := vuln.ParamNames()
+	for _, name := range params {
+		if value := extractor.ExtractMessage(message, name); value != "" {
+			return value, name
+		}
+	}
+	return "", params[0]
+}
@@ -0,0 +1,133 @@
+package builder
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// TestExtractInput tests the first present param is used and reported with its placement
+func TestExtractInput(t *testing.T) {
+	extractor := server.NewExtractor()
+	jsonRequest := func(target, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+
+	tests := []struct {
+		name          string
+		vuln          config.VulnerabilityConfig
+		r             *http.Request
+		wantInput     string
+		wantPlacement string
+		wantParam     string
+	}{
+		{
+			name:          "single param",
+			vuln:          config.VulnerabilityConfig{Type: "sql_injection", Placement: "query_param", Param: "q"},
+			r:             httptest.NewRequest(http.MethodGet, "/?q=1", nil),
+			wantInput:     "1",
+			wantPlacement: "query_param",
+			wantParam:     "q",
+		},
+		{
+			name:          "second of several params",
+			vuln:          config.VulnerabilityConfig{Type: "sql_injection", Placement: "query_param", Params: []string{"q", "sort", "filter"}},
+			r:             httptest.NewRequest(http.MethodGet, "/?sort=name'", nil),
+			wantInput:     "name'",
+			wantPlacement: "query_param",
+			wantParam:     "sort",
+		},
+		{
+			name:          "no param present",
+			vuln:          config.VulnerabilityConfig{Type: "sql_injection", Placement: "query_param", Params: []string{"q", "sort"}},
+			r:             httptest.NewRequest(http.MethodGet, "/", nil),
+			wantPlacement: "query_param",
+			wantParam:     "q",
+		},
+		{
+			name:          "any placement finds a JSON field",
+			vuln:          config.VulnerabilityConfig{Type: "sql_injection", Placement: "any", Params: []string{"q", "sort"}},
+			r:             jsonRequest("/", `{"sort":"id DESC"}`),
+			wantInput:     "id DESC",
+			wantPlacement: "json_field",
+			wantParam:     "sort",
+		},
+		{
+			name:          "any placement skips a body that isn't JSON",
+			vuln:          config.VulnerabilityConfig{Type: "sql_injection", Placement: "any", Param: "id"},
+			r:             jsonRequest("/", `not json`),
+			wantPlacement: "query_param",
+			wantParam:     "id",
+		},
+		{
+			name:          "any placement finds a multipart field",
+			vuln:          config.VulnerabilityConfig{Type: "path_traversal", Placement: "any", Param: "file"},
+			r:             multipartRequest("file", "../../etc/passwd"),
+			wantInput:     "../../etc/passwd",
+			wantPlacement: "multipart_field",
+			wantParam:     "file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, placement, param, err := extractInput(tt.r, extractor, tt.vuln)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if input != tt.wantInput || placement != tt.wantPlacement || param != tt.wantParam {
+				t.Errorf("Expected %q from %s:%s, got %q from %s:%s", tt.wantInput, tt.wantPlacement, tt.wantParam, input, placement, param)
+			}
+		})
+	}
+}
+
+// multipartRequest builds a multipart form request with one field
+func multipartRequest(name, value string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField(name, value)
+	writer.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+// TestExtractInput_BodyStaysReadable tests the body is restored for modules after trying several fields
+func TestExtractInput_BodyStaysReadable(t *testing.T) {
+	const body = `{"filter":"x"}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	vuln := config.VulnerabilityConfig{Type: "nosql_injection", Placement: "json_field", Params: []string{"q", "filter"}}
+
+	input, _, param, err := extractInput(r, server.NewExtractor(), vuln)
+	if err != nil || input != "x" || param != "filter" {
+		t.Fatalf("Expected x from filter, got %q from %s (%v)", input, param, err)
+	}
+	if rest, _ := io.ReadAll(r.Body); string(rest) != body {
+		t.Errorf("Expected the body to be readable again, got %q", rest)
+	}
+}
+
+// TestPlacementsFor tests placement any is narrowed to the module's supported placements
+func TestPlacementsFor(t *testing.T) {
+	vuln := config.VulnerabilityConfig{Type: "sql_injection", Placement: "any"}
+	want := "query_param path_param form_field json_field graphql_variable graphql_query soap_param grpc_field cookie header"
+	if got := strings.Join(placementsFor(vuln), " "); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	vuln.Type = "no_such_module"
+	if got := placementsFor(vuln); len(got) != len(anyPlacements) {
+		t.Errorf("Expected every placement for an unknown module, got %v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Plan is what building a config creates, as DryRun reports it
+type Plan struct {
+	Listeners   []PlanListener
+	Sinks       []PlanSink
+	Seeds       []PlanSeed
+	Routes      []string // "METHOD path" of the app's listener, in registration order
+	GRPCRoutes  []string // Of the gRPC listener
+	AdminRoutes []string // Of the admin API
+	Persistence string   // The database file a real run uses, which the dry run leaves alone
+}
+
+// PlanListener is an address the lab would listen on
+type PlanListener struct {
+	Name    string // http, https, admin, grpc, oob-http, oob-dns or smtp
+	Address string
+}
+
+// PlanSink is an initialized sink and the vulnerabilities that use it
+type PlanSink struct {
+	Name            string
+	Description     string
+	Vulnerabilities []string // "METHOD path: type"; a sink without any is needed by other config sections
+}
+
+// PlanSeed is data put into a sink before the lab serves
+type PlanSeed struct {
+	Sink  string
+	Name  string // The table, collection or bucket, or empty for the whole sink
+	Count int
+	Unit  string // rows, users, keys, documents, entries, objects, files or directories
+}
+
+// DryRun builds the server as Build does, initializing and seeding the sinks
+// and registering the routes, and returns what was created. Nothing listens,
+// and a persistent database is replaced by an in-memory one so its file is
+// left untouched. Close the builder afterwards.
+func (b *Builder) DryRun() (*Plan, error) {
+	plan := &Plan{}
+	if p := b.persistence(); p != nil {
+		plan.Persistence = p.Path
+		if plan.Persistence == "" {
+			plan.Persistence = defaultDatabasePath
+		}
+	}
+
+	b.InMemory()
+	srv, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Listeners = b.planListeners()
+	for _, s := range b.sinks.initialized {
+		plan.Sinks = append(plan.Sinks, PlanSink{Name: s.name, Description: s.sink.Describe()})
+	}
+	for _, endpoint := range b.config.Endpoints {
+		for _, vuln := range endpoint.Vulnerabilities {
+			use := fmt.Sprintf("%s %s%s: %s", strings.ToUpper(endpoint.Method), endpoint.Host, endpoint.Path, vuln.Type)
+			for _, name := range VulnerabilitySinks(vuln) {
+				if i := slices.IndexFunc(plan.Sinks, func(s PlanSink) bool { return s.Name == name }); i >= 0 {
+					plan.Sinks[i].Vulnerabilities = append(plan.Sinks[i].Vulnerabilities, use)
+				}
+			}
+		}
+	}
+	plan.Seeds = b.planSeeds()
+
+	plan.Routes = srv.Router().Routes()
+	if b.grpc != nil {
+		plan.GRPCRoutes = b.grpc.Routes()
+	}
+	if admin := srv.AdminRouter(); admin != nil {
+		plan.AdminRoutes = admin.Routes()
+	}
+	return plan, nil
+}
+
+// planListeners returns the addresses the app's listeners would take
+func (b *Builder) planListeners() []PlanListener {
+	app := b.config.App
+	host := app.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	name := "http"
+	if app.TLS != nil && app.TLS.Enabled {
+		name = "https"
+	}
+	listeners := []PlanListener{{name, net.JoinHostPort(host, strconv.Itoa(app.Port))}}
+	if app.Admin != nil {
+		listeners = append(listeners, PlanListener{"admin", app.Admin.Address(app.Port)})
+	}
+	if app.GRPC != nil {
+		listeners = append(listeners, PlanListener{"grpc", app.GRPC.Address(host)})
+	}
+	if app.OOB != nil && app.OOB.HTTPListen != "" {
+		listeners = append(listeners, PlanListener{"oob-http", app.OOB.HTTPListen})
+	}
+	if app.OOB != nil && app.OOB.DNSListen != "" {
+		listeners = append(listeners, PlanListener{"oob-dns", app.OOB.DNSListen})
+	}
+	if app.Mail != nil && app.Mail.SMTPListen != "" {
+		listeners = append(listeners, PlanListener{"smtp", app.Mail.SMTPListen})
+	}
+	return listeners
+}
+
+// planSeeds returns the data the sinks were seeded with, mirroring prepare
+func (b *Builder) planSeeds() []PlanSeed {
+	var seeds []PlanSeed
+	data := b.config.Data
+	if b.sinks.sqlite != nil {
+		for _, name := range slices.Sorted(maps.Keys(b.tables)) {
+			seeds = append(seeds, PlanSeed{"sqlite", name, len(b.tables[name].Rows), "rows"})
+		}
+		if auth := b.config.Auth; auth != nil && len(auth.Users) > 0 {
+			seeds = append(seeds, PlanSeed{"sqlite", auth.TableName(), len(auth.Users), "users"})
+		}
+	}
+	if data != nil && b.sinks.redis != nil && len(data.Redis) > 0 {
+		seeds = append(seeds, PlanSeed{"redis", "", len(data.Redis), "keys"})
+	}
+	if data != nil && b.sinks.documents != nil {
+		collections := make(map[string]int)
+		for name, table := range b.tables {
+			collections[name] = len(table.Rows)
+		}
+		for name, docs := range data.Collections {
+			collections[name] = len(docs)
+		}
+		for _, name := range slices.Sorted(maps.Keys(collections)) {
+			seeds = append(seeds, PlanSeed{"documents", name, collections[name], "documents"})
+		}
+	}
+	if data != nil && data.LDAP != nil && b.sinks.ldap != nil {
+		seeds = append(seeds, PlanSeed{"ldap", "", len(data.LDAP.Users) + len(data.LDAP.Groups), "entries"})
+	}
+	if data != nil && b.sinks.objects != nil {
+		for _, bucket := range data.Buckets {
+			seeds = append(seeds, PlanSeed{"objectstore", bucket.Name, len(bucket.Objects), "objects"})
+		}
+	}
+	if b.sinks.filesystem != nil {
+		if len(b.config.Files) > 0 {
+			seeds = append(seeds, PlanSeed{"filesystem", "", len(b.config.Files), "files"})
+		}
+		if len(b.config.Static) > 0 {
+			seeds = append(seeds, PlanSeed{"filesystem", "", len(b.config.Static), "directories"})
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,53 @@
+package builder
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_DryRun tests the plan lists the sinks with the vulnerabilities
+// using them, the seeded files and the routes, with nothing listening
+func TestBuilder_DryRun(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:  "dry-run-test",
+			Port:  8080,
+			Admin: &config.AdminConfig{Token: "s3cret"},
+		},
+		Files: []config.FileConfig{
+			{Path: "reports/q1.txt", Content: "quarterly report"},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/file",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	plan, err := builder.DryRun()
+	if err != nil {
+		t.Fatalf("Failed to dry run: %v", err)
+	}
+	defer builder.Close()
+
+	if len(plan.Listeners) != 2 || plan.Listeners[0] != (PlanListener{"http", "127.0.0.1:8080"}) || plan.Listeners[1].Name != "admin" {
+		t.Errorf("Unexpected listeners %v", plan.Listeners)
+	}
+	i := slices.IndexFunc(plan.Sinks, func(s PlanSink) bool { return s.Name == "filesystem" })
+	if i < 0 || !slices.Equal(plan.Sinks[i].Vulnerabilities, []string{"GET /file: path_traversal"}) {
+		t.Errorf("Expected the filesystem sink used by /file, got %+v", plan.Sinks)
+	}
+	if !slices.Contains(plan.Seeds, PlanSeed{"filesystem", "", 1, "files"}) {
+		t.Errorf("Expected the file seeded, got %+v", plan.Seeds)
+	}
+	if !slices.Contains(plan.Routes, "GET /file") || len(plan.AdminRoutes) == 0 {
+		t.Errorf("Expected the endpoint and admin API routed, got %v and %v", plan.Routes, plan.AdminRoutes)
+	}
+}
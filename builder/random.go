@@ -0,0 +1,38 @@
+package builder
+
+import (
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"sync"
+)
+
+// seededReader is a reproducible random source shared by modules and sinks
+type seededReader struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// newRandom returns the source for an app's tokens and IDs: seeded when
+// app.seed is set, or nil so every instance draws from crypto/rand
+func newRandom(seed int64) io.Reader {
+	if seed == 0 {
+		return nil
+	}
+	return &seededReader{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Read fills p with the next bytes of the seeded stream
+func (s *seededReader) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Read(p)
+}
+
+// deriveSeed combines the app seed with a name, so each table generated from
+// app.seed gets its own reproducible rows
+func deriveSeed(seed int64, name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return seed ^ int64(h.Sum64())
+}
@@ -0,0 +1,57 @@
+package builder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestNewRandom tests the app seed gives the same stream on every run
+func TestNewRandom(t *testing.T) {
+	if newRandom(0) != nil {
+		t.Error("Expected no seeded source without a seed")
+	}
+
+	read := func(seed int64) []byte {
+		b := make([]byte, 32)
+		newRandom(seed).Read(b)
+		return b
+	}
+	if !bytes.Equal(read(42), read(42)) {
+		t.Error("Expected the same seed to give the same bytes")
+	}
+	if bytes.Equal(read(42), read(43)) {
+		t.Error("Expected different seeds to give different bytes")
+	}
+}
+
+// TestBuilder_GenerateTablesAppSeed tests app.seed picks the rows of tables without their own seed
+func TestBuilder_GenerateTablesAppSeed(t *testing.T) {
+	generate := func(appSeed, tableSeed int64) string {
+		cfg := &config.Config{
+			App: config.AppConfig{Seed: appSeed},
+			Data: &config.DataConfig{Tables: map[string]config.TableConfig{
+				"users": {
+					Columns:  []string{"id", "token"},
+					Generate: &config.GenerateConfig{Rows: 5, Seed: tableSeed, Columns: map[string]string{"token": "faker.uuid"}},
+				},
+			}},
+		}
+		tables, err := New(cfg, "").generateTables()
+		if err != nil {
+			t.Fatalf("Failed to generate: %v", err)
+		}
+		return tables["users"].Rows[4][1].(string)
+	}
+
+	if generate(7, 0) != generate(7, 0) {
+		t.Error("Expected app.seed to give the same rows on every run")
+	}
+	if generate(7, 0) == generate(0, 0) || generate(7, 0) == generate(8, 0) {
+		t.Error("Expected app.seed to change the generated rows")
+	}
+	if generate(7, 3) != generate(8, 3) {
+		t.Error("Expected a table's own seed to win over app.seed")
+	}
+}
@@ -0,0 +1,38 @@
+package builder
+
+import (
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// redactSecrets hides the lab's secrets in srv's request log when
+// app.logging.redact asks to, so shipped logs don't give away the exercise
+func (b *Builder) redactSecrets(srv *server.Server) {
+	if b.config.App.Logging.RedactsSecrets() {
+		srv.RedactSecrets(b.secrets())
+	}
+}
+
+// secrets lists the values a request log could leak: the flags, the login
+// wall's passwords and tokens, the admin token and the cloud credentials
+func (b *Builder) secrets() []string {
+	var secrets []string
+	for _, value := range b.flags {
+		secrets = append(secrets, value)
+	}
+	if b.config.Auth != nil {
+		for _, user := range b.config.Auth.Users {
+			secrets = append(secrets, user.Password, user.Token)
+		}
+	}
+	app := b.config.App
+	if app.Admin != nil {
+		secrets = append(secrets, app.Admin.Token)
+	}
+	if metadata := app.CloudMetadata; metadata != nil {
+		secrets = append(secrets, metadata.SecretAccessKey, metadata.Token, metadata.Flag)
+	}
+	if storage := app.ObjectStorage; storage != nil {
+		secrets = append(secrets, storage.SecretAccessKey)
+	}
+	return secrets
+}
@@ -0,0 +1,57 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestRedactSecrets tests the lab's flags and tokens are hidden in logged
+// bodies and headers
+func TestRedactSecrets(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name:    "redact",
+			Port:    8080,
+			Admin:   &config.AdminConfig{Token: "admin-token"},
+			Logging: &config.LoggingConfig{Bodies: true, Redact: &config.RedactConfig{}},
+		},
+		Flags: []config.FlagConfig{{Name: "loot", Value: "FLAG{loot}"}},
+		Endpoints: []config.EndpointConfig{{
+			Path:             "/echo",
+			Method:           "GET",
+			ResponseTemplate: "{{.Input}} ${flag:loot}",
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xss_reflected", Placement: "query_param", Param: "q"},
+			},
+		}},
+	}
+	path := filepath.Join(t.TempDir(), "redact.json")
+	b := New(cfg, path)
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer srv.Stop(t.Context()) // Closes the log file
+	defer b.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/echo?q=admin-token", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	srv.Router().ServeHTTP(httptest.NewRecorder(), req)
+
+	data, _ := os.ReadFile(path)
+	line := string(data)
+	if strings.Contains(line, "FLAG{loot}") || strings.Contains(line, "admin-token") {
+		t.Errorf("Expected the flag and token hidden, got %s", line)
+	}
+	for _, want := range []string{`"response_body":"[REDACTED] [REDACTED]"`, `"Authorization":"[REDACTED]"`, `"path":"/echo"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %s in %s", want, line)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// Sink is a sink managed by the builder. Init receives the builder so a sink can
+// read the configuration and use sinks initialized before it.
+type Sink interface {
+	// Init creates the sink's resources
+	Init(b *Builder) error
+
+	// Close releases the sink's resources
+	Close() error
+
+	// Describe summarizes the initialized sink for logs, e.g. "SQLite sink (in-memory)"
+	Describe() string
+}
+
+// ModuleSink is implemented by sinks that modules use directly. The value is
+// exposed to modules in SinkContext.Extra under the sink's name.
+type ModuleSink interface {
+	ModuleSink() interface{}
+}
+
+// SinkFactory describes a sink that can be registered with the builder
+type SinkFactory struct {
+	// Name identifies the sink in logs, statistics and Builder.Sink
+	Name string
+
+	// Needed reports whether the configuration uses the sink
+	Needed func(cfg *config.Config) bool
+
+	// New creates an uninitialized sink
+	New func() Sink
+}
+
+// SinkRegistry holds sink factories in registration order, which is also the
+// order sinks are initialized in
+type SinkRegistry struct {
+	mu        sync.RWMutex
+	factories []SinkFactory
+}
+
+// Global sink registry instance
+var globalSinkRegistry = &SinkRegistry{}
+
+// RegisterSink adds a sink factory to the global registry. Sinks registered by
+// plugins are initialized after the built-in sinks, so they can use them.
+func RegisterSink(factory SinkFactory) error {
+	return globalSinkRegistry.Register(factory)
+}
+
+// RegisteredSinks returns the names of all registered sinks in initialization order
+func RegisteredSinks() []string {
+	return globalSinkRegistry.Names()
+}
+
+// VulnerabilitySinks returns the registered sinks a vulnerability uses, in
+// initialization order: those the builder would create for it alone, and the
+// one its sink setting selects
+func VulnerabilitySinks(vuln config.VulnerabilityConfig) []string {
+	cfg := &config.Config{Endpoints: []config.EndpointConfig{{Vulnerabilities: []config.VulnerabilityConfig{vuln}}}}
+	var names []string
+	for _, factory := range globalSinkRegistry.Factories() {
+		if factory.Needed(cfg) || vuln.Sink == factory.Name {
+			names = append(names, factory.Name)
+		}
+	}
+	return names
+}
+
+// Register adds a sink factory to the registry
+func (r *SinkRegistry) Register(factory SinkFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory.Name == "" {
+		return fmt.Errorf("sink name cannot be empty")
+	}
+	if factory.Needed == nil || factory.New == nil {
+		return fmt.Errorf("sink '%s' must define Needed and New", factory.Name)
+	}
+
+	for _, existing := range r.factories {
+		if existing.Name == factory.Name {
+			return fmt.Errorf("sink '%s' is already registered", factory.Name)
+		}
+	}
+
+	r.factories = append(r.factories, factory)
+	return nil
+}
+
+// Names returns the registered sink names in initialization order
+func (r *SinkRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, len(r.factories))
+	for i, factory := range r.factories {
+		names[i] = factory.Name
+	}
+	return names
+}
+
+// Factories returns a copy of the registered factories
+func (r *SinkRegistry) Factories() []SinkFactory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]SinkFactory(nil), r.factories...)
+}
+
+// namedSink is an initialized sink
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// initializeSinks creates every registered sink the configuration needs
+func (b *Builder) initializeSinks() error {
+	if err := b.checkSinkSelection(); err != nil {
+		return err
+	}
+
+	for _, factory := range globalSinkRegistry.Factories() {
+		// A sink is needed when the configuration implies it or a vulnerability selects it
+		if !factory.Needed(b.config) && !b.selected(factory.Name) {
+			continue
+		}
+
+		sink := factory.New()
+		if err := sink.Init(b); err != nil {
+			return fmt.Errorf("failed to create %s sink: %w", factory.Name, err)
+		}
+		b.sinks.initialized = append(b.sinks.initialized, namedSink{name: factory.Name, sink: sink})
+
+		if description := sink.Describe(); description != "" {
+			log.Printf("Initialized %s", description)
+		}
+	}
+
+	return nil
+}
+
+// Sink returns an initialized sink by name
+func (b *Builder) Sink(name string) (Sink, bool) {
+	for _, s := range b.sinks.initialized {
+		if s.name == name {
+			return s.sink, true
+		}
+	}
+	return nil, false
+}
+
+// closeSinks closes initialized sinks in reverse order
+func (b *Builder) closeSinks() error {
+	var errs []string
+
+	if b.sinks.copies != nil {
+		if err := b.sinks.copies.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("isolated copies: %v", err))
+		}
+		b.sinks.copies = nil
+	}
+	if b.sinks.seed != nil {
+		if err := b.sinks.seed.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("seeded database: %v", err))
+		}
+		b.sinks.seed = nil
+	}
+
+	for i := len(b.sinks.initialized) - 1; i >= 0; i-- {
+		s := b.sinks.initialized[i]
+		if err := s.sink.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.name, err))
+		}
+	}
+	b.sinks.initialized = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors closing sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package builder
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// pluginSink is a sink provided outside the builder
+type pluginSink struct {
+	initialized   bool
+	closed        bool
+	sawFilesystem bool
+}
+
+func (s *pluginSink) Init(b *Builder) error {
+	s.initialized = true
+	s.sawFilesystem = b.sinks.filesystem != nil
+	return nil
+}
+
+func (s *pluginSink) Close() error {
+	s.closed = true
+	return errors.New("plugin close failed")
+}
+
+func (s *pluginSink) Describe() string {
+	return "test plugin sink"
+}
+
+func (s *pluginSink) ModuleSink() interface{} {
+	return s
+}
+
+// TestSinkRegistry_Register tests registration errors and ordering
+func TestSinkRegistry_Register(t *testing.T) {
+	registry := &SinkRegistry{}
+	needed := func(cfg *config.Config) bool { return true }
+	factory := func() Sink { return &pluginSink{} }
+
+	if err := registry.Register(SinkFactory{Needed: needed, New: factory}); err == nil {
+		t.Error("Expected error for empty name")
+	}
+	if err := registry.Register(SinkFactory{Name: "a", New: factory}); err == nil {
+		t.Error("Expected error for missing Needed")
+	}
+	if err := registry.Register(SinkFactory{Name: "a", Needed: needed, New: factory}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := registry.Register(SinkFactory{Name: "b", Needed: needed, New: factory}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := registry.Register(SinkFactory{Name: "a", Needed: needed, New: factory}); err == nil {
+		t.Error("Expected error for duplicate name")
+	}
+
+	if names := registry.Names(); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("Unexpected names %v", names)
+	}
+
+	if err := RegisterSink(SinkFactory{Name: "sqlite", Needed: needed, New: factory}); err == nil {
+		t.Error("Expected built-in sink names to be taken")
+	}
+}
+
+// TestBuilder_PluginSink tests a registered sink is initialized, exposed to modules and closed
+func TestBuilder_PluginSink(t *testing.T) {
+	plugin := &pluginSink{}
+	err := RegisterSink(SinkFactory{
+		Name: "test-plugin",
+		Needed: func(cfg *config.Config) bool {
+			return cfg.App.Name == "plugin-test"
+		},
+		New: func() Sink { return plugin },
+	})
+	if err != nil {
+		t.Fatalf("Failed to register sink: %v", err)
+	}
+
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "plugin-test",
+			Port: 8080,
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/file",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+
+	if !plugin.initialized || !plugin.sawFilesystem {
+		t.Error("Expected plugin to be initialized after the built-in sinks")
+	}
+	if sink, ok := builder.Sink("test-plugin"); !ok || sink.Describe() != "test plugin sink" {
+		t.Error("Expected plugin sink to be available by name")
+	}
+	if _, ok := builder.Sink("sqlite"); ok {
+		t.Error("Expected unneeded SQLite sink to be skipped")
+	}
+
+	ctx := builder.createSinkContext()
+	if ctx.Extra["test-plugin"] != plugin {
+		t.Errorf("Expected plugin in module sink context, got %v", ctx.Extra)
+	}
+	if _, ok := builder.sinks.Stats()["test-plugin"]; !ok {
+		t.Error("Expected plugin sink in statistics")
+	}
+
+	if err := builder.Close(); err == nil {
+		t.Error("Expected plugin close error to be reported")
+	}
+	if !plugin.closed {
+		t.Error("Expected plugin to be closed")
+	}
+}
+
+// TestVulnerabilitySinks tests the sinks are those a vulnerability's type and
+// config imply, with the one it selects
+func TestVulnerabilitySinks(t *testing.T) {
+	tests := []struct {
+		vuln config.VulnerabilityConfig
+		want []string
+	}{
+		{config.VulnerabilityConfig{Type: "sql_injection"}, []string{"sqlite"}},
+		{config.VulnerabilityConfig{Type: "ssti", Config: map[string]interface{}{"execute_commands": true}}, []string{"command", "template"}},
+		{config.VulnerabilityConfig{Type: "command_injection", Config: map[string]interface{}{"variant": "blind", "oob_hosts": []interface{}{"oob.lab"}}}, []string{"command", "http"}},
+		{config.VulnerabilityConfig{Type: "xss_reflected"}, nil},
+		{config.VulnerabilityConfig{Type: "idor", Sink: "filesystem"}, []string{"filesystem"}},
+	}
+	for _, tt := range tests {
+		if got := VulnerabilitySinks(tt.vuln); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.vuln.Type, tt.want, got)
+		}
+	}
+}
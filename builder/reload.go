@@ -0,0 +1,245 @@
+package builder
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// EndpointDiff describes how a reloaded config changed the endpoints
+type EndpointDiff struct {
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Unchanged int
+}
+
+// String summarizes the diff for logging
+func (d EndpointDiff) String() string {
+	parts := []string{
+		fmt.Sprintf("%d added", len(d.Added)),
+		fmt.Sprintf("%d removed", len(d.Removed)),
+		fmt.Sprintf("%d changed", len(d.Changed)),
+		fmt.Sprintf("%d unchanged", d.Unchanged),
+	}
+	return strings.Join(parts, ", ")
+}
+
+// endpointKey identifies an endpoint by method, host and path
+func endpointKey(endpoint config.EndpointConfig) string {
+	return fmt.Sprintf("%s %s%s", strings.ToUpper(endpoint.Method), endpoint.Host, endpoint.Path)
+}
+
+// diffEndpoints compares endpoints by method, host and path
+func diffEndpoints(old, new []config.EndpointConfig) EndpointDiff {
+	previous := make(map[string]config.EndpointConfig, len(old))
+	for _, endpoint := range old {
+		previous[endpointKey(endpoint)] = endpoint
+	}
+
+	var diff EndpointDiff
+	for _, endpoint := range new {
+		k := endpointKey(endpoint)
+		prev, exists := previous[k]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, k)
+		case reflect.DeepEqual(prev, endpoint):
+			diff.Unchanged++
+		default:
+			diff.Changed = append(diff.Changed, k)
+		}
+		delete(previous, k)
+	}
+	for k := range previous {
+		diff.Removed = append(diff.Removed, k)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// Reload builds cfg and atomically swaps its routes into srv, which keeps
+// serving throughout. The running sinks, and the state exercises have built up
+// in them, are kept when the app, data, files, auth and flags sections are unchanged and no
+// new sink is needed; otherwise new ones are created and seeded while the old
+// ones keep serving, and closed only after the swap. If that fails, or a new
+// sink would listen on an address an old one holds, the reload is refused and
+// b keeps serving unchanged.
+//
+// Reload returns the builder now serving srv, which replaces b: close it, not
+// b, on shutdown. The listen address can't change without a restart.
+func (b *Builder) Reload(srv *server.Server, cfg *config.Config) (*Builder, EndpointDiff, error) {
+	diff := diffEndpoints(b.config.Endpoints, cfg.Endpoints)
+	b.keepAddress(cfg)
+
+	next := New(cfg, b.logFilePath)
+	next.toggles = b.toggles
+	next.events = b.events
+	next.coverage = b.coverage
+	// Clients keep their progress through chains that are unchanged
+	if reflect.DeepEqual(b.config.Chains, cfg.Chains) {
+		next.chains = b.chains
+	}
+	if err := next.checkSinkSelection(); err != nil {
+		return b, diff, err
+	}
+
+	if b.sinksReusable(next) {
+		next.sinks = b.sinks
+		// The users are unchanged, so logged-in clients stay logged in, and
+		// sessions keep what modules stored in them
+		if b.auth != nil {
+			next.auth = b.auth
+		}
+		if b.sessions != nil {
+			next.sessions = b.sessions
+		}
+		// And pending flows, such as logins awaiting a code
+		next.state = b.state
+		// So are the flags, which the seeded data already holds
+		next.flags, next.flagEnv = b.flags, b.flagEnv
+		if err := next.createFilesystemRoots(); err != nil {
+			return b, diff, err
+		}
+
+		router := srv.NewRouter()
+		if err := next.registerRoutes(router); err != nil {
+			return b, diff, err
+		}
+		// So do the modules' background states, for the vulnerabilities kept
+		if err := next.takeModules(b); err != nil {
+			return b, diff, err
+		}
+		srv.SwapRouter(router)
+		next.serveAdmin(srv)
+		next.serveGRPC(srv)
+		next.redactSecrets(srv)
+
+		// The periodic filesystem reset works on the shared sinks, so it carries over
+		next.stop, b.stop = b.stop, nil
+		log.Printf("Reloaded config, keeping sinks: %s", diff)
+		return next, diff, nil
+	}
+
+	// Build the new sinks alongside the old ones, which keep serving until the
+	// swap. Listeners can't take an address the old sinks still hold.
+	if addr := b.listenerClash(next); addr != "" {
+		return b, diff, fmt.Errorf("sinks must be recreated but %s is still held by the running lab; restart to apply this change", addr)
+	}
+	next.inMemory = b.inMemory
+	// The modules don't hold sinks, so the running ones carry over
+	next.running = b.running
+	router := srv.NewRouter()
+	err := next.prepare()
+	if err == nil {
+		err = next.registerRoutes(router)
+	}
+	if err != nil {
+		// Close what next created, leaving b's modules and sinks serving
+		next.running = next.running[len(b.running):]
+		next.Close()
+		return b, diff, err
+	}
+	srv.SwapRouter(router)
+	next.serveAdmin(srv)
+	next.serveGRPC(srv)
+	next.redactSecrets(srv)
+
+	// Only now are the old sinks idle: stop the modules next no longer uses and close them
+	b.running = nil
+	used := make(map[string]bool)
+	for _, name := range usedModules(next.config) {
+		used[name] = true
+	}
+	if err := next.stopModules(func(name string) bool { return used[name] }); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	log.Printf("Reloaded config, sinks recreated: %s", diff)
+	return next, diff, nil
+}
+
+// keepAddress carries the listen address and the TLS, admin, HTTP, gRPC,
+// logging, tracing and capture settings over to a reloaded config, since the
+// server is already listening
+func (b *Builder) keepAddress(cfg *config.Config) {
+	app := &cfg.App
+	if app.Port != b.config.App.Port || app.Host != b.config.App.Host || !reflect.DeepEqual(app.TLS, b.config.App.TLS) || !reflect.DeepEqual(app.Admin, b.config.App.Admin) || !reflect.DeepEqual(app.HTTP, b.config.App.HTTP) || !reflect.DeepEqual(app.GRPC, b.config.App.GRPC) || !reflect.DeepEqual(app.Logging, b.config.App.Logging) || !reflect.DeepEqual(app.Tracing, b.config.App.Tracing) || !reflect.DeepEqual(app.Capture, b.config.App.Capture) {
+		log.Printf("Warning: port, host, TLS, admin, HTTP, gRPC, logging, tracing and capture changes take effect after a restart")
+	}
+	app.Port = b.config.App.Port
+	app.Host = b.config.App.Host
+	app.TLS = b.config.App.TLS
+	app.Admin = b.config.App.Admin
+	app.HTTP = b.config.App.HTTP
+	app.GRPC = b.config.App.GRPC
+	app.Logging = b.config.App.Logging
+	app.Tracing = b.config.App.Tracing
+	app.Capture = b.config.App.Capture
+}
+
+// sinksReusable reports whether next can keep b's sinks: the config they were
+// created and seeded from is unchanged, and they include every sink next needs
+func (b *Builder) sinksReusable(next *Builder) bool {
+	if !reflect.DeepEqual(sinkSettings(b.config), sinkSettings(next.config)) {
+		return false
+	}
+
+	for _, factory := range globalSinkRegistry.Factories() {
+		if !factory.Needed(next.config) && !next.selected(factory.Name) {
+			continue
+		}
+		if _, ok := b.Sink(factory.Name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// listenerClash returns an address b's sinks listen on that next's config
+// asks them to listen on again, or "" if there is none
+func (b *Builder) listenerClash(next *Builder) string {
+	held := func(addr string) bool {
+		_, port, err := net.SplitHostPort(addr)
+		return addr != "" && err == nil && port != "0"
+	}
+	if oob, prev := next.config.App.OOB, b.config.App.OOB; b.sinks.oob != nil && oob != nil && prev != nil {
+		if held(oob.HTTPListen) && oob.HTTPListen == prev.HTTPListen {
+			return "the OOB HTTP listener's " + oob.HTTPListen
+		}
+		if held(oob.DNSListen) && oob.DNSListen == prev.DNSListen {
+			return "the OOB DNS listener's " + oob.DNSListen
+		}
+	}
+	if mail, prev := next.config.App.Mail, b.config.App.Mail; b.sinks.mail != nil && mail != nil && prev != nil {
+		if held(mail.SMTPListen) && mail.SMTPListen == prev.SMTPListen {
+			return "the SMTP listener's " + mail.SMTPListen
+		}
+	}
+	if storage, prev := next.config.App.ObjectStorage, b.config.App.ObjectStorage; b.sinks.objects != nil && storage != nil && prev != nil {
+		if held(storage.Listen) && storage.Listen == prev.Listen {
+			return "the object store's " + storage.Listen
+		}
+	}
+	return ""
+}
+
+// sinkSettings returns the parts of a config that sinks are created and seeded
+// from. Endpoints only decide which sinks are needed.
+func sinkSettings(cfg *config.Config) config.Config {
+	app := cfg.App
+	app.Name, app.Description, app.Notifications = "", "", nil
+	return config.Config{App: app, Data: cfg.Data, Files: cfg.Files, Auth: cfg.Auth, Flags: cfg.Flags, Static: cfg.Static}
+}
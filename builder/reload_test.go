@@ -0,0 +1,226 @@
+package builder
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// reloadConfig returns a path_traversal lab serving its files at path
+func reloadConfig(path, content string) *config.Config {
+	return &config.Config{
+		App: config.AppConfig{
+			Name: "reload-test",
+			Port: 8080,
+		},
+		Files: []config.FileConfig{
+			{Path: "notes.txt", Content: content},
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   path,
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+}
+
+// TestBuilder_Reload tests swapping routes in while keeping or recreating sinks
+func TestBuilder_Reload(t *testing.T) {
+	builder := New(reloadConfig("/file", "v1"), "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer func() { builder.Close() }()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// State written by an exercise survives a reload that only changes endpoints
+	if err := builder.sinks.filesystem.WriteFile("upload.txt", "uploaded"); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	next, diff, err := builder.Reload(srv, reloadConfig("/read", "v1"))
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	builder = next
+
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || diff.Added[0] != "GET /read" {
+		t.Errorf("Expected /file replaced by /read, got %+v", diff)
+	}
+	if rec := get("/file?name=notes.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected removed endpoint to return 404, got %d", rec.Code)
+	}
+	if body := get("/read?name=upload.txt").Body.String(); !strings.Contains(body, "uploaded") {
+		t.Errorf("Expected uploaded file to be kept, got %s", body)
+	}
+
+	// Changing the files recreates the sinks from the new config
+	next, diff, err = builder.Reload(srv, reloadConfig("/read", "v2"))
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	builder = next
+
+	if diff.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged endpoint, got %+v", diff)
+	}
+	if body := get("/read?name=notes.txt").Body.String(); !strings.Contains(body, "v2") {
+		t.Errorf("Expected reseeded file, got %s", body)
+	}
+	if body := get("/read?name=upload.txt").Body.String(); strings.Contains(body, "uploaded") {
+		t.Errorf("Expected recreated filesystem to drop the upload, got %s", body)
+	}
+
+	// A port change can't be applied to the running server
+	cfg := reloadConfig("/read", "v2")
+	cfg.App.Port = 9090
+	if builder, _, err = builder.Reload(srv, cfg); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if cfg.App.Port != 8080 {
+		t.Errorf("Expected the running port to be kept, got %d", cfg.App.Port)
+	}
+}
+
+// TestBuilder_ReloadRefused tests that a reload whose new sinks can't start
+// leaves the running lab serving with its sinks open
+func TestBuilder_ReloadRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to pick a port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.OOB = &config.OOBConfig{HTTPListen: addr}
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	// New files recreate the sinks, whose OOB listener wants the same address
+	next := reloadConfig("/file", "v2")
+	next.App.OOB = &config.OOBConfig{HTTPListen: addr}
+	kept, _, err := builder.Reload(srv, next)
+	if err == nil || !strings.Contains(err.Error(), addr) {
+		t.Fatalf("Expected the reload to be refused over %s, got %v", addr, err)
+	}
+	if kept != builder {
+		t.Fatal("Expected the running builder back")
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil))
+	if !strings.Contains(rec.Body.String(), "v1") {
+		t.Errorf("Expected the previous config to keep serving, got %d %s", rec.Code, rec.Body.String())
+	}
+	if resp, err := http.Get("http://" + addr + "/"); err != nil {
+		t.Errorf("Expected the OOB listener to stay open: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+}
+
+// TestDiffEndpoints tests comparing endpoints by method, host and path
+// TestBuilder_ReloadKeepsChainProgress tests clients keep their progress
+// through chains a reload leaves unchanged, and start over on changed ones
+func TestBuilder_ReloadKeepsChainProgress(t *testing.T) {
+	chainConfig := func(proof string) *config.Config {
+		cfg := reloadConfig("/file", "token=tok_123")
+		admin := cfg.Endpoints[0]
+		admin.Path = "/admin"
+		cfg.Endpoints = append(cfg.Endpoints, admin)
+		cfg.Chains = []config.ChainConfig{{
+			Name: "leak_to_admin",
+			Steps: []config.ChainStepConfig{
+				{Endpoint: "GET /file", Proof: proof},
+				{Endpoint: "GET /admin"},
+			},
+		}}
+		return cfg
+	}
+
+	builder := New(chainConfig("tok_123"), "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer func() { builder.Close() }()
+
+	get := func(target string) int {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Code
+	}
+	if code := get("/file?name=notes.txt"); code != http.StatusOK {
+		t.Fatalf("Expected the first step to be open, got %d", code)
+	}
+
+	// Adding an endpoint leaves the chain as it was
+	cfg := chainConfig("tok_123")
+	extra := cfg.Endpoints[0]
+	extra.Path = "/extra"
+	cfg.Endpoints = append(cfg.Endpoints, extra)
+	next, _, err := builder.Reload(srv, cfg)
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	builder = next
+	if code := get("/admin?name=notes.txt"); code != http.StatusOK {
+		t.Errorf("Expected the second step to stay unlocked, got %d", code)
+	}
+
+	// A changed chain starts over
+	next, _, err = builder.Reload(srv, chainConfig("tok_"))
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	builder = next
+	if code := get("/admin?name=notes.txt"); code != http.StatusForbidden {
+		t.Errorf("Expected the second step to be locked again, got %d", code)
+	}
+}
+
+func TestDiffEndpoints(t *testing.T) {
+	old := []config.EndpointConfig{
+		{Path: "/a", Method: "GET"},
+		{Path: "/b", Method: "GET"},
+		{Path: "/c", Method: "POST", ResponseType: "json"},
+	}
+	new := []config.EndpointConfig{
+		{Path: "/a", Method: "GET"},
+		{Path: "/c", Method: "POST", ResponseType: "html"},
+		{Path: "/d", Method: "GET", Host: "admin.lab"},
+	}
+
+	diff := diffEndpoints(old, new)
+	if diff.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged, got %d", diff.Unchanged)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != "POST /c" {
+		t.Errorf("Expected POST /c changed, got %v", diff.Changed)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "GET admin.lab/d" {
+		t.Errorf("Expected GET admin.lab/d added, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "GET /b" {
+		t.Errorf("Expected GET /b removed, got %v", diff.Removed)
+	}
+}
@@ -0,0 +1,82 @@
+package builder
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// responseTemplate renders an endpoint's response_template
+type responseTemplate struct {
+	tmpl   *template.Template
+	tables map[string][]map[string]interface{} // Seeded rows keyed by column, as .Tables
+}
+
+// responseView is the data a response template is executed with. The first
+// result's fields are promoted, so single-vulnerability templates can use
+// .Input and .Data directly.
+type responseView struct {
+	server.ModuleResult
+	Results []server.ModuleResult
+	Request *http.Request
+	Tables  map[string][]map[string]interface{}
+}
+
+// newResponseTemplate parses the endpoint's response template, or returns nil if it has none
+func (b *Builder) newResponseTemplate(endpoint config.EndpointConfig) (*responseTemplate, error) {
+	tmpl, err := endpoint.ParseResponseTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid response_template for %s %s: %w", endpoint.Method, endpoint.Path, err)
+	}
+	if tmpl == nil {
+		return nil, nil
+	}
+
+	tables, err := b.seedTables()
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &responseTemplate{tmpl: tmpl, tables: make(map[string][]map[string]interface{}, len(tables))}
+	for name, table := range tables {
+		rows := make([]map[string]interface{}, 0, len(table.Rows))
+		for _, values := range table.Rows {
+			row := make(map[string]interface{}, len(table.Columns))
+			for i, column := range table.Columns {
+				if i < len(values) {
+					row[column] = values[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+		rt.tables[name] = rows
+	}
+	return rt, nil
+}
+
+// render executes the template for a request's results and returns the body
+// with the status code of the first result: its own, or 500 if it failed. The
+// status is 0 when no result sets one, leaving it to the endpoint.
+func (rt *responseTemplate) render(r *http.Request, results []server.ModuleResult) (string, int, error) {
+	view := responseView{Results: results, Request: r, Tables: rt.tables}
+	statusCode := 0
+	if len(results) > 0 {
+		view.ModuleResult = results[0]
+		if results[0].StatusCode != 0 {
+			statusCode = results[0].StatusCode
+		} else if results[0].Error != "" {
+			statusCode = http.StatusInternalServerError
+		}
+	}
+	view.RequestID = server.RequestID(r) // Also when no module ran
+
+	var body strings.Builder
+	if err := rt.tmpl.Execute(&body, view); err != nil {
+		return "", 0, err
+	}
+	return body.String(), statusCode, nil
+}
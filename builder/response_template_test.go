@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_ResponseTemplate tests endpoint responses are rendered from the template
+func TestBuilder_ResponseTemplate(t *testing.T) {
+	cfg := reloadConfig("/file", "secret notes")
+	cfg.Endpoints[0].ResponseType = "html"
+	cfg.Endpoints[0].ResponseTemplate = `<h1>{{ .Input | upper }}</h1>{{ if .Error }}<p class="error">{{ .Error }}</p>{{ else }}<pre>{{ .Data.content }}</pre>{{ end }}`
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Expected an HTML content type, got %s", got)
+	}
+	if body, want := rec.Body.String(), "<h1>NOTES.TXT</h1><pre>secret notes</pre>"; body != want {
+		t.Errorf("Expected %q, got %q", want, body)
+	}
+
+	// Module errors are rendered by the template too, keeping their status code
+	rec = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file?name=missing.txt", nil))
+	if rec.Code == http.StatusOK {
+		t.Errorf("Expected an error status, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(body, `<h1>MISSING.TXT</h1><p class="error">`) {
+		t.Errorf("Expected the error to be rendered, got %q", body)
+	}
+}
+
+// TestResponseTemplate_Tables tests seeded tables are exposed to templates as rows keyed by column
+func TestResponseTemplate_Tables(t *testing.T) {
+	cfg := reloadConfig("/users", "")
+	cfg.Data = &config.DataConfig{Tables: map[string]config.TableConfig{
+		"users": {Columns: []string{"id", "username"}, Rows: [][]interface{}{{1, "admin"}, {2, "alice"}}},
+	}}
+	cfg.Endpoints[0].ResponseTemplate = `{{ range .Tables.users }}{{ .username }};{{ end }}{{ json .Results }}`
+
+	rt, err := New(cfg, "").newResponseTemplate(cfg.Endpoints[0])
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	body, statusCode, err := rt.render(httptest.NewRequest(http.MethodGet, "/users", nil), nil)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if statusCode != 0 {
+		t.Errorf("Expected no status with no results, got %d", statusCode)
+	}
+	if want := "admin;alice;null"; body != want {
+		t.Errorf("Expected %q, got %q", want, body)
+	}
+}
@@ -0,0 +1,31 @@
+package builder
+
+import (
+	"net/http"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// withRouteTypes answers 404 when a typed path segment, like {id:int}, doesn't
+// match its type, as if no route matched
+func withRouteTypes(params []config.RouteParam, next http.HandlerFunc) http.HandlerFunc {
+	var typed []config.RouteParam
+	for _, param := range params {
+		if param.Type != "" {
+			typed = append(typed, param)
+		}
+	}
+	if len(typed) == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, param := range typed {
+			if !param.Matches(r.PathValue(param.Name)) {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
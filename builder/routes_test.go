@@ -0,0 +1,75 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_TypedRoutes tests typed path segments only match values of their type
+func TestBuilder_TypedRoutes(t *testing.T) {
+	cfg := reloadConfig("/files/{dir:alpha}/{name}", "v1")
+	cfg.Endpoints[0].Vulnerabilities = []config.VulnerabilityConfig{
+		{Type: "path_traversal", Placement: "path_param", Param: "name"},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	tests := map[string]int{
+		"/files/docs/notes.txt": http.StatusOK,
+		"/files/2024/notes.txt": http.StatusNotFound,
+		"/files/docs":           http.StatusNotFound,
+	}
+	for target, expected := range tests {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != expected {
+			t.Errorf("%s: expected %d, got %d", target, expected, rec.Code)
+		}
+		if expected == http.StatusOK && !strings.Contains(rec.Body.String(), "v1") {
+			t.Errorf("%s: expected the path_param to be read, got %s", target, rec.Body.String())
+		}
+	}
+}
+
+// TestBuilder_DeepRoutes tests catch-all segments take the rest of the path and
+// regex-constrained ones only match whole values
+func TestBuilder_DeepRoutes(t *testing.T) {
+	cfg := reloadConfig("/orgs/{org:re:[a-z]{3}}/files/*name", "v1")
+	cfg.Files = append(cfg.Files, config.FileConfig{Path: "docs/deep/notes.txt", Content: "deep"})
+	cfg.Endpoints[0].Vulnerabilities = []config.VulnerabilityConfig{
+		{Type: "path_traversal", Placement: "path_param", Param: "name"},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	tests := map[string]string{
+		"/orgs/abc/files/notes.txt":           "v1",
+		"/orgs/abc/files/docs/deep/notes.txt": "deep",
+		"/orgs/abcd/files/notes.txt":          "",
+		"/orgs/ab1/files/notes.txt":           "",
+	}
+	for target, expected := range tests {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if expected == "" && rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", target, rec.Code)
+		}
+		if expected != "" && !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("%s: expected %q, got %d %s", target, expected, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// selected reports whether any vulnerability selects the named sink with its sink setting
+func (b *Builder) selected(name string) bool {
+	return anyVuln(b.config, func(vuln config.VulnerabilityConfig) bool {
+		return vuln.Sink == name
+	})
+}
+
+// checkSinkSelection rejects vulnerabilities that select a sink nobody registered
+func (b *Builder) checkSinkSelection() error {
+	registered := make(map[string]bool)
+	for _, name := range RegisteredSinks() {
+		registered[name] = true
+	}
+
+	for _, endpoint := range b.config.Endpoints {
+		for _, vuln := range endpoint.Vulnerabilities {
+			if vuln.Sink != "" && !registered[vuln.Sink] {
+				return fmt.Errorf("endpoint %s: unknown sink '%s' for %s", endpoint.Path, vuln.Sink, vuln.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// createFilesystemRoots creates the directories vulnerabilities use as their
+// filesystem root, so they are part of the reset state
+func (b *Builder) createFilesystemRoots() error {
+	if b.sinks.filesystem == nil {
+		return nil
+	}
+
+	for _, endpoint := range b.config.Endpoints {
+		for _, vuln := range endpoint.Vulnerabilities {
+			root := filesystemRoot(vuln)
+			if root == "" {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Join(b.sinks.filesystem.BasePath(), root), 0755); err != nil {
+				return fmt.Errorf("failed to create filesystem root %s: %w", root, err)
+			}
+		}
+	}
+	return nil
+}
+
+// filesystemRoot returns the directory a vulnerability's file operations are relative to
+func filesystemRoot(vuln config.VulnerabilityConfig) string {
+	if vuln.Sink != "filesystem" {
+		return ""
+	}
+	root, _ := vuln.SinkOptions["root"].(string)
+	return root
+}
+
+// applySinkOptions scopes the module's sinks to the vulnerability's sink settings
+func (b *Builder) applySinkOptions(ctx *modules.SinkContext, vuln config.VulnerabilityConfig) {
+	if fs, ok := ctx.Filesystem.(*filesystemSinkAdapter); ok {
+		if root := filesystemRoot(vuln); root != "" {
+			ctx.Filesystem = &filesystemSinkAdapter{fs.sink, root, fs.stats}
+		}
+	}
+
+	if ctx.Command == nil {
+		return
+	}
+	// Sink options for the command sink are sandbox settings, like a sandbox block in the config
+	var policy sinks.CommandPolicy
+	if vuln.Sink == "command" && len(vuln.SinkOptions) > 0 {
+		policy = commandPolicy(vuln.SinkOptions)
+	} else if sandbox, ok := vuln.Config["sandbox"].(map[string]interface{}); ok {
+		policy = commandPolicy(sandbox)
+	} else {
+		return
+	}
+	policy.Env = b.flagEnv
+	ctx.Command = &commandSinkAdapter{b.sinks.command, policy, &b.sinks.stats}
+}
@@ -0,0 +1,108 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_FilesystemRoots tests path_traversal endpoints reading from separate directory trees
+func TestBuilder_FilesystemRoots(t *testing.T) {
+	endpoint := func(path, root string) config.EndpointConfig {
+		return config.EndpointConfig{
+			Path:   path,
+			Method: "GET",
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{
+					Type:        "path_traversal",
+					Placement:   "query_param",
+					Param:       "name",
+					Sink:        "filesystem",
+					SinkOptions: map[string]interface{}{"root": root},
+				},
+			},
+		}
+	}
+
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "roots-test",
+			Port: 8080,
+		},
+		Files: []config.FileConfig{
+			{Path: "tenants/a/report.txt", Content: "tenant a report"},
+			{Path: "tenants/b/report.txt", Content: "tenant b report"},
+		},
+		Endpoints: []config.EndpointConfig{
+			endpoint("/a/file", "tenants/a"),
+			endpoint("/b/file", "tenants/b"),
+			endpoint("/c/file", "tenants/c"),
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+
+	if body := get("/a/file?name=report.txt"); !strings.Contains(body, "tenant a report") {
+		t.Errorf("Expected tenant a's file, got %s", body)
+	}
+	if body := get("/b/file?name=report.txt"); !strings.Contains(body, "tenant b report") {
+		t.Errorf("Expected tenant b's file, got %s", body)
+	}
+	if body := get("/a/file?name=../b/report.txt"); !strings.Contains(body, "tenant b report") {
+		t.Errorf("Expected traversal into a sibling root, got %s", body)
+	}
+	if body := get("/c/file?name=../../etc/passwd"); !strings.Contains(body, "root:x:0:0") {
+		t.Errorf("Expected traversal out of the root, got %s", body)
+	}
+	if !builder.sinks.filesystem.Exists("tenants/c") {
+		t.Error("Expected the empty root directory to be created")
+	}
+}
+
+// TestBuilder_SinkSelection tests selecting a sink the vulnerability type doesn't imply
+func TestBuilder_SinkSelection(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "selection-test",
+			Port: 8080,
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/xml",
+				Method: "POST",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "xxe", Placement: "form_field", Param: "xml", Sink: "filesystem"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	if _, ok := builder.Sink("filesystem"); !ok {
+		t.Error("Expected selected filesystem sink to be initialized")
+	}
+
+	cfg.Endpoints[0].Vulnerabilities[0].Sink = "missing"
+	if _, err := New(cfg, "").Build(); err == nil || !strings.Contains(err.Error(), "unknown sink 'missing'") {
+		t.Errorf("Expected unknown sink error, got %v", err)
+	}
+}
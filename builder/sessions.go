@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// sessionKey is the context key of a request's session
+type sessionKey struct{}
+
+// newSessionStore creates the store behind app.sessions and session auth, or
+// returns nil if neither is configured
+func newSessionStore(cfg *config.Config, random io.Reader) *server.SessionStore {
+	sessions := cfg.App.Sessions
+	if sessions == nil && (cfg.Auth == nil || cfg.Auth.Type != "session") {
+		return nil
+	}
+
+	options := server.SessionOptions{
+		Cookie:      sessions.CookieName(cfg.Auth),
+		IdleTimeout: sessions.IdleTimeout(),
+		Random:      random,
+	}
+	if sessions != nil {
+		options.SameSite = sameSiteModes[sessions.SameSite]
+		options.ScriptReadable = sessions.ScriptReadable
+		options.AdoptIDs = sessions.AdoptIDs
+		options.KeepIDOnLogin = sessions.KeepIDOnLogin
+	}
+	return server.NewSessionStore(options)
+}
+
+// sameSiteModes maps same_site to the cookie attribute
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// withSession gives the request one session for all of its vulnerabilities,
+// so what one module stores the next can read
+func (b *Builder) withSession(w http.ResponseWriter, r *http.Request) *http.Request {
+	if b.sessions == nil {
+		return r
+	}
+	session := b.sessions.For(w, r)
+
+	// Isolation by session gives every client a session, and so a copy, at once
+	if isolation := b.config.App.Isolation; isolation != nil && isolation.By == "session" && w != nil {
+		if err := session.Start(); err != nil {
+			log.Printf("Failed to start session: %v", err)
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), sessionKey{}, session))
+}
+
+// requestSession returns the session withSession gave the request, or nil
+func requestSession(r *http.Request) *server.RequestSession {
+	session, _ := r.Context().Value(sessionKey{}).(*server.RequestSession)
+	return session
+}
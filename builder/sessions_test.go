@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// visitCounter counts visits in the session, to test modules share it
+type visitCounter struct{}
+
+func (visitCounter) Info() modules.ModuleInfo {
+	return modules.ModuleInfo{Name: "test_visit_counter", SupportedPlacements: []string{"query_param"}}
+}
+
+func (visitCounter) Handle(ctx *modules.HandlerContext) (*modules.Result, error) {
+	if ctx.Session == nil {
+		return modules.NewErrorResult("no session"), nil
+	}
+	visits, _ := strconv.Atoi(ctx.Session.Get("visits"))
+	if err := ctx.Session.Set("visits", strconv.Itoa(visits+1)); err != nil {
+		return nil, err
+	}
+	return modules.NewResult(map[string]interface{}{"visits": visits + 1}), nil
+}
+
+func init() {
+	modules.Register(visitCounter{})
+}
+
+// TestSessions tests the vulnerabilities of a request share one session,
+// which later requests find by its cookie
+func TestSessions(t *testing.T) {
+	vuln := config.VulnerabilityConfig{Type: "test_visit_counter", Placement: "query_param", Param: "q"}
+	endpoint := config.EndpointConfig{Path: "/visit", Method: "GET", Vulnerabilities: []config.VulnerabilityConfig{vuln, vuln}}
+	cfg := &config.Config{
+		App:       config.AppConfig{Name: "lab", Sessions: &config.SessionsConfig{Cookie: "sid"}},
+		Endpoints: []config.EndpointConfig{endpoint},
+	}
+	b := New(cfg, "")
+	handler := b.createHandler(endpoint, "json", nil)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/visit", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" {
+		t.Fatalf("Expected one sid cookie, got %v", cookies)
+	}
+	if session := b.sessions.Get(cookies[0].Value); session == nil || session.Get("visits") != "2" {
+		t.Fatalf("Expected both vulnerabilities to count a visit, got %v", session)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/visit", nil)
+	req.AddCookie(cookies[0])
+	handler(httptest.NewRecorder(), req)
+	if visits := b.sessions.Get(cookies[0].Value).Get("visits"); visits != "4" {
+		t.Errorf("Expected 4 visits, got %s", visits)
+	}
+}
+
+// TestNewSessionStore tests session auth alone sets up sessions under its cookie
+func TestNewSessionStore(t *testing.T) {
+	if newSessionStore(&config.Config{Auth: &config.AuthConfig{Type: "basic"}}, nil) != nil {
+		t.Error("Expected no sessions for basic auth")
+	}
+	store := newSessionStore(&config.Config{Auth: &config.AuthConfig{Type: "session", Cookie: "sid"}}, nil)
+	if store == nil || store.CookieName() != "sid" {
+		t.Errorf("Expected sessions under the auth cookie, got %v", store)
+	}
+}
@@ -0,0 +1,416 @@
+package builder
+
+import (
+	"fmt"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// init registers the built-in sinks. Order matters: the HTTP sink wraps the
+// OOB listener, object store and metadata service, so it comes last.
+func init() {
+	for _, factory := range []SinkFactory{
+		builtinSink("sqlite", needsSQLite, initSQLite),
+		builtinSink("filesystem", needsFilesystem, initFilesystem),
+		builtinSink("command", needsCommand, initCommand),
+		builtinSink("redis", needsRedis, initRedis),
+		builtinSink("documents", needsDocuments, initDocuments),
+		builtinSink("ldap", needsLDAP, initLDAP),
+		builtinSink("template", needsTemplate, initTemplate),
+		builtinSink("mail", needsMail, initMail),
+		builtinSink("oob", needsOOB, initOOB),
+		builtinSink("metadata", needsMetadata, initMetadata),
+		builtinSink("objectstore", needsObjectStore, initObjectStore),
+		builtinSink("http", needsHTTP, initHTTP),
+	} {
+		if err := RegisterSink(factory); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// closer is a sink resource that needs releasing
+type closer interface {
+	Close() error
+}
+
+// builtin adapts a built-in sink's init function to the Sink interface.
+// The init function stores the sink on the SinkManager for the rest of the builder.
+type builtin struct {
+	init        func(b *Builder) (closer, string, error)
+	resource    closer
+	description string
+}
+
+// builtinSink creates the factory for a built-in sink
+func builtinSink(name string, needed func(cfg *config.Config) bool, init func(b *Builder) (closer, string, error)) SinkFactory {
+	return SinkFactory{
+		Name:   name,
+		Needed: needed,
+		New: func() Sink {
+			return &builtin{init: init}
+		},
+	}
+}
+
+// Init implements Sink
+func (s *builtin) Init(b *Builder) error {
+	resource, description, err := s.init(b)
+	if err != nil {
+		return err
+	}
+	s.resource, s.description = resource, description
+	return nil
+}
+
+// Close implements Sink
+func (s *builtin) Close() error {
+	if s.resource == nil {
+		return nil
+	}
+	return s.resource.Close()
+}
+
+// Describe implements Sink
+func (s *builtin) Describe() string {
+	return s.description
+}
+
+// anyVuln reports whether any configured vulnerability matches
+func anyVuln(cfg *config.Config, match func(vuln config.VulnerabilityConfig) bool) bool {
+	for _, endpoint := range cfg.Endpoints {
+		for _, vuln := range endpoint.Vulnerabilities {
+			if match(vuln) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configFlag reads a boolean vulnerability config value
+func configFlag(vuln config.VulnerabilityConfig, key string) bool {
+	value, _ := vuln.Config[key].(bool)
+	return value
+}
+
+func needsSQLite(cfg *config.Config) bool {
+	// A data section with tables implies SQLite is needed, as does the auth table
+	if (cfg.Data != nil && len(cfg.Data.Tables) > 0) || cfg.Auth != nil {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		switch vuln.Type {
+		case "sql_injection", "method_override", "business_logic", "excessive_data_exposure", "bfla", "account_enumeration":
+			return true
+		case "websocket_injection":
+			// The sink depends on where messages are routed
+			return vuln.Config["sink"] != "command" && vuln.Config["sink"] != "xss"
+		}
+		return false
+	})
+}
+
+func needsFilesystem(cfg *config.Config) bool {
+	if len(cfg.Files) > 0 || len(cfg.Static) > 0 {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		return vuln.Type == "path_traversal"
+	})
+}
+
+func needsCommand(cfg *config.Config) bool {
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		switch vuln.Type {
+		case "command_injection":
+			return true
+		case "ssti":
+			// Commands reached from the template run for real instead of being simulated
+			return configFlag(vuln, "execute_commands")
+		case "websocket_injection":
+			return vuln.Config["sink"] == "command"
+		}
+		return false
+	})
+}
+
+func needsRedis(cfg *config.Config) bool {
+	if cfg.Data != nil && len(cfg.Data.Redis) > 0 {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		// Queries run against the embedded stores instead of emulated results
+		return vuln.Type == "nosql_injection" && configFlag(vuln, "use_real_sink") && vuln.Config["database"] == "redis"
+	})
+}
+
+func needsDocuments(cfg *config.Config) bool {
+	if cfg.Data != nil && len(cfg.Data.Collections) > 0 {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		return vuln.Type == "nosql_injection" && configFlag(vuln, "use_real_sink") && vuln.Config["database"] != "redis"
+	})
+}
+
+func needsLDAP(cfg *config.Config) bool {
+	if cfg.Data != nil && cfg.Data.LDAP != nil {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		return vuln.Type == "ldap_injection"
+	})
+}
+
+func needsTemplate(cfg *config.Config) bool {
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		return vuln.Type == "ssti"
+	})
+}
+
+func needsMail(cfg *config.Config) bool {
+	if cfg.App.Mail != nil {
+		return true
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		// Reset links are mailed to the capture mailbox
+		return vuln.Type == "account_enumeration" && configFlag(vuln, "send_email")
+	})
+}
+
+func needsOOB(cfg *config.Config) bool {
+	return cfg.App.OOB != nil
+}
+
+func needsMetadata(cfg *config.Config) bool {
+	return cfg.App.CloudMetadata != nil
+}
+
+func needsObjectStore(cfg *config.Config) bool {
+	if cfg.App.ObjectStorage != nil || (cfg.Data != nil && len(cfg.Data.Buckets) > 0) {
+		return true
+	}
+	if egress := cfg.App.Egress; egress != nil {
+		for _, service := range egress.Routes {
+			if service == "object_storage" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func needsHTTP(cfg *config.Config) bool {
+	// Proxy endpoints forward requests through the sink's egress policy
+	for _, endpoint := range cfg.Endpoints {
+		if endpoint.Type == "proxy" {
+			return true
+		}
+	}
+	return anyVuln(cfg, func(vuln config.VulnerabilityConfig) bool {
+		switch vuln.Type {
+		case "ssrf":
+			return true
+		case "command_injection":
+			// Blind callbacks are delivered to the OOB listener over HTTP
+			return vuln.Config["variant"] == "blind" && (vuln.Config["oob_hosts"] != nil || cfg.App.OOB != nil)
+		case "xxe":
+			// Out-of-band resolution makes real requests to the listener
+			return configFlag(vuln, "oob_resolution")
+		}
+		return false
+	})
+}
+
+func initSQLite(b *Builder) (closer, string, error) {
+	p := b.persistence()
+	if p == nil {
+		db, err := sinks.NewSQLite()
+		if err != nil {
+			return nil, "", err
+		}
+		b.sinks.sqlite = db
+		return db, "SQLite sink (in-memory)", nil
+	}
+
+	path := p.Path
+	if path == "" {
+		path = defaultDatabasePath
+	}
+	db, err := sinks.NewSQLiteFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	b.sinks.sqlite = db
+	return db, fmt.Sprintf("SQLite sink (%s)", path), nil
+}
+
+func initFilesystem(b *Builder) (closer, string, error) {
+	fs, err := sinks.NewFilesystem()
+	if err != nil {
+		return nil, "", err
+	}
+	b.sinks.filesystem = fs
+	return fs, fmt.Sprintf("filesystem sink at %s", fs.BasePath()), nil
+}
+
+func initCommand(b *Builder) (closer, string, error) {
+	b.sinks.command = sinks.NewCommand()
+	return b.sinks.command, "command sink", nil
+}
+
+func initRedis(b *Builder) (closer, string, error) {
+	b.sinks.redis = sinks.NewRedis()
+	return b.sinks.redis, "Redis sink (in-memory)", nil
+}
+
+func initDocuments(b *Builder) (closer, string, error) {
+	b.sinks.documents = sinks.NewDocumentStore()
+	return b.sinks.documents, "document store sink (in-memory)", nil
+}
+
+func initLDAP(b *Builder) (closer, string, error) {
+	baseDN := ""
+	if b.config.Data != nil && b.config.Data.LDAP != nil {
+		baseDN = b.config.Data.LDAP.BaseDN
+	}
+	b.sinks.ldap = sinks.NewLDAP(baseDN)
+	return b.sinks.ldap, fmt.Sprintf("LDAP sink (%s)", b.sinks.ldap.BaseDN()), nil
+}
+
+func initTemplate(b *Builder) (closer, string, error) {
+	b.sinks.template = sinks.NewTemplate()
+	return b.sinks.template, "template sink", nil
+}
+
+func initMail(b *Builder) (closer, string, error) {
+	mailbox := sinks.NewSMTP()
+	if mail := b.config.App.Mail; mail != nil && mail.SMTPListen != "" {
+		if err := mailbox.Listen(mail.SMTPListen); err != nil {
+			return nil, "", fmt.Errorf("failed to start SMTP listener: %w", err)
+		}
+		b.sinks.mail = mailbox
+		return mailbox, fmt.Sprintf("SMTP sink (listening on %s)", mailbox.Addr()), nil
+	}
+	b.sinks.mail = mailbox
+	return mailbox, "SMTP sink (capture only)", nil
+}
+
+// initOOB creates the OOB listener and starts its network listeners
+func initOOB(b *Builder) (closer, string, error) {
+	cfg := b.config.App.OOB
+	if cfg == nil {
+		// Selected by a vulnerability without an oob section
+		cfg = &config.OOBConfig{}
+	}
+	oob := sinks.NewOOB(cfg.Domain)
+	oob.SetRandom(b.random) // Reproducible tokens when app.seed is set
+
+	if cfg.HTTPListen != "" {
+		if err := oob.ListenHTTP(cfg.HTTPListen); err != nil {
+			oob.Close()
+			return nil, "", fmt.Errorf("failed to start OOB HTTP listener: %w", err)
+		}
+	}
+	if cfg.DNSListen != "" {
+		if err := oob.ListenDNS(cfg.DNSListen); err != nil {
+			oob.Close()
+			return nil, "", fmt.Errorf("failed to start OOB DNS listener: %w", err)
+		}
+	}
+	b.sinks.oob = oob
+
+	description := fmt.Sprintf("OOB listener (*.%s", oob.Domain())
+	if addr := oob.HTTPAddr(); addr != "" {
+		description += ", HTTP callbacks on " + addr
+	}
+	if addr := oob.DNSAddr(); addr != "" {
+		description += ", DNS on " + addr + "/udp"
+	}
+	return oob, description + ")", nil
+}
+
+func initMetadata(b *Builder) (closer, string, error) {
+	md := b.config.App.CloudMetadata
+	if md == nil {
+		// Selected by a vulnerability without a cloud_metadata section
+		md = &config.CloudMetadataConfig{}
+	}
+	b.sinks.metadata = sinks.NewMetadataService(sinks.MetadataConfig{
+		Provider:        md.Provider,
+		RoleName:        md.RoleName,
+		AccessKeyID:     md.AccessKeyID,
+		SecretAccessKey: md.SecretAccessKey,
+		Token:           md.Token,
+		Flag:            md.Flag,
+		RequireToken:    md.IMDSv2,
+	})
+	return nil, fmt.Sprintf("%s cloud metadata service at 169.254.169.254", b.sinks.metadata.Provider()), nil
+}
+
+// initObjectStore creates the object store and starts its listener. Without
+// explicit keys it accepts the metadata service's credentials, so SSRF-stolen keys work.
+func initObjectStore(b *Builder) (closer, string, error) {
+	cfg := b.config.App.ObjectStorage
+	if cfg == nil {
+		cfg = &config.ObjectStorageConfig{}
+	}
+
+	accessKeyID, secretAccessKey := cfg.AccessKeyID, cfg.SecretAccessKey
+	if accessKeyID == "" && b.sinks.metadata != nil {
+		accessKeyID, secretAccessKey = b.sinks.metadata.Credentials()
+	}
+
+	store := sinks.NewObjectStore(cfg.Host, accessKeyID, secretAccessKey)
+	store.Presign = sinks.PresignOptions{
+		IgnoreExpiry: cfg.PresignIgnoreExpiry,
+		BucketScoped: cfg.PresignBucketScoped,
+	}
+
+	if cfg.Listen != "" {
+		if err := store.Listen(cfg.Listen); err != nil {
+			return nil, "", fmt.Errorf("failed to start object store listener: %w", err)
+		}
+	}
+
+	b.sinks.objects = store
+	return store, fmt.Sprintf("object store sink (%s)", store.Endpoint()), nil
+}
+
+// initHTTP creates the HTTP sink, routing the emulated services in-process
+func initHTTP(b *Builder) (closer, string, error) {
+	b.sinks.httpSink = sinks.NewHTTP()
+
+	// Requests to the callback domain reach the OOB listener in-process
+	if b.sinks.oob != nil {
+		b.sinks.httpSink.SetOOB(b.sinks.oob)
+	}
+
+	// Requests to the object store's hostname reach it in-process
+	if b.sinks.objects != nil {
+		b.sinks.httpSink.SetObjectStore(b.sinks.objects)
+	}
+
+	if b.sinks.metadata != nil {
+		b.sinks.httpSink.SetMetadataService(b.sinks.metadata)
+	}
+
+	b.configureEgress()
+	return b.sinks.httpSink, "HTTP sink", nil
+}
+
+// saveSeed clones the seeded database, when isolation makes copies of it or
+// the admin API can reset it
+func (b *Builder) saveSeed() error {
+	if b.sinks.sqlite == nil || (b.config.App.Isolation == nil && b.config.App.Admin == nil) {
+		return nil
+	}
+	seed, err := b.sinks.sqlite.Clone()
+	if err != nil {
+		return err
+	}
+	b.sinks.seed = seed
+	return nil
+}
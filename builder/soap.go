@@ -0,0 +1,207 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// responseRecorder holds the JSON response of a soap or grpc endpoint's
+// handler until it is sent in an envelope or message. Headers go straight to
+// the real response.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+// soapHandler serves a soap endpoint: it checks that requests are envelopes
+// for one of its operations, then sends what next answers in JSON as the
+// operation's response element, or a SOAP fault for an error. A response
+// template's body is sent as written, being the whole envelope.
+func soapHandler(endpoint config.EndpointConfig, next http.HandlerFunc) http.HandlerFunc {
+	respBuilder := server.NewResponseBuilder()
+	namespace := endpoint.SOAP.TargetNamespace()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		envelope, err := server.ReadSOAP(r)
+		if err != nil {
+			version := server.SOAP11Namespace
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/soap+xml") {
+				version = server.SOAP12Namespace
+			}
+			respBuilder.SendSOAPFault(w, version, http.StatusInternalServerError, true, err.Error(), nil)
+			return
+		}
+		if _, ok := endpoint.SOAP.Operation(envelope.Operation); !ok {
+			respBuilder.SendSOAPFault(w, envelope.Namespace, http.StatusInternalServerError, true,
+				fmt.Sprintf("unknown operation '%s'", envelope.Operation), nil)
+			return
+		}
+
+		rec := &responseRecorder{header: w.Header()}
+		next(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		var data interface{}
+		decoder := json.NewDecoder(&rec.body)
+		decoder.UseNumber()
+		if endpoint.ResponseTemplate != "" || decoder.Decode(&data) != nil {
+			w.Header().Set("Content-Type", server.SOAPContentType(envelope.Namespace))
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		fields, _ := data.(map[string]interface{})
+		if message, ok := fields["error"].(string); ok && rec.status >= 400 {
+			respBuilder.SendSOAPFault(w, envelope.Namespace, rec.status, rec.status < 500, message, fields["debug"])
+			return
+		}
+		if wrapped, ok := fields["data"]; ok && len(fields) == 1 {
+			data = wrapped // The response wrapper's {"data": ...}
+		}
+		if _, ok := data.(map[string]interface{}); !ok {
+			data = map[string]interface{}{"return": data}
+		}
+		respBuilder.SendSOAP(w, envelope.Namespace, rec.status, envelope.Operation+"Response", namespace, data)
+	}
+}
+
+// wsdlHandler serves the WSDL of a soap endpoint, generated from its operations
+func wsdlHandler(endpoint config.EndpointConfig) http.HandlerFunc {
+	view := wsdlView{
+		Service:   endpoint.SOAP.ServiceName(),
+		Namespace: endpoint.SOAP.TargetNamespace(),
+	}
+	for _, operation := range endpoint.SOAP.Operations {
+		view.Operations = append(view.Operations, wsdlOperation{
+			Name:   operation.Name,
+			Params: endpoint.SOAPParams(operation),
+		})
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		page := view
+		page.Location = scheme + "://" + r.Host + r.URL.Path
+
+		var buf bytes.Buffer
+		if err := wsdlTemplate.Execute(&buf, page); err != nil {
+			http.Error(w, "failed to generate WSDL: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write(buf.Bytes())
+	}
+}
+
+// wsdlView is the data the WSDL template is executed with
+type wsdlView struct {
+	Service    string
+	Namespace  string
+	Location   string
+	Operations []wsdlOperation
+}
+
+// wsdlOperation is an operation of the WSDL and the string elements of its request
+type wsdlOperation struct {
+	Name   string
+	Params []string
+}
+
+// wsdlTemplate is a WSDL 1.1 document for a document/literal SOAP 1.1 service
+var wsdlTemplate = template.Must(template.New("wsdl").Funcs(template.FuncMap{
+	"xml": func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="{{.Service}}" targetNamespace="{{xml .Namespace}}"
+    xmlns="http://schemas.xmlsoap.org/wsdl/"
+    xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+    xmlns:tns="{{xml .Namespace}}"
+    xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xsd:schema targetNamespace="{{xml .Namespace}}" elementFormDefault="qualified">
+{{- range .Operations}}
+      <xsd:element name="{{.Name}}">
+        <xsd:complexType>
+          <xsd:sequence>
+{{- range .Params}}
+            <xsd:element name="{{.}}" type="xsd:string" minOccurs="0"/>
+{{- end}}
+          </xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+      <xsd:element name="{{.Name}}Response">
+        <xsd:complexType>
+          <xsd:sequence>
+            <xsd:any minOccurs="0" maxOccurs="unbounded" processContents="lax"/>
+          </xsd:sequence>
+        </xsd:complexType>
+      </xsd:element>
+{{- end}}
+    </xsd:schema>
+  </types>
+{{- range .Operations}}
+  <message name="{{.Name}}Request">
+    <part name="parameters" element="tns:{{.Name}}"/>
+  </message>
+  <message name="{{.Name}}Response">
+    <part name="parameters" element="tns:{{.Name}}Response"/>
+  </message>
+{{- end}}
+  <portType name="{{.Service}}PortType">
+{{- range .Operations}}
+    <operation name="{{.Name}}">
+      <input message="tns:{{.Name}}Request"/>
+      <output message="tns:{{.Name}}Response"/>
+    </operation>
+{{- end}}
+  </portType>
+  <binding name="{{.Service}}Binding" type="tns:{{.Service}}PortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+{{- range .Operations}}
+    <operation name="{{.Name}}">
+      <soap:operation soapAction="{{xml $.Namespace}}/{{.Name}}"/>
+      <input><soap:body use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+    </operation>
+{{- end}}
+  </binding>
+  <service name="{{.Service}}">
+    <port name="{{.Service}}Port" binding="tns:{{.Service}}Binding">
+      <soap:address location="{{xml .Location}}"/>
+    </port>
+  </service>
+</definitions>
+`))
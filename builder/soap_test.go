@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestSOAPEndpoint tests a soap endpoint serves its WSDL, answers its
+// operations in envelopes and other requests with faults
+func TestSOAPEndpoint(t *testing.T) {
+	soap := &config.SOAPConfig{
+		Service:    "ImportService",
+		Operations: []config.SOAPOperation{{Name: "Import"}},
+	}
+	router := endpointTypesRouter(t, []config.EndpointConfig{
+		{
+			Path:   "/ws/import",
+			Method: "POST",
+			Type:   "soap",
+			SOAP:   soap,
+			Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xxe", Placement: "soap_param", Param: "doc"},
+			},
+		},
+		{Path: "/ws/down", Method: "POST", Type: "soap", SOAP: soap, ErrorRate: 1},
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws/import?wsdl", nil))
+	for _, expected := range []string{
+		`targetNamespace="urn:ImportService"`,
+		`<xsd:element name="doc" type="xsd:string" minOccurs="0"/>`,
+		`<soap:operation soapAction="urn:ImportService/Import"/>`,
+		`<soap:address location="http://example.com/ws/import"/>`,
+	} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Expected the WSDL to contain %s, got:\n%s", expected, rec.Body.String())
+		}
+	}
+
+	envelope := func(operation, content string) string {
+		return `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><` + operation + ` xmlns="urn:ImportService">` +
+			content + `</` + operation + `></soap:Body></soap:Envelope>`
+	}
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		status   int
+		expected string
+	}{
+		{"operation", "/ws/import", envelope("Import", "<doc>&lt;a&gt;1&lt;/a&gt;</doc>"), http.StatusOK, `<ImportResponse xmlns="urn:ImportService"><elements><item>a</item></elements>`},
+		{"unknown operation", "/ws/import", envelope("Export", ""), http.StatusInternalServerError, `<faultcode>soap:Client</faultcode><faultstring>unknown operation &#39;Export&#39;</faultstring>`},
+		{"not an envelope", "/ws/import", "<Import/>", http.StatusInternalServerError, `<faultcode>soap:Client</faultcode><faultstring>not a SOAP envelope`},
+		{"error", "/ws/down", envelope("Import", ""), http.StatusServiceUnavailable, `<faultcode>soap:Server</faultcode><faultstring>service temporarily unavailable</faultstring><detail><message>`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.expected) {
+			t.Errorf("%s: expected %d with %s, got %d: %s", tt.name, tt.status, tt.expected, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Content-Type") != "text/xml; charset=utf-8" {
+			t.Errorf("%s: expected a SOAP 1.1 content type, got %s", tt.name, rec.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// createStaticDirs creates the static directories before the filesystem is
+// checkpointed, so they survive resets even while empty
+func (b *Builder) createStaticDirs() error {
+	if b.sinks.filesystem == nil {
+		return nil
+	}
+	for _, static := range b.config.Static {
+		if err := b.sinks.filesystem.CreateDir(static.Dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerStaticEndpoints serves each static directory under its URL prefix,
+// from the client's own filesystem with isolation. Requests can't leave the
+// directory; what's in it is up to the lab.
+func (b *Builder) registerStaticEndpoints(router *server.Router) {
+	if b.sinks.filesystem == nil {
+		return
+	}
+
+	for _, static := range b.config.Static {
+		router.HandleFunc("GET", static.Prefix()+"/", func(w http.ResponseWriter, r *http.Request) {
+			fs, err := b.filesystemFor(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			var root http.FileSystem = http.Dir(filepath.Join(fs.BasePath(), static.Dir))
+			if !static.Listing {
+				root = noListing{root}
+			}
+			http.StripPrefix(static.Prefix(), http.FileServer(root)).ServeHTTP(w, r)
+		})
+		log.Printf("Serving %s from %s", static.Prefix()+"/", static.Dir)
+	}
+}
+
+// noListing hides directories without an index.html, which http.FileServer
+// would otherwise list
+type noListing struct {
+	http.FileSystem
+}
+
+// Open opens name, failing for directories that would be listed
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || !info.IsDir() {
+		return f, nil // Stat errors are left to the file server
+	}
+
+	index, err := n.FileSystem.Open(path.Join(name, "index.html"))
+	if err != nil {
+		f.Close()
+		return nil, err // Not found, so answered with a 404
+	}
+	index.Close()
+	return f, nil
+}
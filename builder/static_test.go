@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/sinks"
+)
+
+// TestRegisterStaticEndpoints tests files are served under their prefix, and
+// directories are only listed when listing is on
+func TestRegisterStaticEndpoints(t *testing.T) {
+	fs, err := sinks.NewFilesystemWithPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create filesystem: %v", err)
+	}
+	b := New(&config.Config{Static: []config.StaticConfig{
+		{Path: "/assets/", Dir: "www/assets"},
+		{Path: "/backup", Dir: "loot", Listing: true},
+		{Path: "/empty", Dir: "empty"},
+	}}, "")
+	b.sinks.filesystem = fs
+
+	for path, content := range map[string]string{
+		"www/assets/app.js":          "console.log('app')",
+		"www/assets/img/logo.svg":    "<svg/>",
+		"www/assets/docs/index.html": "<h1>Docs</h1>",
+		"loot/db.sql.bak":            "INSERT INTO users",
+		"secret.txt":                 "outside",
+	} {
+		if err := fs.WriteFile(path, content); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if err := b.createStaticDirs(); err != nil {
+		t.Fatalf("Failed to create static directories: %v", err)
+	}
+
+	router := server.NewRouter(nil)
+	b.registerStaticEndpoints(router)
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log('app')"},
+		{"/assets/docs/", http.StatusOK, "<h1>Docs</h1>"},
+		{"/assets/img/", http.StatusNotFound, ""},
+		{"/assets/secret.txt", http.StatusNotFound, ""},
+		{"/backup/", http.StatusOK, "db.sql.bak"},
+		{"/backup/db.sql.bak", http.StatusOK, "INSERT INTO users"},
+		{"/empty/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: expected %d with %q, got %d %q", tt.target, tt.status, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
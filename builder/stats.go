@@ -0,0 +1,145 @@
+package builder
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// SinkStats counts module activity on one sink
+type SinkStats struct {
+	Operations int64      `json:"operations"`
+	Errors     int64      `json:"errors"`
+	Bytes      int64      `json:"bytes"` // Output returned to modules: file contents, command output, response bodies
+	LastError  string     `json:"last_error,omitempty"`
+	LastUsed   *time.Time `json:"last_used,omitempty"`
+}
+
+// sinkStats records activity from the sink adapters
+type sinkStats struct {
+	mu    sync.Mutex
+	sinks map[string]*SinkStats
+}
+
+// record counts one operation on a sink
+func (s *sinkStats) record(sink string, bytes int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sinks == nil {
+		s.sinks = make(map[string]*SinkStats)
+	}
+	stats, ok := s.sinks[sink]
+	if !ok {
+		stats = &SinkStats{}
+		s.sinks[sink] = stats
+	}
+
+	stats.Operations++
+	stats.Bytes += int64(bytes)
+	now := time.Now()
+	stats.LastUsed = &now
+	if err != nil {
+		stats.Errors++
+		stats.LastError = err.Error()
+	}
+}
+
+// get returns a copy of a sink's counters
+func (s *sinkStats) get(sink string) SinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stats, ok := s.sinks[sink]; ok {
+		return *stats
+	}
+	return SinkStats{}
+}
+
+// active returns the names of the initialized sinks
+func (m *SinkManager) active() []string {
+	names := make([]string, len(m.initialized))
+	for i, s := range m.initialized {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Stats returns activity counters for every initialized sink
+func (m *SinkManager) Stats() map[string]SinkStats {
+	stats := make(map[string]SinkStats)
+	for _, name := range m.active() {
+		stats[name] = m.stats.get(name)
+	}
+	return stats
+}
+
+// Health checks each initialized sink, returning "ok" or the failure
+func (m *SinkManager) Health() map[string]string {
+	health := make(map[string]string)
+	for _, name := range m.active() {
+		health[name] = "ok"
+	}
+
+	if m.sqlite != nil {
+		if _, err := m.sqlite.QuerySingle("SELECT 1"); err != nil {
+			health["sqlite"] = err.Error()
+		}
+	}
+	if m.filesystem != nil {
+		if _, err := os.Stat(m.filesystem.BasePath()); err != nil {
+			health["filesystem"] = err.Error()
+		}
+	}
+	return health
+}
+
+// registerHealthEndpoints serves lab and sink health:
+//
+//	GET /health         overall status and per-sink health (503 if a sink is failing)
+//	GET /health/sinks   per-sink health and activity counters
+func (b *Builder) registerHealthEndpoints(router *server.Router) {
+	router.HandleFunc("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
+		health := b.sinks.Health()
+
+		status, code := "healthy", http.StatusOK
+		for _, state := range health {
+			if state != "ok" {
+				status, code = "degraded", http.StatusServiceUnavailable
+			}
+		}
+
+		report := map[string]interface{}{
+			"status": status,
+			"app":    b.config.App.Name,
+			"sinks":  health,
+		}
+		if b.sinks.copies != nil {
+			report["isolated_clients"] = b.sinks.copies.count()
+		}
+		writeJSON(w, code, report)
+	})
+
+	router.HandleFunc("GET", "/health/sinks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sinks": b.sinkReports()})
+	})
+}
+
+// sinkReport is a sink's health and activity counters
+type sinkReport struct {
+	Health string `json:"health"`
+	SinkStats
+}
+
+// sinkReports returns the health and activity counters of every initialized sink
+func (b *Builder) sinkReports() map[string]sinkReport {
+	health := b.sinks.Health()
+	reports := make(map[string]sinkReport)
+	for name, stats := range b.sinks.Stats() {
+		reports[name] = sinkReport{Health: health[name], SinkStats: stats}
+	}
+	return reports
+}
@@ -0,0 +1,73 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestBuilder_SinkStats tests sink activity is counted and served by the health endpoints
+func TestBuilder_SinkStats(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{
+			Name: "stats-test",
+			Port: 8080,
+		},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/file",
+				Method: "GET",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "path_traversal", Placement: "query_param", Param: "name"},
+				},
+			},
+		},
+	}
+
+	builder := New(cfg, "")
+	srv, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer builder.Close()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	get("/file?name=" + url.QueryEscape("etc/passwd"))
+	get("/file?name=missing.txt")
+
+	stats := builder.sinks.Stats()["filesystem"]
+	if stats.Operations != 2 || stats.Errors != 1 || stats.Bytes == 0 || stats.LastUsed == nil {
+		t.Errorf("Unexpected filesystem stats %+v", stats)
+	}
+
+	var health struct {
+		Status string            `json:"status"`
+		Sinks  map[string]string `json:"sinks"`
+	}
+	rec := get("/health")
+	json.Unmarshal(rec.Body.Bytes(), &health)
+	if rec.Code != http.StatusOK || health.Status != "healthy" || health.Sinks["filesystem"] != "ok" {
+		t.Errorf("Unexpected health %d %s", rec.Code, rec.Body.String())
+	}
+
+	var detail struct {
+		Sinks map[string]struct {
+			Health     string `json:"health"`
+			Operations int64  `json:"operations"`
+			Errors     int64  `json:"errors"`
+		} `json:"sinks"`
+	}
+	json.Unmarshal(get("/health/sinks").Body.Bytes(), &detail)
+	if fs := detail.Sinks["filesystem"]; fs.Health != "ok" || fs.Operations != 2 || fs.Errors != 1 {
+		t.Errorf("Unexpected sink detail %+v", detail.Sinks)
+	}
+}
@@ -0,0 +1,319 @@
+package builder
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+	"github.com/RIZZZIOM/FlawFactory/server"
+	"github.com/RIZZZIOM/FlawFactory/tracing"
+)
+
+// maxTracedValue bounds the payloads and statements recorded on spans
+const maxTracedValue = 1000
+
+// traceExtraction ends the span of an input extraction
+func traceExtraction(span *tracing.Span, placement, param, input string, err error) {
+	if span == nil {
+		return
+	}
+	span.Set("flawfactory.placement", placement)
+	span.Set("flawfactory.param", param)
+	span.Set("flawfactory.input.length", len(input))
+	span.SetError(err)
+	span.End()
+}
+
+// traceModule ends the span of a module run with what it found
+func traceModule(span *tracing.Span, vuln config.VulnerabilityConfig, result *server.ModuleResult) {
+	span.Set("flawfactory.module", vuln.Type)
+	span.Set("flawfactory.placement", result.Placement)
+	span.Set("flawfactory.param", result.Param)
+	span.Set("flawfactory.input", truncate(result.Input, maxTracedValue))
+	if data := exploitableData(*result); data != nil {
+		span.Set("flawfactory.exploitable", true)
+		span.Set("flawfactory.attack_type", attackType(vuln.Type, data))
+	}
+	if result.Error != "" {
+		span.Fail(result.Error)
+	}
+	span.End()
+}
+
+// traceSinks wraps the module's sinks so each operation is a span under the
+// module's, in ctx. Plugin sinks, whose interfaces are their own, aren't traced.
+func traceSinks(ctx context.Context, sinks *modules.SinkContext) {
+	if sinks.SQLite != nil {
+		sinks.SQLite = &tracedSQLite{sinks.SQLite, ctx}
+	}
+	if sinks.Filesystem != nil {
+		sinks.Filesystem = &tracedFilesystem{sinks.Filesystem, ctx}
+	}
+	if sinks.Command != nil {
+		sinks.Command = &tracedCommand{sinks.Command, ctx}
+	}
+	if sinks.HTTP != nil {
+		sinks.HTTP = &tracedHTTP{sinks.HTTP, ctx}
+	}
+	if sinks.Redis != nil {
+		sinks.Redis = &tracedRedis{sinks.Redis, ctx}
+	}
+	if sinks.Documents != nil {
+		sinks.Documents = &tracedDocuments{sinks.Documents, ctx}
+	}
+	if sinks.LDAP != nil {
+		sinks.LDAP = &tracedLDAP{sinks.LDAP, ctx}
+	}
+	if sinks.Mail != nil {
+		sinks.Mail = &tracedMail{sinks.Mail, ctx}
+	}
+	if sinks.OOB != nil {
+		sinks.OOB = &tracedOOB{sinks.OOB, ctx}
+	}
+	if sinks.Template != nil {
+		sinks.Template = &tracedTemplate{sinks.Template, ctx}
+	}
+}
+
+// startSink starts the span of a sink operation, e.g. "sqlite query"
+func startSink(ctx context.Context, sink, operation string, kind tracing.SpanKind) *tracing.Span {
+	_, span := tracing.Start(ctx, sink+" "+operation, kind)
+	span.Set("flawfactory.sink", sink)
+	return span
+}
+
+// endSink ends the span of a sink operation
+func endSink(span *tracing.Span, err error) {
+	span.SetError(err)
+	span.End()
+}
+
+type tracedSQLite struct {
+	next modules.SQLiteSink
+	ctx  context.Context
+}
+
+func (t *tracedSQLite) Query(query string) ([]map[string]interface{}, error) {
+	span := startSink(t.ctx, "sqlite", "query", tracing.KindClient)
+	span.Set("db.system", "sqlite")
+	span.Set("db.query.text", truncate(query, maxTracedValue))
+	rows, err := t.next.Query(query)
+	span.Set("db.response.returned_rows", len(rows))
+	endSink(span, err)
+	return rows, err
+}
+
+func (t *tracedSQLite) Exec(statement string) error {
+	span := startSink(t.ctx, "sqlite", "exec", tracing.KindClient)
+	span.Set("db.system", "sqlite")
+	span.Set("db.query.text", truncate(statement, maxTracedValue))
+	err := t.next.Exec(statement)
+	endSink(span, err)
+	return err
+}
+
+func (t *tracedSQLite) ExecArgs(statement string, args ...interface{}) (int64, error) {
+	span := startSink(t.ctx, "sqlite", "exec", tracing.KindClient)
+	span.Set("db.system", "sqlite")
+	span.Set("db.query.text", truncate(statement, maxTracedValue))
+	id, err := t.next.ExecArgs(statement, args...)
+	endSink(span, err)
+	return id, err
+}
+
+type tracedFilesystem struct {
+	next modules.FilesystemSink
+	ctx  context.Context
+}
+
+func (t *tracedFilesystem) Read(path string) (string, error) {
+	span := startSink(t.ctx, "filesystem", "read", tracing.KindInternal)
+	span.Set("file.path", path)
+	content, err := t.next.Read(path)
+	span.Set("file.size", len(content))
+	endSink(span, err)
+	return content, err
+}
+
+func (t *tracedFilesystem) Exists(path string) bool {
+	return t.next.Exists(path)
+}
+
+func (t *tracedFilesystem) BasePath() string {
+	return t.next.BasePath()
+}
+
+type tracedCommand struct {
+	next modules.CommandSink
+	ctx  context.Context
+}
+
+func (t *tracedCommand) Execute(command string) (string, error) {
+	span := startSink(t.ctx, "command", "execute", tracing.KindInternal)
+	span.Set("process.command_line", truncate(command, maxTracedValue))
+	output, err := t.next.Execute(command)
+	span.Set("flawfactory.output.length", len(output))
+	endSink(span, err)
+	return output, err
+}
+
+type tracedHTTP struct {
+	next modules.HTTPSink
+	ctx  context.Context
+}
+
+func (t *tracedHTTP) Fetch(url string) (*modules.HTTPResponse, error) {
+	span := t.start("GET", url)
+	resp, err := t.next.Fetch(url)
+	t.end(span, resp, err)
+	return resp, err
+}
+
+func (t *tracedHTTP) FetchWithOptions(url string, opts modules.HTTPOptions) (*modules.HTTPResponse, error) {
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = "GET"
+	}
+	span := t.start(method, url)
+	resp, err := t.next.FetchWithOptions(url, opts)
+	t.end(span, resp, err)
+	return resp, err
+}
+
+// start starts the span of a request
+func (t *tracedHTTP) start(method, url string) *tracing.Span {
+	span := startSink(t.ctx, "http", method, tracing.KindClient)
+	span.Set("http.request.method", method)
+	span.Set("url.full", truncate(url, maxTracedValue))
+	return span
+}
+
+// end ends the span of a request with its response
+func (t *tracedHTTP) end(span *tracing.Span, resp *modules.HTTPResponse, err error) {
+	if resp != nil {
+		span.Set("http.response.status_code", resp.StatusCode)
+	}
+	endSink(span, err)
+}
+
+type tracedRedis struct {
+	next modules.RedisSink
+	ctx  context.Context
+}
+
+func (t *tracedRedis) Execute(command string) (interface{}, error) {
+	span := startSink(t.ctx, "redis", "execute", tracing.KindClient)
+	span.Set("db.system", "redis")
+	span.Set("db.query.text", truncate(command, maxTracedValue))
+	reply, err := t.next.Execute(command)
+	endSink(span, err)
+	return reply, err
+}
+
+type tracedDocuments struct {
+	next modules.DocumentSink
+	ctx  context.Context
+}
+
+// start starts the span of an operation on a collection
+func (t *tracedDocuments) start(operation, collection string) *tracing.Span {
+	span := startSink(t.ctx, "documents", operation, tracing.KindClient)
+	span.Set("db.system", "mongodb")
+	span.Set("db.collection.name", collection)
+	return span
+}
+
+func (t *tracedDocuments) Find(collection string, filter map[string]interface{}) ([]map[string]interface{}, error) {
+	span := t.start("find", collection)
+	docs, err := t.next.Find(collection, filter)
+	span.Set("db.response.returned_rows", len(docs))
+	endSink(span, err)
+	return docs, err
+}
+
+func (t *tracedDocuments) Insert(collection string, doc map[string]interface{}) (interface{}, error) {
+	span := t.start("insert", collection)
+	id, err := t.next.Insert(collection, doc)
+	endSink(span, err)
+	return id, err
+}
+
+func (t *tracedDocuments) Update(collection string, filter, update map[string]interface{}, multi bool) (int, error) {
+	span := t.start("update", collection)
+	n, err := t.next.Update(collection, filter, update, multi)
+	endSink(span, err)
+	return n, err
+}
+
+func (t *tracedDocuments) Delete(collection string, filter map[string]interface{}, multi bool) (int, error) {
+	span := t.start("delete", collection)
+	n, err := t.next.Delete(collection, filter, multi)
+	endSink(span, err)
+	return n, err
+}
+
+type tracedLDAP struct {
+	next modules.LDAPSink
+	ctx  context.Context
+}
+
+func (t *tracedLDAP) Search(baseDN, scope, filter string) ([]modules.LDAPEntry, error) {
+	span := startSink(t.ctx, "ldap", "search", tracing.KindClient)
+	span.Set("flawfactory.ldap.base_dn", baseDN)
+	span.Set("flawfactory.ldap.filter", truncate(filter, maxTracedValue))
+	entries, err := t.next.Search(baseDN, scope, filter)
+	span.Set("flawfactory.ldap.entries", len(entries))
+	endSink(span, err)
+	return entries, err
+}
+
+func (t *tracedLDAP) BaseDN() string {
+	return t.next.BaseDN()
+}
+
+type tracedMail struct {
+	next modules.MailSink
+	ctx  context.Context
+}
+
+func (t *tracedMail) Send(from string, to []string, message string) (int, error) {
+	span := startSink(t.ctx, "mail", "send", tracing.KindClient)
+	span.Set("flawfactory.mail.from", from)
+	span.Set("flawfactory.mail.recipients", len(to))
+	id, err := t.next.Send(from, to, message)
+	endSink(span, err)
+	return id, err
+}
+
+type tracedOOB struct {
+	next modules.OOBSink
+	ctx  context.Context
+}
+
+func (t *tracedOOB) Hosts() []string {
+	return t.next.Hosts()
+}
+
+func (t *tracedOOB) Lookup(name string) bool {
+	span := startSink(t.ctx, "oob", "lookup", tracing.KindClient)
+	span.Set("dns.question.name", name)
+	ok := t.next.Lookup(name)
+	span.Set("flawfactory.oob.matched", ok)
+	endSink(span, nil)
+	return ok
+}
+
+type tracedTemplate struct {
+	next modules.TemplateSink
+	ctx  context.Context
+}
+
+func (t *tracedTemplate) Render(engine, source string, data map[string]interface{}, unsafe func(modules.UnsafeExpression) string) (string, error) {
+	span := startSink(t.ctx, "template", "render", tracing.KindInternal)
+	span.Set("flawfactory.template.engine", engine)
+	span.Set("flawfactory.template.source", truncate(source, maxTracedValue))
+	output, err := t.next.Render(engine, source, data, unsafe)
+	endSink(span, err)
+	return output, err
+}
@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// otlpSpan is the part of an exported span the tests check
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	} `json:"attributes"`
+}
+
+// attribute returns the value of one of the span's attributes
+func (s otlpSpan) attribute(key string) interface{} {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			for _, v := range attr.Value {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// TestTracing tests a request is traced from the router through extraction
+// and the module down to the sink, and exported to the collector
+func TestTracing(t *testing.T) {
+	var mu sync.Mutex
+	var spans []otlpSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var export struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/v1/traces" || json.Unmarshal(body, &export) != nil {
+			t.Errorf("Unexpected export to %s: %s", r.URL.Path, body)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, resource := range export.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				spans = append(spans, scope.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	cfg := reloadConfig("/file", "v1")
+	cfg.App.Tracing = &config.TracingConfig{Endpoint: collector.URL}
+	b := New(cfg, "")
+	srv, err := b.Build()
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	defer b.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/file?name=notes.txt", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.Router().ServeHTTP(httptest.NewRecorder(), req)
+	srv.Stop(context.Background()) // Flushes the exporter
+
+	byName := make(map[string]otlpSpan)
+	for _, span := range spans {
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Expected %s to continue the caller's trace, got %s", span.Name, span.TraceID)
+		}
+		byName[span.Name] = span
+	}
+	root, extract, module, sink := byName["GET /file"], byName["extract query_param"], byName["module path_traversal"], byName["filesystem read"]
+	if root.ParentSpanID != "00f067aa0ba902b7" || root.attribute("http.response.status_code") != "200" {
+		t.Fatalf("Expected the request's span under the caller's, got %+v", spans)
+	}
+	if extract.ParentSpanID != root.SpanID || module.ParentSpanID != root.SpanID || sink.ParentSpanID != module.SpanID {
+		t.Errorf("Expected request > extract, module > sink, got %+v", spans)
+	}
+	if module.attribute("flawfactory.input") != "notes.txt" || sink.attribute("file.path") != "notes.txt" {
+		t.Errorf("Expected the payload on the module and sink spans, got %+v and %+v", module, sink)
+	}
+}
@@ -0,0 +1,231 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// uiButtons labels the submit button of each page type
+var uiButtons = map[string]string{
+	"login":    "Log in",
+	"search":   "Search",
+	"comments": "Post",
+}
+
+// uiTemplate lays out the UI's pages: the navigation, then either the index or
+// a form whose response is shown in the frame below it
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Heading}} - {{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 0; color: #222; }
+nav { background: #2b3a4a; padding: 0.6em 1em; }
+nav a { color: #fff; margin-right: 1.2em; text-decoration: none; }
+nav a.brand { font-weight: bold; }
+main { padding: 1em 2em; max-width: 60em; }
+label { display: block; margin: 0.6em 0 0.2em; }
+input, textarea { width: 24em; padding: 0.3em; }
+textarea { height: 6em; }
+button { margin-top: 0.8em; padding: 0.4em 1.2em; }
+iframe { width: 100%; height: 18em; border: 1px solid #ccc; margin-top: 1em; }
+table { border-collapse: collapse; }
+td, th { border-bottom: 1px solid #ddd; padding: 0.3em 1em 0.3em 0; text-align: left; }
+</style>
+</head>
+<body>
+<nav><a class="brand" href="{{.Index}}">{{.Title}}</a>{{range .Nav}}<a href="{{.Path}}">{{.Label}}</a>{{end}}</nav>
+<main>
+<h1>{{.Heading}}</h1>
+{{- if .Form}}
+<form method="{{.Form.Method}}" action="{{.Form.Action}}" target="result">
+{{- range .Form.Fields}}
+<label for="{{.Name}}">{{.Label}}</label>
+{{- if eq .Input "textarea"}}
+<textarea id="{{.Name}}" name="{{.Name}}"></textarea>
+{{- else}}
+<input id="{{.Name}}" name="{{.Name}}" type="{{.Input}}">
+{{- end}}
+{{- end}}
+<br><button type="submit">{{.Form.Button}}</button>
+</form>
+<iframe name="result" title="Response"{{if .List}} onload="document.getElementById('list').contentWindow.location.reload()"{{end}}></iframe>
+{{- if .List}}
+<h2>Posted</h2>
+<iframe id="list" src="{{.List}}" title="Posted"></iframe>
+{{- end}}
+{{- else}}
+{{- with .Description}}<p>{{.}}</p>{{end}}
+<h2>Endpoints</h2>
+<table>
+<tr><th>Method</th><th>Path</th><th>Vulnerabilities</th></tr>
+{{- range .Endpoints}}
+<tr><td>{{.Method}}</td><td>{{if .Link}}<a href="{{.Path}}">{{.Path}}</a>{{else}}{{.Path}}{{end}}</td><td>{{.Vulnerabilities}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// uiPage is the data a UI page is rendered from
+type uiPage struct {
+	Title       string
+	Heading     string
+	Index       string
+	Nav         []uiLink
+	Form        *uiForm
+	List        string              // Comments: path of the endpoint listing them
+	Description string              // Index: the app's description
+	Endpoints   []uiEndpointSummary // Index: every configured endpoint
+}
+
+// uiLink is an entry of the navigation
+type uiLink struct {
+	Label string
+	Path  string
+}
+
+// uiForm is a page's form and the endpoint it submits to
+type uiForm struct {
+	Method string
+	Action string
+	Fields []uiField
+	Button string
+}
+
+// uiField is one input of a form
+type uiField struct {
+	Name  string
+	Label string
+	Input string // text, password or textarea
+}
+
+// uiEndpointSummary is an endpoint listed on the index page
+type uiEndpointSummary struct {
+	Method          string
+	Path            string
+	Link            bool // GET endpoints a browser can open as they are
+	Vulnerabilities string
+}
+
+// registerUIPages serves the index and pages of the ui section. Pages aren't
+// behind the login wall, so the login page stays reachable.
+func (b *Builder) registerUIPages(router *server.Router) error {
+	ui := b.config.UI
+	if ui == nil {
+		return nil
+	}
+
+	title := ui.Title
+	if title == "" {
+		title = b.config.App.Name
+	}
+	nav := make([]uiLink, len(ui.Pages))
+	for i, page := range ui.Pages {
+		nav[i] = uiLink{Label: page.PageTitle(), Path: ui.PagePath(page)}
+	}
+
+	index := uiPage{
+		Title:       title,
+		Heading:     "Home",
+		Index:       ui.IndexPath(),
+		Nav:         nav,
+		Description: b.config.App.Description,
+		Endpoints:   uiEndpointSummaries(b.config.Endpoints),
+	}
+	if err := servePage(router, ui.IndexPath(), index); err != nil {
+		return err
+	}
+
+	for _, page := range ui.Pages {
+		endpoint, ok := page.FormEndpoint(b.config.Endpoints, b.config.Auth)
+		if !ok {
+			return fmt.Errorf("ui page %s: no endpoint matches '%s'", ui.PagePath(page), page.Endpoint)
+		}
+		data := uiPage{
+			Title:   title,
+			Heading: page.PageTitle(),
+			Index:   ui.IndexPath(),
+			Nav:     nav,
+			Form:    newUIForm(page, endpoint),
+		}
+		if list, ok := page.ListEndpoint(b.config.Endpoints); ok {
+			data.List = list.Path
+		}
+		if err := servePage(router, ui.PagePath(page), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// servePage renders a page once and serves it at path
+func servePage(router *server.Router, path string, page uiPage) error {
+	var buf bytes.Buffer
+	if err := uiTemplate.Execute(&buf, page); err != nil {
+		return fmt.Errorf("failed to render ui page %s: %w", path, err)
+	}
+	html := buf.Bytes()
+
+	if path == "/" {
+		path = "/{$}" // Only the root, not every unmatched path
+	}
+	router.HandleFunc("GET", path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	})
+	return nil
+}
+
+// newUIForm builds a page's form. A comments page's last field is the comment
+// itself, so it gets a text area.
+func newUIForm(page config.UIPageConfig, endpoint config.EndpointConfig) *uiForm {
+	names := page.FormFields(endpoint)
+	form := &uiForm{
+		Method: strings.ToLower(endpoint.Method),
+		Action: endpoint.Path,
+		Fields: make([]uiField, len(names)),
+		Button: uiButtons[page.Type],
+	}
+	for i, name := range names {
+		input := "text"
+		switch {
+		case strings.Contains(strings.ToLower(name), "password"):
+			input = "password"
+		case page.Type == "comments" && i == len(names)-1:
+			input = "textarea"
+		}
+		label := strings.ReplaceAll(name, "_", " ")
+		form.Fields[i] = uiField{Name: name, Label: strings.ToUpper(label[:1]) + label[1:], Input: input}
+	}
+	return form
+}
+
+// uiEndpointSummaries lists the endpoints for the index page
+func uiEndpointSummaries(endpoints []config.EndpointConfig) []uiEndpointSummary {
+	summaries := make([]uiEndpointSummary, len(endpoints))
+	for i, endpoint := range endpoints {
+		_, params, err := endpoint.Route()
+		var types []string
+		for _, vuln := range endpoint.Vulnerabilities {
+			types = append(types, vuln.Type)
+		}
+		summaries[i] = uiEndpointSummary{
+			Method:          strings.ToUpper(endpoint.Method),
+			Path:            endpoint.Host + endpoint.Path,
+			Link:            strings.EqualFold(endpoint.Method, "GET") && endpoint.Host == "" && !endpoint.WebSocket && err == nil && len(params) == 0,
+			Vulnerabilities: strings.Join(types, ", "),
+		}
+	}
+	return summaries
+}
@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/server"
+)
+
+// TestRegisterUIPages tests the index lists the endpoints and each page's form
+// submits the params its endpoint reads
+func TestRegisterUIPages(t *testing.T) {
+	b := New(&config.Config{
+		App: config.AppConfig{Name: "shop", Description: "A vulnerable shop"},
+		Endpoints: []config.EndpointConfig{
+			{Path: "/search", Method: "GET", Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xss_reflected", Placement: "query_param", Param: "q"},
+			}},
+			{Path: "/comments", Method: "POST", Vulnerabilities: []config.VulnerabilityConfig{
+				{Type: "xss_reflected", Placement: "form_field", Params: []string{"author", "body"}},
+			}},
+			{Path: "/comments", Method: "GET"},
+			{Path: "/users/{id}", Method: "GET"},
+		},
+		Auth: &config.AuthConfig{Type: "session"},
+		UI: &config.UIConfig{
+			Title: "Acme <Shop>",
+			Pages: []config.UIPageConfig{
+				{Type: "login"},
+				{Type: "search", Endpoint: "GET /search"},
+				{Type: "comments", Endpoint: "POST /comments", List: "GET /comments"},
+			},
+		},
+	}, "")
+
+	router := server.NewRouter(nil)
+	if err := b.registerUIPages(router); err != nil {
+		t.Fatalf("Failed to register UI pages: %v", err)
+	}
+
+	tests := map[string][]string{
+		"/ui": {
+			"Acme &lt;Shop&gt;",
+			"<p>A vulnerable shop</p>",
+			`<a href="/search">/search</a>`,
+			"<td>/users/{id}</td>",
+			`<a href="/ui/comments">Comments</a>`,
+		},
+		"/ui/login": {
+			`<form method="post" action="/login" target="result">`,
+			`<input id="username" name="username" type="text">`,
+			`<input id="password" name="password" type="password">`,
+		},
+		"/ui/search": {
+			`<form method="get" action="/search" target="result">`,
+			`name="q" type="text"`,
+		},
+		"/ui/comments": {
+			`<form method="post" action="/comments" target="result">`,
+			`<input id="author" name="author" type="text">`,
+			`<textarea id="body" name="body"></textarea>`,
+			`<iframe id="list" src="/comments"`,
+		},
+	}
+	for path, expected := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("%s: expected an HTML page, got %d %s", path, rec.Code, rec.Header().Get("Content-Type"))
+			continue
+		}
+		for _, s := range expected {
+			if !strings.Contains(rec.Body.String(), s) {
+				t.Errorf("%s: expected page to contain %s, got:\n%s", path, s, rec.Body.String())
+			}
+		}
+	}
+}
+
+// TestRegisterUIPages_Root tests a UI at / only answers the root path
+func TestRegisterUIPages_Root(t *testing.T) {
+	b := New(&config.Config{App: config.AppConfig{Name: "lab"}, UI: &config.UIConfig{Path: "/"}}, "")
+	router := server.NewRouter(nil)
+	if err := b.registerUIPages(router); err != nil {
+		t.Fatalf("Failed to register UI pages: %v", err)
+	}
+
+	for path, status := range map[string]int{"/": http.StatusOK, "/missing": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != status {
+			t.Errorf("%s: expected %d, got %d", path, status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// adminPortOffset places the admin listener beside the app's by default
+const adminPortOffset = 1000
+
+// Address returns the host:port the admin API listens on
+func (a *AdminConfig) Address(appPort int) string {
+	host := a.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	port := a.Port
+	if port == 0 {
+		port = appPort + adminPortOffset
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// validateAdmin validates the admin listener against the app's own ports
+func validateAdmin(admin *AdminConfig, app AppConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if admin.Token == "" {
+		errs = append(errs, ValidationError{
+			Field:   "app.admin.token",
+			Message: "a token is required to protect the admin API",
+		})
+	}
+
+	port := admin.Port
+	if port == 0 {
+		port = app.Port + adminPortOffset
+	}
+	switch {
+	case port < 1 || port > 65535:
+		errs = append(errs, ValidationError{
+			Field:   "app.admin.port",
+			Message: fmt.Sprintf("admin port must be between 1 and 65535, got %d", port),
+		})
+	case port == app.Port || (app.TLS != nil && app.TLS.Enabled && port == app.TLS.RedirectPort):
+		errs = append(errs, ValidationError{
+			Field:   "app.admin.port",
+			Message: fmt.Sprintf("admin port %d is already used by the app", port),
+		})
+	}
+
+	return errs
+}
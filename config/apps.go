@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Applications returns the applications a config defines: each entry of apps,
+// or the config itself when it defines a single app. Each is a standalone
+// config that is built and served on its own.
+func (c *Config) Applications() []*Config {
+	if len(c.Apps) == 0 {
+		return []*Config{c}
+	}
+
+	apps := make([]*Config, len(c.Apps))
+	for i, app := range c.Apps {
+		apps[i] = &Config{
+			App:       app.App,
+			Data:      app.Data,
+			Files:     app.Files,
+			Endpoints: app.Endpoints,
+			Chains:    app.Chains,
+			Auth:      app.Auth,
+			Flags:     app.Flags,
+			UI:        app.UI,
+			Static:    app.Static,
+			Sources:   c.Sources,
+		}
+	}
+	return apps
+}
+
+// validateApps validates a multi-app config, each app with validate, and
+// checks that apps have distinct names and ports
+func validateApps(cfg *Config, validate func(*Config) *ValidationResult) *ValidationResult {
+	result := &ValidationResult{}
+
+	// The single-app sections would be silently ignored next to apps
+	single := map[string]bool{
+		"app":       !reflect.DeepEqual(cfg.App, AppConfig{}),
+		"data":      cfg.Data != nil,
+		"files":     len(cfg.Files) > 0,
+		"endpoints": len(cfg.Endpoints) > 0,
+		"chains":    len(cfg.Chains) > 0,
+		"auth":      cfg.Auth != nil,
+		"flags":     len(cfg.Flags) > 0,
+		"ui":        cfg.UI != nil,
+		"static":    len(cfg.Static) > 0,
+	}
+	for _, section := range []string{"app", "data", "files", "endpoints", "chains", "auth", "flags", "ui", "static"} {
+		if single[section] {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   section,
+				Message: fmt.Sprintf("%s can't be used together with apps, move it into an entry of apps", section),
+			})
+		}
+	}
+
+	names := make(map[string]int)
+	ports := make(map[int]int)
+	for i, app := range cfg.Applications() {
+		prefix := fmt.Sprintf("apps[%d]", i)
+
+		appResult := validate(app)
+		for _, err := range appResult.Errors {
+			err.Field = prefix + "." + err.Field
+			result.Errors = append(result.Errors, err)
+		}
+		for _, warn := range appResult.Warnings {
+			warn.Field = prefix + "." + warn.Field
+			result.Warnings = append(result.Warnings, warn)
+		}
+
+		if prev, exists := names[app.App.Name]; exists && app.App.Name != "" {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   prefix + ".app.name",
+				Message: fmt.Sprintf("duplicate app name '%s' (previously used by apps[%d])", app.App.Name, prev),
+			})
+		} else {
+			names[app.App.Name] = i
+		}
+
+		if prev, exists := ports[app.App.Port]; exists && app.App.Port != 0 {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   prefix + ".app.port",
+				Message: fmt.Sprintf("port %d is already used by apps[%d]", app.App.Port, prev),
+			})
+		} else {
+			ports[app.App.Port] = i
+		}
+	}
+
+	return result
+}
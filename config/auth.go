@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Defaults for the optional auth settings
+const (
+	DefaultAuthTable  = "auth_users"
+	DefaultLoginPath  = "/login"
+	DefaultAuthCookie = "session"
+	DefaultAuthHeader = "X-API-Key"
+)
+
+// validAuthTypes lists the supported login walls
+var validAuthTypes = []string{"basic", "session", "bearer", "api_key"}
+
+// sqlIdentifierPattern matches table names that are safe to use unquoted in SQL
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// TableName returns the table holding the users
+func (a *AuthConfig) TableName() string {
+	if a.Table == "" {
+		return DefaultAuthTable
+	}
+	return a.Table
+}
+
+// LoginRoute returns the path credentials are posted to, for session and bearer auth
+func (a *AuthConfig) LoginRoute() string {
+	if a.LoginPath == "" {
+		return DefaultLoginPath
+	}
+	return a.LoginPath
+}
+
+// CookieName returns the session cookie's name
+func (a *AuthConfig) CookieName() string {
+	if a.Cookie == "" {
+		return DefaultAuthCookie
+	}
+	return a.Cookie
+}
+
+// HeaderName returns the header carrying the API key
+func (a *AuthConfig) HeaderName() string {
+	if a.Header == "" {
+		return DefaultAuthHeader
+	}
+	return a.Header
+}
+
+// Protects reports whether the endpoint is behind the login wall
+func (a *AuthConfig) Protects(endpoint EndpointConfig) bool {
+	if a == nil {
+		return false
+	}
+	if a.Protect == "listed" {
+		return endpoint.Auth == "required"
+	}
+	return endpoint.Auth != "none"
+}
+
+// hasLogin reports whether the auth type issues credentials from a login endpoint
+func (a *AuthConfig) hasLogin() bool {
+	return a.Type == "session" || a.Type == "bearer"
+}
+
+// validateAuth validates the auth section and each endpoint's auth setting
+func validateAuth(auth *AuthConfig, endpoints []EndpointConfig, data *DataConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	for i, endpoint := range endpoints {
+		field := fmt.Sprintf("endpoints[%d].auth", i)
+		switch {
+		case endpoint.Auth != "" && endpoint.Auth != "required" && endpoint.Auth != "none":
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("invalid auth '%s', must be required or none", endpoint.Auth),
+			})
+		case endpoint.Auth != "" && auth == nil:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "auth is set but there is no auth section",
+			})
+		case auth != nil && auth.hasLogin() && endpoint.Host == "" && endpoint.Path == auth.LoginRoute() && strings.EqualFold(endpoint.Method, "POST"):
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("endpoints[%d].path", i),
+				Message: fmt.Sprintf("POST %s is the login endpoint; set auth.login_path to move it", endpoint.Path),
+			})
+		}
+	}
+
+	if auth == nil {
+		return errs
+	}
+
+	if !slices.Contains(validAuthTypes, auth.Type) {
+		errs = append(errs, ValidationError{
+			Field:   "auth.type",
+			Message: fmt.Sprintf("invalid auth type '%s', must be one of: %s", auth.Type, strings.Join(validAuthTypes, ", ")),
+		})
+	}
+	if auth.Protect != "" && auth.Protect != "all" && auth.Protect != "listed" {
+		errs = append(errs, ValidationError{
+			Field:   "auth.protect",
+			Message: fmt.Sprintf("invalid protect '%s', must be all or listed", auth.Protect),
+		})
+	}
+	if !sqlIdentifierPattern.MatchString(auth.TableName()) {
+		errs = append(errs, ValidationError{
+			Field:   "auth.table",
+			Message: fmt.Sprintf("invalid table name '%s'", auth.Table),
+		})
+	}
+	if auth.hasLogin() && !strings.HasPrefix(auth.LoginRoute(), "/") {
+		errs = append(errs, ValidationError{
+			Field:   "auth.login_path",
+			Message: "login_path must start with /",
+		})
+	}
+
+	// Users are seeded into their own table, or read from a data table
+	var table *TableConfig
+	if data != nil {
+		if t, ok := data.Tables[auth.TableName()]; ok {
+			table = &t
+		}
+	}
+	switch {
+	case len(auth.Users) > 0 && table != nil:
+		errs = append(errs, ValidationError{
+			Field:   "auth.users",
+			Message: fmt.Sprintf("table '%s' is already defined in data.tables; drop users to authenticate against its rows", auth.TableName()),
+		})
+	case len(auth.Users) > 0:
+		errs = append(errs, validateAuthUsers(auth)...)
+	case table == nil:
+		errs = append(errs, ValidationError{
+			Field:   "auth.users",
+			Message: fmt.Sprintf("users are required unless table names one of data.tables (no table '%s')", auth.TableName()),
+		})
+	default:
+		for _, column := range authColumns(auth.Type) {
+			if !slices.Contains(table.Columns, column) {
+				errs = append(errs, ValidationError{
+					Field:   "auth.table",
+					Message: fmt.Sprintf("table '%s' needs a %s column for %s auth", auth.TableName(), column, auth.Type),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// authColumns returns the columns a data table needs to serve as the auth table
+func authColumns(authType string) []string {
+	if authType == "api_key" {
+		return []string{"username", "token"}
+	}
+	return []string{"username", "password"}
+}
+
+// validateAuthUsers checks that each user can authenticate with the auth type
+func validateAuthUsers(auth *AuthConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	usernames := make(map[string]int)
+	tokens := make(map[string]int)
+	for i, user := range auth.Users {
+		prefix := fmt.Sprintf("auth.users[%d]", i)
+
+		if user.Username == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".username",
+				Message: "username is required",
+			})
+		} else if prev, exists := usernames[user.Username]; exists {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".username",
+				Message: fmt.Sprintf("duplicate user '%s' (previously defined at index %d)", user.Username, prev),
+			})
+		} else {
+			usernames[user.Username] = i
+		}
+
+		if user.Token != "" {
+			if prev, exists := tokens[user.Token]; exists {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".token",
+					Message: fmt.Sprintf("token is already used by users[%d]", prev),
+				})
+			} else {
+				tokens[user.Token] = i
+			}
+		}
+
+		switch {
+		case auth.Type == "api_key" && user.Token == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".token",
+				Message: "token is required for api_key auth",
+			})
+		case auth.Type == "bearer" && user.Password == "" && user.Token == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".password",
+				Message: "password or token is required for bearer auth",
+			})
+		case (auth.Type == "basic" || auth.Type == "session") && user.Password == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".password",
+				Message: fmt.Sprintf("password is required for %s auth", auth.Type),
+			})
+		}
+	}
+
+	return errs
+}
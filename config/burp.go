@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// burpItems is a list of requests in the XML Burp Suite saves items as, which
+// site map importers load
+type burpItems struct {
+	XMLName     xml.Name   `xml:"items"`
+	BurpVersion string     `xml:"burpVersion,attr"`
+	ExportTime  string     `xml:"exportTime,attr"`
+	Items       []burpItem `xml:"item"`
+}
+
+// burpItem is one request, without a response
+type burpItem struct {
+	Time           string      `xml:"time"`
+	URL            string      `xml:"url"`
+	Host           burpHost    `xml:"host"`
+	Port           int         `xml:"port"`
+	Protocol       string      `xml:"protocol"`
+	Method         string      `xml:"method"`
+	Path           string      `xml:"path"`
+	Extension      string      `xml:"extension"`
+	Request        burpMessage `xml:"request"`
+	Status         string      `xml:"status"`
+	ResponseLength string      `xml:"responselength"`
+	MimeType       string      `xml:"mimetype"`
+	Response       burpMessage `xml:"response"`
+	Comment        string      `xml:"comment"`
+}
+
+// burpHost is the host of an item and the address it resolved to
+type burpHost struct {
+	IP   string `xml:"ip,attr"`
+	Name string `xml:",chardata"`
+}
+
+// burpMessage is a raw HTTP message, base64 encoded
+type burpMessage struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+// burpTimeFormat is the layout of Burp's item times
+const burpTimeFormat = "Mon Jan 02 15:04:05 MST 2006"
+
+// BurpItems returns an app's example requests in the XML Burp Suite saves
+// items as, each commented with what it tries. Protected requests carry the
+// first user's credentials, or a <token> to replace; behind a session login,
+// send the login request first.
+func BurpItems(cfg *Config, exported time.Time) ([]byte, error) {
+	scheme, host := serverAddress(cfg)
+	ip := "127.0.0.1"
+	if parsed := net.ParseIP(host); parsed != nil {
+		ip = parsed.String()
+	}
+	hostPort := fmt.Sprintf("%s:%d", host, cfg.App.Port)
+	if strings.Contains(host, ":") {
+		hostPort = fmt.Sprintf("[%s]:%d", host, cfg.App.Port)
+	}
+
+	items := burpItems{BurpVersion: "2024.1", ExportTime: exported.Format(burpTimeFormat)}
+	for _, req := range ExampleRequests(cfg) {
+		header := req.Header
+		if req.Protected {
+			header = append(header, credentialHeader(cfg.Auth))
+		}
+		items.Items = append(items.Items, burpItem{
+			Time:      exported.Format(burpTimeFormat),
+			URL:       scheme + "://" + hostPort + req.Path,
+			Host:      burpHost{IP: ip, Name: host},
+			Port:      cfg.App.Port,
+			Protocol:  scheme,
+			Method:    req.Method,
+			Path:      req.Path,
+			Extension: "null",
+			Request:   burpMessage{Base64: true, Data: base64.StdEncoding.EncodeToString(rawHTTPRequest(req, header, hostPort))},
+			Response:  burpMessage{Base64: true},
+			Comment:   req.Group + ": " + req.Name,
+		})
+	}
+
+	data, err := xml.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// rawHTTPRequest returns a request as it's sent over HTTP/1.1, with a Host
+// header for the app's address unless it has one for a virtual host
+func rawHTTPRequest(req ExampleRequest, header [][2]string, hostPort string) []byte {
+	var raw strings.Builder
+	fmt.Fprintf(&raw, "%s %s HTTP/1.1\r\n", req.Method, req.Path)
+
+	cookies := -1
+	if len(header) == 0 || header[0][0] != "Host" {
+		fmt.Fprintf(&raw, "Host: %s\r\n", hostPort)
+	}
+	for i, h := range header {
+		if h[0] == "Cookie" {
+			if cookies >= 0 {
+				continue // Joined into the first
+			}
+			cookies = i
+			for _, other := range header[i+1:] {
+				if other[0] == "Cookie" {
+					h[1] += "; " + other[1]
+				}
+			}
+		}
+		fmt.Fprintf(&raw, "%s: %s\r\n", h[0], h[1])
+	}
+	if req.Body != "" {
+		fmt.Fprintf(&raw, "Content-Length: %d\r\n", len(req.Body))
+	}
+	raw.WriteString("Connection: close\r\n\r\n")
+	raw.WriteString(req.Body)
+	return []byte(raw.String())
+}
@@ -0,0 +1,63 @@
+package config
+
+// Capture defaults
+const (
+	DefaultCaptureEntries = 1000
+	DefaultCaptureBody    = 1 << 20
+	DefaultCapturePCAP    = 64 << 20
+)
+
+// Entries returns how many request/response pairs are kept
+func (c *CaptureConfig) Entries() int {
+	if c == nil || c.MaxEntries == 0 {
+		return DefaultCaptureEntries
+	}
+	return c.MaxEntries
+}
+
+// BodyLimit returns how many bytes of each body are kept
+func (c *CaptureConfig) BodyLimit() int64 {
+	if c == nil || c.MaxBody == 0 {
+		return DefaultCaptureBody
+	}
+	return c.MaxBody
+}
+
+// PCAPLimit returns how many bytes of packets are kept
+func (c *CaptureConfig) PCAPLimit() int64 {
+	if c == nil || c.MaxPCAP == 0 {
+		return DefaultCapturePCAP
+	}
+	return c.MaxPCAP
+}
+
+// validateCapture validates the traffic capture limits
+func validateCapture(capture *CaptureConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if capture.MaxEntries < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.capture.max_entries",
+			Message: "max_entries cannot be negative",
+		})
+	}
+	if capture.MaxBody < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.capture.max_body",
+			Message: "max_body cannot be negative",
+		})
+	}
+	if capture.MaxPCAP < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.capture.max_pcap",
+			Message: "max_pcap cannot be negative",
+		})
+	} else if capture.MaxPCAP > 0 && !capture.PCAP {
+		errs = append(errs, ValidationError{
+			Field:   "app.capture.max_pcap",
+			Message: "max_pcap needs pcap: true to record packets",
+		})
+	}
+
+	return errs
+}
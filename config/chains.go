@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Targets reports whether the step refers to the endpoint. The method is
+// matched case-insensitively; the rest must equal the endpoint's host and path.
+func (s ChainStepConfig) Targets(endpoint EndpointConfig) bool {
+	return targets(s.Endpoint, endpoint)
+}
+
+// targets reports whether a METHOD /path reference names the endpoint
+func targets(ref string, endpoint EndpointConfig) bool {
+	method, target, ok := strings.Cut(strings.TrimSpace(ref), " ")
+	return ok && strings.EqualFold(method, endpoint.Method) && strings.TrimSpace(target) == endpoint.Host+endpoint.Path
+}
+
+// validateChains validates chain definitions against the endpoints they link
+func validateChains(chains []ChainConfig, endpoints []EndpointConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	// Track unique chain names
+	nameMap := make(map[string]int)
+
+	for i, chain := range chains {
+		prefix := fmt.Sprintf("chains[%d]", i)
+
+		if chain.Name == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.name", prefix),
+				Message: "chain name is required",
+			})
+		} else if prevIndex, exists := nameMap[chain.Name]; exists {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.name", prefix),
+				Message: fmt.Sprintf("duplicate chain '%s' (previously defined at index %d)", chain.Name, prevIndex),
+			})
+		} else {
+			nameMap[chain.Name] = i
+		}
+
+		if len(chain.Steps) < 2 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.steps", prefix),
+				Message: "a chain needs at least two steps",
+			})
+		}
+
+		for j, step := range chain.Steps {
+			stepPrefix := fmt.Sprintf("%s.steps[%d]", prefix, j)
+
+			found := false
+			for _, endpoint := range endpoints {
+				if step.Targets(endpoint) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.endpoint", stepPrefix),
+					Message: fmt.Sprintf("no endpoint matches '%s' (expected METHOD /path)", step.Endpoint),
+				})
+			}
+
+			if step.Proof == "" && j < len(chain.Steps)-1 {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.proof", stepPrefix),
+					Message: "proof is required on every step but the last, to tell when it has been exploited",
+				})
+			}
+		}
+	}
+
+	return errs
+}
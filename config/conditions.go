@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SplitCondition splits a NAME=value condition into its name and value.
+// hasValue is false when only the name is given.
+func SplitCondition(condition string) (name, value string, hasValue bool) {
+	name, value, hasValue = strings.Cut(condition, "=")
+	return strings.TrimSpace(name), value, hasValue
+}
+
+// Window parses the time condition into minutes after midnight. A window whose
+// end is before its start wraps past midnight.
+func (c *ConditionConfig) Window() (start, end int, err error) {
+	from, to, ok := strings.Cut(c.Time, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("time must be a window like 09:00-17:00")
+	}
+	if start, err = clockMinutes(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = clockMinutes(to); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("time window '%s' is empty", c.Time)
+	}
+	return start, end, nil
+}
+
+// clockMinutes parses an HH:MM time of day into minutes after midnight
+func clockMinutes(clock string) (int, error) {
+	clock = strings.TrimSpace(clock)
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s', expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateConditions validates a vulnerability's enabled_if conditions
+func validateConditions(cond *ConditionConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if cond == nil {
+		return errs
+	}
+	if *cond == (ConditionConfig{}) {
+		errs = append(errs, ValidationError{
+			Field:   prefix,
+			Message: "enabled_if needs at least one of: header, cookie, query, time, percent",
+		})
+		return errs
+	}
+
+	named := []struct{ key, condition string }{
+		{"header", cond.Header},
+		{"cookie", cond.Cookie},
+		{"query", cond.Query},
+	}
+	for _, n := range named {
+		if name, _, _ := SplitCondition(n.condition); n.condition != "" && name == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.%s", prefix, n.key),
+				Message: fmt.Sprintf("%s condition needs a name, as NAME=value or NAME", n.key),
+			})
+		}
+	}
+
+	if cond.Time != "" {
+		if _, _, err := cond.Window(); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".time",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	if cond.Percent < 0 || cond.Percent > 100 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".percent",
+			Message: fmt.Sprintf("percent must be between 1 and 100, got %d", cond.Percent),
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// applyDifficulty fills in the module settings each vulnerability's difficulty
+// stands for, keeping any value its config sets. Unknown types and levels are
+// left for the validator to report.
+func applyDifficulty(cfg *Config) {
+	endpoints := [][]EndpointConfig{cfg.Endpoints}
+	for _, app := range cfg.Apps {
+		endpoints = append(endpoints, app.Endpoints)
+	}
+
+	for _, list := range endpoints {
+		for i := range list {
+			for j := range list[i].Vulnerabilities {
+				vuln := &list[i].Vulnerabilities[j]
+				if vuln.Difficulty == "" {
+					continue
+				}
+				values, err := modules.DifficultyConfig(vuln.Type, vuln.Difficulty)
+				if err != nil {
+					continue
+				}
+
+				if vuln.Config == nil {
+					vuln.Config = make(map[string]interface{})
+				}
+				for key, value := range values {
+					if _, set := vuln.Config[key]; !set {
+						vuln.Config[key] = value
+					}
+				}
+			}
+		}
+	}
+}
+
+// validateDifficulty checks a vulnerability's difficulty is a level its module offers
+func validateDifficulty(vuln VulnerabilityConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if vuln.Difficulty == "" {
+		return errs
+	}
+
+	if !slices.Contains(modules.DifficultyLevels, vuln.Difficulty) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".difficulty",
+			Message: fmt.Sprintf("invalid difficulty '%s', must be one of: %s", vuln.Difficulty, strings.Join(modules.DifficultyLevels, ", ")),
+		})
+		return errs
+	}
+
+	// Unknown types are reported elsewhere
+	if !modules.Has(vuln.Type) {
+		return errs
+	}
+	if _, err := modules.DifficultyConfig(vuln.Type, vuln.Difficulty); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".difficulty",
+			Message: err.Error(),
+		})
+	}
+
+	return errs
+}
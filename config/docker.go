@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DockerCompanions are the services export docker can run beside a lab: the
+// OOB listener's HTTP and DNS callback ports, the SMTP listener of the mail
+// capture (read at /mailbox), and a Jaeger collector and UI for app.tracing
+var DockerCompanions = []string{"oob", "mail", "jaeger"}
+
+// Ports the companions listen on in the container, offset by the app's index
+// in multi-app configs
+const (
+	dockerOOBHTTPPort = 8880
+	dockerOOBDNSPort  = 5353
+	dockerSMTPPort    = 2525
+)
+
+// dockerJaegerUI is the Jaeger UI's port
+const dockerJaegerUI = 16686
+
+// dockerfile builds FlawFactory and runs the lab as an unprivileged user, with
+// busybox's sh, ping and nslookup for command injection payloads
+const dockerfile = `# Runs the lab in config.yaml; start it with: docker compose up
+FROM golang:1.24-alpine AS build
+ARG FLAWFACTORY_VERSION=latest
+RUN CGO_ENABLED=0 go install github.com/RIZZZIOM/FlawFactory@${FLAWFACTORY_VERSION}
+
+FROM alpine:3.20
+RUN adduser -D -h /lab lab
+COPY --from=build /go/bin/FlawFactory /usr/local/bin/flawfactory
+WORKDIR /lab
+COPY --chown=lab . .
+RUN mkdir -p %[1]s && chown lab %[1]s
+USER lab
+EXPOSE %[2]s
+ENTRYPOINT ["flawfactory", "run", "-c", "config.yaml"]
+`
+
+// dockerIgnore keeps the generated files the image doesn't need out of it
+const dockerIgnore = "Dockerfile\ncompose.yaml\n.dockerignore\n"
+
+// ComposeFile is a Docker Compose file
+type ComposeFile struct {
+	Services map[string]ComposeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes,omitempty"`
+}
+
+// ComposeService is a service of a Compose file
+type ComposeService struct {
+	Build       string            `yaml:"build,omitempty"`
+	Image       string            `yaml:"image"`
+	Init        bool              `yaml:"init,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+}
+
+// DockerFiles returns the files that run a lab with docker compose up, by
+// name: a Dockerfile building FlawFactory into a small image holding the
+// config, a compose.yaml publishing each of its listeners, and config.yaml,
+// the config resolved and listening on the container's interfaces. The admin
+// API stays reachable from the Docker host only, and TLS files the config
+// names are copied in. Companions, from DockerCompanions, add what they name.
+func DockerFiles(cfg *Config, companions []string) (map[string][]byte, error) {
+	lab, err := containerize(cfg, companions)
+	if err != nil {
+		return nil, err
+	}
+
+	service := ComposeService{
+		Build:   ".",
+		Image:   "flawfactory-" + dockerName(cfg),
+		Init:    true, // Reaps the processes command injection starts
+		Restart: "unless-stopped",
+	}
+	var expose []string
+	for _, p := range lab.ports {
+		port := strconv.Itoa(p.port)
+		mapping := port + ":" + port
+		if p.private {
+			mapping = "127.0.0.1:" + mapping
+		}
+		if p.udp {
+			mapping, port = mapping+"/udp", port+"/udp"
+		}
+		service.Ports = append(service.Ports, mapping)
+		expose = append(expose, port)
+	}
+
+	// The directories the lab writes to are kept in volumes, made from the
+	// image's so the lab user owns them
+	compose := ComposeFile{Services: make(map[string]ComposeService), Volumes: make(map[string]struct{})}
+	var labDirs []string
+	for _, dir := range lab.dirs {
+		target := dir
+		if !filepath.IsAbs(dir) {
+			target = "/lab/" + filepath.ToSlash(dir)
+		}
+		labDirs = append(labDirs, target)
+		service.Volumes = append(service.Volumes, dockerVolume(dir)+":"+target)
+		compose.Volumes[dockerVolume(dir)] = struct{}{}
+	}
+	if slices.Contains(companions, "jaeger") {
+		compose.Services["jaeger"] = ComposeService{
+			Image:       "jaegertracing/all-in-one:latest",
+			Restart:     "unless-stopped",
+			Ports:       []string{fmt.Sprintf("%d:%d", dockerJaegerUI, dockerJaegerUI)},
+			Environment: map[string]string{"COLLECTOR_OTLP_ENABLED": "true"},
+		}
+		service.DependsOn = []string{"jaeger"}
+	}
+	compose.Services["lab"] = service
+
+	files := lab.files
+	if files["config.yaml"], err = encodeYAML(lab.config); err != nil {
+		return nil, err
+	}
+	if files["compose.yaml"], err = encodeYAML(compose); err != nil {
+		return nil, err
+	}
+	files["Dockerfile"] = fmt.Appendf(nil, dockerfile, strings.Join(labDirs, " "), strings.Join(expose, " "))
+	files[".dockerignore"] = []byte(dockerIgnore)
+	return files, nil
+}
+
+// containerPort is a port a containerized lab listens on
+type containerPort struct {
+	name    string
+	port    int
+	udp     bool
+	private bool // The admin API's, kept off the network
+}
+
+// containerLab is a lab set up to run in a container
+type containerLab struct {
+	config *Config           // Resolved, listening on all of the container's interfaces
+	ports  []containerPort   // Each app's, in order
+	files  map[string][]byte // The TLS files the config names, by their path under tls/
+	dirs   []string          // The directories the lab writes to, relative to its working directory unless absolute
+}
+
+// containerize returns a copy of a config set up to run in a container, with
+// the companions added
+func containerize(cfg *Config, companions []string) (*containerLab, error) {
+	for _, companion := range companions {
+		if !slices.Contains(DockerCompanions, companion) {
+			return nil, fmt.Errorf("unknown companion '%s' (available: %s)", companion, strings.Join(DockerCompanions, ", "))
+		}
+	}
+
+	// Work on a copy, which is also the config as it will be loaded again
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lab := &containerLab{config: new(Config), files: make(map[string][]byte)}
+	if err := yaml.Unmarshal(data, lab.config); err != nil {
+		return nil, err
+	}
+
+	apps := dockerApps(lab.config)
+	for i, app := range apps {
+		suffix := ""
+		if len(apps) > 1 {
+			suffix = "-" + strconv.Itoa(i+1)
+		}
+		publish := func(name string, port int, udp, private bool) {
+			lab.ports = append(lab.ports, containerPort{name: name + suffix, port: port, udp: udp, private: private})
+		}
+
+		app.Host = ""
+		publish("http", app.Port, false, false)
+		if app.TLS != nil {
+			if app.TLS.RedirectPort > 0 {
+				publish("redirect", app.TLS.RedirectPort, false, false)
+			}
+			for _, path := range []*string{&app.TLS.CertFile, &app.TLS.KeyFile, &app.TLS.ClientCAFile} {
+				if *path == "" {
+					continue
+				}
+				name := "tls/" + filepath.Base(*path)
+				if len(apps) > 1 {
+					name = fmt.Sprintf("tls/%d-%s", i+1, filepath.Base(*path))
+				}
+				if lab.files[name], err = os.ReadFile(*path); err != nil {
+					return nil, fmt.Errorf("failed to read %s for app.tls: %w", *path, err)
+				}
+				*path = name
+			}
+			if app.TLS.AutoGenerate {
+				dir := app.TLS.CertDir
+				if dir == "" {
+					dir = "certs"
+				}
+				lab.dirs = append(lab.dirs, dir)
+			}
+		}
+		if app.Admin != nil {
+			_, port, _ := net.SplitHostPort(app.Admin.Address(app.Port))
+			app.Admin.Host = "0.0.0.0"
+			n, _ := strconv.Atoi(port)
+			publish("admin", n, false, true)
+		}
+		if app.GRPC != nil {
+			app.GRPC.Host = ""
+			publish("grpc", app.GRPC.Port, false, false)
+		}
+
+		if slices.Contains(companions, "oob") {
+			if app.OOB == nil {
+				app.OOB = &OOBConfig{}
+			}
+			if app.OOB.HTTPListen == "" {
+				app.OOB.HTTPListen = ":" + strconv.Itoa(dockerOOBHTTPPort+i)
+			}
+			if app.OOB.DNSListen == "" {
+				app.OOB.DNSListen = ":" + strconv.Itoa(dockerOOBDNSPort+i)
+			}
+		}
+		if app.OOB != nil {
+			if app.OOB.HTTPListen, err = containerListen(app.OOB.HTTPListen, func(port int) { publish("oob-http", port, false, false) }); err != nil {
+				return nil, fmt.Errorf("app.oob.http_listen: %w", err)
+			}
+			if app.OOB.DNSListen, err = containerListen(app.OOB.DNSListen, func(port int) { publish("oob-dns", port, true, false) }); err != nil {
+				return nil, fmt.Errorf("app.oob.dns_listen: %w", err)
+			}
+		}
+		if slices.Contains(companions, "mail") {
+			if app.Mail == nil {
+				app.Mail = &MailConfig{}
+			}
+			if app.Mail.SMTPListen == "" {
+				app.Mail.SMTPListen = ":" + strconv.Itoa(dockerSMTPPort+i)
+			}
+		}
+		if app.Mail != nil {
+			if app.Mail.SMTPListen, err = containerListen(app.Mail.SMTPListen, func(port int) { publish("smtp", port, false, false) }); err != nil {
+				return nil, fmt.Errorf("app.mail.smtp_listen: %w", err)
+			}
+		}
+		if slices.Contains(companions, "jaeger") {
+			if app.Tracing == nil {
+				app.Tracing = &TracingConfig{}
+			}
+			app.Tracing.Endpoint = "http://jaeger:4318"
+		}
+
+		dir := "log"
+		if app.Logging != nil && app.Logging.Dir != "" {
+			dir = app.Logging.Dir
+		}
+		lab.dirs = append(lab.dirs, dir)
+	}
+	slices.Sort(lab.dirs)
+	lab.dirs = slices.Compact(lab.dirs)
+	return lab, nil
+}
+
+// dockerApps returns the settings of each app of a config, to change in place
+func dockerApps(cfg *Config) []*AppConfig {
+	if len(cfg.Apps) == 0 {
+		return []*AppConfig{&cfg.App}
+	}
+	apps := make([]*AppConfig, len(cfg.Apps))
+	for i := range cfg.Apps {
+		apps[i] = &cfg.Apps[i].App
+	}
+	return apps
+}
+
+// containerListen returns a listen address on all of the container's
+// interfaces and publishes its port, unless addr is empty
+func containerListen(addr string, publish func(int)) (string, error) {
+	if addr == "" {
+		return "", nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return "", fmt.Errorf("invalid port in %s", addr)
+	}
+	publish(n)
+	return net.JoinHostPort("0.0.0.0", port), nil
+}
+
+// dockerName returns a name for the lab's image, from its app's name
+func dockerName(cfg *Config) string {
+	if name := strings.Trim(nucleiIDPattern.ReplaceAllString(strings.ToLower(cfg.App.Name), "-"), "-"); name != "" {
+		return name
+	}
+	return "lab"
+}
+
+// dockerVolume names the volume keeping a directory the lab writes to
+func dockerVolume(dir string) string {
+	return strings.Trim(nucleiIDPattern.ReplaceAllString(strings.ToLower(dir), "-"), "-")
+}
+
+// encodeYAML encodes v as YAML indented by two spaces, as configs are
+func encodeYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
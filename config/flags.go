@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Defaults for the optional flag settings
+const (
+	DefaultFlagFormat = "FLAG{%s}"
+	DefaultFlagHeader = "X-Flag"
+)
+
+// flagRefPattern matches a ${flag:NAME} reference. Variable substitution leaves
+// it alone, since a variable name can't contain a colon.
+var flagRefPattern = regexp.MustCompile(`\$\{flag:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolveFlags replaces ${flag:NAME} references with the flags' values.
+// References to unknown flags are left as they are.
+func ResolveFlags(s string, values map[string]string) string {
+	if !strings.Contains(s, "${flag:") {
+		return s
+	}
+	return flagRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := values[flagRefPattern.FindStringSubmatch(ref)[1]]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// HeaderName returns the response header the flag is revealed in
+func (r *FlagRevealConfig) HeaderName() string {
+	if r.Header == "" {
+		return DefaultFlagHeader
+	}
+	return r.Header
+}
+
+// Targets reports whether the flag is revealed by the endpoint
+func (r *FlagRevealConfig) Targets(endpoint EndpointConfig) bool {
+	return targets(r.Endpoint, endpoint)
+}
+
+// validateFlags validates flag definitions and the ${flag:NAME} references
+// placed in the rest of the config
+func validateFlags(cfg *Config) ValidationErrors {
+	var errs ValidationErrors
+
+	names := make(map[string]int)
+	envs := make(map[string]int)
+	for i, flag := range cfg.Flags {
+		prefix := fmt.Sprintf("flags[%d]", i)
+
+		switch {
+		case flag.Name == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".name",
+				Message: "flag name is required",
+			})
+		case !varName.MatchString(flag.Name):
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".name",
+				Message: fmt.Sprintf("invalid flag name '%s', use letters, digits and underscores", flag.Name),
+			})
+		default:
+			if prev, exists := names[flag.Name]; exists {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".name",
+					Message: fmt.Sprintf("duplicate flag '%s' (previously defined at index %d)", flag.Name, prev),
+				})
+			} else {
+				names[flag.Name] = i
+			}
+		}
+
+		if flag.Format != "" {
+			if flag.Value != "" {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".format",
+					Message: "format only applies to generated flags, drop it or value",
+				})
+			} else if strings.Count(flag.Format, "%s") != 1 || strings.Count(flag.Format, "%") != 1 {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".format",
+					Message: fmt.Sprintf("format must contain %%s exactly once, got '%s'", flag.Format),
+				})
+			}
+		}
+
+		if flag.Env != "" {
+			if !varName.MatchString(flag.Env) {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".env",
+					Message: fmt.Sprintf("invalid environment variable name '%s'", flag.Env),
+				})
+			} else if prev, exists := envs[flag.Env]; exists {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".env",
+					Message: fmt.Sprintf("environment variable '%s' is already used by flags[%d]", flag.Env, prev),
+				})
+			} else {
+				envs[flag.Env] = i
+			}
+		}
+
+		if flag.Reveal != nil {
+			errs = append(errs, validateFlagReveal(flag.Reveal, cfg.Endpoints, prefix+".reveal")...)
+		}
+	}
+
+	for _, ref := range flagRefs(cfg) {
+		if _, ok := names[ref.name]; !ok {
+			errs = append(errs, ValidationError{
+				Field:   ref.field,
+				Message: fmt.Sprintf("unknown flag '%s', declare it under flags", ref.name),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateFlagReveal checks that a reveal names an endpoint that can be exploited
+func validateFlagReveal(reveal *FlagRevealConfig, endpoints []EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	var endpoint *EndpointConfig
+	for i := range endpoints {
+		if reveal.Targets(endpoints[i]) {
+			endpoint = &endpoints[i]
+			break
+		}
+	}
+
+	switch {
+	case endpoint == nil:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: fmt.Sprintf("no endpoint matches '%s' (expected METHOD /path)", reveal.Endpoint),
+		})
+	case endpoint.WebSocket:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: fmt.Sprintf("'%s' is a WebSocket endpoint, which can't send the flag in a header", reveal.Endpoint),
+		})
+	case len(endpoint.Vulnerabilities) == 0:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: fmt.Sprintf("'%s' has no vulnerabilities to exploit", reveal.Endpoint),
+		})
+	}
+
+	if strings.ContainsAny(reveal.Header, " :\t\r\n") {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".header",
+			Message: fmt.Sprintf("invalid header name '%s'", reveal.Header),
+		})
+	}
+
+	return errs
+}
+
+// flagRef is a ${flag:NAME} reference and the field it was found in
+type flagRef struct {
+	field string
+	name  string
+}
+
+// flagRefs returns the flag references in the fields flags are resolved in:
+// table rows, file and object contents, endpoint headers and response templates
+func flagRefs(cfg *Config) []flagRef {
+	var refs []flagRef
+	find := func(field, s string) {
+		for _, match := range flagRefPattern.FindAllStringSubmatch(s, -1) {
+			refs = append(refs, flagRef{field: field, name: match[1]})
+		}
+	}
+
+	if cfg.Data != nil {
+		for _, name := range sortedKeys(cfg.Data.Tables) {
+			for i, row := range cfg.Data.Tables[name].Rows {
+				for j, value := range row {
+					if s, ok := value.(string); ok {
+						find(fmt.Sprintf("data.tables.%s.rows[%d][%d]", name, i, j), s)
+					}
+				}
+			}
+		}
+		for i, bucket := range cfg.Data.Buckets {
+			for j, object := range bucket.Objects {
+				find(fmt.Sprintf("data.buckets[%d].objects[%d].content", i, j), object.Content)
+			}
+		}
+	}
+
+	for i, file := range cfg.Files {
+		find(fmt.Sprintf("files[%d].content", i), file.Content)
+	}
+
+	for i, endpoint := range cfg.Endpoints {
+		for _, name := range sortedKeys(endpoint.Headers) {
+			find(fmt.Sprintf("endpoints[%d].headers.%s", i, name), endpoint.Headers[name])
+		}
+		find(fmt.Sprintf("endpoints[%d].response_template", i), endpoint.ResponseTemplate)
+	}
+
+	return refs
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// grpcFieldTypes are the scalar and message types a request field can have
+var grpcFieldTypes = []string{"string", "bytes", "bool", "int32", "int64", "uint32", "uint64", "double", "float", "message"}
+
+// grpcPathPattern matches the path of a gRPC method: /package.Service/Method,
+// the package being optional
+var grpcPathPattern = regexp.MustCompile(`^/((?:[A-Za-z_][A-Za-z0-9_]*\.)*)([A-Za-z_][A-Za-z0-9_]*)/([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// grpcNamePattern matches a field name
+var grpcNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Field numbers protobuf reserves, and the largest one
+const (
+	grpcReservedFirst = 19000
+	grpcReservedLast  = 19999
+	grpcMaxField      = 1<<29 - 1
+)
+
+// Address returns the host:port the gRPC listener binds, on the app's host by default
+func (g *GRPCConfig) Address(appHost string) string {
+	host := g.Host
+	if host == "" {
+		host = appHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(g.Port))
+}
+
+// ReflectionEnabled reports whether the listener serves server reflection
+func (g *GRPCConfig) ReflectionEnabled() bool {
+	return g.Reflection == nil || *g.Reflection
+}
+
+// GRPCMethod splits a grpc endpoint's path into the package, service and
+// method names; ok is false for a path that isn't a gRPC method
+func (e EndpointConfig) GRPCMethod() (pkg, service, method string, ok bool) {
+	match := grpcPathPattern.FindStringSubmatch(e.Path)
+	if match == nil {
+		return "", "", "", false
+	}
+	return strings.TrimSuffix(match[1], "."), match[2], match[3], true
+}
+
+// FieldNumber returns the field's number, index being its position in its message
+func (f GRPCField) FieldNumber(index int) int {
+	if f.Number == 0 {
+		return index + 1
+	}
+	return f.Number
+}
+
+// FieldType returns the field's type
+func (f GRPCField) FieldType() string {
+	if f.Type == "" {
+		return "string"
+	}
+	return f.Type
+}
+
+// Field returns the field at a dot separated path, as in filter.name
+func (m *GRPCMethodConfig) Field(path string) (GRPCField, bool) {
+	var fields []GRPCField
+	if m != nil {
+		fields = m.Fields
+	}
+	var field GRPCField
+	for _, name := range strings.Split(path, ".") {
+		i := slices.IndexFunc(fields, func(f GRPCField) bool { return f.Name == name })
+		if i < 0 {
+			return GRPCField{}, false
+		}
+		field = fields[i]
+		fields = field.Fields
+	}
+	return field, true
+}
+
+// validateGRPC validates the gRPC listener, the grpc endpoints it serves and
+// the grpc_field placements that read their messages
+func validateGRPC(cfg *Config) ValidationErrors {
+	var errs ValidationErrors
+
+	app := cfg.App
+	if app.GRPC != nil {
+		port := app.GRPC.Port
+		adminPort := 0
+		if app.Admin != nil {
+			adminPort = app.Admin.Port
+			if adminPort == 0 {
+				adminPort = app.Port + adminPortOffset
+			}
+		}
+		switch {
+		case port < 1 || port > 65535:
+			errs = append(errs, ValidationError{
+				Field:   "app.grpc.port",
+				Message: fmt.Sprintf("grpc port must be between 1 and 65535, got %d", port),
+			})
+		case port == app.Port || port == adminPort || (app.TLS != nil && app.TLS.Enabled && port == app.TLS.RedirectPort):
+			errs = append(errs, ValidationError{
+				Field:   "app.grpc.port",
+				Message: fmt.Sprintf("grpc port %d is already used by the app", port),
+			})
+		}
+	}
+
+	// Each package's messages are described in one file, by method name
+	requests := make(map[string]string)
+
+	for i, endpoint := range cfg.Endpoints {
+		prefix := fmt.Sprintf("endpoints[%d]", i)
+
+		if endpoint.Type != "grpc" {
+			if endpoint.GRPC != nil {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".grpc",
+					Message: "grpc only applies to endpoints of type grpc",
+				})
+			}
+			for j, vuln := range endpoint.Vulnerabilities {
+				if vuln.Placement == "grpc_field" {
+					errs = append(errs, ValidationError{
+						Field:   fmt.Sprintf("%s.vulnerabilities[%d].placement", prefix, j),
+						Message: "placement 'grpc_field' requires 'type: grpc' on the endpoint",
+					})
+				}
+			}
+			continue
+		}
+
+		if app.GRPC == nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".type",
+				Message: "grpc endpoints are served on app.grpc's listener, which isn't configured",
+			})
+		}
+
+		// The gRPC framing decides the response, so these settings have nothing to shape
+		ignored := []struct {
+			field string
+			set   bool
+		}{
+			{"target", endpoint.Target != ""},
+			{"websocket", endpoint.WebSocket},
+			{"response_type", endpoint.ResponseType != ""},
+			{"status", endpoint.Status != 0},
+			{"csp", endpoint.CSP != nil},
+		}
+		for _, setting := range ignored {
+			if setting.set {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.%s", prefix, setting.field),
+					Message: fmt.Sprintf("%s doesn't apply to grpc endpoints", setting.field),
+				})
+			}
+		}
+
+		if !strings.EqualFold(endpoint.Method, "POST") {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".method",
+				Message: fmt.Sprintf("grpc endpoints must use POST, got '%s'", endpoint.Method),
+			})
+		}
+
+		pkg, service, method, ok := endpoint.GRPCMethod()
+		if !ok {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("grpc endpoint path must be /package.Service/Method, got '%s'", endpoint.Path),
+			})
+		} else if other, taken := requests[pkg+"."+method]; taken && other != service {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("method %s is also defined by %s in package '%s', whose %sRequest message would clash", method, other, pkg, method),
+			})
+		} else {
+			requests[pkg+"."+method] = service
+		}
+
+		var fields []GRPCField
+		if endpoint.GRPC != nil {
+			fields = endpoint.GRPC.Fields
+		}
+		errs = append(errs, validateGRPCFields(fields, prefix+".grpc.fields")...)
+
+		for j, vuln := range endpoint.Vulnerabilities {
+			if vuln.Placement != "grpc_field" {
+				continue
+			}
+			for _, param := range vuln.ParamNames() {
+				if field, ok := endpoint.GRPC.Field(param); !ok || field.FieldType() == "message" {
+					errs = append(errs, ValidationError{
+						Field:   fmt.Sprintf("%s.vulnerabilities[%d].param", prefix, j),
+						Message: fmt.Sprintf("'%s' isn't a scalar field declared under grpc.fields", param),
+					})
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateGRPCFields validates the fields of a message and its nested messages
+func validateGRPCFields(fields []GRPCField, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	names := make(map[string]bool)
+	numbers := make(map[int]string)
+	for i, field := range fields {
+		fieldPrefix := fmt.Sprintf("%s[%d]", prefix, i)
+
+		if !grpcNamePattern.MatchString(field.Name) {
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".name",
+				Message: fmt.Sprintf("invalid field name '%s', must be letters, digits and underscores", field.Name),
+			})
+		} else if names[field.Name] {
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".name",
+				Message: fmt.Sprintf("duplicate field '%s'", field.Name),
+			})
+		}
+		names[field.Name] = true
+
+		number := field.FieldNumber(i)
+		switch {
+		case number < 1 || number > grpcMaxField:
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".number",
+				Message: fmt.Sprintf("field number must be between 1 and %d, got %d", grpcMaxField, number),
+			})
+		case number >= grpcReservedFirst && number <= grpcReservedLast:
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".number",
+				Message: fmt.Sprintf("field numbers %d-%d are reserved by protobuf, got %d", grpcReservedFirst, grpcReservedLast, number),
+			})
+		case numbers[number] != "":
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".number",
+				Message: fmt.Sprintf("field number %d is already used by '%s'", number, numbers[number]),
+			})
+		default:
+			numbers[number] = field.Name
+		}
+
+		switch fieldType := field.FieldType(); {
+		case !slices.Contains(grpcFieldTypes, fieldType):
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".type",
+				Message: fmt.Sprintf("invalid field type '%s', must be one of: %s", fieldType, strings.Join(grpcFieldTypes, ", ")),
+			})
+		case fieldType == "message" && len(field.Fields) == 0:
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".fields",
+				Message: "message fields need fields of their own",
+			})
+		case fieldType == "message":
+			errs = append(errs, validateGRPCFields(field.Fields, fieldPrefix+".fields")...)
+		case len(field.Fields) > 0:
+			errs = append(errs, ValidationError{
+				Field:   fieldPrefix + ".fields",
+				Message: fmt.Sprintf("fields only apply to message fields, not %s", fieldType),
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Protected reports whether the listener keeps its timeouts and body limits
+func (h *HTTPConfig) Protected() bool {
+	return h == nil || h.Protection == nil || *h.Protection
+}
+
+// ParseTimeout parses one of app.http's timeouts. ok is false when it's unset,
+// so the server keeps its default.
+func ParseTimeout(value string) (d time.Duration, ok bool) {
+	if value == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	return d, err == nil
+}
+
+// validateHTTP validates the listener's protocol settings and the body limits
+// of app.http and the endpoints
+func validateHTTP(cfg *Config) ValidationErrors {
+	var errs ValidationErrors
+
+	for i, endpoint := range cfg.Endpoints {
+		if endpoint.MaxBodySize < 0 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("endpoints[%d].max_body_size", i),
+				Message: "max_body_size cannot be negative",
+			})
+		} else if endpoint.MaxBodySize > 0 && !cfg.App.HTTP.Protected() {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("endpoints[%d].max_body_size", i),
+				Message: "max_body_size has no effect with app.http.protection off",
+			})
+		}
+	}
+
+	settings := cfg.App.HTTP
+	if settings == nil {
+		return errs
+	}
+
+	timeouts := []struct {
+		field string
+		value string
+	}{
+		{"read_timeout", settings.ReadTimeout},
+		{"read_header_timeout", settings.ReadHeaderTimeout},
+		{"write_timeout", settings.WriteTimeout},
+		{"idle_timeout", settings.IdleTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(timeout.value); err != nil || d < 0 {
+			errs = append(errs, ValidationError{
+				Field:   "app.http." + timeout.field,
+				Message: fmt.Sprintf("invalid %s '%s' (a duration such as 30s, or 0 for none)", timeout.field, timeout.value),
+			})
+		} else if !settings.Protected() {
+			errs = append(errs, ValidationError{
+				Field:   "app.http." + timeout.field,
+				Message: fmt.Sprintf("%s has no effect with protection off", timeout.field),
+			})
+		}
+	}
+
+	if settings.MaxHeaderBytes < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.http.max_header_bytes",
+			Message: "max_header_bytes cannot be negative",
+		})
+	}
+	if settings.MaxBodySize < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.http.max_body_size",
+			Message: "max_body_size cannot be negative",
+		})
+	} else if settings.MaxBodySize > 0 && !settings.Protected() {
+		errs = append(errs, ValidationError{
+			Field:   "app.http.max_body_size",
+			Message: "max_body_size has no effect with protection off",
+		})
+	}
+
+	if settings.H2C && cfg.App.TLS != nil && cfg.App.TLS.Enabled {
+		errs = append(errs, ValidationError{
+			Field:   "app.http.h2c",
+			Message: "h2c is HTTP/2 without TLS; with tls enabled, HTTP/2 is set by http2",
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loader reads a config file and the files it includes
+type loader struct {
+	vars    map[string]string // Values for ${VAR} references, ahead of the environment
+	strict  bool              // Reject keys that don't match a config field
+	loading map[string]bool   // Files currently being loaded, to detect include cycles
+	loaded  map[string]bool   // Files already merged, so diamond includes are merged once
+
+	// Where each merged item came from, for duplicate errors
+	endpoints map[string]string
+	files     map[string]string
+	tables    map[string]string
+	redis     map[string]string
+	colls     map[string]string
+	buckets   map[string]string
+	apps      map[string]string
+	chains    map[string]string
+	flags     map[string]string
+	static    map[string]string
+	auth      string
+	ui        string
+	ldap      string
+	persist   string
+}
+
+// newLoader creates a loader with empty provenance maps
+func newLoader(vars map[string]string) *loader {
+	return &loader{
+		vars:      vars,
+		loading:   make(map[string]bool),
+		loaded:    make(map[string]bool),
+		endpoints: make(map[string]string),
+		files:     make(map[string]string),
+		tables:    make(map[string]string),
+		redis:     make(map[string]string),
+		colls:     make(map[string]string),
+		buckets:   make(map[string]string),
+		apps:      make(map[string]string),
+		chains:    make(map[string]string),
+		flags:     make(map[string]string),
+		static:    make(map[string]string),
+	}
+}
+
+// parseFile reads one YAML file and substitutes variables, without resolving its includes
+func (l *loader) parseFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return l.parse(path, data)
+}
+
+// parse parses YAML read from path and substitutes variables
+func (l *loader) parse(path string, data []byte) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+	}
+	if err := substituteNode(&doc, l.vars); err != nil {
+		return nil, fmt.Errorf("failed to substitute variables in %s: %w", path, err)
+	}
+	if l.strict {
+		if err := checkKnownFields(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+		}
+	}
+
+	var cfg Config
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// load reads the main config file and merges its includes into it, then
+// deep-merges each overlay file over the result
+func (l *loader) load(path string, overlays ...string) (*Config, error) {
+	cfg, err := l.parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A preset's contents come first, so the file can add to them
+	merged := &Config{App: cfg.App}
+	if cfg.Preset != "" {
+		if len(cfg.Apps) > 0 {
+			return nil, fmt.Errorf("%s: preset can't be used together with apps", path)
+		}
+		if err := l.mergePreset(merged, cfg.Preset); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := l.merge(merged, cfg, path); err != nil {
+		return nil, err
+	}
+	if len(overlays) > 0 {
+		if merged, err = l.applyOverlays(merged, overlays); err != nil {
+			return nil, err
+		}
+	}
+	merged.Sources = sortedKeys(l.loaded)
+	applyDifficulty(merged)
+	return merged, nil
+}
+
+// merge adds a parsed file's contents, then those of its includes, to dst
+func (l *loader) merge(dst, src *Config, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	l.loading[abs] = true
+	l.loaded[abs] = true
+	defer delete(l.loading, abs)
+
+	if err := l.mergeContent(dst, src, path); err != nil {
+		return err
+	}
+
+	for _, pattern := range src.Include {
+		paths, err := resolveInclude(filepath.Dir(path), pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, included := range paths {
+			incAbs, err := filepath.Abs(included)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", included, err)
+			}
+			if l.loading[incAbs] {
+				return fmt.Errorf("%s: include cycle through %s", path, included)
+			}
+			if l.loaded[incAbs] {
+				continue
+			}
+
+			inc, err := l.parseFile(included)
+			if err != nil {
+				return err
+			}
+			// App settings describe the whole lab, so only the main file may set them
+			if !reflect.DeepEqual(inc.App, AppConfig{}) {
+				return fmt.Errorf("%s: app settings are only allowed in the main config file", included)
+			}
+			if inc.Preset != "" {
+				return fmt.Errorf("%s: preset is only allowed in the main config file", included)
+			}
+			if err := l.merge(dst, inc, included); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveInclude expands an include entry relative to the including file's directory.
+// Glob patterns match files in sorted order; a plain path must exist.
+func resolveInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("included file not found: %s", pattern)
+		}
+		matches = []string{pattern}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// mergeContent appends a file's endpoints, files, chains, auth, flags, ui, static, apps and data to dst, rejecting
+// anything already defined by another file. Duplicates within one file are
+// left to the validator, which reports them by index.
+func (l *loader) mergeContent(dst, src *Config, path string) error {
+	for _, endpoint := range src.Endpoints {
+		key := fmt.Sprintf("%s %s%s", endpoint.Method, endpoint.Host, endpoint.Path)
+		if prev, exists := l.endpoints[key]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate endpoint '%s' (previously defined in %s)", path, key, prev)
+		}
+		l.endpoints[key] = path
+		dst.Endpoints = append(dst.Endpoints, endpoint)
+	}
+
+	for _, file := range src.Files {
+		if prev, exists := l.files[file.Path]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate file '%s' (previously defined in %s)", path, file.Path, prev)
+		}
+		l.files[file.Path] = path
+		dst.Files = append(dst.Files, file)
+	}
+
+	for _, chain := range src.Chains {
+		if prev, exists := l.chains[chain.Name]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate chain '%s' (previously defined in %s)", path, chain.Name, prev)
+		}
+		l.chains[chain.Name] = path
+		dst.Chains = append(dst.Chains, chain)
+	}
+
+	if src.Auth != nil {
+		if l.auth != "" {
+			return fmt.Errorf("%s: auth is already configured in %s", path, l.auth)
+		}
+		l.auth = path
+		dst.Auth = src.Auth
+	}
+
+	for _, flag := range src.Flags {
+		if prev, exists := l.flags[flag.Name]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate flag '%s' (previously defined in %s)", path, flag.Name, prev)
+		}
+		l.flags[flag.Name] = path
+		dst.Flags = append(dst.Flags, flag)
+	}
+
+	if src.UI != nil {
+		if l.ui != "" {
+			return fmt.Errorf("%s: ui is already configured in %s", path, l.ui)
+		}
+		l.ui = path
+		dst.UI = src.UI
+	}
+
+	for _, static := range src.Static {
+		if prev, exists := l.static[static.Path]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate static path '%s' (previously defined in %s)", path, static.Path, prev)
+		}
+		l.static[static.Path] = path
+		dst.Static = append(dst.Static, static)
+	}
+
+	for _, app := range src.Apps {
+		if prev, exists := l.apps[app.App.Name]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate app '%s' (previously defined in %s)", path, app.App.Name, prev)
+		}
+		l.apps[app.App.Name] = path
+		dst.Apps = append(dst.Apps, app)
+	}
+
+	if src.Data == nil {
+		return nil
+	}
+	if dst.Data == nil {
+		dst.Data = &DataConfig{}
+	}
+	data := dst.Data
+
+	for _, name := range sortedKeys(src.Data.Tables) {
+		if prev, exists := l.tables[name]; exists {
+			return fmt.Errorf("%s: duplicate table '%s' (previously defined in %s)", path, name, prev)
+		}
+		l.tables[name] = path
+		if data.Tables == nil {
+			data.Tables = make(map[string]TableConfig)
+		}
+		data.Tables[name] = src.Data.Tables[name]
+	}
+
+	for _, key := range sortedKeys(src.Data.Redis) {
+		if prev, exists := l.redis[key]; exists {
+			return fmt.Errorf("%s: duplicate Redis key '%s' (previously defined in %s)", path, key, prev)
+		}
+		l.redis[key] = path
+		if data.Redis == nil {
+			data.Redis = make(map[string]interface{})
+		}
+		data.Redis[key] = src.Data.Redis[key]
+	}
+
+	for _, name := range sortedKeys(src.Data.Collections) {
+		if prev, exists := l.colls[name]; exists {
+			return fmt.Errorf("%s: duplicate collection '%s' (previously defined in %s)", path, name, prev)
+		}
+		l.colls[name] = path
+		if data.Collections == nil {
+			data.Collections = make(map[string][]map[string]interface{})
+		}
+		data.Collections[name] = src.Data.Collections[name]
+	}
+
+	for _, bucket := range src.Data.Buckets {
+		if prev, exists := l.buckets[bucket.Name]; exists && prev != path {
+			return fmt.Errorf("%s: duplicate bucket '%s' (previously defined in %s)", path, bucket.Name, prev)
+		}
+		l.buckets[bucket.Name] = path
+		data.Buckets = append(data.Buckets, bucket)
+	}
+
+	if src.Data.LDAP != nil {
+		if l.ldap != "" {
+			return fmt.Errorf("%s: LDAP directory is already defined in %s", path, l.ldap)
+		}
+		l.ldap = path
+		data.LDAP = src.Data.LDAP
+	}
+
+	if src.Data.Persistence != nil {
+		if l.persist != "" {
+			return fmt.Errorf("%s: persistence is already configured in %s", path, l.persist)
+		}
+		l.persist = path
+		data.Persistence = src.Data.Persistence
+	}
+
+	return nil
+}
+
+// sortedKeys returns a map's keys in sorted order, so merge errors are deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxCopies is how many clients get their own copy of the sinks at once
+const DefaultMaxCopies = 100
+
+// CopyLimit returns how many copies are kept at once
+func (i *IsolationConfig) CopyLimit() int {
+	if i.MaxCopies == 0 {
+		return DefaultMaxCopies
+	}
+	return i.MaxCopies
+}
+
+// IdleTimeout returns how long an unused copy is kept, 0 for ever
+func (i *IsolationConfig) IdleTimeout() time.Duration {
+	d, _ := time.ParseDuration(i.IdleExpiry)
+	return d
+}
+
+// validateIsolation validates the isolation settings against the auth and
+// sessions that tell clients apart
+func validateIsolation(cfg *Config) ValidationErrors {
+	var errs ValidationErrors
+	isolation := cfg.App.Isolation
+
+	switch isolation.By {
+	case "user":
+		if cfg.Auth == nil {
+			errs = append(errs, ValidationError{
+				Field:   "app.isolation.by",
+				Message: "isolation by user needs an auth section to log users in",
+			})
+		}
+	case "session":
+		if cfg.App.Sessions == nil && (cfg.Auth == nil || cfg.Auth.Type != "session") {
+			errs = append(errs, ValidationError{
+				Field:   "app.isolation.by",
+				Message: "isolation by session needs app.sessions or session auth",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "app.isolation.by",
+			Message: fmt.Sprintf("invalid isolation '%s', must be user or session", isolation.By),
+		})
+	}
+
+	if isolation.MaxCopies < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.isolation.max_copies",
+			Message: "max_copies cannot be negative",
+		})
+	}
+	if isolation.IdleExpiry != "" {
+		if d, err := time.ParseDuration(isolation.IdleExpiry); err != nil || d < time.Second {
+			errs = append(errs, ValidationError{
+				Field:   "app.isolation.idle_expiry",
+				Message: fmt.Sprintf("invalid idle_expiry '%s' (a duration of at least 1s)", isolation.IdleExpiry),
+			})
+		}
+	}
+
+	return errs
+}
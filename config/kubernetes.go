@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Defaults of KubernetesOptions
+const (
+	defaultKubernetesImage = "flawfactory:latest"
+	defaultNodePort        = 30000
+	maxNodePort            = 32767 // The end of Kubernetes' default NodePort range
+)
+
+// maxKubernetesName bounds the lab's part of object names, which leaves room
+// for a student and a duplicate number within the 63 characters of a label
+const maxKubernetesName = 52
+
+// kubernetesConfigDir is where an instance's ConfigMap is mounted
+const kubernetesConfigDir = "/etc/flawfactory"
+
+// KubernetesOptions sets up the instances KubernetesManifests deploys
+type KubernetesOptions struct {
+	Students  int    // Instances of each lab, one per student (default: 1)
+	NodePort  int    // The first NodePort handed out, the next ones following it (default: 30000)
+	Seed      int64  // The first student's app.seed, the next students getting the next ones (default: the lab's seed, or 1)
+	Image     string // Image with flawfactory, such as one built from export docker's Dockerfile (default: flawfactory:latest)
+	Namespace string // Namespace of every object, or none for kubectl's
+}
+
+// KubernetesInstance is one student's instance of a lab
+type KubernetesInstance struct {
+	Name      string         // Of its objects
+	Lab       string         // The app's name
+	Student   int            // From 1
+	Seed      int64          // Its app.seed
+	NodePorts map[string]int // By port name: http, grpc, smtp, ...
+}
+
+// KubernetesManifests returns the objects that deploy each lab once per
+// student: a ConfigMap with the instance's config, a Secret with the TLS files
+// it names, a Deployment running it and a NodePort Service publishing its
+// listeners. Instances differ in their app.seed, so each student gets their
+// own generated data and tokens, and in their NodePorts, handed out in order.
+// The admin API isn't published; reach it with kubectl port-forward.
+func KubernetesManifests(labs []*Config, opts KubernetesOptions) ([]map[string]interface{}, []KubernetesInstance, error) {
+	if opts.Students <= 0 {
+		opts.Students = 1
+	}
+	if opts.NodePort == 0 {
+		opts.NodePort = defaultNodePort
+	}
+	if opts.Image == "" {
+		opts.Image = defaultKubernetesImage
+	}
+
+	var objects []map[string]interface{}
+	var instances []KubernetesInstance
+	nodePort := opts.NodePort
+	names := make(map[string]int)
+
+	for _, cfg := range labs {
+		base := dockerName(cfg)
+		if len(base) > maxKubernetesName {
+			base = strings.TrimRight(base[:maxKubernetesName], "-")
+		}
+		if names[base]++; names[base] > 1 {
+			base = fmt.Sprintf("%s-%d", base, names[base])
+		}
+		seed := opts.Seed
+		if seed == 0 {
+			seed = cfg.App.Seed
+		}
+		if seed == 0 {
+			seed = 1
+		}
+
+		for student := 1; student <= opts.Students; student++ {
+			lab, err := containerize(cfg, nil)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", cfg.App.Name, err)
+			}
+			instance := KubernetesInstance{
+				Name:      fmt.Sprintf("%s-%02d", base, student),
+				Lab:       cfg.App.Name,
+				Student:   student,
+				Seed:      seed + int64(student-1),
+				NodePorts: make(map[string]int),
+			}
+			for _, app := range dockerApps(lab.config) {
+				app.Seed = instance.Seed
+			}
+
+			labels := map[string]interface{}{
+				"app.kubernetes.io/name":     "flawfactory",
+				"app.kubernetes.io/instance": instance.Name,
+				"flawfactory/student":        strconv.Itoa(student),
+			}
+			metadata := func() map[string]interface{} {
+				m := map[string]interface{}{"name": instance.Name, "labels": labels}
+				if opts.Namespace != "" {
+					m["namespace"] = opts.Namespace
+				}
+				return m
+			}
+
+			data, err := encodeYAML(lab.config)
+			if err != nil {
+				return nil, nil, err
+			}
+			objects = append(objects, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   metadata(),
+				"data":       map[string]interface{}{"config.yaml": string(data)},
+			})
+
+			mounts := []interface{}{
+				map[string]interface{}{"name": "config", "mountPath": kubernetesConfigDir, "readOnly": true},
+			}
+			volumes := []interface{}{
+				map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": instance.Name}},
+			}
+			if len(lab.files) > 0 {
+				tls := make(map[string]interface{})
+				for name, content := range lab.files {
+					tls[path.Base(name)] = string(content)
+				}
+				objects = append(objects, map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Secret",
+					"metadata":   metadata(),
+					"type":       "Opaque",
+					"stringData": tls,
+				})
+				mounts = append(mounts, map[string]interface{}{"name": "tls", "mountPath": "/lab/tls", "readOnly": true})
+				volumes = append(volumes, map[string]interface{}{"name": "tls", "secret": map[string]interface{}{"secretName": instance.Name}})
+			}
+			for i, dir := range lab.dirs {
+				name := fmt.Sprintf("data-%d", i+1)
+				if !path.IsAbs(dir) {
+					dir = "/lab/" + dir
+				}
+				mounts = append(mounts, map[string]interface{}{"name": name, "mountPath": dir})
+				volumes = append(volumes, map[string]interface{}{"name": name, "emptyDir": map[string]interface{}{}})
+			}
+
+			var containerPorts, servicePorts []interface{}
+			for _, p := range lab.ports {
+				protocol := "TCP"
+				if p.udp {
+					protocol = "UDP"
+				}
+				containerPorts = append(containerPorts, map[string]interface{}{"name": p.name, "containerPort": p.port, "protocol": protocol})
+				if p.private {
+					continue
+				}
+				if nodePort > maxNodePort {
+					return nil, nil, fmt.Errorf("ran out of NodePorts at %d; start from a lower --node-port or deploy fewer students", maxNodePort)
+				}
+				servicePorts = append(servicePorts, map[string]interface{}{"name": p.name, "port": p.port, "targetPort": p.port, "nodePort": nodePort, "protocol": protocol})
+				instance.NodePorts[p.name] = nodePort
+				nodePort++
+			}
+
+			objects = append(objects, map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   metadata(),
+				"spec": map[string]interface{}{
+					"replicas": 1,
+					"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app.kubernetes.io/instance": instance.Name}},
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": labels},
+						"spec": map[string]interface{}{
+							"containers": []interface{}{map[string]interface{}{
+								"name":         "lab",
+								"image":        opts.Image,
+								"command":      []string{"flawfactory", "run", "-c", kubernetesConfigDir + "/config.yaml"},
+								"workingDir":   "/lab",
+								"ports":        containerPorts,
+								"volumeMounts": mounts,
+								// A plain connection, as a login wall can guard even /health
+								"readinessProbe": map[string]interface{}{"tcpSocket": map[string]interface{}{"port": lab.ports[0].port}},
+								"resources": map[string]interface{}{
+									"requests": map[string]interface{}{"cpu": "50m", "memory": "64Mi"},
+									"limits":   map[string]interface{}{"memory": "256Mi"},
+								},
+								"securityContext": map[string]interface{}{"allowPrivilegeEscalation": false},
+							}},
+							"volumes": volumes,
+						},
+					},
+				},
+			})
+			objects = append(objects, map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   metadata(),
+				"spec": map[string]interface{}{
+					"type":     "NodePort",
+					"selector": map[string]interface{}{"app.kubernetes.io/instance": instance.Name},
+					"ports":    servicePorts,
+				},
+			})
+			instances = append(instances, instance)
+		}
+	}
+	return objects, instances, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+)
+
+// TestKubernetesManifests tests each student gets an instance of each lab,
+// with its own seed and NodePorts
+func TestKubernetesManifests(t *testing.T) {
+	shop, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", `
+app:
+  name: "Shop"
+  port: 9000
+  seed: 40
+  admin:
+    token: s3cret
+  mail:
+    smtp_listen: 127.0.0.1:2525
+
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+`))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	objects, instances, err := KubernetesManifests([]*Config{shop, shop}, KubernetesOptions{Students: 2, NodePort: 31000, Namespace: "class"})
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(objects) != 12 || len(instances) != 4 {
+		t.Fatalf("Expected a ConfigMap, Deployment and Service for 4 instances, got %d objects", len(objects))
+	}
+
+	second := instances[1]
+	if second.Name != "shop-02" || second.Seed != 41 || second.NodePorts["http"] != 31002 || second.NodePorts["smtp"] != 31003 {
+		t.Errorf("Expected the second student's seed and ports, got %+v", second)
+	}
+	if _, ok := second.NodePorts["admin"]; ok {
+		t.Errorf("Expected the admin API left unpublished, got %v", second.NodePorts)
+	}
+	if instances[2].Name != "shop-2-01" || instances[2].Seed != 40 {
+		t.Errorf("Expected the second lab named apart and seeded from the first, got %+v", instances[2])
+	}
+
+	metadata := objects[0]["metadata"].(map[string]interface{})
+	if objects[0]["kind"] != "ConfigMap" || metadata["name"] != "shop-01" || metadata["namespace"] != "class" {
+		t.Errorf("Expected the first student's ConfigMap, got %v", objects[0])
+	}
+
+	if _, _, err := KubernetesManifests([]*Config{shop}, KubernetesOptions{Students: 3, NodePort: 32764}); err == nil {
+		t.Errorf("Expected an error past the NodePort range")
+	}
+}
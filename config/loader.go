@@ -1,30 +1,61 @@
 package config
 
-import (
-	"fmt"
-	"os"
+// Load reads and parses a YAML config file, merging in any included files.
+// ${VAR} references are resolved from the environment.
+func Load(path string) (*Config, error) {
+	return LoadWithVars(path, nil)
+}
+
+// LoadWithVars is like Load, but resolves ${VAR} references from vars before the environment.
+// Each overlay file is deep-merged, in order, over the config before it is validated.
+func LoadWithVars(path string, vars map[string]string, overlays ...string) (*Config, error) {
+	// Parse the file and its includes into one Config
+	cfg, err := newLoader(vars).load(path, overlays...)
+	if err != nil {
+		return nil, err
+	}
 
-	"gopkg.in/yaml.v3"
-)
+	// Validate the configuration
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
 
-// Load reads and parses a YAML config file
-func Load(path string) (*Config, error) {
-	// Read the file
-	data, err := os.ReadFile(path)
+	return cfg, nil
+}
+
+// LoadStrict is like LoadWithVars, but rejects unknown keys anywhere in the
+// files and validates with ValidateStrict, so typos fail instead of being ignored
+func LoadStrict(path string, vars map[string]string, overlays ...string) (*Config, error) {
+	l := newLoader(vars)
+	l.strict = true
+
+	cfg, err := l.load(path, overlays...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
-	// Parse YAML into our Config struct
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	if result := ValidateStrict(cfg); result.HasErrors() {
+		return nil, result.Errors
 	}
 
-	// Validate the configuration
-	if err := Validate(&cfg); err != nil {
+	return cfg, nil
+}
+
+// LoadPreset loads a built-in preset as a complete config. Presets ship with
+// FlawFactory, so they are always held to ValidateStrict.
+func LoadPreset(name string, vars map[string]string) (*Config, error) {
+	l := newLoader(vars)
+	l.strict = true
+
+	cfg := &Config{}
+	if err := l.mergePreset(cfg, name); err != nil {
 		return nil, err
 	}
+	applyDifficulty(cfg)
+
+	if result := ValidateStrict(cfg); result.HasErrors() {
+		return nil, result.Errors
+	}
 
-	return &cfg, nil
+	return cfg, nil
 }
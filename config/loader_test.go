@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
 )
 
 // TestLoad_ValidConfig tests loading a valid config file
@@ -235,3 +240,1891 @@ func createTempYAML(t *testing.T, content string) string {
 
 	return tmpFile
 }
+
+// writeYAML writes a config file under dir, creating parent directories
+func writeYAML(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	return path
+}
+
+// TestLoad_WithIncludes tests merging included files, globs and nested includes
+func TestLoad_WithIncludes(t *testing.T) {
+	dir := t.TempDir()
+	main := writeYAML(t, dir, "lab.yaml", `
+include:
+  - endpoints/*.yaml
+  - data.yaml
+app:
+  name: "Include Test"
+  port: 8080
+endpoints:
+  - path: /health-check
+    method: GET
+    vulnerabilities: []
+`)
+	writeYAML(t, dir, "endpoints/sqli.yaml", `
+endpoints:
+  - path: /users
+    method: GET
+    vulnerabilities:
+      - type: sql_injection
+        placement: query_param
+        param: id
+`)
+	writeYAML(t, dir, "endpoints/files.yaml", `
+include:
+  - ../files/seed.yaml
+endpoints:
+  - path: /download
+    method: GET
+    vulnerabilities:
+      - type: path_traversal
+        placement: query_param
+        param: name
+`)
+	writeYAML(t, dir, "files/seed.yaml", `
+files:
+  - path: secret.txt
+    content: "flag"
+`)
+	writeYAML(t, dir, "data.yaml", `
+data:
+  tables:
+    users:
+      columns: [id, username]
+      rows:
+        - [1, "admin"]
+`)
+
+	cfg, err := Load(main)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var paths []string
+	for _, endpoint := range cfg.Endpoints {
+		paths = append(paths, endpoint.Path)
+	}
+	// Glob matches are merged in sorted order, after the including file's own endpoints
+	if strings.Join(paths, ",") != "/health-check,/download,/users" {
+		t.Errorf("Unexpected endpoint order %v", paths)
+	}
+	if len(cfg.Files) != 1 || cfg.Files[0].Path != "secret.txt" {
+		t.Errorf("Expected nested include's file, got %v", cfg.Files)
+	}
+	if cfg.Data == nil || len(cfg.Data.Tables["users"].Rows) != 1 {
+		t.Error("Expected included users table")
+	}
+	if cfg.App.Name != "Include Test" {
+		t.Errorf("Expected main file's app settings, got %q", cfg.App.Name)
+	}
+	if len(cfg.Sources) != 5 {
+		t.Errorf("Expected all 5 loaded files as sources, got %v", cfg.Sources)
+	}
+}
+
+// TestLoad_IncludeErrors tests duplicates across files, cycles and misplaced app settings
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		main    string
+		other   string
+		wantErr string
+	}{
+		{
+			name:    "duplicate endpoint",
+			main:    "endpoints:\n  - path: /a\n    method: GET\n    vulnerabilities: []\n",
+			other:   "endpoints:\n  - path: /a\n    method: GET\n    vulnerabilities: []\n",
+			wantErr: "duplicate endpoint 'GET /a'",
+		},
+		{
+			name:    "duplicate table",
+			main:    "data:\n  tables:\n    users:\n      columns: [id]\n      rows: []\n",
+			other:   "data:\n  tables:\n    users:\n      columns: [id]\n      rows: []\n",
+			wantErr: "duplicate table 'users'",
+		},
+		{
+			name:    "include cycle",
+			other:   "include: [lab.yaml]\n",
+			wantErr: "include cycle",
+		},
+		{
+			name:    "app in include",
+			other:   "app:\n  port: 9090\n",
+			wantErr: "app settings are only allowed in the main config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			main := writeYAML(t, dir, "lab.yaml", "include: [other.yaml]\napp:\n  name: test\n  port: 8080\n"+tt.main)
+			writeYAML(t, dir, "other.yaml", tt.other)
+
+			_, err := Load(main)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	main := writeYAML(t, dir, "lab.yaml", "include: [missing.yaml]\napp:\n  name: test\n  port: 8080\n")
+	if _, err := Load(main); err == nil || !strings.Contains(err.Error(), "included file not found") {
+		t.Errorf("Expected missing include error, got %v", err)
+	}
+}
+
+// TestLoad_Variables tests ${VAR} substitution from --set values, the environment and defaults
+func TestLoad_Variables(t *testing.T) {
+	t.Setenv("FF_TEST_HOST", "0.0.0.0")
+	t.Setenv("FF_TEST_NAME", "from env")
+
+	content := `
+app:
+  name: "${FF_TEST_NAME}"
+  host: ${FF_TEST_HOST}
+  port: ${FF_TEST_PORT:-8080}
+  description: "price is $${AMOUNT}"
+
+files:
+  - path: flag.txt
+    content: "${FLAG}"
+
+endpoints:
+  # comments like ${UNDEFINED} are never expanded
+  - path: /health
+    method: GET
+    vulnerabilities: []
+`
+	tmpFile := createTempYAML(t, content)
+
+	cfg, err := LoadWithVars(tmpFile, map[string]string{"FLAG": "ctf{vars}", "FF_TEST_NAME": "from set"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.App.Name != "from set" {
+		t.Errorf("Expected --set to override the environment, got %q", cfg.App.Name)
+	}
+	if cfg.App.Host != "0.0.0.0" {
+		t.Errorf("Expected host from environment, got %q", cfg.App.Host)
+	}
+	if cfg.App.Port != 8080 {
+		t.Errorf("Expected default port 8080, got %d", cfg.App.Port)
+	}
+	if cfg.App.Description != "price is ${AMOUNT}" {
+		t.Errorf("Expected escaped reference to be kept, got %q", cfg.App.Description)
+	}
+	if cfg.Files[0].Content != "ctf{vars}" {
+		t.Errorf("Expected flag from --set, got %q", cfg.Files[0].Content)
+	}
+
+	if _, err := Load(tmpFile); err == nil || !strings.Contains(err.Error(), "undefined variable 'FLAG'") {
+		t.Errorf("Expected undefined variable error, got %v", err)
+	}
+}
+
+// TestParseVars tests parsing --set assignments
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"PORT=9090", "FLAG=a=b", "EMPTY="})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if vars["PORT"] != "9090" || vars["FLAG"] != "a=b" || vars["EMPTY"] != "" {
+		t.Errorf("Unexpected vars %v", vars)
+	}
+
+	for _, invalid := range []string{"PORT", "=value", "1PORT=x", "MY-VAR=x"} {
+		if _, err := ParseVars([]string{invalid}); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestLoadStrict tests that strict loading rejects what Load ignores
+func TestLoadStrict(t *testing.T) {
+	const header = "app:\n  name: test\n  port: 8080\nendpoints:\n  - path: /a\n    method: GET\n    vulnerabilities:\n"
+
+	tests := []struct {
+		name    string
+		vuln    string
+		wantErr string
+	}{
+		{
+			name:    "misspelled field",
+			vuln:    "      - type: sql_injection\n        placment: query_param\n        param: id\n",
+			wantErr: "line 9: field placment not found in type config.VulnerabilityConfig",
+		},
+		{
+			name:    "unknown module",
+			vuln:    "      - type: sql_injektion\n        placement: query_param\n        param: id\n",
+			wantErr: "unknown vulnerability type 'sql_injektion'",
+		},
+		{
+			name:    "unsupported placement",
+			vuln:    "      - type: ssrf\n        placement: ws_message\n        param: url\n",
+			wantErr: "placement 'ws_message' is not supported by module 'ssrf'",
+		},
+		{
+			name:    "unknown config key",
+			vuln:    "      - type: sql_injection\n        placement: query_param\n        param: id\n        config:\n          query_tempalte: SELECT 1\n",
+			wantErr: "unknown config key 'query_tempalte' for sql_injection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTempYAML(t, header+tt.vuln)
+
+			if _, err := LoadStrict(path, nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Known keys, delegated websocket keys and a ${VAR} in an int field all pass
+	path := createTempYAML(t, `
+app:
+  name: test
+  port: ${PORT:-8080}
+endpoints:
+  - path: /ws
+    method: GET
+    websocket: true
+    vulnerabilities:
+      - type: websocket_injection
+        placement: ws_message
+        param: id
+        config:
+          sink: sql
+          query_template: "SELECT * FROM users WHERE id = {input}"
+`)
+	cfg, err := LoadStrict(path, nil)
+	if err != nil {
+		t.Fatalf("Expected strict load to succeed, got %v", err)
+	}
+	if cfg.App.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", cfg.App.Port)
+	}
+}
+
+// TestLoad_ModuleExamples tests the example endpoint each module documents
+// loads strictly
+func TestLoad_ModuleExamples(t *testing.T) {
+	for _, info := range modules.List() {
+		example := "  " + strings.ReplaceAll(strings.TrimSuffix(info.Example, "\n"), "\n", "\n  ")
+		path := createTempYAML(t, "app:\n  name: test\n  port: 8080\nendpoints:\n"+example+"\n")
+		if _, err := LoadStrict(path, nil); err != nil {
+			t.Errorf("%s: expected the example to load, got %v", info.Name, err)
+		}
+	}
+}
+
+// TestLoad_Apps tests configs defining several apps
+func TestLoad_Apps(t *testing.T) {
+	const apps = `
+apps:
+  - app:
+      name: frontend
+      port: 8080
+    endpoints:
+      - path: /preview
+        method: GET
+        vulnerabilities:
+          - type: ssrf
+            placement: query_param
+            param: url
+  - app:
+      name: internal
+      port: %d
+    files:
+      - path: secret.txt
+        content: flag
+    endpoints:
+      - path: /docs
+        method: GET
+        vulnerabilities:
+          - type: path_traversal
+            placement: query_param
+            param: name
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(apps, 8081)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	labs := cfg.Applications()
+	if len(labs) != 2 {
+		t.Fatalf("Expected 2 apps, got %d", len(labs))
+	}
+	if labs[1].App.Name != "internal" || len(labs[1].Files) != 1 || labs[1].Endpoints[0].Path != "/docs" {
+		t.Errorf("Unexpected second app %+v", labs[1])
+	}
+	if len(labs[0].Sources) != 1 {
+		t.Errorf("Expected apps to share the config's sources, got %v", labs[0].Sources)
+	}
+
+	// Errors are reported per app
+	_, err = Load(createTempYAML(t, fmt.Sprintf(apps, 8080)))
+	if err == nil || !strings.Contains(err.Error(), "apps[1].app.port: port 8080 is already used by apps[0]") {
+		t.Errorf("Expected duplicate port error, got %v", err)
+	}
+
+	// Single-app sections can't be mixed with apps
+	_, err = Load(createTempYAML(t, "endpoints:\n  - path: /a\n    method: GET\n    vulnerabilities: []\n"+fmt.Sprintf(apps, 8081)))
+	if err == nil || !strings.Contains(err.Error(), "endpoints can't be used together with apps") {
+		t.Errorf("Expected mixed sections error, got %v", err)
+	}
+}
+
+// TestLoadPreset tests every built-in preset loads and passes strict validation
+func TestLoadPreset(t *testing.T) {
+	names := Presets()
+	if len(names) == 0 {
+		t.Fatal("Expected built-in presets")
+	}
+
+	for _, name := range names {
+		cfg, err := LoadPreset(name, nil)
+		if err != nil {
+			t.Errorf("Preset %s: %v", name, err)
+			continue
+		}
+		if cfg.App.Name == "" || len(cfg.Endpoints) == 0 {
+			t.Errorf("Preset %s: expected a complete lab, got %+v", name, cfg.App)
+		}
+	}
+
+	_, err := LoadPreset("owasp-top10-1999", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown preset 'owasp-top10-1999' (valid: ") {
+		t.Errorf("Expected unknown preset error, got %v", err)
+	}
+}
+
+// TestLoad_Preset tests a config building on a preset
+func TestLoad_Preset(t *testing.T) {
+	cfg, err := Load(createTempYAML(t, `
+preset: beginner
+app:
+  port: 9090
+endpoints:
+  - path: /extra
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	preset, err := LoadPreset("beginner", nil)
+	if err != nil {
+		t.Fatalf("Failed to load preset: %v", err)
+	}
+
+	// App settings the file sets override the preset's, the rest are kept
+	if cfg.App.Port != 9090 || cfg.App.Name != preset.App.Name {
+		t.Errorf("Expected preset app on port 9090, got %+v", cfg.App)
+	}
+	if len(cfg.Endpoints) != len(preset.Endpoints)+1 || cfg.Endpoints[len(cfg.Endpoints)-1].Path != "/extra" {
+		t.Errorf("Expected the preset's endpoints followed by /extra, got %d endpoints", len(cfg.Endpoints))
+	}
+	if len(cfg.Files) != len(preset.Files) || len(cfg.Data.Tables) != len(preset.Data.Tables) {
+		t.Errorf("Expected the preset's files and data to be merged")
+	}
+
+	_, err = Load(createTempYAML(t, "preset: beginner\nendpoints:\n  - path: /search\n    method: GET\n    vulnerabilities: []\n"))
+	if err == nil || !strings.Contains(err.Error(), "duplicate endpoint 'GET /search' (previously defined in preset beginner)") {
+		t.Errorf("Expected duplicate endpoint error, got %v", err)
+	}
+
+	_, err = Load(createTempYAML(t, "preset: expert\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown preset 'expert'") {
+		t.Errorf("Expected unknown preset error, got %v", err)
+	}
+}
+
+// TestLoad_Difficulty tests difficulty levels fill in module settings
+func TestLoad_Difficulty(t *testing.T) {
+	const difficulty = `
+app:
+  name: difficulty
+  port: 8080
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+        difficulty: %s
+        config:
+          context: body
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(difficulty, "hard")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Values the config sets win over the level's
+	config := cfg.Endpoints[0].Vulnerabilities[0].Config
+	if config["context"] != "body" || config["encoding"] != "incomplete_html" {
+		t.Errorf("Expected hard encoding with the configured context, got %v", config)
+	}
+
+	_, err = Load(createTempYAML(t, fmt.Sprintf(difficulty, "insane")))
+	if err == nil || !strings.Contains(err.Error(), "invalid difficulty 'insane', must be one of: easy, medium, hard") {
+		t.Errorf("Expected invalid difficulty error, got %v", err)
+	}
+
+	_, err = Load(createTempYAML(t, strings.Replace(fmt.Sprintf(difficulty, "easy"), "xss_reflected", "vhost_takeover", 1)))
+	if err == nil || !strings.Contains(err.Error(), "module 'vhost_takeover' has no difficulty levels") {
+		t.Errorf("Expected no difficulty levels error, got %v", err)
+	}
+}
+
+// TestLoad_EnabledIf tests validation of vulnerability conditions
+func TestLoad_EnabledIf(t *testing.T) {
+	const conditional = `
+app:
+  name: conditional
+  port: 8080
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+        enabled_if: %s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(conditional, `{header: "X-Debug=1", time: "22:00-06:00", percent: 30}`)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	cond := cfg.Endpoints[0].Vulnerabilities[0].EnabledIf
+	if cond == nil || cond.Header != "X-Debug=1" || cond.Percent != 30 {
+		t.Errorf("Unexpected conditions %+v", cond)
+	}
+
+	tests := map[string]string{
+		"{}":                    "enabled_if needs at least one of",
+		`{header: "=1"}`:        "enabled_if.header: header condition needs a name",
+		`{time: "9am-5pm"}`:     "invalid time of day '9am', expected HH:MM",
+		`{time: "09:00"}`:       "time must be a window like 09:00-17:00",
+		`{time: "09:00-09:00"}`: "time window '09:00-09:00' is empty",
+		`{percent: 150}`:        "percent must be between 1 and 100, got 150",
+	}
+	for conditions, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(conditional, conditions)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", conditions, expected, err)
+		}
+	}
+}
+
+// TestLoad_ResponseTemplate tests response templates are loaded and checked for syntax errors
+func TestLoad_ResponseTemplate(t *testing.T) {
+	const templated = `
+app:
+  name: templated
+  port: 8080
+endpoints:
+  - path: /search
+    method: GET
+    response_type: html
+    response_template: %q
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(templated, "<h1>Results for {{ .Input }}</h1>")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	tmpl, err := cfg.Endpoints[0].ParseResponseTemplate()
+	if err != nil || tmpl == nil {
+		t.Fatalf("Expected a parsed template, got %v, %v", tmpl, err)
+	}
+
+	_, err = Load(createTempYAML(t, fmt.Sprintf(templated, "{{ .Input ")))
+	if err == nil || !strings.Contains(err.Error(), "endpoints[0].response_template: invalid response_template") {
+		t.Errorf("Expected a response_template error, got %v", err)
+	}
+
+	_, err = Load(createTempYAML(t, fmt.Sprintf(templated, "{{ shout .Input }}")))
+	if err == nil || !strings.Contains(err.Error(), `function "shout" not defined`) {
+		t.Errorf("Expected an undefined function error, got %v", err)
+	}
+}
+
+// TestLoad_Headers tests static response headers and status are loaded and validated
+func TestLoad_Headers(t *testing.T) {
+	const endpoint = `
+app:
+  name: headers
+  port: 8080
+endpoints:
+  - path: /
+    method: GET
+%s
+    vulnerabilities: []
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(endpoint, "    status: 418\n    headers:\n      Server: nginx/1.14.0")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Endpoints[0].Status != 418 || cfg.Endpoints[0].Headers["Server"] != "nginx/1.14.0" {
+		t.Errorf("Unexpected endpoint %+v", cfg.Endpoints[0])
+	}
+
+	tests := map[string]string{
+		"    status: 42":                         "invalid status 42, must be between 100 and 599",
+		"    status: 201\n    websocket: true":   "status can't be set on websocket endpoints",
+		"    headers:\n      \"X Powered\": PHP": "invalid header name 'X Powered'",
+	}
+	for fields, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(endpoint, fields)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", fields, expected, err)
+		}
+	}
+}
+
+// TestLoad_Faults tests endpoint latency and error rate are loaded and validated
+func TestLoad_Faults(t *testing.T) {
+	const endpoint = `
+app:
+  name: faults
+  port: 8080
+endpoints:
+  - path: /
+    method: GET
+%s
+    vulnerabilities: []
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(endpoint, "    latency: {min: 100ms, max: 2s}\n    error_rate: 0.05")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	minDelay, maxDelay, err := cfg.Endpoints[0].Latency.Range()
+	if err != nil || minDelay != 100*time.Millisecond || maxDelay != 2*time.Second {
+		t.Errorf("Unexpected latency range %s-%s (%v)", minDelay, maxDelay, err)
+	}
+
+	tests := map[string]string{
+		"    latency: {}":                    "latency needs a min, a max or both",
+		"    latency: {min: fast}":           "invalid duration 'fast'",
+		"    latency: {min: 2s, max: 100ms}": "max 100ms is less than min 2s",
+		"    error_rate: 5":                  "error_rate must be between 0 and 1, got 5",
+	}
+	for fields, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(endpoint, fields)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", fields, expected, err)
+		}
+	}
+}
+
+// TestLoad_Params tests several params per vulnerability and placement any
+func TestLoad_Params(t *testing.T) {
+	const vulnerability = `
+app:
+  name: params
+  port: 8080
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: %s
+%s
+`
+
+	cfg, err := LoadStrict(createTempYAML(t, fmt.Sprintf(vulnerability, "any", "        params: [q, sort, filter]")), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := cfg.Endpoints[0].Vulnerabilities[0].ParamNames(); len(got) != 3 || got[1] != "sort" {
+		t.Errorf("Unexpected params %v", got)
+	}
+
+	tests := map[string]string{
+		"        param: q\n        params: [sort]": "param and params can't be used together",
+		"        params: [q, '']":                  "params[1]: param can't be empty",
+		"        params: [q, q]":                   "params[1]: duplicate param 'q'",
+		"        params: []":                       "param is required",
+	}
+	for fields, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(vulnerability, "query_param", fields)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", fields, expected, err)
+		}
+	}
+}
+
+// TestLoad_Chains tests chains are loaded and checked against the endpoints they link
+func TestLoad_Chains(t *testing.T) {
+	const chained = `
+app:
+  name: chained
+  port: 8080
+endpoints:
+  - path: /download
+    method: GET
+    vulnerabilities: []
+  - path: /admin
+    method: POST
+    vulnerabilities: []
+chains:
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(chained, "  - name: leak\n    steps:\n      - {endpoint: GET /download, proof: tok_1}\n      - {endpoint: post /admin}")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(cfg.Chains) != 1 || !cfg.Chains[0].Steps[1].Targets(cfg.Endpoints[1]) {
+		t.Errorf("Unexpected chains %+v", cfg.Chains)
+	}
+
+	tests := map[string]string{
+		"  - steps: [{endpoint: GET /download, proof: x}, {endpoint: POST /admin}]":                  "chains[0].name: chain name is required",
+		"  - name: short\n    steps: [{endpoint: GET /download}]":                                    "a chain needs at least two steps",
+		"  - name: typo\n    steps: [{endpoint: GET /downloads, proof: x}, {endpoint: POST /admin}]": "no endpoint matches 'GET /downloads'",
+		"  - name: unproven\n    steps: [{endpoint: GET /download}, {endpoint: POST /admin}]":        "steps[0].proof: proof is required on every step but the last",
+	}
+	for chains, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(chained, chains)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", chains, expected, err)
+		}
+	}
+}
+
+func TestLoad_Overlays(t *testing.T) {
+	dir := t.TempDir()
+	base := writeYAML(t, dir, "base.yaml", `
+app:
+  name: base
+  port: 8080
+  description: Base lab
+endpoints:
+  - path: /search
+    method: GET
+    status: 200
+    vulnerabilities:
+      - type: sql_injection
+        placement: query_param
+        param: q
+        config: {variant: error_based}
+  - path: /debug
+    method: GET
+    vulnerabilities: []
+`)
+	variant := writeYAML(t, dir, "hard.yaml", `
+app:
+  port: 9090
+  description: null
+endpoints:
+  - path: /search
+    method: get
+    vulnerabilities:
+      - type: sql_injection
+        placement: query_param
+        param: q
+        difficulty: hard
+  - path: /debug
+    method: GET
+    remove: true
+  - path: /health
+    method: GET
+    vulnerabilities: []
+`)
+
+	cfg, err := LoadStrict(base, nil, variant)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.App.Name != "base" || cfg.App.Port != 9090 || cfg.App.Description != "" {
+		t.Errorf("Unexpected app %+v", cfg.App)
+	}
+	if len(cfg.Endpoints) != 2 || cfg.Endpoints[0].Path != "/search" || cfg.Endpoints[1].Path != "/health" {
+		t.Fatalf("Expected /search and /health, got %+v", cfg.Endpoints)
+	}
+	search := cfg.Endpoints[0]
+	if search.Status != 200 || search.Method != "get" {
+		t.Errorf("Expected the overlay to merge into /search, got %+v", search)
+	}
+	if vuln := search.Vulnerabilities[0]; vuln.Difficulty != "hard" || vuln.Config["variant"] == "error_based" {
+		t.Errorf("Expected the vulnerabilities list to be replaced, got %+v", vuln)
+	}
+	if len(cfg.Sources) != 2 {
+		t.Errorf("Expected both files in Sources, got %v", cfg.Sources)
+	}
+
+	tests := map[string]string{
+		"endpoints:\n  - {path: /missing, method: GET, remove: true}":     "no endpoint 'GET /missing' to remove",
+		"endpoints:\n  - {path: /debug, method: GET, remove: yes please}": "remove must be true or false",
+		"include: [extra.yaml]": "include is only allowed in the base config file",
+		"app:\n  prot: 9090":    "field prot not found",
+		"- just a list":         "an overlay must be a mapping",
+	}
+	for content, expected := range tests {
+		overlay := writeYAML(t, dir, "bad.yaml", content)
+		_, err := LoadStrict(base, nil, overlay)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", content, expected, err)
+		}
+	}
+}
+
+func TestLoad_Auth(t *testing.T) {
+	const walled = `
+app:
+  name: walled
+  port: 8080
+data:
+  tables:
+    accounts:
+      columns: [username, password]
+      rows: [[admin, hunter2]]
+endpoints:
+  - path: /admin
+    method: GET
+    vulnerabilities: []
+  - path: /
+    method: GET
+    auth: none
+    vulnerabilities: []
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(walled, "auth:\n  type: session\n  table: accounts")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !cfg.Auth.Protects(cfg.Endpoints[0]) || cfg.Auth.Protects(cfg.Endpoints[1]) {
+		t.Errorf("Expected only /admin to be protected")
+	}
+	if cfg.Auth.LoginRoute() != DefaultLoginPath || cfg.Auth.CookieName() != DefaultAuthCookie {
+		t.Errorf("Unexpected defaults %q %q", cfg.Auth.LoginRoute(), cfg.Auth.CookieName())
+	}
+
+	tests := map[string]string{
+		"":                     "auth is set but there is no auth section",
+		"auth:\n  type: oauth": "invalid auth type 'oauth'",
+		"auth:\n  type: basic": "users are required unless table names one of data.tables",
+		"auth:\n  type: api_key\n  table: accounts":                                           "table 'accounts' needs a token column",
+		"auth:\n  type: basic\n  table: accounts\n  users: [{username: a, password: b}]":      "already defined in data.tables",
+		"auth:\n  type: basic\n  protect: some\n  table: accounts":                            "invalid protect 'some'",
+		"auth:\n  type: basic\n  table: \"users; --\"":                                        "invalid table name",
+		"auth:\n  type: session\n  users: [{username: a}]":                                    "password is required for session auth",
+		"auth:\n  type: api_key\n  users: [{username: a, token: t}, {username: b, token: t}]": "token is already used by users[0]",
+		"auth:\n  type: session\n  login_path: /\n  table: accounts":                          "POST / is the login endpoint",
+	}
+	for auth, expected := range tests {
+		content := fmt.Sprintf(walled, auth)
+		if strings.Contains(auth, "login_path") {
+			content = strings.Replace(content, "method: GET\n    auth: none", "method: POST\n    auth: none", 1)
+		}
+		_, err := Load(createTempYAML(t, content))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", auth, expected, err)
+		}
+	}
+}
+
+func TestLoad_Routes(t *testing.T) {
+	const routed = `
+app:
+  name: routed
+  port: 8080
+endpoints:
+  - path: %s
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: path_param
+        param: id
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(routed, "/users/{id:int}")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	pattern, params, err := cfg.Endpoints[0].Route()
+	if err != nil || pattern != "/users/{id}" || len(params) != 1 || params[0].Type != "int" {
+		t.Errorf("Unexpected route %q %+v %v", pattern, params, err)
+	}
+	if !params[0].Matches("42") || params[0].Matches("42 OR 1=1") {
+		t.Errorf("Expected {id:int} to match only integers")
+	}
+
+	for path, expected := range map[string]string{
+		"/files/*id":                          "/files/{id...}",
+		"/orgs/{org}/users/{id:re:u[0-9]{3}}": "/orgs/{org}/users/{id}",
+		"/files/{id...:re:.*\\.txt}":          "/files/{id...}",
+	} {
+		cfg, err := Load(createTempYAML(t, fmt.Sprintf(routed, path)))
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", path, err)
+		}
+		if pattern, _, _ := cfg.Endpoints[0].Route(); pattern != expected {
+			t.Errorf("%s: expected pattern %s, got %s", path, expected, pattern)
+		}
+	}
+	_, params, _ = EndpointConfig{Path: "/users/{id:re:u[0-9]{3}}"}.Route()
+	if !params[0].Matches("u123") || params[0].Matches("u1234") || params[0].Matches("xu123") {
+		t.Errorf("Expected {id:re:...} to match whole values only")
+	}
+	_, params, _ = EndpointConfig{Path: "/files/*path"}.Route()
+	if !params[0].Wildcard || params[0].Name != "path" {
+		t.Errorf("Expected *path to be a catch-all, got %+v", params[0])
+	}
+
+	tests := map[string]string{
+		"/users":                 "path_param 'id' has no {id} segment in path '/users'",
+		"/users/{uid}":           "path_param 'id' has no {id} segment",
+		"/users/{id:number}":     "unknown type 'number' for path parameter 'id'",
+		"/users/user-{id}":       "'user-{id}' must be a whole path segment",
+		"/users/{id}/{id}":       "path parameter 'id' is used more than once",
+		"/users/{id...}/profile": "{id...} must be the last path segment",
+		"/users/{user-id}/{id}":  "invalid path parameter name 'user-id'",
+		"/users/{id:re:[0-9}":    "invalid pattern for path parameter 'id'",
+		"/users/*id/profile":     "{id...} must be the last path segment",
+	}
+	for path, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(routed, path)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", path, expected, err)
+		}
+	}
+}
+
+// TestLoad_TLS tests HTTPS settings are validated
+func TestLoad_TLS(t *testing.T) {
+	const secure = `
+app:
+  name: secure
+  port: 8443
+  tls:
+    enabled: true
+%s
+endpoints:
+  - path: /
+    method: GET
+    response:
+      type: json
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(secure, "    auto_generate: true\n    ca: true\n    hosts: [lab.local, 10.0.0.5]\n    redirect_port: 8080\n    client_auth: verify")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if tls := cfg.App.TLS; !tls.CA || len(tls.Hosts) != 2 || tls.RedirectPort != 8080 || !tls.VerifiesClients() {
+		t.Errorf("Unexpected TLS settings %+v", tls)
+	}
+
+	tests := map[string]string{
+		"    cert_file: server.crt":                               "cert_file and key_file are required unless auto_generate is set",
+		"    cert_file: a.crt\n    key_file: a.key\n    ca: true": "ca only applies to auto_generate certificates",
+		"    auto_generate: true\n    hosts: ['http://lab']":      "invalid host 'http://lab'",
+		"    auto_generate: true\n    redirect_port: 8443":        "redirect_port must be between 1 and 65535 and differ from app.port",
+		"    auto_generate: true\n    client_auth: always":        "invalid client_auth 'always'",
+		"    auto_generate: true\n    client_auth: verify":        "client_auth verify needs client_ca_file, or auto_generate with ca: true",
+		"    auto_generate: true\n    client_ca_file: ca.crt":     "client_ca_file is only used with client_auth verify or verify_if_given",
+	}
+	for settings, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(secure, settings)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", settings, expected, err)
+		}
+	}
+}
+
+// TestLoad_Flags tests flag definitions and references are validated
+func TestLoad_Flags(t *testing.T) {
+	const ctf = `
+app:
+  name: ctf
+  port: 8080
+data:
+  tables:
+    secrets:
+      columns: [id, value]
+      rows:
+        - [1, "${flag:db}"]
+files:
+  - path: /etc/flag.txt
+    content: "${flag:%s}"
+endpoints:
+  - path: /search
+    method: GET
+    headers:
+      X-Debug: "${flag:db}"
+    vulnerabilities:
+      - type: nosql_injection
+        placement: query_param
+        param: q
+  - path: /ws
+    method: GET
+    websocket: true
+flags:
+  - name: db
+  - name: file
+    value: FLAG{file}
+    env: FILE_FLAG
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(ctf, "file", "  - name: search\n    reveal:\n      endpoint: GET /search")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(cfg.Flags) != 3 || cfg.Flags[2].Reveal.HeaderName() != "X-Flag" {
+		t.Errorf("Unexpected flags %+v", cfg.Flags)
+	}
+	if cfg.Files[0].Content != "${flag:file}" {
+		t.Errorf("Expected the reference to survive variable substitution, got %q", cfg.Files[0].Content)
+	}
+
+	tests := map[[2]string]string{
+		{"missing", ""}:                                                 "files[0].content: unknown flag 'missing'",
+		{"file", "  - name: db"}:                                        "duplicate flag 'db'",
+		{"file", "  - name: bad-name"}:                                  "invalid flag name 'bad-name'",
+		{"file", "  - name: f\n    value: x\n    format: F{%s}"}:        "format only applies to generated flags",
+		{"file", "  - name: f\n    format: FLAG{%d}"}:                   "format must contain %s exactly once",
+		{"file", "  - name: f\n    env: FILE_FLAG"}:                     "environment variable 'FILE_FLAG' is already used by flags[1]",
+		{"file", "  - name: f\n    reveal:\n      endpoint: GET /nope"}: "no endpoint matches 'GET /nope'",
+		{"file", "  - name: f\n    reveal:\n      endpoint: GET /ws"}:   "'GET /ws' is a WebSocket endpoint",
+	}
+	for input, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(ctf, input[0], input[1])))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", input, expected, err)
+		}
+	}
+}
+
+// TestLoad_EndpointTypes tests redirect, proxy and alias endpoints are validated
+func TestLoad_EndpointTypes(t *testing.T) {
+	const gateway = `
+app:
+  name: gateway
+  port: 8080
+endpoints:
+  - path: /users/{id}
+    method: GET
+    vulnerabilities:
+      - type: idor
+        placement: path_param
+        param: id
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(gateway, `  - path: /profile/{id}
+    method: GET
+    type: redirect
+    target: /users/{id}
+    status: 301
+  - path: /internal/{rest...}
+    method: GET
+    type: proxy
+    target: http://127.0.0.1:8082/{rest}
+  - path: /v1/users/{id}
+    method: GET
+    type: alias
+    target: GET /users/{id}`)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if target, ok := cfg.Endpoints[3].AliasTarget(cfg.Endpoints); !ok || target.Path != "/users/{id}" {
+		t.Errorf("Expected the alias to resolve, got %+v", target)
+	}
+
+	tests := map[string]string{
+		"  - path: /a\n    method: GET\n    type: rewrite\n    target: /b":                       "invalid endpoint type 'rewrite'",
+		"  - path: /a\n    method: GET\n    target: /b":                                          "target only applies to endpoints of type redirect, proxy, alias",
+		"  - path: /a\n    method: GET\n    type: redirect":                                      "target is required for redirect endpoints",
+		"  - path: /a\n    method: GET\n    type: redirect\n    target: /b\n    status: 200":     "invalid redirect status 200",
+		"  - path: /a\n    method: GET\n    type: redirect\n    target: b":                       "redirect target must be a path or an http(s) URL",
+		"  - path: /a\n    method: GET\n    type: redirect\n    target: /b/{id}":                 "{id} in target has no {id} segment in path '/a'",
+		"  - path: /a\n    method: GET\n    type: proxy\n    target: /b":                         "proxy target must be an http(s) URL",
+		"  - path: /a\n    method: GET\n    type: proxy\n    target: http://x/\n    status: 200": "status doesn't apply to proxy endpoints",
+		"  - path: /a\n    method: GET\n    type: alias\n    target: GET /nope":                  "no endpoint matches 'GET /nope'",
+		"  - path: /a\n    method: GET\n    type: alias\n    target: GET /users/{id}":            "path must have a {id} segment like its target '/users/{id}'",
+		"  - path: /a/{id}\n    method: GET\n    type: alias\n    target: GET /users/{id}\n    vulnerabilities:\n      - type: xss_reflected\n        placement: query_param\n        param: q": "vulnerabilities doesn't apply to alias endpoints",
+	}
+	for endpoint, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(gateway, endpoint)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", endpoint, expected, err)
+		}
+	}
+}
+
+// TestLoad_UI tests the ui section's pages are validated against their endpoints
+func TestLoad_UI(t *testing.T) {
+	const lab = `
+app:
+  name: shop
+  port: 8080
+auth:
+  type: session
+  protect: listed
+  users:
+    - username: admin
+      password: admin
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+  - path: /comments
+    method: POST
+    vulnerabilities:
+      - type: xss_reflected
+        placement: form_field
+        param: body
+  - path: /comments
+    method: GET
+  - path: /items/{id}
+    method: GET
+  - path: /items
+    method: DELETE
+ui:
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, `  title: Acme
+  pages:
+    - type: login
+    - type: search
+      endpoint: GET /search
+    - type: comments
+      endpoint: POST /comments
+      list: GET /comments`)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if path := cfg.UI.PagePath(cfg.UI.Pages[1]); path != "/ui/search" {
+		t.Errorf("Expected /ui/search, got %s", path)
+	}
+	if endpoint, ok := cfg.UI.Pages[0].FormEndpoint(cfg.Endpoints, cfg.Auth); !ok || endpoint.Path != "/login" {
+		t.Errorf("Expected the login page to post to /login, got %+v", endpoint)
+	}
+
+	tests := map[string]string{
+		"  pages:\n    - type: cart":                                                                                 "invalid page type 'cart'",
+		"  pages:\n    - type: search":                                                                               "endpoint is required",
+		"  pages:\n    - type: search\n      endpoint: GET /nope":                                                    "no endpoint matches 'GET /nope'",
+		"  pages:\n    - type: search\n      endpoint: DELETE /items\n      fields: [id]":                            "only sends GET or POST",
+		"  pages:\n    - type: search\n      endpoint: GET /items/{id}\n      fields: [q]":                           "has path parameters",
+		"  pages:\n    - type: search\n      endpoint: GET /comments":                                                "fields is required, no vulnerability of 'GET /comments' reads a query_param",
+		"  pages:\n    - type: search\n      endpoint: GET /search\n      fields: [q, q]":                            "duplicate field 'q'",
+		"  pages:\n    - type: search\n      endpoint: GET /search\n      path: /search":                             "'/search' is already used by endpoint 'GET /search'",
+		"  pages:\n    - type: search\n      endpoint: GET /search\n    - type: search\n      endpoint: GET /search": "'/ui/search' is already used by ui.pages[0]",
+		"  pages:\n    - type: search\n      endpoint: GET /search\n      list: GET /comments":                       "list doesn't apply to search pages",
+		"  pages:\n    - type: comments\n      endpoint: POST /comments\n      list: POST /comments":                 "must be a GET endpoint",
+		"  path: ui": "path must start with '/'",
+	}
+	for ui, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, ui)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", ui, expected, err)
+		}
+	}
+}
+
+// TestLoad_Static tests static directories are validated
+func TestLoad_Static(t *testing.T) {
+	const lab = `
+app:
+  name: assets
+  port: 8080
+endpoints:
+  - path: /files/
+    method: GET
+static:
+%s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "  - path: /assets/\n    dir: www/assets\n  - path: /backup\n    dir: .\n    listing: true")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(cfg.Static) != 2 || cfg.Static[0].Prefix() != "/assets" || !cfg.Static[1].Listing {
+		t.Errorf("Unexpected static entries: %+v", cfg.Static)
+	}
+
+	tests := map[string]string{
+		"  - dir: www":                                          "path is required",
+		"  - path: assets\n    dir: www":                        "path must start with '/'",
+		"  - path: /a/{id}\n    dir: www":                       "path can't have parameters",
+		"  - path: /a\n    dir: www\n  - path: /a/\n    dir: x": "duplicate static path '/a/'",
+		"  - path: /files\n    dir: www":                        "'/files' is already used by endpoint 'GET /files/'",
+		"  - path: /a":                                          "dir is required",
+		"  - path: /a\n    dir: ../etc":                         "dir must be a relative path inside the filesystem sink",
+		"  - path: /a\n    dir: /etc":                           "dir must be a relative path inside the filesystem sink",
+	}
+	for static, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, static)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", static, expected, err)
+		}
+	}
+}
+
+// TestLoad_Uploads tests the multipart placements and upload limits are validated
+func TestLoad_Uploads(t *testing.T) {
+	const lab = `
+app:
+  name: uploads
+  port: 8080
+%s
+endpoints:
+  - path: /upload
+    method: POST
+    vulnerabilities:
+      - type: path_traversal
+        placement: %s
+        param: file
+`
+
+	for _, placement := range []string{"multipart_field", "multipart-form", "file"} {
+		if _, err := Load(createTempYAML(t, fmt.Sprintf(lab, "", placement))); err != nil {
+			t.Errorf("%s: expected no error, got: %v", placement, err)
+		}
+	}
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "  uploads:\n    max_body_size: 1048576\n    max_file_size: 65536", "file")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.App.Uploads == nil || cfg.App.Uploads.MaxFileSize != 65536 {
+		t.Errorf("Expected max_file_size 65536, got %+v", cfg.App.Uploads)
+	}
+
+	tests := map[string]string{
+		"  uploads:\n    max_file_size: -1":                            "cannot be negative",
+		"  uploads:\n    max_body_size: 1024\n    max_file_size: 2048": "max_file_size (2048) can't be larger than max_body_size (1024)",
+	}
+	for uploads, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, uploads, "file")))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", uploads, expected, err)
+		}
+	}
+}
+
+// TestLoad_BodyPlacements tests raw_body and graphql_query take no param, while graphql_variable needs one
+func TestLoad_BodyPlacements(t *testing.T) {
+	const lab = `
+app:
+  name: body
+  port: 8080
+endpoints:
+  - path: /graphql
+    method: POST
+    vulnerabilities:
+      - type: %s
+        placement: %s
+%s`
+
+	valid := map[string]string{
+		"raw_body":         "",
+		"graphql_query":    "",
+		"graphql_variable": "        param: filter.name\n",
+	}
+	for placement, param := range valid {
+		module := "nosql_injection"
+		if placement == "raw_body" {
+			module = "insecure_deserialization"
+		}
+		if _, err := Load(createTempYAML(t, fmt.Sprintf(lab, module, placement, param))); err != nil {
+			t.Errorf("%s: expected no error, got: %v", placement, err)
+		}
+	}
+
+	tests := map[string]string{
+		"raw_body":         "raw_body reads the whole body, so it takes no param",
+		"graphql_variable": "param",
+	}
+	for placement, expected := range tests {
+		module, param := "nosql_injection", ""
+		if placement == "raw_body" {
+			module, param = "insecure_deserialization", "        param: data\n"
+		}
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, module, placement, param)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got %v", placement, expected, err)
+		}
+	}
+}
+
+// TestLoad_Sessions tests the sessions settings and their cookie shared with session auth
+func TestLoad_Sessions(t *testing.T) {
+	const lab = `
+app:
+  name: sessions
+  port: 8080
+  sessions:
+%s
+%s
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    ttl: 30m\n    same_site: none\n    keep_id_on_login: true", "")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if sessions := cfg.App.Sessions; sessions.IdleTimeout() != 30*time.Minute || sessions.CookieName(cfg.Auth) != "session" || !sessions.KeepIDOnLogin {
+		t.Errorf("Unexpected sessions %+v", sessions)
+	}
+
+	auth := "auth:\n  type: session\n  cookie: sid\n  users:\n    - username: alice\n      password: secret"
+	if cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    adopt_ids: true", auth))); err != nil || cfg.App.Sessions.CookieName(cfg.Auth) != "sid" {
+		t.Errorf("Expected the auth cookie, got %v", err)
+	}
+
+	tests := map[string]string{
+		"    ttl: 10ms":         "invalid ttl '10ms'",
+		"    same_site: loose":  "invalid same_site 'loose'",
+		"    cookie: my cookie": "invalid cookie name 'my cookie'",
+		"    cookie: other":     "the cookie must match auth.cookie 'sid'",
+	}
+	for sessions, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, sessions, auth)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", sessions, expected, err)
+		}
+	}
+}
+
+func TestLoad_Isolation(t *testing.T) {
+	const lab = `
+app:
+  name: isolation
+  port: 8080
+  isolation:
+%s
+%s
+endpoints:
+  - path: /
+    method: GET
+`
+	sessions := "  sessions:\n    ttl: 1h"
+	auth := "auth:\n  type: basic\n  users:\n    - username: alice\n      password: secret"
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    by: session\n    idle_expiry: 15m", sessions)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if isolation := cfg.App.Isolation; isolation.CopyLimit() != DefaultMaxCopies || isolation.IdleTimeout() != 15*time.Minute {
+		t.Errorf("Unexpected isolation %+v", isolation)
+	}
+	if cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    by: user\n    max_copies: 5", auth))); err != nil || cfg.App.Isolation.CopyLimit() != 5 {
+		t.Errorf("Expected isolation by user, got %v", err)
+	}
+
+	tests := []struct{ isolation, rest, expected string }{
+		{"    by: user", "", "isolation by user needs an auth section"},
+		{"    by: session", auth, "isolation by session needs app.sessions or session auth"},
+		{"    by: ip", sessions, "invalid isolation 'ip'"},
+		{"    by: session\n    max_copies: -1", sessions, "max_copies cannot be negative"},
+		{"    by: session\n    idle_expiry: 10ms", sessions, "invalid idle_expiry '10ms'"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, tt.isolation, tt.rest)))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.isolation, tt.expected, err)
+		}
+	}
+}
+
+func TestLoad_Admin(t *testing.T) {
+	const lab = `
+app:
+  name: admin
+  port: 8080
+  admin:
+%s
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    token: secret")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if addr := cfg.App.Admin.Address(cfg.App.Port); addr != "127.0.0.1:9080" {
+		t.Errorf("Expected the admin API beside the app, got %s", addr)
+	}
+
+	tests := map[string]string{
+		"    port: 9000":                     "token",
+		"    token: secret\n    port: 8080":  "admin port 8080 is already used by the app",
+		"    token: secret\n    port: 70000": "admin port must be between 1 and 65535",
+	}
+	for admin, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, admin)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", admin, expected, err)
+		}
+	}
+}
+
+func TestLoad_VirtualHosts(t *testing.T) {
+	const lab = `
+app:
+  name: vhosts
+  port: 8080
+endpoints:
+  - path: /
+    method: GET
+    host: "%s"
+`
+	for _, host := range []string{"portal.local", "*.tenants.local"} {
+		if _, err := Load(createTempYAML(t, fmt.Sprintf(lab, host))); err != nil {
+			t.Errorf("%s: expected no error, got: %v", host, err)
+		}
+	}
+	for _, host := range []string{"*", "*.", "api.*.local", "**.local", "portal.local:8080"} {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, host)))
+		if err == nil || !strings.Contains(err.Error(), "host must be a bare hostname") {
+			t.Errorf("%s: expected a host error, got %v", host, err)
+		}
+	}
+}
+
+func TestLoad_ErrorPages(t *testing.T) {
+	const lab = `
+app:
+  name: errors
+  port: 8080
+  errors:
+%s
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "    not_found:\n      status: 200\n      body: \"<h1>{{.Request.URL.Path | html}}</h1>\"\n    method_not_allowed:\n      verbose: true")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if errors := cfg.App.Errors; errors.NotFound.Status != 200 || !errors.MethodNotAllowed.Verbose {
+		t.Errorf("Unexpected error pages %+v", errors)
+	}
+
+	tests := map[string]string{
+		"    not_found:\n      status: 99":                   "invalid status 99",
+		"    not_found:\n      body: \"{{.Status\"":          "invalid body template",
+		"    not_found:\n      body: x\n      verbose: true": "body and verbose can't be used together",
+	}
+	for errors, expected := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, errors)))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", errors, expected, err)
+		}
+	}
+}
+
+// TestLoad_HTTP tests the listener's protocol settings
+func TestLoad_HTTP(t *testing.T) {
+	const lab = `
+app:
+  name: http
+  port: 8080
+%s
+  http:
+%s
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "", "    h2c: true\n    keep_alive: false\n    read_timeout: \"0\"\n    idle_timeout: 5m\n    max_header_bytes: 8192")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	settings := cfg.App.HTTP
+	if !settings.H2C || settings.KeepAlive == nil || *settings.KeepAlive || settings.MaxHeaderBytes != 8192 {
+		t.Errorf("Unexpected HTTP settings %+v", settings)
+	}
+	if d, ok := ParseTimeout(settings.ReadTimeout); !ok || d != 0 {
+		t.Errorf("Expected read_timeout 0 to be set, got %v %v", d, ok)
+	}
+	if _, ok := ParseTimeout(settings.WriteTimeout); ok {
+		t.Error("Expected an unset write_timeout")
+	}
+
+	tests := []struct {
+		tls      string
+		http     string
+		expected string
+	}{
+		{"", "    read_timeout: soon", "invalid read_timeout 'soon'"},
+		{"", "    idle_timeout: -1s", "invalid idle_timeout '-1s'"},
+		{"", "    max_header_bytes: -1", "max_header_bytes cannot be negative"},
+		{"  tls:\n    enabled: true\n    auto_generate: true", "    h2c: true", "h2c is HTTP/2 without TLS"},
+		{"", "    max_body_size: -1", "max_body_size cannot be negative"},
+		{"", "    protection: false\n    read_header_timeout: 5s", "read_header_timeout has no effect with protection off"},
+		{"", "    protection: false\n    max_body_size: 1024", "max_body_size has no effect with protection off"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, tt.tls, tt.http)))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.http, tt.expected, err)
+		}
+	}
+}
+
+// TestLoad_EndpointBodyLimit tests an endpoint's own body limit
+func TestLoad_EndpointBodyLimit(t *testing.T) {
+	const lab = `
+app:
+  name: limits
+  port: 8080
+%s
+endpoints:
+  - path: /upload
+    method: POST
+    max_body_size: %s
+`
+
+	cfg, err := Load(createTempYAML(t, fmt.Sprintf(lab, "", "1024")))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Endpoints[0].MaxBodySize != 1024 || !cfg.App.HTTP.Protected() {
+		t.Errorf("Expected a protected 1024 byte limit, got %d", cfg.Endpoints[0].MaxBodySize)
+	}
+
+	tests := []struct {
+		app      string
+		limit    string
+		expected string
+	}{
+		{"", "-1", "max_body_size cannot be negative"},
+		{"  http:\n    protection: false", "1024", "max_body_size has no effect with app.http.protection off"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, fmt.Sprintf(lab, tt.app, tt.limit)))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q %s: expected error containing %q, got %v", tt.app, tt.limit, tt.expected, err)
+		}
+	}
+}
+
+// TestLoad_SOAPEndpoint tests soap endpoints, their WSDL params and the
+// soap_param placement only they read
+func TestLoad_SOAPEndpoint(t *testing.T) {
+	const lab = `
+app:
+  name: soap
+  port: 8080
+endpoints:
+  - path: /ws/users
+    method: POST
+    type: soap
+    soap:
+      service: UserService
+      operations:
+        - name: GetUser
+        - name: FindUsers
+          params: [filter]
+    vulnerabilities:
+      - type: xxe
+        placement: soap_param
+        params: [id, user.id]
+      - type: xxe
+        placement: raw_body
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	endpoint := cfg.Endpoints[0]
+	if endpoint.SOAP.TargetNamespace() != "urn:UserService" {
+		t.Errorf("Expected namespace urn:UserService, got %s", endpoint.SOAP.TargetNamespace())
+	}
+	operation, ok := endpoint.SOAP.Operation("GetUser")
+	if params := endpoint.SOAPParams(operation); !ok || strings.Join(params, ",") != "id,user" {
+		t.Errorf("Expected GetUser to take id and user, got %v", params)
+	}
+	operation, _ = endpoint.SOAP.Operation("FindUsers")
+	if params := endpoint.SOAPParams(operation); strings.Join(params, ",") != "filter" {
+		t.Errorf("Expected FindUsers to take filter, got %v", params)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected string
+	}{
+		{"method", "method: POST", "method: PUT", "soap endpoints must use POST"},
+		{"no operations", "      operations:\n        - name: GetUser\n        - name: FindUsers\n          params: [filter]", "      operations: []", "soap endpoints need at least one operation"},
+		{"operation name", "name: GetUser", "name: Get User", "invalid operation name 'Get User'"},
+		{"duplicate operation", "name: FindUsers", "name: GetUser", "duplicate operation 'GetUser'"},
+		{"namespace", "service: UserService", "namespace: users", "namespace must be a URI"},
+		{"target", "type: soap", "type: soap\n    target: /x", "target doesn't apply to soap endpoints"},
+		{"placement", "type: soap", "type: \"\"", "placement 'soap_param' requires 'type: soap' on the endpoint"},
+		{"wsdl route", "endpoints:", "endpoints:\n  - path: /ws/users\n    method: GET", "serves the soap endpoint's WSDL"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, tt.from, tt.to, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+// TestLoad_GRPC tests grpc endpoints, their message fields and the gRPC listener
+func TestLoad_GRPC(t *testing.T) {
+	const lab = `
+app:
+  name: grpc
+  port: 8080
+  grpc:
+    port: 50051
+endpoints:
+  - path: /shop.v1.ProductService/Search
+    method: POST
+    type: grpc
+    grpc:
+      fields:
+        - name: query
+        - name: filter
+          type: message
+          number: 5
+          fields:
+            - name: category
+        - name: limit
+          type: int32
+    vulnerabilities:
+      - type: sql_injection
+        placement: grpc_field
+        params: [query, filter.category]
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	endpoint := cfg.Endpoints[0]
+	pkg, service, method, ok := endpoint.GRPCMethod()
+	if !ok || pkg != "shop.v1" || service != "ProductService" || method != "Search" {
+		t.Errorf("Expected shop.v1 ProductService Search, got %q %q %q", pkg, service, method)
+	}
+	if field, ok := endpoint.GRPC.Field("filter.category"); !ok || field.FieldType() != "string" {
+		t.Errorf("Expected filter.category to be a string field, got %+v", field)
+	}
+	if number := endpoint.GRPC.Fields[2].FieldNumber(2); number != 3 {
+		t.Errorf("Expected limit to default to field number 3, got %d", number)
+	}
+	if addr := cfg.App.GRPC.Address("0.0.0.0"); addr != "0.0.0.0:50051" || !cfg.App.GRPC.ReflectionEnabled() {
+		t.Errorf("Expected reflection on 0.0.0.0:50051, got %s", addr)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		expected string
+	}{
+		{"no listener", "  grpc:\n    port: 50051\n", "", "app.grpc's listener, which isn't configured"},
+		{"port clash", "port: 50051", "port: 8080", "grpc port 8080 is already used by the app"},
+		{"method", "method: POST", "method: GET", "grpc endpoints must use POST"},
+		{"path", "/shop.v1.ProductService/Search", "/search", "grpc endpoint path must be /package.Service/Method"},
+		{"status", "type: grpc", "type: grpc\n    status: 201", "status doesn't apply to grpc endpoints"},
+		{"field type", "type: int32", "type: int", "invalid field type 'int'"},
+		{"field number", "number: 5", "number: 1", "field number 1 is already used by 'query'"},
+		{"reserved number", "number: 5", "number: 19500", "reserved by protobuf"},
+		{"empty message", "          fields:\n            - name: category\n", "", "message fields need fields of their own"},
+		{"undeclared param", "filter.category]", "filter.name]", "'filter.name' isn't a scalar field"},
+		{"message param", "filter.category]", "filter]", "'filter' isn't a scalar field"},
+		{"placement", "type: grpc", "type: \"\"", "placement 'grpc_field' requires 'type: grpc' on the endpoint"},
+		{"method clash", "endpoints:", "endpoints:\n  - path: /shop.v1.OrderService/Search\n    method: POST\n    type: grpc", "whose SearchRequest message would clash"},
+		{"alias", "endpoints:", "endpoints:\n  - path: /search\n    method: POST\n    type: alias\n    target: POST /shop.v1.ProductService/Search", "can't be aliased"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, tt.from, tt.to, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+// TestLoad_Logging tests the request log format and the file extension it picks
+func TestLoad_Logging(t *testing.T) {
+	const lab = `
+app:
+  name: logging
+  port: 8080
+  logging:
+    format: cef
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.App.Logging.FormatName() != "cef" || cfg.App.Logging.FileExtension() != ".log" {
+		t.Errorf("Expected cef in a .log file, got %s in %s", cfg.App.Logging.FormatName(), cfg.App.Logging.FileExtension())
+	}
+	var unset *LoggingConfig
+	if unset.FormatName() != "json" || unset.FileExtension() != ".json" {
+		t.Errorf("Expected JSON lines by default, got %s", unset.FormatName())
+	}
+
+	_, err = Load(createTempYAML(t, strings.Replace(lab, "format: cef", "format: leef", 1)))
+	if err == nil || !strings.Contains(err.Error(), "invalid log format 'leef'") {
+		t.Errorf("Expected an invalid format error, got %v", err)
+	}
+
+	cfg, err = Load(createTempYAML(t, strings.Replace(lab, "format: cef", `dir: /var/log/labs
+    max_bytes: 1048576
+    rotate_every: 24h
+    compress: true
+    max_files: 7
+    max_age: 168h`, 1)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	logging := cfg.App.Logging
+	if logging.Directory() != "/var/log/labs" || logging.RotationInterval() != 24*time.Hour || logging.MaxAgeDuration() != 168*time.Hour {
+		t.Errorf("Expected /var/log/labs rotated daily for a week, got %s, %s, %s", logging.Directory(), logging.RotationInterval(), logging.MaxAgeDuration())
+	}
+	if unset.Directory() != "log" || unset.RotationInterval() != 0 {
+		t.Errorf("Expected unrotated logs in log/ by default, got %s", unset.Directory())
+	}
+	if unset.BodyLimit() != DefaultLogBody || unset.RedactedHeaders() != nil || unset.RedactsSecrets() {
+		t.Errorf("Expected %d-byte bodies without redaction by default, got %d, %v", DefaultLogBody, unset.BodyLimit(), unset.RedactedHeaders())
+	}
+
+	cfg, err = Load(createTempYAML(t, strings.Replace(lab, "format: cef", `bodies: true
+    max_body: 4096
+    redact:
+      patterns: ['password=([^&]+)']`, 1)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	logging = cfg.App.Logging
+	if !logging.Bodies || logging.BodyLimit() != 4096 || !logging.RedactsSecrets() || len(logging.RedactedHeaders()) != 4 {
+		t.Errorf("Expected 4096-byte bodies with default redaction, got %+v", logging)
+	}
+
+	cfg, err = Load(createTempYAML(t, strings.Replace(lab, "format: cef", `outputs:
+      - type: elasticsearch
+        url: https://es.lab:9200
+      - type: loki
+        url: http://loki:3100
+        labels: {team: blue}
+      - type: syslog
+        address: siem.lab:514
+        network: tcp
+        facility: auth
+        format: cef
+        timeout: 2s`, 1)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	outputs := cfg.App.Logging.Outputs
+	if es := outputs[0]; es.FormatName(cfg.App.Logging) != "ecs" || es.IndexName() != DefaultLogIndex || es.TimeoutDuration() != DefaultLogOutputTimeout {
+		t.Errorf("Expected ECS documents in %s by default, got %s in %s", DefaultLogIndex, es.FormatName(cfg.App.Logging), es.IndexName())
+	}
+	if loki := outputs[1]; loki.FormatName(cfg.App.Logging) != "json" || loki.Labels["team"] != "blue" {
+		t.Errorf("Expected the log file's format, got %s", loki.FormatName(cfg.App.Logging))
+	}
+	if syslog := outputs[2]; syslog.NetworkName() != "tcp" || syslog.FacilityCode() != 4 || syslog.TimeoutDuration() != 2*time.Second {
+		t.Errorf("Expected tcp to facility 4 within 2s, got %s, %d, %s", syslog.NetworkName(), syslog.FacilityCode(), syslog.TimeoutDuration())
+	}
+
+	tests := []struct {
+		logging string
+		want    string
+	}{
+		{"max_bytes: -1", "max_bytes cannot be negative"},
+		{"rotate_every: 10ms", "invalid rotate_every '10ms'"},
+		{"rotate_every: 1h\n    max_age: forever", "invalid max_age 'forever'"},
+		{"compress: true", "compress needs max_bytes or rotate_every"},
+		{"max_files: 3", "max_files needs max_bytes or rotate_every"},
+		{"max_body: -1", "max_body cannot be negative"},
+		{"redact:\n      patterns: ['token=(']", "invalid pattern 'token=('"},
+		{"outputs:\n      - type: splunk", "invalid output type 'splunk'"},
+		{"outputs:\n      - type: loki", "invalid url ''"},
+		{"outputs:\n      - type: elasticsearch\n        url: es:9200", "invalid url 'es:9200'"},
+		{"outputs:\n      - type: elasticsearch\n        url: http://es:9200\n        format: cef", "its format must be json or ecs"},
+		{"outputs:\n      - type: syslog\n        address: siem.lab", "invalid address 'siem.lab'"},
+		{"outputs:\n      - type: syslog\n        address: siem.lab:514\n        network: tls", "invalid network 'tls'"},
+		{"outputs:\n      - type: syslog\n        address: siem.lab:514\n        facility: local9", "invalid facility 'local9'"},
+		{"outputs:\n      - type: syslog\n        address: siem.lab:514\n        index: labs", "index only applies to elasticsearch outputs"},
+		{"outputs:\n      - type: elasticsearch\n        url: http://es:9200\n        labels: {team: blue}", "labels only applies to loki outputs"},
+		{"outputs:\n      - type: loki\n        url: http://loki:3100\n        labels: {team-name: blue}", "invalid label name 'team-name'"},
+		{"outputs:\n      - type: loki\n        url: http://loki:3100\n        timeout: soon", "invalid timeout 'soon'"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, "format: cef", tt.logging, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.logging, tt.want, err)
+		}
+	}
+}
+
+// TestLoad_Notifications tests the targets of exploitation events
+func TestLoad_Notifications(t *testing.T) {
+	const lab = `
+app:
+  name: notifications
+  port: 8080
+  notifications:
+    - url: https://hooks.example.com/lab
+      headers:
+        Authorization: Bearer hook
+    - type: slack
+      url: https://hooks.slack.com/services/T0/B0/X
+      timeout: 2s
+    - type: http
+      url: http://grader.local/pwned
+      body: "{{.Client}} {{.Module}}"
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	notifications := cfg.App.Notifications
+	if len(notifications) != 3 || notifications[0].TypeName() != "webhook" || notifications[0].TimeoutDuration() != DefaultNotificationTimeout || notifications[1].TimeoutDuration() != 2*time.Second {
+		t.Errorf("Expected a webhook, Slack and http target, got %+v", notifications)
+	}
+
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"type: slack", "type: teams", "invalid notification type 'teams'"},
+		{"url: http://grader.local/pwned", "url: grader.local", "invalid url 'grader.local'"},
+		{`body: "{{.Client}} {{.Module}}"`, `body: "{{.Client"`, "invalid body template"},
+		{`body: "{{.Client}} {{.Module}}"`, "content_type: text/plain", "an http notification needs a body template"},
+		{"timeout: 2s", "timeout: soon", "invalid timeout 'soon'"},
+		{"type: slack\n", "type: slack\n      body: hi\n", "body and content_type only apply to http notifications"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, tt.from, tt.to, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.to, tt.want, err)
+		}
+	}
+}
+
+// TestLoad_Tracing tests the OpenTelemetry collector settings
+func TestLoad_Tracing(t *testing.T) {
+	const lab = `
+app:
+  name: tracing
+  port: 8080
+  tracing:
+    endpoint: http://localhost:4318
+    sample_ratio: 0.25
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	tracing := cfg.App.Tracing
+	if tracing.TracesURL() != "http://localhost:4318/v1/traces" || tracing.Service("tracing") != "tracing" || tracing.Ratio() != 0.25 {
+		t.Errorf("Expected traces sent to the collector's traces path, got %s", tracing.TracesURL())
+	}
+	custom := &TracingConfig{Endpoint: "https://otel.example.com/api/traces"}
+	if custom.TracesURL() != custom.Endpoint || custom.Ratio() != 1 {
+		t.Errorf("Expected an endpoint with a path used as is, got %s", custom.TracesURL())
+	}
+
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"endpoint: http://localhost:4318", "endpoint: localhost:4318", "invalid endpoint 'localhost:4318'"},
+		{"sample_ratio: 0.25", "sample_ratio: 2", "sample_ratio must be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, tt.from, tt.to, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.to, tt.want, err)
+		}
+	}
+}
+
+// TestLoad_Capture tests the traffic capture limits
+func TestLoad_Capture(t *testing.T) {
+	const lab = `
+app:
+  name: capture
+  port: 8080
+  capture:
+    max_entries: 50
+    pcap: true
+endpoints:
+  - path: /
+    method: GET
+`
+
+	cfg, err := Load(createTempYAML(t, lab))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	capture := cfg.App.Capture
+	if capture.Entries() != 50 || capture.BodyLimit() != DefaultCaptureBody || !capture.PCAP || capture.PCAPLimit() != DefaultCapturePCAP {
+		t.Errorf("Expected 50 entries and the default limits, got %+v", capture)
+	}
+
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"max_entries: 50", "max_entries: -1", "max_entries cannot be negative"},
+		{"max_entries: 50", "max_body: -1", "max_body cannot be negative"},
+		{"pcap: true", "max_pcap: 1024", "max_pcap needs pcap: true"},
+	}
+	for _, tt := range tests {
+		_, err := Load(createTempYAML(t, strings.Replace(lab, tt.from, tt.to, 1)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.to, tt.want, err)
+		}
+	}
+}
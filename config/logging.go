@@ -0,0 +1,328 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// logFormats are the formats request logs can be written in
+var logFormats = []string{"json", "cef", "ecs"}
+
+// DefaultLogDir is the directory request logs are written to
+const DefaultLogDir = "log"
+
+// DefaultLogBody is how many bytes of each body are logged
+const DefaultLogBody = 10000
+
+// defaultRedactedHeaders are the request headers redaction hides by default
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// FormatName returns the request log format
+func (l *LoggingConfig) FormatName() string {
+	if l == nil || l.Format == "" {
+		return "json"
+	}
+	return l.Format
+}
+
+// FileExtension returns the extension of request log files in the format:
+// .json for JSON lines, .log for CEF
+func (l *LoggingConfig) FileExtension() string {
+	if l.FormatName() == "cef" {
+		return ".log"
+	}
+	return ".json"
+}
+
+// Directory returns the directory request logs are written to
+func (l *LoggingConfig) Directory() string {
+	if l == nil || l.Dir == "" {
+		return DefaultLogDir
+	}
+	return l.Dir
+}
+
+// RotationInterval returns how old a log file is rotated at, 0 for never
+func (l *LoggingConfig) RotationInterval() time.Duration {
+	if l == nil {
+		return 0
+	}
+	d, _ := time.ParseDuration(l.RotateEvery)
+	return d
+}
+
+// MaxAgeDuration returns how long rotated files are kept, 0 for ever
+func (l *LoggingConfig) MaxAgeDuration() time.Duration {
+	if l == nil {
+		return 0
+	}
+	d, _ := time.ParseDuration(l.MaxAge)
+	return d
+}
+
+// BodyLimit returns how many bytes of each body are logged
+func (l *LoggingConfig) BodyLimit() int {
+	if l == nil || l.MaxBody == 0 {
+		return DefaultLogBody
+	}
+	return l.MaxBody
+}
+
+// RedactedHeaders returns the request headers whose values are hidden, none
+// without redaction
+func (l *LoggingConfig) RedactedHeaders() []string {
+	if l == nil || l.Redact == nil {
+		return nil
+	}
+	if l.Redact.Headers == nil {
+		return defaultRedactedHeaders
+	}
+	return l.Redact.Headers
+}
+
+// RedactsSecrets reports whether the lab's configured secrets are hidden
+func (l *LoggingConfig) RedactsSecrets() bool {
+	return l != nil && l.Redact != nil && (l.Redact.Secrets == nil || *l.Redact.Secrets)
+}
+
+// validateLogging validates the request log settings
+func validateLogging(logging *LoggingConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if !slices.Contains(logFormats, logging.FormatName()) {
+		errs = append(errs, ValidationError{
+			Field:   "app.logging.format",
+			Message: fmt.Sprintf("invalid log format '%s', must be one of: %s", logging.Format, strings.Join(logFormats, ", ")),
+		})
+	}
+
+	if logging.MaxBytes < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.logging.max_bytes",
+			Message: "max_bytes cannot be negative",
+		})
+	}
+	if logging.MaxFiles < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.logging.max_files",
+			Message: "max_files cannot be negative",
+		})
+	}
+	if logging.MaxBody < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.logging.max_body",
+			Message: "max_body cannot be negative",
+		})
+	}
+	if logging.Redact != nil {
+		for i, pattern := range logging.Redact.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("app.logging.redact.patterns[%d]", i),
+					Message: fmt.Sprintf("invalid pattern '%s': %v", pattern, err),
+				})
+			}
+		}
+	}
+	for _, duration := range []struct{ field, value string }{
+		{"rotate_every", logging.RotateEvery},
+		{"max_age", logging.MaxAge},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d < time.Second {
+			errs = append(errs, ValidationError{
+				Field:   "app.logging." + duration.field,
+				Message: fmt.Sprintf("invalid %s '%s' (a duration of at least 1s)", duration.field, duration.value),
+			})
+		}
+	}
+
+	for i, output := range logging.Outputs {
+		errs = append(errs, validateLogOutput(output, logging, fmt.Sprintf("app.logging.outputs[%d]", i))...)
+	}
+
+	// Retention applies to rotated files, which there are none of without rotation
+	if logging.MaxBytes <= 0 && logging.RotateEvery == "" {
+		for _, setting := range []struct {
+			field string
+			set   bool
+		}{
+			{"compress", logging.Compress},
+			{"max_files", logging.MaxFiles > 0},
+			{"max_age", logging.MaxAge != ""},
+		} {
+			if setting.set {
+				errs = append(errs, ValidationError{
+					Field:   "app.logging." + setting.field,
+					Message: setting.field + " needs max_bytes or rotate_every to rotate log files",
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// logOutputTypes are the backends request logs can be shipped to
+var logOutputTypes = []string{"elasticsearch", "loki", "syslog"}
+
+// syslogFacilities are the syslog facility names, by code
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// lokiLabelPattern matches the label names Loki accepts
+var lokiLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// DefaultLogIndex is the Elasticsearch index entries are shipped to
+const DefaultLogIndex = "flawfactory-requests"
+
+// DefaultLogOutputTimeout bounds how long a backend has to accept a batch
+const DefaultLogOutputTimeout = 10 * time.Second
+
+// FormatName returns the format entries are shipped in: the output's, else
+// ecs for Elasticsearch and the log file's format for the others
+func (o *LogOutputConfig) FormatName(logging *LoggingConfig) string {
+	switch {
+	case o.Format != "":
+		return o.Format
+	case o.Type == "elasticsearch":
+		return "ecs"
+	}
+	return logging.FormatName()
+}
+
+// IndexName returns the Elasticsearch index entries are shipped to
+func (o *LogOutputConfig) IndexName() string {
+	if o.Index == "" {
+		return DefaultLogIndex
+	}
+	return o.Index
+}
+
+// NetworkName returns how syslog messages are sent, udp by default
+func (o *LogOutputConfig) NetworkName() string {
+	if o.Network == "" {
+		return "udp"
+	}
+	return o.Network
+}
+
+// FacilityCode returns the syslog facility messages are sent with, local0
+// by default
+func (o *LogOutputConfig) FacilityCode() int {
+	if o.Facility == "" {
+		return slices.Index(syslogFacilities, "local0")
+	}
+	return slices.Index(syslogFacilities, o.Facility)
+}
+
+// TimeoutDuration returns how long a backend has to accept a batch
+func (o *LogOutputConfig) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(o.Timeout)
+	if err != nil || d <= 0 {
+		return DefaultLogOutputTimeout
+	}
+	return d
+}
+
+// validateLogOutput validates a backend request logs are shipped to
+func validateLogOutput(output LogOutputConfig, logging *LoggingConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if !slices.Contains(logOutputTypes, output.Type) {
+		return append(errs, ValidationError{
+			Field:   prefix + ".type",
+			Message: fmt.Sprintf("invalid output type '%s', must be one of: %s", output.Type, strings.Join(logOutputTypes, ", ")),
+		})
+	}
+
+	if output.Type == "syslog" {
+		if _, _, err := net.SplitHostPort(output.Address); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".address",
+				Message: fmt.Sprintf("invalid address '%s', a syslog output needs the receiver's host:port", output.Address),
+			})
+		}
+		if network := output.NetworkName(); network != "udp" && network != "tcp" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".network",
+				Message: fmt.Sprintf("invalid network '%s', must be udp or tcp", output.Network),
+			})
+		}
+		if output.FacilityCode() < 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".facility",
+				Message: fmt.Sprintf("invalid facility '%s', must be one of: %s", output.Facility, strings.Join(syslogFacilities, ", ")),
+			})
+		}
+	} else if u, err := url.Parse(output.URL); output.URL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".url",
+			Message: fmt.Sprintf("invalid url '%s', %s needs an http or https URL", output.URL, output.Type),
+		})
+	}
+
+	// Settings of one backend set on another are mistakes
+	for _, setting := range []struct {
+		field, owner string
+		set          bool
+	}{
+		{"address", "syslog", output.Address != ""},
+		{"network", "syslog", output.Network != ""},
+		{"facility", "syslog", output.Facility != ""},
+		{"index", "elasticsearch", output.Index != ""},
+		{"labels", "loki", len(output.Labels) > 0},
+		{"headers", "elasticsearch and loki", len(output.Headers) > 0},
+		{"url", "elasticsearch and loki", output.URL != ""},
+	} {
+		if setting.set && !strings.Contains(setting.owner, output.Type) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + setting.field,
+				Message: fmt.Sprintf("%s only applies to %s outputs", setting.field, setting.owner),
+			})
+		}
+	}
+
+	for name := range output.Labels {
+		if !lokiLabelPattern.MatchString(name) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".labels",
+				Message: fmt.Sprintf("invalid label name '%s', must be letters, digits and underscores, not starting with a digit", name),
+			})
+		}
+	}
+
+	format := output.FormatName(logging)
+	if !slices.Contains(logFormats, format) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".format",
+			Message: fmt.Sprintf("invalid log format '%s', must be one of: %s", output.Format, strings.Join(logFormats, ", ")),
+		})
+	} else if output.Type == "elasticsearch" && format == "cef" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".format",
+			Message: "elasticsearch indexes JSON documents, so its format must be json or ecs",
+		})
+	}
+
+	if output.Timeout != "" {
+		if d, err := time.ParseDuration(output.Timeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".timeout",
+				Message: fmt.Sprintf("invalid timeout '%s' (a positive duration)", output.Timeout),
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// notificationTypes are the kinds of target exploitation events are sent to
+var notificationTypes = []string{"webhook", "slack", "http"}
+
+// DefaultNotificationTimeout bounds how long a target has to accept an event
+const DefaultNotificationTimeout = 5 * time.Second
+
+// TypeName returns the kind of target, webhook by default
+func (n *NotificationConfig) TypeName() string {
+	if n.Type == "" {
+		return "webhook"
+	}
+	return n.Type
+}
+
+// TimeoutDuration returns how long a target has to accept an event
+func (n *NotificationConfig) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(n.Timeout)
+	if err != nil || d <= 0 {
+		return DefaultNotificationTimeout
+	}
+	return d
+}
+
+// ParseBody parses the body template of an http target, or returns nil if it has none
+func (n *NotificationConfig) ParseBody() (*template.Template, error) {
+	if n.Body == "" {
+		return nil, nil
+	}
+	return template.New("notification").Option("missingkey=zero").Parse(n.Body)
+}
+
+// validateNotification validates a target of exploitation events
+func validateNotification(notification NotificationConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	kind := notification.TypeName()
+	if !slices.Contains(notificationTypes, kind) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".type",
+			Message: fmt.Sprintf("invalid notification type '%s', must be one of: %s", notification.Type, strings.Join(notificationTypes, ", ")),
+		})
+	}
+
+	if u, err := url.Parse(notification.URL); notification.URL == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".url",
+			Message: "url is required",
+		})
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".url",
+			Message: fmt.Sprintf("invalid url '%s', must be an http or https URL", notification.URL),
+		})
+	}
+
+	if kind == "http" && notification.Body == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".body",
+			Message: "an http notification needs a body template",
+		})
+	}
+	if kind != "http" && (notification.Body != "" || notification.ContentType != "") {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".body",
+			Message: fmt.Sprintf("body and content_type only apply to http notifications, %s sends its own", kind),
+		})
+	}
+	if _, err := notification.ParseBody(); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".body",
+			Message: fmt.Sprintf("invalid body template: %v", err),
+		})
+	}
+
+	if notification.Timeout != "" {
+		if d, err := time.ParseDuration(notification.Timeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".timeout",
+				Message: fmt.Sprintf("invalid timeout '%s' (a positive duration)", notification.Timeout),
+			})
+		}
+	}
+
+	return errs
+}
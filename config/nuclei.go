@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// NucleiTemplate is a Nuclei template checking one vulnerability of an endpoint
+type NucleiTemplate struct {
+	ID   string          `yaml:"id"`
+	Info NucleiInfo      `yaml:"info"`
+	HTTP []NucleiRequest `yaml:"http"`
+}
+
+// NucleiInfo describes what a template finds
+type NucleiInfo struct {
+	Name        string            `yaml:"name"`
+	Author      string            `yaml:"author"`
+	Severity    string            `yaml:"severity"`
+	Description string            `yaml:"description"`
+	Tags        string            `yaml:"tags"`
+	Metadata    map[string]string `yaml:"metadata,omitempty"`
+}
+
+// NucleiRequest is the raw requests a template sends and what their responses
+// must match
+type NucleiRequest struct {
+	Raw               []string          `yaml:"raw"`
+	CookieReuse       bool              `yaml:"cookie-reuse,omitempty"`
+	ReqCondition      bool              `yaml:"req-condition,omitempty"`
+	Extractors        []NucleiExtractor `yaml:"extractors,omitempty"`
+	MatchersCondition string            `yaml:"matchers-condition,omitempty"`
+	Matchers          []NucleiMatcher   `yaml:"matchers"`
+}
+
+// NucleiMatcher is one check on the responses
+type NucleiMatcher struct {
+	Type      string   `yaml:"type"`
+	Part      string   `yaml:"part,omitempty"`
+	Words     []string `yaml:"words,omitempty"`
+	Regex     []string `yaml:"regex,omitempty"`
+	DSL       []string `yaml:"dsl,omitempty"`
+	Status    []int    `yaml:"status,omitempty"`
+	Negative  bool     `yaml:"negative,omitempty"`
+	Condition string   `yaml:"condition,omitempty"`
+}
+
+// NucleiExtractor takes a value from a response for later requests
+type NucleiExtractor struct {
+	Type     string   `yaml:"type"`
+	Name     string   `yaml:"name"`
+	JSON     []string `yaml:"json"`
+	Internal bool     `yaml:"internal"`
+}
+
+// nucleiSeverities are the severities of the modules' findings, after the
+// scores the request log gives their attacks
+var nucleiSeverities = map[string]string{
+	"sql_injection":           "critical",
+	"command_injection":       "critical",
+	"deserialization":         "critical",
+	"ssti":                    "critical",
+	"nosql_injection":         "high",
+	"ssrf":                    "high",
+	"xxe":                     "high",
+	"bfla":                    "high",
+	"two_factor_bypass":       "high",
+	"path_traversal":          "high",
+	"vhost_takeover":          "high",
+	"ldap_injection":          "high",
+	"idor":                    "medium",
+	"business_logic":          "medium",
+	"method_override":         "medium",
+	"xss_reflected":           "medium",
+	"excessive_data_exposure": "medium",
+	"account_enumeration":     "medium",
+	"insecure_cookies":        "medium",
+}
+
+// nucleiIDPattern matches the runs of characters left out of template IDs
+var nucleiIDPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NucleiTemplates returns a Nuclei template per canonical exploit of an app,
+// sending its requests and matching what they show when it worked. The
+// templates target the lab itself, so a scanner's or template's results can
+// be checked against what the config says is there.
+func NucleiTemplates(cfg *Config) []NucleiTemplate {
+	var templates []NucleiTemplate
+	seen := make(map[string]int)
+
+	for _, x := range Exploits(cfg) {
+		id := strings.Trim(nucleiIDPattern.ReplaceAllString(strings.ToLower(x.Group+" "+x.Vuln.Type), "-"), "-")
+		if seen[id]++; seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+
+		request := NucleiRequest{ReqCondition: len(x.Requests) > 1}
+		var credentials [][2]string
+		if x.Login != nil {
+			request.Raw = append(request.Raw, nucleiRaw(*x.Login, x.Login.Header))
+			request.CookieReuse = !x.BearerLogin
+			if x.BearerLogin {
+				request.Extractors = []NucleiExtractor{{Type: "json", Name: "token", JSON: []string{".token"}, Internal: true}}
+				credentials = [][2]string{{"Authorization", "Bearer {{token}}"}}
+			}
+		}
+		first := len(request.Raw) + 1 // The index of the first exploit response in DSL matchers
+		for _, req := range x.Requests {
+			request.Raw = append(request.Raw, nucleiRaw(req, append(slices.Clone(req.Header), credentials...)))
+		}
+
+		for _, m := range x.Matchers {
+			matcher := NucleiMatcher{Type: "word", Part: "body", Words: m.Words, Negative: m.Negative}
+			if m.Part != "" {
+				matcher.Part = m.Part
+			}
+			if len(m.Regex) > 0 {
+				matcher.Type, matcher.Regex = "regex", m.Regex
+			}
+			request.Matchers = append(request.Matchers, matcher)
+		}
+		var dsl []string
+		switch x.Compare {
+		case CompareDiffer:
+			dsl = append(dsl, fmt.Sprintf("status_code_%d != status_code_%d || body_%d != body_%d", first, first+1, first, first+1))
+		case CompareRecords:
+			dsl = append(dsl, fmt.Sprintf("status_code_%d == 200 && status_code_%d == 200 && body_%d != body_%d", first, first+1, first, first+1))
+		}
+		if x.Delay > 0 {
+			dsl = append(dsl, fmt.Sprintf("duration_%d >= %g", len(request.Raw), x.Delay.Seconds()))
+		}
+		if len(dsl) > 0 {
+			matcher := NucleiMatcher{Type: "dsl", DSL: dsl}
+			if len(dsl) > 1 {
+				matcher.Condition = "and"
+			}
+			request.Matchers = append(request.Matchers, matcher)
+		}
+		if x.MatchAll || len(dsl) > 0 && len(x.Matchers) > 0 {
+			request.MatchersCondition = "and"
+		}
+
+		templates = append(templates, NucleiTemplate{
+			ID: id,
+			Info: NucleiInfo{
+				Name:        fmt.Sprintf("%s - %s at %s", cfg.App.Name, x.Name, x.Group),
+				Author:      "flawfactory",
+				Severity:    nucleiSeverities[x.Vuln.Type],
+				Description: fmt.Sprintf("%s configured at %s reads %s; generated from the lab's config.", x.Vuln.Type, x.Group, strings.TrimPrefix(x.Name, x.Vuln.Type+" in ")),
+				Tags:        "flawfactory," + x.Vuln.Type + "," + x.Vuln.Placement,
+				Metadata:    map[string]string{"app": cfg.App.Name, "module": x.Vuln.Type, "endpoint": x.Group},
+			},
+			HTTP: []NucleiRequest{request},
+		})
+	}
+	return templates
+}
+
+// nucleiRaw returns a request as a raw Nuclei request, sent to {{Hostname}}
+// unless it has a virtual host. Nuclei would evaluate {{ in the payloads of
+// JSON bodies, so those are sent escaped.
+func nucleiRaw(req ExampleRequest, header [][2]string) string {
+	raw := string(rawHTTPRequest(req, header, "{{Hostname}}"))
+	head, body, _ := strings.Cut(raw, "\r\n\r\n")
+	if strings.HasPrefix(strings.TrimSpace(body), "{") {
+		body = strings.ReplaceAll(body, "{{", `\u007b{`)
+	}
+	return strings.ReplaceAll(head, "\r\n", "\n") + "\n\n" + body
+}
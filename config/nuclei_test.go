@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// nucleiTestConfig has vulnerabilities with a marker, with a true and false
+// condition to compare and with nothing to detect, one behind a session login
+const nucleiTestConfig = `
+app:
+  name: "Nuclei Lab"
+  port: 9000
+
+auth:
+  type: session
+  protect: listed
+  users:
+    - {username: alice, password: secret}
+
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: sql_injection
+        placement: query_param
+        param: q
+        config:
+          variant: blind_boolean
+          query_template: "SELECT * FROM users WHERE name = '{input}'"
+  - path: /ping
+    method: POST
+    auth: required
+    vulnerabilities:
+      - type: command_injection
+        placement: form_field
+        param: host
+        config:
+          base_command: "ping -c 1 {input}"
+          filter: basic_pipe
+          variant: blind
+  - path: /fetch
+    method: GET
+    vulnerabilities:
+      - type: ssrf
+        placement: query_param
+        param: url
+        config:
+          filter: basic_host
+      - type: ssti
+        placement: query_param
+        param: name
+        config:
+          engine: smarty
+  - path: /read
+    method: GET
+    vulnerabilities:
+      - type: path_traversal
+        placement: query_param
+        param: file
+        config:
+          base_path: var/www
+`
+
+// TestNucleiTemplates tests a template is generated per detectable
+// vulnerability, with its payload, matchers and the login it needs
+func TestNucleiTemplates(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", nucleiTestConfig))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	templates := make(map[string]NucleiTemplate)
+	for _, template := range NucleiTemplates(cfg) {
+		templates[template.ID] = template
+	}
+	if len(templates) != 4 {
+		t.Errorf("Expected a template per vulnerability but the unknown engine's, got %v", templates)
+	}
+
+	sqli := templates["get-search-sql-injection"]
+	request := sqli.HTTP[0]
+	if sqli.Info.Severity != "critical" || len(request.Raw) != 2 || !request.ReqCondition {
+		t.Fatalf("Expected a critical template comparing 2 requests, got %+v", sqli)
+	}
+	if !strings.HasPrefix(request.Raw[0], "GET /search?q=x%27+OR+%271%27%3D%271 HTTP/1.1\nHost: {{Hostname}}\n") {
+		t.Errorf("Expected the true condition sent to {{Hostname}}, got %q", request.Raw[0])
+	}
+	if request.Matchers[0].DSL[0] != "status_code_1 != status_code_2 || body_1 != body_2" {
+		t.Errorf("Expected the responses compared, got %v", request.Matchers)
+	}
+
+	ping := templates["post-ping-command-injection"].HTTP[0]
+	if len(ping.Raw) != 2 || !strings.HasPrefix(ping.Raw[0], "POST /login ") || !ping.CookieReuse {
+		t.Fatalf("Expected a login first with its cookie kept, got %+v", ping)
+	}
+	if !strings.HasSuffix(ping.Raw[1], "\n\nhost=127.0.0.1%3B+sleep+5+%23") {
+		t.Errorf("Expected a semicolon past the pipe filter, got %q", ping.Raw[1])
+	}
+	if len(ping.Matchers) != 1 || ping.Matchers[0].DSL[0] != "duration_2 >= 5" {
+		t.Errorf("Expected the delay of the response after the login's, got %+v", ping.Matchers)
+	}
+
+	read := templates["get-read-path-traversal"].HTTP[0]
+	if !strings.HasPrefix(read.Raw[0], "GET /read?file=..%2F..%2Fetc%2Fpasswd ") {
+		t.Errorf("Expected a step out of each directory of the base path, got %q", read.Raw[0])
+	}
+
+	ssrf := templates["get-fetch-ssrf"].HTTP[0]
+	if !strings.Contains(ssrf.Raw[0], "url=http%3A%2F%2F%5B%3A%3Affff%3A7f00%3A1%5D%3A9000%2F") {
+		t.Errorf("Expected loopback past the host filter, got %q", ssrf.Raw[0])
+	}
+	if ssrf.MatchersCondition != "and" || !ssrf.Matchers[1].Negative {
+		t.Errorf("Expected the blocked message negated, got %+v", ssrf)
+	}
+}
@@ -0,0 +1,401 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OpenAPIVersion is the OpenAPI Specification version of exported documents
+const OpenAPIVersion = "3.0.3"
+
+// openAPIContentTypes maps response types to the media type they're sent as
+var openAPIContentTypes = map[string]string{
+	"json": "application/json",
+	"html": "text/html",
+	"xml":  "application/xml",
+	"text": "text/plain",
+}
+
+// openAPIParamIn maps the placements read from the URL, headers and cookies to
+// the parameter location. any tries every placement, so query stands for it.
+var openAPIParamIn = map[string]string{
+	"query_param": "query",
+	"path_param":  "path",
+	"header":      "header",
+	"cookie":      "cookie",
+	AnyPlacement:  "query",
+}
+
+// openAPIBodyTypes maps the placements read from the body to its media type;
+// ws_message and grpc_field aren't HTTP request bodies
+var openAPIBodyTypes = map[string]string{
+	"form_field":       "application/x-www-form-urlencoded",
+	"json_field":       "application/json",
+	"graphql_variable": "application/json",
+	"graphql_query":    "application/json",
+	"multipart_field":  "multipart/form-data",
+	"multipart-form":   "multipart/form-data",
+	"file":             "multipart/form-data",
+	"raw_body":         "text/plain",
+	"soap_param":       "text/xml",
+}
+
+// operationIDPattern matches the runs of characters left out of operation IDs
+var operationIDPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// OpenAPI returns an OpenAPI document of an app's endpoints: their paths and
+// methods, the parameters and body fields their vulnerabilities read and what
+// they respond with, plus the login, WSDL, UI and static routes the lab adds.
+// It is meant for Swagger UI, Postman and scanners that take specs, so it
+// doesn't say which vulnerability reads what. gRPC methods, served on their own
+// listener, are left out, and of endpoints sharing a method and path on
+// different virtual hosts only the first is listed.
+func OpenAPI(cfg *Config) map[string]interface{} {
+	paths := make(map[string]interface{})
+	add := func(path, method string, operation map[string]interface{}) {
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		if _, exists := item[strings.ToLower(method)]; !exists {
+			item[strings.ToLower(method)] = operation
+		}
+	}
+
+	for _, endpoint := range cfg.Endpoints {
+		if endpoint.Type == "grpc" {
+			continue
+		}
+		path, operation, err := openAPIOperation(cfg, endpoint)
+		if err != nil {
+			continue // Reported by validation
+		}
+		add(path, endpoint.Method, operation)
+
+		// A soap endpoint serves its WSDL to GET requests
+		if endpoint.Type == "soap" {
+			add(path, "GET", map[string]interface{}{
+				"operationId": operationID("GET", endpoint.Host, path) + "_wsdl",
+				"summary":     "WSDL of GET " + endpoint.Host + path,
+				"responses":   openAPIResponse("200", "The service's WSDL", "text/xml", openAPIString()),
+			})
+		}
+	}
+
+	if auth := cfg.Auth; auth != nil && auth.hasLogin() {
+		fields := map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"username": openAPIString(), "password": openAPIString()},
+			"required":   []interface{}{"username", "password"},
+		}
+		add(auth.LoginRoute(), "POST", map[string]interface{}{
+			"operationId": operationID("POST", "", auth.LoginRoute()),
+			"summary":     "Log in",
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/x-www-form-urlencoded": map[string]interface{}{"schema": fields},
+					"application/json":                  map[string]interface{}{"schema": fields},
+				},
+			},
+			"responses": openAPIResponse("200", "Logged in", "application/json", map[string]interface{}{"type": "object"}),
+		})
+	}
+
+	if cfg.UI != nil {
+		pages := []string{cfg.UI.IndexPath()}
+		for _, page := range cfg.UI.Pages {
+			pages = append(pages, cfg.UI.PagePath(page))
+		}
+		for _, path := range pages {
+			add(path, "GET", map[string]interface{}{
+				"operationId": operationID("GET", "", path),
+				"summary":     "UI page " + path,
+				"responses":   openAPIResponse("200", "The page", "text/html", openAPIString()),
+			})
+		}
+	}
+
+	for _, static := range cfg.Static {
+		path := strings.TrimRight(static.Path, "/") + "/{file}"
+		add(path, "GET", map[string]interface{}{
+			"operationId": operationID("GET", "", path),
+			"summary":     "Static files under " + static.Path,
+			"parameters": []interface{}{map[string]interface{}{
+				"name":        "file",
+				"in":          "path",
+				"required":    true,
+				"description": "Path of the file, slashes included",
+				"schema":      openAPIString(),
+			}},
+			"responses": openAPIResponse("200", "The file", "*/*", map[string]interface{}{"type": "string", "format": "binary"}),
+		})
+	}
+
+	info := map[string]interface{}{"title": cfg.App.Name, "version": "1.0"}
+	if cfg.App.Description != "" {
+		info["description"] = cfg.App.Description
+	}
+	doc := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info":    info,
+		"servers": []interface{}{map[string]interface{}{"url": openAPIServerURL(cfg, "")}},
+		"paths":   paths,
+	}
+	if cfg.Auth != nil {
+		doc["components"] = map[string]interface{}{
+			"securitySchemes": map[string]interface{}{cfg.Auth.Type: openAPISecurityScheme(cfg.Auth)},
+		}
+	}
+	return doc
+}
+
+// openAPIOperation returns the OpenAPI path of an endpoint and its operation
+func openAPIOperation(cfg *Config, endpoint EndpointConfig) (string, map[string]interface{}, error) {
+	pattern, routeParams, err := endpoint.Route()
+	if err != nil {
+		return "", nil, err
+	}
+	path := strings.ReplaceAll(strings.ReplaceAll(pattern, "...}", "}"), "/{$}", "/")
+
+	method := strings.ToUpper(endpoint.Method)
+	operation := map[string]interface{}{
+		"operationId": operationID(method, endpoint.Host, path),
+		"summary":     method + " " + endpoint.Host + path,
+	}
+	if endpoint.Host != "" {
+		operation["servers"] = []interface{}{map[string]interface{}{"url": openAPIServerURL(cfg, endpoint.Host)}}
+	}
+	if cfg.Auth.Protects(endpoint) {
+		operation["security"] = []interface{}{map[string]interface{}{cfg.Auth.Type: []interface{}{}}}
+	}
+
+	// An alias serves its target, so it reads the target's parameters
+	source := endpoint
+	if endpoint.Type == "alias" {
+		if target, ok := endpoint.AliasTarget(cfg.Endpoints); ok {
+			source = target
+		}
+	}
+
+	var params []interface{}
+	seen := make(map[string]bool)
+	for _, p := range routeParams {
+		params = append(params, openAPIPathParam(p))
+		seen["path "+p.Name] = true
+	}
+
+	bodies := make(map[string]map[string]interface{})
+	var messageFields []string
+	for _, vuln := range source.Vulnerabilities {
+		for _, name := range vuln.ParamNames() {
+			if in, ok := openAPIParamIn[vuln.Placement]; ok {
+				if name != "" && !seen[in+" "+name] {
+					params = append(params, map[string]interface{}{"name": name, "in": in, "schema": openAPIString()})
+					seen[in+" "+name] = true
+				}
+				continue
+			}
+			if vuln.Placement == "ws_message" {
+				messageFields = append(messageFields, name)
+				continue
+			}
+			if mediaType, ok := openAPIBodyTypes[vuln.Placement]; ok {
+				if bodies[mediaType] == nil {
+					bodies[mediaType] = openAPIBodySchema(mediaType)
+				}
+				addOpenAPIBodyField(bodies[mediaType], vuln.Placement, name)
+			}
+		}
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+	if len(bodies) > 0 {
+		content := make(map[string]interface{}, len(bodies))
+		for mediaType, schema := range bodies {
+			content[mediaType] = map[string]interface{}{"schema": schema}
+		}
+		operation["requestBody"] = map[string]interface{}{"content": content}
+	}
+
+	switch {
+	case endpoint.Type == "redirect":
+		status := endpoint.Status
+		if status == 0 {
+			status = 302
+		}
+		operation["responses"] = map[string]interface{}{
+			fmt.Sprint(status): map[string]interface{}{
+				"description": "Redirect to " + endpoint.Target,
+				"headers":     map[string]interface{}{"Location": map[string]interface{}{"schema": openAPIString()}},
+			},
+		}
+	case endpoint.Type == "proxy":
+		operation["responses"] = map[string]interface{}{
+			"default": map[string]interface{}{"description": "The response of " + endpoint.Target},
+		}
+	case source.WebSocket:
+		description := "Upgrades to a WebSocket"
+		if len(messageFields) > 0 {
+			description += "; messages are JSON objects with the fields " + strings.Join(messageFields, ", ")
+		}
+		operation["description"] = description
+		operation["responses"] = map[string]interface{}{
+			"101": map[string]interface{}{"description": "Switching Protocols"},
+		}
+	default:
+		status := endpoint.Status
+		if status == 0 {
+			status = 200
+		}
+		mediaType := openAPIContentTypes[source.ResponseType]
+		if mediaType == "" {
+			mediaType = openAPIContentTypes["json"]
+		}
+		if source.Type == "soap" {
+			mediaType = "text/xml"
+		}
+		schema := openAPIString()
+		if mediaType == "application/json" {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		operation["responses"] = openAPIResponse(fmt.Sprint(status), "Response", mediaType, schema)
+	}
+
+	return path, operation, nil
+}
+
+// openAPIPathParam returns the parameter of a {name} path segment, typed as
+// the segment restricts it
+func openAPIPathParam(p RouteParam) map[string]interface{} {
+	schema := openAPIString()
+	switch {
+	case p.Type == "int":
+		schema = map[string]interface{}{"type": "integer"}
+	case p.Type == "uuid":
+		schema["format"] = "uuid"
+	case p.Regexp != nil:
+		schema["pattern"] = p.Regexp.String()
+	case p.Type != "":
+		schema["pattern"] = routeParamTypes[p.Type].String()
+	}
+
+	param := map[string]interface{}{"name": p.Name, "in": "path", "required": true, "schema": schema}
+	if p.Wildcard {
+		param["description"] = "The rest of the path, slashes included"
+	}
+	return param
+}
+
+// openAPIBodySchema returns the empty schema of a request body of a media type
+func openAPIBodySchema(mediaType string) map[string]interface{} {
+	if mediaType == "text/plain" || mediaType == "text/xml" {
+		return openAPIString()
+	}
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+// addOpenAPIBodyField adds the field a vulnerability reads to its body's schema.
+// JSON fields in dot notation become nested objects, and GraphQL placements
+// add the query and its variables.
+func addOpenAPIBodyField(schema map[string]interface{}, placement, name string) {
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return // The whole body is read
+	}
+
+	switch placement {
+	case "graphql_query", "graphql_variable":
+		properties["query"] = openAPIString()
+		schema["required"] = []interface{}{"query"}
+		if placement == "graphql_query" || name == "" {
+			return
+		}
+		variables, ok := properties["variables"].(map[string]interface{})
+		if !ok {
+			variables = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			properties["variables"] = variables
+		}
+		properties = variables["properties"].(map[string]interface{})
+	case "file":
+		properties[name] = map[string]interface{}{"type": "string", "format": "binary"}
+		return
+	case "form_field", "multipart_field", "multipart-form":
+		properties[name] = openAPIString()
+		return
+	}
+
+	// Dot notation navigates nested objects
+	parts := strings.Split(name, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := properties[part].(map[string]interface{})
+		if !ok || child["type"] != "object" {
+			child = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			properties[part] = child
+		}
+		properties = child["properties"].(map[string]interface{})
+	}
+	if _, exists := properties[parts[len(parts)-1]]; !exists {
+		properties[parts[len(parts)-1]] = openAPIString()
+	}
+}
+
+// openAPISecurityScheme returns the security scheme of the login wall
+func openAPISecurityScheme(auth *AuthConfig) map[string]interface{} {
+	switch auth.Type {
+	case "basic", "bearer":
+		return map[string]interface{}{"type": "http", "scheme": auth.Type}
+	case "session":
+		return map[string]interface{}{"type": "apiKey", "in": "cookie", "name": auth.CookieName()}
+	}
+	return map[string]interface{}{"type": "apiKey", "in": "header", "name": auth.HeaderName()}
+}
+
+// openAPIServerURL returns the URL the app is reached at, for a virtual host
+// or, when host is empty, the address it listens on
+func openAPIServerURL(cfg *Config, host string) string {
+	scheme, address := serverAddress(cfg)
+	if host == "" {
+		host = address
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, cfg.App.Port)
+}
+
+// serverAddress returns the scheme and host clients reach the app at, which is
+// localhost when it listens on every interface
+func serverAddress(cfg *Config) (string, string) {
+	scheme := "http"
+	if cfg.App.TLS != nil && cfg.App.TLS.Enabled {
+		scheme = "https"
+	}
+	host := cfg.App.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme, host
+}
+
+// openAPIResponse returns the responses of an operation with one response
+func openAPIResponse(status, description, mediaType string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		status: map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{mediaType: map[string]interface{}{"schema": schema}},
+		},
+	}
+}
+
+// openAPIString returns the schema of a string
+func openAPIString() map[string]interface{} {
+	return map[string]interface{}{"type": "string"}
+}
+
+// operationID returns an operation ID from an endpoint's method, host and path,
+// like get_api_users_id
+func operationID(method, host, path string) string {
+	id := operationIDPattern.ReplaceAllString(strings.ToLower(method+" "+host+path), "_")
+	return strings.Trim(id, "_")
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// TestOpenAPI tests the operations, parameters, bodies and security of an exported document
+func TestOpenAPI(t *testing.T) {
+	path := writeYAML(t, t.TempDir(), "lab.yaml", `
+app:
+  name: "API Lab"
+  port: 9000
+
+auth:
+  type: bearer
+  protect: listed
+  users:
+    - {username: guest, password: guest}
+
+endpoints:
+  - path: /users/{id:int}
+    method: GET
+    auth: required
+    vulnerabilities:
+      - type: idor
+        placement: path_param
+        param: id
+  - path: /profile
+    method: POST
+    vulnerabilities:
+      - type: nosql_injection
+        placement: json_field
+        param: filter.name
+      - type: sql_injection
+        placement: query_param
+        param: sort
+  - path: /v2/profile
+    method: POST
+    type: alias
+    target: POST /profile
+  - path: /old
+    method: GET
+    type: redirect
+    status: 301
+    target: /profile
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	doc := OpenAPI(cfg)
+	if doc["openapi"] != OpenAPIVersion || doc["servers"].([]interface{})[0].(map[string]interface{})["url"] != "http://localhost:9000" {
+		t.Errorf("Expected version and server, got %v %v", doc["openapi"], doc["servers"])
+	}
+	paths := doc["paths"].(map[string]interface{})
+	operation := func(path, method string) map[string]interface{} {
+		t.Helper()
+		item, _ := paths[path].(map[string]interface{})
+		op, ok := item[method].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected %s %s in %v", method, path, paths)
+		}
+		return op
+	}
+
+	user := operation("/users/{id}", "get")
+	param := user["parameters"].([]interface{})[0].(map[string]interface{})
+	if param["in"] != "path" || param["schema"].(map[string]interface{})["type"] != "integer" || len(user["parameters"].([]interface{})) != 1 {
+		t.Errorf("Expected one integer path parameter, got %v", user["parameters"])
+	}
+	if _, ok := user["security"]; !ok {
+		t.Error("Expected the protected endpoint to require auth")
+	}
+
+	for _, path := range []string{"/profile", "/v2/profile"} {
+		profile := operation(path, "post")
+		if _, ok := profile["security"]; ok {
+			t.Errorf("Expected %s unprotected", path)
+		}
+		schema := profile["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+		filter := schema["properties"].(map[string]interface{})["filter"].(map[string]interface{})
+		if _, ok := filter["properties"].(map[string]interface{})["name"]; !ok {
+			t.Errorf("Expected filter.name nested in %s, got %v", path, schema)
+		}
+		if query := profile["parameters"].([]interface{})[0].(map[string]interface{}); query["name"] != "sort" || query["in"] != "query" {
+			t.Errorf("Expected the sort query parameter on %s, got %v", path, query)
+		}
+	}
+
+	if _, ok := operation("/old", "get")["responses"].(map[string]interface{})["301"]; !ok {
+		t.Error("Expected the redirect to respond 301")
+	}
+	if _, ok := operation("/login", "post")["requestBody"]; !ok {
+		t.Error("Expected the login route with a body")
+	}
+	if scheme := doc["components"].(map[string]interface{})["securitySchemes"].(map[string]interface{})["bearer"].(map[string]interface{}); scheme["scheme"] != "bearer" {
+		t.Errorf("Expected a bearer scheme, got %v", scheme)
+	}
+}
+
+// TestOpenAPI_Templates tests every path parameter of the bundled templates'
+// documents is declared, as OpenAPI requires
+func TestOpenAPI_Templates(t *testing.T) {
+	paths, _ := filepath.Glob("../templates/*.yaml")
+	if len(paths) == 0 {
+		t.Skip("no templates found")
+	}
+
+	segment := regexp.MustCompile(`\{([^}]+)\}`)
+	for _, path := range paths {
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+		for _, app := range cfg.Applications() {
+			for route, item := range OpenAPI(app)["paths"].(map[string]interface{}) {
+				for method, op := range item.(map[string]interface{}) {
+					declared := map[string]bool{}
+					params, _ := op.(map[string]interface{})["parameters"].([]interface{})
+					for _, p := range params {
+						if p.(map[string]interface{})["in"] == "path" {
+							declared[p.(map[string]interface{})["name"].(string)] = true
+						}
+					}
+					for _, match := range segment.FindAllStringSubmatch(route, -1) {
+						if !declared[match[1]] {
+							t.Errorf("%s: %s %s doesn't declare {%s}", filepath.Base(path), method, route, match[1])
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay files are deep-merged over the config loaded before them, so a variant
+// of a lab only spells out what it changes:
+//   - mappings merge key by key, and a null value removes the key
+//   - endpoints, files, chains, flags, static, apps and buckets are matched by identity and
+//     merged the same way; unmatched entries are added, and remove: true drops
+//     the entry it matches
+//   - any other value, including a vulnerabilities list, replaces the earlier one
+
+// overlayList describes a list whose entries are matched by identity
+type overlayList struct {
+	noun string                  // What an entry is called in errors
+	key  func(*yaml.Node) string // Identity of an entry, or "" if it has none
+}
+
+var (
+	endpointList = overlayList{"endpoint", func(n *yaml.Node) string {
+		path := scalarAt(n, "path")
+		if path == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s %s%s", strings.ToUpper(scalarAt(n, "method")), scalarAt(n, "host"), path)
+	}}
+	fileList   = overlayList{"file", func(n *yaml.Node) string { return scalarAt(n, "path") }}
+	chainList  = overlayList{"chain", func(n *yaml.Node) string { return scalarAt(n, "name") }}
+	flagList   = overlayList{"flag", func(n *yaml.Node) string { return scalarAt(n, "name") }}
+	staticList = overlayList{"static", func(n *yaml.Node) string { return scalarAt(n, "path") }}
+	appList    = overlayList{"app", func(n *yaml.Node) string { return scalarAt(n, "app", "name") }}
+	bucketList = overlayList{"bucket", func(n *yaml.Node) string { return scalarAt(n, "name") }}
+)
+
+// overlayLists maps the path of each list merged by identity to how its entries match
+var overlayLists = map[string]overlayList{
+	"endpoints":         endpointList,
+	"files":             fileList,
+	"chains":            chainList,
+	"flags":             flagList,
+	"static":            staticList,
+	"data.buckets":      bucketList,
+	"apps":              appList,
+	"apps.endpoints":    endpointList,
+	"apps.files":        fileList,
+	"apps.chains":       chainList,
+	"apps.flags":        flagList,
+	"apps.static":       staticList,
+	"apps.data.buckets": bucketList,
+}
+
+// overlay merges parsed overlay files into a config's YAML
+type overlay struct {
+	removed map[*yaml.Node]bool // List entries marked remove: true
+}
+
+// applyOverlays deep-merges each overlay file, in order, over cfg
+func (l *loader) applyOverlays(cfg *Config, paths []string) (*Config, error) {
+	var root yaml.Node
+	if err := root.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to prepare config for overlays: %w", err)
+	}
+
+	for _, path := range paths {
+		o := &overlay{removed: make(map[*yaml.Node]bool)}
+		node, err := l.parseOverlay(path, o)
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			continue
+		}
+		if err := o.mergeNode(&root, node, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var merged Config
+	if err := root.Decode(&merged); err != nil {
+		return nil, fmt.Errorf("failed to apply overlays: %w", err)
+	}
+	return &merged, nil
+}
+
+// parseOverlay reads an overlay file and substitutes variables. It returns the
+// top-level mapping, or nil for an empty file.
+func (l *loader) parseOverlay(path string, o *overlay) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	l.loaded[abs] = true
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: an overlay must be a mapping of config keys", path)
+	}
+	for _, key := range []string{"include", "preset"} {
+		if mappingIndex(root, key) >= 0 {
+			return nil, fmt.Errorf("%s: %s is only allowed in the base config file", path, key)
+		}
+	}
+
+	if err := substituteNode(&doc, l.vars); err != nil {
+		return nil, fmt.Errorf("failed to substitute variables in %s: %w", path, err)
+	}
+	if err := o.takeRemovals(root, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Checked once the remove keys are gone, as they aren't config fields
+	if l.strict {
+		if err := checkKnownFields(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+		}
+	}
+	return root, nil
+}
+
+// takeRemovals records the list entries under node marked remove: true and
+// deletes the remove key from every entry
+func (o *overlay) takeRemovals(node *yaml.Node, path string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		childPath := joinPath(path, node.Content[i].Value)
+		value := node.Content[i+1]
+		if _, keyed := overlayLists[childPath]; !keyed || value.Kind != yaml.SequenceNode {
+			if err := o.takeRemovals(value, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, item := range value.Content {
+			if j := mappingIndex(item, "remove"); j >= 0 {
+				var remove bool
+				if err := item.Content[j+1].Decode(&remove); err != nil {
+					return fmt.Errorf("line %d: remove must be true or false", item.Content[j].Line)
+				}
+				item.Content = slices.Delete(item.Content, j, j+2)
+				if remove {
+					o.removed[item] = true
+				}
+			}
+			if err := o.takeRemovals(item, childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeNode merges src over dst, where path locates them in the config
+func (o *overlay) mergeNode(dst, src *yaml.Node, path string) error {
+	if src.Kind == yaml.AliasNode {
+		src = src.Alias
+	}
+	if src.Kind != yaml.MappingNode {
+		*dst = *src
+		return nil
+	}
+	if dst.Kind != yaml.MappingNode {
+		*dst = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+
+		j := mappingIndex(dst, key.Value)
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+			if j >= 0 {
+				dst.Content = slices.Delete(dst.Content, j, j+2)
+			}
+			continue
+		}
+		if j < 0 {
+			dst.Content = append(dst.Content, key, &yaml.Node{})
+			j = len(dst.Content) - 2
+		}
+
+		childPath := joinPath(path, key.Value)
+		if list, keyed := overlayLists[childPath]; keyed && value.Kind == yaml.SequenceNode {
+			if err := o.mergeList(dst.Content[j+1], value, childPath, list); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := o.mergeNode(dst.Content[j+1], value, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeList merges each entry of src into the dst entry with the same identity,
+// adding the entries that match none and dropping those marked for removal
+func (o *overlay) mergeList(dst, src *yaml.Node, path string, list overlayList) error {
+	if dst.Kind != yaml.SequenceNode {
+		*dst = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	for _, item := range src.Content {
+		id := list.key(item)
+		match := -1
+		if id != "" {
+			match = slices.IndexFunc(dst.Content, func(n *yaml.Node) bool { return list.key(n) == id })
+		}
+
+		switch {
+		case o.removed[item] && match < 0:
+			return fmt.Errorf("line %d: no %s '%s' to remove", item.Line, list.noun, id)
+		case o.removed[item]:
+			dst.Content = slices.Delete(dst.Content, match, match+1)
+		case match < 0:
+			entry := &yaml.Node{}
+			if err := o.mergeNode(entry, item, path); err != nil {
+				return err
+			}
+			dst.Content = append(dst.Content, entry)
+		default:
+			if err := o.mergeNode(dst.Content[match], item, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1
+func mappingIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// scalarAt returns the scalar found by following keys through nested mappings, or ""
+func scalarAt(node *yaml.Node, keys ...string) string {
+	for _, key := range keys {
+		i := mappingIndex(node, key)
+		if i < 0 {
+			return ""
+		}
+		node = node.Content[i+1]
+	}
+	if node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// joinPath appends a key to a dotted config path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
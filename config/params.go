@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// AnyPlacement reads the vulnerability's params from every placement its module supports
+const AnyPlacement = "any"
+
+// paramlessPlacements read input without a param: raw_body the whole body, and
+// graphql_query the whole query unless a param names one of its arguments
+var paramlessPlacements = []string{"raw_body", "graphql_query"}
+
+// ParamNames returns the parameters a vulnerability reads, in the order they are tried
+func (v VulnerabilityConfig) ParamNames() []string {
+	if len(v.Params) > 0 {
+		return v.Params
+	}
+	return []string{v.Param}
+}
+
+// validateParams checks a vulnerability's param or params, recording each name
+// in paramMap so a parameter isn't claimed by two vulnerabilities of one endpoint
+func validateParams(vuln VulnerabilityConfig, prefix string, index int, paramMap map[string]int) ValidationErrors {
+	var errs ValidationErrors
+
+	field := fmt.Sprintf("%s.param", prefix)
+	switch {
+	case vuln.Placement == "raw_body" && (vuln.Param != "" || len(vuln.Params) > 0):
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "raw_body reads the whole body, so it takes no param",
+		})
+		return errs
+	case vuln.Param == "" && len(vuln.Params) == 0:
+		if slices.Contains(paramlessPlacements, vuln.Placement) {
+			return errs
+		}
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "param is required",
+		})
+		return errs
+	case vuln.Param != "" && len(vuln.Params) > 0:
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "param and params can't be used together",
+		})
+		return errs
+	}
+
+	for j, param := range vuln.ParamNames() {
+		if len(vuln.Params) > 0 {
+			field = fmt.Sprintf("%s.params[%d]", prefix, j)
+		}
+		if param == "" {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "param can't be empty",
+			})
+			continue
+		}
+
+		// Check for duplicate params
+		if prevIndex, exists := paramMap[param]; exists {
+			message := fmt.Sprintf("duplicate param '%s' (previously used at vulnerability index %d)", param, prevIndex)
+			if prevIndex == index {
+				message = fmt.Sprintf("duplicate param '%s'", param)
+			}
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: message,
+			})
+		} else {
+			paramMap[param] = index
+		}
+	}
+
+	return errs
+}
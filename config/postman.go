@@ -0,0 +1,97 @@
+package config
+
+import "fmt"
+
+// PostmanSchema is the schema of exported Postman collections
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Postman returns a Postman collection of an app's example requests, a folder
+// per endpoint, sent to the {{baseUrl}} variable. Protected requests use the
+// collection's auth: basic with the first user, a {{token}} the login request
+// sets for bearer, or an {{apiKey}}; session cookies are kept by Postman's
+// cookie jar once the login request has run.
+func Postman(cfg *Config) map[string]interface{} {
+	scheme, host := serverAddress(cfg)
+	variables := []interface{}{
+		map[string]interface{}{"key": "baseUrl", "value": fmt.Sprintf("%s://%s:%d", scheme, host, cfg.App.Port)},
+	}
+
+	var folders []interface{}
+	folderIndex := make(map[string]int)
+	for _, req := range ExampleRequests(cfg) {
+		item := map[string]interface{}{"name": req.Name, "request": postmanRequest(req)}
+		if cfg.Auth != nil && !req.Protected {
+			item["request"].(map[string]interface{})["auth"] = map[string]interface{}{"type": "noauth"}
+		}
+		if cfg.Auth != nil && cfg.Auth.Type == "bearer" && req.Path == cfg.Auth.LoginRoute() && req.Method == "POST" {
+			item["event"] = []interface{}{map[string]interface{}{
+				"listen": "test",
+				"script": map[string]interface{}{
+					"type": "text/javascript",
+					"exec": []interface{}{`pm.collectionVariables.set("token", pm.response.json().token);`},
+				},
+			}}
+		}
+
+		i, ok := folderIndex[req.Group]
+		if !ok {
+			i = len(folders)
+			folderIndex[req.Group] = i
+			folders = append(folders, map[string]interface{}{"name": req.Group, "item": []interface{}{}})
+		}
+		folder := folders[i].(map[string]interface{})
+		folder["item"] = append(folder["item"].([]interface{}), item)
+	}
+
+	info := map[string]interface{}{"name": cfg.App.Name, "schema": PostmanSchema}
+	if cfg.App.Description != "" {
+		info["description"] = cfg.App.Description
+	}
+	collection := map[string]interface{}{"info": info, "item": folders}
+
+	if auth := cfg.Auth; auth != nil {
+		username, password, token := "", "", ""
+		if len(auth.Users) > 0 {
+			username, password, token = auth.Users[0].Username, auth.Users[0].Password, auth.Users[0].Token
+		}
+		switch auth.Type {
+		case "basic":
+			collection["auth"] = map[string]interface{}{"type": "basic", "basic": postmanPairs("username", username, "password", password)}
+		case "bearer":
+			collection["auth"] = map[string]interface{}{"type": "bearer", "bearer": postmanPairs("token", "{{token}}")}
+			variables = append(variables, map[string]interface{}{"key": "token", "value": token})
+		case "api_key":
+			collection["auth"] = map[string]interface{}{"type": "apikey", "apikey": postmanPairs("key", auth.HeaderName(), "value", "{{apiKey}}", "in", "header")}
+			variables = append(variables, map[string]interface{}{"key": "apiKey", "value": token})
+		}
+	}
+	collection["variable"] = variables
+
+	return collection
+}
+
+// postmanRequest returns the request of a collection item
+func postmanRequest(req ExampleRequest) map[string]interface{} {
+	headers := make([]interface{}, len(req.Header))
+	for i, header := range req.Header {
+		headers[i] = map[string]interface{}{"key": header[0], "value": header[1]}
+	}
+	request := map[string]interface{}{
+		"method": req.Method,
+		"header": headers,
+		"url":    "{{baseUrl}}" + req.Path,
+	}
+	if req.Body != "" {
+		request["body"] = map[string]interface{}{"mode": "raw", "raw": req.Body}
+	}
+	return request
+}
+
+// postmanPairs returns the key-value list Postman keeps auth settings in
+func postmanPairs(pairs ...string) []interface{} {
+	list := make([]interface{}, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list = append(list, map[string]interface{}{"key": pairs[i], "value": pairs[i+1], "type": "string"})
+	}
+	return list
+}
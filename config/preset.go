@@ -0,0 +1,62 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+)
+
+// presetFiles holds the built-in presets, one complete lab per file
+//
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// Presets returns the names of the built-in presets in sorted order
+func Presets() []string {
+	entries, err := fs.ReadDir(presetFiles, "presets")
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	return names
+}
+
+// presetSource names a preset where errors would name a file
+func presetSource(name string) string {
+	return "preset " + name
+}
+
+// mergePreset adds a built-in preset's endpoints, files and data to dst. The
+// preset's app settings are used for any field dst.App leaves unset.
+func (l *loader) mergePreset(dst *Config, name string) error {
+	data, err := presetFiles.ReadFile("presets/" + name + ".yaml")
+	if err != nil {
+		return fmt.Errorf("unknown preset '%s' (valid: %s)", name, strings.Join(Presets(), ", "))
+	}
+
+	src, err := l.parse(presetSource(name), data)
+	if err != nil {
+		return err
+	}
+
+	dst.App = overlayApp(src.App, dst.App)
+	return l.mergeContent(dst, src, presetSource(name))
+}
+
+// overlayApp returns base with each field that override sets replaced
+func overlayApp(base, override AppConfig) AppConfig {
+	dst := reflect.ValueOf(&base).Elem()
+	src := reflect.ValueOf(override)
+	for i := 0; i < src.NumField(); i++ {
+		if !src.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return base
+}
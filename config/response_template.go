@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// responseTemplateFuncs are the functions available to response templates
+var responseTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+}
+
+// ParseResponseTemplate parses the endpoint's response template, or returns nil
+// if it has none. The template is rendered with the first active module result
+// (.Module, .Param, .Input, .Data, .Error), all of them as .Results, the
+// request as .Request and its ID as .RequestID, and the seeded tables as .Tables, and can use json,
+// upper, lower and default. It is a text template, so output is not escaped:
+// reflected input stays exploitable.
+func (e *EndpointConfig) ParseResponseTemplate() (*template.Template, error) {
+	if e.ResponseTemplate == "" {
+		return nil, nil
+	}
+	return template.New(e.Method + " " + e.Path).Funcs(responseTemplateFuncs).Option("missingkey=zero").Parse(e.ResponseTemplate)
+}
+
+// validateResponseTemplate checks that an endpoint's response template parses
+func validateResponseTemplate(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if _, err := endpoint.ParseResponseTemplate(); err != nil {
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.response_template", prefix),
+			Message: fmt.Sprintf("invalid response_template: %v", err),
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// routeParamTypes are the types a {name:type} path segment can be restricted to
+var routeParamTypes = map[string]*regexp.Regexp{
+	"int":   regexp.MustCompile(`^-?[0-9]+$`),
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+	"alpha": regexp.MustCompile(`^[A-Za-z]+$`),
+	"alnum": regexp.MustCompile(`^[A-Za-z0-9]+$`),
+	"hex":   regexp.MustCompile(`^[0-9a-fA-F]+$`),
+	"slug":  regexp.MustCompile(`^[A-Za-z0-9_-]+$`),
+}
+
+// routeParamName matches the names ServeMux accepts for path wildcards
+var routeParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RouteParam is a {name} segment of an endpoint path, optionally typed as
+// {name:type} or constrained by a regular expression as {name:re:pattern}
+type RouteParam struct {
+	Name     string
+	Type     string         // Empty when any value matches; "regex" for {name:re:pattern}
+	Regexp   *regexp.Regexp // The whole value must match, for {name:re:pattern}
+	Wildcard bool           // {name...}, or *name, matches the rest of the path
+}
+
+// Matches reports whether a path value has the parameter's type
+func (p RouteParam) Matches(value string) bool {
+	switch {
+	case p.Regexp != nil:
+		return p.Regexp.MatchString(value)
+	case p.Type == "":
+		return true
+	}
+	return routeParamTypes[p.Type].MatchString(value)
+}
+
+// Route returns the endpoint's path as a ServeMux pattern, with segment types
+// removed, and the parameters it declares
+func (e EndpointConfig) Route() (string, []RouteParam, error) {
+	segments := strings.Split(e.Path, "/")
+	var params []RouteParam
+
+	for i, segment := range segments {
+		// *name is shorthand for {name...}
+		if name, ok := strings.CutPrefix(segment, "*"); ok {
+			segment = "{" + name + "...}"
+		}
+		if !strings.ContainsAny(segment, "{}") || segment == "{$}" {
+			continue
+		}
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			return "", nil, fmt.Errorf("'%s' must be a whole path segment, like /{id}", segment)
+		}
+
+		inner := segment[1 : len(segment)-1]
+		name, typ, typed := strings.Cut(inner, ":")
+		param := RouteParam{Name: name, Type: typ}
+		if n, ok := strings.CutSuffix(name, "..."); ok && (!typed || strings.HasPrefix(typ, "re:")) {
+			param.Name, param.Wildcard = n, true
+			if i != len(segments)-1 {
+				return "", nil, fmt.Errorf("{%s} must be the last path segment", inner)
+			}
+		}
+
+		if !routeParamName.MatchString(param.Name) {
+			return "", nil, fmt.Errorf("invalid path parameter name '%s'", param.Name)
+		}
+		if pattern, ok := strings.CutPrefix(typ, "re:"); typed && ok {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid pattern for path parameter '%s': %v", name, err)
+			}
+			param.Type, param.Regexp = "regex", re
+		} else if typed {
+			if _, ok := routeParamTypes[typ]; !ok {
+				return "", nil, fmt.Errorf("unknown type '%s' for path parameter '%s', must be one of: %s, or re:pattern", typ, name, strings.Join(RouteParamTypes(), ", "))
+			}
+		}
+		if slices.ContainsFunc(params, func(p RouteParam) bool { return p.Name == param.Name }) {
+			return "", nil, fmt.Errorf("path parameter '%s' is used more than once", param.Name)
+		}
+
+		params = append(params, param)
+		segments[i] = "{" + name + "}"
+	}
+
+	return strings.Join(segments, "/"), params, nil
+}
+
+// RouteParamTypes returns the supported path parameter types in sorted order
+func RouteParamTypes() []string {
+	types := make([]string, 0, len(routeParamTypes))
+	for typ := range routeParamTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// validateRoute validates the endpoint's path parameters and checks that every
+// path_param placement names one of them
+func validateRoute(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if !strings.HasPrefix(endpoint.Path, "/") {
+		return errs // Reported with the path itself
+	}
+
+	_, params, err := endpoint.Route()
+	if err != nil {
+		return append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.path", prefix),
+			Message: err.Error(),
+		})
+	}
+
+	for i, vuln := range endpoint.Vulnerabilities {
+		if vuln.Placement != "path_param" {
+			continue
+		}
+		for _, name := range vuln.ParamNames() {
+			if name != "" && !slices.ContainsFunc(params, func(p RouteParam) bool { return p.Name == name }) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.vulnerabilities[%d].param", prefix, i),
+					Message: fmt.Sprintf("path_param '%s' has no {%s} segment in path '%s'", name, name, endpoint.Path),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// validVirtualHost reports whether host is empty, a bare hostname, or *.domain
+// matching any subdomain of domain
+func validVirtualHost(host string) bool {
+	if strings.ContainsAny(host, "/: ") {
+		return false
+	}
+	domain, wildcard := strings.CutPrefix(host, "*.")
+	return !strings.Contains(domain, "*") && (!wildcard || domain != "")
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// SchemaURI identifies the JSON Schema draft the generated schema follows
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaRequired lists required keys per config type, matching what the validator enforces
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(ApplicationConfig{}):   {"app", "endpoints"},
+	reflect.TypeOf(AppConfig{}):           {"name", "port"},
+	reflect.TypeOf(EndpointConfig{}):      {"path", "method"},
+	reflect.TypeOf(VulnerabilityConfig{}): {"type", "placement"}, // Plus param or params, see Schema
+	reflect.TypeOf(TableConfig{}):         {"columns"},
+	reflect.TypeOf(GenerateConfig{}):      {"rows", "columns"},
+	reflect.TypeOf(FileConfig{}):          {"path"},
+	reflect.TypeOf(BucketConfig{}):        {"name"},
+	reflect.TypeOf(ChainConfig{}):         {"name", "steps"},
+	reflect.TypeOf(ChainStepConfig{}):     {"endpoint"},
+	reflect.TypeOf(AuthConfig{}):          {"type"},
+	reflect.TypeOf(AuthUserConfig{}):      {"username"},
+	reflect.TypeOf(FlagConfig{}):          {"name"},
+	reflect.TypeOf(FlagRevealConfig{}):    {"endpoint"},
+	reflect.TypeOf(NotificationConfig{}):  {"url"},
+	reflect.TypeOf(TracingConfig{}):       {"endpoint"},
+	reflect.TypeOf(UIPageConfig{}):        {"type"},
+	reflect.TypeOf(StaticConfig{}):        {"path", "dir"},
+	reflect.TypeOf(IsolationConfig{}):     {"by"},
+	reflect.TypeOf(AdminConfig{}):         {"token"},
+	reflect.TypeOf(GRPCConfig{}):          {"port"},
+	reflect.TypeOf(GRPCField{}):           {"name"},
+}
+
+// schemaDefs are the config types that contain themselves, like a message
+// field's fields: their schema is defined once under $defs and referenced
+var schemaDefs = []reflect.Type{reflect.TypeOf(GRPCField{})}
+
+// schemaEnums restricts fields to known values, keyed by "Type.yaml_key"
+var schemaEnums = map[string][]interface{}{
+	"EndpointConfig.method":          {"GET", "POST", "PUT", "DELETE", "PATCH", "get", "post", "put", "delete", "patch"},
+	"EndpointConfig.response_type":   {"json", "html", "xml", "text"},
+	"CSPConfig.preset":               {"unsafe_inline", "wildcard", "jsonp", "strict"},
+	"CloudMetadataConfig.provider":   {"aws", "gcp", "azure"},
+	"BucketConfig.acl":               {"private", "public-read", "public-read-write"},
+	"PersistenceConfig.reseed":       {"on_change", "always", "never"},
+	"VulnerabilityConfig.difficulty": {"easy", "medium", "hard"},
+	"EndpointConfig.auth":            {"required", "none"},
+	"EndpointConfig.type":            {"redirect", "proxy", "alias", "soap", "grpc"},
+	"AuthConfig.type":                {"basic", "session", "bearer", "api_key"},
+	"AuthConfig.protect":             {"all", "listed"},
+	"TLSConfig.client_auth":          {"none", "request", "require", "verify_if_given", "verify"},
+	"UIPageConfig.type":              {"login", "search", "comments"},
+	"SessionsConfig.same_site":       {"lax", "strict", "none"},
+	"IsolationConfig.by":             {"user", "session"},
+	"LoggingConfig.format":           {"json", "cef", "ecs"},
+	"NotificationConfig.type":        {"webhook", "slack", "http"},
+	"GRPCField.type":                 {"string", "bytes", "bool", "int32", "int64", "uint32", "uint64", "double", "float", "message"},
+	"VulnerabilityConfig.placement": {
+		"query_param", "path_param", "form_field", "json_field", "header", "cookie", "multipart_field", "multipart-form", "file", "raw_body", "graphql_variable", "graphql_query", "ws_message", "soap_param", "grpc_field", AnyPlacement,
+	},
+}
+
+// Schema returns a JSON Schema for the config format, built from the config
+// types and the registered modules' placements and config options. Editors can
+// use it for completion and external tools for validation.
+func Schema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = SchemaURI
+	schema["title"] = "FlawFactory lab configuration"
+
+	defs := make(map[string]interface{})
+	for _, t := range schemaDefs {
+		defs[t.Name()] = structSchema(t)
+	}
+	schema["$defs"] = defs
+
+	// A config defines a single app, or several under apps, or builds on a preset
+	schema["anyOf"] = []interface{}{
+		map[string]interface{}{"required": []interface{}{"app", "endpoints"}},
+		map[string]interface{}{"required": []interface{}{"apps"}},
+		map[string]interface{}{"required": []interface{}{"preset"}},
+	}
+
+	presets := []interface{}{}
+	for _, name := range Presets() {
+		presets = append(presets, name)
+	}
+	schemaAt(schema, "properties", "preset")["enum"] = presets
+
+	// Narrow each vulnerability by its module: placements and known config values
+	infos := modules.List()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	names := make([]interface{}, len(infos))
+	var rules []interface{}
+	for i, info := range infos {
+		names[i] = info.Name
+		rules = append(rules, moduleSchema(info))
+	}
+
+	for _, endpoints := range []map[string]interface{}{
+		schemaAt(schema, "properties", "endpoints"),
+		schemaAt(schema, "properties", "apps", "items", "properties", "endpoints"),
+	} {
+		vuln := schemaAt(endpoints, "items", "properties", "vulnerabilities", "items")
+		schemaAt(vuln, "properties", "type")["enum"] = names
+		vuln["oneOf"] = []interface{}{
+			map[string]interface{}{"required": []interface{}{"param"}},
+			map[string]interface{}{"required": []interface{}{"params"}},
+		}
+		if len(rules) > 0 {
+			vuln["allOf"] = rules
+		}
+	}
+
+	return schema
+}
+
+// moduleSchema returns the rule applied to vulnerabilities of one module type
+func moduleSchema(info modules.ModuleInfo) map[string]interface{} {
+	then := map[string]interface{}{}
+	props := map[string]interface{}{}
+
+	if len(info.SupportedPlacements) > 0 {
+		placements := make([]interface{}, len(info.SupportedPlacements), len(info.SupportedPlacements)+1)
+		for i, p := range info.SupportedPlacements {
+			placements[i] = p
+		}
+		placements = append(placements, AnyPlacement)
+		props["placement"] = map[string]interface{}{"enum": placements}
+	}
+
+	if len(info.ValidVariants) > 0 {
+		configProps := make(map[string]interface{}, len(info.ValidVariants))
+		for key, options := range info.ValidVariants {
+			configProps[key] = map[string]interface{}{"enum": optionValues(options)}
+		}
+		// Modules accept more keys than they list options for, so other keys are allowed
+		props["config"] = map[string]interface{}{"type": "object", "properties": configProps}
+	}
+
+	var levels []interface{}
+	for _, level := range modules.DifficultyLevels {
+		if _, ok := info.Difficulty[level]; ok {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) > 0 {
+		props["difficulty"] = map[string]interface{}{"enum": levels}
+	}
+
+	then["properties"] = props
+	return map[string]interface{}{
+		"if": map[string]interface{}{
+			"properties": map[string]interface{}{"type": map[string]interface{}{"const": info.Name}},
+			"required":   []interface{}{"type"},
+		},
+		"then":        then,
+		"description": info.Description,
+	}
+}
+
+// optionValues returns module config options as enum values. Options are
+// compared as strings by the validator, so YAML booleans and numbers that
+// print the same are accepted too.
+func optionValues(options []string) []interface{} {
+	values := make([]interface{}, 0, len(options))
+	for _, option := range options {
+		values = append(values, option)
+		if option == "true" || option == "false" {
+			values = append(values, option == "true")
+		} else if n, err := strconv.ParseInt(option, 10, 64); err == nil {
+			values = append(values, n)
+		}
+	}
+	return values
+}
+
+// schemaAt returns the nested schema at a path of keys
+func schemaAt(schema map[string]interface{}, path ...string) map[string]interface{} {
+	for _, key := range path {
+		schema = schema[key].(map[string]interface{})
+	}
+	return schema
+}
+
+// typeSchema converts a Go type from the config structs into a JSON Schema
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		if slices.Contains(schemaDefs, t) {
+			return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		}
+		return structSchema(t)
+	}
+	// interface{} holds any YAML value
+	return map[string]interface{}{}
+}
+
+// structSchema converts a config struct using its yaml tags
+func structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		prop := typeSchema(field.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+			prop["enum"] = enum
+		}
+		props[name] = prop
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t]; ok {
+		list := make([]interface{}, len(required))
+		for i, key := range required {
+			list[i] = key
+		}
+		schema["required"] = list
+	}
+	return schema
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkSchema reports keys and enum values in a decoded YAML value that the schema rejects.
+// It covers the parts of JSON Schema the generated schema uses outside module rules.
+func checkSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var problems []string
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v not in enum", path, value))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for key, child := range v {
+			childPath := path + "." + key
+			if prop, ok := props[key].(map[string]interface{}); ok {
+				problems = append(problems, checkSchema(prop, child, childPath)...)
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				problems = append(problems, checkSchema(extra, child, childPath)...)
+			} else if schema["additionalProperties"] == false {
+				problems = append(problems, childPath+": unknown key")
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, child := range v {
+				problems = append(problems, checkSchema(items, child, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	return problems
+}
+
+// TestSchema_Templates tests every bundled template and preset conforms to the schema
+func TestSchema_Templates(t *testing.T) {
+	schema := Schema()
+
+	paths, _ := filepath.Glob("../templates/*.yaml")
+	if len(paths) == 0 {
+		t.Skip("no templates found")
+	}
+	presets, _ := filepath.Glob("presets/*.yaml")
+	paths = append(paths, presets...)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("Failed to parse %s: %v", path, err)
+		}
+
+		for _, problem := range checkSchema(schema, doc, filepath.Base(path)) {
+			t.Error(problem)
+		}
+	}
+}
+
+// TestSchema_Modules tests module types, placements and config options are included
+func TestSchema_Modules(t *testing.T) {
+	schema := Schema()
+
+	if schema["$schema"] != SchemaURI {
+		t.Errorf("Expected $schema %s", SchemaURI)
+	}
+
+	vuln := schemaAt(schema, "properties", "endpoints", "items", "properties", "vulnerabilities", "items")
+	types, _ := schemaAt(vuln, "properties", "type")["enum"].([]interface{})
+	rules, _ := vuln["allOf"].([]interface{})
+	if len(types) == 0 || len(rules) != len(types) {
+		t.Fatalf("Expected one rule per module type, got %d types and %d rules", len(types), len(rules))
+	}
+
+	for _, r := range rules {
+		rule := r.(map[string]interface{})
+		if schemaAt(rule, "if", "properties", "type")["const"] != "sql_injection" {
+			continue
+		}
+		placements := schemaAt(rule, "then", "properties", "placement")["enum"].([]interface{})
+		variants := schemaAt(rule, "then", "properties", "config", "properties", "variant")["enum"].([]interface{})
+		levels := schemaAt(rule, "then", "properties", "difficulty")["enum"].([]interface{})
+		if len(placements) == 0 || len(variants) == 0 || len(levels) != 3 {
+			t.Errorf("Expected sql_injection placements, variants and difficulty levels, got %v, %v and %v", placements, variants, levels)
+		}
+		return
+	}
+	t.Error("Expected a rule for sql_injection")
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// validSameSite lists the SameSite values a session cookie can take
+var validSameSite = []string{"lax", "strict", "none"}
+
+// CookieName returns the cookie carrying the session ID. Session auth's
+// cookie is the default, so logins and module state share one session.
+func (s *SessionsConfig) CookieName(auth *AuthConfig) string {
+	switch {
+	case s != nil && s.Cookie != "":
+		return s.Cookie
+	case auth != nil:
+		return auth.CookieName()
+	}
+	return DefaultAuthCookie
+}
+
+// IdleTimeout returns how long an unused session lives, 0 for ever
+func (s *SessionsConfig) IdleTimeout() time.Duration {
+	if s == nil || s.TTL == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(s.TTL)
+	return d
+}
+
+// validateSessions validates the sessions settings, whose cookie session auth shares
+func validateSessions(sessions *SessionsConfig, auth *AuthConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.ContainsAny(sessions.Cookie, " ;,=\"\t") {
+		errs = append(errs, ValidationError{
+			Field:   "app.sessions.cookie",
+			Message: fmt.Sprintf("invalid cookie name '%s'", sessions.Cookie),
+		})
+	}
+	if auth != nil && auth.Type == "session" && auth.Cookie != "" && sessions.Cookie != "" && auth.Cookie != sessions.Cookie {
+		errs = append(errs, ValidationError{
+			Field:   "app.sessions.cookie",
+			Message: fmt.Sprintf("session auth keeps its logins in the sessions, so the cookie must match auth.cookie '%s'", auth.Cookie),
+		})
+	}
+
+	if sessions.TTL != "" {
+		if d, err := time.ParseDuration(sessions.TTL); err != nil || d < time.Second {
+			errs = append(errs, ValidationError{
+				Field:   "app.sessions.ttl",
+				Message: fmt.Sprintf("invalid ttl '%s' (a duration of at least 1s)", sessions.TTL),
+			})
+		}
+	}
+
+	if sessions.SameSite != "" && !slices.Contains(validSameSite, sessions.SameSite) {
+		errs = append(errs, ValidationError{
+			Field:   "app.sessions.same_site",
+			Message: fmt.Sprintf("invalid same_site '%s', must be one of: %s", sessions.SameSite, strings.Join(validSameSite, ", ")),
+		})
+	}
+
+	return errs
+}
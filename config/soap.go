@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// soapNamePattern matches the XML names an operation and its params can take
+var soapNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// ServiceName returns the name of the SOAP service
+func (s *SOAPConfig) ServiceName() string {
+	if s == nil || s.Service == "" {
+		return "FlawFactoryService"
+	}
+	return s.Service
+}
+
+// TargetNamespace returns the namespace of the service's elements
+func (s *SOAPConfig) TargetNamespace() string {
+	if s == nil || s.Namespace == "" {
+		return "urn:" + s.ServiceName()
+	}
+	return s.Namespace
+}
+
+// Operation returns the operation named name
+func (s *SOAPConfig) Operation(name string) (SOAPOperation, bool) {
+	if s != nil {
+		for _, operation := range s.Operations {
+			if operation.Name == name {
+				return operation, true
+			}
+		}
+	}
+	return SOAPOperation{}, false
+}
+
+// SOAPParams returns the child elements the WSDL declares for an operation of
+// a soap endpoint: its own params, or else the top-level elements the
+// endpoint's soap_param vulnerabilities read
+func (e EndpointConfig) SOAPParams(operation SOAPOperation) []string {
+	if len(operation.Params) > 0 {
+		return operation.Params
+	}
+	var params []string
+	seen := make(map[string]bool)
+	for _, vuln := range e.Vulnerabilities {
+		if vuln.Placement != "soap_param" {
+			continue
+		}
+		for _, param := range vuln.ParamNames() {
+			name, _, _ := strings.Cut(param, ".")
+			if name != "" && !seen[name] {
+				seen[name] = true
+				params = append(params, name)
+			}
+		}
+	}
+	return params
+}
+
+// validateSOAP validates soap endpoints and the soap_param placements that
+// only they can read
+func validateSOAP(endpoint EndpointConfig, endpoints []EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if endpoint.Type != "soap" {
+		if endpoint.SOAP != nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".soap",
+				Message: "soap only applies to endpoints of type soap",
+			})
+		}
+		for i, vuln := range endpoint.Vulnerabilities {
+			if vuln.Placement == "soap_param" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.vulnerabilities[%d].placement", prefix, i),
+					Message: "placement 'soap_param' requires 'type: soap' on the endpoint",
+				})
+			}
+		}
+		return errs
+	}
+
+	// The envelope decides the response, so these settings have nothing to shape
+	ignored := []struct {
+		field string
+		set   bool
+	}{
+		{"target", endpoint.Target != ""},
+		{"websocket", endpoint.WebSocket},
+		{"response_type", endpoint.ResponseType != ""},
+	}
+	for _, setting := range ignored {
+		if setting.set {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.%s", prefix, setting.field),
+				Message: fmt.Sprintf("%s doesn't apply to soap endpoints", setting.field),
+			})
+		}
+	}
+
+	if !strings.EqualFold(endpoint.Method, "POST") {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".method",
+			Message: fmt.Sprintf("soap endpoints must use POST, got '%s'", endpoint.Method),
+		})
+	}
+
+	// GET on the path serves the WSDL
+	for i, other := range endpoints {
+		if strings.EqualFold(other.Method, "GET") && other.Path == endpoint.Path && other.Host == endpoint.Host {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("GET %s serves the soap endpoint's WSDL, but endpoints[%d] is defined for it", endpoint.Path, i),
+			})
+		}
+	}
+
+	soap := endpoint.SOAP
+	if soap == nil || len(soap.Operations) == 0 {
+		return append(errs, ValidationError{
+			Field:   prefix + ".soap.operations",
+			Message: "soap endpoints need at least one operation",
+		})
+	}
+
+	if soap.Service != "" && !soapNamePattern.MatchString(soap.Service) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".soap.service",
+			Message: fmt.Sprintf("invalid service name '%s', must be an XML name", soap.Service),
+		})
+	}
+	if soap.Namespace != "" {
+		if u, err := url.Parse(soap.Namespace); err != nil || u.Scheme == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".soap.namespace",
+				Message: fmt.Sprintf("namespace must be a URI, e.g. urn:example or http://example.com/service, got '%s'", soap.Namespace),
+			})
+		}
+	}
+
+	seen := make(map[string]int)
+	for i, operation := range soap.Operations {
+		field := fmt.Sprintf("%s.soap.operations[%d]", prefix, i)
+		if !soapNamePattern.MatchString(operation.Name) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("invalid operation name '%s', must be an XML name", operation.Name),
+			})
+		} else if prev, ok := seen[operation.Name]; ok {
+			errs = append(errs, ValidationError{
+				Field:   field + ".name",
+				Message: fmt.Sprintf("duplicate operation '%s' (previously defined at index %d)", operation.Name, prev),
+			})
+		} else {
+			seen[operation.Name] = i
+		}
+		for j, param := range operation.Params {
+			if !soapNamePattern.MatchString(param) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.params[%d]", field, j),
+					Message: fmt.Sprintf("invalid param '%s', must be an XML name", param),
+				})
+			}
+		}
+	}
+
+	return errs
+}
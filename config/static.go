@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix returns the URL prefix without a trailing slash, "" for the root
+func (s StaticConfig) Prefix() string {
+	return strings.TrimRight(s.Path, "/")
+}
+
+// validateStatic validates static directories, which must stay inside the
+// filesystem sink and not take the place of an endpoint
+func validateStatic(static []StaticConfig, endpoints []EndpointConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	prefixes := make(map[string]int)
+	for i, entry := range static {
+		prefix := fmt.Sprintf("static[%d]", i)
+
+		switch {
+		case entry.Path == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: "path is required",
+			})
+		case !strings.HasPrefix(entry.Path, "/"):
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("path must start with '/', got '%s'", entry.Path),
+			})
+		case strings.ContainsAny(entry.Path, "{}"):
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("path can't have parameters, got '%s'", entry.Path),
+			})
+		default:
+			if prev, exists := prefixes[entry.Prefix()]; exists {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".path",
+					Message: fmt.Sprintf("duplicate static path '%s' (previously defined at index %d)", entry.Path, prev),
+				})
+			} else {
+				prefixes[entry.Prefix()] = i
+			}
+
+			for _, endpoint := range endpoints {
+				if strings.EqualFold(endpoint.Method, "GET") && endpoint.Host == "" && endpoint.Path == entry.Prefix()+"/" {
+					errs = append(errs, ValidationError{
+						Field:   prefix + ".path",
+						Message: fmt.Sprintf("'%s' is already used by endpoint 'GET %s'", entry.Path, endpoint.Path),
+					})
+				}
+			}
+		}
+
+		if entry.Dir == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".dir",
+				Message: "dir is required, use . for the whole filesystem sink",
+			})
+		} else if !filepath.IsLocal(entry.Dir) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".dir",
+				Message: fmt.Sprintf("dir must be a relative path inside the filesystem sink, got '%s'", entry.Dir),
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateStrict validates the configuration like ValidateWithWarnings, and
+// additionally rejects vulnerabilities whose type isn't a registered module,
+// whose placement the module doesn't support, or whose config has keys the
+// module doesn't read
+func ValidateStrict(cfg *Config) *ValidationResult {
+	if len(cfg.Apps) > 0 {
+		return validateApps(cfg, validateLabStrict)
+	}
+	return validateLabStrict(cfg)
+}
+
+// validateLabStrict strictly validates a single application's configuration
+func validateLabStrict(cfg *Config) *ValidationResult {
+	result := validateLab(cfg)
+
+	for i, endpoint := range cfg.Endpoints {
+		for j, vuln := range endpoint.Vulnerabilities {
+			prefix := fmt.Sprintf("endpoints[%d].vulnerabilities[%d]", i, j)
+			result.Errors = append(result.Errors, validateVulnerabilityStrict(vuln, prefix)...)
+		}
+	}
+
+	return result
+}
+
+// validateVulnerabilityStrict checks a vulnerability against its module's info
+func validateVulnerabilityStrict(vuln VulnerabilityConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if vuln.Type == "" {
+		return errs // Already reported as missing
+	}
+
+	module, err := modules.Get(vuln.Type)
+	if err != nil {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".type",
+			Message: fmt.Sprintf("unknown vulnerability type '%s' (valid: %s)", vuln.Type, strings.Join(sortedModuleNames(), ", ")),
+		})
+		return errs
+	}
+	info := module.Info()
+
+	if vuln.Placement != "" && vuln.Placement != AnyPlacement {
+		if err := modules.ValidatePlacement(vuln.Type, vuln.Placement); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".placement",
+				Message: fmt.Sprintf("%v (supported: %s)", err, strings.Join(info.SupportedPlacements, ", ")),
+			})
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, key := range modules.ConfigKeys(info) {
+		known[key] = true
+	}
+	known["sandbox"] = true // Read by the builder for command sinks
+
+	for _, key := range sortedKeys(vuln.Config) {
+		if !known[key] {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.config.%s", prefix, key),
+				Message: fmt.Sprintf("unknown config key '%s' for %s (valid: %s)", key, vuln.Type, strings.Join(modules.ConfigKeys(info), ", ")),
+			})
+		}
+	}
+
+	return errs
+}
+
+// checkKnownFields reports mapping keys that don't match a field of the config
+// struct they decode into, in the same form as yaml.Decoder's KnownFields. It
+// runs on the parsed node rather than re-decoding the file, so line numbers
+// still point into the original file after variables are substituted.
+func checkKnownFields(node *yaml.Node) error {
+	var unknown []string
+	walkKnownFields(node, reflect.TypeOf(Config{}), &unknown)
+	if len(unknown) > 0 {
+		return &yaml.TypeError{Errors: unknown}
+	}
+	return nil
+}
+
+// walkKnownFields checks one node against the Go type it decodes into
+func walkKnownFields(node *yaml.Node, t reflect.Type, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkKnownFields(child, t, unknown)
+		}
+
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, child := range node.Content {
+				walkKnownFields(child, t.Elem(), unknown)
+			}
+		}
+
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 1; i < len(node.Content); i += 2 {
+				walkKnownFields(node.Content[i], t.Elem(), unknown)
+			}
+		case reflect.Struct:
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				if key.Value == "<<" {
+					continue // Merge keys are checked where their anchors are defined
+				}
+				field, ok := fields[key.Value]
+				if !ok {
+					*unknown = append(*unknown, fmt.Sprintf("line %d: field %s not found in type %s", key.Line, key.Value, t))
+					continue
+				}
+				walkKnownFields(node.Content[i+1], field.Type, unknown)
+			}
+		}
+	}
+}
+
+// yamlFields maps a struct's yaml keys to its fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// sortedModuleNames returns the registered module names in sorted order
+func sortedModuleNames() []string {
+	infos := modules.List()
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// validClientAuth lists the client certificate policies, weakest first
+var validClientAuth = []string{"none", "request", "require", "verify_if_given", "verify"}
+
+// VerifiesClients reports whether client certificates are checked against a CA
+func (t *TLSConfig) VerifiesClients() bool {
+	return t.ClientAuth == "verify" || t.ClientAuth == "verify_if_given"
+}
+
+// validateTLS validates the HTTPS settings
+func validateTLS(t *TLSConfig, port int) ValidationErrors {
+	var errs ValidationErrors
+	if t == nil || !t.Enabled {
+		return errs
+	}
+
+	if !t.AutoGenerate && (t.CertFile == "" || t.KeyFile == "") {
+		errs = append(errs, ValidationError{
+			Field:   "app.tls",
+			Message: "cert_file and key_file are required unless auto_generate is set",
+		})
+	}
+	if !t.AutoGenerate {
+		generated := []struct {
+			field string
+			set   bool
+		}{{"ca", t.CA}, {"hosts", len(t.Hosts) > 0}, {"cert_dir", t.CertDir != ""}}
+		for _, setting := range generated {
+			if setting.set {
+				errs = append(errs, ValidationError{
+					Field:   "app.tls." + setting.field,
+					Message: fmt.Sprintf("%s only applies to auto_generate certificates", setting.field),
+				})
+			}
+		}
+	}
+
+	for i, host := range t.Hosts {
+		if host == "" || (net.ParseIP(host) == nil && strings.ContainsAny(host, "/: ")) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("app.tls.hosts[%d]", i),
+				Message: fmt.Sprintf("invalid host '%s', expected a DNS name or IP address", host),
+			})
+		}
+	}
+
+	if t.RedirectPort != 0 && (t.RedirectPort < 1 || t.RedirectPort > 65535 || t.RedirectPort == port) {
+		errs = append(errs, ValidationError{
+			Field:   "app.tls.redirect_port",
+			Message: fmt.Sprintf("redirect_port must be between 1 and 65535 and differ from app.port, got %d", t.RedirectPort),
+		})
+	}
+
+	switch {
+	case t.ClientAuth != "" && !slices.Contains(validClientAuth, t.ClientAuth):
+		errs = append(errs, ValidationError{
+			Field:   "app.tls.client_auth",
+			Message: fmt.Sprintf("invalid client_auth '%s', must be one of: %s", t.ClientAuth, strings.Join(validClientAuth, ", ")),
+		})
+	case t.VerifiesClients() && t.ClientCAFile == "" && !(t.AutoGenerate && t.CA):
+		errs = append(errs, ValidationError{
+			Field:   "app.tls.client_ca_file",
+			Message: fmt.Sprintf("client_auth %s needs client_ca_file, or auto_generate with ca: true", t.ClientAuth),
+		})
+	case t.ClientCAFile != "" && !t.VerifiesClients():
+		errs = append(errs, ValidationError{
+			Field:   "app.tls.client_ca_file",
+			Message: "client_ca_file is only used with client_auth verify or verify_if_given",
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TracesURL returns the URL spans are POSTed to: the endpoint, with the OTLP
+// traces path added when it has none
+func (t *TracingConfig) TracesURL() string {
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return t.Endpoint
+	}
+	u.Path = "/v1/traces"
+	return u.String()
+}
+
+// Service returns the service name traces are reported under
+func (t *TracingConfig) Service(appName string) string {
+	if t.ServiceName == "" {
+		return appName
+	}
+	return t.ServiceName
+}
+
+// Ratio returns the share of requests traced
+func (t *TracingConfig) Ratio() float64 {
+	if t.SampleRatio == nil {
+		return 1
+	}
+	return *t.SampleRatio
+}
+
+// validateTracing validates the trace collector settings
+func validateTracing(tracing *TracingConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if u, err := url.Parse(tracing.Endpoint); tracing.Endpoint == "" {
+		errs = append(errs, ValidationError{
+			Field:   "app.tracing.endpoint",
+			Message: "endpoint is required",
+		})
+	} else if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, ValidationError{
+			Field:   "app.tracing.endpoint",
+			Message: fmt.Sprintf("invalid endpoint '%s', must be the http or https URL of an OTLP collector", tracing.Endpoint),
+		})
+	}
+
+	if ratio := tracing.Ratio(); ratio < 0 || ratio > 1 {
+		errs = append(errs, ValidationError{
+			Field:   "app.tracing.sample_ratio",
+			Message: fmt.Sprintf("sample_ratio must be between 0 and 1, got %g", ratio),
+		})
+	}
+
+	return errs
+}
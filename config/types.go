@@ -2,10 +2,37 @@ package config
 
 // Config represents the entire YAML configuration file
 type Config struct {
+	Include   []string         `yaml:"include,omitempty"` // Files (or glob patterns) merged in, relative to this file
+	Preset    string           `yaml:"preset,omitempty"`  // Built-in lab merged in first; see Presets
 	App       AppConfig        `yaml:"app"`
 	Data      *DataConfig      `yaml:"data,omitempty"`
 	Files     []FileConfig     `yaml:"files,omitempty"`
 	Endpoints []EndpointConfig `yaml:"endpoints"`
+	Chains    []ChainConfig    `yaml:"chains,omitempty"`
+	Auth      *AuthConfig      `yaml:"auth,omitempty"`
+	Flags     []FlagConfig     `yaml:"flags,omitempty"`
+	UI        *UIConfig        `yaml:"ui,omitempty"`
+	Static    []StaticConfig   `yaml:"static,omitempty"`
+
+	// Apps defines several applications run together, each with its own address,
+	// sinks and endpoints, in place of the single app above
+	Apps []ApplicationConfig `yaml:"apps,omitempty"`
+
+	// Sources lists the files the config was loaded from, including included files
+	Sources []string `yaml:"-"`
+}
+
+// ApplicationConfig is one application of a multi-app config
+type ApplicationConfig struct {
+	App       AppConfig        `yaml:"app"`
+	Data      *DataConfig      `yaml:"data,omitempty"`
+	Files     []FileConfig     `yaml:"files,omitempty"`
+	Endpoints []EndpointConfig `yaml:"endpoints"`
+	Chains    []ChainConfig    `yaml:"chains,omitempty"`
+	Auth      *AuthConfig      `yaml:"auth,omitempty"`
+	Flags     []FlagConfig     `yaml:"flags,omitempty"`
+	UI        *UIConfig        `yaml:"ui,omitempty"`
+	Static    []StaticConfig   `yaml:"static,omitempty"`
 }
 
 // AppConfig holds application-level settings
@@ -15,6 +42,60 @@ type AppConfig struct {
 	Port        int        `yaml:"port"`
 	Host        string     `yaml:"host,omitempty"` // Host to bind to (default: 0.0.0.0)
 	TLS         *TLSConfig `yaml:"tls,omitempty"`
+	Seed        int64      `yaml:"seed,omitempty"` // Makes generated data, tokens and IDs the same on every run (default: random per instance)
+
+	// CloudMetadata emulates a 169.254.169.254 metadata service for SSRF targets
+	CloudMetadata *CloudMetadataConfig `yaml:"cloud_metadata,omitempty"`
+
+	// Mail captures outbound email and exposes it for inspection
+	Mail *MailConfig `yaml:"mail,omitempty"`
+
+	// OOB runs a local listener that records out-of-band callbacks from blind payloads
+	OOB *OOBConfig `yaml:"oob,omitempty"`
+
+	// ObjectStorage runs an in-memory S3-compatible object store, seeded from data.buckets
+	ObjectStorage *ObjectStorageConfig `yaml:"object_storage,omitempty"`
+
+	// Egress restricts what the HTTP sink can reach; by default only loopback and emulated services
+	Egress *EgressConfig `yaml:"egress,omitempty"`
+
+	// Filesystem sets quotas on the filesystem sink and controls resetting it to the initial files
+	Filesystem *FilesystemConfig `yaml:"filesystem,omitempty"`
+
+	// Uploads limits the multipart bodies read by the multipart_field and file placements
+	Uploads *UploadsConfig `yaml:"uploads,omitempty"`
+
+	// Sessions keeps a server-side key/value bag per client that modules read and write
+	Sessions *SessionsConfig `yaml:"sessions,omitempty"`
+
+	// Isolation gives each user or session its own copy of the SQLite database and filesystem
+	Isolation *IsolationConfig `yaml:"isolation,omitempty"`
+
+	// Admin serves a token-protected management API on a separate listener
+	Admin *AdminConfig `yaml:"admin,omitempty"`
+
+	// Errors replaces the plain responses to requests no endpoint matches
+	Errors *ErrorsConfig `yaml:"errors,omitempty"`
+
+	// HTTP tunes the listener's protocols, timeouts and header limits
+	HTTP *HTTPConfig `yaml:"http,omitempty"`
+
+	// GRPC serves the endpoints of type grpc on a separate listener
+	GRPC *GRPCConfig `yaml:"grpc,omitempty"`
+
+	// Logging sets the format, directory and rotation of request logs
+	Logging *LoggingConfig `yaml:"logging,omitempty"`
+
+	// Notifications alert these targets whenever a request exploits a vulnerability
+	Notifications []NotificationConfig `yaml:"notifications,omitempty"`
+
+	// Tracing exports a trace of each request's extraction, modules and sink
+	// operations to an OpenTelemetry collector
+	Tracing *TracingConfig `yaml:"tracing,omitempty"`
+
+	// Capture records full request/response pairs, exported as HAR, and
+	// optionally the listener's TCP streams, exported as PCAP
+	Capture *CaptureConfig `yaml:"capture,omitempty"`
 }
 
 // TLSConfig holds HTTPS/TLS configuration
@@ -23,17 +104,287 @@ type TLSConfig struct {
 	CertFile     string `yaml:"cert_file,omitempty"`
 	KeyFile      string `yaml:"key_file,omitempty"`
 	AutoGenerate bool   `yaml:"auto_generate,omitempty"`
+
+	// Settings for auto_generate: a lab CA (ca.crt, to import into a proxy or
+	// browser) signs the certificate in place of self-signing it
+	CA      bool     `yaml:"ca,omitempty"`
+	Hosts   []string `yaml:"hosts,omitempty"`    // DNS names and IPs the certificate covers (default: localhost, 127.0.0.1, ::1)
+	CertDir string   `yaml:"cert_dir,omitempty"` // Where generated files are kept (default: certs)
+
+	RedirectPort int `yaml:"redirect_port,omitempty"` // Also serve plain HTTP on this port, redirecting to HTTPS
+
+	// ClientAuth asks for client certificates: none (default), request, require,
+	// verify_if_given or verify. Verifying uses client_ca_file, or the lab CA,
+	// which then also issues client.crt and client.key.
+	ClientAuth   string `yaml:"client_auth,omitempty"`
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+}
+
+// CloudMetadataConfig configures the emulated cloud metadata service
+type CloudMetadataConfig struct {
+	Provider        string `yaml:"provider,omitempty"` // aws, gcp or azure (default: aws)
+	RoleName        string `yaml:"role_name,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	Token           string `yaml:"token,omitempty"`
+	Flag            string `yaml:"flag,omitempty"`
+	IMDSv2          bool   `yaml:"imdsv2,omitempty"` // AWS only - require a session token
+}
+
+// MailConfig configures the SMTP capture sink
+type MailConfig struct {
+	SMTPListen  string `yaml:"smtp_listen,omitempty"`  // Also accept mail over SMTP on this address (e.g. 127.0.0.1:2525)
+	MailboxPath string `yaml:"mailbox_path,omitempty"` // Inspection endpoint (default: /mailbox)
+}
+
+// OOBConfig configures the out-of-band interaction listener
+type OOBConfig struct {
+	Domain     string `yaml:"domain,omitempty"`      // Callback domain (default: oob.flawfactory.local)
+	HTTPListen string `yaml:"http_listen,omitempty"` // Also accept HTTP callbacks on this address (e.g. 127.0.0.1:8880)
+	DNSListen  string `yaml:"dns_listen,omitempty"`  // Answer DNS queries over UDP on this address (e.g. 127.0.0.1:5353)
+	APIPath    string `yaml:"api_path,omitempty"`    // Token and interaction API (default: /oob)
+}
+
+// ObjectStorageConfig configures the S3-compatible object store
+type ObjectStorageConfig struct {
+	Listen              string `yaml:"listen,omitempty"`                // Also serve the S3 API on this internal address (e.g. 127.0.0.1:9000)
+	Host                string `yaml:"host,omitempty"`                  // Virtual hostname (default: s3.flawfactory.local)
+	AccessKeyID         string `yaml:"access_key_id,omitempty"`         // Default: the cloud_metadata credentials, if configured
+	SecretAccessKey     string `yaml:"secret_access_key,omitempty"`     // Signs presigned URLs
+	PresignIgnoreExpiry bool   `yaml:"presign_ignore_expiry,omitempty"` // Accept expired presigned URLs
+	PresignBucketScoped bool   `yaml:"presign_bucket_scoped,omitempty"` // Presigned URLs for one key open the whole bucket
+}
+
+// EgressConfig controls outbound requests from the HTTP sink
+type EgressConfig struct {
+	AllowExternal   bool              `yaml:"allow_external,omitempty"`    // Reach any address, not just loopback and allowed_hosts
+	AllowedHosts    []string          `yaml:"allowed_hosts,omitempty"`     // Reachable despite the default deny; "*.example.com" matches subdomains
+	MaxResponseSize int64             `yaml:"max_response_size,omitempty"` // Bytes (default: 5MB)
+	Timeout         string            `yaml:"timeout,omitempty"`           // Cap on every request, e.g. 10s
+	Routes          map[string]string `yaml:"routes,omitempty"`            // Hostname or IP -> emulated service: app, metadata or object_storage
+}
+
+// FilesystemConfig limits and resets the filesystem sink
+type FilesystemConfig struct {
+	MaxBytes      int64  `yaml:"max_bytes,omitempty"`      // Total size of all files, seeded ones included
+	MaxFiles      int    `yaml:"max_files,omitempty"`      // Total number of files, seeded ones included
+	ResetInterval string `yaml:"reset_interval,omitempty"` // Restore the initial files periodically, e.g. 15m
+	APIPath       string `yaml:"api_path,omitempty"`       // Usage and reset endpoints (default: /filesystem)
+}
+
+// UploadsConfig limits multipart form data
+type UploadsConfig struct {
+	MaxBodySize int64 `yaml:"max_body_size,omitempty"` // Bytes in the whole body (default: 32MB)
+	MaxFileSize int64 `yaml:"max_file_size,omitempty"` // Bytes in each uploaded file (default: 10MB)
+}
+
+// SessionsConfig sets up server-side sessions, found by the ID in a cookie.
+// Session auth keeps its logins in them too.
+type SessionsConfig struct {
+	Cookie         string `yaml:"cookie,omitempty"`           // Cookie carrying the session ID (default: auth.cookie, or session)
+	TTL            string `yaml:"ttl,omitempty"`              // Idle time after which a session expires, e.g. 30m (default: never)
+	SameSite       string `yaml:"same_site,omitempty"`        // SameSite of the cookie: lax (default), strict or none
+	ScriptReadable bool   `yaml:"script_readable,omitempty"`  // Leave HttpOnly off, so scripts can steal the cookie
+	AdoptIDs       bool   `yaml:"adopt_ids,omitempty"`        // Accept session IDs the client made up (session fixation)
+	KeepIDOnLogin  bool   `yaml:"keep_id_on_login,omitempty"` // Don't issue a new ID when logging in (session fixation)
+}
+
+// IsolationConfig gives each client its own copy of the mutable sinks, made
+// from their seeded state on the client's first request. Clients that aren't
+// logged in, or have no session, share the original.
+type IsolationConfig struct {
+	By         string `yaml:"by"`                    // user (the auth section's logged-in user) or session
+	MaxCopies  int    `yaml:"max_copies,omitempty"`  // Copies kept at once; the least recently used goes first (default: 100)
+	IdleExpiry string `yaml:"idle_expiry,omitempty"` // Drop copies unused this long, e.g. 2h (default: never)
+}
+
+// AdminConfig serves the admin API: routes, sink stats, state resets,
+// vulnerability toggles and request logs, for requests carrying the token
+type AdminConfig struct {
+	Host  string `yaml:"host,omitempty"` // Host to bind to (default: 127.0.0.1)
+	Port  int    `yaml:"port,omitempty"` // Default: app.port + 1000
+	Token string `yaml:"token"`          // Sent as Authorization: Bearer <token>
+}
+
+// GRPCConfig is the listener of the endpoints of type grpc, which speaks
+// plaintext HTTP/2 (h2c), as grpcurl -plaintext does
+type GRPCConfig struct {
+	Host       string `yaml:"host,omitempty"`       // Host to bind to (default: app.host)
+	Port       int    `yaml:"port"`                 // Required
+	Reflection *bool  `yaml:"reflection,omitempty"` // Serve server reflection, so tools can list and call the services (default: true)
+}
+
+// LoggingConfig sets how requests are logged to the app's log file
+type LoggingConfig struct {
+	Format string `yaml:"format,omitempty"` // json (default), cef or ecs
+	Dir    string `yaml:"dir,omitempty"`    // Directory of the log files, "log" by default
+
+	// Rotation, off unless max_bytes or rotate_every is set
+	MaxBytes    int64  `yaml:"max_bytes,omitempty"`    // Rotate before the file grows past this size
+	RotateEvery string `yaml:"rotate_every,omitempty"` // Rotate files this old, e.g. "24h"
+	Compress    bool   `yaml:"compress,omitempty"`     // Gzip rotated files
+	MaxFiles    int    `yaml:"max_files,omitempty"`    // Rotated files kept, all by default
+	MaxAge      string `yaml:"max_age,omitempty"`      // Delete rotated files older than this, e.g. "168h"
+
+	// Bodies, the request's of POST, PUT and PATCH by default
+	Bodies  bool          `yaml:"bodies,omitempty"`   // Log the request body whatever the method, and the response body
+	MaxBody int           `yaml:"max_body,omitempty"` // Bytes of each body logged (default: 10000)
+	Redact  *RedactConfig `yaml:"redact,omitempty"`   // Hide secrets in logged entries, off unless set
+
+	// Outputs ship every entry to external backends too, in the background
+	Outputs []LogOutputConfig `yaml:"outputs,omitempty"`
+}
+
+// LogOutputConfig ships request log entries to Elasticsearch, Grafana Loki or
+// a syslog receiver
+type LogOutputConfig struct {
+	Type     string            `yaml:"type"`               // elasticsearch, loki or syslog
+	URL      string            `yaml:"url,omitempty"`      // elasticsearch and loki: the server, e.g. http://localhost:9200
+	Address  string            `yaml:"address,omitempty"`  // syslog: host:port of the receiver
+	Network  string            `yaml:"network,omitempty"`  // syslog: udp (default) or tcp
+	Facility string            `yaml:"facility,omitempty"` // syslog: e.g. local0 (default), auth or daemon
+	Index    string            `yaml:"index,omitempty"`    // elasticsearch: default flawfactory-requests
+	Labels   map[string]string `yaml:"labels,omitempty"`   // loki: stream labels, besides job=flawfactory and app
+	Headers  map[string]string `yaml:"headers,omitempty"`  // elasticsearch and loki: e.g. Authorization or X-Scope-OrgID
+	Format   string            `yaml:"format,omitempty"`   // json, cef or ecs (default: ecs for elasticsearch, else app.logging.format)
+	Timeout  string            `yaml:"timeout,omitempty"`  // Longest a batch takes to send (default: 10s)
+}
+
+// RedactConfig hides secrets in logged entries, replacing them with [REDACTED]
+type RedactConfig struct {
+	Headers  []string `yaml:"headers,omitempty"`  // Request headers whose values are hidden (default: Authorization, Proxy-Authorization, Cookie, X-Api-Key)
+	Patterns []string `yaml:"patterns,omitempty"` // Regular expressions hidden wherever they match; of one with groups, only the groups
+	Secrets  *bool    `yaml:"secrets,omitempty"`  // Hide the flags, user passwords and tokens, and keys the lab is configured with (default: true)
+}
+
+// TracingConfig sends traces to an OpenTelemetry collector over OTLP/HTTP
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`               // Collector URL, e.g. http://localhost:4318; /v1/traces is added to one without a path
+	ServiceName string            `yaml:"service_name,omitempty"` // Default: the app name
+	Headers     map[string]string `yaml:"headers,omitempty"`      // Sent with every export, e.g. an API key
+	SampleRatio *float64          `yaml:"sample_ratio,omitempty"` // Share of requests traced, 0 to 1 (default: 1)
+}
+
+// CaptureConfig records the app's traffic for export through the admin API or
+// run --har and --pcap
+type CaptureConfig struct {
+	MaxEntries int   `yaml:"max_entries,omitempty"` // Request/response pairs kept, the oldest dropped (default: 1000)
+	MaxBody    int64 `yaml:"max_body,omitempty"`    // Bytes of each body kept (default: 1 MiB)
+	PCAP       bool  `yaml:"pcap,omitempty"`        // Also record the listener's TCP streams, as they are on the wire
+	MaxPCAP    int64 `yaml:"max_pcap,omitempty"`    // Bytes of packets kept, the oldest dropped (default: 64 MiB)
+}
+
+// NotificationConfig is a target exploitation events are POSTed to
+type NotificationConfig struct {
+	Type        string            `yaml:"type,omitempty"`         // webhook (default: the event as JSON), slack or http
+	URL         string            `yaml:"url"`                    // Slack: the incoming webhook URL
+	Headers     map[string]string `yaml:"headers,omitempty"`      // e.g. Authorization
+	Body        string            `yaml:"body,omitempty"`         // http: Go template of the body, executed with the event
+	ContentType string            `yaml:"content_type,omitempty"` // http: default text/plain
+	Timeout     string            `yaml:"timeout,omitempty"`      // Default: 5s
+}
+
+// ErrorsConfig sets the responses to requests no route matches
+type ErrorsConfig struct {
+	NotFound         *ErrorPageConfig `yaml:"not_found,omitempty"`          // No route for the path
+	MethodNotAllowed *ErrorPageConfig `yaml:"method_not_allowed,omitempty"` // Routes for the path, but not the method
+}
+
+// HTTPConfig tunes the app's listener for protocol-level exercises. Timeouts
+// are durations such as 30s; "0" turns one off.
+type HTTPConfig struct {
+	HTTP2             *bool  `yaml:"http2,omitempty"`               // HTTP/2 over TLS, negotiated with ALPN (default: true)
+	H2C               bool   `yaml:"h2c,omitempty"`                 // HTTP/2 over plain HTTP, with prior knowledge
+	KeepAlive         *bool  `yaml:"keep_alive,omitempty"`          // Reuse HTTP/1.1 connections (default: true)
+	ReadTimeout       string `yaml:"read_timeout,omitempty"`        // Reading a whole request (default: 15s)
+	ReadHeaderTimeout string `yaml:"read_header_timeout,omitempty"` // Reading request headers (default: read_timeout)
+	WriteTimeout      string `yaml:"write_timeout,omitempty"`       // Writing a response (default: 15s)
+	IdleTimeout       string `yaml:"idle_timeout,omitempty"`        // Keeping an idle connection open (default: 60s)
+	MaxHeaderBytes    int    `yaml:"max_header_bytes,omitempty"`    // Request line and headers (default: 1MB)
+	MaxBodySize       int64  `yaml:"max_body_size,omitempty"`       // Bytes in a request body; larger ones get a 413 (default: no limit)
+
+	// Protection set to false drops the timeouts and body limits, so slowloris
+	// and oversized bodies can be shown against an unprotected listener
+	Protection *bool `yaml:"protection,omitempty"`
+}
+
+// ErrorPageConfig is the response to an unmatched request: a templated body,
+// or a verbose JSON dump as a framework's debug mode would leak
+type ErrorPageConfig struct {
+	Status      int    `yaml:"status,omitempty"`       // Default: 404 or 405; 200 makes a soft 404
+	ContentType string `yaml:"content_type,omitempty"` // Default: text/html, or application/json when verbose
+	Body        string `yaml:"body,omitempty"`         // Text template with .Request, .Status and .RequestID
+	Verbose     bool   `yaml:"verbose,omitempty"`      // JSON with the request, the app's routes and a stack trace
 }
 
 // DataConfig holds database table definitions
 type DataConfig struct {
 	Tables map[string]TableConfig `yaml:"tables,omitempty"`
+
+	// Redis seeds the embedded Redis store: scalars become strings, lists become lists, maps become hashes
+	Redis map[string]interface{} `yaml:"redis,omitempty"`
+
+	// Collections seeds the document store; tables are also loaded as collections of the same name
+	Collections map[string][]map[string]interface{} `yaml:"collections,omitempty"`
+
+	// LDAP seeds the in-memory directory
+	LDAP *LDAPDataConfig `yaml:"ldap,omitempty"`
+
+	// Buckets seeds the object store
+	Buckets []BucketConfig `yaml:"buckets,omitempty"`
+
+	// Persistence stores the SQLite database in a file so state survives restarts
+	Persistence *PersistenceConfig `yaml:"persistence,omitempty"`
+}
+
+// PersistenceConfig configures the file-backed SQLite database
+type PersistenceConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path,omitempty"`   // Default: flawfactory.db
+	Reseed  string `yaml:"reseed,omitempty"` // on_change (default), always, never
+}
+
+// LDAPDataConfig defines the directory tree: users go under ou=people, groups under ou=groups
+type LDAPDataConfig struct {
+	BaseDN        string                   `yaml:"base_dn,omitempty"`        // Default: dc=flawfactory,dc=local
+	StrictFilters bool                     `yaml:"strict_filters,omitempty"` // Reject trailing input after a filter instead of ignoring it
+	Users         []map[string]interface{} `yaml:"users,omitempty"`          // Attributes per user; uid is required
+	Groups        []LDAPGroupConfig        `yaml:"groups,omitempty"`
+}
+
+// BucketConfig defines an object store bucket and its objects
+type BucketConfig struct {
+	Name    string         `yaml:"name"`
+	ACL     string         `yaml:"acl,omitempty"` // private (default), public-read or public-read-write
+	Objects []ObjectConfig `yaml:"objects,omitempty"`
+}
+
+// ObjectConfig defines an object stored in a bucket
+type ObjectConfig struct {
+	Key         string `yaml:"key"`
+	Content     string `yaml:"content"`
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// LDAPGroupConfig defines a groupOfNames entry
+type LDAPGroupConfig struct {
+	CN          string   `yaml:"cn"`
+	Description string   `yaml:"description,omitempty"`
+	Members     []string `yaml:"members,omitempty"` // Member uids
 }
 
 // TableConfig defines a database table structure
 type TableConfig struct {
-	Columns []string        `yaml:"columns"`
-	Rows    [][]interface{} `yaml:"rows"`
+	Columns  []string        `yaml:"columns"`
+	Rows     [][]interface{} `yaml:"rows"`
+	Generate *GenerateConfig `yaml:"generate,omitempty"` // Synthesize more rows at startup, after the listed ones
+}
+
+// GenerateConfig describes rows synthesized for a table
+type GenerateConfig struct {
+	Rows    int               `yaml:"rows"`
+	Seed    int64             `yaml:"seed,omitempty"` // The same seed gives the same rows (default: derived from app.seed, or 1)
+	Columns map[string]string `yaml:"columns"`        // Column to generator: sequence, faker.<kind> or one_of:a,b,c
 }
 
 // FileConfig defines a file to be created
@@ -42,18 +393,198 @@ type FileConfig struct {
 	Content string `yaml:"content"`
 }
 
+// StaticConfig serves a directory of the filesystem sink under a URL prefix,
+// for assets and loot files found by browsing
+type StaticConfig struct {
+	Path    string `yaml:"path"`              // URL prefix, e.g. /assets
+	Dir     string `yaml:"dir"`               // Directory in the filesystem sink, e.g. www/assets; created if missing
+	Listing bool   `yaml:"listing,omitempty"` // List directories without an index.html instead of answering 404
+}
+
 // EndpointConfig defines an HTTP endpoint
 type EndpointConfig struct {
 	Path            string                `yaml:"path"`
 	Method          string                `yaml:"method"`
+	Host            string                `yaml:"host,omitempty"` // Only match requests for this virtual host
 	ResponseType    string                `yaml:"response_type,omitempty"`
+	WebSocket       bool                  `yaml:"websocket,omitempty"` // Upgrade GET requests to a WebSocket
+	Status          int                   `yaml:"status,omitempty"`    // Sent in place of 200 OK; module and error statuses still win
+	CSP             *CSPConfig            `yaml:"csp,omitempty"`
 	Vulnerabilities []VulnerabilityConfig `yaml:"vulnerabilities"`
+
+	// Type makes the endpoint a redirect (to target, with status 3xx), a proxy
+	// (forwarding requests to the target URL) or an alias (serving the endpoint
+	// target names, given as METHOD /path). {name} in a redirect or proxy target
+	// is replaced by the request's path parameter. A soap endpoint takes SOAP
+	// envelopes instead, described by SOAP, and a grpc endpoint is a gRPC method
+	// (path /package.Service/Method) served on app.grpc's listener, whose request
+	// message GRPC declares.
+	Type   string            `yaml:"type,omitempty"`
+	Target string            `yaml:"target,omitempty"`
+	SOAP   *SOAPConfig       `yaml:"soap,omitempty"`
+	GRPC   *GRPCMethodConfig `yaml:"grpc,omitempty"`
+
+	// ResponseTemplate renders the response body in place of the default wrapper;
+	// see ParseResponseTemplate for the fields and functions it can use
+	ResponseTemplate string `yaml:"response_template,omitempty"`
+
+	// Headers are sent on every response, e.g. Server banners or Cache-Control.
+	// A value with several lines is sent as one header per line, for Set-Cookie.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Latency delays every request by a random duration in its range, and
+	// ErrorRate fails that fraction of requests (0-1) with a 503, before the
+	// vulnerabilities run
+	Latency   *LatencyConfig `yaml:"latency,omitempty"`
+	ErrorRate float64        `yaml:"error_rate,omitempty"`
+
+	// Auth is required to put the endpoint behind the login wall when auth.protect
+	// is listed, or none to leave it public when every endpoint is protected
+	Auth string `yaml:"auth,omitempty"`
+
+	// MaxBodySize caps the endpoint's request bodies in place of app.http.max_body_size
+	MaxBodySize int64 `yaml:"max_body_size,omitempty"`
+}
+
+// SOAPConfig describes the service of a soap endpoint, which answers POSTed
+// envelopes and serves a WSDL generated from this to GET requests
+type SOAPConfig struct {
+	Service    string          `yaml:"service,omitempty"`   // Default: FlawFactoryService
+	Namespace  string          `yaml:"namespace,omitempty"` // Target namespace (default: urn:<service>)
+	Operations []SOAPOperation `yaml:"operations"`
+}
+
+// SOAPOperation is an operation of a SOAP service, the element in the Body of
+// its requests
+type SOAPOperation struct {
+	Name   string   `yaml:"name"`
+	Params []string `yaml:"params,omitempty"` // Child elements in the WSDL (default: the soap_param params of the vulnerabilities)
+}
+
+// GRPCMethodConfig declares the request message of a grpc endpoint. Its
+// response is a message with one string field, result (1), holding the JSON
+// the endpoint would answer over HTTP.
+type GRPCMethodConfig struct {
+	Fields []GRPCField `yaml:"fields"`
+}
+
+// GRPCField is a field of a gRPC request message
+type GRPCField struct {
+	Name   string      `yaml:"name"`
+	Number int         `yaml:"number,omitempty"` // Default: its position, from 1
+	Type   string      `yaml:"type,omitempty"`   // string (default), bytes, bool, int32, int64, uint32, uint64, double, float or message
+	Fields []GRPCField `yaml:"fields,omitempty"` // Of a message
+}
+
+// LatencyConfig is the range of an endpoint's artificial delay
+type LatencyConfig struct {
+	Min string `yaml:"min,omitempty"` // Duration, e.g. 200ms (default: 0)
+	Max string `yaml:"max,omitempty"` // Duration (default: min, for a fixed delay)
+}
+
+// AuthConfig puts endpoints behind a login wall, checked against users stored
+// in a SQLite table, so vulnerabilities can sit behind a realistic login
+type AuthConfig struct {
+	Type      string           `yaml:"type"`                 // basic, session, bearer or api_key
+	Protect   string           `yaml:"protect,omitempty"`    // all (default) or listed, for only endpoints with auth: required
+	Table     string           `yaml:"table,omitempty"`      // SQLite table holding the users (default: auth_users)
+	Users     []AuthUserConfig `yaml:"users,omitempty"`      // Seeded into table; without users, table must be one of data.tables
+	Realm     string           `yaml:"realm,omitempty"`      // basic: realm shown in the login prompt (default: app name)
+	LoginPath string           `yaml:"login_path,omitempty"` // session and bearer: where credentials are posted (default: /login)
+	Cookie    string           `yaml:"cookie,omitempty"`     // session: cookie carrying the session ID (default: session)
+	Header    string           `yaml:"header,omitempty"`     // api_key: header carrying the key (default: X-API-Key)
+}
+
+// AuthUserConfig is one user seeded into the auth table
+type AuthUserConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+	Role     string `yaml:"role,omitempty"`
+	Token    string `yaml:"token,omitempty"` // bearer and api_key: accepted in place of logging in
+}
+
+// ChainConfig links endpoints into a multi-step scenario. Each step stays
+// locked for a client until that client has exploited the step before it.
+type ChainConfig struct {
+	Name  string            `yaml:"name"`
+	Steps []ChainStepConfig `yaml:"steps"`
+}
+
+// ChainStepConfig is one step of a chain
+type ChainStepConfig struct {
+	Endpoint string `yaml:"endpoint"`        // METHOD /path, or METHOD host/path for a virtual host
+	Proof    string `yaml:"proof,omitempty"` // Text only a successful exploit reveals, e.g. a leaked token; required on all but the last step
+}
+
+// FlagConfig declares a CTF flag. It is placed by referencing it as ${flag:NAME}
+// in table rows, file and object contents, endpoint headers and response
+// templates, and can also be set in the environment of executed commands or
+// handed out by a successful exploit.
+type FlagConfig struct {
+	Name   string            `yaml:"name"`
+	Value  string            `yaml:"value,omitempty"`  // Fixed flag; without it one is generated per instance (from app.seed, if set)
+	Format string            `yaml:"format,omitempty"` // Generated flags, with %s standing for random hex (default: FLAG{%s})
+	Env    string            `yaml:"env,omitempty"`    // Environment variable holding the flag for commands the command sink runs
+	Reveal *FlagRevealConfig `yaml:"reveal,omitempty"`
+}
+
+// FlagRevealConfig sends a flag in a response header once the endpoint's
+// module results show it was exploited
+type FlagRevealConfig struct {
+	Endpoint string `yaml:"endpoint"`         // METHOD /path, or METHOD host/path for a virtual host
+	Proof    string `yaml:"proof,omitempty"`  // Text only a successful exploit outputs (default: a result reporting exploitable: true)
+	Header   string `yaml:"header,omitempty"` // Default: X-Flag
+}
+
+// UIConfig generates HTML pages bound to the endpoints, so the lab can be
+// explored in a browser instead of only with curl
+type UIConfig struct {
+	Path  string         `yaml:"path,omitempty"`  // Where the index page is served (default: /ui)
+	Title string         `yaml:"title,omitempty"` // Shown on every page (default: app name)
+	Pages []UIPageConfig `yaml:"pages,omitempty"`
+}
+
+// UIPageConfig is a page of the UI, a form submitting to one of the endpoints.
+// The endpoint's response is shown below the form.
+type UIPageConfig struct {
+	Type     string   `yaml:"type"`               // login, search or comments
+	Endpoint string   `yaml:"endpoint,omitempty"` // METHOD /path the form submits to; login defaults to the auth login endpoint
+	Path     string   `yaml:"path,omitempty"`     // Default: the UI path followed by /type, e.g. /ui/search
+	Title    string   `yaml:"title,omitempty"`    // Default: from the type, e.g. Search
+	Fields   []string `yaml:"fields,omitempty"`   // Default: the params the endpoint's vulnerabilities read from the form; login: username and password
+	List     string   `yaml:"list,omitempty"`     // comments: GET /path listing the comments, reloaded after each post
+}
+
+// CSPConfig sets a Content-Security-Policy header on an endpoint
+type CSPConfig struct {
+	Preset     string `yaml:"preset,omitempty"`      // unsafe_inline, wildcard, jsonp or strict
+	Policy     string `yaml:"policy,omitempty"`      // Raw policy - overrides the preset
+	ReportOnly bool   `yaml:"report_only,omitempty"` // Send Content-Security-Policy-Report-Only instead
+	JSONPPath  string `yaml:"jsonp_path,omitempty"`  // JSONP endpoint served for the jsonp preset (default: /api/jsonp)
 }
 
 // VulnerabilityConfig defines a vulnerability on an endpoint
 type VulnerabilityConfig struct {
-	Type      string                 `yaml:"type"`
-	Placement string                 `yaml:"placement"`
-	Param     string                 `yaml:"param"`
-	Config    map[string]interface{} `yaml:"config,omitempty"`
+	Type        string                 `yaml:"type"`
+	Placement   string                 `yaml:"placement"` // Where the param is read from, or any to try every placement the module supports
+	Param       string                 `yaml:"param,omitempty"`
+	Params      []string               `yaml:"params,omitempty"` // Several parameters in place of param; the first one present in the request is used
+	Config      map[string]interface{} `yaml:"config,omitempty"`
+	Difficulty  string                 `yaml:"difficulty,omitempty"`   // easy, medium or hard - fills in the module settings config leaves unset
+	Sink        string                 `yaml:"sink,omitempty"`         // Sink to use instead of the one implied by the type, e.g. filesystem or a plugin sink
+	SinkOptions map[string]interface{} `yaml:"sink_options,omitempty"` // Per-vulnerability settings for the selected sink
+
+	// EnabledIf limits the vulnerability to requests meeting its conditions;
+	// otherwise the endpoint handles the request as if it weren't configured
+	EnabledIf *ConditionConfig `yaml:"enabled_if,omitempty"`
+}
+
+// ConditionConfig holds the conditions a request must meet for a vulnerability
+// to be active. Every condition that is set must hold.
+type ConditionConfig struct {
+	Header  string `yaml:"header,omitempty"`  // NAME=value, or just NAME to require the header
+	Cookie  string `yaml:"cookie,omitempty"`  // NAME=value, or just NAME to require the cookie
+	Query   string `yaml:"query,omitempty"`   // NAME=value, or just NAME to require the query parameter
+	Time    string `yaml:"time,omitempty"`    // Daily window in server local time, e.g. 09:00-17:00 (may wrap past midnight)
+	Percent int    `yaml:"percent,omitempty"` // Active for this percentage of requests (1-100)
 }
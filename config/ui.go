@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultUIPath is where the UI's index page is served
+const DefaultUIPath = "/ui"
+
+// uiPageTypes lists the pages the UI can generate
+var uiPageTypes = []string{"login", "search", "comments"}
+
+// IndexPath returns the path of the index page
+func (u *UIConfig) IndexPath() string {
+	if u.Path == "" {
+		return DefaultUIPath
+	}
+	if path := strings.TrimRight(u.Path, "/"); path != "" {
+		return path
+	}
+	return "/"
+}
+
+// PagePath returns the path a page is served at
+func (u *UIConfig) PagePath(page UIPageConfig) string {
+	if page.Path != "" {
+		return page.Path
+	}
+	return strings.TrimRight(u.IndexPath(), "/") + "/" + page.Type
+}
+
+// PageTitle returns the page's heading
+func (p UIPageConfig) PageTitle() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	if p.Type == "" {
+		return ""
+	}
+	return strings.ToUpper(p.Type[:1]) + p.Type[1:]
+}
+
+// FormEndpoint returns the endpoint the page's form submits to. Login pages
+// default to, and may name, the login endpoint of session and bearer auth.
+func (p UIPageConfig) FormEndpoint(endpoints []EndpointConfig, auth *AuthConfig) (EndpointConfig, bool) {
+	ref := p.Endpoint
+	hasLogin := auth != nil && auth.hasLogin()
+	if ref == "" && p.Type == "login" && hasLogin {
+		ref = "POST " + auth.LoginRoute()
+	}
+
+	for _, endpoint := range endpoints {
+		if targets(ref, endpoint) {
+			return endpoint, true
+		}
+	}
+	if hasLogin {
+		login := EndpointConfig{Method: "POST", Path: auth.LoginRoute()}
+		if targets(ref, login) {
+			return login, true
+		}
+	}
+	return EndpointConfig{}, false
+}
+
+// ListEndpoint returns the endpoint a comments page lists comments from
+func (p UIPageConfig) ListEndpoint(endpoints []EndpointConfig) (EndpointConfig, bool) {
+	for _, endpoint := range endpoints {
+		if targets(p.List, endpoint) {
+			return endpoint, true
+		}
+	}
+	return EndpointConfig{}, false
+}
+
+// FormFields returns the fields of the page's form: the configured ones, or
+// else the params the endpoint's vulnerabilities read from what the form
+// sends, query parameters for GET and form fields for POST
+func (p UIPageConfig) FormFields(endpoint EndpointConfig) []string {
+	if len(p.Fields) > 0 {
+		return p.Fields
+	}
+
+	placement := "form_field"
+	if strings.EqualFold(endpoint.Method, "GET") {
+		placement = "query_param"
+	}
+	var fields []string
+	for _, vuln := range endpoint.Vulnerabilities {
+		if vuln.Placement != placement && vuln.Placement != AnyPlacement {
+			continue
+		}
+		for _, name := range vuln.ParamNames() {
+			if name != "" && !slices.Contains(fields, name) {
+				fields = append(fields, name)
+			}
+		}
+	}
+
+	if len(fields) == 0 && p.Type == "login" {
+		return []string{"username", "password"}
+	}
+	return fields
+}
+
+// validateUI validates the ui section's pages against the endpoints they submit to
+func validateUI(ui *UIConfig, endpoints []EndpointConfig, auth *AuthConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if ui.Path != "" && !strings.HasPrefix(ui.Path, "/") {
+		errs = append(errs, ValidationError{
+			Field:   "ui.path",
+			Message: fmt.Sprintf("path must start with '/', got '%s'", ui.Path),
+		})
+	}
+
+	// Pages can't take the place of each other or of an endpoint
+	paths := map[string]string{ui.IndexPath(): "ui.path"}
+	for _, endpoint := range endpoints {
+		if strings.EqualFold(endpoint.Method, "GET") && endpoint.Host == "" {
+			paths[endpoint.Path] = fmt.Sprintf("endpoint 'GET %s'", endpoint.Path)
+		}
+	}
+	if prev, exists := paths[ui.IndexPath()]; exists && prev != "ui.path" {
+		errs = append(errs, ValidationError{
+			Field:   "ui.path",
+			Message: fmt.Sprintf("'%s' is already used by %s", ui.IndexPath(), prev),
+		})
+	}
+
+	for i, page := range ui.Pages {
+		prefix := fmt.Sprintf("ui.pages[%d]", i)
+
+		if !slices.Contains(uiPageTypes, page.Type) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".type",
+				Message: fmt.Sprintf("invalid page type '%s', must be one of: %s", page.Type, strings.Join(uiPageTypes, ", ")),
+			})
+			continue
+		}
+
+		path := ui.PagePath(page)
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("path must start with '/', got '%s'", path),
+			})
+		} else if prev, exists := paths[path]; exists {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".path",
+				Message: fmt.Sprintf("'%s' is already used by %s, set a path", path, prev),
+			})
+		} else {
+			paths[path] = prefix
+		}
+
+		errs = append(errs, validateUIForm(page, endpoints, auth, prefix)...)
+
+		if page.List != "" {
+			if page.Type != "comments" {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".list",
+					Message: fmt.Sprintf("list doesn't apply to %s pages", page.Type),
+				})
+				continue
+			}
+			list, ok := page.ListEndpoint(endpoints)
+			switch {
+			case !ok:
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".list",
+					Message: fmt.Sprintf("no endpoint matches '%s' (expected GET /path)", page.List),
+				})
+			case !strings.EqualFold(list.Method, "GET"):
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".list",
+					Message: fmt.Sprintf("'%s' must be a GET endpoint to be shown in the page", page.List),
+				})
+			default:
+				errs = append(errs, validateUITarget(page.List, list, prefix+".list")...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateUIForm checks that a page's form submits to an endpoint a browser
+// form can reach, with fields for it to read
+func validateUIForm(page UIPageConfig, endpoints []EndpointConfig, auth *AuthConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if page.Endpoint == "" && !(page.Type == "login" && auth != nil && auth.hasLogin()) {
+		message := "endpoint is required"
+		if page.Type == "login" {
+			message = "endpoint is required unless auth is session or bearer"
+		}
+		return append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: message,
+		})
+	}
+
+	endpoint, ok := page.FormEndpoint(endpoints, auth)
+	if !ok {
+		return append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: fmt.Sprintf("no endpoint matches '%s' (expected METHOD /path)", page.Endpoint),
+		})
+	}
+	ref := endpoint.Method + " " + endpoint.Host + endpoint.Path
+
+	if !strings.EqualFold(endpoint.Method, "GET") && !strings.EqualFold(endpoint.Method, "POST") {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".endpoint",
+			Message: fmt.Sprintf("'%s' can't be submitted by a form, which only sends GET or POST", ref),
+		})
+	}
+	errs = append(errs, validateUITarget(ref, endpoint, prefix+".endpoint")...)
+
+	seen := make(map[string]bool)
+	for j, name := range page.Fields {
+		switch {
+		case name == "":
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.fields[%d]", prefix, j),
+				Message: "field name is required",
+			})
+		case seen[name]:
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.fields[%d]", prefix, j),
+				Message: fmt.Sprintf("duplicate field '%s'", name),
+			})
+		}
+		seen[name] = true
+	}
+
+	if len(page.FormFields(endpoint)) == 0 {
+		placement := "form_field"
+		if strings.EqualFold(endpoint.Method, "GET") {
+			placement = "query_param"
+		}
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".fields",
+			Message: fmt.Sprintf("fields is required, no vulnerability of '%s' reads a %s", ref, placement),
+		})
+	}
+
+	return errs
+}
+
+// validateUITarget checks that a page can point the browser at the endpoint
+func validateUITarget(ref string, endpoint EndpointConfig, field string) ValidationErrors {
+	var errs ValidationErrors
+
+	_, params, err := endpoint.Route()
+	switch {
+	case endpoint.WebSocket:
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("'%s' is a WebSocket endpoint, which a page can't load", ref),
+		})
+	case endpoint.Host != "":
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("'%s' is served on another host, which the UI can't link to", ref),
+		})
+	case err == nil && len(params) > 0:
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("'%s' has path parameters, which a page can't fill", ref),
+		})
+	}
+
+	return errs
+}
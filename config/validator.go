@@ -2,8 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/RIZZZIOM/FlawFactory/faker"
 	"github.com/RIZZZIOM/FlawFactory/modules"
 )
 
@@ -77,6 +82,14 @@ func Validate(cfg *Config) error {
 
 // ValidateWithWarnings validates the entire configuration and returns both errors and warnings
 func ValidateWithWarnings(cfg *Config) *ValidationResult {
+	if len(cfg.Apps) > 0 {
+		return validateApps(cfg, validateLab)
+	}
+	return validateLab(cfg)
+}
+
+// validateLab validates a single application's configuration
+func validateLab(cfg *Config) *ValidationResult {
 	result := &ValidationResult{}
 
 	// Validate app section
@@ -95,6 +108,72 @@ func ValidateWithWarnings(cfg *Config) *ValidationResult {
 	// Validate files section
 	result.Errors = append(result.Errors, validateFiles(cfg.Files)...)
 
+	// Validate redirect, proxy and alias endpoints
+	result.Errors = append(result.Errors, validateEndpointTypes(cfg.Endpoints)...)
+
+	// Validate chains section
+	result.Errors = append(result.Errors, validateChains(cfg.Chains, cfg.Endpoints)...)
+
+	// Validate auth section
+	result.Errors = append(result.Errors, validateAuth(cfg.Auth, cfg.Endpoints, cfg.Data)...)
+
+	// Validate session settings, which session auth shares
+	if cfg.App.Sessions != nil {
+		result.Errors = append(result.Errors, validateSessions(cfg.App.Sessions, cfg.Auth)...)
+	}
+
+	// Validate isolation, which tells clients apart by auth or sessions
+	if cfg.App.Isolation != nil {
+		result.Errors = append(result.Errors, validateIsolation(cfg)...)
+	}
+
+	// Validate the admin listener, which must not clash with the app's
+	if cfg.App.Admin != nil {
+		result.Errors = append(result.Errors, validateAdmin(cfg.App.Admin, cfg.App)...)
+	}
+
+	// Validate the request log settings
+	if cfg.App.Logging != nil {
+		result.Errors = append(result.Errors, validateLogging(cfg.App.Logging)...)
+	}
+
+	// Validate the trace collector
+	if cfg.App.Tracing != nil {
+		result.Errors = append(result.Errors, validateTracing(cfg.App.Tracing)...)
+	}
+
+	// Validate the traffic capture limits
+	if cfg.App.Capture != nil {
+		result.Errors = append(result.Errors, validateCapture(cfg.App.Capture)...)
+	}
+
+	// Validate the targets of exploitation events
+	for i, notification := range cfg.App.Notifications {
+		result.Errors = append(result.Errors, validateNotification(notification, fmt.Sprintf("app.notifications[%d]", i))...)
+	}
+
+	// Validate the error pages
+	if cfg.App.Errors != nil {
+		result.Errors = append(result.Errors, validateErrorPages(cfg.App.Errors)...)
+	}
+
+	// Validate the listener's protocol settings and body limits
+	result.Errors = append(result.Errors, validateHTTP(cfg)...)
+
+	// Validate the gRPC listener and the methods it serves
+	result.Errors = append(result.Errors, validateGRPC(cfg)...)
+
+	// Validate flags section
+	result.Errors = append(result.Errors, validateFlags(cfg)...)
+
+	// Validate static section
+	result.Errors = append(result.Errors, validateStatic(cfg.Static, cfg.Endpoints)...)
+
+	// Validate ui section
+	if cfg.UI != nil {
+		result.Errors = append(result.Errors, validateUI(cfg.UI, cfg.Endpoints, cfg.Auth)...)
+	}
+
 	return result
 }
 
@@ -118,6 +197,177 @@ func validateApp(app *AppConfig) ValidationErrors {
 		})
 	}
 
+	// Validate HTTPS settings
+	errs = append(errs, validateTLS(app.TLS, app.Port)...)
+
+	// Validate cloud metadata provider
+	if app.CloudMetadata != nil {
+		switch app.CloudMetadata.Provider {
+		case "", "aws", "gcp", "azure":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   "app.cloud_metadata.provider",
+				Message: fmt.Sprintf("invalid provider '%s', must be one of: aws, gcp, azure", app.CloudMetadata.Provider),
+			})
+		}
+	}
+
+	// Validate mail capture settings
+	if app.Mail != nil {
+		if app.Mail.SMTPListen != "" {
+			if _, _, err := net.SplitHostPort(app.Mail.SMTPListen); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   "app.mail.smtp_listen",
+					Message: fmt.Sprintf("invalid listen address '%s', expected host:port", app.Mail.SMTPListen),
+				})
+			}
+		}
+		if app.Mail.MailboxPath != "" && !strings.HasPrefix(app.Mail.MailboxPath, "/") {
+			errs = append(errs, ValidationError{
+				Field:   "app.mail.mailbox_path",
+				Message: "mailbox_path must start with /",
+			})
+		}
+	}
+
+	// Validate OOB listener settings
+	if app.OOB != nil {
+		listeners := map[string]string{"http_listen": app.OOB.HTTPListen, "dns_listen": app.OOB.DNSListen}
+		for _, field := range []string{"http_listen", "dns_listen"} {
+			if addr := listeners[field]; addr != "" {
+				if _, _, err := net.SplitHostPort(addr); err != nil {
+					errs = append(errs, ValidationError{
+						Field:   "app.oob." + field,
+						Message: fmt.Sprintf("invalid listen address '%s', expected host:port", addr),
+					})
+				}
+			}
+		}
+		if strings.ContainsAny(app.OOB.Domain, "/: ") {
+			errs = append(errs, ValidationError{
+				Field:   "app.oob.domain",
+				Message: fmt.Sprintf("invalid domain '%s'", app.OOB.Domain),
+			})
+		}
+		if app.OOB.APIPath != "" && !strings.HasPrefix(app.OOB.APIPath, "/") {
+			errs = append(errs, ValidationError{
+				Field:   "app.oob.api_path",
+				Message: "api_path must start with /",
+			})
+		}
+	}
+
+	// Validate object store settings
+	if app.ObjectStorage != nil {
+		if addr := app.ObjectStorage.Listen; addr != "" {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   "app.object_storage.listen",
+					Message: fmt.Sprintf("invalid listen address '%s', expected host:port", addr),
+				})
+			}
+		}
+		if strings.ContainsAny(app.ObjectStorage.Host, "/: ") {
+			errs = append(errs, ValidationError{
+				Field:   "app.object_storage.host",
+				Message: fmt.Sprintf("invalid host '%s'", app.ObjectStorage.Host),
+			})
+		}
+	}
+
+	if app.Egress != nil {
+		errs = append(errs, validateEgress(app)...)
+	}
+
+	if uploads := app.Uploads; uploads != nil {
+		if uploads.MaxBodySize < 0 || uploads.MaxFileSize < 0 {
+			errs = append(errs, ValidationError{
+				Field:   "app.uploads",
+				Message: "max_body_size and max_file_size cannot be negative",
+			})
+		} else if uploads.MaxBodySize > 0 && uploads.MaxFileSize > uploads.MaxBodySize {
+			errs = append(errs, ValidationError{
+				Field:   "app.uploads.max_file_size",
+				Message: fmt.Sprintf("max_file_size (%d) can't be larger than max_body_size (%d)", uploads.MaxFileSize, uploads.MaxBodySize),
+			})
+		}
+	}
+
+	if fsCfg := app.Filesystem; fsCfg != nil {
+		if fsCfg.MaxBytes < 0 || fsCfg.MaxFiles < 0 {
+			errs = append(errs, ValidationError{
+				Field:   "app.filesystem",
+				Message: "max_bytes and max_files cannot be negative",
+			})
+		}
+		if fsCfg.ResetInterval != "" {
+			if d, err := time.ParseDuration(fsCfg.ResetInterval); err != nil || d < time.Second {
+				errs = append(errs, ValidationError{
+					Field:   "app.filesystem.reset_interval",
+					Message: fmt.Sprintf("invalid interval '%s' (a duration of at least 1s)", fsCfg.ResetInterval),
+				})
+			}
+		}
+		if fsCfg.APIPath != "" && !strings.HasPrefix(fsCfg.APIPath, "/") {
+			errs = append(errs, ValidationError{
+				Field:   "app.filesystem.api_path",
+				Message: "api_path must start with /",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateEgress validates the HTTP sink's egress policy and routing table
+func validateEgress(app *AppConfig) ValidationErrors {
+	var errs ValidationErrors
+	egress := app.Egress
+
+	if egress.MaxResponseSize < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "app.egress.max_response_size",
+			Message: "max_response_size cannot be negative",
+		})
+	}
+
+	if egress.Timeout != "" {
+		if d, err := time.ParseDuration(egress.Timeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "app.egress.timeout",
+				Message: fmt.Sprintf("invalid duration '%s'", egress.Timeout),
+			})
+		}
+	}
+
+	for i, host := range egress.AllowedHosts {
+		if host == "" || strings.ContainsAny(host, "/: ") {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("app.egress.allowed_hosts[%d]", i),
+				Message: fmt.Sprintf("invalid host '%s'", host),
+			})
+		}
+	}
+
+	for host, service := range egress.Routes {
+		field := fmt.Sprintf("app.egress.routes.%s", host)
+		switch service {
+		case "app", "object_storage":
+		case "metadata":
+			if app.CloudMetadata == nil {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: "routing to metadata requires app.cloud_metadata",
+				})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("unknown service '%s' (must be app, metadata or object_storage)", service),
+			})
+		}
+	}
+
 	return errs
 }
 
@@ -188,17 +438,47 @@ func validateEndpoints(endpoints []EndpointConfig) ValidationErrors {
 			})
 		}
 
-		// Check for duplicate path+method combinations
-		key := fmt.Sprintf("%s:%s", strings.ToUpper(endpoint.Method), endpoint.Path)
+		// Validate virtual host
+		if !validVirtualHost(endpoint.Host) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.host", prefix),
+				Message: fmt.Sprintf("host must be a bare hostname, or *.domain for its subdomains, without scheme, port or path, got '%s'", endpoint.Host),
+			})
+		}
+
+		// Check for duplicate host+path+method combinations, ignoring path parameter types
+		route := endpoint.Path
+		if pattern, _, err := endpoint.Route(); err == nil {
+			route = pattern
+		}
+		key := fmt.Sprintf("%s:%s%s", strings.ToUpper(endpoint.Method), endpoint.Host, route)
 		if prevIndex, exists := pathMap[key]; exists {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("%s.path", prefix),
-				Message: fmt.Sprintf("duplicate endpoint '%s %s' (previously defined at index %d)", endpoint.Method, endpoint.Path, prevIndex),
+				Message: fmt.Sprintf("duplicate endpoint '%s %s%s' (previously defined at index %d)", endpoint.Method, endpoint.Host, endpoint.Path, prevIndex),
 			})
 		} else {
 			pathMap[key] = i
 		}
 
+		// Validate WebSocket settings
+		errs = append(errs, validateWebSocket(endpoint, prefix)...)
+
+		// Validate Content-Security-Policy settings
+		errs = append(errs, validateCSP(endpoint.CSP, prefix)...)
+
+		// Validate static headers and status
+		errs = append(errs, validateHeaders(endpoint, prefix)...)
+
+		// Validate latency and error injection
+		errs = append(errs, validateFaults(endpoint, prefix)...)
+
+		// Validate the response template
+		errs = append(errs, validateResponseTemplate(endpoint, prefix)...)
+
+		// Validate path parameters and the path_param placements that read them
+		errs = append(errs, validateRoute(endpoint, prefix)...)
+
 		// Validate vulnerabilities
 		errs = append(errs, validateVulnerabilities(endpoint.Vulnerabilities, prefix)...)
 	}
@@ -274,17 +554,47 @@ func validateEndpointsWithWarnings(endpoints []EndpointConfig) (ValidationErrors
 			})
 		}
 
-		// Check for duplicate path+method combinations
-		key := fmt.Sprintf("%s:%s", strings.ToUpper(endpoint.Method), endpoint.Path)
+		// Validate virtual host
+		if !validVirtualHost(endpoint.Host) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.host", prefix),
+				Message: fmt.Sprintf("host must be a bare hostname, or *.domain for its subdomains, without scheme, port or path, got '%s'", endpoint.Host),
+			})
+		}
+
+		// Check for duplicate host+path+method combinations, ignoring path parameter types
+		route := endpoint.Path
+		if pattern, _, err := endpoint.Route(); err == nil {
+			route = pattern
+		}
+		key := fmt.Sprintf("%s:%s%s", strings.ToUpper(endpoint.Method), endpoint.Host, route)
 		if prevIndex, exists := pathMap[key]; exists {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("%s.path", prefix),
-				Message: fmt.Sprintf("duplicate endpoint '%s %s' (previously defined at index %d)", endpoint.Method, endpoint.Path, prevIndex),
+				Message: fmt.Sprintf("duplicate endpoint '%s %s%s' (previously defined at index %d)", endpoint.Method, endpoint.Host, endpoint.Path, prevIndex),
 			})
 		} else {
 			pathMap[key] = i
 		}
 
+		// Validate WebSocket settings
+		errs = append(errs, validateWebSocket(endpoint, prefix)...)
+
+		// Validate Content-Security-Policy settings
+		errs = append(errs, validateCSP(endpoint.CSP, prefix)...)
+
+		// Validate static headers and status
+		errs = append(errs, validateHeaders(endpoint, prefix)...)
+
+		// Validate latency and error injection
+		errs = append(errs, validateFaults(endpoint, prefix)...)
+
+		// Validate the response template
+		errs = append(errs, validateResponseTemplate(endpoint, prefix)...)
+
+		// Validate path parameters and the path_param placements that read them
+		errs = append(errs, validateRoute(endpoint, prefix)...)
+
 		// Validate vulnerabilities with warnings
 		vulnErrs, vulnWarns := validateVulnerabilitiesWithWarnings(endpoint.Vulnerabilities, prefix, endpoint.Path)
 		errs = append(errs, vulnErrs...)
@@ -294,6 +604,102 @@ func validateEndpointsWithWarnings(endpoints []EndpointConfig) (ValidationErrors
 	return errs, warns
 }
 
+// validateWebSocket validates WebSocket endpoint settings and ws_message placements
+func validateWebSocket(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if endpoint.WebSocket && endpoint.Method != "" && strings.ToUpper(endpoint.Method) != "GET" {
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.method", prefix),
+			Message: fmt.Sprintf("websocket endpoints must use GET, got '%s'", endpoint.Method),
+		})
+	}
+
+	if !endpoint.WebSocket {
+		for i, vuln := range endpoint.Vulnerabilities {
+			if vuln.Placement == "ws_message" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.vulnerabilities[%d].placement", prefix, i),
+					Message: "placement 'ws_message' requires 'websocket: true' on the endpoint",
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateHeaders validates an endpoint's static response headers and status
+func validateHeaders(endpoint EndpointConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if endpoint.Status != 0 {
+		if endpoint.Status < 100 || endpoint.Status > 599 {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.status", prefix),
+				Message: fmt.Sprintf("invalid status %d, must be between 100 and 599", endpoint.Status),
+			})
+		} else if endpoint.WebSocket {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.status", prefix),
+				Message: "status can't be set on websocket endpoints",
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(endpoint.Headers) {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.headers", prefix),
+				Message: fmt.Sprintf("invalid header name '%s'", name),
+			})
+			continue
+		}
+		if strings.ContainsRune(endpoint.Headers[name], '\r') {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.headers.%s", prefix, name),
+				Message: "header values can't contain carriage returns",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateCSP validates an endpoint's Content-Security-Policy settings
+func validateCSP(csp *CSPConfig, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if csp == nil {
+		return errs
+	}
+
+	switch csp.Preset {
+	case "unsafe_inline", "wildcard", "jsonp", "strict":
+	case "":
+		if csp.Policy == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.csp", prefix),
+				Message: "csp requires either a preset or a policy",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.csp.preset", prefix),
+			Message: fmt.Sprintf("invalid preset '%s', must be one of: unsafe_inline, wildcard, jsonp, strict", csp.Preset),
+		})
+	}
+
+	if csp.JSONPPath != "" && !strings.HasPrefix(csp.JSONPPath, "/") {
+		errs = append(errs, ValidationError{
+			Field:   fmt.Sprintf("%s.csp.jsonp_path", prefix),
+			Message: fmt.Sprintf("jsonp_path must start with '/', got '%s'", csp.JSONPPath),
+		})
+	}
+
+	return errs
+}
+
 // validateVulnerabilities validates vulnerability configurations
 func validateVulnerabilities(vulns []VulnerabilityConfig, endpointPrefix string) ValidationErrors {
 	var errs ValidationErrors
@@ -302,13 +708,22 @@ func validateVulnerabilities(vulns []VulnerabilityConfig, endpointPrefix string)
 	paramMap := make(map[string]int)
 
 	validPlacements := map[string]bool{
-		"query_param":    true,
-		"path_param":     true,
-		"form_field":     true,
-		"json_field":     true,
-		"header":         true,
-		"cookie":         true,
-		"multipart-form": true,
+		"query_param":      true,
+		"path_param":       true,
+		"form_field":       true,
+		"json_field":       true,
+		"header":           true,
+		"cookie":           true,
+		"multipart-form":   true, // Older name of multipart_field
+		"multipart_field":  true,
+		"file":             true,
+		"raw_body":         true,
+		"graphql_variable": true,
+		"graphql_query":    true,
+		"ws_message":       true,
+		"soap_param":       true,
+		"grpc_field":       true,
+		AnyPlacement:       true,
 	}
 
 	for i, vuln := range vulns {
@@ -333,27 +748,12 @@ func validateVulnerabilities(vulns []VulnerabilityConfig, endpointPrefix string)
 		} else if !validPlacements[vuln.Placement] {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("%s.placement", prefix),
-				Message: fmt.Sprintf("invalid placement '%s', must be one of: query_param, path_param, form_field, json_field, header, cookie, multipart-form", vuln.Placement),
+				Message: fmt.Sprintf("invalid placement '%s', must be one of: query_param, path_param, form_field, json_field, header, cookie, multipart_field, file, raw_body, graphql_variable, graphql_query, ws_message, soap_param, grpc_field, any", vuln.Placement),
 			})
 		}
 
 		// Validate param
-		if vuln.Param == "" {
-			errs = append(errs, ValidationError{
-				Field:   fmt.Sprintf("%s.param", prefix),
-				Message: "param is required",
-			})
-		} else {
-			// Check for duplicate params
-			if prevIndex, exists := paramMap[vuln.Param]; exists {
-				errs = append(errs, ValidationError{
-					Field:   fmt.Sprintf("%s.param", prefix),
-					Message: fmt.Sprintf("duplicate param '%s' (previously used at vulnerability index %d)", vuln.Param, prevIndex),
-				})
-			} else {
-				paramMap[vuln.Param] = i
-			}
-		}
+		errs = append(errs, validateParams(vuln, prefix, i, paramMap)...)
 	}
 
 	return errs
@@ -368,13 +768,22 @@ func validateVulnerabilitiesWithWarnings(vulns []VulnerabilityConfig, endpointPr
 	paramMap := make(map[string]int)
 
 	validPlacements := map[string]bool{
-		"query_param":    true,
-		"path_param":     true,
-		"form_field":     true,
-		"json_field":     true,
-		"header":         true,
-		"cookie":         true,
-		"multipart-form": true,
+		"query_param":      true,
+		"path_param":       true,
+		"form_field":       true,
+		"json_field":       true,
+		"header":           true,
+		"cookie":           true,
+		"multipart-form":   true, // Older name of multipart_field
+		"multipart_field":  true,
+		"file":             true,
+		"raw_body":         true,
+		"graphql_variable": true,
+		"graphql_query":    true,
+		"ws_message":       true,
+		"soap_param":       true,
+		"grpc_field":       true,
+		AnyPlacement:       true,
 	}
 
 	for i, vuln := range vulns {
@@ -397,31 +806,30 @@ func validateVulnerabilitiesWithWarnings(vulns []VulnerabilityConfig, endpointPr
 		} else if !validPlacements[vuln.Placement] {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("%s.placement", prefix),
-				Message: fmt.Sprintf("invalid placement '%s', must be one of: query_param, path_param, form_field, json_field, header, cookie, multipart-form", vuln.Placement),
+				Message: fmt.Sprintf("invalid placement '%s', must be one of: query_param, path_param, form_field, json_field, header, cookie, multipart_field, file, raw_body, graphql_variable, graphql_query, ws_message, soap_param, grpc_field, any", vuln.Placement),
 			})
 		}
 
 		// Validate param
-		if vuln.Param == "" {
-			errs = append(errs, ValidationError{
-				Field:   fmt.Sprintf("%s.param", prefix),
-				Message: "param is required",
-			})
-		} else {
-			// Check for duplicate params
-			if prevIndex, exists := paramMap[vuln.Param]; exists {
-				errs = append(errs, ValidationError{
-					Field:   fmt.Sprintf("%s.param", prefix),
-					Message: fmt.Sprintf("duplicate param '%s' (previously used at vulnerability index %d)", vuln.Param, prevIndex),
-				})
-			} else {
-				paramMap[vuln.Param] = i
-			}
+		errs = append(errs, validateParams(vuln, prefix, i, paramMap)...)
+
+		if sandbox, ok := vuln.Config["sandbox"]; ok {
+			errs = append(errs, validateSandbox(sandbox, prefix+".config.sandbox")...)
 		}
 
+		errs = append(errs, validateDifficulty(vuln, prefix)...)
+		errs = append(errs, validateConditions(vuln.EnabledIf, prefix+".enabled_if")...)
+
+		sinkErrs, sinkWarns := validateSinkSelection(vuln, prefix, endpointPath)
+		errs = append(errs, sinkErrs...)
+		warns = append(warns, sinkWarns...)
+
 		// Validate module-specific config values (generates warnings, not errors)
 		if vuln.Type != "" && vuln.Config != nil {
 			for configKey, configValue := range vuln.Config {
+				if configKey == "sandbox" {
+					continue
+				}
 				// Convert value to string for validation
 				valueStr := fmt.Sprintf("%v", configValue)
 				isValid, validOptions, defaultVal := modules.ValidateConfigValue(vuln.Type, configKey, valueStr)
@@ -439,10 +847,170 @@ func validateVulnerabilitiesWithWarnings(vulns []VulnerabilityConfig, endpointPr
 	return errs, warns
 }
 
-// validateData validates the data section (database tables)
+// optionlessSinks are built-in sinks that take no sink_options. Names not listed
+// here or handled below may be plugin sinks, which are checked when the lab is built.
+var optionlessSinks = map[string]bool{
+	"sqlite":      true,
+	"http":        true,
+	"redis":       true,
+	"documents":   true,
+	"ldap":        true,
+	"mail":        true,
+	"oob":         true,
+	"template":    true,
+	"metadata":    true,
+	"objectstore": true,
+}
+
+// validateSinkSelection validates a vulnerability's sink and sink_options
+func validateSinkSelection(vuln VulnerabilityConfig, prefix string, endpointPath string) (ValidationErrors, ValidationWarnings) {
+	var errs ValidationErrors
+	var warns ValidationWarnings
+
+	if vuln.Sink == "" {
+		if len(vuln.SinkOptions) > 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".sink_options",
+				Message: "sink_options requires a sink",
+			})
+		}
+		return errs, warns
+	}
+
+	// A module that needs a specific sink keeps using it whatever is selected
+	if module, err := modules.Get(vuln.Type); err == nil {
+		if required := module.Info().RequiresSink; required != "" && required != vuln.Sink {
+			warns = append(warns, ValidationWarning{
+				Field:   prefix + ".sink",
+				Message: fmt.Sprintf("%s at %s reads from the %s sink, selecting '%s' only makes it available", vuln.Type, endpointPath, required, vuln.Sink),
+			})
+		}
+	}
+
+	optionsPrefix := prefix + ".sink_options"
+	switch {
+	case vuln.Sink == "filesystem":
+		for key, value := range vuln.SinkOptions {
+			if key != "root" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.%s", optionsPrefix, key),
+					Message: "unknown filesystem option (valid: root)",
+				})
+				continue
+			}
+			root, ok := value.(string)
+			if !ok || root == "" || filepath.IsAbs(root) || strings.HasPrefix(filepath.Clean(root), "..") {
+				errs = append(errs, ValidationError{
+					Field:   optionsPrefix + ".root",
+					Message: "root must be a relative directory inside the lab filesystem",
+				})
+			}
+		}
+	case vuln.Sink == "command":
+		// Command sink options are sandbox settings
+		if len(vuln.SinkOptions) > 0 {
+			errs = append(errs, validateSandbox(vuln.SinkOptions, optionsPrefix)...)
+		}
+	case optionlessSinks[vuln.Sink]:
+		if len(vuln.SinkOptions) > 0 {
+			errs = append(errs, ValidationError{
+				Field:   optionsPrefix,
+				Message: fmt.Sprintf("the %s sink takes no options", vuln.Sink),
+			})
+		}
+	}
+
+	return errs, warns
+}
+
+// validateSandbox validates a vulnerability's command sandbox settings
+func validateSandbox(sandbox interface{}, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	settings, ok := sandbox.(map[string]interface{})
+	if !ok {
+		return ValidationErrors{{Field: prefix, Message: "sandbox must be a map"}}
+	}
+
+	for key, value := range settings {
+		field := fmt.Sprintf("%s.%s", prefix, key)
+		switch key {
+		case "allowed_binaries":
+			if _, ok := value.([]interface{}); !ok {
+				errs = append(errs, ValidationError{Field: field, Message: "must be a list of binary names"})
+			}
+		case "timeout":
+			switch v := value.(type) {
+			case int:
+				if v <= 0 {
+					errs = append(errs, ValidationError{Field: field, Message: "must be positive"})
+				}
+			case float64:
+				if v <= 0 {
+					errs = append(errs, ValidationError{Field: field, Message: "must be positive"})
+				}
+			case string:
+				if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+					errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf("invalid duration '%s'", v)})
+				}
+			default:
+				errs = append(errs, ValidationError{Field: field, Message: "must be a duration or number of seconds"})
+			}
+		case "workdir":
+			if _, ok := value.(string); !ok {
+				errs = append(errs, ValidationError{Field: field, Message: "must be a path"})
+			}
+		case "scrub_env", "emulate_only":
+			if _, ok := value.(bool); !ok {
+				errs = append(errs, ValidationError{Field: field, Message: "must be true or false"})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "unknown sandbox setting (valid: allowed_binaries, timeout, workdir, scrub_env, emulate_only)",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateData validates the data section (database tables, Redis keys, the LDAP directory and buckets)
 func validateData(data *DataConfig) ValidationErrors {
 	var errs ValidationErrors
 
+	if data.LDAP != nil {
+		errs = append(errs, validateLDAPData(data.LDAP)...)
+	}
+
+	errs = append(errs, validateBuckets(data.Buckets)...)
+
+	if p := data.Persistence; p != nil {
+		switch p.Reseed {
+		case "", "on_change", "always", "never":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   "data.persistence.reseed",
+				Message: fmt.Sprintf("invalid reseed mode '%s' (must be on_change, always or never)", p.Reseed),
+			})
+		}
+		if p.Path != "" && strings.HasSuffix(p.Path, "/") {
+			errs = append(errs, ValidationError{
+				Field:   "data.persistence.path",
+				Message: "path must name a file, not a directory",
+			})
+		}
+	}
+
+	for key, value := range data.Redis {
+		if value == nil {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("data.redis.%s", key),
+				Message: "value is required",
+			})
+		}
+	}
+
 	if len(data.Tables) == 0 {
 		// Having a data section with no tables is unusual but not an error
 		return errs
@@ -477,6 +1045,131 @@ func validateData(data *DataConfig) ValidationErrors {
 				})
 			}
 		}
+
+		if table.Generate != nil {
+			errs = append(errs, validateGenerate(table.Generate, table.Columns, prefix+".generate")...)
+		}
+	}
+
+	return errs
+}
+
+// maxGeneratedRows keeps generated tables small enough to seed quickly
+const maxGeneratedRows = 100000
+
+// validateGenerate validates a table's row generators
+func validateGenerate(gen *GenerateConfig, columns []string, prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if gen.Rows <= 0 || gen.Rows > maxGeneratedRows {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".rows",
+			Message: fmt.Sprintf("rows must be between 1 and %d", maxGeneratedRows),
+		})
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	for column, spec := range gen.Columns {
+		field := fmt.Sprintf("%s.columns.%s", prefix, column)
+		if !known[column] {
+			errs = append(errs, ValidationError{Field: field, Message: "column is not in the table's columns"})
+		}
+		if _, err := faker.Parse(spec); err != nil {
+			errs = append(errs, ValidationError{Field: field, Message: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+// validateLDAPData validates directory users and groups
+func validateLDAPData(ldap *LDAPDataConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	uids := make(map[string]bool)
+	for i, user := range ldap.Users {
+		uid, _ := user["uid"].(string)
+		if uid == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("data.ldap.users[%d].uid", i),
+				Message: "uid is required",
+			})
+			continue
+		}
+		if uids[uid] {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("data.ldap.users[%d].uid", i),
+				Message: fmt.Sprintf("duplicate uid '%s'", uid),
+			})
+		}
+		uids[uid] = true
+	}
+
+	for i, group := range ldap.Groups {
+		if group.CN == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("data.ldap.groups[%d].cn", i),
+				Message: "cn is required",
+			})
+		}
+		for _, member := range group.Members {
+			if !uids[member] {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("data.ldap.groups[%d].members", i),
+					Message: fmt.Sprintf("unknown member uid '%s'", member),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// bucketNamePattern matches valid S3 bucket names
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// validateBuckets validates object store buckets and their objects
+func validateBuckets(buckets []BucketConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	names := make(map[string]bool)
+	for i, bucket := range buckets {
+		prefix := fmt.Sprintf("data.buckets[%d]", i)
+
+		if !bucketNamePattern.MatchString(bucket.Name) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".name",
+				Message: fmt.Sprintf("invalid bucket name '%s' (3-63 lowercase letters, digits, dots or hyphens)", bucket.Name),
+			})
+		} else if names[bucket.Name] {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".name",
+				Message: fmt.Sprintf("duplicate bucket '%s'", bucket.Name),
+			})
+		}
+		names[bucket.Name] = true
+
+		switch bucket.ACL {
+		case "", "private", "public-read", "public-read-write":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".acl",
+				Message: fmt.Sprintf("invalid ACL '%s', must be one of: private, public-read, public-read-write", bucket.ACL),
+			})
+		}
+
+		for j, object := range bucket.Objects {
+			if object.Key == "" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.objects[%d].key", prefix, j),
+					Message: "key is required",
+				})
+			}
+		}
 	}
 
 	return errs
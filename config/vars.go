@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// varPattern matches ${NAME} and ${NAME:-default}. A leading $$ escapes the
+// reference so it is kept literally, minus one $.
+var varPattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// varName matches a valid variable name
+var varName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseVars parses key=value assignments, as given to --set, into a variable map
+func ParseVars(assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || !varName.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable '%s', expected NAME=value", assignment)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// lookupVar resolves a variable from the given values, then the environment
+func lookupVar(vars map[string]string, name string) (string, bool) {
+	if value, ok := vars[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// substitute replaces variable references in one string
+func substitute(s string, vars map[string]string) (string, error) {
+	var undefined string
+
+	result := varPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		match := varPattern.FindStringSubmatch(ref)
+		escaped, name, fallback := match[1] != "", match[2], match[3]
+
+		if escaped {
+			return ref[1:]
+		}
+
+		// ${NAME:-default} falls back when the variable is unset or empty
+		value, ok := lookupVar(vars, name)
+		hasDefault := strings.Contains(ref, ":-")
+		if ok && (value != "" || !hasDefault) {
+			return value
+		}
+		if hasDefault {
+			return fallback
+		}
+		if undefined == "" {
+			undefined = name
+		}
+		return ref
+	})
+
+	if undefined != "" {
+		return "", fmt.Errorf("undefined variable '%s' (set it in the environment, with --set or give a default with ${%s:-value})", undefined, undefined)
+	}
+	return result, nil
+}
+
+// substituteNode replaces variable references in every scalar of a parsed YAML
+// document. Comments are left alone, so payload examples in them are never expanded.
+func substituteNode(node *yaml.Node, vars map[string]string) error {
+	if node.Kind == yaml.ScalarNode {
+		value, err := substitute(node.Value, vars)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if value != node.Value {
+			node.Value = value
+			// Let plain scalars be retyped, so ${PORT:-8080} can fill an int field
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := substituteNode(child, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// Walkthrough returns a solution guide to an app as Markdown, a section per
+// endpoint: each vulnerability with what its module does and the config that
+// shapes it, then its canonical exploit, as test sends it, with the payload,
+// the requests and what the response shows when it worked. Vulnerabilities
+// without a canonical exploit list their module's example payloads instead.
+func Walkthrough(cfg *Config) []byte {
+	scheme, host := serverAddress(cfg)
+	hostPort := net.JoinHostPort(host, strconv.Itoa(cfg.App.Port))
+
+	// The exploits of an endpoint, in the order of its vulnerabilities
+	exploits := make(map[string][]Exploit)
+	for _, x := range Exploits(cfg) {
+		exploits[x.Group] = append(exploits[x.Group], x)
+	}
+
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# %s walkthrough\n\n", cfg.App.Name)
+	if cfg.App.Description != "" {
+		fmt.Fprintf(&doc, "%s\n\n", cfg.App.Description)
+	}
+	fmt.Fprintf(&doc, "The lab runs at `%s://%s`.", scheme, hostPort)
+	if cfg.Auth != nil {
+		fmt.Fprintf(&doc, " Protected endpoints need %s authentication, shown in their requests.", cfg.Auth.Type)
+	}
+	doc.WriteString("\n")
+
+	number := 0
+	for _, endpoint := range cfg.Endpoints {
+		source := endpoint
+		if endpoint.Type == "alias" {
+			if target, ok := endpoint.AliasTarget(cfg.Endpoints); ok {
+				source = target
+			}
+		}
+		if len(source.Vulnerabilities) == 0 {
+			continue
+		}
+		group := strings.ToUpper(endpoint.Method) + " " + endpoint.Host + endpoint.Path
+		fmt.Fprintf(&doc, "\n## %s\n\n", group)
+		var notes []string
+		if endpoint.Type == "alias" {
+			notes = append(notes, fmt.Sprintf("Serves `%s %s%s`.", strings.ToUpper(source.Method), source.Host, source.Path))
+		}
+		if cfg.Auth.Protects(endpoint) {
+			notes = append(notes, "Behind the login wall.")
+		}
+		if endpoint.Type == "grpc" {
+			notes = append(notes, "A gRPC method, called on the gRPC listener.")
+		}
+		if source.WebSocket {
+			notes = append(notes, "A WebSocket; the payloads go in its messages.")
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&doc, "%s\n\n", strings.Join(notes, " "))
+		}
+
+		for i, vuln := range source.Vulnerabilities {
+			number++
+			if i > 0 {
+				doc.WriteString("\n")
+			}
+			fmt.Fprintf(&doc, "### %d. %s\n\n", number, vulnName(vuln))
+			if module, err := modules.Get(vuln.Type); err == nil {
+				fmt.Fprintf(&doc, "%s.\n\n", strings.TrimSuffix(module.Info().Description, "."))
+			}
+			if vuln.Difficulty != "" {
+				fmt.Fprintf(&doc, "- Difficulty: %s\n", vuln.Difficulty)
+			}
+			for _, key := range slices.Sorted(maps.Keys(vuln.Config)) {
+				fmt.Fprintf(&doc, "- `%s`: %s\n", key, markdownCode(fmt.Sprint(vuln.Config[key])))
+			}
+			if vuln.Difficulty != "" || len(vuln.Config) > 0 {
+				doc.WriteString("\n")
+			}
+
+			// Take the exploit of this vulnerability off its endpoint's list
+			queue := exploits[group]
+			j := slices.IndexFunc(queue, func(x Exploit) bool { return x.Name == vulnName(vuln) })
+			if j < 0 {
+				walkthroughPayloads(&doc, vuln)
+				continue
+			}
+			x := queue[j]
+			exploits[group] = slices.Delete(queue, j, j+1)
+			walkthroughExploit(&doc, x, hostPort)
+		}
+	}
+	return []byte(doc.String())
+}
+
+// walkthroughExploit writes an exploit's payloads, requests and the signs in
+// the response that it worked
+func walkthroughExploit(doc *strings.Builder, x Exploit, hostPort string) {
+	var payloads []string
+	for _, payload := range x.Payloads {
+		if payload != "" {
+			payloads = append(payloads, markdownCode(payload))
+		}
+	}
+	switch len(payloads) {
+	case 0:
+	case 1:
+		fmt.Fprintf(doc, "**Payload:** %s\n\n", payloads[0])
+	default:
+		fmt.Fprintf(doc, "**Payloads:** %s\n\n", strings.Join(payloads, ", then "))
+	}
+
+	if x.Login != nil {
+		login := "Log in first; the session cookie it sets goes with the requests:"
+		if x.BearerLogin {
+			login = "Log in first, then send the `token` it returns as `Authorization: Bearer <token>`:"
+		}
+		fmt.Fprintf(doc, "%s\n\n```http\n%s\n```\n\n", login, walkthroughRaw(*x.Login, hostPort))
+	}
+	for _, req := range x.Requests {
+		fmt.Fprintf(doc, "```http\n%s\n```\n\n", walkthroughRaw(req, hostPort))
+	}
+
+	doc.WriteString("**Expected response:**\n\n")
+	join := "any of"
+	if x.MatchAll {
+		join = "all of"
+	}
+	if len(x.Matchers) > 1 {
+		fmt.Fprintf(doc, "- It shows %s:\n", join)
+	}
+	for _, m := range x.Matchers {
+		indent := ""
+		if len(x.Matchers) > 1 {
+			indent = "  "
+		}
+		fmt.Fprintf(doc, "%s- %s\n", indent, describeMatcher(m))
+	}
+	switch x.Compare {
+	case CompareDiffer:
+		doc.WriteString("- The two responses differ in status or body\n")
+	case CompareRecords:
+		doc.WriteString("- Both requests find a record (200 OK), and the bodies differ\n")
+	}
+	if x.Delay > 0 {
+		fmt.Fprintf(doc, "- The response takes at least %s\n", x.Delay)
+	}
+}
+
+// walkthroughPayloads writes the example payloads of a vulnerability's module,
+// for vulnerabilities without a canonical exploit
+func walkthroughPayloads(doc *strings.Builder, vuln VulnerabilityConfig) {
+	module, err := modules.Get(vuln.Type)
+	if err != nil || len(module.Info().Payloads) == 0 {
+		doc.WriteString("No canonical exploit or example payloads; explore the endpoint by hand.\n")
+		return
+	}
+	doc.WriteString("No canonical exploit; the module's example payloads:\n\n")
+	for _, payload := range module.Info().Payloads {
+		fmt.Fprintf(doc, "- %s\n", markdownCode(payload))
+	}
+}
+
+// walkthroughRaw returns a request as sent over HTTP/1.1, with line feeds
+func walkthroughRaw(req ExampleRequest, hostPort string) string {
+	raw := strings.ReplaceAll(string(rawHTTPRequest(req, req.Header, hostPort)), "\r\n", "\n")
+	return strings.TrimRight(raw, "\n")
+}
+
+// describeMatcher puts a response check in words
+func describeMatcher(m ResponseMatcher) string {
+	part := "body"
+	if m.Part == "header" {
+		part = "headers"
+	}
+	var values []string
+	for _, word := range m.Words {
+		values = append(values, markdownCode(word))
+	}
+	for _, pattern := range m.Regex {
+		values = append(values, "a match of "+markdownCode(pattern))
+	}
+	verb := "contain"
+	if m.Negative {
+		verb = "don't contain"
+		if part == "body" {
+			verb = "doesn't contain"
+		}
+	} else if part == "body" {
+		verb = "contains"
+	}
+	return fmt.Sprintf("The %s %s %s", part, verb, strings.Join(values, " or "))
+}
+
+// markdownCode returns s as inline code, fenced so backticks in it are kept
+func markdownCode(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
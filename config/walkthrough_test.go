@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestWalkthrough tests each endpoint gets a section solving its
+// vulnerabilities: the exploit's request and what it shows when it worked, or
+// the module's payloads without one
+func TestWalkthrough(t *testing.T) {
+	cfg, err := Load(writeYAML(t, t.TempDir(), "lab.yaml", `
+app:
+  name: "Walkthrough Lab"
+  port: 9000
+
+endpoints:
+  - path: /search
+    method: GET
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+        difficulty: easy
+  - path: /find
+    method: GET
+    type: alias
+    target: GET /search
+  - path: /verify
+    method: POST
+    vulnerabilities:
+      - type: two_factor_bypass
+        placement: form_field
+        param: code
+`))
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	doc := string(Walkthrough(cfg))
+	for _, want := range []string{
+		"# Walkthrough Lab walkthrough\n",
+		"The lab runs at `http://localhost:9000`.",
+		"## GET /search\n\n### 1. xss_reflected in query_param q\n",
+		"- Difficulty: easy\n",
+		"**Payload:** `",
+		"```http\nGET /search?q=",
+		"**Expected response:**\n\n- The body contains `",
+		"## GET /find\n\nServes `GET /search`.\n\n### 2. xss_reflected in query_param q\n",
+		"### 3. two_factor_bypass in form_field code\n",
+		"No canonical exploit; the module's example payloads:\n\n- `0000`\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Expected %q in the walkthrough, got:\n%s", want, doc)
+		}
+	}
+
+	if got := markdownCode("a`b"); got != "`` a`b ``" {
+		t.Errorf("Expected the backtick fenced, got %s", got)
+	}
+}
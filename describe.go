@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// describeCommand prints the config reference of the named modules, or of
+// every module when none are named
+func describeCommand(args []string) {
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
+		fmt.Println("Usage: flawfactory modules describe [module...]")
+		return
+	}
+
+	var infos []modules.ModuleInfo
+	if len(args) == 0 {
+		infos = modules.List()
+		slices.SortFunc(infos, func(a, b modules.ModuleInfo) int { return strings.Compare(a.Name, b.Name) })
+	}
+	for _, name := range args {
+		module, err := modules.Get(name)
+		if err != nil {
+			var names []string
+			for _, info := range modules.List() {
+				names = append(names, info.Name)
+			}
+			slices.Sort(names)
+			fmt.Printf("\n  %s✗ Error:%s unknown module '%s' (available: %s)\n\n", colorRed, colorReset, name, strings.Join(names, ", "))
+			os.Exit(1)
+		}
+		infos = append(infos, module.Info())
+	}
+
+	fmt.Println()
+	for _, info := range infos {
+		describeModule(os.Stdout, info)
+	}
+}
+
+// describeModule writes a module's config keys, valid values and defaults,
+// difficulty levels, example endpoint and example payloads
+func describeModule(w io.Writer, info modules.ModuleInfo) {
+	fmt.Fprintf(w, "  %s%s%s\n", colorGreen+colorBold, info.Name, colorReset)
+	fmt.Fprintf(w, "     %sDescription:%s %s\n", colorDim, colorReset, info.Description)
+	fmt.Fprintf(w, "     %sPlacements:%s  %s%s%s\n", colorDim, colorReset, colorCyan, strings.Join(info.SupportedPlacements, ", "), colorReset)
+	if info.RequiresSink != "" {
+		fmt.Fprintf(w, "     %sRequires:%s    %s%s sink%s\n", colorDim, colorReset, colorYellow, info.RequiresSink, colorReset)
+	}
+	fmt.Fprintln(w)
+
+	if keys := modules.ConfigKeys(info); len(keys) > 0 {
+		fmt.Fprintln(w, colorYellow+"  ◆ CONFIG"+colorReset)
+		for _, key := range keys {
+			option := info.Options[key]
+			fmt.Fprintf(w, "    %s%s%s\n", colorCyan, key, colorReset)
+			fmt.Fprintf(w, "       %s\n", option.Description)
+			if values := info.ValidVariants[key]; len(values) > 0 {
+				fmt.Fprintf(w, "       %sValues:%s  %s\n", colorDim, colorReset, strings.Join(values, ", "))
+			}
+			if option.Default != "" {
+				fmt.Fprintf(w, "       %sDefault:%s %s\n", colorDim, colorReset, option.Default)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(info.Difficulty) > 0 {
+		fmt.Fprintln(w, colorYellow+"  ◆ DIFFICULTY"+colorReset)
+		for _, level := range modules.DifficultyLevels {
+			values, ok := info.Difficulty[level]
+			if !ok {
+				continue
+			}
+			var settings []string
+			for key, value := range values {
+				settings = append(settings, fmt.Sprintf("%s: %v", key, value))
+			}
+			slices.Sort(settings)
+			fmt.Fprintf(w, "    %-8s%s%s%s\n", level, colorDim, strings.Join(settings, ", "), colorReset)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if info.Example != "" {
+		fmt.Fprintln(w, colorYellow+"  ◆ EXAMPLE"+colorReset+colorDim+" (under endpoints:)"+colorReset)
+		for _, line := range strings.Split(strings.TrimSuffix(info.Example, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(info.Payloads) > 0 {
+		fmt.Fprintln(w, colorYellow+"  ◆ PAYLOADS"+colorReset)
+		for _, payload := range info.Payloads {
+			fmt.Fprintf(w, "    %s•%s %s\n", colorGreen, colorReset, payload)
+		}
+		fmt.Fprintln(w)
+	}
+}
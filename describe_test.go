@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// TestDescribeModule tests a module's keys, valid values, defaults, example
+// and payloads are printed
+func TestDescribeModule(t *testing.T) {
+	module, err := modules.Get("ssrf")
+	if err != nil {
+		t.Fatalf("Failed to get ssrf: %v", err)
+	}
+	var out bytes.Buffer
+	describeModule(&out, module.Info())
+
+	for _, expected := range []string{
+		"Requires:" + colorReset + "    " + colorYellow + "http sink",
+		colorCyan + "filter" + colorReset + "\n",
+		"Values:" + colorReset + "  none, scheme_only, basic_host\n",
+		"Default:" + colorReset + " [http://, https://]\n",
+		"medium  " + colorDim + "filter: scheme_only",
+		"    - path: /fetch\n",
+		"http://169.254.169.254/latest/meta-data/\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/builder"
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// dryRunLabs builds each app as run would, without listening, and prints
+// what was created: the listeners, sinks, seeded data and routes
+func dryRunLabs(apps []*config.Config) {
+	// The plan lists what the build log would, route by route
+	log.SetOutput(io.Discard)
+
+	for _, app := range apps {
+		b := builder.New(app, "")
+		plan, err := b.DryRun()
+		if err != nil {
+			b.Close()
+			fmt.Printf("\n  %s✗ Error:%s failed to build %s: %v\n\n", colorRed, colorReset, app.App.Name, err)
+			os.Exit(1)
+		}
+		printBuildPlan(app.App.Name, plan)
+		b.Close()
+	}
+	fmt.Printf("  %s✓ Dry run complete:%s %snothing was started%s\n\n", colorGreen, colorReset, colorDim, colorReset)
+}
+
+// printBuildPlan prints a lab's build plan, section by section
+func printBuildPlan(name string, plan *builder.Plan) {
+	fmt.Println(colorCyan + colorBold + "┌─────────────────────────────────────────┐" + colorReset)
+	fmt.Println(colorCyan + colorBold + "│               BUILD PLAN                │" + colorReset)
+	fmt.Println(colorCyan + colorBold + "└─────────────────────────────────────────┘" + colorReset)
+	fmt.Println()
+	fmt.Printf("  %s%s%s\n\n", colorGreen+colorBold, name, colorReset)
+
+	fmt.Println(colorYellow + "  ◆ LISTENERS" + colorReset)
+	for _, l := range plan.Listeners {
+		fmt.Printf("    %s%-9s%s %s\n", colorDim, l.Name, colorReset, l.Address)
+	}
+	fmt.Println()
+
+	fmt.Println(colorYellow + "  ◆ SINKS" + colorReset)
+	if len(plan.Sinks) == 0 {
+		fmt.Printf("    %snone%s\n", colorDim, colorReset)
+	}
+	for _, s := range plan.Sinks {
+		fmt.Printf("    %s%-12s%s %s\n", colorGreen, s.Name, colorReset, s.Description)
+		if s.Name == "sqlite" && plan.Persistence != "" {
+			fmt.Printf("    %s%-12s persistent at %s on a real run, left untouched%s\n", colorDim, "", plan.Persistence, colorReset)
+		}
+		for _, use := range s.Vulnerabilities {
+			fmt.Printf("    %-12s %s↳ %s%s\n", "", colorDim, use, colorReset)
+		}
+	}
+	fmt.Println()
+
+	if len(plan.Seeds) > 0 {
+		fmt.Println(colorYellow + "  ◆ SEEDS" + colorReset)
+		for _, seed := range plan.Seeds {
+			name := seed.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Printf("    %s%-12s%s %-24s %s%d %s%s\n", colorGreen, seed.Sink, colorReset, name, colorCyan, seed.Count, seedUnit(seed), colorReset)
+		}
+		fmt.Println()
+	}
+
+	printPlanRoutes("ROUTES", plan.Routes)
+	printPlanRoutes("GRPC ROUTES", plan.GRPCRoutes)
+	printPlanRoutes("ADMIN ROUTES", plan.AdminRoutes)
+}
+
+// printPlanRoutes prints a listener's routes under a heading, unless it has none
+func printPlanRoutes(heading string, routes []string) {
+	if len(routes) == 0 {
+		return
+	}
+	fmt.Printf("%s  ◆ %s%s %s(%d)%s\n", colorYellow, heading, colorReset, colorDim, len(routes), colorReset)
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		fmt.Printf("    %s%-7s%s %s\n", colorCyan, method, colorReset, path)
+	}
+	fmt.Println()
+}
+
+// seedUnit returns a seed's unit, singular for one
+func seedUnit(seed builder.PlanSeed) string {
+	switch {
+	case seed.Count != 1:
+		return seed.Unit
+	case strings.HasSuffix(seed.Unit, "ies"):
+		return strings.TrimSuffix(seed.Unit, "ies") + "y"
+	}
+	return strings.TrimSuffix(seed.Unit, "s")
+}
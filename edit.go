@@ -0,0 +1,592 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// editMethods and editResponseTypes are the choices edit offers for a new
+// endpoint, as validation accepts them
+var (
+	editMethods       = []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+	editResponseTypes = []string{"json", "html", "xml", "text"}
+)
+
+// maxEditErrors bounds the validation errors edit shows after each change
+const maxEditErrors = 5
+
+// configDoc is a config file being edited. It's kept as a YAML node tree, so
+// comments, key order and quoting outlive the edit.
+type configDoc struct {
+	path      string
+	document  *yaml.Node
+	root      *yaml.Node // The document's top-level mapping
+	endpoints *yaml.Node // The endpoints sequence, created on the first add
+	changed   bool
+}
+
+// loadConfigDoc reads a config file to edit
+func loadConfigDoc(path string) (*configDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s isn't a config: expected a mapping with app and endpoints", path)
+	}
+
+	doc := &configDoc{path: path, document: &document, root: document.Content[0]}
+	if value := mappingValue(doc.root, "apps"); value != nil && mappingValue(doc.root, "endpoints") == nil {
+		return nil, fmt.Errorf("%s has several apps; edit works on the endpoints of a single-app config", path)
+	}
+	if value := mappingValue(doc.root, "endpoints"); value != nil {
+		if value.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("%s: endpoints must be a list", path)
+		}
+		doc.endpoints = value
+	}
+	return doc, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in a mapping node, appending it when it's new
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// scalarNode returns a node for a value typed in at a prompt: true and false
+// are booleans, whole numbers are ints, and anything else a string
+func scalarNode(value string) *yaml.Node {
+	var node yaml.Node
+	if value == "true" || value == "false" {
+		node.Encode(value == "true")
+	} else if n, err := strconv.Atoi(value); err == nil {
+		node.Encode(n)
+	} else {
+		node.Encode(value)
+	}
+	return &node
+}
+
+// Endpoints decodes the endpoints, for listing
+func (d *configDoc) Endpoints() []config.EndpointConfig {
+	if d.endpoints == nil {
+		return nil
+	}
+	endpoints := make([]config.EndpointConfig, len(d.endpoints.Content))
+	for i, node := range d.endpoints.Content {
+		node.Decode(&endpoints[i])
+	}
+	return endpoints
+}
+
+// AddEndpoint appends an endpoint without vulnerabilities
+func (d *configDoc) AddEndpoint(path, method, responseType string) {
+	if d.endpoints == nil {
+		d.endpoints = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(d.root, "endpoints", d.endpoints)
+	}
+	endpoint := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(endpoint, "path", scalarNode(path))
+	setMappingValue(endpoint, "method", scalarNode(method))
+	setMappingValue(endpoint, "response_type", scalarNode(responseType))
+	setMappingValue(endpoint, "vulnerabilities", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle})
+	d.endpoints.Content = append(d.endpoints.Content, endpoint)
+	d.changed = true
+}
+
+// RemoveEndpoint deletes endpoint i
+func (d *configDoc) RemoveEndpoint(i int) {
+	d.endpoints.Content = slices.Delete(d.endpoints.Content, i, i+1)
+	d.changed = true
+}
+
+// vulnerabilities returns endpoint i's vulnerabilities sequence, creating it
+// if the endpoint has none
+func (d *configDoc) vulnerabilities(i int) *yaml.Node {
+	endpoint := d.endpoints.Content[i]
+	vulns := mappingValue(endpoint, "vulnerabilities")
+	if vulns == nil || vulns.Kind != yaml.SequenceNode {
+		vulns = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(endpoint, "vulnerabilities", vulns)
+	}
+	return vulns
+}
+
+// AddVulnerability appends a vulnerability to endpoint i, with its config
+// keys in the order given
+func (d *configDoc) AddVulnerability(i int, module, placement, param, difficulty string, options [][2]string) {
+	vuln := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(vuln, "type", scalarNode(module))
+	setMappingValue(vuln, "placement", scalarNode(placement))
+	if param != "" {
+		setMappingValue(vuln, "param", scalarNode(param))
+	}
+	if difficulty != "" {
+		setMappingValue(vuln, "difficulty", scalarNode(difficulty))
+	}
+	if len(options) > 0 {
+		cfg := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, option := range options {
+			setMappingValue(cfg, option[0], scalarNode(option[1]))
+		}
+		setMappingValue(vuln, "config", cfg)
+	}
+
+	vulns := d.vulnerabilities(i)
+	vulns.Style = 0 // An empty [] becomes a block list once it has items
+	vulns.Content = append(vulns.Content, vuln)
+	d.changed = true
+}
+
+// SetOption sets a config key of endpoint i's vulnerability j
+func (d *configDoc) SetOption(i, j int, key, value string) {
+	vuln := d.vulnerabilities(i).Content[j]
+	cfg := mappingValue(vuln, "config")
+	if cfg == nil || cfg.Kind != yaml.MappingNode {
+		cfg = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(vuln, "config", cfg)
+	}
+	setMappingValue(cfg, key, scalarNode(value))
+	d.changed = true
+}
+
+// RemoveVulnerability deletes endpoint i's vulnerability j
+func (d *configDoc) RemoveVulnerability(i, j int) {
+	vulns := d.vulnerabilities(i)
+	vulns.Content = slices.Delete(vulns.Content, j, j+1)
+	if len(vulns.Content) == 0 {
+		vulns.Style = yaml.FlowStyle
+	}
+	d.changed = true
+}
+
+// Bytes encodes the document as it now is
+func (d *configDoc) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return spaceSections(buf.Bytes()), nil
+}
+
+// spaceSections puts back the blank lines the encoder drops: before each
+// top-level key and each endpoint, with its comment, as configs lay them out
+func spaceSections(data []byte) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	var b strings.Builder
+	section, previous := "", ""
+	for _, line := range lines {
+		topLevel := line != "" && line[0] != ' ' && line[0] != '#' && line[0] != '\n'
+		comment := strings.HasPrefix(line, "#")
+		endpoint := section == "endpoints" && (strings.HasPrefix(line, "  - ") || strings.HasPrefix(line, "  #"))
+		if (topLevel || comment || endpoint) && previous != "" && previous != "\n" && !strings.HasPrefix(strings.TrimSpace(previous), "#") && !strings.HasSuffix(previous, "endpoints:\n") {
+			b.WriteString("\n")
+		}
+		if topLevel {
+			section, _, _ = strings.Cut(line, ":")
+		}
+		b.WriteString(line)
+		previous = line
+	}
+	return []byte(b.String())
+}
+
+// Validate loads the document as it now is, next to the file so includes
+// resolve the same, and returns its errors and warnings
+func (d *configDoc) Validate(vars map[string]string) (config.ValidationErrors, config.ValidationWarnings, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), ".flawfactory-edit-*.yaml")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := config.LoadWithVars(tmp.Name(), vars)
+	if err != nil {
+		var errs config.ValidationErrors
+		if errors.As(err, &errs) {
+			return errs, nil, nil
+		}
+		return nil, nil, err
+	}
+	result := config.ValidateWithWarnings(cfg)
+	return result.Errors, result.Warnings, nil
+}
+
+// Save writes the document over the file, through a temporary file so an
+// interrupted save leaves the old one intact
+func (d *configDoc) Save() error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(d.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	d.changed = false
+	return nil
+}
+
+func editCommand() {
+	editFlags := flag.NewFlagSet("edit", flag.ExitOnError)
+	configFile := editFlags.String("config", "", "Path to the YAML config file to edit (required)")
+	configFileShort := editFlags.String("c", "", "Path to the YAML config file to edit (shorthand)")
+	var sets setFlags
+	editFlags.Var(&sets, "set", "Set a config variable as NAME=value, for validation (repeatable)")
+
+	editFlags.Parse(os.Args[2:])
+
+	path := *configFile
+	if path == "" {
+		path = *configFileShort
+	}
+	if path == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config flag is required (flawfactory init writes a new one)\n\n", colorRed, colorReset)
+		editFlags.PrintDefaults()
+		os.Exit(1)
+	}
+	vars, err := config.ParseVars(sets)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	doc, err := loadConfigDoc(path)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+
+	e := &editor{doc: doc, vars: vars, p: &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}}
+	e.run()
+}
+
+// editor is edit's menu-driven terminal UI over a config document
+type editor struct {
+	doc  *configDoc
+	vars map[string]string
+	p    *prompter
+}
+
+// run shows the endpoints and the menu until the user quits, validating
+// after every change
+func (e *editor) run() {
+	out := e.p.out
+	for {
+		e.show()
+		fmt.Fprintf(out, "  %sa%s add endpoint  %sv%s add vulnerability  %so%s set option  %sr%s remove vulnerability  %sd%s delete endpoint  %ss%s save  %sq%s quit\n",
+			colorGreen, colorReset, colorGreen, colorReset, colorGreen, colorReset, colorGreen, colorReset, colorGreen, colorReset, colorGreen, colorReset, colorGreen, colorReset)
+		choice := strings.ToLower(e.p.ask("Action", "q"))
+		switch choice {
+		case "a":
+			e.addEndpoint()
+		case "v":
+			e.addVulnerability()
+		case "o":
+			e.setOption()
+		case "r":
+			e.removeVulnerability()
+		case "d":
+			e.removeEndpoint()
+		case "s":
+			e.save()
+		case "q":
+			if e.doc.changed && !e.p.defaults && !e.p.confirm("Quit without saving your changes", false) {
+				continue
+			}
+			return
+		default:
+			fmt.Fprintf(out, "    %sUnknown action %q%s\n", colorYellow, choice, colorReset)
+		}
+	}
+}
+
+// show lists the endpoints and their vulnerabilities, and how the document
+// validates as it now is
+func (e *editor) show() {
+	out := e.p.out
+	state := ""
+	if e.doc.changed {
+		state = colorYellow + " (unsaved changes)" + colorReset
+	}
+	fmt.Fprintf(out, "\n  %sENDPOINTS%s  %s%s\n", colorYellow, colorReset, e.doc.path, state)
+	endpoints := e.doc.Endpoints()
+	if len(endpoints) == 0 {
+		fmt.Fprintf(out, "    %snone yet%s\n", colorDim, colorReset)
+	}
+	for i, endpoint := range endpoints {
+		fmt.Fprintf(out, "    %s%2d%s %s%-6s%s %s %s%s%s\n", colorCyan, i+1, colorReset, colorGreen, strings.ToUpper(endpoint.Method), colorReset, endpoint.Path, colorDim, endpoint.ResponseType, colorReset)
+		for j, vuln := range endpoint.Vulnerabilities {
+			fmt.Fprintf(out, "         %s%d.%d%s %s %s(%s %s)%s%s\n", colorDim, i+1, j+1, colorReset, vuln.Type, colorDim, vuln.Placement, strings.Join(vuln.ParamNames(), ", "), colorReset, formatEditOptions(vuln.Config))
+		}
+	}
+
+	errs, warns, err := e.doc.Validate(e.vars)
+	fmt.Fprintln(out)
+	switch {
+	case err != nil:
+		fmt.Fprintf(out, "  %s✗ %v%s\n", colorRed, err, colorReset)
+	case len(errs) > 0:
+		fmt.Fprintf(out, "  %s✗ %d validation error%s%s\n", colorRed, len(errs), pluralize(len(errs)), colorReset)
+		for _, validationErr := range errs[:min(len(errs), maxEditErrors)] {
+			fmt.Fprintf(out, "    %s•%s %s: %s\n", colorRed, colorReset, validationErr.Field, validationErr.Message)
+		}
+	default:
+		fmt.Fprintf(out, "  %s✓ Valid%s", colorGreen, colorReset)
+		if len(warns) > 0 {
+			fmt.Fprintf(out, " %s(%d warning%s)%s", colorYellow, len(warns), pluralize(len(warns)), colorReset)
+		}
+		fmt.Fprintln(out)
+		for _, warn := range warns[:min(len(warns), maxEditErrors)] {
+			fmt.Fprintf(out, "    %s•%s %s: %s\n", colorYellow, colorReset, warn.Field, warn.Message)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// formatEditOptions lists a vulnerability's config keys and values, sorted
+func formatEditOptions(options map[string]interface{}) string {
+	if len(options) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", key, options[key])
+	}
+	return " " + colorDim + strings.Join(parts, " ") + colorReset
+}
+
+func (e *editor) addEndpoint() {
+	path := e.p.ask("Path, e.g. /search or /users/{id}", "")
+	if !strings.HasPrefix(path, "/") {
+		fmt.Fprintf(e.p.out, "    %sA path starts with /%s\n", colorYellow, colorReset)
+		return
+	}
+	method, _ := e.pick("Method", editMethods, "GET")
+	responseType, _ := e.pick("Response type", editResponseTypes, "json")
+	e.doc.AddEndpoint(path, method, responseType)
+}
+
+func (e *editor) removeEndpoint() {
+	if i, ok := e.pickEndpoint(); ok {
+		e.doc.RemoveEndpoint(i)
+	}
+}
+
+// addVulnerability adds a module to an endpoint, offering the placements it
+// supports, its difficulty levels and the values of each of its variant keys
+func (e *editor) addVulnerability() {
+	i, ok := e.pickEndpoint()
+	if !ok {
+		return
+	}
+	infos := modules.List()
+	slices.SortFunc(infos, func(a, b modules.ModuleInfo) int { return strings.Compare(a.Name, b.Name) })
+	names := make([]string, len(infos))
+	for j, info := range infos {
+		names[j] = info.Name
+	}
+	module, ok := e.pick("Module", names, "")
+	if !ok {
+		return
+	}
+	info := infos[slices.Index(names, module)]
+
+	placement, _ := e.pick("Placement", info.SupportedPlacements, info.SupportedPlacements[0])
+	param := ""
+	if placement != "raw_body" {
+		param = e.p.ask("Param, the name of the input to read", "")
+	}
+	difficulty := ""
+	if len(info.Difficulty) > 0 {
+		difficulty, _ = e.pick("Difficulty, filling in the options left unset", modules.DifficultyLevels, "skip")
+	}
+
+	var options [][2]string
+	for _, key := range slices.Sorted(maps.Keys(info.ValidVariants)) {
+		if value, ok := e.pick(key, info.ValidVariants[key], "skip"); ok {
+			options = append(options, [2]string{key, value})
+		}
+	}
+	for _, key := range info.ConfigKeys {
+		if strings.HasSuffix(key, "_template") || strings.HasSuffix(key, "_query") || key == "base_command" {
+			if value := e.p.ask(key, "skip"); value != "skip" {
+				options = append(options, [2]string{key, value})
+			}
+		}
+	}
+	e.doc.AddVulnerability(i, module, placement, param, difficulty, options)
+	if info.RequiresSink != "" {
+		fmt.Fprintf(e.p.out, "    %s%s uses the %s sink; see templates/ for the data or files it reads%s\n", colorDim, module, info.RequiresSink, colorReset)
+	}
+}
+
+// setOption sets one config key of a vulnerability, offering the module's
+// keys and, for variant keys, their values
+func (e *editor) setOption() {
+	i, j, vuln, ok := e.pickVulnerability()
+	if !ok {
+		return
+	}
+	var info modules.ModuleInfo
+	if module, err := modules.Get(vuln.Type); err == nil {
+		info = module.Info()
+	}
+	keys := slices.Sorted(maps.Keys(info.ValidVariants))
+	keys = append(keys, info.ConfigKeys...)
+	key, ok := e.pick("Option", keys, "")
+	if !ok {
+		return
+	}
+	current := ""
+	if value, set := vuln.Config[key]; set {
+		current = fmt.Sprint(value)
+	}
+	var value string
+	if values, isVariant := info.ValidVariants[key]; isVariant {
+		value, ok = e.pick(key, values, current)
+	} else {
+		value = e.p.ask(key, current)
+		ok = value != ""
+	}
+	if ok {
+		e.doc.SetOption(i, j, key, value)
+	}
+}
+
+func (e *editor) removeVulnerability() {
+	if i, j, _, ok := e.pickVulnerability(); ok {
+		e.doc.RemoveVulnerability(i, j)
+	}
+}
+
+func (e *editor) save() {
+	errs, _, err := e.doc.Validate(e.vars)
+	if err == nil && len(errs) > 0 {
+		err = fmt.Errorf("fix the %d validation error%s first", len(errs), pluralize(len(errs)))
+	}
+	if err == nil {
+		err = e.doc.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(e.p.out, "    %s✗ Not saved:%s %v\n", colorRed, colorReset, err)
+		return
+	}
+	fmt.Fprintf(e.p.out, "    %s✓ Saved%s %s\n", colorGreen, colorReset, e.doc.path)
+}
+
+// pick offers numbered options and returns the one chosen, by number or
+// name, or def. Choosing "skip" or leaving it blank without a def picks nothing.
+func (e *editor) pick(question string, options []string, def string) (string, bool) {
+	for {
+		fmt.Fprintf(e.p.out, "    %s%s%s\n", colorYellow, question, colorReset)
+		for i, option := range options {
+			fmt.Fprintf(e.p.out, "      %s%2d%s %s\n", colorCyan, i+1, colorReset, option)
+		}
+		answer := e.p.ask("Choose", def)
+		if answer == "" || answer == "skip" {
+			return "", false
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return options[n-1], true
+		}
+		if slices.Contains(options, answer) {
+			return answer, true
+		}
+		if e.p.defaults {
+			return "", false
+		}
+		fmt.Fprintf(e.p.out, "    %sPick 1 to %d, or a name from the list%s\n", colorYellow, len(options), colorReset)
+	}
+}
+
+// pickEndpoint asks for an endpoint by its number in the list
+func (e *editor) pickEndpoint() (int, bool) {
+	count := len(e.doc.Endpoints())
+	if count == 0 {
+		fmt.Fprintf(e.p.out, "    %sAdd an endpoint first%s\n", colorYellow, colorReset)
+		return 0, false
+	}
+	n, err := strconv.Atoi(e.p.ask("Endpoint number", "1"))
+	if err != nil || n < 1 || n > count {
+		fmt.Fprintf(e.p.out, "    %sThere is no endpoint %d%s\n", colorYellow, n, colorReset)
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// pickVulnerability asks for a vulnerability by its number in the list, e.g. 2.1
+func (e *editor) pickVulnerability() (int, int, config.VulnerabilityConfig, bool) {
+	answer := e.p.ask("Vulnerability number, e.g. 1.1", "1.1")
+	endpoint, vuln, _ := strings.Cut(answer, ".")
+	i, errI := strconv.Atoi(endpoint)
+	j, errJ := strconv.Atoi(vuln)
+	endpoints := e.doc.Endpoints()
+	if errI != nil || errJ != nil || i < 1 || i > len(endpoints) || j < 1 || j > len(endpoints[i-1].Vulnerabilities) {
+		fmt.Fprintf(e.p.out, "    %sThere is no vulnerability %s%s\n", colorYellow, answer, colorReset)
+		return 0, 0, config.VulnerabilityConfig{}, false
+	}
+	return i - 1, j - 1, endpoints[i-1].Vulnerabilities[j-1], true
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+const editTestConfig = `# Lab for the edit tests
+
+app:
+  name: "Edit Lab"
+  port: 8080
+
+endpoints:
+  # reflected XSS → curl "http://localhost:8080/search?q=<script>"
+  - path: /search
+    method: GET
+    response_type: html
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+`
+
+// writeEditTestConfig writes editTestConfig to a temporary file
+func writeEditTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "lab.yaml")
+	if err := os.WriteFile(path, []byte(editTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestConfigDoc tests endpoints, vulnerabilities and options are edited in
+// place, keeping the file's comments and layout
+func TestConfigDoc(t *testing.T) {
+	path := writeEditTestConfig(t)
+	doc, err := loadConfigDoc(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	doc.AddEndpoint("/files", "GET", "json")
+	doc.AddVulnerability(1, "ssrf", "query_param", "url", "", [][2]string{{"filter", "none"}, {"timeout", "5"}})
+	doc.SetOption(0, 0, "context", "attribute")
+	errs, _, err := doc.Validate(nil)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("Expected a valid config, got %v %v", errs, err)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	saved := string(data)
+	for _, expected := range []string{
+		"# Lab for the edit tests\n\napp:\n",
+		"\n\nendpoints:\n  # reflected XSS",
+		"        param: q\n        config:\n          context: attribute\n\n  - path: /files\n",
+		"          filter: none\n          timeout: 5\n",
+	} {
+		if !strings.Contains(saved, expected) {
+			t.Errorf("Expected %q in the saved config, got\n%s", expected, saved)
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Expected the saved config to load, got %v", err)
+	}
+	if cfg.Endpoints[1].Vulnerabilities[0].Config["timeout"] != 5 {
+		t.Errorf("Expected timeout saved as a number, got %#v", cfg.Endpoints[1].Vulnerabilities[0].Config["timeout"])
+	}
+
+	// An invalid change is reported, and the last valid save is left alone
+	doc.AddVulnerability(1, "sql_injection", "query_param", "", "", nil)
+	if errs, _, _ := doc.Validate(nil); len(errs) == 0 {
+		t.Error("Expected a vulnerability without a param to be invalid")
+	}
+	doc.RemoveVulnerability(1, 1)
+	doc.RemoveEndpoint(1)
+	if endpoints := doc.Endpoints(); len(endpoints) != 1 || endpoints[0].Path != "/search" {
+		t.Errorf("Expected only /search left, got %+v", endpoints)
+	}
+}
+
+// TestEditor tests a session adding an endpoint from the menu and saving it
+func TestEditor(t *testing.T) {
+	path := writeEditTestConfig(t)
+	doc, err := loadConfigDoc(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	var out bytes.Buffer
+	input := "a\n/admin\n2\ntext\nx\ns\nq\n"
+	e := &editor{doc: doc, p: &prompter{in: bufio.NewReader(strings.NewReader(input)), out: &out}}
+	e.run()
+
+	if !strings.Contains(out.String(), `Unknown action "x"`) || !strings.Contains(out.String(), "✓ Saved") {
+		t.Errorf("Expected the unknown action reported and the config saved, got\n%s", out.String())
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Expected the saved config to load, got %v", err)
+	}
+	if len(cfg.Endpoints) != 2 || cfg.Endpoints[1].Path != "/admin" || cfg.Endpoints[1].Method != "POST" || cfg.Endpoints[1].ResponseType != "text" {
+		t.Errorf("Expected POST /admin added, got %+v", cfg.Endpoints)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/builder"
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// endpointSummary is an endpoint and the vulnerabilities it serves
+type endpointSummary struct {
+	App             string                 `json:"app"`
+	Method          string                 `json:"method"`
+	Path            string                 `json:"path"`            // Prefixed with the endpoint's host, if it has one
+	Alias           string                 `json:"alias,omitempty"` // The path of the endpoint it serves, for aliases
+	Vulnerabilities []vulnerabilitySummary `json:"vulnerabilities"`
+}
+
+// vulnerabilitySummary is a vulnerability and what it takes to serve it
+type vulnerabilitySummary struct {
+	Type       string   `json:"type"`
+	Placement  string   `json:"placement"`
+	Params     []string `json:"params,omitempty"`
+	Difficulty string   `json:"difficulty,omitempty"`
+	Sinks      []string `json:"sinks,omitempty"`
+}
+
+// endpointsCommand prints each endpoint of a config with the vulnerabilities
+// it serves: where they read input, their difficulty and the sinks they need
+func endpointsCommand() {
+	endpointsFlags := flag.NewFlagSet("endpoints", flag.ExitOnError)
+	var configFiles configFlags
+	endpointsFlags.Var(&configFiles, "config", "Path to YAML configuration file (repeat to layer overlays over it)")
+	endpointsFlags.Var(&configFiles, "c", "Path to YAML configuration file (shorthand)")
+	preset := endpointsFlags.String("preset", "", "List a built-in lab's endpoints instead of a config file's")
+	var sets setFlags
+	endpointsFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	strict := endpointsFlags.Bool("strict", false, "Reject unknown config keys, module types and placements")
+	asJSON := endpointsFlags.Bool("json", false, "Print the endpoints as JSON")
+
+	endpointsFlags.Parse(os.Args[2:])
+
+	if len(configFiles) == 0 && *preset == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config flag is required\n\n", colorRed, colorReset)
+		endpointsFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(configFiles, *preset, sets, *strict)
+	if err != nil {
+		printConfigError(strings.Join(configFiles, ", "), err)
+		os.Exit(1)
+	}
+
+	apps := cfg.Applications()
+	if *asJSON {
+		summaries := []endpointSummary{}
+		for _, app := range apps {
+			summaries = append(summaries, summarizeEndpoints(app)...)
+		}
+		data, _ := json.MarshalIndent(summaries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Println()
+	for _, app := range apps {
+		if len(apps) > 1 {
+			fmt.Printf("  %s%s%s\n\n", colorGreen+colorBold, app.App.Name, colorReset)
+		}
+		printEndpointsTable(summarizeEndpoints(app))
+	}
+}
+
+// summarizeEndpoints lists an app's endpoints in config order, an alias with
+// its target's vulnerabilities
+func summarizeEndpoints(app *config.Config) []endpointSummary {
+	var summaries []endpointSummary
+	for _, endpoint := range app.Endpoints {
+		summary := endpointSummary{
+			App:             app.App.Name,
+			Method:          strings.ToUpper(endpoint.Method),
+			Path:            endpoint.Host + endpoint.Path,
+			Vulnerabilities: []vulnerabilitySummary{},
+		}
+		if endpoint.Type == "alias" {
+			if target, ok := endpoint.AliasTarget(app.Endpoints); ok {
+				summary.Alias = target.Host + target.Path
+				endpoint = target
+			}
+		}
+		for _, vuln := range endpoint.Vulnerabilities {
+			summary.Vulnerabilities = append(summary.Vulnerabilities, vulnerabilitySummary{
+				Type:       vuln.Type,
+				Placement:  vuln.Placement,
+				Params:     vuln.ParamNames(),
+				Difficulty: vuln.Difficulty,
+				Sinks:      builder.VulnerabilitySinks(vuln),
+			})
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// printEndpointsTable prints a row per endpoint, listing each of the distinct
+// placements, types, difficulties and sinks of its vulnerabilities once
+func printEndpointsTable(summaries []endpointSummary) {
+	rows := [][]string{{"METHOD", "PATH", "PLACEMENTS", "VULNERABILITIES", "DIFFICULTY", "SINKS"}}
+	for _, s := range summaries {
+		var placements, types, difficulties, sinks []string
+		for _, vuln := range s.Vulnerabilities {
+			placements = append(placements, vuln.Placement)
+			types = append(types, vuln.Type)
+			difficulties = append(difficulties, vuln.Difficulty)
+			sinks = append(sinks, vuln.Sinks...)
+		}
+		path := s.Path
+		if s.Alias != "" {
+			path += " → " + s.Alias
+		}
+		method := s.Method
+		if method == "" {
+			method = "-"
+		}
+		rows = append(rows, []string{method, path, endpointsColumn(placements), endpointsColumn(types), endpointsColumn(difficulties), endpointsColumn(sinks)})
+	}
+
+	// Widths count runes, as fmt pads by them
+	widths := make([]int, len(rows[0])-1)
+	for _, row := range rows {
+		for i := range widths {
+			widths[i] = max(widths[i], len([]rune(row[i])))
+		}
+	}
+	for r, row := range rows {
+		var line strings.Builder
+		for i, width := range widths {
+			fmt.Fprintf(&line, "%-*s  ", width, row[i])
+		}
+		line.WriteString(row[len(row)-1])
+		if r == 0 {
+			fmt.Printf("  %s%s%s\n", colorYellow, line.String(), colorReset)
+			continue
+		}
+		fmt.Printf("  %s\n", line.String())
+	}
+	fmt.Println()
+}
+
+// endpointsColumn joins the distinct non-empty values in their first order,
+// or is "-" without any
+func endpointsColumn(values []string) string {
+	var distinct []string
+	for _, value := range values {
+		if value != "" && !slices.Contains(distinct, value) {
+			distinct = append(distinct, value)
+		}
+	}
+	if len(distinct) == 0 {
+		return "-"
+	}
+	return strings.Join(distinct, ", ")
+}
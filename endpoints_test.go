@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestSummarizeEndpoints tests each endpoint is listed with its
+// vulnerabilities' sinks, an alias with its target's vulnerabilities
+func TestSummarizeEndpoints(t *testing.T) {
+	app := &config.Config{
+		App: config.AppConfig{Name: "Answer Key"},
+		Endpoints: []config.EndpointConfig{
+			{
+				Path:   "/users",
+				Method: "get",
+				Vulnerabilities: []config.VulnerabilityConfig{
+					{Type: "sql_injection", Placement: "query_param", Param: "id", Difficulty: "hard"},
+					{Type: "xss_reflected", Placement: "query_param", Param: "q"},
+				},
+			},
+			{Path: "/v2/users", Method: "GET", Type: "alias", Target: "GET /users"},
+			{Path: "/health", Method: "GET"},
+		},
+	}
+
+	summaries := summarizeEndpoints(app)
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 3 endpoints, got %d", len(summaries))
+	}
+	users := summaries[0]
+	if users.App != "Answer Key" || users.Method != "GET" || len(users.Vulnerabilities) != 2 {
+		t.Fatalf("Unexpected summary %+v", users)
+	}
+	if vuln := users.Vulnerabilities[0]; vuln.Difficulty != "hard" || !slices.Equal(vuln.Params, []string{"id"}) || !slices.Equal(vuln.Sinks, []string{"sqlite"}) {
+		t.Errorf("Unexpected vulnerability %+v", vuln)
+	}
+	if alias := summaries[1]; alias.Alias != "/users" || len(alias.Vulnerabilities) != 2 {
+		t.Errorf("Expected the alias to serve /users' vulnerabilities, got %+v", alias)
+	}
+	if summaries[2].Vulnerabilities == nil {
+		t.Error("Expected an empty vulnerability list, for JSON's []")
+	}
+
+	if got := endpointsColumn([]string{"query_param", "", "query_param", "header"}); got != "query_param, header" {
+		t.Errorf("Expected the distinct values, got %q", got)
+	}
+	if got := endpointsColumn(nil); got != "-" {
+		t.Errorf("Expected - without values, got %q", got)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"gopkg.in/yaml.v3"
+)
+
+// exportFormats lists what export can write a config as
+var exportFormats = []string{"openapi", "postman", "burp", "nuclei", "walkthrough", "docker", "kubernetes"}
+
+// exportNames names what each format is written as
+var exportNames = map[string]string{
+	"openapi":     "OpenAPI document",
+	"postman":     "Postman collection",
+	"burp":        "Burp items",
+	"nuclei":      "Nuclei templates",
+	"walkthrough": "Walkthrough",
+	"docker":      "Docker files",
+	"kubernetes":  "Kubernetes manifests",
+}
+
+// exportCommand writes a config's endpoints in a format other tools load: an
+// OpenAPI document for Swagger UI and API scanners, a Postman collection or
+// Burp Suite items with a request per endpoint and per vulnerability, or
+// Nuclei templates detecting each configured vulnerability, a Markdown
+// walkthrough solving each of them, the files that run the lab with docker
+// compose up, or Kubernetes manifests deploying an instance of it per student
+func exportCommand() {
+	if len(os.Args) < 3 || !slices.Contains(exportFormats, os.Args[2]) {
+		fmt.Printf("\n  %s✗ Error:%s export needs a format (available: %s)\n\n", colorRed, colorReset, strings.Join(exportFormats, ", "))
+		fmt.Println("Usage: flawfactory export <openapi|postman|burp|nuclei|walkthrough|docker|kubernetes> -config <file> [-o file] [more configs (kubernetes)]")
+		os.Exit(1)
+	}
+	format := os.Args[2]
+
+	exportFlags := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	var configFiles configFlags
+	exportFlags.Var(&configFiles, "config", "Path to YAML config file (repeat to layer overlays over it)")
+	exportFlags.Var(&configFiles, "c", "Path to YAML config file (shorthand)")
+	preset := exportFlags.String("preset", "", "Export a built-in lab instead of a config file")
+	var sets setFlags
+	exportFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	port := exportFlags.Int("port", 0, "Port the lab is run with, if overridden")
+	portShort := exportFlags.Int("p", 0, "Port the lab is run with, if overridden (shorthand)")
+	appName := exportFlags.String("app", "", "App to export, when the config has several (docker exports them all)")
+	with := exportFlags.String("with", "", "Companions to add to the Docker files, comma-separated: "+strings.Join(config.DockerCompanions, ", "))
+	students := exportFlags.Int("students", 1, "Instances of each lab to deploy, one per student (kubernetes)")
+	nodePort := exportFlags.Int("node-port", 0, "First NodePort to hand out, the next ones following it (kubernetes; default 30000)")
+	seed := exportFlags.Int64("seed", 0, "First student's app.seed, the next students getting the next ones (kubernetes; default: the lab's, or 1)")
+	image := exportFlags.String("image", "", "Image with flawfactory, e.g. built from export docker's Dockerfile (kubernetes; default flawfactory:latest)")
+	namespace := exportFlags.String("namespace", "", "Namespace of the objects (kubernetes)")
+	output := exportFlags.String("output", "", "Write to a file instead of stdout (openapi: YAML for .yaml and .yml, else JSON; nuclei: a directory of templates; docker: the directory to write, required)")
+	outputShort := exportFlags.String("o", "", "Write to a file instead of stdout (shorthand)")
+
+	exportFlags.Parse(os.Args[3:])
+
+	outputFile := *output
+	if outputFile == "" {
+		outputFile = *outputShort
+	}
+	portOverride := *port
+	if portOverride == 0 {
+		portOverride = *portShort
+	}
+	if format == "kubernetes" {
+		opts := config.KubernetesOptions{Students: *students, NodePort: *nodePort, Seed: *seed, Image: *image, Namespace: *namespace}
+		exportKubernetes(configFiles, *preset, exportFlags.Args(), sets, portOverride, opts, outputFile)
+		return
+	}
+	if len(configFiles) == 0 && *preset == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config or -preset flag is required\n\n", colorRed, colorReset)
+		exportFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(configFiles, *preset, sets, false)
+	if err != nil {
+		printConfigError(strings.Join(configFiles, ", "), err)
+		os.Exit(1)
+	}
+	if format == "docker" {
+		if portOverride > 0 {
+			cfg.App.Port = portOverride
+		}
+		exportDocker(cfg, *with, outputFile)
+		return
+	}
+	app, err := exportApp(cfg.Applications(), *appName)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	if portOverride > 0 {
+		app.App.Port = portOverride
+	}
+
+	if format == "nuclei" && outputFile != "" {
+		count, err := writeNucleiTemplates(config.NucleiTemplates(app), outputFile)
+		if err != nil {
+			fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  %s✓ %d Nuclei templates written to%s %s\n\n", colorGreen, count, colorReset, outputFile)
+		return
+	}
+
+	data, err := exportData(format, app, outputFile)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s failed to encode %s: %v\n\n", colorRed, colorReset, exportNames[format], err)
+		os.Exit(1)
+	}
+
+	if outputFile == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n  %s✓ %s written to%s %s\n\n", colorGreen, exportNames[format], colorReset, outputFile)
+}
+
+// exportData encodes an app in a format: Postman collections as JSON, Burp
+// items as XML, Nuclei templates as a stream of YAML documents, walkthroughs
+// as Markdown, and OpenAPI
+// documents as YAML when written to a .yaml or .yml file, else JSON
+func exportData(format string, app *config.Config, outputFile string) ([]byte, error) {
+	var doc map[string]interface{}
+	switch format {
+	case "burp":
+		return config.BurpItems(app, time.Now())
+	case "nuclei":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		for _, template := range config.NucleiTemplates(app) {
+			if err := encoder.Encode(template); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	case "walkthrough":
+		return config.Walkthrough(app), nil
+	case "postman":
+		doc = config.Postman(app)
+	default:
+		doc = config.OpenAPI(app)
+		if ext := strings.ToLower(filepath.Ext(outputFile)); ext == ".yaml" || ext == ".yml" {
+			var buf bytes.Buffer
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(doc); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeNucleiTemplates writes each template to <id>.yaml in a directory,
+// creating it if needed, and returns how many it wrote
+func writeNucleiTemplates(templates []config.NucleiTemplate, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	for _, template := range templates {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(template); err != nil {
+			return 0, fmt.Errorf("failed to encode %s: %w", template.ID, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, template.ID+".yaml"), buf.Bytes(), 0644); err != nil {
+			return 0, err
+		}
+	}
+	return len(templates), nil
+}
+
+// exportDocker writes the files that run a config's lab with docker compose
+// up to the output directory, creating it if needed
+func exportDocker(cfg *config.Config, with, dir string) {
+	if dir == "" {
+		fmt.Printf("\n  %s✗ Error:%s export docker writes several files; pass the directory for them with -o\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+	var companions []string
+	for _, companion := range strings.Split(with, ",") {
+		if companion = strings.TrimSpace(companion); companion != "" {
+			companions = append(companions, companion)
+		}
+	}
+
+	files, err := config.DockerFiles(cfg, companions)
+	if err == nil {
+		err = writeExportFiles(files, dir)
+	}
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n  %s✓ Docker files written to%s %s\n", colorGreen, colorReset, dir)
+	fmt.Printf("    %sStart the lab with: cd %s && docker compose up%s\n\n", colorDim, dir, colorReset)
+}
+
+// exportKubernetes writes the manifests deploying each lab once per student:
+// the one -config or -preset names, then one per config file in labFiles
+func exportKubernetes(configFiles []string, preset string, labFiles []string, sets setFlags, portOverride int, opts config.KubernetesOptions, outputFile string) {
+	if len(configFiles) == 0 && preset == "" && len(labFiles) == 0 {
+		fmt.Printf("\n  %s✗ Error:%s -config, -preset or config files to deploy are required\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+	var labs []*config.Config
+	if len(configFiles) > 0 || preset != "" {
+		cfg, err := loadConfig(configFiles, preset, sets, false)
+		if err != nil {
+			printConfigError(strings.Join(configFiles, ", "), err)
+			os.Exit(1)
+		}
+		if portOverride > 0 {
+			cfg.App.Port = portOverride
+		}
+		labs = append(labs, cfg)
+	}
+	for _, path := range labFiles {
+		cfg, err := loadConfig([]string{path}, "", sets, false)
+		if err != nil {
+			printConfigError(path, err)
+			os.Exit(1)
+		}
+		labs = append(labs, cfg)
+	}
+
+	objects, instances, err := config.KubernetesManifests(labs, opts)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, object := range objects {
+		if err := encoder.Encode(object); err != nil {
+			fmt.Printf("\n  %s✗ Error:%s failed to encode Kubernetes manifests: %v\n\n", colorRed, colorReset, err)
+			os.Exit(1)
+		}
+	}
+
+	if outputFile == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n  %s✓ %d instances written to%s %s\n\n", colorGreen, len(instances), colorReset, outputFile)
+	width := 0
+	for _, instance := range instances {
+		width = max(width, len(instance.Name))
+	}
+	for _, instance := range instances {
+		var ports []string
+		for _, name := range slices.Sorted(maps.Keys(instance.NodePorts)) {
+			ports = append(ports, fmt.Sprintf("%s %d", name, instance.NodePorts[name]))
+		}
+		fmt.Printf("    %-*s  %sstudent %d, seed %d:%s %s\n", width, instance.Name, colorDim, instance.Student, instance.Seed, colorReset, strings.Join(ports, ", "))
+	}
+	fmt.Printf("\n    %sDeploy them with: kubectl apply -f %s%s\n\n", colorDim, outputFile, colorReset)
+}
+
+// writeExportFiles writes files, by path relative to dir, into dir
+func writeExportFiles(files map[string][]byte, dir string) error {
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportApp picks the named app, or the only one when name is empty
+func exportApp(apps []*config.Config, name string) (*config.Config, error) {
+	var names []string
+	for _, app := range apps {
+		if name == "" && len(apps) == 1 || app.App.Name == name {
+			return app, nil
+		}
+		names = append(names, app.App.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("the config has %d apps, pick one with --app (%s)", len(apps), strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("unknown app '%s' (available: %s)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestExportApp tests the app to export is picked by name, or is the only one
+func TestExportApp(t *testing.T) {
+	one := []*config.Config{{App: config.AppConfig{Name: "Shop"}}}
+	if app, err := exportApp(one, ""); err != nil || app != one[0] {
+		t.Errorf("Expected the only app, got %v %v", app, err)
+	}
+
+	two := append(one, &config.Config{App: config.AppConfig{Name: "Blog"}})
+	if _, err := exportApp(two, ""); err == nil || !strings.Contains(err.Error(), "--app (Shop, Blog)") {
+		t.Errorf("Expected the apps listed, got %v", err)
+	}
+	if app, err := exportApp(two, "Blog"); err != nil || app != two[1] {
+		t.Errorf("Expected Blog, got %v %v", app, err)
+	}
+	if _, err := exportApp(two, "Wiki"); err == nil || !strings.Contains(err.Error(), "unknown app 'Wiki'") {
+		t.Errorf("Expected an unknown app error, got %v", err)
+	}
+}
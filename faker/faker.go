@@ -0,0 +1,266 @@
+// Package faker generates realistic, reproducible fake data for seeding lab tables.
+package faker
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator produces one column's value for the row with the given number
+type Generator func(f *Faker, row int) interface{}
+
+// Faker generates values from a seeded source, so the same seed gives the same rows
+type Faker struct {
+	rand   *rand.Rand
+	person *person // Identity shared by name, username and email columns within a row
+}
+
+// person is the identity behind one generated row
+type person struct {
+	first, last string
+	username    string
+}
+
+// New creates a faker with the given seed
+func New(seed int64) *Faker {
+	return &Faker{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Rows generates count rows, numbering them from start. A nil generator leaves its column NULL.
+func (f *Faker) Rows(count, start int, generators []Generator) [][]interface{} {
+	rows := make([][]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		f.person = nil
+		row := make([]interface{}, len(generators))
+		for j, generate := range generators {
+			if generate != nil {
+				row[j] = generate(f, start+i)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Parse returns the generator for a column spec:
+//
+//	sequence          the row number
+//	faker.<kind>      a fake value, e.g. faker.email (see Kinds)
+//	one_of:a,b,c      one of the listed values
+func Parse(spec string) (Generator, error) {
+	switch {
+	case spec == "sequence":
+		return func(f *Faker, row int) interface{} { return row }, nil
+
+	case strings.HasPrefix(spec, "faker."):
+		kind := strings.TrimPrefix(spec, "faker.")
+		generate, ok := kinds[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown faker kind '%s' (valid: %s)", kind, strings.Join(Kinds(), ", "))
+		}
+		return func(f *Faker, row int) interface{} { return generate(f) }, nil
+
+	case strings.HasPrefix(spec, "one_of:"):
+		choices := strings.Split(strings.TrimPrefix(spec, "one_of:"), ",")
+		for i := range choices {
+			choices[i] = strings.TrimSpace(choices[i])
+		}
+		return func(f *Faker, row int) interface{} { return f.pick(choices) }, nil
+	}
+
+	return nil, fmt.Errorf("unknown generator '%s' (use sequence, faker.<kind> or one_of:a,b,c)", spec)
+}
+
+// Kinds returns the supported faker kinds in sorted order
+func Kinds() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// kinds maps faker.<kind> names to their generators
+var kinds = map[string]func(f *Faker) interface{}{
+	"first_name":    func(f *Faker) interface{} { return f.identity().first },
+	"last_name":     func(f *Faker) interface{} { return f.identity().last },
+	"name":          func(f *Faker) interface{} { return f.identity().first + " " + f.identity().last },
+	"username":      func(f *Faker) interface{} { return f.username() },
+	"email":         func(f *Faker) interface{} { return f.username() + "@" + f.pick(emailDomains) },
+	"password":      func(f *Faker) interface{} { return f.password() },
+	"password_hash": func(f *Faker) interface{} { return md5Hex(f.password()) },
+	"phone": func(f *Faker) interface{} {
+		return fmt.Sprintf("+1-%03d-%03d-%04d", f.between(201, 989), f.between(200, 999), f.rand.Intn(10000))
+	},
+	"ssn": func(f *Faker) interface{} {
+		return fmt.Sprintf("%03d-%02d-%04d", f.between(1, 665), f.between(1, 99), f.between(1, 9999))
+	},
+	"credit_card": func(f *Faker) interface{} { return f.creditCard() },
+	"address": func(f *Faker) interface{} {
+		return fmt.Sprintf("%d %s %s", f.between(1, 9999), f.pick(streets), f.pick(streetSuffixes))
+	},
+	"city":      func(f *Faker) interface{} { return f.pick(cities) },
+	"state":     func(f *Faker) interface{} { return f.pick(states) },
+	"zip":       func(f *Faker) interface{} { return fmt.Sprintf("%05d", f.between(1001, 99950)) },
+	"country":   func(f *Faker) interface{} { return f.pick(countries) },
+	"company":   func(f *Faker) interface{} { return f.pick(companyWords) + " " + f.pick(companySuffixes) },
+	"job_title": func(f *Faker) interface{} { return f.pick(jobTitles) },
+	"ipv4": func(f *Faker) interface{} {
+		return fmt.Sprintf("10.%d.%d.%d", f.rand.Intn(256), f.rand.Intn(256), f.between(1, 254))
+	},
+	"uuid":          func(f *Faker) interface{} { return f.uuid() },
+	"date":          func(f *Faker) interface{} { return f.date(0, 3) },
+	"date_of_birth": func(f *Faker) interface{} { return f.date(18, 80) },
+	"boolean":       func(f *Faker) interface{} { return f.rand.Intn(2) == 1 },
+	"integer":       func(f *Faker) interface{} { return f.between(0, 1000) },
+	"price":         func(f *Faker) interface{} { return float64(f.between(100, 99999)) / 100 },
+	"api_key":       func(f *Faker) interface{} { return "sk_live_" + f.hex(24) },
+	"word":          func(f *Faker) interface{} { return f.pick(words) },
+	"sentence":      func(f *Faker) interface{} { return f.sentence() },
+	"url":           func(f *Faker) interface{} { return "https://" + f.pick(companyWords) + ".example.com/" + f.pick(words) },
+}
+
+// identity returns the row's person, creating one on first use
+func (f *Faker) identity() *person {
+	if f.person == nil {
+		f.person = &person{first: f.pick(firstNames), last: f.pick(lastNames)}
+	}
+	return f.person
+}
+
+// username derives a login name from the row's person, the same one for the username and email columns
+func (f *Faker) username() string {
+	p := f.identity()
+	if p.username != "" {
+		return p.username
+	}
+
+	first, last := strings.ToLower(p.first), strings.ToLower(p.last)
+	switch f.rand.Intn(3) {
+	case 0:
+		p.username = first + "." + last
+	case 1:
+		p.username = first[:1] + last
+	default:
+		p.username = first + strconv.Itoa(f.between(1, 99))
+	}
+	return p.username
+}
+
+// password returns a weak, realistic password, so cracked hashes are satisfying
+func (f *Faker) password() string {
+	return f.pick(passwordBases) + strconv.Itoa(f.between(1, 2024))
+}
+
+// creditCard returns a Luhn-valid 16 digit card number in the 4xxx test range
+func (f *Faker) creditCard() string {
+	digits := make([]int, 16)
+	digits[0] = 4
+	for i := 1; i < 15; i++ {
+		digits[i] = f.rand.Intn(10)
+	}
+
+	// Luhn check digit: double every second digit counting from the check digit
+	sum := 0
+	for i := 14; i >= 0; i-- {
+		d := digits[i]
+		if (14-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	digits[15] = (10 - sum%10) % 10
+
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(byte('0' + d))
+	}
+	return sb.String()
+}
+
+// uuid returns a random version 4 UUID
+func (f *Faker) uuid() string {
+	b := make([]byte, 16)
+	f.rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// date returns a YYYY-MM-DD date between minYears and maxYears before a fixed
+// reference date, so output doesn't change from day to day
+func (f *Faker) date(minYears, maxYears int) string {
+	reference := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := f.between(minYears*365, maxYears*365)
+	return reference.AddDate(0, 0, -days).Format("2006-01-02")
+}
+
+// sentence returns a short sentence of filler words
+func (f *Faker) sentence() string {
+	parts := make([]string, f.between(4, 9))
+	for i := range parts {
+		parts[i] = f.pick(words)
+	}
+	s := strings.Join(parts, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// hex returns n random hex characters
+func (f *Faker) hex(n int) string {
+	b := make([]byte, (n+1)/2)
+	f.rand.Read(b)
+	return hex.EncodeToString(b)[:n]
+}
+
+// between returns a random int in [min, max]
+func (f *Faker) between(min, max int) int {
+	return min + f.rand.Intn(max-min+1)
+}
+
+// pick returns a random element
+func (f *Faker) pick(values []string) string {
+	return values[f.rand.Intn(len(values))]
+}
+
+// md5Hex hashes a password the way legacy apps store them
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	firstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+		"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Carlos", "Karen",
+		"Priya", "Wei", "Fatima", "Olga", "Kenji", "Amara", "Lucas", "Sofia", "Mateo", "Aisha",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Hernandez", "Lopez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin", "Lee",
+		"Patel", "Chen", "Khan", "Ivanova", "Tanaka", "Okafor", "Silva", "Rossi", "Nguyen", "Cohen",
+	}
+	emailDomains    = []string{"gmail.com", "yahoo.com", "outlook.com", "proton.me", "example.com", "corp.local"}
+	passwordBases   = []string{"password", "Summer", "Winter", "welcome", "qwerty", "letmein", "dragon", "monkey", "Company", "iloveyou"}
+	streets         = []string{"Main", "Oak", "Pine", "Maple", "Cedar", "Elm", "Washington", "Lake", "Hill", "Park", "Sunset", "Lincoln"}
+	streetSuffixes  = []string{"St", "Ave", "Blvd", "Rd", "Ln", "Dr", "Ct", "Way"}
+	cities          = []string{"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison", "Georgetown", "Arlington", "Ashland"}
+	states          = []string{"CA", "TX", "NY", "FL", "IL", "PA", "OH", "GA", "NC", "MI", "WA", "AZ", "MA", "CO"}
+	countries       = []string{"United States", "United Kingdom", "Canada", "Germany", "France", "India", "Japan", "Brazil", "Australia", "Nigeria", "Mexico", "Spain"}
+	companyWords    = []string{"Acme", "Globex", "Initech", "Umbrella", "Stark", "Wayne", "Hooli", "Vandelay", "Soylent", "Cyberdyne", "Tyrell", "Wonka"}
+	companySuffixes = []string{"Inc", "LLC", "Corp", "Group", "Holdings", "Labs", "Systems"}
+	jobTitles       = []string{"Software Engineer", "Accountant", "Sales Manager", "HR Specialist", "Support Agent", "Data Analyst", "CFO", "System Administrator", "Intern", "Product Manager", "Security Analyst", "Office Manager"}
+	words           = []string{"account", "invoice", "order", "report", "secret", "payment", "customer", "delivery", "update", "review", "project", "meeting", "budget", "access", "token", "backup", "internal", "draft", "quarterly", "refund"}
+)
@@ -0,0 +1,92 @@
+package faker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generators parses column specs, failing the test on error
+func generators(t *testing.T, specs ...string) []Generator {
+	t.Helper()
+
+	gens := make([]Generator, len(specs))
+	for i, spec := range specs {
+		gen, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", spec, err)
+		}
+		gens[i] = gen
+	}
+	return gens
+}
+
+// TestRows_Deterministic tests the same seed gives the same rows
+func TestRows_Deterministic(t *testing.T) {
+	gens := generators(t, "sequence", "faker.email", "faker.ssn", "one_of:admin,user")
+
+	first := New(42).Rows(20, 1, gens)
+	second := New(42).Rows(20, 1, gens)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected identical rows for the same seed")
+	}
+	if reflect.DeepEqual(first, New(7).Rows(20, 1, gens)) {
+		t.Error("Expected different rows for a different seed")
+	}
+
+	for i, row := range first {
+		if row[0] != i+1 {
+			t.Errorf("Expected sequence %d, got %v", i+1, row[0])
+		}
+		if role := row[3]; role != "admin" && role != "user" {
+			t.Errorf("Unexpected one_of value %v", role)
+		}
+	}
+}
+
+// TestRows_SharedIdentity tests name, username and email describe the same person
+func TestRows_SharedIdentity(t *testing.T) {
+	rows := New(1).Rows(50, 1, generators(t, "faker.first_name", "faker.last_name", "faker.username", "faker.email"))
+
+	for _, row := range rows {
+		first := strings.ToLower(row[0].(string))
+		username, email := row[2].(string), row[3].(string)
+		if !strings.HasPrefix(email, username+"@") {
+			t.Errorf("Expected email %q to use username %q", email, username)
+		}
+		if !strings.HasPrefix(username, first[:1]) {
+			t.Errorf("Expected username %q to come from %q", username, row[0])
+		}
+	}
+}
+
+// TestCreditCard_Luhn tests generated card numbers pass the Luhn check
+func TestCreditCard_Luhn(t *testing.T) {
+	f := New(3)
+	for i := 0; i < 100; i++ {
+		number := strings.ReplaceAll(f.creditCard(), "-", "")
+		sum := 0
+		for j := range number {
+			d := int(number[len(number)-1-j] - '0')
+			if j%2 == 1 {
+				d *= 2
+				if d > 9 {
+					d -= 9
+				}
+			}
+			sum += d
+		}
+		if len(number) != 16 || sum%10 != 0 {
+			t.Fatalf("Invalid card number %s", number)
+		}
+	}
+}
+
+// TestParse_Errors tests unknown generators are rejected
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{"faker.nope", "random", ""} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/RIZZZIOM/FlawFactory
 
-go 1.22.0
+go 1.24.0
 
 require (
 	gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// initStarter is the endpoint init writes for a module: a small, unfiltered
+// example of the flaw, like the beginner preset's. Endpoint is its YAML under
+// endpoints:, with {port} standing for the app's port in the curl hint.
+type initStarter struct {
+	Module   string
+	Endpoint string
+	Users    bool // Reads the users table
+	Files    bool // Reads the files under public/
+}
+
+// initStarters are the modules init offers, in the order it lists them. The
+// other modules need more setup than a question can cover; templates/ has a
+// lab for each.
+var initStarters = []initStarter{
+	{Module: "sql_injection", Users: true, Endpoint: `  # SQL injection → curl "http://localhost:{port}/user?id=1%20OR%201=1"
+  - path: /user
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: sql_injection
+        placement: query_param
+        param: id
+        config:
+          variant: error_based
+          query_template: "SELECT username, email FROM users WHERE id = {input}"
+`},
+	{Module: "xss_reflected", Endpoint: `  # reflected XSS → curl "http://localhost:{port}/search?q=<script>alert(1)</script>"
+  - path: /search
+    method: GET
+    response_type: html
+    vulnerabilities:
+      - type: xss_reflected
+        placement: query_param
+        param: q
+        config:
+          context: body
+`},
+	{Module: "path_traversal", Files: true, Endpoint: `  # path traversal → curl "http://localhost:{port}/read?file=../flag.txt"
+  - path: /read
+    method: GET
+    response_type: text
+    vulnerabilities:
+      - type: path_traversal
+        placement: query_param
+        param: file
+        config:
+          base_path: "public"
+          filter: none
+`},
+	{Module: "command_injection", Endpoint: `  # command injection → curl "http://localhost:{port}/ping?host=127.0.0.1;id"
+  - path: /ping
+    method: GET
+    response_type: text
+    vulnerabilities:
+      - type: command_injection
+        placement: query_param
+        param: host
+        config:
+          base_command: "ping -c 1 {input}"
+          filter: none
+`},
+	{Module: "idor", Users: true, Endpoint: `  # other users' profiles → curl "http://localhost:{port}/profile/2"
+  - path: /profile/{id}
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: idor
+        placement: path_param
+        param: id
+        config:
+          variant: numeric
+          query_template: "SELECT id, username, email FROM users WHERE id = {input}"
+`},
+	{Module: "ssrf", Endpoint: `  # SSRF → curl "http://localhost:{port}/fetch?url=http://example.com"
+  - path: /fetch
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: ssrf
+        placement: query_param
+        param: url
+        config:
+          filter: none
+`},
+	{Module: "ssti", Endpoint: `  # template injection → curl -G "http://localhost:{port}/greet" --data-urlencode "name={{7*'7'}}"
+  - path: /greet
+    method: GET
+    response_type: html
+    vulnerabilities:
+      - type: ssti
+        placement: query_param
+        param: name
+        config:
+          engine: jinja2
+`},
+	{Module: "xxe", Endpoint: `  # XXE → curl "http://localhost:{port}/import" -X POST --data-urlencode 'xml=<!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><foo>&xxe;</foo>'
+  - path: /import
+    method: POST
+    response_type: json
+    vulnerabilities:
+      - type: xxe
+        placement: form_field
+        param: xml
+        config:
+          filter: none
+          emulate_resolution: true
+          allow_file_read: true
+`},
+	{Module: "insecure_deserialization", Endpoint: `  # insecure deserialization → curl "http://localhost:{port}/restore?data=rO0ABXNyABFqYXZhLnV0aWwuSGFzaE1hcA"
+  - path: /restore
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: insecure_deserialization
+        placement: query_param
+        param: data
+        config:
+          format: java
+          emulate_execution: true
+`},
+	{Module: "nosql_injection", Endpoint: `  # NoSQL injection → curl 'http://localhost:{port}/accounts?filter={"$ne":""}'
+  - path: /accounts
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: nosql_injection
+        placement: query_param
+        param: filter
+        config:
+          database: mongodb
+          collection: users
+          operation: find
+          show_errors: true
+`},
+	{Module: "excessive_data_exposure", Users: true, Endpoint: `  # every column of every user → curl "http://localhost:{port}/api/users"
+  - path: /api/users
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: excessive_data_exposure
+        placement: query_param
+        param: q
+        config:
+          filter_level: none
+          query_template: "SELECT * FROM users WHERE username LIKE '%{input}%'"
+`},
+	{Module: "bfla", Users: true, Endpoint: `  # admin function without a check → curl "http://localhost:{port}/api/admin/users/3" -X DELETE
+  - path: /api/admin/users/{id}
+    method: DELETE
+    response_type: json
+    vulnerabilities:
+      - type: bfla
+        placement: path_param
+        param: id
+        config:
+          function: admin
+          auth_check: none
+          query_template: "DELETE FROM users WHERE id = {input}"
+          result_query: "SELECT id, username, role FROM users"
+`},
+	{Module: "account_enumeration", Users: true, Endpoint: `  # account enumeration → curl "http://localhost:{port}/login" -X POST -d "username=rick&password=x"
+  - path: /login
+    method: POST
+    response_type: json
+    vulnerabilities:
+      - type: account_enumeration
+        placement: form_field
+        param: username
+        config:
+          flow: login
+          discrepancy: message
+          query_template: "SELECT * FROM users WHERE username = '{input}'"
+`},
+	{Module: "insecure_cookies", Users: true, Endpoint: `  # guessable session cookie → curl -i "http://localhost:{port}/session" -X POST -d "username=rick"
+  - path: /session
+    method: POST
+    response_type: json
+    vulnerabilities:
+      - type: insecure_cookies
+        placement: form_field
+        param: username
+        config:
+          mode: issue
+          session_value: base64_id
+          query_template: "SELECT id, username, role FROM users WHERE username = '{input}'"
+`},
+	{Module: "method_override", Users: true, Endpoint: `  # method override → curl "http://localhost:{port}/users?id=2" -H "X-HTTP-Method-Override: PUT"
+  - path: /users
+    method: GET
+    response_type: json
+    vulnerabilities:
+      - type: method_override
+        placement: query_param
+        param: id
+        config:
+          channel: all
+          query_template: "SELECT * FROM users WHERE id = {input}"
+          actions:
+            PUT: "UPDATE users SET role = 'admin' WHERE id = {input}"
+            DELETE: "DELETE FROM users WHERE id = {input}"
+`},
+}
+
+// initDefaultModules are picked when the modules question is left blank
+var initDefaultModules = []string{"sql_injection", "xss_reflected", "path_traversal", "command_injection", "idor"}
+
+// initAnswers is what init asks for
+type initAnswers struct {
+	Name    string
+	Port    int
+	Modules []string // Names of initStarters, in their order
+	Seed    bool     // Fill the users table with sample accounts
+}
+
+func initCommand() {
+	initFlags := flag.NewFlagSet("init", flag.ExitOnError)
+	output := initFlags.String("output", "config.yaml", "Write the config to this file")
+	outputShort := initFlags.String("o", "", "Write the config to this file (shorthand)")
+	name := initFlags.String("name", "", "App name, in place of asking")
+	port := initFlags.Int("port", 0, "Port, in place of asking")
+	portShort := initFlags.Int("p", 0, "Port, in place of asking (shorthand)")
+	moduleList := initFlags.String("modules", "", "Comma-separated modules, or all, in place of asking")
+	yes := initFlags.Bool("yes", false, "Take the default for every question not answered by a flag")
+	force := initFlags.Bool("force", false, "Overwrite the output file if it exists")
+
+	initFlags.Parse(os.Args[2:])
+
+	path := *output
+	if *outputShort != "" {
+		path = *outputShort
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Printf("\n  %s✗ Error:%s %s already exists (use --force to overwrite it)\n\n", colorRed, colorReset, path)
+		os.Exit(1)
+	}
+
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, defaults: *yes}
+	answers := initAnswers{Name: *name, Port: *port}
+	if answers.Port == 0 {
+		answers.Port = *portShort
+	}
+	if *moduleList != "" {
+		picked, err := parseInitModules(*moduleList)
+		if err != nil {
+			fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+			os.Exit(1)
+		}
+		answers.Modules = picked
+	}
+
+	fmt.Println()
+	fmt.Printf("  %sNew FlawFactory lab%s %s(press Enter to take the default in brackets)%s\n\n", colorPurple+colorBold, colorReset, colorDim, colorReset)
+	askInit(p, &answers)
+
+	data := renderInitConfig(answers, path)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	if _, err := config.LoadStrict(path, nil); err != nil {
+		printConfigError(path, err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s✓ Wrote%s %s with %d endpoint%s\n", colorGreen, colorReset, path, len(answers.Modules), pluralize(len(answers.Modules)))
+	fmt.Println()
+	fmt.Printf("    $ flawfactory %srun%s -c %s%s%s\n", colorGreen, colorReset, colorCyan, path, colorReset)
+	fmt.Println()
+	fmt.Printf("  %sEach endpoint has a curl command to try in a comment above it.%s\n", colorDim, colorReset)
+	fmt.Println()
+}
+
+// askInit asks for each answer not already given
+func askInit(p *prompter, answers *initAnswers) {
+	if answers.Name == "" {
+		answers.Name = p.ask("App name", "My Vulnerable Lab")
+	}
+	for answers.Port == 0 {
+		port, err := strconv.Atoi(p.ask("Port", "8080"))
+		if err != nil || port < 1 || port > 65535 {
+			fmt.Fprintf(p.out, "    %sEnter a port between 1 and 65535%s\n", colorYellow, colorReset)
+			continue
+		}
+		answers.Port = port
+	}
+
+	for answers.Modules == nil {
+		descriptions := make(map[string]string)
+		for _, info := range modules.List() {
+			descriptions[info.Name] = info.Description
+		}
+		fmt.Fprintf(p.out, "\n  %sModules%s\n", colorYellow, colorReset)
+		for i, starter := range initStarters {
+			fmt.Fprintf(p.out, "    %s%2d%s %s%-25s%s %s%s%s\n", colorCyan, i+1, colorReset, colorGreen, starter.Module, colorReset, colorDim, descriptions[starter.Module], colorReset)
+		}
+		fmt.Fprintf(p.out, "    %sMore modules, with more setup, have example labs in templates/%s\n\n", colorDim, colorReset)
+		picked, err := parseInitModules(p.ask("Modules, by number or name, comma-separated, or all", strings.Join(initDefaultModules, ",")))
+		if err != nil {
+			fmt.Fprintf(p.out, "    %s%v%s\n", colorYellow, err, colorReset)
+			continue
+		}
+		answers.Modules = picked
+	}
+
+	if slices.ContainsFunc(answers.Modules, func(module string) bool { return initStarterFor(module).Users }) {
+		answers.Seed = p.confirm("Seed the users table with sample accounts", true)
+	}
+}
+
+// parseInitModules reads a list of modules, by number in initStarters or by
+// name, and returns their names in initStarters' order
+func parseInitModules(list string) ([]string, error) {
+	if strings.TrimSpace(strings.ToLower(list)) == "all" {
+		names := make([]string, len(initStarters))
+		for i, starter := range initStarters {
+			names[i] = starter.Module
+		}
+		return names, nil
+	}
+
+	picked := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(item); err == nil {
+			if n < 1 || n > len(initStarters) {
+				return nil, fmt.Errorf("there is no module %d (pick 1 to %d)", n, len(initStarters))
+			}
+			picked[initStarters[n-1].Module] = true
+			continue
+		}
+		if initStarterFor(item).Module == "" {
+			return nil, fmt.Errorf("init has no starter endpoint for module %q; templates/ has example labs for the others", item)
+		}
+		picked[item] = true
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("pick at least one module")
+	}
+
+	var names []string
+	for _, starter := range initStarters {
+		if picked[starter.Module] {
+			names = append(names, starter.Module)
+		}
+	}
+	return names, nil
+}
+
+// initStarterFor returns a module's starter, or a zero one if init has none
+func initStarterFor(module string) initStarter {
+	for _, starter := range initStarters {
+		if starter.Module == module {
+			return starter
+		}
+	}
+	return initStarter{}
+}
+
+// renderInitConfig writes the YAML config for a set of answers, to be saved
+// at path, laid out like the presets
+func renderInitConfig(answers initAnswers, path string) []byte {
+	var users, files bool
+	for _, module := range answers.Modules {
+		starter := initStarterFor(module)
+		users = users || starter.Users
+		files = files || starter.Files
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by flawfactory init\n#\n")
+	fmt.Fprintf(&b, "# flawfactory run -c %s\n\n", path)
+	b.WriteString("app:\n")
+	fmt.Fprintf(&b, "  name: %s\n", strconv.Quote(answers.Name))
+	b.WriteString("  host: \"0.0.0.0\"\n")
+	fmt.Fprintf(&b, "  port: %d\n", answers.Port)
+
+	if users {
+		b.WriteString("\ndata:\n  tables:\n    users:\n")
+		b.WriteString("      columns: [id, username, password, email, role]\n")
+		if answers.Seed {
+			b.WriteString("      rows:\n")
+			b.WriteString("        - [1, \"admin\", \"adminpass\", \"admin@example.com\", \"admin\"]\n")
+			b.WriteString("        - [2, \"rick\", \"c137\", \"rick@example.com\", \"user\"]\n")
+			b.WriteString("        - [3, \"morty\", \"sidekick\", \"morty@example.com\", \"user\"]\n")
+		} else {
+			b.WriteString("      rows: []\n")
+		}
+	}
+	if files {
+		b.WriteString("\nfiles:\n")
+		b.WriteString("  - path: public/welcome.txt\n    content: \"Welcome! Can you read the file next to this directory?\"\n")
+		b.WriteString("  - path: flag.txt\n    content: \"FLAG{path_traversal}\"\n")
+	}
+
+	b.WriteString("\nendpoints:\n")
+	for i, module := range answers.Modules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.ReplaceAll(initStarterFor(module).Endpoint, "{port}", strconv.Itoa(answers.Port)))
+	}
+	return []byte(b.String())
+}
+
+// prompter asks questions on in and reads the answers, or takes every
+// default when defaults is set or in runs out
+type prompter struct {
+	in       *bufio.Reader
+	out      io.Writer
+	defaults bool
+}
+
+// ask returns the answer to a question, or def when it's left blank
+func (p *prompter) ask(question, def string) string {
+	if def == "" {
+		fmt.Fprintf(p.out, "  %s?%s %s: ", colorCyan, colorReset, question)
+	} else {
+		fmt.Fprintf(p.out, "  %s?%s %s %s[%s]%s: ", colorCyan, colorReset, question, colorDim, def, colorReset)
+	}
+	if p.defaults {
+		fmt.Fprintln(p.out, def)
+		return def
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		// Nothing more to read, so the rest of the questions take their defaults
+		p.defaults = true
+		fmt.Fprintln(p.out, def)
+		return def
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "no"
+	if def {
+		hint = "yes"
+	}
+	for {
+		switch strings.ToLower(p.ask(question, hint)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
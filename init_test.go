@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/modules"
+)
+
+// TestInitStarters tests every starter is a registered module, and a config
+// with all of them, seeded or not, passes strict validation
+func TestInitStarters(t *testing.T) {
+	for _, starter := range initStarters {
+		if _, err := modules.Get(starter.Module); err != nil {
+			t.Errorf("Starter for unknown module %s", starter.Module)
+		}
+	}
+
+	all, err := parseInitModules("all")
+	if err != nil {
+		t.Fatalf("Expected every module, got %v", err)
+	}
+	for _, seed := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "lab.yaml")
+		data := renderInitConfig(initAnswers{Name: `Rick's "Lab"`, Port: 9090, Modules: all, Seed: seed}, path)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		cfg, err := config.LoadStrict(path, nil)
+		if err != nil {
+			t.Fatalf("Expected a valid config (seed %v), got %v\n%s", seed, err, data)
+		}
+		if cfg.App.Name != `Rick's "Lab"` || cfg.App.Port != 9090 || len(cfg.Endpoints) != len(initStarters) {
+			t.Errorf("Expected the answers in the config, got %q on %d with %d endpoints", cfg.App.Name, cfg.App.Port, len(cfg.Endpoints))
+		}
+		if rows := len(cfg.Data.Tables["users"].Rows); seed != (rows > 0) {
+			t.Errorf("Expected seeded users only when asked (seed %v), got %d rows", seed, rows)
+		}
+	}
+}
+
+// TestInitQuestions tests answers are read, blank ones take the defaults, and
+// invalid ones are asked again
+func TestInitQuestions(t *testing.T) {
+	var out bytes.Buffer
+	p := &prompter{in: bufio.NewReader(strings.NewReader("Demo\nabc\n8081\n99\n2, sql_injection\nn\n")), out: &out}
+	var answers initAnswers
+	askInit(p, &answers)
+	if answers.Name != "Demo" || answers.Port != 8081 || answers.Seed {
+		t.Errorf("Expected Demo on 8081 unseeded, got %+v", answers)
+	}
+	if !slices.Equal(answers.Modules, []string{"sql_injection", "xss_reflected"}) {
+		t.Errorf("Expected modules in starter order, got %v", answers.Modules)
+	}
+
+	// Running out of input takes every default
+	p = &prompter{in: bufio.NewReader(strings.NewReader("")), out: &out}
+	answers = initAnswers{}
+	askInit(p, &answers)
+	if answers.Name != "My Vulnerable Lab" || answers.Port != 8080 || !answers.Seed || !slices.Equal(answers.Modules, initDefaultModules) {
+		t.Errorf("Expected the defaults, got %+v", answers)
+	}
+
+	if _, err := parseInitModules("ldap_injection"); err == nil {
+		t.Error("Expected an error for a module without a starter")
+	}
+}
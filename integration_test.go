@@ -540,15 +540,8 @@ func TestIntegration_ExtractorJSONBody(t *testing.T) {
 	extractor := server.NewExtractor()
 
 	router.HandleFunc("POST", "/data", func(w http.ResponseWriter, r *http.Request) {
-		// Read body once and parse JSON manually since extractor consumes the body
-		bodyBytes, _ := io.ReadAll(r.Body)
-
-		// Create a new reader for each extraction by restoring the body
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		// The router buffers the body, so each field is extracted from the same one
 		name, _ := extractor.Extract(r, "json_field", "user.name")
-
-		// Restore body again for second extraction
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 		email, _ := extractor.Extract(r, "json_field", "user.email")
 
 		json.NewEncoder(w).Encode(map[string]string{
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// maxAttacks bounds the attacks an entry records, as a WebSocket connection
+// logged once can carry any number of messages
+const maxAttacks = 64
+
+// Attack labels an attack a request made on one of the lab's vulnerabilities,
+// so logs double as ground truth for WAF rules and detectors
+type Attack struct {
+	Module      string  `json:"module"`
+	Type        string  `json:"attack_type"` // As the module reported it, or as its payload matched
+	Param       string  `json:"param,omitempty"`
+	Placement   string  `json:"placement,omitempty"`
+	Exploitable bool    `json:"exploitable"` // The module reported the attack worked
+	Score       float64 `json:"score"`       // 0 to 10, banded as CVSS base scores
+	Severity    string  `json:"severity"`    // low, medium, high or critical
+	Payload     string  `json:"payload"`     // The part of the input that matched
+}
+
+// Severity names the CVSS band of a score: none, low, medium, high or critical
+func Severity(score float64) string {
+	switch {
+	case score >= 9:
+		return "critical"
+	case score >= 7:
+		return "high"
+	case score >= 4:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return "none"
+}
+
+// attacksKey is the context key of the attacks classified while a request is served
+type attacksKey struct{}
+
+// attackRecord collects the attacks of one request. Handlers add to it while
+// the router, which logs the request, holds it.
+type attackRecord struct {
+	mu      sync.Mutex
+	attacks []Attack
+}
+
+// WithAttacks returns a request its handlers can Classify attacks on, for its
+// log entry. The router does this for every request.
+func WithAttacks(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(attacksKey{}).(*attackRecord); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), attacksKey{}, &attackRecord{}))
+}
+
+// Classify records attacks on the request, for its log entry. Requests not
+// passed through WithAttacks record nothing.
+func Classify(r *http.Request, attacks ...Attack) {
+	record, ok := r.Context().Value(attacksKey{}).(*attackRecord)
+	if !ok {
+		return
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	for _, attack := range attacks {
+		if len(record.attacks) >= maxAttacks {
+			return
+		}
+		record.attacks = append(record.attacks, attack)
+	}
+}
+
+// attacksOf returns the attacks classified on a request
+func attacksOf(r *http.Request) []Attack {
+	record, ok := r.Context().Value(attacksKey{}).(*attackRecord)
+	if !ok {
+		return nil
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	return append([]Attack(nil), record.attacks...)
+}
+
+// worstAttack returns the entry's highest scored attack, the first of equals,
+// or nil for none
+func (entry *RequestLog) worstAttack() *Attack {
+	var worst *Attack
+	for i, attack := range entry.Attacks {
+		if worst == nil || attack.Score > worst.Score {
+			worst = &entry.Attacks[i]
+		}
+	}
+	return worst
+}
+
+// maxScore returns the score of the entry's worst attack, 0 for none
+func (entry *RequestLog) maxScore() float64 {
+	if worst := entry.worstAttack(); worst != nil {
+		return worst.Score
+	}
+	return 0
+}
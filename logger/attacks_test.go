@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClassify tests attacks classified on a request reach its log entry
+func TestClassify(t *testing.T) {
+	req := httptest.NewRequest("GET", "/search?q=1", nil)
+	Classify(req, Attack{Module: "sql_injection"}) // Without WithAttacks it's dropped
+	if entry := NewRequestLog(req, 200, time.Millisecond, 0); entry.Attacks != nil {
+		t.Errorf("Expected no attacks, got %+v", entry.Attacks)
+	}
+
+	req = WithAttacks(req)
+	handled := req.WithContext(req.Context()) // Handlers see a request derived from the router's
+	for i := 0; i < maxAttacks+1; i++ {
+		Classify(handled, Attack{Module: "sql_injection", Score: 9.8})
+	}
+	entry := NewRequestLog(req, 200, time.Millisecond, 0)
+	if len(entry.Attacks) != maxAttacks {
+		t.Errorf("Expected %d attacks, got %d", maxAttacks, len(entry.Attacks))
+	}
+}
+
+// TestSeverity tests scores are named by their CVSS band
+func TestSeverity(t *testing.T) {
+	for score, expected := range map[float64]string{0: "none", 3.9: "low", 4: "medium", 7.5: "high", 9.8: "critical"} {
+		if got := Severity(score); got != expected {
+			t.Errorf("%.1f: expected %s, got %s", score, expected, got)
+		}
+	}
+}
+
+// TestFormatters_Attacks tests each format carries an entry's attacks
+func TestFormatters_Attacks(t *testing.T) {
+	entry := testEntry()
+	entry.StatusCode = 200
+	entry.Attacks = []Attack{
+		{Module: "xss_reflected", Type: "xss_reflected", Score: 6.1, Severity: "medium", Payload: "<script>"},
+		{Module: "sql_injection", Type: "sql_injection", Score: 9.8, Severity: "critical", Payload: "1 OR 1=1"},
+	}
+
+	line, _ := JSONFormatter{}.Format(entry)
+	if !strings.Contains(string(line), `"attacks":[{"module":"xss_reflected","attack_type":"xss_reflected"`) {
+		t.Errorf("Expected the attacks in the JSON entry, got %s", line)
+	}
+
+	line, _ = CEFFormatter{}.Format(entry)
+	for _, expected := range []string{"|POST /search\\|x|10|", `cs2=sql_injection cs2Label=attackType cs3=1 OR 1\=1`, "cfp1=9.8 cfp1Label=severityScore"} {
+		if !strings.Contains(string(line), expected) {
+			t.Errorf("Expected %s in %s", expected, line)
+		}
+	}
+
+	line, _ = ECSFormatter{}.Format(entry)
+	var doc struct {
+		Event struct {
+			Kind      string  `json:"kind"`
+			RiskScore float64 `json:"risk_score"`
+		} `json:"event"`
+		FlawFactory struct {
+			Attacks []Attack `json:"attacks"`
+		} `json:"flawfactory"`
+	}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatalf("Expected a JSON document, got %s", line)
+	}
+	if doc.Event.Kind != "alert" || doc.Event.RiskScore != 9.8 || len(doc.FlawFactory.Attacks) != 2 {
+		t.Errorf("Expected an alert scored 9.8 with both attacks, got %s", line)
+	}
+}
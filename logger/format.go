@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formatter turns a request log entry into one line of a log format
+type Formatter interface {
+	Format(entry *RequestLog) ([]byte, error)
+}
+
+// productVersion is the FlawFactory version CEF and ECS entries report
+const productVersion = "1.0"
+
+// formatters are the formats by the names app.logging.format takes
+var formatters = map[string]Formatter{
+	"json": JSONFormatter{},
+	"cef":  CEFFormatter{},
+	"ecs":  ECSFormatter{},
+}
+
+// NewFormatter returns the formatter of a log format: json, cef or ecs
+func NewFormatter(format string) (Formatter, error) {
+	formatter, ok := formatters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown log format '%s'", format)
+	}
+	return formatter, nil
+}
+
+// JSONFormatter writes entries as JSON lines, FlawFactory's own format
+type JSONFormatter struct{}
+
+// Format encodes the entry as a JSON object
+func (JSONFormatter) Format(entry *RequestLog) ([]byte, error) {
+	return json.Marshal(entry)
+}
+
+// CEFFormatter writes entries in ArcSight's Common Event Format, which most
+// SIEMs ingest from files and syslog
+type CEFFormatter struct{}
+
+// cefEscaper escapes the header fields of a CEF line
+var cefEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+
+// cefValueEscaper escapes the values of a CEF line's extension
+var cefValueEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+
+// Format encodes the entry as a CEF line. Its severity follows the status:
+// 3 for successes and redirects, 5 for client errors and 7 for server errors,
+// raised to the score of the worst attack the request made.
+func (CEFFormatter) Format(entry *RequestLog) ([]byte, error) {
+	severity := 3
+	switch {
+	case entry.StatusCode >= 500:
+		severity = 7
+	case entry.StatusCode >= 400:
+		severity = 5
+	}
+	severity = max(severity, int(math.Ceil(entry.maxScore())))
+
+	ip, port := splitHostPort(entry.RemoteAddr)
+	dhost, _ := splitHostPort(entry.Host)
+	extension := [][2]string{
+		{"rt", strconv.FormatInt(entry.Time.UnixMilli(), 10)},
+		{"externalId", entry.RequestID},
+		{"src", ip},
+		{"spt", port},
+		{"dhost", dhost},
+		{"requestMethod", entry.Method},
+		{"request", entry.url()},
+		{"requestClientApplication", entry.Headers["User-Agent"]},
+		{"requestCookies", entry.Headers["Cookie"]},
+		{"app", entry.Proto},
+		{"outcome", strconv.Itoa(entry.StatusCode)},
+		{"in", strconv.Itoa(len(entry.Body))},
+		{"out", strconv.FormatInt(entry.ContentLength, 10)},
+		{"cn1", strconv.FormatInt(entry.Duration.Milliseconds(), 10)},
+		{"cn1Label", "responseTimeMs"},
+		{"cs1", entry.Body},
+		{"cs1Label", "requestBody"},
+		{"cs5", entry.ResponseBody},
+		{"cs5Label", "responseBody"},
+	}
+	if worst := entry.worstAttack(); worst != nil {
+		extension = append(extension,
+			[2]string{"cs2", worst.Type},
+			[2]string{"cs2Label", "attackType"},
+			[2]string{"cs3", worst.Payload},
+			[2]string{"cs3Label", "attackPayload"},
+			[2]string{"cs4", worst.Module},
+			[2]string{"cs4Label", "module"},
+			[2]string{"cfp1", strconv.FormatFloat(worst.Score, 'f', 1, 64)},
+			[2]string{"cfp1Label", "severityScore"},
+		)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|FlawFactory|FlawFactory|%s|%d|%s|%d|",
+		cefEscaper.Replace(productVersion), entry.StatusCode, cefEscaper.Replace(entry.Method+" "+entry.Path), severity)
+	first := true
+	for _, field := range extension {
+		if field[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(field[0] + "=" + cefValueEscaper.Replace(field[1]))
+	}
+	return []byte(b.String()), nil
+}
+
+// ECSFormatter writes entries as JSON lines in the Elastic Common Schema, for
+// Elasticsearch and the SIEMs that read it
+type ECSFormatter struct{}
+
+// ecsVersion is the Elastic Common Schema version the entries follow
+const ecsVersion = "8.11.0"
+
+// Format encodes the entry as an ECS document. Request headers, query
+// parameters and attacks, which ECS has no fields for, go under flawfactory;
+// a request that made attacks is an alert, its risk score the worst one's.
+func (ECSFormatter) Format(entry *RequestLog) ([]byte, error) {
+	outcome := "success"
+	if entry.StatusCode >= 400 {
+		outcome = "failure"
+	}
+
+	request := map[string]interface{}{
+		"method": entry.Method,
+		"body":   map[string]interface{}{"bytes": len(entry.Body)},
+	}
+	if entry.Body != "" {
+		request["body"].(map[string]interface{})["content"] = entry.Body
+	}
+	if referrer := entry.Headers["Referer"]; referrer != "" {
+		request["referrer"] = referrer
+	}
+	if entry.RequestID != "" {
+		request["id"] = entry.RequestID
+	}
+
+	response := map[string]interface{}{
+		"status_code": entry.StatusCode,
+		"body":        map[string]interface{}{"bytes": entry.ContentLength},
+	}
+	if entry.ResponseBody != "" {
+		response["body"].(map[string]interface{})["content"] = entry.ResponseBody
+	}
+
+	urlFields := map[string]interface{}{
+		"path":     entry.Path,
+		"original": entry.url(),
+	}
+	if domain, port := splitHostPort(entry.Host); domain != "" {
+		urlFields["domain"] = domain
+		if n, err := strconv.Atoi(port); err == nil {
+			urlFields["port"] = n
+		}
+	}
+	if entry.RawQuery != "" {
+		urlFields["query"] = entry.RawQuery
+	}
+
+	ip, port := splitHostPort(entry.RemoteAddr)
+	source := map[string]interface{}{"address": ip}
+	if net.ParseIP(ip) != nil {
+		source["ip"] = ip
+	}
+	if n, err := strconv.Atoi(port); err == nil {
+		source["port"] = n
+	}
+
+	doc := map[string]interface{}{
+		"@timestamp": entry.Time.UTC().Format(time.RFC3339Nano),
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+		"event": map[string]interface{}{
+			"kind":     "event",
+			"category": []string{"web"},
+			"type":     []string{"access"},
+			"outcome":  outcome,
+			"duration": entry.Duration.Nanoseconds(),
+			"dataset":  "flawfactory.requests",
+		},
+		"http": map[string]interface{}{
+			"version":  strings.TrimPrefix(entry.Proto, "HTTP/"),
+			"request":  request,
+			"response": response,
+		},
+		"url":    urlFields,
+		"source": source,
+		"service": map[string]interface{}{
+			"name":    "flawfactory",
+			"version": productVersion,
+		},
+		"flawfactory": map[string]interface{}{
+			"headers":      entry.Headers,
+			"query_params": entry.QueryParams,
+		},
+	}
+	if len(entry.Attacks) > 0 {
+		event := doc["event"].(map[string]interface{})
+		event["kind"] = "alert"
+		event["category"] = []string{"web", "intrusion_detection"}
+		event["risk_score"] = entry.maxScore()
+		doc["flawfactory"].(map[string]interface{})["attacks"] = entry.Attacks
+	}
+	if agent := entry.Headers["User-Agent"]; agent != "" {
+		doc["user_agent"] = map[string]interface{}{"original": agent}
+	}
+	return json.Marshal(doc)
+}
+
+// url returns the path and query the entry's request was made to
+func (entry *RequestLog) url() string {
+	if entry.RawQuery == "" {
+		return entry.Path
+	}
+	return entry.Path + "?" + entry.RawQuery
+}
+
+// splitHostPort splits a remote address or Host header into its host and
+// port, keeping one without a port whole
+func splitHostPort(addr string) (host, port string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, ""
+	}
+	return host, port
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEntry is a logged POST with characters each format must escape
+func testEntry() *RequestLog {
+	return &RequestLog{
+		Timestamp:     "2026-01-19T05:03:54Z",
+		Method:        "POST",
+		Path:          "/search|x",
+		QueryParams:   map[string]string{"q": "a=b"},
+		Headers:       map[string]string{"User-Agent": "sqlmap/1.8", "Cookie": "sid=1"},
+		Body:          "id=1 OR 1=1\n--",
+		RemoteAddr:    "10.0.0.7:51234",
+		StatusCode:    500,
+		ResponseTime:  "12ms",
+		ContentLength: 42,
+		Time:          time.Date(2026, 1, 19, 5, 3, 54, 0, time.UTC),
+		Duration:      12 * time.Millisecond,
+		RawQuery:      "q=a%3Db",
+		Host:          "lab.local",
+		Proto:         "HTTP/1.1",
+	}
+}
+
+// TestNewFormatter tests formats are looked up by name
+func TestNewFormatter(t *testing.T) {
+	for _, format := range []string{"json", "cef", "ecs"} {
+		if _, err := NewFormatter(format); err != nil {
+			t.Errorf("%s: expected a formatter, got %v", format, err)
+		}
+	}
+	if _, err := NewFormatter("syslog"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+// TestJSONFormatter tests the JSON format keeps its fields and leaves out the formatters' own
+func TestJSONFormatter(t *testing.T) {
+	line, err := JSONFormatter{}.Format(testEntry())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		t.Fatalf("Expected a JSON object, got %s", line)
+	}
+	if fields["status_code"] != float64(500) || fields["response_time"] != "12ms" {
+		t.Errorf("Expected the logged fields, got %s", line)
+	}
+	if _, ok := fields["Duration"]; ok {
+		t.Errorf("Expected no fields of the other formats, got %s", line)
+	}
+}
+
+// TestCEFFormatter tests CEF lines escape their header and extension values
+func TestCEFFormatter(t *testing.T) {
+	line, err := CEFFormatter{}.Format(testEntry())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got := string(line)
+	for _, expected := range []string{
+		`CEF:0|FlawFactory|FlawFactory|1.0|500|POST /search\|x|7|`,
+		"rt=1768799034000 src=10.0.0.7 spt=51234 dhost=lab.local",
+		`request=/search|x?q\=a%3Db`,
+		"requestClientApplication=sqlmap/1.8",
+		`cs1=id\=1 OR 1\=1\n-- cs1Label=requestBody`,
+		"cn1=12 cn1Label=responseTimeMs",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("Expected %s in %s", expected, got)
+		}
+	}
+	if strings.Contains(got, "\n") {
+		t.Errorf("Expected one line, got %q", got)
+	}
+}
+
+// TestECSFormatter tests ECS documents put the request in its standard fields
+func TestECSFormatter(t *testing.T) {
+	line, err := ECSFormatter{}.Format(testEntry())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var doc struct {
+		Timestamp string `json:"@timestamp"`
+		Event     struct {
+			Outcome  string `json:"outcome"`
+			Duration int64  `json:"duration"`
+		} `json:"event"`
+		HTTP struct {
+			Request struct {
+				Method string `json:"method"`
+				Body   struct {
+					Content string `json:"content"`
+				} `json:"body"`
+			} `json:"request"`
+			Response struct {
+				StatusCode int `json:"status_code"`
+			} `json:"response"`
+		} `json:"http"`
+		URL struct {
+			Original string `json:"original"`
+		} `json:"url"`
+		Source struct {
+			IP   string `json:"ip"`
+			Port int    `json:"port"`
+		} `json:"source"`
+		UserAgent struct {
+			Original string `json:"original"`
+		} `json:"user_agent"`
+	}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		t.Fatalf("Expected a JSON document, got %s", line)
+	}
+	if doc.Timestamp != "2026-01-19T05:03:54Z" || doc.Event.Outcome != "failure" || doc.Event.Duration != int64(12*time.Millisecond) {
+		t.Errorf("Expected the event's time, outcome and duration, got %s", line)
+	}
+	if doc.HTTP.Request.Method != "POST" || doc.HTTP.Request.Body.Content != "id=1 OR 1=1\n--" || doc.HTTP.Response.StatusCode != 500 {
+		t.Errorf("Expected the request and response, got %s", line)
+	}
+	if doc.URL.Original != "/search|x?q=a%3Db" || doc.Source.IP != "10.0.0.7" || doc.Source.Port != 51234 || doc.UserAgent.Original != "sqlmap/1.8" {
+		t.Errorf("Expected the URL, source and user agent, got %s", line)
+	}
+}
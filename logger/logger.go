@@ -1,7 +1,6 @@
 package logger
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,26 +13,53 @@ import (
 // RequestLog represents a single HTTP request log entry
 type RequestLog struct {
 	Timestamp     string            `json:"timestamp"`
+	RequestID     string            `json:"request_id,omitempty"` // X-Request-ID of the request and its response
 	Method        string            `json:"method"`
 	Path          string            `json:"path"`
 	QueryParams   map[string]string `json:"query_params,omitempty"`
 	Headers       map[string]string `json:"headers"`
 	Body          string            `json:"body,omitempty"`
+	ResponseBody  string            `json:"response_body,omitempty"` // Logged with app.logging.bodies
 	RemoteAddr    string            `json:"remote_addr"`
 	StatusCode    int               `json:"status_code"`
 	ResponseTime  string            `json:"response_time"`
 	ContentLength int64             `json:"content_length,omitempty"`
+	Attacks       []Attack          `json:"attacks,omitempty"` // Attacks the lab's modules classified
+
+	// Read by the CEF and ECS formats, not part of the JSON one
+	Time     time.Time     `json:"-"`
+	Duration time.Duration `json:"-"`
+	RawQuery string        `json:"-"`
+	Host     string        `json:"-"`
+	Proto    string        `json:"-"`
 }
 
-// Logger handles JSON logging to a file
+// Logger writes request logs to a file, one line per request
 type Logger struct {
-	file     *os.File
-	encoder  *json.Encoder
-	mu       sync.Mutex
-	filePath string
+	file      *os.File
+	formatter Formatter
+	mu        sync.Mutex
+	filePath  string
+
+	rotation Rotation
+	size     int64          // Bytes in the current file
+	started  time.Time      // When the current file was started, for time-based rotation
+	cleanup  sync.WaitGroup // Compression and pruning of rotated files
+	tidying  sync.Mutex     // Held while rotated files are compressed and pruned
+
+	bodies   bool      // Log every request's body and the response's, not just POST, PUT and PATCH requests'
+	maxBody  int       // Bytes of each body logged
+	redactor *Redactor // Hides secrets in entries, or nil
+	outputs  []*Output // Ship entries to external backends too
 }
 
-// New creates a new Logger that writes to the specified file
+// DefaultBodyLimit is how many bytes of each body are logged by default
+const DefaultBodyLimit = 10000
+
+// Truncated ends a body cut short in the log
+const Truncated = "... (truncated)"
+
+// New creates a new Logger that writes JSON lines to the specified file
 // If the directory doesn't exist, it will be created
 func New(logFilePath string) (*Logger, error) {
 	// Create the directory if it doesn't exist
@@ -49,17 +75,73 @@ func New(logFilePath string) (*Logger, error) {
 	}
 
 	return &Logger{
-		file:     file,
-		encoder:  json.NewEncoder(file),
-		filePath: logFilePath,
+		file:      file,
+		formatter: JSONFormatter{},
+		filePath:  logFilePath,
+		maxBody:   DefaultBodyLimit,
 	}, nil
 }
 
-// LogRequest logs an HTTP request to the JSON file
-func (l *Logger) LogRequest(r *http.Request, statusCode int, duration time.Duration, contentLength int64) error {
+// SetFormatter switches the format of the entries logged from now on
+func (l *Logger) SetFormatter(formatter Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.formatter = formatter
+}
+
+// SetBodies sets how many bytes of each body are logged, and whether every
+// request's body and the response's are, not just POST, PUT and PATCH
+// requests'
+func (l *Logger) SetBodies(all bool, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bodies, l.maxBody = all, limit
+}
+
+// Bodies reports whether every body is logged and how many bytes of each. A
+// nil logger logs the bodies of POST, PUT and PATCH requests by default.
+func (l *Logger) Bodies() (all bool, limit int) {
+	if l == nil {
+		return false, DefaultBodyLimit
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	return l.bodies, l.maxBody
+}
+
+// SetRedactor hides secrets in the entries logged from now on, or stops
+// hiding them when r is nil
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.redactor = r
+}
 
+// Redactor returns the redactor hiding secrets in entries, or nil
+func (l *Logger) Redactor() *Redactor {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.redactor
+}
+
+// AddOutput ships the entries logged from now on to an external backend too.
+// The logger closes it.
+func (l *Logger) AddOutput(output *Output) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outputs = append(l.outputs, output)
+}
+
+// LogRequest logs an HTTP request to the file, in the logger's format
+func (l *Logger) LogRequest(r *http.Request, statusCode int, duration time.Duration, contentLength int64) error {
+	return l.Log(NewRequestLog(r, statusCode, duration, contentLength))
+}
+
+// NewRequestLog creates the log entry of an answered request
+func NewRequestLog(r *http.Request, statusCode int, duration time.Duration, contentLength int64) *RequestLog {
 	// Extract query parameters
 	queryParams := make(map[string]string)
 	for key, values := range r.URL.Query() {
@@ -81,16 +163,14 @@ func (l *Logger) LogRequest(r *http.Request, statusCode int, duration time.Durat
 	if r.Body != nil && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) {
 		// Body might have been read already, so we use the saved body if available
 		if bodyBytes, ok := r.Context().Value(RequestBodyKey).([]byte); ok {
-			body = string(bodyBytes)
 			// Limit body size in logs
-			if len(body) > 10000 {
-				body = body[:10000] + "... (truncated)"
-			}
+			body = truncateBody(string(bodyBytes), DefaultBodyLimit, false)
 		}
 	}
 
-	logEntry := RequestLog{
-		Timestamp:     time.Now().Format(time.RFC3339),
+	now := time.Now()
+	return &RequestLog{
+		Timestamp:     now.Format(time.RFC3339),
 		Method:        r.Method,
 		Path:          r.URL.Path,
 		QueryParams:   queryParams,
@@ -100,20 +180,79 @@ func (l *Logger) LogRequest(r *http.Request, statusCode int, duration time.Durat
 		StatusCode:    statusCode,
 		ResponseTime:  duration.String(),
 		ContentLength: contentLength,
+		Attacks:       attacksOf(r),
+		Time:          now,
+		Duration:      duration,
+		RawQuery:      r.URL.RawQuery,
+		Host:          r.Host,
+		Proto:         r.Proto,
 	}
+}
 
-	if err := l.encoder.Encode(logEntry); err != nil {
+// SetBodies sets the entry's request and response bodies, cut at limit
+// bytes. The response body, nil when it isn't logged, is the start of the
+// ContentLength bytes sent. The redactor, if any, hides secrets before the
+// bodies are cut, so none is left half visible at the cut.
+func (entry *RequestLog) SetBodies(request, response []byte, limit int, redactor *Redactor) {
+	entry.Body = truncateBody(redactor.redact(string(request)), limit, false)
+	if response != nil {
+		entry.ResponseBody = truncateBody(redactor.redact(string(response)), limit, int64(len(response)) < entry.ContentLength)
+	}
+}
+
+// truncateBody returns up to limit bytes of body, marked when it is cut or
+// already was
+func truncateBody(body string, limit int, truncated bool) string {
+	if len(body) > limit {
+		body, truncated = body[:limit], true
+	}
+	if truncated {
+		return body + Truncated
+	}
+	return body
+}
+
+// Log writes an entry to the file, in the logger's format. Secrets are hidden
+// in the entry itself, so whatever else it is passed to doesn't leak them.
+func (l *Logger) Log(entry *RequestLog) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.redactor != nil {
+		l.redactor.Redact(entry)
+	}
+	for _, output := range l.outputs {
+		output.ship(entry)
+	}
+
+	line, err := l.formatter.Format(entry)
+	if err != nil {
+		return fmt.Errorf("failed to format log entry: %w", err)
+	}
+	line = append(line, '\n')
+	if err := l.rotateIfDue(len(line), entry.Time); err != nil {
+		return err
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
 		return fmt.Errorf("failed to write log entry: %w", err)
 	}
 
 	return nil
 }
 
-// Close closes the log file
+// Close closes the log file, once rotated files are compressed, and the
+// outputs, once they've shipped the entries queued
 func (l *Logger) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	for _, output := range l.outputs {
+		output.Close()
+	}
+	l.outputs = nil
+	l.cleanup.Wait()
 	if l.file != nil {
 		return l.file.Close()
 	}
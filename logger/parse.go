@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseEntry reads back an entry logged in the json, ecs or cef format, e.g.
+// to replay its request. What a format leaves out stays empty: json lines
+// don't have the Host, and cef lines only the User-Agent and Cookie headers.
+// The query of a json line is rebuilt from its parameters, so only the first
+// value of each is kept.
+func ParseEntry(line []byte) (*RequestLog, error) {
+	line = bytes.TrimSpace(line)
+	switch {
+	case len(line) == 0:
+		return nil, errors.New("empty line")
+	case bytes.HasPrefix(line, []byte("CEF:")):
+		return parseCEF(string(line))
+	case bytes.Contains(line, []byte(`"@timestamp"`)):
+		return parseECS(line)
+	}
+
+	var entry RequestLog
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, fmt.Errorf("not a json, ecs or cef entry: %w", err)
+	}
+	if entry.Method == "" {
+		return nil, errors.New("not a request log entry: no method")
+	}
+	entry.Time, _ = time.Parse(time.RFC3339, entry.Timestamp)
+	entry.Duration, _ = time.ParseDuration(entry.ResponseTime)
+	if len(entry.QueryParams) > 0 {
+		query := url.Values{}
+		for key, value := range entry.QueryParams {
+			query.Set(key, value)
+		}
+		entry.RawQuery = query.Encode()
+	}
+	return &entry, nil
+}
+
+// parseECS reads back an entry of the ecs format
+func parseECS(line []byte) (*RequestLog, error) {
+	var doc struct {
+		Timestamp time.Time `json:"@timestamp"`
+		Event     struct {
+			Duration int64 `json:"duration"`
+		} `json:"event"`
+		HTTP struct {
+			Version string `json:"version"`
+			Request struct {
+				ID     string `json:"id"`
+				Method string `json:"method"`
+				Body   struct {
+					Content string `json:"content"`
+				} `json:"body"`
+			} `json:"request"`
+			Response struct {
+				StatusCode int `json:"status_code"`
+				Body       struct {
+					Bytes   int64  `json:"bytes"`
+					Content string `json:"content"`
+				} `json:"body"`
+			} `json:"response"`
+		} `json:"http"`
+		URL struct {
+			Path   string `json:"path"`
+			Query  string `json:"query"`
+			Domain string `json:"domain"`
+			Port   int    `json:"port"`
+		} `json:"url"`
+		Source struct {
+			Address string `json:"address"`
+			Port    int    `json:"port"`
+		} `json:"source"`
+		FlawFactory struct {
+			Headers     map[string]string `json:"headers"`
+			QueryParams map[string]string `json:"query_params"`
+			Attacks     []Attack          `json:"attacks"`
+		} `json:"flawfactory"`
+	}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return nil, fmt.Errorf("not an ecs entry: %w", err)
+	}
+	if doc.HTTP.Request.Method == "" {
+		return nil, errors.New("not a request log entry: no http.request.method")
+	}
+
+	entry := &RequestLog{
+		Timestamp:     doc.Timestamp.Format(time.RFC3339),
+		RequestID:     doc.HTTP.Request.ID,
+		Method:        doc.HTTP.Request.Method,
+		Path:          doc.URL.Path,
+		QueryParams:   doc.FlawFactory.QueryParams,
+		Headers:       doc.FlawFactory.Headers,
+		Body:          doc.HTTP.Request.Body.Content,
+		ResponseBody:  doc.HTTP.Response.Body.Content,
+		RemoteAddr:    doc.Source.Address,
+		StatusCode:    doc.HTTP.Response.StatusCode,
+		ContentLength: doc.HTTP.Response.Body.Bytes,
+		Attacks:       doc.FlawFactory.Attacks,
+		Time:          doc.Timestamp,
+		Duration:      time.Duration(doc.Event.Duration),
+		RawQuery:      doc.URL.Query,
+		Host:          doc.URL.Domain,
+		Proto:         "HTTP/" + doc.HTTP.Version,
+	}
+	entry.ResponseTime = entry.Duration.String()
+	if doc.Source.Port != 0 {
+		entry.RemoteAddr = net.JoinHostPort(doc.Source.Address, strconv.Itoa(doc.Source.Port))
+	}
+	if doc.URL.Port != 0 {
+		entry.Host = net.JoinHostPort(doc.URL.Domain, strconv.Itoa(doc.URL.Port))
+	}
+	return entry, nil
+}
+
+// parseCEF reads back an entry of the cef format
+func parseCEF(line string) (*RequestLog, error) {
+	// Seven header fields, with \| and \\ escaped, then the extension
+	var header []string
+	var field strings.Builder
+	rest := ""
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case c == '|':
+			header = append(header, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+		if len(header) == 7 {
+			rest = line[i+1:]
+			break
+		}
+	}
+	if len(header) < 7 {
+		return nil, errors.New("not a cef entry: fewer than 7 header fields")
+	}
+
+	extension := cefExtension(rest)
+	method := extension["requestMethod"]
+	if method == "" {
+		return nil, errors.New("not a request log entry: no requestMethod")
+	}
+	path, query, _ := strings.Cut(extension["request"], "?")
+	entry := &RequestLog{
+		RequestID:    extension["externalId"],
+		Method:       method,
+		Path:         path,
+		RawQuery:     query,
+		Headers:      make(map[string]string),
+		Body:         extension["cs1"],
+		ResponseBody: extension["cs5"],
+		RemoteAddr:   extension["src"],
+		Host:         extension["dhost"],
+		Proto:        extension["app"],
+	}
+	if port := extension["spt"]; port != "" {
+		entry.RemoteAddr = net.JoinHostPort(entry.RemoteAddr, port)
+	}
+	for key, header := range map[string]string{"requestClientApplication": "User-Agent", "requestCookies": "Cookie"} {
+		if value := extension[key]; value != "" {
+			entry.Headers[header] = value
+		}
+	}
+	if values, err := url.ParseQuery(query); err == nil && len(values) > 0 {
+		entry.QueryParams = make(map[string]string, len(values))
+		for key, value := range values {
+			entry.QueryParams[key] = value[0]
+		}
+	}
+	entry.StatusCode, _ = strconv.Atoi(extension["outcome"])
+	entry.ContentLength, _ = strconv.ParseInt(extension["out"], 10, 64)
+	if ms, err := strconv.ParseInt(extension["rt"], 10, 64); err == nil {
+		entry.Time = time.UnixMilli(ms).UTC()
+		entry.Timestamp = entry.Time.Format(time.RFC3339)
+	}
+	if ms, err := strconv.ParseInt(extension["cn1"], 10, 64); err == nil {
+		entry.Duration = time.Duration(ms) * time.Millisecond
+		entry.ResponseTime = entry.Duration.String()
+	}
+	if attackType := extension["cs2"]; attackType != "" {
+		score, _ := strconv.ParseFloat(extension["cfp1"], 64)
+		entry.Attacks = []Attack{{Module: extension["cs4"], Type: attackType, Payload: extension["cs3"], Score: score, Severity: Severity(score)}}
+	}
+	return entry, nil
+}
+
+// cefExtension splits a CEF extension into its keys and unescaped values. A
+// value runs up to the space before the next unescaped key=.
+func cefExtension(extension string) map[string]string {
+	fields := make(map[string]string)
+	key := ""
+	var value strings.Builder
+	flush := func() {
+		if key != "" {
+			fields[key] = value.String()
+		}
+		value.Reset()
+	}
+
+	for i := 0; i < len(extension); i++ {
+		c := extension[i]
+		if c == '\\' && i+1 < len(extension) {
+			i++
+			switch extension[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			default:
+				value.WriteByte(extension[i])
+			}
+			continue
+		}
+		if c == '=' && key == "" {
+			key = value.String()
+			value.Reset()
+			continue
+		}
+		if c == ' ' && key != "" && cefKeyAt(extension[i+1:]) {
+			flush()
+			key = ""
+			continue
+		}
+		value.WriteByte(c)
+	}
+	flush()
+	return fields
+}
+
+// cefKeyAt reports whether s starts with an extension key and its =
+func cefKeyAt(s string) bool {
+	end := strings.IndexByte(s, '=')
+	if end <= 0 {
+		return false
+	}
+	for i := 0; i < end; i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"testing"
+)
+
+// TestParseEntry tests entries logged in each format are read back with the
+// fields replaying their requests needs
+func TestParseEntry(t *testing.T) {
+	for _, formatter := range []Formatter{JSONFormatter{}, ECSFormatter{}, CEFFormatter{}} {
+		entry := testEntry()
+		entry.RequestID = "req-1"
+		entry.Attacks = []Attack{{Module: "sql_injection", Type: "sql_injection", Payload: "1 OR 1=1", Score: 9.8}}
+		line, err := formatter.Format(entry)
+		if err != nil {
+			t.Fatalf("%T: failed to format: %v", formatter, err)
+		}
+
+		parsed, err := ParseEntry(line)
+		if err != nil {
+			t.Fatalf("%T: expected the entry parsed, got %v", formatter, err)
+		}
+		if parsed.Method != "POST" || parsed.Path != "/search|x" || parsed.RawQuery != "q=a%3Db" || parsed.Body != entry.Body {
+			t.Errorf("%T: expected the request back, got %s %s?%s %q", formatter, parsed.Method, parsed.Path, parsed.RawQuery, parsed.Body)
+		}
+		if parsed.StatusCode != 500 || parsed.RequestID != "req-1" || parsed.RemoteAddr != "10.0.0.7:51234" || !parsed.Time.Equal(entry.Time) {
+			t.Errorf("%T: expected status, ID, client and time back, got %+v", formatter, parsed)
+		}
+		if parsed.Headers["User-Agent"] != "sqlmap/1.8" || parsed.Headers["Cookie"] != "sid=1" {
+			t.Errorf("%T: expected the headers back, got %v", formatter, parsed.Headers)
+		}
+		if len(parsed.Attacks) != 1 || parsed.Attacks[0].Module != "sql_injection" || parsed.Attacks[0].Payload != "1 OR 1=1" {
+			t.Errorf("%T: expected the attack back, got %+v", formatter, parsed.Attacks)
+		}
+	}
+
+	for _, line := range []string{"", "not a log line", `{"level":"info","msg":"started"}`, "CEF:0|FlawFactory|lab"} {
+		if _, err := ParseEntry([]byte(line)); err == nil {
+			t.Errorf("Expected an error for %q", line)
+		}
+	}
+}
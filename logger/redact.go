@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"cmp"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the secrets a Redactor hides
+const Redacted = "[REDACTED]"
+
+// minSecret is the shortest secret hidden: shorter ones would hide ordinary
+// words and numbers too
+const minSecret = 4
+
+// RedactMargin is how many bytes past the logged part of a body should be
+// kept for redaction, so a secret straddling the cut is still recognised
+const RedactMargin = 1024
+
+// Redactor hides secrets in log entries before they are written
+type Redactor struct {
+	headers  map[string]bool // Canonical names of the headers whose values are hidden
+	patterns []*regexp.Regexp
+	secrets  []string // Longest first, so a secret containing another is hidden whole
+}
+
+// NewRedactor creates a redactor hiding the values of headers and what the
+// patterns match. Of a pattern with groups, only the groups are hidden, so
+// `password=([^&]+)` keeps the parameter's name.
+func NewRedactor(headers, patterns []string) (*Redactor, error) {
+	r := &Redactor{headers: make(map[string]bool, len(headers))}
+	for _, header := range headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// WithSecrets returns a copy of r that also hides each secret wherever it
+// appears, in place of the secrets r hid
+func (r *Redactor) WithSecrets(secrets []string) *Redactor {
+	c := *r
+	c.secrets = nil
+	for _, secret := range secrets {
+		if len(secret) >= minSecret && !slices.Contains(c.secrets, secret) {
+			c.secrets = append(c.secrets, secret)
+		}
+	}
+	slices.SortFunc(c.secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return &c
+}
+
+// Redact hides the secrets in the entry's request line, headers, bodies and
+// attack payloads
+func (r *Redactor) Redact(entry *RequestLog) {
+	entry.Path = r.redact(entry.Path)
+	entry.RawQuery = r.redact(entry.RawQuery)
+	for name, value := range entry.QueryParams {
+		entry.QueryParams[name] = r.redact(value)
+	}
+	for name, value := range entry.Headers {
+		if r.headers[name] {
+			entry.Headers[name] = Redacted
+		} else {
+			entry.Headers[name] = r.redact(value)
+		}
+	}
+	entry.Body = r.redact(entry.Body)
+	entry.ResponseBody = r.redact(entry.ResponseBody)
+	for i := range entry.Attacks {
+		entry.Attacks[i].Payload = r.redact(entry.Attacks[i].Payload)
+	}
+}
+
+// redact hides the secrets and pattern matches in s. A nil redactor hides
+// nothing.
+func (r *Redactor) redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	for _, re := range r.patterns {
+		s = redactMatches(re, s)
+	}
+	return s
+}
+
+// redactMatches replaces what re matches in s, or only its groups when it has
+// any
+func redactMatches(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, Redacted)
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		for i := 2; i < len(match); i += 2 {
+			start, end := match[i], match[i+1]
+			if start < last || start == end {
+				continue // Unmatched, empty or nested in a group already hidden
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Redacted)
+			last = end
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
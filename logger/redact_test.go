@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRedactor tests headers, pattern matches and secrets are hidden
+// throughout an entry, and short secrets aren't
+func TestRedactor(t *testing.T) {
+	redactor, err := NewRedactor([]string{"authorization", "Cookie"}, []string{`password=([^&\s]+)`, `\d{4}-\d{4}-\d{4}-\d{4}`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	redactor = redactor.WithSecrets([]string{"FLAG{abc}", "FLAG{abc}-2", "hunter2", "abc", ""})
+
+	entry := testEntry()
+	entry.Path = "/flags/FLAG{abc}-2"
+	entry.Headers["Authorization"] = "Bearer s3cr3t"
+	entry.Headers["X-Note"] = "hunter2 was here"
+	entry.Body = "user=admin&password=hunter2x&card=4111-1111-1111-1111"
+	entry.ResponseBody = `{"flag":"FLAG{abc}","abc":1}`
+	entry.Attacks = []Attack{{Module: "sql_injection", Payload: "' UNION SELECT password=letmein"}}
+	redactor.Redact(entry)
+
+	expected := map[string][2]string{
+		"path":          {entry.Path, "/flags/[REDACTED]"},
+		"authorization": {entry.Headers["Authorization"], Redacted},
+		"cookie":        {entry.Headers["Cookie"], Redacted},
+		"other header":  {entry.Headers["X-Note"], "[REDACTED] was here"},
+		"user agent":    {entry.Headers["User-Agent"], "sqlmap/1.8"},
+		"body":          {entry.Body, "user=admin&password=[REDACTED]&card=[REDACTED]"},
+		"response":      {entry.ResponseBody, `{"flag":"[REDACTED]","abc":1}`},
+		"payload":       {entry.Attacks[0].Payload, "' UNION SELECT password=[REDACTED]"},
+	}
+	for name, got := range expected {
+		if got[0] != got[1] {
+			t.Errorf("%s: expected %q, got %q", name, got[1], got[0])
+		}
+	}
+
+	if _, err := NewRedactor(nil, []string{"("}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+// TestLogger_Bodies tests the logger writes redacted entries with response
+// bodies cut at its limit
+func TestLogger_Bodies(t *testing.T) {
+	l, err := New(filepath.Join(t.TempDir(), "lab.json"))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer l.Close()
+	if all, limit := (*Logger)(nil).Bodies(); all || limit != DefaultBodyLimit {
+		t.Errorf("Expected POST, PUT and PATCH bodies by default, got %v, %d", all, limit)
+	}
+	l.SetBodies(true, 8)
+	redactor, _ := NewRedactor([]string{"Cookie"}, nil)
+	l.SetRedactor(redactor.WithSecrets([]string{"FLAG{x}"}))
+
+	entry := testEntry()
+	entry.ContentLength = 20
+	_, limit := l.Bodies()
+	entry.SetBodies([]byte("q=1"), []byte("more FLAG{x}"), limit, l.Redactor()) // The cut falls inside the flag
+	if err := l.Log(entry); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	unlogged := testEntry()
+	unlogged.SetBodies(nil, nil, limit, nil) // Responses aren't logged without app.logging.bodies
+	if unlogged.ResponseBody != "" {
+		t.Errorf("Expected no response body, got %q", unlogged.ResponseBody)
+	}
+
+	data, _ := os.ReadFile(l.FilePath())
+	line := string(data)
+	for _, want := range []string{`"body":"q=1"`, `"response_body":"more [RE... (truncated)"`, `"Cookie":"[REDACTED]"`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %s in %s", want, line)
+		}
+	}
+}
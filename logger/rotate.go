@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotatedTimeFormat stamps rotated files, sorting them oldest first by name
+const rotatedTimeFormat = "20060102T150405"
+
+// Rotation sets when a log file is rotated and how long rotated files are kept
+type Rotation struct {
+	MaxBytes int64         // Rotate before the file grows past this size, 0 for no limit
+	Interval time.Duration // Rotate at the first entry this long after the file was started, 0 for never
+	Compress bool          // Gzip rotated files
+	MaxFiles int           // Rotated files kept, newest first, 0 for all
+	MaxAge   time.Duration // Delete rotated files older than this, 0 to keep them
+}
+
+// enabled reports whether the file is ever rotated
+func (r Rotation) enabled() bool {
+	return r.MaxBytes > 0 || r.Interval > 0
+}
+
+// SetRotation rotates the log file from now on. The current file counts as
+// started now, at its current size.
+func (l *Logger) SetRotation(rotation Rotation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	l.rotation = rotation
+	l.size = info.Size()
+	l.started = time.Now()
+	return nil
+}
+
+// rotateIfDue rotates the file before an entry of n bytes is written, if it
+// is due. The caller holds l.mu.
+func (l *Logger) rotateIfDue(n int, now time.Time) error {
+	r := l.rotation
+	if !r.enabled() || l.size == 0 {
+		return nil
+	}
+	if (r.MaxBytes == 0 || l.size+int64(n) <= r.MaxBytes) && (r.Interval == 0 || now.Sub(l.started) < r.Interval) {
+		return nil
+	}
+
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	rotated := l.rotatedPath(now)
+	renameErr := os.Rename(l.filePath, rotated)
+
+	// Keep logging, to the same file if it couldn't be rotated
+	file, err := os.OpenFile(l.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.file = file
+	if renameErr != nil {
+		l.started = now // Not retried on every entry
+		return fmt.Errorf("failed to rotate log file: %w", renameErr)
+	}
+	l.size, l.started = 0, now
+
+	// Compressing can take a while, so the next entries don't wait for it
+	l.cleanup.Add(1)
+	go func() {
+		defer l.cleanup.Done()
+		l.tidy(r, time.Now())
+	}()
+	return nil
+}
+
+// rotatedPath returns the name the file is rotated to, e.g. log/ssrf.json to
+// log/ssrf-20260119T050354.json, numbered if that is taken
+func (l *Logger) rotatedPath(now time.Time) string {
+	ext := filepath.Ext(l.filePath)
+	base := strings.TrimSuffix(l.filePath, ext) + "-" + now.Format(rotatedTimeFormat)
+	path := base + ext
+	for i := 1; fileExists(path) || fileExists(path+".gz"); i++ {
+		path = fmt.Sprintf("%s.%d%s", base, i, ext)
+	}
+	return path
+}
+
+// RotatedFiles returns the log file's rotated files, oldest first
+func (l *Logger) RotatedFiles() ([]string, error) {
+	dir := filepath.Dir(l.filePath)
+	ext := filepath.Ext(l.filePath)
+	prefix := strings.TrimSuffix(filepath.Base(l.filePath), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type rotated struct {
+		path  string
+		stamp string
+		n     int // Of files rotated within the same second
+	}
+	var files []rotated
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() || len(rest) < len(rotatedTimeFormat) {
+			continue
+		}
+		stamp := rest[:len(rotatedTimeFormat)]
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err != nil {
+			continue // Another config's log, like ssrf-internal.json beside ssrf.json
+		}
+		number := strings.TrimSuffix(strings.TrimSuffix(rest[len(stamp):], ".gz"), ext)
+		n, _ := strconv.Atoi(strings.TrimPrefix(number, "."))
+		files = append(files, rotated{filepath.Join(dir, entry.Name()), stamp, n})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].stamp != files[j].stamp {
+			return files[i].stamp < files[j].stamp
+		}
+		return files[i].n < files[j].n
+	})
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+// tidy compresses the rotated files that aren't yet and deletes those past
+// the rotation's retention. Runs one at a time, as each rotation starts one.
+func (l *Logger) tidy(r Rotation, now time.Time) {
+	l.tidying.Lock()
+	defer l.tidying.Unlock()
+
+	if r.Compress {
+		files, err := l.RotatedFiles()
+		if err != nil {
+			log.Printf("Warning: failed to list rotated logs: %v", err)
+			return
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, ".gz") {
+				continue
+			}
+			if err := compressFile(file); err != nil {
+				log.Printf("Warning: failed to compress %s: %v", file, err)
+			}
+		}
+	}
+	l.prune(r, now)
+}
+
+// prune deletes the rotated files past the rotation's retention
+func (l *Logger) prune(r Rotation, now time.Time) {
+	if r.MaxFiles == 0 && r.MaxAge == 0 {
+		return
+	}
+	files, err := l.RotatedFiles()
+	if err != nil {
+		log.Printf("Warning: failed to list rotated logs: %v", err)
+		return
+	}
+	for i, file := range files {
+		expired := r.MaxFiles > 0 && i < len(files)-r.MaxFiles
+		if !expired && r.MaxAge > 0 {
+			if info, err := os.Stat(file); err == nil && now.Sub(info.ModTime()) > r.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(file); err != nil {
+				log.Printf("Warning: failed to delete rotated log %s: %v", file, err)
+			}
+		}
+	}
+}
+
+// compressFile gzips a file to file.gz and removes it
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// fileExists reports whether there is a file at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
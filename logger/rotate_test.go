@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRotatingLogger returns a logger writing to a temporary directory
+func newRotatingLogger(t *testing.T, rotation Rotation) *Logger {
+	t.Helper()
+	l, err := New(filepath.Join(t.TempDir(), "lab.json"))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	if err := l.SetRotation(rotation); err != nil {
+		t.Fatalf("Failed to set rotation: %v", err)
+	}
+	return l
+}
+
+// logRequests logs n requests
+func logRequests(t *testing.T, l *Logger, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := l.LogRequest(httptest.NewRequest("GET", "/search?q=1", nil), 200, time.Millisecond, 0); err != nil {
+			t.Fatalf("Failed to log request: %v", err)
+		}
+	}
+}
+
+// TestRotation_Size tests files are rotated before they grow past max_bytes
+func TestRotation_Size(t *testing.T) {
+	l := newRotatingLogger(t, Rotation{MaxBytes: 1})
+	logRequests(t, l, 3)
+	if err := l.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+
+	files, err := l.RotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 rotated files, got %v", files)
+	}
+	for _, file := range append(files, l.FilePath()) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if strings.Count(string(data), "\n") != 1 {
+			t.Errorf("Expected one entry in %s, got %q", file, data)
+		}
+	}
+}
+
+// TestRotatedFiles tests rotated files are listed oldest first, past other logs
+func TestRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"lab-20260119T050354.1.json.gz",
+		"lab-20260119T050354.json.gz",
+		"lab-20260120T000000.json",
+		"lab-20260119T050354.10.json",
+		"lab-20260119T050354.2.json",
+		"lab-internal.json",
+		"lab.json",
+	}
+	for _, name := range names {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	files, err := (&Logger{filePath: filepath.Join(dir, "lab.json")}).RotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	want := []string{names[1], names[0], names[4], names[3], names[2]}
+	if len(files) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, files)
+	}
+	for i, file := range files {
+		if filepath.Base(file) != want[i] {
+			t.Errorf("Expected %v, got %v", want, files)
+			break
+		}
+	}
+}
+
+// TestRotation_Interval tests files are rotated once they get old
+func TestRotation_Interval(t *testing.T) {
+	l := newRotatingLogger(t, Rotation{Interval: time.Hour})
+	defer l.Close()
+	logRequests(t, l, 2)
+	if files, _ := l.RotatedFiles(); len(files) != 0 {
+		t.Fatalf("Expected no rotation within the interval, got %v", files)
+	}
+
+	l.started = time.Now().Add(-2 * time.Hour)
+	logRequests(t, l, 1)
+	if files, _ := l.RotatedFiles(); len(files) != 1 {
+		t.Errorf("Expected a rotation after the interval, got %v", files)
+	}
+}
+
+// TestRotation_Retention tests rotated files are compressed and pruned
+func TestRotation_Retention(t *testing.T) {
+	l := newRotatingLogger(t, Rotation{MaxBytes: 1, Compress: true, MaxFiles: 2})
+	logRequests(t, l, 5)
+	l.Close()
+
+	files, err := l.RotatedFiles()
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected the 2 newest rotated files, got %v", files)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".json.gz") {
+			t.Errorf("Expected %s to be compressed", file)
+		}
+	}
+
+	f, err := os.Open(files[1])
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", files[1], err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Expected a gzip file: %v", err)
+	}
+	data, _ := io.ReadAll(zr)
+	if !strings.Contains(string(data), `"path":"/search"`) {
+		t.Errorf("Expected the entry in the compressed file, got %q", data)
+	}
+}
+
+// TestRotation_MaxAge tests rotated files past max_age are deleted
+func TestRotation_MaxAge(t *testing.T) {
+	l := newRotatingLogger(t, Rotation{MaxBytes: 1, MaxAge: time.Hour})
+	defer l.Close()
+	logRequests(t, l, 2)
+	l.cleanup.Wait()
+
+	files, _ := l.RotatedFiles()
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %v", files)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(files[0], old, old)
+	l.prune(l.rotation, time.Now())
+	if files, _ := l.RotatedFiles(); len(files) != 0 {
+		t.Errorf("Expected the old file deleted, got %v", files)
+	}
+}
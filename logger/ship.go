@@ -0,0 +1,374 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Batching of shipped entries
+const (
+	shipBatchSize     = 200           // Entries sent at once
+	shipBatchInterval = time.Second   // Longest an entry waits to be sent
+	maxShipQueued     = 10000         // Entries kept while a backend is slow; more are dropped
+	maxShipError      = 512           // Bytes of a backend's error response reported
+	syslogAppName     = "flawfactory" // APP-NAME of syslog messages
+	lokiPushPath      = "/loki/api/v1/push"
+)
+
+// Shipment is an entry formatted for a backend
+type Shipment struct {
+	Time     time.Time
+	Severity int    // Syslog severity, 0 (emergency) to 7 (debug)
+	Line     []byte // The entry in the output's format
+}
+
+// Backend receives batches of entries. Send is never called concurrently.
+type Backend interface {
+	Send(batch []Shipment) error
+	Close() error
+	String() string // Where entries go, for warnings
+}
+
+// Output ships entries to a backend in the background, in batches, so a slow
+// or unreachable backend doesn't hold up the lab
+type Output struct {
+	backend   Backend
+	formatter Formatter
+
+	mu      sync.Mutex
+	queue   []Shipment
+	dropped int
+	flush   chan struct{}
+	done    chan struct{}
+	closed  bool
+	stopped sync.WaitGroup
+}
+
+// NewOutput creates an output shipping entries to backend in the format of
+// formatter, and starts sending
+func NewOutput(backend Backend, formatter Formatter) *Output {
+	o := &Output{
+		backend:   backend,
+		formatter: formatter,
+		flush:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	o.stopped.Add(1)
+	go o.run()
+	return o
+}
+
+// ship queues an entry, waking the sender once a batch is full
+func (o *Output) ship(entry *RequestLog) {
+	line, err := o.formatter.Format(entry)
+	if err != nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed || len(o.queue) >= maxShipQueued {
+		o.dropped++
+		return
+	}
+	o.queue = append(o.queue, Shipment{Time: entry.Time, Severity: syslogSeverity(entry), Line: line})
+	if len(o.queue) >= shipBatchSize {
+		select {
+		case o.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends the queue every shipBatchInterval, or sooner when a batch is
+// full, until Close
+func (o *Output) run() {
+	defer o.stopped.Done()
+	ticker := time.NewTicker(shipBatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.flush:
+		case <-o.done:
+			o.send()
+			return
+		}
+		o.send()
+	}
+}
+
+// send ships the queued entries, shipBatchSize at a time. Failures are
+// logged and the batch dropped.
+func (o *Output) send() {
+	for {
+		o.mu.Lock()
+		n := min(len(o.queue), shipBatchSize)
+		batch := o.queue[:n]
+		o.queue = o.queue[n:]
+		dropped := o.dropped
+		o.dropped = 0
+		o.mu.Unlock()
+
+		if dropped > 0 {
+			log.Printf("Warning: dropped %d log entries, %s isn't keeping up", dropped, o.backend)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := o.backend.Send(batch); err != nil {
+			log.Printf("Warning: failed to ship %d log entries to %s: %v", len(batch), o.backend, err)
+		}
+	}
+}
+
+// Close sends the entries still queued and closes the backend
+func (o *Output) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+
+	close(o.done)
+	o.stopped.Wait()
+	return o.backend.Close()
+}
+
+// syslogSeverity rates an entry: informational, a warning for client errors
+// and attacks, an error for server errors and high-scoring attacks, and
+// critical for critical ones
+func syslogSeverity(entry *RequestLog) int {
+	severity := 6
+	switch {
+	case entry.StatusCode >= 500:
+		severity = 3
+	case entry.StatusCode >= 400:
+		severity = 4
+	}
+	if len(entry.Attacks) == 0 {
+		return severity
+	}
+	switch score := entry.maxScore(); {
+	case score >= 9:
+		return 2
+	case score >= 7:
+		return min(severity, 3)
+	default:
+		return min(severity, 4)
+	}
+}
+
+// httpBackend POSTs batches to an HTTP API
+type httpBackend struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// post sends one request body, reporting a response that isn't a success
+func (h *httpBackend) post(contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "FlawFactory")
+	for name, value := range h.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s answered %s: %s", h.url, resp.Status, bytes.TrimSpace(answer[:min(len(answer), maxShipError)]))
+	}
+	return answer, nil
+}
+
+func (h *httpBackend) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+func (h *httpBackend) String() string {
+	return h.url
+}
+
+// Elasticsearch indexes entries, which must be JSON documents, through the
+// bulk API
+type Elasticsearch struct {
+	httpBackend
+	index string
+}
+
+// NewElasticsearch creates a backend indexing entries in index of the
+// Elasticsearch server at baseURL, e.g. http://localhost:9200
+func NewElasticsearch(baseURL, index string, headers map[string]string, timeout time.Duration) *Elasticsearch {
+	return &Elasticsearch{
+		httpBackend: httpBackend{url: strings.TrimSuffix(baseURL, "/") + "/_bulk", headers: headers, client: &http.Client{Timeout: timeout}},
+		index:       index,
+	}
+}
+
+// Send indexes a batch. Documents are created, which indices and data
+// streams both accept.
+func (e *Elasticsearch) Send(batch []Shipment) error {
+	action, _ := json.Marshal(map[string]interface{}{"create": map[string]string{"_index": e.index}})
+	var body bytes.Buffer
+	for _, shipment := range batch {
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(shipment.Line)
+		body.WriteByte('\n')
+	}
+	answer, err := e.post("application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The bulk API answers 200 even when it rejects documents
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if json.Unmarshal(answer, &result) != nil || !result.Errors {
+		return nil
+	}
+	rejected := 0
+	var reason string
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Error != nil {
+				rejected++
+				reason = outcome.Error.Type + ": " + outcome.Error.Reason
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d documents rejected, e.g. %s", rejected, len(batch), reason)
+}
+
+// Loki pushes entries to a Grafana Loki stream
+type Loki struct {
+	httpBackend
+	labels map[string]string
+}
+
+// NewLoki creates a backend pushing entries to the Loki server at baseURL,
+// e.g. http://localhost:3100, in a stream with the given labels
+func NewLoki(baseURL string, labels, headers map[string]string, timeout time.Duration) *Loki {
+	return &Loki{
+		httpBackend: httpBackend{url: strings.TrimSuffix(baseURL, "/") + lokiPushPath, headers: headers, client: &http.Client{Timeout: timeout}},
+		labels:      labels,
+	}
+}
+
+// Send pushes a batch, in a stream per severity, which becomes the level
+// label Grafana colors lines by
+func (l *Loki) Send(batch []Shipment) error {
+	streams := make(map[string][][2]string)
+	for _, shipment := range batch {
+		level := syslogLevels[shipment.Severity]
+		streams[level] = append(streams[level], [2]string{strconv.FormatInt(shipment.Time.UnixNano(), 10), string(shipment.Line)})
+	}
+	push := struct {
+		Streams []map[string]interface{} `json:"streams"`
+	}{}
+	for level, values := range streams {
+		labels := map[string]string{"level": level}
+		for name, value := range l.labels {
+			labels[name] = value
+		}
+		push.Streams = append(push.Streams, map[string]interface{}{"stream": labels, "values": values})
+	}
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	_, err = l.post("application/json", body)
+	return err
+}
+
+// syslogLevels name the syslog severities, as Loki's level label has them
+var syslogLevels = []string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// Syslog sends entries to a syslog receiver as RFC 5424 messages: a datagram
+// each over UDP, or octet-counted (RFC 6587) over TCP
+type Syslog struct {
+	network, address string
+	facility         int
+	hostname         string
+	timeout          time.Duration
+	conn             net.Conn // Dialed when first needed, and again after a failure
+}
+
+// NewSyslog creates a backend sending entries to the receiver at address
+// over network, udp or tcp, with the facility's code
+func NewSyslog(network, address string, facility int, timeout time.Duration) *Syslog {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &Syslog{network: network, address: address, facility: facility, hostname: hostname, timeout: timeout}
+}
+
+// Send writes a batch, a message per entry
+func (s *Syslog) Send(batch []Shipment) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	for _, shipment := range batch {
+		message := s.message(shipment)
+		if s.network == "tcp" {
+			message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+		}
+		if _, err := s.conn.Write(message); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// message formats a shipment as an RFC 5424 message without structured data
+func (s *Syslog) message(shipment Shipment) []byte {
+	priority := s.facility*8 + shipment.Severity
+	header := fmt.Sprintf("<%d>1 %s %s %s %d request - ", priority, shipment.Time.UTC().Format(time.RFC3339Nano), s.hostname, syslogAppName, os.Getpid())
+	return append([]byte(header), shipment.Line...)
+}
+
+func (s *Syslog) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *Syslog) String() string {
+	return s.network + "://" + s.address
+}
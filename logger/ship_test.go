@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOutput_Elasticsearch tests entries are indexed through the bulk API,
+// and rejected documents are reported
+func TestOutput_Elasticsearch(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.URL.Path+" "+r.Header.Get("Content-Type")+" "+r.Header.Get("Authorization")+"\n"+string(body))
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	l, err := New(filepath.Join(t.TempDir(), "lab.json"))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	l.AddOutput(NewOutput(NewElasticsearch(srv.URL+"/", "lab", map[string]string{"Authorization": "ApiKey k"}, time.Second), ECSFormatter{}))
+	l.Log(testEntry())
+	l.Log(testEntry())
+	l.Close() // Ships the queued entries
+
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one batch, got %d", len(bodies))
+	}
+	lines := strings.Split(strings.TrimSuffix(bodies[0], "\n"), "\n")
+	if lines[0] != "/_bulk application/x-ndjson ApiKey k" || len(lines) != 5 {
+		t.Fatalf("Expected 2 documents posted to /_bulk, got %q", bodies[0])
+	}
+	if lines[1] != `{"create":{"_index":"lab"}}` || !strings.Contains(lines[2], `"ecs":{"version"`) {
+		t.Errorf("Expected an action and an ECS document, got %s and %s", lines[1], lines[2])
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors":true,"items":[{"create":{"error":{"type":"mapper_parsing_exception","reason":"bad"}}}]}`))
+	}))
+	defer rejecting.Close()
+	err = NewElasticsearch(rejecting.URL, "lab", nil, time.Second).Send([]Shipment{{Line: []byte("{}")}})
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("Expected the rejection reported, got %v", err)
+	}
+}
+
+// TestOutput_Loki tests entries are pushed in streams labeled with their level
+func TestOutput_Loki(t *testing.T) {
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	loki := NewLoki(srv.URL, map[string]string{"job": "flawfactory", "app": "lab"}, nil, time.Second)
+	entry := testEntry()
+	line, _ := JSONFormatter{}.Format(entry)
+	if err := loki.Send([]Shipment{{Time: entry.Time, Severity: syslogSeverity(entry), Line: line}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if path != lokiPushPath || len(push.Streams) != 1 {
+		t.Fatalf("Expected a stream pushed to %s, got %d to %s", lokiPushPath, len(push.Streams), path)
+	}
+	stream := push.Streams[0]
+	if stream.Stream["app"] != "lab" || stream.Stream["level"] != "error" {
+		t.Errorf("Expected the app's labels and level error, got %v", stream.Stream)
+	}
+	if stream.Values[0][0] != "1768799034000000000" || stream.Values[0][1] != string(line) {
+		t.Errorf("Expected the entry at its nanosecond timestamp, got %v", stream.Values[0])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	err := NewLoki(failing.URL, nil, nil, time.Second).Send([]Shipment{{Line: line}})
+	if err == nil || !strings.Contains(err.Error(), "entry too far behind") {
+		t.Errorf("Expected Loki's answer reported, got %v", err)
+	}
+}
+
+// TestOutput_Syslog tests entries are sent as RFC 5424 messages, with the
+// priority of the facility and the entry's severity
+func TestOutput_Syslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Can't listen on UDP: %v", err)
+	}
+	defer conn.Close()
+
+	syslog := NewSyslog("udp", conn.LocalAddr().String(), 16, time.Second)
+	defer syslog.Close()
+	entry := testEntry()
+	entry.StatusCode = 200
+	entry.Attacks = []Attack{{Type: "sql_injection", Score: 9.8}}
+	if err := syslog.Send([]Shipment{{Time: entry.Time, Severity: syslogSeverity(entry), Line: []byte("hello")}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a message, got %v", err)
+	}
+	message := string(buf[:n])
+	// local0 is 16, and a critical attack severity 2
+	if !strings.HasPrefix(message, "<130>1 2026-01-19T05:03:54Z ") || !strings.HasSuffix(message, " flawfactory "+strconv.Itoa(os.Getpid())+" request - hello") {
+		t.Errorf("Expected an RFC 5424 message, got %q", message)
+	}
+}
+
+// TestSyslogSeverity tests entries are rated by status and attack score
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		status   int
+		score    float64
+		expected int
+	}{
+		{200, 0, 6},
+		{404, 0, 4},
+		{500, 0, 3},
+		{200, 5, 4},
+		{200, 7.5, 3},
+		{500, 5, 3},
+		{404, 9.1, 2},
+	}
+	for _, tt := range tests {
+		entry := &RequestLog{StatusCode: tt.status}
+		if tt.score > 0 {
+			entry.Attacks = []Attack{{Score: tt.score}}
+		}
+		if got := syslogSeverity(entry); got != tt.expected {
+			t.Errorf("status %d, score %.1f: expected %d, got %d", tt.status, tt.score, tt.expected, got)
+		}
+	}
+}
@@ -1,20 +1,28 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/RIZZZIOM/FlawFactory/builder"
 	"github.com/RIZZZIOM/FlawFactory/config"
 	"github.com/RIZZZIOM/FlawFactory/modules"
+	"github.com/RIZZZIOM/FlawFactory/server"
 )
 
 // ANSI color codes for terminal output
@@ -42,10 +50,28 @@ func main() {
 	switch subcommand {
 	case "run":
 		runCommand()
+	case "run-all":
+		runAllCommand()
+	case "init":
+		initCommand()
+	case "edit":
+		editCommand()
 	case "validate":
 		validateCommand()
 	case "modules":
 		modulesCommand()
+	case "schema":
+		schemaCommand()
+	case "report":
+		reportCommand()
+	case "replay":
+		replayCommand()
+	case "export":
+		exportCommand()
+	case "endpoints":
+		endpointsCommand()
+	case "test":
+		testCommand()
 	default:
 		fmt.Printf("Unknown command: %s\n", subcommand)
 		printUsage()
@@ -55,106 +81,341 @@ func main() {
 
 func runCommand() {
 	runFlags := flag.NewFlagSet("run", flag.ExitOnError)
-	configPath := runFlags.String("config", "", "Path to YAML config file (required)")
-	configShort := runFlags.String("c", "", "Path to YAML config file (shorthand)")
+	var configFiles configFlags
+	runFlags.Var(&configFiles, "config", "Path to YAML config file (required; repeat to layer overlays over it)")
+	runFlags.Var(&configFiles, "c", "Path to YAML config file (shorthand)")
 	port := runFlags.Int("port", 0, "Override port from config")
 	portShort := runFlags.Int("p", 0, "Override port from config (shorthand)")
+	var sets setFlags
+	runFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	strict := runFlags.Bool("strict", false, "Reject unknown config keys, module types and placements")
+	watch := runFlags.Bool("watch", false, "Reload the config when its files change")
+	preset := runFlags.String("preset", "", "Run a built-in lab instead of a config file ("+strings.Join(config.Presets(), ", ")+")")
+	harFile := runFlags.String("har", "", "Record the traffic and write it to this HAR file on shutdown")
+	pcapFile := runFlags.String("pcap", "", "Record the listener's TCP streams and write them to this pcap file on shutdown")
+	dryRun := runFlags.Bool("dry-run", false, "Build the lab without listening and print its sinks, seeded data and routes")
 
 	runFlags.Parse(os.Args[2:])
 
-	configFile := *configPath
-	if configFile == "" {
-		configFile = *configShort
-	}
-
 	portOverride := *port
 	if portOverride == 0 {
 		portOverride = *portShort
 	}
 
-	if configFile == "" {
-		fmt.Printf("\n  %s✗ Error:%s -config flag is required\n\n", colorRed, colorReset)
+	if len(configFiles) == 0 && *preset == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config or -preset flag is required\n\n", colorRed, colorReset)
 		runFlags.PrintDefaults()
 		os.Exit(1)
 	}
+	if len(configFiles) > 0 && *preset != "" {
+		fmt.Printf("\n  %s✗ Error:%s use either -config or -preset; a config file can build on a preset with preset: %s\n\n", colorRed, colorReset, *preset)
+		os.Exit(1)
+	}
+
+	// A preset stands in for the config file in messages and log file names.
+	// With overlays the last file names the variant, so each gets its own log.
+	source := configFiles.last()
+	if source == "" {
+		source = *preset
+	}
 
 	// Print startup banner
 	printBanner()
 
-	// Load configuration
-	cfg, err := config.Load(configFile)
+	// Load configuration, substituting --set and environment variables
+	cfg, err := loadConfig(configFiles, *preset, sets, *strict)
 	if err != nil {
-		printConfigError(configFile, err)
+		printConfigError(source, err)
 		os.Exit(1)
 	}
 
 	// Override port if specified
+	apps := cfg.Applications()
 	if portOverride > 0 {
+		if len(apps) > 1 {
+			fmt.Printf("\n  %s✗ Error:%s --port can't be used with multiple apps\n\n", colorRed, colorReset)
+			os.Exit(1)
+		}
 		cfg.App.Port = portOverride
 	}
 
-	// Derive log file path from config file name
-	// e.g., ssrf.yaml -> log/ssrf.json
-	configBaseName := filepath.Base(configFile)
-	configNameWithoutExt := strings.TrimSuffix(configBaseName, filepath.Ext(configBaseName))
-	logFilePath := filepath.Join("log", configNameWithoutExt+".json")
+	if *dryRun {
+		dryRunLabs(apps)
+		return
+	}
 
-	// Build the server with JSON logging
-	b := builder.New(cfg, logFilePath)
-	srv, err := b.Build()
-	if err != nil {
-		log.Fatalf("Failed to build server: %v", err)
+	// A restarted lab waits for the process it replaces to step down
+	if err := server.HandoverReady(); err != nil {
+		log.Fatalf("Restart failed: %v", err)
 	}
 
-	// Print configuration summary
-	printConfigSummary(cfg)
+	// Build a server with JSON logging for each app
+	labs := make([]*lab, 0, len(apps))
+	for _, app := range apps {
+		recordTraffic(app, *harFile != "", *pcapFile != "")
+		b := builder.New(app, logFilePath(source, app, len(apps)))
+		srv, err := b.Build()
+		if err != nil {
+			closeLabs(labs)
+			log.Fatalf("Failed to build server for %s: %v", app.App.Name, err)
+		}
+		labs = append(labs, &lab{name: app.App.Name, builder: b, srv: srv})
 
-	// Start server in a goroutine
-	go func() {
-		if err := srv.Start(); err != nil {
-			log.Fatalf("Server failed: %v", err)
+		// Print configuration summary
+		printConfigSummary(app)
+	}
+
+	// Listen first, taking over the sockets of the process this one restarted
+	for _, l := range labs {
+		if err := l.srv.Listen(); err != nil {
+			closeLabs(labs)
+			log.Fatalf("Failed to start %s: %v", l.name, err)
 		}
-	}()
+	}
+	server.CloseInherited()
+
+	// Start servers in goroutines; if one fails, all of them shut down
+	failed := make(chan error, len(labs))
+	for _, l := range labs {
+		go func(srv *server.Server) {
+			if err := srv.Start(); err != nil {
+				failed <- err
+			}
+		}(l.srv)
+	}
+
+	// SIGHUP, or a file change with --watch, reloads the config into the running server
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	var ticks <-chan time.Time
+	sources := cfg.Sources
+	modTimes := configModTimes(sources)
+	if *watch {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+		log.Printf("Watching %d config file%s for changes", len(sources), pluralize(len(sources)))
+	}
+
+	// SIGUSR2 restarts the lab in a new process, which takes over the sockets
+	// and the config as it now is, while this one finishes its requests
+	restart := make(chan os.Signal, 1)
+	if len(restartSignals) > 0 {
+		signal.Notify(restart, restartSignals...)
+	}
+	var handover *server.Handover
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
 
-	// Graceful shutdown with 5 second timeout
+	exitCode := 0
+wait:
+	for {
+		select {
+		case <-quit:
+			break wait
+		case <-restart:
+			log.Printf("Received SIGUSR2, restarting with %s", source)
+			next, err := restartLabs(labs)
+			if err != nil {
+				log.Printf("Restart failed, still serving: %v", err)
+				continue
+			}
+			handover = next
+			break wait
+		case err := <-failed:
+			log.Printf("Server failed: %v", err)
+			exitCode = 1
+			break wait
+		case <-reload:
+			log.Printf("Received SIGHUP, reloading %s", source)
+		case <-ticks:
+			current := configModTimes(sources)
+			if maps.Equal(current, modTimes) {
+				continue
+			}
+			log.Printf("Config changed, reloading %s", source)
+		}
+
+		sources = reloadLabs(labs, sources, configFiles, *preset, sets, *strict, portOverride)
+		modTimes = configModTimes(sources)
+	}
+
+	// Graceful shutdown of every app, sharing a 5 second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := srv.Stop(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v", err)
+	for _, l := range labs {
+		if err := l.srv.Stop(ctx); err != nil {
+			log.Printf("Server shutdown failed: %v", err)
+			exitCode = 1
+		}
 	}
 
 	// Clean up builder resources
-	if err := b.Close(); err != nil {
-		log.Printf("Warning: cleanup error: %v", err)
+	closeLabs(labs)
+
+	// Write the traffic recorded for --har and --pcap
+	if err := writeCaptures(labs, *harFile, *pcapFile); err != nil {
+		log.Printf("Failed to write the recorded traffic: %v", err)
+		exitCode = 1
+	}
+
+	// The sinks' ports are free, so the restarted lab can take over
+	if handover != nil {
+		handover.Proceed()
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// restartTimeout is how long a restarted lab has to load its config
+const restartTimeout = 30 * time.Second
+
+// restartLabs starts a new process that takes over the labs' sockets, once it
+// has loaded the config
+func restartLabs(labs []*lab) (*server.Handover, error) {
+	servers := make([]*server.Server, 0, len(labs))
+	for _, l := range labs {
+		servers = append(servers, l.srv)
+	}
+	handover, err := server.Restart(servers)
+	if err != nil {
+		return nil, err
+	}
+	if err := handover.WaitReady(restartTimeout); err != nil {
+		return nil, err
+	}
+	return handover, nil
+}
+
+// lab is one running app of the config
+type lab struct {
+	name    string
+	builder *builder.Builder
+	srv     *server.Server
+}
+
+// closeLabs releases the builders' sink resources
+func closeLabs(labs []*lab) {
+	for _, l := range labs {
+		if err := l.builder.Close(); err != nil {
+			log.Printf("Warning: cleanup error: %v", err)
+		}
+	}
+}
+
+// recordTraffic turns on app.capture for --har, and its packet capture for
+// --pcap, keeping the limits the config sets
+func recordTraffic(app *config.Config, har, pcap bool) {
+	if !har && !pcap {
+		return
+	}
+	capture := config.CaptureConfig{}
+	if app.App.Capture != nil {
+		capture = *app.App.Capture
+	}
+	capture.PCAP = capture.PCAP || pcap
+	app.App.Capture = &capture
+}
+
+// writeCaptures writes each lab's recorded traffic to the --har and --pcap
+// files, named after the app when there are several, as logs are
+func writeCaptures(labs []*lab, harFile, pcapFile string) error {
+	for _, l := range labs {
+		capture := l.srv.Capture()
+		if harFile != "" {
+			path := captureFilePath(harFile, l.name, len(labs))
+			if err := writeFile(path, capture.WriteHAR); err != nil {
+				return err
+			}
+			log.Printf("Wrote %d recorded requests to %s", capture.Len(), path)
+		}
+		if pcapFile != "" {
+			path := captureFilePath(pcapFile, l.name, len(labs))
+			if err := writeFile(path, capture.WritePCAP); err != nil {
+				return err
+			}
+			log.Printf("Wrote the recorded TCP streams to %s", path)
+		}
+	}
+	return nil
+}
+
+// captureFilePath adds the app's name to a capture file's with several apps,
+// e.g. lab.har -> lab-internal-api.har
+func captureFilePath(path, appName string, appCount int) string {
+	if appCount <= 1 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + appSlug(appName) + ext
+}
+
+// writeFile creates path and writes it with write
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// appSlug lower-cases an app name and replaces what isn't a letter or digit
+// with dashes, e.g. "Internal API" -> internal-api
+func appSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, name)
+	return strings.Trim(slug, "-")
+}
+
+// logFilePath derives an app's request log path from the config file name,
+// e.g. ssrf.yaml -> log/ssrf.json, or log/chain-internal-api.json for the
+// "Internal API" app of chain.yaml; CEF logs end in .log, and app.logging.dir
+// replaces log/
+func logFilePath(configFile string, app *config.Config, appCount int) string {
+	configBaseName := filepath.Base(configFile)
+	name := strings.TrimSuffix(configBaseName, filepath.Ext(configBaseName))
+
+	if appCount > 1 {
+		name += "-" + appSlug(app.App.Name)
+	}
+
+	return filepath.Join(app.App.Logging.Directory(), name+app.App.Logging.FileExtension())
 }
 
 func validateCommand() {
 	validateFlags := flag.NewFlagSet("validate", flag.ExitOnError)
-	configPath := validateFlags.String("config", "", "Path to YAML config file (required)")
-	configShort := validateFlags.String("c", "", "Path to YAML config file (shorthand)")
+	var configFiles configFlags
+	validateFlags.Var(&configFiles, "config", "Path to YAML config file (required; repeat to layer overlays over it)")
+	validateFlags.Var(&configFiles, "c", "Path to YAML config file (shorthand)")
+	var sets setFlags
+	validateFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	strict := validateFlags.Bool("strict", false, "Reject unknown config keys, module types and placements")
 
 	validateFlags.Parse(os.Args[2:])
 
-	configFile := *configPath
-	if configFile == "" {
-		configFile = *configShort
-	}
-
+	configFile := strings.Join(configFiles, ", ")
 	if configFile == "" {
 		fmt.Printf("\n  %s✗ Error:%s -config flag is required\n\n", colorRed, colorReset)
 		validateFlags.PrintDefaults()
 		os.Exit(1)
 	}
 
-	// Load configuration
-	cfg, err := config.Load(configFile)
+	// Load configuration, substituting --set and environment variables
+	cfg, err := loadConfig(configFiles, "", sets, *strict)
 	if err != nil {
 		printConfigError(configFile, err)
 		os.Exit(1)
@@ -162,6 +423,9 @@ func validateCommand() {
 
 	// Validate with warnings
 	result := config.ValidateWithWarnings(cfg)
+	if *strict {
+		result = config.ValidateStrict(cfg)
+	}
 	if result.HasErrors() {
 		printConfigError(configFile, result.Errors)
 		os.Exit(1)
@@ -187,7 +451,14 @@ func validateCommand() {
 
 	fmt.Println()
 
-	// Summary
+	// Summary, per app
+	for _, app := range cfg.Applications() {
+		printValidationSummary(app)
+	}
+}
+
+// printValidationSummary prints one app's summary after validation
+func printValidationSummary(cfg *config.Config) {
 	fmt.Println(colorYellow + "  SUMMARY" + colorReset)
 	fmt.Printf("    %sApp Name:%s    %s\n", colorDim, colorReset, cfg.App.Name)
 	if cfg.App.Description != "" {
@@ -216,7 +487,312 @@ func validateCommand() {
 	fmt.Println()
 }
 
+// watchInterval is how often --watch checks the config files for changes
+const watchInterval = time.Second
+
+// configModTimes returns the modification time of each config file. Files that
+// can't be read are left out, so deleting one also counts as a change.
+func configModTimes(paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// reloadLabs loads the config again and swaps each app into its running
+// server. It returns the files to watch; if the new config can't be loaded or
+// built, the labs keep serving the previous one.
+func reloadLabs(labs []*lab, sources []string, configFiles configFlags, preset string, sets setFlags, strict bool, portOverride int) []string {
+	cfg, err := loadConfig(configFiles, preset, sets, strict)
+	if err != nil {
+		log.Printf("Reload failed, still serving the previous config: %v", err)
+		return sources
+	}
+
+	apps := cfg.Applications()
+	if len(apps) != len(labs) {
+		log.Printf("Reload failed, still serving the previous config: adding or removing apps requires a restart")
+		return sources
+	}
+	if portOverride > 0 {
+		cfg.App.Port = portOverride
+	}
+
+	for i, l := range labs {
+		if len(labs) > 1 {
+			log.Printf("Reloading app %s", l.name)
+		}
+
+		next, diff, err := l.builder.Reload(l.srv, apps[i])
+		l.builder, l.name = next, apps[i].App.Name
+		if err != nil {
+			log.Printf("Reload failed: %v", err)
+			continue
+		}
+
+		for _, endpoint := range diff.Added {
+			log.Printf("  + %s", endpoint)
+		}
+		for _, endpoint := range diff.Removed {
+			log.Printf("  - %s", endpoint)
+		}
+		for _, endpoint := range diff.Changed {
+			log.Printf("  ~ %s", endpoint)
+		}
+	}
+	return cfg.Sources
+}
+
+// setFlags collects repeated --set NAME=value flags
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// configFlags collects repeated -config flags: a base file, then overlays
+type configFlags []string
+
+func (c *configFlags) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configFlags) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+// last returns the last config file given, or "" if there is none
+func (c configFlags) last() string {
+	if len(c) == 0 {
+		return ""
+	}
+	return c[len(c)-1]
+}
+
+// loadConfig loads the config files, the first as the base and the rest as
+// overlays over it, or the built-in preset when there are none, with --set
+// values taking precedence over the environment.
+// In strict mode unknown keys, module types and placements are errors.
+func loadConfig(configFiles configFlags, preset string, sets setFlags, strict bool) (*config.Config, error) {
+	vars, err := config.ParseVars(sets)
+	if err != nil {
+		return nil, err
+	}
+	if len(configFiles) == 0 {
+		return config.LoadPreset(preset, vars)
+	}
+	if strict {
+		return config.LoadStrict(configFiles[0], vars, configFiles[1:]...)
+	}
+	return config.LoadWithVars(configFiles[0], vars, configFiles[1:]...)
+}
+
+func schemaCommand() {
+	schemaFlags := flag.NewFlagSet("schema", flag.ExitOnError)
+	output := schemaFlags.String("output", "", "Write the schema to a file instead of stdout")
+	outputShort := schemaFlags.String("o", "", "Write the schema to a file instead of stdout (shorthand)")
+
+	schemaFlags.Parse(os.Args[2:])
+
+	outputFile := *output
+	if outputFile == "" {
+		outputFile = *outputShort
+	}
+
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s failed to encode schema: %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+
+	if outputFile == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n  %s✓ Schema written to%s %s\n\n", colorGreen, colorReset, outputFile)
+}
+
+// reportTimeout bounds each request report makes to a lab's admin API
+const reportTimeout = 10 * time.Second
+
+func reportCommand() {
+	reportFlags := flag.NewFlagSet("report", flag.ExitOnError)
+	var configFiles configFlags
+	reportFlags.Var(&configFiles, "config", "Path to the running lab's YAML config file (repeat to layer overlays over it)")
+	reportFlags.Var(&configFiles, "c", "Path to the running lab's YAML config file (shorthand)")
+	preset := reportFlags.String("preset", "", "The built-in lab running instead of a config file")
+	var sets setFlags
+	reportFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	port := reportFlags.Int("port", 0, "The port the lab was run with, if overridden")
+	portShort := reportFlags.Int("p", 0, "The port the lab was run with, if overridden (shorthand)")
+	admin := reportFlags.String("admin", "", "Admin API address, in place of app.admin's (host:port)")
+	token := reportFlags.String("token", "", "Admin token, in place of app.admin.token")
+	asJSON := reportFlags.Bool("json", false, "Print the reports as JSON")
+
+	reportFlags.Parse(os.Args[2:])
+
+	portOverride := *port
+	if portOverride == 0 {
+		portOverride = *portShort
+	}
+	if len(configFiles) == 0 && *preset == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config or -preset flag is required\n\n", colorRed, colorReset)
+		reportFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(configFiles, *preset, sets, false)
+	if err != nil {
+		printConfigError(strings.Join(configFiles, ", "), err)
+		os.Exit(1)
+	}
+	apps := cfg.Applications()
+	if (portOverride > 0 || *admin != "") && len(apps) > 1 {
+		fmt.Printf("\n  %s✗ Error:%s --port and --admin can't be used with multiple apps\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+	if portOverride > 0 {
+		cfg.App.Port = portOverride
+	}
+
+	client := &http.Client{Timeout: reportTimeout}
+	reports := make([]builder.CoverageReport, 0, len(apps))
+	failed := false
+	for _, app := range apps {
+		report, err := fetchCoverage(client, app, *admin, *token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\n  %s✗ Error:%s %s: %v\n", colorRed, colorReset, app.App.Name, err)
+			failed = true
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, report := range reports {
+			printCoverageReport(report)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fetchCoverage asks a running app's admin API for its coverage report, at
+// address and with token when they are set, else app.admin's
+func fetchCoverage(client *http.Client, app *config.Config, address, token string) (builder.CoverageReport, error) {
+	var report builder.CoverageReport
+	if address == "" {
+		if app.App.Admin == nil {
+			return report, fmt.Errorf("app.admin isn't set, so the lab has no admin API to report from (or pass --admin)")
+		}
+		address = app.App.Admin.Address(app.App.Port)
+	}
+	if token == "" && app.App.Admin != nil {
+		token = app.App.Admin.Token
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+address+"/coverage", nil)
+	if err != nil {
+		return report, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return report, fmt.Errorf("is the lab running? %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return report, fmt.Errorf("the admin API answered %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return report, fmt.Errorf("failed to read the report: %w", err)
+	}
+	return report, nil
+}
+
+// printCoverageReport prints which of an app's vulnerabilities were
+// triggered and exploited, and by which clients
+func printCoverageReport(report builder.CoverageReport) {
+	fmt.Println()
+	fmt.Printf("  %sCOVERAGE%s  %s\n", colorYellow, colorReset, report.App)
+	fmt.Printf("    %s%d of %d%s vulnerabilities triggered %s(%.1f%%)%s, %s%d exploited%s\n",
+		colorCyan, report.Triggered, report.Total, colorReset, colorDim, report.Percent, colorReset, colorRed, report.Exploited, colorReset)
+	fmt.Println()
+
+	width := 0
+	for _, vuln := range report.Vulnerabilities {
+		width = max(width, len(vuln.ID))
+	}
+	for _, vuln := range report.Vulnerabilities {
+		mark := colorDim + "·" + colorReset
+		switch {
+		case vuln.Exploits > 0:
+			mark = colorRed + "✓" + colorReset
+		case vuln.Triggered():
+			mark = colorYellow + "✓" + colorReset
+		}
+		state := ""
+		if !vuln.Enabled {
+			state = colorDim + " (disabled)" + colorReset
+		}
+		fmt.Printf("    %s %-*s  %s%s%s%s  %s%d requests, %d attacks, %d exploits%s\n",
+			mark, width, vuln.ID, colorGreen, vuln.Type, colorReset, state, colorDim, vuln.Requests, vuln.Attacks, vuln.Exploits, colorReset)
+		if len(vuln.AttackTypes) > 0 {
+			fmt.Printf("      %sattack types:%s %s\n", colorDim, colorReset, formatCounts(vuln.AttackTypes))
+		}
+		if len(vuln.Clients) > 0 {
+			fmt.Printf("      %sclients:%s      %s\n", colorDim, colorReset, formatCounts(vuln.Clients))
+		}
+	}
+
+	if len(report.Clients) > 0 {
+		fmt.Println()
+		fmt.Println(colorYellow + "  CLIENTS" + colorReset)
+		for _, client := range report.Clients {
+			fmt.Printf("    %s%s%s  %d triggered, %d exploited  %slast seen %s%s\n",
+				colorCyan, client.Client, colorReset, len(client.Triggered), len(client.Exploited), colorDim, client.LastSeen.Local().Format("2006-01-02 15:04:05"), colorReset)
+		}
+	}
+	fmt.Println()
+}
+
+// formatCounts lists counts by name, highest first, e.g. "sqli (3), xss (1)"
+func formatCounts(counts map[string]int64) string {
+	names := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s (%d)", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
 func modulesCommand() {
+	if len(os.Args) > 2 && os.Args[2] == "describe" {
+		describeCommand(os.Args[3:])
+		return
+	}
+
 	fmt.Println()
 	fmt.Println(colorCyan + colorBold + "┌─────────────────────────────────────────┐" + colorReset)
 	fmt.Println(colorCyan + colorBold + "│       AVAILABLE VULNERABILITY MODULES   │" + colorReset)
@@ -239,6 +815,7 @@ func modulesCommand() {
 		}
 		fmt.Println()
 	}
+	fmt.Printf("  %sRun '%sflawfactory modules describe <module>%s' for a module's config keys, example and payloads%s\n\n", colorDim, colorReset, colorDim, colorReset)
 }
 
 func printBanner() {
@@ -293,6 +870,22 @@ func printConfigSummary(cfg *config.Config) {
 	if len(cfg.Files) > 0 {
 		fmt.Printf("    %sFiles:%s       %s%d%s\n", colorDim, colorReset, colorCyan, len(cfg.Files), colorReset)
 	}
+
+	if cfg.Auth != nil {
+		fmt.Printf("    %sAuth:%s        %s%s%s\n", colorDim, colorReset, colorCyan, cfg.Auth.Type, colorReset)
+	}
+
+	if len(cfg.Flags) > 0 {
+		fmt.Printf("    %sFlags:%s       %s%d%s\n", colorDim, colorReset, colorCyan, len(cfg.Flags), colorReset)
+	}
+
+	if len(cfg.Static) > 0 {
+		fmt.Printf("    %sStatic:%s      %s%d%s\n", colorDim, colorReset, colorCyan, len(cfg.Static), colorReset)
+	}
+
+	if cfg.UI != nil {
+		fmt.Printf("    %sUI:%s          %s%s%s\n", colorDim, colorReset, colorCyan, cfg.UI.IndexPath(), colorReset)
+	}
 	fmt.Println()
 
 	// Count vulnerabilities by type
@@ -313,7 +906,11 @@ func printConfigSummary(cfg *config.Config) {
 	}
 
 	fmt.Println(colorDim + "  ─────────────────────────────────────────" + colorReset)
-	fmt.Printf("  %s✓ Server ready at:%s %shttp://%s:%d%s\n", colorGreen, colorReset, colorBold, host, cfg.App.Port, colorReset)
+	scheme := "http"
+	if cfg.App.TLS != nil && cfg.App.TLS.Enabled {
+		scheme = "https"
+	}
+	fmt.Printf("  %s✓ Server ready at:%s %s%s://%s:%d%s\n", colorGreen, colorReset, colorBold, scheme, host, cfg.App.Port, colorReset)
 	fmt.Println(colorDim + "  ─────────────────────────────────────────" + colorReset)
 	fmt.Println()
 }
@@ -336,26 +933,117 @@ func printUsage() {
 	// Commands section
 	fmt.Println(colorYellow + "  COMMANDS" + colorReset)
 	fmt.Printf("    %srun%s        %sStart the vulnerable web server%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %srun-all%s    %sStart every config in a directory at once, with a status table%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sinit%s       %sAnswer a few questions to write a new config%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sedit%s       %sAdd endpoints and vulnerabilities to a config, validating as you go%s\n", colorGreen, colorReset, colorDim, colorReset)
 	fmt.Printf("    %svalidate%s   %sValidate config file without starting%s\n", colorGreen, colorReset, colorDim, colorReset)
-	fmt.Printf("    %smodules%s    %sList available vulnerability modules%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %smodules%s    %sList available vulnerability modules, or describe one's config%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sschema%s     %sPrint a JSON Schema for config files%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sendpoints%s  %sList a config's endpoints with their vulnerabilities, difficulty and sinks%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sreport%s     %sShow which vulnerabilities a running lab's clients triggered%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sreplay%s     %sRe-send the requests in a request log to a lab%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %stest%s       %sRun a canonical exploit against each configured vulnerability of a fresh lab%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %sexport%s     %sWrite a config's endpoints as OpenAPI, Postman, Burp items, Nuclei templates or a walkthrough, or its Docker files or Kubernetes manifests%s\n", colorGreen, colorReset, colorDim, colorReset)
 	fmt.Println()
 
 	// Examples section
 	fmt.Println(colorYellow + "  EXAMPLES" + colorReset)
+	fmt.Printf("    %s# Write a first config, picking the vulnerabilities to include%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sinit%s\n", colorGreen, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Add vulnerabilities to it, picking from each module's options%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sedit%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
 	fmt.Printf("    %s# Start server with config%s\n", colorDim, colorReset)
 	fmt.Printf("    $ flawfactory %srun%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
 	fmt.Println()
 	fmt.Printf("    %s# Start on custom port%s\n", colorDim, colorReset)
 	fmt.Printf("    $ flawfactory %srun%s -c %sconfig.yaml%s -p %s9090%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
 	fmt.Println()
+	fmt.Printf("    %s# Serve a whole course's labs together, from port 8000 on%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun-all%s -d %sconfigs/%s --base-port %s8000%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Fill in ${PORT} and ${FLAG} placeholders%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun%s -c %sconfig.yaml%s --set %sPORT=9090%s --set %sFLAG=ctf{demo}%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Start a built-in OWASP Top 10 lab without writing any YAML%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun%s --preset %sowasp-top10-2021%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Layer a classroom variant over a base lab%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun%s -c %sbase.yaml%s -c %soverrides.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Reload the lab whenever the config changes (or send SIGHUP)%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun%s -c %sconfig.yaml%s --watch\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Restart a running lab in a new process, without dropping connections%s\n", colorDim, colorReset)
+	fmt.Printf("    $ kill -USR2 %s<pid>%s\n", colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Record the session for Burp and Wireshark, written on Ctrl+C%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %srun%s -c %sconfig.yaml%s --har %slab.har%s --pcap %slab.pcap%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# See which vulnerabilities students have exploited so far (needs app.admin)%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sreport%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Reproduce the logged SSRF attempts against a fresh lab, after a config change%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sreplay%s -l %slog/ssrf.json%s --filter %sattack=ssrf%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Check every vulnerability of a new lab can actually be exploited%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %stest%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Load the lab's endpoints into Swagger UI, Postman or an API scanner%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport openapi%s -c %sconfig.yaml%s -o %sopenapi.json%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Bootstrap Postman or Burp Suite with a request per endpoint and per vulnerability%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport postman%s -c %sconfig.yaml%s -o %slab.postman.json%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Write a Nuclei template per configured vulnerability, to benchmark a scanner%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport nuclei%s -c %sconfig.yaml%s -o %snuclei/%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Write the answer key: each vulnerability's payload, request and expected response%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport walkthrough%s -c %sconfig.yaml%s -o %sWALKTHROUGH.md%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Package the lab to run anywhere with docker compose up, OOB callbacks published%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport docker%s -c %sconfig.yaml%s -o %slab/%s --with %soob%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Deploy an instance per student, each with its own seed and NodePort%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sexport kubernetes%s -c %sconfig.yaml%s --students %s30%s -o %sclass.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset, colorCyan, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# Look up a module's config keys, defaults, example endpoint and payloads%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %smodules describe%s %sssrf%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
+	fmt.Printf("    %s# List each endpoint's vulnerabilities, for an answer key%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %sendpoints%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
 	fmt.Printf("    %s# Validate configuration%s\n", colorDim, colorReset)
 	fmt.Printf("    $ flawfactory %svalidate%s -c %sconfig.yaml%s\n", colorGreen, colorReset, colorCyan, colorReset)
 	fmt.Println()
+	fmt.Printf("    %s# Fail on typos and unknown module config keys%s\n", colorDim, colorReset)
+	fmt.Printf("    $ flawfactory %svalidate%s -c %sconfig.yaml%s --strict\n", colorGreen, colorReset, colorCyan, colorReset)
+	fmt.Println()
 
 	// Flags section
 	fmt.Println(colorYellow + "  FLAGS" + colorReset)
-	fmt.Printf("    %s-c, --config%s  %spath%s   %sPath to YAML configuration file%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
-	fmt.Printf("    %s-p, --port%s    %sint%s    %sOverride port from config%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s-c, --config%s  %spath%s   %sPath to YAML configuration file; repeat to add overlays%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s-p, --port%s    %sint%s    %sOverride port from config; test and replay pick a free one without it%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--preset%s      %sname%s   %sRun a built-in lab: %s (run)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, strings.Join(config.Presets(), ", "), colorReset)
+	fmt.Printf("    %s--set%s         %sk=v%s    %sSet a ${k} config variable (repeatable)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s-d, --dir%s     %spath%s   %sDirectory of configs to run together (run-all)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--base-port%s   %sint%s    %sGive the labs consecutive ports from this one (run-all)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--strict%s              %sReject unknown keys, module types and placements%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--watch%s               %sReload the config when its files change (run)%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--dry-run%s             %sBuild without listening and print the sinks, seeded data and routes (run)%s\n", colorGreen, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--har%s         %spath%s   %sRecord the traffic to a HAR file, written on shutdown (run)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--pcap%s        %spath%s   %sRecord the TCP streams to a pcap file, written on shutdown (run)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--admin%s       %saddr%s   %sAdmin API to report from, in place of app.admin's (report)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--token%s       %stoken%s  %sAdmin token, in place of app.admin.token (report)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s-l, --log%s     %spath%s   %sRequest log to replay (replay)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--filter%s      %sk=v%s    %sReplay only matching requests, by %s (replay, repeatable)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, strings.Join(replayFilterKeys, ", "), colorReset)
+	fmt.Printf("    %s--target%s      %surl%s    %sReplay against a running lab instead of a fresh one (replay)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--app%s         %sname%s   %sApp to export from a multi-app config (export)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--with%s        %slist%s   %sCompanions for the Docker files: %s (export docker)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, strings.Join(config.DockerCompanions, ", "), colorReset)
+	fmt.Printf("    %s--students%s    %sint%s    %sInstances of each lab, one per student (export kubernetes)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--node-port%s   %sint%s    %sFirst NodePort handed out to the instances (export kubernetes)%s\n", colorGreen, colorReset, colorCyan, colorReset, colorDim, colorReset)
+	fmt.Printf("    %s--json%s                %sPrint the coverage report, replay or test results, or the endpoints as JSON (report, replay, test, endpoints)%s\n", colorGreen, colorReset, colorDim, colorReset)
 	fmt.Printf("    %s-h, --help%s            %sShow help for a command%s\n", colorGreen, colorReset, colorDim, colorReset)
 	fmt.Println()
 
@@ -383,6 +1071,22 @@ func printConfigError(configFile string, err error) {
 		return
 	}
 
+	// Check if it's an unknown key rejected in strict mode
+	if strings.Contains(errStr, "not found in type") {
+		fmt.Printf("  %s%s UNKNOWN FIELDS%s\n", colorRed, "●", colorReset)
+		fmt.Printf("    %sThe configuration file has keys that FlawFactory doesn't recognize.%s\n", colorDim, colorReset)
+		fmt.Println()
+		for _, line := range strings.Split(errStr, "\n") {
+			if strings.Contains(line, "not found in type") {
+				fmt.Printf("    %s•%s %s\n", colorYellow, colorReset, strings.TrimSpace(line))
+			}
+		}
+		fmt.Println()
+		fmt.Printf("  %sTip:%s Check these keys for typos, or run without --strict to ignore them.\n", colorCyan, colorReset)
+		fmt.Println()
+		return
+	}
+
 	// Check if it's a YAML parse error
 	if strings.Contains(errStr, "failed to parse YAML") {
 		fmt.Printf("  %s%s YAML SYNTAX ERROR%s\n", colorRed, "●", colorReset)
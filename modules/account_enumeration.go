@@ -0,0 +1,269 @@
+package modules
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AccountEnumeration implements the account_enumeration vulnerability module
+type AccountEnumeration struct{}
+
+// init registers the module
+func init() {
+	Register(&AccountEnumeration{})
+}
+
+// Info returns module metadata
+func (m *AccountEnumeration) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "account_enumeration",
+		Description: "Account enumeration through login, registration and password reset response discrepancies",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "sqlite",
+		ValidVariants: map[string][]string{
+			"flow":        {"login", "register", "forgot_password"},
+			"discrepancy": {"message", "status_code", "timing", "none"},
+		},
+		ConfigKeys: []string{
+			"delay_ms", "email_column", "insert_template", "mail_from", "password_column", "password_param",
+			"query_template", "reset_host", "reset_url", "send_email", "show_errors", "trust_forwarded_host",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"discrepancy": "message"},
+			"medium": {"discrepancy": "status_code"},
+			"hard":   {"discrepancy": "timing", "show_errors": false},
+		},
+		Options: map[string]Option{
+			"query_template":       {Description: "Query looking up the account, with {input} replaced by the username; required"},
+			"flow":                 {Description: "Form the endpoint is: login, register or forgot_password", Default: "login"},
+			"discrepancy":          {Description: "What gives existing accounts away: message (message and status), status_code (status only), timing (existing accounts are delay_ms slower) or none", Default: "message"},
+			"delay_ms":             {Description: "For timing, milliseconds existing accounts add", Default: "250"},
+			"password_param":       {Description: "For login, the request parameter holding the password", Default: "password"},
+			"password_column":      {Description: "For login, the column the password is compared with", Default: "password"},
+			"insert_template":      {Description: "For register, statement creating a new account, with {input} replaced by the username"},
+			"email_column":         {Description: "For forgot_password, the column holding the account's email", Default: "email"},
+			"send_email":           {Description: "For forgot_password, mail a reset link to the account through the mail sink", Default: "false"},
+			"reset_host":           {Description: "Host in reset links; when unset it's taken from the request, so it can be poisoned"},
+			"trust_forwarded_host": {Description: "Take the reset link's host from X-Forwarded-Host when set", Default: "true"},
+			"reset_url":            {Description: "Reset link, with {host} and {token} replaced", Default: "http://{host}/reset-password?token={token}"},
+			"mail_from":            {Description: "Sender of reset emails", Default: "no-reply@flawfactory.local"},
+			"show_errors":          {Description: "Return database errors", Default: "true"},
+		},
+		Example: `- path: /login
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: account_enumeration
+      placement: form_field
+      param: username
+      config:
+        flow: login
+        discrepancy: message
+        query_template: "SELECT * FROM users WHERE username = '{input}'"
+`,
+		Payloads: []string{"admin", "administrator", "root", "test"},
+	}
+}
+
+// Handle looks up the username and answers with a response that leaks whether it exists
+func (m *AccountEnumeration) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+
+	// Get configuration
+	flow := ctx.GetConfigString("flow", "login")
+	discrepancy := ctx.GetConfigString("discrepancy", "message")
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	if queryTemplate == "" {
+		return nil, fmt.Errorf("query_template is required for account_enumeration")
+	}
+
+	if ctx.Input == "" {
+		return &Result{
+			Error:      "username is required",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", ctx.Input)
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": query,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	var user map[string]interface{}
+	if len(results) > 0 {
+		user = results[0]
+	}
+
+	// Timing discrepancy - only existing accounts pay for the (simulated) password hash check
+	if discrepancy == "timing" && user != nil {
+		time.Sleep(time.Duration(ctx.GetConfigInt("delay_ms", 250)) * time.Millisecond)
+	}
+
+	switch flow {
+	case "login":
+		return m.handleLogin(ctx, user, discrepancy), nil
+	case "register":
+		return m.handleRegister(ctx, user, discrepancy)
+	case "forgot_password":
+		return m.handleForgotPassword(ctx, user, discrepancy), nil
+	default:
+		return m.handleLogin(ctx, user, discrepancy), nil
+	}
+}
+
+// handleLogin checks the submitted password against the looked-up user
+func (m *AccountEnumeration) handleLogin(ctx *HandlerContext, user map[string]interface{}, discrepancy string) *Result {
+	passwordParam := ctx.GetConfigString("password_param", "password")
+	passwordColumn := ctx.GetConfigString("password_column", "password")
+
+	password := ""
+	if ctx.Request != nil {
+		password = ctx.Request.FormValue(passwordParam)
+	}
+
+	if user != nil && password != "" && fmt.Sprintf("%v", user[passwordColumn]) == password {
+		return NewResult(map[string]interface{}{
+			"message":  "Login successful",
+			"username": ctx.Input,
+		})
+	}
+
+	return enumerationResponse(discrepancy, user != nil,
+		"Invalid password", http.StatusUnauthorized,
+		"User not found", http.StatusNotFound,
+		"Invalid username or password", http.StatusUnauthorized)
+}
+
+// handleRegister refuses taken usernames and optionally inserts new ones
+func (m *AccountEnumeration) handleRegister(ctx *HandlerContext, user map[string]interface{}, discrepancy string) (*Result, error) {
+	if user == nil {
+		if insertTemplate := ctx.GetConfigString("insert_template", ""); insertTemplate != "" {
+			statement := strings.ReplaceAll(insertTemplate, "{input}", ctx.Input)
+			if err := ctx.Sinks.SQLite.Exec(statement); err != nil {
+				return &Result{
+					Error: err.Error(),
+					Data: map[string]interface{}{
+						"statement": statement,
+						"error":     err.Error(),
+					},
+				}, nil
+			}
+		}
+	}
+
+	return enumerationResponse(discrepancy, user != nil,
+		"Username is already taken", http.StatusConflict,
+		"Account created", http.StatusCreated,
+		"If the username is available, your account has been created", http.StatusOK), nil
+}
+
+// handleForgotPassword leaks account existence (and a masked email) through the reset flow
+func (m *AccountEnumeration) handleForgotPassword(ctx *HandlerContext, user map[string]interface{}, discrepancy string) *Result {
+	emailColumn := ctx.GetConfigString("email_column", "email")
+
+	validMessage := "Password reset link sent"
+	if user != nil && discrepancy == "message" {
+		if email, ok := user[emailColumn]; ok {
+			validMessage = fmt.Sprintf("Password reset link sent to %s", maskEmail(fmt.Sprintf("%v", email)))
+		}
+	}
+
+	if user != nil && ctx.GetConfigBool("send_email", false) && ctx.Sinks.Mail != nil {
+		if email, ok := user[emailColumn]; ok {
+			m.sendResetEmail(ctx, fmt.Sprintf("%v", email))
+		}
+	}
+
+	return enumerationResponse(discrepancy, user != nil,
+		validMessage, http.StatusOK,
+		"No account found with that username", http.StatusNotFound,
+		"If an account exists, a password reset link has been sent", http.StatusOK)
+}
+
+// sendResetEmail mails a reset link to the account. Unless reset_host is set, the link's
+// host comes from the request (X-Forwarded-Host first), so it can be poisoned.
+func (m *AccountEnumeration) sendResetEmail(ctx *HandlerContext, to string) {
+	host := ctx.GetConfigString("reset_host", "")
+	if host == "" && ctx.Request != nil {
+		host = ctx.Request.Host
+		if forwarded := ctx.Request.Header.Get("X-Forwarded-Host"); forwarded != "" && ctx.GetConfigBool("trust_forwarded_host", true) {
+			host = forwarded
+		}
+	}
+
+	token := ctx.RandomBytes(16)
+
+	link := ctx.GetConfigString("reset_url", "http://{host}/reset-password?token={token}")
+	link = strings.ReplaceAll(link, "{host}", host)
+	link = strings.ReplaceAll(link, "{token}", hex.EncodeToString(token))
+
+	from := ctx.GetConfigString("mail_from", "no-reply@flawfactory.local")
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Password reset\r\n\r\n"+
+		"Someone requested a password reset for %s.\r\nReset your password: %s\r\n",
+		from, to, ctx.Input, link)
+
+	ctx.Sinks.Mail.Send(from, nil, message)
+}
+
+// enumerationResponse builds the response for a flow according to the configured discrepancy
+func enumerationResponse(discrepancy string, exists bool, validMsg string, validStatus int, invalidMsg string, invalidStatus int, genericMsg string, genericStatus int) *Result {
+	message := genericMsg
+	status := genericStatus
+
+	switch discrepancy {
+	case "message":
+		// Different messages and status codes
+		if exists {
+			message, status = validMsg, validStatus
+		} else {
+			message, status = invalidMsg, invalidStatus
+		}
+	case "status_code":
+		// Same generic message, different status codes
+		if exists {
+			status = validStatus
+		} else {
+			status = invalidStatus
+		}
+	}
+
+	return &Result{
+		Data: map[string]interface{}{
+			"message": message,
+		},
+		StatusCode: status,
+	}
+}
+
+// maskEmail partially hides an email address (r***@example.com)
+func maskEmail(email string) string {
+	at := strings.Index(email, "@")
+	if at <= 0 {
+		return email
+	}
+	return email[:1] + "***" + email[at:]
+}
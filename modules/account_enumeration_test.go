@@ -0,0 +1,251 @@
+package modules
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEnumerationSink returns a mock sink that knows only the user "rick"
+func newEnumerationSink() *MockSQLiteSinkIDOR {
+	return &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			if strings.Contains(query, "'rick'") {
+				return []map[string]interface{}{
+					{"id": "2", "username": "rick", "password": "c137", "email": "rick@example.com"},
+				}, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+// TestAccountEnumeration_Info tests module metadata
+func TestAccountEnumeration_Info(t *testing.T) {
+	m := &AccountEnumeration{}
+	info := m.Info()
+
+	if info.Name != "account_enumeration" {
+		t.Errorf("Expected Name 'account_enumeration', got '%s'", info.Name)
+	}
+
+	if info.RequiresSink != "sqlite" {
+		t.Errorf("Expected RequiresSink 'sqlite', got '%s'", info.RequiresSink)
+	}
+}
+
+// TestAccountEnumeration_Login_MessageDiscrepancy tests different messages for valid/invalid users
+func TestAccountEnumeration_Login_MessageDiscrepancy(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	handle := func(username string) *Result {
+		ctx := &HandlerContext{
+			Input:   username,
+			Request: httptest.NewRequest("POST", "/login?password=wrong", nil),
+			Config: map[string]interface{}{
+				"flow":           "login",
+				"discrepancy":    "message",
+				"query_template": "SELECT * FROM users WHERE username = '{input}'",
+			},
+			Sinks: &SinkContext{SQLite: newEnumerationSink()},
+		}
+		result, err := m.Handle(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result
+	}
+
+	valid := handle("rick")
+	invalid := handle("nobody")
+
+	validMsg := valid.Data.(map[string]interface{})["message"]
+	invalidMsg := invalid.Data.(map[string]interface{})["message"]
+	if validMsg == invalidMsg {
+		t.Errorf("Expected different messages, both were '%v'", validMsg)
+	}
+	if valid.StatusCode == invalid.StatusCode {
+		t.Errorf("Expected different status codes, both were %d", valid.StatusCode)
+	}
+}
+
+// TestAccountEnumeration_Login_Success tests a correct password
+func TestAccountEnumeration_Login_Success(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	ctx := &HandlerContext{
+		Input:   "rick",
+		Request: httptest.NewRequest("POST", "/login?password=c137", nil),
+		Config: map[string]interface{}{
+			"query_template": "SELECT * FROM users WHERE username = '{input}'",
+		},
+		Sinks: &SinkContext{SQLite: newEnumerationSink()},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Data.(map[string]interface{})["message"] != "Login successful" {
+		t.Errorf("Expected successful login, got %v", result.Data)
+	}
+}
+
+// TestAccountEnumeration_StatusCodeDiscrepancy tests same message but different status codes
+func TestAccountEnumeration_StatusCodeDiscrepancy(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	handle := func(username string) *Result {
+		ctx := &HandlerContext{
+			Input: username,
+			Config: map[string]interface{}{
+				"flow":           "forgot_password",
+				"discrepancy":    "status_code",
+				"query_template": "SELECT * FROM users WHERE username = '{input}'",
+			},
+			Sinks: &SinkContext{SQLite: newEnumerationSink()},
+		}
+		result, _ := m.Handle(ctx)
+		return result
+	}
+
+	valid := handle("rick")
+	invalid := handle("nobody")
+
+	if valid.Data.(map[string]interface{})["message"] != invalid.Data.(map[string]interface{})["message"] {
+		t.Error("Expected identical messages for status_code discrepancy")
+	}
+	if valid.StatusCode == invalid.StatusCode {
+		t.Error("Expected different status codes for status_code discrepancy")
+	}
+}
+
+// TestAccountEnumeration_TimingDiscrepancy tests that only existing users are delayed
+func TestAccountEnumeration_TimingDiscrepancy(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	elapsed := func(username string) time.Duration {
+		ctx := &HandlerContext{
+			Input: username,
+			Config: map[string]interface{}{
+				"discrepancy":    "timing",
+				"delay_ms":       100,
+				"query_template": "SELECT * FROM users WHERE username = '{input}'",
+			},
+			Sinks: &SinkContext{SQLite: newEnumerationSink()},
+		}
+		start := time.Now()
+		m.Handle(ctx)
+		return time.Since(start)
+	}
+
+	if d := elapsed("rick"); d < 100*time.Millisecond {
+		t.Errorf("Expected delay for existing user, took %v", d)
+	}
+	if d := elapsed("nobody"); d >= 100*time.Millisecond {
+		t.Errorf("Expected no delay for unknown user, took %v", d)
+	}
+}
+
+// TestAccountEnumeration_Register tests taken usernames and inserts for new ones
+func TestAccountEnumeration_Register(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	sink := newEnumerationSink()
+	var inserted string
+	sink.ExecFunc = func(statement string) error {
+		inserted = statement
+		return nil
+	}
+
+	config := map[string]interface{}{
+		"flow":            "register",
+		"query_template":  "SELECT * FROM users WHERE username = '{input}'",
+		"insert_template": "INSERT INTO users (username) VALUES ('{input}')",
+	}
+
+	result, _ := m.Handle(&HandlerContext{Input: "rick", Config: config, Sinks: &SinkContext{SQLite: sink}})
+	if result.StatusCode != 409 {
+		t.Errorf("Expected 409 for taken username, got %d", result.StatusCode)
+	}
+	if inserted != "" {
+		t.Error("Expected no insert for a taken username")
+	}
+
+	result, _ = m.Handle(&HandlerContext{Input: "summer", Config: config, Sinks: &SinkContext{SQLite: sink}})
+	if result.StatusCode != 201 {
+		t.Errorf("Expected 201 for new username, got %d", result.StatusCode)
+	}
+	if inserted != "INSERT INTO users (username) VALUES ('summer')" {
+		t.Errorf("Unexpected insert statement: %q", inserted)
+	}
+}
+
+// TestMaskEmail tests email masking
+func TestMaskEmail(t *testing.T) {
+	if got := maskEmail("rick@example.com"); got != "r***@example.com" {
+		t.Errorf("Expected 'r***@example.com', got '%s'", got)
+	}
+	if got := maskEmail("invalid"); got != "invalid" {
+		t.Errorf("Expected 'invalid', got '%s'", got)
+	}
+}
+
+// MockMailSink records sent messages
+type MockMailSink struct {
+	Messages []string
+}
+
+func (m *MockMailSink) Send(from string, to []string, message string) (int, error) {
+	m.Messages = append(m.Messages, message)
+	return len(m.Messages), nil
+}
+
+// TestAccountEnumeration_ForgotPassword_ResetEmail tests that reset links follow the request host
+func TestAccountEnumeration_ForgotPassword_ResetEmail(t *testing.T) {
+	m := &AccountEnumeration{}
+
+	send := func(username string, config map[string]interface{}) *MockMailSink {
+		config["flow"] = "forgot_password"
+		config["query_template"] = "SELECT * FROM users WHERE username = '{input}'"
+		config["send_email"] = true
+
+		req := httptest.NewRequest("POST", "/forgot", nil)
+		req.Host = "shop.example.com"
+		req.Header.Set("X-Forwarded-Host", "evil.test")
+
+		mail := &MockMailSink{}
+		ctx := &HandlerContext{
+			Input:   username,
+			Request: req,
+			Config:  config,
+			Sinks:   &SinkContext{SQLite: newEnumerationSink(), Mail: mail},
+		}
+		if _, err := m.Handle(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return mail
+	}
+
+	poisoned := send("rick", map[string]interface{}{})
+	if len(poisoned.Messages) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(poisoned.Messages))
+	}
+	if !strings.Contains(poisoned.Messages[0], "To: rick@example.com") {
+		t.Errorf("Expected email to the account address, got %q", poisoned.Messages[0])
+	}
+	if !strings.Contains(poisoned.Messages[0], "http://evil.test/reset-password?token=") {
+		t.Errorf("Expected link on the forwarded host, got %q", poisoned.Messages[0])
+	}
+
+	fixed := send("rick", map[string]interface{}{"reset_host": "shop.example.com"})
+	if !strings.Contains(fixed.Messages[0], "http://shop.example.com/reset-password?token=") {
+		t.Errorf("Expected link on the configured host, got %q", fixed.Messages[0])
+	}
+
+	if unknown := send("nobody", map[string]interface{}{}); len(unknown.Messages) != 0 {
+		t.Error("Expected no email for an unknown account")
+	}
+}
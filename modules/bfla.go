@@ -0,0 +1,223 @@
+package modules
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// BFLA implements the Broken Function Level Authorization vulnerability module
+type BFLA struct{}
+
+// init registers the module
+func init() {
+	Register(&BFLA{})
+}
+
+// Info returns module metadata
+func (m *BFLA) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "bfla",
+		Description: "Broken Function Level Authorization - admin functions reachable by regular users through weak checks",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "sqlite",
+		ValidVariants: map[string][]string{
+			"function":   {"user", "admin"},
+			"auth_check": {"none", "header_role", "url_prefix", "strict"},
+		},
+		ConfigKeys: []string{
+			"admin_prefix", "admin_role", "admin_token", "query_template", "result_query", "role_header",
+			"show_errors", "success_message",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"auth_check": "none"},
+			"medium": {"auth_check": "header_role"},
+			"hard":   {"auth_check": "header_role", "show_errors": false},
+		},
+		Options: map[string]Option{
+			"query_template":  {Description: "Query or, for admin functions, statement run with {input} replaced by the input; required"},
+			"function":        {Description: "user runs a read-only query with no check; admin runs a statement behind auth_check", Default: "user"},
+			"auth_check":      {Description: "Check on admin functions: none, header_role (trusts role_header), url_prefix (only paths under admin_prefix need admin_token) or strict (always needs admin_token)", Default: "none"},
+			"role_header":     {Description: "Header header_role trusts", Default: "X-Role"},
+			"admin_role":      {Description: "role_header value header_role accepts", Default: "admin"},
+			"admin_prefix":    {Description: "Path prefix url_prefix protects", Default: "/admin"},
+			"admin_token":     {Description: "Bearer token url_prefix and strict accept; when unset no token is accepted"},
+			"success_message": {Description: "Message returned after an admin function", Default: "Admin action completed"},
+			"result_query":    {Description: "Query run after an admin function, to show its effect"},
+			"show_errors":     {Description: "Return database and authorization errors", Default: "true"},
+		},
+		Example: `- path: /api/users/promote
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: bfla
+      placement: form_field
+      param: username
+      config:
+        function: admin
+        auth_check: header_role
+        query_template: "UPDATE users SET role = 'admin' WHERE username = '{input}'"
+`,
+		Payloads: []string{"X-Role: admin", "Authorization: Bearer <token>"},
+	}
+}
+
+// Handle runs a user-level query or an admin-level mutation behind the configured check
+func (m *BFLA) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+
+	// Get configuration
+	function := ctx.GetConfigString("function", "user")
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	if queryTemplate == "" {
+		return nil, fmt.Errorf("query_template is required for bfla")
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", ctx.Input)
+
+	// User-level functions are read-only and need no authorization
+	if function != "admin" {
+		return m.runQuery(ctx, query, showErrors, map[string]interface{}{
+			"function": "user",
+		})
+	}
+
+	authCheck := ctx.GetConfigString("auth_check", "none")
+	allowed, authorizedBy, reason := m.authorize(ctx, authCheck)
+	if !allowed {
+		if showErrors {
+			return &Result{
+				Error: reason,
+				Data: map[string]interface{}{
+					"error":      reason,
+					"auth_check": authCheck,
+					"blocked":    true,
+				},
+				StatusCode: 403,
+			}, nil
+		}
+		return &Result{
+			Data:       map[string]interface{}{"message": "Access denied"},
+			StatusCode: 403,
+		}, nil
+	}
+
+	// Admin function - perform the real mutation
+	if err := ctx.Sinks.SQLite.Exec(query); err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"statement": query,
+					"error":     err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	data := map[string]interface{}{
+		"function":      "admin",
+		"message":       ctx.GetConfigString("success_message", "Admin action completed"),
+		"statement":     query,
+		"auth_check":    authCheck,
+		"authorized_by": authorizedBy,
+	}
+
+	// Optionally show the table state after the mutation
+	if resultQuery := ctx.GetConfigString("result_query", ""); resultQuery != "" {
+		return m.runQuery(ctx, strings.ReplaceAll(resultQuery, "{input}", ctx.Input), showErrors, data)
+	}
+
+	return NewResult(data), nil
+}
+
+// authorize applies the configured (intentionally weak) function-level check.
+// It returns whether the call is allowed, what granted it, and a denial reason.
+func (m *BFLA) authorize(ctx *HandlerContext, authCheck string) (bool, string, string) {
+	roleHeader := ctx.GetConfigString("role_header", "X-Role")
+	adminRole := ctx.GetConfigString("admin_role", "admin")
+	adminPrefix := ctx.GetConfigString("admin_prefix", "/admin")
+	adminToken := ctx.GetConfigString("admin_token", "")
+
+	switch authCheck {
+	case "header_role":
+		// Trusts a client-supplied role header
+		if ctx.Request != nil && ctx.Request.Header.Get(roleHeader) == adminRole {
+			return true, "header:" + roleHeader, ""
+		}
+		return false, "", fmt.Sprintf("forbidden: %s must be '%s'", roleHeader, adminRole)
+
+	case "url_prefix":
+		// Only routes under the admin prefix are protected - the same function
+		// exposed elsewhere (or with different casing) skips the check
+		path := ""
+		if ctx.Request != nil {
+			path = ctx.Request.URL.Path
+		}
+		if !strings.HasPrefix(path, adminPrefix) {
+			return true, "unprotected_path", ""
+		}
+		if m.hasAdminToken(ctx, adminToken) {
+			return true, "admin_token", ""
+		}
+		return false, "", "forbidden: admin token required for " + adminPrefix + " routes"
+
+	case "strict":
+		// Every call requires the admin token (secure comparison)
+		if m.hasAdminToken(ctx, adminToken) {
+			return true, "admin_token", ""
+		}
+		return false, "", "forbidden: admin token required"
+
+	default:
+		// none - admin functions are simply unguarded
+		return true, "none", ""
+	}
+}
+
+// hasAdminToken checks the Authorization header against the configured admin
+// token, in constant time
+func (m *BFLA) hasAdminToken(ctx *HandlerContext, adminToken string) bool {
+	if adminToken == "" || ctx.Request == nil {
+		return false
+	}
+	given := []byte(ctx.Request.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(given, []byte("Bearer "+adminToken)) == 1
+}
+
+// runQuery executes a read query and merges the rows into the response data
+func (m *BFLA) runQuery(ctx *HandlerContext, query string, showErrors bool, data map[string]interface{}) (*Result, error) {
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": query,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	if results == nil {
+		results = []map[string]interface{}{}
+	}
+	data["results"] = results
+	data["count"] = len(results)
+
+	return NewResult(data), nil
+}
@@ -0,0 +1,130 @@
+package modules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBFLASink returns a mock sink that records executed statements
+func newBFLASink(executed *[]string) *MockSQLiteSinkIDOR {
+	return &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{{"id": "1", "username": "admin"}}, nil
+		},
+		ExecFunc: func(statement string) error {
+			*executed = append(*executed, statement)
+			return nil
+		},
+	}
+}
+
+// runBFLA executes an admin delete against the given path with optional headers
+func runBFLA(t *testing.T, authCheck, path string, headers map[string]string) (*Result, []string) {
+	t.Helper()
+	m := &BFLA{}
+	var executed []string
+
+	req := httptest.NewRequest("DELETE", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	result, err := m.Handle(&HandlerContext{
+		Input: "2",
+		Config: map[string]interface{}{
+			"function":       "admin",
+			"auth_check":     authCheck,
+			"admin_token":    "s3cr3t",
+			"query_template": "DELETE FROM users WHERE id = {input}",
+		},
+		Sinks:   &SinkContext{SQLite: newBFLASink(&executed)},
+		Request: req,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result, executed
+}
+
+// TestBFLA_Info tests module metadata
+func TestBFLA_Info(t *testing.T) {
+	m := &BFLA{}
+	info := m.Info()
+
+	if info.Name != "bfla" {
+		t.Errorf("Expected Name 'bfla', got '%s'", info.Name)
+	}
+	if info.RequiresSink != "sqlite" {
+		t.Errorf("Expected RequiresSink 'sqlite', got '%s'", info.RequiresSink)
+	}
+}
+
+// TestBFLA_UserFunction tests the read-only user function
+func TestBFLA_UserFunction(t *testing.T) {
+	m := &BFLA{}
+	var executed []string
+
+	result, err := m.Handle(&HandlerContext{
+		Config: map[string]interface{}{"query_template": "SELECT id, username FROM users"},
+		Sinks:  &SinkContext{SQLite: newBFLASink(&executed)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Data.(map[string]interface{})["count"] != 1 {
+		t.Errorf("Expected 1 result, got %v", result.Data)
+	}
+	if len(executed) != 0 {
+		t.Errorf("Expected no mutations for user function, got %v", executed)
+	}
+}
+
+// TestBFLA_AuthChecks tests each authorization check
+func TestBFLA_AuthChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		authCheck string
+		path      string
+		headers   map[string]string
+		allowed   bool
+	}{
+		{"none", "none", "/admin/users/2", nil, true},
+		{"header_role_missing", "header_role", "/admin/users/2", nil, false},
+		{"header_role_spoofed", "header_role", "/admin/users/2", map[string]string{"X-Role": "admin"}, true},
+		{"url_prefix_protected", "url_prefix", "/admin/users/2", nil, false},
+		{"url_prefix_bypass", "url_prefix", "/api/users/2", nil, true},
+		{"url_prefix_case_bypass", "url_prefix", "/Admin/users/2", nil, true},
+		{"strict_spoofed_role", "strict", "/api/users/2", map[string]string{"X-Role": "admin"}, false},
+		{"strict_token", "strict", "/admin/users/2", map[string]string{"Authorization": "Bearer s3cr3t"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, executed := runBFLA(t, tt.authCheck, tt.path, tt.headers)
+
+			if tt.allowed {
+				if len(executed) != 1 || executed[0] != "DELETE FROM users WHERE id = 2" {
+					t.Errorf("Expected delete to execute, got %v", executed)
+				}
+			} else {
+				if result.StatusCode != http.StatusForbidden {
+					t.Errorf("Expected 403, got %d", result.StatusCode)
+				}
+				if len(executed) != 0 {
+					t.Errorf("Expected no mutation, got %v", executed)
+				}
+			}
+		})
+	}
+}
+
+// TestBFLA_NoSink tests error when SQLite sink is missing
+func TestBFLA_NoSink(t *testing.T) {
+	m := &BFLA{}
+
+	if _, err := m.Handle(&HandlerContext{Sinks: &SinkContext{}}); err == nil {
+		t.Error("Expected error when SQLite sink is not available")
+	}
+}
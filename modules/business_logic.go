@@ -0,0 +1,473 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BusinessLogic implements the business_logic vulnerability module
+type BusinessLogic struct{}
+
+// init registers the module
+func init() {
+	Register(&BusinessLogic{})
+}
+
+// Tables managed by the business_logic module (created on first use)
+var businessLogicSchema = []string{
+	"CREATE TABLE IF NOT EXISTS cart_items (cart_id TEXT, product_id TEXT, name TEXT, price REAL, quantity INTEGER)",
+	"CREATE TABLE IF NOT EXISTS cart_coupons (cart_id TEXT, code TEXT, percent REAL)",
+	"CREATE TABLE IF NOT EXISTS checkout_orders (id INTEGER PRIMARY KEY, cart_id TEXT, total REAL, status TEXT)",
+}
+
+// Info returns module metadata
+func (m *BusinessLogic) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "business_logic",
+		Description: "Cart and checkout API with price/quantity manipulation, coupon stacking and payment step skipping",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "sqlite",
+		ValidVariants: map[string][]string{
+			"action": {"add_item", "apply_coupon", "view_cart", "checkout", "pay", "confirm"},
+		},
+		ConfigKeys: []string{
+			"allow_coupon_stacking", "allow_negative_quantity", "cart_cookie", "coupons", "name_column",
+			"price_column", "price_param", "product_query", "quantity_param", "require_payment",
+			"secret", "trust_client_price",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"trust_client_price": true, "allow_negative_quantity": true, "allow_coupon_stacking": true},
+			"medium": {"trust_client_price": false, "allow_negative_quantity": true, "allow_coupon_stacking": true},
+			"hard":   {"trust_client_price": false, "allow_negative_quantity": false, "allow_coupon_stacking": true},
+		},
+		Options: map[string]Option{
+			"action":                  {Description: "Step the endpoint performs: add_item (the input is a product ID), apply_coupon (a coupon code), view_cart, checkout, pay or confirm (an order ID)", Default: "view_cart"},
+			"product_query":           {Description: "Query looking up a product, with {input} replaced by its ID", Default: "SELECT * FROM products WHERE id = {input}"},
+			"price_column":            {Description: "Product column holding the price", Default: "price"},
+			"name_column":             {Description: "Product column holding the name", Default: "name"},
+			"trust_client_price":      {Description: "Let price_param override the catalog price", Default: "true"},
+			"price_param":             {Description: "Request parameter trust_client_price takes the price from", Default: "price"},
+			"allow_negative_quantity": {Description: "Accept negative quantities, which lower the total", Default: "true"},
+			"quantity_param":          {Description: "Request parameter holding the quantity", Default: "quantity"},
+			"coupons":                 {Description: "Map of coupon code to percent off"},
+			"allow_coupon_stacking":   {Description: "Accept more than one coupon, or the same one again, per cart", Default: "true"},
+			"require_payment":         {Description: "Make confirm refuse unpaid orders", Default: "false"},
+			"cart_cookie":             {Description: "Cookie holding the cart ID", Default: "cart_id"},
+			"secret":                  {Description: "Value confirm reveals for unpaid or free orders"},
+		},
+		Example: `- path: /cart/add
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: business_logic
+      placement: form_field
+      param: product_id
+      config:
+        action: add_item
+        trust_client_price: true
+`,
+		Payloads: []string{"product_id=1&price=0.01", "product_id=1&quantity=-5", "code=SAVE10 (applied twice)"},
+	}
+}
+
+// Handle performs one step of the shopping flow for the cart identified by the cart cookie
+func (m *BusinessLogic) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+
+	for _, statement := range businessLogicSchema {
+		if err := ctx.Sinks.SQLite.Exec(statement); err != nil {
+			return nil, fmt.Errorf("failed to prepare cart tables: %w", err)
+		}
+	}
+
+	action := ctx.GetConfigString("action", "view_cart")
+	cartID := m.cartID(ctx)
+
+	switch action {
+	case "add_item":
+		return m.handleAddItem(ctx, cartID)
+	case "apply_coupon":
+		return m.handleApplyCoupon(ctx, cartID)
+	case "view_cart":
+		return m.handleViewCart(ctx, cartID)
+	case "checkout":
+		return m.handleCheckout(ctx, cartID)
+	case "pay":
+		return m.handlePay(ctx)
+	case "confirm":
+		return m.handleConfirm(ctx)
+	default:
+		return m.handleViewCart(ctx, cartID)
+	}
+}
+
+// cartID returns the cart from the cart cookie, issuing a new one if needed
+func (m *BusinessLogic) cartID(ctx *HandlerContext) string {
+	cartCookie := ctx.GetConfigString("cart_cookie", "cart_id")
+
+	if ctx.Request != nil {
+		if c, err := ctx.Request.Cookie(cartCookie); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+
+	id := hex.EncodeToString(ctx.RandomBytes(8))
+	if ctx.ResponseWriter != nil {
+		http.SetCookie(ctx.ResponseWriter, &http.Cookie{Name: cartCookie, Value: id, Path: "/"})
+	}
+	return id
+}
+
+// handleAddItem adds the product in the input to the cart
+func (m *BusinessLogic) handleAddItem(ctx *HandlerContext, cartID string) (*Result, error) {
+	productQuery := ctx.GetConfigString("product_query", "SELECT * FROM products WHERE id = {input}")
+	priceColumn := ctx.GetConfigString("price_column", "price")
+	nameColumn := ctx.GetConfigString("name_column", "name")
+	trustClientPrice := ctx.GetConfigBool("trust_client_price", true)
+	allowNegative := ctx.GetConfigBool("allow_negative_quantity", true)
+
+	products, err := ctx.Sinks.SQLite.Query(strings.ReplaceAll(productQuery, "{input}", ctx.Input))
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	if len(products) == 0 {
+		return &Result{
+			Data:       map[string]interface{}{"message": "Product not found"},
+			StatusCode: http.StatusNotFound,
+		}, nil
+	}
+	product := products[0]
+
+	quantity := 1
+	if raw := requestValue(ctx, ctx.GetConfigString("quantity_param", "quantity")); raw != "" {
+		quantity, err = strconv.Atoi(raw)
+		if err != nil {
+			return &Result{
+				Error:      "quantity must be an integer",
+				StatusCode: http.StatusBadRequest,
+			}, nil
+		}
+	}
+	if quantity == 0 || (!allowNegative && quantity < 0) {
+		return &Result{
+			Error:      "quantity must be a positive number",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	price := toFloat(product[priceColumn])
+	priceSource := "server"
+	if trustClientPrice {
+		// The price submitted by the client overrides the catalog price
+		if raw := requestValue(ctx, ctx.GetConfigString("price_param", "price")); raw != "" {
+			if clientPrice, err := strconv.ParseFloat(raw, 64); err == nil {
+				price = clientPrice
+				priceSource = "client"
+			}
+		}
+	}
+
+	if math.IsNaN(price) || math.IsInf(price, 0) {
+		return &Result{
+			Error:      "price must be a finite number",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	name := fmt.Sprintf("%v", product[nameColumn])
+	if _, err := ctx.Sinks.SQLite.ExecArgs("INSERT INTO cart_items (cart_id, product_id, name, price, quantity) VALUES (?, ?, ?, ?, ?)",
+		cartID, ctx.Input, name, price, quantity); err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+
+	cart, err := m.loadCart(ctx, cartID)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	cart["message"] = fmt.Sprintf("Added %d x %s", quantity, name)
+	cart["price_source"] = priceSource
+
+	return NewResult(cart), nil
+}
+
+// handleApplyCoupon applies the coupon code in the input to the cart
+func (m *BusinessLogic) handleApplyCoupon(ctx *HandlerContext, cartID string) (*Result, error) {
+	allowStacking := ctx.GetConfigBool("allow_coupon_stacking", true)
+
+	percent, ok := getCoupons(ctx.Config)[ctx.Input]
+	if !ok {
+		return &Result{
+			Data:       map[string]interface{}{"message": "Invalid coupon code"},
+			StatusCode: http.StatusNotFound,
+		}, nil
+	}
+
+	if !allowStacking {
+		applied, err := ctx.Sinks.SQLite.Query(fmt.Sprintf("SELECT code FROM cart_coupons WHERE cart_id = %s", sqlQuote(cartID)))
+		if err != nil {
+			return &Result{Error: err.Error()}, nil
+		}
+		if len(applied) > 0 {
+			return &Result{
+				Data:       map[string]interface{}{"message": "A coupon has already been applied to this cart"},
+				StatusCode: http.StatusConflict,
+			}, nil
+		}
+	}
+
+	if _, err := ctx.Sinks.SQLite.ExecArgs("INSERT INTO cart_coupons (cart_id, code, percent) VALUES (?, ?, ?)",
+		cartID, ctx.Input, percent); err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+
+	cart, err := m.loadCart(ctx, cartID)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	cart["message"] = fmt.Sprintf("Coupon %s applied (%v%% off)", ctx.Input, percent)
+
+	return NewResult(cart), nil
+}
+
+// handleViewCart returns the cart contents and totals
+func (m *BusinessLogic) handleViewCart(ctx *HandlerContext, cartID string) (*Result, error) {
+	cart, err := m.loadCart(ctx, cartID)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	return NewResult(cart), nil
+}
+
+// handleCheckout turns the cart into an order awaiting payment
+func (m *BusinessLogic) handleCheckout(ctx *HandlerContext, cartID string) (*Result, error) {
+	cart, err := m.loadCart(ctx, cartID)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	if cart["item_count"].(int) == 0 {
+		return &Result{
+			Data:       map[string]interface{}{"message": "Cart is empty"},
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	// The order ID is the row ID SQLite assigns, unique however many checkouts race
+	total := cart["total"].(float64)
+	orderID, err := ctx.Sinks.SQLite.ExecArgs("INSERT INTO checkout_orders (cart_id, total, status) VALUES (?, ?, 'pending_payment')", cartID, total)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	for _, statement := range []string{
+		"DELETE FROM cart_items WHERE cart_id = ?",
+		"DELETE FROM cart_coupons WHERE cart_id = ?",
+	} {
+		if _, err := ctx.Sinks.SQLite.ExecArgs(statement, cartID); err != nil {
+			return &Result{Error: err.Error()}, nil
+		}
+	}
+
+	return NewResult(map[string]interface{}{
+		"message":  "Order created, awaiting payment",
+		"order_id": orderID,
+		"total":    total,
+		"status":   "pending_payment",
+	}), nil
+}
+
+// handlePay marks the order in the input as paid
+func (m *BusinessLogic) handlePay(ctx *HandlerContext) (*Result, error) {
+	order, result := m.loadOrder(ctx)
+	if result != nil {
+		return result, nil
+	}
+
+	if _, err := ctx.Sinks.SQLite.ExecArgs("UPDATE checkout_orders SET status = 'paid' WHERE id = ?", ctx.Input); err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+
+	return NewResult(map[string]interface{}{
+		"message":  "Payment accepted",
+		"order_id": order["id"],
+		"charged":  toFloat(order["total"]),
+		"status":   "paid",
+	}), nil
+}
+
+// handleConfirm ships the order in the input, optionally without checking payment
+func (m *BusinessLogic) handleConfirm(ctx *HandlerContext) (*Result, error) {
+	requirePayment := ctx.GetConfigBool("require_payment", false)
+
+	order, result := m.loadOrder(ctx)
+	if result != nil {
+		return result, nil
+	}
+
+	status := fmt.Sprintf("%v", order["status"])
+	if requirePayment && status != "paid" {
+		return &Result{
+			Data: map[string]interface{}{
+				"message": "Order has not been paid",
+				"status":  status,
+			},
+			StatusCode: http.StatusPaymentRequired,
+		}, nil
+	}
+
+	if _, err := ctx.Sinks.SQLite.ExecArgs("UPDATE checkout_orders SET status = 'shipped' WHERE id = ?", ctx.Input); err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+
+	data := map[string]interface{}{
+		"message":         "Order confirmed and shipped",
+		"order_id":        order["id"],
+		"total":           toFloat(order["total"]),
+		"previous_status": status,
+		"status":          "shipped",
+	}
+
+	// Reward a successful logic abuse (unpaid or non-positive total)
+	if secret := ctx.GetConfigString("secret", ""); secret != "" && (status != "paid" || toFloat(order["total"]) <= 0) {
+		data["secret"] = secret
+	}
+
+	return NewResult(data), nil
+}
+
+// loadCart reads the cart items and coupons and computes the totals
+func (m *BusinessLogic) loadCart(ctx *HandlerContext, cartID string) (map[string]interface{}, error) {
+	items, err := ctx.Sinks.SQLite.Query(fmt.Sprintf("SELECT product_id, name, price, quantity FROM cart_items WHERE cart_id = %s", sqlQuote(cartID)))
+	if err != nil {
+		return nil, err
+	}
+	coupons, err := ctx.Sinks.SQLite.Query(fmt.Sprintf("SELECT code, percent FROM cart_coupons WHERE cart_id = %s", sqlQuote(cartID)))
+	if err != nil {
+		return nil, err
+	}
+
+	subtotal := 0.0
+	for _, item := range items {
+		subtotal += toFloat(item["price"]) * toFloat(item["quantity"])
+	}
+
+	// Discounts are added up, so stacked coupons can exceed 100%
+	discount := 0.0
+	for _, coupon := range coupons {
+		discount += toFloat(coupon["percent"])
+	}
+
+	if items == nil {
+		items = []map[string]interface{}{}
+	}
+	if coupons == nil {
+		coupons = []map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"cart_id":    cartID,
+		"items":      items,
+		"item_count": len(items),
+		"coupons":    coupons,
+		"subtotal":   subtotal,
+		"discount":   discount,
+		"total":      subtotal * (1 - discount/100),
+	}, nil
+}
+
+// loadOrder reads the order whose ID is in the input, returning a result on failure
+func (m *BusinessLogic) loadOrder(ctx *HandlerContext) (map[string]interface{}, *Result) {
+	if _, err := strconv.Atoi(ctx.Input); err != nil {
+		return nil, &Result{
+			Error:      "order ID must be numeric",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	orders, err := ctx.Sinks.SQLite.Query(fmt.Sprintf("SELECT * FROM checkout_orders WHERE id = %s", ctx.Input))
+	if err != nil {
+		return nil, &Result{Error: err.Error()}
+	}
+	if len(orders) == 0 {
+		return nil, &Result{
+			Data:       map[string]interface{}{"message": "Order not found"},
+			StatusCode: http.StatusNotFound,
+		}
+	}
+	return orders[0], nil
+}
+
+// getCoupons reads the coupon code → percent map from config
+func getCoupons(cfg map[string]interface{}) map[string]float64 {
+	coupons := map[string]float64{}
+	raw, ok := cfg["coupons"].(map[string]interface{})
+	if !ok {
+		return coupons
+	}
+	for code, value := range raw {
+		coupons[code] = toFloat(value)
+	}
+	return coupons
+}
+
+// requestValue reads a value from the JSON body when the input is a
+// json_field, else from the query string or form body
+func requestValue(ctx *HandlerContext, name string) string {
+	if ctx.Request == nil {
+		return ""
+	}
+	if ctx.Placement == "json_field" {
+		decoder := json.NewDecoder(bytes.NewReader(ctx.Body))
+		decoder.UseNumber() // Keep the number as sent, such as -1 or 0.01
+		var body map[string]interface{}
+		if decoder.Decode(&body) == nil {
+			switch value := body[name].(type) {
+			case json.Number:
+				return value.String()
+			case string:
+				return value
+			}
+		}
+	}
+	return ctx.Request.FormValue(name)
+}
+
+// toFloat converts a database or config value to float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	case []byte:
+		f, _ := strconv.ParseFloat(string(n), 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+// sqlQuote quotes a string literal for SQLite
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
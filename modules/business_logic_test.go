@@ -0,0 +1,292 @@
+package modules
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newCartSink returns a mock sink with one product and records executed statements
+func newCartSink(executed *[]string) *MockSQLiteSinkIDOR {
+	return &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			switch {
+			case strings.Contains(query, "FROM products"):
+				return []map[string]interface{}{{"id": "1", "name": "Portal Gun", "price": "999.99"}}, nil
+			case strings.Contains(query, "FROM cart_coupons"):
+				return []map[string]interface{}{{"code": "HALF", "percent": 50.0}}, nil
+			case strings.Contains(query, "FROM checkout_orders"):
+				return []map[string]interface{}{{"id": int64(1), "total": 10.0, "status": "pending_payment"}}, nil
+			}
+			return nil, nil
+		},
+		ExecFunc: func(statement string) error {
+			*executed = append(*executed, statement)
+			return nil
+		},
+		ExecArgsFunc: func(statement string, args ...interface{}) (int64, error) {
+			*executed = append(*executed, fmt.Sprintf("%s %v", statement, args))
+			return 7, nil
+		},
+	}
+}
+
+// cartRequest builds a request for the given cart with optional form values
+func cartRequest(query string) *http.Request {
+	req := httptest.NewRequest("POST", "/cart?"+query, nil)
+	req.AddCookie(&http.Cookie{Name: "cart_id", Value: "cart1"})
+	return req
+}
+
+// findStatement returns the first executed statement with the given prefix
+func findStatement(executed []string, prefix string) string {
+	for _, s := range executed {
+		if strings.HasPrefix(s, prefix) {
+			return s
+		}
+	}
+	return ""
+}
+
+// TestBusinessLogic_Info tests module metadata
+func TestBusinessLogic_Info(t *testing.T) {
+	m := &BusinessLogic{}
+	info := m.Info()
+
+	if info.Name != "business_logic" {
+		t.Errorf("Expected Name 'business_logic', got '%s'", info.Name)
+	}
+	if info.RequiresSink != "sqlite" {
+		t.Errorf("Expected RequiresSink 'sqlite', got '%s'", info.RequiresSink)
+	}
+}
+
+// TestBusinessLogic_AddItem_NegativeQuantityAndClientPrice tests quantity and price manipulation
+func TestBusinessLogic_AddItem_NegativeQuantityAndClientPrice(t *testing.T) {
+	m := &BusinessLogic{}
+	var executed []string
+
+	result, err := m.Handle(&HandlerContext{
+		Input:   "1",
+		Config:  map[string]interface{}{"action": "add_item"},
+		Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+		Request: cartRequest("quantity=-5&price=0.01"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	insert := findStatement(executed, "INSERT INTO cart_items")
+	if !strings.Contains(insert, "0.01 -5]") {
+		t.Errorf("Expected client price and negative quantity to be stored, got %q", insert)
+	}
+
+	if result.Data.(map[string]interface{})["price_source"] != "client" {
+		t.Error("Expected price_source 'client'")
+	}
+}
+
+// TestBusinessLogic_AddItem_JSONBody tests price and quantity are read from a
+// JSON body when the product ID is a json_field
+func TestBusinessLogic_AddItem_JSONBody(t *testing.T) {
+	m := &BusinessLogic{}
+	var executed []string
+
+	body := `{"product_id": 1, "quantity": -5, "price": 0.01}`
+	req := httptest.NewRequest("POST", "/cart", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "cart_id", Value: "cart1"})
+	result, err := m.Handle(&HandlerContext{
+		Input:     "1",
+		Placement: "json_field",
+		Config:    map[string]interface{}{"action": "add_item"},
+		Sinks:     &SinkContext{SQLite: newCartSink(&executed)},
+		Request:   req,
+		Body:      []byte(body),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	insert := findStatement(executed, "INSERT INTO cart_items")
+	if !strings.Contains(insert, "0.01 -5]") {
+		t.Errorf("Expected client price and negative quantity from the body to be stored, got %q", insert)
+	}
+	if result.Data.(map[string]interface{})["price_source"] != "client" {
+		t.Error("Expected price_source 'client'")
+	}
+}
+
+// TestBusinessLogic_AddItem_Hardened tests that the flaws can be turned off
+func TestBusinessLogic_AddItem_Hardened(t *testing.T) {
+	m := &BusinessLogic{}
+	var executed []string
+
+	config := map[string]interface{}{
+		"action":                  "add_item",
+		"trust_client_price":      false,
+		"allow_negative_quantity": false,
+	}
+
+	result, _ := m.Handle(&HandlerContext{
+		Input:   "1",
+		Config:  config,
+		Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+		Request: cartRequest("quantity=-5"),
+	})
+	if result.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for negative quantity, got %d", result.StatusCode)
+	}
+
+	m.Handle(&HandlerContext{
+		Input:   "1",
+		Config:  config,
+		Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+		Request: cartRequest("quantity=1&price=0.01"),
+	})
+	if insert := findStatement(executed, "INSERT INTO cart_items"); !strings.Contains(insert, "999.99 1]") {
+		t.Errorf("Expected catalog price to be used, got %q", insert)
+	}
+}
+
+// TestBusinessLogic_AddItem_NonFinitePrice tests that NaN and infinite client prices are refused
+func TestBusinessLogic_AddItem_NonFinitePrice(t *testing.T) {
+	m := &BusinessLogic{}
+
+	for _, price := range []string{"NaN", "Inf", "-Infinity"} {
+		var executed []string
+		result, _ := m.Handle(&HandlerContext{
+			Input:   "1",
+			Config:  map[string]interface{}{"action": "add_item"},
+			Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+			Request: cartRequest("price=" + price),
+		})
+		if result.StatusCode != http.StatusBadRequest {
+			t.Errorf("price=%s: expected 400, got %d", price, result.StatusCode)
+		}
+		if insert := findStatement(executed, "INSERT INTO cart_items"); insert != "" {
+			t.Errorf("price=%s: expected nothing stored, got %q", price, insert)
+		}
+	}
+}
+
+// TestBusinessLogic_Checkout tests that the order ID is the inserted row's
+func TestBusinessLogic_Checkout(t *testing.T) {
+	m := &BusinessLogic{}
+	var executed []string
+
+	sink := newCartSink(&executed)
+	query := sink.QueryFunc
+	sink.QueryFunc = func(q string) ([]map[string]interface{}, error) {
+		if strings.Contains(q, "FROM cart_items") {
+			return []map[string]interface{}{{"product_id": "1", "name": "Portal Gun", "price": 10.0, "quantity": int64(1)}}, nil
+		}
+		return query(q)
+	}
+
+	result, _ := m.Handle(&HandlerContext{
+		Config:  map[string]interface{}{"action": "checkout"},
+		Sinks:   &SinkContext{SQLite: sink},
+		Request: cartRequest(""),
+	})
+	if id := result.Data.(map[string]interface{})["order_id"]; id != int64(7) {
+		t.Errorf("Expected order_id 7 from the insert, got %v", id)
+	}
+	if insert := findStatement(executed, "INSERT INTO checkout_orders"); !strings.Contains(insert, "[cart1 ") {
+		t.Errorf("Expected the cart ID bound as a parameter, got %q", insert)
+	}
+}
+
+// TestBusinessLogic_CouponStacking tests stacking with and without the flaw
+func TestBusinessLogic_CouponStacking(t *testing.T) {
+	m := &BusinessLogic{}
+
+	for _, allow := range []bool{true, false} {
+		var executed []string
+		result, _ := m.Handle(&HandlerContext{
+			Input: "HALF",
+			Config: map[string]interface{}{
+				"action":                "apply_coupon",
+				"allow_coupon_stacking": allow,
+				"coupons":               map[string]interface{}{"HALF": 50},
+			},
+			Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+			Request: cartRequest(""),
+		})
+
+		inserted := findStatement(executed, "INSERT INTO cart_coupons") != ""
+		if inserted != allow {
+			t.Errorf("allow_coupon_stacking=%v: expected insert=%v, got %v", allow, allow, inserted)
+		}
+		if !allow && result.StatusCode != http.StatusConflict {
+			t.Errorf("Expected 409 when stacking is disabled, got %d", result.StatusCode)
+		}
+	}
+}
+
+// TestBusinessLogic_SkipPayment tests confirming an unpaid order
+func TestBusinessLogic_SkipPayment(t *testing.T) {
+	m := &BusinessLogic{}
+
+	var executed []string
+	result, _ := m.Handle(&HandlerContext{
+		Input:   "1",
+		Config:  map[string]interface{}{"action": "confirm", "secret": "FLAG{free_stuff}"},
+		Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+		Request: cartRequest(""),
+	})
+
+	data := result.Data.(map[string]interface{})
+	if data["status"] != "shipped" || data["secret"] != "FLAG{free_stuff}" {
+		t.Errorf("Expected unpaid order to ship with secret, got %v", data)
+	}
+
+	result, _ = m.Handle(&HandlerContext{
+		Input:   "1",
+		Config:  map[string]interface{}{"action": "confirm", "require_payment": true},
+		Sinks:   &SinkContext{SQLite: newCartSink(&executed)},
+		Request: cartRequest(""),
+	})
+	if result.StatusCode != http.StatusPaymentRequired {
+		t.Errorf("Expected 402 when payment is required, got %d", result.StatusCode)
+	}
+}
+
+// TestBusinessLogic_NewCartCookie tests that a cart cookie is issued when missing
+func TestBusinessLogic_NewCartCookie(t *testing.T) {
+	m := &BusinessLogic{}
+	rec := httptest.NewRecorder()
+	var executed []string
+
+	m.Handle(&HandlerContext{
+		Config:         map[string]interface{}{"action": "view_cart"},
+		Sinks:          &SinkContext{SQLite: newCartSink(&executed)},
+		Request:        httptest.NewRequest("GET", "/cart", nil),
+		ResponseWriter: rec,
+	})
+
+	if !strings.HasPrefix(rec.Header().Get("Set-Cookie"), "cart_id=") {
+		t.Errorf("Expected cart_id cookie, got '%s'", rec.Header().Get("Set-Cookie"))
+	}
+}
+
+// TestToFloat tests value conversion
+func TestToFloat(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want float64
+	}{
+		{"9.99", 9.99},
+		{int64(3), 3},
+		{2, 2},
+		{1.5, 1.5},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := toFloat(tt.in); got != tt.want {
+			t.Errorf("toFloat(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
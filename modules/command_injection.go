@@ -25,11 +25,40 @@ func (m *CommandInjection) Info() ModuleInfo {
 			"form_field",
 			"json_field",
 			"header",
+			"grpc_field",
 		},
 		RequiresSink: "command",
 		ValidVariants: map[string][]string{
-			"filter": {"none", "basic_semicolon", "basic_pipe", "basic_both", "url_decode"},
+			"filter":  {"none", "basic_semicolon", "basic_pipe", "basic_both", "url_decode"},
+			"variant": {"direct", "blind"},
 		},
+		ConfigKeys: []string{"base_command", "max_delay", "oob_hosts", "response_message", "show_interactions"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"variant": "direct", "filter": "none"},
+			"medium": {"variant": "direct", "filter": "basic_semicolon"},
+			"hard":   {"variant": "blind", "filter": "basic_both"},
+		},
+		Options: map[string]Option{
+			"base_command":      {Description: "Command run with {input} replaced by the input, e.g. \"ping -c 1 {input}\"; when unset the input is run as the whole command"},
+			"filter":            {Description: "Applied to the input first: none, basic_semicolon (removes ;), basic_pipe (removes |), basic_both (removes ; and |) or url_decode (removes ; | & ` $ and then URL-decodes)", Default: "none"},
+			"variant":           {Description: "direct returns the command's output; blind returns response_message only", Default: "direct"},
+			"max_delay":         {Description: "Longest sleep or ping delay in seconds a blind injection emulates", Default: "10"},
+			"oob_hosts":         {Description: "Extra hosts a blind injection's curl, wget, nslookup, dig or host callbacks are delivered to, besides the OOB listener's"},
+			"response_message":  {Description: "Message a blind injection responds with", Default: "Request received"},
+			"show_interactions": {Description: "Include the OOB callbacks a blind injection fired in the response", Default: "false"},
+		},
+		Example: `- path: /ping
+  method: GET
+  response_type: text
+  vulnerabilities:
+    - type: command_injection
+      placement: query_param
+      param: host
+      config:
+        base_command: "ping -c 1 {input}"
+        filter: none
+`,
+		Payloads: []string{"127.0.0.1; id", "127.0.0.1 | whoami", "127.0.0.1 && sleep 5", "127.0.0.1%0aid"},
 	}
 }
 
@@ -58,6 +87,11 @@ func (m *CommandInjection) Handle(ctx *HandlerContext) (*Result, error) {
 		command = input
 	}
 
+	// Blind injection never returns output
+	if ctx.GetConfigString("variant", "direct") == "blind" {
+		return m.handleBlind(ctx, command)
+	}
+
 	// Execute the command
 	output, err := ctx.Sinks.Command.Execute(command)
 	if err != nil {
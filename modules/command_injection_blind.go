@@ -0,0 +1,238 @@
+package modules
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// blindSleepPattern matches sleep N (seconds, optionally fractional)
+var blindSleepPattern = regexp.MustCompile(`\bsleep\s+(\d+(?:\.\d+)?)s?\b`)
+
+// blindPingPattern matches ping -c N / ping -n N, which takes roughly N-1 seconds
+var blindPingPattern = regexp.MustCompile(`\bping\s+(?:[^;&|\n]*?\s)?-[cn]\s*(\d+)[^;&|\n]*`)
+
+// blindCallbackPattern finds commands that reach out over the network
+var blindCallbackPattern = regexp.MustCompile(`\b(curl|wget|nslookup|dig|host)\s+`)
+
+// blindSubstitutionPattern matches $(...) and `...` command substitutions
+var blindSubstitutionPattern = regexp.MustCompile("\\$\\(([^()]*)\\)|`([^`]*)`")
+
+// blindCallback is a network callback found in an injected command
+type blindCallback struct {
+	Tool    string
+	Target  string
+	Segment string
+}
+
+// handleBlind executes the command without returning its output.
+// Delays from sleep/ping are emulated (bounded by max_delay) instead of run, and
+// curl/wget/nslookup-style callbacks to configured OOB hosts are fired by the lab.
+func (m *CommandInjection) handleBlind(ctx *HandlerContext, command string) (*Result, error) {
+	maxDelay := ctx.GetConfigInt("max_delay", 10)
+	oobHosts := oobTargets(ctx)
+	showInteractions := ctx.GetConfigBool("show_interactions", false)
+
+	delay := blindCommandDelay(command, time.Duration(maxDelay)*time.Second)
+
+	// Only callbacks to the OOB listener are delivered by the lab; anything else runs as-is
+	var callbacks []blindCallback
+	for _, cb := range findBlindCallbacks(command) {
+		if isOOBTarget(blindCallbackURL(cb, "x"), oobHosts) {
+			callbacks = append(callbacks, cb)
+		}
+	}
+
+	// Neutralize what the lab emulates and run the rest for real
+	executed := blindSleepPattern.ReplaceAllString(command, ":")
+	executed = blindPingPattern.ReplaceAllString(executed, ":")
+	for _, cb := range callbacks {
+		executed = strings.Replace(executed, cb.Segment, ":", 1)
+	}
+	if strings.Trim(executed, ":;&| \n\t") != "" {
+		ctx.Sinks.Command.Execute(executed)
+	}
+
+	var interactions []OOBInteraction
+	for _, cb := range callbacks {
+		interactions = append(interactions, fireBlindCallback(ctx, cb))
+	}
+
+	if delay > 0 {
+		var done <-chan struct{}
+		if ctx.Request != nil {
+			done = ctx.Request.Context().Done()
+		}
+		select {
+		case <-time.After(delay):
+		case <-done:
+		}
+	}
+
+	// Nothing about the command's outcome is reflected back
+	data := map[string]interface{}{
+		"status":  "ok",
+		"message": ctx.GetConfigString("response_message", "Request received"),
+	}
+	if showInteractions && len(interactions) > 0 {
+		data["oob_interactions"] = interactions
+	}
+
+	return NewResult(data), nil
+}
+
+// blindCommandDelay sums the delays requested by sleep and ping, capped at maxDelay
+func blindCommandDelay(command string, maxDelay time.Duration) time.Duration {
+	var total time.Duration
+
+	for _, match := range blindSleepPattern.FindAllStringSubmatch(command, -1) {
+		if seconds, err := strconv.ParseFloat(match[1], 64); err == nil {
+			total += time.Duration(seconds * float64(time.Second))
+		}
+	}
+	for _, match := range blindPingPattern.FindAllStringSubmatch(command, -1) {
+		// The first echo request goes out immediately, then one per second
+		if count, err := strconv.Atoi(match[1]); err == nil && count > 1 {
+			total += time.Duration(count-1) * time.Second
+		}
+	}
+
+	if total > maxDelay {
+		return maxDelay
+	}
+	return total
+}
+
+// findBlindCallbacks extracts curl/wget URLs and nslookup/dig/host names from a command
+func findBlindCallbacks(command string) []blindCallback {
+	var callbacks []blindCallback
+
+	for _, loc := range blindCallbackPattern.FindAllStringSubmatchIndex(command, -1) {
+		tool := command[loc[2]:loc[3]]
+		end := commandSegmentEnd(command, loc[1])
+		segment := command[loc[0]:end]
+
+		for _, arg := range shellFields(command[loc[1]:end]) {
+			arg = strings.Trim(arg, `"'`)
+			if arg == "" || strings.HasPrefix(arg, "-") {
+				continue
+			}
+			callbacks = append(callbacks, blindCallback{Tool: tool, Target: arg, Segment: segment})
+			break
+		}
+	}
+
+	return callbacks
+}
+
+// commandSegmentEnd finds where a shell command ends, skipping over
+// operators nested inside $(...) and backtick substitutions
+func commandSegmentEnd(command string, start int) int {
+	depth := 0
+	inBacktick := false
+
+	for i := start; i < len(command); i++ {
+		switch c := command[i]; {
+		case c == '`':
+			inBacktick = !inBacktick
+		case c == '$' && i+1 < len(command) && command[i+1] == '(':
+			depth++
+			i++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && !inBacktick && strings.IndexByte(";&|\n", c) != -1:
+			return i
+		}
+	}
+	return len(command)
+}
+
+// shellFields splits arguments on whitespace outside quotes and substitutions
+func shellFields(s string) []string {
+	var fields []string
+	var current strings.Builder
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(s) && s[i+1] == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t'):
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
+
+// blindCallbackURL builds the URL a callback reaches, with substitutions replaced by expand
+func blindCallbackURL(cb blindCallback, expand string) string {
+	target := blindSubstitutionPattern.ReplaceAllLiteralString(cb.Target, expand)
+	if cb.Tool != "curl" && cb.Tool != "wget" {
+		return "http://" + target + "/"
+	}
+	if !strings.Contains(target, "://") {
+		return "http://" + target
+	}
+	return target
+}
+
+// fireBlindCallback delivers a callback to the OOB listener after expanding
+// command substitutions, as the shell would have done
+func fireBlindCallback(ctx *HandlerContext, cb blindCallback) OOBInteraction {
+	dns := cb.Tool != "curl" && cb.Tool != "wget"
+	expanded := cb
+	expanded.Target = blindSubstitutionPattern.ReplaceAllStringFunc(cb.Target, func(sub string) string {
+		inner := blindSubstitutionPattern.FindStringSubmatch(sub)
+		output, _ := ctx.Sinks.Command.Execute(inner[1] + inner[2])
+		output = strings.TrimSpace(output)
+		if dns {
+			// Hostname labels cannot contain spaces or newlines
+			return strings.Join(strings.Fields(output), ".")
+		}
+		return url.PathEscape(output)
+	})
+
+	uri := blindCallbackURL(expanded, "")
+	interaction := OOBInteraction{URI: uri, Source: cb.Tool}
+
+	// Lookups of the built-in listener's domain are recorded as DNS interactions
+	if dns && ctx.Sinks.OOB != nil {
+		if parsed, err := url.Parse(uri); err == nil && ctx.Sinks.OOB.Lookup(parsed.Hostname()) {
+			return interaction
+		}
+	}
+
+	if ctx.Sinks.HTTP == nil {
+		interaction.Error = "HTTP sink not available"
+		return interaction
+	}
+
+	resp, err := ctx.Sinks.HTTP.Fetch(uri)
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.StatusCode = resp.StatusCode
+	}
+	return interaction
+}
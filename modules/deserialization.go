@@ -22,16 +22,49 @@ func (m *Deserialization) Info() ModuleInfo {
 		Description: "Insecure Deserialization vulnerability that emulates processing of Java/PHP serialized objects",
 		SupportedPlacements: []string{
 			"query_param",
+			"path_param",
 			"form_field",
 			"json_field",
 			"header",
 			"cookie",
+			"raw_body",
+			"grpc_field",
 		},
 		RequiresSink: "", // No external sink required - emulates deserialization behavior
 		ValidVariants: map[string][]string{
 			"format": {"auto", "java", "php", "python_pickle", "dotnet"},
 			"filter": {"none", "basic_signature", "basic_class", "php_basic", "allowlist", "blocklist"},
 		},
+		ConfigKeys: []string{"allowed_classes", "blocked_patterns", "emulate_execution", "show_decoded"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"filter": "none", "show_decoded": true},
+			"medium": {"filter": "basic_class", "show_decoded": true},
+			"hard":   {"filter": "basic_class", "show_decoded": false},
+		},
+		Options: map[string]Option{
+			"format":            {Description: "Serialization format the input is read as: auto (detected), java, php, python_pickle or dotnet", Default: "auto"},
+			"filter":            {Description: "Check on the input: none, basic_signature (rejects Java magic bytes and rO0AB), basic_class (rejects known gadget class names), php_basic (rejects PHP objects), allowlist (only allowed_classes) or blocklist (rejects blocked_patterns)", Default: "none"},
+			"allowed_classes":   {Description: "For the allowlist filter, class names the input must mention one of"},
+			"blocked_patterns":  {Description: "For the blocklist filter, strings the input is rejected for containing"},
+			"show_decoded":      {Description: "Return the decoded object", Default: "true"},
+			"emulate_execution": {Description: "Report what known gadget chains in the input would have run", Default: "true"},
+		},
+		Example: `- path: /api/session
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: insecure_deserialization
+      placement: cookie
+      param: session
+      config:
+        format: auto
+        filter: none
+`,
+		Payloads: []string{
+			`O:8:"stdClass":1:{s:5:"admin";b:1;}`,
+			"rO0ABXNyABdqYXZhLnV0aWwuUHJpb3JpdHlRdWV1ZZTaMLT7P4KxAwACSQAEc2l6ZUwACmNvbXBhcmF0b3J0ABZMamF2YS91dGlsL0NvbXBhcmF0b3I7eHA=",
+			"gASVIAAAAAAAAACMBXBvc2l4lIwGc3lzdGVtlJOUjAJpZJSFlFKULg==",
+		},
 	}
 }
 
@@ -190,31 +223,45 @@ func detectSerializationFormat(data string) string {
 	return "unknown"
 }
 
-// processJavaSerialized processes Java serialized objects
+// javaGadgetChains maps class name patterns to known gadget chains
+var javaGadgetChains = map[string]string{
+	"org.apache.commons.collections.functors.InvokerTransformer": "CommonsCollections",
+	"org.apache.commons.collections4":                            "CommonsCollections4",
+	"org.springframework.beans":                                  "Spring",
+	"com.sun.org.apache.xalan":                                   "Jdk7u21",
+	"java.lang.Runtime.getRuntime":                               "Runtime.exec",
+	"javax.management":                                           "JMX",
+	"org.hibernate":                                              "Hibernate",
+	"com.mchange.v2.c3p0":                                        "C3P0",
+	"org.jboss":                                                  "JBoss",
+	"bsh.Interpreter":                                            "BeanShell",
+	"clojure.core":                                               "Clojure",
+	"groovy.util":                                                "Groovy",
+	"org.codehaus.groovy.runtime":                                "Groovy",
+	"com.alibaba.fastjson":                                       "Fastjson",
+	"org.apache.wicket":                                          "Wicket",
+}
+
+// processJavaSerialized processes Java serialized objects.
+// Binary 0xACED streams are parsed properly; anything else falls back to pattern matching.
 func processJavaSerialized(result *DeserializationResult, data string, emulateExec bool) {
 	result.Detected = true
 	result.Format = "java"
 
+	if strings.HasPrefix(data, "\xac\xed") {
+		stream, err := parseJavaStream([]byte(data))
+		if err == nil {
+			processJavaStream(result, stream, emulateExec)
+			return
+		}
+		// Truncated or corrupt stream - report why and keep guessing
+		result.Properties = map[string]interface{}{
+			"parse_error": err.Error(),
+		}
+	}
+
 	// Check for common dangerous gadget chains
-	gadgetChains := map[string]string{
-		"org.apache.commons.collections.functors.InvokerTransformer": "CommonsCollections",
-		"org.apache.commons.collections4":                            "CommonsCollections4",
-		"org.springframework.beans":                                  "Spring",
-		"com.sun.org.apache.xalan":                                   "Jdk7u21",
-		"java.lang.Runtime.getRuntime":                               "Runtime.exec",
-		"javax.management":                                           "JMX",
-		"org.hibernate":                                              "Hibernate",
-		"com.mchange.v2.c3p0":                                        "C3P0",
-		"org.jboss":                                                  "JBoss",
-		"bsh.Interpreter":                                            "BeanShell",
-		"clojure.core":                                               "Clojure",
-		"groovy.util":                                                "Groovy",
-		"org.codehaus.groovy.runtime":                                "Groovy",
-		"com.alibaba.fastjson":                                       "Fastjson",
-		"org.apache.wicket":                                          "Wicket",
-	}
-
-	for pattern, chain := range gadgetChains {
+	for pattern, chain := range javaGadgetChains {
 		if strings.Contains(data, pattern) {
 			result.Exploitable = true
 			result.GadgetChain = chain
@@ -243,6 +290,144 @@ func processJavaSerialized(result *DeserializationResult, data string, emulateEx
 	}
 }
 
+// processJavaStream fills the result from a parsed serialization stream
+func processJavaStream(result *DeserializationResult, stream *JavaStream, emulateExec bool) {
+	result.Properties = map[string]interface{}{
+		"stream_version": stream.Version,
+		"classes":        stream.Classes,
+		"contents":       stream.Contents,
+	}
+
+	// The root object's class is what readObject() instantiates
+	for _, content := range stream.Contents {
+		if obj, ok := content.(map[string]interface{}); ok {
+			if name, ok := obj["class"].(string); ok {
+				result.ClassName = name
+				break
+			}
+		}
+	}
+
+	// Gadget chains are identified by the classes actually present in the stream
+	for _, class := range stream.Classes {
+		if chain := javaGadgetChain(class.Name); chain != "" {
+			result.Exploitable = true
+			result.GadgetChain = chain
+			result.PayloadType = "gadget_chain"
+			result.Warning = fmt.Sprintf("Dangerous gadget chain detected: %s (%s)", chain, class.Name)
+			break
+		}
+	}
+
+	if emulateExec {
+		if cmd := javaStreamCommand(stream); cmd != "" {
+			result.SimulatedCmd = cmd
+			result.Exploitable = true
+			result.Warning = fmt.Sprintf("Command execution payload detected: %s", cmd)
+		}
+	}
+
+	if !result.Exploitable {
+		result.Warning = "Java serialized object detected - potential deserialization vulnerability"
+		result.PayloadType = "serialized_object"
+	}
+}
+
+// javaGadgetChain returns the gadget chain a class name belongs to, if any
+func javaGadgetChain(className string) string {
+	// Check longer patterns first so the most specific chain wins
+	var best, chain string
+	for pattern, name := range javaGadgetChains {
+		if strings.HasPrefix(className, pattern) && len(pattern) > len(best) {
+			best, chain = pattern, name
+		}
+	}
+	return chain
+}
+
+// javaStreamCommand finds the command passed to Runtime.exec / ProcessBuilder.
+// Transformer gadgets store the method name next to its arguments, e.g.
+// InvokerTransformer{iArgs: ["calc"], iMethodName: "exec"}.
+func javaStreamCommand(stream *JavaStream) string {
+	for _, content := range stream.Contents {
+		if cmd := findExecArgument(content); cmd != "" {
+			return cmd
+		}
+	}
+	return extractCommand(strings.Join(stream.Strings, "\n"))
+}
+
+// findExecArgument walks rendered stream values looking for an exec call's argument
+func findExecArgument(v interface{}) string {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if fields, ok := t["fields"].(map[string]interface{}); ok {
+			if hasExecMethod(fields) {
+				for _, value := range fields {
+					if arg := firstJavaString(value); arg != "" {
+						return arg
+					}
+				}
+			}
+			for _, value := range fields {
+				if cmd := findExecArgument(value); cmd != "" {
+					return cmd
+				}
+			}
+		}
+		for _, key := range []string{"elements", "annotations"} {
+			if items, ok := t[key].([]interface{}); ok {
+				for _, item := range items {
+					if cmd := findExecArgument(item); cmd != "" {
+						return cmd
+					}
+				}
+			}
+		}
+	case []interface{}:
+		for _, item := range t {
+			if cmd := findExecArgument(item); cmd != "" {
+				return cmd
+			}
+		}
+	}
+	return ""
+}
+
+// hasExecMethod reports whether an object's fields name a process-spawning method
+func hasExecMethod(fields map[string]interface{}) bool {
+	for _, value := range fields {
+		if s, ok := value.(string); ok && (s == "exec" || s == "start") {
+			return true
+		}
+	}
+	return false
+}
+
+// firstJavaString returns the first argument-like string inside a value
+func firstJavaString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		if t != "exec" && t != "start" && !isJavaTypeDescriptor(t) {
+			return t
+		}
+	case map[string]interface{}:
+		if items, ok := t["elements"].([]interface{}); ok {
+			for _, item := range items {
+				if s := firstJavaString(item); s != "" {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isJavaTypeDescriptor reports whether s is a JVM field type such as Ljava/lang/String;
+func isJavaTypeDescriptor(s string) bool {
+	return strings.HasPrefix(s, "[") || (strings.HasPrefix(s, "L") && strings.HasSuffix(s, ";"))
+}
+
 // processPHPSerialized processes PHP serialized objects
 func processPHPSerialized(result *DeserializationResult, data string, emulateExec bool) {
 	result.Detected = true
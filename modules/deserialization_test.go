@@ -19,7 +19,7 @@ func TestDeserializationModuleInfo(t *testing.T) {
 	}
 
 	// Check supported placements
-	expectedPlacements := []string{"query_param", "form_field", "json_field", "header", "cookie"}
+	expectedPlacements := []string{"query_param", "path_param", "form_field", "json_field", "header", "cookie", "raw_body", "grpc_field"}
 	if len(info.SupportedPlacements) != len(expectedPlacements) {
 		t.Errorf("Expected %d placements, got %d", len(expectedPlacements), len(info.SupportedPlacements))
 	}
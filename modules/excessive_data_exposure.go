@@ -0,0 +1,195 @@
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExcessiveDataExposure implements the excessive_data_exposure vulnerability module
+type ExcessiveDataExposure struct{}
+
+// init registers the module
+func init() {
+	Register(&ExcessiveDataExposure{})
+}
+
+// defaultSensitiveFields are column name fragments treated as sensitive
+var defaultSensitiveFields = []string{
+	"password",
+	"password_hash",
+	"ssn",
+	"token",
+	"api_key",
+	"secret",
+	"credit_card",
+}
+
+// Info returns module metadata
+func (m *ExcessiveDataExposure) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "excessive_data_exposure",
+		Description: "Excessive Data Exposure (API3) - list endpoints return full database rows including sensitive columns",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "sqlite",
+		ValidVariants: map[string][]string{
+			"filter_level": {"none", "client_side", "partial", "strict"},
+		},
+		ConfigKeys: []string{"fields", "query_template", "sensitive_fields", "show_errors"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"filter_level": "none"},
+			"medium": {"filter_level": "client_side"},
+			"hard":   {"filter_level": "partial"},
+		},
+		Options: map[string]Option{
+			"query_template":   {Description: "Query run with {input} replaced by the input; required"},
+			"filter_level":     {Description: "Columns removed from rows: none, client_side (none, with display_fields telling the UI what to show), partial (only password columns) or strict (only fields, or no sensitive_fields)", Default: "none"},
+			"fields":           {Description: "Columns the UI shows, for client_side, or that strict returns"},
+			"sensitive_fields": {Description: "Column name fragments treated as sensitive", Default: "password, ssn, token and other secrets"},
+			"show_errors":      {Description: "Return database errors", Default: "true"},
+		},
+		Example: `- path: /api/users
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: excessive_data_exposure
+      placement: query_param
+      param: role
+      config:
+        query_template: "SELECT * FROM users WHERE role = '{input}'"
+        filter_level: client_side
+        fields: [id, username]
+`,
+		Payloads: []string{"user", "admin"},
+	}
+}
+
+// Handle runs the list query and filters the rows according to the configured level
+func (m *ExcessiveDataExposure) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+
+	// Get configuration
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	filterLevel := ctx.GetConfigString("filter_level", "none")
+	fields := getStringSlice(ctx.Config, "fields", nil)
+	sensitive := getStringSlice(ctx.Config, "sensitive_fields", defaultSensitiveFields)
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	if queryTemplate == "" {
+		return nil, fmt.Errorf("query_template is required for excessive_data_exposure")
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", ctx.Input)
+	rows, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": query,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	var filtered []map[string]interface{}
+	for _, row := range rows {
+		filtered = append(filtered, filterExposedRow(row, filterLevel, fields, sensitive))
+	}
+	if filtered == nil {
+		filtered = []map[string]interface{}{}
+	}
+
+	data := map[string]interface{}{
+		"results":        filtered,
+		"count":          len(filtered),
+		"filter_level":   filterLevel,
+		"exposed_fields": sensitiveFieldsPresent(filtered, sensitive),
+	}
+
+	// Client-side filtering - the API sends everything and tells the UI what to show
+	if filterLevel == "client_side" && len(fields) > 0 {
+		data["display_fields"] = fields
+	}
+
+	return NewResult(data), nil
+}
+
+// filterExposedRow removes columns from a row according to the filter level
+func filterExposedRow(row map[string]interface{}, level string, fields, sensitive []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+
+	switch level {
+	case "partial":
+		// Only the obvious password columns are removed - other secrets still leak
+		for k, v := range row {
+			if strings.Contains(strings.ToLower(k), "password") {
+				continue
+			}
+			out[k] = v
+		}
+	case "strict":
+		// Allowlist of fields, falling back to dropping every sensitive column
+		if len(fields) > 0 {
+			for _, f := range fields {
+				if v, ok := row[f]; ok {
+					out[f] = v
+				}
+			}
+		} else {
+			for k, v := range row {
+				if !isSensitiveField(k, sensitive) {
+					out[k] = v
+				}
+			}
+		}
+	default:
+		// none / client_side - return the full row
+		for k, v := range row {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+// sensitiveFieldsPresent lists the sensitive columns present in the response rows
+func sensitiveFieldsPresent(rows []map[string]interface{}, sensitive []string) []string {
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for k := range row {
+			if isSensitiveField(k, sensitive) {
+				seen[k] = true
+			}
+		}
+	}
+
+	exposed := make([]string, 0, len(seen))
+	for k := range seen {
+		exposed = append(exposed, k)
+	}
+	sort.Strings(exposed)
+	return exposed
+}
+
+// isSensitiveField reports whether a column name matches a sensitive field pattern
+func isSensitiveField(name string, sensitive []string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range sensitive {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
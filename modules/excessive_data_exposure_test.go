@@ -0,0 +1,103 @@
+package modules
+
+import (
+	"testing"
+)
+
+// newExposureSink returns a mock sink with rows containing sensitive columns
+func newExposureSink() *MockSQLiteSinkIDOR {
+	return &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{
+				{"id": "1", "username": "admin", "password_hash": "$2a$10$abc", "ssn": "123-45-6789", "api_token": "tok_1"},
+				{"id": "2", "username": "rick", "password_hash": "$2a$10$def", "ssn": "987-65-4321", "api_token": "tok_2"},
+			}, nil
+		},
+	}
+}
+
+// runExposure executes the module with the given filter level and fields
+func runExposure(t *testing.T, level string, fields []interface{}) map[string]interface{} {
+	t.Helper()
+	m := &ExcessiveDataExposure{}
+
+	config := map[string]interface{}{
+		"query_template": "SELECT * FROM users",
+		"filter_level":   level,
+	}
+	if fields != nil {
+		config["fields"] = fields
+	}
+
+	result, err := m.Handle(&HandlerContext{
+		Config: config,
+		Sinks:  &SinkContext{SQLite: newExposureSink()},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result.Data.(map[string]interface{})
+}
+
+// TestExcessiveDataExposure_Info tests module metadata
+func TestExcessiveDataExposure_Info(t *testing.T) {
+	m := &ExcessiveDataExposure{}
+	info := m.Info()
+
+	if info.Name != "excessive_data_exposure" {
+		t.Errorf("Expected Name 'excessive_data_exposure', got '%s'", info.Name)
+	}
+	if info.RequiresSink != "sqlite" {
+		t.Errorf("Expected RequiresSink 'sqlite', got '%s'", info.RequiresSink)
+	}
+}
+
+// TestExcessiveDataExposure_FilterLevels tests the contrast between filter levels
+func TestExcessiveDataExposure_FilterLevels(t *testing.T) {
+	tests := []struct {
+		level       string
+		fields      []interface{}
+		wantExposed int
+		wantColumns int
+	}{
+		{"none", nil, 3, 5},
+		{"client_side", []interface{}{"id", "username"}, 3, 5},
+		{"partial", nil, 2, 4},
+		{"strict", nil, 0, 2},
+		{"strict", []interface{}{"username"}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			data := runExposure(t, tt.level, tt.fields)
+
+			exposed := data["exposed_fields"].([]string)
+			if len(exposed) != tt.wantExposed {
+				t.Errorf("Expected %d exposed fields, got %v", tt.wantExposed, exposed)
+			}
+
+			rows := data["results"].([]map[string]interface{})
+			if len(rows[0]) != tt.wantColumns {
+				t.Errorf("Expected %d columns, got %v", tt.wantColumns, rows[0])
+			}
+		})
+	}
+}
+
+// TestExcessiveDataExposure_ClientSideDisplayFields tests the display_fields hint
+func TestExcessiveDataExposure_ClientSideDisplayFields(t *testing.T) {
+	data := runExposure(t, "client_side", []interface{}{"id", "username"})
+
+	if _, ok := data["display_fields"]; !ok {
+		t.Error("Expected display_fields for client_side filtering")
+	}
+}
+
+// TestExcessiveDataExposure_NoSink tests error when SQLite sink is missing
+func TestExcessiveDataExposure_NoSink(t *testing.T) {
+	m := &ExcessiveDataExposure{}
+
+	if _, err := m.Handle(&HandlerContext{Sinks: &SinkContext{}}); err == nil {
+		t.Error("Expected error when SQLite sink is not available")
+	}
+}
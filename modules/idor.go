@@ -29,9 +29,55 @@ func (m *IDOR) Info() ModuleInfo {
 		},
 		RequiresSink: "sqlite",
 		ValidVariants: map[string][]string{
-			"variant":        {"numeric", "uuid", "encoded", "predictable"},
+			"variant":        {"numeric", "uuid", "encoded", "predictable", "nested", "global_id"},
+			"nested_check":   {"parent_only", "child_only", "both"},
 			"access_control": {"none", "weak_header", "weak_cookie", "role_based", "predictable_token"},
+			"operation":      {"read", "update", "delete"},
+			"ownership":      {"none", "read_only", "enforced"},
 		},
+		ConfigKeys: []string{
+			"query_template", "show_errors", "exposed_types", "node_type_check", "node_types",
+			"parent_column", "parent_param", "count_query", "id_hints", "identity_cookie", "identity_header",
+			"lookup_query", "owner_column", "value_param",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"access_control": "none", "show_errors": true},
+			"medium": {"access_control": "weak_cookie", "show_errors": true},
+			"hard":   {"access_control": "predictable_token", "show_errors": false},
+		},
+		Options: map[string]Option{
+			"query_template":  {Description: "Query run with {input} replaced by the ID, and {parent} and {value} for nested and write operations; required except for global_id"},
+			"variant":         {Description: "How IDs look: numeric, uuid, encoded, predictable, nested (a child under a parent ID) or global_id (Relay-style base64 \"Type:id\" resolved through node_types)", Default: "numeric"},
+			"access_control":  {Description: "Check before the lookup, none of which checks ownership: none, weak_header (needs X-User-ID), weak_cookie (needs a user_id cookie), role_based or predictable_token (needs \"Bearer user_<id>\")", Default: "none"},
+			"operation":       {Description: "What query_template does: read, update or delete", Default: "read"},
+			"ownership":       {Description: "Compare the record's owner_column with the caller's identity: none, read_only (only reads are checked) or enforced", Default: "none"},
+			"show_errors":     {Description: "Return database and validation errors", Default: "true"},
+			"nested_check":    {Description: "For nested, the ID checked: parent_only (the parent is the caller), child_only (the child belongs to the parent) or both", Default: "parent_only"},
+			"parent_param":    {Description: "For nested, the path or request parameter holding the parent ID", Default: "uid"},
+			"parent_column":   {Description: "For nested, the child's column holding its parent ID", Default: "owner_id"},
+			"node_types":      {Description: "For global_id, a map of node type to query template"},
+			"node_type_check": {Description: "For global_id, only resolve types listed in exposed_types", Default: "false"},
+			"exposed_types":   {Description: "For global_id, node types node_type_check allows"},
+			"identity_header": {Description: "Header naming the caller when there's no bearer token", Default: "X-User-ID"},
+			"identity_cookie": {Description: "Cookie naming the caller when there's no bearer token or identity header", Default: "user_id"},
+			"lookup_query":    {Description: "Query fetching the record for ownership checks and after updates, with {input} replaced by the ID", Default: "query_template, for reads"},
+			"owner_column":    {Description: "Column holding the record's owner, for ownership checks", Default: "owner_id"},
+			"value_param":     {Description: "Request parameter whose value replaces {value} in update statements", Default: "value"},
+			"id_hints":        {Description: "For numeric, include the previous and next IDs in responses", Default: "false"},
+			"count_query":     {Description: "For id_hints, query whose first value is reported as the total number of records"},
+		},
+		Example: `- path: /api/invoices/{id}
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: idor
+      placement: path_param
+      param: id
+      config:
+        query_template: "SELECT * FROM invoices WHERE id = {input}"
+        access_control: none
+`,
+		Payloads: []string{"1", "2", "0", "-1"},
 	}
 }
 
@@ -48,7 +94,8 @@ func (m *IDOR) Handle(ctx *HandlerContext) (*Result, error) {
 	showErrors := ctx.GetConfigBool("show_errors", true)
 	accessControl := ctx.GetConfigString("access_control", "none")
 
-	if queryTemplate == "" {
+	// Global IDs are resolved through per-type templates in node_types
+	if queryTemplate == "" && variant != "global_id" {
 		return nil, fmt.Errorf("query_template is required for idor")
 	}
 
@@ -86,22 +133,39 @@ func (m *IDOR) Handle(ctx *HandlerContext) (*Result, error) {
 		}, nil
 	}
 
+	// Check the record's owner against the authenticated identity, if configured
+	operation := ctx.GetConfigString("operation", "read")
+	if result := m.checkOwnership(ctx, input, operation, showErrors); result != nil {
+		return m.withIDHints(ctx, result, input, variant), nil
+	}
+
 	// Build the query by replacing {input} with user input (vulnerable to IDOR)
 	query := strings.ReplaceAll(queryTemplate, "{input}", input)
 
 	// Execute based on variant
-	switch variant {
-	case "numeric":
-		return m.handleNumeric(ctx, query, showErrors)
-	case "uuid":
-		return m.handleUUID(ctx, query, showErrors)
-	case "encoded":
-		return m.handleEncoded(ctx, query, showErrors, input)
-	case "predictable":
-		return m.handlePredictable(ctx, query, showErrors, input)
+	var result *Result
+	var err error
+	switch {
+	case operation == "update" || operation == "delete":
+		result, err = m.handleWrite(ctx, query, operation, showErrors, input)
+	case variant == "nested":
+		result, err = m.handleNested(ctx, query, showErrors, input)
+	case variant == "global_id":
+		result, err = m.handleGlobalID(ctx, showErrors, input)
+	case variant == "uuid":
+		result, err = m.handleUUID(ctx, query, showErrors)
+	case variant == "encoded":
+		result, err = m.handleEncoded(ctx, query, showErrors, input)
+	case variant == "predictable":
+		result, err = m.handlePredictable(ctx, query, showErrors, input)
 	default:
-		return m.handleNumeric(ctx, query, showErrors)
+		result, err = m.handleNumeric(ctx, query, showErrors)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.withIDHints(ctx, result, input, variant), nil
 }
 
 // validateInput validates the input based on the variant
@@ -126,7 +190,7 @@ func (m *IDOR) validateInput(input string, variant string) error {
 		if len(input) < 1 {
 			return fmt.Errorf("ID cannot be empty")
 		}
-	case "predictable":
+	case "predictable", "nested", "global_id":
 		// Accept any pattern-based value
 		if len(input) < 1 {
 			return fmt.Errorf("ID cannot be empty")
@@ -0,0 +1,168 @@
+package modules
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// graphQLNodePattern extracts the ID argument from a node(id: "...") query
+var graphQLNodePattern = regexp.MustCompile(`node\s*\(\s*id\s*:\s*"([^"]+)"`)
+
+// handleNested handles child resources addressed through a parent, such as
+// /api/users/{uid}/documents/{did}. Depending on nested_check only one of the
+// two identifiers is validated, which is how these endpoints usually break.
+func (m *IDOR) handleNested(ctx *HandlerContext, query string, showErrors bool, input string) (*Result, error) {
+	nestedCheck := ctx.GetConfigString("nested_check", "parent_only")
+	parentParam := ctx.GetConfigString("parent_param", "uid")
+	parentColumn := ctx.GetConfigString("parent_column", "owner_id")
+
+	parent := ""
+	if ctx.Request != nil {
+		parent = ctx.Request.PathValue(parentParam)
+		if parent == "" {
+			parent = requestValue(ctx, parentParam)
+		}
+	}
+	if parent == "" {
+		return &Result{
+			Error:      fmt.Sprintf("missing parent identifier '%s'", parentParam),
+			StatusCode: 400,
+		}, nil
+	}
+
+	// The parent must be the authenticated user
+	if nestedCheck == "parent_only" || nestedCheck == "both" {
+		if identity := m.identity(ctx); identity != parent {
+			return &Result{
+				Error:      "forbidden: cannot access another user's resources",
+				Data:       map[string]interface{}{"error": "forbidden: cannot access another user's resources"},
+				StatusCode: 403,
+			}, nil
+		}
+	}
+
+	query = strings.ReplaceAll(query, "{parent}", parent)
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": query,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	// The child must belong to the parent in the URL
+	if nestedCheck == "child_only" || nestedCheck == "both" {
+		if len(results) > 0 && fmt.Sprint(results[0][parentColumn]) != parent {
+			results = nil
+		}
+	}
+
+	if len(results) == 0 {
+		return &Result{
+			Data: map[string]interface{}{
+				"message": "Resource not found",
+			},
+			StatusCode: 404,
+		}, nil
+	}
+
+	return NewResult(map[string]interface{}{
+		"resource":      results[0],
+		"resource_type": "nested",
+		"parent_id":     parent,
+	}), nil
+}
+
+// handleGlobalID resolves Relay-style global IDs - base64("Type:id") - the way a
+// GraphQL node() resolver does. Any node type can be fetched by ID unless
+// node_type_check restricts it to exposed_types.
+func (m *IDOR) handleGlobalID(ctx *HandlerContext, showErrors bool, input string) (*Result, error) {
+	// Accept either a bare global ID or a GraphQL document containing node(id: "...")
+	globalID := input
+	if match := graphQLNodePattern.FindStringSubmatch(input); match != nil {
+		globalID = match[1]
+	}
+
+	nodeType, id, err := decodeGlobalID(globalID)
+	if err != nil {
+		return graphQLError(err.Error(), 400), nil
+	}
+
+	nodeTypes := getStringMap(ctx.Config, "node_types")
+	queryTemplate, ok := nodeTypes[nodeType]
+	if !ok {
+		return graphQLError(fmt.Sprintf("unknown node type '%s'", nodeType), 400), nil
+	}
+
+	if ctx.GetConfigBool("node_type_check", false) {
+		allowed := false
+		for _, t := range getStringSlice(ctx.Config, "exposed_types", nil) {
+			if t == nodeType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return graphQLError(fmt.Sprintf("not authorized to access %s", nodeType), 403), nil
+		}
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", id)
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return graphQLError(err.Error(), 500), nil
+		}
+		return graphQLError("Database error", 500), nil
+	}
+
+	var node interface{}
+	if len(results) > 0 {
+		fields := results[0]
+		fields["__typename"] = nodeType
+		fields["id"] = globalID
+		node = fields
+	}
+
+	return NewResult(map[string]interface{}{
+		"data": map[string]interface{}{
+			"node": node,
+		},
+	}), nil
+}
+
+// decodeGlobalID splits a base64 "Type:id" global ID
+func decodeGlobalID(globalID string) (string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		decoded, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(globalID, "="))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("invalid global ID '%s'", globalID)
+	}
+
+	nodeType, id, found := strings.Cut(string(decoded), ":")
+	if !found || nodeType == "" || id == "" {
+		return "", "", fmt.Errorf("invalid global ID '%s'", globalID)
+	}
+	return nodeType, id, nil
+}
+
+// graphQLError returns an error in the GraphQL response format
+func graphQLError(message string, statusCode int) *Result {
+	return &Result{
+		Data: map[string]interface{}{
+			"data":   map[string]interface{}{"node": nil},
+			"errors": []map[string]interface{}{{"message": message}},
+		},
+		StatusCode: statusCode,
+	}
+}
@@ -8,8 +8,9 @@ import (
 
 // MockSQLiteSink is a mock implementation of SQLiteSink for testing IDOR
 type MockSQLiteSinkIDOR struct {
-	QueryFunc func(query string) ([]map[string]interface{}, error)
-	ExecFunc  func(statement string) error
+	QueryFunc    func(query string) ([]map[string]interface{}, error)
+	ExecFunc     func(statement string) error
+	ExecArgsFunc func(statement string, args ...interface{}) (int64, error)
 }
 
 func (m *MockSQLiteSinkIDOR) Query(query string) ([]map[string]interface{}, error) {
@@ -26,6 +27,13 @@ func (m *MockSQLiteSinkIDOR) Exec(statement string) error {
 	return nil
 }
 
+func (m *MockSQLiteSinkIDOR) ExecArgs(statement string, args ...interface{}) (int64, error) {
+	if m.ExecArgsFunc != nil {
+		return m.ExecArgsFunc(statement, args...)
+	}
+	return 0, nil
+}
+
 // TestIDOR_Info tests module metadata
 func TestIDOR_Info(t *testing.T) {
 	m := &IDOR{}
@@ -640,3 +648,250 @@ func TestIDOR_Registration(t *testing.T) {
 		t.Errorf("Expected module name 'idor', got '%s'", info.Name)
 	}
 }
+
+// TestIDOR_Handle_Ownership tests the owner column check against the authenticated identity
+func TestIDOR_Handle_Ownership(t *testing.T) {
+	m := &IDOR{}
+
+	var executed []string
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{
+				{"id": int64(5), "owner_id": int64(1), "title": "Salary review"},
+			}, nil
+		},
+		ExecFunc: func(statement string) error {
+			executed = append(executed, statement)
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		operation  string
+		ownership  string
+		userID     string
+		wantStatus int
+	}{
+		{"read own record", "read", "enforced", "1", 0},
+		{"read other user's record", "read", "enforced", "2", 403},
+		{"read without identity", "read", "enforced", "", 401},
+		{"read unchecked", "read", "none", "2", 0},
+		{"update checked on reads only", "update", "read_only", "2", 0},
+		{"update enforced", "update", "enforced", "2", 403},
+		{"delete enforced for owner", "delete", "enforced", "1", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executed = nil
+
+			queryTemplate := "SELECT * FROM documents WHERE id = {input}"
+			switch tt.operation {
+			case "update":
+				queryTemplate = "UPDATE documents SET title = '{value}' WHERE id = {input}"
+			case "delete":
+				queryTemplate = "DELETE FROM documents WHERE id = {input}"
+			}
+
+			req := httptest.NewRequest("POST", "/documents?value=it's+mine", nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+
+			ctx := &HandlerContext{
+				Input: "5",
+				Config: map[string]interface{}{
+					"variant":        "numeric",
+					"operation":      tt.operation,
+					"ownership":      tt.ownership,
+					"query_template": queryTemplate,
+					"lookup_query":   "SELECT * FROM documents WHERE id = {input}",
+				},
+				Sinks:   &SinkContext{SQLite: mockSink},
+				Request: req,
+			}
+
+			result, err := m.Handle(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.wantStatus, result.StatusCode, result.Data)
+			}
+
+			wantExec := tt.operation != "read" && tt.wantStatus == 0
+			if wantExec != (len(executed) == 1) {
+				t.Errorf("Expected write executed = %v, got %v", wantExec, executed)
+			}
+			if tt.operation == "update" && wantExec && executed[0] != "UPDATE documents SET title = 'it''s mine' WHERE id = 5" {
+				t.Errorf("Unexpected update statement: %s", executed[0])
+			}
+		})
+	}
+}
+
+// TestIDOR_Handle_IDHints tests sequential ID hints in responses
+func TestIDOR_Handle_IDHints(t *testing.T) {
+	m := &IDOR{}
+
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			if query == "SELECT COUNT(*) AS total FROM users" {
+				return []map[string]interface{}{{"total": int64(4)}}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	ctx := &HandlerContext{
+		Input: "3",
+		Config: map[string]interface{}{
+			"variant":        "numeric",
+			"query_template": "SELECT * FROM users WHERE id = {input}",
+			"id_hints":       true,
+			"count_query":    "SELECT COUNT(*) AS total FROM users",
+		},
+		Sinks: &SinkContext{SQLite: mockSink},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Hints are included even when the resource does not exist
+	if result.StatusCode != 404 {
+		t.Errorf("Expected status 404, got %d", result.StatusCode)
+	}
+
+	hints, ok := result.Data.(map[string]interface{})["hints"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected hints in response, got %v", result.Data)
+	}
+	if hints["next_id"] != 4 || hints["previous_id"] != 2 || hints["total_records"] != int64(4) {
+		t.Errorf("Unexpected hints: %v", hints)
+	}
+}
+
+// TestIDOR_Handle_NestedVariant tests which of the two identifiers each nested_check validates
+func TestIDOR_Handle_NestedVariant(t *testing.T) {
+	m := &IDOR{}
+
+	// Document 7 belongs to user 2
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{
+				{"id": int64(7), "owner_id": int64(2), "title": "Rick's secrets"},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		nestedCheck string
+		uid         string
+		userID      string
+		wantStatus  int
+	}{
+		{"parent_only - own uid, other user's document", "parent_only", "3", "3", 0},
+		{"parent_only - other uid", "parent_only", "2", "3", 403},
+		{"child_only - other user's uid and document", "child_only", "2", "3", 0},
+		{"child_only - document not under uid", "child_only", "3", "3", 404},
+		{"both - own uid, other user's document", "both", "3", "3", 404},
+		{"both - other uid", "both", "2", "3", 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/users/"+tt.uid+"/documents/7", nil)
+			req.SetPathValue("uid", tt.uid)
+			req.Header.Set("X-User-ID", tt.userID)
+
+			ctx := &HandlerContext{
+				Input: "7",
+				Config: map[string]interface{}{
+					"variant":        "nested",
+					"nested_check":   tt.nestedCheck,
+					"query_template": "SELECT * FROM documents WHERE id = {input}",
+				},
+				Sinks:   &SinkContext{SQLite: mockSink},
+				Request: req,
+			}
+
+			result, err := m.Handle(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.wantStatus, result.StatusCode, result.Data)
+			}
+		})
+	}
+}
+
+// TestIDOR_Handle_GlobalIDVariant tests relay-style node() resolution
+func TestIDOR_Handle_GlobalIDVariant(t *testing.T) {
+	m := &IDOR{}
+
+	var lastQuery string
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			lastQuery = query
+			return []map[string]interface{}{
+				{"username": "admin", "password": "adminpass"},
+			}, nil
+		},
+	}
+
+	config := map[string]interface{}{
+		"variant": "global_id",
+		"node_types": map[string]interface{}{
+			"Note": "SELECT * FROM notes WHERE id = {input}",
+			"User": "SELECT username, password FROM users WHERE id = {input}",
+		},
+	}
+
+	// base64("User:1") inside a GraphQL document
+	ctx := &HandlerContext{
+		Input:  `{ node(id: "VXNlcjox") { ... on User { username password } } }`,
+		Config: config,
+		Sinks:  &SinkContext{SQLite: mockSink},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lastQuery != "SELECT username, password FROM users WHERE id = 1" {
+		t.Errorf("Unexpected query: %s", lastQuery)
+	}
+
+	node, ok := result.Data.(map[string]interface{})["data"].(map[string]interface{})["node"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected node in response, got %v", result.Data)
+	}
+	if node["__typename"] != "User" || node["id"] != "VXNlcjox" || node["password"] != "adminpass" {
+		t.Errorf("Unexpected node: %v", node)
+	}
+
+	// Restricting node() to exposed types blocks the User lookup
+	config["node_type_check"] = true
+	config["exposed_types"] = []interface{}{"Note"}
+	ctx.Input = "VXNlcjox"
+
+	result, err = m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.StatusCode != 403 {
+		t.Errorf("Expected status 403 for unexposed type, got %d", result.StatusCode)
+	}
+
+	// Malformed IDs are reported as GraphQL errors
+	ctx.Input = "not-a-global-id"
+	result, _ = m.Handle(ctx)
+	if _, ok := result.Data.(map[string]interface{})["errors"]; !ok {
+		t.Errorf("Expected GraphQL errors for invalid ID, got %v", result.Data)
+	}
+}
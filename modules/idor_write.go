@@ -0,0 +1,162 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// identity returns the authenticated user ID the request claims, taken from a
+// "Bearer user_<id>" token, the identity header or the identity cookie
+func (m *IDOR) identity(ctx *HandlerContext) string {
+	if ctx.Request == nil {
+		return ""
+	}
+
+	if token := ctx.Request.Header.Get("Authorization"); strings.HasPrefix(token, "Bearer user_") {
+		return strings.TrimPrefix(token, "Bearer user_")
+	}
+	if id := ctx.Request.Header.Get(ctx.GetConfigString("identity_header", "X-User-ID")); id != "" {
+		return id
+	}
+	if cookie, err := ctx.Request.Cookie(ctx.GetConfigString("identity_cookie", "user_id")); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// checkOwnership compares the record's owner column with the authenticated identity.
+// It returns nil when the operation may proceed. With ownership "read_only" only
+// reads are checked, so updates and deletes on other users' records still go through.
+func (m *IDOR) checkOwnership(ctx *HandlerContext, input, operation string, showErrors bool) *Result {
+	ownership := ctx.GetConfigString("ownership", "none")
+	if ownership == "none" || (ownership == "read_only" && operation != "read") {
+		return nil
+	}
+
+	lookupQuery := ctx.GetConfigString("lookup_query", "")
+	if lookupQuery == "" && operation == "read" {
+		lookupQuery = ctx.GetConfigString("query_template", "")
+	}
+	if lookupQuery == "" {
+		return &Result{
+			Error:      "lookup_query is required to check ownership of write operations",
+			StatusCode: 500,
+		}
+	}
+
+	identity := m.identity(ctx)
+	if identity == "" {
+		return &Result{
+			Error:      "unauthorized: no authenticated user",
+			Data:       map[string]interface{}{"error": "unauthorized: no authenticated user"},
+			StatusCode: 401,
+		}
+	}
+
+	rows, err := ctx.Sinks.SQLite.Query(strings.ReplaceAll(lookupQuery, "{input}", input))
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data:  map[string]interface{}{"error": err.Error()},
+			}
+		}
+		return NewErrorResult("Database error")
+	}
+	if len(rows) == 0 {
+		return &Result{
+			Data:       map[string]interface{}{"message": "Resource not found"},
+			StatusCode: 404,
+		}
+	}
+
+	ownerColumn := ctx.GetConfigString("owner_column", "owner_id")
+	if owner := fmt.Sprint(rows[0][ownerColumn]); owner != identity {
+		return &Result{
+			Error:      "forbidden: resource belongs to another user",
+			Data:       map[string]interface{}{"error": "forbidden: resource belongs to another user"},
+			StatusCode: 403,
+		}
+	}
+
+	return nil
+}
+
+// handleWrite runs an UPDATE or DELETE statement for the referenced object.
+// {value} is replaced with the value_param request value (quotes escaped).
+func (m *IDOR) handleWrite(ctx *HandlerContext, statement, operation string, showErrors bool, input string) (*Result, error) {
+	value := requestValue(ctx, ctx.GetConfigString("value_param", "value"))
+	statement = strings.ReplaceAll(statement, "{value}", strings.ReplaceAll(value, "'", "''"))
+
+	if err := ctx.Sinks.SQLite.Exec(statement); err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": statement,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	data := map[string]interface{}{
+		"operation": operation,
+		"id":        input,
+		"success":   true,
+	}
+
+	switch operation {
+	case "update":
+		data["message"] = "Resource updated"
+		// Show the record as it is now
+		if lookupQuery := ctx.GetConfigString("lookup_query", ""); lookupQuery != "" {
+			if rows, err := ctx.Sinks.SQLite.Query(strings.ReplaceAll(lookupQuery, "{input}", input)); err == nil && len(rows) > 0 {
+				data["resource"] = rows[0]
+			}
+		}
+	case "delete":
+		data["message"] = "Resource deleted"
+	}
+
+	return NewResult(data), nil
+}
+
+// withIDHints adds neighbouring IDs to responses for numeric IDs, to teach
+// that sequential identifiers can simply be enumerated
+func (m *IDOR) withIDHints(ctx *HandlerContext, result *Result, input, variant string) *Result {
+	if !ctx.GetConfigBool("id_hints", false) || variant != "numeric" {
+		return result
+	}
+
+	data, ok := result.Data.(map[string]interface{})
+	if !ok {
+		return result
+	}
+
+	id, err := strconv.Atoi(input)
+	if err != nil {
+		return result
+	}
+
+	hints := map[string]interface{}{
+		"next_id": id + 1,
+	}
+	if id > 1 {
+		hints["previous_id"] = id - 1
+	}
+	if countQuery := ctx.GetConfigString("count_query", ""); countQuery != "" {
+		if rows, err := ctx.Sinks.SQLite.Query(countQuery); err == nil && len(rows) > 0 {
+			for _, v := range rows[0] {
+				hints["total_records"] = v
+				break
+			}
+		}
+	}
+	data["hints"] = hints
+
+	return result
+}
@@ -0,0 +1,311 @@
+package modules
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// InsecureCookies implements the insecure_cookies vulnerability module
+type InsecureCookies struct{}
+
+// init registers the module
+func init() {
+	Register(&InsecureCookies{})
+}
+
+// Info returns module metadata
+func (m *InsecureCookies) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "insecure_cookies",
+		Description: "Insecure cookie configuration with missing attributes, predictable session values and client-trusted role cookies",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "", // Optionally uses the SQLite sink to look up users
+		ValidVariants: map[string][]string{
+			"mode":          {"issue", "verify"},
+			"session_value": {"base64_id", "plain_id", "hex_id"},
+			"same_site":     {"unset", "none", "lax", "strict"},
+		},
+		ConfigKeys: []string{
+			"admin_query", "admin_role", "default_role", "http_only", "query_template", "role_cookie",
+			"secure", "session_cookie",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"session_value": "plain_id"},
+			"medium": {"session_value": "base64_id"},
+			"hard":   {"session_value": "hex_id", "http_only": true},
+		},
+		Options: map[string]Option{
+			"mode":           {Description: "issue logs in the user named by the input and sets cookies; verify trusts the session cookie (the input) and the role cookie", Default: "issue"},
+			"session_value":  {Description: "How the session cookie encodes the user ID: base64_id, plain_id or hex_id", Default: "base64_id"},
+			"same_site":      {Description: "SameSite attribute: unset, none, lax or strict", Default: "unset"},
+			"http_only":      {Description: "Set HttpOnly on the cookies", Default: "false"},
+			"secure":         {Description: "Set Secure on the cookies", Default: "false"},
+			"session_cookie": {Description: "Name of the session cookie", Default: "session"},
+			"role_cookie":    {Description: "Name of the role cookie", Default: "role"},
+			"default_role":   {Description: "Role issued when the user isn't looked up or has no role column", Default: "user"},
+			"admin_role":     {Description: "Role cookie value verify treats as an admin", Default: "admin"},
+			"query_template": {Description: "Query looking up the user, with {input} replaced by the username when issuing and the session's user ID when verifying; when unset the input is trusted"},
+			"admin_query":    {Description: "For verify, query whose rows are shown to admins"},
+		},
+		Example: `- path: /profile
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: insecure_cookies
+      placement: cookie
+      param: session
+      config:
+        mode: verify
+        session_value: base64_id
+`,
+		Payloads: []string{"Cookie: session=MQ==; role=admin", "Cookie: session=1; role=admin"},
+	}
+}
+
+// CookieInfo describes a cookie issued by the module
+type CookieInfo struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	HttpOnly bool   `json:"http_only"`
+	Secure   bool   `json:"secure"`
+	SameSite string `json:"same_site"`
+}
+
+// Handle issues insecure cookies or honors (possibly tampered) cookies
+func (m *InsecureCookies) Handle(ctx *HandlerContext) (*Result, error) {
+	mode := ctx.GetConfigString("mode", "issue")
+
+	switch mode {
+	case "issue":
+		return m.handleIssue(ctx)
+	case "verify":
+		return m.handleVerify(ctx)
+	default:
+		return m.handleIssue(ctx)
+	}
+}
+
+// handleIssue "logs in" the user identified by the input and sets session and role cookies
+func (m *InsecureCookies) handleIssue(ctx *HandlerContext) (*Result, error) {
+	sessionCookie := ctx.GetConfigString("session_cookie", "session")
+	roleCookie := ctx.GetConfigString("role_cookie", "role")
+	sessionValue := ctx.GetConfigString("session_value", "base64_id")
+	defaultRole := ctx.GetConfigString("default_role", "user")
+
+	if ctx.Input == "" {
+		return &Result{
+			Error:      "username is required",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	// Resolve the user - fall back to trusting the input when no lookup is configured
+	userID := ctx.Input
+	role := defaultRole
+	if user, err := lookupCookieUser(ctx, ctx.Input); err != nil {
+		return &Result{
+			Error:      err.Error(),
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	} else if user != nil {
+		if id, ok := user["id"]; ok {
+			userID = fmt.Sprintf("%v", id)
+		}
+		if r, ok := user["role"]; ok {
+			role = fmt.Sprintf("%v", r)
+		}
+	}
+
+	cookies := []CookieInfo{
+		m.buildCookieInfo(ctx, sessionCookie, encodeSessionValue(userID, sessionValue)),
+		m.buildCookieInfo(ctx, roleCookie, role),
+	}
+
+	if ctx.ResponseWriter != nil {
+		for _, c := range cookies {
+			http.SetCookie(ctx.ResponseWriter, toHTTPCookie(c))
+		}
+	}
+
+	return NewResult(map[string]interface{}{
+		"message":            "Logged in",
+		"user":               ctx.Input,
+		"user_id":            userID,
+		"role":               role,
+		"cookies":            cookies,
+		"missing_attributes": missingCookieAttributes(cookies[0]),
+	}), nil
+}
+
+// handleVerify trusts the session cookie (input) and the role cookie without any integrity check
+func (m *InsecureCookies) handleVerify(ctx *HandlerContext) (*Result, error) {
+	roleCookie := ctx.GetConfigString("role_cookie", "role")
+	sessionValue := ctx.GetConfigString("session_value", "base64_id")
+	adminRole := ctx.GetConfigString("admin_role", "admin")
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	adminQuery := ctx.GetConfigString("admin_query", "")
+
+	if ctx.Input == "" {
+		return &Result{
+			Error:      "not authenticated: missing session cookie",
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	userID, err := decodeSessionValue(ctx.Input, sessionValue)
+	if err != nil {
+		return &Result{
+			Error:      fmt.Sprintf("invalid session: %v", err),
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	role := ""
+	if ctx.Request != nil {
+		if c, err := ctx.Request.Cookie(roleCookie); err == nil {
+			role = c.Value
+		}
+	}
+
+	data := map[string]interface{}{
+		"user_id": userID,
+		"role":    role,
+		"admin":   role == adminRole,
+	}
+
+	// Load the profile of whichever user the (tamperable) session points to
+	if queryTemplate != "" && ctx.Sinks != nil && ctx.Sinks.SQLite != nil {
+		query := strings.ReplaceAll(queryTemplate, "{input}", userID)
+		results, err := ctx.Sinks.SQLite.Query(query)
+		if err != nil {
+			return &Result{
+				Error: err.Error(),
+				Data:  map[string]interface{}{"query": query, "error": err.Error()},
+			}, nil
+		}
+		data["profile"] = results
+	}
+
+	if role == adminRole {
+		data["message"] = "Welcome to the admin panel"
+		if adminQuery != "" && ctx.Sinks != nil && ctx.Sinks.SQLite != nil {
+			if results, err := ctx.Sinks.SQLite.Query(adminQuery); err == nil {
+				data["admin_data"] = results
+			}
+		}
+	} else {
+		data["message"] = "Welcome back"
+	}
+
+	return NewResult(data), nil
+}
+
+// buildCookieInfo applies the configured (insecure) attributes to a cookie
+func (m *InsecureCookies) buildCookieInfo(ctx *HandlerContext, name, value string) CookieInfo {
+	return CookieInfo{
+		Name:     name,
+		Value:    value,
+		HttpOnly: ctx.GetConfigBool("http_only", false),
+		Secure:   ctx.GetConfigBool("secure", false),
+		SameSite: ctx.GetConfigString("same_site", "unset"),
+	}
+}
+
+// lookupCookieUser finds the user row for the given username when a lookup query is configured
+func lookupCookieUser(ctx *HandlerContext, username string) (map[string]interface{}, error) {
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	if queryTemplate == "" || ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, nil
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", username)
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("unknown user: %s", username)
+	}
+	return results[0], nil
+}
+
+// encodeSessionValue derives a predictable session value from the user ID
+func encodeSessionValue(userID, format string) string {
+	switch format {
+	case "base64_id":
+		return base64.StdEncoding.EncodeToString([]byte(userID))
+	case "hex_id":
+		return hex.EncodeToString([]byte(userID))
+	case "plain_id":
+		return userID
+	default:
+		return base64.StdEncoding.EncodeToString([]byte(userID))
+	}
+}
+
+// decodeSessionValue reverses encodeSessionValue
+func decodeSessionValue(value, format string) (string, error) {
+	switch format {
+	case "hex_id":
+		decoded, err := hex.DecodeString(value)
+		if err != nil {
+			return "", err
+		}
+		return string(decoded), nil
+	case "plain_id":
+		return value, nil
+	default:
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", err
+		}
+		return string(decoded), nil
+	}
+}
+
+// toHTTPCookie converts a CookieInfo into an http.Cookie
+func toHTTPCookie(c CookieInfo) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     "/",
+		HttpOnly: c.HttpOnly,
+		Secure:   c.Secure,
+	}
+
+	switch c.SameSite {
+	case "none":
+		cookie.SameSite = http.SameSiteNoneMode
+	case "lax":
+		cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	}
+
+	return cookie
+}
+
+// missingCookieAttributes lists the security attributes a cookie lacks
+func missingCookieAttributes(c CookieInfo) []string {
+	missing := []string{}
+	if !c.HttpOnly {
+		missing = append(missing, "HttpOnly")
+	}
+	if !c.Secure {
+		missing = append(missing, "Secure")
+	}
+	if c.SameSite == "unset" || c.SameSite == "none" || c.SameSite == "" {
+		missing = append(missing, "SameSite")
+	}
+	return missing
+}
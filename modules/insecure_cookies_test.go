@@ -0,0 +1,175 @@
+package modules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestInsecureCookies_Info tests module metadata
+func TestInsecureCookies_Info(t *testing.T) {
+	m := &InsecureCookies{}
+	info := m.Info()
+
+	if info.Name != "insecure_cookies" {
+		t.Errorf("Expected Name 'insecure_cookies', got '%s'", info.Name)
+	}
+
+	for _, key := range []string{"mode", "session_value", "same_site"} {
+		if _, ok := info.ValidVariants[key]; !ok {
+			t.Errorf("Expected '%s' in ValidVariants", key)
+		}
+	}
+}
+
+// TestInsecureCookies_Issue tests that cookies are issued without security attributes
+func TestInsecureCookies_Issue(t *testing.T) {
+	m := &InsecureCookies{}
+	rec := httptest.NewRecorder()
+
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{
+				{"id": "2", "username": "rick", "role": "user"},
+			}, nil
+		},
+	}
+
+	ctx := &HandlerContext{
+		Input:          "rick",
+		ResponseWriter: rec,
+		Request:        httptest.NewRequest("POST", "/login", nil),
+		Config: map[string]interface{}{
+			"mode":           "issue",
+			"query_template": "SELECT * FROM users WHERE username = '{input}'",
+		},
+		Sinks: &SinkContext{SQLite: mockSink},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := result.Data.(map[string]interface{})
+	if data["user_id"] != "2" {
+		t.Errorf("Expected user_id '2', got '%v'", data["user_id"])
+	}
+
+	setCookies := rec.Header().Values("Set-Cookie")
+	if len(setCookies) != 2 {
+		t.Fatalf("Expected 2 Set-Cookie headers, got %d", len(setCookies))
+	}
+
+	// base64("2") = "Mg=="
+	if !strings.HasPrefix(setCookies[0], "session=Mg==") {
+		t.Errorf("Expected predictable session cookie, got '%s'", setCookies[0])
+	}
+	if strings.Contains(setCookies[0], "HttpOnly") || strings.Contains(setCookies[0], "Secure") {
+		t.Errorf("Expected cookie without HttpOnly/Secure, got '%s'", setCookies[0])
+	}
+
+	missing := data["missing_attributes"].([]string)
+	if len(missing) != 3 {
+		t.Errorf("Expected 3 missing attributes, got %v", missing)
+	}
+}
+
+// TestInsecureCookies_Issue_WithAttributes tests configured cookie attributes
+func TestInsecureCookies_Issue_WithAttributes(t *testing.T) {
+	m := &InsecureCookies{}
+	rec := httptest.NewRecorder()
+
+	ctx := &HandlerContext{
+		Input:          "1",
+		ResponseWriter: rec,
+		Config: map[string]interface{}{
+			"http_only":     true,
+			"same_site":     "strict",
+			"session_value": "hex_id",
+		},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cookie := rec.Header().Values("Set-Cookie")[0]
+	if !strings.Contains(cookie, "HttpOnly") || !strings.Contains(cookie, "SameSite=Strict") {
+		t.Errorf("Expected HttpOnly and SameSite=Strict, got '%s'", cookie)
+	}
+	if !strings.HasPrefix(cookie, "session=31") {
+		t.Errorf("Expected hex encoded session, got '%s'", cookie)
+	}
+
+	missing := result.Data.(map[string]interface{})["missing_attributes"].([]string)
+	if len(missing) != 1 || missing[0] != "Secure" {
+		t.Errorf("Expected only Secure to be missing, got %v", missing)
+	}
+}
+
+// TestInsecureCookies_Verify_TamperedRole tests that a tampered role cookie is trusted
+func TestInsecureCookies_Verify_TamperedRole(t *testing.T) {
+	m := &InsecureCookies{}
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "Mg=="})
+	req.AddCookie(&http.Cookie{Name: "role", Value: "admin"})
+
+	ctx := &HandlerContext{
+		Input:   "Mg==",
+		Request: req,
+		Config: map[string]interface{}{
+			"mode": "verify",
+		},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := result.Data.(map[string]interface{})
+	if data["user_id"] != "2" {
+		t.Errorf("Expected decoded user_id '2', got '%v'", data["user_id"])
+	}
+	if data["admin"] != true {
+		t.Error("Expected tampered role cookie to grant admin access")
+	}
+}
+
+// TestInsecureCookies_Verify_InvalidSession tests rejection of undecodable sessions
+func TestInsecureCookies_Verify_InvalidSession(t *testing.T) {
+	m := &InsecureCookies{}
+
+	ctx := &HandlerContext{
+		Input:   "!!!not-base64",
+		Request: httptest.NewRequest("GET", "/dashboard", nil),
+		Config:  map[string]interface{}{"mode": "verify"},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", result.StatusCode)
+	}
+}
+
+// TestInsecureCookies_SessionEncoding tests session value round trips
+func TestInsecureCookies_SessionEncoding(t *testing.T) {
+	for _, format := range []string{"base64_id", "plain_id", "hex_id"} {
+		encoded := encodeSessionValue("42", format)
+		decoded, err := decodeSessionValue(encoded, format)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", format, err)
+		}
+		if decoded != "42" {
+			t.Errorf("%s: expected '42', got '%s'", format, decoded)
+		}
+	}
+}
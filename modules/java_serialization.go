@@ -0,0 +1,690 @@
+package modules
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Java Object Serialization Stream Protocol constants
+const (
+	javaStreamMagic    = 0xACED
+	javaBaseWireHandle = 0x7E0000
+	javaMaxDepth       = 64
+
+	tcNull           = 0x70
+	tcReference      = 0x71
+	tcClassDesc      = 0x72
+	tcObject         = 0x73
+	tcString         = 0x74
+	tcArray          = 0x75
+	tcClass          = 0x76
+	tcBlockData      = 0x77
+	tcEndBlockData   = 0x78
+	tcReset          = 0x79
+	tcBlockDataLong  = 0x7A
+	tcException      = 0x7B
+	tcLongString     = 0x7C
+	tcProxyClassDesc = 0x7D
+	tcEnum           = 0x7E
+
+	scWriteMethod    = 0x01
+	scSerializable   = 0x02
+	scExternalizable = 0x04
+	scBlockData      = 0x08
+)
+
+// JavaStream is a parsed Java serialization stream
+type JavaStream struct {
+	Version  int              `json:"version"`
+	Contents []interface{}    `json:"contents"`
+	Classes  []*JavaClassDesc `json:"classes"`
+	Strings  []string         `json:"strings,omitempty"`
+}
+
+// JavaClassDesc describes a class descriptor found in the stream
+type JavaClassDesc struct {
+	Name             string         `json:"name"`
+	SerialVersionUID string         `json:"serial_version_uid"`
+	Flags            byte           `json:"flags"`
+	Fields           []JavaField    `json:"fields,omitempty"`
+	Interfaces       []string       `json:"interfaces,omitempty"` // proxy classes only
+	SuperClass       string         `json:"super_class,omitempty"`
+	super            *JavaClassDesc // used to walk the hierarchy when reading class data
+}
+
+// JavaField describes a serializable field of a class
+type JavaField struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	ClassName string `json:"class_name,omitempty"`
+}
+
+// javaObject is an instance read from the stream
+type javaObject struct {
+	class       *JavaClassDesc
+	fields      []javaFieldValue
+	annotations []interface{}
+}
+
+// javaFieldValue is a field value, qualified with its declaring class
+type javaFieldValue struct {
+	name  string
+	value interface{}
+}
+
+// javaArray is an array read from the stream
+type javaArray struct {
+	class    *JavaClassDesc
+	elements []interface{}
+	bytes    int // byte[] arrays are summarized rather than expanded
+}
+
+// javaEnum is an enum constant read from the stream
+type javaEnum struct {
+	class    *JavaClassDesc
+	constant string
+}
+
+// javaClassRef is a java.lang.Class instance read from the stream
+type javaClassRef struct {
+	class *JavaClassDesc
+}
+
+// javaBlock is a block of opaque data written by writeObject/writeExternal
+type javaBlock struct {
+	size int
+}
+
+// javaStreamParser walks the stream, tracking handles for back-references
+type javaStreamParser struct {
+	data    []byte
+	pos     int
+	depth   int
+	handles []interface{}
+	classes []*JavaClassDesc
+	strings []string
+}
+
+// parseJavaStream parses a complete 0xACED serialization stream
+func parseJavaStream(data []byte) (*JavaStream, error) {
+	p := &javaStreamParser{data: data}
+
+	magic, err := p.readUint16()
+	if err != nil || magic != javaStreamMagic {
+		return nil, fmt.Errorf("missing 0xACED stream magic")
+	}
+	version, err := p.readUint16()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &JavaStream{Version: int(version)}
+	for p.pos < len(p.data) {
+		content, err := p.readContent()
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: %w", p.pos, err)
+		}
+		stream.Contents = append(stream.Contents, renderJavaValue(content, make(map[interface{}]bool)))
+	}
+
+	stream.Classes = p.classes
+	stream.Strings = p.strings
+	return stream, nil
+}
+
+// readContent reads a top-level content item (object or block data)
+func (p *javaStreamParser) readContent() (interface{}, error) {
+	tc, err := p.peekByte()
+	if err != nil {
+		return nil, err
+	}
+	if tc == tcBlockData || tc == tcBlockDataLong {
+		return p.readBlockData()
+	}
+	return p.readObject()
+}
+
+// readObject reads any object-valued grammar element
+func (p *javaStreamParser) readObject() (interface{}, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > javaMaxDepth {
+		return nil, fmt.Errorf("nesting exceeds %d levels", javaMaxDepth)
+	}
+
+	tc, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tc {
+	case tcNull:
+		return nil, nil
+
+	case tcReference:
+		return p.readReference()
+
+	case tcClassDesc, tcProxyClassDesc:
+		p.pos--
+		return p.readClassDesc()
+
+	case tcObject:
+		return p.readNewObject()
+
+	case tcString, tcLongString:
+		return p.readNewString(tc)
+
+	case tcArray:
+		return p.readNewArray()
+
+	case tcClass:
+		class, err := p.readClassDesc()
+		if err != nil {
+			return nil, err
+		}
+		ref := &javaClassRef{class: class}
+		p.newHandle(ref)
+		return ref, nil
+
+	case tcEnum:
+		class, err := p.readClassDesc()
+		if err != nil {
+			return nil, err
+		}
+		if class == nil {
+			return nil, fmt.Errorf("enum with null class descriptor")
+		}
+		enum := &javaEnum{class: class}
+		p.newHandle(enum)
+		name, err := p.readObject()
+		if err != nil {
+			return nil, err
+		}
+		enum.constant, _ = name.(string)
+		return enum, nil
+
+	case tcException:
+		p.handles = nil
+		throwable, err := p.readObject()
+		p.handles = nil
+		return throwable, err
+
+	case tcReset:
+		p.handles = nil
+		return p.readObject()
+
+	case tcBlockData, tcBlockDataLong:
+		p.pos--
+		return p.readBlockData()
+
+	default:
+		return nil, fmt.Errorf("unexpected type code 0x%02x", tc)
+	}
+}
+
+// readReference resolves a TC_REFERENCE to a previously assigned handle
+func (p *javaStreamParser) readReference() (interface{}, error) {
+	handle, err := p.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	idx := int(handle) - javaBaseWireHandle
+	if idx < 0 || idx >= len(p.handles) {
+		return nil, fmt.Errorf("invalid handle 0x%x", handle)
+	}
+	return p.handles[idx], nil
+}
+
+// readClassDesc reads a classDesc: new, proxy, null or a reference
+func (p *javaStreamParser) readClassDesc() (*JavaClassDesc, error) {
+	tc, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tc {
+	case tcNull:
+		return nil, nil
+
+	case tcReference:
+		ref, err := p.readReference()
+		if err != nil {
+			return nil, err
+		}
+		class, ok := ref.(*JavaClassDesc)
+		if !ok {
+			return nil, fmt.Errorf("reference is not a class descriptor")
+		}
+		return class, nil
+
+	case tcClassDesc:
+		name, err := p.readUTF()
+		if err != nil {
+			return nil, err
+		}
+		suid, err := p.readInt64()
+		if err != nil {
+			return nil, err
+		}
+
+		class := &JavaClassDesc{
+			Name:             name,
+			SerialVersionUID: fmt.Sprintf("0x%016x", uint64(suid)),
+		}
+		p.newHandle(class)
+		p.classes = append(p.classes, class)
+
+		if class.Flags, err = p.readByte(); err != nil {
+			return nil, err
+		}
+		count, err := p.readUint16()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(count); i++ {
+			field, err := p.readFieldDesc()
+			if err != nil {
+				return nil, err
+			}
+			class.Fields = append(class.Fields, field)
+		}
+
+		return class, p.readClassDescTail(class)
+
+	case tcProxyClassDesc:
+		class := &JavaClassDesc{Name: "$Proxy", SerialVersionUID: "0x0000000000000000"}
+		p.newHandle(class)
+		p.classes = append(p.classes, class)
+
+		count, err := p.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 || int(count) > len(p.data)-p.pos {
+			return nil, fmt.Errorf("invalid proxy interface count %d", count)
+		}
+		for i := 0; i < int(count); i++ {
+			iface, err := p.readUTF()
+			if err != nil {
+				return nil, err
+			}
+			class.Interfaces = append(class.Interfaces, iface)
+		}
+
+		return class, p.readClassDescTail(class)
+
+	default:
+		return nil, fmt.Errorf("expected class descriptor, got type code 0x%02x", tc)
+	}
+}
+
+// readClassDescTail reads the class annotation and super class descriptor
+func (p *javaStreamParser) readClassDescTail(class *JavaClassDesc) error {
+	if _, err := p.readAnnotations(); err != nil {
+		return err
+	}
+	super, err := p.readClassDesc()
+	if err != nil {
+		return err
+	}
+	class.super = super
+	if super != nil {
+		class.SuperClass = super.Name
+	}
+	return nil
+}
+
+// readFieldDesc reads a primitive or object field descriptor
+func (p *javaStreamParser) readFieldDesc() (JavaField, error) {
+	typeCode, err := p.readByte()
+	if err != nil {
+		return JavaField{}, err
+	}
+	name, err := p.readUTF()
+	if err != nil {
+		return JavaField{}, err
+	}
+
+	field := JavaField{Name: name, Type: string(typeCode)}
+	if typeCode == '[' || typeCode == 'L' {
+		className, err := p.readObject()
+		if err != nil {
+			return JavaField{}, err
+		}
+		field.ClassName, _ = className.(string)
+	} else if !isJavaPrimitive(typeCode) {
+		return JavaField{}, fmt.Errorf("invalid field type code '%c'", typeCode)
+	}
+
+	return field, nil
+}
+
+// readNewObject reads TC_OBJECT classDesc newHandle classdata[]
+func (p *javaStreamParser) readNewObject() (interface{}, error) {
+	class, err := p.readClassDesc()
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, fmt.Errorf("object with null class descriptor")
+	}
+
+	obj := &javaObject{class: class}
+	p.newHandle(obj)
+
+	// Class data is written from the topmost superclass down
+	var hierarchy []*JavaClassDesc
+	for c := class; c != nil; c = c.super {
+		hierarchy = append([]*JavaClassDesc{c}, hierarchy...)
+	}
+
+	for _, c := range hierarchy {
+		switch {
+		case c.Flags&scExternalizable != 0:
+			if c.Flags&scBlockData == 0 {
+				return nil, fmt.Errorf("externalizable class %s uses unsupported protocol version 1", c.Name)
+			}
+			annotations, err := p.readAnnotations()
+			if err != nil {
+				return nil, err
+			}
+			obj.annotations = append(obj.annotations, annotations...)
+
+		case c.Flags&scSerializable != 0:
+			for _, field := range c.Fields {
+				value, err := p.readFieldValue(field.Type[0])
+				if err != nil {
+					return nil, err
+				}
+				obj.fields = append(obj.fields, javaFieldValue{name: field.Name, value: value})
+			}
+			if c.Flags&scWriteMethod != 0 {
+				annotations, err := p.readAnnotations()
+				if err != nil {
+					return nil, err
+				}
+				obj.annotations = append(obj.annotations, annotations...)
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+// readNewArray reads TC_ARRAY classDesc newHandle size values
+func (p *javaStreamParser) readNewArray() (interface{}, error) {
+	class, err := p.readClassDesc()
+	if err != nil {
+		return nil, err
+	}
+	if class == nil || len(class.Name) < 2 || class.Name[0] != '[' {
+		return nil, fmt.Errorf("array with invalid class descriptor")
+	}
+
+	arr := &javaArray{class: class}
+	p.newHandle(arr)
+
+	size, err := p.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	// Every element takes at least one byte, so larger sizes are malformed
+	if size < 0 || int(size) > len(p.data)-p.pos {
+		return nil, fmt.Errorf("invalid array size %d", size)
+	}
+
+	elemType := class.Name[1]
+	if elemType == 'B' {
+		arr.bytes = int(size)
+		p.pos += int(size)
+		return arr, nil
+	}
+
+	for i := 0; i < int(size); i++ {
+		value, err := p.readFieldValue(elemType)
+		if err != nil {
+			return nil, err
+		}
+		arr.elements = append(arr.elements, value)
+	}
+
+	return arr, nil
+}
+
+// readNewString reads TC_STRING / TC_LONGSTRING
+func (p *javaStreamParser) readNewString(tc byte) (interface{}, error) {
+	var s string
+	var err error
+	if tc == tcLongString {
+		var length int64
+		if length, err = p.readInt64(); err == nil {
+			s, err = p.readBytes(length)
+		}
+	} else {
+		s, err = p.readUTF()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.newHandle(s)
+	p.strings = append(p.strings, s)
+	return s, nil
+}
+
+// readBlockData reads TC_BLOCKDATA / TC_BLOCKDATALONG
+func (p *javaStreamParser) readBlockData() (interface{}, error) {
+	tc, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	if tc == tcBlockData {
+		b, err := p.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size = int64(b)
+	} else {
+		n, err := p.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		size = int64(n)
+	}
+
+	if _, err := p.readBytes(size); err != nil {
+		return nil, err
+	}
+	return &javaBlock{size: int(size)}, nil
+}
+
+// readAnnotations reads contents up to TC_ENDBLOCKDATA
+func (p *javaStreamParser) readAnnotations() ([]interface{}, error) {
+	var contents []interface{}
+	for {
+		tc, err := p.peekByte()
+		if err != nil {
+			return nil, err
+		}
+		if tc == tcEndBlockData {
+			p.pos++
+			return contents, nil
+		}
+		content, err := p.readContent()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+}
+
+// readFieldValue reads a single value of the given field type code
+func (p *javaStreamParser) readFieldValue(typeCode byte) (interface{}, error) {
+	switch typeCode {
+	case 'B':
+		b, err := p.readByte()
+		return int8(b), err
+	case 'C':
+		c, err := p.readUint16()
+		return string(rune(c)), err
+	case 'D':
+		v, err := p.readInt64()
+		return math.Float64frombits(uint64(v)), err
+	case 'F':
+		v, err := p.readInt32()
+		return math.Float32frombits(uint32(v)), err
+	case 'I':
+		return p.readInt32()
+	case 'J':
+		return p.readInt64()
+	case 'S':
+		v, err := p.readUint16()
+		return int16(v), err
+	case 'Z':
+		b, err := p.readByte()
+		return b != 0, err
+	case 'L', '[':
+		return p.readObject()
+	default:
+		return nil, fmt.Errorf("invalid field type code '%c'", typeCode)
+	}
+}
+
+// newHandle assigns the next wire handle to a value
+func (p *javaStreamParser) newHandle(v interface{}) {
+	p.handles = append(p.handles, v)
+}
+
+func (p *javaStreamParser) peekByte() (byte, error) {
+	if p.pos >= len(p.data) {
+		return 0, fmt.Errorf("unexpected end of stream")
+	}
+	return p.data[p.pos], nil
+}
+
+func (p *javaStreamParser) readByte() (byte, error) {
+	b, err := p.peekByte()
+	if err == nil {
+		p.pos++
+	}
+	return b, err
+}
+
+func (p *javaStreamParser) readBytes(n int64) (string, error) {
+	if n < 0 || n > int64(len(p.data)-p.pos) {
+		return "", fmt.Errorf("unexpected end of stream")
+	}
+	s := string(p.data[p.pos : p.pos+int(n)])
+	p.pos += int(n)
+	return s, nil
+}
+
+func (p *javaStreamParser) readUint16() (uint16, error) {
+	b, err := p.readBytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16([]byte(b)), nil
+}
+
+func (p *javaStreamParser) readInt32() (int32, error) {
+	b, err := p.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32([]byte(b))), nil
+}
+
+func (p *javaStreamParser) readInt64() (int64, error) {
+	b, err := p.readBytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64([]byte(b))), nil
+}
+
+// readUTF reads a length-prefixed (modified) UTF-8 string
+func (p *javaStreamParser) readUTF() (string, error) {
+	length, err := p.readUint16()
+	if err != nil {
+		return "", err
+	}
+	return p.readBytes(int64(length))
+}
+
+// isJavaPrimitive reports whether a field type code is a primitive type
+func isJavaPrimitive(typeCode byte) bool {
+	switch typeCode {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z':
+		return true
+	}
+	return false
+}
+
+// renderJavaValue converts parsed values into JSON-friendly maps.
+// Objects already being rendered are emitted as references to avoid cycles.
+func renderJavaValue(v interface{}, visiting map[interface{}]bool) interface{} {
+	switch t := v.(type) {
+	case *javaObject:
+		if visiting[t] {
+			return map[string]interface{}{"$ref": t.class.Name}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		fields := make(map[string]interface{}, len(t.fields))
+		for _, f := range t.fields {
+			fields[f.name] = renderJavaValue(f.value, visiting)
+		}
+		out := map[string]interface{}{
+			"class":  t.class.Name,
+			"fields": fields,
+		}
+		if len(t.annotations) > 0 {
+			var annotations []interface{}
+			for _, a := range t.annotations {
+				annotations = append(annotations, renderJavaValue(a, visiting))
+			}
+			out["annotations"] = annotations
+		}
+		return out
+
+	case *javaArray:
+		if t.bytes > 0 || t.class.Name == "[B" {
+			return map[string]interface{}{"class": t.class.Name, "length": t.bytes}
+		}
+		if visiting[t] {
+			return map[string]interface{}{"$ref": t.class.Name}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		elements := make([]interface{}, 0, len(t.elements))
+		for _, e := range t.elements {
+			elements = append(elements, renderJavaValue(e, visiting))
+		}
+		return map[string]interface{}{"class": t.class.Name, "elements": elements}
+
+	case *javaEnum:
+		return map[string]interface{}{"enum": t.class.Name, "constant": t.constant}
+
+	case *javaClassRef:
+		name := ""
+		if t.class != nil {
+			name = t.class.Name
+		}
+		return map[string]interface{}{"class": "java.lang.Class", "value": name}
+
+	case *JavaClassDesc:
+		return map[string]interface{}{"class_desc": t.Name}
+
+	case *javaBlock:
+		return map[string]interface{}{"block_data": t.size}
+
+	default:
+		return t
+	}
+}
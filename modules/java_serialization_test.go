@@ -0,0 +1,237 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// javaStreamWriter builds serialization streams for tests
+type javaStreamWriter struct {
+	bytes.Buffer
+}
+
+func newJavaStreamWriter() *javaStreamWriter {
+	w := &javaStreamWriter{}
+	w.Write([]byte{0xAC, 0xED, 0x00, 0x05})
+	return w
+}
+
+func (w *javaStreamWriter) utf(s string) {
+	binary.Write(w, binary.BigEndian, uint16(len(s)))
+	w.WriteString(s)
+}
+
+func (w *javaStreamWriter) str(s string) {
+	w.WriteByte(tcString)
+	w.utf(s)
+}
+
+func (w *javaStreamWriter) classDesc(name string, suid int64, flags byte, fields ...[3]string) {
+	w.WriteByte(tcClassDesc)
+	w.utf(name)
+	binary.Write(w, binary.BigEndian, suid)
+	w.WriteByte(flags)
+	binary.Write(w, binary.BigEndian, uint16(len(fields)))
+	for _, f := range fields {
+		w.WriteByte(f[0][0])
+		w.utf(f[1])
+		if f[2] != "" {
+			w.str(f[2])
+		}
+	}
+	w.WriteByte(tcEndBlockData)
+}
+
+// buildInvokerTransformerStream writes a minimal CommonsCollections-style
+// InvokerTransformer calling exec with a String[] argument
+func buildInvokerTransformerStream(cmd string) []byte {
+	w := newJavaStreamWriter()
+	w.WriteByte(tcObject)
+	w.classDesc("org.apache.commons.collections.functors.InvokerTransformer", -8653385846894047688, scSerializable,
+		[3]string{"[", "iArgs", "[Ljava/lang/Object;"},
+		[3]string{"L", "iMethodName", "Ljava/lang/String;"},
+	)
+	w.WriteByte(tcNull) // no superclass
+
+	// iArgs = new Object[]{cmd}
+	w.WriteByte(tcArray)
+	w.classDesc("[Ljava.lang.Object;", -8012369246846506644, scSerializable)
+	w.WriteByte(tcNull)
+	binary.Write(w, binary.BigEndian, int32(1))
+	w.str(cmd)
+
+	// iMethodName
+	w.str("exec")
+	return w.Bytes()
+}
+
+func TestParseJavaStream_ObjectWithSuperclass(t *testing.T) {
+	w := newJavaStreamWriter()
+	w.WriteByte(tcObject)
+	w.classDesc("com.example.Admin", 42, scSerializable,
+		[3]string{"Z", "superuser", ""},
+	)
+	w.classDesc("com.example.User", 7, scSerializable,
+		[3]string{"I", "age", ""},
+		[3]string{"L", "name", "Ljava/lang/String;"},
+	)
+	w.WriteByte(tcNull)
+	// User fields come first, then Admin
+	binary.Write(w, binary.BigEndian, int32(31))
+	w.str("alice")
+	w.WriteByte(1)
+
+	// A second object reusing the Admin descriptor by handle
+	w.WriteByte(tcObject)
+	w.WriteByte(tcReference)
+	binary.Write(w, binary.BigEndian, int32(javaBaseWireHandle))
+	binary.Write(w, binary.BigEndian, int32(40))
+	w.WriteByte(tcReference)
+	binary.Write(w, binary.BigEndian, int32(javaBaseWireHandle+4)) // "alice"
+	w.WriteByte(0)
+
+	stream, err := parseJavaStream(w.Bytes())
+	if err != nil {
+		t.Fatalf("parseJavaStream() error = %v", err)
+	}
+
+	if stream.Version != 5 {
+		t.Errorf("Version = %d, want 5", stream.Version)
+	}
+	if len(stream.Classes) != 2 {
+		t.Fatalf("expected 2 classes, got %d", len(stream.Classes))
+	}
+	admin := stream.Classes[0]
+	if admin.Name != "com.example.Admin" || admin.SerialVersionUID != "0x000000000000002a" || admin.SuperClass != "com.example.User" {
+		t.Errorf("unexpected class descriptor: %+v", admin)
+	}
+	if stream.Classes[1].Fields[1].ClassName != "Ljava/lang/String;" {
+		t.Errorf("expected field class name, got %+v", stream.Classes[1].Fields[1])
+	}
+
+	if len(stream.Contents) != 2 {
+		t.Fatalf("expected 2 top-level objects, got %d", len(stream.Contents))
+	}
+	first := stream.Contents[0].(map[string]interface{})
+	fields := first["fields"].(map[string]interface{})
+	if first["class"] != "com.example.Admin" || fields["age"] != int32(31) || fields["name"] != "alice" || fields["superuser"] != true {
+		t.Errorf("unexpected first object: %v", first)
+	}
+	second := stream.Contents[1].(map[string]interface{})
+	fields = second["fields"].(map[string]interface{})
+	if fields["name"] != "alice" || fields["superuser"] != false {
+		t.Errorf("unexpected second object: %v", second)
+	}
+}
+
+func TestParseJavaStream_Errors(t *testing.T) {
+	full := buildInvokerTransformerStream("id")
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"bad magic", []byte{0xCA, 0xFE, 0x00, 0x05}},
+		{"truncated", full[:len(full)-3]},
+		{"invalid handle", append([]byte{0xAC, 0xED, 0x00, 0x05, tcReference}, 0x00, 0x7E, 0x00, 0x09)},
+		{"unknown type code", []byte{0xAC, 0xED, 0x00, 0x05, 0x01}},
+		{"enum with null class", []byte{0xAC, 0xED, 0x00, 0x05, tcEnum, tcNull, tcNull}},
+		{"object with null class", []byte{0xAC, 0xED, 0x00, 0x05, tcObject, tcNull}},
+		{"array with null class", []byte{0xAC, 0xED, 0x00, 0x05, tcArray, tcNull, 0x00, 0x00, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseJavaStream(tt.input); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseJavaStream_DepthLimit(t *testing.T) {
+	w := newJavaStreamWriter()
+	w.WriteByte(tcObject)
+	w.classDesc("Node", 1, scSerializable, [3]string{"L", "next", "LNode;"})
+	w.WriteByte(tcNull)
+	for i := 0; i < javaMaxDepth+1; i++ {
+		w.WriteByte(tcObject)
+		w.WriteByte(tcReference)
+		binary.Write(w, binary.BigEndian, int32(javaBaseWireHandle))
+	}
+	w.WriteByte(tcNull)
+
+	if _, err := parseJavaStream(w.Bytes()); err == nil {
+		t.Error("expected nesting limit error")
+	}
+}
+
+func TestProcessJavaSerialized_BinaryStream(t *testing.T) {
+	data := string(buildInvokerTransformerStream("touch /tmp/pwned"))
+
+	result := &DeserializationResult{}
+	processJavaSerialized(result, data, true)
+
+	if result.ClassName != "org.apache.commons.collections.functors.InvokerTransformer" {
+		t.Errorf("ClassName = %q", result.ClassName)
+	}
+	if result.GadgetChain != "CommonsCollections" {
+		t.Errorf("GadgetChain = %q, want CommonsCollections", result.GadgetChain)
+	}
+	if result.SimulatedCmd != "touch /tmp/pwned" {
+		t.Errorf("SimulatedCmd = %q, want the exec argument", result.SimulatedCmd)
+	}
+	if _, ok := result.Properties["classes"]; !ok {
+		t.Error("expected parsed classes in properties")
+	}
+}
+
+func TestProcessJavaSerialized_CorruptStreamFallsBack(t *testing.T) {
+	full := buildInvokerTransformerStream("id")
+	data := string(full[:len(full)-10])
+
+	result := &DeserializationResult{}
+	processJavaSerialized(result, data, false)
+
+	if _, ok := result.Properties["parse_error"]; !ok {
+		t.Error("expected parse_error in properties")
+	}
+	if result.GadgetChain != "CommonsCollections" {
+		t.Errorf("expected pattern fallback to detect the chain, got %q", result.GadgetChain)
+	}
+}
+
+func TestProcessJavaSerialized_NullClassEnum(t *testing.T) {
+	result := &DeserializationResult{}
+	processJavaSerialized(result, "\xac\xed00~pp", false)
+
+	if result.Properties["parse_error"] == nil {
+		t.Errorf("expected parse_error in properties, got %v", result.Properties)
+	}
+}
+
+func TestDeserializationHandle_Base64JavaStream(t *testing.T) {
+	m := &Deserialization{}
+	ctx := &HandlerContext{
+		Input:  base64.StdEncoding.EncodeToString(buildInvokerTransformerStream("whoami")),
+		Config: map[string]interface{}{"format": "java", "emulate_execution": true},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	data, ok := result.Data.(*DeserializationResult)
+	if !ok {
+		t.Fatalf("unexpected result data: %T", result.Data)
+	}
+	if data.ClassName != "org.apache.commons.collections.functors.InvokerTransformer" {
+		t.Errorf("ClassName = %q", data.ClassName)
+	}
+	if data.SimulatedCmd != "whoami" {
+		t.Errorf("SimulatedCmd = %q, want whoami", data.SimulatedCmd)
+	}
+}
@@ -0,0 +1,192 @@
+package modules
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LDAPInjection implements the ldap_injection vulnerability module
+type LDAPInjection struct{}
+
+// init registers the module
+func init() {
+	Register(&LDAPInjection{})
+}
+
+// Info returns module metadata
+func (m *LDAPInjection) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "ldap_injection",
+		Description: "LDAP Injection in directory search and login filters",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "ldap",
+		ValidVariants: map[string][]string{
+			"variant": {"search", "login"},
+			"scope":   {"base", "one", "sub"},
+		},
+		ConfigKeys: []string{
+			"base_dn", "escape_input", "filter_template", "hidden_attributes", "password_param",
+			"show_errors",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"show_errors": true},
+			"medium": {"show_errors": false},
+		},
+		Options: map[string]Option{
+			"variant":           {Description: "search returns matching entries; login succeeds if the filter matches", Default: "search"},
+			"filter_template":   {Description: "LDAP filter with {input} replaced by the input and {password} by password_param", Default: "(uid={input}), or (&(uid={input})(userPassword={password})) for login"},
+			"base_dn":           {Description: "Base DN searched", Default: "the directory's base DN"},
+			"scope":             {Description: "Search scope: base, one or sub", Default: "sub"},
+			"escape_input":      {Description: "Escape filter metacharacters in the input, the fixed version", Default: "false"},
+			"password_param":    {Description: "Request parameter holding the password for login", Default: "password"},
+			"hidden_attributes": {Description: "Attributes left out of search results", Default: "[userPassword]"},
+			"show_errors":       {Description: "Return the filter with directory errors", Default: "true"},
+		},
+		Example: `- path: /directory
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: ldap_injection
+      placement: query_param
+      param: user
+      config:
+        variant: search
+`,
+		Payloads: []string{"*", "*)(uid=*", "admin)(|(uid=*", "*)(userPassword=*"},
+	}
+}
+
+// Handle builds an LDAP filter from the input and runs it against the directory
+func (m *LDAPInjection) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.LDAP == nil {
+		return nil, fmt.Errorf("LDAP sink not available")
+	}
+
+	// Get configuration
+	variant := ctx.GetConfigString("variant", "search")
+	scope := ctx.GetConfigString("scope", "sub")
+	baseDN := ctx.GetConfigString("base_dn", ctx.Sinks.LDAP.BaseDN())
+	escapeInput := ctx.GetConfigBool("escape_input", false)
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	defaultTemplate := "(uid={input})"
+	if variant == "login" {
+		defaultTemplate = "(&(uid={input})(userPassword={password}))"
+	}
+	filterTemplate := ctx.GetConfigString("filter_template", defaultTemplate)
+
+	input := ctx.Input
+	password := requestValue(ctx, ctx.GetConfigString("password_param", "password"))
+	if escapeInput {
+		input = escapeLDAPFilter(input)
+		password = escapeLDAPFilter(password)
+	}
+
+	filter := strings.ReplaceAll(filterTemplate, "{input}", input)
+	filter = strings.ReplaceAll(filter, "{password}", password)
+
+	entries, err := ctx.Sinks.LDAP.Search(baseDN, scope, filter)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"filter": filter,
+					"error":  err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Directory error"), nil
+	}
+
+	if variant == "login" {
+		return m.handleLogin(entries), nil
+	}
+
+	hidden := getStringSlice(ctx.Config, "hidden_attributes", []string{"userPassword"})
+	results := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, ldapEntryData(entry, hidden))
+	}
+
+	return NewResult(map[string]interface{}{
+		"base_dn": baseDN,
+		"count":   len(results),
+		"entries": results,
+	}), nil
+}
+
+// handleLogin authenticates as the first entry the filter matched
+func (m *LDAPInjection) handleLogin(entries []LDAPEntry) *Result {
+	if len(entries) == 0 {
+		return &Result{
+			Data: map[string]interface{}{
+				"authenticated": false,
+				"message":       "Invalid username or password",
+			},
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+
+	user := entries[0]
+	uid := ""
+	if values := user.Attributes["uid"]; len(values) > 0 {
+		uid = values[0]
+	}
+
+	return NewResult(map[string]interface{}{
+		"authenticated": true,
+		"message":       "Login successful",
+		"user":          uid,
+		"dn":            user.DN,
+	})
+}
+
+// ldapEntryData flattens an entry for the response, leaving out hidden attributes.
+// Single-valued attributes are returned as strings.
+func ldapEntryData(entry LDAPEntry, hidden []string) map[string]interface{} {
+	data := map[string]interface{}{"dn": entry.DN}
+
+	for name, values := range entry.Attributes {
+		skip := false
+		for _, h := range hidden {
+			if strings.EqualFold(name, h) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+
+		if len(values) == 1 {
+			data[name] = values[0]
+		} else {
+			data[name] = values
+		}
+	}
+
+	return data
+}
+
+// escapeLDAPFilter escapes a value for use in a search filter (RFC 4515)
+func escapeLDAPFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, `\%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
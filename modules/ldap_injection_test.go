@@ -0,0 +1,170 @@
+package modules
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// MockLDAPSink records filters and answers with fixed entries
+type MockLDAPSink struct {
+	Entries []LDAPEntry
+	Err     error
+	Filters []string
+}
+
+func (m *MockLDAPSink) Search(baseDN, scope, filter string) ([]LDAPEntry, error) {
+	m.Filters = append(m.Filters, filter)
+	return m.Entries, m.Err
+}
+
+func (m *MockLDAPSink) BaseDN() string {
+	return "dc=flawfactory,dc=local"
+}
+
+// TestLDAPInjectionModuleInfo tests module metadata
+func TestLDAPInjectionModuleInfo(t *testing.T) {
+	m := &LDAPInjection{}
+	info := m.Info()
+
+	if info.Name != "ldap_injection" {
+		t.Errorf("Expected name 'ldap_injection', got '%s'", info.Name)
+	}
+	if info.RequiresSink != "ldap" {
+		t.Errorf("Expected RequiresSink 'ldap', got '%s'", info.RequiresSink)
+	}
+	if !Has("ldap_injection") {
+		t.Error("ldap_injection module should be registered")
+	}
+}
+
+// TestLDAPInjection_Search tests filters are built from the raw input and results hide passwords
+func TestLDAPInjection_Search(t *testing.T) {
+	m := &LDAPInjection{}
+	sink := &MockLDAPSink{Entries: []LDAPEntry{
+		{DN: "uid=admin,ou=people,dc=flawfactory,dc=local", Attributes: map[string][]string{
+			"uid": {"admin"}, "userPassword": {"S3cret!"}, "objectClass": {"top", "person"},
+		}},
+	}}
+
+	ctx := &HandlerContext{
+		Input:  "*)(uid=*",
+		Config: map[string]interface{}{"filter_template": "(&(objectClass=person)(uid={input}))"},
+		Sinks:  &SinkContext{LDAP: sink},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sink.Filters[0] != "(&(objectClass=person)(uid=*)(uid=*))" {
+		t.Errorf("Unexpected filter: %s", sink.Filters[0])
+	}
+
+	data := result.Data.(map[string]interface{})
+	entry := data["entries"].([]map[string]interface{})[0]
+	if _, ok := entry["userPassword"]; ok {
+		t.Error("Expected userPassword to be hidden by default")
+	}
+	if entry["uid"] != "admin" {
+		t.Errorf("Expected single values as strings, got %v", entry["uid"])
+	}
+	if classes, ok := entry["objectClass"].([]string); !ok || len(classes) != 2 {
+		t.Errorf("Expected multi-valued attribute as list, got %v", entry["objectClass"])
+	}
+}
+
+// TestLDAPInjection_EscapeInput tests the secure configuration escapes filter metacharacters
+func TestLDAPInjection_EscapeInput(t *testing.T) {
+	m := &LDAPInjection{}
+	sink := &MockLDAPSink{}
+
+	ctx := &HandlerContext{
+		Input:  "*)(uid=*",
+		Config: map[string]interface{}{"escape_input": true},
+		Sinks:  &SinkContext{LDAP: sink},
+	}
+	m.Handle(ctx)
+
+	if sink.Filters[0] != `(uid=\2a\29\28uid=\2a)` {
+		t.Errorf("Expected escaped filter, got %s", sink.Filters[0])
+	}
+}
+
+// TestLDAPInjection_Login tests the login variant and the password placeholder
+func TestLDAPInjection_Login(t *testing.T) {
+	m := &LDAPInjection{}
+
+	tests := []struct {
+		name       string
+		entries    []LDAPEntry
+		wantStatus int
+		wantAuth   bool
+	}{
+		{"filter matched", []LDAPEntry{{DN: "uid=admin,ou=people", Attributes: map[string][]string{"uid": {"admin"}}}}, 0, true},
+		{"no match", nil, 401, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &MockLDAPSink{Entries: tt.entries}
+			form := url.Values{"username": {"admin)(&))"}, "password": {"anything"}}
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			ctx := &HandlerContext{
+				Input:   "admin)(&))",
+				Config:  map[string]interface{}{"variant": "login"},
+				Sinks:   &SinkContext{LDAP: sink},
+				Request: req,
+			}
+
+			result, err := m.Handle(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sink.Filters[0] != "(&(uid=admin)(&)))(userPassword=anything))" {
+				t.Errorf("Unexpected filter: %s", sink.Filters[0])
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, result.StatusCode)
+			}
+			if auth := result.Data.(map[string]interface{})["authenticated"]; auth != tt.wantAuth {
+				t.Errorf("Expected authenticated=%v, got %v", tt.wantAuth, auth)
+			}
+		})
+	}
+}
+
+// TestLDAPInjection_Errors tests filter errors are surfaced or hidden by show_errors
+func TestLDAPInjection_Errors(t *testing.T) {
+	m := &LDAPInjection{}
+	sink := &MockLDAPSink{Err: fmt.Errorf("Bad search filter")}
+
+	ctx := &HandlerContext{
+		Input:  "admin)",
+		Config: map[string]interface{}{},
+		Sinks:  &SinkContext{LDAP: sink},
+	}
+
+	result, _ := m.Handle(ctx)
+	if result.Error != "Bad search filter" {
+		t.Errorf("Expected directory error, got %q", result.Error)
+	}
+	if result.Data.(map[string]interface{})["filter"] != "(uid=admin))" {
+		t.Errorf("Expected filter in error response, got %v", result.Data)
+	}
+
+	ctx.Config["show_errors"] = false
+	result, _ = m.Handle(ctx)
+	if result.Error != "Directory error" {
+		t.Errorf("Expected generic error, got %q", result.Error)
+	}
+
+	if _, err := m.Handle(&HandlerContext{Config: map[string]interface{}{}}); err == nil {
+		t.Error("Expected error without LDAP sink")
+	}
+}
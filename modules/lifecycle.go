@@ -0,0 +1,34 @@
+package modules
+
+import "context"
+
+// Initializer is implemented by modules that hold background state, such as
+// listeners, caches or timers. The builder calls Init before the lab serves
+// requests; ctx is cancelled once the module is shut down, so goroutines it
+// starts can stop with it.
+type Initializer interface {
+	Init(ctx context.Context) error
+}
+
+// Shutdowner is implemented by modules with resources to release. The builder
+// calls Shutdown when the lab stops, or a reload drops the module's last
+// vulnerability.
+type Shutdowner interface {
+	Shutdown() error
+}
+
+// Init starts a module's background state, if it has any
+func Init(ctx context.Context, module Module) error {
+	if initializer, ok := module.(Initializer); ok {
+		return initializer.Init(ctx)
+	}
+	return nil
+}
+
+// Shutdown releases a module's resources, if it holds any
+func Shutdown(module Module) error {
+	if shutdowner, ok := module.(Shutdowner); ok {
+		return shutdowner.Shutdown()
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package modules
+
+import (
+	"context"
+	"testing"
+)
+
+// TestLifecycle tests Init and Shutdown reach modules with hooks, and skip
+// those without
+func TestLifecycle(t *testing.T) {
+	if err := Init(context.Background(), &mockModule{name: "plain"}); err != nil {
+		t.Errorf("Expected no error for a module without hooks, got %v", err)
+	}
+	if err := Shutdown(&mockModule{name: "plain"}); err != nil {
+		t.Errorf("Expected no error for a module without hooks, got %v", err)
+	}
+
+	module := &hookedModule{mockModule: mockModule{name: "hooked"}}
+	if err := Init(context.Background(), module); err != nil || !module.started {
+		t.Fatalf("Expected Init to start the module, got %v", err)
+	}
+	if err := Shutdown(module); err != nil || module.started {
+		t.Errorf("Expected Shutdown to stop the module, got %v", err)
+	}
+}
+
+// hookedModule is a module with lifecycle hooks
+type hookedModule struct {
+	mockModule
+	started bool
+}
+
+func (m *hookedModule) Init(ctx context.Context) error {
+	m.started = true
+	return nil
+}
+
+func (m *hookedModule) Shutdown() error {
+	m.started = false
+	return nil
+}
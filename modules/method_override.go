@@ -0,0 +1,243 @@
+package modules
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MethodOverride implements the method_override vulnerability module
+type MethodOverride struct{}
+
+// init registers the module
+func init() {
+	Register(&MethodOverride{})
+}
+
+// defaultOverrideHeaders are the headers commonly honored by frameworks for method overriding
+var defaultOverrideHeaders = []string{
+	"X-HTTP-Method-Override",
+	"X-HTTP-Method",
+	"X-Method-Override",
+}
+
+// Info returns module metadata
+func (m *MethodOverride) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "method_override",
+		Description: "HTTP Method Override bypass where read-only endpoints honor X-HTTP-Method-Override / _method to perform privileged state changes",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "sqlite",
+		ValidVariants: map[string][]string{
+			"channel": {"all", "header", "query_param", "form_field"},
+		},
+		ConfigKeys: []string{"actions", "headers", "method_param", "query_template", "show_errors"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"channel": "all", "show_errors": true},
+			"medium": {"channel": "header", "show_errors": true},
+			"hard":   {"channel": "form_field", "show_errors": false},
+		},
+		Options: map[string]Option{
+			"query_template": {Description: "Read-only query for the resource, with {input} replaced by the ID; required"},
+			"actions":        {Description: "Map of overridden method, e.g. DELETE, to the statement it runs, with {input} replaced by the ID"},
+			"channel":        {Description: "Where an override is honored: all, header, query_param or form_field", Default: "all"},
+			"headers":        {Description: "Headers honored as overrides", Default: "[X-HTTP-Method-Override, X-HTTP-Method, X-Method-Override]"},
+			"method_param":   {Description: "Query or form parameter honored as an override", Default: "_method"},
+			"show_errors":    {Description: "Return database errors", Default: "true"},
+		},
+		Example: `- path: /api/users/{id}
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: method_override
+      placement: path_param
+      param: id
+      config:
+        query_template: "SELECT * FROM users WHERE id = {input}"
+        actions:
+          DELETE: "DELETE FROM users WHERE id = {input}"
+`,
+		Payloads: []string{"X-HTTP-Method-Override: DELETE", "?_method=DELETE", "_method=PUT"},
+	}
+}
+
+// Handle reads the target resource, or performs a privileged action when the
+// request smuggles a different method through an override channel
+func (m *MethodOverride) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil, fmt.Errorf("SQLite sink not available")
+	}
+
+	// Get configuration
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	channel := ctx.GetConfigString("channel", "all")
+	methodParam := ctx.GetConfigString("method_param", "_method")
+	headers := getStringSlice(ctx.Config, "headers", defaultOverrideHeaders)
+	actions := make(map[string]string)
+	for method, statement := range getStringMap(ctx.Config, "actions") {
+		actions[strings.ToUpper(method)] = statement
+	}
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	if queryTemplate == "" {
+		return nil, fmt.Errorf("query_template is required for method_override")
+	}
+
+	input := ctx.Input
+	if input == "" {
+		return &Result{
+			Error:      "ID parameter is required",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	query := strings.ReplaceAll(queryTemplate, "{input}", input)
+
+	// Look for an override in the honored channels (intentionally trusted)
+	overrideMethod, overrideSource := findMethodOverride(ctx.Request, ctx.Body, channel, methodParam, headers)
+	if overrideMethod == "" || overrideMethod == ctx.Request.Method {
+		return m.read(ctx, query, showErrors)
+	}
+
+	statementTemplate, ok := actions[overrideMethod]
+	if !ok {
+		return &Result{
+			Error: fmt.Sprintf("method %s is not supported for this resource", overrideMethod),
+			Data: map[string]interface{}{
+				"override_method": overrideMethod,
+				"override_source": overrideSource,
+			},
+			StatusCode: http.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	// Perform the privileged action - no authorization check for the smuggled method
+	statement := strings.ReplaceAll(statementTemplate, "{input}", input)
+	if err := ctx.Sinks.SQLite.Exec(statement); err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"override_method": overrideMethod,
+					"override_source": overrideSource,
+					"statement":       statement,
+					"error":           err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	data := map[string]interface{}{
+		"message":         fmt.Sprintf("%s performed via method override", overrideMethod),
+		"override_method": overrideMethod,
+		"override_source": overrideSource,
+		"statement":       statement,
+	}
+
+	// Show the resource after the change so the effect is visible
+	if results, err := ctx.Sinks.SQLite.Query(query); err == nil {
+		data["resource"] = results
+	}
+
+	return NewResult(data), nil
+}
+
+// read executes the read-only query for the resource
+func (m *MethodOverride) read(ctx *HandlerContext, query string, showErrors bool) (*Result, error) {
+	results, err := ctx.Sinks.SQLite.Query(query)
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"query": query,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+		return NewErrorResult("Database error"), nil
+	}
+
+	if len(results) == 0 {
+		return &Result{
+			Data: map[string]interface{}{
+				"message": "Resource not found",
+			},
+			StatusCode: http.StatusNotFound,
+		}, nil
+	}
+
+	return NewResult(map[string]interface{}{
+		"resource": results[0],
+		"count":    len(results),
+	}), nil
+}
+
+// findMethodOverride returns the overridden method and the channel it came
+// from. The form field is read from a form body whatever the method, GET too.
+func findMethodOverride(r *http.Request, body []byte, channel, methodParam string, headers []string) (string, string) {
+	if r == nil {
+		return "", ""
+	}
+
+	if channel == "all" || channel == "header" {
+		for _, header := range headers {
+			if value := r.Header.Get(header); value != "" {
+				return strings.ToUpper(strings.TrimSpace(value)), "header:" + header
+			}
+		}
+	}
+
+	if channel == "all" || channel == "query_param" {
+		if value := r.URL.Query().Get(methodParam); value != "" {
+			return strings.ToUpper(strings.TrimSpace(value)), "query_param:" + methodParam
+		}
+	}
+
+	if channel == "all" || channel == "form_field" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/x-www-form-urlencoded" {
+			form, _ := url.ParseQuery(string(body))
+			if value := form.Get(methodParam); value != "" {
+				return strings.ToUpper(strings.TrimSpace(value)), "form_field:" + methodParam
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// getStringMap safely gets a string-to-string map from config
+func getStringMap(cfg map[string]interface{}, key string) map[string]string {
+	result := make(map[string]string)
+	if cfg == nil {
+		return result
+	}
+	val, ok := cfg[key]
+	if !ok {
+		return result
+	}
+	switch v := val.(type) {
+	case map[string]string:
+		for k, item := range v {
+			result[k] = item
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			if str, ok := item.(string); ok {
+				result[k] = str
+			}
+		}
+	}
+	return result
+}
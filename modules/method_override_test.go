@@ -0,0 +1,217 @@
+package modules
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMethodOverride_Info tests module metadata
+func TestMethodOverride_Info(t *testing.T) {
+	m := &MethodOverride{}
+	info := m.Info()
+
+	if info.Name != "method_override" {
+		t.Errorf("Expected Name 'method_override', got '%s'", info.Name)
+	}
+
+	if info.RequiresSink != "sqlite" {
+		t.Errorf("Expected RequiresSink 'sqlite', got '%s'", info.RequiresSink)
+	}
+
+	if _, ok := info.ValidVariants["channel"]; !ok {
+		t.Error("Expected 'channel' in ValidVariants")
+	}
+}
+
+// TestMethodOverride_Handle_PlainRead tests that requests without an override only read
+func TestMethodOverride_Handle_PlainRead(t *testing.T) {
+	m := &MethodOverride{}
+
+	execCalled := false
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{
+				{"id": "2", "username": "rick", "role": "user"},
+			}, nil
+		},
+		ExecFunc: func(statement string) error {
+			execCalled = true
+			return nil
+		},
+	}
+
+	ctx := &HandlerContext{
+		Input: "2",
+		Config: map[string]interface{}{
+			"query_template": "SELECT * FROM users WHERE id = {input}",
+			"actions": map[string]interface{}{
+				"DELETE": "DELETE FROM users WHERE id = {input}",
+			},
+		},
+		Sinks:   &SinkContext{SQLite: mockSink},
+		Request: httptest.NewRequest("GET", "/users?id=2", nil),
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if execCalled {
+		t.Error("Expected no statement to be executed without an override")
+	}
+
+	data := result.Data.(map[string]interface{})
+	if _, ok := data["resource"]; !ok {
+		t.Error("Expected 'resource' in result data")
+	}
+}
+
+// TestMethodOverride_Handle_HeaderOverride tests privileged action via override header
+func TestMethodOverride_Handle_HeaderOverride(t *testing.T) {
+	m := &MethodOverride{}
+
+	var executed string
+	mockSink := &MockSQLiteSinkIDOR{
+		ExecFunc: func(statement string) error {
+			executed = statement
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/users?id=2", nil)
+	req.Header.Set("X-HTTP-Method-Override", "put")
+
+	ctx := &HandlerContext{
+		Input: "2",
+		Config: map[string]interface{}{
+			"query_template": "SELECT * FROM users WHERE id = {input}",
+			"actions": map[string]interface{}{
+				"put": "UPDATE users SET role = 'admin' WHERE id = {input}",
+			},
+		},
+		Sinks:   &SinkContext{SQLite: mockSink},
+		Request: req,
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if executed != "UPDATE users SET role = 'admin' WHERE id = 2" {
+		t.Errorf("Unexpected statement executed: %q", executed)
+	}
+
+	data := result.Data.(map[string]interface{})
+	if data["override_method"] != "PUT" {
+		t.Errorf("Expected override_method 'PUT', got '%v'", data["override_method"])
+	}
+	if !strings.HasPrefix(data["override_source"].(string), "header:") {
+		t.Errorf("Expected header override source, got '%v'", data["override_source"])
+	}
+}
+
+// TestMethodOverride_Handle_ChannelRestriction tests that only configured channels are honored
+func TestMethodOverride_Handle_ChannelRestriction(t *testing.T) {
+	m := &MethodOverride{}
+
+	execCalled := false
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{{"id": "2"}}, nil
+		},
+		ExecFunc: func(statement string) error {
+			execCalled = true
+			return nil
+		},
+	}
+
+	config := map[string]interface{}{
+		"query_template": "SELECT * FROM users WHERE id = {input}",
+		"channel":        "header",
+		"actions": map[string]interface{}{
+			"DELETE": "DELETE FROM users WHERE id = {input}",
+		},
+	}
+
+	// _method in the query string is ignored when only headers are honored
+	ctx := &HandlerContext{
+		Input:   "2",
+		Config:  config,
+		Sinks:   &SinkContext{SQLite: mockSink},
+		Request: httptest.NewRequest("GET", "/users?id=2&_method=DELETE", nil),
+	}
+
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if execCalled {
+		t.Error("Expected query_param override to be ignored for channel 'header'")
+	}
+
+	// The same override through the query string works with channel query_param
+	config["channel"] = "query_param"
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !execCalled {
+		t.Error("Expected query_param override to be honored")
+	}
+
+	// A form body is read for the form_field channel even on a GET
+	execCalled = false
+	config["channel"] = "form_field"
+	body := "_method=DELETE"
+	req := httptest.NewRequest("GET", "/users?id=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx.Request, ctx.Body = req, []byte(body)
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !execCalled {
+		t.Error("Expected form_field override in a GET body to be honored")
+	}
+}
+
+// TestMethodOverride_Handle_UnsupportedMethod tests overrides without a configured action
+func TestMethodOverride_Handle_UnsupportedMethod(t *testing.T) {
+	m := &MethodOverride{}
+
+	req := httptest.NewRequest("GET", "/users?id=2", nil)
+	req.Header.Set("X-HTTP-Method", "PATCH")
+
+	ctx := &HandlerContext{
+		Input: "2",
+		Config: map[string]interface{}{
+			"query_template": "SELECT * FROM users WHERE id = {input}",
+		},
+		Sinks:   &SinkContext{SQLite: &MockSQLiteSinkIDOR{}},
+		Request: req,
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.StatusCode != 405 {
+		t.Errorf("Expected status 405, got %d", result.StatusCode)
+	}
+}
+
+// TestMethodOverride_Handle_NoSink tests error when SQLite sink is missing
+func TestMethodOverride_Handle_NoSink(t *testing.T) {
+	m := &MethodOverride{}
+
+	ctx := &HandlerContext{
+		Input:  "1",
+		Config: map[string]interface{}{"query_template": "SELECT 1"},
+		Sinks:  &SinkContext{},
+	}
+
+	if _, err := m.Handle(ctx); err == nil {
+		t.Error("Expected error when SQLite sink is not available")
+	}
+}
@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"crypto/rand"
+	"io"
 	"net/http"
 )
 
@@ -30,8 +32,35 @@ type ModuleInfo struct {
 	// ValidVariants maps config keys to their valid values (e.g., "variant" -> ["error_based", "blind_boolean"])
 	// Used for validation warnings when invalid values are provided
 	ValidVariants map[string][]string
+
+	// ConfigKeys lists the other config keys the module reads (e.g., "query_template").
+	// Strict validation rejects keys found in neither ConfigKeys nor ValidVariants.
+	ConfigKeys []string
+
+	// Difficulty maps each difficulty level the module supports (see DifficultyLevels)
+	// to the config values it stands for. Values set in the vulnerability's own
+	// config take precedence.
+	Difficulty map[string]map[string]interface{}
+
+	// Options documents each key in ValidVariants and ConfigKeys, for
+	// flawfactory modules describe
+	Options map[string]Option
+
+	// Example is an endpoint using the module, as YAML for a config's endpoints
+	// list, and Payloads are inputs that exploit it
+	Example  string
+	Payloads []string
+}
+
+// Option documents a module config key
+type Option struct {
+	Description string
+	Default     string // What an unset key means, or "" when it must be set or is simply off
 }
 
+// DifficultyLevels are the values a vulnerability's difficulty can take, easiest first
+var DifficultyLevels = []string{"easy", "medium", "hard"}
+
 // HandlerContext provides all the context needed by a module to handle a request
 type HandlerContext struct {
 	// Request is the original HTTP request
@@ -54,6 +83,59 @@ type HandlerContext struct {
 
 	// Sinks provides access to the available sinks
 	Sinks *SinkContext
+
+	// Body is the request body as the router buffered it, whatever the method
+	Body []byte
+
+	// File is the uploaded file for the file placement: its name, content type
+	// and content, which is also the Input. Nil for other placements.
+	File *UploadedFile
+
+	// Random is the source for tokens and IDs. It is seeded from app.seed so
+	// runs are reproducible; nil uses crypto/rand.
+	Random io.Reader
+
+	// Session is the client's server-side session, shared by every vulnerability
+	// of the request. Nil unless app.sessions or session auth is configured.
+	Session Session
+
+	// State is where modules keep what they need between requests, for this app
+	State *State
+}
+
+// Session is a client's server-side key/value bag, found by the ID in its
+// cookie. It is started, setting the cookie, on the first Set or Login.
+type Session interface {
+	// ID returns the session ID, "" before the session is started
+	ID() string
+
+	// Get returns a value, "" if it isn't set
+	Get(key string) string
+
+	// Set stores a value
+	Set(key, value string) error
+
+	// Delete removes a value
+	Delete(key string)
+
+	// Values returns a copy of every value
+	Values() map[string]string
+
+	// User returns the logged-in user, "" if nobody is
+	User() string
+
+	// Login records the user, issuing a new session ID unless the lab keeps it
+	Login(username string) error
+
+	// Logout ends the session and clears its cookie
+	Logout()
+}
+
+// UploadedFile is a file sent in multipart form data
+type UploadedFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
 }
 
 // SinkContext holds references to available sinks
@@ -69,6 +151,27 @@ type SinkContext struct {
 
 	// HTTP provides outbound HTTP requests
 	HTTP HTTPSink
+
+	// Redis provides an embedded key-value store
+	Redis RedisSink
+
+	// Documents provides a MongoDB-style document store
+	Documents DocumentSink
+
+	// LDAP provides directory searches
+	LDAP LDAPSink
+
+	// Mail captures outbound email
+	Mail MailSink
+
+	// OOB records out-of-band callbacks
+	OOB OOBSink
+
+	// Template renders server-side templates
+	Template TemplateSink
+
+	// Extra holds sinks registered by plugins, by name
+	Extra map[string]interface{}
 }
 
 // SQLiteSink interface for database operations
@@ -78,6 +181,10 @@ type SQLiteSink interface {
 
 	// Exec executes a SQL statement
 	Exec(statement string) error
+
+	// ExecArgs executes a statement binding args to its ? placeholders, for
+	// values the lab writes itself, and returns the ID of the row it inserted
+	ExecArgs(statement string, args ...interface{}) (int64, error)
 }
 
 // FilesystemSink interface for file operations
@@ -107,6 +214,74 @@ type HTTPSink interface {
 	FetchWithOptions(url string, opts HTTPOptions) (*HTTPResponse, error)
 }
 
+// RedisSink interface for key-value store commands
+type RedisSink interface {
+	// Execute runs a single inline command (e.g. "GET user:1") and returns its reply
+	Execute(command string) (interface{}, error)
+}
+
+// DocumentSink interface for MongoDB-style document queries
+type DocumentSink interface {
+	// Find returns the documents in a collection matching a filter
+	Find(collection string, filter map[string]interface{}) ([]map[string]interface{}, error)
+
+	// Insert stores a document and returns its _id
+	Insert(collection string, doc map[string]interface{}) (interface{}, error)
+
+	// Update modifies the first (or every, with multi) matching document
+	Update(collection string, filter, update map[string]interface{}, multi bool) (int, error)
+
+	// Delete removes the first (or every, with multi) matching document
+	Delete(collection string, filter map[string]interface{}, multi bool) (int, error)
+}
+
+// LDAPSink interface for directory searches
+type LDAPSink interface {
+	// Search returns entries under baseDN (scope base, one or sub) matching an LDAP filter
+	Search(baseDN, scope, filter string) ([]LDAPEntry, error)
+
+	// BaseDN returns the directory suffix
+	BaseDN() string
+}
+
+// MailSink interface for sending email
+type MailSink interface {
+	// Send delivers a raw message (headers, blank line, body) and returns its mailbox ID.
+	// Recipients come from the envelope and the To, Cc and Bcc headers.
+	Send(from string, to []string, message string) (int, error)
+}
+
+// OOBSink interface for the out-of-band interaction listener
+type OOBSink interface {
+	// Hosts returns the callback domain and any address the listener is reachable on
+	Hosts() []string
+
+	// Lookup records a DNS lookup, returning false if the name is not under the callback domain
+	Lookup(name string) bool
+}
+
+// TemplateSink interface for server-side template rendering
+type TemplateSink interface {
+	// Render renders source with an engine (jinja2, twig, freemarker, erb or go).
+	// Shell commands and file reads reached from the template are passed to unsafe.
+	Render(engine, source string, data map[string]interface{}, unsafe func(UnsafeExpression) string) (string, error)
+}
+
+// UnsafeExpression is a shell command or file read reached from a template
+type UnsafeExpression struct {
+	Engine     string
+	Expression string
+	Kind       string // rce or file_read
+	Command    string
+	Path       string
+}
+
+// LDAPEntry is a directory entry returned by an LDAP search
+type LDAPEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
 // HTTPResponse represents the response from an HTTP request
 type HTTPResponse struct {
 	StatusCode int
@@ -151,6 +326,17 @@ func NewErrorResult(err string) *Result {
 	return &Result{Error: err}
 }
 
+// RandomBytes returns n random bytes from the context's source
+func (ctx *HandlerContext) RandomBytes(n int) []byte {
+	b := make([]byte, n)
+	if ctx.Random != nil {
+		io.ReadFull(ctx.Random, b)
+	} else {
+		rand.Read(b)
+	}
+	return b
+}
+
 // GetConfigString safely gets a string from the config map
 func (ctx *HandlerContext) GetConfigString(key string, defaultValue string) string {
 	if ctx.Config == nil {
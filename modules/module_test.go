@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -166,6 +167,20 @@ func TestHandlerContext_GetConfigInt(t *testing.T) {
 	}
 }
 
+// TestHandlerContext_RandomBytes tests bytes come from the context's source when one is set
+func TestHandlerContext_RandomBytes(t *testing.T) {
+	ctx := &HandlerContext{Random: bytes.NewReader([]byte{1, 2, 3, 4})}
+	if got := ctx.RandomBytes(3); !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("Expected bytes from the source, got %v", got)
+	}
+
+	// Without a source, crypto/rand is used
+	ctx = &HandlerContext{}
+	if a, b := ctx.RandomBytes(16), ctx.RandomBytes(16); len(a) != 16 || bytes.Equal(a, b) {
+		t.Errorf("Expected 16 fresh random bytes, got %v and %v", a, b)
+	}
+}
+
 // TestSinkContext tests SinkContext struct
 func TestSinkContext(t *testing.T) {
 	sinkCtx := &SinkContext{
@@ -29,12 +29,44 @@ func (m *NoSQLInjection) Info() ModuleInfo {
 			"json_field",
 			"header",
 			"cookie",
+			"graphql_variable",
+			"graphql_query",
 		},
-		RequiresSink: "", // No external sink required - emulates NoSQL behavior
+		RequiresSink: "", // No sink required - collections are read from seeded data when available
 		ValidVariants: map[string][]string{
 			"database":  {"mongodb", "mongo", "redis"},
 			"operation": {"find", "findOne", "aggregate", "update", "updateOne", "updateMany", "delete", "deleteOne", "deleteMany", "insert", "insertOne", "get", "set", "hget", "hgetall", "lpush", "rpush", "lrange", "smembers", "zadd", "zrange", "exists", "del", "incr", "decr", "ttl", "ping", "info"},
 		},
+		ConfigKeys: []string{
+			"collection", "query_template", "show_errors", "update_template", "use_real_sink",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"show_errors": true},
+			"medium": {"show_errors": false},
+		},
+		Options: map[string]Option{
+			"database":        {Description: "Database emulated: mongodb (or mongo) or redis", Default: "mongodb"},
+			"operation":       {Description: "MongoDB method (find, findOne, aggregate, update*, delete*, insert*) or Redis command (get, set, hget, hgetall, lpush, rpush, lrange, smembers, zadd, zrange, exists, del, incr, decr, ttl, ping, info) the input reaches", Default: "find"},
+			"collection":      {Description: "MongoDB collection queried, read from the data table of the same name when there is one", Default: "users"},
+			"query_template":  {Description: "Query or command with {input} replaced by the input; when unset the input is the whole query"},
+			"update_template": {Description: "For MongoDB updates on the document sink, the update document applied", Default: `{"$set": {"updated": true}}`},
+			"use_real_sink":   {Description: "Run against the lab's document store or Redis sink instead of the emulation", Default: "false"},
+			"show_errors":     {Description: "Return query errors", Default: "true"},
+		},
+		Example: `- path: /api/users/lookup
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: nosql_injection
+      placement: json_field
+      param: username
+      config:
+        database: mongodb
+        collection: users
+        operation: findOne
+        query_template: '{"username": {input}}'
+`,
+		Payloads: []string{`{"$ne": ""}`, `{"$gt": ""}`, `{"$regex": "^a"}`, `"admin", "$where": "1==1"`},
 	}
 }
 
@@ -67,12 +99,19 @@ func (m *NoSQLInjection) Handle(ctx *HandlerContext) (*Result, error) {
 	// Process based on database type
 	var result *NoSQLResult
 	switch strings.ToLower(database) {
-	case "mongodb", "mongo":
-		result = processMongoDBQuery(input, collection, operation, queryTemplate, showErrors)
 	case "redis":
-		result = processRedisCommand(input, operation, queryTemplate, showErrors)
+		if ctx.GetConfigBool("use_real_sink", false) && ctx.Sinks != nil && ctx.Sinks.Redis != nil {
+			result = executeRedisCommand(ctx.Sinks.Redis, input, operation, queryTemplate, showErrors)
+		} else {
+			result = processRedisCommand(input, operation, queryTemplate, showErrors)
+		}
 	default:
-		result = processMongoDBQuery(input, collection, operation, queryTemplate, showErrors)
+		if ctx.GetConfigBool("use_real_sink", false) && ctx.Sinks != nil && ctx.Sinks.Documents != nil {
+			updateTemplate := ctx.GetConfigString("update_template", `{"$set": {"updated": true}}`)
+			result = executeMongoQuery(ctx.Sinks.Documents, input, collection, operation, queryTemplate, updateTemplate, showErrors)
+		} else {
+			result = processMongoDBQuery(input, loadMongoCollection(ctx, collection), operation, queryTemplate, showErrors)
+		}
 	}
 
 	return NewResult(result), nil
@@ -82,59 +121,149 @@ func (m *NoSQLInjection) Handle(ctx *HandlerContext) (*Result, error) {
 // MongoDB Emulation
 // =============================================================================
 
-// processMongoDBQuery emulates MongoDB query processing
-func processMongoDBQuery(input, collection, operation, queryTemplate string, showErrors bool) *NoSQLResult {
+// loadMongoCollection returns the documents of a collection. Tables seeded from the
+// config data section are used when present, so results match the rest of the lab;
+// otherwise built-in sample documents are returned.
+func loadMongoCollection(ctx *HandlerContext, collection string) []map[string]interface{} {
+	if ctx.Sinks != nil && ctx.Sinks.SQLite != nil {
+		table := `"` + strings.ReplaceAll(collection, `"`, `""`) + `"`
+		if rows, err := ctx.Sinks.SQLite.Query("SELECT * FROM " + table); err == nil {
+			return rows
+		}
+	}
+	return getMongoSampleData(collection)
+}
+
+// processMongoDBQuery emulates MongoDB query processing against a collection's documents
+func processMongoDBQuery(input string, documents []map[string]interface{}, operation, queryTemplate string, showErrors bool) *NoSQLResult {
 	result := &NoSQLResult{
 		Database:  "mongodb",
 		Operation: operation,
 		RawInput:  input,
 	}
 
-	// Build the query
-	var query interface{}
-	var queryStr string
+	query, queryStr := buildMongoQuery(input, queryTemplate)
+	result.Query = query
 
+	// Detect injection patterns
+	injectionType, exploitable := detectMongoDBInjection(input, queryStr)
+	result.InjectionType = injectionType
+	result.Exploitable = exploitable
+
+	if exploitable {
+		result.Warning = fmt.Sprintf("MongoDB %s injection detected", injectionType)
+	}
+
+	// Emulate query results based on operation and injection
+	switch operation {
+	case "find", "findOne":
+		result.Results, result.Count = emulateMongoFind(documents, query, injectionType, exploitable)
+	case "aggregate":
+		result.Results, result.Count = emulateMongoAggregate(documents, query, exploitable)
+	case "update", "updateOne", "updateMany":
+		result.Results, result.Count = emulateMongoUpdate(documents, query, exploitable)
+	case "delete", "deleteOne", "deleteMany":
+		result.Results, result.Count = emulateMongoDelete(documents, query, exploitable)
+	case "insert", "insertOne":
+		result.Results, result.Count = emulateMongoInsert(documents, query)
+	default:
+		result.Results, result.Count = emulateMongoFind(documents, query, injectionType, exploitable)
+	}
+
+	return result
+}
+
+// buildMongoQuery substitutes the input into the template and parses it as JSON.
+// Input that is not valid JSON is treated as a username value.
+func buildMongoQuery(input, queryTemplate string) (interface{}, string) {
+	queryStr := input
 	if queryTemplate != "" {
 		queryStr = strings.ReplaceAll(queryTemplate, "{input}", input)
-	} else {
-		queryStr = input
 	}
 
-	// Try to parse as JSON
-	err := json.Unmarshal([]byte(queryStr), &query)
-	if err != nil {
-		// If not valid JSON, treat as field value injection
+	var query interface{}
+	if err := json.Unmarshal([]byte(queryStr), &query); err != nil {
 		query = map[string]interface{}{
 			"username": input,
 		}
 		queryStr = fmt.Sprintf(`{"username": "%s"}`, input)
 	}
 
+	return query, queryStr
+}
+
+// executeMongoQuery runs the query against the document store, so operators and
+// $where expressions are evaluated for real over the seeded collection
+func executeMongoQuery(store DocumentSink, input, collection, operation, queryTemplate, updateTemplate string, showErrors bool) *NoSQLResult {
+	result := &NoSQLResult{
+		Database:  "mongodb",
+		Operation: operation,
+		RawInput:  input,
+	}
+
+	query, queryStr := buildMongoQuery(input, queryTemplate)
 	result.Query = query
 
-	// Detect injection patterns
 	injectionType, exploitable := detectMongoDBInjection(input, queryStr)
 	result.InjectionType = injectionType
 	result.Exploitable = exploitable
-
 	if exploitable {
 		result.Warning = fmt.Sprintf("MongoDB %s injection detected", injectionType)
 	}
 
-	// Emulate query results based on operation and injection
+	filter, ok := query.(map[string]interface{})
+	if !ok {
+		result.Error = "query filter must be an object"
+		return result
+	}
+
+	var err error
 	switch operation {
-	case "find", "findOne":
-		result.Results, result.Count = emulateMongoFind(collection, query, injectionType, exploitable)
-	case "aggregate":
-		result.Results, result.Count = emulateMongoAggregate(collection, query, exploitable)
 	case "update", "updateOne", "updateMany":
-		result.Results, result.Count = emulateMongoUpdate(collection, query, exploitable)
+		var update map[string]interface{}
+		if err = json.Unmarshal([]byte(strings.ReplaceAll(updateTemplate, "{input}", input)), &update); err != nil {
+			break
+		}
+		var modified int
+		modified, err = store.Update(collection, filter, update, operation == "updateMany")
+		result.Results = []map[string]interface{}{
+			{"acknowledged": err == nil, "matchedCount": modified, "modifiedCount": modified},
+		}
+		result.Count = modified
 	case "delete", "deleteOne", "deleteMany":
-		result.Results, result.Count = emulateMongoDelete(collection, query, exploitable)
+		// Like the legacy remove(), "delete" removes every match
+		var deleted int
+		deleted, err = store.Delete(collection, filter, operation != "deleteOne")
+		result.Results = []map[string]interface{}{
+			{"acknowledged": err == nil, "deletedCount": deleted},
+		}
+		result.Count = deleted
 	case "insert", "insertOne":
-		result.Results, result.Count = emulateMongoInsert(collection, query)
+		var id interface{}
+		id, err = store.Insert(collection, filter)
+		result.Results = []map[string]interface{}{
+			{"acknowledged": err == nil, "insertedId": id},
+		}
+		result.Count = 1
 	default:
-		result.Results, result.Count = emulateMongoFind(collection, query, injectionType, exploitable)
+		// find, findOne and aggregate (as a $match stage)
+		var docs []map[string]interface{}
+		docs, err = store.Find(collection, filter)
+		if operation == "findOne" && len(docs) > 1 {
+			docs = docs[:1]
+		}
+		result.Results = docs
+		result.Count = len(docs)
+	}
+
+	if err != nil {
+		result.Results = nil
+		result.Count = 0
+		if showErrors {
+			result.Error = err.Error()
+		} else {
+			result.Error = "query failed"
+		}
 	}
 
 	return result
@@ -211,41 +340,66 @@ func detectMongoDBInjection(input, queryStr string) (string, bool) {
 }
 
 // emulateMongoFind emulates MongoDB find operation
-func emulateMongoFind(collection string, query interface{}, injType string, exploitable bool) ([]map[string]interface{}, int) {
-	// Sample data based on collection
-	sampleData := getMongoSampleData(collection)
-
+func emulateMongoFind(documents []map[string]interface{}, query interface{}, injType string, exploitable bool) ([]map[string]interface{}, int) {
 	if exploitable {
 		// If injection detected, return all data (auth bypass simulation)
 		switch injType {
 		case "operator_ne", "auth_bypass", "operator_gt", "operator_exists":
 			// $ne:null or $gt:"" bypasses return all records
-			return sampleData, len(sampleData)
+			return documents, len(documents)
 		case "javascript_injection":
 			// JavaScript injection could expose all data
-			return sampleData, len(sampleData)
+			return documents, len(documents)
 		case "operator_regex":
 			// Regex could match multiple records
-			return sampleData[:min(2, len(sampleData))], min(2, len(sampleData))
+			return documents[:min(2, len(documents))], min(2, len(documents))
 		}
 	}
 
-	// Normal query - return first matching record
-	if len(sampleData) > 0 {
-		return sampleData[:1], 1
+	// Normal query - return the first record matching the query's fields
+	for _, doc := range documents {
+		if matchMongoDocument(doc, query) {
+			return []map[string]interface{}{doc}, 1
+		}
 	}
 	return nil, 0
 }
 
+// matchMongoDocument reports whether a document has every plain field value in
+// the query. Operator expressions and fields the document lacks are not compared.
+func matchMongoDocument(doc map[string]interface{}, query interface{}) bool {
+	filter, ok := query.(map[string]interface{})
+	if !ok {
+		return true
+	}
+
+	for field, want := range filter {
+		if strings.HasPrefix(field, "$") {
+			continue
+		}
+		if _, isExpr := want.(map[string]interface{}); isExpr {
+			continue
+		}
+		have, exists := doc[field]
+		if !exists {
+			continue
+		}
+		if fmt.Sprint(have) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
 // emulateMongoAggregate emulates MongoDB aggregate operation
-func emulateMongoAggregate(collection string, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
+func emulateMongoAggregate(documents []map[string]interface{}, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
 	if exploitable {
 		// Aggregation with injection might expose statistics or all data
 		return []map[string]interface{}{
 			{
 				"_id":   nil,
-				"count": 150,
-				"data":  getMongoSampleData(collection),
+				"count": len(documents),
+				"data":  documents,
 			},
 		}, 1
 	}
@@ -259,17 +413,17 @@ func emulateMongoAggregate(collection string, query interface{}, exploitable boo
 }
 
 // emulateMongoUpdate emulates MongoDB update operation
-func emulateMongoUpdate(collection string, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
+func emulateMongoUpdate(documents []map[string]interface{}, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
 	if exploitable {
-		// Injection in update could modify multiple records
+		// Injection in update could modify every record in the collection
 		return []map[string]interface{}{
 			{
 				"acknowledged":  true,
-				"matchedCount":  100,
-				"modifiedCount": 100,
+				"matchedCount":  len(documents),
+				"modifiedCount": len(documents),
 				"warning":       "Mass update detected - injection may have affected all records",
 			},
-		}, 100
+		}, len(documents)
 	}
 
 	return []map[string]interface{}{
@@ -282,16 +436,16 @@ func emulateMongoUpdate(collection string, query interface{}, exploitable bool)
 }
 
 // emulateMongoDelete emulates MongoDB delete operation
-func emulateMongoDelete(collection string, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
+func emulateMongoDelete(documents []map[string]interface{}, query interface{}, exploitable bool) ([]map[string]interface{}, int) {
 	if exploitable {
-		// Injection in delete could remove all records
+		// Injection in delete could remove every record in the collection
 		return []map[string]interface{}{
 			{
 				"acknowledged": true,
-				"deletedCount": 100,
+				"deletedCount": len(documents),
 				"warning":      "Mass deletion detected - injection may have deleted all records",
 			},
-		}, 100
+		}, len(documents)
 	}
 
 	return []map[string]interface{}{
@@ -303,7 +457,7 @@ func emulateMongoDelete(collection string, query interface{}, exploitable bool)
 }
 
 // emulateMongoInsert emulates MongoDB insert operation
-func emulateMongoInsert(collection string, query interface{}) ([]map[string]interface{}, int) {
+func emulateMongoInsert(documents []map[string]interface{}, query interface{}) ([]map[string]interface{}, int) {
 	return []map[string]interface{}{
 		{
 			"acknowledged": true,
@@ -312,7 +466,7 @@ func emulateMongoInsert(collection string, query interface{}) ([]map[string]inte
 	}, 1
 }
 
-// getMongoSampleData returns sample data for a collection
+// getMongoSampleData returns built-in sample data for a collection that was not seeded
 func getMongoSampleData(collection string) []map[string]interface{} {
 	switch collection {
 	case "users":
@@ -385,6 +539,53 @@ func processRedisCommand(input, operation, commandTemplate string, showErrors bo
 	return result
 }
 
+// executeRedisCommand runs the command against the Redis sink. Like a real server
+// reading the inline protocol, every line is a separate command, so injected
+// CRLFs chain commands.
+func executeRedisCommand(sink RedisSink, input, operation, commandTemplate string, showErrors bool) *NoSQLResult {
+	result := &NoSQLResult{
+		Database:  "redis",
+		Operation: operation,
+		RawInput:  input,
+	}
+
+	command := input
+	if commandTemplate != "" {
+		command = strings.ReplaceAll(commandTemplate, "{input}", input)
+	}
+	result.ExecutedCmd = command
+
+	injectionType, exploitable := detectRedisInjection(input, command)
+	result.InjectionType = injectionType
+	result.Exploitable = exploitable
+	if exploitable {
+		result.Warning = fmt.Sprintf("Redis %s detected", injectionType)
+	}
+
+	for _, line := range strings.Split(command, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		reply, err := sink.Execute(line)
+		entry := map[string]interface{}{"command": line}
+		if err != nil {
+			if showErrors {
+				entry["error"] = err.Error()
+			} else {
+				entry["error"] = "command failed"
+			}
+		} else {
+			entry["reply"] = reply
+		}
+		result.Results = append(result.Results, entry)
+	}
+	result.Count = len(result.Results)
+
+	return result
+}
+
 // detectRedisInjection detects Redis injection patterns
 func detectRedisInjection(input, command string) (string, bool) {
 	combined := strings.ToUpper(input + " " + command)
@@ -2,6 +2,8 @@ package modules
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +21,7 @@ func TestNoSQLInjectionModuleInfo(t *testing.T) {
 	}
 
 	// Check supported placements
-	expectedPlacements := []string{"query_param", "path_param", "form_field", "json_field", "header", "cookie"}
+	expectedPlacements := []string{"query_param", "path_param", "form_field", "json_field", "header", "cookie", "graphql_variable", "graphql_query"}
 	if len(info.SupportedPlacements) != len(expectedPlacements) {
 		t.Errorf("Expected %d placements, got %d", len(expectedPlacements), len(info.SupportedPlacements))
 	}
@@ -273,7 +275,7 @@ func TestDetectRedisInjection(t *testing.T) {
 func TestProcessMongoDBQuery_Find(t *testing.T) {
 	result := processMongoDBQuery(
 		`{"username": {"$ne": ""}}`,
-		"users",
+		getMongoSampleData("users"),
 		"find",
 		"",
 		true,
@@ -299,7 +301,7 @@ func TestProcessMongoDBQuery_Find(t *testing.T) {
 func TestProcessMongoDBQuery_WithTemplate(t *testing.T) {
 	result := processMongoDBQuery(
 		"admin",
-		"users",
+		getMongoSampleData("users"),
 		"findOne",
 		`{"username": "{input}"}`,
 		true,
@@ -322,7 +324,7 @@ func TestProcessMongoDBQuery_AllOperations(t *testing.T) {
 		t.Run(op, func(t *testing.T) {
 			result := processMongoDBQuery(
 				`{"$ne": null}`,
-				"users",
+				getMongoSampleData("users"),
 				op,
 				"",
 				true,
@@ -484,6 +486,227 @@ func TestNoSQLInjectionHandle_QueryTemplate(t *testing.T) {
 	}
 }
 
+func TestNoSQLInjectionHandle_SeededCollection(t *testing.T) {
+	m := &NoSQLInjection{}
+
+	var lastQuery string
+	mockSink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			lastQuery = query
+			if query != `SELECT * FROM "users"` {
+				return nil, fmt.Errorf("no such table")
+			}
+			return []map[string]interface{}{
+				{"id": int64(1), "username": "rick", "password": "wubbalubba"},
+				{"id": int64(2), "username": "morty", "password": "jessica"},
+				{"id": int64(3), "username": "summer", "password": "phone"},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		collection string
+		wantCount  int
+		wantUser   string
+	}{
+		{"operator injection returns every seeded document", `{"username": {"$ne": ""}}`, "users", 3, "rick"},
+		{"plain query matches the seeded document", `{"username": "morty"}`, "users", 1, "morty"},
+		{"plain query without a match", `{"username": "jerry"}`, "users", 0, ""},
+		{"unseeded collection falls back to samples", `{"username": "admin"}`, "accounts", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &HandlerContext{
+				Input: tt.input,
+				Config: map[string]interface{}{
+					"collection": tt.collection,
+					"operation":  "find",
+				},
+				Sinks: &SinkContext{SQLite: mockSink},
+			}
+
+			result, err := m.Handle(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			nosqlResult := result.Data.(*NoSQLResult)
+			if nosqlResult.Count != tt.wantCount {
+				t.Errorf("Expected %d results, got %d (query %s)", tt.wantCount, nosqlResult.Count, lastQuery)
+			}
+			if tt.wantUser != "" && nosqlResult.Results[0]["username"] != tt.wantUser {
+				t.Errorf("Expected first result %q, got %v", tt.wantUser, nosqlResult.Results[0])
+			}
+		})
+	}
+}
+
+// MockRedisSink records commands and replies from a fixed key set
+type MockRedisSink struct {
+	Values   map[string]string
+	Executed []string
+}
+
+func (m *MockRedisSink) Execute(command string) (interface{}, error) {
+	m.Executed = append(m.Executed, command)
+	parts := strings.Fields(command)
+	switch strings.ToUpper(parts[0]) {
+	case "GET":
+		if value, ok := m.Values[parts[1]]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "CONFIG":
+		return "OK", nil
+	}
+	return nil, fmt.Errorf("ERR unknown command '%s'", strings.ToLower(parts[0]))
+}
+
+func TestNoSQLInjectionHandle_RedisRealSink(t *testing.T) {
+	m := &NoSQLInjection{}
+	sink := &MockRedisSink{Values: map[string]string{"user:1": "admin"}}
+
+	ctx := &HandlerContext{
+		Input: "1\r\nCONFIG SET dir /tmp\r\nFLUSHALL",
+		Config: map[string]interface{}{
+			"database":       "redis",
+			"operation":      "get",
+			"query_template": "GET user:{input}",
+			"use_real_sink":  true,
+		},
+		Sinks: &SinkContext{Redis: sink},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	nosqlResult := result.Data.(*NoSQLResult)
+	if nosqlResult.InjectionType != "crlf_injection" {
+		t.Errorf("Expected crlf_injection, got %s", nosqlResult.InjectionType)
+	}
+
+	// Each injected line runs as its own command
+	wantExecuted := []string{"GET user:1", "CONFIG SET dir /tmp", "FLUSHALL"}
+	if strings.Join(sink.Executed, "|") != strings.Join(wantExecuted, "|") {
+		t.Errorf("Expected commands %q, got %q", wantExecuted, sink.Executed)
+	}
+	if nosqlResult.Count != 3 {
+		t.Fatalf("Expected 3 replies, got %d", nosqlResult.Count)
+	}
+	if nosqlResult.Results[0]["reply"] != "admin" {
+		t.Errorf("Expected reply from the sink, got %v", nosqlResult.Results[0])
+	}
+	if _, ok := nosqlResult.Results[2]["error"]; !ok {
+		t.Errorf("Expected error reply for unsupported command, got %v", nosqlResult.Results[2])
+	}
+
+	// Without the flag the canned emulation is used
+	ctx.Config["use_real_sink"] = false
+	sink.Executed = nil
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sink.Executed) != 0 {
+		t.Errorf("Expected no sink commands without use_real_sink, got %q", sink.Executed)
+	}
+}
+
+// MockDocumentSink records the filters it receives and returns fixed documents
+type MockDocumentSink struct {
+	Docs    []map[string]interface{}
+	Err     error
+	Filters []map[string]interface{}
+	Multi   []bool
+}
+
+func (m *MockDocumentSink) Find(collection string, filter map[string]interface{}) ([]map[string]interface{}, error) {
+	m.Filters = append(m.Filters, filter)
+	return m.Docs, m.Err
+}
+
+func (m *MockDocumentSink) Insert(collection string, doc map[string]interface{}) (interface{}, error) {
+	return "new-id", m.Err
+}
+
+func (m *MockDocumentSink) Update(collection string, filter, update map[string]interface{}, multi bool) (int, error) {
+	m.Filters = append(m.Filters, filter)
+	m.Multi = append(m.Multi, multi)
+	return len(m.Docs), m.Err
+}
+
+func (m *MockDocumentSink) Delete(collection string, filter map[string]interface{}, multi bool) (int, error) {
+	m.Filters = append(m.Filters, filter)
+	m.Multi = append(m.Multi, multi)
+	return len(m.Docs), m.Err
+}
+
+func TestNoSQLInjectionHandle_DocumentStore(t *testing.T) {
+	m := &NoSQLInjection{}
+	store := &MockDocumentSink{Docs: []map[string]interface{}{
+		{"username": "admin"}, {"username": "rick"},
+	}}
+
+	config := map[string]interface{}{
+		"collection":     "users",
+		"operation":      "find",
+		"query_template": `{"$where": "this.username == '{input}'"}`,
+		"use_real_sink":  true,
+	}
+	ctx := &HandlerContext{
+		Input:  "x' || '1'=='1",
+		Config: config,
+		Sinks:  &SinkContext{Documents: store},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	nosqlResult := result.Data.(*NoSQLResult)
+	if got := store.Filters[0]["$where"]; got != "this.username == 'x' || '1'=='1'" {
+		t.Errorf("Expected the injected $where to reach the store, got %v", got)
+	}
+	if nosqlResult.Count != 2 || !nosqlResult.Exploitable {
+		t.Errorf("Expected 2 exploitable results from the store, got %d (%s)", nosqlResult.Count, nosqlResult.InjectionType)
+	}
+
+	// findOne keeps the first document only
+	config["operation"] = "findOne"
+	result, _ = m.Handle(ctx)
+	if count := result.Data.(*NoSQLResult).Count; count != 1 {
+		t.Errorf("Expected 1 result for findOne, got %d", count)
+	}
+
+	// updateMany applies to every match, updateOne to the first
+	config["operation"] = "updateMany"
+	m.Handle(ctx)
+	config["operation"] = "updateOne"
+	m.Handle(ctx)
+	if len(store.Multi) != 2 || !store.Multi[0] || store.Multi[1] {
+		t.Errorf("Expected multi=[true false], got %v", store.Multi)
+	}
+
+	// Store errors are surfaced when show_errors is on
+	store.Err = fmt.Errorf("SyntaxError: unterminated string literal")
+	config["operation"] = "find"
+	result, _ = m.Handle(ctx)
+	if got := result.Data.(*NoSQLResult).Error; got != "SyntaxError: unterminated string literal" {
+		t.Errorf("Expected store error, got %q", got)
+	}
+
+	config["show_errors"] = false
+	result, _ = m.Handle(ctx)
+	if got := result.Data.(*NoSQLResult).Error; got != "query failed" {
+		t.Errorf("Expected generic error, got %q", got)
+	}
+}
+
 // =============================================================================
 // NoSQLResult Struct Tests
 // =============================================================================
@@ -534,7 +757,7 @@ func containsHelper(s, substr string) bool {
 // =============================================================================
 
 func TestProcessMongoDBQuery_EmptyInput(t *testing.T) {
-	result := processMongoDBQuery("", "users", "find", "", true)
+	result := processMongoDBQuery("", getMongoSampleData("users"), "find", "", true)
 	if result == nil {
 		t.Error("Result should not be nil for empty input")
 	}
@@ -548,7 +771,7 @@ func TestProcessRedisCommand_EmptyInput(t *testing.T) {
 }
 
 func TestProcessMongoDBQuery_InvalidJSON(t *testing.T) {
-	result := processMongoDBQuery("not valid json {{{", "users", "find", "", true)
+	result := processMongoDBQuery("not valid json {{{", getMongoSampleData("users"), "find", "", true)
 	if result == nil {
 		t.Error("Result should not be nil for invalid JSON")
 	}
@@ -572,7 +795,7 @@ func TestEmulateMongoFind_AllInjectionTypes(t *testing.T) {
 	for _, injType := range injectionTypes {
 		t.Run(injType, func(t *testing.T) {
 			exploitable := injType != "none"
-			results, count := emulateMongoFind("users", nil, injType, exploitable)
+			results, count := emulateMongoFind(getMongoSampleData("users"), nil, injType, exploitable)
 			if exploitable && count == 0 {
 				t.Errorf("Expected results for exploitable injection type '%s'", injType)
 			}
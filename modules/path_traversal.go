@@ -2,7 +2,10 @@ package modules
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -24,12 +27,39 @@ func (m *PathTraversal) Info() ModuleInfo {
 			"path_param",
 			"form_field",
 			"json_field",
+			"multipart_field",
 			"multipart-form",
 		},
 		RequiresSink: "filesystem",
 		ValidVariants: map[string][]string{
-			"filter": {"none", "basic_dots", "basic_slashes", "null_byte", "url_decode"},
+			"filter": {"none", "basic_dots", "strip_once", "basic_slashes", "null_byte", "url_decode", "block_absolute", "normalize_check"},
+			"decode": {"none", "url", "double_url", "overlong_utf8"},
 		},
+		ConfigKeys: []string{"append_extension", "base_path", "null_byte_truncation"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"filter": "none", "decode": "none"},
+			"medium": {"filter": "strip_once", "decode": "none"},
+			"hard":   {"filter": "normalize_check", "decode": "url"},
+		},
+		Options: map[string]Option{
+			"base_path":            {Description: "Directory of the filesystem sink the input is joined to"},
+			"filter":               {Description: "Applied to the input first: none, basic_dots or strip_once (removes ../ once), basic_slashes (normalizes slashes), null_byte (removes null bytes), url_decode (decodes %2e, %2f and %5c), block_absolute (rejects absolute paths) or normalize_check (rejects paths that clean to a traversal, before decode runs)", Default: "none"},
+			"decode":               {Description: "Decoding the application does after the filter: none, url, double_url or overlong_utf8 (accepts non-shortest UTF-8 forms of . and /)", Default: "none"},
+			"append_extension":     {Description: "Appended to the path, e.g. .txt, as if the application only served one kind of file"},
+			"null_byte_truncation": {Description: "End the path at a null byte, as C string handling would, so %00 cuts off append_extension", Default: "false"},
+		},
+		Example: `- path: /read
+  method: GET
+  response_type: text
+  vulnerabilities:
+    - type: path_traversal
+      placement: query_param
+      param: file
+      config:
+        base_path: "public"
+        filter: none
+`,
+		Payloads: []string{"../flag.txt", "../../../../etc/passwd", "....//....//etc/passwd"},
 	}
 }
 
@@ -42,13 +72,37 @@ func (m *PathTraversal) Handle(ctx *HandlerContext) (*Result, error) {
 	// Get configuration
 	basePath := ctx.GetConfigString("base_path", "")
 	filter := ctx.GetConfigString("filter", "none")
+	decode := ctx.GetConfigString("decode", "none")
 	appendExtension := ctx.GetConfigString("append_extension", "")
+	nullByteTruncation := ctx.GetConfigBool("null_byte_truncation", false)
 
 	// Build the file path
 	filePath := ctx.Input
 
+	// Rejecting filters inspect the input before it is decoded
+	if err := checkPathFilter(filePath, filter); err != nil {
+		return &Result{
+			Error: err.Error(),
+			Data: map[string]interface{}{
+				"requested_path": ctx.Input,
+				"filter":         filter,
+				"blocked":        true,
+				"error":          err.Error(),
+			},
+		}, nil
+	}
+
 	// Apply filter
-	filePath = applyPathFilter(filePath, filter)
+	filtered := applyPathFilter(filePath, filter)
+	var techniques []string
+	if filtered != filePath && strings.Contains(filtered, "../") && (filter == "basic_dots" || filter == "strip_once") {
+		techniques = append(techniques, "nested_traversal")
+	}
+	filePath = filtered
+
+	// Decoding done by the application after the filter has run
+	filePath, decodeTechniques := decodePathInput(filePath, decode)
+	techniques = append(techniques, decodeTechniques...)
 
 	// Prepend base path if configured
 	if basePath != "" {
@@ -60,6 +114,14 @@ func (m *PathTraversal) Handle(ctx *HandlerContext) (*Result, error) {
 		filePath = filePath + appendExtension
 	}
 
+	// Emulate C-style string handling, where a null byte ends the path
+	if nullByteTruncation {
+		if idx := strings.IndexByte(filePath, 0); idx != -1 {
+			filePath = filePath[:idx]
+			techniques = append(techniques, "null_byte")
+		}
+	}
+
 	// Attempt to read the file
 	content, err := ctx.Sinks.Filesystem.Read(filePath)
 	if err != nil {
@@ -73,12 +135,26 @@ func (m *PathTraversal) Handle(ctx *HandlerContext) (*Result, error) {
 		}, nil
 	}
 
-	return NewResult(map[string]interface{}{
+	data := map[string]interface{}{
 		"content":        content,
 		"requested_path": ctx.Input,
 		"resolved_path":  filePath,
 		"size":           len(content),
-	}), nil
+	}
+
+	// Report how the read escaped the intended directory
+	if escapesBasePath(filePath, basePath) {
+		if len(techniques) == 0 {
+			if filepath.IsAbs(filePath) {
+				techniques = append(techniques, "absolute_path")
+			} else {
+				techniques = append(techniques, "dot_dot_slash")
+			}
+		}
+		data["bypass_techniques"] = techniques
+	}
+
+	return NewResult(data), nil
 }
 
 // applyPathFilter applies path filtering based on configuration
@@ -87,7 +163,7 @@ func applyPathFilter(path, filter string) string {
 	case "none":
 		// No filtering - fully vulnerable
 		return path
-	case "basic_dots":
+	case "basic_dots", "strip_once":
 		// Basic filter that removes "../" sequences (bypassed with nested sequences like "....//")
 		// "....//etc/passwd" → removes "../" at pos 2-4 → "../etc/passwd" (still has traversal!)
 		return strings.ReplaceAll(path, "../", "")
@@ -113,3 +189,110 @@ func applyPathFilter(path, filter string) string {
 		return path
 	}
 }
+
+// windowsDrivePattern matches absolute Windows paths such as C:\ or C:/
+var windowsDrivePattern = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// checkPathFilter rejects input for filters that block rather than transform
+func checkPathFilter(p, filter string) error {
+	switch filter {
+	case "block_absolute":
+		// Absolute paths are blocked, but relative traversal is not
+		if strings.HasPrefix(p, "/") || strings.HasPrefix(p, "\\") || windowsDrivePattern.MatchString(p) {
+			return fmt.Errorf("absolute paths are not allowed")
+		}
+	case "normalize_check":
+		// The path is normalized and checked, but the application decodes it
+		// afterwards - encoded traversal is only resolved after the check
+		cleaned := path.Clean(strings.ReplaceAll(p, "\\", "/"))
+		if cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.HasPrefix(cleaned, "/") {
+			return fmt.Errorf("path traversal detected: %s", cleaned)
+		}
+	}
+	return nil
+}
+
+// overlongPercentPatterns match percent-encoded overlong UTF-8 forms of '.', '/' and backslash
+var overlongPercentPatterns = []struct {
+	pattern *regexp.Regexp
+	char    string
+}{
+	{regexp.MustCompile(`(?i)%c0%ae|%e0%80%ae`), "."},
+	{regexp.MustCompile(`(?i)%c0%af|%e0%80%af`), "/"},
+	{regexp.MustCompile(`(?i)%c1%9c`), "\\"},
+}
+
+// overlongByteReplacer handles the same forms once the server has already decoded them to raw bytes
+var overlongByteReplacer = strings.NewReplacer(
+	"\xc0\xae", ".", "\xe0\x80\xae", ".",
+	"\xc0\xaf", "/", "\xe0\x80\xaf", "/",
+	"\xc1\x9c", "\\",
+)
+
+// decodeOverlongUTF8 emulates a lenient UTF-8 decoder that accepts non-shortest forms
+func decodeOverlongUTF8(p string) string {
+	p = overlongByteReplacer.Replace(p)
+	for _, o := range overlongPercentPatterns {
+		p = o.pattern.ReplaceAllLiteralString(p, o.char)
+	}
+	return p
+}
+
+// decodePathInput emulates decoding applied by the application after filtering,
+// returning the decoded path and the encoding techniques that changed it
+func decodePathInput(p, decode string) (string, []string) {
+	var techniques []string
+
+	switch decode {
+	case "url":
+		if decoded := urlDecodePath(p); decoded != p {
+			p = decoded
+			techniques = append(techniques, "url_encoding")
+		}
+	case "double_url":
+		if decoded := urlDecodePath(p); decoded != p {
+			p = decoded
+			techniques = append(techniques, "url_encoding")
+		}
+		if decoded := urlDecodePath(p); decoded != p {
+			p = decoded
+			techniques = append(techniques, "double_url_encoding")
+		}
+	case "overlong_utf8":
+		if decoded := decodeOverlongUTF8(p); decoded != p {
+			p = decoded
+			techniques = append(techniques, "overlong_utf8")
+		}
+		if decoded := urlDecodePath(p); decoded != p {
+			p = decoded
+			techniques = append(techniques, "url_encoding")
+		}
+	}
+
+	return p, techniques
+}
+
+// urlDecodePath percent-decodes a path, leaving it unchanged if it is malformed
+func urlDecodePath(p string) string {
+	decoded, err := url.PathUnescape(p)
+	if err != nil {
+		return p
+	}
+	return decoded
+}
+
+// escapesBasePath reports whether a resolved path leaves the configured base directory
+func escapesBasePath(resolved, basePath string) bool {
+	if filepath.IsAbs(resolved) {
+		return true
+	}
+	cleaned := filepath.Clean(resolved)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return true
+	}
+	if basePath == "" {
+		return false
+	}
+	base := filepath.Clean(basePath)
+	return cleaned != base && !strings.HasPrefix(cleaned, base+"/")
+}
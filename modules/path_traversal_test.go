@@ -0,0 +1,160 @@
+package modules
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// MockFilesystemSink resolves paths like the real sink, rooted at /lab
+type MockFilesystemSink struct {
+	Files map[string]string
+}
+
+func (m *MockFilesystemSink) Read(path string) (string, error) {
+	full := filepath.Join("/lab", path)
+	if content, ok := m.Files[full]; ok {
+		return content, nil
+	}
+	return "", fmt.Errorf("file not found: %s", path)
+}
+
+func (m *MockFilesystemSink) Exists(path string) bool {
+	_, ok := m.Files[filepath.Join("/lab", path)]
+	return ok
+}
+
+func (m *MockFilesystemSink) BasePath() string {
+	return "/lab"
+}
+
+func newPathTraversalContext(input string, config map[string]interface{}) *HandlerContext {
+	return &HandlerContext{
+		Input:  input,
+		Config: config,
+		Sinks: &SinkContext{
+			Filesystem: &MockFilesystemSink{Files: map[string]string{
+				"/lab/public/readme.txt": "welcome",
+				"/lab/etc/passwd":        "root:x:0:0:root:/root:/bin/bash",
+				"/etc/passwd":            "root:x:0:0:host",
+			}},
+		},
+	}
+}
+
+func TestPathTraversalBypassTechniques(t *testing.T) {
+	m := &PathTraversal{}
+
+	tests := []struct {
+		name       string
+		input      string
+		config     map[string]interface{}
+		techniques []string
+	}{
+		{
+			name:       "plain traversal",
+			input:      "../etc/passwd",
+			config:     map[string]interface{}{"base_path": "public"},
+			techniques: []string{"dot_dot_slash"},
+		},
+		{
+			name:       "strip once bypassed by nesting",
+			input:      "....//etc/passwd",
+			config:     map[string]interface{}{"base_path": "public", "filter": "strip_once"},
+			techniques: []string{"nested_traversal"},
+		},
+		{
+			name:       "block absolute bypassed by relative traversal",
+			input:      "../../../../etc/passwd",
+			config:     map[string]interface{}{"filter": "block_absolute"},
+			techniques: []string{"dot_dot_slash"},
+		},
+		{
+			name:       "normalize check bypassed by url encoding",
+			input:      "%2e%2e/etc/passwd",
+			config:     map[string]interface{}{"base_path": "public", "filter": "normalize_check", "decode": "url"},
+			techniques: []string{"url_encoding"},
+		},
+		{
+			name:       "double url encoding",
+			input:      "%252e%252e%252fetc/passwd",
+			config:     map[string]interface{}{"base_path": "public", "filter": "normalize_check", "decode": "double_url"},
+			techniques: []string{"url_encoding", "double_url_encoding"},
+		},
+		{
+			name:       "overlong utf-8",
+			input:      "%c0%ae%c0%ae%c0%afetc/passwd",
+			config:     map[string]interface{}{"base_path": "public", "filter": "normalize_check", "decode": "overlong_utf8"},
+			techniques: []string{"overlong_utf8"},
+		},
+		{
+			name:       "null byte drops appended extension",
+			input:      "../etc/passwd\x00",
+			config:     map[string]interface{}{"base_path": "public", "append_extension": ".txt", "null_byte_truncation": true},
+			techniques: []string{"null_byte"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Handle(newPathTraversalContext(tt.input, tt.config))
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if result.Error != "" {
+				t.Fatalf("unexpected error: %s", result.Error)
+			}
+
+			data := result.Data.(map[string]interface{})
+			if !reflect.DeepEqual(data["bypass_techniques"], tt.techniques) {
+				t.Errorf("bypass_techniques = %v, want %v", data["bypass_techniques"], tt.techniques)
+			}
+		})
+	}
+}
+
+func TestPathTraversalFiltersBlock(t *testing.T) {
+	m := &PathTraversal{}
+
+	tests := []struct {
+		name   string
+		input  string
+		filter string
+	}{
+		{"absolute unix path", "/etc/passwd", "block_absolute"},
+		{"absolute windows path", `C:\Windows\win.ini`, "block_absolute"},
+		{"plain traversal after normalization", "public/../../etc/passwd", "normalize_check"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Handle(newPathTraversalContext(tt.input, map[string]interface{}{"filter": tt.filter}))
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			data := result.Data.(map[string]interface{})
+			if data["blocked"] != true {
+				t.Errorf("expected input to be blocked, got %v", data)
+			}
+		})
+	}
+}
+
+func TestPathTraversalWithinBase(t *testing.T) {
+	m := &PathTraversal{}
+
+	result, err := m.Handle(newPathTraversalContext("readme.txt", map[string]interface{}{"base_path": "public"}))
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	data := result.Data.(map[string]interface{})
+	if data["content"] != "welcome" {
+		t.Errorf("content = %v, want welcome", data["content"])
+	}
+	if _, ok := data["bypass_techniques"]; ok {
+		t.Error("expected no bypass techniques for a file inside the base path")
+	}
+}
@@ -2,6 +2,8 @@ package modules
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -113,6 +115,31 @@ func ValidatePlacement(moduleName, placement string) error {
 	return fmt.Errorf("placement '%s' is not supported by module '%s'", placement, moduleName)
 }
 
+// DifficultyConfig returns the config values a difficulty level stands for in
+// a module, or an error if the module doesn't offer that level
+func DifficultyConfig(moduleName, level string) (map[string]interface{}, error) {
+	module, err := Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := module.Info()
+	if values, ok := info.Difficulty[level]; ok {
+		return values, nil
+	}
+
+	var available []string
+	for _, l := range DifficultyLevels {
+		if _, ok := info.Difficulty[l]; ok {
+			available = append(available, l)
+		}
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("module '%s' has no difficulty levels", moduleName)
+	}
+	return nil, fmt.Errorf("difficulty '%s' is not offered by module '%s' (available: %s)", level, moduleName, strings.Join(available, ", "))
+}
+
 // ValidateConfigValue checks if a config value is valid for a module
 // Returns: (isValid bool, validOptions []string, defaultValue string)
 // If the module doesn't define valid options for the key, returns (true, nil, "")
@@ -146,3 +173,26 @@ func ValidateConfigValue(moduleName, configKey, configValue string) (bool, []str
 	}
 	return false, validOptions, defaultVal
 }
+
+// ConfigKeys returns every config key a module accepts, from its ValidVariants
+// and ConfigKeys, in sorted order
+func ConfigKeys(info ModuleInfo) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range info.ValidVariants {
+		add(key)
+	}
+	for _, key := range info.ConfigKeys {
+		add(key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
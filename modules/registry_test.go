@@ -1,6 +1,10 @@
 package modules
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -227,6 +231,85 @@ func TestModuleInfo_FromRegistry(t *testing.T) {
 	}
 }
 
+// TestConfigKeys tests that config keys combine ValidVariants and ConfigKeys
+func TestConfigKeys(t *testing.T) {
+	info := ModuleInfo{
+		ValidVariants: map[string][]string{"variant": {"a", "b"}, "filter": {"none"}},
+		ConfigKeys:    []string{"query_template", "filter"},
+	}
+
+	keys := ConfigKeys(info)
+	expected := []string{"filter", "query_template", "variant"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+
+	// websocket_injection hands its config through to the module for its sink
+	ws := ConfigKeys((&WebSocketInjection{}).Info())
+	for _, key := range []string{"sink", "query_template", "base_command", "context"} {
+		found := false
+		for _, k := range ws {
+			found = found || k == key
+		}
+		if !found {
+			t.Errorf("Expected websocket_injection to accept %s, got %v", key, ws)
+		}
+	}
+}
+
+// TestOptions tests that every module documents exactly the config keys it
+// accepts, with an example endpoint and payloads
+func TestOptions(t *testing.T) {
+	for _, info := range List() {
+		documented := slices.Sorted(maps.Keys(info.Options))
+		if keys := ConfigKeys(info); !slices.Equal(documented, keys) {
+			t.Errorf("%s: expected options for %v, got %v", info.Name, keys, documented)
+		}
+		for key, option := range info.Options {
+			if option.Description == "" {
+				t.Errorf("%s: option %s has no description", info.Name, key)
+			}
+		}
+		if !strings.HasPrefix(info.Example, "- path: ") || !strings.Contains(info.Example, "type: "+info.Name+"\n") {
+			t.Errorf("%s: expected an example endpoint using the module, got %q", info.Name, info.Example)
+		}
+		if len(info.Payloads) == 0 {
+			t.Errorf("%s: expected example payloads", info.Name)
+		}
+	}
+}
+
+// TestDifficulty tests that difficulty levels only set config the module accepts
+func TestDifficulty(t *testing.T) {
+	for _, info := range List() {
+		keys := make(map[string]bool)
+		for _, key := range ConfigKeys(info) {
+			keys[key] = true
+		}
+
+		for level, values := range info.Difficulty {
+			if !slices.Contains(DifficultyLevels, level) {
+				t.Errorf("%s: unexpected difficulty level '%s'", info.Name, level)
+			}
+			for key, value := range values {
+				if !keys[key] {
+					t.Errorf("%s %s: unknown config key '%s'", info.Name, level, key)
+				}
+				if valid, options, _ := ValidateConfigValue(info.Name, key, fmt.Sprint(value)); !valid {
+					t.Errorf("%s %s: invalid %s '%v' (valid: %v)", info.Name, level, key, value, options)
+				}
+			}
+		}
+	}
+
+	if _, err := DifficultyConfig("vhost_takeover", "easy"); err == nil || !strings.Contains(err.Error(), "has no difficulty levels") {
+		t.Errorf("Expected no difficulty levels error, got %v", err)
+	}
+	if _, err := DifficultyConfig("ldap_injection", "hard"); err == nil || !strings.Contains(err.Error(), "(available: easy, medium)") {
+		t.Errorf("Expected available levels in error, got %v", err)
+	}
+}
+
 // TestModuleHandle tests that Handle returns result
 func TestModuleHandle(t *testing.T) {
 	mock := &mockModule{
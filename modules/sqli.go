@@ -25,11 +25,39 @@ func (m *SQLInjection) Info() ModuleInfo {
 			"json_field",
 			"header",
 			"cookie",
+			"graphql_variable",
+			"graphql_query",
+			"soap_param",
+			"grpc_field",
 		},
 		RequiresSink: "sqlite",
 		ValidVariants: map[string][]string{
 			"variant": {"error_based", "blind_boolean"},
 		},
+		ConfigKeys: []string{"filter", "query_template", "show_errors"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"variant": "error_based", "show_errors": true, "filter": "none"},
+			"medium": {"variant": "error_based", "show_errors": false, "filter": "remove_comments"},
+			"hard":   {"variant": "blind_boolean", "show_errors": false, "filter": "remove_comments"},
+		},
+		Options: map[string]Option{
+			"variant":        {Description: "error_based answers with the rows and database errors, blind_boolean only with whether the query matched", Default: "error_based"},
+			"query_template": {Description: "SQL run on the sqlite sink, with {input} replaced by the filtered input"},
+			"filter":         {Description: "Applied to the input first: none, basic_quotes (doubles single quotes), remove_comments (strips --, /* and */) or remove_union (uppercases and strips UNION)", Default: "none"},
+			"show_errors":    {Description: "Include database errors in error_based answers", Default: "true"},
+		},
+		Example: `- path: /user
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: sql_injection
+      placement: query_param
+      param: id
+      config:
+        variant: error_based
+        query_template: "SELECT username, email FROM users WHERE id = {input}"
+`,
+		Payloads: []string{"1 OR 1=1", "0 UNION SELECT name, sql FROM sqlite_master", "1 AND 1=2"},
 	}
 }
 
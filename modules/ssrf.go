@@ -26,8 +26,42 @@ func (m *SSRF) Info() ModuleInfo {
 		},
 		RequiresSink: "http",
 		ValidVariants: map[string][]string{
-			"filter": {"none", "scheme_only", "basic_host"},
+			"filter":   {"none", "scheme_only", "basic_host"},
+			"variant":  {"direct", "url_preview"},
+			"renderer": {"unfurl", "pdf"},
 		},
+		ConfigKeys: []string{
+			"allowed_schemes", "follow_redirects", "return_body", "timeout", "base_url", "filter_subresources",
+			"max_subresources",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"filter": "none"},
+			"medium": {"filter": "scheme_only"},
+			"hard":   {"filter": "basic_host"},
+		},
+		Options: map[string]Option{
+			"filter":              {Description: "Check on the URL: none, scheme_only (URL must start with one of allowed_schemes) or basic_host (rejects URLs containing localhost, loopback or private IP prefixes)", Default: "none"},
+			"variant":             {Description: "direct fetches the URL and returns the response; url_preview renders a preview that also fetches the page's subresources", Default: "direct"},
+			"renderer":            {Description: "For url_preview: unfurl fetches the input URL, pdf renders the input as HTML", Default: "unfurl"},
+			"allowed_schemes":     {Description: "URL prefixes the scheme_only filter accepts", Default: "[http://, https://]"},
+			"follow_redirects":    {Description: "Follow redirects, past the filter", Default: "true"},
+			"return_body":         {Description: "Include the fetched body, up to 10000 bytes, in the response", Default: "true"},
+			"timeout":             {Description: "Request timeout in seconds", Default: "30"},
+			"base_url":            {Description: "For the pdf renderer, the URL relative subresources resolve against"},
+			"filter_subresources": {Description: "For url_preview, also run the filter on subresource URLs", Default: "false"},
+			"max_subresources":    {Description: "For url_preview, most subresources fetched per preview", Default: "5"},
+		},
+		Example: `- path: /fetch
+  method: GET
+  response_type: json
+  vulnerabilities:
+    - type: ssrf
+      placement: query_param
+      param: url
+      config:
+        filter: none
+`,
+		Payloads: []string{"http://169.254.169.254/latest/meta-data/", "http://127.0.0.1:8080/admin", "http://0x7f000001/", "file:///etc/passwd"},
 	}
 }
 
@@ -37,6 +71,11 @@ func (m *SSRF) Handle(ctx *HandlerContext) (*Result, error) {
 		return nil, fmt.Errorf("HTTP sink not available")
 	}
 
+	// URL preview / PDF rendering makes secondary fetches from the HTML
+	if ctx.GetConfigString("variant", "direct") == "url_preview" {
+		return m.handleURLPreview(ctx)
+	}
+
 	// Get configuration
 	filter := ctx.GetConfigString("filter", "none")
 	followRedirects := ctx.GetConfigBool("follow_redirects", true)
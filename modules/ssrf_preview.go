@@ -0,0 +1,210 @@
+package modules
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// previewTagRegex matches tags that make a renderer fetch a secondary resource
+var previewTagRegex = regexp.MustCompile(`(?is)<(iframe|img|embed|object|link|script|meta)\b([^>]*)>`)
+
+// previewAttrRegex matches attributes within a tag
+var previewAttrRegex = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// previewTitleRegex matches the document title
+var previewTitleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// previewStripRegex removes tags when rendering the document text
+var previewStripRegex = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// previewSubresource is a secondary resource referenced by the rendered HTML
+type previewSubresource struct {
+	Tag string
+	URL string
+}
+
+// handleURLPreview emulates link unfurling and HTML-to-PDF rendering.
+// The renderer fetches any iframes, images and other subresources found in the
+// HTML and reflects what it fetched into the preview object.
+func (m *SSRF) handleURLPreview(ctx *HandlerContext) (*Result, error) {
+	renderer := ctx.GetConfigString("renderer", "unfurl")
+	filter := ctx.GetConfigString("filter", "none")
+	filterSubresources := ctx.GetConfigBool("filter_subresources", false)
+	maxSubresources := ctx.GetConfigInt("max_subresources", 5)
+	timeout := ctx.GetConfigInt("timeout", 30)
+
+	opts := HTTPOptions{
+		Method:          "GET",
+		FollowRedirects: ctx.GetConfigBool("follow_redirects", true),
+		Timeout:         timeout,
+	}
+
+	var html, baseURL string
+	preview := map[string]interface{}{
+		"renderer": renderer,
+	}
+
+	switch renderer {
+	case "pdf":
+		// The input is attacker-controlled HTML rendered into a document
+		html = ctx.Input
+		baseURL = ctx.GetConfigString("base_url", "")
+		preview["content_type"] = "application/pdf"
+		preview["pages"] = 1
+		preview["text"] = strings.Join(strings.Fields(previewStripRegex.ReplaceAllString(html, " ")), " ")
+
+	default:
+		// The input is a link to unfurl - only this URL goes through the filter
+		baseURL = ctx.Input
+		if err := validateURL(baseURL, filter, ctx.Config); err != nil {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"url":     baseURL,
+					"error":   err.Error(),
+					"blocked": true,
+				},
+			}, nil
+		}
+
+		resp, err := ctx.Sinks.HTTP.FetchWithOptions(baseURL, opts)
+		if err != nil {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"url":   baseURL,
+					"error": err.Error(),
+				},
+			}, nil
+		}
+
+		html = resp.Body
+		preview["url"] = baseURL
+		preview["status_code"] = resp.StatusCode
+	}
+
+	title, description, image, subresources := parsePreviewHTML(html, baseURL)
+	preview["title"] = title
+	preview["description"] = description
+	if image != "" {
+		preview["image"] = image
+	}
+
+	// Secondary fetches - the flaw is that these skip the URL filter by default
+	var embeds []map[string]interface{}
+	for _, sub := range subresources {
+		if len(embeds) >= maxSubresources {
+			break
+		}
+
+		embed := map[string]interface{}{
+			"tag": sub.Tag,
+			"url": sub.URL,
+		}
+
+		if filterSubresources {
+			if err := validateURL(sub.URL, filter, ctx.Config); err != nil {
+				embed["error"] = err.Error()
+				embed["blocked"] = true
+				embeds = append(embeds, embed)
+				continue
+			}
+		}
+
+		resp, err := ctx.Sinks.HTTP.FetchWithOptions(sub.URL, opts)
+		if err != nil {
+			embed["error"] = err.Error()
+		} else {
+			content := resp.Body
+			if len(content) > 2000 {
+				content = content[:2000] + "\n...(truncated)"
+			}
+			embed["status_code"] = resp.StatusCode
+			embed["content"] = content
+		}
+		embeds = append(embeds, embed)
+	}
+
+	if embeds == nil {
+		embeds = []map[string]interface{}{}
+	}
+	preview["embeds"] = embeds
+
+	return NewResult(map[string]interface{}{
+		"preview": preview,
+	}), nil
+}
+
+// parsePreviewHTML extracts the title, description, image and subresources from HTML
+func parsePreviewHTML(html, baseURL string) (string, string, string, []previewSubresource) {
+	var title, description, image string
+	var subresources []previewSubresource
+
+	if match := previewTitleRegex.FindStringSubmatch(html); match != nil {
+		title = strings.TrimSpace(match[1])
+	}
+
+	for _, tag := range previewTagRegex.FindAllStringSubmatch(html, -1) {
+		name := strings.ToLower(tag[1])
+		attrs := parsePreviewAttrs(tag[2])
+
+		switch name {
+		case "meta":
+			property := strings.ToLower(attrs["property"] + attrs["name"])
+			switch property {
+			case "og:title":
+				title = attrs["content"]
+			case "og:description", "description":
+				description = attrs["content"]
+			case "og:image":
+				image = resolvePreviewURL(baseURL, attrs["content"])
+				subresources = append(subresources, previewSubresource{Tag: "og:image", URL: image})
+			}
+			// <meta http-equiv="refresh" content="0;url=...">
+			if strings.EqualFold(attrs["http-equiv"], "refresh") {
+				if idx := strings.Index(strings.ToLower(attrs["content"]), "url="); idx != -1 {
+					target := resolvePreviewURL(baseURL, attrs["content"][idx+4:])
+					subresources = append(subresources, previewSubresource{Tag: "meta_refresh", URL: target})
+				}
+			}
+		case "link":
+			if href := attrs["href"]; href != "" {
+				subresources = append(subresources, previewSubresource{Tag: "link", URL: resolvePreviewURL(baseURL, href)})
+			}
+		case "object":
+			if data := attrs["data"]; data != "" {
+				subresources = append(subresources, previewSubresource{Tag: "object", URL: resolvePreviewURL(baseURL, data)})
+			}
+		default:
+			if src := attrs["src"]; src != "" {
+				subresources = append(subresources, previewSubresource{Tag: name, URL: resolvePreviewURL(baseURL, src)})
+			}
+		}
+	}
+
+	return title, description, image, subresources
+}
+
+// parsePreviewAttrs parses tag attributes into a map with lowercased names
+func parsePreviewAttrs(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range previewAttrRegex.FindAllStringSubmatch(raw, -1) {
+		attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attrs
+}
+
+// resolvePreviewURL resolves a reference against the page URL, as a browser would
+func resolvePreviewURL(baseURL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	base, err := url.Parse(baseURL)
+	if err != nil || baseURL == "" {
+		return ref
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(target).String()
+}
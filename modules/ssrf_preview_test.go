@@ -0,0 +1,117 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// MockHTTPSink serves canned bodies and records fetched URLs
+type MockHTTPSink struct {
+	Pages   map[string]string
+	Fetched []string
+}
+
+func (m *MockHTTPSink) Fetch(url string) (*HTTPResponse, error) {
+	return m.FetchWithOptions(url, HTTPOptions{})
+}
+
+func (m *MockHTTPSink) FetchWithOptions(url string, opts HTTPOptions) (*HTTPResponse, error) {
+	m.Fetched = append(m.Fetched, url)
+	body, ok := m.Pages[url]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return &HTTPResponse{StatusCode: 200, Body: body}, nil
+}
+
+// TestSSRF_URLPreview_Unfurl tests that subresources of an unfurled page are fetched
+func TestSSRF_URLPreview_Unfurl(t *testing.T) {
+	m := &SSRF{}
+	sink := &MockHTTPSink{Pages: map[string]string{
+		"http://attacker.example/page": `<html><head><title>Cat pics</title>
+			<meta property="og:description" content="Totally harmless">
+			<meta property="og:image" content="http://169.254.169.254/latest/meta-data/iam/">
+			</head><body><iframe src="http://127.0.0.1:8080/admin"></iframe></body></html>`,
+		"http://169.254.169.254/latest/meta-data/iam/": "security-credentials/",
+		"http://127.0.0.1:8080/admin":                  "internal admin panel",
+	}}
+
+	result, err := m.Handle(&HandlerContext{
+		Input:  "http://attacker.example/page",
+		Config: map[string]interface{}{"variant": "url_preview", "filter": "basic_host"},
+		Sinks:  &SinkContext{HTTP: sink},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	preview := result.Data.(map[string]interface{})["preview"].(map[string]interface{})
+	if preview["title"] != "Cat pics" || preview["description"] != "Totally harmless" {
+		t.Errorf("Unexpected preview metadata: %v", preview)
+	}
+
+	embeds := preview["embeds"].([]map[string]interface{})
+	if len(embeds) != 2 {
+		t.Fatalf("Expected 2 embeds, got %v", embeds)
+	}
+	if embeds[1]["content"] != "internal admin panel" {
+		t.Errorf("Expected internal content to be reflected, got %v", embeds[1])
+	}
+}
+
+// TestSSRF_URLPreview_FilterSubresources tests that the filter can cover secondary fetches
+func TestSSRF_URLPreview_FilterSubresources(t *testing.T) {
+	m := &SSRF{}
+	sink := &MockHTTPSink{Pages: map[string]string{
+		"http://attacker.example/page": `<img src="http://127.0.0.1/secret">`,
+	}}
+
+	result, _ := m.Handle(&HandlerContext{
+		Input: "http://attacker.example/page",
+		Config: map[string]interface{}{
+			"variant":             "url_preview",
+			"filter":              "basic_host",
+			"filter_subresources": true,
+		},
+		Sinks: &SinkContext{HTTP: sink},
+	})
+
+	embeds := result.Data.(map[string]interface{})["preview"].(map[string]interface{})["embeds"].([]map[string]interface{})
+	if embeds[0]["blocked"] != true {
+		t.Errorf("Expected subresource to be blocked, got %v", embeds[0])
+	}
+	if len(sink.Fetched) != 1 {
+		t.Errorf("Expected only the page to be fetched, got %v", sink.Fetched)
+	}
+}
+
+// TestSSRF_URLPreview_PDF tests HTML-to-PDF rendering of attacker HTML
+func TestSSRF_URLPreview_PDF(t *testing.T) {
+	m := &SSRF{}
+	sink := &MockHTTPSink{Pages: map[string]string{
+		"http://internal.local/report": "Q3 salaries",
+	}}
+
+	result, err := m.Handle(&HandlerContext{
+		Input: `<h1>Invoice</h1><iframe src="/report"></iframe>`,
+		Config: map[string]interface{}{
+			"variant":  "url_preview",
+			"renderer": "pdf",
+			"base_url": "http://internal.local/",
+		},
+		Sinks: &SinkContext{HTTP: sink},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	preview := result.Data.(map[string]interface{})["preview"].(map[string]interface{})
+	if !strings.Contains(preview["text"].(string), "Invoice") {
+		t.Errorf("Expected rendered text, got %v", preview["text"])
+	}
+	embeds := preview["embeds"].([]map[string]interface{})
+	if len(embeds) != 1 || embeds[0]["content"] != "Q3 salaries" {
+		t.Errorf("Expected resolved iframe to be fetched, got %v", embeds)
+	}
+}
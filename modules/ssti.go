@@ -0,0 +1,198 @@
+package modules
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SSTI implements the ssti (server-side template injection) vulnerability module
+type SSTI struct{}
+
+// init registers the module
+func init() {
+	Register(&SSTI{})
+}
+
+// Info returns module metadata
+func (m *SSTI) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "ssti",
+		Description: "Server-Side Template Injection into Jinja2, Twig, FreeMarker, ERB and Go templates",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "template",
+		ValidVariants: map[string][]string{
+			"engine": {"jinja2", "twig", "freemarker", "erb", "go"},
+		},
+		ConfigKeys: []string{
+			"allow_file_read", "context", "escape_input", "execute_commands", "secret_key", "show_errors",
+			"template",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"show_errors": true, "allow_file_read": true},
+			"medium": {"show_errors": false, "allow_file_read": true},
+			"hard":   {"show_errors": false, "allow_file_read": false},
+		},
+		Options: map[string]Option{
+			"engine":           {Description: "Template engine rendering the page: jinja2, twig, freemarker, erb or go", Default: "jinja2"},
+			"template":         {Description: "Template source with {input} where the input is concatenated in", Default: "a greeting page"},
+			"escape_input":     {Description: "Pass the input as template data instead of source, the fixed version", Default: "false"},
+			"show_errors":      {Description: "Return template errors instead of a bare 500", Default: "true"},
+			"secret_key":       {Description: "config.SECRET_KEY visible to the template", Default: "ff-dev-secret-9c1e7b"},
+			"context":          {Description: "Extra variables visible to the template, as a map"},
+			"allow_file_read":  {Description: "Let file reads reached from the template return the lab's files", Default: "true"},
+			"execute_commands": {Description: "Run commands reached from the template on the command sink instead of simulating them", Default: "false"},
+		},
+		Example: `- path: /greet
+  method: GET
+  response_type: html
+  vulnerabilities:
+    - type: ssti
+      placement: query_param
+      param: name
+      config:
+        engine: jinja2
+`,
+		Payloads: []string{"{{7*7}}", "{{config.SECRET_KEY}}", "{{ cycler.__init__.__globals__.os.popen('id').read() }}", "${7*7}", "<%= 7*7 %>"},
+	}
+}
+
+// defaultSSTITemplate is the page the input is concatenated into
+const defaultSSTITemplate = `<div class="greeting">
+    <h2>Hello {input}!</h2>
+    <p>Welcome back to your dashboard.</p>
+</div>`
+
+// sstiVariableRefs reference the input as a template variable, per engine
+var sstiVariableRefs = map[string]string{
+	"jinja2":     "{{ input }}",
+	"twig":       "{{ input }}",
+	"freemarker": "${input}",
+	"erb":        "<%= input %>",
+	"go":         "{{.input}}",
+}
+
+// Handle builds a template from the input and renders it
+func (m *SSTI) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.Sinks == nil || ctx.Sinks.Template == nil {
+		return nil, fmt.Errorf("Template sink not available")
+	}
+
+	// Get configuration
+	engine := ctx.GetConfigString("engine", "jinja2")
+	source := ctx.GetConfigString("template", defaultSSTITemplate)
+	escapeInput := ctx.GetConfigBool("escape_input", false)
+	showErrors := ctx.GetConfigBool("show_errors", true)
+
+	// The vulnerable pattern concatenates input into the template source;
+	// the safe one passes it as data
+	if escapeInput {
+		source = strings.ReplaceAll(source, "{input}", sstiVariableRefs[engine])
+	} else {
+		source = strings.ReplaceAll(source, "{input}", ctx.Input)
+	}
+
+	data := map[string]interface{}{
+		"input": ctx.Input,
+		"config": map[string]interface{}{
+			"SECRET_KEY": ctx.GetConfigString("secret_key", "ff-dev-secret-9c1e7b"),
+			"DEBUG":      true,
+		},
+	}
+	if extra, ok := ctx.Config["context"].(map[string]interface{}); ok {
+		for k, v := range extra {
+			data[k] = v
+		}
+	}
+
+	output, err := ctx.Sinks.Template.Render(engine, source, data, func(u UnsafeExpression) string {
+		return m.runUnsafe(ctx, u)
+	})
+	if err != nil {
+		if showErrors {
+			return &Result{
+				Error: err.Error(),
+				Data: map[string]interface{}{
+					"engine": engine,
+					"error":  err.Error(),
+				},
+				StatusCode: http.StatusInternalServerError,
+			}, nil
+		}
+		return &Result{Error: "Internal Server Error", StatusCode: http.StatusInternalServerError}, nil
+	}
+
+	result := NewResult(map[string]interface{}{
+		"rendered": output,
+		"engine":   engine,
+	})
+
+	// Set raw output for HTML responses
+	result.RawOutput = []byte(output)
+
+	return result, nil
+}
+
+// runUnsafe runs a command or file read reached from the template. Commands go
+// to the command sink when execute_commands is set and are simulated otherwise.
+func (m *SSTI) runUnsafe(ctx *HandlerContext, u UnsafeExpression) string {
+	allowFileRead := ctx.GetConfigBool("allow_file_read", true)
+
+	if u.Kind == "file_read" {
+		return simulateFileRead(u.Path, allowFileRead, ctx)
+	}
+
+	if ctx.GetConfigBool("execute_commands", false) && ctx.Sinks.Command != nil {
+		output, err := ctx.Sinks.Command.Execute(u.Command)
+		if err != nil && output == "" {
+			return err.Error()
+		}
+		return output
+	}
+	return simulateShellCommand(u.Command, allowFileRead, ctx)
+}
+
+// simulateShellCommand returns plausible output for common recon commands
+func simulateShellCommand(command string, allowFileRead bool, ctx *HandlerContext) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Allow absolute paths such as /usr/bin/id
+	name := fields[0][strings.LastIndex(fields[0], "/")+1:]
+
+	switch name {
+	case "id":
+		return "uid=33(www-data) gid=33(www-data) groups=33(www-data)\n"
+	case "whoami":
+		return "www-data\n"
+	case "hostname":
+		return "ssti-lab\n"
+	case "pwd":
+		return "/var/www/app\n"
+	case "uname":
+		if len(fields) > 1 && fields[1] == "-a" {
+			return "Linux ssti-lab 5.15.0-91-generic #101-Ubuntu SMP x86_64 GNU/Linux\n"
+		}
+		return "Linux\n"
+	case "ls":
+		return "app.py\nconfig.py\nrequirements.txt\nstatic\ntemplates\n"
+	case "cat", "head", "tail":
+		if len(fields) < 2 {
+			return ""
+		}
+		return simulateFileRead(fields[len(fields)-1], allowFileRead, ctx) + "\n"
+	case "echo":
+		return strings.Join(fields[1:], " ") + "\n"
+	default:
+		return fmt.Sprintf("sh: 1: %s: not found\n", name)
+	}
+}
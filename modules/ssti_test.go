@@ -0,0 +1,178 @@
+package modules
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// MockTemplateSink records rendered sources and raises any queued unsafe expressions
+type MockTemplateSink struct {
+	Sources []string
+	Data    map[string]interface{}
+	Unsafe  []UnsafeExpression
+	Err     error
+}
+
+func (m *MockTemplateSink) Render(engine, source string, data map[string]interface{}, unsafe func(UnsafeExpression) string) (string, error) {
+	m.Sources = append(m.Sources, source)
+	m.Data = data
+	if m.Err != nil {
+		return "", m.Err
+	}
+
+	out := source
+	for _, u := range m.Unsafe {
+		out += unsafe(u)
+	}
+	return out, nil
+}
+
+// TestSSTIModuleInfo tests module metadata
+func TestSSTIModuleInfo(t *testing.T) {
+	m := &SSTI{}
+	info := m.Info()
+
+	if info.Name != "ssti" {
+		t.Errorf("Expected name 'ssti', got '%s'", info.Name)
+	}
+	if info.RequiresSink != "template" {
+		t.Errorf("Expected RequiresSink 'template', got '%s'", info.RequiresSink)
+	}
+	if !Has("ssti") {
+		t.Error("ssti module should be registered")
+	}
+}
+
+// TestSSTI_InputInTemplateSource tests that input is concatenated into the template unless escaped
+func TestSSTI_InputInTemplateSource(t *testing.T) {
+	m := &SSTI{}
+
+	tests := []struct {
+		config map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"template": "Hi {input}"}, "Hi {{7*7}}"},
+		{map[string]interface{}{"template": "Hi {input}", "escape_input": true}, "Hi {{ input }}"},
+		{map[string]interface{}{"template": "Hi {input}", "escape_input": true, "engine": "freemarker"}, "Hi ${input}"},
+	}
+
+	for _, tt := range tests {
+		sink := &MockTemplateSink{}
+		ctx := &HandlerContext{
+			Input:  "{{7*7}}",
+			Config: tt.config,
+			Sinks:  &SinkContext{Template: sink},
+		}
+
+		result, err := m.Handle(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sink.Sources[0] != tt.want {
+			t.Errorf("Expected source %q, got %q", tt.want, sink.Sources[0])
+		}
+		if sink.Data["input"] != "{{7*7}}" {
+			t.Errorf("Expected raw input in template data, got %v", sink.Data["input"])
+		}
+		if string(result.RawOutput) != tt.want {
+			t.Errorf("Expected rendered output as raw HTML, got %q", result.RawOutput)
+		}
+	}
+}
+
+// TestSSTI_Context tests that configured context variables reach the template
+func TestSSTI_Context(t *testing.T) {
+	m := &SSTI{}
+	sink := &MockTemplateSink{}
+
+	ctx := &HandlerContext{
+		Config: map[string]interface{}{
+			"secret_key": "k3y",
+			"context":    map[string]interface{}{"flag": "FLAG{test}"},
+		},
+		Sinks: &SinkContext{Template: sink},
+	}
+	if _, err := m.Handle(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sink.Data["flag"] != "FLAG{test}" {
+		t.Errorf("Expected context variable, got %v", sink.Data["flag"])
+	}
+	if config := sink.Data["config"].(map[string]interface{}); config["SECRET_KEY"] != "k3y" {
+		t.Errorf("Expected configured secret key, got %v", config["SECRET_KEY"])
+	}
+}
+
+// TestSSTI_UnsafeExpressions tests simulated and real command execution and file reads
+func TestSSTI_UnsafeExpressions(t *testing.T) {
+	m := &SSTI{}
+
+	render := func(config map[string]interface{}, command *MockCommandSink, unsafe ...UnsafeExpression) string {
+		ctx := &HandlerContext{
+			Input:  "x",
+			Config: config,
+			Sinks:  &SinkContext{Template: &MockTemplateSink{Unsafe: unsafe}},
+		}
+		if command != nil {
+			ctx.Sinks.Command = command
+		}
+		result, err := m.Handle(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return string(result.RawOutput)
+	}
+
+	rce := UnsafeExpression{Kind: "rce", Command: "id"}
+
+	if out := render(map[string]interface{}{"template": ""}, nil, rce); !strings.Contains(out, "uid=33(www-data)") {
+		t.Errorf("Expected simulated id output, got %q", out)
+	}
+
+	out := render(map[string]interface{}{"template": ""}, nil, UnsafeExpression{Kind: "file_read", Path: "/etc/passwd"})
+	if !strings.Contains(out, "root:x:0:0") {
+		t.Errorf("Expected simulated file contents, got %q", out)
+	}
+
+	command := &MockCommandSink{Outputs: map[string]string{"id": "uid=1000(lab)\n"}}
+	out = render(map[string]interface{}{"template": "", "execute_commands": true}, command, rce)
+	if out != "uid=1000(lab)\n" || len(command.Executed) != 1 {
+		t.Errorf("Expected command sink output, got %q (executed %v)", out, command.Executed)
+	}
+}
+
+// TestSSTI_Errors tests render errors are exposed only when show_errors is set
+func TestSSTI_Errors(t *testing.T) {
+	m := &SSTI{}
+	renderErr := errors.New("jinja2.exceptions.TemplateSyntaxError: unexpected '}'")
+
+	for _, showErrors := range []bool{true, false} {
+		ctx := &HandlerContext{
+			Input:  "{{",
+			Config: map[string]interface{}{"show_errors": showErrors},
+			Sinks:  &SinkContext{Template: &MockTemplateSink{Err: renderErr}},
+		}
+
+		result, err := m.Handle(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Expected 500, got %d", result.StatusCode)
+		}
+		if leaked := strings.Contains(result.Error, "TemplateSyntaxError"); leaked != showErrors {
+			t.Errorf("show_errors=%v: unexpected error %q", showErrors, result.Error)
+		}
+	}
+}
+
+// TestSSTI_NoSink tests the module requires the template sink
+func TestSSTI_NoSink(t *testing.T) {
+	m := &SSTI{}
+	if _, err := m.Handle(&HandlerContext{Sinks: &SinkContext{}}); err == nil {
+		t.Error("Expected error without template sink")
+	}
+}
@@ -0,0 +1,36 @@
+package modules
+
+import "sync"
+
+// State holds what modules keep between requests for one app, such as pending
+// logins. Modules are shared by every app a process serves, so they keep such
+// state here rather than on themselves, and each app starts with its own.
+type State struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// NewState creates an empty state
+func NewState() *State {
+	return &State{values: make(map[string]interface{})}
+}
+
+// Get returns the value kept under key, usually the module's name, creating
+// it with create on first use
+func (s *State) Get(key string, create func() interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		value = create()
+		s.values[key] = value
+	}
+	return value
+}
+
+// Clear forgets every module's state
+func (s *State) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]interface{})
+}
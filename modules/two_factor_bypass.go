@@ -0,0 +1,334 @@
+package modules
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// TwoFactorBypass implements the two_factor_bypass vulnerability module
+type TwoFactorBypass struct{}
+
+// otpState is an app's pending logins and active codes, kept in its State
+type otpState struct {
+	mu       sync.Mutex
+	sessions map[string]*otpSession
+	codes    map[string]string // username -> active OTP code
+}
+
+// otpSession tracks a login that is waiting for (or has passed) OTP verification
+type otpSession struct {
+	Username string
+	Verified bool
+	Attempts int
+}
+
+// init registers the module
+func init() {
+	Register(&TwoFactorBypass{})
+}
+
+// Info returns module metadata
+func (m *TwoFactorBypass) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "two_factor_bypass",
+		Description: "Login + OTP flow with brute-forceable codes, client-trusted verification flags and code reuse",
+		SupportedPlacements: []string{
+			"query_param",
+			"path_param",
+			"form_field",
+			"json_field",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "", // Optionally uses the SQLite sink to check credentials
+		ValidVariants: map[string][]string{
+			"step": {"login", "verify", "protected"},
+		},
+		ConfigKeys: []string{
+			"allow_code_reuse", "code_length", "leak_code", "max_attempts", "password_column",
+			"password_param", "query_template", "secret", "session_cookie", "trust_client_verified",
+			"verify_url",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"leak_code": true, "code_length": 4},
+			"medium": {"leak_code": false, "code_length": 4},
+			"hard":   {"leak_code": false, "code_length": 6},
+		},
+		Options: map[string]Option{
+			"step":                  {Description: "Part of the flow the endpoint is: login (issues a code), verify (checks it) or protected (needs a verified session)", Default: "login"},
+			"code_length":           {Description: "Digits in issued codes", Default: "4"},
+			"leak_code":             {Description: "Include the issued code in the login response", Default: "false"},
+			"max_attempts":          {Description: "Wrong codes allowed per session; 0 allows any number", Default: "0"},
+			"allow_code_reuse":      {Description: "Keep a user's code valid after use and across logins", Default: "false"},
+			"trust_client_verified": {Description: "Let protected accept a client-sent verified flag", Default: "false"},
+			"session_cookie":        {Description: "Cookie holding the pending session", Default: "otp_session"},
+			"verify_url":            {Description: "Next step URL the login response points to", Default: "/2fa/verify"},
+			"secret":                {Description: "Value protected reveals once verified"},
+			"query_template":        {Description: "Query looking up the user at login, with {input} replaced by the username; when unset any password is accepted"},
+			"password_param":        {Description: "Request parameter holding the password", Default: "password"},
+			"password_column":       {Description: "Column the password is compared with", Default: "password"},
+		},
+		Example: `- path: /2fa/verify
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: two_factor_bypass
+      placement: form_field
+      param: code
+      config:
+        step: verify
+        code_length: 4
+`,
+		Payloads: []string{"0000", "1234", "9999", "Cookie: verified=true"},
+	}
+}
+
+// Handle runs one step of the login + OTP flow
+func (m *TwoFactorBypass) Handle(ctx *HandlerContext) (*Result, error) {
+	if ctx.State == nil {
+		return nil, fmt.Errorf("module state not available")
+	}
+	state := ctx.State.Get("two_factor_bypass", func() interface{} {
+		return &otpState{sessions: make(map[string]*otpSession), codes: make(map[string]string)}
+	}).(*otpState)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	step := ctx.GetConfigString("step", "login")
+
+	switch step {
+	case "login":
+		return m.handleLogin(ctx, state)
+	case "verify":
+		return m.handleVerify(ctx, state)
+	case "protected":
+		return m.handleProtected(ctx, state)
+	default:
+		return m.handleLogin(ctx, state)
+	}
+}
+
+// handleLogin checks the password, starts a pending session and issues an OTP code
+func (m *TwoFactorBypass) handleLogin(ctx *HandlerContext, state *otpState) (*Result, error) {
+	sessionCookie := ctx.GetConfigString("session_cookie", "otp_session")
+	codeLength := ctx.GetConfigInt("code_length", 4)
+	allowReuse := ctx.GetConfigBool("allow_code_reuse", false)
+	leakCode := ctx.GetConfigBool("leak_code", false)
+
+	username := ctx.Input
+	if username == "" {
+		return &Result{
+			Error:      "username is required",
+			StatusCode: http.StatusBadRequest,
+		}, nil
+	}
+
+	if err := checkOTPCredentials(ctx, username); err != nil {
+		return &Result{
+			Data:       map[string]interface{}{"message": err.Error()},
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	// With code reuse enabled a previously issued code stays valid for the user
+	code, exists := state.codes[username]
+	if !exists || !allowReuse {
+		code = generateOTPCode(ctx, codeLength)
+		state.codes[username] = code
+	}
+
+	sessionID := newOTPSessionID(ctx)
+	state.sessions[sessionID] = &otpSession{Username: username}
+
+	if ctx.ResponseWriter != nil {
+		http.SetCookie(ctx.ResponseWriter, &http.Cookie{Name: sessionCookie, Value: sessionID, Path: "/"})
+	}
+
+	data := map[string]interface{}{
+		"message":       fmt.Sprintf("A %d-digit code has been sent to your device", codeLength),
+		"session":       sessionID,
+		"otp_required":  true,
+		"code_length":   codeLength,
+		"next_step_url": ctx.GetConfigString("verify_url", "/2fa/verify"),
+	}
+	if leakCode {
+		data["debug_code"] = code
+	}
+
+	return NewResult(data), nil
+}
+
+// handleVerify checks the submitted OTP code for the session in the cookie
+func (m *TwoFactorBypass) handleVerify(ctx *HandlerContext, state *otpState) (*Result, error) {
+	maxAttempts := ctx.GetConfigInt("max_attempts", 0)
+	allowReuse := ctx.GetConfigBool("allow_code_reuse", false)
+
+	sessionID, session := m.sessionFromRequest(ctx, state)
+	if session == nil {
+		return &Result{
+			Data:       map[string]interface{}{"message": "no pending login", "verified": false},
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	// Rate limiting is off unless max_attempts is configured (brute-forceable)
+	if maxAttempts > 0 && session.Attempts >= maxAttempts {
+		delete(state.sessions, sessionID)
+		return &Result{
+			Data:       map[string]interface{}{"message": "too many attempts, please log in again", "verified": false},
+			StatusCode: http.StatusTooManyRequests,
+		}, nil
+	}
+	session.Attempts++
+
+	code, ok := state.codes[session.Username]
+	if !ok || ctx.Input != code {
+		if ctx.ResponseWriter != nil {
+			http.SetCookie(ctx.ResponseWriter, &http.Cookie{Name: "verified", Value: "false", Path: "/"})
+		}
+		return &Result{
+			Data: map[string]interface{}{
+				"message":  "invalid code",
+				"verified": false,
+				"attempts": session.Attempts,
+			},
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	session.Verified = true
+	if !allowReuse {
+		delete(state.codes, session.Username)
+	}
+
+	return NewResult(map[string]interface{}{
+		"message":  "verification successful",
+		"verified": true,
+		"username": session.Username,
+		"attempts": session.Attempts,
+	}), nil
+}
+
+// handleProtected grants access to verified sessions (or to clients claiming to be verified)
+func (m *TwoFactorBypass) handleProtected(ctx *HandlerContext, state *otpState) (*Result, error) {
+	trustClient := ctx.GetConfigBool("trust_client_verified", false)
+
+	_, session := m.sessionFromRequest(ctx, state)
+	if session == nil {
+		return &Result{
+			Data:       map[string]interface{}{"message": "not logged in"},
+			StatusCode: http.StatusUnauthorized,
+		}, nil
+	}
+
+	verified := session.Verified
+	source := "server"
+
+	// Response manipulation - the server trusts the verification flag sent back by the client
+	if !verified && trustClient && clientClaimsVerified(ctx.Request) {
+		verified = true
+		source = "client"
+	}
+
+	if !verified {
+		return &Result{
+			Data: map[string]interface{}{
+				"message":  "two-factor verification required",
+				"verified": false,
+			},
+			StatusCode: http.StatusForbidden,
+		}, nil
+	}
+
+	data := map[string]interface{}{
+		"message":         fmt.Sprintf("Welcome, %s", session.Username),
+		"username":        session.Username,
+		"verified":        true,
+		"verified_source": source,
+	}
+	if secret := ctx.GetConfigString("secret", ""); secret != "" {
+		data["secret"] = secret
+	}
+
+	return NewResult(data), nil
+}
+
+// sessionFromRequest resolves the session from the input or the session cookie
+func (m *TwoFactorBypass) sessionFromRequest(ctx *HandlerContext, state *otpState) (string, *otpSession) {
+	sessionCookie := ctx.GetConfigString("session_cookie", "otp_session")
+
+	sessionID := ""
+	if ctx.Placement == "cookie" && ctx.Param == sessionCookie {
+		sessionID = ctx.Input
+	} else if ctx.Request != nil {
+		if c, err := ctx.Request.Cookie(sessionCookie); err == nil {
+			sessionID = c.Value
+		}
+	}
+
+	return sessionID, state.sessions[sessionID]
+}
+
+// checkOTPCredentials validates the password when a credential query is configured
+func checkOTPCredentials(ctx *HandlerContext, username string) error {
+	queryTemplate := ctx.GetConfigString("query_template", "")
+	if queryTemplate == "" || ctx.Sinks == nil || ctx.Sinks.SQLite == nil {
+		return nil
+	}
+
+	passwordParam := ctx.GetConfigString("password_param", "password")
+	passwordColumn := ctx.GetConfigString("password_column", "password")
+
+	password := ""
+	if ctx.Request != nil {
+		password = ctx.Request.FormValue(passwordParam)
+	}
+
+	results, err := ctx.Sinks.SQLite.Query(strings.ReplaceAll(queryTemplate, "{input}", username))
+	if err != nil || len(results) == 0 {
+		return fmt.Errorf("invalid username or password")
+	}
+	if fmt.Sprintf("%v", results[0][passwordColumn]) != password {
+		return fmt.Errorf("invalid username or password")
+	}
+	return nil
+}
+
+// clientClaimsVerified reports whether the request carries a client-side "verified" flag
+func clientClaimsVerified(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if c, err := r.Cookie("verified"); err == nil && strings.EqualFold(c.Value, "true") {
+		return true
+	}
+	if strings.EqualFold(r.Header.Get("X-OTP-Verified"), "true") {
+		return true
+	}
+	return strings.EqualFold(r.URL.Query().Get("verified"), "true")
+}
+
+// generateOTPCode returns a numeric code of the given length
+func generateOTPCode(ctx *HandlerContext, length int) string {
+	if length <= 0 {
+		length = 4
+	}
+	var sb strings.Builder
+	for sb.Len() < length {
+		for _, b := range ctx.RandomBytes(length) {
+			// Bytes from 250 up would favor the low digits
+			if b < 250 && sb.Len() < length {
+				sb.WriteByte('0' + b%10)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// newOTPSessionID returns a random session identifier
+func newOTPSessionID(ctx *HandlerContext) string {
+	return hex.EncodeToString(ctx.RandomBytes(16))
+}
@@ -0,0 +1,204 @@
+package modules
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// otpLogin performs the login step and returns the session ID and leaked code
+func otpLogin(t *testing.T, m *TwoFactorBypass, state *State, username string, config map[string]interface{}) (string, string) {
+	t.Helper()
+
+	cfg := map[string]interface{}{"step": "login", "leak_code": true}
+	for k, v := range config {
+		cfg[k] = v
+	}
+
+	result, err := m.Handle(&HandlerContext{
+		Input:          username,
+		Config:         cfg,
+		State:          state,
+		ResponseWriter: httptest.NewRecorder(),
+		Request:        httptest.NewRequest("POST", "/login", nil),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data := result.Data.(map[string]interface{})
+	return data["session"].(string), data["debug_code"].(string)
+}
+
+// otpRequest builds a request carrying the OTP session cookie
+func otpRequest(sessionID string) *http.Request {
+	req := httptest.NewRequest("POST", "/2fa", nil)
+	req.AddCookie(&http.Cookie{Name: "otp_session", Value: sessionID})
+	return req
+}
+
+// TestTwoFactorBypass_Info tests module metadata
+func TestTwoFactorBypass_Info(t *testing.T) {
+	m := &TwoFactorBypass{}
+	info := m.Info()
+
+	if info.Name != "two_factor_bypass" {
+		t.Errorf("Expected Name 'two_factor_bypass', got '%s'", info.Name)
+	}
+}
+
+// TestTwoFactorBypass_Flow tests the full login → verify → protected flow
+func TestTwoFactorBypass_Flow(t *testing.T) {
+	m, state := &TwoFactorBypass{}, NewState()
+
+	sessionID, code := otpLogin(t, m, state, "rick", nil)
+	if len(code) != 4 {
+		t.Fatalf("Expected 4-digit code, got '%s'", code)
+	}
+
+	// Protected resource is denied before verification
+	result, _ := m.Handle(&HandlerContext{
+		Config:  map[string]interface{}{"step": "protected"},
+		State:   state,
+		Request: otpRequest(sessionID),
+	})
+	if result.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 before verification, got %d", result.StatusCode)
+	}
+
+	result, _ = m.Handle(&HandlerContext{
+		Input:   code,
+		Config:  map[string]interface{}{"step": "verify"},
+		State:   state,
+		Request: otpRequest(sessionID),
+	})
+	if result.Data.(map[string]interface{})["verified"] != true {
+		t.Fatalf("Expected verification to succeed, got %v", result.Data)
+	}
+
+	result, _ = m.Handle(&HandlerContext{
+		Config:  map[string]interface{}{"step": "protected", "secret": "FLAG{otp}"},
+		State:   state,
+		Request: otpRequest(sessionID),
+	})
+	data := result.Data.(map[string]interface{})
+	if data["secret"] != "FLAG{otp}" {
+		t.Errorf("Expected access to protected resource, got %v", data)
+	}
+}
+
+// TestTwoFactorBypass_BruteForce tests that codes can be brute forced without rate limiting
+func TestTwoFactorBypass_BruteForce(t *testing.T) {
+	m, state := &TwoFactorBypass{}, NewState()
+	sessionID, _ := otpLogin(t, m, state, "morty", nil)
+
+	verified := false
+	for i := 0; i < 10000 && !verified; i++ {
+		result, _ := m.Handle(&HandlerContext{
+			Input:   fmt.Sprintf("%04d", i),
+			Config:  map[string]interface{}{"step": "verify"},
+			State:   state,
+			Request: otpRequest(sessionID),
+		})
+		verified = result.Data.(map[string]interface{})["verified"] == true
+	}
+
+	if !verified {
+		t.Error("Expected brute force of 4-digit code to succeed")
+	}
+}
+
+// TestTwoFactorBypass_RateLimit tests that max_attempts stops brute forcing
+func TestTwoFactorBypass_RateLimit(t *testing.T) {
+	m, state := &TwoFactorBypass{}, NewState()
+	sessionID, code := otpLogin(t, m, state, "beth", nil)
+
+	wrong := "0000"
+	if code == wrong {
+		wrong = "1111"
+	}
+
+	config := map[string]interface{}{"step": "verify", "max_attempts": 3}
+	for i := 0; i < 3; i++ {
+		m.Handle(&HandlerContext{Input: wrong, Config: config, State: state, Request: otpRequest(sessionID)})
+	}
+
+	result, _ := m.Handle(&HandlerContext{Input: code, Config: config, State: state, Request: otpRequest(sessionID)})
+	if result.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 after max attempts, got %d", result.StatusCode)
+	}
+}
+
+// TestTwoFactorBypass_ResponseManipulation tests the client-trusted verified flag
+func TestTwoFactorBypass_ResponseManipulation(t *testing.T) {
+	m, state := &TwoFactorBypass{}, NewState()
+	sessionID, _ := otpLogin(t, m, state, "summer", nil)
+
+	req := otpRequest(sessionID)
+	req.AddCookie(&http.Cookie{Name: "verified", Value: "true"})
+
+	result, _ := m.Handle(&HandlerContext{
+		Config:  map[string]interface{}{"step": "protected"},
+		State:   state,
+		Request: req,
+	})
+	if result.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected client flag to be ignored by default, got %d", result.StatusCode)
+	}
+
+	result, _ = m.Handle(&HandlerContext{
+		Config:  map[string]interface{}{"step": "protected", "trust_client_verified": true},
+		State:   state,
+		Request: req,
+	})
+	data := result.Data.(map[string]interface{})
+	if data["verified_source"] != "client" {
+		t.Errorf("Expected client-trusted verification, got %v", data)
+	}
+}
+
+// TestTwoFactorBypass_CodeReuse tests that a used code remains valid when reuse is allowed
+func TestTwoFactorBypass_CodeReuse(t *testing.T) {
+	for _, allowReuse := range []bool{false, true} {
+		m, state := &TwoFactorBypass{}, NewState()
+		config := map[string]interface{}{"allow_code_reuse": allowReuse, "code_length": 6}
+
+		first, code := otpLogin(t, m, state, "jerry", config)
+		m.Handle(&HandlerContext{
+			Input:   code,
+			Config:  map[string]interface{}{"step": "verify", "allow_code_reuse": allowReuse},
+			State:   state,
+			Request: otpRequest(first),
+		})
+
+		second, _ := otpLogin(t, m, state, "jerry", config)
+		result, _ := m.Handle(&HandlerContext{
+			Input:   code,
+			Config:  map[string]interface{}{"step": "verify", "allow_code_reuse": allowReuse},
+			State:   state,
+			Request: otpRequest(second),
+		})
+
+		reused := result.Data.(map[string]interface{})["verified"] == true
+		if reused != allowReuse {
+			t.Errorf("allow_code_reuse=%v: expected reuse=%v, got %v", allowReuse, allowReuse, reused)
+		}
+	}
+}
+
+// TestTwoFactorBypass_StatePerApp tests that apps don't see each other's logins
+func TestTwoFactorBypass_StatePerApp(t *testing.T) {
+	m, state := &TwoFactorBypass{}, NewState()
+	sessionID, code := otpLogin(t, m, state, "rick", nil)
+
+	result, _ := m.Handle(&HandlerContext{
+		Input:   code,
+		Config:  map[string]interface{}{"step": "verify"},
+		State:   NewState(),
+		Request: otpRequest(sessionID),
+	})
+	if result.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected another app not to know the login, got %d", result.StatusCode)
+	}
+}
@@ -0,0 +1,163 @@
+package modules
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// VHostTakeover implements the vhost_takeover vulnerability module
+type VHostTakeover struct{}
+
+// init registers the module
+func init() {
+	Register(&VHostTakeover{})
+}
+
+// takeoverFingerprint is the response a provider returns for an unclaimed resource
+type takeoverFingerprint struct {
+	StatusCode int
+	Headers    map[string]string
+	CNAME      string
+	Body       string
+}
+
+// takeoverFingerprints holds dangling-CNAME fingerprints keyed by provider.
+// {host} and {bucket} are replaced at request time.
+var takeoverFingerprints = map[string]takeoverFingerprint{
+	"github_pages": {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Server": "GitHub.com", "X-GitHub-Request-Id": "A1B2:3C4D:5E6F:7A8B:9C0D"},
+		CNAME:      "flawfactory.github.io",
+		Body: `<h1>404</h1>
+<p><strong>There isn't a GitHub Pages site here.</strong></p>
+<p>If you're trying to publish one, <a href="https://help.github.com/pages/">read the full documentation</a> to learn how to set up <strong>GitHub Pages</strong> for your repository, organization, or user account.</p>`,
+	},
+	"s3": {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Server": "AmazonS3", "X-Amz-Request-Id": "4F2A9C1E7B3D8E60"},
+		CNAME:      "{bucket}.s3.amazonaws.com",
+		Body: `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message><BucketName>{bucket}</BucketName><RequestId>4F2A9C1E7B3D8E60</RequestId><HostId>flawfactory/hostid</HostId></Error>`,
+	},
+	"heroku": {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Server": "Cowboy", "Via": "1.1 vegur"},
+		CNAME:      "flawfactory-app.herokuapp.com",
+		Body: `<iframe src="//www.herokucdn.com/error-pages/no-such-app.html"></iframe>
+<title>No such app</title>
+<p>There is no app configured at that hostname.</p>`,
+	},
+	"azure": {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Server": "Microsoft-IIS/10.0"},
+		CNAME:      "flawfactory.azurewebsites.net",
+		Body: `<title>404 Web Site not found.</title>
+<h1>Error 404 - Web app not found.</h1>
+<p>The web app you have attempted to reach is not available in this Microsoft Azure App Service region.</p>`,
+	},
+	"shopify": {
+		StatusCode: http.StatusNotFound,
+		Headers:    map[string]string{"Server": "nginx", "X-ShopId": "0"},
+		CNAME:      "shops.myshopify.com",
+		Body: `<title>Create an Ecommerce Website and Sell Online! Ecommerce Software by Shopify</title>
+<p>Sorry, this shop is currently unavailable.</p>`,
+	},
+	"fastly": {
+		StatusCode: http.StatusInternalServerError,
+		Headers:    map[string]string{"Server": "Varnish", "X-Served-By": "cache-iad-flawfactory"},
+		CNAME:      "flawfactory.global.ssl.fastly.net",
+		Body:       "Fastly error: unknown domain: {host}. Please check that this domain has been added to a service.",
+	},
+}
+
+// Info returns module metadata
+func (m *VHostTakeover) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "vhost_takeover",
+		Description: "Subdomain Takeover - virtual hosts with dangling CNAMEs return unclaimed-resource fingerprints",
+		SupportedPlacements: []string{
+			"header",
+			"query_param",
+		},
+		RequiresSink: "", // No sink needed
+		ValidVariants: map[string][]string{
+			"provider": {"github_pages", "s3", "heroku", "azure", "shopify", "fastly", "claimed"},
+		},
+		ConfigKeys: []string{"bucket_name", "cname", "content"},
+		Options: map[string]Option{
+			"provider":    {Description: "Provider whose unclaimed-resource response the host returns: github_pages, s3, heroku, azure, shopify or fastly; claimed serves content instead", Default: "github_pages"},
+			"cname":       {Description: "Dangling CNAME reported in X-FlawFactory-CNAME, with {host} and {bucket} replaced", Default: "the provider's"},
+			"bucket_name": {Description: "Bucket named in the s3 response", Default: "the host"},
+			"content":     {Description: "For claimed, the page served", Default: "a welcome page"},
+		},
+		Example: `- path: /
+  method: GET
+  host: docs.example.local
+  response_type: html
+  vulnerabilities:
+    - type: vhost_takeover
+      placement: header
+      param: Host
+      config:
+        provider: github_pages
+`,
+		Payloads: []string{"Host: docs.example.local", "Host: assets.example.local"},
+	}
+}
+
+// Handle returns the provider fingerprint for the requested virtual host
+func (m *VHostTakeover) Handle(ctx *HandlerContext) (*Result, error) {
+	provider := ctx.GetConfigString("provider", "github_pages")
+
+	host := ctx.Input
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" && ctx.Request != nil {
+		host = ctx.Request.Host
+	}
+
+	// A claimed host serves its normal content - the secure comparison
+	if provider == "claimed" {
+		content := ctx.GetConfigString("content", fmt.Sprintf("<h1>Welcome to %s</h1>", host))
+		result := NewResult(map[string]interface{}{
+			"host":    host,
+			"claimed": true,
+		})
+		result.RawOutput = []byte(content)
+		return result, nil
+	}
+
+	fp, ok := takeoverFingerprints[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider '%s' for vhost_takeover", provider)
+	}
+
+	// Bucket names default to the host, as with S3 static website hosting
+	bucket := ctx.GetConfigString("bucket_name", host)
+	replacer := strings.NewReplacer("{host}", host, "{bucket}", bucket)
+	cname := replacer.Replace(ctx.GetConfigString("cname", fp.CNAME))
+
+	if ctx.ResponseWriter != nil {
+		for k, v := range fp.Headers {
+			ctx.ResponseWriter.Header().Set(k, v)
+		}
+		// Hint for recon tooling - real DNS would expose this as a CNAME record
+		ctx.ResponseWriter.Header().Set("X-FlawFactory-CNAME", cname)
+	}
+
+	body := replacer.Replace(fp.Body)
+	result := NewResult(map[string]interface{}{
+		"host":     host,
+		"provider": provider,
+		"cname":    cname,
+		"claimed":  false,
+		"body":     body,
+	})
+	result.RawOutput = []byte(body)
+	result.StatusCode = fp.StatusCode
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package modules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestVHostTakeover_Info tests module metadata
+func TestVHostTakeover_Info(t *testing.T) {
+	m := &VHostTakeover{}
+	info := m.Info()
+
+	if info.Name != "vhost_takeover" {
+		t.Errorf("Expected Name 'vhost_takeover', got '%s'", info.Name)
+	}
+}
+
+// TestVHostTakeover_Fingerprints tests the fingerprint for each provider
+func TestVHostTakeover_Fingerprints(t *testing.T) {
+	tests := []struct {
+		provider    string
+		fingerprint string
+		status      int
+	}{
+		{"github_pages", "There isn't a GitHub Pages site here.", http.StatusNotFound},
+		{"s3", "<BucketName>assets.example.com</BucketName>", http.StatusNotFound},
+		{"heroku", "No such app", http.StatusNotFound},
+		{"azure", "Web app not found", http.StatusNotFound},
+		{"shopify", "Sorry, this shop is currently unavailable.", http.StatusNotFound},
+		{"fastly", "unknown domain: assets.example.com", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			m := &VHostTakeover{}
+			rec := httptest.NewRecorder()
+
+			result, err := m.Handle(&HandlerContext{
+				Input:          "assets.example.com:8098",
+				Config:         map[string]interface{}{"provider": tt.provider},
+				ResponseWriter: rec,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !strings.Contains(string(result.RawOutput), tt.fingerprint) {
+				t.Errorf("Expected fingerprint '%s', got '%s'", tt.fingerprint, result.RawOutput)
+			}
+			if result.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, result.StatusCode)
+			}
+			if rec.Header().Get("X-FlawFactory-CNAME") == "" {
+				t.Error("Expected CNAME hint header")
+			}
+		})
+	}
+}
+
+// TestVHostTakeover_Claimed tests that claimed hosts serve normal content
+func TestVHostTakeover_Claimed(t *testing.T) {
+	m := &VHostTakeover{}
+
+	result, err := m.Handle(&HandlerContext{
+		Input:  "www.example.com",
+		Config: map[string]interface{}{"provider": "claimed"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.StatusCode != 0 || !strings.Contains(string(result.RawOutput), "www.example.com") {
+		t.Errorf("Expected normal content, got %d '%s'", result.StatusCode, result.RawOutput)
+	}
+}
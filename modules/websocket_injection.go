@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WebSocketInjection implements the websocket_injection vulnerability module
+type WebSocketInjection struct{}
+
+// init registers the module
+func init() {
+	Register(&WebSocketInjection{})
+}
+
+// websocketSinkModules maps the sink option to the module that emulates it
+var websocketSinkModules = map[string]string{
+	"sql":     "sql_injection",
+	"command": "command_injection",
+	"xss":     "xss_reflected",
+}
+
+// Info returns module metadata
+func (m *WebSocketInjection) Info() ModuleInfo {
+	return ModuleInfo{
+		Name:        "websocket_injection",
+		Description: "WebSocket Injection - message fields flow unsanitized into SQL, command or HTML sinks",
+		SupportedPlacements: []string{
+			"ws_message",
+			"query_param",
+			"header",
+			"cookie",
+		},
+		RequiresSink: "", // Depends on the configured sink
+		ValidVariants: map[string][]string{
+			"sink": {"sql", "command", "xss"},
+		},
+		ConfigKeys: websocketConfigKeys(),
+		Options:    websocketOptions(),
+		Example: `- path: /ws/history
+  method: GET
+  websocket: true
+  response_type: json
+  vulnerabilities:
+    - type: websocket_injection
+      placement: ws_message
+      param: room
+      config:
+        sink: sql
+        query_template: "SELECT author, body FROM messages WHERE room = {input}"
+`,
+		Payloads: []string{`{"room": "1 OR 1=1"}`, `{"host": "127.0.0.1; id"}`, `{"text": "<img src=x onerror=alert(1)>"}`},
+	}
+}
+
+// websocketOptions documents sink and the options handed through, naming the
+// modules each one goes to
+func websocketOptions() map[string]Option {
+	options := map[string]Option{
+		"sink": {Description: "Where message fields flow: sql (as sql_injection), command (as command_injection) or xss (as xss_reflected)", Default: "sql"},
+	}
+	users := map[string][]string{}
+	for _, module := range []Module{&SQLInjection{}, &CommandInjection{}, &XSSReflected{}} {
+		info := module.Info()
+		for key, option := range info.Options {
+			users[key] = append(users[key], info.Name)
+			option.Description = "As for " + info.Name + ": " + option.Description
+			options[key] = option
+		}
+	}
+	for key, names := range users {
+		if len(names) > 1 {
+			options[key] = Option{Description: "As for " + strings.Join(names, " and ") + ", depending on sink"}
+		}
+	}
+	return options
+}
+
+// websocketConfigKeys returns the config keys of the modules the sinks delegate
+// to, since the remaining config is handed through to them. The modules are
+// created directly, as Info is called while the registry is locked.
+func websocketConfigKeys() []string {
+	var delegated ModuleInfo
+	for _, module := range []Module{&SQLInjection{}, &CommandInjection{}, &XSSReflected{}} {
+		delegated.ConfigKeys = append(delegated.ConfigKeys, ConfigKeys(module.Info())...)
+	}
+	return ConfigKeys(delegated)
+}
+
+// Handle passes the message input to the emulation path for the configured sink.
+// The remaining config (query_template, base_command, context, ...) is handed
+// through unchanged, so it accepts the same options as the underlying module.
+func (m *WebSocketInjection) Handle(ctx *HandlerContext) (*Result, error) {
+	sink := ctx.GetConfigString("sink", "sql")
+
+	moduleName, ok := websocketSinkModules[sink]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sink '%s' for websocket_injection", sink)
+	}
+
+	target, err := Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	return target.Handle(ctx)
+}
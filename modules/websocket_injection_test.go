@@ -0,0 +1,74 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestWebSocketInjection_Info tests module metadata
+func TestWebSocketInjection_Info(t *testing.T) {
+	m := &WebSocketInjection{}
+	info := m.Info()
+
+	if info.Name != "websocket_injection" {
+		t.Errorf("Expected Name 'websocket_injection', got '%s'", info.Name)
+	}
+	if info.SupportedPlacements[0] != "ws_message" {
+		t.Errorf("Expected ws_message placement, got %v", info.SupportedPlacements)
+	}
+}
+
+// TestWebSocketInjection_SQL tests that message input reaches the SQL sink
+func TestWebSocketInjection_SQL(t *testing.T) {
+	m := &WebSocketInjection{}
+	var executed string
+
+	sink := &MockSQLiteSinkIDOR{
+		QueryFunc: func(query string) ([]map[string]interface{}, error) {
+			executed = query
+			return []map[string]interface{}{{"id": "1"}}, nil
+		},
+	}
+
+	_, err := m.Handle(&HandlerContext{
+		Input: "1 OR 1=1",
+		Config: map[string]interface{}{
+			"sink":           "sql",
+			"query_template": "SELECT * FROM messages WHERE room = {input}",
+		},
+		Sinks: &SinkContext{SQLite: sink},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if executed != "SELECT * FROM messages WHERE room = 1 OR 1=1" {
+		t.Errorf("Expected injected query, got '%s'", executed)
+	}
+}
+
+// TestWebSocketInjection_XSS tests that message input is reflected unescaped
+func TestWebSocketInjection_XSS(t *testing.T) {
+	m := &WebSocketInjection{}
+
+	result, err := m.Handle(&HandlerContext{
+		Input:  "<img src=x onerror=alert(1)>",
+		Config: map[string]interface{}{"sink": "xss", "template": "<p>{input}</p>"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(string(result.RawOutput), "<img src=x onerror=alert(1)>") {
+		t.Errorf("Expected payload to be reflected, got '%s'", result.RawOutput)
+	}
+}
+
+// TestWebSocketInjection_InvalidSink tests error for an unknown sink
+func TestWebSocketInjection_InvalidSink(t *testing.T) {
+	m := &WebSocketInjection{}
+
+	if _, err := m.Handle(&HandlerContext{Config: map[string]interface{}{"sink": "ldap"}}); err == nil {
+		t.Error("Expected error for unsupported sink")
+	}
+}
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"fmt"
+	"html"
 	"strings"
 )
 
@@ -17,7 +18,7 @@ func init() {
 func (m *XSSReflected) Info() ModuleInfo {
 	return ModuleInfo{
 		Name:        "xss_reflected",
-		Description: "Reflected Cross-Site Scripting with multiple contexts (body, attribute, script)",
+		Description: "Reflected Cross-Site Scripting with multiple contexts (body, attribute, script, js_string, url, css)",
 		SupportedPlacements: []string{
 			"query_param",
 			"path_param",
@@ -27,9 +28,32 @@ func (m *XSSReflected) Info() ModuleInfo {
 		},
 		RequiresSink: "", // No sink needed
 		ValidVariants: map[string][]string{
-			"context":  {"body", "attribute", "script"},
-			"encoding": {"none", "incomplete_html", "incomplete_js", "weak_encode"},
+			"context":  {"body", "attribute", "script", "js_string", "url", "css"},
+			"encoding": {"none", "incomplete_html", "incomplete_js", "weak_encode", "html_entities", "js_escape", "url_scheme_filter", "css_strip_tags"},
 		},
+		ConfigKeys: []string{"full_page", "template"},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"context": "body", "encoding": "none"},
+			"medium": {"context": "body", "encoding": "weak_encode"},
+			"hard":   {"context": "attribute", "encoding": "incomplete_html"},
+		},
+		Options: map[string]Option{
+			"context":   {Description: "Where the input is reflected: body (HTML), attribute (a quoted value), script (a script block), js_string (a quoted JS string in an event handler), url (an href) or css (a style block)", Default: "body"},
+			"encoding":  {Description: "Applied to the input first: none, incomplete_html (encodes < and > only), incomplete_js (escapes single quotes only), weak_encode (strips lowercase <script> tags), html_entities, js_escape (escapes quotes but not </script>), url_scheme_filter (blocks URLs starting with javascript:) or css_strip_tags (removes < and >)", Default: "none"},
+			"template":  {Description: "HTML to reflect the input into, with {input} in place of the context's built-in snippet"},
+			"full_page": {Description: "Wrap the output in a complete HTML page", Default: "false"},
+		},
+		Example: `- path: /search
+  method: GET
+  response_type: html
+  vulnerabilities:
+    - type: xss_reflected
+      placement: query_param
+      param: q
+      config:
+        context: body
+`,
+		Payloads: []string{"<script>alert(1)</script>", "<img src=x onerror=alert(1)>", "\" autofocus onfocus=alert(1) x=\""},
 	}
 }
 
@@ -39,6 +63,7 @@ func (m *XSSReflected) Handle(ctx *HandlerContext) (*Result, error) {
 	context := ctx.GetConfigString("context", "body")
 	encoding := ctx.GetConfigString("encoding", "none")
 	template := ctx.GetConfigString("template", "")
+	fullPage := ctx.GetConfigBool("full_page", false)
 
 	input := ctx.Input
 
@@ -55,14 +80,25 @@ func (m *XSSReflected) Handle(ctx *HandlerContext) (*Result, error) {
 		output = m.handleAttributeContext(input, template)
 	case "script":
 		output = m.handleScriptContext(input, template)
+	case "js_string":
+		output = m.handleJSStringContext(input, template)
+	case "url":
+		output = m.handleURLContext(input, template)
+	case "css":
+		output = m.handleCSSContext(input, template)
 	default:
 		output = m.handleBodyContext(input, template)
 	}
 
+	if fullPage {
+		output = renderXSSPage(context, output)
+	}
+
 	result := NewResult(map[string]interface{}{
 		"reflected": output,
 		"input":     ctx.Input,
 		"context":   context,
+		"encoding":  encoding,
 	})
 
 	// Set raw output for HTML responses
@@ -110,6 +146,70 @@ func (m *XSSReflected) handleScriptContext(input, template string) string {
 </script>`, input, input)
 }
 
+// handleJSStringContext reflects input in a JS string inside an event handler.
+// The browser HTML-decodes the attribute before running it, so entity encoding does not help here.
+func (m *XSSReflected) handleJSStringContext(input, template string) string {
+	if template != "" {
+		return strings.ReplaceAll(template, "{input}", input)
+	}
+	return fmt.Sprintf(`<div class="result">
+    <button onclick="trackSearch('%s')">Save search</button>
+    <a href="#" onmouseover="showTooltip('Results for %s')">Hover for details</a>
+</div>`, input, input)
+}
+
+// handleURLContext reflects input as a full URL in href/src attributes
+func (m *XSSReflected) handleURLContext(input, template string) string {
+	if template != "" {
+		return strings.ReplaceAll(template, "{input}", input)
+	}
+	return fmt.Sprintf(`<div class="profile">
+    <h2>User Profile</h2>
+    <p>Website: <a href="%s">Visit my homepage</a></p>
+    <iframe src="%s" width="400" height="200" title="Homepage preview"></iframe>
+</div>`, input, input)
+}
+
+// handleCSSContext reflects input inside a style block and a style attribute
+func (m *XSSReflected) handleCSSContext(input, template string) string {
+	if template != "" {
+		return strings.ReplaceAll(template, "{input}", input)
+	}
+	return fmt.Sprintf(`<style>
+    .profile-banner { background-color: %s; padding: 20px; }
+</style>
+<div class="profile-banner" style="color: %s">
+    <h2>Your theme has been saved</h2>
+</div>`, input, input)
+}
+
+// renderXSSPage wraps reflected output in a complete HTML document.
+// Script and style snippets are placed in the head, as a real page would.
+func renderXSSPage(context, output string) string {
+	head, body := "", output
+	switch context {
+	case "script":
+		head, body = output, "<h1>Search</h1>"
+	case "css":
+		// The style block goes in the head, the styled element stays in the body
+		if idx := strings.Index(output, "</style>"); strings.HasPrefix(output, "<style>") && idx != -1 {
+			head, body = output[:idx+len("</style>")], strings.TrimSpace(output[idx+len("</style>"):])
+		}
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>FlawFactory - %s context</title>
+%s
+</head>
+<body>
+%s
+</body>
+</html>`, context, head, body)
+}
+
 // applyXSSEncoding applies encoding/filtering to input
 func applyXSSEncoding(input, encoding string) string {
 	switch encoding {
@@ -133,6 +233,27 @@ func applyXSSEncoding(input, encoding string) string {
 		input = strings.ReplaceAll(input, "</script>", "")
 		input = strings.ReplaceAll(input, "<script", "")
 		return input
+	case "html_entities":
+		// Full HTML entity encoding - safe in body and quoted attributes, but
+		// javascript: URLs and entity-decoded event handlers are still exploitable
+		return html.EscapeString(input)
+	case "js_escape":
+		// Escapes quotes and backslashes but not "</script>", so the
+		// script block can still be closed from inside a string
+		return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`, "\n", `\n`).Replace(input)
+	case "url_scheme_filter":
+		// Blocks URLs starting with javascript: - bypass with leading whitespace,
+		// tabs inside the scheme or HTML entities (e.g. javascript&colon;)
+		if strings.HasPrefix(strings.ToLower(input), "javascript:") {
+			return "#blocked"
+		}
+		return strings.ReplaceAll(input, `"`, "&quot;")
+	case "css_strip_tags":
+		// Removes angle brackets so </style> cannot be used, but CSS
+		// injection (url() exfiltration, attribute selectors) still works
+		input = strings.ReplaceAll(input, "<", "")
+		input = strings.ReplaceAll(input, ">", "")
+		return input
 	default:
 		return input
 	}
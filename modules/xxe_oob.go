@@ -0,0 +1,139 @@
+package modules
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// OOBInteraction records a real request made to an out-of-band listener
+type OOBInteraction struct {
+	URI        string `json:"uri"`
+	Source     string `json:"source"` // entity name, or "dtd:<uri>" for requests made by a fetched DTD
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// oobSystemPattern finds SYSTEM identifiers inside an external DTD, including
+// ones nested in entity values with the % escaped as &#x25; or &#37;
+var oobSystemPattern = regexp.MustCompile(`(?i)SYSTEM\s+["']([^"']+)["']`)
+
+// oobParamRefPattern matches parameter entity references such as %file;
+var oobParamRefPattern = regexp.MustCompile(`%(\w+);`)
+
+// resolveOOBEntities actually requests external entity URLs that point at a
+// configured out-of-band listener, so blind XXE can be confirmed via callbacks.
+// External DTDs fetched this way are processed one level deep: their SYSTEM
+// URLs are requested with parameter entity references (e.g. %file;) expanded.
+func resolveOOBEntities(result *XXEResult, xmlContent string, ctx *HandlerContext) {
+	if ctx.Sinks == nil || ctx.Sinks.HTTP == nil {
+		result.Error = "oob_resolution is enabled but the HTTP sink is not available"
+		return
+	}
+
+	oobHosts := oobTargets(ctx)
+	maxRequests := ctx.GetConfigInt("max_oob_requests", 5)
+	allowFileRead := ctx.GetConfigBool("allow_file_read", true)
+
+	// Parameter entity values available for expansion in exfiltration URLs
+	params := collectParameterValues(xmlContent, allowFileRead, ctx)
+
+	requested := make(map[string]bool)
+	fetch := func(uri, source string) string {
+		if len(result.OOBInteractions) >= maxRequests || requested[uri] || !isOOBTarget(uri, oobHosts) {
+			return ""
+		}
+		requested[uri] = true
+
+		interaction := OOBInteraction{URI: uri, Source: source}
+		resp, err := ctx.Sinks.HTTP.Fetch(uri)
+		if err != nil {
+			interaction.Error = err.Error()
+			result.OOBInteractions = append(result.OOBInteractions, interaction)
+			return ""
+		}
+		interaction.StatusCode = resp.StatusCode
+		result.OOBInteractions = append(result.OOBInteractions, interaction)
+		return resp.Body
+	}
+
+	for _, entity := range result.ExternalEntities {
+		protocol := detectProtocol(entity.URI)
+		if protocol != "http" && protocol != "https" {
+			continue
+		}
+
+		body := fetch(expandParameterRefs(entity.URI, params), entity.Name)
+		if body == "" || (entity.Type != "PARAMETER" && entity.Name != "DOCTYPE") {
+			continue
+		}
+
+		// The response is an external DTD - merge its definitions and follow its URLs
+		dtd := strings.NewReplacer("&#x25;", "%", "&#37;", "%").Replace(body)
+		for name, value := range collectParameterValues(dtd, allowFileRead, ctx) {
+			if _, exists := params[name]; !exists {
+				params[name] = value
+			}
+		}
+		for _, match := range oobSystemPattern.FindAllStringSubmatch(dtd, -1) {
+			fetch(expandParameterRefs(match[1], params), "dtd:"+entity.URI)
+		}
+	}
+}
+
+// collectParameterValues maps parameter entity names to their (emulated) values
+func collectParameterValues(content string, allowFileRead bool, ctx *HandlerContext) map[string]string {
+	values := make(map[string]string)
+
+	paramPattern := regexp.MustCompile(`(?i)<!ENTITY\s+%\s*(\w+)\s+(?:SYSTEM\s+["']([^"']+)["']|["']([^"']*)["'])`)
+	for _, match := range paramPattern.FindAllStringSubmatch(content, -1) {
+		name, uri, literal := match[1], match[2], match[3]
+		switch {
+		case uri != "" && detectProtocol(uri) == "file":
+			path := strings.TrimPrefix(strings.TrimPrefix(uri, "file://"), "/")
+			values[name] = simulateFileRead(path, allowFileRead, ctx)
+		case uri == "":
+			values[name] = literal
+		}
+	}
+
+	return values
+}
+
+// expandParameterRefs replaces %name; references with URL-escaped entity values
+func expandParameterRefs(uri string, params map[string]string) string {
+	return oobParamRefPattern.ReplaceAllStringFunc(uri, func(ref string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(ref, "%"), ";")
+		if value, ok := params[name]; ok {
+			return url.QueryEscape(value)
+		}
+		return ref
+	})
+}
+
+// oobTargets returns the configured oob_hosts plus the built-in listener's hosts
+func oobTargets(ctx *HandlerContext) []string {
+	hosts := getStringSlice(ctx.Config, "oob_hosts", nil)
+	if ctx.Sinks != nil && ctx.Sinks.OOB != nil {
+		hosts = append(hosts, ctx.Sinks.OOB.Hosts()...)
+	}
+	return hosts
+}
+
+// isOOBTarget reports whether a URL points at one of the configured OOB listener hosts
+func isOOBTarget(uri string, oobHosts []string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Host)
+	for _, allowed := range oobHosts {
+		allowed = strings.ToLower(allowed)
+		// Match exact host[:port] or any subdomain of the listener domain
+		if host == allowed || parsed.Hostname() == allowed || strings.HasSuffix(parsed.Hostname(), "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
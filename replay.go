@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/builder"
+	"github.com/RIZZZIOM/FlawFactory/config"
+	"github.com/RIZZZIOM/FlawFactory/logger"
+)
+
+// replayTimeout bounds each request replay sends
+const replayTimeout = 30 * time.Second
+
+// maxReplayLine bounds a log line replay reads, bodies included
+const maxReplayLine = 16 << 20
+
+// replaySkippedHeaders aren't replayed: they describe the logged connection
+// and body encoding, and are set again for the new ones
+var replaySkippedHeaders = []string{
+	"Accept-Encoding", "Connection", "Content-Length", "Host", "Keep-Alive",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// replayFilterKeys are the fields --filter matches logged requests by
+var replayFilterKeys = []string{"method", "path", "status", "attack", "module", "client", "id"}
+
+// replayResult is what replaying one logged request got back
+type replayResult struct {
+	RequestID    string `json:"request_id,omitempty"` // The logged request's
+	Method       string `json:"method"`
+	URL          string `json:"url"`
+	LoggedStatus int    `json:"logged_status"`
+	Status       int    `json:"status,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"` // The body was cut in the log, so only its start was sent
+}
+
+func replayCommand() {
+	replayFlags := flag.NewFlagSet("replay", flag.ExitOnError)
+	logFile := replayFlags.String("log", "", "Request log to replay, in the json, ecs or cef format (required; .gz for a rotated one)")
+	logFileShort := replayFlags.String("l", "", "Request log to replay (shorthand)")
+	var filters replayFilters
+	replayFlags.Var(&filters, "filter", "Replay only requests matching key=value, e.g. attack=ssrf or status=5xx (repeatable; keys: "+strings.Join(replayFilterKeys, ", ")+")")
+	target := replayFlags.String("target", "", "URL of the running lab to replay against")
+	var configFiles configFlags
+	replayFlags.Var(&configFiles, "config", "Start a fresh lab from this YAML config file to replay against (repeat to layer overlays over it)")
+	replayFlags.Var(&configFiles, "c", "Start a fresh lab from this YAML config file (shorthand)")
+	preset := replayFlags.String("preset", "", "Start a fresh built-in lab to replay against")
+	var sets setFlags
+	replayFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	port := replayFlags.Int("port", 0, "Port for the fresh lab (default: a free one)")
+	portShort := replayFlags.Int("p", 0, "Port for the fresh lab (shorthand)")
+	var headers setFlags
+	replayFlags.Var(&headers, "header", "Add or replace a header as \"Name: value\" on every request (repeatable)")
+	host := replayFlags.String("host", "", "Host header to send, in place of the logged one")
+	delay := replayFlags.Duration("delay", 0, "Wait between requests, e.g. 200ms")
+	limit := replayFlags.Int("limit", 0, "Replay at most this many requests")
+	verbose := replayFlags.Bool("verbose", false, "Show the fresh lab's server log")
+	asJSON := replayFlags.Bool("json", false, "Print the results as JSON")
+
+	replayFlags.Parse(os.Args[2:])
+
+	path := *logFile
+	if path == "" {
+		path = *logFileShort
+	}
+	portOverride := *port
+	if portOverride == 0 {
+		portOverride = *portShort
+	}
+	fresh := len(configFiles) > 0 || *preset != ""
+	if path == "" {
+		fmt.Printf("\n  %s✗ Error:%s -log flag is required\n\n", colorRed, colorReset)
+		replayFlags.PrintDefaults()
+		os.Exit(1)
+	}
+	if fresh == (*target != "") {
+		fmt.Printf("\n  %s✗ Error:%s replay against either a running lab with --target or a fresh one with -config or -preset\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+	extra, err := parseReplayHeaders(headers)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+
+	entries, skipped, err := readReplayLog(path, filters)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+
+	base, stop := *target, func() {}
+	if fresh {
+		cfg, err := loadConfig(configFiles, *preset, sets, false)
+		if err != nil {
+			printConfigError(strings.Join(configFiles, ", "), err)
+			os.Exit(1)
+		}
+		if !*verbose {
+			log.SetOutput(io.Discard)
+		}
+		base, stop, err = startReplayLab(cfg, portOverride)
+		if err != nil {
+			fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+			os.Exit(1)
+		}
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		fmt.Printf("\n  %s✗ Error:%s --target must be a URL like http://localhost:8080\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+
+	client := &http.Client{
+		Timeout: replayTimeout,
+		// Labs use self-signed certificates, and redirects are part of what's compared
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !*asJSON {
+		fmt.Printf("\n  %sREPLAY%s  %d requests from %s to %s\n\n", colorYellow, colorReset, len(entries), path, baseURL)
+	}
+	results := make([]replayResult, 0, len(entries))
+	for i, entry := range entries {
+		if i > 0 && *delay > 0 {
+			time.Sleep(*delay)
+		}
+		result := replayEntry(client, baseURL, entry, extra, *host)
+		results = append(results, result)
+		if !*asJSON {
+			printReplayResult(result)
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printReplaySummary(results, skipped)
+	}
+	stop()
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readReplayLog reads the requests logged in path that match the filters, in
+// order, and counts the lines that aren't request log entries
+func readReplayLog(path string, filters replayFilters) ([]*logger.RequestLog, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var entries []*logger.RequestLog
+	skipped := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxReplayLine)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		entry, err := logger.ParseEntry(scanner.Bytes())
+		if err != nil {
+			skipped++
+			continue
+		}
+		if filters.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, skipped, nil
+}
+
+// startReplayLab starts a fresh lab, without a request log, and returns its
+// URL and how to stop it
+func startReplayLab(cfg *config.Config, port int) (string, func(), error) {
+	apps := cfg.Applications()
+	if len(apps) > 1 {
+		return "", nil, fmt.Errorf("the config has %d apps; replay against one of them with --target", len(apps))
+	}
+	lab, stop, err := startLab(apps[0], port)
+	if err != nil {
+		return "", nil, err
+	}
+	return labURL(lab), stop, nil
+}
+
+// startLab starts one app of a config in-process, without a request log, and
+// returns its config with the port it listens on and how to stop it. It
+// listens on port, or a free one for 0, and keeps a persistent database in
+// memory, so a lab already running from the config and its database file are
+// left alone. The endpoints' injected latency and errors are dropped, so every
+// request gets the endpoint's real answer.
+func startLab(app *config.Config, port int) (*config.Config, func(), error) {
+	lab := *app
+	lab.App.Port = port
+	// Nothing is sent to the admin API, whose default port follows the app's
+	lab.App.Admin = nil
+	lab.Endpoints = slices.Clone(app.Endpoints)
+	for i := range lab.Endpoints {
+		lab.Endpoints[i].Latency = nil
+		lab.Endpoints[i].ErrorRate = 0
+	}
+
+	b := builder.New(&lab, "")
+	b.InMemory()
+	srv, err := b.Build()
+	if err != nil {
+		b.Close()
+		return nil, nil, fmt.Errorf("failed to build server for %s: %w", app.App.Name, err)
+	}
+	if err := srv.Listen(); err != nil {
+		b.Close()
+		return nil, nil, err
+	}
+	go srv.Start()
+
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Stop(ctx)
+		b.Close()
+	}
+	if _, bound, err := net.SplitHostPort(srv.Addr()); err == nil {
+		lab.App.Port, _ = strconv.Atoi(bound)
+	}
+	return &lab, stop, nil
+}
+
+// labURL returns the URL an app is reached at from this machine, on loopback
+// when it listens on every interface
+func labURL(app *config.Config) string {
+	scheme := "http"
+	if app.App.TLS != nil && app.App.TLS.Enabled {
+		scheme = "https"
+	}
+	host := app.App.Host
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(app.App.Port))
+}
+
+// replayEntry sends a logged request again to the lab at base, with the
+// extra headers over the logged ones and host, if set, as its Host
+func replayEntry(client *http.Client, base *url.URL, entry *logger.RequestLog, extra http.Header, host string) replayResult {
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + entry.Path
+	target.RawPath = ""
+	target.RawQuery = entry.RawQuery
+	result := replayResult{
+		RequestID:    entry.RequestID,
+		Method:       entry.Method,
+		URL:          target.String(),
+		LoggedStatus: entry.StatusCode,
+		Truncated:    strings.HasSuffix(entry.Body, logger.Truncated),
+	}
+
+	body := strings.TrimSuffix(entry.Body, logger.Truncated)
+	req, err := http.NewRequest(entry.Method, result.URL, strings.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for name, value := range entry.Headers {
+		if !slices.Contains(replaySkippedHeaders, http.CanonicalHeaderKey(name)) {
+			req.Header.Set(name, value)
+		}
+	}
+	for name, values := range extra {
+		req.Header[name] = values
+	}
+	if host != "" {
+		req.Host = host
+	} else if entry.Host != "" {
+		req.Host = entry.Host
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	result.Status = resp.StatusCode
+	return result
+}
+
+// parseReplayHeaders parses --header "Name: value" flags
+func parseReplayHeaders(headers []string) (http.Header, error) {
+	parsed := make(http.Header)
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("--header %q must be \"Name: value\"", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// printReplayResult prints a replayed request's status next to the logged one
+func printReplayResult(result replayResult) {
+	request := result.Method + " " + result.URL
+	note := ""
+	if result.Truncated {
+		note = colorDim + " (body truncated in the log)" + colorReset
+	}
+	switch {
+	case result.Error != "":
+		fmt.Printf("    %s✗%s %s%s\n      %s%s%s\n", colorRed, colorReset, request, note, colorRed, result.Error, colorReset)
+	case result.Status == result.LoggedStatus:
+		fmt.Printf("    %s✓ %d%s %s%s\n", colorGreen, result.Status, colorReset, request, note)
+	default:
+		fmt.Printf("    %s≠ %d%s %s %s(logged %d)%s%s\n", colorYellow, result.Status, colorReset, request, colorDim, result.LoggedStatus, colorReset, note)
+	}
+}
+
+// printReplaySummary counts the requests answered as logged, differently, and
+// not at all
+func printReplaySummary(results []replayResult, skipped int) {
+	same, changed, failed := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			failed++
+		case result.Status == result.LoggedStatus:
+			same++
+		default:
+			changed++
+		}
+	}
+	fmt.Println()
+	fmt.Printf("  %s%d as logged%s, %s%d changed%s, %s%d failed%s\n", colorGreen, same, colorReset, colorYellow, changed, colorReset, colorRed, failed, colorReset)
+	if skipped > 0 {
+		fmt.Printf("  %s%d log lines weren't request entries and were skipped%s\n", colorDim, skipped, colorReset)
+	}
+	fmt.Println()
+}
+
+// replayFilter matches logged requests by one field. Its values, given
+// comma-separated, are alternatives.
+type replayFilter struct {
+	key    string
+	values []string
+}
+
+// replayFilters collects repeated --filter key=value flags; a request is
+// replayed when it matches them all
+type replayFilters []replayFilter
+
+func (f *replayFilters) String() string {
+	parts := make([]string, len(*f))
+	for i, filter := range *f {
+		parts[i] = filter.key + "=" + strings.Join(filter.values, ",")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f *replayFilters) Set(value string) error {
+	key, values, ok := strings.Cut(value, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !ok || values == "" {
+		return fmt.Errorf("filter %q must be key=value", value)
+	}
+	if !slices.Contains(replayFilterKeys, key) {
+		return fmt.Errorf("unknown filter key %q (use %s)", key, strings.Join(replayFilterKeys, ", "))
+	}
+	filter := replayFilter{key: key}
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			filter.values = append(filter.values, v)
+		}
+	}
+	*f = append(*f, filter)
+	return nil
+}
+
+// match reports whether an entry matches every filter
+func (f replayFilters) match(entry *logger.RequestLog) bool {
+	for _, filter := range f {
+		if !slices.ContainsFunc(filter.values, func(value string) bool {
+			return filter.matchValue(entry, value)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValue reports whether an entry's field matches one of the filter's
+// values. Paths match with * standing for anything, statuses like 5xx by
+// class, and attack=any any request with an attack.
+func (f replayFilter) matchValue(entry *logger.RequestLog, value string) bool {
+	switch f.key {
+	case "method":
+		return strings.EqualFold(entry.Method, value)
+	case "path":
+		return matchWildcard(value, entry.Path)
+	case "status":
+		status := strconv.Itoa(entry.StatusCode)
+		if len(value) == 3 && strings.EqualFold(value[1:], "xx") {
+			return status[:1] == value[:1]
+		}
+		return status == value
+	case "attack":
+		return slices.ContainsFunc(entry.Attacks, func(attack logger.Attack) bool {
+			return value == "any" || strings.EqualFold(attack.Type, value)
+		})
+	case "module":
+		return slices.ContainsFunc(entry.Attacks, func(attack logger.Attack) bool {
+			return strings.EqualFold(attack.Module, value)
+		})
+	case "client":
+		client, _, err := net.SplitHostPort(entry.RemoteAddr)
+		if err != nil {
+			client = entry.RemoteAddr
+		}
+		return client == value
+	case "id":
+		return entry.RequestID == value
+	}
+	return false
+}
+
+// matchWildcard reports whether s matches pattern, where * matches anything,
+// slashes included
+func matchWildcard(pattern, s string) bool {
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	return regexp.MustCompile(expr).MatchString(s)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/logger"
+)
+
+// TestReplay tests logged requests are filtered and sent again with their
+// method, path, query, headers and body
+func TestReplay(t *testing.T) {
+	log := `{"timestamp":"2026-01-19T05:03:54Z","request_id":"a","method":"GET","path":"/fetch","query_params":{"url":"http://169.254.169.254/"},"headers":{"User-Agent":"curl/8.5.0","Content-Length":"0"},"remote_addr":"10.0.0.7:5000","status_code":200,"response_time":"1ms","attacks":[{"module":"ssrf","type":"ssrf","payload":"http://169.254.169.254/"}]}
+not json
+{"timestamp":"2026-01-19T05:03:55Z","request_id":"b","method":"POST","path":"/api/login","headers":{"Content-Type":"application/x-www-form-urlencoded"},"body":"user=admin' --","remote_addr":"10.0.0.8:5000","status_code":500,"response_time":"1ms","attacks":[{"module":"sql_injection","type":"sql_injection","payload":"admin' --"}]}
+{"timestamp":"2026-01-19T05:03:56Z","request_id":"c","method":"GET","path":"/","headers":{},"remote_addr":"10.0.0.7:5000","status_code":200,"response_time":"1ms"}
+`
+	path := filepath.Join(t.TempDir(), "lab.json")
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	var filters replayFilters
+	filters.Set("attack=any")
+	filters.Set("path=/api/*,/fetch")
+	entries, skipped, err := readReplayLog(path, filters)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if len(entries) != 2 || skipped != 1 || entries[0].RequestID != "a" || entries[1].RequestID != "b" {
+		t.Fatalf("Expected requests a and b with 1 line skipped, got %d entries and %d skipped", len(entries), skipped)
+	}
+
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+r.UserAgent()+" "+r.Header.Get("X-Lab")+" "+string(body))
+		if r.URL.Path == "/api/login" {
+			w.WriteHeader(http.StatusOK) // Fixed since it was logged
+		}
+	}))
+	defer srv.Close()
+
+	base, _ := url.Parse(srv.URL)
+	extra, _ := parseReplayHeaders([]string{"X-Lab: replay"})
+	first := replayEntry(srv.Client(), base, entries[0], extra, "")
+	second := replayEntry(srv.Client(), base, entries[1], extra, "")
+	if first.Status != 200 || first.LoggedStatus != 200 || second.Status != 200 || second.LoggedStatus != 500 {
+		t.Errorf("Expected 200 as logged and 200 for a logged 500, got %+v and %+v", first, second)
+	}
+	expected := []string{
+		"GET /fetch?url=http%3A%2F%2F169.254.169.254%2F curl/8.5.0 replay ",
+		"POST /api/login Go-http-client/1.1 replay user=admin' --",
+	}
+	if len(received) != 2 || received[0] != expected[0] || received[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, received)
+	}
+}
+
+// TestReplayFilters tests filter values, keys and status classes
+func TestReplayFilters(t *testing.T) {
+	entry := &logger.RequestLog{Method: "GET", Path: "/files/a.txt", StatusCode: 404, RemoteAddr: "10.0.0.7:5000"}
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{"method=get", true},
+		{"method=POST,PUT", false},
+		{"path=/files/*", true},
+		{"path=/files", false},
+		{"status=4xx", true},
+		{"status=200,404", true},
+		{"status=5xx", false},
+		{"attack=any", false},
+		{"client=10.0.0.7", true},
+	}
+	for _, tt := range tests {
+		var filters replayFilters
+		if err := filters.Set(tt.filter); err != nil {
+			t.Fatalf("%s: %v", tt.filter, err)
+		}
+		if got := filters.match(entry); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.filter, tt.expected, got)
+		}
+	}
+
+	var filters replayFilters
+	for _, bad := range []string{"user=admin", "status", "path="} {
+		if err := filters.Set(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
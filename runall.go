@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/builder"
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// runAllLab is an app of one of the configs run-all serves, and how starting
+// it went
+type runAllLab struct {
+	lab
+	file      string
+	app       *config.Config
+	assigned  bool  // Given another port than its config's
+	err       error // Why it isn't running
+	stopped   bool  // Failed while serving, after it started
+	vulnCount int
+}
+
+// runAllCommand serves every config in a directory at once, each app on its
+// own port, until interrupted
+func runAllCommand() {
+	runAllFlags := flag.NewFlagSet("run-all", flag.ExitOnError)
+	dir := runAllFlags.String("dir", "", "Directory of YAML config files to run (required)")
+	dirShort := runAllFlags.String("d", "", "Directory of YAML config files to run (shorthand)")
+	basePort := runAllFlags.Int("base-port", 0, "Give the apps consecutive ports from this one instead of their configs' ports")
+	var sets setFlags
+	runAllFlags.Var(&sets, "set", "Set a config variable as NAME=value in every config (repeatable)")
+	strict := runAllFlags.Bool("strict", false, "Reject unknown config keys, module types and placements")
+	quiet := runAllFlags.Bool("quiet", false, "Hide the server log, leaving the status table")
+
+	runAllFlags.Parse(os.Args[2:])
+
+	configDir := *dir
+	if configDir == "" {
+		configDir = *dirShort
+	}
+	if configDir == "" {
+		fmt.Printf("\n  %s✗ Error:%s -dir flag is required\n\n", colorRed, colorReset)
+		runAllFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	files, err := configFilesIn(configDir)
+	if err != nil {
+		fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Printf("\n  %s✗ Error:%s no .yaml or .yml config files in %s\n\n", colorRed, colorReset, configDir)
+		os.Exit(1)
+	}
+
+	printBanner()
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	// Load every config first, so ports are handed out knowing them all
+	var labs []*runAllLab
+	for _, file := range files {
+		cfg, err := loadConfig(configFlags{file}, "", sets, *strict)
+		if err != nil {
+			labs = append(labs, &runAllLab{file: file, err: fmt.Errorf("invalid config: %w", err)})
+			continue
+		}
+		apps := cfg.Applications()
+		for _, app := range apps {
+			labs = append(labs, &runAllLab{
+				lab:       lab{name: app.App.Name},
+				file:      file,
+				app:       app,
+				vulnCount: countVulnerabilities(app),
+			})
+		}
+	}
+	assignPorts(labs, *basePort)
+
+	// Build and listen; a lab that can't start is reported, and the rest run
+	type failure struct {
+		lab *runAllLab
+		err error
+	}
+	failed := make(chan failure, len(labs))
+	running := 0
+	for _, l := range labs {
+		if l.err != nil {
+			continue
+		}
+		appCount := 0
+		for _, other := range labs {
+			if other.file == l.file {
+				appCount++
+			}
+		}
+		l.builder = builder.New(l.app, logFilePath(l.file, l.app, appCount))
+		if l.srv, l.err = l.builder.Build(); l.err == nil {
+			l.err = l.srv.Listen()
+		}
+		if l.err != nil {
+			l.builder.Close()
+			l.builder = nil
+			continue
+		}
+		running++
+		go func(l *runAllLab) {
+			if err := l.srv.Start(); err != nil {
+				failed <- failure{l, err}
+			}
+		}(l)
+	}
+
+	printRunAllTable(labs)
+	if running == 0 {
+		fmt.Printf("  %s✗ No lab could be started%s\n\n", colorRed, colorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("  %s✓ %d of %d labs running.%s %sPress Ctrl+C to stop them all.%s\n\n", colorGreen, running, len(labs), colorReset, colorDim, colorReset)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	exitCode := 0
+wait:
+	for running > 0 {
+		select {
+		case <-quit:
+			break wait
+		case f := <-failed:
+			f.lab.stopped = true
+			running--
+			exitCode = 1
+			fmt.Printf("\n  %s✗ %s stopped:%s %v\n", colorRed, f.lab.name, colorReset, f.err)
+		}
+	}
+
+	// Graceful shutdown of every lab, sharing a 5 second timeout
+	fmt.Printf("\n  %sStopping %d labs...%s\n", colorDim, running, colorReset)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, l := range labs {
+		if l.builder == nil {
+			continue
+		}
+		if !l.stopped {
+			if err := l.srv.Stop(ctx); err != nil {
+				log.Printf("%s: server shutdown failed: %v", l.name, err)
+				exitCode = 1
+			}
+		}
+		if err := l.builder.Close(); err != nil {
+			log.Printf("%s: cleanup error: %v", l.name, err)
+		}
+	}
+	fmt.Printf("  %s✓ All labs stopped%s\n\n", colorGreen, colorReset)
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// configFilesIn returns the .yaml and .yml files in a directory, by name
+func configFilesIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// assignPorts gives each lab that loaded a port of its own. Labs keep their
+// config's port unless an earlier lab has it, then taking the next free one;
+// with basePort, they get consecutive ports from it instead.
+func assignPorts(labs []*runAllLab, basePort int) {
+	used := make(map[int]bool)
+	next := basePort
+	for _, l := range labs {
+		if l.app == nil {
+			continue
+		}
+		port := l.app.App.Port
+		if basePort > 0 {
+			port = next
+		}
+		for used[port] {
+			port++
+		}
+		if port != l.app.App.Port {
+			l.app.App.Port, l.assigned = port, true
+		}
+		used[port] = true
+		next = port + 1
+	}
+}
+
+// printRunAllTable prints each lab's address and whether it started
+func printRunAllTable(labs []*runAllLab) {
+	rows := [][]string{{"LAB", "CONFIG", "URL", "VULNS", "STATUS"}}
+	for _, l := range labs {
+		name, url, vulns := l.name, "", ""
+		if name == "" {
+			name = "-"
+		}
+		if l.app != nil {
+			url = labURL(l.app)
+			if l.assigned {
+				url += " *"
+			}
+			vulns = fmt.Sprint(l.vulnCount)
+		}
+		status := "running"
+		if l.err != nil {
+			status = "failed: " + strings.ReplaceAll(l.err.Error(), "\n", " ")
+		}
+		rows = append(rows, []string{name, filepath.Base(l.file), url, vulns, status})
+	}
+
+	widths := make([]int, len(rows[0])-1)
+	for _, row := range rows {
+		for i := range widths {
+			widths[i] = max(widths[i], len(row[i]))
+		}
+	}
+
+	fmt.Println(colorCyan + colorBold + "┌─────────────────────────────────────────┐" + colorReset)
+	fmt.Println(colorCyan + colorBold + "│              RUNNING LABS               │" + colorReset)
+	fmt.Println(colorCyan + colorBold + "└─────────────────────────────────────────┘" + colorReset)
+	fmt.Println()
+	for r, row := range rows {
+		var line strings.Builder
+		for i, width := range widths {
+			fmt.Fprintf(&line, "%-*s  ", width, row[i])
+		}
+		status := row[len(row)-1]
+		switch {
+		case r == 0:
+			fmt.Printf("  %s%s%s%s\n", colorYellow, line.String(), status, colorReset)
+		case status == "running":
+			fmt.Printf("  %s%s✓ %s%s\n", line.String(), colorGreen, status, colorReset)
+		default:
+			fmt.Printf("  %s%s✗ %s%s\n", line.String(), colorRed, status, colorReset)
+		}
+	}
+	if slices.ContainsFunc(labs, func(l *runAllLab) bool { return l.assigned }) {
+		fmt.Printf("\n  %s* given a free port in place of its config's%s\n", colorDim, colorReset)
+	}
+	fmt.Println()
+}
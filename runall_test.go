@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestAssignPorts tests labs keep their ports unless taken, or get
+// consecutive ones from the base port
+func TestAssignPorts(t *testing.T) {
+	newLabs := func() []*runAllLab {
+		var labs []*runAllLab
+		for _, port := range []int{8080, 8080, 0, 8081} {
+			if port == 0 {
+				labs = append(labs, &runAllLab{file: "broken.yaml"})
+				continue
+			}
+			labs = append(labs, &runAllLab{app: &config.Config{App: config.AppConfig{Port: port}}})
+		}
+		return labs
+	}
+
+	labs := newLabs()
+	assignPorts(labs, 0)
+	if labs[0].app.App.Port != 8080 || labs[1].app.App.Port != 8081 || labs[3].app.App.Port != 8082 {
+		t.Errorf("Expected the taken ports moved to free ones, got %d %d %d", labs[0].app.App.Port, labs[1].app.App.Port, labs[3].app.App.Port)
+	}
+	if labs[0].assigned || !labs[1].assigned || !labs[3].assigned {
+		t.Errorf("Expected the moved labs marked")
+	}
+
+	labs = newLabs()
+	assignPorts(labs, 9000)
+	if labs[0].app.App.Port != 9000 || labs[1].app.App.Port != 9001 || labs[3].app.App.Port != 9002 {
+		t.Errorf("Expected consecutive ports from the base, got %d %d %d", labs[0].app.App.Port, labs[1].app.App.Port, labs[3].app.App.Port)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServeAdmin serves router on a separate admin listener at addr, to requests
+// carrying token as "Authorization: Bearer <token>". The first call sets up the
+// listener, which Start opens; later calls swap in the router of a reloaded
+// config, keeping the address and token.
+func (s *Server) ServeAdmin(addr, token string, router *Router) {
+	s.adminRouter.Store(router)
+	if s.admin != nil {
+		return
+	}
+	s.admin = &http.Server{
+		Addr: addr,
+		Handler: AdminAuth(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.adminRouter.Load().ServeHTTP(w, r)
+		})),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+}
+
+// AdminRouter returns the router serving the admin API, or nil without one
+func (s *Server) AdminRouter() *Router {
+	return s.adminRouter.Load()
+}
+
+// startAdmin serves the admin listener, if there is one, in the background
+func (s *Server) startAdmin() {
+	if s.admin == nil {
+		return
+	}
+	listener := s.listener(s.admin)
+	go func() {
+		if err := s.admin.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Admin API failed: %v", err)
+		}
+	}()
+	log.Printf("Admin API listening on http://%s", s.admin.Addr)
+}
+
+// AdminAuth rejects requests without the admin token. It guards the admin
+// listener, and the admin routes served on the app's own. Browsers can't set
+// headers on EventSource and WebSocket requests, so the token is also taken
+// from an access_token query parameter.
+func AdminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && r.URL.Query().Has("access_token") {
+			given, ok = r.URL.Query().Get("access_token"), true
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "admin token required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
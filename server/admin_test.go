@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestServeAdmin tests the admin API wants the token, and serves the router
+// swapped in last
+func TestServeAdmin(t *testing.T) {
+	srv, err := New("127.0.0.1", 0, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if srv.AdminRouter() != nil {
+		t.Fatal("Expected no admin router until ServeAdmin")
+	}
+
+	for _, version := range []string{"v1", "v2"} {
+		router := NewRouter(nil)
+		router.HandleFunc("GET", "/version", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(version))
+		})
+		srv.ServeAdmin("127.0.0.1:0", "secret", router)
+	}
+
+	tests := []struct {
+		auth   string
+		query  string
+		status int
+		body   string
+	}{
+		{"", "", http.StatusUnauthorized, ""},
+		{"Bearer wrong", "", http.StatusUnauthorized, ""},
+		{"secret", "", http.StatusUnauthorized, ""},
+		{"Bearer secret", "", http.StatusOK, "v2"},
+		{"", "?access_token=secret", http.StatusOK, "v2"}, // For EventSource and WebSocket clients
+		{"", "?access_token=wrong", http.StatusUnauthorized, ""},
+		{"Bearer wrong", "?access_token=secret", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/version"+tt.query, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		srv.admin.Handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%q%s: expected %d %q, got %d %q", tt.auth, tt.query, tt.status, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// bodyKey is the context key of a request's buffered body
+type bodyKey struct{}
+
+// requestBody is a request body read once, so every vulnerability of an
+// endpoint can extract from it. Its JSON or SOAP envelope is parsed on first use.
+type requestBody struct {
+	raw []byte
+	err error
+
+	parsed  bool
+	json    map[string]interface{}
+	jsonErr error
+
+	soapParsed bool
+	soap       *SOAPEnvelope
+	soapErr    error
+}
+
+// BufferBody reads the request body into its context, where extractions find
+// it. The router does this for every request; handlers served some other way
+// can call it themselves. The returned request's body can be read as usual.
+func BufferBody(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(bodyKey{}).(*requestBody); ok {
+		return r
+	}
+	body := readBody(r)
+	return r.WithContext(context.WithValue(r.Context(), bodyKey{}, body))
+}
+
+// Body returns the request body as BufferBody buffered it, whatever the
+// method, reading it if the request wasn't buffered. It is left readable.
+func Body(r *http.Request) []byte {
+	return bodyOf(r).raw
+}
+
+// bodyOf returns the request's buffered body, reading it if the request
+// wasn't buffered. Either way the body is left readable.
+func bodyOf(r *http.Request) *requestBody {
+	if body, ok := r.Context().Value(bodyKey{}).(*requestBody); ok {
+		return body
+	}
+	return readBody(r)
+}
+
+// readBody reads the request body and puts it back, so it can be read again
+func readBody(r *http.Request) *requestBody {
+	body := &requestBody{}
+	if r.Body == nil || r.Body == http.NoBody {
+		return body
+	}
+	body.raw, body.err = io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = body.reader()
+	return body
+}
+
+// reader returns a fresh reader over the body
+func (b *requestBody) reader() io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(b.raw))
+}
+
+// rewind puts the body back on the request after a parser like ParseForm
+// consumed it
+func (b *requestBody) rewind(r *http.Request) {
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = b.reader()
+	}
+}
+
+// parseJSON returns the body parsed as a JSON object
+func (b *requestBody) parseJSON() (map[string]interface{}, error) {
+	if !b.parsed {
+		b.parsed = true
+		b.jsonErr = json.Unmarshal(b.raw, &b.json)
+	}
+	return b.json, b.jsonErr
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harVersion is the FlawFactory version HAR logs name as their creator
+const harVersion = "1.0"
+
+// Capture records the request/response pairs the app answers, for export as
+// HAR, and optionally the TCP streams of its listener, for export as PCAP.
+// A nil capture records nothing.
+type Capture struct {
+	mu         sync.Mutex
+	entries    []harEntry // Oldest first
+	maxEntries int
+	maxBody    int64
+	packets    *packetCapture // nil unless PCAP is recorded
+}
+
+// NewCapture creates a capture keeping maxEntries pairs with up to maxBody
+// bytes of each body, and pcapLimit bytes of packets when pcapLimit isn't 0
+func NewCapture(maxEntries int, maxBody, pcapLimit int64) *Capture {
+	c := &Capture{maxEntries: maxEntries, maxBody: maxBody}
+	if pcapLimit > 0 {
+		c.packets = &packetCapture{limit: pcapLimit}
+	}
+	return c
+}
+
+// RecordsPCAP reports whether the capture records packets for PCAP export
+func (c *Capture) RecordsPCAP() bool {
+	return c != nil && c.packets != nil
+}
+
+// Len returns how many pairs are recorded
+func (c *Capture) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear drops the pairs and packets recorded so far
+func (c *Capture) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+	if c.packets != nil {
+		c.packets.clear()
+	}
+}
+
+// record adds an answered request, dropping the oldest past maxEntries
+func (c *Capture) record(req *http.Request, rw *responseWriter, start time.Time, duration time.Duration) {
+	entry := c.entry(req, rw, start, duration)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+	if len(c.entries) > c.maxEntries {
+		c.entries = c.entries[len(c.entries)-c.maxEntries:]
+	}
+}
+
+// WriteHAR writes the recorded pairs as a HAR 1.2 log, oldest first, which
+// browsers, Burp and most proxies import
+func (c *Capture) WriteHAR(w io.Writer) error {
+	entries := []harEntry{}
+	if c != nil {
+		c.mu.Lock()
+		entries = append(entries, c.entries...)
+		c.mu.Unlock()
+	}
+	har := map[string]interface{}{
+		"log": map[string]interface{}{
+			"version": "1.2",
+			"creator": map[string]string{"name": "FlawFactory", "version": harVersion},
+			"pages":   []interface{}{},
+			"entries": entries,
+		},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(har)
+}
+
+// harEntry is a request/response pair of a HAR log
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Milliseconds
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harCookie    `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // base64 for bodies that aren't UTF-8
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// entry builds the HAR entry of an answered request. The server's processing
+// time is all "wait": the capture sees neither the network nor the client.
+func (c *Capture) entry(req *http.Request, rw *responseWriter, start time.Time, duration time.Duration) harEntry {
+	millis := float64(duration.Microseconds()) / 1000
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	request := harRequest{
+		Method:      req.Method,
+		URL:         scheme + "://" + req.Host + req.URL.RequestURI(),
+		HTTPVersion: req.Proto,
+		Cookies:     []harCookie{},
+		Headers:     append([]harNameValue{{Name: "Host", Value: req.Host}}, harHeaders(req.Header)...),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+	}
+	for _, cookie := range req.Cookies() {
+		request.Cookies = append(request.Cookies, harCookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			request.QueryString = append(request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	slices.SortStableFunc(request.QueryString, func(a, b harNameValue) int { return strings.Compare(a.Name, b.Name) })
+	if body := bodyOf(req).raw; len(body) > 0 {
+		request.BodySize = int64(len(body))
+		text, truncated := c.bodyText(body)
+		request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: text}
+		if truncated {
+			request.PostData.Comment = "truncated"
+		}
+	}
+
+	header := rw.Header()
+	response := harResponse{
+		Status:      rw.statusCode,
+		StatusText:  http.StatusText(rw.statusCode),
+		HTTPVersion: req.Proto,
+		Cookies:     []harCookie{},
+		Headers:     harHeaders(header),
+		Content: harContent{
+			Size:     rw.contentLength,
+			MimeType: header.Get("Content-Type"),
+		},
+		RedirectURL: header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    rw.contentLength,
+	}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		hc := harCookie{Name: cookie.Name, Value: cookie.Value, Path: cookie.Path, Domain: cookie.Domain, HTTPOnly: cookie.HttpOnly, Secure: cookie.Secure}
+		if !cookie.Expires.IsZero() {
+			hc.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+		}
+		response.Cookies = append(response.Cookies, hc)
+	}
+	if rw.captured != nil && rw.captured.Len() > 0 {
+		body := rw.captured.Bytes()
+		body = body[:min(int64(len(body)), c.maxBody)] // The log may keep more
+		if utf8.Valid(body) {
+			response.Content.Text = string(body)
+		} else {
+			response.Content.Text, response.Content.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+		}
+		if int64(len(body)) < rw.contentLength {
+			response.Content.Comment = "truncated"
+		}
+	}
+
+	entry := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            millis,
+		Request:         request,
+		Response:        response,
+		Timings:         harTimings{Wait: millis},
+	}
+	if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+	return entry
+}
+
+// bodyText returns up to maxBody bytes of a request body as text
+func (c *Capture) bodyText(body []byte) (text string, truncated bool) {
+	if int64(len(body)) > c.maxBody {
+		body, truncated = body[:c.maxBody], true
+	}
+	return string(bytes.ToValidUTF8(body, []byte("�"))), truncated
+}
+
+// harHeaders lists headers by name, each value as its own header
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		for _, value := range header[name] {
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
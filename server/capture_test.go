@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCapture_HAR tests answered requests are recorded as HAR entries, their
+// bodies cut at the limit and the oldest dropped
+func TestCapture_HAR(t *testing.T) {
+	router := NewRouter(nil)
+	router.capture = NewCapture(3, 5, 0)
+	router.HandleFunc("POST", "/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("welcome back"))
+	})
+
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodPost, "http://lab.local/login?next=%2Fhome", strings.NewReader("user=admin"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if router.capture.Len() != 3 {
+		t.Errorf("Expected the 3 latest requests kept, got %d", router.capture.Len())
+	}
+
+	var har bytes.Buffer
+	if err := router.capture.WriteHAR(&har); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var doc struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(har.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected a HAR log, got %s", har.String())
+	}
+	if doc.Log.Version != "1.2" || len(doc.Log.Entries) != 3 {
+		t.Fatalf("Expected a HAR 1.2 log of 3 entries, got %s", har.String())
+	}
+
+	entry := doc.Log.Entries[0]
+	request, response := entry.Request, entry.Response
+	if request.URL != "http://lab.local/login?next=%2Fhome" || request.QueryString[0] != (harNameValue{"next", "/home"}) || request.Cookies[0].Name != "theme" {
+		t.Errorf("Expected the request's URL, query and cookies, got %+v", request)
+	}
+	if request.PostData == nil || request.PostData.Text != "user=" || request.PostData.Comment != "truncated" || request.BodySize != 10 {
+		t.Errorf("Expected the request body cut at 5 bytes, got %+v", request.PostData)
+	}
+	if response.Status != 200 || response.Content.Text != "welco" || response.Content.Size != 12 || response.Content.MimeType != "text/plain" {
+		t.Errorf("Expected the response body cut at 5 bytes, got %+v", response.Content)
+	}
+	if len(response.Cookies) != 1 || response.Cookies[0].Name != "session" || !response.Cookies[0].HTTPOnly {
+		t.Errorf("Expected the response's cookie, got %+v", response.Cookies)
+	}
+}
+
+// TestCapture_PCAP tests a listener's connections are recorded as TCP
+// packets with valid checksums, from handshake to teardown
+func TestCapture_PCAP(t *testing.T) {
+	capture := NewCapture(10, 1024, 1<<20)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})}
+	go srv.Serve(capture.Listener(listener))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + listener.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var packets [][]byte
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var pcap bytes.Buffer
+		capture.WritePCAP(&pcap)
+		packets = readPCAP(t, pcap.Bytes())
+		if len(packets) > 0 && packets[len(packets)-1][33]&tcpFlagFIN == 0 && packets[len(packets)-2][33]&tcpFlagFIN != 0 {
+			break
+		}
+	}
+
+	if len(packets) < 7 {
+		t.Fatalf("Expected a handshake, request, response and teardown, got %d packets", len(packets))
+	}
+	if flags := packets[0][33]; flags != tcpFlagSYN {
+		t.Errorf("Expected the first packet to be a SYN, got flags %#x", flags)
+	}
+	var request, response bool
+	for _, packet := range packets {
+		if onesComplementSum(0, packet[:ipv4Header]) != 0xffff {
+			t.Errorf("Expected a valid IP checksum, got %x", packet[:ipv4Header])
+		}
+		var src, dst [4]byte
+		copy(src[:], packet[12:16])
+		copy(dst[:], packet[16:20])
+		tcp := packet[ipv4Header:]
+		if tcpChecksum(netip.AddrFrom4(src), netip.AddrFrom4(dst), tcp) != 0 {
+			t.Errorf("Expected a valid TCP checksum, got %x", tcp[:tcpHeaderLen])
+		}
+		payload := string(tcp[tcpHeaderLen:])
+		request = request || strings.HasPrefix(payload, "GET /ping HTTP/1.1")
+		response = response || (strings.HasPrefix(payload, "HTTP/1.1 200 OK") && strings.HasSuffix(payload, "pong"))
+	}
+	if !request || !response {
+		t.Errorf("Expected the request and response among the packets")
+	}
+
+	capture.Clear()
+	var pcap bytes.Buffer
+	capture.WritePCAP(&pcap)
+	if len(readPCAP(t, pcap.Bytes())) != 0 {
+		t.Error("Expected no packets after Clear")
+	}
+}
+
+// readPCAP returns the packets of a little-endian pcap file of raw IP
+func readPCAP(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	if len(data) < 24 || binary.LittleEndian.Uint32(data) != pcapMagic || binary.LittleEndian.Uint32(data[20:]) != linkTypeRaw {
+		t.Fatalf("Expected a pcap header for raw IP, got %x", data[:min(len(data), 24)])
+	}
+	var packets [][]byte
+	for data = data[24:]; len(data) >= 16; {
+		n := binary.LittleEndian.Uint32(data[8:])
+		packets = append(packets, data[16:16+n])
+		data = data[16+n:]
+	}
+	return packets
+}
@@ -2,23 +2,41 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+// Default limits for multipart form data
+const (
+	DefaultMaxBodySize = 32 << 20
+	DefaultMaxFileSize = 10 << 20
+)
+
+// multipartMemory is how much of a multipart body is held in memory; larger
+// files are buffered in temporary files
+const multipartMemory = 8 << 20
+
 // Extractor handles extracting user input from various placements in HTTP requests
-type Extractor struct{}
+type Extractor struct {
+	MaxBodySize int64 // Limit on a multipart body (default: 32MB)
+	MaxFileSize int64 // Limit on each uploaded file (default: 10MB)
+}
 
 // NewExtractor creates a new extractor instance
 func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
+// NewExtractorWithLimits creates an extractor with multipart size limits; zero keeps a default
+func NewExtractorWithLimits(maxBodySize, maxFileSize int64) *Extractor {
+	return &Extractor{MaxBodySize: maxBodySize, MaxFileSize: maxFileSize}
+}
+
 // Extract extracts a value from the request based on placement and param name
 func (e *Extractor) Extract(r *http.Request, placement, param string) (string, error) {
 	switch placement {
@@ -34,8 +52,24 @@ func (e *Extractor) Extract(r *http.Request, placement, param string) (string, e
 		return e.extractFormField(r, param)
 	case "json_field":
 		return e.extractJSONField(r, param)
-	case "multipart-form":
-		return e.extractMultipartForm(r, param)
+	case "multipart_field", "multipart-form":
+		return e.extractMultipartField(r, placement, param)
+	case "file":
+		file, err := e.ExtractFile(r, param)
+		if err != nil || file == nil {
+			return "", err
+		}
+		return string(file.Content), nil
+	case "raw_body":
+		return e.extractRawBody(r)
+	case "graphql_variable":
+		return e.extractGraphQLVariable(r, param)
+	case "graphql_query":
+		return e.extractGraphQLQuery(r, param)
+	case "soap_param":
+		return e.extractSOAPParam(r, param)
+	case "grpc_field":
+		return e.extractGRPCField(r, param)
 	default:
 		return "", &ExtractionError{
 			Placement: placement,
@@ -79,8 +113,12 @@ func (e *Extractor) extractCookie(r *http.Request, param string) string {
 
 // extractFormField extracts a value from URL-encoded form data
 func (e *Extractor) extractFormField(r *http.Request, param string) (string, error) {
-	// ParseForm is idempotent and populates r.Form
-	if err := r.ParseForm(); err != nil {
+	// ParseForm is idempotent and populates r.Form; the body is put back for
+	// other placements
+	body := bodyOf(r)
+	err := r.ParseForm()
+	body.rewind(r)
+	if err != nil {
 		return "", &ExtractionError{
 			Placement: "form_field",
 			Param:     param,
@@ -93,19 +131,18 @@ func (e *Extractor) extractFormField(r *http.Request, param string) (string, err
 // extractJSONField extracts a value from JSON body
 // Supports dot notation for nested fields: "user.profile.name"
 func (e *Extractor) extractJSONField(r *http.Request, param string) (string, error) {
-	// Read the body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
+	body := bodyOf(r)
+	if body.err != nil {
 		return "", &ExtractionError{
 			Placement: "json_field",
 			Param:     param,
-			Message:   "failed to read body: " + err.Error(),
+			Message:   "failed to read body: " + body.err.Error(),
 		}
 	}
 
-	// Parse JSON into a generic map
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
+	// The body is parsed once, however many fields are extracted from it
+	data, err := body.parseJSON()
+	if err != nil {
 		return "", &ExtractionError{
 			Placement: "json_field",
 			Param:     param,
@@ -118,6 +155,28 @@ func (e *Extractor) extractJSONField(r *http.Request, param string) (string, err
 	return value, nil
 }
 
+// extractRawBody returns the whole body, which stays readable for modules
+func (e *Extractor) extractRawBody(r *http.Request) (string, error) {
+	body := bodyOf(r)
+	if body.err != nil {
+		return "", &ExtractionError{
+			Placement: "raw_body",
+			Message:   "failed to read body: " + body.err.Error(),
+		}
+	}
+	return string(body.raw), nil
+}
+
+// ExtractMessage extracts a value from a WebSocket message.
+// JSON object messages are navigated with dot notation; anything else is used as-is.
+func (e *Extractor) ExtractMessage(message, param string) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(message), &data); err != nil {
+		return message
+	}
+	return navigateJSON(data, param)
+}
+
 // navigateJSON navigates a nested JSON structure using dot notation
 func navigateJSON(data map[string]interface{}, path string) string {
 	parts := strings.Split(path, ".")
@@ -156,58 +215,137 @@ func navigateJSON(data map[string]interface{}, path string) string {
 	}
 }
 
-// extractMultipartForm extracts a value from multipart form data
-func (e *Extractor) extractMultipartForm(r *http.Request, param string) (string, error) {
-	// Check content type
-	contentType := r.Header.Get("Content-Type")
-	mediaType, params, err := mime.ParseMediaType(contentType)
+// extractMultipartField extracts a field from multipart form data. A file
+// part with the name gives its content.
+func (e *Extractor) extractMultipartField(r *http.Request, placement, param string) (string, error) {
+	if err := e.parseMultipart(r, placement, param); err != nil {
+		return "", err
+	}
+	if values := r.MultipartForm.Value[param]; len(values) > 0 {
+		return values[0], nil
+	}
+	file, err := e.ExtractFile(r, param)
+	if err != nil || file == nil {
+		return "", err
+	}
+	return string(file.Content), nil
+}
+
+// ExtractFile returns the file uploaded under param in multipart form data,
+// or nil if there is none. The parsed form is kept on the request, so a file
+// can be read again after its content was extracted.
+func (e *Extractor) ExtractFile(r *http.Request, param string) (*UploadedFile, error) {
+	if err := e.parseMultipart(r, "file", param); err != nil {
+		return nil, err
+	}
+	headers := r.MultipartForm.File[param]
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	header := headers[0]
+
+	if header.Size > e.maxFileSize() {
+		return nil, &ExtractionError{
+			Placement: "file",
+			Param:     param,
+			Message:   fmt.Sprintf("file '%s' is larger than the %d byte limit", header.Filename, e.maxFileSize()),
+		}
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, &ExtractionError{
+			Placement: "file",
+			Param:     param,
+			Message:   "failed to open file: " + err.Error(),
+		}
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &ExtractionError{
+			Placement: "file",
+			Param:     param,
+			Message:   "failed to read file: " + err.Error(),
+		}
+	}
+
+	// The form keeps only the filename's base, but traversal in it is the point
+	// of upload labs, so it's read from the part as sent
+	filename := header.Filename
+	if _, params, err := mime.ParseMediaType(header.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = params["filename"]
+	}
+
+	return &UploadedFile{
+		Filename:    filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Content:     content,
+	}, nil
+}
+
+// parseMultipart parses the request's multipart form data once, within the
+// body size limit
+func (e *Extractor) parseMultipart(r *http.Request, placement, param string) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
-		return "", &ExtractionError{
-			Placement: "multipart-form",
+		return &ExtractionError{
+			Placement: placement,
 			Param:     param,
 			Message:   "request is not multipart form data",
 		}
 	}
-
-	boundary := params["boundary"]
-	if boundary == "" {
-		return "", &ExtractionError{
-			Placement: "multipart-form",
+	if params["boundary"] == "" {
+		return &ExtractionError{
+			Placement: placement,
 			Param:     param,
 			Message:   "no boundary in multipart form",
 		}
 	}
 
-	// Parse multipart form
-	reader := multipart.NewReader(r.Body, boundary)
-	for {
-		part, err := reader.NextPart()
-		if err == io.EOF {
-			break
+	body := bodyOf(r)
+	r.Body = http.MaxBytesReader(nil, body.reader(), e.maxBodySize())
+	err = r.ParseMultipartForm(multipartMemory)
+	body.rewind(r)
+	if err != nil {
+		message := "failed to parse multipart: " + err.Error()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			message = fmt.Sprintf("multipart body is larger than the %d byte limit", tooLarge.Limit)
 		}
-		if err != nil {
-			return "", &ExtractionError{
-				Placement: "multipart-form",
-				Param:     param,
-				Message:   "failed to parse multipart: " + err.Error(),
-			}
+		return &ExtractionError{
+			Placement: placement,
+			Param:     param,
+			Message:   message,
 		}
+	}
+	return nil
+}
 
-		// Check if this is the field we're looking for
-		if part.FormName() == param {
-			value, err := io.ReadAll(part)
-			if err != nil {
-				return "", &ExtractionError{
-					Placement: "multipart-form",
-					Param:     param,
-					Message:   "failed to read part: " + err.Error(),
-				}
-			}
-			return string(value), nil
-		}
+// maxBodySize returns the multipart body limit
+func (e *Extractor) maxBodySize() int64 {
+	if e.MaxBodySize > 0 {
+		return e.MaxBodySize
 	}
+	return DefaultMaxBodySize
+}
+
+// maxFileSize returns the uploaded file limit
+func (e *Extractor) maxFileSize() int64 {
+	if e.MaxFileSize > 0 {
+		return e.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
 
-	return "", nil
+// UploadedFile is a file sent in multipart form data
+type UploadedFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
 }
 
 // ExtractionError represents an error during input extraction
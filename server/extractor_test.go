@@ -2,8 +2,12 @@ package server
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"strings"
 	"testing"
 )
@@ -194,6 +198,59 @@ func TestExtract_JSONField_Invalid(t *testing.T) {
 	}
 }
 
+// TestExtract_BodyReused tests several placements can read one body, which
+// stays readable afterwards, buffered by the router or not
+func TestExtract_BodyReused(t *testing.T) {
+	extractor := NewExtractor()
+	const body = `{"user":{"name":"john","email":"john@example.com"}}`
+
+	for _, buffered := range []bool{false, true} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if buffered {
+			req = BufferBody(req)
+		}
+
+		name, err := extractor.Extract(req, "json_field", "user.name")
+		if err != nil || name != "john" {
+			t.Errorf("buffered=%v: expected john, got %q, err %v", buffered, name, err)
+		}
+		if _, err := extractor.Extract(req, "form_field", "user"); err != nil {
+			t.Errorf("buffered=%v: unexpected form error: %v", buffered, err)
+		}
+		email, err := extractor.Extract(req, "json_field", "user.email")
+		if err != nil || email != "john@example.com" {
+			t.Errorf("buffered=%v: expected john@example.com, got %q, err %v", buffered, email, err)
+		}
+		if raw, _ := extractor.Extract(req, "raw_body", ""); raw != body {
+			t.Errorf("buffered=%v: expected the raw body, got %q", buffered, raw)
+		}
+		if rest, _ := io.ReadAll(req.Body); string(rest) != body {
+			t.Errorf("buffered=%v: expected the body to stay readable, got %q", buffered, rest)
+		}
+	}
+}
+
+// TestBufferBody tests a buffered body is parsed once, even after the
+// handler consumed r.Body
+func TestBufferBody(t *testing.T) {
+	req := BufferBody(httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1}`)))
+	if BufferBody(req) != req {
+		t.Error("Expected a buffered request to be returned as is")
+	}
+	io.ReadAll(req.Body)
+
+	first, _ := bodyOf(req).parseJSON()
+	value, err := NewExtractor().Extract(req, "json_field", "id")
+	if err != nil || value != "1" {
+		t.Fatalf("Expected 1, got %q, err %v", value, err)
+	}
+	first["id"] = "cached"
+	if value, _ := NewExtractor().Extract(req, "json_field", "id"); value != "cached" {
+		t.Errorf("Expected the parsed body to be reused, got %q", value)
+	}
+}
+
 // TestExtract_UnsupportedPlacement tests unsupported placement error
 func TestExtract_UnsupportedPlacement(t *testing.T) {
 	extractor := NewExtractor()
@@ -254,6 +311,69 @@ func TestExtract_MultipartForm_NotMultipart(t *testing.T) {
 	}
 }
 
+// uploadRequest builds a multipart request with a note field and a file
+func uploadRequest(t *testing.T, filename, contentType, content string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("note", "avatar")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="upload"; filename="%s"`, filename))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("Failed to create part: %v", err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// TestExtract_File tests the file placement exposes the upload's name, content
+// type and content, and fields and files can both be read from one body
+func TestExtract_File(t *testing.T) {
+	extractor := NewExtractor()
+	req := uploadRequest(t, "../shell.php", "image/png", "<?php system($_GET['c']); ?>")
+
+	content, err := extractor.Extract(req, "file", "upload")
+	if err != nil || content != "<?php system($_GET['c']); ?>" {
+		t.Fatalf("Expected the file's content, got %q, err %v", content, err)
+	}
+	if note, err := extractor.Extract(req, "multipart_field", "note"); err != nil || note != "avatar" {
+		t.Errorf("Expected 'avatar', got %q, err %v", note, err)
+	}
+
+	file, err := extractor.ExtractFile(req, "upload")
+	if err != nil || file == nil {
+		t.Fatalf("Expected a file, got err %v", err)
+	}
+	if file.Filename != "../shell.php" || file.ContentType != "image/png" {
+		t.Errorf("Expected ../shell.php as image/png, got %s as %s", file.Filename, file.ContentType)
+	}
+
+	if missing, err := extractor.ExtractFile(req, "other"); missing != nil || err != nil {
+		t.Errorf("Expected no file for a missing param, got %v, err %v", missing, err)
+	}
+}
+
+// TestExtract_FileLimits tests uploads over the size limits are rejected
+func TestExtract_FileLimits(t *testing.T) {
+	content := strings.Repeat("A", 2048)
+
+	_, err := NewExtractorWithLimits(0, 1024).Extract(uploadRequest(t, "big.bin", "application/octet-stream", content), "file", "upload")
+	if err == nil || !strings.Contains(err.Error(), "larger than the 1024 byte limit") {
+		t.Errorf("Expected the file limit error, got %v", err)
+	}
+
+	_, err = NewExtractorWithLimits(1024, 0).Extract(uploadRequest(t, "big.bin", "application/octet-stream", content), "multipart_field", "note")
+	if err == nil || !strings.Contains(err.Error(), "multipart body is larger than the 1024 byte limit") {
+		t.Errorf("Expected the body limit error, got %v", err)
+	}
+}
+
 // TestExtractionError tests ExtractionError formatting
 func TestExtractionError(t *testing.T) {
 	err := &ExtractionError{
@@ -268,6 +388,31 @@ func TestExtractionError(t *testing.T) {
 	}
 }
 
+// TestExtractMessage tests WebSocket message extraction
+func TestExtractMessage(t *testing.T) {
+	extractor := NewExtractor()
+
+	tests := []struct {
+		name     string
+		message  string
+		param    string
+		expected string
+	}{
+		{"json field", `{"room":"1 OR 1=1"}`, "room", "1 OR 1=1"},
+		{"nested json field", `{"msg":{"text":"hi"}}`, "msg.text", "hi"},
+		{"missing json field", `{"room":"1"}`, "user", ""},
+		{"plain text", "hello world", "room", "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := extractor.ExtractMessage(tt.message, tt.param); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
 // TestNavigateJSON tests the JSON navigation helper
 func TestNavigateJSON(t *testing.T) {
 	tests := []struct {
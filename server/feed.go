@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedBuffer is how many entries a stream can fall behind before it misses some
+const feedBuffer = 256
+
+// feedKeepAlive is how often an idle stream is sent a comment, so proxies
+// and browsers keep the connection open
+const feedKeepAlive = 15 * time.Second
+
+// FeedTypes are the kinds of entry a feed carries
+var FeedTypes = []string{"request", "exploitation"}
+
+// FeedEntry is an entry of the live feed: a request log entry, or an
+// exploitation event
+type FeedEntry struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Feed fans entries out to the admin API's live streams as they happen
+type Feed struct {
+	mu     sync.Mutex
+	subs   map[chan FeedEntry]struct{}
+	closed bool
+}
+
+// NewFeed creates a feed with no streams
+func NewFeed() *Feed {
+	return &Feed{subs: make(map[chan FeedEntry]struct{})}
+}
+
+// Active reports whether anyone is streaming, so entries needn't be built otherwise
+func (f *Feed) Active() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs) > 0
+}
+
+// Publish sends an entry to every stream. A stream that has fallen
+// feedBuffer entries behind misses it rather than holding up requests.
+func (f *Feed) Publish(kind string, data interface{}) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub <- FeedEntry{Type: kind, Data: data}:
+		default:
+		}
+	}
+}
+
+// Subscribe starts a stream. Its channel is closed by cancel, or when the feed is.
+func (f *Feed) Subscribe() (entries <-chan FeedEntry, cancel func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub := make(chan FeedEntry, feedBuffer)
+	if f.closed {
+		close(sub)
+		return sub, func() {}
+	}
+	f.subs[sub] = struct{}{}
+	return sub, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[sub]; ok {
+			delete(f.subs, sub)
+			close(sub)
+		}
+	}
+}
+
+// Close ends every stream, so a shutdown doesn't wait on them
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for sub := range f.subs {
+		delete(f.subs, sub)
+		close(sub)
+	}
+}
+
+// ServeHTTP streams the feed as server-sent events, or over a WebSocket when
+// the request asks for an upgrade. ?types= picks the kinds of entry:
+// request, exploitation or both, comma-separated (default both).
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	types := FeedTypes
+	if value := r.URL.Query().Get("types"); value != "" {
+		types = strings.Split(value, ",")
+		for _, kind := range types {
+			if !slices.Contains(FeedTypes, kind) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unknown type '%s', must be one of: %s", kind, strings.Join(FeedTypes, ", "))})
+				return
+			}
+		}
+	}
+
+	if IsWebSocketRequest(r) {
+		f.streamWebSocket(w, r, types)
+		return
+	}
+	f.streamEvents(w, r, types)
+}
+
+// streamEvents sends entries as server-sent events named by their type
+func (f *Feed) streamEvents(w http.ResponseWriter, r *http.Request, types []string) {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // The listener's write timeout would end the stream
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": streaming "+strings.Join(types, ", ")+"\n\n")
+	if err := rc.Flush(); err != nil {
+		log.Printf("Warning: live stream can't be flushed: %v", err)
+		return
+	}
+
+	entries, cancel := f.Subscribe()
+	defer cancel()
+	keepAlive := time.NewTicker(feedKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if !slices.Contains(types, entry.Type) {
+				continue
+			}
+			data, err := json.Marshal(entry.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", entry.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// streamWebSocket sends entries as JSON text messages: {"type": ..., "data": ...}
+func (f *Feed) streamWebSocket(w http.ResponseWriter, r *http.Request, types []string) {
+	conn, err := UpgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+	conn.conn.SetDeadline(time.Time{}) // The listener's timeouts would end the stream
+
+	// Messages from the client are ignored; reading notices it leave
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	entries, cancel := f.Subscribe()
+	defer cancel()
+	keepAlive := time.NewTicker(feedKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if !slices.Contains(types, entry.Type) {
+				continue
+			}
+			message, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			if err := conn.WriteMessage(string(message)); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := conn.writeFrame(opPing, nil); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
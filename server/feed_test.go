@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForStream waits until a stream has subscribed to the feed
+func waitForStream(t *testing.T, feed *Feed) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !feed.Active(); {
+		if time.Now().After(deadline) {
+			t.Fatal("Stream never subscribed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestFeed_ServerSentEvents tests requests served by the app's router are
+// streamed as events, filtered by type
+func TestFeed_ServerSentEvents(t *testing.T) {
+	feed := NewFeed()
+	app := NewRouter(nil)
+	app.feed = feed
+	app.HandleFunc("GET", "/search", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	admin := NewRouter(nil)
+	admin.HandleFunc("GET", "/stream", feed.ServeHTTP)
+	srv := httptest.NewServer(admin)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream?types=request")
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+	waitForStream(t, feed)
+
+	feed.Publish("exploitation", map[string]string{"module": "sqli"}) // Filtered out
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search?q=1", nil))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+	if lines[0] != "event: request" || !strings.Contains(lines[1], `"path":"/search"`) || !strings.Contains(lines[1], `"status_code":418`) {
+		t.Errorf("Expected the request's log entry, got %v", lines)
+	}
+
+	// Closing the feed ends the stream
+	feed.Close()
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewFeed().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream?types=attacks", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown type, got %d", rec.Code)
+	}
+}
+
+// TestFeed_WebSocket tests entries are sent as JSON messages over a WebSocket
+func TestFeed_WebSocket(t *testing.T) {
+	feed := NewFeed()
+	router := NewRouter(nil)
+	router.HandleFunc("GET", "/ws", feed.ServeHTTP)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	conn, reader := dialWebSocket(t, srv.URL)
+	defer conn.Close()
+	waitForStream(t, feed)
+
+	feed.Publish("exploitation", map[string]string{"module": "xxe"})
+	if message := readServerFrame(t, reader); message != `{"type":"exploitation","data":{"module":"xxe"}}` {
+		t.Errorf("Unexpected message %s", message)
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// graphQLRequest is a GraphQL operation: a query document and its variables
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// extractGraphQLVariable extracts a variable of a GraphQL request
+// Supports dot notation for nested fields: "filter.name"
+func (e *Extractor) extractGraphQLVariable(r *http.Request, param string) (string, error) {
+	req, err := readGraphQL(r, "graphql_variable", param)
+	if err != nil {
+		return "", err
+	}
+	return navigateJSON(req.Variables, param), nil
+}
+
+// extractGraphQLQuery extracts the value of an argument in a GraphQL query, or
+// the whole query without a param. The param is the argument's name, or
+// field.argument to only match the argument of one field, as in user.id for
+// user(id: "1"). Arguments passed as $variables are resolved.
+func (e *Extractor) extractGraphQLQuery(r *http.Request, param string) (string, error) {
+	req, err := readGraphQL(r, "graphql_query", param)
+	if err != nil {
+		return "", err
+	}
+	if param == "" {
+		return req.Query, nil
+	}
+	return graphQLArgument(req.Query, req.Variables, param), nil
+}
+
+// readGraphQL reads a GraphQL request from the query and variables URL
+// parameters of a GET, or else the body: JSON, or application/graphql holding
+// only the query. The body is left readable for modules.
+func readGraphQL(r *http.Request, placement, param string) (*graphQLRequest, error) {
+	req := &graphQLRequest{}
+
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return nil, &ExtractionError{
+					Placement: placement,
+					Param:     param,
+					Message:   "failed to parse variables: " + err.Error(),
+				}
+			}
+		}
+		return req, nil
+	}
+
+	body := bodyOf(r)
+	if body.err != nil {
+		return nil, &ExtractionError{
+			Placement: placement,
+			Param:     param,
+			Message:   "failed to read body: " + body.err.Error(),
+		}
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+		req.Query = string(body.raw)
+		return req, nil
+	}
+	if err := json.Unmarshal(body.raw, req); err != nil {
+		return nil, &ExtractionError{
+			Placement: placement,
+			Param:     param,
+			Message:   "failed to parse GraphQL request: " + err.Error(),
+		}
+	}
+	return req, nil
+}
+
+// graphQLArgument returns the value of the first argument matching param in a
+// query document. Strings are unquoted, variables resolved, and lists and
+// objects returned as written.
+func graphQLArgument(query string, variables map[string]interface{}, param string) string {
+	field, arg := "", param
+	if i := strings.LastIndex(param, "."); i >= 0 {
+		field, arg = param[:i], param[i+1:]
+	}
+
+	tokens := lexGraphQL(query)
+	for i, t := range tokens {
+		if !t.is("(") || (i+1 < len(tokens) && tokens[i+1].is("$")) {
+			continue // Not an argument list; ($id: ID) declares variables
+		}
+		owner := ""
+		if i > 0 && tokens[i-1].kind == graphQLName {
+			owner = tokens[i-1].text
+		}
+
+		for j := i + 1; j < len(tokens) && !tokens[j].is(")"); {
+			if tokens[j].kind != graphQLName || j+1 >= len(tokens) || !tokens[j+1].is(":") {
+				j++
+				continue
+			}
+			end := skipGraphQLValue(tokens, j+2)
+			if tokens[j].text == arg && (field == "" || field == owner) {
+				return graphQLValue(query, tokens[j+2:end], variables)
+			}
+			j = end
+		}
+	}
+	return ""
+}
+
+// graphQLValue returns the text of a value made of tokens
+func graphQLValue(query string, tokens []graphQLToken, variables map[string]interface{}) string {
+	switch {
+	case len(tokens) == 0:
+		return ""
+	case len(tokens) == 1:
+		return tokens[0].text
+	case len(tokens) == 2 && tokens[0].is("$"):
+		return navigateJSON(variables, tokens[1].text)
+	}
+	return query[tokens[0].start:tokens[len(tokens)-1].end]
+}
+
+// skipGraphQLValue returns the index after the value starting at tokens[i]
+func skipGraphQLValue(tokens []graphQLToken, i int) int {
+	if i >= len(tokens) {
+		return i
+	}
+	switch {
+	case tokens[i].is("$"):
+		return min(i+2, len(tokens))
+	case tokens[i].is("[") || tokens[i].is("{"):
+		depth := 0
+		for ; i < len(tokens); i++ {
+			switch {
+			case tokens[i].is("[") || tokens[i].is("{"):
+				depth++
+			case tokens[i].is("]") || tokens[i].is("}"):
+				depth--
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		return i
+	}
+	return i + 1
+}
+
+// Kinds of GraphQL tokens
+const (
+	graphQLPunctuator = iota
+	graphQLName
+	graphQLString
+	graphQLNumber
+)
+
+// graphQLToken is a token of a query document and where it is in the source
+type graphQLToken struct {
+	kind       int
+	text       string // Source text, or the value of a string
+	start, end int
+}
+
+// is reports whether the token is the punctuator p
+func (t graphQLToken) is(p string) bool {
+	return t.kind == graphQLPunctuator && t.text == p
+}
+
+// lexGraphQL splits a query document into tokens, skipping whitespace, commas
+// and comments. It doesn't reject malformed documents: an unterminated string
+// runs to the end, and unknown characters are single punctuators.
+func lexGraphQL(query string) []graphQLToken {
+	var tokens []graphQLToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			if end < 0 {
+				i = len(query)
+				tokens = append(tokens, graphQLToken{graphQLString, query[start+3:], start, i})
+				continue
+			}
+			i += 3 + end + 3
+			tokens = append(tokens, graphQLToken{graphQLString, query[start+3 : i-3], start, i})
+
+		case c == '"':
+			i++
+			for i < len(query) && query[i] != '"' && query[i] != '\n' {
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i = min(i+1, len(query))
+			tokens = append(tokens, graphQLToken{graphQLString, unquoteGraphQL(query[start:i]), start, i})
+
+		case c == '_' || isLetter(c):
+			for i < len(query) && (query[i] == '_' || isLetter(query[i]) || isDigit(query[i])) {
+				i++
+			}
+			tokens = append(tokens, graphQLToken{graphQLName, query[start:i], start, i})
+
+		case c == '-' || isDigit(c):
+			i++
+			for i < len(query) && (isDigit(query[i]) || strings.IndexByte(".eE+-", query[i]) >= 0) {
+				i++
+			}
+			tokens = append(tokens, graphQLToken{graphQLNumber, query[start:i], start, i})
+
+		case strings.HasPrefix(query[i:], "..."):
+			i += 3
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, "...", start, i})
+
+		default:
+			i++
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, query[start:i], start, i})
+		}
+	}
+	return tokens
+}
+
+// unquoteGraphQL returns the value of a quoted string, whose escapes are JSON's.
+// Strings that don't decode, like an unterminated one, lose only their quotes.
+func unquoteGraphQL(quoted string) string {
+	var value string
+	if json.Unmarshal([]byte(quoted), &value) == nil {
+		return value
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(quoted, `"`), `"`)
+}
+
+// isLetter reports whether c is an ASCII letter
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestExtract_GraphQLVariable tests variables are read from JSON bodies and GET parameters
+func TestExtract_GraphQLVariable(t *testing.T) {
+	extractor := NewExtractor()
+
+	body := `{"query":"query Find($filter: UserFilter) { users(filter: $filter) { name } }","variables":{"filter":{"name":"admin' --"}}}`
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	value, err := extractor.Extract(req, "graphql_variable", "filter.name")
+	if err != nil || value != "admin' --" {
+		t.Errorf("Expected \"admin' --\", got %q, err %v", value, err)
+	}
+	if rest, _ := io.ReadAll(req.Body); string(rest) != body {
+		t.Errorf("Expected the body to stay readable, got %q", rest)
+	}
+
+	query := url.Values{"query": {"query($id: ID) { user(id: $id) { name } }"}, "variables": {`{"id": 7}`}}
+	req = httptest.NewRequest("GET", "/graphql?"+query.Encode(), nil)
+	if value, err := extractor.Extract(req, "graphql_variable", "id"); err != nil || value != "7" {
+		t.Errorf("Expected 7, got %q, err %v", value, err)
+	}
+
+	req = httptest.NewRequest("POST", "/graphql", strings.NewReader("not json"))
+	if _, err := extractor.Extract(req, "graphql_variable", "id"); err == nil {
+		t.Error("Expected an error for a body that isn't a GraphQL request")
+	}
+}
+
+// TestExtract_GraphQLQuery tests arguments are found by name or field.name,
+// with variables resolved, and the whole query is used without a param
+func TestExtract_GraphQLQuery(t *testing.T) {
+	extractor := NewExtractor()
+	const query = `query Find($role: String = "user") {
+  # user(id: "commented out")
+  post(id: 3) { title }
+  user(id: "1' OR '1'='1", role: $role) { name }
+  search(filter: {name: "bob", tags: ["a", "b"]}, note: """multi
+line""") { id }
+}`
+	graphql := func(variables string) *http.Request {
+		req := httptest.NewRequest("POST", "/graphql", strings.NewReader(query))
+		if variables != "" {
+			body := `{"query":` + jsonString(query) + `,"variables":` + variables + `}`
+			req = httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}
+		req.Header.Set("Content-Type", "application/graphql")
+		return req
+	}
+
+	tests := []struct {
+		param     string
+		variables string
+		expected  string
+	}{
+		{"id", "", "3"},
+		{"user.id", "", "1' OR '1'='1"},
+		{"user.role", `{"role": "admin"}`, "admin"},
+		{"search.filter", "", `{name: "bob", tags: ["a", "b"]}`},
+		{"note", "", "multi\nline"},
+		{"missing", "", ""},
+		{"", "", query},
+	}
+	for _, tt := range tests {
+		value, err := extractor.Extract(graphql(tt.variables), "graphql_query", tt.param)
+		if err != nil || value != tt.expected {
+			t.Errorf("%q: expected %q, got %q, err %v", tt.param, tt.expected, value, err)
+		}
+	}
+}
+
+// TestGraphQLArgument_Malformed tests injected queries that break the syntax still yield the argument
+func TestGraphQLArgument_Malformed(t *testing.T) {
+	tests := map[string]string{
+		`{ user(id: "1\" OR 1=1") { name } }`: `1" OR 1=1`,
+		`{ user(id: "unterminated`:            "unterminated",
+		`{ user(id: 1 OR 1=1) { name } }`:     "1",
+	}
+	for query, expected := range tests {
+		if value := graphQLArgument(query, nil, "user.id"); value != expected {
+			t.Errorf("%s: expected %q, got %q", query, expected, value)
+		}
+	}
+}
+
+// TestExtract_RawBody tests the whole body is the input and stays readable
+func TestExtract_RawBody(t *testing.T) {
+	const body = "\xac\xed\x00\x05sr\x00\x11java.util.HashMap"
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+
+	value, err := NewExtractor().Extract(req, "raw_body", "")
+	if err != nil || value != body {
+		t.Fatalf("Expected the raw body, got %q, err %v", value, err)
+	}
+	if rest, _ := io.ReadAll(req.Body); string(rest) != body {
+		t.Errorf("Expected the body to stay readable, got %q", rest)
+	}
+}
+
+// jsonString quotes s as a JSON string
+func jsonString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
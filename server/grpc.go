@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// gRPC status codes the server answers with
+const (
+	GRPCOK                 = 0
+	GRPCUnknown            = 2
+	GRPCInvalidArgument    = 3
+	GRPCDeadlineExceeded   = 4
+	GRPCNotFound           = 5
+	GRPCPermissionDenied   = 7
+	GRPCResourceExhausted  = 8
+	GRPCFailedPrecondition = 9
+	GRPCUnimplemented      = 12
+	GRPCInternal           = 13
+	GRPCUnavailable        = 14
+	GRPCUnauthenticated    = 16
+)
+
+// maxGRPCMessage caps the size of a single message of a streaming request,
+// as gRPC's default receive limit does
+const maxGRPCMessage = 4 << 20
+
+// grpcMessageKey is the context key of a gRPC request's decoded message
+type grpcMessageKey struct{}
+
+// GRPCError is a failed gRPC call: its status code and message
+type GRPCError struct {
+	Code    int
+	Message string
+}
+
+func (e *GRPCError) Error() string {
+	return e.Message
+}
+
+// ServeGRPC serves router on a plaintext HTTP/2 listener at addr, for gRPC
+// clients, with the reflection service when reflection isn't nil. Like
+// ServeAdmin, the first call sets up the listener and later ones swap in the
+// router and reflection of a reloaded config.
+func (s *Server) ServeGRPC(addr string, router *Router, reflection http.Handler) {
+	s.grpcRouter.Store(router)
+	s.grpcReflection.Store(&reflection)
+	if s.grpc != nil {
+		return
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true) // Answers anything but gRPC with the router's errors
+	protocols.SetUnencryptedHTTP2(true)
+	s.grpc = &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reflection streams, so it skips the router, which reads whole bodies
+			if reflection := *s.grpcReflection.Load(); reflection != nil && isReflectionPath(r.URL.Path) {
+				reflection.ServeHTTP(w, r)
+				return
+			}
+			s.grpcRouter.Load().ServeHTTP(w, r)
+		}),
+		Protocols:         protocols,
+		ReadHeaderTimeout: 15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// startGRPC serves the gRPC listener, if there is one, in the background
+func (s *Server) startGRPC() {
+	if s.grpc == nil {
+		return
+	}
+	listener := s.listener(s.grpc)
+	go func() {
+		if err := s.grpc.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("gRPC listener failed: %v", err)
+		}
+	}()
+	log.Printf("gRPC listening on %s (plaintext HTTP/2)", s.grpc.Addr)
+}
+
+// IsGRPC reports whether a request is a gRPC call, by its content type
+func IsGRPC(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// ReadGRPC reads the message of a unary gRPC request and decodes it by its
+// declared fields. The error is a *GRPCError.
+func ReadGRPC(r *http.Request, fields []config.GRPCField) (map[string]interface{}, error) {
+	body := bodyOf(r)
+	if body.err != nil {
+		return nil, &GRPCError{GRPCInternal, "failed to read request: " + body.err.Error()}
+	}
+
+	compressed, message, rest, err := readGRPCFrame(body.raw)
+	switch {
+	case err != nil:
+		return nil, &GRPCError{GRPCInternal, err.Error()}
+	case len(rest) > 0:
+		return nil, &GRPCError{GRPCUnimplemented, "only unary calls are supported: the request has more than one message"}
+	case compressed:
+		return nil, &GRPCError{GRPCUnimplemented, "compressed messages are not supported (grpc-accept-encoding: identity)"}
+	}
+
+	decoded, err := decodeProto(message, fields)
+	if err != nil {
+		return nil, &GRPCError{GRPCInternal, "failed to parse request message: " + err.Error()}
+	}
+	return decoded, nil
+}
+
+// readGRPCFrame splits the first length-prefixed message off data
+func readGRPCFrame(data []byte) (compressed bool, message, rest []byte, err error) {
+	if len(data) < 5 {
+		return false, nil, nil, fmt.Errorf("missing gRPC message: %d byte body", len(data))
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint64(size) > uint64(len(data)-5) {
+		return false, nil, nil, fmt.Errorf("truncated gRPC message: %d of %d bytes", len(data)-5, size)
+	}
+	return data[0] == 1, data[5 : 5+size], data[5+size:], nil
+}
+
+// appendGRPCFrame appends a message with its uncompressed length prefix
+func appendGRPCFrame(b, message []byte) []byte {
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(message)))
+	return append(b, message...)
+}
+
+// WithGRPCMessage stores a request's decoded message, for the grpc_field placement
+func WithGRPCMessage(r *http.Request, message map[string]interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), grpcMessageKey{}, message))
+}
+
+// extractGRPCField extracts a field of a gRPC request's message
+// Supports dot notation for nested messages: "filter.name"
+func (e *Extractor) extractGRPCField(r *http.Request, param string) (string, error) {
+	message, ok := r.Context().Value(grpcMessageKey{}).(map[string]interface{})
+	if !ok {
+		return "", &ExtractionError{
+			Placement: "grpc_field",
+			Param:     param,
+			Message:   "not a gRPC request",
+		}
+	}
+	return navigateJSON(message, param), nil
+}
+
+// SendGRPC answers a unary call with a response message whose result field
+// (1) holds result, then an OK status
+func (rb *ResponseBuilder) SendGRPC(w http.ResponseWriter, result string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+	w.Write(appendGRPCFrame(nil, appendStringField(nil, 1, result)))
+	setGRPCStatus(w, GRPCOK, "")
+}
+
+// SendGRPCStatus ends a call without a response message, with a status code and message
+func (rb *ResponseBuilder) SendGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+	setGRPCStatus(w, code, message)
+}
+
+// setGRPCStatus sets the status trailers of a call
+func setGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeGRPCMessage(message))
+	}
+}
+
+// encodeGRPCMessage percent-encodes a status message as gRPC requires
+func encodeGRPCMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// GRPCCode returns the gRPC status code for an HTTP error status
+func GRPCCode(status int) int {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return GRPCInvalidArgument
+	case http.StatusUnauthorized:
+		return GRPCUnauthenticated
+	case http.StatusForbidden:
+		return GRPCPermissionDenied
+	case http.StatusNotFound:
+		return GRPCNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed, http.StatusLocked:
+		return GRPCFailedPrecondition
+	case http.StatusTooManyRequests:
+		return GRPCResourceExhausted
+	case http.StatusNotImplemented:
+		return GRPCUnimplemented
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return GRPCUnavailable
+	case http.StatusGatewayTimeout:
+		return GRPCDeadlineExceeded
+	}
+	if status >= 500 {
+		return GRPCInternal
+	}
+	return GRPCUnknown
+}
+
+// readGRPCMessages reads the messages of a streaming request as they arrive,
+// calling handle for each until the client closes its side
+func readGRPCMessages(body io.Reader, handle func(message []byte) error) error {
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(body, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if header[0] == 1 {
+			return &GRPCError{GRPCUnimplemented, "compressed messages are not supported"}
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > maxGRPCMessage {
+			return &GRPCError{GRPCResourceExhausted, fmt.Sprintf("message of %d bytes exceeds %d bytes", size, maxGRPCMessage)}
+		}
+		message := make([]byte, size)
+		if _, err := io.ReadFull(body, message); err != nil {
+			return err
+		}
+		if err := handle(message); err != nil {
+			return err
+		}
+	}
+}
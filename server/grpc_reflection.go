@@ -0,0 +1,278 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// Paths of the reflection service, in its current and alpha versions
+const (
+	reflectionPath      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	reflectionAlphaPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// FieldDescriptorProto types of the field types a request can declare
+var descriptorTypes = map[string]uint64{
+	"double":  1,
+	"float":   2,
+	"int64":   3,
+	"uint64":  4,
+	"int32":   5,
+	"bool":    8,
+	"string":  9,
+	"message": 11,
+	"bytes":   12,
+	"uint32":  13,
+}
+
+// isReflectionPath reports whether path is the reflection service's
+func isReflectionPath(path string) bool {
+	return path == reflectionPath || path == reflectionAlphaPath
+}
+
+// grpcReflection serves server reflection, so clients like grpcurl can list
+// the grpc endpoints' services and describe their messages without .proto
+// files. Each package is described by one generated file.
+type grpcReflection struct {
+	services []string          // Full service names, in the order they are declared
+	files    map[string][]byte // Serialized FileDescriptorProtos by file name
+	symbols  map[string]string // File names by the full names they define
+}
+
+// protoPackage collects the services and messages of one package's file
+type protoPackage struct {
+	name     string
+	services []string
+	methods  map[string][][]byte // MethodDescriptorProtos by service
+	messages [][]byte
+	symbols  []string // Full names of the methods and messages
+}
+
+// NewGRPCReflection builds the reflection service of the grpc endpoints
+func NewGRPCReflection(endpoints []config.EndpointConfig) http.Handler {
+	refl := &grpcReflection{
+		files:   make(map[string][]byte),
+		symbols: make(map[string]string),
+	}
+
+	var packages []*protoPackage
+	byName := make(map[string]*protoPackage)
+	for _, endpoint := range endpoints {
+		if endpoint.Type != "grpc" {
+			continue
+		}
+		pkgName, service, method, ok := endpoint.GRPCMethod()
+		if !ok {
+			continue
+		}
+		pkg := byName[pkgName]
+		if pkg == nil {
+			pkg = &protoPackage{name: pkgName, methods: make(map[string][][]byte)}
+			byName[pkgName] = pkg
+			packages = append(packages, pkg)
+		}
+		if _, ok := pkg.methods[service]; !ok {
+			pkg.services = append(pkg.services, service)
+			refl.services = append(refl.services, qualify(pkgName, service))
+		}
+
+		var fields []config.GRPCField
+		if endpoint.GRPC != nil {
+			fields = endpoint.GRPC.Fields
+		}
+		request, response := method+"Request", method+"Response"
+		var m []byte
+		m = appendStringField(m, 1, method)
+		m = appendStringField(m, 2, "."+qualify(pkgName, request))
+		m = appendStringField(m, 3, "."+qualify(pkgName, response))
+		pkg.methods[service] = append(pkg.methods[service], m)
+		pkg.messages = append(pkg.messages,
+			describeMessage(request, qualify(pkgName, request), fields),
+			describeMessage(response, qualify(pkgName, response), []config.GRPCField{{Name: "result"}}))
+
+		pkg.symbols = append(pkg.symbols,
+			qualify(pkgName, service)+"."+method, qualify(pkgName, request), qualify(pkgName, response))
+	}
+
+	for _, pkg := range packages {
+		file := "flawfactory.proto"
+		if pkg.name != "" {
+			file = strings.ReplaceAll(pkg.name, ".", "/") + ".proto"
+		}
+
+		var fd []byte
+		fd = appendStringField(fd, 1, file)
+		fd = appendStringField(fd, 2, pkg.name)
+		for _, message := range pkg.messages {
+			fd = appendBytesField(fd, 4, message)
+		}
+		for _, service := range pkg.services {
+			var sd []byte
+			sd = appendStringField(sd, 1, service)
+			for _, method := range pkg.methods[service] {
+				sd = appendBytesField(sd, 2, method)
+			}
+			fd = appendBytesField(fd, 6, sd)
+			refl.symbols[qualify(pkg.name, service)] = file
+		}
+		fd = appendStringField(fd, 12, "proto3")
+		refl.files[file] = fd
+
+		for _, symbol := range pkg.symbols {
+			refl.symbols[symbol] = file
+		}
+	}
+
+	return refl
+}
+
+// describeMessage returns the DescriptorProto of a message, fullName being
+// its name with the package and any enclosing messages
+func describeMessage(name, fullName string, fields []config.GRPCField) []byte {
+	var d []byte
+	d = appendStringField(d, 1, name)
+	for i, field := range fields {
+		fieldType := field.FieldType()
+		var f []byte
+		f = appendStringField(f, 1, field.Name)
+		f = appendVarintField(f, 3, uint64(field.FieldNumber(i)))
+		f = appendVarintField(f, 4, 1) // LABEL_OPTIONAL
+		f = appendVarintField(f, 5, descriptorTypes[fieldType])
+		if fieldType == "message" {
+			nested := messageName(field.Name)
+			f = appendStringField(f, 6, "."+fullName+"."+nested)
+			d = appendBytesField(d, 3, describeMessage(nested, fullName+"."+nested, field.Fields))
+		}
+		d = appendBytesField(d, 2, f)
+	}
+	return d
+}
+
+// messageName returns the message type name of a message field: its name in
+// CamelCase, as in filter_opts → FilterOpts
+func messageName(field string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range field {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "Message"
+	}
+	return b.String()
+}
+
+// qualify prefixes name with its package, when there is one
+func qualify(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// ServeHTTP answers a reflection stream: a response for each request, as the
+// requests arrive, then an OK status once the client is done
+func (refl *grpcReflection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rb := NewResponseBuilder()
+	if r.Method != http.MethodPost || !IsGRPC(r) {
+		rb.SendGRPCStatus(w, GRPCInvalidArgument, "not a gRPC request")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+
+	err := readGRPCMessages(r.Body, func(message []byte) error {
+		response, err := refl.answer(message)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(appendGRPCFrame(nil, response)); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err != nil {
+		code := GRPCInternal
+		if grpcErr, ok := err.(*GRPCError); ok {
+			code = grpcErr.Code
+		}
+		log.Printf("gRPC reflection stream failed: %v", err)
+		setGRPCStatus(w, code, err.Error())
+		return
+	}
+	setGRPCStatus(w, GRPCOK, "")
+}
+
+// answer returns the ServerReflectionResponse to a ServerReflectionRequest
+func (refl *grpcReflection) answer(request []byte) ([]byte, error) {
+	fields, err := readProtoFields(request)
+	if err != nil {
+		return nil, &GRPCError{GRPCInvalidArgument, "failed to parse reflection request: " + err.Error()}
+	}
+
+	var response []byte
+	for _, field := range fields {
+		if field.number == 1 && field.wireType == wireBytes {
+			response = appendStringField(response, 1, string(field.bytes)) // valid_host
+		}
+	}
+	response = appendBytesField(response, 2, request) // original_request
+
+	for _, field := range fields {
+		if field.wireType != wireBytes {
+			continue
+		}
+		switch name := string(field.bytes); field.number {
+		case 3: // file_by_filename
+			return refl.fileResponse(response, refl.files[name], "file "+name), nil
+		case 4: // file_containing_symbol
+			return refl.fileResponse(response, refl.files[refl.symbols[name]], "symbol "+name), nil
+		case 5: // file_containing_extension
+			return appendErrorResponse(response, GRPCNotFound, "extensions are not declared"), nil
+		case 6: // all_extension_numbers_of_type
+			if refl.symbols[name] == "" {
+				return appendErrorResponse(response, GRPCNotFound, "type "+name+" not found"), nil
+			}
+			return appendBytesField(response, 5, appendStringField(nil, 1, name)), nil
+		case 7: // list_services
+			var list []byte
+			for _, service := range refl.services {
+				list = appendBytesField(list, 1, appendStringField(nil, 1, service))
+			}
+			return appendBytesField(response, 6, list), nil
+		}
+	}
+	return appendErrorResponse(response, GRPCUnimplemented, "unsupported reflection request"), nil
+}
+
+// fileResponse appends a file_descriptor_response holding file, or an error
+// response when there is no such file
+func (refl *grpcReflection) fileResponse(response, file []byte, what string) []byte {
+	if file == nil {
+		return appendErrorResponse(response, GRPCNotFound, what+" not found")
+	}
+	return appendBytesField(response, 4, appendBytesField(nil, 1, file))
+}
+
+// appendErrorResponse appends the error_response of a failed reflection request
+func appendErrorResponse(response []byte, code int, message string) []byte {
+	var e []byte
+	e = appendVarintField(e, 1, uint64(code))
+	e = appendStringField(e, 2, message)
+	return appendBytesField(response, 7, e)
+}
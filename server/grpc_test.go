@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// grpcRequest builds a unary gRPC request carrying message
+func grpcRequest(path string, message []byte) *http.Request {
+	req := httptest.NewRequest("POST", path, bytes.NewReader(appendGRPCFrame(nil, message)))
+	req.Header.Set("Content-Type", "application/grpc")
+	return req
+}
+
+// TestReadGRPC tests request messages are decoded by their declared fields
+// and read by the grpc_field placement, with dot notation for nested ones
+func TestReadGRPC(t *testing.T) {
+	fields := []config.GRPCField{
+		{Name: "query"},
+		{Name: "filter", Type: "message", Number: 4, Fields: []config.GRPCField{{Name: "name"}, {Name: "admin", Type: "bool"}}},
+		{Name: "limit", Type: "int32"},
+		{Name: "score", Type: "double", Number: 5},
+	}
+
+	var filter []byte
+	filter = appendStringField(filter, 1, "' OR 1=1--")
+	filter = appendVarintField(filter, 2, 1)
+	var message []byte
+	message = appendStringField(message, 1, "1 UNION SELECT 1")
+	message = appendBytesField(message, 4, filter)
+	message = appendVarintField(message, 3, uint64(math.MaxUint64)) // -1 as an int32
+	message = appendStringField(message, 9, "ignored")              // Undeclared fields are skipped
+	message = appendTag(message, 5, wireI64)
+	message = append(message, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f) // 1.5
+
+	req := grpcRequest("/shop.Products/Search", message)
+	decoded, err := ReadGRPC(req, fields)
+	if err != nil {
+		t.Fatalf("Expected the message to decode, got %v", err)
+	}
+	req = WithGRPCMessage(req, decoded)
+
+	extractor := NewExtractor()
+	tests := []struct {
+		param    string
+		expected string
+	}{
+		{"query", "1 UNION SELECT 1"},
+		{"filter.name", "' OR 1=1--"},
+		{"filter.admin", "true"},
+		{"limit", "-1"},
+		{"score", "1.5"},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if value, err := extractor.Extract(req, "grpc_field", tt.param); err != nil || value != tt.expected {
+			t.Errorf("%s: expected %q, got %q, err %v", tt.param, tt.expected, value, err)
+		}
+	}
+
+	if _, err := extractor.Extract(httptest.NewRequest("POST", "/", nil), "grpc_field", "query"); err == nil {
+		t.Error("Expected an error for a request that isn't a gRPC call")
+	}
+
+	invalid := []struct {
+		name string
+		body []byte
+		code int
+	}{
+		{"wire type", appendGRPCFrame(nil, appendVarintField(nil, 1, 7)), GRPCInternal},
+		{"truncated field", appendGRPCFrame(nil, appendStringField(nil, 1, "abc")[:3]), GRPCInternal},
+		{"truncated frame", appendGRPCFrame(nil, message)[:10], GRPCInternal},
+		{"two messages", appendGRPCFrame(appendGRPCFrame(nil, nil), nil), GRPCUnimplemented},
+		{"compressed", []byte{1, 0, 0, 0, 0}, GRPCUnimplemented},
+	}
+	for _, tt := range invalid {
+		req := httptest.NewRequest("POST", "/shop.Products/Search", bytes.NewReader(tt.body))
+		_, err := ReadGRPC(req, fields)
+		if grpcErr, ok := err.(*GRPCError); !ok || grpcErr.Code != tt.code {
+			t.Errorf("%s: expected status %d, got %v", tt.name, tt.code, err)
+		}
+	}
+}
+
+// TestSendGRPC tests responses carry the result in field 1 of a framed
+// message, with the status in trailers
+func TestSendGRPC(t *testing.T) {
+	rb := NewResponseBuilder()
+
+	rec := httptest.NewRecorder()
+	rb.SendGRPC(rec, `{"rows":1}`)
+	res := rec.Result()
+	body, _ := io.ReadAll(res.Body)
+	if res.Header.Get("Content-Type") != "application/grpc" || res.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("Expected an OK gRPC response, got %v with trailers %v", res.Header, res.Trailer)
+	}
+	_, message, rest, err := readGRPCFrame(body)
+	if err != nil || len(rest) != 0 || !bytes.Equal(message, appendStringField(nil, 1, `{"rows":1}`)) {
+		t.Errorf("Expected one message holding the result, got %q (%v)", body, err)
+	}
+
+	rec = httptest.NewRecorder()
+	rb.SendGRPCStatus(rec, GRPCCode(http.StatusForbidden), "denied: 100% sure")
+	res = rec.Result()
+	if res.Trailer.Get("Grpc-Status") != "7" || res.Trailer.Get("Grpc-Message") != "denied: 100%25 sure" {
+		t.Errorf("Expected PERMISSION_DENIED with an encoded message, got %v", res.Trailer)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected no message with an error status, got %q", rec.Body.Bytes())
+	}
+}
+
+// TestGRPCReflection tests reflection lists the services and describes each
+// package's messages in one file
+func TestGRPCReflection(t *testing.T) {
+	endpoints := []config.EndpointConfig{
+		{Path: "/shop.v1.Products/Search", Type: "grpc", GRPC: &config.GRPCMethodConfig{Fields: []config.GRPCField{
+			{Name: "filter_opts", Type: "message", Fields: []config.GRPCField{{Name: "name"}}},
+		}}},
+		{Path: "/shop.v1.Orders/Get", Type: "grpc"},
+		{Path: "/search", Type: ""},
+	}
+	refl := NewGRPCReflection(endpoints)
+
+	var body []byte
+	body = appendGRPCFrame(body, appendStringField(nil, 7, "*"))                     // list_services
+	body = appendGRPCFrame(body, appendStringField(nil, 4, "shop.v1.Orders"))        // file_containing_symbol
+	body = appendGRPCFrame(body, appendStringField(nil, 4, "shop.v1.SearchRequest")) // ...of a message
+	body = appendGRPCFrame(body, appendStringField(nil, 3, "missing.proto"))         // file_by_filename
+	req := httptest.NewRequest("POST", reflectionPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc")
+	rec := httptest.NewRecorder()
+	refl.ServeHTTP(rec, req)
+
+	if status := rec.Result().Trailer.Get("Grpc-Status"); status != "0" {
+		t.Fatalf("Expected an OK stream, got status %s", status)
+	}
+	var responses [][]protoField
+	for data := rec.Body.Bytes(); len(data) > 0; {
+		_, message, rest, err := readGRPCFrame(data)
+		if err != nil {
+			t.Fatalf("Expected framed responses, got %v", err)
+		}
+		fields, err := readProtoFields(message)
+		if err != nil {
+			t.Fatalf("Expected a response message, got %v", err)
+		}
+		responses = append(responses, fields)
+		data = rest
+	}
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses, got %d", len(responses))
+	}
+
+	find := func(fields []protoField, number int) []byte {
+		for _, field := range fields {
+			if field.number == number {
+				return field.bytes
+			}
+		}
+		return nil
+	}
+
+	list := find(responses[0], 6)
+	services, _ := readProtoFields(list)
+	if len(services) != 2 || !bytes.Contains(services[0].bytes, []byte("shop.v1.Products")) || !bytes.Contains(services[1].bytes, []byte("shop.v1.Orders")) {
+		t.Errorf("Expected the two services, got %q", list)
+	}
+
+	for i, symbol := range []string{"shop.v1.Orders", "shop.v1.SearchRequest"} {
+		file := find(responses[i+1], 4)
+		for _, want := range []string{"shop/v1.proto", "Products", "Orders", "GetResponse", "FilterOpts", ".shop.v1.SearchRequest.FilterOpts", "proto3"} {
+			if !bytes.Contains(file, []byte(want)) {
+				t.Errorf("%s: expected the file to describe %s, got %q", symbol, want, file)
+			}
+		}
+	}
+
+	errResponse, _ := readProtoFields(find(responses[3], 7))
+	if len(errResponse) == 0 || errResponse[0].varint != GRPCNotFound {
+		t.Errorf("Expected NOT_FOUND for a missing file, got %v", errResponse)
+	}
+}
+
+// TestGRPCReflection_MessageTooLarge tests a stream announcing a message over
+// the cap ends with RESOURCE_EXHAUSTED, without reading or allocating it
+func TestGRPCReflection_MessageTooLarge(t *testing.T) {
+	refl := NewGRPCReflection(nil)
+
+	body := []byte{0, 0xFF, 0xFF, 0xFF, 0xFF}
+	req := httptest.NewRequest("POST", reflectionPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc")
+	rec := httptest.NewRecorder()
+	refl.ServeHTTP(rec, req)
+
+	if status := rec.Result().Trailer.Get("Grpc-Status"); status != "8" {
+		t.Errorf("Expected RESOURCE_EXHAUSTED, got status %s", status)
+	}
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// PCAP file settings: packets are raw IP, without a link layer
+const (
+	pcapMagic    = 0xa1b2c3d4
+	pcapSnapLen  = 262144
+	linkTypeRaw  = 101
+	maxSegment   = 32 * 1024 // Payload bytes per synthesized TCP segment
+	tcpWindow    = 65535
+	defaultTTL   = 64
+	protocolTCP  = 6
+	tcpFlagFIN   = 0x01
+	tcpFlagSYN   = 0x02
+	tcpFlagPSH   = 0x08
+	tcpFlagACK   = 0x10
+	ipv4Header   = 20
+	ipv6Header   = 40
+	tcpHeaderLen = 20
+)
+
+// packetCapture keeps the packets of a listener's connections, dropping the
+// oldest past limit bytes
+type packetCapture struct {
+	mu      sync.Mutex
+	packets []capturedPacket // Oldest first
+	size    int64
+	limit   int64
+}
+
+// capturedPacket is an IP packet and when it was sent
+type capturedPacket struct {
+	time time.Time
+	data []byte
+}
+
+// add keeps a packet
+func (p *packetCapture) add(packet capturedPacket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.packets = append(p.packets, packet)
+	p.size += int64(len(packet.data))
+	for p.size > p.limit && len(p.packets) > 1 {
+		p.size -= int64(len(p.packets[0].data))
+		p.packets[0] = capturedPacket{}
+		p.packets = p.packets[1:]
+	}
+}
+
+// clear drops every packet
+func (p *packetCapture) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.packets, p.size = nil, 0
+}
+
+// WritePCAP writes the recorded packets as a pcap file, which Wireshark and
+// tcpdump read. Without PCAP recording the file has no packets.
+func (c *Capture) WritePCAP(w io.Writer) error {
+	var packets []capturedPacket
+	if c.RecordsPCAP() {
+		c.packets.mu.Lock()
+		packets = append(packets, c.packets.packets...)
+		c.packets.mu.Unlock()
+	}
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]byte, 16)
+	for _, packet := range packets {
+		binary.LittleEndian.PutUint32(record[0:], uint32(packet.time.Unix()))
+		binary.LittleEndian.PutUint32(record[4:], uint32(packet.time.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(record[8:], uint32(len(packet.data)))
+		binary.LittleEndian.PutUint32(record[12:], uint32(len(packet.data)))
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+		if _, err := w.Write(packet.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Listener returns l recording the TCP streams of its connections when the
+// capture records PCAP, or l itself. Streams are recorded as they are on the
+// wire, so HTTPS traffic is encrypted.
+func (c *Capture) Listener(l net.Listener) net.Listener {
+	if !c.RecordsPCAP() || l == nil {
+		return l
+	}
+	return &captureListener{Listener: l, packets: c.packets}
+}
+
+// captureListener records the connections it accepts
+type captureListener struct {
+	net.Listener
+	packets *packetCapture
+}
+
+func (l *captureListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return conn, err
+	}
+	flow, ok := newTCPFlow(l.packets, conn)
+	if !ok {
+		return conn, nil
+	}
+	return &captureConn{Conn: conn, flow: flow}, nil
+}
+
+// captureConn records what is read from and written to a connection
+type captureConn struct {
+	net.Conn
+	flow  *tcpFlow
+	close sync.Once
+}
+
+func (c *captureConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.flow.send(true, tcpFlagPSH|tcpFlagACK, b[:n])
+	}
+	return n, err
+}
+
+func (c *captureConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.flow.send(false, tcpFlagPSH|tcpFlagACK, b[:n])
+	}
+	return n, err
+}
+
+func (c *captureConn) Close() error {
+	c.close.Do(c.flow.finish)
+	return c.Conn.Close()
+}
+
+// tcpFlow synthesizes the packets of one connection: a handshake when it is
+// accepted, a segment per read or write, and a teardown when it is closed
+type tcpFlow struct {
+	packets        *packetCapture
+	client, server netip.AddrPort
+
+	mu                   sync.Mutex
+	clientSeq, serverSeq uint32
+	id                   uint16 // IPv4 identification
+}
+
+// newTCPFlow starts the flow of a TCP connection, recording its handshake.
+// It reports false for connections that aren't TCP.
+func newTCPFlow(packets *packetCapture, conn net.Conn) (*tcpFlow, bool) {
+	remote, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, false
+	}
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return nil, false
+	}
+	client, server := remote.AddrPort(), local.AddrPort()
+	client = netip.AddrPortFrom(client.Addr().Unmap(), client.Port())
+	server = netip.AddrPortFrom(server.Addr().Unmap(), server.Port())
+	if client.Addr().Is4() != server.Addr().Is4() {
+		return nil, false
+	}
+
+	f := &tcpFlow{packets: packets, client: client, server: server, clientSeq: rand.Uint32(), serverSeq: rand.Uint32()}
+	f.send(true, tcpFlagSYN, nil)
+	f.send(false, tcpFlagSYN|tcpFlagACK, nil)
+	f.send(true, tcpFlagACK, nil)
+	return f, true
+}
+
+// finish records the teardown of a connection the server closed
+func (f *tcpFlow) finish() {
+	f.send(false, tcpFlagFIN|tcpFlagACK, nil)
+	f.send(true, tcpFlagFIN|tcpFlagACK, nil)
+	f.send(false, tcpFlagACK, nil)
+}
+
+// send records payload sent by the client or the server, in segments of at
+// most maxSegment bytes
+func (f *tcpFlow) send(fromClient bool, flags byte, payload []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for {
+		n := min(len(payload), maxSegment)
+		f.packets.add(capturedPacket{time: now, data: f.segment(fromClient, flags, payload[:n])})
+		payload = payload[n:]
+		if len(payload) == 0 {
+			return
+		}
+	}
+}
+
+// segment builds one IP packet of the flow and advances its sequence numbers
+func (f *tcpFlow) segment(fromClient bool, flags byte, payload []byte) []byte {
+	src, dst := f.server, f.client
+	seq, ack := &f.serverSeq, f.clientSeq
+	if fromClient {
+		src, dst = f.client, f.server
+		seq, ack = &f.clientSeq, f.serverSeq
+	}
+	if flags&tcpFlagSYN != 0 && flags&tcpFlagACK == 0 {
+		ack = 0
+	}
+
+	tcp := make([]byte, tcpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], src.Port())
+	binary.BigEndian.PutUint16(tcp[2:], dst.Port())
+	binary.BigEndian.PutUint32(tcp[4:], *seq)
+	binary.BigEndian.PutUint32(tcp[8:], ack)
+	tcp[12] = (tcpHeaderLen / 4) << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], tcpWindow)
+	copy(tcp[tcpHeaderLen:], payload)
+	binary.BigEndian.PutUint16(tcp[16:], tcpChecksum(src.Addr(), dst.Addr(), tcp))
+
+	*seq += uint32(len(payload))
+	if flags&(tcpFlagSYN|tcpFlagFIN) != 0 {
+		*seq++ // SYN and FIN each take a sequence number
+	}
+
+	if src.Addr().Is4() {
+		f.id++
+		ip := make([]byte, ipv4Header, ipv4Header+len(tcp))
+		ip[0] = 0x45 // Version 4, 5 words of header
+		binary.BigEndian.PutUint16(ip[2:], uint16(ipv4Header+len(tcp)))
+		binary.BigEndian.PutUint16(ip[4:], f.id)
+		binary.BigEndian.PutUint16(ip[6:], 0x4000) // Don't fragment
+		ip[8] = defaultTTL
+		ip[9] = protocolTCP
+		srcIP, dstIP := src.Addr().As4(), dst.Addr().As4()
+		copy(ip[12:], srcIP[:])
+		copy(ip[16:], dstIP[:])
+		binary.BigEndian.PutUint16(ip[10:], ^onesComplementSum(0, ip))
+		return append(ip, tcp...)
+	}
+	ip := make([]byte, ipv6Header, ipv6Header+len(tcp))
+	ip[0] = 0x60 // Version 6
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(tcp)))
+	ip[6] = protocolTCP
+	ip[7] = defaultTTL
+	srcIP, dstIP := src.Addr().As16(), dst.Addr().As16()
+	copy(ip[8:], srcIP[:])
+	copy(ip[24:], dstIP[:])
+	return append(ip, tcp...)
+}
+
+// tcpChecksum computes a TCP segment's checksum over its IP pseudo-header
+func tcpChecksum(src, dst netip.Addr, tcp []byte) uint16 {
+	var sum uint32
+	sum = onesComplementAdd(sum, src.AsSlice())
+	sum = onesComplementAdd(sum, dst.AsSlice())
+	var lengths [8]byte
+	binary.BigEndian.PutUint32(lengths[0:], uint32(len(tcp)))
+	lengths[7] = protocolTCP
+	if src.Is4() {
+		// Zero, protocol and the 16-bit TCP length
+		sum = onesComplementAdd(sum, []byte{0, protocolTCP, lengths[2], lengths[3]})
+	} else {
+		sum = onesComplementAdd(sum, lengths[:])
+	}
+	return ^onesComplementSum(sum, tcp)
+}
+
+// onesComplementSum adds data to sum as 16-bit words and folds the carries
+func onesComplementSum(sum uint32, data []byte) uint16 {
+	sum = onesComplementAdd(sum, data)
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
+
+// onesComplementAdd adds data to sum as big-endian 16-bit words, padding an
+// odd last byte with zero
+func onesComplementAdd(sum uint32, data []byte) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
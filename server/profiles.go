@@ -0,0 +1,281 @@
+package server
+
+import (
+	"cmp"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/logger"
+)
+
+// Bounds on what profiles keep, so a scanner spraying paths or rotating user
+// agents can't grow them without limit
+const (
+	maxProfiles          = 10000 // Clients profiled; the least recently seen is dropped for a new one
+	maxProfileEndpoints  = 500   // Endpoints counted per client; the rest count under otherEndpoints
+	maxProfileUserAgents = 20
+	maxProfileSessions   = 50
+)
+
+// otherEndpoints counts a client's requests past maxProfileEndpoints
+const otherEndpoints = "(other)"
+
+// ClientProfile aggregates what one client, by IP, did to the lab
+type ClientProfile struct {
+	Client      string           `json:"client"`
+	FirstSeen   time.Time        `json:"first_seen"`
+	LastSeen    time.Time        `json:"last_seen"`
+	Requests    int64            `json:"requests"`
+	Errors      int64            `json:"errors"`                 // Requests answered with a 4xx or 5xx
+	Attacks     int64            `json:"attacks"`                // Requests carrying an attack payload
+	Exploits    int64            `json:"exploits"`               // Of those, the ones a module reported exploitable
+	Endpoints   map[string]int64 `json:"endpoints"`              // Requests by "METHOD path", unmatched paths included
+	Modules     map[string]int64 `json:"modules,omitempty"`      // Attacks by the module they reached
+	AttackTypes map[string]int64 `json:"attack_types,omitempty"` // Attacks by attack type
+	Tools       []string         `json:"tools,omitempty"`        // Tools fingerprinted from user agents and headers
+	UserAgents  []string         `json:"user_agents,omitempty"`
+	Sessions    []string         `json:"sessions,omitempty"` // Session IDs sent, with app.sessions or session auth
+}
+
+// Profiles aggregates the request log into a profile per client, for the
+// admin API. The server keeps one for its lifetime, so profiles outlive reloads.
+type Profiles struct {
+	mu       sync.Mutex
+	profiles map[string]*ClientProfile
+}
+
+// NewProfiles creates an empty set of profiles
+func NewProfiles() *Profiles {
+	return &Profiles{profiles: make(map[string]*ClientProfile)}
+}
+
+// record adds a request, whose log entry is entry, to its client's profile.
+// sessionCookie names the cookie carrying session IDs, "" for none.
+func (p *Profiles) record(r *http.Request, entry *logger.RequestLog, sessionCookie string) {
+	if p == nil {
+		return
+	}
+	client := profileClient(r)
+	now := entry.Time
+	tools := fingerprintTools(r)
+	session := ""
+	if sessionCookie != "" {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			session = cookie.Value
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile, ok := p.profiles[client]
+	if !ok {
+		if len(p.profiles) >= maxProfiles {
+			p.evict()
+		}
+		profile = &ClientProfile{Client: client, FirstSeen: now, Endpoints: make(map[string]int64)}
+		p.profiles[client] = profile
+	}
+	profile.LastSeen = now
+	profile.Requests++
+	if entry.StatusCode >= 400 {
+		profile.Errors++
+	}
+
+	endpoint := r.Method + " " + r.URL.Path
+	if _, ok := profile.Endpoints[endpoint]; !ok && len(profile.Endpoints) >= maxProfileEndpoints {
+		endpoint = otherEndpoints
+	}
+	profile.Endpoints[endpoint]++
+
+	if len(entry.Attacks) > 0 {
+		profile.Attacks++
+		if profile.Modules == nil {
+			profile.Modules, profile.AttackTypes = make(map[string]int64), make(map[string]int64)
+		}
+		exploited := false
+		for _, attack := range entry.Attacks {
+			profile.Modules[attack.Module]++
+			profile.AttackTypes[attack.Type]++
+			exploited = exploited || attack.Exploitable
+		}
+		if exploited {
+			profile.Exploits++
+		}
+	}
+
+	for _, tool := range tools {
+		if !slices.Contains(profile.Tools, tool) {
+			profile.Tools = append(profile.Tools, tool)
+		}
+	}
+	if agent := r.UserAgent(); agent != "" && !slices.Contains(profile.UserAgents, agent) && len(profile.UserAgents) < maxProfileUserAgents {
+		profile.UserAgents = append(profile.UserAgents, agent)
+	}
+	if session != "" && !slices.Contains(profile.Sessions, session) && len(profile.Sessions) < maxProfileSessions {
+		profile.Sessions = append(profile.Sessions, session)
+	}
+}
+
+// evict drops the least recently seen profile
+func (p *Profiles) evict() {
+	oldest := ""
+	for client, profile := range p.profiles {
+		if oldest == "" || profile.LastSeen.Before(p.profiles[oldest].LastSeen) {
+			oldest = client
+		}
+	}
+	delete(p.profiles, oldest)
+}
+
+// List returns a copy of every profile, the clients that attacked most first
+func (p *Profiles) List() []ClientProfile {
+	if p == nil {
+		return []ClientProfile{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]ClientProfile, 0, len(p.profiles))
+	for _, profile := range p.profiles {
+		list = append(list, profile.clone())
+	}
+	slices.SortFunc(list, func(a, b ClientProfile) int {
+		return cmp.Or(
+			cmp.Compare(b.Exploits, a.Exploits),
+			cmp.Compare(b.Attacks, a.Attacks),
+			cmp.Compare(b.Requests, a.Requests),
+			cmp.Compare(a.Client, b.Client),
+		)
+	})
+	return list
+}
+
+// Get returns a copy of a client's profile
+func (p *Profiles) Get(client string) (ClientProfile, bool) {
+	if p == nil {
+		return ClientProfile{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	profile, ok := p.profiles[client]
+	if !ok {
+		return ClientProfile{}, false
+	}
+	return profile.clone(), true
+}
+
+// Clear forgets every profile
+func (p *Profiles) Clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profiles = make(map[string]*ClientProfile)
+}
+
+// clone copies a profile, so callers can read it without the lock
+func (c *ClientProfile) clone() ClientProfile {
+	copied := *c
+	copied.Endpoints = maps.Clone(c.Endpoints)
+	copied.Modules = maps.Clone(c.Modules)
+	copied.AttackTypes = maps.Clone(c.AttackTypes)
+	copied.Tools = slices.Clone(c.Tools)
+	copied.UserAgents = slices.Clone(c.UserAgents)
+	copied.Sessions = slices.Clone(c.Sessions)
+	return copied
+}
+
+// profileClient identifies a request's client by IP, like coverage and chains
+func profileClient(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// toolSignatures name tools by a lowercase fragment of the user agents they send
+var toolSignatures = []struct {
+	fragment, tool string
+}{
+	{"sqlmap", "sqlmap"},
+	{"nikto", "Nikto"},
+	{"nmap scripting engine", "Nmap"},
+	{"nuclei", "Nuclei"},
+	{"fuzz faster u fool", "ffuf"},
+	{"ffuf", "ffuf"},
+	{"gobuster", "Gobuster"},
+	{"feroxbuster", "feroxbuster"},
+	{"dirbuster", "DirBuster"},
+	{"wfuzz", "Wfuzz"},
+	{"wpscan", "WPScan"},
+	{"commix", "Commix"},
+	{"hydra", "Hydra"},
+	{"masscan", "masscan"},
+	{"zgrab", "ZGrab"},
+	{"whatweb", "WhatWeb"},
+	{"skipfish", "Skipfish"},
+	{"arachni", "Arachni"},
+	{"openvas", "OpenVAS"},
+	{"acunetix", "Acunetix"},
+	{"netsparker", "Netsparker"},
+	{"burp", "Burp Suite"},
+	{"zaproxy", "OWASP ZAP"},
+	{"xsstrike", "XSStrike"},
+	{"dalfox", "Dalfox"},
+	{"jaeles", "Jaeles"},
+	{"interactsh", "interactsh"},
+	{"postmanruntime", "Postman"},
+	{"insomnia", "Insomnia"},
+	{"httpie", "HTTPie"},
+	{"curl/", "curl"},
+	{"wget/", "Wget"},
+	{"python-requests", "Python requests"},
+	{"python-urllib", "Python urllib"},
+	{"aiohttp", "Python aiohttp"},
+	{"go-http-client", "Go net/http"},
+	{"okhttp", "OkHttp"},
+	{"java/", "Java"},
+	{"ruby", "Ruby"},
+	{"powershell", "PowerShell"},
+}
+
+// toolHeaders name tools by a header only they send
+var toolHeaders = []struct {
+	header, tool string
+}{
+	{"Acunetix-Product", "Acunetix"},
+	{"Acunetix-Aspect", "Acunetix"},
+	{"X-Wvs-Id", "Acunetix"},
+	{"X-Scanner", "Netsparker"},
+	{"X-Burp-Extension", "Burp Suite"},
+	{"X-ZAP-Scan-ID", "OWASP ZAP"},
+}
+
+// fingerprintTools names the tools a request looks like it was sent by, from
+// its user agent and the headers some scanners add
+func fingerprintTools(r *http.Request) []string {
+	var tools []string
+	add := func(tool string) {
+		if !slices.Contains(tools, tool) {
+			tools = append(tools, tool)
+		}
+	}
+	agent := strings.ToLower(r.UserAgent())
+	for _, signature := range toolSignatures {
+		if strings.Contains(agent, signature.fragment) {
+			add(signature.tool)
+		}
+	}
+	for _, signature := range toolHeaders {
+		if r.Header.Get(signature.header) != "" {
+			add(signature.tool)
+		}
+	}
+	return tools
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/logger"
+)
+
+// TestProfiles tests requests are aggregated per client, with the endpoints,
+// attacks, tools and sessions each used
+func TestProfiles(t *testing.T) {
+	router := NewRouter(nil)
+	router.profiles = NewProfiles()
+	router.ProfileSessions("sid")
+	router.HandleFunc("GET", "/search", func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "shoes" {
+			logger.Classify(r, logger.Attack{Module: "sql_injection", Type: "sql_injection", Exploitable: q == "' OR 1=1--", Payload: q})
+		}
+		w.Write([]byte("ok"))
+	})
+
+	send := func(client, target, agent string, header ...string) {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = client + ":40000"
+		req.Header.Set("User-Agent", agent)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("10.0.0.1", "/search?q=shoes", "Mozilla/5.0", "Cookie", "sid=abc")
+	send("10.0.0.1", "/search?q=%27%20OR%201%3D1--", "sqlmap/1.8#stable (https://sqlmap.org)")
+	send("10.0.0.1", "/admin", "sqlmap/1.8#stable (https://sqlmap.org)")
+	send("10.0.0.2", "/search?q=%27", "curl/8.5.0", "Acunetix-Product", "WVS/14")
+
+	profiles := router.profiles.List()
+	if len(profiles) != 2 || profiles[0].Client != "10.0.0.1" {
+		t.Fatalf("Expected the exploiting client first of 2, got %+v", profiles)
+	}
+	first := profiles[0]
+	if first.Requests != 3 || first.Errors != 1 || first.Attacks != 1 || first.Exploits != 1 {
+		t.Errorf("Expected 3 requests, 1 error, 1 exploit, got %+v", first)
+	}
+	if first.Endpoints["GET /search"] != 2 || first.Endpoints["GET /admin"] != 1 || first.Modules["sql_injection"] != 1 {
+		t.Errorf("Expected the endpoints probed and modules triggered, got %v and %v", first.Endpoints, first.Modules)
+	}
+	if !slices.Equal(first.Tools, []string{"sqlmap"}) || len(first.UserAgents) != 2 || !slices.Equal(first.Sessions, []string{"abc"}) {
+		t.Errorf("Expected sqlmap, 2 user agents and session abc, got %v, %v, %v", first.Tools, first.UserAgents, first.Sessions)
+	}
+	if second, ok := router.profiles.Get("10.0.0.2"); !ok || !slices.Equal(second.Tools, []string{"curl", "Acunetix"}) || second.Exploits != 0 {
+		t.Errorf("Expected curl and Acunetix without exploits, got %+v", second)
+	}
+
+	// Copies don't share the profile's maps
+	first.Endpoints["GET /search"] = 100
+	if again, _ := router.profiles.Get("10.0.0.1"); again.Endpoints["GET /search"] != 2 {
+		t.Error("Expected the profile unchanged by its copy")
+	}
+
+	router.profiles.Clear()
+	if _, ok := router.profiles.Get("10.0.0.1"); ok {
+		t.Error("Expected the profiles cleared")
+	}
+}
+
+// TestProfiles_Bounds tests a client spraying paths is counted under
+// otherEndpoints past the limit
+func TestProfiles_Bounds(t *testing.T) {
+	profiles := NewProfiles()
+	for i := range maxProfileEndpoints + 5 {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/probe/%d", i), nil)
+		profiles.record(req, &logger.RequestLog{StatusCode: 404}, "")
+	}
+	profile, _ := profiles.Get("192.0.2.1")
+	if len(profile.Endpoints) != maxProfileEndpoints+1 || profile.Endpoints[otherEndpoints] != 5 || profile.Errors != maxProfileEndpoints+5 {
+		t.Errorf("Expected %d endpoints and 5 others, got %d and %d", maxProfileEndpoints, len(profile.Endpoints), profile.Endpoints[otherEndpoints])
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// Protobuf wire types
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// errTruncated is returned for a message that ends inside a field
+var errTruncated = errors.New("truncated message")
+
+// appendVarint appends v as a base 128 varint
+func appendVarint(b []byte, v uint64) []byte {
+	return binary.AppendUvarint(b, v)
+}
+
+// appendTag appends the key of field number with a wire type
+func appendTag(b []byte, number int, wireType int) []byte {
+	return appendVarint(b, uint64(number)<<3|uint64(wireType))
+}
+
+// appendBytesField appends a length-delimited field: a string, bytes or a message
+func appendBytesField(b []byte, number int, value []byte) []byte {
+	b = appendTag(b, number, wireBytes)
+	b = appendVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// appendStringField appends a string field, unless it is empty like proto3 leaves it
+func appendStringField(b []byte, number int, value string) []byte {
+	if value == "" {
+		return b
+	}
+	return appendBytesField(b, number, []byte(value))
+}
+
+// appendVarintField appends a varint field, unless it is zero like proto3 leaves it
+func appendVarintField(b []byte, number int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = appendTag(b, number, wireVarint)
+	return appendVarint(b, value)
+}
+
+// protoField is a field read from the wire, before its declared type is applied
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64 // Of varint, i64 and i32 fields
+	bytes    []byte // Of length-delimited fields
+}
+
+// readProtoFields splits a message into its fields, in wire order
+func readProtoFields(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		field := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		if field.number < 1 {
+			return nil, fmt.Errorf("invalid field number %d", field.number)
+		}
+
+		switch field.wireType {
+		case wireVarint:
+			field.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			data = data[n:]
+		case wireI64:
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			field.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireI32:
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			field.varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return nil, errTruncated
+			}
+			field.bytes = data[n : n+int(size)]
+			data = data[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", field.wireType, field.number)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// decodeProto decodes a message by its declared fields into the values JSON
+// decoding gives, for dot notation: strings, numbers, booleans and, for
+// messages, maps. Undeclared fields are skipped like a real server would; a
+// field sent with another wire type than declared is an error.
+func decodeProto(data []byte, declared []config.GRPCField) (map[string]interface{}, error) {
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	message := make(map[string]interface{})
+	for _, field := range fields {
+		var decl config.GRPCField
+		found := false
+		for i, d := range declared {
+			if d.FieldNumber(i) == field.number {
+				decl, found = d, true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		fieldType := decl.FieldType()
+		if field.wireType != protoWireType(fieldType) {
+			return nil, fmt.Errorf("field %s (%d) is %s, but was sent with wire type %d", decl.Name, field.number, fieldType, field.wireType)
+		}
+		switch fieldType {
+		case "string", "bytes":
+			message[decl.Name] = string(field.bytes)
+		case "message":
+			// A message sent more than once is merged, as protobuf does
+			nested, err := decodeProto(field.bytes, decl.Fields)
+			if err != nil {
+				return nil, fmt.Errorf("in %s: %w", decl.Name, err)
+			}
+			if prev, ok := message[decl.Name].(map[string]interface{}); ok {
+				for key, value := range nested {
+					prev[key] = value
+				}
+				nested = prev
+			}
+			message[decl.Name] = nested
+		case "bool":
+			message[decl.Name] = field.varint != 0
+		case "int32":
+			message[decl.Name] = int64(int32(field.varint))
+		case "int64":
+			message[decl.Name] = int64(field.varint)
+		case "uint32":
+			message[decl.Name] = uint64(uint32(field.varint))
+		case "uint64":
+			message[decl.Name] = field.varint
+		case "double":
+			message[decl.Name] = math.Float64frombits(field.varint)
+		case "float":
+			message[decl.Name] = float64(math.Float32frombits(uint32(field.varint)))
+		}
+	}
+	return message, nil
+}
+
+// protoWireType returns the wire type of a field type
+func protoWireType(fieldType string) int {
+	switch fieldType {
+	case "string", "bytes", "message":
+		return wireBytes
+	case "double":
+		return wireI64
+	case "float":
+		return wireI32
+	}
+	return wireVarint
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// ConfigureHTTP applies app.http to the app's listener: the protocols it
+// speaks, its timeouts and its header limit. Unset settings keep the
+// defaults New gives, and with protection off there are no timeouts. It must
+// be called before Start.
+func (s *Server) ConfigureHTTP(cfg *config.HTTPConfig) {
+	if cfg == nil {
+		return
+	}
+	hs := s.httpServer
+
+	if d, ok := config.ParseTimeout(cfg.ReadTimeout); ok {
+		hs.ReadTimeout = d
+	}
+	if d, ok := config.ParseTimeout(cfg.ReadHeaderTimeout); ok {
+		hs.ReadHeaderTimeout = d
+	}
+	if d, ok := config.ParseTimeout(cfg.WriteTimeout); ok {
+		hs.WriteTimeout = d
+	}
+	if d, ok := config.ParseTimeout(cfg.IdleTimeout); ok {
+		hs.IdleTimeout = d
+	}
+	if !cfg.Protected() {
+		hs.ReadTimeout, hs.ReadHeaderTimeout, hs.WriteTimeout, hs.IdleTimeout = 0, 0, 0, 0
+		log.Printf("Warning: the listener has no timeouts or body limits (app.http.protection is off)")
+	}
+	if cfg.MaxHeaderBytes > 0 {
+		hs.MaxHeaderBytes = cfg.MaxHeaderBytes
+	}
+	if cfg.KeepAlive != nil {
+		hs.SetKeepAlivesEnabled(*cfg.KeepAlive)
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2 == nil || *cfg.HTTP2)
+	protocols.SetUnencryptedHTTP2(cfg.H2C)
+	hs.Protocols = protocols
+
+	if cfg.H2C {
+		log.Printf("Plain HTTP connections may speak HTTP/2 with prior knowledge (h2c)")
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestConfigureHTTP tests app.http overrides the listener's defaults, and
+// unset settings keep them
+func TestConfigureHTTP(t *testing.T) {
+	srv, err := New("127.0.0.1", 0, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	off := false
+	srv.ConfigureHTTP(&config.HTTPConfig{
+		HTTP2:          &off,
+		ReadTimeout:    "0",
+		IdleTimeout:    "2m",
+		MaxHeaderBytes: 4096,
+	})
+
+	hs := srv.httpServer
+	if hs.ReadTimeout != 0 || hs.WriteTimeout != 15*time.Second || hs.IdleTimeout != 2*time.Minute || hs.MaxHeaderBytes != 4096 {
+		t.Errorf("Unexpected timeouts %v %v %v or header limit %d", hs.ReadTimeout, hs.WriteTimeout, hs.IdleTimeout, hs.MaxHeaderBytes)
+	}
+	if hs.Protocols.HTTP2() || !hs.Protocols.HTTP1() || hs.Protocols.UnencryptedHTTP2() {
+		t.Errorf("Expected HTTP/1 only, got %v", hs.Protocols)
+	}
+}
+
+// TestConfigureHTTP_Unprotected tests protection off drops every timeout
+func TestConfigureHTTP_Unprotected(t *testing.T) {
+	srv, err := New("127.0.0.1", 0, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	off := false
+	srv.ConfigureHTTP(&config.HTTPConfig{Protection: &off})
+
+	hs := srv.httpServer
+	if hs.ReadTimeout != 0 || hs.ReadHeaderTimeout != 0 || hs.WriteTimeout != 0 || hs.IdleTimeout != 0 {
+		t.Errorf("Expected no timeouts, got %v %v %v %v", hs.ReadTimeout, hs.ReadHeaderTimeout, hs.WriteTimeout, hs.IdleTimeout)
+	}
+}
+
+// TestConfigureHTTP_H2C tests plain HTTP connections speak HTTP/2 with prior
+// knowledge when h2c is on
+func TestConfigureHTTP_H2C(t *testing.T) {
+	srv, err := New("127.0.0.1", 0, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.ConfigureHTTP(&config.HTTPConfig{H2C: true})
+	srv.Router().HandleFunc("GET", "/proto", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go srv.httpServer.Serve(listener)
+	defer srv.httpServer.Close()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}, Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + listener.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+}
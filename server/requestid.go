@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader carries a request's ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestID bounds the inbound IDs adopted; longer ones are replaced
+const maxRequestID = 128
+
+// correlationHeaders are the inbound headers whose ID a request adopts, in
+// order of preference. The one adopted is echoed back along with
+// X-Request-ID, so clients find their ID where they sent it.
+var correlationHeaders = []string{RequestIDHeader, "X-Correlation-ID", "Request-Id"}
+
+// requestIDKey is the context key of a request's ID
+type requestIDKey struct{}
+
+// withRequestID gives a request its ID: the one a correlation header carries,
+// or a new one. It's set on the response before anything is written.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := ""
+	for _, header := range correlationHeaders {
+		if value := r.Header.Get(header); validRequestID(value) {
+			id = value
+			if header != RequestIDHeader {
+				w.Header().Set(header, id)
+			}
+			break
+		}
+	}
+	if id == "" {
+		id = newRequestID()
+	}
+	w.Header().Set(RequestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// RequestID returns the ID the router gave a request, or "" for one it
+// didn't serve
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random 128-bit ID in hex. IDs don't come from
+// app.seed, as runs with the same seed would repeat them.
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// validRequestID reports whether an inbound ID can be adopted: printable
+// ASCII without spaces, which is safe to echo in headers and log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/logger"
+)
+
+// TestRouter_RequestID tests requests get an ID, or keep the one a
+// correlation header carries, which the response and the log entry repeat
+func TestRouter_RequestID(t *testing.T) {
+	jsonLogger, err := logger.New(filepath.Join(t.TempDir(), "requests.json"))
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer jsonLogger.Close()
+	router := NewRouter(jsonLogger)
+	var seen string
+	router.HandleFunc("GET", "/fail", func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r)
+		NewResponseBuilder().SendError(w, "json", http.StatusInternalServerError, "boom", DebugInfo{Message: "boom"})
+	})
+
+	tests := []struct {
+		name, header, value string
+		adopted             bool
+	}{
+		{"generated", "", "", false},
+		{"request id", "X-Request-ID", "scan-42", true},
+		{"correlation id", "X-Correlation-ID", "chain-7:step-2", true},
+		{"spaces", "X-Request-ID", "a b", false},
+		{"too long", "X-Request-ID", strings.Repeat("a", maxRequestID+1), false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/fail", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(RequestIDHeader)
+		if tt.adopted && id != tt.value {
+			t.Errorf("%s: expected %q adopted, got %q", tt.name, tt.value, id)
+		}
+		if !tt.adopted && len(id) != 32 {
+			t.Errorf("%s: expected a new 32-character ID, got %q", tt.name, id)
+		}
+		if tt.adopted && rec.Header().Get(tt.header) != tt.value {
+			t.Errorf("%s: expected %s echoed", tt.name, tt.header)
+		}
+		if seen != id {
+			t.Errorf("%s: expected the handler to see %q, got %q", tt.name, id, seen)
+		}
+		var body ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.RequestID != id {
+			t.Errorf("%s: expected the error to carry %q, got %s", tt.name, id, rec.Body.String())
+		}
+	}
+
+	data, _ := os.ReadFile(jsonLogger.FilePath())
+	if !strings.Contains(string(data), `"request_id":"chain-7:step-2"`) {
+		t.Errorf("Expected the IDs logged, got %s", data)
+	}
+}
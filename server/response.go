@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // ResponseBuilder handles formatting and sending HTTP responses
@@ -31,8 +32,9 @@ type DebugInfo struct {
 
 // ErrorResponse is the structure for error responses with debug info
 type ErrorResponse struct {
-	Error string    `json:"error" xml:"error"`
-	Debug DebugInfo `json:"debug" xml:"debug"`
+	Error     string    `json:"error" xml:"error"`
+	RequestID string    `json:"request_id,omitempty" xml:"request_id,omitempty"` // To find the request in the log
+	Debug     DebugInfo `json:"debug" xml:"debug"`
 }
 
 // Send sends a successful response in the specified format
@@ -58,10 +60,12 @@ func (rb *ResponseBuilder) SendWithStatus(w http.ResponseWriter, responseType st
 }
 
 // SendError sends an error response with debug information (always enabled)
+// and the request's ID, which the router set on the response
 func (rb *ResponseBuilder) SendError(w http.ResponseWriter, responseType string, statusCode int, err string, debug DebugInfo) {
 	errResp := ErrorResponse{
-		Error: err,
-		Debug: debug,
+		Error:     err,
+		RequestID: w.Header().Get(RequestIDHeader),
+		Debug:     debug,
 	}
 
 	switch responseType {
@@ -94,6 +98,26 @@ func (rb *ResponseBuilder) SendRaw(w http.ResponseWriter, responseType string, s
 	}
 }
 
+// bodyContentTypes maps response types to the content type of a body sent as-is
+var bodyContentTypes = map[string]string{
+	"json": "application/json",
+	"html": "text/html; charset=utf-8",
+	"xml":  "application/xml; charset=utf-8",
+	"text": "text/plain; charset=utf-8",
+}
+
+// SendBody sends an already rendered body, e.g. from a response template,
+// without wrapping it
+func (rb *ResponseBuilder) SendBody(w http.ResponseWriter, responseType string, statusCode int, body string) {
+	contentType, ok := bodyContentTypes[responseType]
+	if !ok {
+		contentType = bodyContentTypes["json"]
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	fmt.Fprint(w, body)
+}
+
 // sendJSON sends a JSON response
 func (rb *ResponseBuilder) sendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -125,6 +149,12 @@ func (rb *ResponseBuilder) sendHTML(w http.ResponseWriter, statusCode int, data
 		content = fmt.Sprintf("<pre>%s</pre>", string(jsonBytes))
 	}
 
+	// Complete documents (e.g. full-page XSS output) are sent as-is
+	if isHTMLDocument(content) {
+		fmt.Fprint(w, content)
+		return
+	}
+
 	fmt.Fprintf(w, `<!DOCTYPE html>
 <html>
 <head>
@@ -140,6 +170,12 @@ func (rb *ResponseBuilder) sendHTML(w http.ResponseWriter, statusCode int, data
 </html>`, content)
 }
 
+// isHTMLDocument reports whether content is already a complete HTML document
+func isHTMLDocument(content string) bool {
+	start := strings.ToLower(strings.TrimSpace(content))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
+
 // sendErrorHTML sends an HTML error response
 func (rb *ResponseBuilder) sendErrorHTML(w http.ResponseWriter, statusCode int, errResp ErrorResponse) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -167,9 +203,10 @@ func (rb *ResponseBuilder) sendErrorHTML(w http.ResponseWriter, statusCode int,
         <div class="debug-item"><span class="label">Module:</span> %s</div>
         <div class="debug-item"><span class="label">Placement:</span> %s</div>
         <div class="debug-item"><span class="label">Param:</span> %s</div>
+        <div class="debug-item"><span class="label">Request ID:</span> %s</div>
     </div>
 </body>
-</html>`, errResp.Error, errResp.Debug.Message, errResp.Debug.Module, errResp.Debug.Placement, errResp.Debug.Param)
+</html>`, errResp.Error, errResp.Debug.Message, errResp.Debug.Module, errResp.Debug.Placement, errResp.Debug.Param, errResp.RequestID)
 }
 
 // XMLResponse wraps data for proper XML encoding
@@ -181,9 +218,10 @@ type XMLResponse struct {
 
 // XMLErrorResponse wraps error data for XML encoding
 type XMLErrorResponse struct {
-	XMLName xml.Name  `xml:"response"`
-	Error   string    `xml:"error"`
-	Debug   DebugInfo `xml:"debug"`
+	XMLName   xml.Name  `xml:"response"`
+	Error     string    `xml:"error"`
+	RequestID string    `xml:"request_id,omitempty"`
+	Debug     DebugInfo `xml:"debug"`
 }
 
 // sendXML sends an XML response
@@ -201,8 +239,9 @@ func (rb *ResponseBuilder) sendXML(w http.ResponseWriter, statusCode int, data i
 	switch v := data.(type) {
 	case ErrorResponse:
 		xmlErr := XMLErrorResponse{
-			Error: v.Error,
-			Debug: v.Debug,
+			Error:     v.Error,
+			RequestID: v.RequestID,
+			Debug:     v.Debug,
 		}
 		if err := encoder.Encode(xmlErr); err != nil {
 			fmt.Fprintf(w, "<response><error>failed to encode response</error></response>")
@@ -245,12 +284,13 @@ func (rb *ResponseBuilder) sendErrorText(w http.ResponseWriter, statusCode int,
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(statusCode)
 
-	fmt.Fprintf(w, "ERROR: %s\n\nDEBUG INFO:\n  Message: %s\n  Module: %s\n  Placement: %s\n  Param: %s\n",
+	fmt.Fprintf(w, "ERROR: %s\n\nDEBUG INFO:\n  Message: %s\n  Module: %s\n  Placement: %s\n  Param: %s\n  Request ID: %s\n",
 		errResp.Error,
 		errResp.Debug.Message,
 		errResp.Debug.Module,
 		errResp.Debug.Placement,
-		errResp.Debug.Param)
+		errResp.Debug.Param,
+		errResp.RequestID)
 }
 
 // CombinedResult holds results from multiple vulnerability handlers
@@ -264,7 +304,10 @@ type ModuleResult struct {
 	Param      string      `json:"param" xml:"param"`
 	Data       interface{} `json:"data,omitempty" xml:"data,omitempty"`
 	Error      string      `json:"error,omitempty" xml:"error,omitempty"`
-	StatusCode int         `json:"-" xml:"-"` // Used internally, not serialized
+	RequestID  string      `json:"request_id,omitempty" xml:"request_id,omitempty"` // Of the request the module ran on
+	StatusCode int         `json:"-" xml:"-"`                                       // Used internally, not serialized
+	Input      string      `json:"-" xml:"-"`                                       // Extracted input, for response templates
+	Placement  string      `json:"-" xml:"-"`                                       // Where Param was read from, resolved for placement any
 }
 
 // SendCombined sends a combined response from multiple vulnerability handlers
@@ -73,6 +73,19 @@ func TestResponseBuilder_SendHTML(t *testing.T) {
 	}
 }
 
+// TestResponseBuilder_SendHTMLDocument tests that complete documents are not wrapped again
+func TestResponseBuilder_SendHTMLDocument(t *testing.T) {
+	rb := NewResponseBuilder()
+	w := httptest.NewRecorder()
+
+	doc := "<!DOCTYPE html>\n<html><head><title>Page</title></head><body>x</body></html>"
+	rb.Send(w, "html", doc)
+
+	if body := w.Body.String(); body != doc {
+		t.Errorf("Expected document to be sent as-is, got '%s'", body)
+	}
+}
+
 // TestResponseBuilder_SendText tests plain text response formatting
 func TestResponseBuilder_SendText(t *testing.T) {
 	rb := NewResponseBuilder()
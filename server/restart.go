@@ -0,0 +1,218 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment of a restarted process: the listening sockets handed over to it,
+// as addr=fd pairs, and the fds of the pipes the handover is agreed on
+const (
+	listenersEnv = "FLAWFACTORY_LISTENERS"
+	handoverEnv  = "FLAWFACTORY_HANDOVER"
+)
+
+// inherited holds the sockets handed over by the process this one restarted,
+// by address, until Listen takes them over
+var inherited struct {
+	once  sync.Once
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+// inheritedFiles parses the handed over sockets from the environment
+func inheritedFiles() map[string]*os.File {
+	inherited.once.Do(func() {
+		inherited.files = make(map[string]*os.File)
+		for _, pair := range strings.Split(os.Getenv(listenersEnv), ",") {
+			addr, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				continue
+			}
+			if fd, err := strconv.Atoi(value); err == nil {
+				inherited.files[addr] = os.NewFile(uintptr(fd), "listener "+addr)
+			}
+		}
+	})
+	return inherited.files
+}
+
+// listen takes over the socket handed over for addr, or opens a new one
+func listen(addr string) (net.Listener, error) {
+	inherited.mu.Lock()
+	files := inheritedFiles()
+	file, ok := files[addr]
+	delete(files, addr)
+	inherited.mu.Unlock()
+
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over the socket for %s: %w", addr, err)
+	}
+	log.Printf("Took over the listening socket for %s", addr)
+	return listener, nil
+}
+
+// CloseInherited closes the handed over sockets Listen didn't take over, such
+// as that of a port the new config moved off. Call it once every server listens.
+func CloseInherited() {
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	for addr, file := range inheritedFiles() {
+		file.Close()
+		delete(inherited.files, addr)
+	}
+}
+
+// Handover is the running process's side of a restart: a new process started
+// with the servers' listening sockets, which takes over once this one lets it
+type Handover struct {
+	process *os.Process
+	ready   *os.File // Read: a byte once the new process has loaded its config
+	proceed *os.File // Written: closed to let the new process build and serve
+}
+
+// Restart starts the running command again, handing it the servers' listening
+// sockets. Connections keep queuing on the sockets while the processes swap,
+// so none are refused. The new process loads its config and waits; see
+// WaitReady and Proceed.
+func Restart(servers []*Server) (*Handover, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the executable: %w", err)
+	}
+
+	readyRead, readyWrite, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the handover pipe: %w", err)
+	}
+	proceedRead, proceedWrite, err := os.Pipe()
+	if err != nil {
+		readyRead.Close()
+		readyWrite.Close()
+		return nil, fmt.Errorf("failed to create the handover pipe: %w", err)
+	}
+
+	// ExtraFiles become fds 3, 4, ... in the new process
+	files := []*os.File{readyWrite, proceedRead}
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	var pairs []string
+	for _, s := range servers {
+		s.mu.Lock()
+		for srv, listener := range s.listeners {
+			tcp, ok := listener.(*net.TCPListener)
+			if !ok {
+				continue
+			}
+			file, err := tcp.File()
+			if err != nil {
+				s.mu.Unlock()
+				readyRead.Close()
+				proceedWrite.Close()
+				return nil, fmt.Errorf("failed to hand over the socket for %s: %w", srv.Addr, err)
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%d", srv.Addr, 3+len(files)))
+			files = append(files, file)
+		}
+		s.mu.Unlock()
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(withoutHandover(os.Environ()),
+		listenersEnv+"="+strings.Join(pairs, ","),
+		handoverEnv+"=3,4",
+	)
+	if err := cmd.Start(); err != nil {
+		readyRead.Close()
+		proceedWrite.Close()
+		return nil, fmt.Errorf("failed to start the new process: %w", err)
+	}
+	log.Printf("Started new process %d, handing over listening sockets: %s", cmd.Process.Pid, strings.Join(pairs, ", "))
+	return &Handover{process: cmd.Process, ready: readyRead, proceed: proceedWrite}, nil
+}
+
+// WaitReady waits for the new process to load its config. If it fails to, or
+// takes longer than timeout, it is stopped and this process keeps serving.
+func (h *Handover) WaitReady(timeout time.Duration) error {
+	h.ready.SetReadDeadline(time.Now().Add(timeout))
+	_, err := h.ready.Read(make([]byte, 1))
+	h.ready.Close()
+	if err == nil {
+		return nil
+	}
+
+	h.process.Kill()
+	h.process.Wait()
+	h.proceed.Close()
+	if err == io.EOF {
+		return fmt.Errorf("the new process exited before loading its config")
+	}
+	return fmt.Errorf("the new process didn't load its config: %w", err)
+}
+
+// Proceed lets the new process build and serve. Call it once this process has
+// stopped its servers and released the ports its sinks listen on.
+func (h *Handover) Proceed() {
+	h.proceed.Close()
+	h.process.Release()
+}
+
+// HandoverReady tells the process being replaced, if this one is its restart,
+// that the config has loaded, then waits for it to step down
+func HandoverReady() error {
+	value := os.Getenv(handoverEnv)
+	if value == "" {
+		return nil
+	}
+	os.Unsetenv(handoverEnv)
+
+	readyFD, proceedFD, _ := strings.Cut(value, ",")
+	ready, err1 := strconv.Atoi(readyFD)
+	proceed, err2 := strconv.Atoi(proceedFD)
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("invalid %s %q", handoverEnv, value)
+	}
+
+	readyFile := os.NewFile(uintptr(ready), "handover ready")
+	_, err := readyFile.Write([]byte{1})
+	readyFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to signal the previous process: %w", err)
+	}
+
+	// The pipe closes when the previous process has stepped down, or died
+	proceedFile := os.NewFile(uintptr(proceed), "handover proceed")
+	defer proceedFile.Close()
+	io.Copy(io.Discard, proceedFile)
+	log.Printf("Previous process stepped down, taking over")
+	return nil
+}
+
+// withoutHandover drops a previous restart's variables from an environment
+func withoutHandover(env []string) []string {
+	kept := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, listenersEnv+"=") && !strings.HasPrefix(kv, handoverEnv+"=") {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"testing"
+)
+
+// TestListen_Inherited tests Listen takes over a handed over socket, and
+// CloseInherited closes those left over
+func TestListen_Inherited(t *testing.T) {
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer original.Close()
+	file, err := original.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get the socket: %v", err)
+	}
+	addr := original.Addr().String()
+
+	inherited.mu.Lock()
+	inheritedFiles()[addr] = file
+	inheritedFiles()["127.0.0.1:1"] = file
+	inherited.mu.Unlock()
+
+	listener, err := listen(addr)
+	if err != nil {
+		t.Fatalf("Failed to take over the socket: %v", err)
+	}
+	defer listener.Close()
+	if listener.Addr().String() != addr {
+		t.Errorf("Expected %s, got %s", addr, listener.Addr())
+	}
+
+	// Connections queued on the socket reach the new listener
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	conn.Close()
+	accepted, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	accepted.Close()
+
+	CloseInherited()
+	if len(inheritedFiles()) != 0 {
+		t.Errorf("Expected no sockets left over, got %v", inheritedFiles())
+	}
+}
+
+// TestWithoutHandover tests a restart's variables aren't passed on to the next
+func TestWithoutHandover(t *testing.T) {
+	env := withoutHandover([]string{"HOME=/root", listenersEnv + "=127.0.0.1:80=5", handoverEnv + "=3,4"})
+	if len(env) != 1 || env[0] != "HOME=/root" {
+		t.Errorf("Expected only HOME, got %v", env)
+	}
+}
@@ -1,21 +1,54 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/RIZZZIOM/FlawFactory/logger"
+	"github.com/RIZZZIOM/FlawFactory/tracing"
 )
 
 // Router handles HTTP routing
 type Router struct {
+	mux       *http.ServeMux
+	wildcards []wildcardHost // Routes of *.domain hosts, most specific domain first
+	logger    *logger.Logger
+	tracer    *tracing.Tracer  // Traces each request, or nil
+	feed      *Feed            // Streams each request live, or nil
+	capture   *Capture         // Records each request and response, or nil
+	profiles  *Profiles        // Profiles each request's client, or nil
+	routes    []string         // "METHOD path" in registration order
+	unmatched UnmatchedHandler // Answers requests no route matches, or nil for ServeMux's plain errors
+
+	maxBodySize int64              // Cap on request bodies, 0 for none
+	bodyLimits  map[routeKey]int64 // Caps of routes with their own
+
+	sessionCookie string // Cookie carrying the lab's session IDs, for profiles
+}
+
+// routeKey identifies a route by the mux it is registered on and its pattern there
+type routeKey struct {
+	mux     *http.ServeMux
+	pattern string
+}
+
+// UnmatchedHandler answers a request no route matched. status is 404, or 405
+// when routes for the path take other methods, listed in the Allow header.
+type UnmatchedHandler func(w http.ResponseWriter, r *http.Request, status int)
+
+// wildcardHost holds the routes of a *.domain host, which ServeMux can't match
+type wildcardHost struct {
+	suffix string // ".domain"
 	mux    *http.ServeMux
-	logger *logger.Logger
 }
 
 // NewRouter creates a new router with optional JSON logging
@@ -26,33 +59,82 @@ func NewRouter(jsonLogger *logger.Logger) *Router {
 	}
 }
 
+// Sibling creates an empty router that logs, traces, streams, captures and
+// profiles like r, for another listener
+func (r *Router) Sibling() *Router {
+	sibling := NewRouter(r.logger)
+	sibling.tracer, sibling.feed, sibling.capture, sibling.profiles = r.tracer, r.feed, r.capture, r.profiles
+	sibling.sessionCookie = r.sessionCookie
+	return sibling
+}
+
+// ProfileSessions records the session IDs clients send in cookie in their
+// profiles
+func (r *Router) ProfileSessions(cookie string) {
+	r.sessionCookie = cookie
+}
+
 // ServeHTTP implements http.Handler interface
 // This allows Router to be used as an HTTP handler
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// Log the request
 	start := time.Now()
+	req = withRequestID(w, req)
 
-	// Capture request body for logging (if applicable)
+	// Buffer the body once: extractions read it from the context, and the
+	// logger captures it for methods that carry one, or all with app.logging.bodies
+	logAll, logLimit := r.logger.Bodies()
+	limit := r.bodyLimit(req)
+	tooLarge := limit > 0 && req.ContentLength > limit
+	if tooLarge {
+		req.Body = http.NoBody // Refused without reading it
+	} else if limit > 0 && req.Body != nil && req.Body != http.NoBody {
+		req.Body = http.MaxBytesReader(w, req.Body, limit)
+	}
+	req = BufferBody(req)
 	var bodyBytes []byte
-	if req.Body != nil && (req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch) {
-		bodyBytes, _ = io.ReadAll(req.Body)
-		req.Body.Close()
-		// Restore the body so handlers can still read it
-		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	if logAll || req.Method == http.MethodPost || req.Method == http.MethodPut || req.Method == http.MethodPatch {
+		bodyBytes = bodyOf(req).raw
 	}
-
-	// Store body in context for the logger
 	ctx := context.WithValue(req.Context(), logger.RequestBodyKey, bodyBytes)
-	req = req.WithContext(ctx)
+	req = logger.WithAttacks(req.WithContext(ctx)) // Handlers label the attacks they see for the log
 
-	// Create a response writer that captures the status code and content length
+	// Create a response writer that captures the status code and content
+	// length, and the start of the body for the capture and the log
 	wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	if r.capture != nil {
+		wrapped.captured, wrapped.captureLimit = new(bytes.Buffer), r.capture.maxBody
+	}
+	if logAll && r.logger != nil {
+		if wrapped.captured == nil {
+			wrapped.captured = new(bytes.Buffer)
+		}
+		wrapped.captureLimit = max(wrapped.captureLimit, int64(logLimit+logger.RedactMargin))
+	}
 
-	// Serve the request
-	r.mux.ServeHTTP(wrapped, req)
+	// Trace the request, its extractions, modules and sink operations
+	req, span := r.tracer.StartRequest(req, req.Method+" "+req.URL.Path)
+	traceRequest(span, req)
+
+	// Serve the request, unless its body is over the limit
+	var maxBytes *http.MaxBytesError
+	if tooLarge || errors.As(bodyOf(req).err, &maxBytes) {
+		http.Error(wrapped, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	} else {
+		r.serve(wrapped, req)
+	}
+
+	span.Set("http.response.status_code", wrapped.statusCode)
+	if wrapped.statusCode >= 500 {
+		span.Fail(http.StatusText(wrapped.statusCode))
+	}
+	span.End()
 
 	// Log after request is handled
 	duration := time.Since(start)
+	if r.capture != nil {
+		r.capture.record(req, wrapped, start, duration)
+	}
 
 	// Console log (existing behavior)
 	log.Printf("[%s] %s %s - %d - %v",
@@ -63,26 +145,214 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		duration,
 	)
 
-	// JSON file log (if logger is configured)
+	// JSON file log (if logger is configured), the admin API's live streams
+	// and client profiles
+	if r.logger == nil && !r.feed.Active() && r.profiles == nil {
+		return
+	}
+	entry := logger.NewRequestLog(req, wrapped.statusCode, duration, wrapped.contentLength)
+	entry.RequestID = RequestID(req)
 	if r.logger != nil {
-		if err := r.logger.LogRequest(req, wrapped.statusCode, duration, wrapped.contentLength); err != nil {
+		var response []byte
+		if logAll {
+			response = wrapped.captured.Bytes()
+		}
+		entry.SetBodies(bodyBytes, response, logLimit, r.logger.Redactor())
+		if err := r.logger.Log(entry); err != nil {
 			log.Printf("Warning: failed to log request to JSON file: %v", err)
 		}
 	}
+	r.feed.Publish("request", entry)
+	r.profiles.record(req, entry, r.sessionCookie)
+}
+
+// traceRequest sets the attributes of a request's server span
+func traceRequest(span *tracing.Span, req *http.Request) {
+	if span == nil {
+		return
+	}
+	span.Set("http.request.method", req.Method)
+	span.Set("url.path", req.URL.Path)
+	if req.URL.RawQuery != "" {
+		span.Set("url.query", req.URL.RawQuery)
+	}
+	span.Set("server.address", req.Host)
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		span.Set("client.address", host)
+	}
+	if agent := req.UserAgent(); agent != "" {
+		span.Set("user_agent.original", agent)
+	}
+	span.Set("network.protocol.version", strings.TrimPrefix(req.Proto, "HTTP/"))
 }
 
 // HandleFunc registers a handler function for a path and method
 func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc) {
 	pattern := fmt.Sprintf("%s %s", method, path)
 	r.mux.HandleFunc(pattern, handler)
+	r.routes = append(r.routes, pattern)
 	log.Printf("Registered route: %s %s", method, path)
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code and content length
+// HandleHostFunc registers a handler function for a path and method on a specific virtual host.
+// Host-specific routes take precedence over routes registered without a host.
+// A *.domain host matches every subdomain of domain that has no route of its own.
+func (r *Router) HandleHostFunc(method, host, path string, handler http.HandlerFunc) {
+	key := r.route(method, host, path)
+	key.mux.HandleFunc(key.pattern, handler)
+	r.routes = append(r.routes, fmt.Sprintf("%s %s%s", method, host, path))
+	log.Printf("Registered route: %s %s%s", method, host, path)
+}
+
+// route returns the mux a route for method, host and path goes on, and its
+// pattern there; *.domain hosts have a mux of their own
+func (r *Router) route(method, host, path string) routeKey {
+	if domain, ok := strings.CutPrefix(host, "*."); ok {
+		return routeKey{mux: r.wildcardMux(domain), pattern: fmt.Sprintf("%s %s", method, path)}
+	}
+	return routeKey{mux: r.mux, pattern: fmt.Sprintf("%s %s%s", method, host, path)}
+}
+
+// LimitBodies caps request bodies at limit bytes, except on routes with a
+// limit of their own. Larger bodies get a 413 before any handler runs.
+func (r *Router) LimitBodies(limit int64) {
+	r.maxBodySize = limit
+}
+
+// LimitBody caps the request bodies of the route for method, host and path,
+// in place of the limit LimitBodies sets. host is empty for routes without one.
+func (r *Router) LimitBody(method, host, path string, limit int64) {
+	if r.bodyLimits == nil {
+		r.bodyLimits = make(map[routeKey]int64)
+	}
+	r.bodyLimits[r.route(method, host, path)] = limit
+}
+
+// bodyLimit returns the cap on the request's body, 0 for none
+func (r *Router) bodyLimit(req *http.Request) int64 {
+	if len(r.bodyLimits) > 0 {
+		mux := r.handler(req)
+		if _, pattern := mux.Handler(req); pattern != "" {
+			if limit, ok := r.bodyLimits[routeKey{mux: mux, pattern: pattern}]; ok {
+				return limit
+			}
+		}
+	}
+	return r.maxBodySize
+}
+
+// wildcardMux returns the mux of a *.domain host, adding it if needed
+func (r *Router) wildcardMux(domain string) *http.ServeMux {
+	suffix := "." + strings.ToLower(domain)
+	for _, w := range r.wildcards {
+		if w.suffix == suffix {
+			return w.mux
+		}
+	}
+	w := wildcardHost{suffix: suffix, mux: http.NewServeMux()}
+	r.wildcards = append(r.wildcards, w)
+	slices.SortStableFunc(r.wildcards, func(a, b wildcardHost) int {
+		return len(b.suffix) - len(a.suffix)
+	})
+	return w.mux
+}
+
+// handler returns the mux to serve a request: the main one when a route for
+// its exact host matches, otherwise that of the most specific *.domain host
+// with a matching route, falling back to the main one's routes without a host
+func (r *Router) handler(req *http.Request) *http.ServeMux {
+	if len(r.wildcards) == 0 {
+		return r.mux
+	}
+	if _, pattern := r.mux.Handler(req); hasHost(pattern) {
+		return r.mux
+	}
+
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, w := range r.wildcards {
+		if strings.HasSuffix(host, w.suffix) {
+			if _, pattern := w.mux.Handler(req); pattern != "" {
+				return w.mux
+			}
+		}
+	}
+	return r.mux
+}
+
+// HandleUnmatched sets the handler for requests no route matches
+func (r *Router) HandleUnmatched(handler UnmatchedHandler) {
+	r.unmatched = handler
+}
+
+// serve dispatches a request to its route, or the unmatched handler
+func (r *Router) serve(w http.ResponseWriter, req *http.Request) {
+	mux := r.handler(req)
+	if r.unmatched == nil {
+		mux.ServeHTTP(w, req)
+		return
+	}
+	handler, pattern := mux.Handler(req)
+	if pattern != "" {
+		mux.ServeHTTP(w, req)
+		return
+	}
+
+	// ServeMux's own handler tells a 404 from a 405, and which methods are allowed
+	probe := &statusProbe{header: make(http.Header)}
+	handler.ServeHTTP(probe, req)
+	if allow := probe.header.Get("Allow"); allow != "" {
+		w.Header().Set("Allow", allow)
+	}
+	r.unmatched(w, req, probe.status)
+}
+
+// statusProbe records the status and headers a handler writes, dropping the body
+type statusProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *statusProbe) Header() http.Header { return p.header }
+
+func (p *statusProbe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (p *statusProbe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+// hasHost reports whether a ServeMux pattern is scoped to a host
+func hasHost(pattern string) bool {
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = rest
+	}
+	return pattern != "" && !strings.HasPrefix(pattern, "/")
+}
+
+// Routes returns the registered routes as "METHOD path", in registration order
+func (r *Router) Routes() []string {
+	return slices.Clone(r.routes)
+}
+
+// responseWriter wraps http.ResponseWriter to capture status code and content
+// length, and the start of the body when traffic is captured or response
+// bodies are logged
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode    int
 	contentLength int64
+	captured      *bytes.Buffer // nil unless traffic is captured or response bodies are logged
+	captureLimit  int64
 }
 
 // WriteHeader captures the status code
@@ -94,6 +364,27 @@ func (rw *responseWriter) WriteHeader(code int) {
 // Write captures the content length
 func (rw *responseWriter) Write(b []byte) (int, error) {
 	n, err := rw.ResponseWriter.Write(b)
+	if rw.captured != nil {
+		if room := rw.captureLimit - int64(rw.captured.Len()); room > 0 {
+			rw.captured.Write(b[:min(int64(n), room)])
+		}
+	}
 	rw.contentLength += int64(n)
 	return n, err
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, to flush
+// streamed responses
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack lets WebSocket handlers take over the underlying connection
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
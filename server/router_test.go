@@ -3,6 +3,8 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -228,3 +230,158 @@ func TestResponseWriter_DefaultStatusCode(t *testing.T) {
 		t.Errorf("Expected status code 200, got %d", rw.statusCode)
 	}
 }
+
+// TestRouter_HandleHostFunc tests virtual host routing
+func TestRouter_HandleHostFunc(t *testing.T) {
+	router := NewRouter(nil)
+
+	router.HandleFunc("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default"))
+	})
+	router.HandleHostFunc("GET", "assets.example.com", "/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("assets"))
+	})
+
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"example.com", "default"},
+		{"assets.example.com", "assets"},
+		{"assets.example.com:8080", "assets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Body.String() != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestRouter_WildcardHost tests *.domain routes serve subdomains without a
+// route of their own, and leave other hosts to the default routes
+func TestRouter_WildcardHost(t *testing.T) {
+	router := NewRouter(nil)
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body + r.PathValue("id")))
+		}
+	}
+	router.HandleFunc("GET", "/", reply("default"))
+	router.HandleHostFunc("GET", "*.tenants.local", "/users/{id}", reply("tenant"))
+	router.HandleHostFunc("GET", "*.eu.tenants.local", "/users/{id}", reply("eu"))
+	router.HandleHostFunc("GET", "admin.tenants.local", "/users/{id}", reply("admin"))
+
+	tests := []struct {
+		host, path, expected string
+	}{
+		{"acme.tenants.local", "/users/7", "tenant7"},
+		{"ACME.tenants.local:8080", "/users/7", "tenant7"},
+		{"acme.eu.tenants.local", "/users/7", "eu7"},
+		{"admin.tenants.local", "/users/7", "admin7"},
+		{"tenants.local", "/", "default"},
+		{"acme.tenants.local", "/", "default"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Body.String() != tt.expected {
+			t.Errorf("%s%s: expected '%s', got '%s'", tt.host, tt.path, tt.expected, rec.Body.String())
+		}
+	}
+}
+
+// TestRouter_Routes tests registered routes are listed in order
+func TestRouter_Routes(t *testing.T) {
+	router := NewRouter(nil)
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("GET", "/users", handler)
+	router.HandleHostFunc("POST", "internal.local", "/admin", handler)
+
+	routes := router.Routes()
+	if len(routes) != 2 || routes[0] != "GET /users" || routes[1] != "POST internal.local/admin" {
+		t.Errorf("Unexpected routes %v", routes)
+	}
+}
+
+// TestRouter_HandleUnmatched tests unmatched requests reach the handler with
+// 404, or 405 and the allowed methods
+func TestRouter_HandleUnmatched(t *testing.T) {
+	router := NewRouter(nil)
+	router.HandleFunc("GET", "/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+	router.HandleUnmatched(func(w http.ResponseWriter, r *http.Request, status int) {
+		w.WriteHeader(status)
+		w.Write([]byte("custom " + strconv.Itoa(status)))
+	})
+
+	tests := []struct {
+		method, path, expected, allow string
+	}{
+		{"GET", "/users", "users", ""},
+		{"GET", "/missing", "custom 404", ""},
+		{"POST", "/users", "custom 405", "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Body.String() != tt.expected || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: expected %q (Allow %q), got %q (Allow %q)", tt.method, tt.path, tt.expected, tt.allow, rec.Body.String(), rec.Header().Get("Allow"))
+		}
+	}
+}
+
+// TestRouter_LimitBodies tests bodies over the router's limit, or a route's
+// own, get a 413 whether or not their length is declared
+func TestRouter_LimitBodies(t *testing.T) {
+	router := NewRouter(nil)
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bodyOf(r).raw)
+	}
+	router.HandleFunc("POST", "/small", echo)
+	router.HandleFunc("POST", "/large", echo)
+	router.HandleHostFunc("POST", "*.example.com", "/large", echo)
+	router.LimitBodies(8)
+	router.LimitBody("POST", "", "/large", 32)
+	router.LimitBody("POST", "*.example.com", "/large", 4)
+
+	tests := []struct {
+		host    string
+		path    string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"", "/small", "12345678", false, http.StatusOK},
+		{"", "/small", "123456789", false, http.StatusRequestEntityTooLarge},
+		{"", "/small", "123456789", true, http.StatusRequestEntityTooLarge},
+		{"", "/large", "123456789", true, http.StatusOK},
+		{"", "/large", strings.Repeat("x", 33), false, http.StatusRequestEntityTooLarge},
+		{"api.example.com", "/large", "12345", false, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status || (tt.status == http.StatusOK && rec.Body.String() != tt.body) {
+			t.Errorf("%s%s with %d bytes: expected %d, got %d %q", tt.host, tt.path, len(tt.body), tt.status, rec.Code, rec.Body.String())
+		}
+	}
+}
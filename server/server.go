@@ -2,30 +2,41 @@ package server
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"fmt"
 	"log"
-	"math/big"
+	"maps"
+	"net"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RIZZZIOM/FlawFactory/config"
 	"github.com/RIZZZIOM/FlawFactory/logger"
+	"github.com/RIZZZIOM/FlawFactory/tracing"
 )
 
 // Server wraps an HTTP server with our configuration
 type Server struct {
 	httpServer *http.Server
-	router     *Router
+	router     atomic.Pointer[Router] // Swapped when the config is reloaded
 	logger     *logger.Logger
+	tracer     *tracing.Tracer // Set by ConfigureTracing, nil without app.tracing
+	feed       *Feed           // Requests and exploitation events, streamed live by the admin API
+	profiles   *Profiles       // What each client did, for the admin API
+	capture    *Capture        // Set by ConfigureCapture, nil without app.capture
 	tlsConfig  *config.TLSConfig
+	redirect   *http.Server // Redirects plain HTTP to HTTPS when tls.redirect_port is set
+
+	admin       *http.Server           // Admin API listener, when app.admin is set
+	adminRouter atomic.Pointer[Router] // Swapped along with router on reload
+
+	grpc           *http.Server                 // gRPC listener, when app.grpc is set
+	grpcRouter     atomic.Pointer[Router]       // Swapped along with router on reload
+	grpcReflection atomic.Pointer[http.Handler] // Swapped with grpcRouter, nil without reflection
+
+	mu        sync.Mutex
+	listeners map[*http.Server]net.Listener // Opened by Listen, for the app, redirect, admin and gRPC servers
 }
 
 // New creates a new server instance with optional JSON logging
@@ -43,155 +54,243 @@ func New(host string, port int, logFilePath string, tlsConfig *config.TLSConfig)
 		log.Printf("Request logs will be saved to: %s", logFilePath)
 	}
 
-	router := NewRouter(jsonLogger)
-
-	return &Server{
-		httpServer: &http.Server{
-			Addr:         fmt.Sprintf("%s:%d", host, port),
-			Handler:      router,
-			ReadTimeout:  15 * time.Second,
-			WriteTimeout: 15 * time.Second,
-			IdleTimeout:  60 * time.Second,
-		},
-		router:    router,
+	s := &Server{
 		logger:    jsonLogger,
+		feed:      NewFeed(),
+		profiles:  NewProfiles(),
 		tlsConfig: tlsConfig,
-	}, nil
-}
+	}
+	s.router.Store(s.NewRouter())
+	s.httpServer = &http.Server{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.router.Load().ServeHTTP(w, r)
+		}),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	s.redirect = newRedirect(host, port, tlsConfig)
 
-// Router returns the server's router
-func (s *Server) Router() *Router {
-	return s.router
+	return s, nil
 }
 
-// Start begins listening for HTTP or HTTPS requests based on TLS configuration
-func (s *Server) Start() error {
-	if s.tlsConfig != nil && s.tlsConfig.Enabled {
-		return s.startTLS()
+// ConfigureLogging sets the format, rotation, bodies, redaction and outputs
+// of the request log from app.logging. It does nothing when requests aren't
+// logged to a file.
+func (s *Server) ConfigureLogging(cfg *config.LoggingConfig, appName string) error {
+	if s.logger == nil {
+		return nil
 	}
-	return s.startHTTP()
-}
-
-// startHTTP starts the server in HTTP mode
-func (s *Server) startHTTP() error {
-	log.Printf("FlawFactory starting on http://%s", s.httpServer.Addr)
+	formatter, err := logger.NewFormatter(cfg.FormatName())
+	if err != nil {
+		return err
+	}
+	s.logger.SetFormatter(formatter)
 
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server error: %w", err)
+	if cfg == nil {
+		return nil
+	}
+	s.logger.SetBodies(cfg.Bodies, cfg.BodyLimit())
+	if cfg.Redact != nil {
+		redactor, err := logger.NewRedactor(cfg.RedactedHeaders(), cfg.Redact.Patterns)
+		if err != nil {
+			return err
+		}
+		s.logger.SetRedactor(redactor)
 	}
+	for _, output := range cfg.Outputs {
+		if err := s.addLogOutput(output, cfg, appName); err != nil {
+			return err
+		}
+	}
+	return s.logger.SetRotation(logger.Rotation{
+		MaxBytes: cfg.MaxBytes,
+		Interval: cfg.RotationInterval(),
+		Compress: cfg.Compress,
+		MaxFiles: cfg.MaxFiles,
+		MaxAge:   cfg.MaxAgeDuration(),
+	})
+}
 
+// addLogOutput ships the request log to an external backend too
+func (s *Server) addLogOutput(output config.LogOutputConfig, cfg *config.LoggingConfig, appName string) error {
+	formatter, err := logger.NewFormatter(output.FormatName(cfg))
+	if err != nil {
+		return err
+	}
+	var backend logger.Backend
+	switch output.Type {
+	case "elasticsearch":
+		backend = logger.NewElasticsearch(output.URL, output.IndexName(), output.Headers, output.TimeoutDuration())
+	case "loki":
+		labels := map[string]string{"job": "flawfactory", "app": appName}
+		maps.Copy(labels, output.Labels)
+		backend = logger.NewLoki(output.URL, labels, output.Headers, output.TimeoutDuration())
+	case "syslog":
+		backend = logger.NewSyslog(output.NetworkName(), output.Address, output.FacilityCode(), output.TimeoutDuration())
+	default:
+		return fmt.Errorf("unknown log output type: %s", output.Type)
+	}
+	s.logger.AddOutput(logger.NewOutput(backend, formatter))
+	log.Printf("Request logs will be shipped to: %s", backend)
 	return nil
 }
 
-// startTLS starts the server in HTTPS mode
-func (s *Server) startTLS() error {
-	certFile := s.tlsConfig.CertFile
-	keyFile := s.tlsConfig.KeyFile
+// RedactSecrets hides secrets, such as the lab's flags and passwords, wherever
+// they appear in the request log. It does nothing unless app.logging.redact
+// configured redaction.
+func (s *Server) RedactSecrets(secrets []string) {
+	if s.logger == nil {
+		return
+	}
+	if redactor := s.logger.Redactor(); redactor != nil {
+		s.logger.SetRedactor(redactor.WithSecrets(secrets))
+	}
+}
 
-	// Auto-generate self-signed certificate if requested
-	if s.tlsConfig.AutoGenerate {
-		var err error
-		certFile, keyFile, err = s.generateSelfSignedCert()
-		if err != nil {
-			return fmt.Errorf("failed to generate self-signed certificate: %w", err)
-		}
-		log.Printf("Generated self-signed certificate: %s, %s", certFile, keyFile)
+// ConfigureTracing starts exporting a trace of each request to the collector
+// of app.tracing. It does nothing without one, and must be called before the
+// server starts.
+func (s *Server) ConfigureTracing(cfg *config.TracingConfig, appName string) {
+	if cfg == nil {
+		return
 	}
+	exporter := tracing.NewExporter(cfg.TracesURL(), cfg.Service(appName), cfg.Headers)
+	s.tracer = tracing.New(exporter, cfg.Ratio())
+	s.router.Load().tracer = s.tracer
+	log.Printf("Traces will be exported to: %s", cfg.TracesURL())
+}
 
-	// Validate that certificate files exist
-	if certFile == "" || keyFile == "" {
-		return fmt.Errorf("TLS is enabled but cert_file and key_file are not specified (set auto_generate: true for self-signed certificates)")
+// ConfigureCapture starts recording the app's traffic for app.capture. It does
+// nothing without it, and must be called before the server starts.
+func (s *Server) ConfigureCapture(cfg *config.CaptureConfig) {
+	if cfg == nil {
+		return
 	}
+	var pcapLimit int64
+	if cfg.PCAP {
+		pcapLimit = cfg.PCAPLimit()
+	}
+	s.capture = NewCapture(cfg.Entries(), cfg.BodyLimit(), pcapLimit)
+	s.router.Load().capture = s.capture
+	if cfg.PCAP {
+		log.Printf("Recording traffic for HAR and PCAP export")
+	} else {
+		log.Printf("Recording traffic for HAR export")
+	}
+}
 
-	log.Printf("FlawFactory starting on https://%s", s.httpServer.Addr)
+// Capture returns the recorded traffic, nil without app.capture
+func (s *Server) Capture() *Capture {
+	return s.capture
+}
 
-	if err := s.httpServer.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server error: %w", err)
-	}
+// Feed returns the feed of requests and exploitation events
+func (s *Server) Feed() *Feed {
+	return s.feed
+}
 
-	return nil
+// Profiles returns the profiles of the clients that sent requests
+func (s *Server) Profiles() *Profiles {
+	return s.profiles
 }
 
-// generateSelfSignedCert creates a self-signed certificate and returns the paths
-func (s *Server) generateSelfSignedCert() (certFile, keyFile string, err error) {
-	// Create certs directory if it doesn't exist
-	certsDir := "certs"
-	if err := os.MkdirAll(certsDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create certs directory: %w", err)
-	}
+// Router returns the router currently serving requests
+func (s *Server) Router() *Router {
+	return s.router.Load()
+}
 
-	certFile = filepath.Join(certsDir, "server.crt")
-	keyFile = filepath.Join(certsDir, "server.key")
+// NewRouter creates an empty router that logs, traces, streams, captures and profiles like the
+// server's, so routes for a reloaded config can be registered before they are swapped in
+func (s *Server) NewRouter() *Router {
+	router := NewRouter(s.logger)
+	router.tracer, router.feed, router.capture, router.profiles = s.tracer, s.feed, s.capture, s.profiles
+	return router
+}
 
-	// Check if certificates already exist
-	if _, err := os.Stat(certFile); err == nil {
-		if _, err := os.Stat(keyFile); err == nil {
-			log.Printf("Using existing self-signed certificates from %s", certsDir)
-			return certFile, keyFile, nil
-		}
-	}
+// SwapRouter atomically replaces the router serving requests. Requests already
+// being handled, including open WebSocket connections, finish on the old router.
+func (s *Server) SwapRouter(router *Router) {
+	s.router.Store(router)
+}
 
-	// Generate a new ECDSA private key
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate private key: %w", err)
+// Listen opens the server's listeners: the app's, and those of the HTTP
+// redirect, admin API and gRPC when configured. Sockets handed over by the process
+// this one restarted are taken over in place of new ones. Start calls Listen
+// if it hasn't been; calling it first reports a taken port before serving.
+func (s *Server) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners != nil {
+		return nil
 	}
 
-	// Create certificate template
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate serial number: %w", err)
+	listeners := make(map[*http.Server]net.Listener)
+	servers := []struct {
+		srv  *http.Server
+		name string
+	}{
+		{s.admin, "the admin API"},
+		{s.grpc, "gRPC"},
+		{s.redirect, "HTTP redirects"},
+		{s.httpServer, "the app"},
 	}
-
-	template := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"FlawFactory"},
-			CommonName:   "localhost",
-		},
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().Add(365 * 24 * time.Hour), // Valid for 1 year
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
-		DNSNames:              []string{"localhost"},
+	for _, server := range servers {
+		if server.srv == nil {
+			continue
+		}
+		listener, err := listen(server.srv.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("failed to listen for %s: %w", server.name, err)
+		}
+		listeners[server.srv] = listener
 	}
+	s.listeners = listeners
+	return nil
+}
 
-	// Create the certificate
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+// Addr returns the address the app listens on: once Listen has run, the one
+// it bound, with the port the system chose when configured with 0
+func (s *Server) Addr() string {
+	if l := s.listener(s.httpServer); l != nil {
+		return l.Addr().String()
 	}
+	return s.httpServer.Addr
+}
 
-	// Write certificate to file
-	certOut, err := os.Create(certFile)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create cert file: %w", err)
-	}
-	defer certOut.Close()
+// listener returns the listener Listen opened for srv
+func (s *Server) listener(srv *http.Server) net.Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listeners[srv]
+}
 
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: certDER}); err != nil {
-		return "", "", fmt.Errorf("failed to write cert: %w", err)
+// Start begins listening for HTTP or HTTPS requests based on TLS configuration
+func (s *Server) Start() error {
+	if err := s.Listen(); err != nil {
+		return err
 	}
-
-	// Write private key to file
-	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create key file: %w", err)
+	s.startAdmin()
+	s.startGRPC()
+	if s.tlsConfig != nil && s.tlsConfig.Enabled {
+		return s.startTLS()
 	}
-	defer keyOut.Close()
+	return s.startHTTP()
+}
 
-	keyBytes, err := x509.MarshalECPrivateKey(privateKey)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal private key: %w", err)
-	}
+// startHTTP starts the server in HTTP mode
+func (s *Server) startHTTP() error {
+	log.Printf("FlawFactory starting on http://%s", s.httpServer.Addr)
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}); err != nil {
-		return "", "", fmt.Errorf("failed to write key: %w", err)
+	if err := s.httpServer.Serve(s.capture.Listener(s.listener(s.httpServer))); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %w", err)
 	}
 
-	log.Printf("Created new self-signed certificate in %s", certsDir)
-	return certFile, keyFile, nil
+	return nil
 }
 
 // Stop gracefully shuts down the server
@@ -205,11 +304,44 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.redirect != nil {
+		if err := s.redirect.Shutdown(ctx); err != nil {
+			log.Printf("Warning: failed to stop HTTP redirect: %v", err)
+		}
+	}
+
+	// End the live streams, which would otherwise hold the shutdown up
+	s.feed.Close()
+
+	if s.admin != nil {
+		if err := s.admin.Shutdown(ctx); err != nil {
+			log.Printf("Warning: failed to stop admin API: %v", err)
+		}
+	}
+
+	if s.grpc != nil {
+		if err := s.grpc.Shutdown(ctx); err != nil {
+			log.Printf("Warning: failed to stop gRPC listener: %v", err)
+		}
+	}
+
 	// Shutdown gracefully waits for existing connections to finish
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown error: %w", err)
 	}
 
+	// Send the spans of the requests just finished
+	if err := s.tracer.Close(); err != nil {
+		log.Printf("Warning: failed to export traces: %v", err)
+	}
+
+	// Close listeners that were opened but never served
+	s.mu.Lock()
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	s.mu.Unlock()
+
 	log.Println("Server stopped")
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -24,7 +25,7 @@ func TestNew(t *testing.T) {
 		t.Fatal("Expected httpServer to be initialized, got nil")
 	}
 
-	if srv.router == nil {
+	if srv.router.Load() == nil {
 		t.Fatal("Expected router to be initialized, got nil")
 	}
 
@@ -46,11 +47,44 @@ func TestServer_Router(t *testing.T) {
 		t.Fatal("Expected router, got nil")
 	}
 
-	if router != srv.router {
+	if router != srv.router.Load() {
 		t.Error("Expected Router() to return the same router instance")
 	}
 }
 
+// TestServer_SwapRouter tests replacing the routes while the server is serving
+func TestServer_SwapRouter(t *testing.T) {
+	srv, err := New("127.0.0.1", 8080, "", nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	srv.Router().HandleFunc("GET", "/old", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("old"))
+	})
+
+	next := srv.NewRouter()
+	next.HandleFunc("GET", "/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("new"))
+	})
+	srv.SwapRouter(next)
+
+	if srv.Router() != next {
+		t.Error("Expected Router() to return the swapped router")
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/new"); rec.Body.String() != "new" {
+		t.Errorf("Expected the new route to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get("/old"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the old route to be gone, got %d", rec.Code)
+	}
+}
+
 // TestServer_StartStop tests starting and stopping the server
 func TestServer_StartStop(t *testing.T) {
 	// Use a random available port
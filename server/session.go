@@ -0,0 +1,369 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionUserKey is the session value holding the logged-in user
+const SessionUserKey = "username"
+
+// maxAdoptedIDLength caps the session IDs a client can make up
+const maxAdoptedIDLength = 128
+
+// SessionOptions configures a session store
+type SessionOptions struct {
+	Cookie         string        // Cookie carrying the session ID
+	IdleTimeout    time.Duration // Sessions unused for this long expire; 0 for never
+	SameSite       http.SameSite // Default: lax
+	ScriptReadable bool          // Leave HttpOnly off the cookie
+	AdoptIDs       bool          // Start sessions under IDs the client made up
+	KeepIDOnLogin  bool          // Don't issue a new ID when logging in
+	Random         io.Reader     // Session IDs; nil for crypto/rand
+}
+
+// SessionStore keeps server-side sessions, found by the ID in a cookie.
+// Its options decide how much of session fixation and hijacking it allows.
+type SessionStore struct {
+	options SessionOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// Session is one client's key/value bag
+type Session struct {
+	mu       sync.Mutex
+	id       string
+	values   map[string]string
+	lastSeen time.Time
+}
+
+// NewSessionStore creates an empty session store
+func NewSessionStore(options SessionOptions) *SessionStore {
+	if options.SameSite == 0 {
+		options.SameSite = http.SameSiteLaxMode
+	}
+	return &SessionStore{
+		options:  options,
+		now:      time.Now,
+		sessions: make(map[string]*Session),
+	}
+}
+
+// CookieName returns the cookie carrying the session ID
+func (s *SessionStore) CookieName() string {
+	return s.options.Cookie
+}
+
+// Lookup returns the session whose ID is in the request's cookie, or nil if
+// there is none or it expired
+func (s *SessionStore) Lookup(r *http.Request) *Session {
+	cookie, err := r.Cookie(s.options.Cookie)
+	if err != nil {
+		return nil
+	}
+	return s.Get(cookie.Value)
+}
+
+// Get returns the session with an ID, or nil if there is none or it expired
+func (s *SessionStore) Get(id string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	now := s.now()
+	if s.expired(session, now) {
+		delete(s.sessions, id)
+		return nil
+	}
+	session.mu.Lock()
+	session.lastSeen = now
+	session.mu.Unlock()
+	return session
+}
+
+// Start returns the request's session, starting one and setting its cookie if
+// there is none. With AdoptIDs, an unknown ID in the cookie is taken as is.
+func (s *SessionStore) Start(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	session, _, err := s.start(w, r)
+	return session, err
+}
+
+// Login records the user in the request's session. The session gets a new ID,
+// keeping its values, unless KeepIDOnLogin leaves it open to fixation.
+func (s *SessionStore) Login(w http.ResponseWriter, r *http.Request, username string) (*Session, error) {
+	session, issued, err := s.start(w, r)
+	if err != nil {
+		return nil, err
+	}
+	return session, s.login(w, session, username, !issued)
+}
+
+// Logout ends the request's session and clears its cookie
+func (s *SessionStore) Logout(w http.ResponseWriter, r *http.Request) {
+	s.logout(w, s.Lookup(r))
+}
+
+// start returns the request's session, and whether its ID was just issued
+func (s *SessionStore) start(w http.ResponseWriter, r *http.Request) (*Session, bool, error) {
+	if session := s.Lookup(r); session != nil {
+		return session, false, nil
+	}
+
+	if s.options.AdoptIDs {
+		if cookie, err := r.Cookie(s.options.Cookie); err == nil && cookie.Value != "" && len(cookie.Value) <= maxAdoptedIDLength {
+			return s.create(cookie.Value), false, nil
+		}
+	}
+
+	if w == nil {
+		return nil, false, errors.New("no session, and no response to set its cookie on")
+	}
+	id, err := s.newID()
+	if err != nil {
+		return nil, false, err
+	}
+	session := s.create(id)
+	s.setCookie(w, id)
+	return session, true, nil
+}
+
+// login records the user in a session. A session whose ID the client already
+// had is moved to a new one first, unless KeepIDOnLogin is set or there is no
+// response to send the ID in.
+func (s *SessionStore) login(w http.ResponseWriter, session *Session, username string, rotate bool) error {
+	if rotate && !s.options.KeepIDOnLogin && w != nil {
+		id, err := s.newID()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		delete(s.sessions, session.ID())
+		session.mu.Lock()
+		session.id = id
+		session.mu.Unlock()
+		s.sessions[id] = session
+		s.mu.Unlock()
+		s.setCookie(w, id)
+	}
+	session.Set(SessionUserKey, username)
+	return nil
+}
+
+// logout deletes a session, if there is one, and clears the cookie
+func (s *SessionStore) logout(w http.ResponseWriter, session *Session) {
+	if session != nil {
+		s.mu.Lock()
+		delete(s.sessions, session.ID())
+		s.mu.Unlock()
+	}
+	if w != nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     s.options.Cookie,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: !s.options.ScriptReadable,
+			SameSite: s.options.SameSite,
+		})
+	}
+}
+
+// Clear ends every session
+func (s *SessionStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// Count returns the number of live sessions
+func (s *SessionStore) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	count := 0
+	for _, session := range s.sessions {
+		if !s.expired(session, now) {
+			count++
+		}
+	}
+	return count
+}
+
+// create stores a new, empty session
+func (s *SessionStore) create(id string) *Session {
+	session := &Session{id: id, values: make(map[string]string), lastSeen: s.now()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = session
+	return session
+}
+
+// expired reports whether a session went unused for longer than the idle timeout
+func (s *SessionStore) expired(session *Session, now time.Time) bool {
+	if s.options.IdleTimeout <= 0 {
+		return false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return now.Sub(session.lastSeen) > s.options.IdleTimeout
+}
+
+// newID generates a session ID
+func (s *SessionStore) newID() (string, error) {
+	source := s.options.Random
+	if source == nil {
+		source = rand.Reader
+	}
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(source, buf); err != nil {
+		return "", fmt.Errorf("failed to generate session: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// setCookie sends the session ID to the client
+func (s *SessionStore) setCookie(w http.ResponseWriter, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.options.Cookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: !s.options.ScriptReadable,
+		SameSite: s.options.SameSite,
+	})
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get returns a value, "" if it isn't set
+func (s *Session) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores a value
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Delete removes a value
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Values returns a copy of every value
+func (s *Session) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.values)
+}
+
+// RequestSession is the session of one request, started on the first write.
+// Modules get it as their HandlerContext's Session.
+type RequestSession struct {
+	store   *SessionStore
+	w       http.ResponseWriter
+	r       *http.Request
+	session *Session
+	issued  bool // The session's ID was issued in this response
+}
+
+// For returns the session of a request. w may be nil, as for WebSocket
+// messages, in which case no new session can be started.
+func (s *SessionStore) For(w http.ResponseWriter, r *http.Request) *RequestSession {
+	return &RequestSession{store: s, w: w, r: r, session: s.Lookup(r)}
+}
+
+// ID returns the session ID, "" before the session is started
+func (rs *RequestSession) ID() string {
+	if rs.session == nil {
+		return ""
+	}
+	return rs.session.ID()
+}
+
+// Get returns a value, "" if it or the session isn't set
+func (rs *RequestSession) Get(key string) string {
+	if rs.session == nil {
+		return ""
+	}
+	return rs.session.Get(key)
+}
+
+// Set stores a value, starting the session if needed
+func (rs *RequestSession) Set(key, value string) error {
+	if err := rs.Start(); err != nil {
+		return err
+	}
+	rs.session.Set(key, value)
+	return nil
+}
+
+// Delete removes a value
+func (rs *RequestSession) Delete(key string) {
+	if rs.session != nil {
+		rs.session.Delete(key)
+	}
+}
+
+// Values returns a copy of every value, nil before the session is started
+func (rs *RequestSession) Values() map[string]string {
+	if rs.session == nil {
+		return nil
+	}
+	return rs.session.Values()
+}
+
+// User returns the logged-in user, "" if nobody is
+func (rs *RequestSession) User() string {
+	return rs.Get(SessionUserKey)
+}
+
+// Login records the user in the session, as the store's Login does
+func (rs *RequestSession) Login(username string) error {
+	if err := rs.Start(); err != nil {
+		return err
+	}
+	return rs.store.login(rs.w, rs.session, username, !rs.issued)
+}
+
+// Logout ends the session
+func (rs *RequestSession) Logout() {
+	rs.store.logout(rs.w, rs.session)
+	rs.session = nil
+}
+
+// Start starts the session, setting its cookie, if the request has none yet
+func (rs *RequestSession) Start() error {
+	if rs.session != nil {
+		return nil
+	}
+	session, issued, err := rs.store.start(rs.w, rs.r)
+	if err != nil {
+		return err
+	}
+	rs.session, rs.issued = session, issued
+	return nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sessionRequest returns a request carrying a session cookie, if id is set
+func sessionRequest(id string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	if id != "" {
+		req.AddCookie(&http.Cookie{Name: "sid", Value: id})
+	}
+	return req
+}
+
+// TestSessionStore_Start tests a session is started once and found again by its cookie
+func TestSessionStore_Start(t *testing.T) {
+	store := NewSessionStore(SessionOptions{Cookie: "sid"})
+
+	rec := httptest.NewRecorder()
+	session, err := store.Start(rec, sessionRequest(""))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	session.Set("cart", "3 items")
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != session.ID() || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Expected an HttpOnly lax cookie with the session ID, got %v", cookies)
+	}
+
+	rec = httptest.NewRecorder()
+	again, _ := store.Start(rec, sessionRequest(session.ID()))
+	if again != session || again.Get("cart") != "3 items" || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("Expected the same session without a new cookie, got %v", again.Values())
+	}
+
+	if store.Lookup(sessionRequest("made-up")) != nil {
+		t.Error("Expected an unknown ID to find no session")
+	}
+	if _, err := store.Start(nil, sessionRequest("")); err == nil {
+		t.Error("Expected an error starting a session without a response")
+	}
+}
+
+// TestSessionStore_Login tests logging in rotates the ID unless the lab allows fixation
+func TestSessionStore_Login(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SessionOptions
+		fixed   bool
+	}{
+		{"rotates", SessionOptions{}, false},
+		{"keep_id_on_login", SessionOptions{KeepIDOnLogin: true}, true},
+	}
+
+	for _, tt := range tests {
+		tt.options.Cookie = "sid"
+		store := NewSessionStore(tt.options)
+
+		// The attacker gets a session and plants its ID on the victim
+		planted, _ := store.Start(httptest.NewRecorder(), sessionRequest(""))
+		id := planted.ID()
+		planted.Set("theme", "dark")
+
+		rec := httptest.NewRecorder()
+		session, err := store.Login(rec, sessionRequest(id), "alice")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if session.Get(SessionUserKey) != "alice" || session.Get("theme") != "dark" {
+			t.Errorf("%s: expected the user and earlier values, got %v", tt.name, session.Values())
+		}
+
+		attacker := store.Get(id)
+		if fixed := attacker != nil && attacker.Get(SessionUserKey) == "alice"; fixed != tt.fixed {
+			t.Errorf("%s: expected the planted ID to be logged in: %v, got %v", tt.name, tt.fixed, fixed)
+		}
+		if cookies := rec.Result().Cookies(); tt.fixed == (len(cookies) == 1) {
+			t.Errorf("%s: unexpected cookies %v", tt.name, cookies)
+		}
+	}
+
+	// A session started by the login itself needs no second ID
+	store := NewSessionStore(SessionOptions{Cookie: "sid"})
+	rec := httptest.NewRecorder()
+	store.Login(rec, sessionRequest(""), "alice")
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 {
+		t.Errorf("Expected one cookie, got %v", cookies)
+	}
+}
+
+// TestSessionStore_AdoptIDs tests made-up IDs are only taken when the lab allows it
+func TestSessionStore_AdoptIDs(t *testing.T) {
+	strict := NewSessionStore(SessionOptions{Cookie: "sid"})
+	if session, _ := strict.Start(httptest.NewRecorder(), sessionRequest("chosen")); session.ID() == "chosen" {
+		t.Error("Expected a made-up ID to be replaced")
+	}
+
+	lax := NewSessionStore(SessionOptions{Cookie: "sid", AdoptIDs: true, KeepIDOnLogin: true})
+	lax.Login(httptest.NewRecorder(), sessionRequest("chosen"), "alice")
+	if session := lax.Get("chosen"); session == nil || session.Get(SessionUserKey) != "alice" {
+		t.Errorf("Expected the chosen ID to be logged in, got %v", session)
+	}
+}
+
+// TestSessionStore_Expiry tests idle sessions expire and logout ends a session
+func TestSessionStore_Expiry(t *testing.T) {
+	store := NewSessionStore(SessionOptions{Cookie: "sid", IdleTimeout: time.Minute})
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	session, _ := store.Start(httptest.NewRecorder(), sessionRequest(""))
+	now = now.Add(50 * time.Second)
+	if store.Get(session.ID()) == nil {
+		t.Fatal("Expected the session to be alive")
+	}
+	now = now.Add(50 * time.Second)
+	if store.Get(session.ID()) == nil {
+		t.Fatal("Expected using the session to keep it alive")
+	}
+	now = now.Add(2 * time.Minute)
+	if store.Get(session.ID()) != nil || store.Count() != 0 {
+		t.Error("Expected the idle session to expire")
+	}
+
+	session, _ = store.Start(httptest.NewRecorder(), sessionRequest(""))
+	rec := httptest.NewRecorder()
+	store.Logout(rec, sessionRequest(session.ID()))
+	if store.Get(session.ID()) != nil {
+		t.Error("Expected logout to end the session")
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Expected the cookie to be cleared, got %v", cookies)
+	}
+}
+
+// TestRequestSession tests a request's session starts on the first write and
+// is seen by later reads in the same request
+func TestRequestSession(t *testing.T) {
+	store := NewSessionStore(SessionOptions{Cookie: "sid", ScriptReadable: true})
+	rec := httptest.NewRecorder()
+	rs := store.For(rec, sessionRequest(""))
+
+	if rs.ID() != "" || rs.Get("role") != "" || rs.Values() != nil {
+		t.Fatal("Expected no session before the first write")
+	}
+	rs.Set("role", "user")
+	if err := rs.Login("alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rs.User() != "alice" || rs.Get("role") != "user" {
+		t.Errorf("Expected alice with role user, got %v", rs.Values())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].HttpOnly || cookies[0].Value != rs.ID() {
+		t.Errorf("Expected one script-readable cookie with the session ID, got %v", cookies)
+	}
+
+	// A WebSocket message can use the session but not start one
+	if err := store.For(nil, sessionRequest("")).Set("role", "admin"); err == nil {
+		t.Error("Expected an error starting a session without a response")
+	}
+	if err := store.For(nil, sessionRequest(rs.ID())).Set("role", "admin"); err != nil || store.Get(rs.ID()).Get("role") != "admin" {
+		t.Errorf("Expected the existing session to be updated, got %v", err)
+	}
+
+	rs.Logout()
+	if store.Count() != 0 || rs.ID() != "" {
+		t.Error("Expected the session to end")
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Namespaces of the SOAP 1.1 and 1.2 envelopes
+const (
+	SOAP11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	SOAP12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// SOAPEnvelope is a SOAP request: the version its envelope is in, and the
+// operation element, the first in its Body
+type SOAPEnvelope struct {
+	Namespace string // SOAP11Namespace or SOAP12Namespace
+	Operation string // Local name of the operation element
+	operation soapElement
+}
+
+// soapElement is an element of a SOAP body, with its text and its children
+type soapElement struct {
+	XMLName  xml.Name
+	Text     string        `xml:",chardata"`
+	Inner    string        `xml:",innerxml"`
+	Children []soapElement `xml:",any"`
+}
+
+// ReadSOAP parses the request's SOAP envelope, once however many params are
+// extracted from it. Entities other than XML's own are left as written, for
+// modules reading the raw_body to resolve. The body stays readable.
+func ReadSOAP(r *http.Request) (*SOAPEnvelope, error) {
+	body := bodyOf(r)
+	if body.err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", body.err)
+	}
+	return body.parseSOAP()
+}
+
+// parseSOAP returns the body parsed as a SOAP envelope
+func (b *requestBody) parseSOAP() (*SOAPEnvelope, error) {
+	if !b.soapParsed {
+		b.soapParsed = true
+		b.soap, b.soapErr = parseSOAPEnvelope(b.raw)
+	}
+	return b.soap, b.soapErr
+}
+
+// parseSOAPEnvelope parses a SOAP 1.1 or 1.2 envelope
+func parseSOAPEnvelope(raw []byte) (*SOAPEnvelope, error) {
+	var envelope struct {
+		XMLName xml.Name
+		Body    *struct {
+			Elements []soapElement `xml:",any"`
+		} `xml:"Body"`
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+	decoder.Strict = false
+	if err := decoder.Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse SOAP envelope: %w", err)
+	}
+
+	name := envelope.XMLName
+	if name.Local != "Envelope" || (name.Space != SOAP11Namespace && name.Space != SOAP12Namespace) {
+		return nil, fmt.Errorf("not a SOAP envelope: expected an Envelope in %s or %s, got <%s> in '%s'", SOAP11Namespace, SOAP12Namespace, name.Local, name.Space)
+	}
+	if envelope.Body == nil || len(envelope.Body.Elements) == 0 {
+		return nil, fmt.Errorf("SOAP Body has no operation element")
+	}
+	operation := envelope.Body.Elements[0]
+	return &SOAPEnvelope{Namespace: name.Space, Operation: operation.XMLName.Local, operation: operation}, nil
+}
+
+// Param returns the value of an element of the operation, by local name, with
+// dot notation for nested ones: "user.name". An element with children gives
+// its XML as written; a missing one, an empty string.
+func (s *SOAPEnvelope) Param(path string) string {
+	current := s.operation
+	for _, name := range strings.Split(path, ".") {
+		found := false
+		for _, child := range current.Children {
+			if child.XMLName.Local == name {
+				current, found = child, true
+				break
+			}
+		}
+		if !found {
+			return ""
+		}
+	}
+	if len(current.Children) > 0 {
+		return current.Inner
+	}
+	return current.Text
+}
+
+// extractSOAPParam extracts an element of a SOAP request's operation
+func (e *Extractor) extractSOAPParam(r *http.Request, param string) (string, error) {
+	envelope, err := ReadSOAP(r)
+	if err != nil {
+		return "", &ExtractionError{
+			Placement: "soap_param",
+			Param:     param,
+			Message:   err.Error(),
+		}
+	}
+	return envelope.Param(param), nil
+}
+
+// SOAPContentType returns the content type of messages in the envelope namespace
+func SOAPContentType(namespace string) string {
+	if namespace == SOAP12Namespace {
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+// SendSOAP sends data as the element name, in namespace ns, of a SOAP envelope
+// of the version namespace is for. Maps become child elements, in key order,
+// list items <item> elements, and other values text.
+func (rb *ResponseBuilder) SendSOAP(w http.ResponseWriter, namespace string, statusCode int, name, ns string, data interface{}) {
+	var body strings.Builder
+	fmt.Fprintf(&body, `<%s xmlns="%s">`, soapName(name), escapeXML(ns))
+	writeSOAPValue(&body, data)
+	fmt.Fprintf(&body, `</%s>`, soapName(name))
+	sendSOAPEnvelope(w, namespace, statusCode, body.String())
+}
+
+// SendSOAPFault sends a SOAP fault. A sender fault blames the request (Client
+// in SOAP 1.1, Sender in 1.2), any other the server. The detail is written
+// like SendSOAP's data.
+func (rb *ResponseBuilder) SendSOAPFault(w http.ResponseWriter, namespace string, statusCode int, sender bool, message string, detail interface{}) {
+	var body strings.Builder
+	if namespace == SOAP12Namespace {
+		code := "soap:Receiver"
+		if sender {
+			code = "soap:Sender"
+		}
+		fmt.Fprintf(&body, `<soap:Fault><soap:Code><soap:Value>%s</soap:Value></soap:Code>`, code)
+		fmt.Fprintf(&body, `<soap:Reason><soap:Text xml:lang="en">%s</soap:Text></soap:Reason>`, escapeXML(message))
+		if detail != nil {
+			body.WriteString("<soap:Detail>")
+			writeSOAPValue(&body, detail)
+			body.WriteString("</soap:Detail>")
+		}
+	} else {
+		code := "soap:Server"
+		if sender {
+			code = "soap:Client"
+		}
+		fmt.Fprintf(&body, `<soap:Fault><faultcode>%s</faultcode><faultstring>%s</faultstring>`, code, escapeXML(message))
+		if detail != nil {
+			body.WriteString("<detail>")
+			writeSOAPValue(&body, detail)
+			body.WriteString("</detail>")
+		}
+	}
+	body.WriteString("</soap:Fault>")
+	sendSOAPEnvelope(w, namespace, statusCode, body.String())
+}
+
+// sendSOAPEnvelope sends body in a SOAP envelope
+func sendSOAPEnvelope(w http.ResponseWriter, namespace string, statusCode int, body string) {
+	if namespace != SOAP12Namespace {
+		namespace = SOAP11Namespace
+	}
+	w.Header().Set("Content-Type", SOAPContentType(namespace))
+	w.Header().Del("Content-Length")
+	w.WriteHeader(statusCode)
+	fmt.Fprint(w, xml.Header)
+	fmt.Fprintf(w, `<soap:Envelope xmlns:soap="%s"><soap:Body>%s</soap:Body></soap:Envelope>`, namespace, body)
+	fmt.Fprintln(w)
+}
+
+// writeSOAPValue writes a value decoded from JSON as XML content
+func writeSOAPValue(b *strings.Builder, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			writeSOAPElement(b, key, v[key])
+		}
+	case []interface{}:
+		for _, item := range v {
+			writeSOAPElement(b, "item", item)
+		}
+	default:
+		b.WriteString(escapeXML(fmt.Sprint(v)))
+	}
+}
+
+// writeSOAPElement writes a value as the element name
+func writeSOAPElement(b *strings.Builder, name string, value interface{}) {
+	name = soapName(name)
+	fmt.Fprintf(b, "<%s>", name)
+	writeSOAPValue(b, value)
+	fmt.Fprintf(b, "</%s>", name)
+}
+
+// soapName turns a key into an XML name, replacing the characters names can't have
+func soapName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		if !(isLetter(c) || c == '_' || (i > 0 && (isDigit(c) || c == '-' || c == '.'))) {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 {
+		return "_"
+	}
+	return string(name)
+}
+
+// escapeXML escapes text for XML content and attributes
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestExtract_SOAPParam tests elements of the operation are read by local
+// name, with dot notation for nested ones, from SOAP 1.1 and 1.2 envelopes
+func TestExtract_SOAPParam(t *testing.T) {
+	extractor := NewExtractor()
+
+	body := `<?xml version="1.0"?>
+<!DOCTYPE soap [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header><token>abc</token></soap:Header>
+  <soap:Body>
+    <GetUser xmlns="urn:UserService">
+      <id>1' OR '1'='1</id>
+      <filter><name>&xxe;</name><role>admin</role></filter>
+    </GetUser>
+  </soap:Body>
+</soap:Envelope>`
+	req := httptest.NewRequest("POST", "/ws", strings.NewReader(body))
+	tests := []struct {
+		param    string
+		expected string
+	}{
+		{"id", "1' OR '1'='1"},
+		{"filter.name", "&xxe;"},
+		{"filter", "<name>&xxe;</name><role>admin</role>"},
+		{"missing", ""},
+		{"token", ""},
+	}
+	for _, tt := range tests {
+		if value, err := extractor.Extract(req, "soap_param", tt.param); err != nil || value != tt.expected {
+			t.Errorf("%s: expected %q, got %q, err %v", tt.param, tt.expected, value, err)
+		}
+	}
+	envelope, err := ReadSOAP(req)
+	if err != nil || envelope.Operation != "GetUser" || envelope.Namespace != SOAP11Namespace {
+		t.Errorf("Expected a SOAP 1.1 GetUser, got %+v, err %v", envelope, err)
+	}
+	if rest, _ := io.ReadAll(req.Body); string(rest) != body {
+		t.Errorf("Expected the body to stay readable, got %q", rest)
+	}
+
+	req = httptest.NewRequest("POST", "/ws", strings.NewReader(`<e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope"><e:Body><Ping><n>1</n></Ping></e:Body></e:Envelope>`))
+	if envelope, err := ReadSOAP(req); err != nil || envelope.Namespace != SOAP12Namespace || envelope.Param("n") != "1" {
+		t.Errorf("Expected a SOAP 1.2 Ping, got %+v, err %v", envelope, err)
+	}
+
+	for _, invalid := range []string{
+		"not xml",
+		`<Envelope><Body><Ping/></Body></Envelope>`,
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body/></soap:Envelope>`,
+	} {
+		req = httptest.NewRequest("POST", "/ws", strings.NewReader(invalid))
+		if _, err := extractor.Extract(req, "soap_param", "id"); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+// TestSendSOAP tests data is sent as nested elements of the response, and
+// errors as faults of the request's SOAP version
+func TestSendSOAP(t *testing.T) {
+	rb := NewResponseBuilder()
+
+	rec := httptest.NewRecorder()
+	rb.SendSOAP(rec, SOAP11Namespace, 200, "GetUserResponse", "urn:users", map[string]interface{}{
+		"user":  map[string]interface{}{"name": "<admin>", "2fa": true},
+		"roles": []interface{}{"a", "b"},
+	})
+	expected := `<soap:Body><GetUserResponse xmlns="urn:users"><roles><item>a</item><item>b</item></roles><user><_fa>true</_fa><name>&lt;admin&gt;</name></user></GetUserResponse></soap:Body>`
+	if !strings.Contains(rec.Body.String(), expected) || rec.Header().Get("Content-Type") != "text/xml; charset=utf-8" {
+		t.Errorf("Unexpected response %s: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	rb.SendSOAPFault(rec, SOAP11Namespace, 500, false, "near \"'\": syntax error", map[string]interface{}{"module": "sql_injection"})
+	expected = `<soap:Fault><faultcode>soap:Server</faultcode><faultstring>near &#34;&#39;&#34;: syntax error</faultstring><detail><module>sql_injection</module></detail></soap:Fault>`
+	if rec.Code != 500 || !strings.Contains(rec.Body.String(), expected) {
+		t.Errorf("Unexpected fault %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	rb.SendSOAPFault(rec, SOAP12Namespace, 401, true, "login required", nil)
+	expected = `<soap:Code><soap:Value>soap:Sender</soap:Value></soap:Code><soap:Reason><soap:Text xml:lang="en">login required</soap:Text></soap:Reason></soap:Fault>`
+	if rec.Code != 401 || !strings.Contains(rec.Body.String(), expected) || rec.Header().Get("Content-Type") != "application/soap+xml; charset=utf-8" {
+		t.Errorf("Unexpected fault %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// defaultCertDir is where generated certificates are kept
+const defaultCertDir = "certs"
+
+// defaultCertHosts are covered by generated certificates unless tls.hosts is set
+var defaultCertHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// clientAuthTypes maps tls.client_auth to the crypto/tls policy
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                tls.NoClientCert,
+	"none":            tls.NoClientCert,
+	"request":         tls.RequestClientCert,
+	"require":         tls.RequireAnyClientCert,
+	"verify_if_given": tls.VerifyClientCertIfGiven,
+	"verify":          tls.RequireAndVerifyClientCert,
+}
+
+// keyPair is a certificate and its private key
+type keyPair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// startTLS starts the server in HTTPS mode
+func (s *Server) startTLS() error {
+	certFile := s.tlsConfig.CertFile
+	keyFile := s.tlsConfig.KeyFile
+
+	// Auto-generate a certificate, signed by the lab CA or self-signed, if requested
+	var ca *keyPair
+	if s.tlsConfig.AutoGenerate {
+		var err error
+		certFile, keyFile, ca, err = s.generateCertificates()
+		if err != nil {
+			return fmt.Errorf("failed to generate certificates: %w", err)
+		}
+	}
+
+	// Validate that certificate files exist
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("TLS is enabled but cert_file and key_file are not specified (set auto_generate: true for self-signed certificates)")
+	}
+
+	tlsConfig, err := s.clientAuthConfig(ca)
+	if err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = tlsConfig
+
+	if s.redirect != nil {
+		listener := s.listener(s.redirect)
+		go func() {
+			if err := s.redirect.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP redirect failed: %v", err)
+			}
+		}()
+		log.Printf("Redirecting http://%s to HTTPS", s.redirect.Addr)
+	}
+
+	log.Printf("FlawFactory starting on https://%s", s.httpServer.Addr)
+
+	if err := s.httpServer.ServeTLS(s.capture.Listener(s.listener(s.httpServer)), certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("server error: %w", err)
+	}
+
+	return nil
+}
+
+// newRedirect creates the server that sends plain HTTP requests on
+// tls.redirect_port to the same host and path over HTTPS
+func newRedirect(host string, port int, tlsConfig *config.TLSConfig) *http.Server {
+	if tlsConfig == nil || !tlsConfig.Enabled || tlsConfig.RedirectPort == 0 {
+		return nil
+	}
+	return &http.Server{
+		Addr:        net.JoinHostPort(host, strconv.Itoa(tlsConfig.RedirectPort)),
+		Handler:     redirectHandler(port),
+		ReadTimeout: 15 * time.Second,
+	}
+}
+
+// redirectHandler redirects each request to HTTPS on port
+func redirectHandler(port int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}
+}
+
+// clientAuthConfig returns the TLS settings for tls.client_auth. Client
+// certificates are verified against client_ca_file, or else the lab CA.
+func (s *Server) clientAuthConfig(ca *keyPair) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ClientAuth: clientAuthTypes[s.tlsConfig.ClientAuth]}
+	if !s.tlsConfig.VerifiesClients() {
+		return tlsConfig, nil
+	}
+
+	pool := x509.NewCertPool()
+	switch {
+	case s.tlsConfig.ClientCAFile != "":
+		data, err := os.ReadFile(s.tlsConfig.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_ca_file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in client_ca_file %s", s.tlsConfig.ClientCAFile)
+		}
+	case ca != nil:
+		pool.AddCert(ca.cert)
+	default:
+		return nil, fmt.Errorf("client_auth %s needs client_ca_file or a generated lab CA", s.tlsConfig.ClientAuth)
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// generateCertificates creates the server certificate, and with ca: true the lab
+// CA that signs it, and returns the server's files. Files from an earlier run
+// are reused while they still cover the configured hosts, so a CA imported into
+// a proxy or pinned by a client keeps working across restarts.
+func (s *Server) generateCertificates() (certFile, keyFile string, ca *keyPair, err error) {
+	dir := s.tlsConfig.CertDir
+	if dir == "" {
+		dir = defaultCertDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", nil, fmt.Errorf("failed to create certs directory: %w", err)
+	}
+
+	hosts := s.tlsConfig.Hosts
+	if len(hosts) == 0 {
+		hosts = defaultCertHosts
+	}
+
+	if s.tlsConfig.CA {
+		if ca, err = labCA(dir); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	certFile = filepath.Join(dir, "server.crt")
+	keyFile = filepath.Join(dir, "server.key")
+	if existing, err := loadKeyPair(certFile, keyFile); err == nil && certValid(existing.cert, ca, hosts) {
+		log.Printf("Using existing certificate from %s", dir)
+	} else {
+		template, err := certTemplate(hosts[0], 365*24*time.Hour)
+		if err != nil {
+			return "", "", nil, err
+		}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+		if _, err := issueKeyPair(certFile, keyFile, template, ca); err != nil {
+			return "", "", nil, err
+		}
+		log.Printf("Generated certificate for %v: %s, %s", hosts, certFile, keyFile)
+	}
+
+	// A client certificate lets the lab's own CA be used for mutual TLS right away
+	if ca != nil && s.tlsConfig.VerifiesClients() && s.tlsConfig.ClientCAFile == "" {
+		clientCert := filepath.Join(dir, "client.crt")
+		clientKey := filepath.Join(dir, "client.key")
+		if existing, err := loadKeyPair(clientCert, clientKey); err != nil || !certValid(existing.cert, ca, nil) {
+			template, err := certTemplate("flawfactory-client", 365*24*time.Hour)
+			if err != nil {
+				return "", "", nil, err
+			}
+			template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+			if _, err := issueKeyPair(clientCert, clientKey, template, ca); err != nil {
+				return "", "", nil, err
+			}
+			log.Printf("Generated client certificate: %s, %s", clientCert, clientKey)
+		}
+	}
+
+	return certFile, keyFile, ca, nil
+}
+
+// labCA loads the lab CA from dir, creating it on first use
+func labCA(dir string) (*keyPair, error) {
+	certFile := filepath.Join(dir, "ca.crt")
+	keyFile := filepath.Join(dir, "ca.key")
+	if ca, err := loadKeyPair(certFile, keyFile); err == nil && ca.cert.IsCA && time.Now().Before(ca.cert.NotAfter) {
+		return ca, nil
+	}
+
+	template, err := certTemplate("FlawFactory Lab CA", 10*365*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	ca, err := issueKeyPair(certFile, keyFile, template, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Generated lab CA %s; import it into your proxy or browser to trust the lab", certFile)
+	return ca, nil
+}
+
+// certTemplate returns a certificate template with a random serial number
+func certTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"FlawFactory"},
+			CommonName:   commonName,
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}, nil
+}
+
+// certValid reports whether a certificate from an earlier run can be reused:
+// it hasn't expired, covers hosts, and was signed by ca (or by itself without one)
+func certValid(cert *x509.Certificate, ca *keyPair, hosts []string) bool {
+	if time.Now().After(cert.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	if ca != nil {
+		return cert.CheckSignatureFrom(ca.cert) == nil
+	}
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// issueKeyPair generates a key, signs template with issuer (or itself when
+// issuer is nil) and writes both as PEM
+func issueKeyPair(certFile, keyFile string, template *x509.Certificate, issuer *keyPair) (*keyPair, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	parent, signer := template, privateKey
+	if issuer != nil {
+		parent, signer = issuer.cert, issuer.key
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &privateKey.PublicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write cert: %w", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+
+	return &keyPair{cert: cert, key: privateKey}, nil
+}
+
+// loadKeyPair reads a PEM certificate and EC private key written by issueKeyPair
+func loadKeyPair(certFile, keyFile string) (*keyPair, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, fmt.Errorf("invalid PEM in %s or %s", certFile, keyFile)
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &keyPair{cert: cert, key: key}, nil
+}
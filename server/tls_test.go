@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestGenerateCertificates tests the lab CA signs the server certificate and
+// both are reused until the hosts change
+func TestGenerateCertificates(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{tlsConfig: &config.TLSConfig{
+		Enabled:      true,
+		AutoGenerate: true,
+		CA:           true,
+		CertDir:      dir,
+		Hosts:        []string{"lab.local", "10.0.0.5"},
+	}}
+
+	certFile, keyFile, ca, err := s.generateCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+	if ca == nil || !ca.cert.IsCA {
+		t.Fatal("Expected a lab CA")
+	}
+
+	server, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	if err := server.cert.CheckSignatureFrom(ca.cert); err != nil {
+		t.Errorf("Expected the server certificate to be signed by the CA: %v", err)
+	}
+	for _, host := range []string{"lab.local", "10.0.0.5"} {
+		if err := server.cert.VerifyHostname(host); err != nil {
+			t.Errorf("Expected the certificate to cover %s: %v", host, err)
+		}
+	}
+
+	// A second start reuses both
+	if _, _, again, err := s.generateCertificates(); err != nil || !again.cert.Equal(ca.cert) {
+		t.Errorf("Expected the CA to be reused, got err %v", err)
+	}
+	if reused, _ := loadKeyPair(certFile, keyFile); !reused.cert.Equal(server.cert) {
+		t.Error("Expected the server certificate to be reused")
+	}
+
+	// New hosts reissue the server certificate from the same CA
+	s.tlsConfig.Hosts = []string{"other.local"}
+	if _, _, again, err := s.generateCertificates(); err != nil || !again.cert.Equal(ca.cert) {
+		t.Fatalf("Expected the CA to be kept, got err %v", err)
+	}
+	reissued, _ := loadKeyPair(certFile, keyFile)
+	if reissued.cert.Equal(server.cert) || reissued.cert.VerifyHostname("other.local") != nil {
+		t.Error("Expected a certificate for the new hosts")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "client.crt")); !os.IsNotExist(err) {
+		t.Error("Expected no client certificate without client_auth")
+	}
+}
+
+// TestGenerateCertificates_SelfSigned tests certificates without ca: true sign themselves
+func TestGenerateCertificates_SelfSigned(t *testing.T) {
+	s := &Server{tlsConfig: &config.TLSConfig{Enabled: true, AutoGenerate: true, CertDir: t.TempDir()}}
+
+	certFile, keyFile, ca, err := s.generateCertificates()
+	if err != nil || ca != nil {
+		t.Fatalf("Expected a self-signed certificate, got CA %v, err %v", ca, err)
+	}
+	pair, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	if !certValid(pair.cert, nil, defaultCertHosts) {
+		t.Error("Expected a self-signed certificate for the default hosts")
+	}
+
+	// Turning on the CA replaces it with a CA-signed certificate
+	s.tlsConfig.CA = true
+	_, _, ca, err = s.generateCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+	if signed, _ := loadKeyPair(certFile, keyFile); signed.cert.CheckSignatureFrom(ca.cert) != nil {
+		t.Error("Expected the certificate to be reissued by the lab CA")
+	}
+}
+
+// TestClientAuthConfig tests mutual TLS trusts the lab CA, which issues a client certificate
+func TestClientAuthConfig(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{tlsConfig: &config.TLSConfig{
+		Enabled:      true,
+		AutoGenerate: true,
+		CA:           true,
+		CertDir:      dir,
+		ClientAuth:   "verify",
+	}}
+
+	_, _, ca, err := s.generateCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates: %v", err)
+	}
+	tlsConfig, err := s.clientAuthConfig(ca)
+	if err != nil {
+		t.Fatalf("Failed to build TLS config: %v", err)
+	}
+	if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("Expected RequireAndVerifyClientCert, got %v", tlsConfig.ClientAuth)
+	}
+
+	client, err := loadKeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	if err != nil {
+		t.Fatalf("Expected a client certificate: %v", err)
+	}
+	_, err = client.cert.Verify(x509.VerifyOptions{
+		Roots:     tlsConfig.ClientCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Errorf("Expected the client certificate to verify against the client CAs: %v", err)
+	}
+
+	// request only asks for a certificate, so no CA is needed
+	s.tlsConfig.ClientAuth = "request"
+	if tlsConfig, err := s.clientAuthConfig(nil); err != nil || tlsConfig.ClientAuth != tls.RequestClientCert || tlsConfig.ClientCAs != nil {
+		t.Errorf("Expected RequestClientCert without client CAs, got %v, err %v", tlsConfig, err)
+	}
+}
+
+// TestRedirectHandler tests plain HTTP requests are sent to the HTTPS port
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port     int
+		host     string
+		target   string
+		expected string
+	}{
+		{8443, "lab.local:8080", "/search?q=1", "https://lab.local:8443/search?q=1"},
+		{8443, "lab.local", "/", "https://lab.local:8443/"},
+		{443, "127.0.0.1:80", "/admin", "https://127.0.0.1/admin"},
+		{8443, "[::1]:8080", "/", "https://[::1]:8443/"},
+		{8443, "[::1]", "/", "https://[::1]:8443/"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		redirectHandler(tt.port)(rec, req)
+
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.expected {
+			t.Errorf("%s%s: expected 301 to %s, got %d %s", tt.host, tt.target, tt.expected, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+
+	if newRedirect("127.0.0.1", 8443, &config.TLSConfig{Enabled: true}) != nil {
+		t.Error("Expected no redirect without redirect_port")
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialWebSocket performs a client handshake against the test server
+func dialWebSocket(t *testing.T, serverURL string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+
+	handshake := "GET /ws HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatalf("Failed to write handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept '%s'", accept)
+	}
+
+	return conn, reader
+}
+
+// writeClientFrame writes a masked text frame as a browser would
+func writeClientFrame(t *testing.T, conn net.Conn, message string) {
+	t.Helper()
+
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80 | byte(len(message))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(message); i++ {
+		frame = append(frame, message[i]^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+}
+
+// readServerFrame reads an unmasked frame sent by the server
+func readServerFrame(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		t.Fatalf("Failed to read frame header: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Failed to read frame payload: %v", err)
+	}
+	return string(payload)
+}
+
+// TestWebSocket_Echo tests the handshake and a message round trip through the router
+func TestWebSocket_Echo(t *testing.T) {
+	router := NewRouter(nil)
+	router.HandleFunc("GET", "/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage("echo: " + msg)
+		}
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conn, reader := dialWebSocket(t, ts.URL)
+	defer conn.Close()
+
+	writeClientFrame(t, conn, `{"room":"1"}`)
+	if got := readServerFrame(t, reader); got != `echo: {"room":"1"}` {
+		t.Errorf("Expected echoed message, got '%s'", got)
+	}
+}
+
+// TestWebSocket_CloseOnce tests the server answers a client's close frame
+// with a single close frame, though the handler closes the connection too
+func TestWebSocket_CloseOnce(t *testing.T) {
+	router := NewRouter(nil)
+	router.HandleFunc("GET", "/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		conn.ReadMessage()
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conn, reader := dialWebSocket(t, ts.URL)
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4}); err != nil {
+		t.Fatalf("Failed to write close frame: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "\x88\x00" {
+		t.Errorf("Expected a single close frame, got %q", rest)
+	}
+}
+
+// TestUpgradeWebSocket_NotUpgrade tests that plain requests are rejected
+func TestUpgradeWebSocket_NotUpgrade(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ws", nil)
+	rec := httptest.NewRecorder()
+
+	if _, err := UpgradeWebSocket(rec, req); err == nil {
+		t.Error("Expected error for request without upgrade headers")
+	}
+}
+
+// TestIsWebSocketRequest tests upgrade header detection
+func TestIsWebSocketRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+
+	if !IsWebSocketRequest(req) {
+		t.Error("Expected request to be detected as a WebSocket upgrade")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignals restart the lab in a new process that takes over its sockets
+var restartSignals = []os.Signal{syscall.SIGUSR2}
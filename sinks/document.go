@@ -0,0 +1,781 @@
+package sinks
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DocumentStore is an in-memory document database implementing a subset of
+// MongoDB query semantics: field equality, comparison and set operators,
+// $regex, $exists, $and/$or/$nor and JavaScript $where expressions
+type DocumentStore struct {
+	mu          sync.Mutex
+	collections map[string][]map[string]interface{}
+	nextID      int
+
+	// MaxWhereSleep caps sleep() calls inside $where expressions
+	MaxWhereSleep time.Duration
+}
+
+// NewDocumentStore creates an empty document store
+func NewDocumentStore() *DocumentStore {
+	return &DocumentStore{
+		collections:   make(map[string][]map[string]interface{}),
+		nextID:        1,
+		MaxWhereSleep: 10 * time.Second,
+	}
+}
+
+// Close is a no-op for the in-memory store
+func (d *DocumentStore) Close() error {
+	return nil
+}
+
+// Seed adds documents to a collection, assigning an _id to those without one
+func (d *DocumentStore) Seed(collection string, docs []map[string]interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, doc := range docs {
+		d.insert(collection, doc)
+	}
+}
+
+// Collections returns the names of all collections, sorted
+func (d *DocumentStore) Collections() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make([]string, 0, len(d.collections))
+	for name := range d.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Find returns copies of the documents matching a filter
+func (d *DocumentStore) Find(collection string, filter map[string]interface{}) ([]map[string]interface{}, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var results []map[string]interface{}
+	for _, doc := range d.collections[collection] {
+		ok, err := d.match(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			results = append(results, copyDocument(doc))
+		}
+	}
+	return results, nil
+}
+
+// Insert stores a document and returns its _id
+func (d *DocumentStore) Insert(collection string, doc map[string]interface{}) (interface{}, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.insert(collection, doc), nil
+}
+
+// Update applies an update document ($set, $unset, $inc or a plain replacement)
+// to the first or all matching documents and returns how many were modified
+func (d *DocumentStore) Update(collection string, filter, update map[string]interface{}, multi bool) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	modified := 0
+	for i, doc := range d.collections[collection] {
+		ok, err := d.match(doc, filter)
+		if err != nil {
+			return modified, err
+		}
+		if !ok {
+			continue
+		}
+
+		updated, err := applyUpdate(doc, update)
+		if err != nil {
+			return modified, err
+		}
+		d.collections[collection][i] = updated
+		modified++
+		if !multi {
+			break
+		}
+	}
+	return modified, nil
+}
+
+// Delete removes the first or all matching documents and returns how many were removed
+func (d *DocumentStore) Delete(collection string, filter map[string]interface{}, multi bool) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var kept []map[string]interface{}
+	deleted := 0
+	for _, doc := range d.collections[collection] {
+		if deleted > 0 && !multi {
+			kept = append(kept, doc)
+			continue
+		}
+		ok, err := d.match(doc, filter)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			deleted++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	d.collections[collection] = kept
+	return deleted, nil
+}
+
+// insert stores a copy of doc, assigning a sequential ObjectId-style _id if needed
+func (d *DocumentStore) insert(collection string, doc map[string]interface{}) interface{} {
+	stored := copyDocument(doc)
+	if _, ok := stored["_id"]; !ok {
+		stored["_id"] = fmt.Sprintf("507f1f77bcf86cd7994%05x", d.nextID)
+		d.nextID++
+	}
+	d.collections[collection] = append(d.collections[collection], stored)
+	return stored["_id"]
+}
+
+// match reports whether a document satisfies a filter
+func (d *DocumentStore) match(doc, filter map[string]interface{}) (bool, error) {
+	for key, cond := range filter {
+		var ok bool
+		var err error
+
+		switch key {
+		case "$and", "$or", "$nor":
+			ok, err = d.matchLogical(doc, key, cond)
+		case "$where":
+			ok, err = d.matchWhere(doc, cond)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("unknown top level operator: %s", key)
+			}
+			ok, err = matchField(lookupField(doc, key), cond)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchLogical evaluates $and, $or and $nor over a list of filters
+func (d *DocumentStore) matchLogical(doc map[string]interface{}, op string, cond interface{}) (bool, error) {
+	clauses, ok := cond.([]interface{})
+	if !ok || len(clauses) == 0 {
+		return false, fmt.Errorf("%s must be a nonempty array", op)
+	}
+
+	for _, clause := range clauses {
+		filter, ok := clause.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s argument's entries must be objects", op)
+		}
+		matched, err := d.match(doc, filter)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case op == "$and" && !matched:
+			return false, nil
+		case op == "$or" && matched:
+			return true, nil
+		case op == "$nor" && matched:
+			return false, nil
+		}
+	}
+	return op != "$or", nil
+}
+
+// matchWhere evaluates a $where JavaScript expression with "this" bound to the document
+func (d *DocumentStore) matchWhere(doc map[string]interface{}, cond interface{}) (bool, error) {
+	code, ok := cond.(string)
+	if !ok {
+		return false, fmt.Errorf("$where got bad type")
+	}
+
+	value, err := EvalWhere(code, doc, d.MaxWhereSleep)
+	if err != nil {
+		return false, err
+	}
+	return jsTruthy(value), nil
+}
+
+// matchField evaluates a field condition: an operator document or a value to equal
+func matchField(value, cond interface{}) (bool, error) {
+	ops, ok := cond.(map[string]interface{})
+	if !ok || !isOperatorDocument(ops) {
+		return valuesEqual(value, cond), nil
+	}
+
+	for op, arg := range ops {
+		var ok bool
+		switch op {
+		case "$eq":
+			ok = valuesEqual(value, arg)
+		case "$ne":
+			ok = !valuesEqual(value, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = compareOp(value, arg, op)
+		case "$in", "$nin":
+			list, isList := arg.([]interface{})
+			if !isList {
+				return false, fmt.Errorf("%s needs an array", op)
+			}
+			found := false
+			for _, item := range list {
+				if valuesEqual(value, item) {
+					found = true
+					break
+				}
+			}
+			ok = found == (op == "$in")
+		case "$exists":
+			ok = (value != nil) == jsTruthy(arg)
+		case "$regex":
+			matched, err := matchRegex(value, arg, ops["$options"])
+			if err != nil {
+				return false, err
+			}
+			ok = matched
+		case "$options":
+			continue
+		case "$not":
+			matched, err := matchField(value, arg)
+			if err != nil {
+				return false, err
+			}
+			ok = !matched
+		default:
+			return false, fmt.Errorf("unknown operator: %s", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isOperatorDocument reports whether every key of a condition is an operator
+func isOperatorDocument(cond map[string]interface{}) bool {
+	if len(cond) == 0 {
+		return false
+	}
+	for key := range cond {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRegex applies $regex (with optional $options) to a string value
+func matchRegex(value, pattern, options interface{}) (bool, error) {
+	str, ok := value.(string)
+	if !ok {
+		return false, nil
+	}
+
+	expr := fmt.Sprint(pattern)
+	if flags, ok := options.(string); ok && flags != "" {
+		var goFlags string
+		for _, f := range flags {
+			if strings.ContainsRune("ims", f) {
+				goFlags += string(f)
+			}
+		}
+		if goFlags != "" {
+			expr = "(?" + goFlags + ")" + expr
+		}
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, fmt.Errorf("Regular expression is invalid: %v", err)
+	}
+	return re.MatchString(str), nil
+}
+
+// valuesEqual compares two values the way an equality match does. Numbers compare
+// by value, and a missing field equals null. Array fields match if any element does.
+func valuesEqual(a, b interface{}) bool {
+	if list, ok := a.([]interface{}); ok {
+		if _, bIsList := b.([]interface{}); !bIsList {
+			for _, item := range list {
+				if valuesEqual(item, b) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	af, aNum := toFloat(a)
+	bf, bNum := toFloat(b)
+	if aNum && bNum {
+		return af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareOp evaluates $gt/$gte/$lt/$lte. Only numbers with numbers and strings
+// with strings are comparable, as with MongoDB's type brackets.
+func compareOp(a, b interface{}, op string) bool {
+	var cmp int
+
+	af, aNum := toFloat(a)
+	bf, bNum := toFloat(b)
+	as, aStr := a.(string)
+	bs, bStr := b.(string)
+
+	switch {
+	case aNum && bNum:
+		switch {
+		case af < bf:
+			cmp = -1
+		case af > bf:
+			cmp = 1
+		}
+	case aStr && bStr:
+		cmp = strings.Compare(as, bs)
+	default:
+		return false
+	}
+
+	switch op {
+	case "$gt":
+		return cmp > 0
+	case "$gte":
+		return cmp >= 0
+	case "$lt":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// toFloat converts numeric types to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// lookupField resolves a dotted field path in a document
+func lookupField(doc map[string]interface{}, path string) interface{} {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// applyUpdate returns the document with an update applied
+func applyUpdate(doc, update map[string]interface{}) (map[string]interface{}, error) {
+	if !isOperatorDocument(update) {
+		// Replacement keeps the original _id
+		replaced := copyDocument(update)
+		replaced["_id"] = doc["_id"]
+		return replaced, nil
+	}
+
+	updated := copyDocument(doc)
+	for op, arg := range update {
+		fields, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Modifiers operate on fields but we found type %T instead", arg)
+		}
+		for field, value := range fields {
+			switch op {
+			case "$set":
+				updated[field] = value
+			case "$unset":
+				delete(updated, field)
+			case "$inc":
+				current, _ := toFloat(updated[field])
+				delta, ok := toFloat(value)
+				if !ok {
+					return nil, fmt.Errorf("Cannot increment with non-numeric argument")
+				}
+				updated[field] = current + delta
+			default:
+				return nil, fmt.Errorf("Unknown modifier: %s", op)
+			}
+		}
+	}
+	return updated, nil
+}
+
+// copyDocument makes a shallow copy of a document
+func copyDocument(doc map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		copied[k] = v
+	}
+	return copied
+}
+
+// =============================================================================
+// $where evaluation
+// =============================================================================
+
+// EvalWhere evaluates a $where expression against a document. It supports the
+// subset of JavaScript these payloads use: literals, this.field access, ==, ===,
+// !=, !==, <, >, <=, >=, &&, ||, !, parentheses, "return", function wrappers,
+// ;-separated statements and sleep(ms).
+func EvalWhere(code string, doc map[string]interface{}, maxSleep time.Duration) (interface{}, error) {
+	tokens, err := tokenizeJS(code)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &jsParser{tokens: tokens, doc: doc, maxSleep: maxSleep}
+	p.skipFunctionWrapper()
+
+	var result interface{}
+	for !p.done() {
+		if p.accept(";") || p.accept("}") {
+			continue
+		}
+		p.accept("return")
+		result, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.done() && !p.accept(";") && !p.accept("}") {
+			return nil, fmt.Errorf("SyntaxError: unexpected token %s", p.peek())
+		}
+	}
+	return result, nil
+}
+
+// jsParser is a recursive descent evaluator over JavaScript tokens
+type jsParser struct {
+	tokens   []string
+	pos      int
+	doc      map[string]interface{}
+	maxSleep time.Duration
+}
+
+func (p *jsParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *jsParser) peek() string {
+	if p.done() {
+		return "end of input"
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *jsParser) accept(token string) bool {
+	if !p.done() && p.tokens[p.pos] == token {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipFunctionWrapper drops a leading "function() {"
+func (p *jsParser) skipFunctionWrapper() {
+	if len(p.tokens) >= 4 && p.tokens[0] == "function" && p.tokens[1] == "(" && p.tokens[2] == ")" && p.tokens[3] == "{" {
+		p.pos = 4
+	}
+}
+
+func (p *jsParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if !jsTruthy(left) {
+			left = right
+		}
+	}
+	return left, nil
+}
+
+func (p *jsParser) parseAnd() (interface{}, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if jsTruthy(left) {
+			left = right
+		}
+	}
+	return left, nil
+}
+
+func (p *jsParser) parseComparison() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for !p.done() {
+		op := p.tokens[p.pos]
+		switch op {
+		case "==", "===", "!=", "!==", "<", ">", "<=", ">=":
+		default:
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "==":
+			left = jsLooseEqual(left, right)
+		case "!=":
+			left = !jsLooseEqual(left, right)
+		case "===":
+			left = jsStrictEqual(left, right)
+		case "!==":
+			left = !jsStrictEqual(left, right)
+		default:
+			left = compareOp(jsComparable(left), jsComparable(right), map[string]string{
+				"<": "$lt", ">": "$gt", "<=": "$lte", ">=": "$gte",
+			}[op])
+		}
+	}
+	return left, nil
+}
+
+func (p *jsParser) parseUnary() (interface{}, error) {
+	if p.accept("!") {
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return !jsTruthy(value), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *jsParser) parsePrimary() (interface{}, error) {
+	if p.done() {
+		return nil, fmt.Errorf("SyntaxError: unexpected end of input")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case token == "(":
+		value, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("SyntaxError: missing ) in parenthetical")
+		}
+		return value, nil
+	case token == "true":
+		return true, nil
+	case token == "false":
+		return false, nil
+	case token == "null", token == "undefined":
+		return nil, nil
+	case token == "this":
+		var value interface{} = p.doc
+		for p.accept(".") {
+			if p.done() {
+				return nil, fmt.Errorf("SyntaxError: missing name after . operator")
+			}
+			m, _ := value.(map[string]interface{})
+			value = m[p.tokens[p.pos]]
+			p.pos++
+		}
+		return value, nil
+	case token == "sleep":
+		if !p.accept("(") {
+			return nil, fmt.Errorf("SyntaxError: unexpected token sleep")
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("SyntaxError: missing ) after argument list")
+		}
+		ms, _ := toFloat(jsComparable(arg))
+		delay := time.Duration(ms) * time.Millisecond
+		if delay > p.maxSleep {
+			delay = p.maxSleep
+		}
+		time.Sleep(delay)
+		return nil, nil
+	case token[0] == '\'' || token[0] == '"':
+		return token[1 : len(token)-1], nil
+	case token[0] >= '0' && token[0] <= '9':
+		n, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("SyntaxError: invalid number %s", token)
+		}
+		return n, nil
+	}
+
+	if isJSIdentifier(token) {
+		return nil, fmt.Errorf("ReferenceError: %s is not defined", token)
+	}
+	return nil, fmt.Errorf("SyntaxError: unexpected token %s", token)
+}
+
+// tokenizeJS splits a JavaScript expression into tokens. String tokens keep
+// their quotes so they can be told apart from identifiers.
+func tokenizeJS(code string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			var value strings.Builder
+			value.WriteByte(c)
+			j := i + 1
+			for ; j < len(code) && code[j] != c; j++ {
+				if code[j] == '\\' && j+1 < len(code) {
+					j++
+				}
+				value.WriteByte(code[j])
+			}
+			if j >= len(code) {
+				return nil, fmt.Errorf("SyntaxError: unterminated string literal")
+			}
+			value.WriteByte(c)
+			tokens = append(tokens, value.String())
+			i = j + 1
+		case strings.HasPrefix(code[i:], "===") || strings.HasPrefix(code[i:], "!=="):
+			tokens = append(tokens, code[i:i+3])
+			i += 3
+		case strings.HasPrefix(code[i:], "==") || strings.HasPrefix(code[i:], "!=") ||
+			strings.HasPrefix(code[i:], "<=") || strings.HasPrefix(code[i:], ">=") ||
+			strings.HasPrefix(code[i:], "&&") || strings.HasPrefix(code[i:], "||"):
+			tokens = append(tokens, code[i:i+2])
+			i += 2
+		case strings.IndexByte("()!<>.;{}", c) != -1:
+			tokens = append(tokens, string(c))
+			i++
+		case isJSIdentStart(c) || (c >= '0' && c <= '9'):
+			j := i
+			for j < len(code) && (isJSIdentStart(code[j]) || (code[j] >= '0' && code[j] <= '9') || (c >= '0' && c <= '9' && code[j] == '.')) {
+				j++
+			}
+			tokens = append(tokens, code[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("SyntaxError: illegal character %q", c)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isJSIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isJSIdentifier(token string) bool {
+	return token != "" && isJSIdentStart(token[0])
+}
+
+// jsTruthy applies JavaScript truthiness
+func jsTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// jsComparable converts numeric strings to numbers for relational and loose comparisons
+func jsComparable(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f
+		}
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return float64(1)
+		}
+		return float64(0)
+	}
+	return v
+}
+
+// jsLooseEqual implements ==
+func jsLooseEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if _, aStr := a.(string); aStr {
+		if _, bStr := b.(string); bStr {
+			return a == b
+		}
+	}
+	return valuesEqual(jsComparable(a), jsComparable(b))
+}
+
+// jsStrictEqual implements ===
+func jsStrictEqual(a, b interface{}) bool {
+	af, aNum := toFloat(a)
+	bf, bNum := toFloat(b)
+	if aNum || bNum {
+		return aNum && bNum && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,175 @@
+package sinks
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newTestDocumentStore returns a store with a seeded users collection
+func newTestDocumentStore() *DocumentStore {
+	d := NewDocumentStore()
+	d.Seed("users", []map[string]interface{}{
+		{"_id": "1", "username": "admin", "password": "adminpass", "role": "administrator", "age": 40},
+		{"_id": "2", "username": "rick", "password": "wubbalubba", "role": "user", "age": 70},
+		{"_id": "3", "username": "morty", "password": "jessica", "role": "user", "age": 14, "tags": []interface{}{"student"}},
+	})
+	return d
+}
+
+// mustFilter parses a JSON filter as the module receives it
+func mustFilter(t *testing.T, filter string) map[string]interface{} {
+	t.Helper()
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(filter), &parsed); err != nil {
+		t.Fatalf("invalid filter %s: %v", filter, err)
+	}
+	return parsed
+}
+
+// TestDocumentStore_Find tests query operator semantics
+func TestDocumentStore_Find(t *testing.T) {
+	d := newTestDocumentStore()
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{`{}`, 3},
+		{`{"username": "admin"}`, 1},
+		{`{"username": "admin", "password": "wrong"}`, 0},
+		{`{"username": {"$eq": "rick"}}`, 1},
+		{`{"username": {"$ne": ""}}`, 3},
+		{`{"username": "admin", "password": {"$ne": null}}`, 1},
+		{`{"password": {"$gt": ""}}`, 3},
+		{`{"age": {"$gte": 40}}`, 2},
+		{`{"age": {"$lt": 18}}`, 1},
+		{`{"age": {"$gt": "1"}}`, 0},
+		{`{"username": {"$in": ["admin", "morty"]}}`, 2},
+		{`{"username": {"$nin": ["admin"]}}`, 2},
+		{`{"username": {"$regex": "^a"}}`, 1},
+		{`{"username": {"$regex": "^R", "$options": "i"}}`, 1},
+		{`{"username": {"$regex": ".*"}}`, 3},
+		{`{"tags": {"$exists": true}}`, 1},
+		{`{"tags": "student"}`, 1},
+		{`{"missing": null}`, 3},
+		{`{"username": {"$not": {"$eq": "admin"}}}`, 2},
+		{`{"$or": [{"username": "admin"}, {"age": 14}]}`, 2},
+		{`{"$and": [{"role": "user"}, {"age": {"$gt": 18}}]}`, 1},
+		{`{"$nor": [{"role": "user"}]}`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			results, err := d.Find("users", mustFilter(t, tt.filter))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("Expected %d documents, got %d: %v", tt.want, len(results), results)
+			}
+		})
+	}
+}
+
+// TestDocumentStore_FindErrors tests errors surfaced from bad operators
+func TestDocumentStore_FindErrors(t *testing.T) {
+	d := newTestDocumentStore()
+
+	tests := []string{
+		`{"username": {"$foo": 1}}`,
+		`{"$bad": 1}`,
+		`{"username": {"$regex": "("}}`,
+		`{"$or": []}`,
+		`{"$where": "this.username == 'admin"}`,
+		`{"$where": "foo()"}`,
+	}
+
+	for _, filter := range tests {
+		if _, err := d.Find("users", mustFilter(t, filter)); err == nil {
+			t.Errorf("%s: expected error", filter)
+		}
+	}
+}
+
+// TestDocumentStore_Where tests $where JavaScript evaluation
+func TestDocumentStore_Where(t *testing.T) {
+	d := newTestDocumentStore()
+
+	tests := []struct {
+		where string
+		want  int
+	}{
+		{"this.username == 'admin'", 1},
+		{"this.username == 'x' || '1'=='1'", 3},
+		{"this.username == 'x' || true", 3},
+		{"function() { return this.age > 18; }", 2},
+		{"this.role === 'user' && !(this.age < 18)", 1},
+		{"this.age == '40'", 1},
+		{"this.age === '40'", 0},
+		{"this.password != 'adminpass'", 2},
+		{"this.missing == null", 3},
+		{"1 == 1; this.username == 'rick'", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			results, err := d.Find("users", map[string]interface{}{"$where": tt.where})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("Expected %d documents, got %d", tt.want, len(results))
+			}
+		})
+	}
+}
+
+// TestDocumentStore_WhereSleep tests sleep() is honoured and capped
+func TestDocumentStore_WhereSleep(t *testing.T) {
+	d := newTestDocumentStore()
+	d.MaxWhereSleep = 50 * time.Millisecond
+
+	start := time.Now()
+	d.Find("users", map[string]interface{}{"$where": "this.username == 'admin' && sleep(100000)"})
+	elapsed := time.Since(start)
+
+	// Only the admin document reaches sleep(), and it is capped
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected one capped sleep, took %v", elapsed)
+	}
+}
+
+// TestDocumentStore_Writes tests insert, update and delete
+func TestDocumentStore_Writes(t *testing.T) {
+	d := newTestDocumentStore()
+
+	id, _ := d.Insert("users", map[string]interface{}{"username": "summer"})
+	if id == nil || id == "" {
+		t.Error("Expected generated _id")
+	}
+
+	modified, err := d.Update("users", mustFilter(t, `{"role": "user"}`), mustFilter(t, `{"$set": {"role": "administrator"}}`), false)
+	if err != nil || modified != 1 {
+		t.Errorf("Expected 1 modified by single update, got %d (%v)", modified, err)
+	}
+
+	modified, _ = d.Update("users", mustFilter(t, `{"username": {"$ne": ""}}`), mustFilter(t, `{"$inc": {"age": 1}}`), true)
+	if modified != 4 {
+		t.Errorf("Expected 4 modified by multi update, got %d", modified)
+	}
+
+	admins, _ := d.Find("users", mustFilter(t, `{"role": "administrator"}`))
+	if len(admins) != 2 {
+		t.Errorf("Expected 2 administrators after update, got %d", len(admins))
+	}
+
+	deleted, _ := d.Delete("users", mustFilter(t, `{"role": "administrator"}`), true)
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted, got %d", deleted)
+	}
+	remaining, _ := d.Find("users", nil)
+	if len(remaining) != 2 {
+		t.Errorf("Expected 2 remaining documents, got %d", len(remaining))
+	}
+}
@@ -0,0 +1,219 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EgressPolicy controls what the HTTP sink may reach on the real network.
+// Routed hosts and the in-process emulated services are never subject to it.
+type EgressPolicy struct {
+	DenyExternal    bool          // Only loopback addresses and AllowedHosts are reachable
+	AllowedHosts    []string      // Hosts reachable despite DenyExternal; "*.example.com" matches subdomains
+	MaxResponseSize int64         // Response bodies are truncated to this many bytes; 0 means unlimited
+	Timeout         time.Duration // Caps every request, including longer per-request timeouts
+}
+
+// allowsHost reports whether host is on the allowlist
+func (p EgressPolicy) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// networkTransport is the HTTP sink's transport. It answers routed hosts, the
+// emulated services among them, in-process and applies the egress policy to
+// everything else.
+type networkTransport struct {
+	mu     sync.RWMutex
+	routes map[string]http.Handler
+	policy EgressPolicy
+	dialer net.Dialer
+	real   *http.Transport
+}
+
+// newNetworkTransport creates a transport with no routes and an open policy
+func newNetworkTransport() *networkTransport {
+	t := &networkTransport{
+		routes: make(map[string]http.Handler),
+		dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	t.real = &http.Transport{
+		Proxy:                 t.proxy,
+		DialContext:           t.dialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return t
+}
+
+// routeKey normalizes a route's host: lowercase, without brackets or a
+// trailing dot, and an IPv4 address however it is written (2852039166,
+// 0xa9fea9fe, ::ffff:169.254.169.254) in dotted form. A port is kept.
+func routeKey(host string) string {
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(routeKey(h), port)
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	if n, err := strconv.ParseUint(host, 0, 32); err == nil {
+		return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String()
+	}
+	return host
+}
+
+// route returns the handler for a routed host: one routed with its port, then
+// by name, then by the closest "*.domain" route
+func (t *networkTransport) route(host string) (http.Handler, bool) {
+	key := routeKey(host)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if handler, ok := t.routes[key]; ok {
+		return handler, true
+	}
+	if h, _, err := net.SplitHostPort(key); err == nil {
+		key = strings.Trim(h, "[]")
+	}
+	if handler, ok := t.routes[key]; ok {
+		return handler, true
+	}
+	for domain := key; strings.Contains(domain, "."); {
+		_, domain, _ = strings.Cut(domain, ".")
+		if handler, ok := t.routes["*."+domain]; ok {
+			return handler, true
+		}
+	}
+	return nil, false
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *networkTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if handler, ok := t.route(req.URL.Host); ok {
+		// Handlers resolve virtual hosts from the Host header
+		if req.Host == "" {
+			req.Host = req.URL.Host
+		}
+		w := newResponseBuffer()
+		handler.ServeHTTP(w, req)
+		return w.response(req), nil
+	}
+
+	return t.real.RoundTrip(req)
+}
+
+// proxy uses the environment's proxy unless external egress is denied, since
+// the dialer would then only see the proxy instead of the real destination
+func (t *networkTransport) proxy(req *http.Request) (*url.URL, error) {
+	t.mu.RLock()
+	denyExternal := t.policy.DenyExternal
+	t.mu.RUnlock()
+
+	if denyExternal {
+		return nil, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// dialContext resolves the destination and refuses addresses the policy denies.
+// The checked address is dialed directly so DNS can't be rebound in between.
+func (t *networkTransport) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	t.mu.RLock()
+	policy := t.policy
+	t.mu.RUnlock()
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.DenyExternal || policy.allowsHost(host) {
+		return t.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if !ip.IP.IsLoopback() && !ip.IP.IsUnspecified() {
+			return nil, fmt.Errorf("egress to %s (%s) blocked by policy", host, ip.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	return t.dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+}
+
+// responseBuffer is the http.ResponseWriter routed handlers write to, turned
+// into the response the transport returns
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// newResponseBuffer creates an empty response
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+// Header implements http.ResponseWriter
+func (w *responseBuffer) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter; only the first status counts
+func (w *responseBuffer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write implements http.ResponseWriter
+func (w *responseBuffer) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// response returns what was written as the response to req
+func (w *responseBuffer) response(req *http.Request) *http.Response {
+	w.WriteHeader(http.StatusOK)
+	if w.header.Get("Content-Type") == "" && w.body.Len() > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(w.body.Bytes()))
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}
+}
@@ -0,0 +1,115 @@
+package sinks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEgressPolicy_AllowsHost tests allowlist matching
+func TestEgressPolicy_AllowsHost(t *testing.T) {
+	policy := EgressPolicy{AllowedHosts: []string{"example.com", "*.partner.io"}}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com.", true},
+		{"www.example.com", false},
+		{"api.partner.io", true},
+		{"partner.io", false},
+		{"evilpartner.io", false},
+	}
+	for _, tt := range tests {
+		if got := policy.allowsHost(tt.host); got != tt.want {
+			t.Errorf("allowsHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestHTTP_EgressDenyExternal tests loopback stays reachable while other addresses are blocked
+func TestHTTP_EgressDenyExternal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local"))
+	}))
+	defer server.Close()
+
+	h := NewHTTP()
+	h.SetEgressPolicy(EgressPolicy{DenyExternal: true})
+
+	resp, err := h.Fetch(server.URL)
+	if err != nil || resp.Body != "local" {
+		t.Errorf("Expected loopback fetch to succeed, got %+v (%v)", resp, err)
+	}
+
+	_, err = h.Fetch("http://10.255.255.1/")
+	if err == nil || !strings.Contains(err.Error(), "blocked by policy") {
+		t.Errorf("Expected private address to be blocked, got %v", err)
+	}
+}
+
+// TestHTTP_EgressRoutes tests routed hosts are answered in-process regardless of policy
+func TestHTTP_EgressRoutes(t *testing.T) {
+	h := NewHTTP()
+	h.SetEgressPolicy(EgressPolicy{DenyExternal: true})
+	h.Route("intranet.corp.local", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("intranet " + r.URL.Path))
+	}))
+	h.Route("10.0.0.5", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("jenkins"))
+	}))
+
+	resp, err := h.Fetch("http://intranet.corp.local:8080/admin")
+	if err != nil || resp.Body != "intranet /admin" {
+		t.Errorf("Unexpected routed response %+v (%v)", resp, err)
+	}
+
+	resp, err = h.Fetch("http://10.0.0.5/")
+	if err != nil || resp.Body != "jenkins" {
+		t.Errorf("Unexpected routed response %+v (%v)", resp, err)
+	}
+
+	// Hosts match however they are written, and wildcards match subdomains
+	h.Route("*.svc.local", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(r.Host))
+	}))
+	for url, want := range map[string]string{
+		"http://0x0a000005/":            "jenkins",
+		"http://INTRANET.corp.local./x": "intranet /x",
+		"http://api.svc.local:9000/":    "api.svc.local:9000",
+	} {
+		if resp, err := h.Fetch(url); err != nil || resp.Body != want {
+			t.Errorf("%s: expected %q, got %+v (%v)", url, want, resp, err)
+		}
+	}
+	if resp, _ := h.Fetch("http://a.b.svc.local/"); resp == nil || resp.StatusCode != http.StatusTeapot {
+		t.Errorf("Expected the handler's status, got %+v", resp)
+	}
+}
+
+// TestHTTP_EgressLimits tests response size and timeout limits
+func TestHTTP_EgressLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		w.Write([]byte(strings.Repeat("A", 1000)))
+	}))
+	defer server.Close()
+
+	h := NewHTTP()
+	h.SetEgressPolicy(EgressPolicy{MaxResponseSize: 10, Timeout: 100 * time.Millisecond})
+
+	resp, err := h.Fetch(server.URL)
+	if err != nil || len(resp.Body) != 10 {
+		t.Errorf("Expected body truncated to 10 bytes, got %+v (%v)", resp, err)
+	}
+
+	if _, err := h.FetchWithOptions(server.URL+"/slow", HTTPOptions{Timeout: 30}); err == nil {
+		t.Error("Expected policy timeout to cap the per-request timeout")
+	}
+}
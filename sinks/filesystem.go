@@ -1,15 +1,52 @@
 package sinks
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// ErrQuotaExceeded is returned when a write would exceed the filesystem quota
+var ErrQuotaExceeded = errors.New("filesystem quota exceeded")
+
+// FilesystemQuota limits the total size and number of files under the base
+// directory. Zero values are unlimited.
+type FilesystemQuota struct {
+	MaxBytes int64
+	MaxFiles int
+}
+
+// FilesystemSnapshot is a point-in-time copy of the files under the base directory
+type FilesystemSnapshot struct {
+	Taken time.Time
+	files map[string][]byte
+	dirs  []string
+}
+
+// Files returns the number of files in the snapshot
+func (s *FilesystemSnapshot) Files() int {
+	return len(s.files)
+}
+
+// Size returns the total size of the snapshot's files in bytes
+func (s *FilesystemSnapshot) Size() int64 {
+	var size int64
+	for _, content := range s.files {
+		size += int64(len(content))
+	}
+	return size
+}
+
 // Filesystem provides file operations for path traversal testing
 type Filesystem struct {
-	basePath string
+	basePath   string
+	mu         sync.Mutex
+	quota      FilesystemQuota
+	checkpoint *FilesystemSnapshot
 }
 
 // NewFilesystem creates a new filesystem sink with a temporary directory
@@ -116,6 +153,13 @@ func (fs *Filesystem) WriteFile(relativePath, content string) error {
 		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.checkQuota(fullPath, int64(len(content))); err != nil {
+		return err
+	}
+
 	// Write the file
 	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
@@ -124,6 +168,193 @@ func (fs *Filesystem) WriteFile(relativePath, content string) error {
 	return nil
 }
 
+// CreateDir creates a directory and any missing parents
+func (fs *Filesystem) CreateDir(relativePath string) error {
+	fullPath := filepath.Join(fs.basePath, relativePath)
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", fullPath, err)
+	}
+	return nil
+}
+
+// SetQuota limits what WriteFile may store; existing files count toward it
+func (fs *Filesystem) SetQuota(quota FilesystemQuota) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.quota = quota
+}
+
+// Usage returns the total size and number of files under the base directory
+func (fs *Filesystem) Usage() (int64, int, error) {
+	var size int64
+	var files int
+	err := filepath.WalkDir(fs.basePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure usage: %w", err)
+	}
+	return size, files, nil
+}
+
+// checkQuota reports whether writing size bytes to fullPath stays within the quota
+func (fs *Filesystem) checkQuota(fullPath string, size int64) error {
+	if fs.quota.MaxBytes == 0 && fs.quota.MaxFiles == 0 {
+		return nil
+	}
+
+	usedBytes, usedFiles, err := fs.Usage()
+	if err != nil {
+		return err
+	}
+
+	// Overwriting a file replaces its size rather than adding a file
+	if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+		usedBytes -= info.Size()
+		usedFiles--
+	}
+
+	if fs.quota.MaxBytes > 0 && usedBytes+size > fs.quota.MaxBytes {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, usedBytes, fs.quota.MaxBytes)
+	}
+	if fs.quota.MaxFiles > 0 && usedFiles+1 > fs.quota.MaxFiles {
+		return fmt.Errorf("%w: %d of %d files used", ErrQuotaExceeded, usedFiles, fs.quota.MaxFiles)
+	}
+	return nil
+}
+
+// Snapshot copies every file under the base directory into memory
+func (fs *Filesystem) Snapshot() (*FilesystemSnapshot, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.snapshot()
+}
+
+// snapshot copies the base directory; the caller holds fs.mu
+func (fs *Filesystem) snapshot() (*FilesystemSnapshot, error) {
+	snap := &FilesystemSnapshot{Taken: time.Now(), files: make(map[string][]byte)}
+
+	err := filepath.WalkDir(fs.basePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(fs.basePath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() {
+			snap.dirs = append(snap.dirs, rel)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		snap.files[rel] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot filesystem: %w", err)
+	}
+	return snap, nil
+}
+
+// Restore replaces everything under the base directory with the snapshot's contents
+func (fs *Filesystem) Restore(snap *FilesystemSnapshot) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	entries, err := os.ReadDir(fs.basePath)
+	if err != nil {
+		return fmt.Errorf("failed to restore filesystem: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(fs.basePath, entry.Name())); err != nil {
+			return fmt.Errorf("failed to restore filesystem: %w", err)
+		}
+	}
+
+	for _, dir := range snap.dirs {
+		if err := os.MkdirAll(filepath.Join(fs.basePath, dir), 0755); err != nil {
+			return fmt.Errorf("failed to restore directory %s: %w", dir, err)
+		}
+	}
+	for rel, content := range snap.files {
+		fullPath := filepath.Join(fs.basePath, rel)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to restore directory for %s: %w", rel, err)
+		}
+		if err := os.WriteFile(fullPath, content, 0644); err != nil {
+			return fmt.Errorf("failed to restore file %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// Checkpoint records the current files as the state Reset returns to
+func (fs *Filesystem) Checkpoint() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	snap, err := fs.snapshot()
+	if err != nil {
+		return err
+	}
+	fs.checkpoint = snap
+	return nil
+}
+
+// Reset restores the files recorded by Checkpoint, discarding everything written since
+func (fs *Filesystem) Reset() error {
+	fs.mu.Lock()
+	snap := fs.checkpoint
+	fs.mu.Unlock()
+
+	if snap == nil {
+		return fmt.Errorf("no checkpoint to reset to")
+	}
+	return fs.Restore(snap)
+}
+
+// Fork creates a filesystem in a new temporary directory holding the files
+// recorded by Checkpoint, or the current files without one. It has the same
+// quota and resets to those files, so a client can be given its own copy.
+func (fs *Filesystem) Fork() (*Filesystem, error) {
+	fs.mu.Lock()
+	snap, quota := fs.checkpoint, fs.quota
+	var err error
+	if snap == nil {
+		snap, err = fs.snapshot()
+	}
+	fs.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "flawfactory-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	fork := &Filesystem{basePath: tmpDir, quota: quota, checkpoint: snap}
+	if err := fork.Restore(snap); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	return fork, nil
+}
+
 // Read reads a file - intentionally vulnerable to path traversal
 func (fs *Filesystem) Read(path string) (string, error) {
 	// This is intentionally vulnerable - no path sanitization
@@ -1,8 +1,10 @@
 package sinks
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -223,3 +225,113 @@ func TestFilesystem_ReadWithFilter(t *testing.T) {
 		t.Errorf("Expected 'filter content', got '%s'", data)
 	}
 }
+
+// TestFilesystem_Quota tests byte and file count limits
+func TestFilesystem_Quota(t *testing.T) {
+	sink, err := NewFilesystemWithPath(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create filesystem sink: %v", err)
+	}
+
+	sink.WriteFile("a.txt", "12345")
+	sink.SetQuota(FilesystemQuota{MaxBytes: 10, MaxFiles: 2})
+
+	if err := sink.WriteFile("b.txt", "123456"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected byte quota error, got %v", err)
+	}
+	if err := sink.WriteFile("a.txt", "1234567890"); err != nil {
+		t.Errorf("Expected overwrite within quota to succeed, got %v", err)
+	}
+	if err := sink.WriteFile("a.txt", "1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sink.WriteFile("b.txt", "2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sink.WriteFile("c.txt", "3"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected file count quota error, got %v", err)
+	}
+
+	if size, files, _ := sink.Usage(); size != 2 || files != 2 {
+		t.Errorf("Expected usage 2 bytes in 2 files, got %d in %d", size, files)
+	}
+}
+
+// TestFilesystem_CheckpointReset tests snapshots and resetting to the checkpoint
+func TestFilesystem_CheckpointReset(t *testing.T) {
+	sink, err := NewFilesystem()
+	if err != nil {
+		t.Fatalf("Failed to create filesystem sink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Reset(); err == nil {
+		t.Error("Expected error resetting without a checkpoint")
+	}
+
+	os.MkdirAll(filepath.Join(sink.BasePath(), "uploads"), 0755)
+	if err := sink.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	sink.WriteFile("uploads/shell.php", "<?php system($_GET['c']); ?>")
+	sink.WriteFile("etc/passwd", "overwritten")
+	os.Remove(filepath.Join(sink.BasePath(), "app/.env"))
+
+	snap, err := sink.Snapshot()
+	if err != nil || snap.Files() != 5 {
+		t.Fatalf("Expected snapshot of 5 files (%v)", err)
+	}
+
+	if err := sink.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if sink.Exists("uploads/shell.php") || !sink.Exists("uploads") {
+		t.Error("Expected uploaded file removed and empty directory kept")
+	}
+	if content, _ := sink.Read("etc/passwd"); !strings.HasPrefix(content, "root:x:0:0") {
+		t.Errorf("Expected original passwd, got %q", content)
+	}
+	if !sink.Exists("app/.env") {
+		t.Error("Expected deleted file to be restored")
+	}
+
+	if err := sink.Restore(snap); err != nil || !sink.Exists("uploads/shell.php") {
+		t.Errorf("Expected snapshot restore to bring back the upload (%v)", err)
+	}
+}
+
+// TestFilesystem_Fork tests a fork starts from the checkpoint and is written separately
+func TestFilesystem_Fork(t *testing.T) {
+	sink, err := NewFilesystem()
+	if err != nil {
+		t.Fatalf("Failed to create filesystem sink: %v", err)
+	}
+	defer sink.Close()
+	sink.SetQuota(FilesystemQuota{MaxFiles: 10})
+	sink.Checkpoint()
+	sink.WriteFile("uploads/after.txt", "written after the checkpoint")
+
+	fork, err := sink.Fork()
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	defer fork.Close()
+
+	if fork.BasePath() == sink.BasePath() || fork.Exists("uploads/after.txt") || !fork.Exists("etc/passwd") {
+		t.Fatal("Expected the fork to hold the checkpointed files in its own directory")
+	}
+	fork.WriteFile("etc/passwd", "overwritten")
+	if content, _ := sink.Read("etc/passwd"); !strings.HasPrefix(content, "root:x:0:0") {
+		t.Errorf("Expected the original to be untouched, got %q", content)
+	}
+	if err := fork.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if content, _ := fork.Read("etc/passwd"); !strings.HasPrefix(content, "root:x:0:0") {
+		t.Errorf("Expected the fork to reset to the checkpoint, got %q", content)
+	}
+	if fork.quota.MaxFiles != 10 {
+		t.Errorf("Expected the quota to carry over, got %+v", fork.quota)
+	}
+}
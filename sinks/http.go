@@ -11,15 +11,18 @@ import (
 // HTTP provides outbound HTTP requests for SSRF testing
 type HTTP struct {
 	client    *http.Client
+	network   *networkTransport
 	userAgent string
 	timeout   time.Duration
 }
 
 // NewHTTP creates a new HTTP sink with default settings
 func NewHTTP() *HTTP {
+	network := newNetworkTransport()
 	return &HTTP{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: network,
 			// Allow redirects by default
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				if len(via) >= 10 {
@@ -28,6 +31,7 @@ func NewHTTP() *HTTP {
 				return nil
 			},
 		},
+		network:   network,
 		userAgent: "FlawFactory/1.0",
 		timeout:   30 * time.Second,
 	}
@@ -35,10 +39,13 @@ func NewHTTP() *HTTP {
 
 // NewHTTPWithOptions creates an HTTP sink with custom options
 func NewHTTPWithOptions(timeout time.Duration, followRedirects bool) *HTTP {
+	network := newNetworkTransport()
 	h := &HTTP{
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: network,
 		},
+		network:   network,
 		userAgent: "FlawFactory/1.0",
 		timeout:   timeout,
 	}
@@ -52,6 +59,67 @@ func NewHTTPWithOptions(timeout time.Duration, followRedirects bool) *HTTP {
 	return h
 }
 
+// SetMetadataService makes requests to 169.254.169.254 (and its aliases) resolve
+// to the emulated cloud metadata service instead of the network
+func (h *HTTP) SetMetadataService(service *MetadataService) {
+	h.Route("169.254.169.254", service)
+	for _, name := range metadataHostnames {
+		h.Route(name, service)
+	}
+}
+
+// SetOOB makes requests to the OOB callback domain (and its HTTP listener)
+// reach the listener in-process, without DNS or network access
+func (h *HTTP) SetOOB(oob *OOB) {
+	callbacks := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		oob.serveHTTP(w, r, true)
+	})
+	h.Route(oob.domain, callbacks)
+	h.Route("*."+oob.domain, callbacks)
+	if addr := oob.HTTPAddr(); addr != "" {
+		h.Route(addr, callbacks)
+	}
+}
+
+// SetObjectStore makes requests to the object store's hostname (and its listener)
+// reach it in-process
+func (h *HTTP) SetObjectStore(store *ObjectStore) {
+	h.Route(store.host, store)
+	h.Route("*."+store.host, store)
+	if addr := store.Addr(); addr != "" {
+		h.Route(addr, store)
+	}
+}
+
+// SetEgressPolicy restricts what requests may reach on the real network
+func (h *HTTP) SetEgressPolicy(policy EgressPolicy) {
+	h.network.mu.Lock()
+	defer h.network.mu.Unlock()
+	h.network.policy = policy
+}
+
+// EgressPolicy returns the current egress policy
+func (h *HTTP) EgressPolicy() EgressPolicy {
+	h.network.mu.RLock()
+	defer h.network.mu.RUnlock()
+	return h.network.policy
+}
+
+// Route answers requests for host (a hostname or IP, any port) with handler in-process,
+// so internal names can point at emulated services. "*.example.com" routes
+// the subdomains of example.com, and a host with a port only that port.
+func (h *HTTP) Route(host string, handler http.Handler) {
+	h.network.mu.Lock()
+	defer h.network.mu.Unlock()
+	h.network.routes[routeKey(host)] = handler
+}
+
+// Transport returns the transport requests are sent with, which applies the
+// egress policy and answers routed hosts and emulated services in-process
+func (h *HTTP) Transport() http.RoundTripper {
+	return h.client.Transport
+}
+
 // Close is a no-op for the HTTP sink
 func (h *HTTP) Close() error {
 	return nil
@@ -85,10 +153,17 @@ type HTTPOptions struct {
 // FetchWithOptions makes an HTTP request with custom options
 func (h *HTTP) FetchWithOptions(url string, opts HTTPOptions) (*HTTPResponse, error) {
 	// Create a client with the specified options
+	policy := h.EgressPolicy()
+
 	client := h.client
 	if opts.Timeout > 0 {
+		timeout := time.Duration(opts.Timeout) * time.Second
+		if policy.Timeout > 0 && timeout > policy.Timeout {
+			timeout = policy.Timeout
+		}
 		client = &http.Client{
-			Timeout: time.Duration(opts.Timeout) * time.Second,
+			Timeout:   timeout,
+			Transport: h.network,
 		}
 
 		if !opts.FollowRedirects {
@@ -96,6 +171,10 @@ func (h *HTTP) FetchWithOptions(url string, opts HTTPOptions) (*HTTPResponse, er
 				return http.ErrUseLastResponse
 			}
 		}
+	} else if policy.Timeout > 0 && policy.Timeout < client.Timeout {
+		capped := *client
+		capped.Timeout = policy.Timeout
+		client = &capped
 	}
 
 	// Create request
@@ -129,8 +208,12 @@ func (h *HTTP) FetchWithOptions(url string, opts HTTPOptions) (*HTTPResponse, er
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read response body, up to the policy's size limit
+	var respBody io.Reader = resp.Body
+	if policy.MaxResponseSize > 0 {
+		respBody = io.LimitReader(resp.Body, policy.MaxResponseSize)
+	}
+	body, err := io.ReadAll(respBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
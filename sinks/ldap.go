@@ -0,0 +1,471 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLDAPBaseDN is the directory suffix used when none is configured
+const DefaultLDAPBaseDN = "dc=flawfactory,dc=local"
+
+// errBadFilter is returned for filters that cannot be parsed, as OpenLDAP reports them
+var errBadFilter = errors.New("Bad search filter")
+
+// LDAPEntry is a directory entry
+type LDAPEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the values of an attribute (names are case-insensitive)
+func (e *LDAPEntry) Get(name string) []string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// LDAP is an in-memory directory information tree that evaluates RFC 4515
+// search filters. With Lenient set, anything after the first complete filter is
+// ignored instead of rejected, as some directory libraries do - which is what
+// makes classic ")(&)" style injections work.
+type LDAP struct {
+	mu      sync.RWMutex
+	baseDN  string
+	entries []*LDAPEntry
+
+	Lenient bool
+}
+
+// NewLDAP creates a directory with the base, people and groups entries
+func NewLDAP(baseDN string) *LDAP {
+	if baseDN == "" {
+		baseDN = DefaultLDAPBaseDN
+	}
+
+	l := &LDAP{baseDN: baseDN, Lenient: true}
+	l.AddEntry(baseDN, map[string][]string{"objectClass": {"top", "domain"}})
+	l.AddEntry("ou=people,"+baseDN, map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"people"}})
+	l.AddEntry("ou=groups,"+baseDN, map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"groups"}})
+	return l
+}
+
+// Close is a no-op for the in-memory directory
+func (l *LDAP) Close() error {
+	return nil
+}
+
+// BaseDN returns the directory suffix
+func (l *LDAP) BaseDN() string {
+	return l.baseDN
+}
+
+// AddEntry adds or replaces an entry
+func (l *LDAP) AddEntry(dn string, attributes map[string][]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &LDAPEntry{DN: dn, Attributes: attributes}
+	for i, existing := range l.entries {
+		if strings.EqualFold(existing.DN, dn) {
+			l.entries[i] = entry
+			return
+		}
+	}
+	l.entries = append(l.entries, entry)
+}
+
+// AddUser adds a person under ou=people. Attribute values may be strings,
+// numbers or lists. The uid attribute names the entry.
+func (l *LDAP) AddUser(attributes map[string]interface{}) (string, error) {
+	attrs := ldapAttributes(attributes)
+	uid := attrs["uid"]
+	if len(uid) == 0 || uid[0] == "" {
+		return "", fmt.Errorf("user entry requires a uid")
+	}
+	if _, ok := attrs["objectClass"]; !ok {
+		attrs["objectClass"] = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+	}
+	if _, ok := attrs["cn"]; !ok {
+		attrs["cn"] = uid
+	}
+
+	dn := fmt.Sprintf("uid=%s,ou=people,%s", uid[0], l.baseDN)
+	l.AddEntry(dn, attrs)
+	return dn, nil
+}
+
+// AddGroup adds a groupOfNames under ou=groups with the given member uids
+func (l *LDAP) AddGroup(cn string, memberUIDs []string, attributes map[string]interface{}) string {
+	attrs := ldapAttributes(attributes)
+	attrs["cn"] = []string{cn}
+	attrs["objectClass"] = []string{"top", "groupOfNames"}
+	for _, uid := range memberUIDs {
+		attrs["member"] = append(attrs["member"], fmt.Sprintf("uid=%s,ou=people,%s", uid, l.baseDN))
+	}
+
+	dn := fmt.Sprintf("cn=%s,ou=groups,%s", cn, l.baseDN)
+	l.AddEntry(dn, attrs)
+	return dn
+}
+
+// Search returns entries under baseDN within scope ("base", "one" or "sub")
+// that match the filter, in DN order
+func (l *LDAP) Search(baseDN, scope, filter string) ([]LDAPEntry, error) {
+	if baseDN == "" {
+		baseDN = l.baseDN
+	}
+
+	parsed, err := ParseLDAPFilter(filter, l.Lenient)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var results []LDAPEntry
+	for _, entry := range l.entries {
+		if !inLDAPScope(entry.DN, baseDN, scope) {
+			continue
+		}
+		if parsed.Match(entry) {
+			results = append(results, *entry)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].DN < results[j].DN })
+	return results, nil
+}
+
+// Bind checks a DN's userPassword. An empty password is an unauthenticated bind and succeeds.
+func (l *LDAP) Bind(dn, password string) error {
+	if password == "" {
+		return nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, entry := range l.entries {
+		if !strings.EqualFold(entry.DN, dn) {
+			continue
+		}
+		for _, stored := range entry.Get("userPassword") {
+			if stored == password {
+				return nil
+			}
+		}
+		break
+	}
+	return errors.New("Invalid credentials")
+}
+
+// inLDAPScope reports whether dn is within scope of baseDN
+func inLDAPScope(dn, baseDN, scope string) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+
+	switch scope {
+	case "base":
+		return dn == baseDN
+	case "one":
+		if !strings.HasSuffix(dn, ","+baseDN) {
+			return false
+		}
+		return !strings.Contains(strings.TrimSuffix(dn, ","+baseDN), ",")
+	default:
+		return dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+// ldapAttributes converts config values to attribute value lists
+func ldapAttributes(values map[string]interface{}) map[string][]string {
+	attrs := make(map[string][]string, len(values))
+	for name, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				attrs[name] = append(attrs[name], fmt.Sprint(item))
+			}
+		case []string:
+			attrs[name] = append(attrs[name], v...)
+		case nil:
+		default:
+			attrs[name] = []string{fmt.Sprint(v)}
+		}
+	}
+	return attrs
+}
+
+// =============================================================================
+// Filters
+// =============================================================================
+
+// LDAPFilter is a parsed search filter
+type LDAPFilter struct {
+	Op       string // and, or, not, equal, substring, present, greater, less, approx
+	Attr     string
+	Value    string
+	Parts    []string // substring components split on *
+	Children []*LDAPFilter
+}
+
+// Match reports whether an entry satisfies the filter
+func (f *LDAPFilter) Match(entry *LDAPEntry) bool {
+	switch f.Op {
+	case "and":
+		for _, child := range f.Children {
+			if !child.Match(entry) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, child := range f.Children {
+			if child.Match(entry) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !f.Children[0].Match(entry)
+	case "present":
+		return len(entry.Get(f.Attr)) > 0
+	}
+
+	for _, value := range entry.Get(f.Attr) {
+		if f.matchValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue compares one attribute value. Passwords compare exactly,
+// everything else case-insensitively (caseIgnoreMatch).
+func (f *LDAPFilter) matchValue(value string) bool {
+	want := f.Value
+	if !strings.EqualFold(f.Attr, "userPassword") {
+		value, want = strings.ToLower(value), strings.ToLower(want)
+	}
+
+	switch f.Op {
+	case "equal", "approx":
+		return value == want
+	case "greater":
+		return compareLDAPValues(value, want) >= 0
+	case "less":
+		return compareLDAPValues(value, want) <= 0
+	case "substring":
+		parts := f.Parts
+		if !strings.EqualFold(f.Attr, "userPassword") {
+			parts = make([]string, len(f.Parts))
+			for i, p := range f.Parts {
+				parts[i] = strings.ToLower(p)
+			}
+		}
+		return matchLDAPSubstring(value, parts)
+	}
+	return false
+}
+
+// compareLDAPValues orders integers numerically and everything else lexically
+func compareLDAPValues(a, b string) int {
+	ai, errA := strconv.ParseInt(a, 10, 64)
+	bi, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// matchLDAPSubstring matches initial*any*final components
+func matchLDAPSubstring(value string, parts []string) bool {
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		idx := strings.Index(value, part)
+		if idx == -1 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return strings.HasSuffix(value, parts[last])
+}
+
+// ParseLDAPFilter parses an RFC 4515 filter. A filter without enclosing
+// parentheses is accepted, as ldapsearch does. With lenient set, trailing
+// input after the first complete filter is ignored.
+func ParseLDAPFilter(filter string, lenient bool) (*LDAPFilter, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, errBadFilter
+	}
+	if filter[0] != '(' {
+		filter = "(" + filter + ")"
+	}
+
+	p := &ldapFilterParser{input: filter}
+	parsed, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) && !lenient {
+		return nil, errBadFilter
+	}
+	return parsed, nil
+}
+
+// ldapFilterParser is a recursive descent parser over a filter string
+type ldapFilterParser struct {
+	input string
+	pos   int
+}
+
+func (p *ldapFilterParser) parse() (*LDAPFilter, error) {
+	if p.pos >= len(p.input) || p.input[p.pos] != '(' {
+		return nil, errBadFilter
+	}
+	p.pos++
+	if p.pos >= len(p.input) {
+		return nil, errBadFilter
+	}
+
+	var f *LDAPFilter
+	var err error
+
+	switch p.input[p.pos] {
+	case '&', '|':
+		op := "and"
+		if p.input[p.pos] == '|' {
+			op = "or"
+		}
+		p.pos++
+		f = &LDAPFilter{Op: op}
+		// (&) and (|) are the absolute true and false filters (RFC 4526)
+		for p.pos < len(p.input) && p.input[p.pos] == '(' {
+			child, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			f.Children = append(f.Children, child)
+		}
+	case '!':
+		p.pos++
+		child, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		f = &LDAPFilter{Op: "not", Children: []*LDAPFilter{child}}
+	default:
+		f, err = p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+		return nil, errBadFilter
+	}
+	p.pos++
+	return f, nil
+}
+
+// parseItem parses attr=value, attr>=value, attr<=value, attr~=value,
+// attr=* and substring assertions
+func (p *ldapFilterParser) parseItem() (*LDAPFilter, error) {
+	end := strings.IndexByte(p.input[p.pos:], ')')
+	if end == -1 {
+		return nil, errBadFilter
+	}
+	item := p.input[p.pos : p.pos+end]
+	if strings.IndexByte(item, '(') != -1 {
+		return nil, errBadFilter
+	}
+	p.pos += end
+
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, errBadFilter
+	}
+
+	attr, rawValue := item[:eq], item[eq+1:]
+	f := &LDAPFilter{Op: "equal"}
+	switch attr[len(attr)-1] {
+	case '>':
+		f.Op, attr = "greater", attr[:len(attr)-1]
+	case '<':
+		f.Op, attr = "less", attr[:len(attr)-1]
+	case '~':
+		f.Op, attr = "approx", attr[:len(attr)-1]
+	}
+	if attr == "" || strings.ContainsAny(attr, " *") {
+		return nil, errBadFilter
+	}
+	f.Attr = attr
+
+	if f.Op == "equal" && rawValue == "*" {
+		f.Op = "present"
+		return f, nil
+	}
+
+	if f.Op == "equal" && strings.Contains(rawValue, "*") {
+		f.Op = "substring"
+		for _, part := range strings.Split(rawValue, "*") {
+			value, err := unescapeLDAPValue(part)
+			if err != nil {
+				return nil, err
+			}
+			f.Parts = append(f.Parts, value)
+		}
+		return f, nil
+	}
+
+	value, err := unescapeLDAPValue(rawValue)
+	if err != nil {
+		return nil, err
+	}
+	f.Value = value
+	return f, nil
+}
+
+// unescapeLDAPValue decodes \XX hex escapes
+func unescapeLDAPValue(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+2 >= len(value) {
+			return "", errBadFilter
+		}
+		n, err := strconv.ParseUint(value[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", errBadFilter
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,147 @@
+package sinks
+
+import (
+	"testing"
+)
+
+// newTestLDAP returns a directory with two users and an admin group
+func newTestLDAP() *LDAP {
+	l := NewLDAP("")
+	l.AddUser(map[string]interface{}{
+		"uid": "admin", "cn": "Administrator", "mail": "admin@flawfactory.local",
+		"userPassword": "S3cret!", "uidNumber": 1000,
+	})
+	l.AddUser(map[string]interface{}{
+		"uid": "rick", "cn": "Rick Sanchez", "mail": "rick@flawfactory.local",
+		"userPassword": "wubbalubba", "uidNumber": 1001, "description": []interface{}{"scientist", "grandpa"},
+	})
+	l.AddGroup("admins", []string{"admin"}, nil)
+	return l
+}
+
+// TestLDAP_Search tests filter evaluation over the directory
+func TestLDAP_Search(t *testing.T) {
+	l := newTestLDAP()
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{"(uid=admin)", 1},
+		{"uid=admin", 1},
+		{"(UID=ADMIN)", 1},
+		{"(uid=*)", 2},
+		{"(objectClass=*)", 6},
+		{"(uid=a*)", 1},
+		{"(cn=*Sanchez)", 1},
+		{"(mail=*@flawfactory*)", 2},
+		{"(&(uid=admin)(userPassword=S3cret!))", 1},
+		{"(&(uid=admin)(userPassword=s3cret!))", 0},
+		{"(&(uid=admin)(userPassword=S3*))", 1},
+		{"(|(uid=admin)(uid=rick))", 2},
+		{"(&(objectClass=person)(!(uid=admin)))", 1},
+		{"(uidNumber>=1001)", 1},
+		{"(uidNumber<=1001)", 2},
+		{"(description=grandpa)", 1},
+		{"(member=uid=admin,ou=people,dc=flawfactory,dc=local)", 1},
+		{"(&)", 6},
+		{"(|)", 0},
+		{`(cn=Rick\20Sanchez)`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			results, err := l.Search("", "sub", tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("Expected %d entries, got %d", tt.want, len(results))
+			}
+		})
+	}
+}
+
+// TestLDAP_Scope tests base, one and sub scopes
+func TestLDAP_Scope(t *testing.T) {
+	l := newTestLDAP()
+
+	tests := []struct {
+		base  string
+		scope string
+		want  int
+	}{
+		{"ou=people," + DefaultLDAPBaseDN, "base", 1},
+		{"ou=people," + DefaultLDAPBaseDN, "one", 2},
+		{DefaultLDAPBaseDN, "one", 2},
+		{DefaultLDAPBaseDN, "sub", 6},
+	}
+
+	for _, tt := range tests {
+		results, err := l.Search(tt.base, tt.scope, "(objectClass=*)")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != tt.want {
+			t.Errorf("%s/%s: expected %d entries, got %d", tt.base, tt.scope, tt.want, len(results))
+		}
+	}
+}
+
+// TestLDAP_LenientFilters tests trailing input is ignored only in lenient mode
+func TestLDAP_LenientFilters(t *testing.T) {
+	l := newTestLDAP()
+
+	// (&(uid=admin)(userPassword={input})) with input "x)(|(uid=*" style payloads
+	injected := "(&(uid=admin)(&))(userPassword=wrong))"
+
+	results, err := l.Search("", "sub", injected)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected lenient parsing to match admin, got %d (%v)", len(results), err)
+	}
+
+	l.Lenient = false
+	if _, err := l.Search("", "sub", injected); err == nil {
+		t.Error("Expected strict parsing to reject trailing input")
+	}
+}
+
+// TestLDAP_BadFilters tests malformed filters are rejected
+func TestLDAP_BadFilters(t *testing.T) {
+	l := newTestLDAP()
+
+	for _, filter := range []string{"", "(uid=admin", "(=admin)", "(uid admin)", `(uid=\zz)`, "(&(uid=admin)", "(!)"} {
+		if _, err := l.Search("", "sub", filter); err == nil {
+			t.Errorf("%q: expected error", filter)
+		}
+	}
+}
+
+// TestLDAP_Bind tests password checks
+func TestLDAP_Bind(t *testing.T) {
+	l := newTestLDAP()
+	dn := "uid=admin,ou=people," + DefaultLDAPBaseDN
+
+	if err := l.Bind(dn, "S3cret!"); err != nil {
+		t.Errorf("Expected bind to succeed: %v", err)
+	}
+	if err := l.Bind(dn, "wrong"); err == nil {
+		t.Error("Expected bind with wrong password to fail")
+	}
+	if err := l.Bind(dn, ""); err != nil {
+		t.Error("Expected unauthenticated bind to succeed")
+	}
+}
+
+// TestLDAP_EscapedWildcard tests an escaped * is matched literally
+func TestLDAP_EscapedWildcard(t *testing.T) {
+	l := newTestLDAP()
+
+	results, err := l.Search("", "sub", `(uid=\2a)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected escaped wildcard to match nothing, got %d", len(results))
+	}
+}
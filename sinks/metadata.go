@@ -0,0 +1,307 @@
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataIP is the link-local address used by cloud metadata services
+const metadataIP = 0xA9FEA9FE // 169.254.169.254
+
+// metadataHostnames are DNS names that resolve to the metadata service
+var metadataHostnames = []string{
+	"metadata",
+	"metadata.google.internal",
+	"instance-data",
+	"instance-data.ec2.internal",
+	"fd00:ec2::254",
+}
+
+// MetadataConfig configures the emulated cloud metadata service
+type MetadataConfig struct {
+	Provider        string // aws, gcp or azure
+	RoleName        string
+	AccessKeyID     string
+	SecretAccessKey string
+	Token           string
+	Flag            string
+	RequireToken    bool // AWS IMDSv2 - require a session token from PUT /latest/api/token
+}
+
+// MetadataService emulates a 169.254.169.254-style cloud instance metadata service
+type MetadataService struct {
+	config       MetadataConfig
+	sessionToken string
+	instanceID   string
+}
+
+// NewMetadataService creates a metadata service, filling in realistic defaults
+func NewMetadataService(cfg MetadataConfig) *MetadataService {
+	if cfg.Provider == "" {
+		cfg.Provider = "aws"
+	}
+	if cfg.RoleName == "" {
+		cfg.RoleName = "flawfactory-instance-role"
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = "ASIAFLAWFACTORYEXAMPLE"
+	}
+	if cfg.SecretAccessKey == "" {
+		cfg.SecretAccessKey = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYFLAWFACTORY"
+	}
+	if cfg.Token == "" {
+		cfg.Token = "IQoJb3JpZ2luX2VjEFlawFactoryExampleSessionToken"
+	}
+	if cfg.Flag == "" {
+		cfg.Flag = "FLAG{cloud_metadata_exposed}"
+	}
+
+	return &MetadataService{
+		config:       cfg,
+		sessionToken: "AQAEAFlawFactoryIMDSv2Token==",
+		instanceID:   "i-0f1a2c3d4e5f6a7b8",
+	}
+}
+
+// Credentials returns the role's access key pair
+func (s *MetadataService) Credentials() (string, string) {
+	return s.config.AccessKeyID, s.config.SecretAccessKey
+}
+
+// Provider returns the emulated cloud provider
+func (s *MetadataService) Provider() string {
+	return s.config.Provider
+}
+
+// IsMetadataHost reports whether a host (with optional port) addresses the metadata service.
+// Decimal, hex and octal encodings of 169.254.169.254 are recognized, as a real resolver would.
+func IsMetadataHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(strings.ToLower(host), "[]")
+
+	for _, name := range metadataHostnames {
+		if host == name {
+			return true
+		}
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		ip4 := ip.To4()
+		return ip4 != nil && uint32(ip4[0])<<24|uint32(ip4[1])<<16|uint32(ip4[2])<<8|uint32(ip4[3]) == metadataIP
+	}
+
+	if n, err := strconv.ParseUint(host, 0, 32); err == nil {
+		return n == metadataIP
+	}
+
+	return false
+}
+
+// ServeHTTP dispatches to the configured provider's API
+func (s *MetadataService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch s.config.Provider {
+	case "gcp":
+		s.serveGCP(w, r)
+	case "azure":
+		s.serveAzure(w, r)
+	default:
+		s.serveAWS(w, r)
+	}
+}
+
+// serveAWS emulates the EC2 instance metadata service (IMDSv1, optionally IMDSv2)
+func (s *MetadataService) serveAWS(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+
+	if path == "/latest/api/token" {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeText(w, s.sessionToken)
+		return
+	}
+
+	if s.config.RequireToken && r.Header.Get("X-aws-ec2-metadata-token") != s.sessionToken {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	credsPath := "/latest/meta-data/iam/security-credentials/"
+	switch path {
+	case "/", "/latest", "/latest/":
+		writeText(w, "dynamic\nmeta-data\nuser-data")
+	case "/latest/meta-data", "/latest/meta-data/":
+		writeText(w, "ami-id\nhostname\ninstance-id\ninstance-type\niam/\nlocal-ipv4\nplacement/")
+	case "/latest/meta-data/ami-id":
+		writeText(w, "ami-0abcdef1234567890")
+	case "/latest/meta-data/hostname":
+		writeText(w, "ip-10-0-1-23.ec2.internal")
+	case "/latest/meta-data/instance-id":
+		writeText(w, s.instanceID)
+	case "/latest/meta-data/instance-type":
+		writeText(w, "t3.micro")
+	case "/latest/meta-data/local-ipv4":
+		writeText(w, "10.0.1.23")
+	case "/latest/meta-data/placement", "/latest/meta-data/placement/":
+		writeText(w, "availability-zone\nregion")
+	case "/latest/meta-data/placement/availability-zone":
+		writeText(w, "us-east-1a")
+	case "/latest/meta-data/placement/region":
+		writeText(w, "us-east-1")
+	case "/latest/meta-data/iam", "/latest/meta-data/iam/":
+		writeText(w, "info\nsecurity-credentials/")
+	case "/latest/meta-data/iam/info":
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"Code":               "Success",
+			"InstanceProfileArn": "arn:aws:iam::123456789012:instance-profile/" + s.config.RoleName,
+			"InstanceProfileId":  "AIPAFLAWFACTORYEXAMPLE",
+		})
+	case strings.TrimSuffix(credsPath, "/"), credsPath:
+		writeText(w, s.config.RoleName)
+	case credsPath + s.config.RoleName:
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"Code":            "Success",
+			"LastUpdated":     time.Now().UTC().Format(time.RFC3339),
+			"Type":            "AWS-HMAC",
+			"AccessKeyId":     s.config.AccessKeyID,
+			"SecretAccessKey": s.config.SecretAccessKey,
+			"Token":           s.config.Token,
+			"Expiration":      time.Now().Add(6 * time.Hour).UTC().Format(time.RFC3339),
+		})
+	case "/latest/user-data":
+		writeText(w, fmt.Sprintf("#!/bin/bash\n# bootstrap\nexport APP_SECRET=%q\n/opt/app/start.sh\n", s.config.Flag))
+	case "/latest/dynamic/instance-identity/document":
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"accountId":        "123456789012",
+			"architecture":     "x86_64",
+			"availabilityZone": "us-east-1a",
+			"imageId":          "ami-0abcdef1234567890",
+			"instanceId":       s.instanceID,
+			"instanceType":     "t3.micro",
+			"privateIp":        "10.0.1.23",
+			"region":           "us-east-1",
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveGCP emulates the Compute Engine metadata server
+func (s *MetadataService) serveGCP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		http.Error(w, "Missing Metadata-Flavor:Google header.", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Metadata-Flavor", "Google")
+
+	prefix := "/computeMetadata/v1/"
+	switch strings.TrimPrefix(r.URL.Path, prefix) {
+	case "", "/":
+		writeText(w, "instance/\nproject/")
+	case "project/project-id":
+		writeText(w, "flawfactory-lab")
+	case "project/numeric-project-id":
+		writeText(w, "123456789012")
+	case "instance/hostname":
+		writeText(w, "flawfactory-vm.c.flawfactory-lab.internal")
+	case "instance/id":
+		writeText(w, "4520031799277581759")
+	case "instance/zone":
+		writeText(w, "projects/123456789012/zones/us-central1-a")
+	case "instance/attributes/":
+		writeText(w, "ssh-keys\nstartup-script")
+	case "instance/attributes/startup-script":
+		writeText(w, fmt.Sprintf("#!/bin/bash\nexport APP_SECRET=%q\n", s.config.Flag))
+	case "instance/attributes/ssh-keys":
+		writeText(w, "admin:ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFlawFactoryExampleKey admin")
+	case "instance/service-accounts/":
+		writeText(w, "default/\n"+s.config.RoleName+"@flawfactory-lab.iam.gserviceaccount.com/")
+	case "instance/service-accounts/default/email":
+		writeText(w, s.config.RoleName+"@flawfactory-lab.iam.gserviceaccount.com")
+	case "instance/service-accounts/default/scopes":
+		writeText(w, "https://www.googleapis.com/auth/cloud-platform")
+	case "instance/service-accounts/default/token":
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"access_token": "ya29." + s.config.Token,
+			"expires_in":   3599,
+			"token_type":   "Bearer",
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveAzure emulates the Azure Instance Metadata Service
+func (s *MetadataService) serveAzure(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata") != "true" {
+		writeJSONBody(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Bad request. Required metadata header not specified",
+		})
+		return
+	}
+	if r.URL.Query().Get("api-version") == "" {
+		writeJSONBody(w, http.StatusBadRequest, map[string]interface{}{
+			"error":           "Bad request. api-version was not specified in the request",
+			"newest-versions": []string{"2021-02-01"},
+		})
+		return
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/metadata/instance":
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"compute": map[string]interface{}{
+				"name":              "flawfactory-vm",
+				"location":          "eastus",
+				"vmId":              "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+				"subscriptionId":    "8d10da13-8125-4ba9-a717-bf7490507b3d",
+				"resourceGroupName": "flawfactory-rg",
+				"tags":              "secret:" + s.config.Flag,
+			},
+			"network": map[string]interface{}{
+				"interface": []map[string]interface{}{{
+					"ipv4": map[string]interface{}{
+						"ipAddress": []map[string]string{{"privateIpAddress": "10.0.1.23"}},
+					},
+				}},
+			},
+		})
+	case "/metadata/identity/oauth2/token":
+		resource := r.URL.Query().Get("resource")
+		if resource == "" {
+			resource = "https://management.azure.com/"
+		}
+		writeJSONBody(w, http.StatusOK, map[string]interface{}{
+			"access_token": "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiJ9." + s.config.Token,
+			"client_id":    "5e4b9f8a-1c2d-4e3f-8a9b-flawfactory",
+			"expires_in":   "86399",
+			"resource":     resource,
+			"token_type":   "Bearer",
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeText writes a plain text metadata response
+func writeText(w http.ResponseWriter, body string) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/plain")
+	}
+	w.Write([]byte(body))
+}
+
+// writeJSONBody writes a JSON metadata response with the given status
+func writeJSONBody(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
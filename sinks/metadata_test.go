@@ -0,0 +1,104 @@
+package sinks
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestIsMetadataHost tests recognition of metadata addresses and encodings
+func TestIsMetadataHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"169.254.169.254", true},
+		{"169.254.169.254:80", true},
+		{"metadata.google.internal", true},
+		{"2852039166", true},
+		{"0xA9FEA9FE", true},
+		{"[::ffff:169.254.169.254]", true},
+		{"169.254.169.253", false},
+		{"example.com", false},
+		{"127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := IsMetadataHost(tt.host); got != tt.expected {
+				t.Errorf("IsMetadataHost(%q) = %v, want %v", tt.host, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestHTTP_MetadataService_AWS tests that the HTTP sink resolves AWS metadata in-process
+func TestHTTP_MetadataService_AWS(t *testing.T) {
+	sink := NewHTTP()
+	sink.SetMetadataService(NewMetadataService(MetadataConfig{RoleName: "lab-role"}))
+
+	resp, err := sink.Fetch("http://169.254.169.254/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if resp.Body != "lab-role" {
+		t.Errorf("Expected role name, got '%s'", resp.Body)
+	}
+
+	resp, err = sink.Fetch("http://2852039166/latest/meta-data/iam/security-credentials/lab-role")
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if !strings.Contains(resp.Body, "SecretAccessKey") {
+		t.Errorf("Expected credentials, got '%s'", resp.Body)
+	}
+}
+
+// TestHTTP_MetadataService_IMDSv2 tests the session token requirement
+func TestHTTP_MetadataService_IMDSv2(t *testing.T) {
+	sink := NewHTTP()
+	sink.SetMetadataService(NewMetadataService(MetadataConfig{RequireToken: true}))
+
+	resp, _ := sink.Fetch("http://169.254.169.254/latest/user-data")
+	if resp.StatusCode != 401 {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	tokenResp, _ := sink.FetchWithOptions("http://169.254.169.254/latest/api/token", HTTPOptions{Method: "PUT", Timeout: 5})
+	resp, _ = sink.FetchWithOptions("http://169.254.169.254/latest/user-data", HTTPOptions{
+		Headers: map[string]string{"X-aws-ec2-metadata-token": tokenResp.Body},
+		Timeout: 5,
+	})
+	if !strings.Contains(resp.Body, "FLAG{") {
+		t.Errorf("Expected flag in user-data, got '%s'", resp.Body)
+	}
+}
+
+// TestHTTP_MetadataService_Headers tests the GCP and Azure header requirements
+func TestHTTP_MetadataService_Headers(t *testing.T) {
+	tests := []struct {
+		provider string
+		url      string
+		header   string
+		value    string
+	}{
+		{"gcp", "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", "Metadata-Flavor", "Google"},
+		{"azure", "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01", "Metadata", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			sink := NewHTTP()
+			sink.SetMetadataService(NewMetadataService(MetadataConfig{Provider: tt.provider}))
+
+			resp, _ := sink.Fetch(tt.url)
+			if resp.StatusCode == 200 {
+				t.Errorf("Expected request without %s header to be rejected", tt.header)
+			}
+
+			resp, _ = sink.FetchWithOptions(tt.url, HTTPOptions{Headers: map[string]string{tt.header: tt.value}, Timeout: 5})
+			if resp.StatusCode != 200 || !strings.Contains(resp.Body, "access_token") {
+				t.Errorf("Expected token response, got %d '%s'", resp.StatusCode, resp.Body)
+			}
+		})
+	}
+}
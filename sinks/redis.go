@@ -0,0 +1,638 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errWrongType is returned when a command is used against a key of another type
+var errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// redisEntry is a single key's value
+type redisEntry struct {
+	kind    string // string, list, hash or set
+	str     string
+	list    []string
+	hash    map[string]string
+	set     map[string]bool
+	expires time.Time
+}
+
+// Redis is an embedded in-process key-value store that follows Redis command
+// semantics closely enough for injection testing. Commands are given in inline
+// form ("SET key value"), as redis-cli and the inline protocol accept them.
+type Redis struct {
+	mu     sync.Mutex
+	data   map[string]*redisEntry
+	config map[string]string
+	now    func() time.Time
+}
+
+// NewRedis creates an empty store with default server configuration
+func NewRedis() *Redis {
+	return &Redis{
+		data: make(map[string]*redisEntry),
+		config: map[string]string{
+			"dir":            "/var/lib/redis",
+			"dbfilename":     "dump.rdb",
+			"requirepass":    "",
+			"maxmemory":      "0",
+			"appendonly":     "no",
+			"protected-mode": "no",
+		},
+		now: time.Now,
+	}
+}
+
+// Close is a no-op for the in-memory store
+func (r *Redis) Close() error {
+	return nil
+}
+
+// Seed stores a value from config: scalars become strings, lists become
+// lists and maps become hashes
+func (r *Redis) Seed(key string, value interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch v := value.(type) {
+	case []interface{}:
+		entry := &redisEntry{kind: "list"}
+		for _, item := range v {
+			entry.list = append(entry.list, fmt.Sprint(item))
+		}
+		r.data[key] = entry
+	case map[string]interface{}:
+		entry := &redisEntry{kind: "hash", hash: make(map[string]string)}
+		for field, item := range v {
+			entry.hash[field] = fmt.Sprint(item)
+		}
+		r.data[key] = entry
+	case nil:
+		return fmt.Errorf("no value for key %s", key)
+	default:
+		r.data[key] = &redisEntry{kind: "string", str: fmt.Sprint(v)}
+	}
+
+	return nil
+}
+
+// Execute runs a single inline command and returns its reply.
+// Replies are strings (status and bulk), nil, int64, []interface{} or,
+// for HGETALL and CONFIG GET, map[string]string.
+func (r *Redis) Execute(command string) (interface{}, error) {
+	args, err := ParseRedisCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("ERR empty command")
+	}
+	return r.Do(args...)
+}
+
+// Do runs a command given as separate arguments
+func (r *Redis) Do(args ...string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("ERR empty command")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+
+	arity, ok := redisArity[name]
+	if !ok {
+		return nil, fmt.Errorf("ERR unknown command '%s'", strings.ToLower(name))
+	}
+	if len(args) < arity {
+		return nil, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+	}
+
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			return args[0], nil
+		}
+		return "PONG", nil
+	case "ECHO":
+		return args[0], nil
+	case "GET":
+		entry, err := r.lookup(args[0], "string")
+		if entry == nil {
+			return nil, err
+		}
+		return entry.str, nil
+	case "MGET":
+		values := make([]interface{}, len(args))
+		for i, key := range args {
+			if entry, _ := r.lookup(key, "string"); entry != nil {
+				values[i] = entry.str
+			}
+		}
+		return values, nil
+	case "SET":
+		return r.set(args)
+	case "APPEND":
+		entry, err := r.lookupOrCreate(args[0], "string")
+		if err != nil {
+			return nil, err
+		}
+		entry.str += args[1]
+		return int64(len(entry.str)), nil
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		delta := int64(1)
+		if name == "INCRBY" || name == "DECRBY" {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+			}
+			n, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return nil, errors.New("ERR value is not an integer or out of range")
+			}
+			delta = n
+		}
+		if strings.HasPrefix(name, "DECR") {
+			delta = -delta
+		}
+		return r.incr(args[0], delta)
+	case "DEL":
+		var deleted int64
+		for _, key := range args {
+			if r.live(key) != nil {
+				delete(r.data, key)
+				deleted++
+			}
+		}
+		return deleted, nil
+	case "EXISTS":
+		var count int64
+		for _, key := range args {
+			if r.live(key) != nil {
+				count++
+			}
+		}
+		return count, nil
+	case "TYPE":
+		if entry := r.live(args[0]); entry != nil {
+			return entry.kind, nil
+		}
+		return "none", nil
+	case "KEYS":
+		return r.keys(args[0]), nil
+	case "DBSIZE":
+		return int64(len(r.keys("*"))), nil
+	case "EXPIRE":
+		entry := r.live(args[0])
+		if entry == nil {
+			return int64(0), nil
+		}
+		seconds, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("ERR value is not an integer or out of range")
+		}
+		entry.expires = r.now().Add(time.Duration(seconds) * time.Second)
+		return int64(1), nil
+	case "PERSIST":
+		entry := r.live(args[0])
+		if entry == nil || entry.expires.IsZero() {
+			return int64(0), nil
+		}
+		entry.expires = time.Time{}
+		return int64(1), nil
+	case "TTL":
+		entry := r.live(args[0])
+		if entry == nil {
+			return int64(-2), nil
+		}
+		if entry.expires.IsZero() {
+			return int64(-1), nil
+		}
+		return int64(entry.expires.Sub(r.now()).Round(time.Second) / time.Second), nil
+	case "HGET":
+		entry, err := r.lookup(args[0], "hash")
+		if entry == nil {
+			return nil, err
+		}
+		if value, ok := entry.hash[args[1]]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "HSET", "HMSET":
+		if len(args)%2 != 1 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(name))
+		}
+		entry, err := r.lookupOrCreate(args[0], "hash")
+		if err != nil {
+			return nil, err
+		}
+		var added int64
+		for i := 1; i < len(args); i += 2 {
+			if _, exists := entry.hash[args[i]]; !exists {
+				added++
+			}
+			entry.hash[args[i]] = args[i+1]
+		}
+		if name == "HMSET" {
+			return "OK", nil
+		}
+		return added, nil
+	case "HGETALL":
+		entry, err := r.lookup(args[0], "hash")
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]string)
+		if entry != nil {
+			for k, v := range entry.hash {
+				fields[k] = v
+			}
+		}
+		return fields, nil
+	case "HKEYS":
+		entry, err := r.lookup(args[0], "hash")
+		if err != nil {
+			return nil, err
+		}
+		fields := []interface{}{}
+		if entry != nil {
+			for _, k := range sortedKeys(entry.hash) {
+				fields = append(fields, k)
+			}
+		}
+		return fields, nil
+	case "HDEL":
+		entry, err := r.lookup(args[0], "hash")
+		if entry == nil {
+			return int64(0), err
+		}
+		var deleted int64
+		for _, field := range args[1:] {
+			if _, ok := entry.hash[field]; ok {
+				delete(entry.hash, field)
+				deleted++
+			}
+		}
+		return deleted, nil
+	case "LPUSH", "RPUSH":
+		entry, err := r.lookupOrCreate(args[0], "list")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range args[1:] {
+			if name == "LPUSH" {
+				entry.list = append([]string{item}, entry.list...)
+			} else {
+				entry.list = append(entry.list, item)
+			}
+		}
+		return int64(len(entry.list)), nil
+	case "LPOP", "RPOP":
+		entry, err := r.lookup(args[0], "list")
+		if entry == nil || len(entry.list) == 0 {
+			return nil, err
+		}
+		var item string
+		if name == "LPOP" {
+			item, entry.list = entry.list[0], entry.list[1:]
+		} else {
+			item, entry.list = entry.list[len(entry.list)-1], entry.list[:len(entry.list)-1]
+		}
+		if len(entry.list) == 0 {
+			delete(r.data, args[0])
+		}
+		return item, nil
+	case "LLEN":
+		entry, err := r.lookup(args[0], "list")
+		if entry == nil {
+			return int64(0), err
+		}
+		return int64(len(entry.list)), nil
+	case "LRANGE":
+		entry, err := r.lookup(args[0], "list")
+		if err != nil {
+			return nil, err
+		}
+		start, err1 := strconv.Atoi(args[1])
+		stop, err2 := strconv.Atoi(args[2])
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("ERR value is not an integer or out of range")
+		}
+		items := []interface{}{}
+		if entry != nil {
+			for _, item := range redisRange(entry.list, start, stop) {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case "SADD":
+		entry, err := r.lookupOrCreate(args[0], "set")
+		if err != nil {
+			return nil, err
+		}
+		var added int64
+		for _, member := range args[1:] {
+			if !entry.set[member] {
+				entry.set[member] = true
+				added++
+			}
+		}
+		return added, nil
+	case "SMEMBERS":
+		entry, err := r.lookup(args[0], "set")
+		if err != nil {
+			return nil, err
+		}
+		members := []interface{}{}
+		if entry != nil {
+			for _, m := range sortedKeys(entry.set) {
+				members = append(members, m)
+			}
+		}
+		return members, nil
+	case "SISMEMBER":
+		entry, err := r.lookup(args[0], "set")
+		if entry == nil || !entry.set[args[1]] {
+			return int64(0), err
+		}
+		return int64(1), nil
+	case "CONFIG":
+		return r.configCommand(args)
+	case "FLUSHALL", "FLUSHDB":
+		r.data = make(map[string]*redisEntry)
+		return "OK", nil
+	case "SAVE", "BGSAVE":
+		// Nothing is written, but report where a real server would have put the dump
+		return fmt.Sprintf("OK (saved to %s)", path.Join(r.config["dir"], r.config["dbfilename"])), nil
+	case "INFO":
+		return fmt.Sprintf("# Server\r\nredis_version:6.2.0\r\nredis_mode:standalone\r\nos:Linux\r\nconfig_file:/etc/redis/redis.conf\r\n# Keyspace\r\ndb0:keys=%d,expires=0\r\n", len(r.keys("*"))), nil
+	}
+
+	return nil, fmt.Errorf("ERR unknown command '%s'", strings.ToLower(name))
+}
+
+// redisArity is the minimum number of arguments each supported command takes
+var redisArity = map[string]int{
+	"PING": 0, "ECHO": 1, "GET": 1, "MGET": 1, "SET": 2, "APPEND": 2,
+	"INCR": 1, "DECR": 1, "INCRBY": 2, "DECRBY": 2, "DEL": 1, "EXISTS": 1,
+	"TYPE": 1, "KEYS": 1, "DBSIZE": 0, "EXPIRE": 2, "PERSIST": 1, "TTL": 1,
+	"HGET": 2, "HSET": 3, "HMSET": 3, "HGETALL": 1, "HKEYS": 1, "HDEL": 2,
+	"LPUSH": 2, "RPUSH": 2, "LPOP": 1, "RPOP": 1, "LLEN": 1, "LRANGE": 3,
+	"SADD": 2, "SMEMBERS": 1, "SISMEMBER": 2,
+	"CONFIG": 1, "FLUSHALL": 0, "FLUSHDB": 0, "SAVE": 0, "BGSAVE": 0, "INFO": 0,
+}
+
+// live returns a key's entry, dropping it first if it has expired
+func (r *Redis) live(key string) *redisEntry {
+	entry, ok := r.data[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expires.IsZero() && !r.now().Before(entry.expires) {
+		delete(r.data, key)
+		return nil
+	}
+	return entry
+}
+
+// lookup returns a key's entry if it holds the given kind.
+// A missing key returns nil with no error.
+func (r *Redis) lookup(key, kind string) (*redisEntry, error) {
+	entry := r.live(key)
+	if entry == nil {
+		return nil, nil
+	}
+	if entry.kind != kind {
+		return nil, errWrongType
+	}
+	return entry, nil
+}
+
+// lookupOrCreate returns a key's entry, creating an empty one of the given kind
+func (r *Redis) lookupOrCreate(key, kind string) (*redisEntry, error) {
+	entry, err := r.lookup(key, kind)
+	if err != nil || entry != nil {
+		return entry, err
+	}
+
+	entry = &redisEntry{kind: kind}
+	switch kind {
+	case "hash":
+		entry.hash = make(map[string]string)
+	case "set":
+		entry.set = make(map[string]bool)
+	}
+	r.data[key] = entry
+	return entry, nil
+}
+
+// set implements SET key value [EX seconds|PX milliseconds] [NX|XX]
+func (r *Redis) set(args []string) (interface{}, error) {
+	key, value := args[0], args[1]
+	var ttl time.Duration
+	nx, xx := false, false
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return nil, errors.New("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return nil, errors.New("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.EqualFold(args[i], "PX") {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return nil, errors.New("ERR syntax error")
+		}
+	}
+
+	exists := r.live(key) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil, nil
+	}
+
+	entry := &redisEntry{kind: "string", str: value}
+	if ttl > 0 {
+		entry.expires = r.now().Add(ttl)
+	}
+	r.data[key] = entry
+	return "OK", nil
+}
+
+// incr adds delta to an integer string value
+func (r *Redis) incr(key string, delta int64) (interface{}, error) {
+	entry, err := r.lookupOrCreate(key, "string")
+	if err != nil {
+		return nil, err
+	}
+
+	current := int64(0)
+	if entry.str != "" {
+		current, err = strconv.ParseInt(entry.str, 10, 64)
+		if err != nil {
+			return nil, errors.New("ERR value is not an integer or out of range")
+		}
+	}
+	current += delta
+	entry.str = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+// keys returns the live keys matching a glob pattern, sorted
+func (r *Redis) keys(pattern string) []interface{} {
+	var names []string
+	for key := range r.data {
+		if r.live(key) == nil {
+			continue
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+
+	keys := make([]interface{}, len(names))
+	for i, name := range names {
+		keys[i] = name
+	}
+	return keys
+}
+
+// configCommand implements CONFIG GET/SET/RESETSTAT
+func (r *Redis) configCommand(args []string) (interface{}, error) {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if len(args) < 2 {
+			return nil, errors.New("ERR wrong number of arguments for 'config|get' command")
+		}
+		values := make(map[string]string)
+		for name, value := range r.config {
+			if ok, _ := path.Match(strings.ToLower(args[1]), name); ok {
+				values[name] = value
+			}
+		}
+		return values, nil
+	case "SET":
+		if len(args) < 3 {
+			return nil, errors.New("ERR wrong number of arguments for 'config|set' command")
+		}
+		name := strings.ToLower(args[1])
+		if _, ok := r.config[name]; !ok {
+			return nil, fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", name)
+		}
+		r.config[name] = args[2]
+		return "OK", nil
+	case "RESETSTAT":
+		return "OK", nil
+	}
+	return nil, fmt.Errorf("ERR unknown subcommand '%s'", args[0])
+}
+
+// redisRange applies LRANGE-style inclusive, negative-aware indexes
+func redisRange(items []string, start, stop int) []string {
+	n := len(items)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return nil
+	}
+	return items[start : stop+1]
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseRedisCommand splits an inline command into arguments the way redis-cli
+// does: whitespace separated, with "double" (escape-aware) and 'single' quotes
+func ParseRedisCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '"':
+			inArg = true
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) {
+					i++
+					switch command[i] {
+					case 'n':
+						current.WriteByte('\n')
+					case 'r':
+						current.WriteByte('\r')
+					case 't':
+						current.WriteByte('\t')
+					default:
+						current.WriteByte(command[i])
+					}
+					continue
+				}
+				current.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New("ERR Protocol error: unbalanced quotes in request")
+			}
+		case c == '\'':
+			inArg = true
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("ERR Protocol error: unbalanced quotes in request")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			current.WriteByte(c)
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,160 @@
+package sinks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRedis_StringCommands tests GET/SET/INCR/DEL semantics
+func TestRedis_StringCommands(t *testing.T) {
+	r := NewRedis()
+
+	steps := []struct {
+		command string
+		want    interface{}
+	}{
+		{"GET missing", nil},
+		{"SET greeting hello", "OK"},
+		{"GET greeting", "hello"},
+		{`SET quoted "hello world"`, "OK"},
+		{"GET quoted", "hello world"},
+		{"SET greeting other NX", nil},
+		{"APPEND greeting !", int64(6)},
+		{"INCR counter", int64(1)},
+		{"INCRBY counter 41", int64(42)},
+		{"DECR counter", int64(41)},
+		{"EXISTS greeting counter missing", int64(2)},
+		{"TYPE counter", "string"},
+		{"DEL greeting missing", int64(1)},
+		{"GET greeting", nil},
+		{"PING", "PONG"},
+	}
+
+	for _, step := range steps {
+		got, err := r.Execute(step.command)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.command, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: expected %#v, got %#v", step.command, step.want, got)
+		}
+	}
+}
+
+// TestRedis_Seed tests seeding strings, lists and hashes from config values
+func TestRedis_Seed(t *testing.T) {
+	r := NewRedis()
+	r.Seed("api_key", "sk_live_123")
+	r.Seed("queue", []interface{}{"job1", "job2", 3})
+	r.Seed("user:1", map[string]interface{}{"name": "admin", "role": "administrator"})
+
+	if got, _ := r.Execute("GET api_key"); got != "sk_live_123" {
+		t.Errorf("Expected seeded string, got %v", got)
+	}
+	if got, _ := r.Execute("LRANGE queue 0 -1"); !reflect.DeepEqual(got, []interface{}{"job1", "job2", "3"}) {
+		t.Errorf("Expected seeded list, got %v", got)
+	}
+	if got, _ := r.Execute("HGET user:1 role"); got != "administrator" {
+		t.Errorf("Expected seeded hash field, got %v", got)
+	}
+	if got, _ := r.Execute("KEYS *"); !reflect.DeepEqual(got, []interface{}{"api_key", "queue", "user:1"}) {
+		t.Errorf("Expected all keys, got %v", got)
+	}
+	if got, _ := r.Execute("KEYS user:*"); !reflect.DeepEqual(got, []interface{}{"user:1"}) {
+		t.Errorf("Expected matching keys, got %v", got)
+	}
+
+	if _, err := r.Execute("GET queue"); err != errWrongType {
+		t.Errorf("Expected WRONGTYPE error, got %v", err)
+	}
+}
+
+// TestRedis_Config tests CONFIG GET/SET and where SAVE reports the dump
+func TestRedis_Config(t *testing.T) {
+	r := NewRedis()
+
+	if _, err := r.Execute("CONFIG SET dir /var/www/html"); err != nil {
+		t.Fatalf("CONFIG SET failed: %v", err)
+	}
+	r.Execute("CONFIG SET dbfilename shell.php")
+
+	got, _ := r.Execute("CONFIG GET dir")
+	if !reflect.DeepEqual(got, map[string]string{"dir": "/var/www/html"}) {
+		t.Errorf("Expected updated dir, got %v", got)
+	}
+	if got, _ := r.Execute("SAVE"); got != "OK (saved to /var/www/html/shell.php)" {
+		t.Errorf("Unexpected SAVE reply: %v", got)
+	}
+	if _, err := r.Execute("CONFIG SET nosuchoption 1"); err == nil {
+		t.Error("Expected error for unknown option")
+	}
+
+	r.Execute("SET a 1")
+	r.Execute("FLUSHALL")
+	if got, _ := r.Execute("DBSIZE"); got != int64(0) {
+		t.Errorf("Expected empty store after FLUSHALL, got %v", got)
+	}
+}
+
+// TestRedis_Expiry tests EXPIRE/TTL with a controlled clock
+func TestRedis_Expiry(t *testing.T) {
+	r := NewRedis()
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	r.Execute("SET session abc EX 60")
+	if got, _ := r.Execute("TTL session"); got != int64(60) {
+		t.Errorf("Expected TTL 60, got %v", got)
+	}
+	if got, _ := r.Execute("TTL missing"); got != int64(-2) {
+		t.Errorf("Expected TTL -2 for missing key, got %v", got)
+	}
+
+	now = now.Add(61 * time.Second)
+	if got, _ := r.Execute("GET session"); got != nil {
+		t.Errorf("Expected expired key to be gone, got %v", got)
+	}
+}
+
+// TestRedis_Errors tests error replies
+func TestRedis_Errors(t *testing.T) {
+	r := NewRedis()
+
+	tests := []string{
+		"NOSUCHCOMMAND",
+		"GET",
+		`SET key "unterminated`,
+		"INCRBY counter notanumber",
+	}
+
+	for _, command := range tests {
+		if _, err := r.Execute(command); err == nil {
+			t.Errorf("%s: expected error", command)
+		}
+	}
+}
+
+// TestParseRedisCommand tests inline command tokenizing
+func TestParseRedisCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"GET user:1", []string{"GET", "user:1"}},
+		{`SET k "a b\nc"`, []string{"SET", "k", "a b\nc"}},
+		{"SET k 'single quoted'", []string{"SET", "k", "single quoted"}},
+		{"  PING  ", []string{"PING"}},
+		{`SET k ""`, []string{"SET", "k", ""}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRedisCommand(tt.command)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.command, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.command, tt.want, got)
+		}
+	}
+}
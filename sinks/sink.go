@@ -10,8 +10,15 @@ type Sink interface {
 type SinkType string
 
 const (
-	SinkTypeSQLite     SinkType = "sqlite"
-	SinkTypeFilesystem SinkType = "filesystem"
-	SinkTypeCommand    SinkType = "command"
-	SinkTypeHTTP       SinkType = "http"
+	SinkTypeSQLite      SinkType = "sqlite"
+	SinkTypeFilesystem  SinkType = "filesystem"
+	SinkTypeCommand     SinkType = "command"
+	SinkTypeHTTP        SinkType = "http"
+	SinkTypeRedis       SinkType = "redis"
+	SinkTypeDocument    SinkType = "document"
+	SinkTypeLDAP        SinkType = "ldap"
+	SinkTypeSMTP        SinkType = "smtp"
+	SinkTypeOOB         SinkType = "oob"
+	SinkTypeTemplate    SinkType = "template"
+	SinkTypeObjectStore SinkType = "objectstore"
 )
@@ -0,0 +1,403 @@
+package sinks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxStoredEmails caps the mailbox; the oldest messages are dropped first
+	maxStoredEmails = 1000
+
+	// maxEmailSize caps a single message received over SMTP
+	maxEmailSize = 10 << 20
+
+	// smtpIdleTimeout closes SMTP connections that stop talking
+	smtpIdleTimeout = 5 * time.Minute
+)
+
+// Email is a captured message
+type Email struct {
+	ID         int                 `json:"id"`
+	From       string              `json:"from"`
+	To         []string            `json:"to"`
+	Subject    string              `json:"subject"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+	Raw        string              `json:"raw"`
+	Source     string              `json:"source"` // capture or smtp
+	ReceivedAt time.Time           `json:"received_at"`
+}
+
+// SMTP captures outbound email in memory instead of delivering it. Messages
+// are handed over directly by modules or, once Listen is called, by any client
+// speaking SMTP to the local port.
+//
+// Recipients are taken from the envelope and from the To, Cc and Bcc headers,
+// the way `sendmail -t` does, so injected headers add real recipients.
+type SMTP struct {
+	mu       sync.Mutex
+	messages []Email
+	nextID   int
+	listener net.Listener
+	hostname string
+}
+
+// NewSMTP creates an empty mailbox
+func NewSMTP() *SMTP {
+	return &SMTP{
+		nextID:   1,
+		hostname: "mail.flawfactory.local",
+	}
+}
+
+// Send parses a raw message (headers, blank line, body) and stores it
+func (s *SMTP) Send(from string, to []string, message string) (*Email, error) {
+	return s.store(from, to, message, "capture")
+}
+
+// store parses and records a message
+func (s *SMTP) store(from string, to []string, message, source string) (*Email, error) {
+	headers, body := parseEmail(message)
+
+	if from == "" {
+		if values := headers["From"]; len(values) > 0 {
+			from = values[0]
+		}
+	}
+
+	var recipients []string
+	seen := make(map[string]bool)
+	addRecipient := func(addr string) {
+		addr = strings.TrimSpace(addr)
+		key := strings.ToLower(addr)
+		if addr == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		recipients = append(recipients, addr)
+	}
+	for _, addr := range to {
+		addRecipient(addr)
+	}
+	for _, name := range []string{"To", "Cc", "Bcc"} {
+		for _, value := range headers[name] {
+			for _, addr := range parseAddressList(value) {
+				addRecipient(addr)
+			}
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	subject := ""
+	if values := headers["Subject"]; len(values) > 0 {
+		subject = values[0]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email := Email{
+		ID:         s.nextID,
+		From:       from,
+		To:         recipients,
+		Subject:    subject,
+		Headers:    headers,
+		Body:       body,
+		Raw:        message,
+		Source:     source,
+		ReceivedAt: time.Now(),
+	}
+	s.nextID++
+
+	s.messages = append(s.messages, email)
+	if len(s.messages) > maxStoredEmails {
+		s.messages = s.messages[len(s.messages)-maxStoredEmails:]
+	}
+
+	return &email, nil
+}
+
+// Messages returns every captured message, oldest first
+func (s *SMTP) Messages() []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Email, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
+
+// Message returns a captured message by ID
+func (s *SMTP) Message(id int) (Email, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, email := range s.messages {
+		if email.ID == id {
+			return email, true
+		}
+	}
+	return Email{}, false
+}
+
+// MessagesFor returns the messages delivered to an address (case-insensitive)
+func (s *SMTP) MessagesFor(address string) []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []Email
+	for _, email := range s.messages {
+		for _, to := range email.To {
+			if strings.EqualFold(to, address) {
+				messages = append(messages, email)
+				break
+			}
+		}
+	}
+	return messages
+}
+
+// Clear empties the mailbox
+func (s *SMTP) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// Listen accepts SMTP connections on addr (e.g. "127.0.0.1:2525") in the background
+func (s *SMTP) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address the SMTP listener is bound to, or "" if not listening
+func (s *SMTP) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close stops the SMTP listener
+func (s *SMTP) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.listener = nil
+	return err
+}
+
+// serveConn runs a minimal SMTP dialogue: HELO/EHLO, MAIL, RCPT, DATA, RSET, NOOP and QUIT
+func (s *SMTP) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	var from string
+	var to []string
+	inTransaction := false
+
+	reply("220 %s ESMTP FlawFactory", s.hostname)
+
+	for {
+		conn.SetDeadline(time.Now().Add(smtpIdleTimeout))
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply("250 %s", s.hostname)
+		case "EHLO":
+			reply("250-%s", s.hostname)
+			reply("250-SIZE %d", maxEmailSize)
+			reply("250 8BITMIME")
+		case "MAIL":
+			from = smtpPath(arg, "FROM:")
+			to = nil
+			inTransaction = true
+			reply("250 OK")
+		case "RCPT":
+			if !inTransaction {
+				reply("503 Bad sequence of commands")
+				continue
+			}
+			to = append(to, smtpPath(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			if !inTransaction || len(to) == 0 {
+				reply("503 Bad sequence of commands")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			message, err := readSMTPData(reader)
+			if err != nil {
+				reply("552 %v", err)
+				return
+			}
+			email, err := s.store(from, to, message, "smtp")
+			if err != nil {
+				reply("554 %v", err)
+			} else {
+				reply("250 OK: queued as %d", email.ID)
+			}
+			from, to, inTransaction = "", nil, false
+		case "RSET":
+			from, to, inTransaction = "", nil, false
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "VRFY":
+			reply("252 Cannot VRFY user, but will accept message")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// smtpPath extracts the address from "FROM:<a@b> SIZE=10" style arguments
+func smtpPath(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = strings.TrimSpace(arg[len(prefix):])
+	}
+	if strings.HasPrefix(arg, "<") {
+		if end := strings.Index(arg, ">"); end > 0 {
+			return arg[1:end]
+		}
+	}
+	if space := strings.IndexByte(arg, ' '); space >= 0 {
+		arg = arg[:space]
+	}
+	return arg
+}
+
+// readSMTPData reads a DATA section up to the terminating "." line, removing dot-stuffing
+func readSMTPData(reader *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "." {
+			return b.String(), nil
+		}
+		if strings.HasPrefix(trimmed, ".") {
+			trimmed = trimmed[1:]
+		}
+		if b.Len()+len(trimmed) > maxEmailSize {
+			return "", errors.New("message exceeds fixed maximum message size")
+		}
+		b.WriteString(trimmed)
+		b.WriteString("\r\n")
+	}
+}
+
+// parseEmail splits a raw message into headers and body. Bare LF line endings are
+// accepted as well as CRLF, as many mail libraries do, so injected "\n" works too.
+func parseEmail(message string) (map[string][]string, string) {
+	headers := make(map[string][]string)
+	lines := strings.Split(message, "\n")
+
+	lastKey := ""
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if line == "" {
+			i++
+			break
+		}
+
+		// Folded continuation of the previous header
+		if (line[0] == ' ' || line[0] == '\t') && lastKey != "" {
+			values := headers[lastKey]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			// Not a header - the message has no header section
+			return headers, strings.Join(lines[i:], "\n")
+		}
+		lastKey = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+		headers[lastKey] = append(headers[lastKey], strings.TrimSpace(value))
+	}
+
+	body := ""
+	if i < len(lines) {
+		body = strings.Join(lines[i:], "\n")
+	}
+	return headers, body
+}
+
+// parseAddressList extracts the bare addresses from a header value
+func parseAddressList(value string) []string {
+	if list, err := mail.ParseAddressList(value); err == nil {
+		addrs := make([]string, 0, len(list))
+		for _, addr := range list {
+			addrs = append(addrs, addr.Address)
+		}
+		return addrs
+	}
+
+	// Fall back to a plain comma split for sloppy values
+	var addrs []string
+	for _, part := range strings.Split(value, ",") {
+		if addr, err := mail.ParseAddress(part); err == nil {
+			addrs = append(addrs, addr.Address)
+		} else if part = strings.TrimSpace(part); strings.Contains(part, "@") {
+			addrs = append(addrs, part)
+		}
+	}
+	return addrs
+}
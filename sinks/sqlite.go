@@ -3,14 +3,20 @@ package sinks
 import (
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
 )
 
-// SQLite provides an in-memory SQLite database for SQL injection testing
+// seedStateTable records which seed data a file-backed database holds
+const seedStateTable = "_flawfactory_seed"
+
+// SQLite provides an in-memory or file-backed SQLite database for SQL injection testing
 type SQLite struct {
-	db *sql.DB
+	db   *sql.DB
+	path string // Empty for in-memory databases
 }
 
 // NewSQLite creates a new in-memory SQLite database
@@ -29,6 +35,77 @@ func NewSQLite() (*SQLite, error) {
 	return &SQLite{db: db}, nil
 }
 
+// NewSQLiteFile opens (or creates) a SQLite database stored at path
+func NewSQLiteFile(path string) (*SQLite, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping SQLite: %w", err)
+	}
+
+	return &SQLite{db: db, path: path}, nil
+}
+
+// Path returns the database file, or an empty string for in-memory databases
+func (s *SQLite) Path() string {
+	return s.path
+}
+
+// SeedHash returns the hash recorded by SetSeedHash, or an empty string if the
+// database has never been seeded
+func (s *SQLite) SeedHash() (string, error) {
+	var exists int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", seedStateTable).Scan(&exists)
+	if err != nil {
+		return "", fmt.Errorf("failed to read seed state: %w", err)
+	}
+	if exists == 0 {
+		return "", nil
+	}
+
+	var hash string
+	err = s.db.QueryRow(fmt.Sprintf("SELECT hash FROM %s LIMIT 1", seedStateTable)).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read seed state: %w", err)
+	}
+	return hash, nil
+}
+
+// SetSeedHash records the hash of the data the database was seeded with
+func (s *SQLite) SetSeedHash(hash string) error {
+	if _, err := s.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (hash TEXT)", seedStateTable)); err != nil {
+		return fmt.Errorf("failed to record seed state: %w", err)
+	}
+	if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s", seedStateTable)); err != nil {
+		return fmt.Errorf("failed to record seed state: %w", err)
+	}
+	if _, err := s.db.Exec(fmt.Sprintf("INSERT INTO %s (hash) VALUES (?)", seedStateTable), hash); err != nil {
+		return fmt.Errorf("failed to record seed state: %w", err)
+	}
+	return nil
+}
+
+// DropTable removes a table if it exists
+func (s *SQLite) DropTable(tableName string) error {
+	if _, err := s.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (s *SQLite) Close() error {
 	if s.db != nil {
@@ -97,7 +174,13 @@ func (s *SQLite) SeedTable(tableName string, columns []string, rows [][]interfac
 // Query executes a SQL query and returns results as a slice of maps
 // This is intentionally vulnerable - it executes raw SQL
 func (s *SQLite) Query(query string) ([]map[string]interface{}, error) {
-	rows, err := s.db.Query(query)
+	return s.QueryArgs(query)
+}
+
+// QueryArgs is like Query, but binds args to the query's ? placeholders, for
+// lookups the lab itself makes rather than the vulnerable modules
+func (s *SQLite) QueryArgs(query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		// Return the SQL error for error-based injection
 		return nil, fmt.Errorf("SQL error: %w", err)
@@ -154,6 +237,17 @@ func (s *SQLite) Exec(statement string) error {
 	return nil
 }
 
+// ExecArgs is like Exec, but binds args to the statement's ? placeholders,
+// and returns the ID of the row it inserted
+func (s *SQLite) ExecArgs(statement string, args ...interface{}) (int64, error) {
+	result, err := s.db.Exec(statement, args...)
+	if err != nil {
+		return 0, fmt.Errorf("SQL error: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	return id, nil
+}
+
 // QuerySingle executes a query and returns a single value
 // Useful for blind boolean-based injection checks
 func (s *SQLite) QuerySingle(query string) (interface{}, error) {
@@ -172,3 +266,91 @@ func (s *SQLite) QuerySingle(query string) (interface{}, error) {
 	}
 	return result, nil
 }
+
+// Clone copies the database's schema and rows into a new in-memory database,
+// so a client can be given its own copy to write to
+func (s *SQLite) Clone() (*SQLite, error) {
+	clone, err := NewSQLite()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.copyInto(clone); err != nil {
+		clone.Close()
+		return nil, err
+	}
+	return clone, nil
+}
+
+// Restore replaces every table and view with those of src, typically a Clone
+// taken after seeding, undoing whatever was changed since
+func (s *SQLite) Restore(src *SQLite) error {
+	// Views first, so no view is left pointing at a dropped table
+	objects, err := s.QueryArgs(`SELECT type, name FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'view' THEN 0 ELSE 1 END`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	for _, object := range objects {
+		name := fmt.Sprint(object["name"])
+		quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		if _, err := s.db.Exec(fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(fmt.Sprint(object["type"])), quoted)); err != nil {
+			return fmt.Errorf("failed to drop %s %s: %w", object["type"], name, err)
+		}
+	}
+	return src.copyInto(s)
+}
+
+// copyInto creates the schema objects of the database in dst and copies their rows
+func (s *SQLite) copyInto(dst *SQLite) error {
+	// Tables first, so the indexes, views and triggers after them find theirs
+	schema, err := s.QueryArgs(`SELECT type, name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	for _, object := range schema {
+		if _, err := dst.db.Exec(fmt.Sprint(object["sql"])); err != nil {
+			return fmt.Errorf("failed to copy %s %s: %w", object["type"], object["name"], err)
+		}
+		if object["type"] == "table" {
+			if err := s.copyRows(dst, fmt.Sprint(object["name"])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyRows copies every row of a table into the same table of another database
+func (s *SQLite) copyRows(dst *SQLite, table string) error {
+	quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+	rows, err := s.db.Query("SELECT * FROM " + quoted)
+	if err != nil {
+		return fmt.Errorf("failed to copy table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to copy table %s: %w", table, err)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert := fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoted, placeholders)
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to copy table %s: %w", table, err)
+		}
+		if _, err := dst.db.Exec(insert, values...); err != nil {
+			return fmt.Errorf("failed to copy table %s: %w", table, err)
+		}
+	}
+	return rows.Err()
+}
@@ -1,6 +1,7 @@
 package sinks
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -210,3 +211,114 @@ func TestSQLite_CloseNil(t *testing.T) {
 		t.Errorf("Expected no error closing nil db, got: %v", err)
 	}
 }
+
+// TestSQLiteFile_Persistence tests data and seed state survive reopening the file
+func TestSQLiteFile_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "lab.db")
+
+	sink, err := NewSQLiteFile(path)
+	if err != nil {
+		t.Fatalf("Failed to create file-backed SQLite sink: %v", err)
+	}
+	if hash, _ := sink.SeedHash(); hash != "" {
+		t.Errorf("Expected no seed hash for a new database, got %q", hash)
+	}
+	if err := sink.SeedTable("users", []string{"name"}, [][]interface{}{{"alice"}}); err != nil {
+		t.Fatalf("Failed to seed table: %v", err)
+	}
+	if err := sink.SetSeedHash("abc"); err != nil {
+		t.Fatalf("Failed to set seed hash: %v", err)
+	}
+	sink.Close()
+
+	sink, err = NewSQLiteFile(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer sink.Close()
+
+	if sink.Path() != path {
+		t.Errorf("Expected path %s, got %s", path, sink.Path())
+	}
+	if hash, _ := sink.SeedHash(); hash != "abc" {
+		t.Errorf("Expected seed hash 'abc', got %q", hash)
+	}
+	if name, _ := sink.QuerySingle("SELECT name FROM users"); name != "alice" {
+		t.Errorf("Expected persisted row, got %v", name)
+	}
+
+	if err := sink.DropTable("users"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	if _, err := sink.Query("SELECT * FROM users"); err == nil {
+		t.Error("Expected error querying dropped table")
+	}
+}
+
+// TestSQLite_Clone tests a clone has the same schema and rows and is written separately
+func TestSQLite_Clone(t *testing.T) {
+	sink, err := NewSQLite()
+	if err != nil {
+		t.Fatalf("Failed to create SQLite sink: %v", err)
+	}
+	defer sink.Close()
+
+	sink.SeedTable("users", []string{"id", "name"}, [][]interface{}{{"1", "alice"}, {"2", "bob"}})
+	sink.Exec("CREATE INDEX users_name ON users (name)")
+
+	clone, err := sink.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+
+	if err := clone.Exec("DROP TABLE users"); err != nil {
+		t.Fatalf("Expected the clone to have the table: %v", err)
+	}
+	rows, err := sink.Query("SELECT name FROM users ORDER BY id")
+	if err != nil || len(rows) != 2 || rows[0]["name"] != "alice" {
+		t.Errorf("Expected the original to be untouched, got %v (%v)", rows, err)
+	}
+
+	clone, err = sink.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+	if rows, err := clone.Query("SELECT name FROM users WHERE name = 'bob'"); err != nil || len(rows) != 1 {
+		t.Errorf("Expected the rows to be copied, got %v (%v)", rows, err)
+	}
+	if rows, _ := clone.Query("SELECT name FROM sqlite_master WHERE type = 'index'"); len(rows) != 1 {
+		t.Errorf("Expected the index to be copied, got %v", rows)
+	}
+}
+
+// TestSQLite_Restore tests a database goes back to a clone's tables and rows
+func TestSQLite_Restore(t *testing.T) {
+	sink, err := NewSQLite()
+	if err != nil {
+		t.Fatalf("Failed to create SQLite sink: %v", err)
+	}
+	defer sink.Close()
+
+	sink.SeedTable("users", []string{"id", "name"}, [][]interface{}{{"1", "alice"}})
+	seed, err := sink.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer seed.Close()
+
+	sink.Exec("DELETE FROM users")
+	sink.Exec("CREATE TABLE pwned (id INTEGER)")
+	sink.Exec("CREATE VIEW everyone AS SELECT * FROM users")
+
+	if err := sink.Restore(seed); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if rows, err := sink.Query("SELECT name FROM users"); err != nil || len(rows) != 1 || rows[0]["name"] != "alice" {
+		t.Errorf("Expected the seeded rows back, got %v (%v)", rows, err)
+	}
+	if rows, _ := sink.Query("SELECT name FROM sqlite_master WHERE name IN ('pwned', 'everyone')"); len(rows) != 0 {
+		t.Errorf("Expected objects created since to be dropped, got %v", rows)
+	}
+}
@@ -0,0 +1,444 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxTemplateOutput caps rendered output (and any single string built while rendering)
+	maxTemplateOutput = 1 << 20
+
+	// maxTemplateIterations caps the total number of loop iterations in one render
+	maxTemplateIterations = 10000
+
+	// templateRenderTimeout bounds real text/template execution
+	templateRenderTimeout = 2 * time.Second
+)
+
+// TemplateEngines lists the engines Render understands
+var TemplateEngines = []string{"jinja2", "twig", "freemarker", "erb", "go"}
+
+// UnsafeExpression is an expression the template sink refuses to evaluate,
+// such as a shell command or a file read reached through object introspection
+type UnsafeExpression struct {
+	Engine     string
+	Expression string // Template expression that triggered it
+	Kind       string // rce or file_read
+	Command    string // Set for rce
+	Path       string // Set for file_read
+}
+
+// UnsafeHandler emulates an unsafe expression and returns its output
+type UnsafeHandler func(UnsafeExpression) string
+
+// TemplateError is a syntax or runtime error, worded the way the engine reports it
+type TemplateError struct {
+	Engine  string
+	Syntax  bool
+	Message string
+}
+
+// templateErrorPrefixes are the exception names each engine reports as [syntax, runtime]
+var templateErrorPrefixes = map[string][2]string{
+	"jinja2":     {"jinja2.exceptions.TemplateSyntaxError", "jinja2.exceptions.UndefinedError"},
+	"twig":       {"Twig\\Error\\SyntaxError", "Twig\\Error\\RuntimeError"},
+	"freemarker": {"freemarker.core.ParseException", "freemarker.core.InvalidReferenceException"},
+	"erb":        {"SyntaxError", "NameError"},
+}
+
+// Error implements error
+func (e *TemplateError) Error() string {
+	prefixes, ok := templateErrorPrefixes[e.Engine]
+	if !ok {
+		return e.Message
+	}
+	if e.Syntax {
+		return prefixes[0] + ": " + e.Message
+	}
+	return prefixes[1] + ": " + e.Message
+}
+
+// Template renders attacker-influenced templates for SSTI testing.
+//
+// The go engine runs real text/template. The others - jinja2, twig, freemarker
+// and erb - are handled by a small sandboxed interpreter that understands each
+// engine's delimiters, literals, arithmetic quirks (7*'7' is 7777777 in Jinja2
+// but 49 in Twig), variables, attribute lookups, filters and set/if/for blocks.
+// Expressions that would reach the host - shell commands, file reads - are never
+// run; they are passed to an UnsafeHandler, which decides what they return.
+type Template struct{}
+
+// NewTemplate creates a template sink
+func NewTemplate() *Template {
+	return &Template{}
+}
+
+// Close is a no-op for the template sink
+func (t *Template) Close() error {
+	return nil
+}
+
+// Render renders source with the given engine and variables. A nil unsafe
+// handler makes unsafe expressions fail instead.
+func (t *Template) Render(engine, source string, data map[string]interface{}, unsafe UnsafeHandler) (string, error) {
+	if engine == "go" {
+		return renderGoTemplate(source, data)
+	}
+	if _, ok := templateErrorPrefixes[engine]; !ok {
+		return "", fmt.Errorf("unsupported template engine '%s'", engine)
+	}
+
+	segments, err := splitTemplate(engine, source)
+	if err != nil {
+		return "", err
+	}
+
+	nodes, _, _, err := parseTemplateNodes(engine, segments, 0, nil)
+	if err != nil {
+		return "", err
+	}
+
+	vars := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	r := &tplRenderer{engine: engine, vars: vars, unsafe: unsafe}
+	if err := r.renderNodes(nodes); err != nil {
+		return "", err
+	}
+	return r.out.String(), nil
+}
+
+// renderGoTemplate executes a real text/template, bounded in time and output size
+func renderGoTemplate(source string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("page").Parse(source)
+	if err != nil {
+		return "", &TemplateError{Engine: "go", Syntax: true, Message: err.Error()}
+	}
+
+	type rendered struct {
+		out string
+		err error
+	}
+	done := make(chan rendered, 1)
+	go func() {
+		w := &limitedWriter{limit: maxTemplateOutput}
+		err := tmpl.Execute(w, data)
+		done <- rendered{w.String(), err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return "", &TemplateError{Engine: "go", Message: result.err.Error()}
+		}
+		return result.out, nil
+	case <-time.After(templateRenderTimeout):
+		return "", &TemplateError{Engine: "go", Message: "template execution timed out"}
+	}
+}
+
+// limitedWriter collects output up to a limit
+type limitedWriter struct {
+	strings.Builder
+	limit int
+}
+
+// Write implements io.Writer
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errors.New("template output too large")
+	}
+	return w.Builder.Write(p)
+}
+
+// tplSegment is a piece of template source: literal text, an output tag or a statement tag
+type tplSegment struct {
+	kind string // text, output or stmt
+	code string
+}
+
+// templateDelimiters are the tags per engine: open, close and segment kind.
+// Longer opening tags come first so "<%=" wins over "<%".
+var templateDelimiters = map[string][][3]string{
+	"jinja2":     {{"{{", "}}", "output"}, {"{%", "%}", "stmt"}, {"{#", "#}", "comment"}},
+	"twig":       {{"{{", "}}", "output"}, {"{%", "%}", "stmt"}, {"{#", "#}", "comment"}},
+	"freemarker": {{"${", "}", "output"}, {"#{", "}", "output"}, {"<#--", "-->", "comment"}, {"</#", ">", "endstmt"}, {"<#", ">", "stmt"}},
+	"erb":        {{"<%#", "%>", "comment"}, {"<%=", "%>", "output"}, {"<%", "%>", "stmt"}},
+}
+
+// splitTemplate cuts source into text and tag segments
+func splitTemplate(engine, source string) ([]tplSegment, error) {
+	var segments []tplSegment
+	delimiters := templateDelimiters[engine]
+
+	for len(source) > 0 {
+		// Find the earliest opening tag
+		start, which := -1, -1
+		for i, d := range delimiters {
+			if idx := strings.Index(source, d[0]); idx >= 0 && (start < 0 || idx < start) {
+				start, which = idx, i
+			}
+		}
+		if start < 0 {
+			segments = append(segments, tplSegment{kind: "text", code: source})
+			break
+		}
+		if start > 0 {
+			segments = append(segments, tplSegment{kind: "text", code: source[:start]})
+		}
+
+		open, close, kind := delimiters[which][0], delimiters[which][1], delimiters[which][2]
+		rest := source[start+len(open):]
+		end := findTagClose(rest, close, kind != "comment")
+		if end < 0 {
+			return nil, &TemplateError{Engine: engine, Syntax: true, Message: fmt.Sprintf("unexpected end of template, expected '%s'", close)}
+		}
+
+		code := rest[:end]
+		source = rest[end+len(close):]
+
+		// Whitespace control markers ({{- -}}, <%- -%>)
+		code = strings.TrimPrefix(code, "-")
+		code = strings.TrimSuffix(code, "-")
+
+		switch kind {
+		case "comment":
+			continue
+		case "endstmt":
+			code = "/" + code
+			kind = "stmt"
+		}
+		segments = append(segments, tplSegment{kind: kind, code: strings.TrimSpace(code)})
+	}
+
+	return segments, nil
+}
+
+// findTagClose finds the closing delimiter, skipping over quoted strings
+func findTagClose(s, close string, skipQuotes bool) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if skipQuotes && (c == '"' || c == '\'') {
+			quote = c
+			continue
+		}
+		if strings.HasPrefix(s[i:], close) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tplStmt is a statement tag normalized across engines
+type tplStmt struct {
+	op     string // set, exec, if, elif, else, endif, for, endfor or other
+	target string
+	expr   string
+}
+
+// parseTemplateStmt normalizes a statement tag
+func parseTemplateStmt(engine, code string) tplStmt {
+	// The keyword may run straight into an expression: {%print(x)%}
+	end := strings.IndexFunc(code, func(c rune) bool {
+		return c != '_' && c != '/' && !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	if end < 0 {
+		end = len(code)
+	}
+	word, rest := code[:end], strings.TrimSpace(code[end:])
+
+	switch engine {
+	case "freemarker":
+		switch word {
+		case "assign":
+			target, expr, _ := strings.Cut(rest, "=")
+			return tplStmt{op: "set", target: strings.TrimSpace(target), expr: strings.TrimSpace(expr)}
+		case "if":
+			return tplStmt{op: "if", expr: rest}
+		case "elseif":
+			return tplStmt{op: "elif", expr: rest}
+		case "else":
+			return tplStmt{op: "else"}
+		case "/if":
+			return tplStmt{op: "endif"}
+		case "list":
+			if idx := strings.LastIndex(rest, " as "); idx >= 0 {
+				return tplStmt{op: "for", target: strings.TrimSpace(rest[idx+4:]), expr: strings.TrimSpace(rest[:idx])}
+			}
+		case "/list":
+			return tplStmt{op: "endfor"}
+		}
+		return tplStmt{op: "other"}
+
+	case "erb":
+		switch word {
+		case "if", "unless", "elsif", "else", "end", "while", "until", "case", "when", "begin", "rescue", "ensure":
+			// Ruby control flow is not interpreted
+			return tplStmt{op: "other"}
+		}
+		if name, value, found := strings.Cut(code, "="); found && isTemplateIdent(strings.TrimSpace(name)) && !strings.HasPrefix(value, "=") {
+			return tplStmt{op: "set", target: strings.TrimSpace(name), expr: strings.TrimSpace(value)}
+		}
+		return tplStmt{op: "exec", expr: code}
+
+	default: // jinja2 and twig
+		switch word {
+		case "set":
+			target, expr, _ := strings.Cut(rest, "=")
+			return tplStmt{op: "set", target: strings.TrimSpace(target), expr: strings.TrimSpace(expr)}
+		case "if":
+			return tplStmt{op: "if", expr: rest}
+		case "elif", "elseif":
+			return tplStmt{op: "elif", expr: rest}
+		case "else":
+			return tplStmt{op: "else"}
+		case "endif":
+			return tplStmt{op: "endif"}
+		case "for":
+			if idx := strings.Index(rest, " in "); idx >= 0 {
+				return tplStmt{op: "for", target: strings.TrimSpace(rest[:idx]), expr: strings.TrimSpace(rest[idx+4:])}
+			}
+		case "endfor":
+			return tplStmt{op: "endfor"}
+		case "print":
+			// Jinja2's do/print extensions
+			return tplStmt{op: "print", expr: rest}
+		case "do":
+			return tplStmt{op: "exec", expr: rest}
+		}
+		return tplStmt{op: "other"}
+	}
+}
+
+// isTemplateIdent reports whether s is a plain variable name
+func isTemplateIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// tplNode is a parsed template element
+type tplNode struct {
+	kind     string // text, output, set, exec, if or for
+	text     string // Literal text or expression source
+	target   string
+	conds    []string // if: one condition per branch, "" for else
+	bodies   [][]tplNode
+	elseBody []tplNode // for: rendered when the sequence is empty
+}
+
+// parseTemplateNodes builds the node tree from segments starting at i, stopping at
+// any statement in stops. It returns the nodes, the index after the stop tag and the stop op.
+func parseTemplateNodes(engine string, segments []tplSegment, i int, stops []string) ([]tplNode, int, string, error) {
+	var nodes []tplNode
+
+	for i < len(segments) {
+		seg := segments[i]
+		i++
+
+		switch seg.kind {
+		case "text":
+			nodes = append(nodes, tplNode{kind: "text", text: seg.code})
+			continue
+		case "output":
+			nodes = append(nodes, tplNode{kind: "output", text: seg.code})
+			continue
+		}
+
+		stmt := parseTemplateStmt(engine, seg.code)
+		for _, stop := range stops {
+			if stmt.op == stop {
+				// Hand the branch condition back through text
+				return nodes, i, stmt.op + "\x00" + stmt.expr, nil
+			}
+		}
+
+		switch stmt.op {
+		case "set":
+			nodes = append(nodes, tplNode{kind: "set", target: stmt.target, text: stmt.expr})
+		case "exec":
+			nodes = append(nodes, tplNode{kind: "exec", text: stmt.expr})
+		case "print":
+			nodes = append(nodes, tplNode{kind: "output", text: stmt.expr})
+		case "if":
+			node := tplNode{kind: "if"}
+			cond := stmt.expr
+			for {
+				body, next, stop, err := parseTemplateNodes(engine, segments, i, []string{"elif", "else", "endif"})
+				if err != nil {
+					return nil, 0, "", err
+				}
+				if stop == "" {
+					return nil, 0, "", unclosedTemplateBlock(engine, "endif")
+				}
+				node.conds = append(node.conds, cond)
+				node.bodies = append(node.bodies, body)
+				i = next
+
+				op, expr, _ := strings.Cut(stop, "\x00")
+				if op == "endif" {
+					break
+				}
+				if op == "else" {
+					cond = ""
+				} else {
+					cond = expr
+				}
+			}
+			nodes = append(nodes, node)
+		case "for":
+			body, next, stop, err := parseTemplateNodes(engine, segments, i, []string{"else", "endfor"})
+			if err != nil {
+				return nil, 0, "", err
+			}
+			if stop == "" {
+				return nil, 0, "", unclosedTemplateBlock(engine, "endfor")
+			}
+			node := tplNode{kind: "for", target: stmt.target, text: stmt.expr, bodies: [][]tplNode{body}}
+			i = next
+			if op, _, _ := strings.Cut(stop, "\x00"); op == "else" {
+				elseBody, next, stop, err := parseTemplateNodes(engine, segments, i, []string{"endfor"})
+				if err != nil {
+					return nil, 0, "", err
+				}
+				if stop == "" {
+					return nil, 0, "", unclosedTemplateBlock(engine, "endfor")
+				}
+				node.elseBody = elseBody
+				i = next
+			}
+			nodes = append(nodes, node)
+		case "elif", "else", "endif", "endfor":
+			return nil, 0, "", &TemplateError{Engine: engine, Syntax: true, Message: fmt.Sprintf("encountered unknown tag '%s'", seg.code)}
+		}
+	}
+
+	return nodes, i, "", nil
+}
+
+// unclosedTemplateBlock reports a block missing its end tag
+func unclosedTemplateBlock(engine, tag string) error {
+	return &TemplateError{Engine: engine, Syntax: true, Message: fmt.Sprintf("unexpected end of template, expected '%s'", tag)}
+}
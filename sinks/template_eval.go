@@ -0,0 +1,2372 @@
+package sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Values produced by the interpreter besides strings, numbers, bools, nil,
+// []interface{} and map[string]interface{}
+
+// tplUndefined is a missing variable or attribute; Jinja2 and Twig render it empty
+type tplUndefined struct {
+	name string
+}
+
+// tplCallable is a function or bound method
+type tplCallable struct {
+	name string
+	fn   func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+}
+
+// tplObject stands in for a host object reached from a template: Python classes,
+// functions, modules and processes, Twig's environment or a Ruby constant
+type tplObject struct {
+	kind   string // class, function, module, globals, builtins, process, file, instance, context, twig_self, twig_env, ruby, java_pb
+	name   string
+	repr   string
+	output string // Process output or file contents
+}
+
+// pyAddress is the fake memory address in Python object reprs
+const pyAddress = "0x7f3a2c1b9e50"
+
+// pySubclasses is what object.__subclasses__() returns, in CPython 3.11 order (abridged).
+// Payloads index into it, so the interesting classes sit at fixed positions.
+var pySubclasses = []string{
+	"type", "weakref", "weakcallableproxy", "weakproxy", "int", "bytearray", "bytes", "list",
+	"NoneType", "NotImplementedType", "traceback", "super", "range", "dict", "dict_keys",
+	"dict_values", "dict_items", "dict_reversekeyiterator", "odict_iterator", "set", "str",
+	"slice", "staticmethod", "complex", "float", "frozenset", "property", "managedbuffer",
+	"memoryview", "tuple", "enumerate", "reversed", "stderrprinter", "code", "frame",
+	"builtin_function_or_method", "method", "function", "mappingproxy", "generator",
+	"getset_descriptor", "wrapper_descriptor", "method-wrapper", "ellipsis", "member_descriptor",
+	"types.SimpleNamespace", "PyCapsule", "longrange_iterator", "cell", "instancemethod",
+	"classmethod_descriptor", "method_descriptor", "callable_iterator", "iterator", "coroutine",
+	"moduledef", "module", "EncodingMap", "fieldnameiterator", "formatteriterator", "filter",
+	"map", "zip", "BaseException", "hamt", "_frozen_importlib._ModuleLock",
+	"_frozen_importlib._DummyModuleLock", "_frozen_importlib._ModuleLockManager",
+	"_frozen_importlib.ModuleSpec", "_frozen_importlib.BuiltinImporter", "classmethod",
+	"_frozen_importlib.FrozenImporter", "_frozen_importlib._ImportLockContext",
+	"_thread._localdummy", "_thread._local", "_thread.lock", "_thread.RLock", "_io._IOBase",
+	"_io._BytesIOBuffer", "_io.IncrementalNewlineDecoder", "posix.ScandirIterator",
+	"posix.DirEntry", "_frozen_importlib_external._LoaderBasics",
+	"_frozen_importlib_external.FileLoader", "_frozen_importlib_external._NamespacePath",
+	"_frozen_importlib_external.PathFinder", "_frozen_importlib_external.FileFinder",
+	"zipimport.zipimporter", "codecs.Codec", "codecs.IncrementalEncoder",
+	"codecs.IncrementalDecoder", "codecs.StreamReaderWriter", "codecs.StreamRecoder",
+	"_abc._abc_data", "abc.ABC", "dict_itemiterator", "collections.abc.Hashable",
+	"collections.abc.Awaitable", "collections.abc.AsyncIterable", "async_generator",
+	"collections.abc.Iterable", "bytes_iterator", "bytearray_iterator", "dict_keyiterator",
+	"dict_valueiterator", "list_iterator", "list_reverseiterator", "range_iterator",
+	"set_iterator", "str_iterator", "tuple_iterator", "collections.abc.Sized",
+	"collections.abc.Container", "collections.abc.Callable", "os._wrap_close",
+	"_sitebuiltins.Quitter", "_sitebuiltins._Printer", "_sitebuiltins._Helper",
+	"types.DynamicClassAttribute", "types._GeneratorWrapper", "warnings.WarningMessage",
+	"warnings.catch_warnings", "importlib.abc.Finder", "importlib.abc.Loader",
+	"operator.itemgetter", "operator.attrgetter", "operator.methodcaller",
+	"itertools.accumulate", "itertools.combinations", "functools.partial",
+	"functools._lru_cache_wrapper", "reprlib.Repr", "collections.deque", "re.Pattern",
+	"re.Match", "subprocess.CompletedProcess", "subprocess.Popen", "jinja2.runtime.Undefined",
+	"jinja2.utils.Cycler", "jinja2.utils.Joiner",
+}
+
+// phpShellFunctions are callables Twig filters can be abused with; the echo ones print directly
+var phpShellFunctions = map[string]bool{"system": true, "passthru": true, "exec": false, "shell_exec": false}
+
+// tplRenderer renders a node tree for one engine
+type tplRenderer struct {
+	engine       string
+	vars         map[string]interface{}
+	unsafe       UnsafeHandler
+	out          strings.Builder
+	iterations   int
+	depth        int
+	expr         string // Outermost expression being evaluated, reported with unsafe calls
+	twigCallback string // Set by _self.env.registerUndefinedFilterCallback
+}
+
+// renderNodes writes the output of nodes
+func (r *tplRenderer) renderNodes(nodes []tplNode) error {
+	for _, node := range nodes {
+		switch node.kind {
+		case "text":
+			r.out.WriteString(node.text)
+
+		case "output":
+			v, err := r.eval(node.text)
+			if err != nil {
+				return err
+			}
+			r.out.WriteString(r.str(v))
+
+		case "set":
+			v, err := r.eval(node.text)
+			if err != nil {
+				return err
+			}
+			r.vars[node.target] = v
+
+		case "exec":
+			if _, err := r.eval(node.text); err != nil {
+				return err
+			}
+
+		case "if":
+			for i, cond := range node.conds {
+				if cond != "" {
+					v, err := r.eval(cond)
+					if err != nil {
+						return err
+					}
+					if !tplTruthy(v) {
+						continue
+					}
+				}
+				if err := r.renderNodes(node.bodies[i]); err != nil {
+					return err
+				}
+				break
+			}
+
+		case "for":
+			seq, err := r.eval(node.text)
+			if err != nil {
+				return err
+			}
+			items := tplItems(seq)
+			if len(items) == 0 {
+				if err := r.renderNodes(node.elseBody); err != nil {
+					return err
+				}
+			}
+			for _, item := range items {
+				r.iterations++
+				if r.iterations > maxTemplateIterations {
+					return r.runtimeError("", "loop iteration limit exceeded")
+				}
+				r.assignLoopTarget(node.target, item)
+				if err := r.renderNodes(node.bodies[0]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if r.out.Len() > maxTemplateOutput {
+			return r.runtimeError("MemoryError", "template output too large")
+		}
+	}
+	return nil
+}
+
+// assignLoopTarget binds a loop variable, unpacking "k, v" targets
+func (r *tplRenderer) assignLoopTarget(target string, item interface{}) {
+	names := strings.Split(target, ",")
+	if len(names) == 1 {
+		r.vars[strings.TrimSpace(target)] = item
+		return
+	}
+	values, _ := item.([]interface{})
+	for i, name := range names {
+		var v interface{}
+		if i < len(values) {
+			v = values[i]
+		}
+		r.vars[strings.TrimSpace(name)] = v
+	}
+}
+
+// eval evaluates a single expression
+func (r *tplRenderer) eval(src string) (interface{}, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > 10 {
+		return nil, r.runtimeError("RecursionError", "maximum recursion depth exceeded")
+	}
+	if r.depth == 1 {
+		r.expr = src
+	}
+
+	toks, err := r.lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tplParser{r: r, toks: toks}
+	v, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tplEOF {
+		return nil, r.unexpected(t)
+	}
+	return v, nil
+}
+
+// syntaxError builds an engine-style syntax error
+func (r *tplRenderer) syntaxError(format string, args ...interface{}) error {
+	return &TemplateError{Engine: r.engine, Syntax: true, Message: fmt.Sprintf(format, args...)}
+}
+
+// runtimeError builds an engine-style runtime error; exception overrides the engine's default class
+func (r *tplRenderer) runtimeError(exception, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	if exception != "" {
+		return &TemplateError{Engine: "", Message: exception + ": " + message}
+	}
+	return &TemplateError{Engine: r.engine, Message: message}
+}
+
+// unexpected reports an unexpected token the way each engine's parser does
+func (r *tplRenderer) unexpected(t tplToken) error {
+	text := t.text
+	if t.kind == tplEOF {
+		text = "end of statement block"
+	}
+
+	switch r.engine {
+	case "twig":
+		kinds := map[int]string{tplEOF: "end of print statement", tplNumber: "number", tplString: "string", tplIdent: "name", tplOp: "punctuation", tplShell: "string"}
+		if t.kind == tplEOF {
+			text = ""
+		}
+		return r.syntaxError("Unexpected token \"%s\" of value \"%s\".", kinds[t.kind], text)
+	case "freemarker":
+		return r.syntaxError("Syntax error in template \"page\": Encountered \"%s\", but was expecting an expression.", text)
+	case "erb":
+		return r.syntaxError("(erb):1: syntax error, unexpected '%s'", text)
+	default:
+		return r.syntaxError("unexpected '%s'", text)
+	}
+}
+
+// callUnsafe hands an unsafe operation to the handler
+func (r *tplRenderer) callUnsafe(u UnsafeExpression) (string, error) {
+	u.Engine = r.engine
+	u.Expression = r.expr
+	if r.unsafe == nil {
+		return "", r.runtimeError("SecurityError", "unsafe expression not allowed: %s", r.expr)
+	}
+	return r.unsafe(u), nil
+}
+
+// runCommand emulates running a shell command
+func (r *tplRenderer) runCommand(command string) (string, error) {
+	return r.callUnsafe(UnsafeExpression{Kind: "rce", Command: command})
+}
+
+// readFile emulates reading a file
+func (r *tplRenderer) readFile(path string) (string, error) {
+	return r.callUnsafe(UnsafeExpression{Kind: "file_read", Path: path})
+}
+
+// callable wraps a Go function as a template callable
+func callable(name string, fn func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)) *tplCallable {
+	return &tplCallable{name: name, fn: fn}
+}
+
+// Lexer
+
+const (
+	tplEOF = iota
+	tplNumber
+	tplString
+	tplIdent
+	tplOp
+	tplShell // ERB backticks and %x()
+)
+
+// tplToken is a lexed expression token
+type tplToken struct {
+	kind int
+	text string
+	num  interface{}
+}
+
+// tplOperators are matched longest first
+var tplOperators = []string{"**", "//", "==", "!=", "<=", ">=", "&&", "||", "::",
+	"+", "-", "*", "/", "%", "~", "(", ")", "[", "]", "{", "}", ",", "|", ":", "!", "<", ">", "=", "?", "."}
+
+// lex splits an expression into tokens
+func (r *tplRenderer) lex(src string) ([]tplToken, error) {
+	var toks []tplToken
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && src[i+1] >= '0' && src[i+1] <= '9' {
+				i++
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+				f, _ := strconv.ParseFloat(src[start:i], 64)
+				toks = append(toks, tplToken{kind: tplNumber, text: src[start:i], num: f})
+			} else {
+				n, err := strconv.ParseInt(src[start:i], 10, 64)
+				if err != nil {
+					f, _ := strconv.ParseFloat(src[start:i], 64)
+					toks = append(toks, tplToken{kind: tplNumber, text: src[start:i], num: f})
+				} else {
+					toks = append(toks, tplToken{kind: tplNumber, text: src[start:i], num: n})
+				}
+			}
+
+		case c == '"' || c == '\'':
+			s, end, ok := decodeTemplateString(src, i)
+			if !ok {
+				return nil, r.syntaxError("unexpected end of string")
+			}
+			toks = append(toks, tplToken{kind: tplString, text: s})
+			i = end
+
+		case c == '`' && r.engine == "erb":
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, r.syntaxError("(erb):1: unterminated string meets end of file")
+			}
+			toks = append(toks, tplToken{kind: tplShell, text: src[i+1 : i+1+end]})
+			i += end + 2
+
+		case r.engine == "erb" && strings.HasPrefix(src[i:], "%x("):
+			end := strings.IndexByte(src[i+3:], ')')
+			if end < 0 {
+				return nil, r.syntaxError("(erb):1: unterminated string meets end of file")
+			}
+			toks = append(toks, tplToken{kind: tplShell, text: src[i+3 : i+3+end]})
+			i += end + 4
+
+		case c == '_' || c == '$' || c == '@' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '$' || src[i] == '@' || unicode.IsLetter(rune(src[i])) || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			// Ruby predicate and bang methods (exist?, gsub!)
+			if r.engine == "erb" && i < len(src) && (src[i] == '?' || src[i] == '!') && (i+1 >= len(src) || src[i+1] != '=') {
+				i++
+			}
+			toks = append(toks, tplToken{kind: tplIdent, text: src[start:i]})
+
+		default:
+			matched := false
+			for _, op := range tplOperators {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, tplToken{kind: tplOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, r.unexpected(tplToken{kind: tplOp, text: string(c)})
+			}
+		}
+	}
+
+	return append(toks, tplToken{kind: tplEOF}), nil
+}
+
+// decodeTemplateString reads a quoted string starting at src[start], decoding
+// backslash escapes (\n, \t, \xHH, \uHHHH). It returns the value and the index after it.
+func decodeTemplateString(src string, start int) (string, int, bool) {
+	quote := src[start]
+	var b strings.Builder
+
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		if c == quote {
+			return b.String(), i + 1, true
+		}
+		if c != '\\' || i+1 >= len(src) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch e := src[i]; e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'x':
+			if i+2 < len(src) {
+				if n, err := strconv.ParseUint(src[i+1:i+3], 16, 8); err == nil {
+					b.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+			}
+			b.WriteString(`\x`)
+		case 'u':
+			if i+4 < len(src) {
+				if n, err := strconv.ParseUint(src[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(n))
+					i += 4
+					continue
+				}
+			}
+			b.WriteString(`\u`)
+		default:
+			if e != quote && e != '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(e)
+		}
+	}
+
+	return "", 0, false
+}
+
+// Parser - evaluates while parsing
+
+// tplParser is a precedence-climbing parser over one expression
+type tplParser struct {
+	r    *tplRenderer
+	toks []tplToken
+	pos  int
+}
+
+func (p *tplParser) peek() tplToken {
+	return p.toks[p.pos]
+}
+
+func (p *tplParser) next() tplToken {
+	t := p.toks[p.pos]
+	if t.kind != tplEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *tplParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tplOp && t.kind != tplIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// expect consumes a required operator
+func (p *tplParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return p.r.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *tplParser) parseExpr() (interface{}, error) {
+	return p.parseOr()
+}
+
+func (p *tplParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if !tplTruthy(left) {
+			left = right
+		}
+	}
+}
+
+func (p *tplParser) parseAnd() (interface{}, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if tplTruthy(left) {
+			left = right
+		}
+	}
+}
+
+func (p *tplParser) parseNot() (interface{}, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		v, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return !tplTruthy(v), nil
+	}
+	return p.parseCompare()
+}
+
+func (p *tplParser) parseCompare() (interface{}, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("==", "!=", "<", ">", "<=", ">=", "in", "not")
+		if !ok {
+			return left, nil
+		}
+		if op == "not" {
+			if err := p.expect("in"); err != nil {
+				return nil, err
+			}
+			op = "not in"
+		}
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left, err = p.r.compare(op, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tplParser) parseConcat() (interface{}, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("~"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		left = p.r.str(left) + p.r.str(right)
+	}
+}
+
+func (p *tplParser) parseAdd() (interface{}, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.r.arith(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tplParser) parseMul() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "//", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.r.arith(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tplParser) parseUnary() (interface{}, error) {
+	if op, ok := p.accept("-", "+"); ok {
+		v, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return v, nil
+		}
+		return p.r.arith("-", int64(0), v)
+	}
+	return p.parsePow()
+}
+
+func (p *tplParser) parsePow() (interface{}, error) {
+	base, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("**"); !ok {
+		return base, nil
+	}
+	exp, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return p.r.arith("**", base, exp)
+}
+
+// parsePostfix handles attribute access, indexing, calls, filters and FreeMarker built-ins
+func (p *tplParser) parsePostfix() (interface{}, error) {
+	v, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tplOp {
+			return v, nil
+		}
+
+		switch {
+		case t.text == "." || t.text == "::":
+			p.next()
+			name := p.next()
+			if name.kind != tplIdent && name.kind != tplNumber {
+				return nil, p.r.unexpected(name)
+			}
+			if v, err = p.r.getAttr(v, name.text); err != nil {
+				return nil, err
+			}
+			// Ruby calls methods without parentheses
+			if c, ok := v.(*tplCallable); ok && p.r.engine == "erb" && p.peek().text != "(" {
+				if v, err = c.fn(nil, nil); err != nil {
+					return nil, err
+				}
+			}
+
+		case t.text == "[":
+			p.next()
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if v, err = p.r.getItem(v, key); err != nil {
+				return nil, err
+			}
+
+		case t.text == "(":
+			p.next()
+			args, kwargs, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			if v, err = p.r.call(v, args, kwargs); err != nil {
+				return nil, err
+			}
+
+		case t.text == "|" && (p.r.engine == "jinja2" || p.r.engine == "twig"):
+			p.next()
+			name := p.next()
+			if name.kind != tplIdent {
+				return nil, p.r.unexpected(name)
+			}
+			var args []interface{}
+			if _, ok := p.accept("("); ok {
+				if args, _, err = p.parseArgs(); err != nil {
+					return nil, err
+				}
+			}
+			if v, err = p.r.applyFilter(v, name.text, args); err != nil {
+				return nil, err
+			}
+
+		case t.text == "?" && p.r.engine == "freemarker":
+			p.next()
+			name := p.next()
+			if name.kind != tplIdent {
+				return nil, p.r.unexpected(name)
+			}
+			var args []interface{}
+			if _, ok := p.accept("("); ok {
+				if args, _, err = p.parseArgs(); err != nil {
+					return nil, err
+				}
+			}
+			if v, err = p.r.applyBuiltin(v, name.text, args); err != nil {
+				return nil, err
+			}
+
+		default:
+			return v, nil
+		}
+	}
+}
+
+// parseArgs parses call arguments after "(", including name=value keyword arguments
+func (p *tplParser) parseArgs() ([]interface{}, map[string]interface{}, error) {
+	var args []interface{}
+	kwargs := make(map[string]interface{})
+
+	if _, ok := p.accept(")"); ok {
+		return args, kwargs, nil
+	}
+	for {
+		if t := p.peek(); t.kind == tplIdent && p.toks[p.pos+1].kind == tplOp && p.toks[p.pos+1].text == "=" {
+			p.pos += 2
+			v, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs[t.text] = v
+		} else {
+			v, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, v)
+		}
+
+		if _, ok := p.accept(")"); ok {
+			return args, kwargs, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (p *tplParser) parsePrimary() (interface{}, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tplNumber:
+		return t.num, nil
+
+	case tplString:
+		return t.text, nil
+
+	case tplShell:
+		return p.r.runCommand(t.text)
+
+	case tplIdent:
+		switch t.text {
+		case "true", "True":
+			return true, nil
+		case "false", "False":
+			return false, nil
+		case "none", "None", "null", "nil":
+			return nil, nil
+		}
+		return p.r.lookup(t.text)
+
+	case tplOp:
+		switch t.text {
+		case "(":
+			if _, ok := p.accept(")"); ok {
+				return []interface{}{}, nil
+			}
+			v, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(","); ok {
+				// Tuple
+				items := []interface{}{v}
+				for {
+					if _, ok := p.accept(")"); ok {
+						return items, nil
+					}
+					item, err := p.parseExpr()
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, item)
+					p.accept(",")
+				}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return v, nil
+
+		case "[":
+			items := []interface{}{}
+			for {
+				if _, ok := p.accept("]"); ok {
+					return items, nil
+				}
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if _, ok := p.accept(","); !ok {
+					if err := p.expect("]"); err != nil {
+						return nil, err
+					}
+					return items, nil
+				}
+			}
+
+		case "{":
+			dict := map[string]interface{}{}
+			for {
+				if _, ok := p.accept("}"); ok {
+					return dict, nil
+				}
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := p.accept(":"); !ok {
+					return nil, p.r.unexpected(p.peek())
+				}
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				dict[p.r.str(key)] = value
+				if _, ok := p.accept(","); !ok {
+					if err := p.expect("}"); err != nil {
+						return nil, err
+					}
+					return dict, nil
+				}
+			}
+		}
+	}
+
+	return nil, p.r.unexpected(t)
+}
+
+// Name resolution
+
+// lookup resolves a bare name: template variables first, then engine globals
+func (r *tplRenderer) lookup(name string) (interface{}, error) {
+	if v, ok := r.vars[name]; ok {
+		return v, nil
+	}
+
+	switch r.engine {
+	case "jinja2":
+		if v := r.jinjaGlobal(name); v != nil {
+			return v, nil
+		}
+		if name == "self" {
+			return &tplObject{kind: "context", name: "self", repr: "<TemplateReference None>"}, nil
+		}
+		return &tplUndefined{name: name}, nil
+
+	case "twig":
+		switch name {
+		case "_self":
+			return &tplObject{kind: "twig_self", name: "_self", repr: "__TwigTemplate_page"}, nil
+		case "_context":
+			return r.vars, nil
+		}
+		return &tplUndefined{name: name}, nil
+
+	case "erb":
+		switch name {
+		case "File", "IO", "Kernel", "Dir", "Open3", "Object", "Process":
+			return &tplObject{kind: "ruby", name: name, repr: name}, nil
+		case "ENV":
+			return map[string]interface{}{"PATH": "/usr/local/bin:/usr/bin:/bin", "HOME": "/var/www", "RACK_ENV": "production"}, nil
+		case "system", "exec", "eval", "open", "require", "puts", "print", "p":
+			return r.rubyAttr(&tplObject{kind: "ruby", name: "Kernel"}, name)
+		}
+		return nil, r.runtimeError("", "undefined local variable or method `%s' for main:Object", name)
+
+	default: // freemarker
+		return nil, r.runtimeError("", "The following has evaluated to null or missing:\n==> %s  [in template \"page\"]", name)
+	}
+}
+
+// jinjaGlobal returns Jinja2's default template globals
+func (r *tplRenderer) jinjaGlobal(name string) interface{} {
+	switch name {
+	case "range":
+		return callable("range", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				n, _, ok := r.toNumber(arg)
+				if !ok {
+					return nil, r.runtimeError("TypeError", "'%s' object cannot be interpreted as an integer", pyTypeName(arg))
+				}
+				bounds[i] = int64(n)
+			}
+			start, stop := int64(0), int64(0)
+			switch len(bounds) {
+			case 1:
+				stop = bounds[0]
+			case 2, 3:
+				start, stop = bounds[0], bounds[1]
+			default:
+				return nil, r.runtimeError("TypeError", "range expected at least 1 argument, got 0")
+			}
+			if stop-start > maxTemplateIterations {
+				return nil, r.runtimeError("SecurityError", "Range too big. The sandbox blocks ranges larger than MAX_RANGE (%d).", maxTemplateIterations)
+			}
+			items := []interface{}{}
+			for i := start; i < stop; i++ {
+				items = append(items, i)
+			}
+			return items, nil
+		})
+	case "dict":
+		return callable("dict", func(_ []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return kwargs, nil
+		})
+	case "lipsum", "cycler", "joiner", "namespace":
+		return pyFunction("jinja2.utils." + name)
+	}
+	return nil
+}
+
+// Python objects
+
+// pyClass returns a class object; name may be module-qualified
+func pyClass(name string) *tplObject {
+	return &tplObject{kind: "class", name: name, repr: fmt.Sprintf("<class '%s'>", name)}
+}
+
+// pyFunction returns a function object
+func pyFunction(name string) *tplObject {
+	return &tplObject{kind: "function", name: name, repr: fmt.Sprintf("<function %s at %s>", name, pyAddress)}
+}
+
+// pyModule returns a module object
+func pyModule(name string) *tplObject {
+	repr := fmt.Sprintf("<module '%s' from '/usr/lib/python3.11/%s.py'>", name, name)
+	if name == "sys" || name == "builtins" {
+		repr = fmt.Sprintf("<module '%s' (built-in)>", name)
+	}
+	return &tplObject{kind: "module", name: name, repr: repr}
+}
+
+// pyGlobals returns a function's module globals. Every module here can reach os.
+func pyGlobals(module string) *tplObject {
+	return &tplObject{kind: "globals", name: module, repr: fmt.Sprintf(
+		"{'__name__': '%s', '__doc__': None, '__builtins__': {...}, 'os': <module 'os' from '/usr/lib/python3.11/os.py'>, 'sys': <module 'sys' (built-in)>, 'popen': <function popen at %s>, ...}",
+		module, pyAddress)}
+}
+
+// pyBuiltins returns the builtins namespace
+func pyBuiltins() *tplObject {
+	return &tplObject{kind: "builtins", name: "builtins", repr: "{'__name__': 'builtins', '__doc__': \"Built-in functions, exceptions, and other objects.\", '__import__': <built-in function __import__>, 'open': <built-in function open>, 'eval': <built-in function eval>, ...}"}
+}
+
+// pyProcess returns a finished process with its output
+func pyProcess(output string) *tplObject {
+	return &tplObject{kind: "process", name: "os._wrap_close", repr: "<os._wrap_close object at " + pyAddress + ">", output: output}
+}
+
+// pyTypeName returns the Python type name of a value
+func pyTypeName(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return "str"
+	case int, int64:
+		return "int"
+	case float64:
+		return "float"
+	case bool:
+		return "bool"
+	case nil:
+		return "NoneType"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	case *tplUndefined:
+		return "jinja2.runtime.Undefined"
+	case *tplCallable:
+		return "builtin_function_or_method"
+	case *tplObject:
+		switch val.kind {
+		case "class":
+			return "type"
+		case "function":
+			return "function"
+		case "module":
+			return "module"
+		case "globals", "builtins":
+			return "dict"
+		case "file":
+			return "_io.TextIOWrapper"
+		default:
+			return val.name
+		}
+	}
+	return "object"
+}
+
+// Attribute and item access
+
+// getAttr resolves v.name
+func (r *tplRenderer) getAttr(v interface{}, name string) (interface{}, error) {
+	if obj, ok := v.(*tplObject); ok {
+		if attr, found, err := r.objectAttr(obj, name); found || err != nil {
+			return attr, err
+		}
+	}
+	if len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__") {
+		if attr := r.dunder(v, name); attr != nil {
+			return attr, nil
+		}
+		return r.missingAttr(v, name)
+	}
+
+	switch val := v.(type) {
+	case *tplUndefined:
+		if r.engine == "jinja2" {
+			return nil, r.runtimeError("", "'%s' is undefined", val.name)
+		}
+		return val, nil
+	case map[string]interface{}:
+		if item, ok := val[name]; ok {
+			return item, nil
+		}
+		if m := r.dictMethod(val, name); m != nil {
+			return m, nil
+		}
+	case string:
+		if m := r.stringMethod(val, name); m != nil {
+			return m, nil
+		}
+	case []interface{}:
+		if m := r.listMethod(val, name); m != nil {
+			return m, nil
+		}
+		if i, err := strconv.Atoi(name); err == nil {
+			return r.getItem(val, int64(i))
+		}
+	}
+
+	return r.missingAttr(v, name)
+}
+
+// missingAttr is what each engine does with an unknown attribute
+func (r *tplRenderer) missingAttr(v interface{}, name string) (interface{}, error) {
+	switch r.engine {
+	case "jinja2", "twig":
+		return &tplUndefined{name: name}, nil
+	case "erb":
+		return nil, r.runtimeError("NoMethodError", "undefined method `%s' for %s", name, r.str(v))
+	default:
+		return nil, r.runtimeError("", "The following has evaluated to null or missing:\n==> %s  [in template \"page\"]", name)
+	}
+}
+
+// getItem resolves v[key]; like Jinja2, string keys fall back to attributes
+func (r *tplRenderer) getItem(v, key interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if item, ok := val[r.str(key)]; ok {
+			return item, nil
+		}
+	case []interface{}:
+		if n, isInt, ok := r.toNumber(key); ok && isInt {
+			i := int(n)
+			if i < 0 {
+				i += len(val)
+			}
+			if i < 0 || i >= len(val) {
+				return nil, r.runtimeError("IndexError", "list index out of range")
+			}
+			return val[i], nil
+		}
+	case string:
+		if n, isInt, ok := r.toNumber(key); ok && isInt {
+			runes := []rune(val)
+			i := int(n)
+			if i < 0 {
+				i += len(runes)
+			}
+			if i < 0 || i >= len(runes) {
+				return nil, r.runtimeError("IndexError", "string index out of range")
+			}
+			return string(runes[i]), nil
+		}
+	}
+
+	if name, ok := key.(string); ok {
+		return r.getAttr(v, name)
+	}
+	return r.missingAttr(v, r.str(key))
+}
+
+// dunder resolves Python special attributes used to walk from any value to os
+func (r *tplRenderer) dunder(v interface{}, name string) interface{} {
+	obj, _ := v.(*tplObject)
+	isClass := obj != nil && obj.kind == "class"
+
+	switch name {
+	case "__class__":
+		return pyClass(pyTypeName(v))
+	case "__mro__":
+		if isClass {
+			if obj.name == "object" {
+				return []interface{}{obj}
+			}
+			return []interface{}{obj, pyClass("object")}
+		}
+	case "__bases__":
+		if isClass {
+			if obj.name == "object" {
+				return []interface{}{}
+			}
+			return []interface{}{pyClass("object")}
+		}
+	case "__base__":
+		if isClass && obj.name != "object" {
+			return pyClass("object")
+		}
+	case "__subclasses__":
+		if isClass {
+			return callable("__subclasses__", func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				classes := []interface{}{}
+				if obj.name == "object" {
+					for _, sub := range pySubclasses {
+						classes = append(classes, pyClass(sub))
+					}
+				}
+				return classes, nil
+			})
+		}
+	case "__init__":
+		if isClass {
+			return pyFunction(obj.name + ".__init__")
+		}
+		return pyFunction(pyTypeName(v) + ".__init__")
+	case "__globals__":
+		if obj != nil && obj.kind == "function" {
+			module := obj.name
+			if idx := strings.LastIndex(module, "."); idx >= 0 {
+				module = module[:idx]
+			}
+			return pyGlobals(module)
+		}
+	case "__builtins__":
+		if obj != nil && (obj.kind == "globals" || obj.kind == "module" || obj.kind == "function") {
+			return pyBuiltins()
+		}
+	case "__name__":
+		if obj != nil && (isClass || obj.kind == "function" || obj.kind == "module") {
+			short := obj.name
+			if idx := strings.LastIndex(short, "."); idx >= 0 && obj.kind != "module" {
+				short = short[idx+1:]
+			}
+			return short
+		}
+	case "__doc__":
+		return fmt.Sprintf("%s(object) -> %s", pyTypeName(v), pyTypeName(v))
+	}
+	return nil
+}
+
+// objectAttr resolves attributes specific to host objects
+func (r *tplRenderer) objectAttr(o *tplObject, name string) (interface{}, bool, error) {
+	switch o.kind {
+	case "globals":
+		switch name {
+		case "os", "sys", "subprocess":
+			return pyModule(name), true, nil
+		case "__builtins__":
+			return pyBuiltins(), true, nil
+		case "popen", "system", "listdir", "getcwd":
+			return r.moduleAttr("os", name), true, nil
+		case "__name__":
+			return o.name, true, nil
+		}
+
+	case "builtins":
+		switch name {
+		case "__import__":
+			return callable("__import__", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TypeError", "__import__() missing required argument 'name' (pos 1)")
+				}
+				return pyModule(r.str(args[0])), nil
+			}), true, nil
+		case "open":
+			return callable("open", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TypeError", "open() missing required argument 'file' (pos 1)")
+				}
+				return r.openFile(r.str(args[0]))
+			}), true, nil
+		case "eval", "exec":
+			return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TypeError", "%s expected at least 1 argument, got 0", name)
+				}
+				v, err := r.eval(r.str(args[0]))
+				if name == "exec" {
+					v = nil
+				}
+				return v, err
+			}), true, nil
+		case "getattr":
+			return callable("getattr", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) < 2 {
+					return nil, r.runtimeError("TypeError", "getattr expected at least 2 arguments, got %d", len(args))
+				}
+				return r.getAttr(args[0], r.str(args[1]))
+			}), true, nil
+		case "chr":
+			return callable("chr", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TypeError", "chr() takes exactly one argument (0 given)")
+				}
+				n, _, _ := r.toNumber(args[0])
+				return string(rune(int(n))), nil
+			}), true, nil
+		case "__name__":
+			return "builtins", true, nil
+		}
+
+	case "module":
+		if attr := r.moduleAttr(o.name, name); attr != nil {
+			return attr, true, nil
+		}
+
+	case "class":
+		if name == "__name__" {
+			return r.dunder(o, name), true, nil
+		}
+
+	case "process", "file":
+		switch name {
+		case "read", "gets":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return o.output, nil
+			}), true, nil
+		case "readlines":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				lines := []interface{}{}
+				for _, line := range strings.SplitAfter(o.output, "\n") {
+					if line != "" {
+						lines = append(lines, line)
+					}
+				}
+				return lines, nil
+			}), true, nil
+		case "communicate":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return []interface{}{o.output, nil}, nil
+			}), true, nil
+		case "stdout":
+			return o, true, nil
+		case "wait", "close":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return int64(0), nil
+			}), true, nil
+		case "returncode":
+			return int64(0), true, nil
+		}
+
+	case "instance":
+		if name == "_module" && o.name == "warnings.catch_warnings" {
+			return pyModule("warnings"), true, nil
+		}
+
+	case "context":
+		if name == "_TemplateReference__context" {
+			context := make(map[string]interface{}, len(r.vars)+6)
+			for _, global := range []string{"range", "dict", "lipsum", "cycler", "joiner", "namespace"} {
+				context[global] = r.jinjaGlobal(global)
+			}
+			for k, v := range r.vars {
+				context[k] = v
+			}
+			return context, true, nil
+		}
+
+	case "twig_self":
+		switch name {
+		case "env":
+			return &tplObject{kind: "twig_env", name: "env", repr: "Twig\\Environment"}, true, nil
+		case "getTemplateName":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return "page", nil
+			}), true, nil
+		}
+
+	case "twig_env":
+		switch name {
+		case "registerUndefinedFilterCallback":
+			return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) > 0 {
+					r.twigCallback = r.str(args[0])
+				}
+				return nil, nil
+			}), true, nil
+		case "getFilter":
+			return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, nil
+				}
+				// Unknown filters are resolved through the registered callback
+				return r.callPHP(r.twigCallback, r.str(args[0]))
+			}), true, nil
+		case "setCache", "enableDebug", "enableAutoReload":
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return nil, nil
+			}), true, nil
+		}
+
+	case "ruby":
+		attr, err := r.rubyAttr(o, name)
+		return attr, attr != nil || err != nil, err
+
+	case "java_pb":
+		if name == "start" {
+			return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+				output, err := r.runCommand(o.name)
+				return &tplObject{kind: "process", name: "java.lang.ProcessImpl", repr: "java.lang.ProcessImpl@6d06d69c", output: output}, err
+			}), true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// moduleAttr resolves attributes of the Python os, subprocess and sys modules
+func (r *tplRenderer) moduleAttr(module, name string) interface{} {
+	command := func(args []interface{}) string {
+		if len(args) == 0 {
+			return ""
+		}
+		if list, ok := args[0].([]interface{}); ok {
+			parts := make([]string, len(list))
+			for i, part := range list {
+				parts[i] = r.str(part)
+			}
+			return strings.Join(parts, " ")
+		}
+		return r.str(args[0])
+	}
+
+	switch module + "." + name {
+	case "os.popen":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			output, err := r.runCommand(command(args))
+			return pyProcess(output), err
+		})
+	case "os.system":
+		// Output goes to the server's stdout, not the page - only the exit status comes back
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			_, err := r.runCommand(command(args))
+			return int64(0), err
+		})
+	case "os.listdir":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			dir := "."
+			if len(args) > 0 {
+				dir = r.str(args[0])
+			}
+			output, err := r.runCommand("ls -A " + dir)
+			entries := []interface{}{}
+			for _, entry := range strings.Fields(output) {
+				entries = append(entries, entry)
+			}
+			return entries, err
+		})
+	case "os.getcwd":
+		return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			output, err := r.runCommand("pwd")
+			return strings.TrimSpace(output), err
+		})
+	case "subprocess.check_output", "subprocess.getoutput":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return r.runCommand(command(args))
+		})
+	case "subprocess.Popen":
+		return pyClass("subprocess.Popen")
+	case "subprocess.run":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			output, err := r.runCommand(command(args))
+			process := pyProcess(output)
+			process.repr = "CompletedProcess(args=" + pyRepr(command(args)) + ", returncode=0)"
+			return process, err
+		})
+	case "sys.modules":
+		return map[string]interface{}{"os": pyModule("os"), "subprocess": pyModule("subprocess"), "sys": pyModule("sys"), "builtins": pyModule("builtins")}
+	case "sys.version":
+		return "3.11.4 (main, Jun  7 2023, 10:13:09) [GCC 12.2.0]"
+	}
+
+	if module == "builtins" {
+		if attr, found, _ := r.objectAttr(pyBuiltins(), name); found {
+			return attr
+		}
+	}
+	return nil
+}
+
+// openFile emulates open(path), or a Ruby pipe open("|cmd")
+func (r *tplRenderer) openFile(path string) (interface{}, error) {
+	if r.engine == "erb" && strings.HasPrefix(path, "|") {
+		output, err := r.runCommand(path[1:])
+		return &tplObject{kind: "process", name: "IO", repr: "#<IO:fd 9>", output: output}, err
+	}
+
+	content, err := r.readFile(path)
+	repr := fmt.Sprintf("<_io.TextIOWrapper name='%s' mode='r' encoding='UTF-8'>", path)
+	if r.engine == "erb" {
+		repr = fmt.Sprintf("#<File:%s>", path)
+	}
+	return &tplObject{kind: "file", name: path, repr: repr, output: content}, err
+}
+
+// rubyAttr resolves methods on Ruby constants such as File, IO and Kernel
+func (r *tplRenderer) rubyAttr(o *tplObject, name string) (interface{}, error) {
+	arg := func(args []interface{}) string {
+		if len(args) == 0 {
+			return ""
+		}
+		return r.str(args[0])
+	}
+	method := func(fn func(args []interface{}) (interface{}, error)) *tplCallable {
+		return callable(o.name+"."+name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return fn(args)
+		})
+	}
+
+	switch o.name + "." + name {
+	case "File.read", "IO.read":
+		return method(func(args []interface{}) (interface{}, error) { return r.readFile(arg(args)) }), nil
+	case "File.open", "Kernel.open":
+		return method(func(args []interface{}) (interface{}, error) { return r.openFile(arg(args)) }), nil
+	case "File.readlines", "IO.readlines":
+		return method(func(args []interface{}) (interface{}, error) {
+			content, err := r.readFile(arg(args))
+			lines := []interface{}{}
+			for _, line := range strings.SplitAfter(content, "\n") {
+				if line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return lines, err
+		}), nil
+	case "IO.popen":
+		return method(func(args []interface{}) (interface{}, error) {
+			output, err := r.runCommand(arg(args))
+			return &tplObject{kind: "process", name: "IO", repr: "#<IO:fd 9>", output: output}, err
+		}), nil
+	case "Kernel.system":
+		// Output goes to the server's stdout; system returns true
+		return method(func(args []interface{}) (interface{}, error) {
+			_, err := r.runCommand(arg(args))
+			return true, err
+		}), nil
+	case "Kernel.exec":
+		return method(func(args []interface{}) (interface{}, error) { return r.runCommand(arg(args)) }), nil
+	case "Kernel.eval":
+		return method(func(args []interface{}) (interface{}, error) { return r.eval(arg(args)) }), nil
+	case "Kernel.require":
+		return method(func(args []interface{}) (interface{}, error) { return true, nil }), nil
+	case "Kernel.puts", "Kernel.print", "Kernel.p":
+		return method(func(args []interface{}) (interface{}, error) { return nil, nil }), nil
+	case "Open3.capture2", "Open3.capture2e", "Open3.capture3":
+		return method(func(args []interface{}) (interface{}, error) {
+			output, err := r.runCommand(arg(args))
+			status := &tplObject{kind: "ruby", name: "Process::Status", repr: "pid 4242 exit 0"}
+			if name == "capture3" {
+				return []interface{}{output, "", status}, err
+			}
+			return []interface{}{output, status}, err
+		}), nil
+	case "Dir.entries":
+		return method(func(args []interface{}) (interface{}, error) {
+			output, err := r.runCommand("ls -a " + arg(args))
+			entries := []interface{}{}
+			for _, entry := range strings.Fields(output) {
+				entries = append(entries, entry)
+			}
+			return entries, err
+		}), nil
+	case "Dir.pwd":
+		return method(func(args []interface{}) (interface{}, error) {
+			output, err := r.runCommand("pwd")
+			return strings.TrimSpace(output), err
+		}), nil
+	case "Object.const_get":
+		return method(func(args []interface{}) (interface{}, error) { return r.lookup(arg(args)) }), nil
+	}
+	return nil, nil
+}
+
+// callPHP calls a PHP function by name the way Twig's callbacks do
+func (r *tplRenderer) callPHP(function, arg string) (interface{}, error) {
+	echoes, isShell := phpShellFunctions[function]
+	if !isShell {
+		return nil, nil
+	}
+
+	output, err := r.runCommand(arg)
+	if err != nil {
+		return nil, err
+	}
+	if echoes {
+		// system and passthru print straight to the response
+		r.out.WriteString(output)
+		lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+		return lines[len(lines)-1], nil
+	}
+	if function == "exec" {
+		lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+		return lines[len(lines)-1], nil
+	}
+	return output, nil
+}
+
+// call invokes a callable or instantiates a class
+func (r *tplRenderer) call(v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	switch f := v.(type) {
+	case *tplCallable:
+		return f.fn(args, kwargs)
+	case *tplObject:
+		if f.kind == "class" {
+			if f.name == "subprocess.Popen" {
+				return r.moduleAttr("os", "popen").(*tplCallable).fn(args, kwargs)
+			}
+			return &tplObject{kind: "instance", name: f.name, repr: fmt.Sprintf("<%s object at %s>", f.name, pyAddress)}, nil
+		}
+	case *tplUndefined:
+		if r.engine == "jinja2" {
+			return nil, r.runtimeError("", "'%s' is undefined", f.name)
+		}
+		return f, nil
+	}
+
+	switch r.engine {
+	case "erb":
+		return nil, r.runtimeError("NoMethodError", "undefined method `call' for %s", r.str(v))
+	case "twig":
+		return nil, r.runtimeError("", "Neither the property nor a method exists for %s.", r.str(v))
+	default:
+		return nil, r.runtimeError("TypeError", "'%s' object is not callable", pyTypeName(v))
+	}
+}
+
+// Methods on plain values
+
+// stringMethod returns Python (Jinja2) or Ruby (ERB) string methods
+func (r *tplRenderer) stringMethod(s, name string) *tplCallable {
+	unary := func(fn func(string) interface{}) *tplCallable {
+		return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return fn(s), nil
+		})
+	}
+
+	switch name {
+	case "upper", "upcase":
+		return unary(func(s string) interface{} { return strings.ToUpper(s) })
+	case "lower", "downcase":
+		return unary(func(s string) interface{} { return strings.ToLower(s) })
+	case "strip":
+		return unary(func(s string) interface{} { return strings.TrimSpace(s) })
+	case "title", "capitalize":
+		return unary(func(s string) interface{} { return tplTitle(s, name == "title") })
+	case "length", "size":
+		if r.engine == "erb" {
+			return unary(func(s string) interface{} { return int64(len([]rune(s))) })
+		}
+	case "reverse":
+		if r.engine == "erb" {
+			return unary(func(s string) interface{} { return tplReverse(s) })
+		}
+	case "replace", "gsub":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) < 2 {
+				return s, nil
+			}
+			return strings.ReplaceAll(s, r.str(args[0]), r.str(args[1])), nil
+		})
+	case "split":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			var parts []string
+			if len(args) == 0 {
+				parts = strings.Fields(s)
+			} else {
+				parts = strings.Split(s, r.str(args[0]))
+			}
+			items := make([]interface{}, len(parts))
+			for i, part := range parts {
+				items[i] = part
+			}
+			return items, nil
+		})
+	case "join":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return s, nil
+			}
+			parts := []string{}
+			for _, item := range tplItems(args[0]) {
+				parts = append(parts, r.str(item))
+			}
+			return strings.Join(parts, s), nil
+		})
+	case "format":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			out := s
+			for _, arg := range args {
+				out = strings.Replace(out, "{}", r.str(arg), 1)
+			}
+			return out, nil
+		})
+	case "startswith", "endswith":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return false, nil
+			}
+			if name == "startswith" {
+				return strings.HasPrefix(s, r.str(args[0])), nil
+			}
+			return strings.HasSuffix(s, r.str(args[0])), nil
+		})
+	}
+	return nil
+}
+
+// listMethod returns list methods
+func (r *tplRenderer) listMethod(list []interface{}, name string) *tplCallable {
+	switch name {
+	case "length", "size", "count":
+		return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return int64(len(list)), nil
+		})
+	case "first", "last":
+		return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(list) == 0 {
+				return nil, nil
+			}
+			if name == "first" {
+				return list[0], nil
+			}
+			return list[len(list)-1], nil
+		})
+	case "join":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return r.applyFilter(list, "join", args)
+		})
+	}
+	return nil
+}
+
+// dictMethod returns Python dict methods
+func (r *tplRenderer) dictMethod(dict map[string]interface{}, name string) *tplCallable {
+	switch name {
+	case "keys", "values", "items":
+		return callable(name, func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			keys := tplSortedKeys(dict)
+			items := make([]interface{}, len(keys))
+			for i, k := range keys {
+				switch name {
+				case "keys":
+					items[i] = k
+				case "values":
+					items[i] = dict[k]
+				default:
+					items[i] = []interface{}{k, dict[k]}
+				}
+			}
+			return items, nil
+		})
+	case "get":
+		return callable(name, func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return nil, nil
+			}
+			if v, ok := dict[r.str(args[0])]; ok {
+				return v, nil
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+			return nil, nil
+		})
+	}
+	return nil
+}
+
+// Filters and built-ins
+
+// applyFilter applies a Jinja2 or Twig filter
+func (r *tplRenderer) applyFilter(v interface{}, name string, args []interface{}) (interface{}, error) {
+	arg := func(i int, fallback interface{}) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		return fallback
+	}
+
+	switch name {
+	case "upper":
+		return strings.ToUpper(r.str(v)), nil
+	case "lower":
+		return strings.ToLower(r.str(v)), nil
+	case "title", "capitalize":
+		return tplTitle(r.str(v), name == "title"), nil
+	case "trim", "strip":
+		return strings.TrimSpace(r.str(v)), nil
+	case "length", "count":
+		return int64(tplLen(v, r)), nil
+	case "string":
+		return r.str(v), nil
+	case "int":
+		n, _, _ := r.toNumber(v)
+		return int64(n), nil
+	case "float":
+		n, _, _ := r.toNumber(v)
+		return n, nil
+	case "e", "escape":
+		return html.EscapeString(r.str(v)), nil
+	case "safe", "raw":
+		return v, nil
+	case "join":
+		parts := []string{}
+		for _, item := range tplItems(v) {
+			parts = append(parts, r.str(item))
+		}
+		return strings.Join(parts, r.str(arg(0, ""))), nil
+	case "first", "last":
+		items := tplItems(v)
+		if len(items) == 0 {
+			return &tplUndefined{name: name}, nil
+		}
+		if name == "first" {
+			return items[0], nil
+		}
+		return items[len(items)-1], nil
+	case "reverse":
+		if s, ok := v.(string); ok {
+			return tplReverse(s), nil
+		}
+		items := tplItems(v)
+		reversed := make([]interface{}, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		return reversed, nil
+	case "list":
+		return tplItems(v), nil
+	case "keys":
+		if dict, ok := v.(map[string]interface{}); ok {
+			keys := []interface{}{}
+			for _, k := range tplSortedKeys(dict) {
+				keys = append(keys, k)
+			}
+			return keys, nil
+		}
+		return []interface{}{}, nil
+	case "default", "d":
+		if _, undefined := v.(*tplUndefined); undefined || v == nil {
+			return arg(0, ""), nil
+		}
+		return v, nil
+	case "json_encode", "tojson":
+		encoded, _ := json.Marshal(tplPlain(v))
+		return string(encoded), nil
+	}
+
+	if r.engine == "jinja2" {
+		switch name {
+		case "attr":
+			return r.getAttr(v, r.str(arg(0, "")))
+		case "format":
+			// printf-style, as used to build strings like "%c"|format(95)
+			out := r.str(v)
+			for _, a := range args {
+				idx := strings.Index(out, "%")
+				if idx < 0 || idx+1 >= len(out) {
+					break
+				}
+				replacement := r.str(a)
+				if out[idx+1] == 'c' {
+					n, _, _ := r.toNumber(a)
+					replacement = string(rune(int(n)))
+				}
+				out = out[:idx] + replacement + out[idx+2:]
+			}
+			return out, nil
+		}
+		return nil, r.syntaxError("No filter named '%s'.", name)
+	}
+
+	// Twig
+	switch name {
+	case "filter", "map", "sort", "reduce":
+		// A string callable is resolved as a PHP function name
+		function := r.str(arg(0, ""))
+		if _, isShell := phpShellFunctions[function]; !isShell {
+			return v, nil
+		}
+		results := []interface{}{}
+		for _, item := range tplItems(v) {
+			result, err := r.callPHP(function, r.str(item))
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		if name == "map" {
+			return results, nil
+		}
+		return v, nil
+	case "file_excerpt":
+		return r.readFile(r.str(v))
+	}
+	return nil, r.syntaxError("Unknown \"%s\" filter.", name)
+}
+
+// applyBuiltin applies a FreeMarker ?built-in
+func (r *tplRenderer) applyBuiltin(v interface{}, name string, args []interface{}) (interface{}, error) {
+	switch name {
+	case "upper_case":
+		return strings.ToUpper(r.str(v)), nil
+	case "lower_case":
+		return strings.ToLower(r.str(v)), nil
+	case "cap_first":
+		return tplTitle(r.str(v), false), nil
+	case "trim":
+		return strings.TrimSpace(r.str(v)), nil
+	case "length", "size":
+		return int64(tplLen(v, r)), nil
+	case "string", "c":
+		return r.str(v), nil
+	case "html":
+		return html.EscapeString(r.str(v)), nil
+	case "reverse":
+		return r.applyFilter(v, "reverse", nil)
+	case "eval":
+		return r.eval(r.str(v))
+	case "new":
+		switch class := r.str(v); class {
+		case "freemarker.template.utility.Execute":
+			return callable("Execute", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TemplateModelException", "Expecting exactly one argument")
+				}
+				return r.runCommand(r.str(args[0]))
+			}), nil
+		case "freemarker.template.utility.ObjectConstructor":
+			return callable("ObjectConstructor", func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return nil, r.runtimeError("TemplateModelException", "This method must have at least one argument, the name of the class to instantiate.")
+				}
+				if r.str(args[0]) != "java.lang.ProcessBuilder" {
+					return &tplObject{kind: "instance", name: r.str(args[0]), repr: r.str(args[0]) + "@1b6d3586"}, nil
+				}
+				parts := []string{}
+				for _, part := range args[1:] {
+					parts = append(parts, r.str(part))
+				}
+				return &tplObject{kind: "java_pb", name: strings.Join(parts, " "), repr: "java.lang.ProcessBuilder@4554617c"}, nil
+			}), nil
+		default:
+			return nil, r.runtimeError("", "Instantiating %s is not allowed in the template for security reasons.", class)
+		}
+	}
+	return nil, r.syntaxError("Unknown built-in: \"%s\".", name)
+}
+
+// Operators
+
+// toNumber converts a value for arithmetic; Twig also coerces numeric strings
+func (r *tplRenderer) toNumber(v interface{}) (float64, bool, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true, true
+	case int:
+		return float64(n), true, true
+	case float64:
+		return n, n == math.Trunc(n) && !math.IsInf(n, 0), true
+	case bool:
+		if n {
+			return 1, true, true
+		}
+		return 0, true, true
+	case string:
+		if r.engine == "twig" {
+			if i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64); err == nil {
+				return float64(i), true, true
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(n), 64); err == nil {
+				return f, false, true
+			}
+			// PHP treats non-numeric strings as 0
+			return 0, true, true
+		}
+	case nil:
+		if r.engine == "twig" {
+			return 0, true, true
+		}
+	}
+	return 0, false, false
+}
+
+// arith applies an arithmetic operator with each engine's semantics
+func (r *tplRenderer) arith(op string, a, b interface{}) (interface{}, error) {
+	as, aIsStr := a.(string)
+	bs, bIsStr := b.(string)
+
+	// String and sequence operators (Twig always converts to numbers)
+	if r.engine != "twig" && (aIsStr || bIsStr) {
+		switch {
+		case op == "+" && aIsStr && bIsStr:
+			return as + bs, nil
+		case op == "+" && r.engine == "freemarker":
+			return r.str(a) + r.str(b), nil
+		case op == "*" && (r.engine == "jinja2" || r.engine == "erb"):
+			s, count := as, b
+			if !aIsStr && r.engine == "jinja2" {
+				s, count = bs, a
+			}
+			if n, isInt, ok := r.toNumber(count); ok && isInt {
+				if n <= 0 {
+					return "", nil
+				}
+				if int(n)*len(s) > maxTemplateOutput {
+					return nil, r.runtimeError("MemoryError", "")
+				}
+				return strings.Repeat(s, int(n)), nil
+			}
+		}
+		return nil, r.operandError(op, a, b)
+	}
+	if la, ok := a.([]interface{}); ok && op == "+" {
+		if lb, ok := b.([]interface{}); ok {
+			return append(append([]interface{}{}, la...), lb...), nil
+		}
+	}
+
+	x, xInt, ok1 := r.toNumber(a)
+	y, yInt, ok2 := r.toNumber(b)
+	if !ok1 || !ok2 {
+		return nil, r.operandError(op, a, b)
+	}
+	bothInt := xInt && yInt
+	if _, isFloat := a.(float64); isFloat && r.engine != "twig" && r.engine != "freemarker" {
+		bothInt = false
+	}
+	if _, isFloat := b.(float64); isFloat && r.engine != "twig" && r.engine != "freemarker" {
+		bothInt = false
+	}
+
+	var result float64
+	switch op {
+	case "+":
+		result = x + y
+	case "-":
+		result = x - y
+	case "*":
+		result = x * y
+	case "/", "//", "%":
+		if y == 0 {
+			return nil, r.zeroDivision()
+		}
+		switch {
+		case op == "%":
+			result = math.Mod(x, y)
+			if r.engine == "jinja2" || r.engine == "erb" {
+				// Python and Ruby take the sign of the divisor
+				if result != 0 && (result < 0) != (y < 0) {
+					result += y
+				}
+			}
+		case op == "//" || (r.engine == "erb" && bothInt):
+			result = math.Floor(x / y)
+		default:
+			result = x / y
+			if r.engine == "jinja2" {
+				// True division always returns a float in Python 3
+				return result, nil
+			}
+		}
+	case "**":
+		result = math.Pow(x, y)
+		if y < 0 {
+			bothInt = false
+		}
+	}
+
+	if bothInt && math.Abs(result) < 1<<62 && result == math.Trunc(result) {
+		return int64(result), nil
+	}
+	return result, nil
+}
+
+// operandError reports incompatible operand types
+func (r *tplRenderer) operandError(op string, a, b interface{}) error {
+	switch r.engine {
+	case "erb":
+		if _, ok := a.(string); ok {
+			return r.runtimeError("TypeError", "no implicit conversion of %s into String", rubyTypeName(b))
+		}
+		return r.runtimeError("TypeError", "%s can't be coerced into %s", rubyTypeName(b), rubyTypeName(a))
+	case "freemarker":
+		return r.runtimeError("", "Expected a number, but this has evaluated to a %s", strings.ToLower(rubyTypeName(b)))
+	default:
+		if _, ok := a.(string); ok && op == "+" {
+			return r.runtimeError("TypeError", "can only concatenate str (not \"%s\") to str", pyTypeName(b))
+		}
+		return r.runtimeError("TypeError", "unsupported operand type(s) for %s: '%s' and '%s'", op, pyTypeName(a), pyTypeName(b))
+	}
+}
+
+// zeroDivision reports division by zero
+func (r *tplRenderer) zeroDivision() error {
+	switch r.engine {
+	case "twig":
+		return r.runtimeError("DivisionByZeroError", "Division by zero")
+	case "erb":
+		return r.runtimeError("ZeroDivisionError", "divided by 0")
+	case "freemarker":
+		return r.runtimeError("", "Arithmetic operation failed: division by zero")
+	default:
+		return r.runtimeError("ZeroDivisionError", "division by zero")
+	}
+}
+
+// compare applies a comparison or membership operator
+func (r *tplRenderer) compare(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return tplEqual(a, b, r), nil
+	case "!=":
+		return !tplEqual(a, b, r), nil
+	case "in", "not in":
+		found := false
+		switch container := b.(type) {
+		case string:
+			found = strings.Contains(container, r.str(a))
+		case map[string]interface{}:
+			_, found = container[r.str(a)]
+		default:
+			for _, item := range tplItems(b) {
+				if tplEqual(a, item, r) {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (op == "in"), nil
+	}
+
+	x, _, ok1 := r.toNumber(a)
+	y, _, ok2 := r.toNumber(b)
+	if !ok1 || !ok2 {
+		as, aok := a.(string)
+		bs, bok := b.(string)
+		if !aok || !bok {
+			return nil, r.operandError(op, a, b)
+		}
+		x, y = float64(strings.Compare(as, bs)), 0
+	}
+	switch op {
+	case "<":
+		return x < y, nil
+	case ">":
+		return x > y, nil
+	case "<=":
+		return x <= y, nil
+	default:
+		return x >= y, nil
+	}
+}
+
+// Conversions
+
+// str renders a value the way the engine prints it
+func (r *tplRenderer) str(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		if r.engine == "jinja2" {
+			return "None"
+		}
+		return ""
+	case *tplUndefined:
+		return ""
+	case string:
+		return val
+	case bool:
+		switch r.engine {
+		case "jinja2":
+			if val {
+				return "True"
+			}
+			return "False"
+		case "twig":
+			if val {
+				return "1"
+			}
+			return ""
+		}
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1e16 {
+			if r.engine == "jinja2" || r.engine == "erb" {
+				return strconv.FormatFloat(val, 'f', 1, 64)
+			}
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case []interface{}:
+		switch r.engine {
+		case "twig":
+			return "Array"
+		case "erb":
+			parts := make([]string, len(val))
+			for i, item := range val {
+				parts[i] = r.inspect(item)
+			}
+			return "[" + strings.Join(parts, ", ") + "]"
+		}
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = r.inspect(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		if r.engine == "twig" {
+			return "Array"
+		}
+		parts := []string{}
+		for _, k := range tplSortedKeys(val) {
+			if r.engine == "erb" {
+				parts = append(parts, strconv.Quote(k)+"=>"+r.inspect(val[k]))
+			} else {
+				parts = append(parts, pyRepr(k)+": "+r.inspect(val[k]))
+			}
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case *tplObject:
+		return val.repr
+	case *tplCallable:
+		switch r.engine {
+		case "erb":
+			return "#<Method: " + val.name + ">"
+		case "twig", "freemarker":
+			return ""
+		}
+		return "<built-in method " + val.name + ">"
+	}
+	return fmt.Sprint(v)
+}
+
+// inspect renders a value inside a container (strings are quoted)
+func (r *tplRenderer) inspect(v interface{}) string {
+	if s, ok := v.(string); ok {
+		if r.engine == "erb" {
+			return strconv.Quote(s)
+		}
+		return pyRepr(s)
+	}
+	if v == nil && r.engine == "erb" {
+		return "nil"
+	}
+	return r.str(v)
+}
+
+// pyRepr quotes a string the way Python's repr does
+func pyRepr(s string) string {
+	quote := "'"
+	if strings.Contains(s, "'") && !strings.Contains(s, `"`) {
+		quote = `"`
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+	if quote == "'" {
+		escaped = strings.ReplaceAll(escaped, "'", `\'`)
+	}
+	return quote + escaped + quote
+}
+
+// rubyTypeName returns the Ruby class name of a value
+func rubyTypeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "String"
+	case int, int64:
+		return "Integer"
+	case float64:
+		return "Float"
+	case nil:
+		return "nil"
+	case []interface{}:
+		return "Array"
+	case map[string]interface{}:
+		return "Hash"
+	}
+	return "Object"
+}
+
+// tplTruthy reports whether a value is true in a condition
+func tplTruthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil, *tplUndefined:
+		return false
+	case bool:
+		return val
+	case int:
+		return val != 0
+	case int64:
+		return val != 0
+	case float64:
+		return val != 0
+	case string:
+		return val != ""
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	}
+	return true
+}
+
+// tplEqual compares two values, numerically when both are numbers
+func tplEqual(a, b interface{}, r *tplRenderer) bool {
+	if x, _, ok := r.toNumber(a); ok {
+		if _, isStr := a.(string); !isStr {
+			if y, _, ok := r.toNumber(b); ok {
+				if _, isStr := b.(string); !isStr {
+					return x == y
+				}
+			}
+		}
+	}
+	if (a == nil) != (b == nil) {
+		return false
+	}
+	return r.str(a) == r.str(b)
+}
+
+// tplItems returns the elements a loop or filter iterates over
+func tplItems(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		return val
+	case map[string]interface{}:
+		keys := []interface{}{}
+		for _, k := range tplSortedKeys(val) {
+			keys = append(keys, k)
+		}
+		return keys
+	case string:
+		items := []interface{}{}
+		for _, c := range val {
+			items = append(items, string(c))
+		}
+		return items
+	}
+	return nil
+}
+
+// tplLen returns the length of a value
+func tplLen(v interface{}, r *tplRenderer) int {
+	switch val := v.(type) {
+	case string:
+		return len([]rune(val))
+	case []interface{}:
+		return len(val)
+	case map[string]interface{}:
+		return len(val)
+	}
+	return len([]rune(r.str(v)))
+}
+
+// tplSortedKeys returns map keys in a stable order
+func tplSortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tplPlain converts interpreter values to plain data for JSON encoding
+func tplPlain(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = tplPlain(item)
+		}
+		return items
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = tplPlain(item)
+		}
+		return m
+	case *tplObject:
+		return val.repr
+	case *tplCallable, *tplUndefined:
+		return nil
+	}
+	return v
+}
+
+// tplTitle capitalizes the first letter of every word (title) or only the first (capitalize)
+func tplTitle(s string, everyWord bool) string {
+	runes := []rune(strings.ToLower(s))
+	start := true
+	for i, c := range runes {
+		if start && unicode.IsLetter(c) {
+			runes[i] = unicode.ToUpper(c)
+			if !everyWord {
+				break
+			}
+		}
+		start = !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}
+	return string(runes)
+}
+
+// tplReverse reverses a string
+func tplReverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
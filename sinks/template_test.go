@@ -0,0 +1,172 @@
+package sinks
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeUnsafe records unsafe expressions and returns canned output
+func fakeUnsafe(seen *[]UnsafeExpression) UnsafeHandler {
+	return func(u UnsafeExpression) string {
+		*seen = append(*seen, u)
+		if u.Kind == "file_read" {
+			return "root:x:0:0:root:/root:/bin/bash\n"
+		}
+		return "uid=33(www-data) gid=33(www-data) groups=33(www-data)\n"
+	}
+}
+
+// TestTemplate_Render tests expression evaluation across engines
+func TestTemplate_Render(t *testing.T) {
+	tpl := NewTemplate()
+	data := map[string]interface{}{"name": "alice", "items": []interface{}{"a", "b"}, "n": 3}
+
+	tests := []struct {
+		engine string
+		source string
+		want   string
+	}{
+		{"jinja2", "{{7*7}}", "49"},
+		{"jinja2", "{{7*'7'}}", "7777777"},
+		{"jinja2", "{{ 7/7 }}", "1.0"},
+		{"jinja2", "Hi {{ name|upper }}!", "Hi ALICE!"},
+		{"jinja2", "{{ missing }}", ""},
+		{"jinja2", "{% for i in items %}[{{ i }}]{% endfor %}", "[a][b]"},
+		{"jinja2", "{% if n > 2 %}big{% else %}small{% endif %}", "big"},
+		{"jinja2", "{% set x = n * 2 %}{{ x }}", "6"},
+		{"jinja2", "{{ ''.__class__ }}", "<class 'str'>"},
+		{"jinja2", "{{ ''.__class__.__mro__[1] }}", "<class 'object'>"},
+		{"jinja2", "{{ ''.__class__.__mro__[1].__subclasses__()[114] }}", "<class 'os._wrap_close'>"},
+		{"jinja2", "{{ '%c%c'|format(95, 95) }}", "__"},
+		{"twig", "{{7*7}}", "49"},
+		{"twig", "{{7*'7'}}", "49"},
+		{"twig", "{{ name ~ '!' }}", "alice!"},
+		{"twig", "{{ items }}", "Array"},
+		{"freemarker", "${7*7}", "49"},
+		{"freemarker", "<#assign x=n+1>${x}", "4"},
+		{"freemarker", "<#list items as i>${i?upper_case}</#list>", "AB"},
+		{"erb", "<%= 7*7 %>", "49"},
+		{"erb", "<%= '7'*7 %>", "7777777"},
+		{"erb", "<%= name.upcase %>", "ALICE"},
+		{"go", "{{.name}} {{len .items}}", "alice 2"},
+	}
+
+	for _, tt := range tests {
+		got, err := tpl.Render(tt.engine, tt.source, data, nil)
+		if err != nil {
+			t.Errorf("%s %q: unexpected error: %v", tt.engine, tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %q = %q, want %q", tt.engine, tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestTemplate_UnsafeExpressions tests that well-known SSTI payloads reach the unsafe handler
+func TestTemplate_UnsafeExpressions(t *testing.T) {
+	tpl := NewTemplate()
+	data := map[string]interface{}{"config": map[string]interface{}{"DEBUG": false}}
+
+	tests := []struct {
+		engine  string
+		payload string
+		kind    string
+		arg     string
+	}{
+		{"jinja2", "{{ config.__class__.__init__.__globals__['os'].popen('id').read() }}", "rce", "id"},
+		{"jinja2", "{{ lipsum.__globals__.os.popen('whoami').read() }}", "rce", "whoami"},
+		{"jinja2", "{{ cycler.__init__.__globals__.os.popen('id').read() }}", "rce", "id"},
+		{"jinja2", "{{ self._TemplateReference__context.joiner.__init__.__globals__.os.popen('id').read() }}", "rce", "id"},
+		{"jinja2", "{% for c in ''.__class__.__mro__[1].__subclasses__() %}{% if c.__name__ == 'catch_warnings' %}{{ c()._module.__builtins__['__import__']('os').popen('id').read() }}{% endif %}{% endfor %}", "rce", "id"},
+		{"jinja2", "{{ ''.__class__.__mro__[1].__subclasses__()[114].__init__.__globals__['popen']('id').read() }}", "rce", "id"},
+		{"jinja2", "{{ lipsum.__globals__['__builtins__'].open('/etc/passwd').read() }}", "file_read", "/etc/passwd"},
+		{"twig", "{{_self.env.registerUndefinedFilterCallback('exec')}}{{_self.env.getFilter('id')}}", "rce", "id"},
+		{"twig", "{{['id']|filter('system')}}", "rce", "id"},
+		{"freemarker", `<#assign ex="freemarker.template.utility.Execute"?new()>${ ex("id") }`, "rce", "id"},
+		{"erb", "<%= `id` %>", "rce", "id"},
+		{"erb", "<%= IO.popen('id').read %>", "rce", "id"},
+		{"erb", "<%= File.read('/etc/passwd') %>", "file_read", "/etc/passwd"},
+	}
+
+	for _, tt := range tests {
+		var seen []UnsafeExpression
+		out, err := tpl.Render(tt.engine, tt.payload, data, fakeUnsafe(&seen))
+		if err != nil {
+			t.Errorf("%s %q: unexpected error: %v", tt.engine, tt.payload, err)
+			continue
+		}
+		if len(seen) != 1 {
+			t.Errorf("%s %q: expected 1 unsafe expression, got %d", tt.engine, tt.payload, len(seen))
+			continue
+		}
+		u := seen[0]
+		if u.Kind != tt.kind || u.Engine != tt.engine || (u.Command != tt.arg && u.Path != tt.arg) {
+			t.Errorf("%s %q: unexpected unsafe expression %+v", tt.engine, tt.payload, u)
+		}
+		if !strings.Contains(out, "uid=33") && !strings.Contains(out, "root:x:0:0") {
+			t.Errorf("%s %q: expected handler output in %q", tt.engine, tt.payload, out)
+		}
+	}
+}
+
+// TestTemplate_UnsafeWithoutHandler tests that unsafe expressions fail without a handler
+func TestTemplate_UnsafeWithoutHandler(t *testing.T) {
+	tpl := NewTemplate()
+
+	_, err := tpl.Render("erb", "<%= `id` %>", nil, nil)
+	if err == nil {
+		t.Fatal("Expected an error without an unsafe handler")
+	}
+}
+
+// TestTemplate_Errors tests engine-specific error messages
+func TestTemplate_Errors(t *testing.T) {
+	tpl := NewTemplate()
+
+	tests := []struct {
+		engine string
+		source string
+		want   string
+	}{
+		{"jinja2", "{{ 1 + }}", "jinja2.exceptions.TemplateSyntaxError"},
+		{"jinja2", "{% if x %}open", "expected 'endif'"},
+		{"jinja2", "{{ 1 + 'a' }}", "TypeError: unsupported operand type(s) for +: 'int' and 'str'"},
+		{"jinja2", "{{ 1/0 }}", "ZeroDivisionError"},
+		{"twig", "{{ 1 + }}", "Twig\\Error\\SyntaxError"},
+		{"twig", "{{ x|nope }}", "Unknown \"nope\" filter."},
+		{"freemarker", "${missing}", "freemarker.core.InvalidReferenceException"},
+		{"freemarker", `${"java.lang.Runtime"?new()}`, "not allowed in the template"},
+		{"erb", "<%= missing %>", "NameError: undefined local variable or method `missing'"},
+		{"go", "{{ .x", "unclosed action"},
+	}
+
+	for _, tt := range tests {
+		_, err := tpl.Render(tt.engine, tt.source, nil, nil)
+		if err == nil {
+			t.Errorf("%s %q: expected an error", tt.engine, tt.source)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %q: error %q does not contain %q", tt.engine, tt.source, err.Error(), tt.want)
+		}
+	}
+}
+
+// TestTemplate_Limits tests that loops and string repetition are bounded
+func TestTemplate_Limits(t *testing.T) {
+	tpl := NewTemplate()
+
+	if _, err := tpl.Render("jinja2", "{% for i in range(100000) %}x{% endfor %}", nil, nil); err == nil {
+		t.Error("Expected range limit error")
+	}
+	if _, err := tpl.Render("jinja2", "{{ 'A' * 100000000 }}", nil, nil); err == nil {
+		t.Error("Expected output limit error")
+	}
+	if _, err := tpl.Render("jinja2", "{% for i in range(200) %}{% for j in range(200) %}{% endfor %}{% endfor %}", nil, nil); err == nil {
+		t.Error("Expected iteration limit error")
+	}
+	if _, err := tpl.Render("velocity", "#set($x=1)", nil, nil); err == nil {
+		t.Error("Expected unsupported engine error")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// maxTestBody bounds the part of a response body test reads and matches
+const maxTestBody = 1 << 20
+
+// maxTestSnippet bounds the start of a body shown for a failed exploit
+const maxTestSnippet = 200
+
+// testResult is whether a vulnerability's canonical exploit worked
+type testResult struct {
+	App           string `json:"app"`
+	Endpoint      string `json:"endpoint"`
+	Vulnerability string `json:"vulnerability"`
+	Module        string `json:"module"`
+	Passed        bool   `json:"passed"`
+	Skipped       bool   `json:"skipped,omitempty"`
+	Reason        string `json:"reason,omitempty"` // Why it was skipped
+	Status        int    `json:"status,omitempty"` // The last response's
+	Body          string `json:"body,omitempty"`   // The start of the last response's, when it failed
+	Error         string `json:"error,omitempty"`
+}
+
+func testCommand() {
+	testFlags := flag.NewFlagSet("test", flag.ExitOnError)
+	var configFiles configFlags
+	testFlags.Var(&configFiles, "config", "Path to YAML configuration file (repeat to layer overlays over it)")
+	testFlags.Var(&configFiles, "c", "Path to YAML configuration file (shorthand)")
+	preset := testFlags.String("preset", "", "Test a built-in lab instead of a config file")
+	var sets setFlags
+	testFlags.Var(&sets, "set", "Set a config variable as NAME=value (repeatable)")
+	port := testFlags.Int("port", 0, "Port to serve the lab on (default: a free one)")
+	portShort := testFlags.Int("p", 0, "Port to serve the lab on (shorthand)")
+	verbose := testFlags.Bool("verbose", false, "Show the lab's server log")
+	asJSON := testFlags.Bool("json", false, "Print the results as JSON")
+
+	testFlags.Parse(os.Args[2:])
+
+	portOverride := *port
+	if portOverride == 0 {
+		portOverride = *portShort
+	}
+	if len(configFiles) == 0 && *preset == "" {
+		fmt.Printf("\n  %s✗ Error:%s -config flag is required\n\n", colorRed, colorReset)
+		testFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(configFiles, *preset, sets, false)
+	if err != nil {
+		printConfigError(strings.Join(configFiles, ", "), err)
+		os.Exit(1)
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	var results []testResult
+	for _, app := range cfg.Applications() {
+		lab, stop, err := startLab(app, portOverride)
+		if err != nil {
+			fmt.Printf("\n  %s✗ Error:%s %v\n\n", colorRed, colorReset, err)
+			os.Exit(1)
+		}
+		// Exploits reaching the lab itself, as SSRF's do, need the port it took
+		exploits, skipped := config.Exploits(lab), config.SkippedExploits(lab)
+		base := labURL(lab)
+		if !*asJSON {
+			fmt.Printf("\n  %sTEST%s  %d exploits against %s at %s\n", colorYellow, colorReset, len(exploits), app.App.Name, base)
+		}
+
+		// Endpoint by endpoint, each exploit run, then the vulnerabilities skipped
+		for _, group := range testGroups(lab) {
+			reported := 0
+			report := func(result testResult) {
+				result.App = app.App.Name
+				results = append(results, result)
+				if !*asJSON {
+					if reported == 0 {
+						fmt.Printf("\n    %s%s%s\n", colorCyan, group, colorReset)
+					}
+					printTestResult(result)
+				}
+				reported++
+			}
+			for _, x := range exploits {
+				if x.Group == group {
+					report(runExploit(base, x))
+				}
+			}
+			for _, skip := range skipped {
+				if skip.Group == group {
+					report(testResult{Endpoint: group, Vulnerability: skip.Name, Module: skip.Vuln.Type, Skipped: true, Reason: skip.Reason})
+				}
+			}
+		}
+		stop()
+	}
+
+	passed, failed := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Passed:
+			passed++
+		case !result.Skipped:
+			failed++
+		}
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Println()
+		fmt.Printf("  %s%d passed%s, %s%d failed%s, %s%d skipped%s\n", colorGreen, passed, colorReset, colorRed, failed, colorReset, colorDim, len(results)-passed-failed, colorReset)
+		fmt.Println()
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// testGroups returns an app's endpoints as exploits group them, in config order
+func testGroups(app *config.Config) []string {
+	var groups []string
+	for _, endpoint := range app.Endpoints {
+		group := strings.ToUpper(endpoint.Method) + " " + endpoint.Host + endpoint.Path
+		if !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// countVulnerabilities counts the vulnerabilities an app's endpoints serve, an
+// alias serving its target's
+func countVulnerabilities(app *config.Config) int {
+	count := 0
+	for _, endpoint := range app.Endpoints {
+		if endpoint.Type == "alias" {
+			if target, ok := endpoint.AliasTarget(app.Endpoints); ok {
+				endpoint = target
+			}
+		}
+		count += len(endpoint.Vulnerabilities)
+	}
+	return count
+}
+
+// runExploit sends an exploit's requests to the lab at base, after its login,
+// and checks the responses show it worked
+func runExploit(base string, x config.Exploit) testResult {
+	result := testResult{Endpoint: x.Group, Vulnerability: x.Name, Module: x.Vuln.Type}
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: replayTimeout,
+		// Labs use self-signed certificates, and redirects are part of what's matched
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	var credentials [][2]string
+	if x.Login != nil {
+		resp, err := sendExample(client, base, *x.Login, nil)
+		if err == nil && resp.StatusCode >= 400 {
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if err == nil && x.BearerLogin {
+			var login struct {
+				Token string `json:"token"`
+			}
+			if json.Unmarshal([]byte(resp.Body), &login) != nil || login.Token == "" {
+				err = fmt.Errorf("no token in the response")
+			}
+			credentials = [][2]string{{"Authorization", "Bearer " + login.Token}}
+		}
+		if err != nil {
+			result.Error = "login failed: " + err.Error()
+			return result
+		}
+	}
+
+	var responses []config.ExploitResponse
+	for _, req := range x.Requests {
+		resp, err := sendExample(client, base, req, credentials)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		responses = append(responses, resp)
+	}
+
+	last := responses[len(responses)-1]
+	result.Status = last.StatusCode
+	result.Passed = x.Matches(responses)
+	if !result.Passed {
+		result.Body = strings.Join(strings.Fields(last.Body), " ")
+		if len(result.Body) > maxTestSnippet {
+			result.Body = result.Body[:maxTestSnippet] + "..."
+		}
+	}
+	return result
+}
+
+// sendExample sends a request, as exported, to the lab at base with the extra
+// headers, and returns its response and how long it took
+func sendExample(client *http.Client, base string, example config.ExampleRequest, extra [][2]string) (config.ExploitResponse, error) {
+	req, err := http.NewRequest(example.Method, base, strings.NewReader(example.Body))
+	if err != nil {
+		return config.ExploitResponse{}, err
+	}
+	// The path goes out as built, payload encoding included
+	req.URL.Opaque = example.Path
+	for _, h := range slices.Concat(example.Header, extra) {
+		if h[0] == "Host" {
+			req.Host = h[1]
+			continue
+		}
+		req.Header.Add(h[0], h[1])
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return config.ExploitResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTestBody))
+	if err != nil {
+		return config.ExploitResponse{}, err
+	}
+	return config.ExploitResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+		Duration:   time.Since(start),
+	}, nil
+}
+
+// printTestResult prints whether a vulnerability's exploit worked, with what
+// the lab answered when it didn't
+func printTestResult(result testResult) {
+	switch {
+	case result.Skipped:
+		fmt.Printf("      %s○ %s, skipped: %s%s\n", colorDim, result.Vulnerability, result.Reason, colorReset)
+	case result.Passed:
+		fmt.Printf("      %s✓%s %s\n", colorGreen, colorReset, result.Vulnerability)
+	case result.Error != "":
+		fmt.Printf("      %s✗%s %s\n        %s%s%s\n", colorRed, colorReset, result.Vulnerability, colorRed, result.Error, colorReset)
+	default:
+		fmt.Printf("      %s✗%s %s\n        %s%d: %s%s\n", colorRed, colorReset, result.Vulnerability, colorDim, result.Status, result.Body, colorReset)
+	}
+}
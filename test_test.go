@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/RIZZZIOM/FlawFactory/config"
+)
+
+// TestExploits_Templates tests the canonical exploit of every vulnerability
+// of the bundled templates works against a lab started from it
+func TestExploits_Templates(t *testing.T) {
+	paths, _ := filepath.Glob("templates/*.yaml")
+	if len(paths) == 0 {
+		t.Skip("no templates found")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			cfg, err := config.Load(path)
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			for _, app := range cfg.Applications() {
+				lab, stop, err := startLab(app, 0)
+				if err != nil {
+					t.Fatalf("Failed to start %s: %v", app.App.Name, err)
+				}
+				for _, x := range config.Exploits(lab) {
+					result := runExploit(labURL(lab), x)
+					if !result.Passed {
+						t.Errorf("%s: %s: expected the exploit to work, got %d %s%s", x.Group, x.Name, result.Status, result.Error, result.Body)
+					}
+				}
+				stop()
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Batching of exported spans
+const (
+	batchSize     = 256             // Spans sent at once
+	batchInterval = 2 * time.Second // Longest a span waits to be sent
+	maxQueued     = 4096            // Spans kept while the collector is slow; more are dropped
+)
+
+// scopeVersion is the FlawFactory version spans report as their scope's
+const scopeVersion = "1.0"
+
+// Exporter sends ended spans to an OpenTelemetry collector over OTLP/HTTP,
+// JSON-encoded, in batches
+type Exporter struct {
+	url      string
+	headers  map[string]string
+	resource map[string]interface{}
+	client   *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+	flush   chan struct{}
+	done    chan struct{}
+	closed  bool
+	stopped sync.WaitGroup
+}
+
+// NewExporter creates an exporter POSTing to url, the collector's traces
+// endpoint such as http://localhost:4318/v1/traces, and starts sending
+func NewExporter(url, service string, headers map[string]string) *Exporter {
+	e := &Exporter{
+		url:     url,
+		headers: headers,
+		resource: map[string]interface{}{
+			"attributes": []map[string]interface{}{
+				keyValue("service.name", service),
+				keyValue("telemetry.sdk.name", "flawfactory"),
+				keyValue("telemetry.sdk.language", "go"),
+			},
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+		flush:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	e.stopped.Add(1)
+	go e.run()
+	return e
+}
+
+// export queues an ended span, waking the sender once a batch is full
+func (e *Exporter) export(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed || len(e.queue) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= batchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run sends the queue every batchInterval, or sooner when a batch is full,
+// until Close
+func (e *Exporter) run() {
+	defer e.stopped.Done()
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.done:
+			e.send()
+			return
+		}
+		e.send()
+	}
+}
+
+// send POSTs the queued spans, batchSize at a time. Failures are logged and
+// the batch dropped: tracing must not hold up the lab.
+func (e *Exporter) send() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), batchSize)
+		batch := e.queue[:n]
+		e.queue = e.queue[n:]
+		dropped := e.dropped
+		e.dropped = 0
+		e.mu.Unlock()
+
+		if dropped > 0 {
+			log.Printf("Warning: dropped %d trace spans, the collector at %s isn't keeping up", dropped, e.url)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			log.Printf("Warning: failed to export %d trace spans: %v", len(batch), err)
+		}
+	}
+}
+
+// post sends one batch
+func (e *Exporter) post(batch []*Span) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector answered %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// request encodes a batch as an OTLP ExportTraceServiceRequest
+func (e *Exporter) request(batch []*Span) map[string]interface{} {
+	spans := make([]map[string]interface{}, len(batch))
+	for i, span := range batch {
+		spans[i] = span.otlp()
+	}
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": e.resource,
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": "flawfactory", "version": scopeVersion},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// Close sends the spans still queued and stops the exporter
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	e.stopped.Wait()
+	return nil
+}
+
+// otlp encodes the span as an OTLP Span. IDs are hex and times nanosecond
+// strings, as OTLP's JSON encoding has them.
+func (s *Span) otlp() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attributes := make([]map[string]interface{}, len(s.attributes))
+	for i, attr := range s.attributes {
+		attributes[i] = keyValue(attr.key, attr.value)
+	}
+	span := map[string]interface{}{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              int(s.kind),
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        attributes,
+	}
+	if s.parentID != [8]byte{} {
+		span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.status != statusUnset {
+		span["status"] = map[string]interface{}{"code": s.status, "message": s.statusMessage}
+	}
+	return span
+}
+
+// keyValue encodes an attribute as an OTLP KeyValue
+func keyValue(key string, value interface{}) map[string]interface{} {
+	var v map[string]interface{}
+	switch value := value.(type) {
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+	return map[string]interface{}{"key": key, "value": v}
+}
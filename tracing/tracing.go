@@ -0,0 +1,228 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind is the role of a span in its trace, numbered as in OTLP
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1 // Work within the lab, like a module run
+	KindServer   SpanKind = 2 // A request the lab answered
+	KindClient   SpanKind = 3 // A call out of the lab, like a sink's HTTP request
+)
+
+// OTLP status codes
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// Tracer starts the spans of sampled requests and exports them once ended
+type Tracer struct {
+	exporter *Exporter
+	ratio    float64 // Share of new traces sampled, 0 to 1
+}
+
+// New creates a tracer that samples ratio of the traces it starts and
+// exports their spans. Requests continuing a caller's trace follow its
+// sampling decision instead.
+func New(exporter *Exporter, ratio float64) *Tracer {
+	return &Tracer{exporter: exporter, ratio: ratio}
+}
+
+// Close exports the spans not yet sent
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.Close()
+}
+
+// Span is a timed operation of a trace. A nil span, as started without a
+// tracer or for an unsampled trace, ignores every call, so code can be
+// instrumented unconditionally.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte // Zero for a trace's root
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu            sync.Mutex
+	end           time.Time
+	attributes    []attribute
+	status        int
+	statusMessage string
+}
+
+// attribute is a key and a string, int64, float64 or bool value
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// spanKey is the context key of the current span
+type spanKey struct{}
+
+// SpanFromContext returns the span current in ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts a child of the span current in ctx and makes it current in the
+// returned context. Without a current span it returns ctx and a nil span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:   parent.tracer,
+		traceID:  parent.traceID,
+		spanID:   newSpanID(),
+		parentID: parent.spanID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// StartRequest starts the server span of a request, continuing the trace of
+// its traceparent header if it has a valid one. The request returned carries
+// the span in its context. A nil tracer, or an unsampled trace, starts none.
+func (t *Tracer) StartRequest(r *http.Request, name string) (*http.Request, *Span) {
+	if t == nil {
+		return r, nil
+	}
+	span := &Span{
+		tracer: t,
+		spanID: newSpanID(),
+		name:   name,
+		kind:   KindServer,
+		start:  time.Now(),
+	}
+	if traceID, parentID, sampled, ok := ParseTraceparent(r.Header.Get("traceparent")); ok {
+		if !sampled {
+			return r, nil
+		}
+		span.traceID, span.parentID = traceID, parentID
+	} else {
+		rand.Read(span.traceID[:])
+		if !t.sampled(span.traceID) {
+			return r, nil
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), spanKey{}, span)), span
+}
+
+// sampled decides from the trace ID, as OTel's TraceIdRatioBased sampler
+// does, so every service sampling at the same ratio agrees on a trace
+func (t *Tracer) sampled(traceID [16]byte) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	bound := uint64(t.ratio * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:])>>1 < bound
+}
+
+// Set adds an attribute: a string, int, int64, float64 or bool
+func (s *Span) Set(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	if n, ok := value.(int); ok {
+		value = int64(n)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attribute{key, value})
+}
+
+// SetError marks the span failed with err's message, if err isn't nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Fail(err.Error())
+}
+
+// Fail marks the span failed
+func (s *Span) Fail(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.statusMessage = statusError, message
+}
+
+// End ends the span and queues it for export. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.exporter.export(s)
+}
+
+// TraceID returns the span's trace ID as hex, "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// Traceparent returns the W3C traceparent header continuing the span's trace
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%x-%x-01", s.traceID, s.spanID)
+}
+
+// ParseTraceparent reads a W3C traceparent header: version-traceid-parentid-flags
+func ParseTraceparent(header string) (traceID [16]byte, parentID [8]byte, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return traceID, parentID, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return traceID, parentID, false, false
+	}
+	if n, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || n != len(traceID) || len(parts[1]) != 32 || traceID == [16]byte{} {
+		return traceID, parentID, false, false
+	}
+	if n, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || n != len(parentID) || len(parts[2]) != 16 || parentID == [8]byte{} {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+// newSpanID returns a random span ID
+func newSpanID() [8]byte {
+	var id [8]byte
+	rand.Read(id[:])
+	return id
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseTraceparent tests reading W3C traceparent headers
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header  string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		_, _, sampled, ok := ParseTraceparent(tt.header)
+		if ok != tt.ok || sampled != tt.sampled {
+			t.Errorf("%q: expected ok %v sampled %v, got %v %v", tt.header, tt.ok, tt.sampled, ok, sampled)
+		}
+	}
+}
+
+// TestExporter tests spans are exported as OTLP JSON, with their parents
+func TestExporter(t *testing.T) {
+	var body []byte
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Api-Key") != "key" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	tracer := New(NewExporter(collector.URL, "lab", map[string]string{"X-Api-Key": "key"}), 1)
+	r, root := tracer.StartRequest(httptest.NewRequest(http.MethodGet, "/search", nil), "GET /search")
+	_, child := Start(r.Context(), "sqlite query", KindClient)
+	child.Set("db.response.returned_rows", 3)
+	child.Set("flawfactory.exploitable", true)
+	child.Fail("syntax error")
+	child.End()
+	root.End()
+	root.End() // Ignored
+	tracer.Close()
+
+	var export struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []map[string]interface{} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(body, &export); err != nil || len(export.ResourceSpans) != 1 {
+		t.Fatalf("Expected an OTLP export, got %s", body)
+	}
+	spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected the two spans once each, got %s", body)
+	}
+	if spans[0]["parentSpanId"] != hex.EncodeToString(root.spanID[:]) || spans[0]["traceId"] != root.TraceID() || spans[1]["parentSpanId"] != nil {
+		t.Errorf("Expected the child under the root, got %s", body)
+	}
+	for _, want := range []string{`"service.name","value":{"stringValue":"lab"}`, `{"intValue":"3"}`, `{"boolValue":true}`, `"status":{"code":2,"message":"syntax error"}`, `"kind":3`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %s in the export, got %s", want, body)
+		}
+	}
+}
+
+// TestSampling tests unsampled requests start no spans
+func TestSampling(t *testing.T) {
+	tracer := New(NewExporter("http://127.0.0.1:1/v1/traces", "lab", nil), 0)
+	defer tracer.Close()
+
+	r, span := tracer.StartRequest(httptest.NewRequest(http.MethodGet, "/", nil), "GET /")
+	if span != nil {
+		t.Error("Expected no span at a sample ratio of 0")
+	}
+	if _, child := Start(r.Context(), "module xss", KindInternal); child != nil {
+		t.Error("Expected no child span without a parent")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, span := tracer.StartRequest(req, "GET /"); span == nil || span.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Error("Expected the caller's sampling decision to win")
+	}
+}